@@ -34,6 +34,9 @@ export IWDLI_LANDSCAPE_CONFIG_DIR="${IWDLI_LANDSCAPE_CONFIG_DIR:-}"
 export IWDLI_DETECTION_CONFIG_DIR="${IWDLI_DETECTION_CONFIG_DIR:-}"
 
 ## Session global private constants
+# version of the CSV output format, read by the reporter: 2 writes one numbered
+# <PRODUCT>_INSTALL_PATH_NN and <PRODUCT>_RUNNING_COMMANDLINES_NN field per path and process
+iwdli_csv_format_version="2"
 iwdli_session_timestamp=$(date -u '+%Y-%m-%d_%H%M%S')
 iwdli_session_audit_dir=${IWDLI_SESSION_AUDIT_DIR:-${IWDLI_AUDIT_DIR}/${iwdli_session_timestamp}}
 # note that user MAY provide a IWDLI_SESSION_AUDIT_DIR folder if they want to keep the audit files in an upfront defined folder
@@ -1652,6 +1655,7 @@ write_error_csv() {
   {
     echo "Parameter,Value"
     echo "DETECTION_TIMESTAMP,$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+    echo "CSV_FORMAT_VERSION,${iwdli_csv_format_version}"
     echo "SESSION_AUDIT_DIRECTORY,${iwdli_session_audit_dir}"
     echo "LANDSCAPE_CONFIG_DIR,${IWDLI_LANDSCAPE_CONFIG_DIR:-NOT_SET}"
     echo "HOSTNAME,${hostname_short}"
@@ -2327,6 +2331,7 @@ main() {
   # Create CSV file with header
   echo "Parameter,Value" > "$iwdli_output_file"
   write_csv "DETECTION_TIMESTAMP" "$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+  write_csv "CSV_FORMAT_VERSION" "$iwdli_csv_format_version"
   write_csv "SESSION_AUDIT_DIRECTORY" "$iwdli_session_audit_dir"
   write_csv "LANDSCAPE_CONFIG_DIR" "$IWDLI_LANDSCAPE_CONFIG_DIR"
   write_csv "HOSTNAME" "$hostname_short"
@@ -167,7 +167,7 @@ test_idempotent_import() {
         --input-dir "${INPUT_DIR}" \
         > /dev/null 2>&1
     
-    # Check count didn't increase (update, not insert)
+    # Check count didn't increase (skipped as already imported, not inserted)
     AFTER_COUNT=$(sqlite3 "${TEST_DB}" "SELECT COUNT(*) FROM measurements;")
     
     if [ "${AFTER_COUNT}" -ne "${BEFORE_COUNT}" ]; then
@@ -176,7 +176,7 @@ test_idempotent_import() {
         exit 1
     fi
     
-    log_info "✓ Idempotent import verified (records updated, not duplicated)"
+    log_info "✓ Idempotent import verified (already imported files skipped, not duplicated)"
 }
 
 # Test 8: Test error handling with invalid CSV
@@ -16,12 +16,14 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/cli"
 )
 
 func main() {
 	if err := cli.Execute(); err != nil {
-		log.Fatal(err)
+		log.Print(err)
+		os.Exit(cli.ExitCode(err))
 	}
 }
@@ -3,11 +3,18 @@ module github.com/ibm-webmethods-aftermarket-tools/iwldr
 go 1.24
 
 require (
+	github.com/ProtonMail/go-crypto v1.5.1
 	github.com/mattn/go-sqlite3 v1.14.28
+	github.com/pkg/sftp v1.13.9
 	github.com/spf13/cobra v1.8.1
+	github.com/spf13/pflag v1.0.5
+	golang.org/x/crypto v0.41.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/cloudflare/circl v1.6.3 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/kr/fs v0.1.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
 )
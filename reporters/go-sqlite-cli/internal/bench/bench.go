@@ -0,0 +1,371 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bench measures the performance of the import and reporting paths on
+// generated landscapes: the CSV parse throughput, the import rate into a
+// database already holding a number of measurements, and the query time of the
+// reporting views at that scale. Results are written as JSON, so that the
+// results of two releases can be compared (see Compare).
+package bench
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+// DefaultViews are the reporting views timed by default: those behind the
+// daily summary, peak, monthly peak, compliance and host detail reports
+var DefaultViews = []string{
+	"v_daily_product_summary",
+	"v_peak_usage",
+	"v_monthly_peak",
+	"v_license_compliance_report",
+	"v_host_detail",
+}
+
+// Options are the parameters of a benchmark run
+type Options struct {
+	// Scales are the numbers of measurements of the databases benchmarked
+	Scales []int
+	// ImportFiles is the number of inspector files parsed and imported at
+	// each scale
+	ImportFiles int
+	// Views are the views timed at each scale (default: DefaultViews)
+	Views []string
+	// ViewTimeout, when set, interrupts the query of a view taking longer
+	ViewTimeout time.Duration
+	// Dir is the directory of the benchmark databases, removed once measured
+	// (default: a temporary directory)
+	Dir string
+	// Label identifies the run in the results, e.g. the release benchmarked
+	Label string
+	// Progress, when set, is called before each step
+	Progress func(step string)
+}
+
+// Result is the result of a benchmark run
+type Result struct {
+	Label         string        `json:"label,omitempty"`
+	StartedAt     time.Time     `json:"started_at"`
+	GoVersion     string        `json:"go_version"`
+	Platform      string        `json:"platform"`
+	CPUs          int           `json:"cpus"`
+	SchemaVersion string        `json:"schema_version"`
+	Parse         ParseResult   `json:"parse"`
+	Scales        []ScaleResult `json:"scales"`
+}
+
+// ParseResult is the CSV parse throughput
+type ParseResult struct {
+	Files          int     `json:"files"`
+	Bytes          int     `json:"bytes"`
+	Seconds        float64 `json:"seconds"`
+	FilesPerSecond float64 `json:"files_per_second"`
+	MBPerSecond    float64 `json:"mb_per_second"`
+}
+
+// ScaleResult are the results of a database of a number of measurements.
+// Scale is the number asked for, Measurements the number generated: the nodes
+// are all measured every day.
+type ScaleResult struct {
+	Scale        int          `json:"scale"`
+	Measurements int          `json:"measurements"`
+	Nodes        int          `json:"nodes"`
+	Days         int          `json:"days"`
+	SeedSeconds  float64      `json:"seed_seconds"`
+	Import       ImportResult `json:"import"`
+	Views        []ViewResult `json:"views"`
+}
+
+// ImportResult is the import rate of inspector files into the database of a
+// scale; records are the measurements and detected products created or
+// updated, as counted by the import summary
+type ImportResult struct {
+	Files            int     `json:"files"`
+	Records          int     `json:"records"`
+	Seconds          float64 `json:"seconds"`
+	FilesPerSecond   float64 `json:"files_per_second"`
+	RecordsPerSecond float64 `json:"records_per_second"`
+}
+
+// ViewResult is the time taken to count the rows of a view. A query
+// interrupted by the view timeout has no rows and the timeout as time.
+type ViewResult struct {
+	View     string  `json:"view"`
+	Rows     int     `json:"rows"`
+	Seconds  float64 `json:"seconds"`
+	TimedOut bool    `json:"timed_out,omitempty"`
+}
+
+// ParseScale parses a number of measurements, with an optional k (thousands)
+// or M (millions) suffix, e.g. 10k or 1M
+func ParseScale(s string) (int, error) {
+	value := strings.TrimSpace(s)
+	multiplier := 1
+	switch {
+	case strings.HasSuffix(value, "k"), strings.HasSuffix(value, "K"):
+		multiplier, value = 1000, value[:len(value)-1]
+	case strings.HasSuffix(value, "M"), strings.HasSuffix(value, "m"):
+		multiplier, value = 1000000, value[:len(value)-1]
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid scale %q: use a number of measurements such as 10000, 10k or 1M", s)
+	}
+	return n * multiplier, nil
+}
+
+// FormatScale formats a number of measurements as ParseScale reads it
+func FormatScale(n int) string {
+	switch {
+	case n >= 1000000 && n%1000000 == 0:
+		return fmt.Sprintf("%dM", n/1000000)
+	case n >= 1000 && n%1000 == 0:
+		return fmt.Sprintf("%dk", n/1000)
+	}
+	return strconv.Itoa(n)
+}
+
+// Run runs the benchmarks. Each scale is measured on a database of its own,
+// seeded with the catalog and a generated landscape, and removed afterwards.
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	if opts.ImportFiles <= 0 {
+		return nil, fmt.Errorf("the number of files imported must be positive")
+	}
+	if len(opts.Views) == 0 {
+		opts.Views = DefaultViews
+	}
+	progress := opts.Progress
+	if progress == nil {
+		progress = func(string) {}
+	}
+
+	dir := opts.Dir
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "iwldr-bench-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create benchmark directory: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+
+	result := &Result{
+		Label:         opts.Label,
+		StartedAt:     time.Now().UTC(),
+		GoVersion:     runtime.Version(),
+		Platform:      runtime.GOOS + "/" + runtime.GOARCH,
+		CPUs:          runtime.NumCPU(),
+		SchemaVersion: database.GetSchemaVersion(),
+	}
+
+	progress(fmt.Sprintf("parsing %d inspector files", opts.ImportFiles))
+	parse, err := benchParse(opts.ImportFiles)
+	if err != nil {
+		return nil, err
+	}
+	result.Parse = *parse
+
+	for _, scale := range opts.Scales {
+		scaleResult, err := runScale(ctx, dir, scale, opts, progress)
+		if err != nil {
+			return nil, fmt.Errorf("scale %s: %w", FormatScale(scale), err)
+		}
+		result.Scales = append(result.Scales, *scaleResult)
+	}
+	return result, nil
+}
+
+// benchParse times the parsing of generated inspector files
+func benchParse(files int) (*ParseResult, error) {
+	landscape, err := NewLandscape(files)
+	if err != nil {
+		return nil, err
+	}
+	names, contents := importFiles(landscape, files)
+
+	result := &ParseResult{Files: files}
+	start := time.Now()
+	for i, content := range contents {
+		if _, err := importer.ParseCSV(bytes.NewReader(content), names[i]); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", names[i], err)
+		}
+		result.Bytes += len(content)
+	}
+	result.Seconds = time.Since(start).Seconds()
+	result.FilesPerSecond = rate(float64(files), result.Seconds)
+	result.MBPerSecond = rate(float64(result.Bytes)/1e6, result.Seconds)
+	return result, nil
+}
+
+// runScale seeds a database of a number of measurements, imports files into
+// it and times the views
+func runScale(ctx context.Context, dir string, scale int, opts Options, progress func(string)) (*ScaleResult, error) {
+	landscape, err := NewLandscape(scale)
+	if err != nil {
+		return nil, err
+	}
+	result := &ScaleResult{Scale: scale, Measurements: landscape.Measurements(), Nodes: len(landscape.Nodes), Days: landscape.Days}
+
+	dbPath := filepath.Join(dir, fmt.Sprintf("bench-%s.db", FormatScale(scale)))
+	os.Remove(dbPath)
+	defer os.Remove(dbPath)
+	db, err := database.Connect(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if err := database.InitSchema(db); err != nil {
+		return nil, err
+	}
+	if err := importer.NewReferenceDataLoader(db).LoadCatalog(ctx, false); err != nil {
+		return nil, err
+	}
+
+	progress(fmt.Sprintf("%s: seeding %d measurements of %d nodes", FormatScale(scale), result.Measurements, result.Nodes))
+	start := time.Now()
+	if err := Seed(ctx, db, landscape); err != nil {
+		return nil, err
+	}
+	result.SeedSeconds = time.Since(start).Seconds()
+
+	progress(fmt.Sprintf("%s: importing %d inspector files", FormatScale(scale), opts.ImportFiles))
+	names, contents := importFiles(landscape, opts.ImportFiles)
+	service := importer.NewImportService(db)
+	result.Import.Files = len(contents)
+	start = time.Now()
+	for i, content := range contents {
+		imported, err := service.ImportCSV(ctx, bytes.NewReader(content), names[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to import %s: %w", names[i], err)
+		}
+		result.Import.Records += imported.RecordsCreated + imported.RecordsUpdated
+	}
+	result.Import.Seconds = time.Since(start).Seconds()
+	result.Import.FilesPerSecond = rate(float64(result.Import.Files), result.Import.Seconds)
+	result.Import.RecordsPerSecond = rate(float64(result.Import.Records), result.Import.Seconds)
+
+	for _, view := range opts.Views {
+		progress(fmt.Sprintf("%s: querying %s", FormatScale(scale), view))
+		viewResult, err := queryView(ctx, db, view, opts.ViewTimeout)
+		if err != nil {
+			return nil, err
+		}
+		result.Views = append(result.Views, *viewResult)
+	}
+	return result, nil
+}
+
+// queryView times the count of the rows of a view, interrupting the query
+// after timeout when set
+func queryView(ctx context.Context, db *sql.DB, view string, timeout time.Duration) (*ViewResult, error) {
+	queryCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		queryCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	result := &ViewResult{View: view}
+	start := time.Now()
+	err := db.QueryRowContext(queryCtx, `SELECT COUNT(*) FROM "`+view+`"`).Scan(&result.Rows)
+	result.Seconds = time.Since(start).Seconds()
+	switch {
+	case err != nil && ctx.Err() == nil && queryCtx.Err() == context.DeadlineExceeded:
+		result.TimedOut, result.Rows, result.Seconds = true, 0, timeout.Seconds()
+	case err != nil:
+		return nil, fmt.Errorf("failed to query %s: %w", view, err)
+	}
+	return result, nil
+}
+
+// importFiles returns inspector files of the nodes of the landscape measured
+// at noon of its days, later than the measurements Seed writes at 06:00
+func importFiles(l *Landscape, files int) (names []string, contents [][]byte) {
+	for i := 0; i < files; i++ {
+		node := l.Nodes[i%len(l.Nodes)]
+		day := (i / len(l.Nodes)) % l.Days
+		name, content := node.InspectorFile(l.Day(day).Add(6*time.Hour + time.Duration(i/(len(l.Nodes)*l.Days))*time.Second))
+		names = append(names, name)
+		contents = append(contents, content)
+	}
+	return names, contents
+}
+
+// rate returns count per second, 0 when no time was measured
+func rate(count, seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	return count / seconds
+}
+
+// Regression is a metric of a benchmark run worse than in a baseline run
+type Regression struct {
+	Scale     string  `json:"scale,omitempty"` // empty for the parse throughput
+	Metric    string  `json:"metric"`
+	Baseline  float64 `json:"baseline"`
+	Current   float64 `json:"current"`
+	ChangePct float64 `json:"change_pct"` // positive when worse
+}
+
+// Compare returns the metrics of current worse than in baseline by more than
+// thresholdPct percent: the parse and import rates, which should not drop, and
+// the view query times, which should not grow. Scales and views missing from
+// either run are not compared.
+func Compare(baseline, current *Result, thresholdPct float64) []Regression {
+	var regressions []Regression
+	check := func(scale, metric string, base, cur float64, higherIsBetter bool) {
+		if base <= 0 {
+			return
+		}
+		change := (cur - base) / base * 100
+		if higherIsBetter {
+			change = -change
+		}
+		if change > thresholdPct {
+			regressions = append(regressions, Regression{Scale: scale, Metric: metric, Baseline: base, Current: cur, ChangePct: change})
+		}
+	}
+
+	check("", "parse files/s", baseline.Parse.FilesPerSecond, current.Parse.FilesPerSecond, true)
+	for _, cur := range current.Scales {
+		for _, base := range baseline.Scales {
+			if base.Scale != cur.Scale {
+				continue
+			}
+			scale := FormatScale(cur.Scale)
+			check(scale, "import records/s", base.Import.RecordsPerSecond, cur.Import.RecordsPerSecond, true)
+			for _, view := range cur.Views {
+				for _, baseView := range base.Views {
+					if baseView.View == view.View {
+						check(scale, view.View+" seconds", baseView.Seconds, view.Seconds, false)
+					}
+				}
+			}
+		}
+	}
+	return regressions
+}
@@ -0,0 +1,152 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bench_test
+
+import (
+	"bytes"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/bench"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestRun(t *testing.T) {
+	for input, want := range map[string]int{"200": 200, "10k": 10000, "1M": 1000000} {
+		if got, err := bench.ParseScale(input); err != nil || got != want {
+			t.Errorf("ParseScale(%q) = %d, %v; want %d", input, got, err, want)
+		}
+	}
+	if _, err := bench.ParseScale("ten"); err == nil {
+		t.Error("Expected an invalid scale to be rejected")
+	}
+
+	result, err := bench.Run(t.Context(), bench.Options{Scales: []int{400}, ImportFiles: 5, Dir: t.TempDir(), Label: "test"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Parse.Files != 5 || result.Parse.Bytes == 0 {
+		t.Errorf("Unexpected parse result: %+v", result.Parse)
+	}
+	if len(result.Scales) != 1 {
+		t.Fatalf("Expected 1 scale, got %d", len(result.Scales))
+	}
+	scale := result.Scales[0]
+	if scale.Measurements != 730 || scale.Nodes != 2 || scale.Days != 365 {
+		t.Errorf("Unexpected landscape: %+v", scale)
+	}
+	if scale.Import.Files != 5 || scale.Import.Records != 15 {
+		t.Errorf("Expected 5 measurements of 2 products imported, got %+v", scale.Import)
+	}
+	if len(scale.Views) != len(bench.DefaultViews) || scale.Views[0].Rows == 0 {
+		t.Errorf("Unexpected view results: %+v", scale.Views)
+	}
+
+	timedOut, err := bench.Run(t.Context(), bench.Options{Scales: []int{400}, ImportFiles: 1, Dir: t.TempDir(),
+		Views: []string{"v_peak_usage"}, ViewTimeout: time.Nanosecond})
+	if err != nil || !timedOut.Scales[0].Views[0].TimedOut {
+		t.Errorf("Expected the view query to time out, got %+v (%v)", timedOut, err)
+	}
+
+	current := *result
+	current.Scales = []bench.ScaleResult{scale}
+	current.Scales[0].Import.RecordsPerSecond = scale.Import.RecordsPerSecond / 2
+	regressions := bench.Compare(result, &current, 20)
+	if len(regressions) != 1 || regressions[0].Metric != "import records/s" {
+		t.Errorf("Expected the import rate regression, got %+v", regressions)
+	}
+}
+
+// BenchmarkParseCSV measures the parsing of an inspector file
+func BenchmarkParseCSV(b *testing.B) {
+	landscape, err := bench.NewLandscape(1)
+	if err != nil {
+		b.Fatal(err)
+	}
+	name, content := landscape.Nodes[0].InspectorFile(landscape.Day(0))
+	b.SetBytes(int64(len(content)))
+	for b.Loop() {
+		if _, err := importer.ParseCSV(bytes.NewReader(content), name); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkImport measures the import of inspector files into databases of
+// 10k and 100k measurements
+func BenchmarkImport(b *testing.B) {
+	for _, scale := range []int{10000, 100000} {
+		b.Run(bench.FormatScale(scale), func(b *testing.B) {
+			landscape, db := seededDB(b, scale)
+			service := importer.NewImportService(db)
+			i := 0
+			for b.Loop() {
+				node := landscape.Nodes[i%len(landscape.Nodes)]
+				day := i / len(landscape.Nodes) % landscape.Days
+				name, content := node.InspectorFile(landscape.Day(day).Add(6*time.Hour + time.Duration(i)*time.Second))
+				if _, err := service.ImportCSV(b.Context(), bytes.NewReader(content), name); err != nil {
+					b.Fatal(err)
+				}
+				i++
+			}
+		})
+	}
+}
+
+// BenchmarkViews measures the queries of the reporting views on databases of
+// 10k and 100k measurements
+func BenchmarkViews(b *testing.B) {
+	for _, scale := range []int{10000, 100000} {
+		b.Run(bench.FormatScale(scale), func(b *testing.B) {
+			_, db := seededDB(b, scale)
+			for _, view := range bench.DefaultViews {
+				b.Run(view, func(b *testing.B) {
+					var rows int
+					for b.Loop() {
+						if err := db.QueryRowContext(b.Context(), `SELECT COUNT(*) FROM `+view).Scan(&rows); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+func seededDB(b *testing.B, scale int) (*bench.Landscape, *sql.DB) {
+	b.Helper()
+	landscape, err := bench.NewLandscape(scale)
+	if err != nil {
+		b.Fatal(err)
+	}
+	db, err := database.Connect(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	if err := database.InitSchema(db); err != nil {
+		b.Fatal(err)
+	}
+	if err := importer.NewReferenceDataLoader(db).LoadCatalog(b.Context(), false); err != nil {
+		b.Fatal(err)
+	}
+	if err := bench.Seed(b.Context(), db, landscape); err != nil {
+		b.Fatal(err)
+	}
+	return landscape, db
+}
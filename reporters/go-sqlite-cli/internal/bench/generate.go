@@ -0,0 +1,208 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bench
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+// productsPerNode is the number of products detected on each generated node
+const productsPerNode = 2
+
+// nodesPerHost is the number of generated nodes sharing a physical host
+const nodesPerHost = 4
+
+// Node is a generated node measured by the benchmarks
+type Node struct {
+	FQDN           string
+	Hostname       string
+	Mode           string // PROD or NON PROD
+	Cores          int
+	PhysicalHostID string
+	HostCores      int
+	Products       []string // product codes of the catalog
+}
+
+// Landscape is a generated landscape of nodes measured once a day over a
+// number of days ending with End
+type Landscape struct {
+	Nodes []Node
+	Days  int
+	End   time.Time // the day of the last measurements, at midnight UTC
+}
+
+// NewLandscape generates a landscape holding the given number of daily
+// measurements, over a year at most, ending yesterday. The nodes detect the
+// products of the embedded catalog, so that a database seeded with the catalog
+// reports them.
+func NewLandscape(measurements int) (*Landscape, error) {
+	catalog, err := importer.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	byMode := map[string][]string{}
+	for _, product := range catalog {
+		byMode[product.Mode] = append(byMode[product.Mode], product.MnemoCode)
+	}
+	if len(byMode["PROD"]) < productsPerNode || len(byMode["NON PROD"]) < productsPerNode {
+		return nil, fmt.Errorf("the catalog has too few products")
+	}
+
+	days := min(measurements, 365)
+	if days < 1 {
+		return nil, fmt.Errorf("the number of measurements must be positive")
+	}
+	nodes := (measurements + days - 1) / days
+
+	l := &Landscape{
+		Days: days,
+		End:  time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1),
+	}
+	for i := 0; i < nodes; i++ {
+		mode := "PROD"
+		if i%3 == 0 {
+			mode = "NON PROD"
+		}
+		codes := byMode[mode]
+		node := Node{
+			FQDN:           fmt.Sprintf("bench%05d.example.com", i),
+			Hostname:       fmt.Sprintf("bench%05d", i),
+			Mode:           mode,
+			Cores:          2 << (i % 4),
+			PhysicalHostID: fmt.Sprintf("bench-host-%04d", i/nodesPerHost),
+			HostCores:      64,
+		}
+		for p := 0; p < productsPerNode; p++ {
+			node.Products = append(node.Products, codes[(i+p)%len(codes)])
+		}
+		l.Nodes = append(l.Nodes, node)
+	}
+	return l, nil
+}
+
+// Measurements returns the number of measurements of the landscape
+func (l *Landscape) Measurements() int {
+	return len(l.Nodes) * l.Days
+}
+
+// Day returns the time of the measurements of a day, 0 being the first
+func (l *Landscape) Day(day int) time.Time {
+	return l.End.AddDate(0, 0, day-l.Days+1).Add(6 * time.Hour)
+}
+
+// InspectorFile returns the inspector CSV of a measurement of the node and
+// its filename
+func (n *Node) InspectorFile(at time.Time) (string, []byte) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Parameter,Value\nDETECTION_TIMESTAMP,%s\n", at.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "HOSTNAME,%s\nNODE_FQDN,%s\nNODE_TYPE,%s\n", n.Hostname, n.FQDN, nodeType(n.Mode))
+	fmt.Fprintf(&b, "OS_NAME,Linux\nOS_VERSION,8.10\nCPU_COUNT,%d\nIS_VIRTUALIZED,yes\nVIRT_TYPE,VMware\n", n.Cores)
+	fmt.Fprintf(&b, "PROCESSOR_VENDOR,GenuineIntel\nPROCESSOR_BRAND,Intel Xeon\nHOST_PHYSICAL_CPUS,%d\n", n.HostCores)
+	fmt.Fprintf(&b, "PHYSICAL_HOST_ID,%s\nHOST_ID_METHOD,vmware-uuid\nHOST_ID_CONFIDENCE,high\n", n.PhysicalHostID)
+	fmt.Fprintf(&b, "PROCESSOR_ELIGIBLE,true\nOS_ELIGIBLE,true\nVIRT_ELIGIBLE,true\nCONSIDERED_CPUS,%d\n", n.Cores)
+	for _, code := range n.Products {
+		fmt.Fprintf(&b, "%s,present\n%s_RUNNING_STATUS,running\n%s_RUNNING_COUNT,1\n", code, code, code)
+		fmt.Fprintf(&b, "%s_RUNNING_COMMANDLINES_01,/opt/softwareag/%s/bin/server\n", code, code)
+		fmt.Fprintf(&b, "%s_INSTALL_STATUS,installed\n%s_INSTALL_COUNT,1\n", code, code)
+		fmt.Fprintf(&b, "%s_INSTALL_PATH_01,/opt/softwareag/%s\n", code, code)
+	}
+	b.WriteString("DETECTION_RESULT,SUCCESS\n")
+
+	name := fmt.Sprintf("iwdli_output_%s_%s.csv", n.Hostname, at.UTC().Format("20060102_150405"))
+	return name, b.Bytes()
+}
+
+// nodeType returns the NODE_TYPE field of a mode
+func nodeType(mode string) string {
+	if mode == "PROD" {
+		return "PROD"
+	}
+	return "NON_PROD"
+}
+
+// Seed writes the nodes, physical hosts, measurements and detected products of
+// the landscape straight into the database, in one transaction: importing
+// a million inspector files one by one would take hours. The license terms
+// and product codes of the catalog must be loaded.
+func Seed(ctx context.Context, db *sql.DB, l *Landscape) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	node, err := tx.PrepareContext(ctx, `INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES (?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer node.Close()
+	host, err := tx.PrepareContext(ctx, `INSERT OR IGNORE INTO physical_hosts
+		(physical_host_id, host_id_method, host_id_confidence, first_seen, last_seen, max_physical_cpus)
+		VALUES (?, 'vmware-uuid', 'high', ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer host.Close()
+	measurement, err := tx.PrepareContext(ctx, `INSERT INTO measurements
+		(main_fqdn, detection_timestamp, node_type, node_fqdn, os_name, os_version, cpu_count, is_virtualized,
+		 virt_type, processor_vendor, processor_brand, host_physical_cpus, processor_eligible, os_eligible,
+		 virt_eligible, considered_cpus, physical_host_id, host_id_method, host_id_confidence)
+		VALUES (?, ?, ?, ?, 'Linux', '8.10', ?, 'yes', 'VMware', 'GenuineIntel', 'Intel Xeon', ?,
+		 'true', 'true', 'true', ?, ?, 'vmware-uuid', 'high')`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer measurement.Close()
+	detected, err := tx.PrepareContext(ctx, `INSERT INTO detected_products
+		(main_fqdn, product_mnemo_code, detection_timestamp, status, running_status, running_count, install_status, install_count)
+		VALUES (?, ?, ?, 'present', 'running', 1, 'installed', 1)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer detected.Close()
+
+	first, last := l.Day(0), l.Day(l.Days-1)
+	for _, n := range l.Nodes {
+		if _, err := node.ExecContext(ctx, n.FQDN, n.Hostname, n.Mode); err != nil {
+			return fmt.Errorf("failed to insert node %s: %w", n.FQDN, err)
+		}
+		if _, err := host.ExecContext(ctx, n.PhysicalHostID, first, last, n.HostCores); err != nil {
+			return fmt.Errorf("failed to insert physical host %s: %w", n.PhysicalHostID, err)
+		}
+		for day := 0; day < l.Days; day++ {
+			at := l.Day(day)
+			if _, err := measurement.ExecContext(ctx, n.FQDN, at, nodeType(n.Mode), n.FQDN, n.Cores,
+				fmt.Sprint(n.HostCores), n.Cores, n.PhysicalHostID); err != nil {
+				return fmt.Errorf("failed to insert measurement: %w", err)
+			}
+			for _, code := range n.Products {
+				if _, err := detected.ExecContext(ctx, n.FQDN, code, at); err != nil {
+					return fmt.Errorf("failed to insert detected product: %w", err)
+				}
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+	"github.com/spf13/cobra"
+)
+
+var (
+	analyzeDBPath      string
+	analyzeFormat      string
+	analyzeOutput      string
+	analyzeHost        string
+	analyzeFromDate    string
+	analyzeToDate      string
+	analyzeMinSeverity string
+	analyzeFailOn      string
+)
+
+// NewAnalyzeCmd creates the analyze command
+func NewAnalyzeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "analyze",
+		Short: "Analyze imported measurements",
+		Long:  "Check the imported measurements for data that needs a closer look before it is reported",
+	}
+
+	cmd.PersistentFlags().StringVar(&analyzeDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+
+	anomalies := &cobra.Command{
+		Use:   "anomalies",
+		Short: "Flag suspicious changes between consecutive measurements",
+		Long: `Compare every measurement with the previous measurement of the same node and
+flag the changes that affect the license count:
+
+  cpu-change             CPU count or considered cores changed: critical when
+                         the considered cores at least doubled, warning when
+                         they grew, info when they shrank
+  virtualization-change  the node switched between virtualized and bare metal
+                         (critical)
+  host-change            the node moved to another physical host ID (warning)
+  product-appeared       a product started running (warning)
+  product-disappeared    a product stopped running (info)
+
+--from and --to select the later measurement of each pair. Use --format json
+for alerting, and --fail-on to exit with an error when anomalies of a severity
+are found.
+
+Example:
+  iwdlr analyze anomalies --db-path data/license-monitor.db
+  iwdlr analyze anomalies --from 2025-10-01 --min-severity warning
+  iwdlr analyze anomalies --format json --fail-on critical`,
+		Args: cobra.NoArgs,
+		RunE: runAnalyzeAnomalies,
+	}
+	anomalies.Flags().StringVarP(&analyzeFormat, "format", "f", "table", "Output format: table, csv, json, xlsx")
+	anomalies.Flags().StringVarP(&analyzeOutput, "output", "o", "", "Output file (default: stdout)")
+	anomalies.Flags().StringVar(&analyzeHost, "host", "", "Filter by host FQDN (substring match)")
+	anomalies.Flags().StringVar(&analyzeFromDate, "from", "", "Filter from date (YYYY-MM-DD)")
+	anomalies.Flags().StringVar(&analyzeToDate, "to", "", "Filter to date (YYYY-MM-DD)")
+	anomalies.Flags().StringVar(&analyzeMinSeverity, "min-severity", "info", "Lowest severity shown: info, warning or critical")
+	anomalies.Flags().StringVar(&analyzeFailOn, "fail-on", "", "Exit with an error when anomalies of this severity or higher are found")
+
+	cmd.AddCommand(anomalies)
+	return cmd
+}
+
+func runAnalyzeAnomalies(cmd *cobra.Command, args []string) error {
+	minSeverity, err := reports.ParseSeverity(analyzeMinSeverity)
+	if err != nil {
+		return err
+	}
+	failOn := ""
+	if analyzeFailOn != "" {
+		if failOn, err = reports.ParseSeverity(analyzeFailOn); err != nil {
+			return err
+		}
+	}
+
+	var fromDate, toDate *time.Time
+	for _, d := range []struct {
+		value  string
+		target **time.Time
+	}{{analyzeFromDate, &fromDate}, {analyzeToDate, &toDate}} {
+		if d.value == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", d.value)
+		if err != nil {
+			return fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", d.value)
+		}
+		*d.target = &t
+	}
+
+	// Check database exists
+	if _, err := os.Stat(analyzeDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", analyzeDBPath)
+	}
+
+	db, err := database.Connect(analyzeDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	report := reports.NewAnomalyReport(db)
+	rows, err := report.Query(cmd.Context(), analyzeHost, minSeverity, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to analyze measurements: %w", err)
+	}
+
+	// An empty JSON array keeps alerting scripts simple
+	if len(rows) == 0 && analyzeFormat == "table" {
+		fmt.Println("No anomalies found")
+		return nil
+	}
+	if rows == nil {
+		rows = []reports.AnomalyRow{}
+	}
+
+	if err := writeOutput(report, rows, analyzeFormat, analyzeOutput); err != nil {
+		return err
+	}
+
+	if failOn != "" {
+		failing := 0
+		for _, row := range rows {
+			if reports.SeverityAtLeast(row.Severity, failOn) {
+				failing++
+			}
+		}
+		if failing > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d anomalies of severity %s or higher", failing, failOn)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/bench"
+)
+
+var (
+	benchScales           []string
+	benchImportFiles      int
+	benchViews            []string
+	benchViewTimeout      time.Duration
+	benchDir              string
+	benchLabel            string
+	benchOutput           string
+	benchBaseline         string
+	benchThreshold        float64
+	benchFailOnRegression bool
+)
+
+// NewBenchCmd creates the bench command
+func NewBenchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Measure the performance of imports and reporting views",
+		Long: `Measure the performance of the import and reporting paths on generated
+landscapes, leaving the database of --db-path alone:
+
+  parse   the parse throughput of inspector CSV files
+  import  the import rate of inspector files into a database already holding
+          the measurements of the scale
+  views   the time taken to query the reporting views at that scale
+
+Each scale of --scale is a number of measurements, e.g. 10k or 1M, generated
+over a year of daily measurements of the products of the catalog and written
+into a temporary database of its own. The 1M database takes minutes to
+generate and needs its disk space in --dir; the queries of the larger views can
+take much longer, so --view-timeout interrupts them.
+
+--output writes the results as JSON, to keep with the release measured.
+--baseline compares the results with those of another run and lists the rates
+and query times worse by more than --threshold percent; --fail-on-regression
+then fails the command.
+
+Example:
+  iwdlr bench --scale 10k,100k --output bench-10.20.json --label 10.20
+  iwdlr bench --baseline bench-10.19.json --fail-on-regression`,
+		Args: cobra.NoArgs,
+		RunE: runBench,
+	}
+
+	cmd.Flags().StringSliceVar(&benchScales, "scale", []string{"10k", "100k", "1M"}, "Numbers of measurements of the databases benchmarked")
+	cmd.Flags().IntVar(&benchImportFiles, "import-files", 100, "Number of inspector files parsed and imported at each scale")
+	cmd.Flags().StringSliceVar(&benchViews, "view", bench.DefaultViews, "Reporting views queried at each scale")
+	cmd.Flags().DurationVar(&benchViewTimeout, "view-timeout", 10*time.Minute, "Interrupt the query of a view taking longer (0 disables the timeout)")
+	cmd.Flags().StringVar(&benchDir, "dir", "", "Directory of the benchmark databases (default: a temporary directory)")
+	cmd.Flags().StringVar(&benchLabel, "label", "", "Label of the results, e.g. the release benchmarked")
+	cmd.Flags().StringVarP(&benchOutput, "output", "o", "", "Write the results as JSON to this file")
+	cmd.Flags().StringVar(&benchBaseline, "baseline", "", "JSON results of a previous run to compare with")
+	cmd.Flags().Float64Var(&benchThreshold, "threshold", 20, "Percentage by which a rate or query time may be worse than the baseline")
+	cmd.Flags().BoolVar(&benchFailOnRegression, "fail-on-regression", false, "Fail when a rate or query time is worse than the baseline")
+
+	return cmd
+}
+
+func runBench(cmd *cobra.Command, args []string) error {
+	opts := bench.Options{
+		ImportFiles: benchImportFiles,
+		Views:       benchViews,
+		ViewTimeout: benchViewTimeout,
+		Dir:         benchDir,
+		Label:       benchLabel,
+		Progress:    func(step string) { fmt.Fprintf(os.Stderr, "%s...\n", step) },
+	}
+	for _, value := range benchScales {
+		scale, err := bench.ParseScale(value)
+		if err != nil {
+			return withExitCode(ExitParse, err)
+		}
+		opts.Scales = append(opts.Scales, scale)
+	}
+
+	var baseline *bench.Result
+	if benchBaseline != "" {
+		data, err := os.ReadFile(benchBaseline)
+		if err != nil {
+			return fmt.Errorf("failed to read baseline: %w", err)
+		}
+		if err := json.Unmarshal(data, &baseline); err != nil {
+			return withExitCode(ExitParse, fmt.Errorf("failed to parse baseline %s: %w", benchBaseline, err))
+		}
+	}
+	cmd.SilenceUsage = true
+
+	result, err := bench.Run(cmd.Context(), opts)
+	if err != nil {
+		return fmt.Errorf("benchmark failed: %w", err)
+	}
+
+	if benchOutput != "" {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(benchOutput, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write results: %w", err)
+		}
+	}
+
+	printBenchResult(result)
+	if benchOutput != "" {
+		fmt.Printf("\nResults written to %s\n", benchOutput)
+	}
+
+	if baseline == nil {
+		return nil
+	}
+	regressions := bench.Compare(baseline, result, benchThreshold)
+	if len(regressions) == 0 {
+		fmt.Printf("\nNo regression from %s (threshold %.0f%%)\n", benchBaseline, benchThreshold)
+		return nil
+	}
+	fmt.Printf("\nRegressions from %s (threshold %.0f%%):\n", benchBaseline, benchThreshold)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SCALE\tMETRIC\tBASELINE\tCURRENT\tWORSE BY")
+	for _, r := range regressions {
+		scale := r.Scale
+		if scale == "" {
+			scale = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.3f\t%.3f\t%.0f%%\n", scale, r.Metric, r.Baseline, r.Current, r.ChangePct)
+	}
+	tw.Flush()
+	if benchFailOnRegression {
+		return fmt.Errorf("%d metrics worse than in %s", len(regressions), benchBaseline)
+	}
+	return nil
+}
+
+// printBenchResult prints the results of a benchmark run as tables
+func printBenchResult(result *bench.Result) {
+	title := "Benchmark"
+	if result.Label != "" {
+		title += " " + result.Label
+	}
+	fmt.Printf("\n%s (%s, %s, %d CPUs, schema %s)\n", title, result.GoVersion, result.Platform, result.CPUs, result.SchemaVersion)
+	fmt.Printf("Parse: %d files, %.0f files/s, %.1f MB/s\n\n",
+		result.Parse.Files, result.Parse.FilesPerSecond, result.Parse.MBPerSecond)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SCALE\tMEASUREMENTS\tSEED (s)\tIMPORT FILES/S\tIMPORT RECORDS/S")
+	for _, scale := range result.Scales {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%.1f\t%.0f\n", bench.FormatScale(scale.Scale), scale.Measurements,
+			scale.SeedSeconds, scale.Import.FilesPerSecond, scale.Import.RecordsPerSecond)
+	}
+	tw.Flush()
+
+	fmt.Println()
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "VIEW")
+	for _, scale := range result.Scales {
+		fmt.Fprintf(tw, "\t%s (s)", bench.FormatScale(scale.Scale))
+	}
+	fmt.Fprintln(tw)
+	if len(result.Scales) > 0 {
+		for i, view := range result.Scales[0].Views {
+			cells := []string{view.View}
+			for _, scale := range result.Scales {
+				cell := fmt.Sprintf("%.3f", scale.Views[i].Seconds)
+				if scale.Views[i].TimedOut {
+					cell = "timed out"
+				}
+				cells = append(cells, cell)
+			}
+			fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		}
+	}
+	tw.Flush()
+}
@@ -0,0 +1,288 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/collector"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	collectDBPath      string
+	collectSourcesPath string
+	collectOnly        []string
+	collectDownloadDir string
+	collectKnownHosts  string
+	collectInsecure    bool
+	collectTimeout     time.Duration
+	collectNoImport    bool
+	collectStrict      bool
+	collectStatus      bool
+	collectWait        time.Duration
+	collectNoWait      bool
+)
+
+// NewCollectCmd creates the collect command
+func NewCollectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "collect",
+		Short: "Download new inspector CSV files over SFTP and import them",
+		Long: `Connect to each configured source (a monitored host or a drop server) over
+SFTP, download the iwdli_output_*.csv files (also gzip compressed) and the
+.zip and .tar.gz bundles found below its remote directory that were not
+collected before, and import them.
+
+Sources are listed in a CSV file:
+
+  source-name,address,user,identity-file,remote-dir
+  drop,drop.example.com,iwldr,/home/iwldr/.ssh/id_ed25519,/data/inspector
+  aix1,aix1.example.com:2222,inspect,,/opt/iwdli/output
+
+Sources without an identity-file authenticate with the password in the
+IWLDR_SFTP_PASSWORD environment variable. Host keys are verified against
+--known-hosts.
+
+Collection state is kept per source in the database: a remote file is only
+downloaded again when its size or modification time changes. Downloads are
+stored below <download-dir>/<source-name>/ with the remote directory layout.
+Files that fail to import are recorded for 'import retry-failed'.
+
+The command exits with an error if any source could not be collected; the
+other sources are still collected and imported.
+
+Example:
+  # Collect from all sources and import new files
+  iwdlr collect --db-path ./data/license-monitor.db --sources ./config/sources.csv
+
+  # Collect from one source without importing
+  iwdlr collect --sources ./config/sources.csv --source drop --no-import
+
+  # Show the collection state of every source
+  iwdlr collect --db-path ./data/license-monitor.db --status`,
+		RunE: runCollect,
+	}
+
+	cmd.Flags().StringVar(&collectDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+	cmd.Flags().StringVar(&collectSourcesPath, "sources", "",
+		"CSV file listing the collection sources")
+	cmd.Flags().StringSliceVar(&collectOnly, "source", nil,
+		"Only collect from the named source (repeatable)")
+	cmd.Flags().StringVar(&collectDownloadDir, "download-dir", "data/collected",
+		"Directory downloaded files are stored in")
+	cmd.Flags().StringVar(&collectKnownHosts, "known-hosts", defaultKnownHosts(),
+		"known_hosts file used to verify host keys")
+	cmd.Flags().BoolVar(&collectInsecure, "insecure-ignore-host-key", false,
+		"Do not verify host keys (testing only)")
+	cmd.Flags().DurationVar(&collectTimeout, "timeout", 30*time.Second,
+		"Connection timeout per source")
+	cmd.Flags().BoolVar(&collectNoImport, "no-import", false,
+		"Only download new files, do not import them")
+	cmd.Flags().BoolVar(&collectStrict, "strict", false,
+		"Reject files with product codes missing from the product_codes reference table")
+	cmd.Flags().BoolVar(&collectStatus, "status", false,
+		"Show the collection state of every source and exit")
+	cmd.Flags().DurationVar(&collectWait, "wait", defaultImportLockWait,
+		"How long to wait for another import holding the database lock")
+	cmd.Flags().BoolVar(&collectNoWait, "no-wait", false,
+		"Fail at once if another import holds the database lock")
+
+	return cmd
+}
+
+// defaultKnownHosts returns ~/.ssh/known_hosts, or an empty string if the home
+// directory is unknown
+func defaultKnownHosts() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh", "known_hosts")
+}
+
+func runCollect(cmd *cobra.Command, args []string) error {
+	// Check database exists
+	if _, err := os.Stat(collectDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", collectDBPath)
+	}
+
+	db, err := database.Connect(collectDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	c := collector.NewCollector(db, collectDownloadDir)
+
+	if collectStatus {
+		return printCollectionStatus(c)
+	}
+
+	// Sources from --sources, otherwise the endpoints of the configuration file
+	sources := collectEndpoints
+	if collectSourcesPath != "" {
+		sources, err = collector.LoadSources(collectSourcesPath)
+		if err != nil {
+			return fmt.Errorf("failed to load sources: %w", err)
+		}
+	} else if len(sources) == 0 {
+		return fmt.Errorf("--sources must be specified")
+	}
+	sources, err = selectSources(sources, collectOnly)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("no sources found in %s", collectSourcesPath)
+	}
+
+	auth := collector.AuthConfig{
+		KnownHostsFile:        collectKnownHosts,
+		InsecureIgnoreHostKey: collectInsecure,
+		Timeout:               collectTimeout,
+	}
+
+	// Collect from every source, continuing after failures
+	var downloaded []string
+	sourcesFailed := 0
+	for i, source := range sources {
+		fmt.Printf("[%d/%d] Collecting from %s (%s:%s)\n", i+1, len(sources), source.Name, source.HostPort(), source.RemoteDir)
+
+		result, err := c.Collect(source, auth)
+		if result != nil {
+			downloaded = append(downloaded, result.Downloaded...)
+			fmt.Printf("  New files: %d\n", len(result.Downloaded))
+			fmt.Printf("  Already collected: %d\n", result.Unchanged)
+		}
+		if err != nil {
+			sourcesFailed++
+			fmt.Printf("  ERROR: %v\n", err)
+		}
+		fmt.Println()
+	}
+
+	imported, skipped := 0, 0
+	if len(downloaded) > 0 && !collectNoImport {
+		// Only one import writes to the database at a time
+		lock, err := acquireImportLock(db, "collect", collectWait, collectNoWait)
+		if err != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%w\nThe downloaded files are in %s; import them with 'iwdlr import --dir'", err, collectDownloadDir)
+		}
+		defer lock.Release()
+
+		service := importer.NewImportService(db)
+		service.Strict = collectStrict
+		service.Lock = lock
+		notifier := newImportNotifier(cmd.Context(), db)
+
+		// Bundles are imported member by member
+		files := importer.ExpandBundles(downloaded)
+		fmt.Printf("Importing %d file(s) into database: %s\n", len(files), collectDBPath)
+		batch := service.ImportFiles(cmd.Context(), files, func(i int, fr importer.FileImportResult) {
+			if fr.Err != nil {
+				fmt.Printf("  ERROR: %s: %v\n", displayPath(collectDownloadDir, fr.FilePath), fr.Err)
+			}
+		})
+		imported, skipped = batch.FilesOK, batch.FilesSkipped
+		if batch.FilesFailed > batch.DetectionErrors {
+			fmt.Println("  Failed files were recorded; retry them with: iwdlr import retry-failed")
+		}
+		if batch.DetectionErrors > 0 {
+			fmt.Println("  Failed detections were recorded; list them with: iwdlr report detection-errors")
+		}
+		printUnknownProductCodes(batch)
+		notifier.notify(cmd.Context(), batch)
+		fmt.Println()
+		if err := interruptedImport(batch, len(files)); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("Collection Summary:")
+	fmt.Printf("  Sources collected: %d/%d\n", len(sources)-sourcesFailed, len(sources))
+	fmt.Printf("  Files downloaded: %d\n", len(downloaded))
+	if !collectNoImport {
+		fmt.Printf("  Files imported: %d\n", imported)
+		if skipped > 0 {
+			fmt.Printf("  Files skipped (already imported): %d\n", skipped)
+		}
+	}
+
+	if sourcesFailed > 0 {
+		return fmt.Errorf("%d of %d source(s) could not be collected", sourcesFailed, len(sources))
+	}
+	return nil
+}
+
+// selectSources keeps the sources named in only; all sources when only is empty
+func selectSources(sources []collector.Source, only []string) ([]collector.Source, error) {
+	if len(only) == 0 {
+		return sources, nil
+	}
+
+	byName := make(map[string]collector.Source, len(sources))
+	for _, source := range sources {
+		byName[source.Name] = source
+	}
+
+	selected := make([]collector.Source, 0, len(only))
+	for _, name := range only {
+		source, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown source: %s", name)
+		}
+		selected = append(selected, source)
+	}
+	return selected, nil
+}
+
+// printCollectionStatus lists the collection state of every source
+func printCollectionStatus(c *collector.Collector) error {
+	sources, err := c.ListSources()
+	if err != nil {
+		return err
+	}
+
+	if len(sources) == 0 {
+		fmt.Println("No collection runs recorded")
+		return nil
+	}
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "never"
+		}
+		return t.Format("2006-01-02 15:04:05")
+	}
+
+	for _, s := range sources {
+		fmt.Printf("%s (%s:%s)\n", s.SourceName, s.Address, s.RemoteDir)
+		fmt.Printf("  Last run:        %s\n", formatTime(s.LastRunAt))
+		fmt.Printf("  Last success:    %s\n", formatTime(s.LastSuccessAt))
+		fmt.Printf("  Files collected: %d\n", s.FilesCollected)
+		if s.LastError != "" {
+			fmt.Printf("  Last error:      %s\n", s.LastError)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/collector"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/confidence"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/config"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/notify"
+)
+
+// DBPathEnv is the environment variable giving the database path
+const DBPathEnv = "IWLDR_DB"
+
+// DefaultDBPath is the database path used when no flag, environment variable
+// or configuration file gives one
+const DefaultDBPath = "data/license-monitor.db"
+
+var (
+	// reportOutputDir is the directory relative report output paths are written to
+	reportOutputDir string
+	// collectEndpoints are the collection sources listed in the configuration file
+	collectEndpoints []collector.Source
+	// reportFilters are the report filters of the configuration file, by name
+	reportFilters map[string]config.ReportFilter
+)
+
+// ResolveDBPath returns the database path of a command: the global --database
+// flag (empty when not given), then the IWLDR_DB environment variable, then the
+// db-path of the configuration file, then DefaultDBPath
+func ResolveDBPath(database, configDBPath string) string {
+	for _, path := range []string{database, os.Getenv(DBPathEnv), configDBPath} {
+		if path != "" {
+			return path
+		}
+	}
+	return DefaultDBPath
+}
+
+// ApplyConfig uses the values of the configuration file as defaults for the
+// flags of cmd. Flags given on the command line are left untouched.
+func ApplyConfig(cmd *cobra.Command, cfg *config.Config) error {
+	if err := applyDBPath(cmd, cfg); err != nil {
+		return err
+	}
+
+	defaults := map[string]string{}
+
+	if isReportCommand(cmd) {
+		defaults["format"] = cfg.Report.Format
+		defaults["product"] = cfg.Report.Product
+		defaults["locale"] = cfg.Report.Locale
+		defaults["date-format"] = cfg.Report.DateFormat
+		defaults["min-confidence"] = cfg.Confidence.DedupMin
+		defaults["rounding"] = cfg.Licensing.Rounding
+		if cfg.Licensing.MinCoresPerInstall > 0 {
+			defaults["min-cores-per-install"] = strconv.Itoa(cfg.Licensing.MinCoresPerInstall)
+		}
+		reportOutputDir = cfg.Report.OutputDir
+		reportName = cmd.Name()
+		reportFilters = cfg.Report.Filters
+		if err := applyReportFilter(cmd); err != nil {
+			return err
+		}
+	}
+
+	if cmd.Name() == "collect" {
+		defaults["sources"] = cfg.Collection.Sources
+		defaults["download-dir"] = cfg.Collection.DownloadDir
+		defaults["known-hosts"] = cfg.Collection.KnownHosts
+		collectEndpoints = nil
+		for _, endpoint := range cfg.Collection.Endpoints {
+			collectEndpoints = append(collectEndpoints, collector.Source{
+				Name:         endpoint.Name,
+				Address:      endpoint.Address,
+				User:         endpoint.User,
+				IdentityFile: endpoint.IdentityFile,
+				RemoteDir:    endpoint.RemoteDir,
+			})
+		}
+	}
+
+	if cmd.Name() == "import" || cmd.Name() == "collect" {
+		webhooks = nil
+		for _, webhook := range cfg.Webhooks {
+			webhooks = append(webhooks, notify.Webhook{
+				Name:   webhook.Name,
+				URL:    webhook.URL,
+				Format: webhook.Format,
+				Events: webhook.Events,
+			})
+		}
+	}
+
+	if cmd.Name() == "daemon" {
+		daemonConfig = cfg
+	}
+	if cmd.Name() == "import" {
+		importConfig = cfg
+	}
+	// Queued jobs run with the configuration of their worker
+	jobsConfigPath = cfg.Path
+
+	if cmd.Name() == "query" {
+		savedQueries = cfg.Queries
+	}
+
+	if cmd.Name() == "confidence" {
+		confidenceRules = nil
+		for _, rule := range cfg.Confidence.Rules {
+			confidenceRules = append(confidenceRules, confidence.Rule{
+				Name:     rule.Name,
+				Evidence: rule.When,
+				MinVMs:   rule.MinVMs,
+				Set:      rule.Set,
+			})
+		}
+	}
+
+	if cmd == reportRunCmd {
+		reportProfiles = cfg.Report.Profiles
+	}
+
+	flags := cmd.Flags()
+	for name, value := range defaults {
+		flag := flags.Lookup(name)
+		if value == "" || flag == nil || flag.Changed {
+			continue
+		}
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid configuration value for %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// applyReportFilter sets the flags of the report filter named by --filter,
+// unless given on the command line. A filter setting a flag the report does
+// not take is an error rather than a silently wider report.
+func applyReportFilter(cmd *cobra.Command) error {
+	flags := cmd.Flags()
+	name := ""
+	if flag := flags.Lookup("filter"); flag != nil {
+		name = flag.Value.String()
+	}
+	if name == "" {
+		return nil
+	}
+
+	filter, ok := reportFilters[name]
+	if !ok {
+		names := make([]string, 0, len(reportFilters))
+		for n := range reportFilters {
+			names = append(names, n)
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("unknown filter %q: the configuration file defines no report filters", name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown filter %q (defined: %s)", name, strings.Join(names, ", "))
+	}
+
+	values := filter.Flags()
+	names := make([]string, 0, len(values))
+	for n := range values {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		flag := flags.Lookup(n)
+		if flag == nil {
+			return fmt.Errorf("filter %q sets --%s, which report %s does not take", name, n, cmd.Name())
+		}
+		if flag.Changed {
+			continue
+		}
+		if err := flags.Set(n, values[n]); err != nil {
+			return fmt.Errorf("filter %q: invalid value for --%s: %w", name, n, err)
+		}
+	}
+	return nil
+}
+
+// applyDBPath sets the --db-path flag of the commands using the database,
+// unless given on the command line, to the path resolved by ResolveDBPath, so
+// that --db-path and the global --database flag are the same setting
+func applyDBPath(cmd *cobra.Command, cfg *config.Config) error {
+	flags := cmd.Flags()
+	dbPath := flags.Lookup("db-path")
+	if dbPath == nil {
+		return nil
+	}
+
+	database := ""
+	if flag := flags.Lookup("database"); flag != nil && flag.Changed {
+		database = flag.Value.String()
+	}
+	if dbPath.Changed {
+		if database != "" && database != dbPath.Value.String() {
+			return fmt.Errorf("--database %s and --db-path %s name different databases", database, dbPath.Value.String())
+		}
+		return nil
+	}
+	return dbPath.Value.Set(ResolveDBPath(database, cfg.DBPath))
+}
+
+// isReportCommand reports whether cmd is a subcommand of report
+func isReportCommand(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == reportCmd {
+			return true
+		}
+	}
+	return false
+}
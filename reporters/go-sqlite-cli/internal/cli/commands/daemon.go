@@ -0,0 +1,282 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/config"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/jobs"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/schedule"
+)
+
+var (
+	daemonOutputDir string
+	daemonList      bool
+	daemonRunJob    string
+	daemonDBPath    string
+	daemonNoQueue   bool
+
+	// daemonConfig is the configuration file holding the schedule
+	daemonConfig *config.Config
+)
+
+// NewDaemonCmd creates the daemon command
+func NewDaemonCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Run the scheduled imports and reports",
+		Long: `Run the jobs of the schedule section of the configuration file on a timer,
+for hosts where system cron is not available.
+
+Each job is an iwldr command (import, collect or report) run at the times of a
+five-field cron expression (minute hour day-of-month month day-of-week, or
+@hourly, @daily, @weekly, @monthly). Jobs run one at a time in the local time
+zone. Every run writes below a dated directory (<output-dir>/YYYY-MM-DD): the
+job output file given by 'output', and <job>.log with the command output.
+
+Example configuration:
+  schedule:
+    output-dir: /srv/iwldr/scheduled
+    jobs:
+      - name: collect
+        cron: "0 1 * * *"
+        command: [collect]
+      - name: compliance
+        cron: "30 2 * * 1-5"
+        command: [report, compliance, --format, csv]
+        output: compliance.csv
+
+The daemon also runs the jobs queued with 'iwdlr jobs submit' in the database
+given by --db-path, one at a time with the scheduled jobs; --no-queue leaves
+them to another worker. With the queue, the schedule may be empty.
+
+The daemon stops on SIGINT or SIGTERM.
+
+Examples:
+  iwdlr daemon --config /etc/iwldr/iwldr.yaml
+  iwdlr daemon --list
+  iwdlr daemon --run compliance`,
+		Args: cobra.NoArgs,
+		RunE: runDaemon,
+	}
+
+	cmd.Flags().StringVar(&daemonOutputDir, "output-dir", "",
+		"Directory of the dated run directories (default: schedule output-dir, then report output-dir, then ./reports)")
+	cmd.Flags().BoolVar(&daemonList, "list", false, "List the scheduled jobs with their next run time and exit")
+	cmd.Flags().StringVar(&daemonRunJob, "run", "", "Run the named job once now and exit")
+	cmd.Flags().StringVar(&daemonDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file whose job queue the daemon runs")
+	cmd.Flags().BoolVar(&daemonNoQueue, "no-queue", false, "Do not run the jobs of the job queue")
+
+	return cmd
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	cfg := daemonConfig
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	if len(cfg.Schedule.Jobs) == 0 && (daemonNoQueue || daemonRunJob != "") {
+		return fmt.Errorf("no scheduled jobs configured\nAdd a schedule section to the configuration file (see 'iwdlr daemon --help')")
+	}
+
+	outputDir := daemonOutputDir
+	for _, dir := range []string{cfg.Schedule.OutputDir, cfg.Report.OutputDir, "reports"} {
+		if outputDir == "" {
+			outputDir = dir
+		}
+	}
+	// Job outputs are passed as absolute paths so the report output-dir does not apply to them
+	outputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return fmt.Errorf("invalid output directory: %w", err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the iwldr executable: %w", err)
+	}
+
+	var scheduled []schedule.Job
+	for _, job := range cfg.Schedule.Jobs {
+		cron, err := schedule.ParseCron(job.Cron)
+		if err != nil {
+			return fmt.Errorf("scheduled job %q: %w", job.Name, err)
+		}
+		scheduled = append(scheduled, schedule.Job{Name: job.Name, Cron: cron, Args: job.Command, Output: job.Output})
+	}
+
+	// Scheduled and queued jobs run one at a time
+	var mu sync.Mutex
+	runner := schedule.NewRunner(scheduled, outputDir, func(ctx context.Context, job schedule.Job, dir string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return execScheduledJob(ctx, executable, cfg.Path, job, dir)
+	})
+	runner.Logf = log.Printf
+
+	if daemonList {
+		next := runner.NextRuns(time.Now())
+		for i, job := range scheduled {
+			fmt.Printf("%-20s %-16s next: %s\n", job.Name, cfg.Schedule.Jobs[i].Cron, formatNextRun(next[i]))
+			fmt.Printf("  iwldr %s\n", strings.Join(job.Args, " "))
+		}
+		fmt.Printf("\nOutput directory: %s\n", outputDir)
+		if !daemonNoQueue {
+			fmt.Printf("Job queue: %s\n", daemonDBPath)
+		}
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if daemonRunJob != "" {
+		for _, job := range scheduled {
+			if job.Name == daemonRunJob {
+				return runner.RunJob(ctx, job)
+			}
+		}
+		return fmt.Errorf("no scheduled job named %q", daemonRunJob)
+	}
+
+	var queue *jobs.Worker
+	if !daemonNoQueue {
+		db, err := openDaemonQueueDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if queue, err = newJobWorker(db, daemonDBPath, &mu); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Daemon started with %d job(s), writing to %s", len(scheduled), outputDir)
+	if queue == nil {
+		if err := runner.Run(ctx); err != nil {
+			return err
+		}
+		log.Printf("Daemon stopped")
+		return nil
+	}
+
+	log.Printf("Running the job queue of %s", daemonDBPath)
+	errs := make(chan error, 2)
+	go func() { errs <- queue.Run(ctx) }()
+	if len(scheduled) > 0 {
+		go func() { errs <- runner.Run(ctx) }()
+	}
+
+	// A failing scheduler stops the daemon; both stop with ctx
+	err = <-errs
+	stop()
+	if len(scheduled) > 0 {
+		if other := <-errs; err == nil {
+			err = other
+		}
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Daemon stopped")
+	return nil
+}
+
+// openDaemonQueueDB opens the database of the job queue
+func openDaemonQueueDB() (*sql.DB, error) {
+	if _, err := os.Stat(daemonDBPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first, or use --no-queue", daemonDBPath)
+	}
+
+	db, err := database.Connect(daemonDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
+
+// execScheduledJob runs the job as a child iwldr process, so that every run
+// starts with fresh flag values, and appends its output to <dir>/<job>.log
+func execScheduledJob(ctx context.Context, executable, configPath string, job schedule.Job, dir string) error {
+	args := append([]string{}, job.Args...)
+	if configPath != "" {
+		args = append(args, "--config", configPath)
+	}
+	if job.Output != "" {
+		args = append(args, "--output", filepath.Join(dir, job.Output))
+	}
+
+	logFile, err := os.OpenFile(filepath.Join(dir, job.Name+".log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open job log: %w", err)
+	}
+	defer logFile.Close()
+
+	fmt.Fprintf(logFile, "=== %s iwldr %s\n", time.Now().Format(time.RFC3339), strings.Join(args, " "))
+
+	child := exec.CommandContext(ctx, executable, args...)
+	interruptOnCancel(child)
+	child.Env = append(os.Environ(), ReportTriggerEnv+"="+reports.RunTriggerSchedule, ReportJobEnv+"="+job.Name)
+	child.Stdout = logFile
+	child.Stderr = logFile
+	if err := child.Run(); err != nil {
+		fmt.Fprintf(logFile, "=== failed: %v\n", err)
+		return fmt.Errorf("%w (see %s)", err, logFile.Name())
+	}
+
+	return nil
+}
+
+// childStopGrace is how long a cancelled child iwldr process may take to roll
+// back its import or query before it is killed
+const childStopGrace = 30 * time.Second
+
+// interruptOnCancel stops a child process whose context is cancelled like
+// Ctrl-C does, so that it rolls back cleanly, and kills it when it did not
+// stop within childStopGrace or cannot be interrupted (Windows)
+func interruptOnCancel(child *exec.Cmd) {
+	child.Cancel = func() error {
+		if err := child.Process.Signal(os.Interrupt); err != nil {
+			return child.Process.Kill()
+		}
+		return nil
+	}
+	child.WaitDelay = childStopGrace
+}
+
+// formatNextRun renders the next run time of a job
+func formatNextRun(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format("2006-01-02 15:04 MST")
+}
@@ -0,0 +1,474 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dbDBPath        string
+	dbDropCache     bool
+	dbSlowThreshold time.Duration
+	dbCreateIndexes bool
+	dbExplainViews  []string
+)
+
+// NewDBCmd creates the db command
+func NewDBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Maintain the database",
+		Long:  "Maintain the SQLite database the measurements are imported into",
+	}
+
+	cmd.PersistentFlags().StringVar(&dbDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+
+	refreshCache := &cobra.Command{
+		Use:   "refresh-cache",
+		Short: "Materialize the peak usage and daily summary views",
+		Long: `Materialize the views behind the peak-usage, peak-breakdown and daily-summary
+reports into cache tables, which the reports read instead of the views. Once a
+year of daily measurements is imported these views take minutes to query.
+
+The cache is optional: until it is refreshed for the first time the reports read
+the views. Once refreshed, every import, retry and rollback refreshes it again.
+A cache is only read while no import changed the data since its refresh; the
+peak usage caches cover the last 31 days and are only read on the day of their
+refresh, so refresh the cache daily (e.g. from the same cron job as the reports).
+
+Use --drop to remove the cache and stop the refreshes after imports.
+
+Example:
+  iwdlr db refresh-cache --db-path data/license-monitor.db`,
+		Args: cobra.NoArgs,
+		RunE: runDBRefreshCache,
+	}
+	refreshCache.Flags().BoolVar(&dbDropCache, "drop", false, "Remove the cache instead of refreshing it")
+
+	analyzePerformance := &cobra.Command{
+		Use:   "analyze-performance",
+		Short: "Time the reporting views and find their slow queries",
+		Long: `Query every reporting view, time it and count the full table scans of its query
+plan (steps reading every row of a table without an index). Views slower than
+--threshold are reported with their full table scans.
+
+Databases initialized with an older schema lack the covering indexes added for
+the reporting views since; --create-indexes creates them before the views are
+timed. Querying the views takes as long as the reports, so run this outside the
+reporting schedule on large databases.
+
+Example:
+  iwdlr db analyze-performance --db-path data/license-monitor.db --create-indexes`,
+		Args: cobra.NoArgs,
+		RunE: runDBAnalyzePerformance,
+	}
+	analyzePerformance.Flags().DurationVar(&dbSlowThreshold, "threshold", time.Second,
+		"Query time above which a view is reported as slow")
+	analyzePerformance.Flags().BoolVar(&dbCreateIndexes, "create-indexes", false,
+		"Create the indexes of the current schema missing from the database")
+
+	explain := &cobra.Command{
+		Use:   "explain",
+		Short: "Print the query plans of the reporting views",
+		Long: `Print the query plan SQLite chooses for each reporting view, with the full scans
+of tables highlighted. A full table scan reads every row of a table, so the
+views scanning the measurement tables get slower with every import; compare the
+plans with the indexes listed by 'iwdlr db analyze-performance'.
+
+The plan is computed without running the view, so the command is fast on any
+database size.
+
+Example:
+  iwdlr db explain --view v_peak_usage
+  iwdlr db explain --view v_daily_product_summary,v_monthly_peak`,
+		Args: cobra.NoArgs,
+		RunE: runDBExplain,
+	}
+	explain.Flags().StringSliceVar(&dbExplainViews, "view", nil,
+		"Views to explain (repeatable or comma-separated, default: all views)")
+
+	dailyAggregation := &cobra.Command{
+		Use:   "daily-aggregation [max|last|average]",
+		Short: "Show or set how nodes measured several times a day are counted",
+		Long: `Show or set the daily aggregation policy: how the daily views and reports count
+a node that reported several measurements on one day (e.g. an inspector run by
+cron every hour).
+
+  max      the measurement with the most license cores of the day (default)
+  last     the latest measurement of the day
+  average  the cores averaged over the measurements of the day, rounded
+
+Every report built from daily measurements names the policy in its footnotes,
+and audit packages record it in their manifest. Setting the policy refreshes
+the report cache when it is enabled.
+
+Example:
+  iwdlr db daily-aggregation
+  iwdlr db daily-aggregation last`,
+		Args:      cobra.MaximumNArgs(1),
+		ValidArgs: []string{database.DailyAggregationMax, database.DailyAggregationLast, database.DailyAggregationAverage},
+		RunE:      runDBDailyAggregation,
+	}
+
+	upgradeViews := &cobra.Command{
+		Use:   "upgrade-views",
+		Short: "Recreate the reporting views of this version",
+		Long: `Drop and recreate the built-in reporting views with the definitions of this
+version, and record their version in the database. Databases keep the views
+they were initialized with, so run it after upgrading iwldr.
+
+The views are replaced in one transaction, rolled back unless every view of the
+database can be queried afterwards, the custom vx_ views reading the built-in
+ones included (see 'iwdlr views'). The command refuses databases whose schema
+is older than the views, which would read tables or columns the database lacks
+(upgrade them with 'iwdlr db upgrade'), or newer than this version. The report cache is refreshed when it is enabled.
+
+Example:
+  iwdlr db upgrade-views --db-path data/license-monitor.db`,
+		Args: cobra.NoArgs,
+		RunE: runDBUpgradeViews,
+	}
+
+	upgrade := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade the schema and views of the database to this version",
+		Long: `Upgrade the schema of a database initialized by an earlier version of iwldr to
+the schema of this version: add the columns added to its tables since, create
+the tables and indexes it lacks and record the new schema version. The reporting
+views are then recreated as 'iwdlr db upgrade-views' does. The measurements and
+reference data of the database are kept.
+
+The schema is upgraded in one transaction, rolled back on any error, and so are
+the views. Databases whose schema is newer than this version are refused. Back
+up the database file first: older versions of iwldr cannot read the upgraded
+database.
+
+Example:
+  iwdlr db upgrade --db-path data/license-monitor.db`,
+		Args: cobra.NoArgs,
+		RunE: runDBUpgrade,
+	}
+
+	cmd.AddCommand(refreshCache)
+	cmd.AddCommand(analyzePerformance)
+	cmd.AddCommand(explain)
+	cmd.AddCommand(dailyAggregation)
+	cmd.AddCommand(upgradeViews)
+	cmd.AddCommand(upgrade)
+	cmd.AddCommand(newDBRecomputeCmd())
+	return cmd
+}
+
+func runDBRefreshCache(cmd *cobra.Command, args []string) error {
+	db, err := openDBForMaintenance()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	cache := reports.NewReportCache(db)
+	if dbDropCache {
+		if err := cache.Drop(); err != nil {
+			return err
+		}
+		fmt.Println("Report cache removed; the reports read the views")
+		return nil
+	}
+
+	start := time.Now()
+	statuses, err := cache.Refresh()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Report cache refreshed in %s\n", time.Since(start).Round(time.Millisecond))
+	for _, status := range statuses {
+		fmt.Printf("  %-25s %d row(s)\n", status.View, status.Rows)
+	}
+	return nil
+}
+
+func runDBAnalyzePerformance(cmd *cobra.Command, args []string) error {
+	db, err := openDBForMaintenance()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if dbCreateIndexes {
+		created, err := database.CreateMissingIndexes(db)
+		if err != nil {
+			return err
+		}
+		for _, name := range created {
+			fmt.Printf("Created index %s\n", name)
+		}
+		if len(created) > 0 {
+			fmt.Println()
+		}
+	}
+	missing, err := database.MissingIndexes(db)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		fmt.Printf("Missing indexes: %s\n", strings.Join(missing, ", "))
+		fmt.Println("  Create them with: iwdlr db analyze-performance --create-indexes")
+		fmt.Println()
+	}
+
+	views, err := database.ReportingViews(db)
+	if err != nil {
+		return err
+	}
+
+	type viewTiming struct {
+		view    string
+		rows    int
+		elapsed time.Duration
+		scans   []database.PlanStep
+	}
+	var slow []viewTiming
+
+	fmt.Printf("%-36s %10s %12s %11s\n", "VIEW", "ROWS", "TIME", "TABLE SCANS")
+	for _, view := range views {
+		timing := viewTiming{view: view}
+		plan, err := database.ExplainView(db, view)
+		if err != nil {
+			return fmt.Errorf("%s: %w", view, err)
+		}
+		for _, step := range plan {
+			if step.Table != "" {
+				timing.scans = append(timing.scans, step)
+			}
+		}
+
+		start := time.Now()
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + view).Scan(&timing.rows); err != nil {
+			return fmt.Errorf("failed to query %s: %w", view, err)
+		}
+		timing.elapsed = time.Since(start)
+
+		marker := ""
+		if timing.elapsed > dbSlowThreshold {
+			marker = "  SLOW"
+			slow = append(slow, timing)
+		}
+		fmt.Printf("%-36s %10d %12s %11d%s\n", view, timing.rows,
+			timing.elapsed.Round(time.Millisecond), len(timing.scans), marker)
+	}
+
+	if len(slow) == 0 {
+		fmt.Printf("\nNo view slower than %s\n", dbSlowThreshold)
+		return nil
+	}
+	fmt.Printf("\n%d view(s) slower than %s:\n", len(slow), dbSlowThreshold)
+	for _, timing := range slow {
+		fmt.Printf("  %s (%s)\n", timing.view, timing.elapsed.Round(time.Millisecond))
+		for _, step := range timing.scans {
+			fmt.Printf("    - %s (full scan of %s)\n", step.Detail, step.Table)
+		}
+	}
+	fmt.Println("\nShow the query plans with: iwdlr db explain --view <view>")
+	fmt.Println("Cache the peak and daily summary views with: iwdlr db refresh-cache")
+	return nil
+}
+
+func runDBExplain(cmd *cobra.Command, args []string) error {
+	db, err := openDBForMaintenance()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	views, err := database.ReportingViews(db)
+	if err != nil {
+		return err
+	}
+	if len(dbExplainViews) > 0 {
+		known := map[string]bool{}
+		for _, view := range views {
+			known[view] = true
+		}
+		for _, view := range dbExplainViews {
+			if !known[view] {
+				return fmt.Errorf("unknown view %q (expected one of %s)", view, strings.Join(views, ", "))
+			}
+		}
+		views = dbExplainViews
+	}
+
+	for i, view := range views {
+		if i > 0 {
+			fmt.Println()
+		}
+		plan, err := database.ExplainView(db, view)
+		if err != nil {
+			return fmt.Errorf("%s: %w", view, err)
+		}
+
+		fmt.Println(view)
+		scans := printPlan(plan, 0, "")
+		if scans == 0 {
+			fmt.Println("No full table scan")
+		} else {
+			fmt.Printf("%d full table scan(s)\n", scans)
+		}
+	}
+	return nil
+}
+
+func runDBDailyAggregation(cmd *cobra.Command, args []string) error {
+	db, err := openDBForMaintenance()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if len(args) == 1 {
+		if err := database.SetDailyAggregation(db, args[0]); err != nil {
+			return err
+		}
+		refreshReportCache(db)
+	}
+
+	policy, err := database.GetDailyAggregation(db)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Daily aggregation: %s (%s)\n", policy, database.DescribeDailyAggregation(policy))
+	return nil
+}
+
+// printPlan prints the steps of a query plan below a parent step as a tree,
+// like the sqlite3 shell, and returns the number of full table scans printed
+func printPlan(plan []database.PlanStep, parent int, indent string) int {
+	var children []database.PlanStep
+	for _, step := range plan {
+		if step.Parent == parent {
+			children = append(children, step)
+		}
+	}
+
+	scans := 0
+	for i, step := range children {
+		branch, childIndent := "|--", indent+"|  "
+		if i == len(children)-1 {
+			branch, childIndent = "`--", indent+"   "
+		}
+		line := indent + branch + step.Detail
+		if step.Table != "" {
+			line += "   <== FULL TABLE SCAN of " + step.Table
+			scans++
+		}
+		fmt.Println(line)
+		scans += printPlan(plan, step.ID, childIndent)
+	}
+	return scans
+}
+
+func runDBUpgradeViews(cmd *cobra.Command, args []string) error {
+	db, err := openDBForMaintenance()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	upgrade, err := database.UpgradeViews(db)
+	if err != nil {
+		return fmt.Errorf("views not upgraded: %w", err)
+	}
+
+	previous := upgrade.PreviousVersion
+	if previous == "" {
+		previous = "unversioned"
+	}
+	if previous == upgrade.Version {
+		fmt.Printf("Recreated %d views (version %s)\n", len(upgrade.Views), upgrade.Version)
+	} else {
+		fmt.Printf("Upgraded %d views from %s to version %s\n", len(upgrade.Views), previous, upgrade.Version)
+	}
+	refreshReportCache(db)
+	return nil
+}
+
+func runDBUpgrade(cmd *cobra.Command, args []string) error {
+	db, err := openDBForMaintenance()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	upgrade, err := database.UpgradeSchema(db)
+	if err != nil {
+		return fmt.Errorf("schema not upgraded: %w", err)
+	}
+	if upgrade.PreviousVersion == upgrade.Version {
+		fmt.Printf("Schema already at version %s\n", upgrade.Version)
+	} else {
+		fmt.Printf("Upgraded the schema from %s to version %s\n", upgrade.PreviousVersion, upgrade.Version)
+	}
+	if len(upgrade.Tables) > 0 {
+		fmt.Printf("  Created %d table(s): %s\n", len(upgrade.Tables), strings.Join(upgrade.Tables, ", "))
+	}
+	if len(upgrade.Columns) > 0 {
+		fmt.Printf("  Added %d column(s): %s\n", len(upgrade.Columns), strings.Join(upgrade.Columns, ", "))
+	}
+
+	views, err := database.UpgradeViews(db)
+	if err != nil {
+		return fmt.Errorf("views not upgraded: %w\nFix the views and run 'iwdlr db upgrade-views'", err)
+	}
+	fmt.Printf("Recreated %d views (version %s)\n", len(views.Views), views.Version)
+	refreshReportCache(db)
+	return nil
+}
+
+// refreshReportCache refreshes the report cache after the data changed, when
+// it was enabled with 'db refresh-cache'. A failed refresh is only a warning:
+// the reports read the views while the cache is out of date.
+func refreshReportCache(db *sql.DB) {
+	cache := reports.NewReportCache(db)
+	enabled, err := cache.Enabled()
+	if err != nil || !enabled {
+		return
+	}
+	if _, err := cache.Refresh(); err != nil {
+		fmt.Printf("WARNING: Failed to refresh the report cache: %v\n", err)
+		return
+	}
+	fmt.Println("Report cache refreshed")
+}
+
+func openDBForMaintenance() (*sql.DB, error) {
+	if _, err := os.Stat(dbDBPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", dbDBPath)
+	}
+
+	db, err := database.Connect(dbDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/eligibility"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+var (
+	recomputeNode    string
+	recomputeFrom    string
+	recomputeTo      string
+	recomputeDryRun  bool
+	recomputeDetails bool
+	recomputeWait    time.Duration
+	recomputeNoWait  bool
+)
+
+// newDBRecomputeCmd creates the db recompute subcommand
+func newDBRecomputeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recompute",
+		Short: "Derive the eligibility and considered CPUs of stored measurements again",
+		Long: `Derive the calculated fields of the stored measurements again from the fields
+the inspectors measured, after the reference data they depend on changed:
+
+  processor_eligible, os_eligible, virt_eligible
+                    the eligibility of the node, derived from the active
+                    eligibility rules ('iwdlr eligibility load')
+  considered_cpus   the license cores of the node, derived as the inspector does
+                    from its CPUs, virtualization, eligibility and host cores,
+                    then capped at its container limit and partition capacity
+
+Without active eligibility rules, the eligibility the measurements were
+imported with is kept. The physical cores of the hosts recorded by 'iwdlr sync
+vcenter' and 'iwdlr import host-capacity' replace the host cores the inspectors
+reported, so VMs of an ineligible virtualization measured before their host was
+known count its cores.
+
+The command prints the number of measurements changed per field, the nodes and
+the days affected; --details lists every value changed. The measurements are
+updated in one transaction, with the changes between measurements of the nodes
+updated, and the report cache is refreshed. --dry-run shows the changes without
+writing them.
+
+Example:
+  iwdlr db recompute --dry-run --details
+  iwdlr db recompute --node app01.example.com --from 2025-01-01`,
+		Args: cobra.NoArgs,
+		RunE: runDBRecompute,
+	}
+
+	cmd.Flags().StringVar(&recomputeNode, "node", "", "Recompute the measurements of this node only (main FQDN)")
+	cmd.Flags().StringVar(&recomputeFrom, "from", "", "Recompute the measurements from this day (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&recomputeTo, "to", "", "Recompute the measurements up to this day (YYYY-MM-DD)")
+	cmd.Flags().BoolVar(&recomputeDryRun, "dry-run", false, "Show the changes without writing them")
+	cmd.Flags().BoolVar(&recomputeDetails, "details", false, "List every value changed")
+	cmd.Flags().DurationVar(&recomputeWait, "wait", defaultImportLockWait,
+		"How long to wait for another import holding the database lock")
+	cmd.Flags().BoolVar(&recomputeNoWait, "no-wait", false,
+		"Fail at once if another import holds the database lock")
+
+	return cmd
+}
+
+func runDBRecompute(cmd *cobra.Command, args []string) error {
+	opts := importer.RecomputeOptions{MainFQDN: recomputeNode, DryRun: recomputeDryRun}
+	for _, d := range []struct {
+		value  string
+		target **time.Time
+	}{{recomputeFrom, &opts.From}, {recomputeTo, &opts.To}} {
+		if d.value == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", d.value)
+		if err != nil {
+			return withExitCode(ExitParse, fmt.Errorf("invalid date %q: use YYYY-MM-DD", d.value))
+		}
+		*d.target = &t
+	}
+
+	db, err := openDBForMaintenance()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rules, err := eligibility.Active(cmd.Context(), db)
+	if err != nil {
+		return err
+	}
+	if rules != nil {
+		fmt.Printf("Eligibility derived from the eligibility rules %s\n", rules.Version)
+		opts.Eligibility = func(m *importer.RecomputedMeasurement) (string, string, string) {
+			eligible := rules.Evaluate(eligibility.Inputs{
+				ProcessorVendor: m.ProcessorVendor,
+				ProcessorBrand:  m.ProcessorBrand,
+				OSName:          m.OSName,
+				OSVersion:       m.OSVersion,
+				IsVirtualized:   m.IsVirtualized,
+				VirtType:        m.VirtType,
+			})
+			return eligible.Processor, eligible.OS, eligible.Virt
+		}
+	}
+
+	// Imports write the eligibility and considered_cpus too
+	if !recomputeDryRun {
+		lock, err := acquireImportLock(db, "db recompute", recomputeWait, recomputeNoWait)
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		defer lock.Release()
+	}
+
+	result, err := importer.NewImportService(db).Recompute(cmd.Context(), opts)
+	if err != nil {
+		return fmt.Errorf("measurements not recomputed: %w", err)
+	}
+
+	printRecomputeResult(result)
+
+	if !recomputeDryRun && result.Updated > 0 {
+		refreshReportCache(db)
+	}
+	return nil
+}
+
+// printRecomputeResult prints the diff summary of a recompute
+func printRecomputeResult(result *importer.RecomputeResult) {
+	if recomputeDryRun {
+		fmt.Println("Dry run: no measurement will be updated")
+		fmt.Println()
+	}
+	fmt.Printf("Recomputed %d measurements: %d changed\n", result.Measurements, result.Updated)
+	if result.Updated == 0 {
+		return
+	}
+
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tMEASUREMENTS")
+	for _, field := range importer.RecomputedFields {
+		if n := result.Fields[field]; n > 0 {
+			fmt.Fprintf(tw, "%s\t%d\n", field, n)
+		}
+	}
+	tw.Flush()
+
+	fmt.Printf("\nNodes affected: %d\n", len(result.Nodes))
+	if len(result.Dates) == 1 {
+		fmt.Printf("Days affected: 1 (%s)\n", result.Dates[0])
+	} else {
+		fmt.Printf("Days affected: %d (%s to %s)\n", len(result.Dates), result.Dates[0], result.Dates[len(result.Dates)-1])
+	}
+
+	if recomputeDetails {
+		fmt.Println()
+		tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NODE\tTIMESTAMP\tFIELD\tOLD\tNEW")
+		for _, change := range result.Changes {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", change.MainFQDN, change.DetectionTimestamp.Format("2006-01-02 15:04:05"),
+				change.Field, change.Old, change.New)
+		}
+		tw.Flush()
+	}
+}
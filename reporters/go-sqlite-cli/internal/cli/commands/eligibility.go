@@ -0,0 +1,291 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"os/user"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/eligibility"
+)
+
+var (
+	eligibilityDBPath  string
+	eligibilityFile    string
+	eligibilityDefault bool
+)
+
+// NewEligibilityCmd creates the eligibility command
+func NewEligibilityCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "eligibility",
+		Short: "Manage the eligibility rules of the measurements",
+		Long: `Manage the versioned eligibility rules that derive PROCESSOR_ELIGIBLE,
+OS_ELIGIBLE and VIRT_ELIGIBLE on import, instead of the values the inspectors
+report: the eligible processor families, operating systems and virtualization
+technologies. Correcting the rules centrally corrects the eligibility without
+redeploying the inspectors.
+
+While rules are active, imports derive the eligibility from them, and the
+considered CPUs from that eligibility; 'iwdlr db recompute' applies them to the
+measurements already imported. Without active rules, the eligibility reported
+by the inspectors is kept.`,
+	}
+
+	cmd.PersistentFlags().StringVar(&eligibilityDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+
+	load := &cobra.Command{
+		Use:   "load",
+		Short: "Load and activate a rules file",
+		Long: `Load a YAML rules file and make it the active rules. Without --file, the
+built-in rules are loaded: they give the eligibility the inspector reports, and
+'iwdlr eligibility show --default' prints them as a starting point for your
+own rules.
+
+The version given in the file identifies the rules: a version cannot be loaded
+again with other content, so edited rules need a new version. Loading a version
+already loaded with the same content activates it.
+
+Example:
+  iwdlr eligibility load
+  iwdlr eligibility load --file eligibility-2025.11.yaml
+  iwdlr db recompute --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: runEligibilityLoad,
+	}
+	load.Flags().StringVarP(&eligibilityFile, "file", "f", "", "YAML rules file (default: the built-in rules)")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the loaded versions of the rules",
+		Long: `List the versions of the rules loaded in the database, the latest loaded
+first, with the version active.
+
+Example:
+  iwdlr eligibility list`,
+		Args: cobra.NoArgs,
+		RunE: runEligibilityList,
+	}
+
+	show := &cobra.Command{
+		Use:   "show [version]",
+		Short: "Print a rules file",
+		Long: `Print the rules file of a loaded version, of the active rules without a
+version, or of the built-in rules with --default.
+
+Example:
+  iwdlr eligibility show
+  iwdlr eligibility show 2025.07
+  iwdlr eligibility show --default > eligibility.yaml`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runEligibilityShow,
+	}
+	show.Flags().BoolVar(&eligibilityDefault, "default", false, "Print the built-in rules")
+
+	activate := &cobra.Command{
+		Use:   "activate <version>",
+		Short: "Activate a loaded version of the rules",
+		Long: `Make a loaded version the active rules, to return to earlier rules.
+
+Example:
+  iwdlr eligibility activate 2025.07`,
+		Args: cobra.ExactArgs(1),
+		RunE: runEligibilityActivate,
+	}
+
+	disable := &cobra.Command{
+		Use:   "disable",
+		Short: "Keep the eligibility reported by the inspectors",
+		Long: `Deactivate the active rules: the measurements imported afterwards keep the
+eligibility reported by the inspectors. The measurements imported or
+recomputed with the rules keep the eligibility derived from them, and the
+loaded versions are kept.
+
+Example:
+  iwdlr eligibility disable`,
+		Args: cobra.NoArgs,
+		RunE: runEligibilityDisable,
+	}
+
+	cmd.AddCommand(load)
+	cmd.AddCommand(list)
+	cmd.AddCommand(show)
+	cmd.AddCommand(activate)
+	cmd.AddCommand(disable)
+	return cmd
+}
+
+// openEligibilityDB opens the existing database given by --db-path
+func openEligibilityDB() (*sql.DB, error) {
+	if _, err := os.Stat(eligibilityDBPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", eligibilityDBPath)
+	}
+
+	db, err := database.Connect(eligibilityDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
+
+func runEligibilityLoad(cmd *cobra.Command, args []string) error {
+	content := eligibility.DefaultContent()
+	if eligibilityFile != "" {
+		var err error
+		if content, err = os.ReadFile(eligibilityFile); err != nil {
+			return fmt.Errorf("failed to read rules file: %w", err)
+		}
+	}
+	if _, err := eligibility.Parse(content); err != nil {
+		return withExitCode(ExitParse, err)
+	}
+
+	db, err := openEligibilityDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	loadedBy := ""
+	if u, err := user.Current(); err == nil {
+		loadedBy = u.Username
+	}
+	rules, err := eligibility.Load(cmd.Context(), db, content, eligibilityFile, loadedBy)
+	if err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+
+	source := eligibilityFile
+	if source == "" {
+		source = "built-in rules"
+	}
+	fmt.Printf("Loaded eligibility rules %s from %s: %d processor vendors, %d operating systems, %d virtualization technologies\n",
+		rules.Version, source, len(rules.Processors), len(rules.OperatingSystems), len(rules.Virtualization))
+	fmt.Printf("Rules %s are active: imports derive the eligibility from them\n", rules.Version)
+	fmt.Printf("Run 'iwdlr db recompute --db-path %s' to apply them to the measurements already imported\n", eligibilityDBPath)
+	return nil
+}
+
+func runEligibilityList(cmd *cobra.Command, args []string) error {
+	db, err := openEligibilityDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	sets, err := eligibility.List(cmd.Context(), db)
+	if err != nil {
+		return err
+	}
+	if len(sets) == 0 {
+		fmt.Println("No eligibility rules loaded: the eligibility reported by the inspectors is kept")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tACTIVE\tLOADED\tBY\tSOURCE\tDESCRIPTION")
+	for _, set := range sets {
+		active := ""
+		if set.Active {
+			active = "yes"
+		}
+		source := set.SourceFile
+		if source == "" {
+			source = "(built-in)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", set.Version, active, set.LoadedAt, set.LoadedBy, source, set.Description)
+	}
+	return w.Flush()
+}
+
+func runEligibilityShow(cmd *cobra.Command, args []string) error {
+	if eligibilityDefault {
+		if len(args) > 0 {
+			return fmt.Errorf("--default cannot be combined with a version")
+		}
+		fmt.Print(string(eligibility.DefaultContent()))
+		return nil
+	}
+
+	db, err := openEligibilityDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	version := ""
+	if len(args) > 0 {
+		version = args[0]
+	} else if version, err = eligibility.ActiveVersion(cmd.Context(), db); err != nil {
+		return err
+	} else if version == "" {
+		fmt.Println("No eligibility rules active: the eligibility reported by the inspectors is kept")
+		return nil
+	}
+
+	set, err := eligibility.Get(cmd.Context(), db, version)
+	if err != nil {
+		return err
+	}
+	if set == nil {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("eligibility rules %s are not loaded", version)
+	}
+	fmt.Print(set.Content)
+	return nil
+}
+
+func runEligibilityActivate(cmd *cobra.Command, args []string) error {
+	db, err := openEligibilityDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := eligibility.Activate(cmd.Context(), db, args[0]); err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+	fmt.Printf("Rules %s are active: imports derive the eligibility from them\n", args[0])
+	fmt.Printf("Run 'iwdlr db recompute --db-path %s' to apply them to the measurements already imported\n", eligibilityDBPath)
+	return nil
+}
+
+func runEligibilityDisable(cmd *cobra.Command, args []string) error {
+	db, err := openEligibilityDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	disabled, err := eligibility.Disable(cmd.Context(), db)
+	if err != nil {
+		return err
+	}
+	if !disabled {
+		fmt.Println("No eligibility rules active")
+		return nil
+	}
+	fmt.Println("Eligibility rules disabled: imports keep the eligibility reported by the inspectors")
+	return nil
+}
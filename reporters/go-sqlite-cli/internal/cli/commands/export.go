@@ -0,0 +1,252 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/dataset"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/sam"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportDBPath      string
+	exportOutDir      string
+	exportFormat      string
+	exportSince       string
+	exportIncremental bool
+)
+
+// NewExportCmd creates the export command
+func NewExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export data from the database",
+		Long:  "Export database content as files that can be versioned or loaded into another database",
+	}
+
+	cmd.PersistentFlags().StringVar(&exportDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+
+	reference := &cobra.Command{
+		Use:   "reference",
+		Short: "Export the reference data as CSV files",
+		Long: `Write the reference data tables as CSV files in the format read by the
+import commands, so reference data can be versioned in git and synchronized
+between environments:
+
+  license-terms.csv       license_terms        (import --load-reference --reference-dir)
+  product-codes.csv       product_codes        (import --load-reference --reference-dir)
+  entitlements.csv        entitlements         (import entitlements --file)
+  product-thresholds.csv  product_thresholds   (import thresholds --file)
+  peak-grace-windows.csv  peak_grace_windows   (import grace-windows --file)
+  pvu-table.csv           pvu_mappings         (import pvu --file)
+
+Rows are sorted by key so that successive exports diff cleanly. Existing files
+in the output directory are overwritten.
+
+Example:
+  iwdlr export reference --db-path ./data/license-monitor.db --out ./reference`,
+		Args: cobra.NoArgs,
+		RunE: runExportReference,
+	}
+	reference.Flags().StringVar(&exportOutDir, "out", "", "Output directory")
+	reference.MarkFlagRequired("out")
+
+	datasetCmd := &cobra.Command{
+		Use:   "dataset",
+		Short: "Export the measurements, landscape and reference data for a data lake",
+		Long: `Write the measurements, detected products, physical hosts, landscape nodes,
+sites and reference data tables as one file per table, for ingestion into a
+data lake:
+
+  jsonl    <table>.jsonl, one JSON object per row with the columns of the table
+  parquet  <table>.parquet for the measurements and detected products only,
+           gzip compressed, with integer, timestamp (UTC) and string columns
+           typed from the table, for Spark and DuckDB
+
+A manifest.json written last lists the files with their row counts and the
+watermark of the export: the database time it started at.
+
+With --since only the rows added or changed at or after a timestamp (UTC,
+YYYY-MM-DD HH:MM:SS) are written; --incremental takes it from the watermark of
+the previous export in the output directory, so a scheduled export only writes
+what changed since its last run. Rows changed in the second of the watermark
+are exported twice, so deduplicate on the primary key of each table. Deleted
+rows (e.g. rolled back imports) are not exported; a full export replaces them.
+
+Existing files in the output directory are overwritten.
+
+Example:
+  iwdlr export dataset --format jsonl --out ./lake/full
+  iwdlr export dataset --out ./lake/daily --incremental
+  iwdlr export dataset --format parquet --out ./lake/parquet
+  iwdlr export dataset --out ./lake/2025-11 --since "2025-11-01 00:00:00"`,
+		Args: cobra.NoArgs,
+		RunE: runExportDataset,
+	}
+	datasetCmd.Flags().StringVar(&exportOutDir, "out", "", "Output directory")
+	datasetCmd.Flags().StringVar(&exportFormat, "format", dataset.FormatJSONL, "Output format: jsonl or parquet")
+	datasetCmd.Flags().StringVar(&exportSince, "since", "", "Export only the rows added or changed at or after this UTC timestamp (YYYY-MM-DD HH:MM:SS)")
+	datasetCmd.Flags().BoolVar(&exportIncremental, "incremental", false, "Export only the rows added or changed since the previous export to --out")
+	datasetCmd.MarkFlagRequired("out")
+
+	samCmd := &cobra.Command{
+		Use:   "sam",
+		Short: "Export the detected products and measurements for a SAM tool",
+		Long: `Write the latest measurement of each node and the products it detected as
+the CSV import files of a software asset management tool, so that the IBM
+deployments can be consolidated with the data of other vendors:
+
+  flexera     Flexera One / FlexNet Manager Suite inventory, for a custom
+              inventory adapter:
+                ImportedComputer.csv           one row per node
+                ImportedVirtualMachine.csv     the virtualized nodes and their host
+                ImportedInstallerEvidence.csv  one row per detected product
+  servicenow  ServiceNow SAM Pro import sets:
+                cmdb_ci_computer.csv           one row per node
+                cmdb_sam_sw_install.csv        one row per detected product
+
+Computers are identified by their main FQDN and products by their IBM product
+code, with publisher IBM and discovery source iwldr. The install date of a
+product is the first measurement that detected it on the node. Columns the
+target table does not have (e.g. u_program_number) are left for the transform
+map to drop or map to custom fields. Decommissioned nodes are not exported.
+
+Existing files in the output directory are overwritten.
+
+Example:
+  iwdlr export sam --format flexera --out ./sam/flexera
+  iwdlr export sam --format servicenow --out ./sam/servicenow`,
+		Args: cobra.NoArgs,
+		RunE: runExportSAM,
+	}
+	samCmd.Flags().StringVar(&exportOutDir, "out", "", "Output directory")
+	samCmd.Flags().StringVar(&exportFormat, "format", "", "SAM tool format: flexera or servicenow")
+	samCmd.MarkFlagRequired("out")
+	samCmd.MarkFlagRequired("format")
+
+	cmd.AddCommand(reference)
+	cmd.AddCommand(datasetCmd)
+	cmd.AddCommand(samCmd)
+	return cmd
+}
+
+func runExportReference(cmd *cobra.Command, args []string) error {
+	// Check database exists
+	if _, err := os.Stat(exportDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", exportDBPath)
+	}
+
+	db, err := database.Connect(exportDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	files, err := importer.NewReferenceDataExporter(db).ExportDir(exportOutDir)
+	if err != nil {
+		return fmt.Errorf("failed to export reference data: %w", err)
+	}
+
+	for _, f := range files {
+		fmt.Printf("Exported %d row(s) to %s\n", f.Rows, f.Path)
+	}
+
+	fmt.Println("\nNext steps:")
+	fmt.Println("  - Load into another database with the next import: iwdlr import --load-reference --reference-dir", exportOutDir, "...")
+	fmt.Printf("  - Load the entitlements: iwdlr import entitlements --file %s\n", files[2].Path)
+	fmt.Printf("  - Load the PVU mappings: iwdlr import pvu --file %s\n", files[3].Path)
+
+	return nil
+}
+
+func runExportDataset(cmd *cobra.Command, args []string) error {
+	if exportIncremental && exportSince != "" {
+		return fmt.Errorf("--since and --incremental cannot be combined")
+	}
+	since := exportSince
+	if since != "" {
+		if _, err := time.Parse("2006-01-02 15:04:05", since); err != nil {
+			return fmt.Errorf("invalid --since %q (expected YYYY-MM-DD HH:MM:SS)", since)
+		}
+	}
+	if exportIncremental {
+		watermark, err := dataset.ReadWatermark(exportOutDir)
+		if err != nil {
+			return err
+		}
+		if watermark == "" {
+			fmt.Printf("No previous export in %s, exporting all rows\n", exportOutDir)
+		}
+		since = watermark
+	}
+
+	// Check database exists
+	if _, err := os.Stat(exportDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", exportDBPath)
+	}
+
+	db, err := database.Connect(exportDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	manifest, err := dataset.NewExporter(db).Export(exportOutDir, exportFormat, since)
+	if err != nil {
+		return fmt.Errorf("failed to export dataset: %w", err)
+	}
+
+	if since != "" {
+		fmt.Printf("Rows added or changed since %s:\n", since)
+	}
+	for _, table := range manifest.Tables {
+		fmt.Printf("Exported %d row(s) to %s\n", table.Rows, filepath.Join(exportOutDir, table.File))
+	}
+	fmt.Printf("\nWatermark: %s (next run: iwdlr export dataset --out %s --incremental)\n", manifest.Watermark, exportOutDir)
+
+	return nil
+}
+
+func runExportSAM(cmd *cobra.Command, args []string) error {
+	// Check database exists
+	if _, err := os.Stat(exportDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", exportDBPath)
+	}
+
+	db, err := database.Connect(exportDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	files, err := sam.NewExporter(db).Export(exportOutDir, exportFormat)
+	if err != nil {
+		return fmt.Errorf("failed to export SAM data: %w", err)
+	}
+
+	for _, f := range files {
+		fmt.Printf("Exported %d row(s) to %s\n", f.Rows, f.Path)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/health"
+)
+
+var (
+	healthDBPath string
+	healthFormat string
+
+	// healthMaxImportAge and healthMinFreeMB are the thresholds of health and
+	// of the /healthz endpoint of serve
+	healthMaxImportAge time.Duration
+	healthMinFreeMB    uint64
+)
+
+// NewHealthCmd creates the health command
+func NewHealthCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "health",
+		Short: "Check the database for monitoring agents",
+		Long: `Check that the database can be read and is kept up to date:
+
+  database     the database opens and its tables can be read
+  schema       the schema version is the one of this iwldr build
+  last_import  the last successful import is younger than --max-import-age
+  disk_space   the file system of the database has --min-free-mb free
+
+A check fails, and the command exits with code 6, when its threshold is not
+met; a threshold of 0 disables its check. --format json prints the checks and
+the values measured as one JSON document for monitoring agents. 'iwdlr serve'
+answers the same checks on /healthz.
+
+Example:
+  iwdlr health --db-path data/license-monitor.db
+  iwdlr health --max-import-age 26h --min-free-mb 2048 --format json`,
+		Args: cobra.NoArgs,
+		RunE: runHealth,
+	}
+
+	cmd.Flags().StringVar(&healthDBPath, "db-path", "data/license-monitor.db", "Path to the SQLite database file")
+	cmd.Flags().StringVarP(&healthFormat, "format", "f", "table", "Output format: table or json")
+	addHealthFlags(cmd.Flags())
+
+	return cmd
+}
+
+// addHealthFlags adds the thresholds of the health checks to flags
+func addHealthFlags(flags *pflag.FlagSet) {
+	flags.DurationVar(&healthMaxImportAge, "max-import-age", health.DefaultMaxImportAge,
+		"Fail the health check when the last successful import is older (0 disables the check)")
+	flags.Uint64Var(&healthMinFreeMB, "min-free-mb", health.DefaultMinFreeBytes>>20,
+		"Fail the health check when the file system of the database has fewer MiB free (0 disables the check)")
+}
+
+// healthOptions returns the thresholds given by the health flags
+func healthOptions() health.Options {
+	return health.Options{MaxImportAge: healthMaxImportAge, MinFreeBytes: healthMinFreeMB << 20}
+}
+
+func runHealth(cmd *cobra.Command, args []string) error {
+	if healthFormat != "table" && healthFormat != "json" {
+		return withExitCode(ExitParse, fmt.Errorf("invalid format %q: must be table or json", healthFormat))
+	}
+
+	var report *health.Report
+	if db, err := database.ConnectReadOnly(healthDBPath); err != nil {
+		report = health.Unavailable(healthDBPath, err, healthOptions())
+	} else {
+		report = health.Run(db, healthOptions())
+		db.Close()
+	}
+
+	if healthFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "CHECK\tSTATUS\tMESSAGE")
+		fmt.Fprintln(tw, "-----\t------\t-------")
+		for _, check := range report.Checks {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", check.Name, check.Status, check.Message)
+		}
+		tw.Flush()
+	}
+
+	if !report.Healthy() {
+		cmd.SilenceUsage = true
+		return withExitCode(ExitUnhealthy, fmt.Errorf("database %s is unhealthy", report.Database))
+	}
+	if healthFormat == "table" {
+		fmt.Printf("\nDatabase %s is healthy\n", report.Database)
+	}
+	return nil
+}
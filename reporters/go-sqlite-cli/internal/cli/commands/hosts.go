@@ -0,0 +1,324 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"os/user"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/confidence"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hostsDBPath string
+	hostsReason string
+	hostsDryRun bool
+
+	// confidenceRules are the confidence rules of the configuration file
+	confidenceRules []confidence.Rule
+)
+
+// NewHostsCmd creates the hosts command
+func NewHostsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hosts",
+		Short: "Correct physical host IDs",
+		Long: `Rename and merge physical host IDs, and set their confidence.
+
+Physical hosts are deduplicated by their ID, so two IDs for the same chassis
+(e.g. one from the VMware UUID method and one from the hostname method) count
+its cores twice. These commands fix the ID in physical_hosts and in every
+measurement referencing it, and record the change in physical_host_merges.
+Later imports reporting the old ID are stored under the new one.
+
+Use 'iwdlr report hosts' to list the physical hosts.`,
+	}
+
+	cmd.PersistentFlags().StringVar(&hostsDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+
+	rename := &cobra.Command{
+		Use:   "rename <old-id> <new-id>",
+		Short: "Rename a physical host ID",
+		Long: `Rename a physical host ID. The new ID must not be in use; use 'hosts merge'
+to combine two hosts that are both known.
+
+Example:
+  iwdlr hosts rename esx01 esx01.example.com --reason "use FQDN as host ID"`,
+		Args: cobra.ExactArgs(2),
+		RunE: runHostsRename,
+	}
+	rename.Flags().StringVar(&hostsReason, "reason", "", "Reason recorded in the audit trail")
+
+	merge := &cobra.Command{
+		Use:   "merge <source-id> <target-id>",
+		Short: "Merge a physical host into another one",
+		Long: `Merge the source physical host into the target host. Measurements of the
+source are moved to the target, the target keeps the earliest first seen, the
+latest last seen and the larger physical CPU count of both, and the source host
+is deleted.
+
+Example:
+  iwdlr hosts merge esx01.local 4c4c4544-0042-3510-8052-b4c04f4e4b32 --reason "same chassis"`,
+		Args: cobra.ExactArgs(2),
+		RunE: runHostsMerge,
+	}
+	merge.Flags().StringVar(&hostsReason, "reason", "", "Reason recorded in the audit trail")
+
+	history := &cobra.Command{
+		Use:   "history",
+		Short: "List physical host renames and merges",
+		Args:  cobra.NoArgs,
+		RunE:  runHostsHistory,
+	}
+
+	confidenceCmd := &cobra.Command{
+		Use:   "confidence",
+		Short: "Set the confidence of physical host IDs with the confidence rules",
+		Long: `Raise or lower the confidence of the physical host IDs reported by the
+inspectors with rules based on corroborating evidence, read from the latest
+measurement of each virtualized node:
+
+  vm-agreement   at least min-vms nodes (default 2) report the host ID, all
+                 with the same physical cores
+  core-conflict  the nodes of the host report different physical cores
+  vcenter        the host is known to vCenter ('iwdlr sync vcenter') or loaded
+                 with 'iwdlr import host-capacity'
+  manual         the host was confirmed with 'iwdlr hosts confirm'
+
+The rules are read from the confidence section of the configuration file and
+applied in order; the last rule whose evidence holds sets the confidence. The
+default rules are:
+
+  confidence:
+    rules:
+      - {name: vms-agree, when: vm-agreement, min-vms: 3, set: medium}
+      - {name: vcenter, when: vcenter, set: high}
+      - {name: cores-disagree, when: core-conflict, set: low}
+      - {name: confirmed, when: manual, set: high}
+
+The confidence of the changed hosts is recorded in host_confidence_escalations,
+replacing the previous evaluation, and used by the sub-capacity reports; the
+measurements keep the confidence the inspectors reported. Run it again after
+each import or sync.
+
+Example:
+  iwdlr hosts confidence --dry-run
+  iwdlr hosts confidence`,
+		Args: cobra.NoArgs,
+		RunE: runHostsConfidence,
+	}
+	confidenceCmd.Flags().BoolVar(&hostsDryRun, "dry-run", false, "Show the confidence of every host without recording it")
+
+	confirm := &cobra.Command{
+		Use:   "confirm <host-id>",
+		Short: "Confirm a physical host ID by hand",
+		Long: `Record that a physical host ID was checked by hand, e.g. against the
+hypervisor console. The confirmation is the evidence of the manual confidence
+rules, applied by the next 'iwdlr hosts confidence'.
+
+Example:
+  iwdlr hosts confirm esx01.example.com --reason "checked in the vSphere client"`,
+		Args: cobra.ExactArgs(1),
+		RunE: runHostsConfirm,
+	}
+	confirm.Flags().StringVar(&hostsReason, "reason", "", "Reason recorded with the confirmation")
+
+	unconfirm := &cobra.Command{
+		Use:   "unconfirm <host-id>",
+		Short: "Remove the confirmation of a physical host ID",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runHostsUnconfirm,
+	}
+
+	cmd.AddCommand(rename, merge, history, confidenceCmd, confirm, unconfirm)
+
+	return cmd
+}
+
+func runHostsRename(cmd *cobra.Command, args []string) error {
+	return editPhysicalHost(func(editor *importer.PhysicalHostEditor) (*models.PhysicalHostMerge, error) {
+		return editor.Rename(args[0], args[1], hostsReason)
+	})
+}
+
+func runHostsMerge(cmd *cobra.Command, args []string) error {
+	return editPhysicalHost(func(editor *importer.PhysicalHostEditor) (*models.PhysicalHostMerge, error) {
+		return editor.Merge(args[0], args[1], hostsReason)
+	})
+}
+
+// editPhysicalHost runs a rename or merge and prints the recorded change
+func editPhysicalHost(edit func(*importer.PhysicalHostEditor) (*models.PhysicalHostMerge, error)) error {
+	db, err := openHostsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	editor := importer.NewPhysicalHostEditor(db)
+	if u, err := user.Current(); err == nil {
+		editor.PerformedBy = u.Username
+	}
+
+	merge, err := edit(editor)
+	if err != nil {
+		return err
+	}
+
+	verb := "Renamed"
+	if merge.Operation == importer.HostOperationMerge {
+		verb = "Merged"
+	}
+	fmt.Printf("%s physical host %s into %s\n", verb, merge.SourceHostID, merge.TargetHostID)
+	fmt.Printf("  Measurements updated: %d\n", merge.MeasurementsUpdated)
+	fmt.Printf("  Recorded as change:   %d\n", merge.MergeID)
+
+	return nil
+}
+
+func runHostsHistory(cmd *cobra.Command, args []string) error {
+	db, err := openHostsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	merges, err := importer.NewPhysicalHostEditor(db).ListMerges()
+	if err != nil {
+		return err
+	}
+
+	if len(merges) == 0 {
+		fmt.Println("No physical host renames or merges recorded")
+		return nil
+	}
+
+	for _, m := range merges {
+		fmt.Printf("#%d %s %s: %s -> %s (%d measurements)\n", m.MergeID,
+			m.PerformedAt.Format("2006-01-02 15:04:05"), m.Operation, m.SourceHostID, m.TargetHostID, m.MeasurementsUpdated)
+		if m.PerformedBy != "" {
+			fmt.Printf("  By:     %s\n", m.PerformedBy)
+		}
+		if m.Reason != "" {
+			fmt.Printf("  Reason: %s\n", m.Reason)
+		}
+	}
+
+	return nil
+}
+
+func runHostsConfidence(cmd *cobra.Command, args []string) error {
+	db, err := openHostsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	engine := confidence.NewEngine(db, confidenceRules)
+	evaluations, err := engine.Evaluate()
+	if err != nil {
+		return err
+	}
+
+	if len(evaluations) == 0 {
+		fmt.Println("No physical hosts reported by virtualized nodes")
+		return nil
+	}
+
+	changed := 0
+	for _, eval := range evaluations {
+		if !eval.Changed() && !hostsDryRun {
+			continue
+		}
+		if eval.Changed() {
+			changed++
+		}
+		rule := eval.Rule
+		if rule == "" {
+			rule = "-"
+		}
+		fmt.Printf("%-40s %-6s -> %-6s rule: %-16s nodes: %d\n",
+			eval.PhysicalHostID, eval.Reported, eval.Confidence, rule, eval.Nodes)
+	}
+
+	if hostsDryRun {
+		fmt.Printf("\nDry run: %d of %d host(s) would change, nothing recorded\n", changed, len(evaluations))
+		return nil
+	}
+
+	applied, err := engine.Apply(evaluations)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Host confidence changed by the rules: %d of %d host(s)\n", applied, len(evaluations))
+
+	return nil
+}
+
+func runHostsConfirm(cmd *cobra.Command, args []string) error {
+	db, err := openHostsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	confirmedBy := ""
+	if u, err := user.Current(); err == nil {
+		confirmedBy = u.Username
+	}
+	if err := confidence.Confirm(db, args[0], confirmedBy, hostsReason); err != nil {
+		return err
+	}
+	fmt.Printf("Confirmed physical host %s\n", args[0])
+	fmt.Println("Run 'iwdlr hosts confidence' to apply the confidence rules")
+
+	return nil
+}
+
+func runHostsUnconfirm(cmd *cobra.Command, args []string) error {
+	db, err := openHostsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := confidence.Unconfirm(db, args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Removed the confirmation of physical host %s\n", args[0])
+	fmt.Println("Run 'iwdlr hosts confidence' to apply the confidence rules")
+
+	return nil
+}
+
+// openHostsDB opens the existing database given by --db-path
+func openHostsDB() (*sql.DB, error) {
+	if _, err := os.Stat(hostsDBPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", hostsDBPath)
+	}
+
+	db, err := database.Connect(hostsDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
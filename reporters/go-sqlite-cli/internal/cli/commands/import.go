@@ -15,11 +15,15 @@
 package commands
 
 import (
+	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/config"
 	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
 	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
 	"github.com/spf13/cobra"
@@ -36,6 +40,34 @@ var (
 	referenceDir      string
 	licenseTermsPath  string
 	productCodesPath  string
+	importStrict      bool
+	importForce       bool
+	importRequireName bool
+	importMaxWarnings int
+	importArchive     bool
+	importVerifySigs  bool
+	importKeyring     string
+	importOrg         string
+	importWait        time.Duration
+	importNoWait      bool
+	importStableFor   time.Duration
+	importEphemeral   bool
+	importReport      string
+
+	// importReportArgs are the flags of --report, given after --
+	importReportArgs []string
+	// importConfig is the configuration file, applied to the report of --report
+	importConfig *config.Config
+	// ephemeralDB is the in-memory database of 'import --ephemeral', read by
+	// the report of --report
+	ephemeralDB *sql.DB
+)
+
+const (
+	// stdinFile is the --file value reading the CSV from standard input
+	stdinFile = "-"
+	// stdinSourceName is the source file recorded for standard input
+	stdinSourceName = "<stdin>"
 )
 
 // NewImportCmd creates the import command
@@ -47,36 +79,83 @@ func NewImportCmd() *cobra.Command {
 
 The import command supports:
 - Single file import: --file <path>
-- Directory import: --dir <path> (imports all .csv files)
+- Standard input: --file - or - (the hostname comes from the HOSTNAME field)
+- Directory import: --dir <path> (recursively imports all iwdli_output_*.csv files)
+- Compressed files: iwdli_output_*.csv.gz files, and .zip, .tar.gz or .tgz
+  bundles holding several inspector files, are imported like plain files;
+  in the folder-based workflow a bundle is moved after its last member
 - Folder-based workflow: --input-dir <path> (with automatic file movement)
 - Automatic node creation if not exists
 - Physical host tracking and aggregation
 - Import audit trail
 - Idempotent imports (upsert on duplicate)
+- Files whose content (SHA-256) was already imported are skipped; --force
+  imports them again
+- Failed files are recorded for later retry (see 'import retry-failed')
+- Import lock: only one import writes to a database at a time; a second
+  import waits for it (--wait) or fails at once (--no-wait)
+- Strict mode: --strict rejects files detecting product codes that are not
+  in the product_codes reference table and summarizes the missing mappings
+- Renamed files: the hostname comes from the iwdli_output_<hostname>_<timestamp>.csv
+  filename, or from the HOSTNAME field when the file was renamed in transfer;
+  --require-filename-pattern rejects files not named after the pattern
+- Field-level resilience: a product field that cannot be parsed (bad count,
+  malformed parameter) is skipped and recorded as a warning on the import
+  session; --max-warnings fails files with more warnings than the threshold
+- Signature verification: --verify-signatures rejects files that do not match
+  the detached OpenPGP signature (<file>.asc or <file>.sig, checked against the
+  public keys of --keyring) delivered with them; without --keyring a SHA-256
+  checksum (<file>.sha256) is accepted instead. With --keyring checksum files
+  are ignored, since they can be replaced with the file. The verification is
+  recorded on the import session
+- Source archiving: --archive-source stores the original CSV content with its
+  import session, retrieved with 'import show-source'
+- Organizations: --org puts the nodes of the imported files in an organization
+  (see 'iwdlr orgs'); a file of a node of another organization fails
+- Partial files: a file the inspector has not finished writing (empty, header
+  only, last line cut, or DETECTION_RESULT missing when CSV_FORMAT_VERSION is
+  declared) is deferred: it is not recorded as failed and stays in place for
+  a later import; --stable-for skips files of --dir and --input-dir modified
+  more recently than the given duration
+- Ephemeral analysis: --ephemeral (or --db-path :memory:) imports the files
+  into an in-memory database, seeded with the catalog of webMethods products
+  and discarded on exit; --report runs a report on it after the import, with
+  the report flags given after --
 
 Folder-based workflow:
   Files in input-dir are processed and moved to:
   - processed-dir on success
   - discards-dir on error
+  Deferred partial files stay in input-dir.
 
 Example:
   # Import single file
   iwdlr import --db-path ./data/license-monitor.db --file ./iwdli_output_omis446_20251021_090906.csv
 
-  # Import all files in directory (no file movement)
-  iwdlr import --db-path ./data/license-monitor.db --dir ./input/
+  # Import a file streamed from a host (the hostname comes from the HOSTNAME field)
+  ssh appsrv01 cat /opt/iwdli/out/latest.csv | iwdlr import --db-path ./data/license-monitor.db -
+
+  # Import all inspector files below a directory tree (no file movement)
+  iwdlr import --db-path ./data/license-monitor.db --dir ./nightly-drop/
 
   # Import with folder workflow (files are moved after processing)
-  iwdlr import --db-path ./data/license-monitor.db --input-dir ./test-data/input`,
+  iwdlr import --db-path ./data/license-monitor.db --input-dir ./test-data/input
+
+  # Polled from cron: leave files copied in the last two minutes for the next run
+  iwdlr import --db-path ./data/license-monitor.db --input-dir ./drop --stable-for 2m
+
+  # Summarize a batch of inspector files without touching the central database
+  iwdlr import --ephemeral --dir ./batch --report daily-summary -- --format csv --output summary.csv`,
+		Args: importArgs,
 		RunE: runImport,
 	}
 
 	cmd.Flags().StringVar(&importDBPath, "db-path", "data/license-monitor.db",
 		"Path to the SQLite database file")
 	cmd.Flags().StringVar(&importFile, "file", "",
-		"Path to a single CSV file to import")
+		"Path to a single CSV file to import, or - to read it from standard input")
 	cmd.Flags().StringVar(&importDir, "dir", "",
-		"Directory containing CSV files to import (no file movement)")
+		"Directory tree to scan recursively for iwdli_output_*.csv files and bundles (no file movement)")
 	cmd.Flags().StringVar(&inputDir, "input-dir", "",
 		"Input directory for folder-based workflow (files moved after processing)")
 	cmd.Flags().StringVar(&processedDir, "processed-dir", "",
@@ -91,10 +170,71 @@ Example:
 		"Path to license-terms.csv file (overrides reference-dir)")
 	cmd.Flags().StringVar(&productCodesPath, "product-codes", "",
 		"Path to product-codes.csv file (overrides reference-dir)")
+	cmd.Flags().BoolVar(&importStrict, "strict", false,
+		"Reject files with product codes missing from the product_codes reference table")
+	cmd.Flags().BoolVar(&importForce, "force", false,
+		"Import files again even if their content was already imported")
+	cmd.Flags().BoolVar(&importRequireName, "require-filename-pattern", false,
+		"Reject files not named iwdli_output_<hostname>_<timestamp>.csv instead of reading the HOSTNAME field")
+	cmd.Flags().IntVar(&importMaxWarnings, "max-warnings", -1,
+		"Fail files with more warnings than this (-1 for no limit)")
+	cmd.Flags().BoolVar(&importArchive, "archive-source", false,
+		"Store the original CSV content with each import session (see 'import show-source')")
+	cmd.Flags().BoolVar(&importVerifySigs, "verify-signatures", false,
+		"Reject files without a valid signature (.asc, .sig) file next to them, or checksum (.sha256) file without --keyring")
+	cmd.Flags().StringVar(&importKeyring, "keyring", "",
+		"OpenPGP public keyring trusted by --verify-signatures (gpg --export output, binary or armored)")
+	cmd.Flags().StringVar(&importOrg, "org", "",
+		"Put the nodes of the imported files in this organization (see 'iwdlr orgs')")
+	cmd.Flags().DurationVar(&importWait, "wait", defaultImportLockWait,
+		"How long to wait for another import holding the database lock")
+	cmd.Flags().BoolVar(&importNoWait, "no-wait", false,
+		"Fail at once if another import holds the database lock")
+	cmd.Flags().DurationVar(&importStableFor, "stable-for", 0,
+		"With --dir or --input-dir, leave files modified more recently than this for a later import (e.g. 2m)")
+	cmd.Flags().BoolVar(&importEphemeral, "ephemeral", false,
+		"Import into an in-memory database discarded on exit, the same as --db-path :memory:")
+	cmd.Flags().StringVar(&importReport, "report", "",
+		"With --ephemeral, run this report after the import (report flags go after --)")
+	cmd.PersistentFlags().BoolVar(&statusJSON, "status-json", false, statusJSONFlagUsage)
+
+	cmd.AddCommand(newImportEntitlementsCmd())
+	cmd.AddCommand(newImportThresholdsCmd())
+	cmd.AddCommand(newImportGraceWindowsCmd())
+	cmd.AddCommand(newImportPVUCmd())
+	cmd.AddCommand(newImportHostCapacityCmd())
+	cmd.AddCommand(newImportRetryFailedCmd())
+	cmd.AddCommand(newImportRollbackCmd())
+	cmd.AddCommand(newImportShowSourceCmd())
+	cmd.AddCommand(newImportUnlockCmd())
 
 	return cmd
 }
 
+// importArgs accepts - as the only argument, the short form of --file -, and
+// the flags of --report after --
+func importArgs(cmd *cobra.Command, args []string) error {
+	importReportArgs = nil
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		if importReport == "" {
+			return fmt.Errorf("arguments after -- are the flags of --report, which is not given")
+		}
+		importReportArgs = args[dash:]
+		args = args[:dash]
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	if len(args) > 1 || args[0] != stdinFile {
+		return fmt.Errorf("unexpected argument %q: import files with --file, --dir or --input-dir, or standard input with -", args[0])
+	}
+	if importFile != "" && importFile != stdinFile {
+		return fmt.Errorf("- and --file cannot both be specified")
+	}
+	importFile = stdinFile
+	return nil
+}
+
 func runImport(cmd *cobra.Command, args []string) error {
 	// Validate flags
 	modeCount := 0
@@ -114,10 +254,38 @@ func runImport(cmd *cobra.Command, args []string) error {
 	if modeCount > 1 {
 		return fmt.Errorf("only one of --file, --dir, or --input-dir can be specified")
 	}
+	if importFile == stdinFile && importRequireName {
+		return fmt.Errorf("--require-filename-pattern cannot be used with standard input, which has no filename")
+	}
+	if importStableFor > 0 && importFile != "" {
+		return fmt.Errorf("--stable-for applies to --dir and --input-dir only")
+	}
+	if importFile == stdinFile && importVerifySigs {
+		return fmt.Errorf("--verify-signatures cannot be used with standard input, which has no signature file")
+	}
+	ephemeral := importEphemeral || importDBPath == database.MemoryPath
+	if ephemeral {
+		if importEphemeral && cmd.Flags().Changed("db-path") && importDBPath != database.MemoryPath {
+			return fmt.Errorf("--ephemeral imports into an in-memory database: --db-path cannot be given")
+		}
+		if inputDir != "" {
+			return fmt.Errorf("--input-dir moves the imported files: import them with --file or --dir into an ephemeral database")
+		}
+		if importOrg != "" {
+			return fmt.Errorf("--org cannot be used with an ephemeral database, which has no organizations")
+		}
+		importDBPath = database.MemoryPath
+	} else if importReport != "" {
+		return fmt.Errorf("--report requires --ephemeral: report on a database file with 'iwdlr report'")
+	}
+	verifier, err := newSignatureVerifier(importVerifySigs, importKeyring)
+	if err != nil {
+		return err
+	}
 
 	// Check database exists
-	if _, err := os.Stat(importDBPath); os.IsNotExist(err) {
-		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", importDBPath)
+	if _, err := os.Stat(importDBPath); os.IsNotExist(err) && !ephemeral {
+		return withExitCode(ExitDatabase, fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", importDBPath))
 	}
 
 	// Setup folder-based workflow if using input-dir
@@ -161,10 +329,38 @@ func runImport(cmd *cobra.Command, args []string) error {
 	// Connect to database
 	db, err := database.Connect(importDBPath)
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return withExitCode(ExitDatabase, fmt.Errorf("failed to connect to database: %w", err))
 	}
 	defer db.Close()
 
+	// The in-memory database starts empty: it gets the schema and the catalog
+	// of webMethods products, overridden by --load-reference
+	if ephemeral {
+		if err := database.InitSchema(db); err != nil {
+			return withExitCode(ExitDatabase, fmt.Errorf("failed to initialize the in-memory database: %w", err))
+		}
+		if err := newReferenceLoader(db).LoadCatalog(cmd.Context(), false); err != nil {
+			return err
+		}
+		fmt.Println()
+	} else if err := database.CheckSchemaVersion(db); err != nil {
+		return withExitCode(ExitDatabase, err)
+	}
+
+	if importOrg != "" {
+		if err := importer.RequireOrg(db, importOrg); err != nil {
+			return err
+		}
+	}
+
+	// Only one import writes to the database at a time
+	lock, err := acquireImportLock(db, "import", importWait, importNoWait)
+	if err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+	defer lock.Release()
+
 	// Load reference data if requested
 	if loadReference {
 		// Determine paths for license terms and product codes
@@ -190,12 +386,12 @@ func runImport(cmd *cobra.Command, args []string) error {
 		}
 
 		fmt.Println("Loading reference data...")
-		loader := importer.NewReferenceDataLoader(db)
+		loader := newReferenceLoader(db)
 		
 		// Load license terms first (product codes reference them)
 		if _, err := os.Stat(ltPath); err == nil {
 			fmt.Printf("Loading license terms from: %s\n", ltPath)
-			if err := loader.LoadLicenseTermsCSV(ltPath); err != nil {
+			if err := loader.LoadLicenseTermsCSV(cmd.Context(), ltPath); err != nil {
 				return fmt.Errorf("failed to load license terms: %w", err)
 			}
 		} else {
@@ -205,7 +401,7 @@ func runImport(cmd *cobra.Command, args []string) error {
 		// Load product codes
 		if _, err := os.Stat(pcPath); err == nil {
 			fmt.Printf("Loading product codes from: %s\n", pcPath)
-			if err := loader.LoadProductCodesCSV(pcPath); err != nil {
+			if err := loader.LoadProductCodesCSV(cmd.Context(), pcPath); err != nil {
 				return fmt.Errorf("failed to load product codes: %w", err)
 			}
 		} else {
@@ -217,15 +413,27 @@ func runImport(cmd *cobra.Command, args []string) error {
 
 	// Create import service
 	service := importer.NewImportService(db)
+	service.Strict = importStrict
+	service.Force = importForce
+	service.RequireFilenamePattern = importRequireName
+	service.MaxWarnings = importMaxWarnings
+	service.ArchiveSource = importArchive
+	service.Org = importOrg
+	service.Signatures = verifier
+	service.Lock = lock
+	// Standard input cannot be read again later, so a partial stream fails
+	service.DeferPartial = importFile != stdinFile
 
 	// Get list of files to import
 	var files []string
-	if importFile != "" {
+	if importFile == stdinFile {
+		files = []string{stdinSourceName}
+	} else if importFile != "" {
 		files = []string{importFile}
 	} else if importDir != "" {
-		files, err = findCSVFiles(importDir)
+		files, err = importer.FindInspectorFiles(importDir)
 		if err != nil {
-			return fmt.Errorf("failed to find CSV files: %w", err)
+			return fmt.Errorf("failed to find inspector files: %w", err)
 		}
 	} else if inputDir != "" {
 		files, err = findCSVFiles(inputDir)
@@ -234,77 +442,143 @@ func runImport(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Files still being copied into the directory are left for a later import
+	var changing []string
+	if importStableFor > 0 {
+		files, changing = importer.SplitStableFiles(files, importStableFor, time.Now())
+		for _, file := range changing {
+			fmt.Printf("Waiting: %s (modified less than %s ago)\n", displayPath(importDir, file), importStableFor)
+		}
+		if len(changing) > 0 {
+			fmt.Println()
+		}
+	}
+
+	// Bundles are imported member by member
+	files = importer.ExpandBundles(files)
+
 	if len(files) == 0 {
+		if len(changing) > 0 {
+			fmt.Printf("No file to import yet: %d file(s) still being written\n", len(changing))
+			return nil
+		}
 		return fmt.Errorf("no CSV files found to import")
 	}
 
+	// Webhooks compare the compliance breaches before and after the import,
+	// of the central database only
+	var notifier *importNotifier
+	if !ephemeral {
+		notifier = newImportNotifier(cmd.Context(), db)
+	}
+
 	fmt.Printf("Importing %d file(s) into database: %s\n", len(files), importDBPath)
 	fmt.Println()
 
-	// Import each file
-	totalCreated := 0
-	totalUpdated := 0
-	totalSkipped := 0
-	totalErrors := 0
+	// Import each file, reporting progress as we go
+	bundles := newBundleMembers(files)
+	onFile := func(i int, fr importer.FileImportResult) {
+		fmt.Printf("[%d/%d] Importing: %s\n", i+1, len(files), displayPath(importDir, fr.FilePath))
 
-	for i, file := range files {
-		fileName := filepath.Base(file)
-		fmt.Printf("[%d/%d] Importing: %s\n", i+1, len(files), fileName)
-
-		result, err := service.ImportCSVFile(file)
-		if err != nil {
-			fmt.Printf("  ERROR: %v\n", err)
-			totalErrors++
-			
-			// Move to discards if folder workflow enabled
-			if moveFiles {
-				discardPath := filepath.Join(targetDiscardsDir, fileName)
-				if moveErr := os.Rename(file, discardPath); moveErr != nil {
-					fmt.Printf("  WARNING: Failed to move to discards: %v\n", moveErr)
-				} else {
-					fmt.Printf("  Moved to: %s\n", targetDiscardsDir)
-				}
+		if fr.Deferred != nil {
+			fmt.Printf("  Deferred: %v (left for a later import)\n", fr.Deferred)
+		} else if fr.Err != nil {
+			fmt.Printf("  ERROR: %v\n", fr.Err)
+		} else {
+			result := fr.Result
+			if result.AlreadyImported {
+				fmt.Printf("  Skipped: content already imported in session %s (use --force to re-import)\n", result.SessionID)
+			} else {
+				fmt.Printf("  Session ID: %s\n", result.SessionID)
+				fmt.Printf("  Records created: %d\n", result.RecordsCreated)
+				fmt.Printf("  Records updated: %d\n", result.RecordsUpdated)
 			}
-			fmt.Println()
-			continue
-		}
-
-		fmt.Printf("  Session ID: %s\n", result.SessionID)
-		fmt.Printf("  Records created: %d\n", result.RecordsCreated)
-		fmt.Printf("  Records updated: %d\n", result.RecordsUpdated)
+			printSignature(result.Signature)
 
-		if len(result.Errors) > 0 {
-			fmt.Printf("  Warnings: %d\n", len(result.Errors))
-			for _, errMsg := range result.Errors {
-				fmt.Printf("    - %s\n", errMsg)
+			if len(result.Errors) > 0 {
+				fmt.Printf("  Warnings: %d\n", len(result.Errors))
+				for _, errMsg := range result.Errors {
+					fmt.Printf("    - %s\n", errMsg)
+				}
 			}
 		}
 
-		totalCreated += result.RecordsCreated
-		totalUpdated += result.RecordsUpdated
-		totalSkipped += result.RecordsSkipped
-
-		// Move to processed if folder workflow enabled
+		// Move to processed or discards if folder workflow enabled
 		if moveFiles {
-			processedPath := filepath.Join(targetProcessedDir, fileName)
-			if moveErr := os.Rename(file, processedPath); moveErr != nil {
-				fmt.Printf("  WARNING: Failed to move to processed: %v\n", moveErr)
-			} else {
-				fmt.Printf("  Moved to: %s\n", targetProcessedDir)
-			}
+			moveImportedFile(service, bundles, fr, targetProcessedDir, targetDiscardsDir)
 		}
-		
+
 		fmt.Println()
 	}
+	var batch *importer.BatchImportResult
+	if importFile == stdinFile {
+		batch = service.ImportStream(cmd.Context(), os.Stdin, stdinSourceName, onFile)
+	} else {
+		batch = service.ImportFiles(cmd.Context(), files, onFile)
+	}
 
 	// Summary
 	fmt.Println("Import Summary:")
 	fmt.Printf("  Files processed: %d\n", len(files))
-	fmt.Printf("  Total records created: %d\n", totalCreated)
-	fmt.Printf("  Total records updated: %d\n", totalUpdated)
-	fmt.Printf("  Total records skipped: %d\n", totalSkipped)
-	if totalErrors > 0 {
-		fmt.Printf("  Files with errors: %d\n", totalErrors)
+	fmt.Printf("  Files imported: %d\n", batch.FilesOK)
+	if batch.FilesSkipped > 0 {
+		fmt.Printf("  Files skipped (already imported): %d\n", batch.FilesSkipped)
+	}
+	if deferred := batch.FilesDeferred + len(changing); deferred > 0 {
+		fmt.Printf("  Files deferred (still being written): %d\n", deferred)
+	}
+	fmt.Printf("  Total records created: %d\n", batch.Total.RecordsCreated)
+	fmt.Printf("  Total records updated: %d\n", batch.Total.RecordsUpdated)
+	fmt.Printf("  Total records skipped: %d\n", batch.Total.RecordsSkipped)
+	if len(batch.Total.Errors) > 0 {
+		fmt.Printf("  Total warnings: %d\n", len(batch.Total.Errors))
+	}
+	if batch.FilesFailed > 0 {
+		fmt.Printf("  Files with errors: %d\n", batch.FilesFailed)
+		for _, fr := range batch.Files {
+			if fr.Err != nil {
+				fmt.Printf("    - %s\n", displayPath(importDir, fr.FilePath))
+			}
+		}
+		if batch.FilesFailed > batch.DetectionErrors && importFile != stdinFile && !ephemeral {
+			fmt.Println("  Failed files were recorded; retry them with: iwdlr import retry-failed")
+		}
+		if batch.DetectionErrors > 0 && !ephemeral {
+			fmt.Println("  Failed detections were recorded; list them with: iwdlr report detection-errors")
+		}
+	}
+	printUnknownProductCodes(batch)
+	if batch.FilesOK > 0 {
+		refreshReportCache(db)
+	}
+	notifier.notify(cmd.Context(), batch)
+	setImportStatus(batch)
+
+	if err := interruptedImport(batch, len(files)); err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+
+	// The report covers the files imported, even when others failed
+	if importReport != "" && batch.FilesOK > 0 {
+		fmt.Println()
+		if err := runImportReport(cmd, db); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+	}
+
+	// Failed files make the command fail after the others were imported
+	if batch.FilesFailed > 0 {
+		cmd.SilenceUsage = true
+		return withExitCode(importExitCode(batch), fmt.Errorf("%d of %d file(s) failed to import", batch.FilesFailed, len(batch.Files)))
+	}
+
+	if ephemeral {
+		if importReport == "" {
+			fmt.Println("\nThe in-memory database is discarded on exit: give --report to report on it")
+		}
+		return nil
 	}
 
 	fmt.Println("\nNext steps:")
@@ -314,7 +588,195 @@ func runImport(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// findCSVFiles finds all CSV files in a directory (non-recursive)
+// runImportReport runs the report of --report, with the flags given after --,
+// on the in-memory database of an ephemeral import. The report closes the
+// database when it is done.
+func runImportReport(cmd *cobra.Command, db *sql.DB) error {
+	report, err := findReport(append([]string{importReport}, importReportArgs...))
+	if err != nil {
+		return fmt.Errorf("--report: %w", err)
+	}
+	// The configuration file sets the defaults of the report flags, as it does
+	// when the report command is run
+	if importConfig != nil {
+		if err := ApplyConfig(report, importConfig); err != nil {
+			return fmt.Errorf("--report: %w", err)
+		}
+	}
+
+	ephemeralDB = db
+	reportDBPath = database.MemoryPath
+	reportName = report.Name()
+	report.SetContext(cmd.Context())
+	return report.RunE(report, report.Flags().Args())
+}
+
+// interruptedImport returns the error of a batch cancelled by Ctrl-C or its
+// scheduler before all of its files were imported, nil otherwise. The file
+// being imported was rolled back, and the files not imported stay in place.
+func interruptedImport(batch *importer.BatchImportResult, files int) error {
+	if batch.Err == nil {
+		return nil
+	}
+	return fmt.Errorf("import interrupted after %d of %d file(s): %w", len(batch.Files), files, batch.Err)
+}
+
+// printUnknownProductCodes summarizes the product codes missing from the
+// reference data (the files were rejected in strict mode), so the mappings can
+// be added
+func printUnknownProductCodes(batch *importer.BatchImportResult) {
+	if len(batch.UnknownProductCodes) == 0 {
+		return
+	}
+
+	codes := make([]string, 0, len(batch.UnknownProductCodes))
+	for code := range batch.UnknownProductCodes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	fmt.Println()
+	fmt.Println("Missing product code mappings (add them to product-codes.csv):")
+	for _, code := range codes {
+		fmt.Printf("  - %s (%d file(s))\n", code, batch.UnknownProductCodes[code])
+	}
+}
+
+// displayPath shows a file relative to the scanned directory when possible,
+// so files with the same name in different subdirectories can be told apart.
+// Bundle members are shown with the name of their bundle.
+func displayPath(baseDir, file string) string {
+	if baseDir != "" {
+		if rel, err := filepath.Rel(baseDir, file); err == nil {
+			return rel
+		}
+	}
+	if bundle, member, ok := importer.SplitBundlePath(file); ok {
+		return filepath.Base(bundle) + "/" + member
+	}
+	return filepath.Base(file)
+}
+
+// bundleMembers tracks the members of the bundles of an import, so that the
+// folder workflow moves a bundle once its last member is imported
+type bundleMembers struct {
+	remaining map[string]int
+	failed    map[string][]string // failed member paths per bundle
+	deferred  map[string]bool     // bundles with a deferred member
+}
+
+func newBundleMembers(files []string) *bundleMembers {
+	b := &bundleMembers{remaining: map[string]int{}, failed: map[string][]string{}, deferred: map[string]bool{}}
+	for _, file := range files {
+		if bundle, _, ok := importer.SplitBundlePath(file); ok {
+			b.remaining[bundle]++
+		}
+	}
+	return b
+}
+
+// done records the outcome of a file. For bundle members it returns the
+// bundle and whether this was its last member; ok is false for other files.
+func (b *bundleMembers) done(fr importer.FileImportResult) (bundle string, last, ok bool) {
+	bundle, _, ok = importer.SplitBundlePath(fr.FilePath)
+	if !ok {
+		return "", false, false
+	}
+	if fr.Err != nil {
+		b.failed[bundle] = append(b.failed[bundle], fr.FilePath)
+	}
+	if fr.Deferred != nil {
+		b.deferred[bundle] = true
+	}
+	b.remaining[bundle]--
+	return bundle, b.remaining[bundle] == 0, true
+}
+
+// moveImportedFile moves a file of the folder workflow to the processed
+// directory, or to the discards directory when it failed. Bundles are moved
+// after their last member, to discards when any member failed; the recorded
+// failed imports follow the file so that a retry can still find them. Deferred
+// files, and bundles with a deferred member, stay for a later import.
+func moveImportedFile(service *importer.ImportService, bundles *bundleMembers, fr importer.FileImportResult, processedDir, discardsDir string) {
+	path := fr.FilePath
+	var failed []string
+	if fr.Err != nil {
+		failed = []string{fr.FilePath}
+	}
+	if bundle, last, ok := bundles.done(fr); ok {
+		if !last {
+			return
+		}
+		if bundles.deferred[bundle] {
+			fmt.Printf("  Left in place: %s has a deferred member\n", filepath.Base(bundle))
+			return
+		}
+		path, failed = bundle, bundles.failed[bundle]
+	} else if fr.Deferred != nil {
+		return
+	}
+
+	targetDir, name := processedDir, "processed"
+	if len(failed) > 0 {
+		targetDir, name = discardsDir, "discards"
+	}
+	target, err := moveWithSignatures(path, targetDir)
+	if err != nil {
+		fmt.Printf("  WARNING: Failed to move to %s: %v\n", name, err)
+		return
+	}
+	fmt.Printf("  Moved to: %s\n", targetDir)
+
+	for _, f := range failed {
+		// Members keep their name inside the moved bundle
+		if err := service.MoveFailedImport(f, target+strings.TrimPrefix(f, path)); err != nil {
+			fmt.Printf("  WARNING: %v\n", err)
+		}
+	}
+}
+
+// moveWithSignatures moves a file to a directory with the signature and
+// checksum files delivered with it, and returns its new path
+func moveWithSignatures(path, targetDir string) (string, error) {
+	signatures := importer.SignatureFiles(path)
+	target := filepath.Join(targetDir, filepath.Base(path))
+	if err := os.Rename(path, target); err != nil {
+		return "", err
+	}
+	for _, signature := range signatures {
+		if err := os.Rename(signature, filepath.Join(targetDir, filepath.Base(signature))); err != nil {
+			return target, err
+		}
+	}
+	return target, nil
+}
+
+// newSignatureVerifier returns the verifier of --verify-signatures, nil when
+// signatures are not verified
+func newSignatureVerifier(verify bool, keyring string) (*importer.SignatureVerifier, error) {
+	if !verify {
+		if keyring != "" {
+			return nil, fmt.Errorf("--keyring requires --verify-signatures")
+		}
+		return nil, nil
+	}
+	return importer.NewSignatureVerifier(keyring)
+}
+
+// printSignature shows the verified signature of an imported file
+func printSignature(signature *importer.SignatureVerification) {
+	if signature == nil {
+		return
+	}
+	if signature.Signer != "" {
+		fmt.Printf("  Signature: %s, signed by %s\n", signature.Status, signature.Signer)
+	} else {
+		fmt.Printf("  Signature: %s (%s)\n", signature.Status, filepath.Base(signature.SignatureFile))
+	}
+}
+
+// findCSVFiles finds all CSV files, plain or gzip compressed, and bundles in a
+// directory (non-recursive)
 func findCSVFiles(dir string) ([]string, error) {
 	var files []string
 
@@ -328,7 +790,8 @@ func findCSVFiles(dir string) ([]string, error) {
 			continue
 		}
 
-		if strings.HasSuffix(strings.ToLower(entry.Name()), ".csv") {
+		name := strings.ToLower(entry.Name())
+		if strings.HasSuffix(name, ".csv") || strings.HasSuffix(name, ".csv.gz") || importer.IsBundleName(name) {
 			files = append(files, filepath.Join(dir, entry.Name()))
 		}
 	}
@@ -0,0 +1,96 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/spf13/cobra"
+)
+
+var (
+	entitlementsDBPath string
+	entitlementsFile   string
+	entitlementsOrg    string
+)
+
+// newImportEntitlementsCmd creates the import entitlements subcommand
+func newImportEntitlementsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "entitlements",
+		Short: "Import licensed capacity per license term",
+		Long: `Import entitlements (licensed core and PVU counts) per license term.
+
+The CSV file must have the header:
+  license-terms-id,licensed-cores,licensed-pvu,notes
+
+Existing entitlements for a term are replaced. The compliance report compares
+these figures against measured usage to show over/under-license deltas.
+
+With --org the file holds the entitlements of an organization (see 'iwdlr
+orgs'), which 'report compliance --org' compares against the usage of its
+nodes.
+
+Example:
+  iwdlr import entitlements --db-path ./data/license-monitor.db --file ./entitlements.csv
+  iwdlr import entitlements --org acme-de --file ./entitlements-acme-de.csv`,
+		RunE: runImportEntitlements,
+	}
+
+	cmd.Flags().StringVar(&entitlementsDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+	cmd.Flags().StringVar(&entitlementsFile, "file", "",
+		"Path to the entitlements CSV file")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringVar(&entitlementsOrg, "org", "",
+		"Load the entitlements of this organization instead of the database-wide ones")
+
+	return cmd
+}
+
+func runImportEntitlements(cmd *cobra.Command, args []string) error {
+	// Check database exists
+	if _, err := os.Stat(entitlementsDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", entitlementsDBPath)
+	}
+
+	db, err := database.Connect(entitlementsDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	fmt.Printf("Loading entitlements from: %s\n", entitlementsFile)
+	loader := newReferenceLoader(db)
+	if entitlementsOrg != "" {
+		err = loader.LoadOrgEntitlementsCSV(cmd.Context(), entitlementsFile, entitlementsOrg)
+	} else {
+		err = loader.LoadEntitlementsCSV(cmd.Context(), entitlementsFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load entitlements: %w", err)
+	}
+
+	fmt.Println("\nNext steps:")
+	if entitlementsOrg != "" {
+		fmt.Printf("  - Check compliance: iwdlr report compliance --db-path %s --org %s\n", entitlementsDBPath, entitlementsOrg)
+	} else {
+		fmt.Println("  - Check compliance: iwdlr report compliance --db-path", entitlementsDBPath)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hostCapacityDBPath string
+	hostCapacityFile   string
+	hostCapacitySource string
+)
+
+// newImportHostCapacityCmd creates the import host-capacity subcommand
+func newImportHostCapacityCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "host-capacity",
+		Short: "Import the physical cores of hypervisor hosts",
+		Long: `Import the physical core counts of hypervisor hosts from an inventory other
+than vCenter, e.g. the IBM Power servers of an HMC, as the reference of
+'iwdlr report capacity-reconciliation'. VMware hosts are read with
+'iwdlr sync vcenter' instead.
+
+The CSV file must have the header:
+  physical-host-id,physical-cores,host-name
+
+physical-host-id is the ID the inspectors report for the host (PHYSICAL_HOST_ID)
+and host-name is optional. The hosts previously imported from the same
+--source are replaced.
+
+Example:
+  iwdlr import host-capacity --db-path ./data/license-monitor.db --file ./hmc-servers.csv --source hmc01`,
+		RunE: runImportHostCapacity,
+	}
+
+	cmd.Flags().StringVar(&hostCapacityDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+	cmd.Flags().StringVar(&hostCapacityFile, "file", "",
+		"Path to the host capacity CSV file")
+	cmd.Flags().StringVar(&hostCapacitySource, "source", "",
+		"Name of the inventory the hosts come from, e.g. the HMC")
+	cmd.MarkFlagRequired("file")
+	cmd.MarkFlagRequired("source")
+
+	return cmd
+}
+
+func runImportHostCapacity(cmd *cobra.Command, args []string) error {
+	// Check database exists
+	if _, err := os.Stat(hostCapacityDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", hostCapacityDBPath)
+	}
+
+	db, err := database.Connect(hostCapacityDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	fmt.Printf("Loading host capacity from: %s\n", hostCapacityFile)
+	loaded, err := importer.NewHostCapacityLoader(db).Load(hostCapacityFile, hostCapacitySource)
+	if err != nil {
+		return fmt.Errorf("failed to load host capacity: %w", err)
+	}
+	fmt.Printf("Hosts loaded from %s: %d\n", hostCapacitySource, loaded)
+
+	fmt.Println("\nNext steps:")
+	fmt.Println("  - Compare with the inspectors: iwdlr report capacity-reconciliation --db-path", hostCapacityDBPath)
+
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+// defaultImportLockWait is how long commands importing files wait for another
+// import to finish by default
+const defaultImportLockWait = 10 * time.Minute
+
+var unlockDBPath string
+
+// acquireImportLock takes the import lock of the database for command. It
+// waits up to wait for another import holding the lock to finish, or fails at
+// once with noWait.
+func acquireImportLock(db *sql.DB, command string, wait time.Duration, noWait bool) (*importer.ImportLock, error) {
+	lock, err := importer.AcquireImportLock(db, command, 0)
+
+	var held *importer.ImportLockedError
+	if errors.As(err, &held) && !noWait && wait > 0 {
+		fmt.Printf("Waiting up to %s for %s (%s, since %s) to finish...\n", wait, held.Command, held.Holder, held.AcquiredAt.Format("2006-01-02 15:04:05"))
+		lock, err = importer.AcquireImportLock(db, command, wait)
+	}
+	if errors.As(err, &held) {
+		return nil, fmt.Errorf("%w\nRetry later, or run 'iwdlr import unlock' if that process no longer runs", err)
+	}
+
+	return lock, err
+}
+
+// newImportUnlockCmd creates the import unlock subcommand
+func newImportUnlockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unlock",
+		Short: "Remove the import lock left by a process that no longer runs",
+		Long: `Commands that import files (import, import retry-failed, import rollback and
+collect) hold an advisory lock on the database so that only one of them writes
+measurements at a time; the others wait (--wait) or fail at once (--no-wait).
+
+A running process refreshes its lock every minute. A process that is killed
+leaves its lock behind until it goes stale, 5 minutes after its last refresh. This command removes the lock at once. Only use it
+when the process holding the lock no longer runs.
+
+Example:
+  iwdlr import unlock --db-path ./data/license-monitor.db`,
+		Args: cobra.NoArgs,
+		RunE: runImportUnlock,
+	}
+
+	cmd.Flags().StringVar(&unlockDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+
+	return cmd
+}
+
+func runImportUnlock(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(unlockDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", unlockDBPath)
+	}
+
+	db, err := database.Connect(unlockDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	held, err := importer.BreakImportLock(db)
+	if err != nil {
+		return err
+	}
+	if held == nil {
+		fmt.Println("The database is not locked")
+		return nil
+	}
+
+	fmt.Printf("Removed the import lock of %s (%s, since %s)\n", held.Command, held.Holder, held.AcquiredAt.Format("2006-01-02 15:04:05"))
+	return nil
+}
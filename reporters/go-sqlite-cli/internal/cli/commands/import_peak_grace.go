@@ -0,0 +1,83 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	graceWindowsDBPath string
+	graceWindowsFile   string
+)
+
+// newImportGraceWindowsCmd creates the import grace-windows subcommand
+func newImportGraceWindowsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "grace-windows",
+		Short: "Import the peak grace windows per product",
+		Long: `Import the grace window of peak smoothing per product. The smoothed peak of
+'report peak' ignores spikes shorter than grace-days consecutive days with
+measurements, such as transient DR failover usage excluded by the counting rules
+of an agreement. The raw peak is reported next to it.
+
+The CSV file must have the header:
+  product-mnemo-id,grace-days,notes
+
+Existing grace windows for a product are replaced. Products without a grace
+window use the --grace-days of 'report peak', if any.
+
+Example:
+  iwdlr import grace-windows --db-path ./data/license-monitor.db --file ./peak-grace-windows.csv`,
+		RunE: runImportGraceWindows,
+	}
+
+	cmd.Flags().StringVar(&graceWindowsDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+	cmd.Flags().StringVar(&graceWindowsFile, "file", "",
+		"Path to the peak grace windows CSV file")
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+func runImportGraceWindows(cmd *cobra.Command, args []string) error {
+	// Check database exists
+	if _, err := os.Stat(graceWindowsDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", graceWindowsDBPath)
+	}
+
+	db, err := database.Connect(graceWindowsDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	fmt.Printf("Loading peak grace windows from: %s\n", graceWindowsFile)
+	loader := importer.NewReferenceDataLoader(db)
+	if err := loader.LoadPeakGraceWindowsCSV(cmd.Context(), graceWindowsFile); err != nil {
+		return fmt.Errorf("failed to load peak grace windows: %w", err)
+	}
+
+	fmt.Println("\nNext steps:")
+	fmt.Println("  - Compare raw and smoothed peaks: iwdlr report peak --db-path", graceWindowsDBPath)
+
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pvuDBPath string
+	pvuFile   string
+)
+
+// newImportPVUCmd creates the import pvu subcommand
+func newImportPVUCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pvu",
+		Short: "Import processor value units per core",
+		Long: `Import the processor-to-PVU mapping used for PVU-based entitlements.
+
+The CSV file must have the header:
+  processor-vendor,processor-brand,processor-model,pvu-per-core,notes
+
+A mapping applies to measurements of the same processor vendor whose processor
+brand starts with processor-brand. An empty processor-model matches every
+model; otherwise the measured brand must contain it, and the mapping wins over
+one without a model. Existing mappings are replaced.
+
+The compliance and peak-usage reports convert license cores to PVUs with this
+mapping.
+
+Example:
+  iwdlr import pvu --db-path ./data/license-monitor.db --file ./pvu-table.csv`,
+		RunE: runImportPVU,
+	}
+
+	cmd.Flags().StringVar(&pvuDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+	cmd.Flags().StringVar(&pvuFile, "file", "",
+		"Path to the PVU mapping CSV file")
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+func runImportPVU(cmd *cobra.Command, args []string) error {
+	// Check database exists
+	if _, err := os.Stat(pvuDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", pvuDBPath)
+	}
+
+	db, err := database.Connect(pvuDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	fmt.Printf("Loading PVU mappings from: %s\n", pvuFile)
+	loader := importer.NewReferenceDataLoader(db)
+	if err := loader.LoadPVUMappingsCSV(cmd.Context(), pvuFile); err != nil {
+		return fmt.Errorf("failed to load PVU mappings: %w", err)
+	}
+
+	fmt.Println("\nNext steps:")
+	fmt.Println("  - Check PVU compliance: iwdlr report compliance --db-path", pvuDBPath)
+
+	return nil
+}
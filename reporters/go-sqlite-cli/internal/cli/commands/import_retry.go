@@ -0,0 +1,215 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	retryDBPath       string
+	retryList         bool
+	retryProcessedDir string
+	retryStrict       bool
+	retryRequireName  bool
+	retryMaxWarnings  int
+	retryArchive      bool
+	retryVerifySigs   bool
+	retryKeyring      string
+	retryOrg          string
+	retryWait         time.Duration
+	retryNoWait       bool
+)
+
+// newImportRetryFailedCmd creates the import retry-failed subcommand
+func newImportRetryFailedCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "retry-failed",
+		Short: "Retry files whose import previously failed",
+		Long: `Retry every file recorded in the failed_imports table.
+
+Any import that fails (parse error, constraint violation) is recorded with
+its file path, error and timestamp. Fix the cause (e.g. load missing product
+codes, or correct the file in place) and run this command to re-attempt them.
+Files that import successfully are removed from the table; files that fail
+again keep their entry with an increased attempt count. Failed detections are
+not retried; see 'report detection-errors'.
+
+Example:
+  # Show files waiting to be retried
+  iwdlr import retry-failed --db-path ./data/license-monitor.db --list
+
+  # Retry them, moving successful files out of the discards directory
+  iwdlr import retry-failed --db-path ./data/license-monitor.db --processed-dir ./test-data/processed`,
+		RunE: runImportRetryFailed,
+	}
+
+	cmd.Flags().StringVar(&retryDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+	cmd.Flags().BoolVar(&retryList, "list", false,
+		"Only list failed imports, do not retry them")
+	cmd.Flags().StringVar(&retryProcessedDir, "processed-dir", "",
+		"Move successfully retried files to this directory")
+	cmd.Flags().BoolVar(&retryStrict, "strict", false,
+		"Reject files with product codes missing from the product_codes reference table")
+	cmd.Flags().BoolVar(&retryRequireName, "require-filename-pattern", false,
+		"Reject files not named iwdli_output_<hostname>_<timestamp>.csv instead of reading the HOSTNAME field")
+	cmd.Flags().IntVar(&retryMaxWarnings, "max-warnings", -1,
+		"Fail files with more warnings than this (-1 for no limit)")
+	cmd.Flags().BoolVar(&retryArchive, "archive-source", false,
+		"Store the original CSV content with each import session (see 'import show-source')")
+	cmd.Flags().BoolVar(&retryVerifySigs, "verify-signatures", false,
+		"Reject files without a valid signature (.asc, .sig) file next to them, or checksum (.sha256) file without --keyring")
+	cmd.Flags().StringVar(&retryKeyring, "keyring", "",
+		"OpenPGP public keyring trusted by --verify-signatures (gpg --export output, binary or armored)")
+	cmd.Flags().StringVar(&retryOrg, "org", "",
+		"Put the nodes of the retried files in this organization (see 'iwdlr orgs')")
+	cmd.Flags().DurationVar(&retryWait, "wait", defaultImportLockWait,
+		"How long to wait for another import holding the database lock")
+	cmd.Flags().BoolVar(&retryNoWait, "no-wait", false,
+		"Fail at once if another import holds the database lock")
+
+	return cmd
+}
+
+func runImportRetryFailed(cmd *cobra.Command, args []string) error {
+	// Check database exists
+	if _, err := os.Stat(retryDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", retryDBPath)
+	}
+
+	db, err := database.Connect(retryDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	service := importer.NewImportService(db)
+	service.Strict = retryStrict
+	service.RequireFilenamePattern = retryRequireName
+	service.MaxWarnings = retryMaxWarnings
+	service.ArchiveSource = retryArchive
+	service.Org = retryOrg
+	if retryOrg != "" {
+		if err := importer.RequireOrg(db, retryOrg); err != nil {
+			return err
+		}
+	}
+	if service.Signatures, err = newSignatureVerifier(retryVerifySigs, retryKeyring); err != nil {
+		return err
+	}
+
+	failed, err := service.ListFailedImports()
+	if err != nil {
+		return err
+	}
+
+	if len(failed) == 0 {
+		fmt.Println("No failed imports recorded")
+		return nil
+	}
+
+	if retryList {
+		fmt.Printf("Failed imports: %d\n\n", len(failed))
+		for _, f := range failed {
+			fmt.Printf("%s\n", f.FilePath)
+			fmt.Printf("  Error:        %s\n", f.ErrorMessage)
+			fmt.Printf("  Attempts:     %d\n", f.AttemptCount)
+			fmt.Printf("  First failed: %s\n", f.FirstFailedAt.Format("2006-01-02 15:04:05"))
+			fmt.Printf("  Last failed:  %s\n", f.LastFailedAt.Format("2006-01-02 15:04:05"))
+		}
+		return nil
+	}
+
+	if retryProcessedDir != "" {
+		if err := os.MkdirAll(retryProcessedDir, 0755); err != nil {
+			return fmt.Errorf("failed to create processed directory: %w", err)
+		}
+	}
+
+	// Only one import writes to the database at a time
+	lock, err := acquireImportLock(db, "import retry-failed", retryWait, retryNoWait)
+	if err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+	defer lock.Release()
+	service.Lock = lock
+
+	fmt.Printf("Retrying %d failed import(s)\n\n", len(failed))
+
+	batch, err := service.RetryFailedImports(cmd.Context(), func(i int, fr importer.FileImportResult) {
+		fmt.Printf("[%d/%d] Retrying: %s\n", i+1, len(failed), fr.FilePath)
+
+		if fr.Err != nil {
+			fmt.Printf("  ERROR: %v\n\n", fr.Err)
+			return
+		}
+
+		if fr.Result.AlreadyImported {
+			fmt.Printf("  Skipped: content already imported in session %s\n", fr.Result.SessionID)
+		} else {
+			fmt.Printf("  Records created: %d\n", fr.Result.RecordsCreated)
+			fmt.Printf("  Records updated: %d\n", fr.Result.RecordsUpdated)
+		}
+		printSignature(fr.Result.Signature)
+
+		// Bundle members stay in their bundle
+		if _, _, inBundle := importer.SplitBundlePath(fr.FilePath); retryProcessedDir != "" && !inBundle {
+			if _, moveErr := moveWithSignatures(fr.FilePath, retryProcessedDir); moveErr != nil {
+				fmt.Printf("  WARNING: Failed to move to processed: %v\n", moveErr)
+			} else {
+				fmt.Printf("  Moved to: %s\n", retryProcessedDir)
+			}
+		}
+
+		fmt.Println()
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Retry Summary:")
+	fmt.Printf("  Files retried: %d\n", len(batch.Files))
+	fmt.Printf("  Files imported: %d\n", batch.FilesOK)
+	if batch.FilesSkipped > 0 {
+		fmt.Printf("  Files skipped (already imported): %d\n", batch.FilesSkipped)
+	}
+	fmt.Printf("  Files still failing: %d\n", batch.FilesFailed)
+	fmt.Printf("  Total records created: %d\n", batch.Total.RecordsCreated)
+	fmt.Printf("  Total records updated: %d\n", batch.Total.RecordsUpdated)
+	printUnknownProductCodes(batch)
+	if batch.FilesOK > 0 {
+		refreshReportCache(db)
+	}
+	setImportStatus(batch)
+
+	if err := interruptedImport(batch, len(failed)); err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+
+	if batch.FilesFailed > 0 {
+		cmd.SilenceUsage = true
+		return withExitCode(importExitCode(batch), fmt.Errorf("%d of %d file(s) still fail to import", batch.FilesFailed, len(batch.Files)))
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rollbackDBPath     string
+	rollbackSessionIDs []string
+	rollbackDryRun     bool
+	rollbackWait       time.Duration
+	rollbackNoWait     bool
+)
+
+// newImportRollbackCmd creates the import rollback subcommand
+func newImportRollbackCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Remove the data written by an import session",
+		Long: `Delete the measurement created by an import session, with its detected
+products, install paths and processes, and the session record itself.
+
+Use it to back out a file that was imported by mistake or with wrong content.
+Once rolled back, the file can be imported again. Landscape nodes and physical
+hosts are kept, as they may be shared with other measurements. Session IDs are
+listed by 'iwdlr report imports'.
+
+Example:
+  # Show what would be removed
+  iwdlr import rollback --session-id i23_20251021_090906 --dry-run
+
+  # Roll back two sessions
+  iwdlr import rollback --db-path ./data/license-monitor.db \
+    --session-id i23_20251021_090906 --session-id i24_20251021_091512`,
+		RunE: runImportRollback,
+	}
+
+	cmd.Flags().StringVar(&rollbackDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+	cmd.Flags().StringArrayVar(&rollbackSessionIDs, "session-id", nil,
+		"Import session to roll back (repeatable)")
+	cmd.Flags().BoolVar(&rollbackDryRun, "dry-run", false,
+		"Show what would be removed without changing the database")
+	cmd.Flags().DurationVar(&rollbackWait, "wait", defaultImportLockWait,
+		"How long to wait for another import holding the database lock")
+	cmd.Flags().BoolVar(&rollbackNoWait, "no-wait", false,
+		"Fail at once if another import holds the database lock")
+	cmd.MarkFlagRequired("session-id")
+
+	return cmd
+}
+
+func runImportRollback(cmd *cobra.Command, args []string) error {
+	// Check database exists
+	if _, err := os.Stat(rollbackDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", rollbackDBPath)
+	}
+
+	db, err := database.Connect(rollbackDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	// A rollback must not remove a measurement an import is writing
+	if !rollbackDryRun {
+		lock, err := acquireImportLock(db, "import rollback", rollbackWait, rollbackNoWait)
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		defer lock.Release()
+	}
+
+	service := importer.NewImportService(db)
+
+	if rollbackDryRun {
+		fmt.Println("Dry run: no data will be removed")
+		fmt.Println()
+	}
+
+	for _, sessionID := range rollbackSessionIDs {
+		result, err := service.Rollback(cmd.Context(), sessionID, rollbackDryRun)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Session: %s\n", result.SessionID)
+		fmt.Printf("  Source file: %s\n", result.SourceFile)
+		if result.MainFQDN == "" {
+			fmt.Println("  No measurement recorded for this session")
+		} else {
+			fmt.Printf("  Measurement: %s at %s\n", result.MainFQDN, result.DetectionTimestamp.Format("2006-01-02 15:04:05"))
+			fmt.Printf("  Measurements removed: %d\n", result.Measurements)
+			fmt.Printf("  Detected products removed: %d\n", result.DetectedProducts)
+			fmt.Printf("  Install paths removed: %d\n", result.Installs)
+			fmt.Printf("  Processes removed: %d\n", result.Processes)
+		}
+		fmt.Println()
+	}
+
+	if !rollbackDryRun {
+		fmt.Printf("Rolled back %d import session(s)\n", len(rollbackSessionIDs))
+		refreshReportCache(db)
+		fmt.Println("\nNext steps:")
+		fmt.Println("  - Re-import the corrected files: iwdlr import --db-path", rollbackDBPath, "--file <path>")
+	}
+
+	return nil
+}
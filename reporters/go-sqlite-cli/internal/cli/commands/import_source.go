@@ -0,0 +1,101 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	showSourceDBPath    string
+	showSourceSessionID string
+	showSourceOutput    string
+)
+
+// newImportShowSourceCmd creates the import show-source subcommand
+func newImportShowSourceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show-source",
+		Short: "Write the original CSV content of an import session",
+		Long: `Write the original inspector CSV of an import session, as archived by
+'iwdlr import --archive-source', to standard output or a file.
+
+The content is the file as imported, decompressed. It is checked against the
+SHA-256 recorded on the import session, so the evidence behind a measurement
+can be produced and verified long after the file itself was deleted. Session
+IDs are listed by 'iwdlr report imports'. Rolling back a session removes its
+archived source.
+
+Files already imported before archiving was enabled get their source archived
+when a later 'import --archive-source' finds them again.
+
+Example:
+  # Show the file behind a measurement
+  iwdlr import show-source --session-id i23_20251021_090906
+
+  # Restore it to a file
+  iwdlr import show-source --db-path ./data/license-monitor.db \
+    --session-id i23_20251021_090906 --output iwdli_output_i23_20251021_090906.csv`,
+		Args: cobra.NoArgs,
+		RunE: runImportShowSource,
+	}
+
+	cmd.Flags().StringVar(&showSourceDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+	cmd.Flags().StringVar(&showSourceSessionID, "session-id", "",
+		"Import session whose source to write")
+	cmd.Flags().StringVarP(&showSourceOutput, "output", "o", "",
+		"Output file (default: stdout)")
+	cmd.MarkFlagRequired("session-id")
+
+	return cmd
+}
+
+func runImportShowSource(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(showSourceDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", showSourceDBPath)
+	}
+
+	db, err := database.Connect(showSourceDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	source, err := importer.GetImportSource(db, showSourceSessionID)
+	if err != nil {
+		return err
+	}
+	if !source.Verified() {
+		fmt.Fprintf(os.Stderr, "Warning: the archived source of %s does not match the SHA-256 recorded on the session (%s)\n",
+			source.SessionID, source.FileSHA256)
+	}
+
+	if showSourceOutput == "" {
+		_, err := os.Stdout.Write(source.Content)
+		return err
+	}
+	if err := os.WriteFile(showSourceOutput, source.Content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", showSourceOutput, err)
+	}
+	fmt.Printf("Wrote the source of %s (%s, %d bytes, SHA-256 %s) to %s\n",
+		source.SessionID, source.SourceFile, len(source.Content), source.FileSHA256, showSourceOutput)
+	return nil
+}
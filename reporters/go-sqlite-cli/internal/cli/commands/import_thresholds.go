@@ -0,0 +1,81 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	thresholdsDBPath string
+	thresholdsFile   string
+)
+
+// newImportThresholdsCmd creates the import thresholds subcommand
+func newImportThresholdsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "thresholds",
+		Short: "Import the license core thresholds per product",
+		Long: `Import the highest license cores allowed per product, for products that
+share the entitlement of a license term with others.
+
+The CSV file must have the header:
+  product-mnemo-id,max-license-cores,notes
+
+Existing thresholds for a product are replaced. The compliance report flags the
+days a product uses more license cores than its threshold as a breach.
+
+Example:
+  iwdlr import thresholds --db-path ./data/license-monitor.db --file ./product-thresholds.csv`,
+		RunE: runImportThresholds,
+	}
+
+	cmd.Flags().StringVar(&thresholdsDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+	cmd.Flags().StringVar(&thresholdsFile, "file", "",
+		"Path to the product thresholds CSV file")
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+func runImportThresholds(cmd *cobra.Command, args []string) error {
+	// Check database exists
+	if _, err := os.Stat(thresholdsDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", thresholdsDBPath)
+	}
+
+	db, err := database.Connect(thresholdsDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	fmt.Printf("Loading product thresholds from: %s\n", thresholdsFile)
+	loader := importer.NewReferenceDataLoader(db)
+	if err := loader.LoadProductThresholdsCSV(cmd.Context(), thresholdsFile); err != nil {
+		return fmt.Errorf("failed to load product thresholds: %w", err)
+	}
+
+	fmt.Println("\nNext steps:")
+	fmt.Println("  - Check breaches: iwdlr report compliance --non-compliant-only --db-path", thresholdsDBPath)
+
+	return nil
+}
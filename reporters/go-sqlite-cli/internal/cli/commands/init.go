@@ -44,6 +44,9 @@ The database schema includes:
 - detected_products: Product detection results
 - import_sessions: Import audit trail
 
+An existing database is never overwritten: upgrade the schema of a database
+initialized by an earlier version with 'iwdlr db upgrade'.
+
 Example:
   iwdlr init --db-path ./data/license-monitor.db`,
 		RunE: runInit,
@@ -58,7 +61,7 @@ Example:
 func runInit(cmd *cobra.Command, args []string) error {
 	// Check if database already exists
 	if _, err := os.Stat(dbPath); err == nil {
-		return fmt.Errorf("database already exists at %s\nUse a different path or delete the existing file", dbPath)
+		return fmt.Errorf("database already exists at %s\nUpgrade it to this version with 'iwdlr db upgrade --db-path %s', or use a different path", dbPath, dbPath)
 	}
 
 	fmt.Printf("Initializing database at: %s\n", dbPath)
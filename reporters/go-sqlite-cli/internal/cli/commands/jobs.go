@@ -0,0 +1,359 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/jobs"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var (
+	jobsDBPath string
+	jobsStatus string
+	jobsLimit  int
+	jobsOnce   bool
+	jobsPoll   time.Duration
+
+	// jobsConfigPath is the configuration file passed to the commands run by
+	// the workers of 'jobs work', the daemon and serve
+	jobsConfigPath string
+)
+
+// NewJobsCmd creates the jobs command
+func NewJobsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "jobs",
+		Short: "Queue imports, cache refreshes and reports to run in the background",
+		Long: `Queue long-running operations in the jobs table of the database, to be run by
+a worker: 'iwdlr jobs work', the daemon, or 'iwdlr serve --jobs'. Workers of
+several processes can share a database; each job runs once, in the worker
+that claims it first, and workers run their jobs one at a time.
+
+Job kinds:
+  import         iwdlr import with the job arguments, e.g. --dir /srv/drop
+  refresh-cache  iwdlr db refresh-cache
+  report         iwdlr report with the job arguments, e.g. compliance --output c.csv
+
+Every job runs against the database of the queue. Its status (queued, running,
+succeeded, failed or cancelled) and the last 64 KiB of its output are kept with
+the job. A running job whose worker no longer runs (no heartbeat for a minute)
+is marked failed by the next worker looking for a job.`,
+	}
+
+	cmd.PersistentFlags().StringVar(&jobsDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+
+	submit := &cobra.Command{
+		Use:   "submit <kind> [args...]",
+		Short: "Queue a job",
+		Long: `Queue a job and print its ID. Arguments after the kind are passed to the
+command of the job; flags of 'jobs submit' itself go before the kind.
+
+Example:
+  iwdlr jobs submit import --dir /srv/iwldr/drop --strict
+  iwdlr jobs submit refresh-cache
+  iwdlr jobs submit report compliance --format csv --output /srv/reports/compliance.csv`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runJobsSubmit,
+	}
+	submit.Flags().SetInterspersed(false)
+	cmd.AddCommand(submit)
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the jobs, newest first",
+		Args:  cobra.NoArgs,
+		RunE:  runJobsList,
+	}
+	list.Flags().StringVar(&jobsStatus, "status", "",
+		"Only list jobs with this status: "+strings.Join(jobs.Statuses, ", "))
+	list.Flags().IntVar(&jobsLimit, "limit", 50, "Most jobs to list (0 for all)")
+	cmd.AddCommand(list)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "show <job-id>",
+		Short: "Show a job with its output",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runJobsShow,
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "cancel <job-id>",
+		Short: "Cancel a queued or running job",
+		Long: `Cancel a job. A queued job is cancelled at once. A running job is stopped by
+its worker within a few seconds; when the worker no longer runs (no heartbeat
+for a minute), the job is marked cancelled at once.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runJobsCancel,
+	})
+
+	work := &cobra.Command{
+		Use:   "work",
+		Short: "Run queued jobs",
+		Long: `Run the queued jobs one at a time until interrupted (SIGINT or SIGTERM), or
+until the queue is empty with --once. A job is run as a child iwldr process.`,
+		Args: cobra.NoArgs,
+		RunE: runJobsWork,
+	}
+	work.Flags().BoolVar(&jobsOnce, "once", false, "Exit when no job is queued")
+	work.Flags().DurationVar(&jobsPoll, "poll", jobs.DefaultPoll,
+		"How often to look for queued jobs and check the running job for cancellation")
+	cmd.AddCommand(work)
+
+	return cmd
+}
+
+// openJobsDB opens the existing database given by --db-path
+func openJobsDB() (*sql.DB, error) {
+	if _, err := os.Stat(jobsDBPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", jobsDBPath)
+	}
+
+	db, err := database.Connect(jobsDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
+
+// parseJobID parses the job ID argument
+func parseJobID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid job ID %q", arg)
+	}
+	return id, nil
+}
+
+func runJobsSubmit(cmd *cobra.Command, args []string) error {
+	kind, jobArgs := args[0], args[1:]
+	for _, arg := range jobArgs {
+		name, _, _ := strings.Cut(arg, "=")
+		if name == "--db-path" || name == "--database" || name == "-d" {
+			return fmt.Errorf("jobs run against the database of the queue; give it with --db-path before the kind")
+		}
+	}
+
+	db, err := openJobsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	submittedBy := ""
+	if u, err := user.Current(); err == nil {
+		submittedBy = u.Username
+	}
+	id, err := jobs.NewQueue(db).Submit(kind, jobArgs, submittedBy)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Queued job %d: iwldr %s\n", id, strings.Join((&jobs.Job{Kind: kind, Args: jobArgs}).Command(), " "))
+	return nil
+}
+
+func runJobsList(cmd *cobra.Command, args []string) error {
+	if jobsStatus != "" && !slices.Contains(jobs.Statuses, jobsStatus) {
+		return fmt.Errorf("invalid status %q (expected %s)", jobsStatus, strings.Join(jobs.Statuses, ", "))
+	}
+
+	db, err := openJobsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	list, err := jobs.NewQueue(db).List(jobsStatus, jobsLimit)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Println("No jobs")
+		return nil
+	}
+
+	for _, job := range list {
+		fmt.Printf("%-6d %-10s %-19s %-13s %s\n", job.ID, job.Status, job.SubmittedAt.Local().Format("2006-01-02 15:04:05"),
+			job.Kind, strings.Join(job.Args, " "))
+		if job.ErrorMessage != "" {
+			fmt.Printf("       %s\n", job.ErrorMessage)
+		}
+	}
+	return nil
+}
+
+func runJobsShow(cmd *cobra.Command, args []string) error {
+	id, err := parseJobID(args[0])
+	if err != nil {
+		return err
+	}
+
+	db, err := openJobsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	job, err := jobs.NewQueue(db).Get(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Job:       %d\n", job.ID)
+	fmt.Printf("Command:   iwldr %s\n", strings.Join(job.Command(), " "))
+	fmt.Printf("Status:    %s\n", job.Status)
+	if job.CancelRequested && job.Status == jobs.StatusRunning {
+		fmt.Println("           (cancellation requested)")
+	}
+	fmt.Printf("Submitted: %s by %s\n", job.SubmittedAt.Local().Format("2006-01-02 15:04:05"), job.SubmittedBy)
+	if job.StartedAt != nil {
+		fmt.Printf("Started:   %s on %s\n", job.StartedAt.Local().Format("2006-01-02 15:04:05"), job.Worker)
+	}
+	if job.FinishedAt != nil {
+		fmt.Printf("Finished:  %s\n", job.FinishedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	if job.ErrorMessage != "" {
+		fmt.Printf("Error:     %s\n", job.ErrorMessage)
+	}
+	if job.Output != "" {
+		fmt.Printf("\nOutput:\n%s", job.Output)
+		if !strings.HasSuffix(job.Output, "\n") {
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+func runJobsCancel(cmd *cobra.Command, args []string) error {
+	id, err := parseJobID(args[0])
+	if err != nil {
+		return err
+	}
+
+	db, err := openJobsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	job, err := jobs.NewQueue(db).Cancel(id)
+	if err != nil {
+		return err
+	}
+	if job.Status == jobs.StatusCancelled {
+		fmt.Printf("Cancelled job %d\n", job.ID)
+	} else {
+		fmt.Printf("Cancellation of job %d requested; its worker %s stops it\n", job.ID, job.Worker)
+	}
+	return nil
+}
+
+func runJobsWork(cmd *cobra.Command, args []string) error {
+	db, err := openJobsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	worker, err := newJobWorker(db, jobsDBPath, nil)
+	if err != nil {
+		return err
+	}
+	worker.Poll = jobsPoll
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if jobsOnce {
+		for {
+			ran, err := worker.RunNext(ctx)
+			if err != nil {
+				return err
+			}
+			if !ran || ctx.Err() != nil {
+				return nil
+			}
+		}
+	}
+
+	log.Printf("Worker started on %s", jobsDBPath)
+	if err := worker.Run(ctx); err != nil {
+		return err
+	}
+	log.Printf("Worker stopped")
+	return nil
+}
+
+// newJobWorker creates a worker running the jobs of the database at dbPath as
+// child iwldr processes. When mu is set, a job runs only while holding it, so
+// that the daemon runs queued and scheduled jobs one at a time.
+func newJobWorker(db *sql.DB, dbPath string, mu *sync.Mutex) (*jobs.Worker, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the iwldr executable: %w", err)
+	}
+	if dbPath, err = filepath.Abs(dbPath); err != nil {
+		return nil, fmt.Errorf("invalid database path: %w", err)
+	}
+
+	worker := jobs.NewWorker(jobs.NewQueue(db), func(ctx context.Context, job *jobs.Job) (string, error) {
+		if mu != nil {
+			mu.Lock()
+			defer mu.Unlock()
+		}
+		return execQueuedJob(ctx, executable, dbPath, job)
+	})
+	worker.Logf = log.Printf
+	return worker, nil
+}
+
+// execQueuedJob runs a job as a child iwldr process against the database of
+// the queue and returns its combined output
+func execQueuedJob(ctx context.Context, executable, dbPath string, job *jobs.Job) (string, error) {
+	args := append(job.Command(), "--db-path", dbPath)
+	if jobsConfigPath != "" {
+		args = append(args, "--config", jobsConfigPath)
+	}
+
+	var output bytes.Buffer
+	fmt.Fprintf(&output, "=== %s iwldr %s\n", time.Now().Format(time.RFC3339), strings.Join(args, " "))
+	child := exec.CommandContext(ctx, executable, args...)
+	interruptOnCancel(child)
+	child.Env = append(os.Environ(), ReportTriggerEnv+"="+reports.RunTriggerQueue, ReportJobEnv+"="+strconv.FormatInt(job.ID, 10))
+	child.Stdout = &output
+	child.Stderr = &output
+	err := child.Run()
+	return output.String(), err
+}
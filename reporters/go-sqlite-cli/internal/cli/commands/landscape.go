@@ -0,0 +1,474 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	landscapeDBPath         string
+	landscapeReason         string
+	landscapeStandbyType    string
+	landscapeClassification string
+	landscapeDecommissionOn string
+	landscapeImportFile     string
+	landscapeImportApply    bool
+	landscapeOwner          string
+	landscapeTeam           string
+	landscapeCostCenter     string
+)
+
+// NewLandscapeCmd creates the landscape command
+func NewLandscapeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "landscape",
+		Short: "Manage the landscape nodes",
+		Long:  "Manage the landscape nodes measurements are imported for",
+	}
+
+	cmd.PersistentFlags().StringVar(&landscapeDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+
+	alias := &cobra.Command{
+		Use:   "alias",
+		Short: "Keep one measurement history for renamed nodes",
+		Long: `Nodes are identified by their main FQDN, so a renamed or re-addressed host
+starts a new measurement history under its new FQDN. An alias maps the former
+FQDN to the main one: the measurements already imported under the former FQDN
+are moved to the main FQDN, and later imports reporting the former FQDN are
+stored under the main one.`,
+	}
+
+	add := &cobra.Command{
+		Use:   "add <alias-fqdn> <main-fqdn>",
+		Short: "Make an FQDN an alias of a node",
+		Long: `Make alias-fqdn an alias of main-fqdn and move the measurements of alias-fqdn
+to main-fqdn. If main-fqdn has not been imported yet it takes over the node of
+alias-fqdn, with its mode, site, classification and expected products. The two
+nodes must not have measurements at the same time, which would mean they are
+different hosts.
+
+Example:
+  iwdlr landscape alias add appsrv01.old.example.com appsrv01.example.com --reason "domain migration"`,
+		Args: cobra.ExactArgs(2),
+		RunE: runLandscapeAliasAdd,
+	}
+	add.Flags().StringVar(&landscapeReason, "reason", "", "Reason recorded with the alias")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the node aliases",
+		Args:  cobra.NoArgs,
+		RunE:  runLandscapeAliasList,
+	}
+
+	remove := &cobra.Command{
+		Use:   "remove <alias-fqdn>",
+		Short: "Remove a node alias",
+		Long: `Remove a node alias. Measurements moved when the alias was added stay under
+the main FQDN; later imports reporting the alias create a node of their own.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runLandscapeAliasRemove,
+	}
+
+	alias.AddCommand(add, list, remove)
+
+	classify := &cobra.Command{
+		Use:   "classify <classification> <fqdn>...",
+		Short: "Classify nodes as active, standby, DR or decommissioned",
+		Long: `Set the classification of landscape nodes: active (the default), standby, dr
+(disaster recovery) or decommissioned. Standby and DR nodes take the IBM backup
+type with --standby-type: cold backups are not started and need no license,
+warm and hot backups are licensed like active nodes. Without a standby type a
+standby or DR node is considered licensable.
+
+The cores, host-detail and instances reports select the standby and DR nodes
+with --standby include, exclude or licensable.
+
+Example:
+  iwdlr landscape classify standby appsrv02.example.com --standby-type cold
+  iwdlr landscape classify dr drsrv01.example.com drsrv02.example.com --standby-type warm
+  iwdlr landscape classify active appsrv02.example.com`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: runLandscapeClassify,
+	}
+	classify.Flags().StringVar(&landscapeStandbyType, "standby-type", "", "IBM backup type of standby and DR nodes: cold, warm or hot")
+
+	nodes := &cobra.Command{
+		Use:   "nodes",
+		Short: "List the landscape nodes with their classification and owner",
+		Args:  cobra.NoArgs,
+		RunE:  runLandscapeNodes,
+	}
+	nodes.Flags().StringVar(&landscapeClassification, "classification", "", "Only list the nodes with this classification")
+
+	decommission := &cobra.Command{
+		Use:   "decommission <fqdn>",
+		Short: "Mark a node decommissioned",
+		Long: `Classify a landscape node as decommissioned on a day. The reports leave out
+its measurements taken after that day and explain it below the table, and
+imports of later measurements of the node warn about them. Classifying the node
+again with 'landscape classify' takes the decommission date back.
+
+Example:
+  iwdlr landscape decommission appsrv03.example.com --date 2025-10-31`,
+		Args: cobra.ExactArgs(1),
+		RunE: runLandscapeDecommission,
+	}
+	decommission.Flags().StringVar(&landscapeDecommissionOn, "date", "", "Last day the node was in service (YYYY-MM-DD, default: today)")
+
+	owner := &cobra.Command{
+		Use:   "owner <fqdn>...",
+		Short: "Set the owner, team and cost center of nodes",
+		Long: `Set the contact metadata of landscape nodes: the owner to ask about their
+usage, the team running them and the cost center their licenses are charged
+to. Only the given flags are changed; an empty value clears the field.
+
+The host-detail report shows the owner, team and cost center of each node and
+selects them with --owner, and the daily-summary and compliance reports group
+the license cores per cost center with --group-by cost-center.
+
+Example:
+  iwdlr landscape owner appsrv01.example.com appsrv02.example.com --owner alice --team integration
+  iwdlr landscape owner appsrv01.example.com --cost-center CC-4711
+  iwdlr landscape owner appsrv03.example.com --owner ""`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runLandscapeOwner,
+	}
+	owner.Flags().StringVar(&landscapeOwner, "owner", "", "Owner of the nodes, e.g. a person or mailbox")
+	owner.Flags().StringVar(&landscapeTeam, "team", "", "Team running the nodes")
+	owner.Flags().StringVar(&landscapeCostCenter, "cost-center", "", "Cost center the licenses of the nodes are charged to")
+
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Populate and refresh the landscape nodes from a CMDB export",
+		Long: `Read a CMDB export in CSV format and add or update the landscape nodes it
+lists: their environment (PROD or NON PROD), site, owner, team, cost center
+and expected products. Columns are recognized by their header:
+
+  fqdn               fqdn, main_fqdn, host_fqdn or dns_name (required)
+  environment        environment, env, mode or used_for: production, prd,
+                     non-production, dev, test, qa, uat, staging, ...
+  site               site, site_id, location or datacenter
+  owner              owner, owned_by or managed_by
+  team               team, support_group or assignment_group
+  cost center        cost_center, cost_centre, costcenter or cost_center_code
+  expected products  expected_products, expected_product_codes or products,
+                     separated by commas, semicolons or spaces
+
+Other columns are ignored, and an empty cell keeps the value of the node. An
+FQDN recorded as a node alias updates its main node. Sites not yet defined
+are created with their ID as name. Nodes of the database missing from the
+export are listed and kept.
+
+Without --apply the changes are only shown.
+
+Example:
+  iwdlr landscape import --file cmdb.csv
+  iwdlr landscape import --file cmdb.csv --apply`,
+		Args: cobra.NoArgs,
+		RunE: runLandscapeImport,
+	}
+	importCmd.Flags().StringVar(&landscapeImportFile, "file", "", "CMDB export in CSV format")
+	importCmd.Flags().BoolVar(&landscapeImportApply, "apply", false, "Apply the changes instead of only showing them")
+	importCmd.MarkFlagRequired("file")
+
+	cmd.AddCommand(alias, classify, decommission, nodes, owner, importCmd)
+
+	return cmd
+}
+
+func runLandscapeAliasAdd(cmd *cobra.Command, args []string) error {
+	db, err := openLandscapeDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	editor := importer.NewNodeAliasEditor(db)
+	if u, err := user.Current(); err == nil {
+		editor.CreatedBy = u.Username
+	}
+
+	alias, err := editor.Add(args[0], args[1], landscapeReason)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Added alias %s of %s\n", alias.AliasFQDN, alias.MainFQDN)
+	fmt.Printf("  Measurements moved: %d\n", alias.MeasurementsMoved)
+
+	return nil
+}
+
+func runLandscapeAliasList(cmd *cobra.Command, args []string) error {
+	db, err := openLandscapeDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	aliases, err := importer.NewNodeAliasEditor(db).List()
+	if err != nil {
+		return err
+	}
+
+	if len(aliases) == 0 {
+		fmt.Println("No node aliases recorded")
+		return nil
+	}
+
+	for _, a := range aliases {
+		fmt.Printf("%s -> %s (added %s, %d measurements moved)\n", a.AliasFQDN, a.MainFQDN,
+			a.CreatedAt.Format("2006-01-02 15:04:05"), a.MeasurementsMoved)
+		if a.CreatedBy != "" {
+			fmt.Printf("  By:     %s\n", a.CreatedBy)
+		}
+		if a.Reason != "" {
+			fmt.Printf("  Reason: %s\n", a.Reason)
+		}
+	}
+
+	return nil
+}
+
+func runLandscapeAliasRemove(cmd *cobra.Command, args []string) error {
+	db, err := openLandscapeDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := importer.NewNodeAliasEditor(db).Remove(args[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed node alias %s\n", args[0])
+	return nil
+}
+
+func runLandscapeClassify(cmd *cobra.Command, args []string) error {
+	db, err := openLandscapeDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	classification, standbyType, err := importer.ParseNodeClassification(args[0], landscapeStandbyType)
+	if err != nil {
+		return err
+	}
+	if err := importer.NewNodeClassificationEditor(db).Classify(classification, standbyType, args[1:]); err != nil {
+		return err
+	}
+
+	if standbyType != "" {
+		classification += " (" + standbyType + ")"
+	}
+	fmt.Printf("Classified %d node(s) as %s\n", len(args)-1, classification)
+	return nil
+}
+
+func runLandscapeDecommission(cmd *cobra.Command, args []string) error {
+	day := time.Now()
+	if landscapeDecommissionOn != "" {
+		var err error
+		day, err = time.Parse("2006-01-02", landscapeDecommissionOn)
+		if err != nil {
+			return fmt.Errorf("invalid --date: %w", err)
+		}
+	}
+
+	db, err := openLandscapeDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := importer.NewNodeClassificationEditor(db).Decommission(args[0], day); err != nil {
+		return err
+	}
+
+	fmt.Printf("Decommissioned %s on %s\n", args[0], day.Format("2006-01-02"))
+	return nil
+}
+
+func runLandscapeOwner(cmd *cobra.Command, args []string) error {
+	var owner importer.NodeOwner
+	if cmd.Flags().Changed("owner") {
+		owner.Owner = &landscapeOwner
+	}
+	if cmd.Flags().Changed("team") {
+		owner.Team = &landscapeTeam
+	}
+	if cmd.Flags().Changed("cost-center") {
+		owner.CostCenter = &landscapeCostCenter
+	}
+
+	db, err := openLandscapeDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := importer.NewNodeOwnerEditor(db).SetOwner(owner, args); err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated the owner of %d node(s)\n", len(args))
+	return nil
+}
+
+func runLandscapeNodes(cmd *cobra.Command, args []string) error {
+	db, err := openLandscapeDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	nodes, err := importer.NewNodeClassificationEditor(db).List(landscapeClassification)
+	if err != nil {
+		return err
+	}
+
+	if len(nodes) == 0 {
+		fmt.Println("No landscape nodes found")
+		return nil
+	}
+
+	for _, node := range nodes {
+		classification := node.Classification
+		if node.StandbyType != "" {
+			classification += " (" + node.StandbyType + ")"
+		}
+		if node.DecommissionedOn != nil {
+			classification += " " + node.DecommissionedOn.Format("2006-01-02")
+		}
+		site := "-"
+		if node.SiteID != nil {
+			site = *node.SiteID
+		}
+		fmt.Printf("%-45s %-8s %-25s %-12s %s\n", node.MainFQDN, node.Mode, classification, site,
+			ownerSummary(node.Owner, node.Team, node.CostCenter))
+	}
+
+	return nil
+}
+
+func runLandscapeImport(cmd *cobra.Command, args []string) error {
+	file, err := os.Open(landscapeImportFile)
+	if err != nil {
+		return fmt.Errorf("failed to open CMDB export: %w", err)
+	}
+	defer file.Close()
+
+	cmdbNodes, err := importer.ReadCMDBExport(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", landscapeImportFile, err)
+	}
+
+	db, err := openLandscapeDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	landscapeImporter := importer.NewLandscapeImporter(db)
+	plan, err := landscapeImporter.Plan(cmdbNodes)
+	if err != nil {
+		return fmt.Errorf("%s: %w", landscapeImportFile, err)
+	}
+
+	fmt.Printf("CMDB export %s: %d node(s)\n\n", landscapeImportFile, len(cmdbNodes))
+	for _, change := range plan.Changes {
+		marker := "~"
+		if change.Added {
+			marker = "+"
+		}
+		fmt.Printf("%s %s\n", marker, change.MainFQDN)
+		for _, field := range change.Changes {
+			if change.Added {
+				fmt.Printf("    %-28s %s\n", field.Field, field.New)
+			} else {
+				fmt.Printf("    %-28s %q -> %q\n", field.Field, field.Old, field.New)
+			}
+		}
+	}
+	if len(plan.NewSites) > 0 {
+		fmt.Printf("\nNew sites: %s\n", strings.Join(plan.NewSites, ", "))
+	}
+	if len(plan.Missing) > 0 {
+		fmt.Printf("\nNodes not in the export (kept): %d\n", len(plan.Missing))
+		for _, fqdn := range plan.Missing {
+			fmt.Printf("  %s\n", fqdn)
+		}
+	}
+
+	added := plan.Added()
+	fmt.Printf("\nNodes to add: %d, to update: %d, unchanged: %d\n", added, len(plan.Changes)-added, plan.Unchanged)
+
+	if !landscapeImportApply {
+		if len(plan.Changes) > 0 || len(plan.NewSites) > 0 {
+			fmt.Println("Nothing was changed; run again with --apply to apply these changes")
+		}
+		return nil
+	}
+	if err := landscapeImporter.Apply(plan); err != nil {
+		return err
+	}
+	fmt.Println("Changes applied")
+	return nil
+}
+
+// ownerSummary joins the owner, team and cost center of a node, "-" when none is set
+func ownerSummary(owner, team, costCenter string) string {
+	var parts []string
+	if owner != "" {
+		parts = append(parts, owner)
+	}
+	if team != "" {
+		parts = append(parts, "team "+team)
+	}
+	if costCenter != "" {
+		parts = append(parts, "cost center "+costCenter)
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// openLandscapeDB opens the existing database given by --db-path
+func openLandscapeDB() (*sql.DB, error) {
+	if _, err := os.Stat(landscapeDBPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", landscapeDBPath)
+	}
+
+	db, err := database.Connect(landscapeDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
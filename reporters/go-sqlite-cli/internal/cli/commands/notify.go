@@ -0,0 +1,150 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/notify"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+// webhooks are the endpoints of the configuration file notified by the import
+// and collect commands
+var webhooks []notify.Webhook
+
+// importNotifier sends the webhook events of an import batch
+type importNotifier struct {
+	notifier *notify.Notifier
+	db       *sql.DB
+	// breaches are the compliance breaches found before the import, so that
+	// only breaches appearing with the imported data are notified
+	breaches map[string]bool
+}
+
+// newImportNotifier returns nil when no webhook is configured. It must be
+// created before the import, to record the breaches that already exist.
+func newImportNotifier(ctx context.Context, db *sql.DB) *importNotifier {
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	n := &importNotifier{notifier: notify.NewNotifier(webhooks), db: db}
+	if n.notifier.Subscribed(notify.EventBreach) {
+		rows, err := complianceBreaches(ctx, db)
+		if err != nil {
+			fmt.Printf("WARNING: breach notifications disabled: %v\n", err)
+		} else {
+			n.breaches = make(map[string]bool, len(rows))
+			for _, row := range rows {
+				n.breaches[breachKey(row)] = true
+			}
+		}
+	}
+	return n
+}
+
+// notify sends the events of the batch. Webhook failures are printed as
+// warnings; they do not fail the import.
+func (n *importNotifier) notify(ctx context.Context, batch *importer.BatchImportResult) {
+	if n == nil {
+		return
+	}
+
+	var events []notify.Event
+
+	if batch.FilesFailed > 0 || len(batch.Total.Errors) > 0 {
+		var details []string
+		for _, fr := range batch.Files {
+			if fr.Err != nil {
+				details = append(details, fmt.Sprintf("%s: %v", filepath.Base(fr.FilePath), fr.Err))
+			}
+		}
+		details = append(details, batch.Total.Errors...)
+		events = append(events, notify.NewEvent(notify.EventImportErrors,
+			fmt.Sprintf("Import finished with %d failed file(s) and %d warning(s)", batch.FilesFailed, len(batch.Total.Errors)),
+			details))
+	}
+
+	if len(batch.UnknownProductCodes) > 0 {
+		codes := make([]string, 0, len(batch.UnknownProductCodes))
+		for code := range batch.UnknownProductCodes {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+
+		details := make([]string, 0, len(codes))
+		for _, code := range codes {
+			details = append(details, fmt.Sprintf("%s (%d file(s))", code, batch.UnknownProductCodes[code]))
+		}
+		events = append(events, notify.NewEvent(notify.EventUnknownProduct,
+			fmt.Sprintf("%d product code(s) missing from the product_codes reference data", len(codes)),
+			details))
+	}
+
+	if n.breaches != nil && batch.FilesOK > 0 {
+		rows, err := complianceBreaches(ctx, n.db)
+		if err != nil {
+			fmt.Printf("WARNING: failed to check compliance breaches: %v\n", err)
+		}
+
+		var details []string
+		for _, row := range rows {
+			if !n.breaches[breachKey(row)] {
+				details = append(details, breachDetail(row))
+			}
+		}
+		if len(details) > 0 {
+			events = append(events, notify.NewEvent(notify.EventBreach,
+				fmt.Sprintf("%d new compliance breach(es)", len(details)),
+				details))
+		}
+	}
+
+	for _, event := range events {
+		if err := n.notifier.Send(event); err != nil {
+			fmt.Printf("WARNING: failed to send %s notification: %v\n", event.Type, err)
+		}
+	}
+}
+
+// complianceBreaches returns the rows of the compliance report flagged as a
+// breach, over all measurement dates
+func complianceBreaches(ctx context.Context, db *sql.DB) ([]reports.ComplianceRow, error) {
+	return reports.NewComplianceReport(db).Query(ctx, "", "", nil, nil, true)
+}
+
+// breachKey identifies a compliance row across two runs of the report
+func breachKey(row reports.ComplianceRow) string {
+	return row.MeasurementDate.Format("2006-01-02") + "|" + row.ProductMnemoCode + "|" + row.Mode + "|" + row.TermID
+}
+
+// breachDetail describes why a compliance row is a breach
+func breachDetail(row reports.ComplianceRow) string {
+	detail := fmt.Sprintf("%s %s (%s): %d license cores",
+		row.MeasurementDate.Format("2006-01-02"), row.ProductMnemoCode, row.Mode, row.LicenseCores)
+	if row.ThresholdDelta != nil && *row.ThresholdDelta < 0 {
+		detail += fmt.Sprintf(", threshold %d", *row.ThresholdCores)
+	}
+	if row.ComplianceStatus == reports.StatusUnderLicensed {
+		detail += fmt.Sprintf(", term %s under-licensed", row.TermID)
+	}
+	return detail
+}
@@ -0,0 +1,239 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	orgsDBPath      string
+	orgsName        string
+	orgsDescription string
+	orgsUnassign    bool
+	orgsListNodes   bool
+)
+
+// NewOrgsCmd creates the orgs command
+func NewOrgsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "orgs",
+		Short: "Manage organizations (subsidiaries or tenants)",
+		Long: `Manage the organizations sharing the database, e.g. subsidiaries whose
+license entitlements must be reported separately.
+
+Nodes belong to one organization each: assign them here, or import their files
+with 'iwdlr import --org'. Each organization has its own entitlements per
+license term, loaded with 'iwdlr import entitlements --org'. The compliance and
+daily-summary reports show the usage of one organization with --org, or of
+every organization with --group-by org; host-detail lists the nodes of one
+organization with --org.
+
+Example:
+  iwdlr orgs add acme-de --name "ACME Deutschland GmbH"
+  iwdlr orgs assign acme-de i23.example.com i24.example.com
+  iwdlr import entitlements --org acme-de --file ./entitlements-acme-de.csv
+  iwdlr report compliance --org acme-de`,
+	}
+
+	cmd.PersistentFlags().StringVar(&orgsDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+
+	add := &cobra.Command{
+		Use:   "add <org-id>",
+		Short: "Add an organization or update its name and description",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runOrgsAdd,
+	}
+	add.Flags().StringVar(&orgsName, "name", "", "Display name of the organization (default: the organization ID)")
+	add.Flags().StringVar(&orgsDescription, "description", "", "Description of the organization")
+
+	remove := &cobra.Command{
+		Use:   "remove <org-id>",
+		Short: "Remove an organization and its entitlements",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runOrgsRemove,
+	}
+	remove.Flags().BoolVar(&orgsUnassign, "unassign", false, "Unassign the nodes of the organization instead of failing")
+
+	assign := &cobra.Command{
+		Use:   "assign <org-id> <fqdn>...",
+		Short: "Assign landscape nodes to an organization",
+		Long:  "Assign landscape nodes to an organization, replacing their previous organization.",
+		Args:  cobra.MinimumNArgs(2),
+		RunE:  runOrgsAssign,
+	}
+
+	unassign := &cobra.Command{
+		Use:   "unassign <fqdn>...",
+		Short: "Remove landscape nodes from their organization",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  runOrgsUnassign,
+	}
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List organizations",
+		Args:  cobra.NoArgs,
+		RunE:  runOrgsList,
+	}
+	list.Flags().BoolVar(&orgsListNodes, "nodes", false, "List the nodes of each organization and the nodes without one")
+
+	cmd.AddCommand(add, remove, assign, unassign, list)
+
+	return cmd
+}
+
+func runOrgsAdd(cmd *cobra.Command, args []string) error {
+	db, err := openOrgsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	created, err := importer.NewOrgEditor(db).Save(args[0], orgsName, orgsDescription)
+	if err != nil {
+		return err
+	}
+
+	if created {
+		fmt.Printf("Added organization %s\n", args[0])
+		fmt.Println("\nNext steps:")
+		fmt.Printf("  - Assign nodes: iwdlr orgs assign --db-path %s %s <fqdn>...\n", orgsDBPath, args[0])
+		fmt.Printf("  - Load entitlements: iwdlr import entitlements --db-path %s --org %s --file <csv>\n", orgsDBPath, args[0])
+	} else {
+		fmt.Printf("Updated organization %s\n", args[0])
+	}
+	return nil
+}
+
+func runOrgsRemove(cmd *cobra.Command, args []string) error {
+	db, err := openOrgsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	nodes, err := importer.NewOrgEditor(db).Remove(args[0], orgsUnassign)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed organization %s\n", args[0])
+	if nodes > 0 {
+		fmt.Printf("  Nodes unassigned: %d\n", nodes)
+	}
+	return nil
+}
+
+func runOrgsAssign(cmd *cobra.Command, args []string) error {
+	db, err := openOrgsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := importer.NewOrgEditor(db).Assign(args[0], args[1:]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Assigned %d node(s) to organization %s\n", len(args)-1, args[0])
+	return nil
+}
+
+func runOrgsUnassign(cmd *cobra.Command, args []string) error {
+	db, err := openOrgsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := importer.NewOrgEditor(db).Assign("", args); err != nil {
+		return err
+	}
+
+	fmt.Printf("Unassigned %d node(s)\n", len(args))
+	return nil
+}
+
+func runOrgsList(cmd *cobra.Command, args []string) error {
+	db, err := openOrgsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	editor := importer.NewOrgEditor(db)
+	orgs, err := editor.ListOrgs()
+	if err != nil {
+		return err
+	}
+
+	if len(orgs) == 0 {
+		fmt.Println("No organizations defined")
+	}
+
+	for _, o := range orgs {
+		fmt.Printf("%-16s %-32s %d node(s), %d entitled term(s)\n", o.OrgID, o.OrgName, o.NodeCount, o.TermCount)
+		if o.Description != "" {
+			fmt.Printf("  %s\n", o.Description)
+		}
+		if orgsListNodes {
+			if err := printOrgNodes(editor, o.OrgID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if orgsListNodes {
+		fmt.Println("\nWithout an organization:")
+		if err := printOrgNodes(editor, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printOrgNodes prints the nodes of an organization, or the nodes without one
+func printOrgNodes(editor *importer.OrgEditor, orgID string) error {
+	nodes, err := editor.ListOrgNodes(orgID)
+	if err != nil {
+		return err
+	}
+	for _, fqdn := range nodes {
+		fmt.Printf("    %s\n", fqdn)
+	}
+	return nil
+}
+
+// openOrgsDB opens the existing database given by --db-path
+func openOrgsDB() (*sql.DB, error) {
+	if _, err := os.Stat(orgsDBPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", orgsDBPath)
+	}
+
+	db, err := database.Connect(orgsDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
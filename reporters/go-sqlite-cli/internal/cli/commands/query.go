@@ -0,0 +1,123 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+	"github.com/spf13/cobra"
+)
+
+var (
+	queryDBPath string
+	queryFormat string
+	queryOutput string
+	queryName   string
+	queryList   bool
+
+	// savedQueries are the named SQL statements of the configuration file
+	savedQueries map[string]string
+)
+
+// NewQueryCmd creates the query command
+func NewQueryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "query [SQL]",
+		Short: "Run a read-only SQL statement against the database",
+		Long: `Run an SQL statement against the database and print its result with the
+report formats, for questions the reports do not answer.
+
+The database is opened read-only: statements that write (INSERT, UPDATE,
+DELETE, CREATE, ...) fail. Statements used regularly can be saved under a name
+in the queries section of the configuration file and run with --name:
+
+  queries:
+    big-hosts: >-
+      SELECT main_fqdn, MAX(considered_cpus) AS cores
+      FROM measurements GROUP BY main_fqdn HAVING cores >= 16
+
+Example:
+  iwdlr query "SELECT product_mnemo_code, mode FROM product_codes"
+  iwdlr query --name big-hosts --format csv --output big-hosts.csv
+  iwdlr query --list`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runQuery,
+	}
+
+	cmd.Flags().StringVar(&queryDBPath, "db-path", "data/license-monitor.db", "Path to the SQLite database file")
+	cmd.Flags().StringVarP(&queryFormat, "format", "f", "table", "Output format: table, csv, json, xlsx")
+	cmd.Flags().StringVarP(&queryOutput, "output", "o", "", "Output file (default: stdout)")
+	cmd.Flags().StringVar(&queryName, "name", "", "Run the saved query of the configuration file with this name")
+	cmd.Flags().BoolVar(&queryList, "list", false, "List the saved queries of the configuration file")
+
+	return cmd
+}
+
+func runQuery(cmd *cobra.Command, args []string) error {
+	if queryList {
+		names := make([]string, 0, len(savedQueries))
+		for name := range savedQueries {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%-20s %s\n", name, savedQueries[name])
+		}
+		return nil
+	}
+
+	var statement string
+	switch {
+	case queryName != "" && len(args) > 0:
+		return fmt.Errorf("give either an SQL statement or --name, not both")
+	case queryName != "":
+		var ok bool
+		if statement, ok = savedQueries[queryName]; !ok {
+			return fmt.Errorf("no saved query named %q in the configuration file", queryName)
+		}
+	case len(args) > 0:
+		statement = args[0]
+	default:
+		return fmt.Errorf("an SQL statement or --name is required")
+	}
+
+	// Check database exists
+	if _, err := os.Stat(queryDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", queryDBPath)
+	}
+
+	// Writes are rejected by SQLite itself
+	db, err := database.ConnectReadOnly(queryDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	report := reports.NewSQLQueryReport(db)
+	rows, err := report.Query(cmd.Context(), statement)
+	if err != nil {
+		return err
+	}
+
+	if rows == nil {
+		rows = []reports.SQLRow{}
+	}
+
+	return writeOutput(report, rows, queryFormat, queryOutput)
+}
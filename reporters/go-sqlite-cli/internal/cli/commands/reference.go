@@ -0,0 +1,197 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"os/user"
+	"text/tabwriter"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	referenceDBPath    string
+	referenceOverwrite bool
+	referenceList      bool
+	referenceTerm      string
+	referenceProduct   string
+)
+
+// NewReferenceCmd creates the reference command
+func NewReferenceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reference",
+		Short: "Manage the reference data",
+		Long:  "Manage the license terms and product codes the measurements are reported against",
+	}
+
+	cmd.PersistentFlags().StringVar(&referenceDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+
+	bootstrap := &cobra.Command{
+		Use:   "bootstrap",
+		Short: "Seed the license terms and product codes from the built-in catalog",
+		Long: `Load the catalog of known webMethods products embedded in the binary into
+license_terms and product_codes: each product mnemonic detected by the
+inspector with its IBM product code, license term and program number. A new
+deployment can import inspector files right after 'iwdlr init' and
+'iwdlr reference bootstrap', without hunting down the reference CSV files.
+
+License terms and product codes already in the database are kept, so the
+bootstrap never undoes reference data loaded with --load-reference; use
+--overwrite to reset them to the catalog. Products missing from the catalog
+still need a product-codes.csv (see 'iwdlr import --load-reference').
+
+Use --list to print the catalog without changing the database.
+
+Example:
+  iwdlr init --db-path data/license-monitor.db
+  iwdlr reference bootstrap --db-path data/license-monitor.db
+  iwdlr reference bootstrap --list`,
+		Args: cobra.NoArgs,
+		RunE: runReferenceBootstrap,
+	}
+	bootstrap.Flags().BoolVar(&referenceOverwrite, "overwrite", false,
+		"Reset the license terms and product codes of the catalog already in the database")
+	bootstrap.Flags().BoolVar(&referenceList, "list", false, "Print the catalog without changing the database")
+
+	history := &cobra.Command{
+		Use:   "history",
+		Short: "List the changes of the license terms and product codes",
+		Long: `List the license terms and product codes inserted or changed by reference data
+loads (import --load-reference, import entitlements and reference bootstrap),
+oldest first, with the old and new value of each changed column, the file or
+catalog they came from, the user and the time.
+
+Changing the license term a product code is mapped to retroactively changes the
+compliance numbers of every past day, so the history shows when and by whom the
+mapping behind a report changed. Loads leaving a row unchanged are not
+recorded, and reference data is never deleted.
+
+Example:
+  iwdlr reference history
+  iwdlr reference history --product IS_ONP_PRD
+  iwdlr reference history --term L-USRQ-RKUUCN`,
+		Args: cobra.NoArgs,
+		RunE: runReferenceHistory,
+	}
+	history.Flags().StringVar(&referenceTerm, "term", "", "Only list the changes of this license term ID")
+	history.Flags().StringVar(&referenceProduct, "product", "", "Only list the changes of this product mnemo code")
+
+	cmd.AddCommand(bootstrap)
+	cmd.AddCommand(history)
+	return cmd
+}
+
+func runReferenceBootstrap(cmd *cobra.Command, args []string) error {
+	if referenceList {
+		products, err := importer.Catalog()
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "PRODUCT\tIBM CODE\tMODE\tTERM\tPROGRAM\tNAME")
+		fmt.Fprintln(tw, "-------\t--------\t----\t----\t-------\t----")
+		for _, p := range products {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", p.MnemoCode, p.ProductCode, p.Mode, p.TermID, p.ProgramNumber, p.ProductName)
+		}
+		return tw.Flush()
+	}
+
+	db, err := openReferenceDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := newReferenceLoader(db).LoadCatalog(cmd.Context(), referenceOverwrite); err != nil {
+		return err
+	}
+	refreshReportCache(db)
+
+	fmt.Println("\nNext steps:")
+	fmt.Println("  - Import inspector files: iwdlr import --db-path", referenceDBPath, "--dir <dir>")
+	fmt.Println("  - Keep the reference data in git: iwdlr export reference --db-path", referenceDBPath, "--out ./reference")
+
+	return nil
+}
+
+func runReferenceHistory(cmd *cobra.Command, args []string) error {
+	db, err := openReferenceDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	changes, err := importer.NewReferenceDataLoader(db).History(referenceTerm, referenceProduct)
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("No reference data changes recorded")
+		return nil
+	}
+
+	for _, c := range changes {
+		fmt.Printf("%s %s %s: %s\n", c.ChangedAt.Format("2006-01-02 15:04:05"), c.Change, c.Table, c.Key)
+		for _, v := range c.Values {
+			if c.Change == importer.ReferenceInsert {
+				if v.New == "" {
+					continue
+				}
+				fmt.Printf("  %-17s %s\n", v.Column+":", v.New)
+			} else {
+				fmt.Printf("  %-17s %s -> %s\n", v.Column+":", v.Old, v.New)
+			}
+		}
+		if c.Source != "" {
+			fmt.Printf("  %-17s %s\n", "Source:", c.Source)
+		}
+		if c.ChangedBy != "" {
+			fmt.Printf("  %-17s %s\n", "By:", c.ChangedBy)
+		}
+	}
+
+	return nil
+}
+
+// newReferenceLoader creates a reference data loader recording the current
+// user as the author of the reference data changes
+func newReferenceLoader(db *sql.DB) *importer.ReferenceDataLoader {
+	loader := importer.NewReferenceDataLoader(db)
+	if u, err := user.Current(); err == nil {
+		loader.ChangedBy = u.Username
+	}
+	return loader
+}
+
+// openReferenceDB opens the existing database given by --db-path
+func openReferenceDB() (*sql.DB, error) {
+	if _, err := os.Stat(referenceDBPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", referenceDBPath)
+	}
+
+	db, err := database.Connect(referenceDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
@@ -2,12 +2,9 @@ package commands
 
 import (
 	"fmt"
-	"os"
-	"time"
 
 	"github.com/spf13/cobra"
 
-	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
 	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
 )
 
@@ -40,7 +37,10 @@ Separates running products (status='present') from installed products.
 Example:
   iwdlr report daily-summary --db-path data/license-monitor.db
   iwdlr report daily-summary --format csv --output report.csv
-  iwdlr report daily-summary --from 2025-10-01 --to 2025-10-31`,
+  iwdlr report daily-summary --from 2025-10-01 --to 2025-10-31
+  iwdlr report daily-summary --group-by site --format csv --output sites.csv
+  iwdlr report daily-summary --group-by cost-center --from 2025-10-01
+  iwdlr report daily-summary --org acme-de --from 2025-10-01`,
 	RunE:  runReportDailySummary,
 }
 
@@ -50,12 +50,20 @@ var reportHostDetailCmd = &cobra.Command{
 	Long: `Shows detailed information for each host including product detection and system details.
 
 Displays host FQDN, date, virtualization status, product codes, running/installed status,
-CPU counts, physical host mapping, OS details, and eligibility flags.
+CPU counts, physical host mapping, OS details, eligibility flags, and the owner,
+team and cost center set with 'iwdlr landscape owner'.
+
+The --host, --os, --virt-type and --owner filters take comma-separated lists of values
+or glob patterns (* for any characters, ? for one), matched case-insensitively.
+A host pattern without a dot also matches the host name of the FQDN, and an
+owner pattern matches the owner or the team of the node.
 
 Example:
   iwdlr report host-detail --db-path data/license-monitor.db
   iwdlr report host-detail --host i4.local --format csv
-  iwdlr report host-detail --product IS_ONP_PRD --from 2025-10-01`,
+  iwdlr report host-detail --host "app0?,db*.example.com" --os Linux --virt-type "VMware*"
+  iwdlr report host-detail --product IS_ONP_PRD --from 2025-10-01
+  iwdlr report host-detail --owner "alice,integration*"`,
 	RunE:  runReportHostDetail,
 }
 
@@ -67,8 +75,26 @@ var reportPeakUsageCmd = &cobra.Command{
 Displays the highest values recorded for running and installed cores, nodes, and 
 eligibility metrics. Useful for capacity planning and license compliance monitoring.
 
+With --period the peaks are computed within a contract period of the license
+term of each product instead (see 'iwdlr terms period'): current, previous or
+the label of a period. Products whose term has no such period are left out.
+
+With --carry-forward N, a node that missed up to N days of reporting, e.g.
+during a collection outage, counts on those days with its last measurement, so
+that the outage does not lower its usage. Peaks reached with carried nodes are
+flagged with a + (and carried_forward_nodes in CSV and JSON).
+
+The smoothed peak (SMOOTHED) ignores spikes shorter than the grace window of the
+product, such as transient DR failover usage: it is the highest level held for
+that many consecutive days with measurements. Grace windows are loaded per
+product with 'iwdlr import grace-windows'; --grace-days sets the window of the
+other products. The raw peak is always shown next to it.
+
 Example:
   iwdlr report peak --db-path data/license-monitor.db
+  iwdlr report peak --period current
+  iwdlr report peak --grace-days 3
+  iwdlr report peak --carry-forward 3
   iwdlr report peak --format csv --output peak-usage.csv
   iwdlr report peak --product IS_ONP_PRD --format json`,
 	RunE:  runReportPeakUsage,
@@ -91,16 +117,52 @@ Example:
 	RunE:  runReportPeakBreakdown,
 }
 
+// carryForwardFlagUsage is the usage of --carry-forward, shared by the
+// compliance and peak reports
+const carryForwardFlagUsage = "Carry the last measurement of nodes that missed up to this many days of reporting forward (max 31, default: off)"
+
+// standbyFlagUsage is the usage of --standby, shared by the node-level reports
+const standbyFlagUsage = "Standby and DR nodes to report: include, exclude, or licensable (leaves out cold standby and DR nodes)"
+
+// orgFlagUsage is the usage of --org, shared by the reports of an organization
+const orgFlagUsage = "Report the nodes of this organization only (see 'iwdlr orgs')"
+
 var (
 	reportDBPath       string
 	reportFormat       string
 	reportOutput       string
+	reportFormatsDir   string
+	reportColumns      string
+	reportNoHeader     bool
+	reportLocale       string
+	reportDateFormat   string
 	reportProduct      string
 	reportFromDate     string
 	reportToDate       string
 	reportHost         string
 	reportSystemType   string
 	reportNonCompliant bool
+	reportQuarter      string
+	reportAllNodes     bool
+	reportLatest       bool
+	reportMode         string
+	reportGroupBy      string
+	reportOrg          string
+	reportTemplate     string
+	reportPeriod       string
+	reportGraceDays    int
+	reportCarryForward int
+	reportStandby      string
+	reportOS           string
+	reportVirtType     string
+	reportOwner        string
+	reportFilter       string
+	reportLimit        int
+	reportOffset       int
+	reportSort         string
+
+	// reportName is the name of the report command being run, for templates
+	reportName string
 )
 
 func init() {
@@ -113,49 +175,88 @@ func init() {
 	
 	// Global report flags
 	reportCmd.PersistentFlags().StringVar(&reportDBPath, "db-path", "data/license-monitor.db", "Path to the SQLite database file")
-	reportCmd.PersistentFlags().StringVarP(&reportFormat, "format", "f", "table", "Output format: table, csv, json")
+	reportCmd.PersistentFlags().StringVarP(&reportFormat, "format", "f", "table", "Output format: table, csv, json, xlsx, or several comma separated with --output-dir")
 	reportCmd.PersistentFlags().StringVarP(&reportOutput, "output", "o", "", "Output file (default: stdout)")
+	reportCmd.PersistentFlags().StringVar(&reportFormatsDir, "output-dir", "", "Write <report>.<format> files to this directory, one per format of a comma separated --format (e.g. table,csv,json)")
 	reportCmd.PersistentFlags().StringVar(&reportProduct, "product", "", "Filter by product code")
 	reportCmd.PersistentFlags().StringVar(&reportFromDate, "from", "", "Filter from date (YYYY-MM-DD)")
 	reportCmd.PersistentFlags().StringVar(&reportToDate, "to", "", "Filter to date (YYYY-MM-DD)")
+	reportCmd.PersistentFlags().StringVar(&reportMode, "mode", "", "Filter by environment: PROD or NON PROD")
+	reportCmd.PersistentFlags().StringVar(&reportFilter, "filter", "", "Apply the named filter set of the configuration file (report.filters); flags given on the command line win")
+	reportCmd.PersistentFlags().StringVar(&reportTemplate, "template", "", "Render the rows with a Go text/template file instead of --format")
+	reportCmd.PersistentFlags().StringVar(&reportColumns, "columns", "", "Write only these comma-separated CSV columns, in this order, each optionally renamed with name=header (csv and table output)")
+	reportCmd.PersistentFlags().BoolVar(&reportNoHeader, "no-header", false, "Leave out the header line (csv and table output)")
+	reportCmd.PersistentFlags().StringVar(&reportLocale, "locale", "", "Write the numbers and dates of table output as in this locale (e.g. de-DE, fr, en-GB)")
+	reportCmd.PersistentFlags().StringVar(&reportDateFormat, "date-format", "", "Write the dates of table output in this format, e.g. DD.MM.YYYY (overrides the date format of --locale)")
+	reportCmd.PersistentFlags().IntVar(&reportLimit, "limit", 0, "Write at most this many rows (default: all)")
+	reportCmd.PersistentFlags().IntVar(&reportOffset, "offset", 0, "Skip this many rows before writing")
+	reportCmd.PersistentFlags().StringVar(&reportSort, "sort", "", "Sort the rows by comma-separated columns, descending with a - prefix (e.g. -peak_running_total_cores,product_mnemo_code)")
+	reportCmd.PersistentFlags().BoolVar(&statusJSON, "status-json", false, statusJSONFlagUsage)
+	
+	// Daily summary specific flags
+	reportDailySummaryCmd.Flags().StringVar(&reportGroupBy, "group-by", "", "Group the usage by: site, org, cost-center")
+	
+	// Peak specific flags
+	reportPeakUsageCmd.Flags().StringVar(&reportPeriod, "period", "", "Compute the peaks within a contract period: current, previous or a period label")
+	reportPeakUsageCmd.Flags().IntVar(&reportGraceDays, "grace-days", 0, "Grace window of the smoothed peak for products without their own (see 'import grace-windows')")
+	reportPeakUsageCmd.Flags().IntVar(&reportCarryForward, "carry-forward", 0, carryForwardFlagUsage)
 	
 	// Host detail specific flags
-	reportHostDetailCmd.Flags().StringVar(&reportHost, "host", "", "Filter by host FQDN (supports wildcards)")
+	reportHostDetailCmd.Flags().StringVar(&reportHost, "host", "", "Filter by host: comma-separated FQDNs, host names or glob patterns (app*.example.com)")
+	reportHostDetailCmd.Flags().StringVar(&reportOS, "os", "", "Filter by operating system name: comma-separated names or glob patterns (Linux, AIX)")
+	reportHostDetailCmd.Flags().StringVar(&reportVirtType, "virt-type", "", "Filter by virtualization type: comma-separated types or glob patterns (VMware*, LPAR)")
+	reportHostDetailCmd.Flags().StringVar(&reportOwner, "owner", "", "Filter by node owner or team: comma-separated names or glob patterns (alice, integration*)")
+	
+	// Organization of the reports (see 'iwdlr orgs')
+	for _, c := range []*cobra.Command{reportDailySummaryCmd, reportHostDetailCmd} {
+		c.Flags().StringVar(&reportOrg, "org", "", orgFlagUsage)
+	}
+	
+	// Standby and DR nodes of the node-level reports
+	for _, c := range []*cobra.Command{reportCoresCmd, reportHostDetailCmd} {
+		c.Flags().StringVar(&reportStandby, "standby", "", standbyFlagUsage)
+	}
 }
 
 func runReportCores(cmd *cobra.Command, args []string) error {
-	// Parse date filters
-	var fromDate, toDate *time.Time
-	var err error
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
 	
-	if reportFromDate != "" {
-		t, err := time.Parse("2006-01-02", reportFromDate)
-		if err != nil {
-			return fmt.Errorf("invalid from date format: %w", err)
-		}
-		fromDate = &t
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
 	}
 	
-	if reportToDate != "" {
-		t, err := time.Parse("2006-01-02", reportToDate)
-		if err != nil {
-			return fmt.Errorf("invalid to date format: %w", err)
-		}
-		toDate = &t
+	standby, err := reports.ParseStandbyPolicy(reportStandby)
+	if err != nil {
+		return err
 	}
 	
 	// Open database
-	db, err := database.Connect(reportDBPath)
+	db, err := openReportDB()
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return err
 	}
 	defer db.Close()
 	
 	// Create report generator
 	report := reports.NewCoreAggregationReport(db)
+	report.SetStandbyPolicy(standby)
+	
+	// Large exports are written while the query runs, unless they are paged
+	if reportPage().IsZero() {
+		streamed, err := streamReportOutput(report, func(fn func(reports.CoreAggregationRow) error) error {
+			return report.Each(cmd.Context(), reportProduct, mode, fromDate, toDate, fn)
+		})
+		if streamed {
+			return err
+		}
+	}
 	
 	// Query data
-	rows, err := report.Query(reportProduct, fromDate, toDate)
+	rows, err := report.Query(cmd.Context(), reportProduct, mode, fromDate, toDate)
 	if err != nil {
 		return fmt.Errorf("failed to query data: %w", err)
 	}
@@ -165,74 +266,47 @@ func runReportCores(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 	
-	// Determine output writer
-	var writer *os.File
-	if reportOutput != "" {
-		writer, err = os.Create(reportOutput)
-		if err != nil {
-			return fmt.Errorf("failed to create output file: %w", err)
-		}
-		defer writer.Close()
-	} else {
-		writer = os.Stdout
-	}
-	
-	// Write output in requested format
-	switch reportFormat {
-	case "table":
-		err = report.WriteTable(writer, rows)
-	case "csv":
-		err = report.WriteCSV(writer, rows)
-	case "json":
-		err = report.WriteJSON(writer, rows)
-	default:
-		return fmt.Errorf("unknown format: %s (use table, csv, or json)", reportFormat)
-	}
-	
-	if err != nil {
-		return fmt.Errorf("failed to write output: %w", err)
-	}
-	
-	if reportOutput != "" {
-		fmt.Printf("Report written to %s\n", reportOutput)
-	}
-	
-	return nil
+	return writeReportOutput(report, rows)
 }
 
 func runReportDailySummary(cmd *cobra.Command, args []string) error {
-	// Parse date filters
-	var fromDate, toDate *time.Time
-	var err error
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
 	
-	if reportFromDate != "" {
-		t, err := time.Parse("2006-01-02", reportFromDate)
-		if err != nil {
-			return fmt.Errorf("invalid from date format: %w", err)
-		}
-		fromDate = &t
+	groupBy, err := parseGroupBy()
+	if err != nil {
+		return err
 	}
 	
-	if reportToDate != "" {
-		t, err := time.Parse("2006-01-02", reportToDate)
-		if err != nil {
-			return fmt.Errorf("invalid to date format: %w", err)
-		}
-		toDate = &t
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
 	}
 	
 	// Open database
-	db, err := database.Connect(reportDBPath)
+	db, err := openReportDB()
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return err
 	}
 	defer db.Close()
 	
+	switch groupBy {
+	case groupBySite:
+		return writeSiteReport(cmd.Context(), reports.NewSiteSummaryReport(db), mode, fromDate, toDate)
+	case groupByCostCenter:
+		return writeCostCenterReport(cmd.Context(), reports.NewCostCenterSummaryReport(db), mode, fromDate, toDate)
+	case groupByOrg:
+		_, err := writeOrgReport(cmd.Context(), db, reports.NewOrgSummaryReport(db), mode, fromDate, toDate, false)
+		return err
+	}
+	
 	// Create report generator
 	report := reports.NewDailySummaryReport(db)
 	
 	// Query data
-	rows, err := report.Query(reportProduct, fromDate, toDate)
+	rows, err := report.Query(cmd.Context(), reportProduct, mode, fromDate, toDate)
 	if err != nil {
 		return fmt.Errorf("failed to query data: %w", err)
 	}
@@ -242,106 +316,96 @@ func runReportDailySummary(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 	
-	// Determine output writer
-	var writer *os.File
-	if reportOutput != "" {
-		writer, err = os.Create(reportOutput)
-		if err != nil {
-			return fmt.Errorf("failed to create output file: %w", err)
-		}
-		defer writer.Close()
-	} else {
-		writer = os.Stdout
-	}
-	
-	// Write output in requested format
-	switch reportFormat {
-	case "table":
-		err = report.WriteTable(writer, rows)
-	case "csv":
-		err = report.WriteCSV(writer, rows)
-	case "json":
-		err = report.WriteJSON(writer, rows)
-	default:
-		return fmt.Errorf("unknown format: %s (use table, csv, or json)", reportFormat)
-	}
-	
-	if err != nil {
-		return fmt.Errorf("failed to write output: %w", err)
-	}
-	
-	if reportOutput != "" {
-		fmt.Printf("Report written to %s\n", reportOutput)
-	}
-	
-	return nil
+	return writeReportOutput(report, rows)
 }
 
 
 func runReportHostDetail(cmd *cobra.Command, args []string) error {
-db, err := database.Connect(reportDBPath)
+mode, err := reports.ParseMode(reportMode)
 if err != nil {
-return fmt.Errorf("failed to open database: %w", err)
+return err
 }
-defer db.Close()
 
-report := reports.NewHostDetailReport(db)
-rows, err := report.Query(reportHost, reportProduct, reportFromDate, reportToDate)
+standby, err := reports.ParseStandbyPolicy(reportStandby)
 if err != nil {
-return fmt.Errorf("failed to query data: %w", err)
-}
-
-if len(rows) == 0 {
-fmt.Println("No data found matching the criteria")
-return nil
+return err
 }
 
-var writer *os.File
-if reportOutput != "" {
-writer, err = os.Create(reportOutput)
+db, err := openReportDB()
 if err != nil {
-return fmt.Errorf("failed to create output file: %w", err)
-}
-defer writer.Close()
-} else {
-writer = os.Stdout
+return err
 }
+defer db.Close()
 
-switch reportFormat {
-case "table":
-err = report.WriteTable(writer, rows)
-case "csv":
-err = report.WriteCSV(writer, rows)
-case "json":
-err = report.WriteJSON(writer, rows)
-default:
-return fmt.Errorf("unknown format: %s (use table, csv, or json)", reportFormat)
+report := reports.NewHostDetailReport(db)
+report.SetStandbyPolicy(standby)
+report.SetSystemFilters(reportOS, reportVirtType)
+if err := requireReportOrg(db); err != nil {
+return err
+}
+report.SetOrg(reportOrg)
+report.SetOwner(reportOwner)
+report.SetPage(reportPage())
+
+// Large exports are written while the query runs
+streamed, err := streamReportOutput(report, func(fn func(reports.HostDetailRow) error) error {
+return report.Each(cmd.Context(), reportHost, reportProduct, mode, reportFromDate, reportToDate, fn)
+})
+if streamed {
+return err
 }
 
+rows, err := report.Query(cmd.Context(), reportHost, reportProduct, mode, reportFromDate, reportToDate)
 if err != nil {
-return fmt.Errorf("failed to write output: %w", err)
+return fmt.Errorf("failed to query data: %w", err)
 }
 
-if reportOutput != "" {
-fmt.Printf("Report written to %s\n", reportOutput)
+if len(rows) == 0 {
+fmt.Println("No data found matching the criteria")
+return nil
 }
 
-return nil
+return writeReportRows(report, rows)
 }
 
 func runReportPeakUsage(cmd *cobra.Command, args []string) error {
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+	if reportGraceDays < 0 {
+		return fmt.Errorf("--grace-days must not be negative")
+	}
+	if err := validateCarryForward(); err != nil {
+		return err
+	}
+	
 	// Open database
-	db, err := database.Connect(reportDBPath)
+	db, err := openReportDB()
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return err
 	}
 	defer db.Close()
 	
 	// Create report generator
 	report := reports.NewPeakUsageReport(db)
+	report.SetDefaultGraceDays(reportGraceDays)
+	report.SetCarryForwardDays(reportCarryForward)
 	
 	// Query data
-	rows, err := report.Query(reportProduct)
+	if reportPeriod != "" {
+		rows, err := report.QueryPeriod(cmd.Context(), reportProduct, mode, reportPeriod)
+		if err != nil {
+			return fmt.Errorf("failed to query data: %w", err)
+		}
+		if len(rows) == 0 {
+			fmt.Printf("No data found in the %s contract period (see 'iwdlr terms period add')\n", reportPeriod)
+			return nil
+		}
+		return writeReportOutput(report, rows)
+	}
+	
+	rows, err := report.Query(cmd.Context(), reportProduct, mode)
 	if err != nil {
 		return fmt.Errorf("failed to query data: %w", err)
 	}
@@ -351,38 +415,14 @@ func runReportPeakUsage(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 	
-	// Determine output writer
-	var writer *os.File
-	if reportOutput != "" {
-		writer, err = os.Create(reportOutput)
-		if err != nil {
-			return fmt.Errorf("failed to create output file: %w", err)
-		}
-		defer writer.Close()
-	} else {
-		writer = os.Stdout
-	}
-	
-	// Write output in requested format
-	switch reportFormat {
-	case "table":
-		err = report.WriteTable(writer, rows)
-	case "csv":
-		err = report.WriteCSV(writer, rows)
-	case "json":
-		err = report.WriteJSON(writer, rows)
-	default:
-		return fmt.Errorf("unknown format: %s (use table, csv, or json)", reportFormat)
-	}
-	
-	if err != nil {
-		return fmt.Errorf("failed to write output: %w", err)
-	}
-	
-	if reportOutput != "" {
-		fmt.Printf("Report written to %s\n", reportOutput)
+	return writeReportOutput(report, rows)
+}
+
+// validateCarryForward checks the --carry-forward days
+func validateCarryForward() error {
+	if reportCarryForward < 0 || reportCarryForward > reports.MaxCarryForwardDays {
+		return fmt.Errorf("--carry-forward must be between 0 and %d days", reports.MaxCarryForwardDays)
 	}
-	
 	return nil
 }
 
@@ -392,10 +432,15 @@ func runReportPeakBreakdown(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("--product flag is required for peak-breakdown report")
 	}
 	
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+	
 	// Open database
-	db, err := database.Connect(reportDBPath)
+	db, err := openReportDB()
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return err
 	}
 	defer db.Close()
 	
@@ -403,7 +448,7 @@ func runReportPeakBreakdown(cmd *cobra.Command, args []string) error {
 	report := reports.NewPeakBreakdownReport(db)
 	
 	// Query data
-	rows, err := report.Query(reportProduct, reportFromDate, reportToDate)
+	rows, err := report.Query(cmd.Context(), reportProduct, mode, reportFromDate, reportToDate)
 	if err != nil {
 		return fmt.Errorf("failed to query data: %w", err)
 	}
@@ -413,37 +458,5 @@ func runReportPeakBreakdown(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 	
-	// Determine output writer
-	var writer *os.File
-	if reportOutput != "" {
-		writer, err = os.Create(reportOutput)
-		if err != nil {
-			return fmt.Errorf("failed to create output file: %w", err)
-		}
-		defer writer.Close()
-	} else {
-		writer = os.Stdout
-	}
-	
-	// Write output in requested format
-	switch reportFormat {
-	case "table":
-		err = report.WriteTable(writer, rows)
-	case "csv":
-		err = report.WriteCSV(writer, rows)
-	case "json":
-		err = report.WriteJSON(writer, rows)
-	default:
-		return fmt.Errorf("unknown format: %s (use table, csv, or json)", reportFormat)
-	}
-	
-	if err != nil {
-		return fmt.Errorf("failed to write output: %w", err)
-	}
-	
-	if reportOutput != "" {
-		fmt.Printf("Report written to %s\n", reportOutput)
-	}
-	
-	return nil
+	return writeReportOutput(report, rows)
 }
@@ -0,0 +1,279 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var (
+	reportAllOutDir  string
+	reportAllFormats string
+)
+
+var reportAllCmd = &cobra.Command{
+	Use:   "all",
+	Short: "Generate every report in every format into a directory",
+	Long: `Generates every report for the selected period in one run, in each format of
+--formats, and writes them to --out-dir as <report>.<format> (table output as
+<report>.txt). manifest.json describes the run: the period and filters, the
+schema version, and the row count and SHA-256 checksum of every file.
+
+The reports are daily-summary, host-detail, cores, compliance, monthly-peak,
+peak, install-detail, trend, subcapacity, cloud, drift, hosts and imports, plus
+peak-breakdown when --product is set. Reports without rows are written too, so
+that every run produces the same files. peak always covers the last 31 days;
+hosts and imports are left out when --mode is set, since they are shared by
+both environments.
+
+Example:
+  iwdlr report all --out-dir reports/2025-10/ --from 2025-10-01 --to 2025-10-31
+  iwdlr report all --out-dir reports/2025-10/ --formats csv,xlsx --mode PROD`,
+	Args: cobra.NoArgs,
+	RunE: runReportAll,
+}
+
+func init() {
+	reportCmd.AddCommand(reportAllCmd)
+	reportAllCmd.Flags().StringVar(&reportAllOutDir, "out-dir", "", "Directory the reports and manifest.json are written to")
+	reportAllCmd.Flags().StringVar(&reportAllFormats, "formats", "csv,json,xlsx", "Comma separated output formats: table, csv, json, xlsx")
+	reportAllCmd.Flags().StringVar(&reportRounding, "rounding", "", roundingFlagUsage+" by subcapacity")
+	reportAllCmd.Flags().IntVar(&reportMinCoresPerInstall, "min-cores-per-install", 0, minCoresPerInstallFlagUsage+" by subcapacity")
+	reportAllCmd.MarkFlagRequired("out-dir")
+}
+
+// bundleFile describes one file written by report all
+type bundleFile struct {
+	Report string `json:"report"`
+	Format string `json:"format"`
+	Name   string `json:"name"`
+	Rows   int    `json:"rows"`
+	SHA256 string `json:"sha256"`
+}
+
+// bundleManifest describes a report all run; it is written as manifest.json
+type bundleManifest struct {
+	GeneratedAt   string       `json:"generated_at"`
+	PeriodFrom    string       `json:"period_from,omitempty"`
+	PeriodTo      string       `json:"period_to,omitempty"`
+	Product       string       `json:"product,omitempty"`
+	Mode          string       `json:"mode,omitempty"`
+	SchemaVersion string       `json:"schema_version"`
+	Files         []bundleFile `json:"files"`
+}
+
+// bundleReport generates one report of the bundle in every format
+type bundleReport func(dir string, formats []string) ([]bundleFile, error)
+
+// newBundleReport queries a report once and writes its rows in each format
+func newBundleReport[T any](name string, report reportWriter[T], query func() ([]T, error)) bundleReport {
+	return func(dir string, formats []string) ([]bundleFile, error) {
+		rows, err := query()
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", name, err)
+		}
+
+		var files []bundleFile
+		for _, format := range formats {
+			write, err := formatWriter(report, format)
+			if err != nil {
+				return nil, err
+			}
+
+			var buf bytes.Buffer
+			if err := write(&buf, rows); err != nil {
+				return nil, fmt.Errorf("failed to write %s as %s: %w", name, format, err)
+			}
+
+			ext := format
+			if format == "table" {
+				ext = "txt"
+			}
+			file := bundleFile{Report: name, Format: format, Name: name + "." + ext, Rows: len(rows)}
+			if err := os.WriteFile(filepath.Join(dir, file.Name), buf.Bytes(), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", file.Name, err)
+			}
+			sum := sha256.Sum256(buf.Bytes())
+			file.SHA256 = hex.EncodeToString(sum[:])
+			files = append(files, file)
+		}
+		return files, nil
+	}
+}
+
+func runReportAll(cmd *cobra.Command, args []string) error {
+	if reportTemplate != "" {
+		return fmt.Errorf("--template is not supported by report all")
+	}
+	if err := rejectReportPage("report all"); err != nil {
+		return err
+	}
+	if err := rejectColumnLayout("report all"); err != nil {
+		return err
+	}
+
+	if reportFormatsDir != "" {
+		return fmt.Errorf("--output-dir is not supported by report all (use --out-dir and --formats)")
+	}
+
+	formats, err := parseReportFormats(reportAllFormats)
+	if err != nil {
+		return err
+	}
+	if len(formats) == 0 {
+		return fmt.Errorf("--formats must list at least one format")
+	}
+	if err := checkCorePolicy(); err != nil {
+		return err
+	}
+
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
+
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+
+	outDir := reportOutputPath(reportAllOutDir)
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	schemaVersion, err := database.GetCurrentSchemaVersion(db)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	manifest := bundleManifest{
+		GeneratedAt:   time.Now().Format(time.RFC3339),
+		PeriodFrom:    reportFromDate,
+		PeriodTo:      reportToDate,
+		Product:       reportProduct,
+		Mode:          mode,
+		SchemaVersion: schemaVersion,
+	}
+
+	for _, generate := range bundleReports(cmd.Context(), db, mode, fromDate, toDate) {
+		files, err := generate(outDir, formats)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			fmt.Printf("  %-28s %6d rows\n", f.Name, f.Rows)
+		}
+		manifest.Files = append(manifest.Files, files...)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "manifest.json"), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	fmt.Printf("%d files and manifest.json written to %s\n", len(manifest.Files), outDir)
+	return nil
+}
+
+// bundleReports lists the reports generated by report all, with the filters of
+// the report flags
+func bundleReports(ctx context.Context, db *sql.DB, mode string, fromDate, toDate *time.Time) []bundleReport {
+	dailySummary := reports.NewDailySummaryReport(db)
+	hostDetail := reports.NewHostDetailReport(db)
+	cores := reports.NewCoreAggregationReport(db)
+	compliance := reports.NewComplianceReport(db)
+	monthlyPeak := reports.NewMonthlyPeakReport(db)
+	peak := reports.NewPeakUsageReport(db)
+	peakBreakdown := reports.NewPeakBreakdownReport(db)
+	installDetail := reports.NewInstallDetailReport(db)
+	trend := reports.NewTrendReport(db)
+	subcapacity := reports.NewSubcapacityReport(db)
+	subcapacity.SetCorePolicy(reportRounding, reportMinCoresPerInstall)
+	cloud := reports.NewCloudUsageReport(db)
+	drift := reports.NewDriftReport(db)
+	hosts := reports.NewPhysicalHostReport(db)
+	imports := reports.NewImportSessionReport(db)
+
+	list := []bundleReport{
+		newBundleReport("daily-summary", dailySummary, func() ([]reports.DailySummaryRow, error) {
+			return dailySummary.Query(ctx, reportProduct, mode, fromDate, toDate)
+		}),
+		newBundleReport("host-detail", hostDetail, func() ([]reports.HostDetailRow, error) {
+			return hostDetail.Query(ctx, "", reportProduct, mode, reportFromDate, reportToDate)
+		}),
+		newBundleReport("cores", cores, func() ([]reports.CoreAggregationRow, error) {
+			return cores.Query(ctx, reportProduct, mode, fromDate, toDate)
+		}),
+		newBundleReport("compliance", compliance, func() ([]reports.ComplianceRow, error) {
+			return compliance.Query(ctx, reportProduct, mode, fromDate, toDate, false)
+		}),
+		newBundleReport("monthly-peak", monthlyPeak, func() ([]reports.MonthlyPeakRow, error) {
+			return monthlyPeak.Query(ctx, reportProduct, mode, fromDate, toDate)
+		}),
+		newBundleReport("peak", peak, func() ([]reports.PeakUsageRow, error) {
+			return peak.Query(ctx, reportProduct, mode)
+		}),
+	}
+
+	// The breakdown is per product
+	if reportProduct != "" {
+		list = append(list, newBundleReport("peak-breakdown", peakBreakdown, func() ([]reports.PeakBreakdownRow, error) {
+			return peakBreakdown.Query(ctx, reportProduct, mode, reportFromDate, reportToDate)
+		}))
+	}
+
+	list = append(list,
+		newBundleReport("install-detail", installDetail, func() ([]reports.InstallDetailRow, error) {
+			return installDetail.Query(ctx, "", reportProduct, mode, fromDate, toDate, false)
+		}),
+		newBundleReport("trend", trend, func() ([]reports.TrendRow, error) {
+			return trend.Query(ctx, reportProduct, mode, fromDate, toDate)
+		}),
+		newBundleReport("subcapacity", subcapacity, func() ([]reports.SubcapacityRow, error) {
+			return subcapacity.Query(ctx, reportProduct, mode, fromDate, toDate)
+		}),
+		newBundleReport("cloud", cloud, func() ([]reports.CloudUsageRow, error) {
+			return cloud.Query(ctx, reportProduct, mode, fromDate, toDate)
+		}),
+		newBundleReport("drift", drift, func() ([]reports.DriftRow, error) {
+			return drift.Query(ctx, "", mode, false)
+		}),
+	)
+
+	// Physical hosts and imports are shared by both environments
+	if mode == "" {
+		list = append(list,
+			newBundleReport("hosts", hosts, func() ([]reports.PhysicalHostRow, error) {
+				return hosts.Query(ctx, "")
+			}),
+			newBundleReport("imports", imports, func() ([]reports.ImportSessionRow, error) {
+				return imports.Query(ctx, "", "", fromDate, toDate)
+			}),
+		)
+	}
+
+	return list
+}
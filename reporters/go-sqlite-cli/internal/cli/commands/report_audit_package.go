@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var reportAuditPackageCmd = &cobra.Command{
+	Use:   "audit-package",
+	Short: "Generate a zip archive with the evidence files for an IBM audit",
+	Long: `Bundles the evidence files an IBM license audit expects into a timestamped
+zip archive (audit-package-YYYYMMDD-HHMMSS.zip):
+
+  peak-usage-by-program.csv         Peak license cores per program number and license term
+  monthly-peak.csv                  Peak license cores per product and month
+  host-breakdown.csv                Daily peak per host and product with the licensing basis
+  physical-host-deduplication.csv   Ineligible VMs sharing a physical host, counted once
+  import-provenance.csv             Import session and source file of every measurement
+  manifest.json                     Period, schema version, row counts and SHA-256 checksums
+
+The period defaults to the last complete calendar quarter. Use --quarter to select
+another quarter, or --from/--to for an arbitrary period. The package always covers
+all products and hosts; --format and --product are ignored.
+
+--output sets the archive path; an existing directory places the timestamped
+archive inside it.
+
+Example:
+  iwdlr report audit-package --db-path data/license-monitor.db
+  iwdlr report audit-package --quarter 2025-Q3 --output audits/
+  iwdlr report audit-package --from 2025-10-01 --to 2025-11-30`,
+	RunE: runReportAuditPackage,
+}
+
+func init() {
+	reportCmd.AddCommand(reportAuditPackageCmd)
+	reportAuditPackageCmd.Flags().StringVar(&reportQuarter, "quarter", "", "Calendar quarter to cover (YYYY-Qn, default: last complete quarter)")
+}
+
+func runReportAuditPackage(cmd *cobra.Command, args []string) error {
+	now := time.Now()
+	
+	// The package is the complete evidence for the period, never a single environment
+	if reportMode != "" {
+		return fmt.Errorf("--mode is not supported by the audit package")
+	}
+	if reportTemplate != "" {
+		return fmt.Errorf("--template is not supported by the audit package")
+	}
+	if reportFormatsDir != "" {
+		return fmt.Errorf("--output-dir is not supported by the audit package (use --output)")
+	}
+	if err := rejectReportPage("the audit package"); err != nil {
+		return err
+	}
+	if err := rejectColumnLayout("the audit package"); err != nil {
+		return err
+	}
+	
+	from, to, err := auditPeriod(now)
+	if err != nil {
+		return err
+	}
+	
+	// Timestamped archive name, also used as the folder inside the archive
+	name := "audit-package-" + now.Format("20060102-150405")
+	outputPath := reportOutputPath(name + ".zip")
+	if reportOutput != "" {
+		outputPath = reportOutputPath(reportOutput)
+		if info, err := os.Stat(outputPath); err == nil && info.IsDir() {
+			outputPath = filepath.Join(outputPath, name+".zip")
+		}
+	}
+	
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer file.Close()
+	
+	manifest, err := reports.NewAuditPackage(db).Write(cmd.Context(), file, name, from, to)
+	if err != nil {
+		os.Remove(outputPath)
+		return fmt.Errorf("failed to write audit package: %w", err)
+	}
+	
+	fmt.Printf("Audit package for %s to %s written to %s\n", manifest.PeriodFrom, manifest.PeriodTo, outputPath)
+	for _, f := range manifest.Files {
+		fmt.Printf("  %-34s %6d rows\n", f.Name, f.Rows)
+	}
+	
+	return nil
+}
+
+// auditPeriod resolves the period covered by the package from --quarter or --from/--to,
+// defaulting to the last complete quarter before now
+func auditPeriod(now time.Time) (from, to time.Time, err error) {
+	if reportQuarter != "" {
+		if reportFromDate != "" || reportToDate != "" {
+			return from, to, fmt.Errorf("--quarter cannot be combined with --from or --to")
+		}
+		return parseQuarter(reportQuarter)
+	}
+	
+	if reportFromDate == "" && reportToDate == "" {
+		start := quarterStart(now).AddDate(0, -3, 0)
+		return start, start.AddDate(0, 3, -1), nil
+	}
+	
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return from, to, err
+	}
+	if fromDate == nil {
+		return from, to, fmt.Errorf("--from is required when --to is set")
+	}
+	from = *fromDate
+	to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if toDate != nil {
+		to = *toDate
+	}
+	if to.Before(from) {
+		return from, to, fmt.Errorf("--to must not be before --from")
+	}
+	return from, to, nil
+}
+
+// parseQuarter converts YYYY-Qn to the first and last day of the quarter
+func parseQuarter(value string) (from, to time.Time, err error) {
+	var year, quarter int
+	if _, err := fmt.Sscanf(strings.ToUpper(value), "%d-Q%d", &year, &quarter); err != nil || quarter < 1 || quarter > 4 {
+		return from, to, fmt.Errorf("invalid quarter %q (use YYYY-Qn, e.g. 2025-Q3)", value)
+	}
+	from = time.Date(year, time.Month((quarter-1)*3+1), 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, 3, -1), nil
+}
+
+// quarterStart returns the first day of the quarter containing t
+func quarterStart(t time.Time) time.Time {
+	month := time.Month((int(t.Month())-1)/3*3 + 1)
+	return time.Date(t.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+}
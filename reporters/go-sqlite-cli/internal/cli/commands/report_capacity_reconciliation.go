@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var reportCapacityMismatchesOnly bool
+
+var reportCapacityReconciliationCmd = &cobra.Command{
+	Use:   "capacity-reconciliation",
+	Short: "Compare the host cores reported by the inspectors with vCenter and HMC data",
+	Long: `Compares the physical cores of each hypervisor host reported by the inspectors
+(HOST_PHYSICAL_CPUS) with the reference cores read from vCenter by 'iwdlr sync
+vcenter' or loaded with 'iwdlr import host-capacity', on the latest day measured
+in the date range:
+
+  match         every node on the host reports the reference cores
+  mismatch      a node reports other cores than the reference
+  not-reported  a node on the host does not report the host cores
+  not-measured  no node running a product is measured on the host that day
+
+LIC_CORES counts the license cores of the host with the sub-capacity rules and
+the reported cores, RECONCILED with the reference cores, summed over the
+products; DELTA is the change of the sub-capacity totals if the reference is
+right, and PRODUCTS lists the products it changes. Hosts the reference does not
+list are not shown.
+
+Example:
+  iwdlr report capacity-reconciliation --db-path data/license-monitor.db
+  iwdlr report capacity-reconciliation --mismatches-only --to 2025-10-31
+  iwdlr report capacity-reconciliation --product IS_ONP_PRD --format csv --output capacity.csv`,
+	RunE: runReportCapacityReconciliation,
+}
+
+func init() {
+	reportCapacityReconciliationCmd.Flags().BoolVar(&reportCapacityMismatchesOnly, "mismatches-only", false,
+		"Only show the hosts that differ from the reference or whose license cores change")
+	reportCmd.AddCommand(reportCapacityReconciliationCmd)
+}
+
+func runReportCapacityReconciliation(cmd *cobra.Command, args []string) error {
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
+
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Create report generator
+	report := reports.NewCapacityReconciliationReport(db)
+
+	// Query data
+	rows, err := report.Query(cmd.Context(), reportProduct, mode, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+
+	if reportCapacityMismatchesOnly {
+		var differing []reports.CapacityReconciliationRow
+		for _, row := range rows {
+			if row.Status == reports.CapacityMismatch || row.Status == reports.CapacityNotReported || row.Delta != 0 {
+				differing = append(differing, row)
+			}
+		}
+		rows = differing
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		fmt.Println("Load the reference cores with 'iwdlr sync vcenter' or 'iwdlr import host-capacity'")
+		return nil
+	}
+
+	return writeReportOutput(report, rows)
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+// reportChangesFields filters the changes by measurement field
+var reportChangesFields string
+
+var reportChangesCmd = &cobra.Command{
+	Use:   "changes",
+	Short: "Generate the change log of the node measurements",
+	Long: `Lists the fields of each measurement that differ from the previous
+measurement of the node: the changes that move its license cores. Every import
+compares the measurement with the previous one of the node, so files imported
+out of order and rolled back sessions are accounted for. The fields compared are:
+
+  cpu_count, considered_cpus      CPUs seen by the node and counted for licensing
+  os_name, os_version             operating system
+  is_virtualized, virt_type       virtualization
+  host_physical_cpus,
+  physical_host_id                physical host the node runs on
+  processor_eligible, os_eligible,
+  virt_eligible                   sub-capacity eligibility
+
+--from and --to select the measurements the changes were detected in, and
+--mode the mode of the node.
+
+Example:
+  iwdlr report changes --db-path data/license-monitor.db --from 2025-10-01
+  iwdlr report changes --field cpu_count,considered_cpus --mode PROD
+  iwdlr report changes --host 'app*' --format csv --output changes.csv`,
+	RunE: runReportChanges,
+}
+
+func init() {
+	reportCmd.AddCommand(reportChangesCmd)
+	reportChangesCmd.Flags().StringVar(&reportHost, "host", "", "Filter by host: comma-separated FQDNs, host names or glob patterns (app*.example.com)")
+	reportChangesCmd.Flags().StringVar(&reportChangesFields, "field", "", "Filter by changed field: comma-separated measurement fields (cpu_count,os_version)")
+}
+
+func runReportChanges(cmd *cobra.Command, args []string) error {
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
+
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Create report generator
+	report := reports.NewNodeChangesReport(db)
+
+	// Query data
+	rows, err := report.Query(cmd.Context(), reportHost, reportChangesFields, mode, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+
+	return writeReportOutput(report, rows)
+}
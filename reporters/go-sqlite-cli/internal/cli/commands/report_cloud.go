@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var reportCloudCmd = &cobra.Command{
+	Use:   "cloud",
+	Short: "Generate core usage report per cloud provider and account",
+	Long: `Summarizes the nodes running products per cloud provider and account, from
+the CLOUD_PROVIDER, CLOUD_ACCOUNT_ID, CLOUD_REGION and CLOUD_INSTANCE_TYPE
+parameters of the inspector output. Nodes without cloud metadata are shown as
+(on-premises).
+
+The latest measurement of each node per day is counted once per product mode
+it runs. CORES sums the considered cores of the nodes, without the physical
+host deduplication of the compliance report, and SHARE is the part of the
+provider and account in the cores of the day and mode.
+
+Example:
+  iwdlr report cloud --db-path data/license-monitor.db
+  iwdlr report cloud --mode PROD --from 2025-10-01 --to 2025-10-31
+  iwdlr report cloud --format xlsx --output cloud.xlsx`,
+	RunE: runReportCloud,
+}
+
+func init() {
+	reportCmd.AddCommand(reportCloudCmd)
+}
+
+func runReportCloud(cmd *cobra.Command, args []string) error {
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
+	
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+	
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	
+	// Create report generator
+	report := reports.NewCloudUsageReport(db)
+	
+	// Query data
+	rows, err := report.Query(cmd.Context(), reportProduct, mode, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+	
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+	
+	return writeReportOutput(report, rows)
+}
@@ -2,100 +2,148 @@ package commands
 
 import (
 	"fmt"
-	"os"
-	"time"
 
 	"github.com/spf13/cobra"
 
-	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
 	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
 )
 
+// reportFailOnBreach makes the compliance report exit with an error on breaches
+var reportFailOnBreach bool
+
 var reportComplianceCmd = &cobra.Command{
 	Use:   "compliance",
 	Short: "Generate license compliance report",
-	Long:  "Shows license compliance status with gap analysis",
+	Long: `Shows license compliance status with gap analysis.
+
+A row is a breach when its license term is under-licensed, or when the product
+uses more license cores than its threshold (see 'iwdlr import thresholds').
+--non-compliant-only shows the breaches only, and --fail-on-breach exits with
+an error after writing the report when there is any, for cron and CI alerting.
+
+With --carry-forward N, a node that missed up to N days of reporting, e.g.
+during a collection outage, counts on those days with its last measurement, so
+that the outage does not look like a drop in usage. Rows counting carried nodes
+are flagged with a + (and carried_forward_nodes in CSV and JSON).
+
+With --group-by site the running license cores are shown per site, with the
+share of each site in the usage of the license term, for internal chargeback.
+--group-by cost-center does the same per cost center of the nodes (see
+'iwdlr landscape owner').
+
+With --org the usage of the nodes of an organization is compared against the
+entitlements of that organization (see 'iwdlr orgs' and 'import entitlements
+--org'); --group-by org does so for every organization.
+
+Example:
+  iwdlr report compliance --db-path data/license-monitor.db
+  iwdlr report compliance --non-compliant-only --from 2025-10-01 --fail-on-breach
+  iwdlr report compliance --carry-forward 3 --from 2025-10-01
+  iwdlr report compliance --group-by site --from 2025-10-01
+  iwdlr report compliance --group-by cost-center --format csv --output chargeback.csv
+  iwdlr report compliance --org acme-de --fail-on-breach`,
 	RunE:  runReportCompliance,
 }
 
 func init() {
 	reportCmd.AddCommand(reportComplianceCmd)
-	reportComplianceCmd.Flags().BoolVar(&reportNonCompliant, "non-compliant-only", false, "Show only non-compliant products")
+	reportComplianceCmd.Flags().BoolVar(&reportNonCompliant, "non-compliant-only", false, "Show only the breaches: under-licensed terms and products over their threshold")
+	reportComplianceCmd.Flags().BoolVar(&reportFailOnBreach, "fail-on-breach", false, "Exit with an error when the report contains a breach")
+	reportComplianceCmd.Flags().StringVar(&reportGroupBy, "group-by", "", "Group the usage by: site, org, cost-center")
+	reportComplianceCmd.Flags().StringVar(&reportOrg, "org", "", "Compare the usage of this organization against its entitlements (see 'iwdlr orgs')")
+	reportComplianceCmd.Flags().IntVar(&reportCarryForward, "carry-forward", 0, carryForwardFlagUsage)
 }
 
 func runReportCompliance(cmd *cobra.Command, args []string) error {
-	// Parse date filters
-	var fromDate, toDate *time.Time
-	var err error
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
 	
-	if reportFromDate != "" {
-		t, err := time.Parse("2006-01-02", reportFromDate)
-		if err != nil {
-			return fmt.Errorf("invalid from date format: %w", err)
-		}
-		fromDate = &t
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
 	}
 	
-	if reportToDate != "" {
-		t, err := time.Parse("2006-01-02", reportToDate)
-		if err != nil {
-			return fmt.Errorf("invalid to date format: %w", err)
-		}
-		toDate = &t
+	groupBy, err := parseGroupBy()
+	if err != nil {
+		return err
+	}
+	if (groupBy == groupBySite || groupBy == groupByCostCenter) && (reportNonCompliant || reportFailOnBreach) {
+		return fmt.Errorf("--non-compliant-only and --fail-on-breach are not supported with --group-by %s", groupBy)
+	}
+	if err := validateCarryForward(); err != nil {
+		return err
+	}
+	if groupBy != "" && reportCarryForward > 0 {
+		return fmt.Errorf("--carry-forward is not supported with --group-by %s", groupBy)
 	}
 	
 	// Open database
-	db, err := database.Connect(reportDBPath)
+	db, err := openReportDB()
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return err
 	}
 	defer db.Close()
 	
+	switch groupBy {
+	case groupBySite:
+		return writeSiteReport(cmd.Context(), reports.NewSiteComplianceReport(db), mode, fromDate, toDate)
+	case groupByCostCenter:
+		return writeCostCenterReport(cmd.Context(), reports.NewCostCenterComplianceReport(db), mode, fromDate, toDate)
+	case groupByOrg:
+		rows, err := writeOrgReport(cmd.Context(), db, reports.NewOrgComplianceReport(db), mode, fromDate, toDate, reportNonCompliant)
+		if err != nil {
+			return err
+		}
+		breaches := 0
+		for _, row := range rows {
+			if row.Breach {
+				breaches++
+			}
+		}
+		return reportBreaches(cmd, breaches)
+	}
+	
 	// Create report generator
 	report := reports.NewComplianceReport(db)
+	report.SetCarryForwardDays(reportCarryForward)
 	
 	// Query data
-	rows, err := report.Query(reportProduct, fromDate, toDate, reportNonCompliant)
+	rows, err := report.Query(cmd.Context(), reportProduct, mode, fromDate, toDate, reportNonCompliant)
 	if err != nil {
 		return fmt.Errorf("failed to query data: %w", err)
 	}
 	
 	if len(rows) == 0 {
-		fmt.Println("No data found matching the criteria")
-		return nil
-	}
-	
-	// Determine output writer
-	var writer *os.File
-	if reportOutput != "" {
-		writer, err = os.Create(reportOutput)
-		if err != nil {
-			return fmt.Errorf("failed to create output file: %w", err)
+		if reportNonCompliant {
+			fmt.Println("No breaches found")
+		} else {
+			fmt.Println("No data found matching the criteria")
 		}
-		defer writer.Close()
-	} else {
-		writer = os.Stdout
+		return nil
 	}
 	
-	// Write output in requested format
-	switch reportFormat {
-	case "table":
-		err = report.WriteTable(writer, rows)
-	case "csv":
-		err = report.WriteCSV(writer, rows)
-	case "json":
-		err = report.WriteJSON(writer, rows)
-	default:
-		return fmt.Errorf("unknown format: %s (use table, csv, or json)", reportFormat)
+	if err := writeReportOutput(report, rows); err != nil {
+		return err
 	}
 	
-	if err != nil {
-		return fmt.Errorf("failed to write output: %w", err)
+	breaches := 0
+	for _, row := range rows {
+		if row.Breach {
+			breaches++
+		}
 	}
-	
-	if reportOutput != "" {
-		fmt.Printf("Report written to %s\n", reportOutput)
+	return reportBreaches(cmd, breaches)
+}
+
+// reportBreaches records the breaches of the report for --status-json and
+// fails with the breach exit code on breaches with --fail-on-breach
+func reportBreaches(cmd *cobra.Command, breaches int) error {
+	currentReportStatus().Breaches = &breaches
+	if reportFailOnBreach && breaches > 0 {
+		cmd.SilenceUsage = true
+		return withExitCode(ExitBreach, fmt.Errorf("%d compliance breaches", breaches))
 	}
-	
 	return nil
 }
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var reportCoverageCmd = &cobra.Command{
+	Use:   "coverage",
+	Short: "Generate measurement coverage report",
+	Long: `Shows, per node and day, whether a measurement exists, with the coverage
+percentage of each node per month and of all nodes per month, to prove the
+continuity of the measurements to auditors.
+
+A node is expected to report every day from its first measurement until its
+decommission date, within --from and --to (by default the first and latest days
+measured in the database). A landscape node never measured is expected over the
+whole range. The DAYS column has one character per day of the month:
+
+  #   measured
+  .   missing (listed in missing_dates in CSV and JSON)
+  -   not expected
+
+Example:
+  iwdlr report coverage --db-path data/license-monitor.db
+  iwdlr report coverage --from 2025-01-01 --to 2025-12-31 --format xlsx --output coverage-2025.xlsx
+  iwdlr report coverage --host i23 --mode PROD`,
+	RunE: runReportCoverage,
+}
+
+func init() {
+	reportCmd.AddCommand(reportCoverageCmd)
+	reportCoverageCmd.Flags().StringVar(&reportHost, "host", "", "Filter by host FQDN (substring match)")
+}
+
+func runReportCoverage(cmd *cobra.Command, args []string) error {
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
+
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Create report generator
+	report := reports.NewCoverageReport(db)
+
+	// Query data
+	rows, err := report.Query(cmd.Context(), reportHost, mode, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+
+	return writeReportOutput(report, rows)
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var reportBrokenOnly bool
+
+var reportDetectionErrorsCmd = &cobra.Command{
+	Use:   "detection-errors",
+	Short: "Generate failed inspector detection report",
+	Long: `Lists the hosts whose inspector reported DETECTION_RESULT=ERROR, one row per
+node: the number of failed detections, the first and last one with its error
+message, and the last measurement imported for the node. A host is still broken
+when no measurement was imported after its last failed detection; those hosts
+are listed first.
+
+--from and --to filter on the time of the failed detection.
+
+Example:
+  iwdlr report detection-errors --db-path data/license-monitor.db
+  iwdlr report detection-errors --broken-only
+  iwdlr report detection-errors --from 2025-10-01 --format csv --output detection-errors.csv`,
+	RunE: runReportDetectionErrors,
+}
+
+func init() {
+	reportCmd.AddCommand(reportDetectionErrorsCmd)
+	reportDetectionErrorsCmd.Flags().StringVar(&reportHost, "host", "", "Filter by hostname or main FQDN (substring match)")
+	reportDetectionErrorsCmd.Flags().BoolVar(&reportBrokenOnly, "broken-only", false, "Only list hosts without a measurement since their last failed detection")
+}
+
+func runReportDetectionErrors(cmd *cobra.Command, args []string) error {
+	// Failed detections carry no product data
+	if reportMode != "" || reportProduct != "" {
+		return fmt.Errorf("--mode and --product are not supported by the detection-errors report")
+	}
+
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
+
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Create report generator
+	report := reports.NewDetectionErrorReport(db)
+
+	// Query data
+	rows, err := report.Query(cmd.Context(), reportHost, fromDate, toDate, reportBrokenOnly)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+
+	return writeReportOutput(report, rows)
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var (
+	reportDiffDateA string
+	reportDiffDateB string
+)
+
+var reportDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare two dates per product and per host",
+	Long: `Compares two measurement dates side by side and shows the deltas in nodes,
+cores and installs, to explain month-end variances.
+
+  product rows   running nodes and running license cores of the day, as in
+                 the compliance report, and the installs of the product
+  host rows      the latest measurement of the host on each day: 1 node when
+                 measured, its considered cores and its installs; DETAIL lists
+                 the products that started (+) or stopped (-) running
+
+With --product or --mode, only those products are compared, and only the hosts
+running one of them. Rows without any change are left out unless --all is set.
+Both dates must have measurements.
+
+Example:
+  iwdlr report diff --date-a 2025-09-30 --date-b 2025-10-31
+  iwdlr report diff --date-a 2025-09-30 --date-b 2025-10-31 --mode PROD --all
+  iwdlr report diff --date-a 2025-09-30 --date-b 2025-10-31 --format csv --output diff.csv`,
+	RunE: runReportDiff,
+}
+
+func init() {
+	reportCmd.AddCommand(reportDiffCmd)
+	reportDiffCmd.Flags().StringVar(&reportDiffDateA, "date-a", "", "First date to compare (YYYY-MM-DD)")
+	reportDiffCmd.Flags().StringVar(&reportDiffDateB, "date-b", "", "Second date to compare (YYYY-MM-DD)")
+	reportDiffCmd.Flags().BoolVar(&reportAllNodes, "all", false, "Include products and hosts without changes")
+	reportDiffCmd.MarkFlagRequired("date-a")
+	reportDiffCmd.MarkFlagRequired("date-b")
+}
+
+func runReportDiff(cmd *cobra.Command, args []string) error {
+	dateA, err := time.Parse("2006-01-02", reportDiffDateA)
+	if err != nil {
+		return fmt.Errorf("invalid date-a format: %w", err)
+	}
+	
+	dateB, err := time.Parse("2006-01-02", reportDiffDateB)
+	if err != nil {
+		return fmt.Errorf("invalid date-b format: %w", err)
+	}
+	
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+	
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	
+	// Create report generator
+	report := reports.NewDiffReport(db, dateA, dateB)
+	
+	// Query data
+	rows, err := report.Query(cmd.Context(), reportProduct, mode, reportAllNodes)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+	
+	if len(rows) == 0 {
+		fmt.Println("No differences found between the two dates")
+		return nil
+	}
+	
+	return writeReportOutput(report, rows)
+}
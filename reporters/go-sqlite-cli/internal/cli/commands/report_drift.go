@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var reportDriftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Generate expected-vs-actual landscape drift report",
+	Long: `Compares the expected landscape recorded in landscape_nodes with the latest
+measurement of each node.
+
+  missing-product      an expected product is neither running nor installed
+  unexpected-product   a running or installed product is not in the expected list
+  cpu-change           the measured CPU count differs from expected_cpu_no
+  not-measured         the node has expectations but no measurements
+
+Product checks apply to nodes with expected_product_codes_list set (product codes
+separated by commas, semicolons or spaces), the CPU check to nodes with
+expected_cpu_no set. Nodes without expectations are not reported.
+
+Example:
+  iwdlr report drift --db-path data/license-monitor.db
+  iwdlr report drift --host i23 --all
+  iwdlr report drift --format csv --output drift.csv`,
+	RunE: runReportDrift,
+}
+
+func init() {
+	reportCmd.AddCommand(reportDriftCmd)
+	reportDriftCmd.Flags().StringVar(&reportHost, "host", "", "Filter by host FQDN (substring match)")
+	reportDriftCmd.Flags().BoolVar(&reportAllNodes, "all", false, "Include nodes that match their expectations")
+}
+
+func runReportDrift(cmd *cobra.Command, args []string) error {
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+	
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	
+	// Create report generator
+	report := reports.NewDriftReport(db)
+	
+	// Query data
+	rows, err := report.Query(cmd.Context(), reportHost, mode, reportAllNodes)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+	
+	if len(rows) == 0 {
+		fmt.Println("No landscape drift found")
+		return nil
+	}
+	
+	return writeReportOutput(report, rows)
+}
@@ -2,11 +2,9 @@ package commands
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 
-	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
 	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
 )
 
@@ -23,10 +21,15 @@ func init() {
 }
 
 func runReportHosts(cmd *cobra.Command, args []string) error {
+	// Physical hosts are shared by the PROD and NON PROD environments
+	if reportMode != "" {
+		return fmt.Errorf("--mode is not supported by the hosts report")
+	}
+	
 	// Open database
-	db, err := database.Connect(reportDBPath)
+	db, err := openReportDB()
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return err
 	}
 	defer db.Close()
 	
@@ -34,7 +37,7 @@ func runReportHosts(cmd *cobra.Command, args []string) error {
 	report := reports.NewPhysicalHostReport(db)
 	
 	// Query data
-	rows, err := report.Query(reportSystemType)
+	rows, err := report.Query(cmd.Context(), reportSystemType)
 	if err != nil {
 		return fmt.Errorf("failed to query data: %w", err)
 	}
@@ -44,37 +47,5 @@ func runReportHosts(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 	
-	// Determine output writer
-	var writer *os.File
-	if reportOutput != "" {
-		writer, err = os.Create(reportOutput)
-		if err != nil {
-			return fmt.Errorf("failed to create output file: %w", err)
-		}
-		defer writer.Close()
-	} else {
-		writer = os.Stdout
-	}
-	
-	// Write output in requested format
-	switch reportFormat {
-	case "table":
-		err = report.WriteTable(writer, rows)
-	case "csv":
-		err = report.WriteCSV(writer, rows)
-	case "json":
-		err = report.WriteJSON(writer, rows)
-	default:
-		return fmt.Errorf("unknown format: %s (use table, csv, or json)", reportFormat)
-	}
-	
-	if err != nil {
-		return fmt.Errorf("failed to write output: %w", err)
-	}
-	
-	if reportOutput != "" {
-		fmt.Printf("Report written to %s\n", reportOutput)
-	}
-	
-	return nil
+	return writeReportOutput(report, rows)
 }
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var reportImportStatus string
+
+var reportImportsCmd = &cobra.Command{
+	Use:   "imports",
+	Short: "Generate import session audit report",
+	Long: `Lists the import sessions recorded for every imported inspector file, newest
+first, with the record counts and status of each import. The session ID is the
+one expected by 'import rollback'.
+
+--from and --to filter on the day of the import, not on the detection time.
+
+Example:
+  iwdlr report imports --db-path data/license-monitor.db
+  iwdlr report imports --host i23 --status failed
+  iwdlr report imports --from 2025-10-01 --format csv --output imports.csv`,
+	RunE: runReportImports,
+}
+
+func init() {
+	reportCmd.AddCommand(reportImportsCmd)
+	reportImportsCmd.Flags().StringVar(&reportHost, "host", "", "Filter by hostname (substring match)")
+	reportImportsCmd.Flags().StringVar(&reportImportStatus, "status", "", "Filter by status: success, partial or failed")
+}
+
+func runReportImports(cmd *cobra.Command, args []string) error {
+	// Import sessions are not tied to a product or an environment
+	if reportMode != "" || reportProduct != "" {
+		return fmt.Errorf("--mode and --product are not supported by the imports report")
+	}
+	
+	switch reportImportStatus {
+	case "", "success", "partial", "failed":
+	default:
+		return fmt.Errorf("invalid status %q (expected success, partial or failed)", reportImportStatus)
+	}
+	
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
+	
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	
+	// Create report generator
+	report := reports.NewImportSessionReport(db)
+	
+	// Query data
+	rows, err := report.Query(cmd.Context(), reportHost, reportImportStatus, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+	
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+	
+	return writeReportOutput(report, rows)
+}
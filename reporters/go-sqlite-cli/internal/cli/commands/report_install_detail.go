@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var reportInstallDetailCmd = &cobra.Command{
+	Use:   "install-detail",
+	Short: "Generate installation evidence report",
+	Long: `Lists the evidence reported by the inspector for each detected product:
+
+  install   an installation path of the product
+  process   the command line of a running process of the product
+
+Each piece of evidence is one row, numbered per product and measurement, so
+auditors can trace install and running counts back to individual installations.
+
+Example:
+  iwdlr report install-detail --db-path data/license-monitor.db --latest
+  iwdlr report install-detail --host i23 --product IS_ONP_PRD
+  iwdlr report install-detail --from 2025-01-01 --format csv --output install-detail.csv`,
+	RunE: runReportInstallDetail,
+}
+
+func init() {
+	reportCmd.AddCommand(reportInstallDetailCmd)
+	reportInstallDetailCmd.Flags().StringVar(&reportHost, "host", "", "Filter by host FQDN (substring match)")
+	reportInstallDetailCmd.Flags().BoolVar(&reportLatest, "latest", false, "Only show the latest measurement of each host")
+}
+
+func runReportInstallDetail(cmd *cobra.Command, args []string) error {
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
+	
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+	
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	
+	// Create report generator
+	report := reports.NewInstallDetailReport(db)
+	
+	// Query data
+	rows, err := report.Query(cmd.Context(), reportHost, reportProduct, mode, fromDate, toDate, reportLatest)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+	
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+	
+	return writeReportOutput(report, rows)
+}
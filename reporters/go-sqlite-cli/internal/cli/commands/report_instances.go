@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var reportInstanceStatus string
+
+var reportInstancesCmd = &cobra.Command{
+	Use:   "instances",
+	Short: "Generate product instance report",
+	Long: `Lists the instances of each product, one per install path reported by the
+inspector, so that a host with many installs of the same product shows which of
+them are actually used. An instance is running when a process command line of
+the product contained its install path in the latest measurement of the node,
+and dormant otherwise. Instances the latest measurement no longer reports are
+removed, and only listed with --status removed.
+
+RUNNING_IN is the number of measurements the instance was running in, out of
+the measurements that reported it. --from and --to are not supported.
+
+Example:
+  iwdlr report instances --db-path data/license-monitor.db
+  iwdlr report instances --host i23 --product IS_ONP_PRD
+  iwdlr report instances --status dormant --format csv --output dormant-instances.csv`,
+	RunE: runReportInstances,
+}
+
+func init() {
+	reportCmd.AddCommand(reportInstancesCmd)
+	reportInstancesCmd.Flags().StringVar(&reportHost, "host", "", "Filter by host FQDN or hostname (substring match)")
+	reportInstancesCmd.Flags().StringVar(&reportInstanceStatus, "status", "", "Filter by instance status: running, dormant or removed")
+	reportInstancesCmd.Flags().StringVar(&reportStandby, "standby", "", standbyFlagUsage)
+}
+
+func runReportInstances(cmd *cobra.Command, args []string) error {
+	// Instances are reported as of the latest measurement of their node
+	if reportFromDate != "" || reportToDate != "" {
+		return fmt.Errorf("--from and --to are not supported by the instances report")
+	}
+
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+
+	status, err := reports.ParseInstanceStatus(reportInstanceStatus)
+	if err != nil {
+		return err
+	}
+
+	standby, err := reports.ParseStandbyPolicy(reportStandby)
+	if err != nil {
+		return err
+	}
+
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Create report generator
+	report := reports.NewInstanceReport(db)
+	report.SetStandbyPolicy(standby)
+
+	// Query data
+	rows, err := report.Query(cmd.Context(), reportHost, reportProduct, mode, status)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+
+	return writeReportOutput(report, rows)
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var reportMonthlyPeakCmd = &cobra.Command{
+	Use:   "monthly-peak",
+	Short: "Generate monthly peak license cores report",
+	Long: `Shows the peak license cores per product for each calendar month.
+
+This is the figure reported to IBM under sub-capacity terms. Daily license cores
+are calculated per product (eligible cores summed, ineligible cores counted once
+per physical host), and the highest day of each month is reported. Running
+(status='present') and installed (install_count > 0) peaks are tracked separately,
+each with the day on which it occurred.
+
+--from and --to select whole months. --period selects the months of a contract
+period of the license term of each product instead (see 'iwdlr terms period'):
+current, previous or the label of a period. Its first and last months only count
+the days inside the period.
+
+Example:
+  iwdlr report monthly-peak --db-path data/license-monitor.db
+  iwdlr report monthly-peak --product IS_ONP_PRD --from 2025-01-01
+  iwdlr report monthly-peak --period previous
+  iwdlr report monthly-peak --format csv --output monthly-peak.csv`,
+	RunE: runReportMonthlyPeak,
+}
+
+func init() {
+	reportCmd.AddCommand(reportMonthlyPeakCmd)
+	reportMonthlyPeakCmd.Flags().StringVar(&reportPeriod, "period", "", "Restrict the months to a contract period: current, previous or a period label")
+}
+
+func runReportMonthlyPeak(cmd *cobra.Command, args []string) error {
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
+	if reportPeriod != "" && (fromDate != nil || toDate != nil) {
+		return fmt.Errorf("--period cannot be combined with --from or --to")
+	}
+	
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+	
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	
+	// Create report generator
+	report := reports.NewMonthlyPeakReport(db)
+	
+	// Query data
+	var rows []reports.MonthlyPeakRow
+	if reportPeriod != "" {
+		rows, err = report.QueryPeriod(cmd.Context(), reportProduct, mode, reportPeriod)
+	} else {
+		rows, err = report.Query(cmd.Context(), reportProduct, mode, fromDate, toDate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+	
+	if len(rows) == 0 {
+		if reportPeriod != "" {
+			fmt.Printf("No data found in the %s contract period (see 'iwdlr terms period add')\n", reportPeriod)
+			return nil
+		}
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+	
+	return writeReportOutput(report, rows)
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+// reportTimelineHost is the node of the timeline
+var reportTimelineHost string
+
+var reportNodeTimelineCmd = &cobra.Command{
+	Use:   "node-timeline",
+	Short: "Generate the chronological history of a node",
+	Long: `Lists the events of a single node, oldest first:
+
+  measurement                     OS, CPUs, virtualization and products running
+  product-appeared, product-gone  products present in a measurement and not in
+                                  the previous one, and the other way round
+  cpu-change                      cpu_count or considered_cpus changed
+  host-move                       physical host ID changed or corrected
+  change                          other measurement fields changed (see report changes)
+  detection-error                 failed detection reported by the inspector
+  import                          import session that wrote a measurement
+
+--host takes the main FQDN of the node, a former FQDN recorded as a node
+alias, or the host name when only one node has it. --from and --to select the
+events by day.
+
+Example:
+  iwdlr report node-timeline --host app1.example.com --db-path data/license-monitor.db
+  iwdlr report node-timeline --host app1 --from 2025-10-01 --format csv --output app1.csv`,
+	RunE: runReportNodeTimeline,
+}
+
+func init() {
+	reportCmd.AddCommand(reportNodeTimelineCmd)
+	reportNodeTimelineCmd.Flags().StringVar(&reportTimelineHost, "host", "", "Node FQDN, alias or host name (required)")
+	reportNodeTimelineCmd.MarkFlagRequired("host")
+}
+
+func runReportNodeTimeline(cmd *cobra.Command, args []string) error {
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
+
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Create report generator
+	report := reports.NewNodeTimelineReport(db)
+
+	// Query data
+	rows, err := report.Query(cmd.Context(), reportTimelineHost, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+
+	return writeReportOutput(report, rows)
+}
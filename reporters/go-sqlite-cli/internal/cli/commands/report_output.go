@@ -0,0 +1,535 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/tracing"
+)
+
+// openReportDB opens the existing database given by --db-path read-only.
+// Reports never write, so they can run against a copy on a read-only mount or
+// while another process is importing. With --db-path :memory: the report reads
+// the in-memory database of 'import --ephemeral', the last user of it.
+func openReportDB() (*sql.DB, error) {
+	if reportDBPath == database.MemoryPath {
+		if ephemeralDB == nil {
+			return nil, withExitCode(ExitDatabase, fmt.Errorf("an in-memory database only lives during 'iwdlr import --ephemeral': report on it with --report"))
+		}
+		return ephemeralDB, nil
+	}
+	if _, err := os.Stat(reportDBPath); os.IsNotExist(err) {
+		return nil, withExitCode(ExitDatabase, fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", reportDBPath))
+	}
+
+	db, err := database.ConnectReadOnly(reportDBPath)
+	if err != nil {
+		return nil, withExitCode(ExitDatabase, fmt.Errorf("failed to open database: %w", err))
+	}
+	if err := database.CheckSchemaVersion(db); err != nil {
+		db.Close()
+		return nil, withExitCode(ExitDatabase, err)
+	}
+	return db, nil
+}
+
+// reportOutputPath resolves a relative output path against the output directory
+// of the configuration file
+func reportOutputPath(path string) string {
+	if reportOutputDir == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(reportOutputDir, path)
+}
+
+// parseReportDates parses the --from and --to filters (YYYY-MM-DD); unset filters are nil
+func parseReportDates() (fromDate, toDate *time.Time, err error) {
+	if reportFromDate != "" {
+		t, err := time.Parse("2006-01-02", reportFromDate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid from date format: %w", err)
+		}
+		fromDate = &t
+	}
+	
+	if reportToDate != "" {
+		t, err := time.Parse("2006-01-02", reportToDate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid to date format: %w", err)
+		}
+		toDate = &t
+	}
+	
+	return fromDate, toDate, nil
+}
+
+// Groupings of the usage selected by --group-by
+const (
+	groupBySite       = "site"
+	groupByOrg        = "org"
+	groupByCostCenter = "cost-center"
+)
+
+// parseGroupBy validates --group-by and returns the grouping of the usage,
+// empty when it is not grouped. --org reports the usage of one organization,
+// grouped by organization.
+func parseGroupBy() (string, error) {
+	switch reportGroupBy {
+	case "":
+		if reportOrg != "" {
+			return groupByOrg, nil
+		}
+		return "", nil
+	case groupBySite, groupByCostCenter:
+		if reportOrg != "" {
+			return "", fmt.Errorf("--org cannot be combined with --group-by %s", reportGroupBy)
+		}
+		return reportGroupBy, nil
+	case groupByOrg:
+		return groupByOrg, nil
+	}
+	return "", fmt.Errorf("invalid --group-by %q (expected site, org or cost-center)", reportGroupBy)
+}
+
+// writeSiteReport queries and writes a report grouped by site
+func writeSiteReport(ctx context.Context, report *reports.SiteUsageReport, mode string, fromDate, toDate *time.Time) error {
+	rows, err := report.Query(ctx, reportProduct, mode, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+	
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+	
+	return writeReportOutput(report, rows)
+}
+
+// writeCostCenterReport queries and writes a report grouped by cost center
+func writeCostCenterReport(ctx context.Context, report *reports.CostCenterUsageReport, mode string, fromDate, toDate *time.Time) error {
+	rows, err := report.Query(ctx, reportProduct, mode, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+
+	return writeReportOutput(report, rows)
+}
+
+// requireReportOrg fails when the organization given by --org does not exist,
+// rather than reporting no data for a misspelled one
+func requireReportOrg(db *sql.DB) error {
+	if reportOrg == "" {
+		return nil
+	}
+	return importer.RequireOrg(db, reportOrg)
+}
+
+// writeOrgReport queries and writes a report of the organization selected by
+// --org, or of every organization, and returns its rows
+func writeOrgReport(ctx context.Context, db *sql.DB, report *reports.OrgUsageReport, mode string, fromDate, toDate *time.Time, nonCompliantOnly bool) ([]reports.OrgUsageRow, error) {
+	if err := requireReportOrg(db); err != nil {
+		return nil, err
+	}
+	report.SetOrg(reportOrg)
+	rows, err := report.Query(ctx, reportProduct, mode, fromDate, toDate, nonCompliantOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query data: %w", err)
+	}
+
+	if len(rows) == 0 {
+		if nonCompliantOnly {
+			fmt.Println("No breaches found")
+		} else {
+			fmt.Println("No data found matching the criteria")
+		}
+		return nil, nil
+	}
+
+	return rows, writeReportOutput(report, rows)
+}
+
+// reportWriter is implemented by every report generator in internal/reports
+type reportWriter[T any] interface {
+	WriteTable(w io.Writer, rows []T) error
+	WriteCSV(w io.Writer, rows []T) error
+	WriteJSON(w io.Writer, rows []T) error
+	WriteXLSX(w io.Writer, rows []T) error
+}
+
+// reportPage returns the page selected by --limit, --offset and --sort
+func reportPage() reports.Page {
+	return reports.Page{Limit: reportLimit, Offset: reportOffset, Sort: reportSort}
+}
+
+// rejectReportPage fails when --limit, --offset or --sort is set for a report
+// command writing several reports
+func rejectReportPage(command string) error {
+	if !reportPage().IsZero() {
+		return fmt.Errorf("--limit, --offset and --sort are not supported by %s", command)
+	}
+	return nil
+}
+
+// writeReportOutput sorts and pages report rows with --sort, --offset and
+// --limit, and writes them in the format selected by --format, either to
+// stdout or to the file given by --output
+func writeReportOutput[T any](report reportWriter[T], rows []T) error {
+	rows, err := reports.Paginate(rows, reportPage())
+	if err != nil {
+		return err
+	}
+	return writeReportRows(report, rows)
+}
+
+// writeReportRows writes report rows already sorted and paged by the query
+// in the format selected by --format
+func writeReportRows[T any](report reportWriter[T], rows []T) (err error) {
+	span := tracing.Start("write report", tracing.String("report.format", reportFormat), tracing.Int("report.rows", len(rows)))
+	defer func() { span.End(err) }()
+
+	layout, err := reportColumnLayout()
+	if err != nil {
+		return err
+	}
+	if !layout.IsZero() {
+		if reportTemplate != "" {
+			return fmt.Errorf("--columns and --no-header cannot be combined with --template")
+		}
+		report = columnWriter[T]{reportWriter: report, layout: layout}
+	}
+	tableFormat, err := reportTableFormat()
+	if err != nil {
+		return err
+	}
+	if !tableFormat.IsZero() {
+		report = formattedTableWriter[T]{reportWriter: report, format: tableFormat}
+	}
+
+	currentReportStatus().Rows = len(rows)
+	if reportFormatsDir != "" || strings.Contains(reportFormat, ",") {
+		return writeReportFormats(report, rows)
+	}
+	if reportTemplate != "" {
+		return writeTemplateOutput(rows, reportOutputPath(reportOutput))
+	}
+	if err := writeOutput(report, rows, reportFormat, reportOutputPath(reportOutput)); err != nil {
+		return err
+	}
+	addReportStatusOutput(reportOutputPath(reportOutput))
+	return nil
+}
+
+// reportColumnLayout returns the columns selected by --columns and --no-header
+func reportColumnLayout() (reports.ColumnLayout, error) {
+	columns, err := reports.ParseColumns(reportColumns)
+	if err != nil {
+		return reports.ColumnLayout{}, err
+	}
+	return reports.ColumnLayout{Columns: columns, NoHeader: reportNoHeader}, nil
+}
+
+// rejectColumnLayout fails when --columns or --no-header is set for a report
+// command writing several reports
+func rejectColumnLayout(command string) error {
+	if reportColumns != "" || reportNoHeader {
+		return fmt.Errorf("--columns and --no-header are not supported by %s", command)
+	}
+	return nil
+}
+
+// columnWriter writes the csv and table output of a report with the layout
+// of --columns and --no-header, picked from its CSV output
+type columnWriter[T any] struct {
+	reportWriter[T]
+	layout reports.ColumnLayout
+}
+
+func (c columnWriter[T]) WriteCSV(w io.Writer, rows []T) error {
+	var buf bytes.Buffer
+	if err := c.reportWriter.WriteCSV(&buf, rows); err != nil {
+		return err
+	}
+	return c.layout.WriteCSV(w, buf.Bytes())
+}
+
+func (c columnWriter[T]) WriteTable(w io.Writer, rows []T) error {
+	var buf bytes.Buffer
+	if err := c.reportWriter.WriteCSV(&buf, rows); err != nil {
+		return err
+	}
+	return c.layout.WriteTable(w, buf.Bytes())
+}
+
+func (c columnWriter[T]) WriteJSON(w io.Writer, rows []T) error {
+	return fmt.Errorf("--columns and --no-header apply to csv and table output only")
+}
+
+func (c columnWriter[T]) WriteXLSX(w io.Writer, rows []T) error {
+	return fmt.Errorf("--columns and --no-header apply to csv and table output only")
+}
+
+// reportTableFormat returns the number and date format of the table output
+// selected by --locale and --date-format
+func reportTableFormat() (reports.TableFormat, error) {
+	var format reports.TableFormat
+	if reportLocale != "" {
+		var err error
+		if format, err = reports.LookupLocale(reportLocale); err != nil {
+			return format, err
+		}
+	}
+	if reportDateFormat != "" {
+		layout, err := reports.ParseDateFormat(reportDateFormat)
+		if err != nil {
+			return format, err
+		}
+		format.DateLayout = layout
+	}
+	return format, nil
+}
+
+// formattedTableWriter writes the table output of a report with the number
+// and date format of --locale and --date-format
+type formattedTableWriter[T any] struct {
+	reportWriter[T]
+	format reports.TableFormat
+}
+
+func (f formattedTableWriter[T]) WriteTable(w io.Writer, rows []T) error {
+	return f.reportWriter.WriteTable(reports.NewFormattedWriter(w, f.format), rows)
+}
+
+// parseReportFormats splits a comma separated list of formats, rejecting
+// unknown ones; a format listed twice is written once
+func parseReportFormats(list string) ([]string, error) {
+	var formats []string
+	for _, format := range strings.Split(list, ",") {
+		format = strings.TrimSpace(format)
+		if format == "" || slices.Contains(formats, format) {
+			continue
+		}
+		switch format {
+		case "table", "csv", "json", "xlsx":
+		default:
+			return nil, fmt.Errorf("unknown format: %s (use table, csv, json, or xlsx)", format)
+		}
+		formats = append(formats, format)
+	}
+	return formats, nil
+}
+
+// writeReportFormats writes report rows in each format of --format to
+// <report>.<format> files (table output to <report>.txt) in --output-dir, so
+// that one query serves every rendering
+func writeReportFormats[T any](report reportWriter[T], rows []T) error {
+	switch {
+	case reportFormatsDir == "":
+		return fmt.Errorf("--output-dir is required to write several formats")
+	case reportOutput != "":
+		return fmt.Errorf("--output cannot be combined with --output-dir")
+	case reportTemplate != "":
+		return fmt.Errorf("--template cannot be combined with --output-dir")
+	}
+
+	formats, err := parseReportFormats(reportFormat)
+	if err != nil {
+		return err
+	}
+	if len(formats) == 0 {
+		return fmt.Errorf("--format must list at least one format")
+	}
+
+	dir := reportOutputPath(reportFormatsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	for _, format := range formats {
+		ext := format
+		if format == "table" {
+			ext = "txt"
+		}
+		path := filepath.Join(dir, reportName+"."+ext)
+		if err := writeOutput(report, rows, format, path); err != nil {
+			return err
+		}
+		addReportStatusOutput(path)
+	}
+	return nil
+}
+
+// rowStreamer is implemented by the reports whose rows can be written while
+// the query runs
+type rowStreamer[T any] interface {
+	NewRowWriter(w io.Writer, format string) (reports.RowWriter[T], error)
+}
+
+// streamReportOutput writes the rows passed by each to its callback in the csv
+// or json format selected by --format as they are read, either to stdout or to
+// the file given by --output, so that large exports are not held in memory. It
+// reports whether the output could be streamed: tables, workbooks, templates,
+// several formats and column layouts need all rows and are left to
+// writeReportOutput.
+func streamReportOutput[T any](report rowStreamer[T], each func(fn func(T) error) error) (bool, error) {
+	if reportTemplate != "" || reportFormatsDir != "" || reportColumns != "" || reportNoHeader ||
+		(reportFormat != "csv" && reportFormat != "json") {
+		return false, nil
+	}
+	outputPath := reportOutputPath(reportOutput)
+
+	// The output is only created with the first row
+	var file *os.File
+	var writer reports.RowWriter[T]
+	reportStatus := currentReportStatus()
+	err := each(func(row T) error {
+		reportStatus.Rows++
+		if writer == nil {
+			out := os.Stdout
+			var err error
+			if outputPath != "" {
+				if file, err = os.Create(outputPath); err != nil {
+					return fmt.Errorf("failed to create output file: %w", err)
+				}
+				out = file
+			}
+			if writer, err = report.NewRowWriter(out, reportFormat); err != nil {
+				return err
+			}
+		}
+		return writer.WriteRow(row)
+	})
+	if file != nil {
+		defer file.Close()
+	}
+	if err != nil {
+		return true, fmt.Errorf("failed to write output: %w", err)
+	}
+
+	if writer == nil {
+		fmt.Println("No data found matching the criteria")
+		return true, nil
+	}
+	if err := writer.Close(); err != nil {
+		return true, fmt.Errorf("failed to write output: %w", err)
+	}
+
+	if outputPath != "" {
+		fmt.Printf("Report written to %s\n", outputPath)
+	}
+	addReportStatusOutput(outputPath)
+	return true, nil
+}
+
+// writeTemplateOutput renders report rows with the --template file, either to
+// stdout or to outputPath when it is set
+func writeTemplateOutput[T any](rows []T, outputPath string) error {
+	if flag := reportCmd.PersistentFlags().Lookup("format"); flag.Changed {
+		return fmt.Errorf("--template cannot be combined with --format")
+	}
+	
+	tmpl, err := reports.ParseTemplate(reportTemplate)
+	if err != nil {
+		return err
+	}
+	
+	data := reports.NewTemplateData(reports.TemplateMetadata{
+		Report:      reportName,
+		GeneratedAt: time.Now(),
+		Database:    reportDBPath,
+		Product:     reportProduct,
+		Mode:        reportMode,
+		From:        reportFromDate,
+		To:          reportToDate,
+	}, rows)
+	
+	// Determine output writer
+	var writer *os.File
+	if outputPath != "" {
+		writer, err = os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer writer.Close()
+	} else {
+		writer = os.Stdout
+	}
+	
+	if err := reports.WriteTemplate(writer, tmpl, data); err != nil {
+		return err
+	}
+	
+	if outputPath != "" {
+		fmt.Printf("Report written to %s\n", outputPath)
+	}
+	addReportStatusOutput(outputPath)
+	
+	return nil
+}
+
+// formatWriter returns the write method of report for a format (table, csv,
+// json or xlsx)
+func formatWriter[T any](report reportWriter[T], format string) (func(io.Writer, []T) error, error) {
+	switch format {
+	case "table":
+		return report.WriteTable, nil
+	case "csv":
+		return report.WriteCSV, nil
+	case "json":
+		return report.WriteJSON, nil
+	case "xlsx":
+		return report.WriteXLSX, nil
+	}
+	return nil, fmt.Errorf("unknown format: %s (use table, csv, json, or xlsx)", format)
+}
+
+// writeOutput writes report rows in the given format (table, csv, json or
+// xlsx), either to stdout or to outputPath when it is set
+func writeOutput[T any](report reportWriter[T], rows []T, format, outputPath string) error {
+	write, err := formatWriter(report, format)
+	if err != nil {
+		return err
+	}
+	
+	// Binary workbook data must not end up on a terminal
+	if format == "xlsx" && outputPath == "" {
+		return fmt.Errorf("--output is required for xlsx format")
+	}
+	
+	// Determine output writer
+	var writer *os.File
+	if outputPath != "" {
+		writer, err = os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer writer.Close()
+	} else {
+		writer = os.Stdout
+	}
+	
+	if err := write(writer, rows); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	
+	if outputPath != "" {
+		fmt.Printf("Report written to %s\n", outputPath)
+	}
+	
+	return nil
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+// ReportsDirEnv is the environment variable giving the directory of the report
+// definitions
+const ReportsDirEnv = "IWLDR_REPORTS_DIR"
+
+// ReportsDir returns the directory of the report definitions: $IWLDR_REPORTS_DIR,
+// else reports.d next to the configuration file given by --config in args,
+// else ~/.iwldr/reports.d. The report commands are added before the command
+// line is parsed, so --config is looked up on its own.
+func ReportsDir(args []string) string {
+	if dir := os.Getenv(ReportsDirEnv); dir != "" {
+		return dir
+	}
+
+	flags := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.SetOutput(io.Discard)
+	configPath := flags.String("config", "", "")
+	flags.Parse(args)
+	if *configPath != "" {
+		return filepath.Join(filepath.Dir(*configPath), "reports.d")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".iwldr", "reports.d")
+}
+
+// AddReportPlugins registers the report definitions of dir and adds a report
+// command for each registered report. Definitions that cannot be read, and
+// reports named after a built-in report, are left out with a warning.
+func AddReportPlugins(dir string) {
+	if dir != "" {
+		_, errs := reports.RegisterReportDefinitions(dir)
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "WARNING: Report definition ignored: %v\n", err)
+		}
+	}
+
+	for _, reporter := range reports.Registered() {
+		if existing, _, err := reportCmd.Find([]string{reporter.Name()}); err == nil && existing != reportCmd {
+			fmt.Fprintf(os.Stderr, "WARNING: Report %s ignored: a built-in report has this name\n", reporter.Name())
+			continue
+		}
+		reportCmd.AddCommand(newPluginReportCmd(reporter))
+	}
+}
+
+// newPluginReportCmd creates the command of a registered report
+func newPluginReportCmd(reporter reports.Reporter) *cobra.Command {
+	long := reporter.Description() + `
+
+The report takes the --product, --mode, --from and --to filters and the output
+flags of the other reports; --template is not supported.`
+	if defined, ok := reporter.(*reports.DefinedReport); ok && defined.Path() != "" {
+		long += "\n\nDefined in " + defined.Path()
+	}
+
+	return &cobra.Command{
+		Use:   reporter.Name(),
+		Short: reporter.Description(),
+		Long:  long,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPluginReport(cmd, reporter)
+		},
+	}
+}
+
+func runPluginReport(cmd *cobra.Command, reporter reports.Reporter) error {
+	if reportTemplate != "" {
+		return fmt.Errorf("--template is not supported by report %s", reporter.Name())
+	}
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rows, err := reporter.Query(cmd.Context(), db, reports.Params{ProductCode: reportProduct, Mode: mode, From: fromDate, To: toDate})
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+
+	rows, err = reports.PaginateSQLRows(reporter.Columns(), rows, reportPage())
+	if err != nil {
+		return err
+	}
+	return writeReportRows[reports.SQLRow](reporter, rows)
+}
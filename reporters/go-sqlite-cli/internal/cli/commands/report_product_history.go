@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var reportProductHistoryCmd = &cobra.Command{
+	Use:   "product-history",
+	Short: "Generate product first-seen and last-seen report",
+	Long: `Lists every product ever detected on each node with its first and last
+detection, and the measurement it disappeared at: the first measurement of the
+node after its last detection. Products detected in the latest measurement of
+the node are present; the others are gone. Use it to explain to auditors why
+usage rose or fell: which products appeared or disappeared on which nodes.
+
+--from and --to keep the products that appeared or disappeared in the period.
+
+Example:
+  iwdlr report product-history --db-path data/license-monitor.db
+  iwdlr report product-history --from 2025-10-01 --to 2025-10-31 --mode PROD
+  iwdlr report product-history --host i23 --format csv --output product-history.csv`,
+	RunE: runReportProductHistory,
+}
+
+func init() {
+	reportCmd.AddCommand(reportProductHistoryCmd)
+	reportProductHistoryCmd.Flags().StringVar(&reportHost, "host", "", "Filter by host FQDN or hostname (substring match)")
+}
+
+func runReportProductHistory(cmd *cobra.Command, args []string) error {
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
+
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Create report generator
+	report := reports.NewProductHistoryReport(db)
+
+	// Query data
+	rows, err := report.Query(cmd.Context(), reportHost, reportProduct, mode, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+
+	return writeReportOutput(report, rows)
+}
@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/config"
+)
+
+var (
+	reportRunList bool
+
+	// reportProfiles are the report profiles of the configuration file
+	reportProfiles map[string]config.ReportProfile
+)
+
+var reportRunCmd = &cobra.Command{
+	Use:   "run <profile>",
+	Short: "Run a report profile of the configuration file",
+	Long: `Runs a report defined under a name in the profiles section of the configuration
+file, so that recurring reports are defined in one place:
+
+  report:
+    profiles:
+      ibm-submission:
+        report: compliance
+        format: xlsx
+        output: ibm-submission-{from}.xlsx
+        period: previous-month
+        mode: PROD
+      ops-summary:
+        report: daily-summary
+        format: csv
+        output: ops/summary-{date}.csv
+        period: previous-week
+        args: [--group-by, site]
+
+A profile sets the report, the format, output, template, product, mode, from
+and to flags, and further flags of the report in args. Instead of from and to,
+period gives a period relative to the day of the run: current-week,
+previous-week, current-month, previous-month, current-quarter,
+previous-quarter or last-<n>-days. {from}, {to} and {date} (the day of the
+run) in the output path are replaced by their dates; relative paths are
+written below the report output-dir of the configuration file.
+
+Report flags given on the command line override those of the profile.
+
+Example:
+  iwdlr report run ibm-submission
+  iwdlr report run ops-summary --format table --output ""
+  iwdlr report run --list`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runReportRun,
+}
+
+func init() {
+	reportCmd.AddCommand(reportRunCmd)
+	reportRunCmd.Flags().BoolVar(&reportRunList, "list", false, "List the report profiles of the configuration file")
+}
+
+func runReportRun(cmd *cobra.Command, args []string) error {
+	if reportRunList {
+		names := make([]string, 0, len(reportProfiles))
+		for name := range reportProfiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			profileArgs, err := reportProfiles[name].Command(time.Now())
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%-20s iwdlr report %s\n", name, strings.Join(profileArgs, " "))
+		}
+		return nil
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("a profile name or --list is required")
+	}
+	profile, ok := reportProfiles[args[0]]
+	if !ok {
+		return fmt.Errorf("no report profile named %q in the configuration file", args[0])
+	}
+
+	reportArgs, err := profile.Command(time.Now())
+	if err != nil {
+		return fmt.Errorf("report profile %q: %w", args[0], err)
+	}
+
+	// The flags given to run are parsed again after those of the profile, so
+	// that they win
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if flag.Name != "list" {
+			reportArgs = append(reportArgs, "--"+flag.Name+"="+flag.Value.String())
+		}
+	})
+
+	report, err := findReport(reportArgs)
+	if err != nil {
+		return fmt.Errorf("report profile %q: %w", args[0], err)
+	}
+
+	// Profiles write to dated paths, whose directories are created as needed
+	if reportOutput != "" {
+		if err := os.MkdirAll(filepath.Dir(reportOutputPath(reportOutput)), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	reportName = report.Name()
+	reportRunCommand = report
+	report.SetContext(cmd.Context())
+	return report.RunE(report, report.Flags().Args())
+}
+
+// findReport returns the report command of reportArgs, the report name followed
+// by the flags of the report, with the flags parsed and checked
+func findReport(reportArgs []string) (*cobra.Command, error) {
+	report, flags, err := reportCmd.Find(reportArgs)
+	if err != nil || report == reportCmd || report.RunE == nil {
+		return nil, fmt.Errorf("unknown report %q", reportArgs[0])
+	}
+	if err := report.ParseFlags(flags); err != nil {
+		return nil, err
+	}
+	if err := applyReportFilter(report); err != nil {
+		return nil, err
+	}
+	if err := report.ValidateArgs(report.Flags().Args()); err != nil {
+		return nil, err
+	}
+	if err := report.ValidateRequiredFlags(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,218 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+// Environment variables set by the daemon and the job worker for the reports
+// they run, so that the runs are recorded with their trigger
+const (
+	ReportTriggerEnv = "IWLDR_REPORT_TRIGGER"
+	ReportJobEnv     = "IWLDR_REPORT_JOB"
+)
+
+var (
+	// reportRecord records the run of a report run by hand in report_runs
+	reportRecord bool
+
+	reportRunsReport  string
+	reportRunsTrigger string
+	reportRunsSHA256  string
+
+	// reportRunCommand is the report command run by 'report run <profile>'
+	reportRunCommand *cobra.Command
+)
+
+// reportRunsIgnoredFlags are left out of the parameters of a recorded run
+var reportRunsIgnoredFlags = map[string]bool{
+	"help":        true,
+	"record":      true,
+	"status-json": true,
+}
+
+var reportRunsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "List the recorded report runs and the files they wrote",
+	Long: `Reports run by the daemon and the job queue are recorded in the report_runs
+table with their command line, parameters, row count, the latest measurement of
+the database and the path, size and SHA-256 of each file written, so that the
+numbers delivered on a day can be proven afterwards. Reports run by hand are
+recorded with --record.`,
+}
+
+var reportRunsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the recorded report runs",
+	Long: `Lists the recorded report runs, newest first. --report and --trigger select the
+runs of a report and of a trigger (manual, schedule or queue), --sha256 the run
+that wrote a file, and --from and --to the day the runs started.
+
+Example:
+  iwdlr report runs list --db-path data/license-monitor.db
+  iwdlr report runs list --report compliance --trigger schedule --from 2025-10-01
+  iwdlr report runs list --sha256 "$(sha256sum compliance.xlsx | cut -d' ' -f1)"`,
+	Args: cobra.NoArgs,
+	RunE: runReportRunsList,
+}
+
+var reportRunsShowCmd = &cobra.Command{
+	Use:   "show <run-id>",
+	Short: "Show a recorded report run and check its files",
+	Long: `Shows a recorded report run with its parameters and files, and compares each
+file with the checksum recorded: unchanged, changed or missing. --format json
+writes the run as JSON.
+
+Example:
+  iwdlr report runs show 42
+  iwdlr report runs show 42 --format json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReportRunsShow,
+}
+
+func init() {
+	reportCmd.AddCommand(reportRunsCmd)
+	reportRunsCmd.AddCommand(reportRunsListCmd)
+	reportRunsCmd.AddCommand(reportRunsShowCmd)
+	reportCmd.PersistentFlags().BoolVar(&reportRecord, "record", false, "Record the run and the files written in report_runs (always on for the daemon and the job queue)")
+	reportRunsListCmd.Flags().StringVar(&reportRunsReport, "report", "", "Filter by report command (e.g. compliance)")
+	reportRunsListCmd.Flags().StringVar(&reportRunsTrigger, "trigger", "", "Filter by trigger: manual, schedule or queue")
+	reportRunsListCmd.Flags().StringVar(&reportRunsSHA256, "sha256", "", "Show the run that wrote the file with this SHA-256 checksum")
+}
+
+func runReportRunsList(cmd *cobra.Command, args []string) error {
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
+
+	switch reportRunsTrigger {
+	case "", reports.RunTriggerManual, reports.RunTriggerSchedule, reports.RunTriggerQueue:
+	default:
+		return withExitCode(ExitParse, fmt.Errorf("invalid trigger %q: must be manual, schedule or queue", reportRunsTrigger))
+	}
+
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Create report generator
+	report := reports.NewReportRunsReport(db)
+
+	// Query data
+	rows, err := report.Query(cmd.Context(), reportRunsReport, reportRunsTrigger, reportRunsSHA256, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+
+	return writeReportOutput(report, rows)
+}
+
+func runReportRunsShow(cmd *cobra.Command, args []string) error {
+	runID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return withExitCode(ExitParse, fmt.Errorf("invalid run ID %q", args[0]))
+	}
+
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report := reports.NewReportRunsReport(db)
+	run, err := report.Show(cmd.Context(), runID)
+	if err != nil {
+		return err
+	}
+
+	if reportFormat == "json" {
+		return report.WriteJSON(os.Stdout, []reports.ReportRun{*run})
+	}
+	return report.WriteRun(os.Stdout, run)
+}
+
+// RecordReportRun records the report run by cmd in report_runs when it was
+// started by the daemon or the job queue, or with --record. Reports that
+// failed are recorded too; a compliance breach is a successful run.
+func RecordReportRun(cmd *cobra.Command, err error, startedAt time.Time) error {
+	trigger := os.Getenv(ReportTriggerEnv)
+	if cmd == nil || !isReportCommand(cmd) || (trigger == "" && !reportRecord) {
+		return nil
+	}
+	if cmd == reportRunCmd {
+		cmd = reportRunCommand
+	}
+	if cmd == nil || cmd == reportCmd || cmd == reportRunsCmd || cmd.Parent() == reportRunsCmd {
+		return nil
+	}
+	if trigger == "" {
+		trigger = reports.RunTriggerManual
+	}
+
+	// A report that could not open its database has nowhere to be recorded
+	if _, statErr := os.Stat(reportDBPath); statErr != nil {
+		return nil
+	}
+
+	run := &reports.ReportRun{
+		Report:      cmd.Name(),
+		CommandLine: strings.Join(os.Args[1:], " "),
+		Parameters:  map[string]string{},
+		Format:      reportFormat,
+		Trigger:     trigger,
+		JobName:     os.Getenv(ReportJobEnv),
+		StartedAt:   startedAt.UTC().Format("2006-01-02 15:04:05"),
+		FinishedAt:  time.Now().UTC().Format("2006-01-02 15:04:05"),
+		Status:      reports.RunSucceeded,
+	}
+	// Flags set on the command line, by a profile, a filter or the
+	// configuration file
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if !reportRunsIgnoredFlags[flag.Name] && flag.Value.String() != flag.DefValue {
+			run.Parameters[flag.Name] = flag.Value.String()
+		}
+	})
+	if err != nil {
+		run.Error = err.Error()
+		if ExitCode(err) != ExitBreach {
+			run.Status = reports.RunFailed
+		}
+	}
+
+	// A failed report may not have written all its files
+	var outputs []string
+	if status.Report != nil {
+		run.RowCount = status.Report.Rows
+		for _, path := range status.Report.Outputs {
+			if _, statErr := os.Stat(path); statErr == nil {
+				outputs = append(outputs, path)
+			}
+		}
+	}
+
+	db, err := database.Connect(reportDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	return reports.RecordReportRun(db, run, outputs)
+}
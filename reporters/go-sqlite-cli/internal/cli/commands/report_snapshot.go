@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var (
+	reportSnapshotLabel  string
+	reportSnapshotReport string
+)
+
+var reportSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Reproduce a report frozen by 'snapshot create'",
+	Long: `Writes the rows of a report as they were when the snapshot was created (see
+'iwdlr snapshot create'), in the layout of that report. Later corrections and
+re-imports do not change the output.
+
+--report selects the report: peak, monthly-peak or compliance (default). The
+snapshot holds the rows as filtered when it was created, so --product, --mode,
+--from and --to are not supported.
+
+Example:
+  iwdlr report snapshot --label 2025-Q3
+  iwdlr report snapshot --label 2025-Q3 --report monthly-peak --format xlsx --output 2025-Q3.xlsx`,
+	Args: cobra.NoArgs,
+	RunE: runReportSnapshot,
+}
+
+func init() {
+	reportCmd.AddCommand(reportSnapshotCmd)
+	reportSnapshotCmd.Flags().StringVar(&reportSnapshotLabel, "label", "", "Label of the snapshot")
+	reportSnapshotCmd.Flags().StringVar(&reportSnapshotReport, "report", reports.SnapshotCompliance, "Report to reproduce: peak, monthly-peak or compliance")
+	reportSnapshotCmd.MarkFlagRequired("label")
+}
+
+func runReportSnapshot(cmd *cobra.Command, args []string) error {
+	for _, name := range []string{"product", "mode", "from", "to"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s is not supported by report snapshot", name)
+		}
+	}
+
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	snap, err := reports.NewSnapshotStore(db).Get(reportSnapshotLabel)
+	if err != nil {
+		return err
+	}
+
+	switch reportSnapshotReport {
+	case reports.SnapshotPeak:
+		rows, err := reports.LoadSnapshotRows[reports.PeakUsageRow](db, snap.Label, reportSnapshotReport)
+		if err != nil {
+			return err
+		}
+		return writeSnapshotOutput(reports.NewPeakUsageReport(db), rows)
+	case reports.SnapshotMonthlyPeak:
+		rows, err := reports.LoadSnapshotRows[reports.MonthlyPeakRow](db, snap.Label, reportSnapshotReport)
+		if err != nil {
+			return err
+		}
+		return writeSnapshotOutput(reports.NewMonthlyPeakReport(db), rows)
+	case reports.SnapshotCompliance:
+		rows, err := reports.LoadSnapshotRows[reports.ComplianceRow](db, snap.Label, reportSnapshotReport)
+		if err != nil {
+			return err
+		}
+		return writeSnapshotOutput(reports.NewComplianceReport(db), rows)
+	}
+	return fmt.Errorf("unknown report: %s (use peak, monthly-peak or compliance)", reportSnapshotReport)
+}
+
+// writeSnapshotOutput writes the rows of a snapshot with the writers of its report
+func writeSnapshotOutput[T any](report reportWriter[T], rows []T) error {
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+	return writeReportOutput(report, rows)
+}
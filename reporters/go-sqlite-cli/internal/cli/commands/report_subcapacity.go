@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/confidence"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/licensing"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var reportSubcapacityCmd = &cobra.Command{
+	Use:   "subcapacity",
+	Short: "Generate sub-capacity license count per physical host",
+	Long: `Counts the license cores of each product per day and physical host with the
+IBM sub-capacity rules, and explains which rule produced each number:
+
+  sub-capacity    eligible VMs count their cores, each at most the host cores
+  host-cap        the VMs of a host exceed its physical cores: the host cores count
+  full-capacity   a VM on the host has an ineligible OS or virtualization:
+                  all physical cores of the host count
+  low-confidence  the physical host is not identified, or only with low
+                  confidence: all physical cores of the host count
+  physical        non-virtualized node: all its cores count
+
+FULL_CAP shows the cores counted under full-capacity licensing for comparison.
+Only running products are counted; the latest measurement of a node on a day is used.
+
+The confidence of a physical host ID is the one set by 'iwdlr hosts confidence',
+or else the one the inspectors reported. With --min-confidence, the VMs of a host
+identified with less are not deduplicated: each node is counted as a host of its
+own, and the table shows the totals with every host ID deduplicated as well.
+Only this report takes --min-confidence: compliance, monthly-peak, peak and
+report all deduplicate every identified host ID whatever its confidence.
+
+The partial cores of a capped partition (1.5 processing units of a shared
+LPAR) are rounded up per VM by default; with --rounding host the capacities of
+the partitions of a host are added up and the sum is rounded up once.
+--min-cores-per-install N counts each installation of the product for at least
+N cores, as some contracts require.
+
+Example:
+  iwdlr report subcapacity --db-path data/license-monitor.db --from 2025-10-01
+  iwdlr report subcapacity --product IS_ONP_PRD --format json
+  iwdlr report subcapacity --format xlsx --output subcapacity.xlsx
+  iwdlr report subcapacity --min-confidence medium
+  iwdlr report subcapacity --rounding host --min-cores-per-install 1`,
+	RunE: runReportSubcapacity,
+}
+
+// reportMinConfidence is the lowest host ID confidence whose cores are deduplicated
+var reportMinConfidence string
+
+// minConfidenceFlagUsage is the usage of the --min-confidence flag
+const minConfidenceFlagUsage = "Lowest physical host ID confidence whose VM cores are deduplicated: low, medium, high (default low)"
+
+// Core rounding policy of the sub-capacity reports (see licensing.Options)
+var (
+	reportRounding           string
+	reportMinCoresPerInstall int
+)
+
+// Usage of the core rounding flags
+const (
+	roundingFlagUsage           = "Round the partial cores of capped partitions up per VM or per host: vm, host (default vm)"
+	minCoresPerInstallFlagUsage = "Lowest license cores of each installation of a product (default: no minimum)"
+)
+
+func init() {
+	reportCmd.AddCommand(reportSubcapacityCmd)
+	reportSubcapacityCmd.Flags().StringVar(&reportMinConfidence, "min-confidence", "", minConfidenceFlagUsage)
+	reportSubcapacityCmd.Flags().StringVar(&reportRounding, "rounding", "", roundingFlagUsage)
+	reportSubcapacityCmd.Flags().IntVar(&reportMinCoresPerInstall, "min-cores-per-install", 0, minCoresPerInstallFlagUsage)
+}
+
+// checkMinConfidence validates --min-confidence
+func checkMinConfidence() error {
+	if reportMinConfidence != "" && !confidence.IsLevel(reportMinConfidence) {
+		return fmt.Errorf("invalid --min-confidence %q (use low, medium or high)", reportMinConfidence)
+	}
+	return nil
+}
+
+// checkCorePolicy validates --rounding and --min-cores-per-install
+func checkCorePolicy() error {
+	if reportRounding != "" && !licensing.IsRounding(reportRounding) {
+		return fmt.Errorf("invalid --rounding %q (use vm or host)", reportRounding)
+	}
+	if reportMinCoresPerInstall < 0 {
+		return fmt.Errorf("--min-cores-per-install must not be negative")
+	}
+	return nil
+}
+
+func runReportSubcapacity(cmd *cobra.Command, args []string) error {
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
+	
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+
+	if err := checkMinConfidence(); err != nil {
+		return err
+	}
+	if err := checkCorePolicy(); err != nil {
+		return err
+	}
+	
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	
+	// Create report generator
+	report := reports.NewSubcapacityReport(db)
+	report.SetMinConfidence(reportMinConfidence)
+	report.SetCorePolicy(reportRounding, reportMinCoresPerInstall)
+	
+	// Query data
+	rows, err := report.Query(cmd.Context(), reportProduct, mode, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+	
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+	
+	return writeReportOutput(report, rows)
+}
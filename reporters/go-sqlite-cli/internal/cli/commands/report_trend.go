@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+var reportTrendCmd = &cobra.Command{
+	Use:   "trend",
+	Short: "Generate core usage trend report with growth rates",
+	Long: `Shows how the running license cores of each product develop over time.
+
+Growth rates compare the peak of the 7 (week-over-week) and 30 (month-over-month)
+days ending on the latest measured day with the peak of the period before.
+
+A least-squares line through the daily usage of the license term (all products
+of the term, as in the compliance report) projects the day on which the term
+will exceed its entitlement. EXCEEDS shows that date, "exceeded" when the latest
+usage is already above the entitlement, and nothing when usage is flat or
+falling or no entitlement is loaded. Use --from to base the projection on a
+representative period.
+
+Example:
+  iwdlr report trend --db-path data/license-monitor.db
+  iwdlr report trend --product IS_ONP_PRD --from 2025-07-01
+  iwdlr report trend --format csv --output trend.csv`,
+	RunE: runReportTrend,
+}
+
+func init() {
+	reportCmd.AddCommand(reportTrendCmd)
+}
+
+func runReportTrend(cmd *cobra.Command, args []string) error {
+	fromDate, toDate, err := parseReportDates()
+	if err != nil {
+		return err
+	}
+	
+	mode, err := reports.ParseMode(reportMode)
+	if err != nil {
+		return err
+	}
+	
+	// Open database
+	db, err := openReportDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	
+	// Create report generator
+	report := reports.NewTrendReport(db)
+	
+	// Query data
+	rows, err := report.Query(cmd.Context(), reportProduct, mode, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to query data: %w", err)
+	}
+	
+	if len(rows) == 0 {
+		fmt.Println("No data found matching the criteria")
+		return nil
+	}
+	
+	return writeReportOutput(report, rows)
+}
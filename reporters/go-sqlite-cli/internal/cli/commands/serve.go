@@ -0,0 +1,151 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/web"
+)
+
+var (
+	serveDBPath string
+	serveListen string
+	serveJobs   bool
+
+	serveQueryTimeout time.Duration
+)
+
+// NewServeCmd creates the serve command
+func NewServeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve a read-only web dashboard",
+		Long: `Serve a read-only web dashboard over the reporting views.
+
+Pages:
+  /compliance  Compliance status per product (latest day unless From/To are set)
+  /peak        Peak usage per product over the last 31 days
+  /hosts       Host inventory with the latest measurement of each node
+  /imports     Import history and the failed imports waiting for a retry
+  /jobs        Queued, running and finished jobs of 'iwdlr jobs'
+  /healthz     The checks of 'iwdlr health' as JSON: 200 when healthy, else 503
+
+The compliance and peak pages can be downloaded as CSV. The queries of a page
+taking longer than --query-timeout are cancelled and the page answers 503; they
+are also cancelled when the browser goes away. The database is opened
+read-only, and the dashboard has no authentication: listen on localhost (the
+default) or put it behind a reverse proxy that restricts access.
+
+With --jobs the server also runs the queued jobs, with a separate read-write
+connection; the dashboard itself stays read-only.
+
+Example:
+  iwdlr serve --db-path ./data/license-monitor.db --listen 127.0.0.1:8080`,
+		Args: cobra.NoArgs,
+		RunE: runServe,
+	}
+
+	cmd.Flags().StringVar(&serveDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+	cmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:8080",
+		"Address to listen on")
+	cmd.Flags().BoolVar(&serveJobs, "jobs", false, "Also run the jobs of the job queue")
+	cmd.Flags().DurationVar(&serveQueryTimeout, "query-timeout", web.DefaultQueryTimeout,
+		"Cancel the queries of a page taking longer (0 for no limit)")
+	addHealthFlags(cmd.Flags())
+
+	return cmd
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	db, err := database.ConnectReadOnly(serveDBPath)
+	if err != nil {
+		return fmt.Errorf("%w\nRun 'iwdlr init' first", err)
+	}
+	defer db.Close()
+
+	server, err := web.NewServer(db)
+	if err != nil {
+		return err
+	}
+	server.Health = healthOptions()
+	server.QueryTimeout = serveQueryTimeout
+
+	httpServer := &http.Server{
+		Addr:              serveListen,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if serveJobs {
+		queueDB, err := database.Connect(serveDBPath)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer queueDB.Close()
+		worker, err := newJobWorker(queueDB, serveDBPath, nil)
+		if err != nil {
+			return err
+		}
+		// The worker stops with the server and finishes recording its job
+		done := make(chan struct{})
+		defer func() {
+			stop()
+			<-done
+		}()
+		go func() {
+			defer close(done)
+			log.Printf("Running the job queue of %s", serveDBPath)
+			worker.Run(ctx)
+		}()
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("Serving the dashboard of %s on http://%s", serveDBPath, serveListen)
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to serve: %w", err)
+		}
+	case <-ctx.Done():
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdown); err != nil {
+			return fmt.Errorf("failed to stop the server: %w", err)
+		}
+		log.Printf("Server stopped")
+	}
+
+	return nil
+}
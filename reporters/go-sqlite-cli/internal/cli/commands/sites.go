@@ -0,0 +1,235 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sitesDBPath      string
+	sitesName        string
+	sitesDescription string
+	sitesUnassign    bool
+	sitesListNodes   bool
+)
+
+// NewSitesCmd creates the sites command
+func NewSitesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sites",
+		Short: "Manage sites (datacenters or clusters)",
+		Long: `Manage the sites landscape nodes are grouped by, e.g. datacenters or clusters.
+
+Nodes are assigned to one site each. The daily-summary and compliance reports
+show the license usage per site with --group-by site, e.g. for internal
+chargeback. Landscape nodes are created by their first import, so import a
+node's inspector output before assigning it.
+
+Example:
+  iwdlr sites add fra1 --name "Frankfurt DC 1"
+  iwdlr sites assign fra1 i23.example.com i24.example.com
+  iwdlr sites list --nodes
+  iwdlr report compliance --group-by site`,
+	}
+
+	cmd.PersistentFlags().StringVar(&sitesDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+
+	add := &cobra.Command{
+		Use:   "add <site-id>",
+		Short: "Add a site or update its name and description",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runSitesAdd,
+	}
+	add.Flags().StringVar(&sitesName, "name", "", "Display name of the site (default: the site ID)")
+	add.Flags().StringVar(&sitesDescription, "description", "", "Description of the site")
+
+	remove := &cobra.Command{
+		Use:   "remove <site-id>",
+		Short: "Remove a site",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runSitesRemove,
+	}
+	remove.Flags().BoolVar(&sitesUnassign, "unassign", false, "Unassign the nodes of the site instead of failing")
+
+	assign := &cobra.Command{
+		Use:   "assign <site-id> <fqdn>...",
+		Short: "Assign landscape nodes to a site",
+		Long:  "Assign landscape nodes to a site, replacing their previous site.",
+		Args:  cobra.MinimumNArgs(2),
+		RunE:  runSitesAssign,
+	}
+
+	unassign := &cobra.Command{
+		Use:   "unassign <fqdn>...",
+		Short: "Remove landscape nodes from their site",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  runSitesUnassign,
+	}
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List sites",
+		Args:  cobra.NoArgs,
+		RunE:  runSitesList,
+	}
+	list.Flags().BoolVar(&sitesListNodes, "nodes", false, "List the nodes of each site and the nodes without a site")
+
+	cmd.AddCommand(add, remove, assign, unassign, list)
+
+	return cmd
+}
+
+func runSitesAdd(cmd *cobra.Command, args []string) error {
+	db, err := openSitesDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	created, err := importer.NewSiteEditor(db).Save(args[0], sitesName, sitesDescription)
+	if err != nil {
+		return err
+	}
+
+	if created {
+		fmt.Printf("Added site %s\n", args[0])
+		fmt.Println("\nNext steps:")
+		fmt.Printf("  - Assign nodes: iwdlr sites assign --db-path %s %s <fqdn>...\n", sitesDBPath, args[0])
+	} else {
+		fmt.Printf("Updated site %s\n", args[0])
+	}
+	return nil
+}
+
+func runSitesRemove(cmd *cobra.Command, args []string) error {
+	db, err := openSitesDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	nodes, err := importer.NewSiteEditor(db).Remove(args[0], sitesUnassign)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed site %s\n", args[0])
+	if nodes > 0 {
+		fmt.Printf("  Nodes unassigned: %d\n", nodes)
+	}
+	return nil
+}
+
+func runSitesAssign(cmd *cobra.Command, args []string) error {
+	db, err := openSitesDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := importer.NewSiteEditor(db).Assign(args[0], args[1:]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Assigned %d node(s) to site %s\n", len(args)-1, args[0])
+	return nil
+}
+
+func runSitesUnassign(cmd *cobra.Command, args []string) error {
+	db, err := openSitesDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := importer.NewSiteEditor(db).Assign("", args); err != nil {
+		return err
+	}
+
+	fmt.Printf("Unassigned %d node(s)\n", len(args))
+	return nil
+}
+
+func runSitesList(cmd *cobra.Command, args []string) error {
+	db, err := openSitesDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	editor := importer.NewSiteEditor(db)
+	sites, err := editor.ListSites()
+	if err != nil {
+		return err
+	}
+
+	if len(sites) == 0 {
+		fmt.Println("No sites defined")
+	}
+
+	for _, s := range sites {
+		fmt.Printf("%-16s %-32s %d node(s)\n", s.SiteID, s.SiteName, s.NodeCount)
+		if s.Description != "" {
+			fmt.Printf("  %s\n", s.Description)
+		}
+		if sitesListNodes {
+			if err := printSiteNodes(editor, s.SiteID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if sitesListNodes {
+		fmt.Println("\nWithout a site:")
+		if err := printSiteNodes(editor, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printSiteNodes prints the nodes of a site, or the nodes without a site
+func printSiteNodes(editor *importer.SiteEditor, siteID string) error {
+	nodes, err := editor.ListSiteNodes(siteID)
+	if err != nil {
+		return err
+	}
+	for _, fqdn := range nodes {
+		fmt.Printf("    %s\n", fqdn)
+	}
+	return nil
+}
+
+// openSitesDB opens the existing database given by --db-path
+func openSitesDB() (*sql.DB, error) {
+	if _, err := os.Stat(sitesDBPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", sitesDBPath)
+	}
+
+	db, err := database.Connect(sitesDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,192 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"os/user"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotDBPath  string
+	snapshotLabel   string
+	snapshotFrom    string
+	snapshotTo      string
+	snapshotProduct string
+	snapshotMode    string
+	snapshotNotes   string
+)
+
+// NewSnapshotCmd creates the snapshot command
+func NewSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Freeze report aggregates into immutable snapshots",
+		Long: `Freeze the peak usage and compliance numbers of a reporting period under a
+label, e.g. the quarter they were reported to IBM for.
+
+A snapshot stores the rows of the peak, monthly-peak and compliance reports as
+they are when it is created. Later corrections, re-imports or reference data
+changes do not alter it: 'iwdlr report snapshot --label <label>' reproduces
+exactly what was reported. Snapshots cannot be updated or deleted.
+
+Example:
+  iwdlr snapshot create --label 2025-Q3 --from 2025-07-01 --to 2025-09-30
+  iwdlr snapshot list
+  iwdlr report snapshot --label 2025-Q3 --report monthly-peak`,
+	}
+
+	cmd.PersistentFlags().StringVar(&snapshotDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create a snapshot of the current report aggregates",
+		Long: `Create a snapshot of the peak, monthly-peak and compliance reports.
+
+--from, --to, --product and --mode filter the reports as with 'iwdlr report'.
+The peak report always covers the 31 days before the snapshot is created.`,
+		Args: cobra.NoArgs,
+		RunE: runSnapshotCreate,
+	}
+	create.Flags().StringVar(&snapshotLabel, "label", "", "Label of the snapshot, e.g. 2025-Q3")
+	create.Flags().StringVar(&snapshotFrom, "from", "", "Start of the period (YYYY-MM-DD)")
+	create.Flags().StringVar(&snapshotTo, "to", "", "End of the period (YYYY-MM-DD)")
+	create.Flags().StringVar(&snapshotProduct, "product", "", "Only include this product code")
+	create.Flags().StringVar(&snapshotMode, "mode", "", "Only include this mode: PROD or NON PROD")
+	create.Flags().StringVar(&snapshotNotes, "notes", "", "Notes stored with the snapshot")
+	create.MarkFlagRequired("label")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List snapshots",
+		Args:  cobra.NoArgs,
+		RunE:  runSnapshotList,
+	}
+
+	cmd.AddCommand(create, list)
+
+	return cmd
+}
+
+func runSnapshotCreate(cmd *cobra.Command, args []string) error {
+	mode, err := reports.ParseMode(snapshotMode)
+	if err != nil {
+		return err
+	}
+
+	db, err := openSnapshotDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	schemaVersion, err := database.GetCurrentSchemaVersion(db)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	snap := &reports.Snapshot{
+		Label:         snapshotLabel,
+		PeriodFrom:    snapshotFrom,
+		PeriodTo:      snapshotTo,
+		Product:       snapshotProduct,
+		Mode:          mode,
+		SchemaVersion: schemaVersion,
+		Notes:         snapshotNotes,
+	}
+	if u, err := user.Current(); err == nil {
+		snap.CreatedBy = u.Username
+	}
+
+	if err := reports.NewSnapshotStore(db).Create(cmd.Context(), snap); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created snapshot %s\n", snap.Label)
+	for _, report := range reports.SnapshotReports {
+		fmt.Printf("  %-14s %d rows\n", report+":", snap.Rows[report])
+	}
+	fmt.Println("\nNext steps:")
+	fmt.Printf("  - Reproduce the reports: iwdlr report snapshot --db-path %s --label %s\n", snapshotDBPath, snap.Label)
+	return nil
+}
+
+func runSnapshotList(cmd *cobra.Command, args []string) error {
+	db, err := openSnapshotDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	snapshots, err := reports.NewSnapshotStore(db).List()
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Println("No snapshots created")
+	}
+
+	for _, snap := range snapshots {
+		period := "all dates"
+		if snap.PeriodFrom != "" || snap.PeriodTo != "" {
+			period = snap.PeriodFrom + " .. " + snap.PeriodTo
+		}
+		fmt.Printf("%-16s %-24s created %s", snap.Label, period, snap.CreatedAt.Format("2006-01-02 15:04:05"))
+		if snap.CreatedBy != "" {
+			fmt.Printf(" by %s", snap.CreatedBy)
+		}
+		fmt.Println()
+
+		var filters []string
+		if snap.Product != "" {
+			filters = append(filters, "product "+snap.Product)
+		}
+		if snap.Mode != "" {
+			filters = append(filters, "mode "+snap.Mode)
+		}
+		for _, filter := range filters {
+			fmt.Printf("  %s\n", filter)
+		}
+		fmt.Printf("  rows: %d peak, %d monthly-peak, %d compliance (schema %s)\n",
+			snap.Rows[reports.SnapshotPeak], snap.Rows[reports.SnapshotMonthlyPeak],
+			snap.Rows[reports.SnapshotCompliance], snap.SchemaVersion)
+		if snap.Notes != "" {
+			fmt.Printf("  %s\n", snap.Notes)
+		}
+	}
+
+	return nil
+}
+
+// openSnapshotDB opens the existing database given by --db-path
+func openSnapshotDB() (*sql.DB, error) {
+	if _, err := os.Stat(snapshotDBPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", snapshotDBPath)
+	}
+
+	db, err := database.Connect(snapshotDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
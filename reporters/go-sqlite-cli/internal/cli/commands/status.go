@@ -0,0 +1,237 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+// Exit codes of iwldr, so that scripts and orchestration tools can branch on
+// the outcome of a command
+const (
+	ExitOK         = 0
+	ExitError      = 1 // any other error
+	ExitParse      = 2 // the command line or an input file could not be parsed
+	ExitDatabase   = 3 // the database could not be opened, read or written
+	ExitValidation = 4 // input files failed validation
+	ExitBreach     = 5 // the compliance report found a breach (--fail-on-breach)
+	ExitUnhealthy  = 6 // a check of the health command failed
+)
+
+// exitStatuses name the exit codes in the --status-json summary
+var exitStatuses = map[int]string{
+	ExitOK:         "ok",
+	ExitError:      "error",
+	ExitParse:      "parse_error",
+	ExitDatabase:   "database_error",
+	ExitValidation: "validation_failure",
+	ExitBreach:     "compliance_breach",
+	ExitUnhealthy:  "unhealthy",
+}
+
+// exitError is an error returned by a command with the exit code of its kind
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode returns err with an exit code; nil stays nil
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// FlagError gives the errors of the command line flags the parse error exit
+// code, for the flag error function of the root command
+func FlagError(cmd *cobra.Command, err error) error {
+	return withExitCode(ExitParse, err)
+}
+
+// ExitCode returns the process exit code of the error of a command: the code
+// it was returned with, else the code of its cause, else ExitError
+func ExitCode(err error) int {
+	var exitErr *exitError
+	var sqliteErr sqlite3.Error
+	var lockedErr *importer.ImportLockedError
+	var parseErr *importer.ParseError
+	var warningsErr *importer.TooManyWarningsError
+	var unknownErr *importer.UnknownProductCodesError
+
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	case errors.As(err, &sqliteErr), errors.As(err, &lockedErr):
+		return ExitDatabase
+	case errors.As(err, &parseErr):
+		return ExitParse
+	case errors.As(err, &warningsErr), errors.As(err, &unknownErr):
+		return ExitValidation
+	}
+	return ExitError
+}
+
+// importExitCode returns the exit code of the files of an import that failed:
+// database errors first, then files that could not be parsed, then files
+// rejected by validation
+func importExitCode(batch *importer.BatchImportResult) int {
+	code := ExitOK
+	for _, fr := range batch.Files {
+		if fr.Err == nil {
+			continue
+		}
+		switch ExitCode(fr.Err) {
+		case ExitDatabase:
+			return ExitDatabase
+		case ExitParse:
+			code = ExitParse
+		default:
+			if code == ExitOK {
+				code = ExitValidation
+			}
+		}
+	}
+	return code
+}
+
+var (
+	// statusJSON prints the result summary of import and report commands to
+	// stderr as JSON
+	statusJSON bool
+
+	// status collects the result summary of the command being run
+	status commandStatus
+)
+
+const statusJSONFlagUsage = "Print a JSON summary of the result to stderr, for orchestration tools"
+
+// commandStatus is the result summary printed by --status-json
+type commandStatus struct {
+	Command    string        `json:"command"`
+	Status     string        `json:"status"`
+	ExitCode   int           `json:"exit_code"`
+	Error      string        `json:"error,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+	DurationMS int64         `json:"duration_ms"`
+	Import     *importStatus `json:"import,omitempty"`
+	Report     *reportStatus `json:"report,omitempty"`
+}
+
+// importStatus summarizes the files of an import
+type importStatus struct {
+	Files          int      `json:"files"`
+	FilesImported  int      `json:"files_imported"`
+	FilesSkipped   int      `json:"files_skipped"`
+	FilesDeferred  int      `json:"files_deferred"`
+	FilesFailed    int      `json:"files_failed"`
+	RecordsCreated int      `json:"records_created"`
+	RecordsUpdated int      `json:"records_updated"`
+	RecordsSkipped int      `json:"records_skipped"`
+	Warnings       int      `json:"warnings"`
+	FailedFiles    []string `json:"failed_files,omitempty"`
+}
+
+// reportStatus summarizes the output of a report
+type reportStatus struct {
+	Report   string   `json:"report"`
+	Format   string   `json:"format"`
+	Rows     int      `json:"rows"`
+	Outputs  []string `json:"outputs,omitempty"`
+	Breaches *int     `json:"breaches,omitempty"`
+}
+
+// setImportStatus records the summary of an import
+func setImportStatus(batch *importer.BatchImportResult) {
+	s := &importStatus{
+		Files:          len(batch.Files),
+		FilesImported:  batch.FilesOK,
+		FilesSkipped:   batch.FilesSkipped,
+		FilesDeferred:  batch.FilesDeferred,
+		FilesFailed:    batch.FilesFailed,
+		RecordsCreated: batch.Total.RecordsCreated,
+		RecordsUpdated: batch.Total.RecordsUpdated,
+		RecordsSkipped: batch.Total.RecordsSkipped,
+		Warnings:       len(batch.Total.Errors),
+	}
+	for _, fr := range batch.Files {
+		if fr.Err != nil {
+			s.FailedFiles = append(s.FailedFiles, fr.FilePath)
+		}
+	}
+	status.Import = s
+}
+
+// currentReportStatus returns the summary of the report being written
+func currentReportStatus() *reportStatus {
+	if status.Report == nil {
+		status.Report = &reportStatus{Report: reportName, Format: reportFormat}
+	}
+	return status.Report
+}
+
+// addReportStatusOutput records a file written by the report; stdout is not
+// recorded
+func addReportStatusOutput(path string) {
+	if path != "" {
+		currentReportStatus().Outputs = append(currentReportStatus().Outputs, path)
+	}
+}
+
+// WriteStatus writes the result summary of cmd to w as one JSON line when
+// --status-json is set
+func WriteStatus(w io.Writer, cmd *cobra.Command, err error, startedAt time.Time) error {
+	if !statusJSON || cmd == nil {
+		return nil
+	}
+
+	s := status
+	if s.Report == nil && reportName != "" {
+		s.Report = &reportStatus{Report: reportName, Format: reportFormat}
+	}
+	s.Command = cmd.CommandPath()
+	s.ExitCode = ExitCode(err)
+	s.Status = exitStatuses[s.ExitCode]
+	if err != nil {
+		s.Error = err.Error()
+	}
+	s.StartedAt = startedAt.UTC()
+	s.DurationMS = time.Since(startedAt).Milliseconds()
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/vcenter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncDBPath   string
+	syncServer   string
+	syncUser     string
+	syncInsecure bool
+	syncTimeout  time.Duration
+	syncDryRun   bool
+)
+
+// NewSyncCmd creates the sync command
+func NewSyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Synchronize the landscape with external inventories",
+	}
+
+	cmd.PersistentFlags().StringVar(&syncDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+
+	vcenterCmd := &cobra.Command{
+		Use:   "vcenter",
+		Short: "Read the ESXi hosts and VM placement from vCenter",
+		Long: `Read the ESXi hosts, their physical core counts and the VMs running on them
+from a vCenter Server (vSphere Web Services API, vCenter 6.7 or later) and store
+them as the authoritative physical host data:
+
+- Each ESXi host becomes a physical host with method vcenter and high
+  confidence, and its physical core count replaces the one reported by the
+  inspectors. A host already known under its hardware UUID or name keeps its
+  ID; a new host is identified by its hardware UUID.
+- VMs are matched to landscape nodes by their guest host name (VMware Tools),
+  else their VM name. A node whose latest measurement has a low confidence
+  host ID other than its vCenter host gets its low confidence measurements
+  with that ID moved to the vCenter host. Nodes with a medium or high
+  confidence host ID that differs are listed as conflicts and not changed;
+  correct them with 'iwdlr hosts merge' if vCenter is right.
+
+Hosts and VMs are recorded in hypervisor_hosts and hypervisor_vms, replacing
+those of the previous sync of the same server, and corrections in
+host_id_corrections. Run it after each import, as new measurements of a node
+report the inspector's host ID again.
+
+The password is read from the IWLDR_VCENTER_PASSWORD environment variable.
+A read-only vCenter role is enough.
+
+Example:
+  export IWLDR_VCENTER_PASSWORD=...
+  iwdlr sync vcenter --server vcenter.example.com --user iwldr@vsphere.local --dry-run
+  iwdlr sync vcenter --server vcenter.example.com --user iwldr@vsphere.local`,
+		Args: cobra.NoArgs,
+		RunE: runSyncVCenter,
+	}
+	vcenterCmd.Flags().StringVar(&syncServer, "server", "", "vCenter Server host name or URL")
+	vcenterCmd.Flags().StringVar(&syncUser, "user", "", "vCenter user, e.g. iwldr@vsphere.local")
+	vcenterCmd.Flags().BoolVar(&syncInsecure, "insecure-skip-verify", false,
+		"Do not verify the certificate of the vCenter Server (testing only)")
+	vcenterCmd.Flags().DurationVar(&syncTimeout, "timeout", 2*time.Minute, "Timeout of each vCenter request")
+	vcenterCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Show what would change without changing the database")
+	vcenterCmd.MarkFlagRequired("server")
+	vcenterCmd.MarkFlagRequired("user")
+
+	cmd.AddCommand(vcenterCmd)
+	return cmd
+}
+
+func runSyncVCenter(cmd *cobra.Command, args []string) error {
+	password := os.Getenv(vcenter.PasswordEnvVar)
+	if password == "" {
+		return fmt.Errorf("%s is not set", vcenter.PasswordEnvVar)
+	}
+
+	// Check database exists
+	if _, err := os.Stat(syncDBPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", syncDBPath)
+	}
+
+	db, err := database.Connect(syncDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	client, err := vcenter.NewClient(syncServer, syncUser, password, syncInsecure, syncTimeout)
+	if err != nil {
+		return err
+	}
+	topology, err := client.Topology()
+	if err != nil {
+		return err
+	}
+
+	// The host name of the server identifies the source of the hosts and VMs
+	source := syncServer
+	if u, err := url.Parse(syncServer); err == nil && u.Hostname() != "" {
+		source = u.Hostname()
+	}
+
+	result, err := vcenter.NewSyncer(db).Sync(source, topology, syncDryRun)
+	if err != nil {
+		return err
+	}
+
+	if syncDryRun {
+		fmt.Println("Dry run, nothing changed")
+	}
+	fmt.Printf("vCenter %s: %d host(s), %d VM(s)\n", source, len(topology.Hosts), result.VMs)
+	fmt.Printf("  Physical hosts added:   %d\n", result.HostsAdded)
+	fmt.Printf("  Physical hosts updated: %d\n", result.HostsUpdated)
+	fmt.Printf("  VMs matched to nodes:   %d\n", result.VMsMatched)
+
+	if len(result.Corrections) > 0 {
+		fmt.Println("\nCorrected low confidence host IDs:")
+		for _, c := range result.Corrections {
+			fmt.Printf("  %s: %s -> %s (%d measurements)\n", c.MainFQDN, c.OldHostID, c.NewHostID, c.MeasurementsUpdated)
+		}
+	}
+	if len(result.Conflicts) > 0 {
+		fmt.Println("\nHost IDs differing from vCenter (not changed):")
+		for _, c := range result.Conflicts {
+			fmt.Printf("  %s: %s (%s confidence), vCenter: %s\n", c.MainFQDN, c.HostID, c.Confidence, c.VCenterHostID)
+		}
+	}
+
+	if !syncDryRun {
+		refreshReportCache(db)
+	}
+	return nil
+}
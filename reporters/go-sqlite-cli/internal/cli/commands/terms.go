@@ -0,0 +1,486 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	termsDBPath        string
+	termsEffectiveFrom string
+	termsEffectiveTo   string
+	termsDescription   string
+	termsPeriodStart   string
+	termsPeriodEnd     string
+	termsPeriodLabel   string
+	termsPeriodNotes   string
+	termsMapFrom       string
+	termsMapTo         string
+	termsMapNotes      string
+)
+
+// NewTermsCmd creates the terms command
+func NewTermsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "terms",
+		Short: "Manage the documents, contract periods and product mappings of the license terms",
+		Long: `Manage the license term documents (PDF files or URLs) attached to the license
+terms loaded from license-terms.csv.
+
+Program terms change over contract renewals, so every document has the period
+it is in force for. The daily-summary and compliance reports show the document
+in force on each measurement date, and the audit package lists the documents in
+force during its period.
+
+The contract periods of a term ('terms period') let the peak reports compute
+the peaks of the current or an earlier contract with --period.
+
+A product moved to another program mid-year is mapped to its former term up to
+the move ('terms map'), so that the reports of the earlier days keep it under
+the former program number.`,
+	}
+
+	cmd.PersistentFlags().StringVar(&termsDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+
+	attach := &cobra.Command{
+		Use:   "attach <term-id> <path-or-url>",
+		Short: "Attach a document to a license term",
+		Long: `Attach a document to a license term. A path must be an existing file and is
+stored as an absolute path; URLs are stored as given. The periods of the
+documents of a term must not overlap, except that the document in force from an
+earlier date without an end date is superseded: it ends the day before the new
+document starts.
+
+Example:
+  iwdlr terms attach T1 /srv/contracts/5724-L88-2025.pdf --effective-from 2025-01-01
+  iwdlr terms attach T1 https://www.ibm.com/terms/?id=L-XXXX-XXXXXX --effective-from 2023-01-01 --effective-to 2024-12-31`,
+		Args: cobra.ExactArgs(2),
+		RunE: runTermsAttach,
+	}
+	attach.Flags().StringVar(&termsEffectiveFrom, "effective-from", "", "First day the document is in force (YYYY-MM-DD, required)")
+	attach.Flags().StringVar(&termsEffectiveTo, "effective-to", "", "Last day the document is in force (YYYY-MM-DD, default: in force until superseded)")
+	attach.Flags().StringVar(&termsDescription, "description", "", "Description of the document, e.g. the contract it belongs to")
+	attach.MarkFlagRequired("effective-from")
+
+	detach := &cobra.Command{
+		Use:   "detach <document-id>",
+		Short: "Remove a document from its license term",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runTermsDetach,
+	}
+
+	list := &cobra.Command{
+		Use:   "list [term-id]",
+		Short: "List the license term documents",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runTermsList,
+	}
+
+	mapProduct := &cobra.Command{
+		Use:   "map <product-code> <term-id>",
+		Short: "Map a product code to a license term over a period",
+		Long: `Map a product code to a license term over a period. On the days the mapping is
+in force the reports attribute the product to that term; on other days the
+term of the product code (product-codes.csv) applies. A product moved from
+program A to program B on 2025-07-01 is recorded by mapping it to the term of A
+up to 2025-06-30 and loading B as its term in product-codes.csv, or by mapping
+it to B from 2025-07-01.
+
+The periods of the mappings of a product must not overlap, except that the
+mapping in force from an earlier date without an end date is superseded: it
+ends the day before the new mapping starts. Mapping a product refreshes the
+report cache when it is enabled.
+
+Example:
+  iwdlr terms map IS_ONP_PRD L-JGNZ-K3Z366 --effective-from 2024-01-01 --effective-to 2025-06-30
+  iwdlr terms map IS_ONP_PRD L-NEW-TERMID --effective-from 2025-07-01 --notes "moved to program 5900-XYZ"`,
+		Args: cobra.ExactArgs(2),
+		RunE: runTermsMap,
+	}
+	mapProduct.Flags().StringVar(&termsMapFrom, "effective-from", "", "First day the product belongs to the term (YYYY-MM-DD, required)")
+	mapProduct.Flags().StringVar(&termsMapTo, "effective-to", "", "Last day the product belongs to the term (YYYY-MM-DD, default: until superseded)")
+	mapProduct.Flags().StringVar(&termsMapNotes, "notes", "", "Notes, e.g. the reason of the move")
+	mapProduct.MarkFlagRequired("effective-from")
+
+	unmap := &cobra.Command{
+		Use:   "unmap <mapping-id>",
+		Short: "Remove a product term mapping",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runTermsUnmap,
+	}
+
+	mappings := &cobra.Command{
+		Use:   "mappings [product-code]",
+		Short: "List the product term mappings",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runTermsMappings,
+	}
+
+	cmd.AddCommand(attach, detach, list, newTermsPeriodCmd(), mapProduct, unmap, mappings)
+
+	return cmd
+}
+
+// newTermsPeriodCmd creates the terms period command group
+func newTermsPeriodCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "period",
+		Short: "Manage the contract periods of the license terms",
+		Long: `Manage the contract periods of the license terms. Each period has a label,
+unique per term, and a start and end date; the periods of a term must not
+overlap. 'report peak' and 'report monthly-peak' compute the peaks within a
+period with --period current, --period previous or --period <label>, so that
+the peaks of former contracts do not drive the current numbers.`,
+	}
+
+	add := &cobra.Command{
+		Use:   "add <term-id>",
+		Short: "Add a contract period to a license term",
+		Long: `Add a contract period to a license term. The label defaults to the year the
+period starts.
+
+Example:
+  iwdlr terms period add T1 --start 2025-04-01 --end 2026-03-31
+  iwdlr terms period add T1 --start 2023-04-01 --end 2025-03-31 --label renewal-2023`,
+		Args: cobra.ExactArgs(1),
+		RunE: runTermsPeriodAdd,
+	}
+	add.Flags().StringVar(&termsPeriodStart, "start", "", "First day of the period (YYYY-MM-DD, required)")
+	add.Flags().StringVar(&termsPeriodEnd, "end", "", "Last day of the period (YYYY-MM-DD, required)")
+	add.Flags().StringVar(&termsPeriodLabel, "label", "", "Label of the period (default: the start year)")
+	add.Flags().StringVar(&termsPeriodNotes, "notes", "", "Notes, e.g. the contract number")
+	add.MarkFlagRequired("start")
+	add.MarkFlagRequired("end")
+
+	remove := &cobra.Command{
+		Use:   "remove <term-id> <label>",
+		Short: "Remove a contract period",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runTermsPeriodRemove,
+	}
+
+	list := &cobra.Command{
+		Use:   "list [term-id]",
+		Short: "List the contract periods",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runTermsPeriodList,
+	}
+
+	cmd.AddCommand(add, remove, list)
+
+	return cmd
+}
+
+func runTermsAttach(cmd *cobra.Command, args []string) error {
+	doc := &models.LicenseTermDocument{
+		TermID:      args[0],
+		Location:    args[1],
+		Description: termsDescription,
+	}
+
+	from, err := time.Parse("2006-01-02", termsEffectiveFrom)
+	if err != nil {
+		return fmt.Errorf("invalid --effective-from date: %w", err)
+	}
+	doc.EffectiveFrom = from
+	if termsEffectiveTo != "" {
+		to, err := time.Parse("2006-01-02", termsEffectiveTo)
+		if err != nil {
+			return fmt.Errorf("invalid --effective-to date: %w", err)
+		}
+		doc.EffectiveTo = &to
+	}
+
+	db, err := openTermsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	superseded, err := importer.NewTermDocumentEditor(db).Attach(doc)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Attached document %d to term %s: %s\n", doc.DocumentID, doc.TermID, doc.Location)
+	fmt.Printf("  In force: %s\n", termDocumentPeriod(*doc))
+	if superseded != 0 {
+		fmt.Printf("  Superseded document %d, now in force until %s\n", superseded, from.AddDate(0, 0, -1).Format("2006-01-02"))
+	}
+	return nil
+}
+
+func runTermsDetach(cmd *cobra.Command, args []string) error {
+	documentID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid document ID %q", args[0])
+	}
+
+	db, err := openTermsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := importer.NewTermDocumentEditor(db).Detach(documentID); err != nil {
+		return err
+	}
+
+	fmt.Printf("Detached document %d\n", documentID)
+	return nil
+}
+
+func runTermsList(cmd *cobra.Command, args []string) error {
+	db, err := openTermsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	termID := ""
+	if len(args) > 0 {
+		termID = args[0]
+	}
+	docs, err := importer.NewTermDocumentEditor(db).List(termID)
+	if err != nil {
+		return err
+	}
+
+	if len(docs) == 0 {
+		fmt.Println("No term documents attached")
+		return nil
+	}
+
+	for _, doc := range docs {
+		fmt.Printf("%-6d %-12s %-25s %s\n", doc.DocumentID, doc.TermID, termDocumentPeriod(doc), doc.Location)
+		if doc.Description != "" {
+			fmt.Printf("       %s\n", doc.Description)
+		}
+	}
+	return nil
+}
+
+func runTermsPeriodAdd(cmd *cobra.Command, args []string) error {
+	start, err := time.Parse("2006-01-02", termsPeriodStart)
+	if err != nil {
+		return fmt.Errorf("invalid --start date: %w", err)
+	}
+	end, err := time.Parse("2006-01-02", termsPeriodEnd)
+	if err != nil {
+		return fmt.Errorf("invalid --end date: %w", err)
+	}
+
+	db, err := openTermsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	period := &models.ContractPeriod{
+		TermID:    args[0],
+		Label:     termsPeriodLabel,
+		StartDate: start,
+		EndDate:   end,
+		Notes:     termsPeriodNotes,
+	}
+	if err := importer.NewContractPeriodEditor(db).Add(period); err != nil {
+		return err
+	}
+
+	fmt.Printf("Added contract period %s to term %s: %s to %s\n", period.Label, period.TermID,
+		termsPeriodStart, termsPeriodEnd)
+	return nil
+}
+
+func runTermsPeriodRemove(cmd *cobra.Command, args []string) error {
+	db, err := openTermsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := importer.NewContractPeriodEditor(db).Remove(args[0], args[1]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed contract period %s of term %s\n", args[1], args[0])
+	return nil
+}
+
+func runTermsPeriodList(cmd *cobra.Command, args []string) error {
+	db, err := openTermsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	termID := ""
+	if len(args) > 0 {
+		termID = args[0]
+	}
+	periods, err := importer.NewContractPeriodEditor(db).List(termID)
+	if err != nil {
+		return err
+	}
+
+	if len(periods) == 0 {
+		fmt.Println("No contract periods defined")
+		return nil
+	}
+
+	for _, p := range periods {
+		fmt.Printf("%-12s %-15s %s to %s", p.TermID, p.Label,
+			p.StartDate.Format("2006-01-02"), p.EndDate.Format("2006-01-02"))
+		if p.Notes != "" {
+			fmt.Printf("  %s", p.Notes)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func runTermsMap(cmd *cobra.Command, args []string) error {
+	mapping := &models.ProductTermMapping{
+		ProductMnemoCode: args[0],
+		TermID:           args[1],
+		Notes:            termsMapNotes,
+	}
+
+	from, err := time.Parse("2006-01-02", termsMapFrom)
+	if err != nil {
+		return fmt.Errorf("invalid --effective-from date: %w", err)
+	}
+	mapping.EffectiveFrom = from
+	if termsMapTo != "" {
+		to, err := time.Parse("2006-01-02", termsMapTo)
+		if err != nil {
+			return fmt.Errorf("invalid --effective-to date: %w", err)
+		}
+		mapping.EffectiveTo = &to
+	}
+
+	db, err := openTermsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	editor := importer.NewProductTermMappingEditor(db)
+	if u, err := user.Current(); err == nil {
+		editor.CreatedBy = u.Username
+	}
+	superseded, err := editor.Map(mapping)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Mapped product %s to term %s (mapping %d)\n", mapping.ProductMnemoCode, mapping.TermID, mapping.MappingID)
+	fmt.Printf("  In force: %s\n", datedPeriod(mapping.EffectiveFrom, mapping.EffectiveTo))
+	if superseded != 0 {
+		fmt.Printf("  Superseded mapping %d, now in force until %s\n", superseded, from.AddDate(0, 0, -1).Format("2006-01-02"))
+	}
+	refreshReportCache(db)
+	return nil
+}
+
+func runTermsUnmap(cmd *cobra.Command, args []string) error {
+	mappingID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid mapping ID %q", args[0])
+	}
+
+	db, err := openTermsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := importer.NewProductTermMappingEditor(db).Remove(mappingID); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed mapping %d\n", mappingID)
+	refreshReportCache(db)
+	return nil
+}
+
+func runTermsMappings(cmd *cobra.Command, args []string) error {
+	db, err := openTermsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	productCode := ""
+	if len(args) > 0 {
+		productCode = args[0]
+	}
+	mappings, err := importer.NewProductTermMappingEditor(db).List(productCode)
+	if err != nil {
+		return err
+	}
+
+	if len(mappings) == 0 {
+		fmt.Println("No product term mappings defined")
+		return nil
+	}
+
+	for _, m := range mappings {
+		fmt.Printf("%-6d %-15s %-15s %s\n", m.MappingID, m.ProductMnemoCode, m.TermID, datedPeriod(m.EffectiveFrom, m.EffectiveTo))
+		if m.Notes != "" {
+			fmt.Printf("       %s\n", m.Notes)
+		}
+	}
+	return nil
+}
+
+// termDocumentPeriod formats the period a document is in force for
+func termDocumentPeriod(doc models.LicenseTermDocument) string {
+	return datedPeriod(doc.EffectiveFrom, doc.EffectiveTo)
+}
+
+// datedPeriod formats an effective period, open while to is nil
+func datedPeriod(from time.Time, to *time.Time) string {
+	end := "open"
+	if to != nil {
+		end = to.Format("2006-01-02")
+	}
+	return from.Format("2006-01-02") + " to " + end
+}
+
+// openTermsDB opens the existing database given by --db-path
+func openTermsDB() (*sql.DB, error) {
+	if _, err := os.Stat(termsDBPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", termsDBPath)
+	}
+
+	db, err := database.Connect(termsDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
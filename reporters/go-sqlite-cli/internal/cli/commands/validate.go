@@ -0,0 +1,165 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	validateFile   string
+	validateDir    string
+	validateFormat string
+	validateStrict bool
+)
+
+// NewValidateCmd creates the validate command
+func NewValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check inspector CSV files without importing them",
+		Long: `Check inspector CSV files against the schema the importer expects, without
+opening the database. Gzip compressed files (.csv.gz) and the members of .zip
+and .tar.gz bundles are checked like plain files.
+
+The checks are:
+  - the file name pattern iwdli_output_<hostname>_<timestamp>.csv, or the
+    HOSTNAME field for files renamed in transfer
+  - the Parameter,Value header and two columns per row
+  - the required fields DETECTION_TIMESTAMP, CPU_COUNT and CONSIDERED_CPUS
+  - DETECTION_TIMESTAMP in RFC 3339 format
+  - the value domains of the known fields (yes/no, true/false, present/absent,
+    running/not-running, installed/not-installed, non-negative counts)
+  - CSV_FORMAT_VERSION and the product field names of that version
+
+Every issue is reported with its line and field. Errors are data the importer
+rejects or misreads; warnings are data it accepts but that does not follow the
+schema. The command exits with an error when a file has errors, or warnings
+with --strict, so it can gate inspector-side pipelines.
+
+Example:
+  iwdlr validate --file iwdli_output_myhost_2025-11-06_133525.csv
+  iwdlr validate --dir ./inspector-output --strict
+  iwdlr validate --dir ./inspector-output --format json`,
+		Args: cobra.NoArgs,
+		RunE: runValidate,
+	}
+
+	cmd.Flags().StringVar(&validateFile, "file", "",
+		"Path to a single inspector CSV file")
+	cmd.Flags().StringVar(&validateDir, "dir", "",
+		"Directory to search recursively for iwdli_output_*.csv files")
+	cmd.Flags().StringVarP(&validateFormat, "format", "f", "table",
+		"Output format: table or json")
+	cmd.Flags().BoolVar(&validateStrict, "strict", false,
+		"Fail on warnings as well as errors")
+
+	return cmd
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	if (validateFile == "") == (validateDir == "") {
+		return fmt.Errorf("one of --file or --dir must be specified")
+	}
+	if validateFormat != "table" && validateFormat != "json" {
+		return fmt.Errorf("unknown format: %s (use table or json)", validateFormat)
+	}
+
+	var files []string
+	if validateFile != "" {
+		if _, err := os.Stat(validateFile); err != nil {
+			return fmt.Errorf("file not found: %s", validateFile)
+		}
+		files = []string{validateFile}
+	} else {
+		var err error
+		files, err = importer.FindInspectorFiles(validateDir)
+		if err != nil {
+			return fmt.Errorf("failed to find inspector files: %w", err)
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("no inspector CSV files found in %s", validateDir)
+		}
+	}
+
+	// Bundles are checked member by member
+	files = importer.ExpandBundles(files)
+
+	results := make([]*importer.ValidationResult, 0, len(files))
+	failed := 0
+	for _, file := range files {
+		result := importer.ValidateCSVFile(file)
+		if !result.Valid() || (validateStrict && result.Warnings() > 0) {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	if validateFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		printValidationResults(results)
+	}
+
+	if failed > 0 {
+		cmd.SilenceUsage = true
+		return withExitCode(ExitValidation, fmt.Errorf("%d of %d files failed validation", failed, len(files)))
+	}
+	return nil
+}
+
+// printValidationResults writes one block per file with its issues, followed
+// by the totals
+func printValidationResults(results []*importer.ValidationResult) {
+	errorCount, warningCount := 0, 0
+	for _, result := range results {
+		name := displayPath(validateDir, result.File)
+		errorCount += result.Errors()
+		warningCount += result.Warnings()
+
+		if len(result.Issues) == 0 {
+			fmt.Printf("OK      %s (format %s, %d products)\n", name, result.FormatVersion, result.Products)
+			continue
+		}
+
+		status := "OK"
+		if !result.Valid() {
+			status = "FAILED"
+		}
+		fmt.Printf("%-7s %s: %d errors, %d warnings\n", status, name, result.Errors(), result.Warnings())
+		for _, issue := range result.Issues {
+			location := "-"
+			if issue.Line > 0 {
+				location = fmt.Sprintf("line %d", issue.Line)
+			}
+			field := issue.Field
+			if field == "" {
+				field = "-"
+			}
+			fmt.Printf("    %-9s %-8s %-36s %s\n", location, issue.Severity, field, issue.Message)
+		}
+	}
+
+	fmt.Printf("\n%d files checked: %d errors, %d warnings\n", len(results), errorCount, warningCount)
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/views"
+	"github.com/spf13/cobra"
+)
+
+var (
+	viewsDBPath string
+	viewsFile   string
+	viewsDryRun bool
+)
+
+// NewViewsCmd creates the views command
+func NewViewsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "views",
+		Short: "Install and list custom reporting views",
+		Long: `Install, list and drop custom reporting views: SQL views defined by the users of
+the database for their own reports and for 'iwdlr query'. Custom views are named
+with the vx_ prefix, apart from the built-in v_ views replaced by schema
+upgrades, so a schema upgrade keeps them.`,
+	}
+
+	cmd.PersistentFlags().StringVar(&viewsDBPath, "db-path", "data/license-monitor.db",
+		"Path to the SQLite database file")
+
+	install := &cobra.Command{
+		Use:   "install",
+		Short: "Install or update the custom views of an SQL file",
+		Long: `Install the views of an SQL file of CREATE VIEW statements, each view named with
+the vx_ prefix. A view already installed with another definition is replaced;
+views installed with the same definition are left as they are.
+
+The file is installed in one transaction: every view is queried once created,
+and so are the custom views already installed, which may read the views
+replaced. When a view fails, nothing is installed. --dry-run checks the file
+the same way without installing it.
+
+Example:
+  iwdlr views install --file custom_views.sql
+  iwdlr views install --file custom_views.sql --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: runViewsInstall,
+	}
+	install.Flags().StringVarP(&viewsFile, "file", "f", "", "SQL file of the CREATE VIEW statements (required)")
+	install.Flags().BoolVar(&viewsDryRun, "dry-run", false, "Check the views without installing them")
+	install.MarkFlagRequired("file")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the custom views",
+		Long: `List the custom views of the database with their columns, the file they were
+installed from and whether they can still be queried. Views created by hand with
+the vx_ prefix are listed too.
+
+Example:
+  iwdlr views list`,
+		Args: cobra.NoArgs,
+		RunE: runViewsList,
+	}
+
+	drop := &cobra.Command{
+		Use:   "drop <view>...",
+		Short: "Drop custom views",
+		Long: `Drop custom views. Only views named with the vx_ prefix can be dropped.
+
+Example:
+  iwdlr views drop vx_host_cores`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runViewsDrop,
+	}
+
+	cmd.AddCommand(install)
+	cmd.AddCommand(list)
+	cmd.AddCommand(drop)
+	return cmd
+}
+
+// openViewsDB opens the existing database given by --db-path
+func openViewsDB() (*sql.DB, error) {
+	if _, err := os.Stat(viewsDBPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("database does not exist at %s\nRun 'iwdlr init' first", viewsDBPath)
+	}
+
+	db, err := database.Connect(viewsDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
+
+func runViewsInstall(cmd *cobra.Command, args []string) error {
+	content, err := os.ReadFile(viewsFile)
+	if err != nil {
+		return fmt.Errorf("failed to read views file: %w", err)
+	}
+	parsed, err := views.ParseFile(string(content))
+	if err != nil {
+		return fmt.Errorf("%s: %w", viewsFile, err)
+	}
+
+	db, err := openViewsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	results, err := views.Install(cmd.Context(), db, parsed, viewsFile, viewsDryRun)
+	if err != nil {
+		return fmt.Errorf("views not installed: %w", err)
+	}
+
+	outcomes := map[string]string{views.Created: "Created", views.Updated: "Updated", views.Unchanged: "Unchanged"}
+	for _, result := range results {
+		fmt.Printf("%s view %s (%d columns)\n", outcomes[result.Outcome], result.Name, len(result.Columns))
+	}
+	if viewsDryRun {
+		fmt.Println("Dry run: no view installed")
+	}
+	return nil
+}
+
+func runViewsList(cmd *cobra.Command, args []string) error {
+	db, err := openViewsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	list, err := views.List(cmd.Context(), db)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Println("No custom views installed")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCOLUMNS\tSTATUS\tUPDATED\tSOURCE")
+	for _, view := range list {
+		status := "ok"
+		if view.Err != nil {
+			status = "broken: " + view.Err.Error()
+		}
+		updated, source := view.UpdatedAt, view.SourceFile
+		if view.InstalledAt == "" {
+			updated, source = "-", "(created by hand)"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", view.Name, len(view.Columns), status, updated, source)
+	}
+	return w.Flush()
+}
+
+func runViewsDrop(cmd *cobra.Command, args []string) error {
+	db, err := openViewsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := views.Drop(cmd.Context(), db, args); err != nil {
+		return err
+	}
+	for _, name := range args {
+		fmt.Printf("Dropped view %s\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+)
+
+func TestReportAll(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("IWLDR_DB", "")
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	outDir := filepath.Join(dir, "bundle")
+
+	db, err := database.Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+	stmts := []string{
+		`INSERT INTO license_terms (term_id, program_number, program_name) VALUES ('T1', '5900-AAA', 'Test Program')`,
+		`INSERT INTO product_codes (product_mnemo_code, ibm_product_code, product_name, mode, term_id)
+			VALUES ('IS_ONP_PRD', 'D0YYWZX', 'Integration Server', 'PROD', 'T1')`,
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES
+			('app01.example.com', 'app01', 'PROD'), ('app02.example.com', 'app02', 'PROD')`,
+	}
+	for _, m := range []struct {
+		fqdn, day string
+		cpus      int
+	}{
+		{"app01.example.com", "2025-08-05", 4},
+		{"app02.example.com", "2025-08-05", 8},
+		{"app01.example.com", "2025-08-06", 4},
+	} {
+		stmts = append(stmts,
+			fmt.Sprintf(`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count, is_virtualized,
+				virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus)
+				VALUES ('%s', '%s 08:00:00', 'Linux', '9', %d, 'no', '', 'unknown', 'true', 'true', 'true', %d)`,
+				m.fqdn, m.day, m.cpus, m.cpus),
+			fmt.Sprintf(`INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, install_count)
+				VALUES ('%s', 'IS_ONP_PRD', '%s 08:00:00', 'present', 1)`, m.fqdn, m.day))
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Exec failed: %v\n%s", err, stmt)
+		}
+	}
+	db.Close()
+
+	rootCmd.SetArgs([]string{"report", "all", "--db-path", dbPath, "--out-dir", outDir,
+		"--formats", "csv,json", "--from", "2025-08-01", "--to", "2025-08-31"})
+	defer rootCmd.SetArgs(nil)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("report all failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("Failed to read manifest.json: %v", err)
+	}
+	var manifest struct {
+		PeriodFrom string `json:"period_from"`
+		PeriodTo   string `json:"period_to"`
+		Files      []struct {
+			Report string `json:"report"`
+			Format string `json:"format"`
+			Name   string `json:"name"`
+			Rows   int    `json:"rows"`
+			SHA256 string `json:"sha256"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("Failed to parse manifest.json: %v", err)
+	}
+	if manifest.PeriodFrom != "2025-08-01" || manifest.PeriodTo != "2025-08-31" {
+		t.Errorf("Unexpected period %s to %s", manifest.PeriodFrom, manifest.PeriodTo)
+	}
+
+	// Every report in each format, and nothing else in the directory
+	var listed []string
+	rows := make(map[string]int)
+	for _, f := range manifest.Files {
+		listed = append(listed, f.Name)
+		rows[f.Name] = f.Rows
+
+		content, err := os.ReadFile(filepath.Join(outDir, f.Name))
+		if err != nil {
+			t.Errorf("%s is listed but missing: %v", f.Name, err)
+			continue
+		}
+		sum := sha256.Sum256(content)
+		if got := hex.EncodeToString(sum[:]); got != f.SHA256 {
+			t.Errorf("%s: checksum %s, manifest says %s", f.Name, got, f.SHA256)
+		}
+		if f.Format == "csv" {
+			records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+			if err != nil {
+				t.Fatalf("Failed to parse %s: %v", f.Name, err)
+			}
+			if len(records)-1 != f.Rows {
+				t.Errorf("%s: %d rows, manifest says %d", f.Name, len(records)-1, f.Rows)
+			}
+		}
+	}
+	var reports []string
+	for _, report := range []string{"daily-summary", "host-detail", "cores", "compliance", "monthly-peak", "peak",
+		"install-detail", "trend", "subcapacity", "cloud", "drift", "hosts", "imports"} {
+		reports = append(reports, report+".csv", report+".json")
+	}
+	sort.Strings(listed)
+	sort.Strings(reports)
+	if got, want := strings.Join(listed, " "), strings.Join(reports, " "); got != want {
+		t.Errorf("Manifest lists %s, want %s", got, want)
+	}
+	entries, err := os.ReadDir(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(reports)+1 {
+		t.Errorf("Expected %d files and manifest.json in %s, found %d", len(reports), outDir, len(entries))
+	}
+
+	// One compliance row per day, one monthly peak for the product
+	for name, want := range map[string]int{"compliance.csv": 2, "monthly-peak.csv": 1, "monthly-peak.json": 1, "subcapacity.csv": 3} {
+		if rows[name] != want {
+			t.Errorf("%s: %d rows, want %d", name, rows[name], want)
+		}
+	}
+}
@@ -15,12 +15,25 @@
 package cli
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/cli/commands"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/config"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/tracing"
 	"github.com/spf13/cobra"
 )
 
 var (
-	dbFile string
+	dbFile     string
+	configFile string
+
+	// commandSpan traces the command being run while tracing is enabled
+	commandSpan *tracing.Span
 )
 
 var rootCmd = &cobra.Command{
@@ -32,28 +45,146 @@ Supports database initialization, CSV import, and compliance reporting.
 The tool provides commands for:
 - Initializing a new database with complete schema
 - Importing inspector CSV files
+- Checking inspector CSV files without importing them (validate)
 - Generating license compliance reports
-- Querying measurement data`,
+- Renaming and merging physical host IDs
+- Reporting the usage and entitlements of several organizations (orgs)
+- Keeping one measurement history for renamed nodes (landscape alias)
+- Loading the landscape nodes from a CMDB export (landscape import)
+- Reading the ESXi hosts and VM placement from vCenter (sync vcenter)
+- Flagging suspicious changes between measurements (analyze)
+- Running read-only SQL statements (query)
+- Installing custom reporting views (views)
+- Deriving the eligibility from versioned rules files (eligibility)
+- Exporting the detected products to Flexera and ServiceNow SAM (export sam)
+- Running scheduled imports and reports (daemon)
+- Queueing imports, cache refreshes and reports for a background worker (jobs)
+- Checking the database for monitoring agents (health)
+- Measuring the performance of imports and reporting views (bench)
+- Serving a read-only web dashboard
+- Querying measurement data
+
+The database is given by --database (or --db-path), else the IWLDR_DB
+environment variable, else the configuration file, else data/license-monitor.db.
+
+Defaults for the database path, report format, output directory, product filter,
+collection sources and the daemon schedule can be kept in ~/.iwldr.yaml or the
+file given by --config. Flags given on the command line override the file.
+
+Errors exit with a code of their kind: 2 when the command line or an input
+file cannot be parsed, 3 for database errors, 4 when input files fail
+validation, 5 for compliance breaches (report compliance --fail-on-breach), 6
+when a health check fails and 1 otherwise. --status-json on import and report
+commands prints a JSON summary of the result to stderr.
+
+Setting OTEL_EXPORTER_OTLP_ENDPOINT traces the command, its file imports and
+report queries as OpenTelemetry spans exported with OTLP over HTTP (JSON).`,
+	PersistentPreRunE: loadConfig,
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&dbFile, "database", "d", "data/default.db", "SQLite database file path")
+	rootCmd.PersistentFlags().StringVarP(&dbFile, "database", "d", "",
+		"SQLite database file path, the same as --db-path (default: $"+commands.DBPathEnv+", then db-path of the configuration file, then "+commands.DefaultDBPath+")")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Configuration file (default: ~/.iwldr.yaml if it exists)")
+	rootCmd.SetFlagErrorFunc(commands.FlagError)
 
 	// Register commands
 	rootCmd.AddCommand(commands.NewInitCmd())
 	rootCmd.AddCommand(commands.NewImportCmd())
+	rootCmd.AddCommand(commands.NewValidateCmd())
+	rootCmd.AddCommand(commands.NewCollectCmd())
 	rootCmd.AddCommand(commands.NewReportCmd())
+	rootCmd.AddCommand(commands.NewHostsCmd())
+	rootCmd.AddCommand(commands.NewSitesCmd())
+	rootCmd.AddCommand(commands.NewOrgsCmd())
+	rootCmd.AddCommand(commands.NewLandscapeCmd())
+	rootCmd.AddCommand(commands.NewSyncCmd())
+	rootCmd.AddCommand(commands.NewTermsCmd())
+	rootCmd.AddCommand(commands.NewReferenceCmd())
+	rootCmd.AddCommand(commands.NewExportCmd())
+	rootCmd.AddCommand(commands.NewAnalyzeCmd())
+	rootCmd.AddCommand(commands.NewQueryCmd())
+	rootCmd.AddCommand(commands.NewDBCmd())
+	rootCmd.AddCommand(commands.NewViewsCmd())
+	rootCmd.AddCommand(commands.NewEligibilityCmd())
+	rootCmd.AddCommand(commands.NewSnapshotCmd())
+	rootCmd.AddCommand(commands.NewDaemonCmd())
+	rootCmd.AddCommand(commands.NewServeCmd())
+	rootCmd.AddCommand(commands.NewJobsCmd())
+	rootCmd.AddCommand(commands.NewHealthCmd())
+	rootCmd.AddCommand(commands.NewBenchCmd())
+}
+
+// loadConfig applies the configuration file to the flags of the command being run
+func loadConfig(cmd *cobra.Command, args []string) error {
+	startTracing(cmd)
+
+	var cfg *config.Config
+	var err error
+	if configFile != "" {
+		cfg, err = config.Load(configFile)
+	} else {
+		cfg, err = config.LoadDefault()
+	}
+	if err != nil {
+		return err
+	}
+
+	return commands.ApplyConfig(cmd, cfg)
 }
 
-// Execute runs the root command
+// startTracing enables tracing when an OTLP endpoint is configured and starts
+// the span of the command. A tracing misconfiguration never stops the command.
+func startTracing(cmd *cobra.Command) {
+	enabled, err := tracing.Init()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Tracing disabled: %v\n", err)
+		return
+	}
+	if enabled {
+		commandSpan = tracing.Start(cmd.CommandPath())
+	}
+}
+
+// Execute runs the root command. With --status-json, the result summary of
+// the command is written to stderr; report runs of the daemon, the job queue
+// and --record are recorded in the database. Ctrl-C or SIGTERM cancels the
+// context of the command, so that its imports and queries are rolled back; a
+// second one stops the process at once. The reports of the report definitions
+// directory are added to the report command first.
 func Execute() error {
-	return rootCmd.Execute()
+	commands.AddReportPlugins(commands.ReportsDir(os.Args[1:]))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	startedAt := time.Now()
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	if err != nil && ctx.Err() != nil {
+		err = fmt.Errorf("interrupted: %w", err)
+	}
+	commandSpan.End(err)
+	tracing.Shutdown()
+	if statusErr := commands.WriteStatus(os.Stderr, cmd, err, startedAt); statusErr != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Failed to write the status: %v\n", statusErr)
+	}
+	if recordErr := commands.RecordReportRun(cmd, err, startedAt); recordErr != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Failed to record the report run: %v\n", recordErr)
+	}
+	return err
 }
 
-// GetDBFile returns the configured database file path
+// ExitCode returns the process exit code of the error returned by Execute
+func ExitCode(err error) int {
+	return commands.ExitCode(err)
+}
+
+// GetDBFile returns the database file path given by --database or IWLDR_DB,
+// without the configuration file
 func GetDBFile() string {
-	if dbFile == "" {
-		return "data/default.db"
-	}
-	return dbFile
+	return commands.ResolveDBPath(dbFile, "")
 }
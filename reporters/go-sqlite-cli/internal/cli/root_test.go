@@ -15,7 +15,16 @@
 package cli
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/cli/commands"
 )
 
 func TestGetDBFile(t *testing.T) {
@@ -27,7 +36,7 @@ func TestGetDBFile(t *testing.T) {
 		{
 			name:     "default database file when dbFile is empty",
 			dbFile:   "",
-			expected: "data/default.db",
+			expected: "data/license-monitor.db",
 		},
 		{
 			name:     "custom database file",
@@ -36,6 +45,7 @@ func TestGetDBFile(t *testing.T) {
 		},
 	}
 
+	t.Setenv("IWLDR_DB", "")
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Set the global dbFile variable
@@ -51,3 +61,80 @@ func TestGetDBFile(t *testing.T) {
 		})
 	}
 }
+
+func TestDatabasePrecedence(t *testing.T) {
+	originalDBFile := dbFile
+	defer func() { dbFile = originalDBFile }()
+
+	t.Setenv("IWLDR_DB", "env.db")
+	dbFile = ""
+	if got := GetDBFile(); got != "env.db" {
+		t.Errorf("GetDBFile() = %v, want the IWLDR_DB path env.db", got)
+	}
+	dbFile = "flag.db"
+	if got := GetDBFile(); got != "flag.db" {
+		t.Errorf("GetDBFile() = %v, want the --database path flag.db", got)
+	}
+
+	rootCmd.SetArgs([]string{"db", "daily-aggregation", "--database", "a.db", "--db-path", "b.db"})
+	defer rootCmd.SetArgs(nil)
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "different databases") {
+		t.Errorf("Expected conflicting --database and --db-path to be rejected, got %v", err)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	badFile := filepath.Join(dir, "iwdli_output_bad_20251021_090906.csv")
+	if err := os.WriteFile(badFile, []byte("garbage\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missingDB := filepath.Join(dir, "missing.db")
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"unknown flag", []string{"report", "peak", "--no-such-flag"}, commands.ExitParse},
+		{"missing database", []string{"report", "peak", "--db-path", missingDB}, commands.ExitDatabase},
+		{"invalid file", []string{"validate", "--file", badFile}, commands.ExitValidation},
+	}
+	defer rootCmd.SetArgs(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rootCmd.SetArgs(tt.args)
+			err := rootCmd.Execute()
+			if code := ExitCode(err); code != tt.code {
+				t.Errorf("Expected exit code %d, got %d (%v)", tt.code, code, err)
+			}
+		})
+	}
+
+	if ExitCode(nil) != commands.ExitOK || ExitCode(errors.New("failed")) != commands.ExitError {
+		t.Error("Expected exit code 0 without error and 1 for other errors")
+	}
+
+	// The status summary names the outcome and the report
+	rootCmd.SetArgs([]string{"report", "peak", "--db-path", missingDB, "--status-json"})
+	cmd, err := rootCmd.ExecuteC()
+	var out bytes.Buffer
+	if err := commands.WriteStatus(&out, cmd, err, time.Now()); err != nil {
+		t.Fatalf("WriteStatus failed: %v", err)
+	}
+	var status struct {
+		Command  string `json:"command"`
+		Status   string `json:"status"`
+		ExitCode int    `json:"exit_code"`
+		Report   struct {
+			Report string `json:"report"`
+		} `json:"report"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &status); err != nil {
+		t.Fatalf("Expected a JSON status, got %q: %v", out.String(), err)
+	}
+	if status.Command != "iwldr report peak" || status.Status != "database_error" || status.ExitCode != commands.ExitDatabase || status.Report.Report != "peak" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+}
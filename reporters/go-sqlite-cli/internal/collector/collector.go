@@ -0,0 +1,277 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+	"github.com/pkg/sftp"
+)
+
+// Collector downloads new inspector files from collection sources and tracks
+// per source which remote files were already collected
+type Collector struct {
+	db          *sql.DB
+	downloadDir string
+}
+
+// NewCollector creates a collector storing downloads below downloadDir/<source-name>
+func NewCollector(db *sql.DB, downloadDir string) *Collector {
+	return &Collector{db: db, downloadDir: downloadDir}
+}
+
+// CollectResult contains the outcome of collecting from one source
+type CollectResult struct {
+	Source     string
+	Downloaded []string // Local paths of new or changed files
+	Unchanged  int      // Remote files already collected by an earlier run
+}
+
+// Collect connects to the source over SFTP and downloads new inspector files
+func (c *Collector) Collect(source Source, auth AuthConfig) (*CollectResult, error) {
+	if err := c.startRun(source); err != nil {
+		return nil, err
+	}
+
+	sshClient, client, err := dial(source, auth)
+	if err != nil {
+		c.finishRun(source, nil, err)
+		return nil, err
+	}
+	defer sshClient.Close()
+	defer client.Close()
+
+	return c.collect(source, client)
+}
+
+// CollectFrom downloads new inspector files from an already connected SFTP client
+func (c *Collector) CollectFrom(source Source, client *sftp.Client) (*CollectResult, error) {
+	if err := c.startRun(source); err != nil {
+		return nil, err
+	}
+	return c.collect(source, client)
+}
+
+// collect walks the remote directory and downloads files that are new or whose
+// size or modification time changed since they were collected
+func (c *Collector) collect(source Source, client *sftp.Client) (*CollectResult, error) {
+	result := &CollectResult{Source: source.Name}
+
+	walker := client.Walk(source.RemoteDir)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			err = fmt.Errorf("failed to list %s: %w", walker.Path(), err)
+			c.finishRun(source, result, err)
+			return result, err
+		}
+
+		info := walker.Stat()
+		if info.IsDir() || !(importer.IsInspectorFileName(info.Name()) || importer.IsBundleName(info.Name())) {
+			continue
+		}
+
+		remotePath := walker.Path()
+		modTime := info.ModTime().UTC()
+
+		collected, err := c.isCollected(source.Name, remotePath, info.Size(), modTime)
+		if err != nil {
+			c.finishRun(source, result, err)
+			return result, err
+		}
+		if collected {
+			result.Unchanged++
+			continue
+		}
+
+		localPath, err := c.download(source, client, remotePath, modTime)
+		if err != nil {
+			c.finishRun(source, result, err)
+			return result, err
+		}
+		if err := c.recordCollected(source.Name, remotePath, info.Size(), modTime, localPath); err != nil {
+			c.finishRun(source, result, err)
+			return result, err
+		}
+		result.Downloaded = append(result.Downloaded, localPath)
+	}
+
+	c.finishRun(source, result, nil)
+	return result, nil
+}
+
+// download copies a remote file below the source download directory, keeping the
+// remote directory structure. The file is written under a temporary name first so
+// a partial download is never picked up by an import.
+func (c *Collector) download(source Source, client *sftp.Client, remotePath string, modTime time.Time) (string, error) {
+	// Remote paths always use forward slashes; cleaning against "/" keeps the
+	// relative path inside the download directory
+	rel := strings.TrimPrefix(remotePath, strings.TrimSuffix(source.RemoteDir, "/")+"/")
+	rel = path.Clean("/" + rel)[1:]
+	localPath := filepath.Join(c.downloadDir, source.Name, filepath.FromSlash(rel))
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create download directory: %w", err)
+	}
+
+	remote, err := client.Open(remotePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", remotePath, err)
+	}
+	defer remote.Close()
+
+	tmpPath := localPath + ".part"
+	local, err := os.Create(tmpPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", tmpPath, err)
+	}
+	if _, err := io.Copy(local, remote); err != nil {
+		local.Close()
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to download %s: %w", remotePath, err)
+	}
+	if err := local.Close(); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := os.Chtimes(tmpPath, modTime, modTime); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to set modification time of %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, localPath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to move %s into place: %w", localPath, err)
+	}
+
+	return localPath, nil
+}
+
+// isCollected reports whether the remote file was already collected unchanged
+func (c *Collector) isCollected(sourceName, remotePath string, size int64, modTime time.Time) (bool, error) {
+	var storedSize int64
+	var storedModTime time.Time
+	err := c.db.QueryRow(`
+		SELECT remote_size, remote_mtime FROM collected_files
+		WHERE source_name = ? AND remote_path = ?
+	`, sourceName, remotePath).Scan(&storedSize, &storedModTime)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check collected file %s: %w", remotePath, err)
+	}
+
+	return storedSize == size && storedModTime.Equal(modTime), nil
+}
+
+// recordCollected stores a downloaded file in the collected_files table
+func (c *Collector) recordCollected(sourceName, remotePath string, size int64, modTime time.Time, localPath string) error {
+	_, err := c.db.Exec(`
+		INSERT INTO collected_files (source_name, remote_path, remote_size, remote_mtime, local_path)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(source_name, remote_path) DO UPDATE SET
+			remote_size = excluded.remote_size,
+			remote_mtime = excluded.remote_mtime,
+			local_path = excluded.local_path,
+			collected_at = CURRENT_TIMESTAMP
+	`, sourceName, remotePath, size, modTime, localPath)
+	if err != nil {
+		return fmt.Errorf("failed to record collected file %s: %w", remotePath, err)
+	}
+
+	return nil
+}
+
+// startRun registers the source and the start of a collection run
+func (c *Collector) startRun(source Source) error {
+	_, err := c.db.Exec(`
+		INSERT INTO collection_sources (source_name, address, remote_dir, last_run_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(source_name) DO UPDATE SET
+			address = excluded.address,
+			remote_dir = excluded.remote_dir,
+			last_run_at = excluded.last_run_at
+	`, source.Name, source.Address, source.RemoteDir)
+	if err != nil {
+		return fmt.Errorf("failed to record collection run for %s: %w", source.Name, err)
+	}
+
+	return nil
+}
+
+// finishRun stores the outcome of a collection run. Files downloaded before an
+// error are counted, since they were recorded as collected.
+func (c *Collector) finishRun(source Source, result *CollectResult, runErr error) {
+	downloaded := 0
+	if result != nil {
+		downloaded = len(result.Downloaded)
+	}
+
+	if runErr != nil {
+		c.db.Exec(`
+			UPDATE collection_sources
+			SET last_error = ?, files_collected = files_collected + ?
+			WHERE source_name = ?
+		`, runErr.Error(), downloaded, source.Name)
+		return
+	}
+
+	c.db.Exec(`
+		UPDATE collection_sources
+		SET last_success_at = CURRENT_TIMESTAMP, last_error = '', files_collected = files_collected + ?
+		WHERE source_name = ?
+	`, downloaded, source.Name)
+}
+
+// ListSources returns the collection state of every source that was ever collected
+func (c *Collector) ListSources() ([]models.CollectionSource, error) {
+	rows, err := c.db.Query(`
+		SELECT source_name, address, remote_dir, last_run_at, last_success_at,
+			COALESCE(last_error, ''), files_collected
+		FROM collection_sources
+		ORDER BY source_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query collection sources: %w", err)
+	}
+	defer rows.Close()
+
+	var sources []models.CollectionSource
+	for rows.Next() {
+		var s models.CollectionSource
+		var lastRun, lastSuccess sql.NullTime
+		if err := rows.Scan(&s.SourceName, &s.Address, &s.RemoteDir, &lastRun, &lastSuccess,
+			&s.LastError, &s.FilesCollected); err != nil {
+			return nil, fmt.Errorf("failed to scan collection source: %w", err)
+		}
+		if lastRun.Valid {
+			s.LastRunAt = &lastRun.Time
+		}
+		if lastSuccess.Valid {
+			s.LastSuccessAt = &lastSuccess.Time
+		}
+		sources = append(sources, s)
+	}
+
+	return sources, rows.Err()
+}
@@ -0,0 +1,163 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/collector"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/pkg/sftp"
+)
+
+// startSFTP serves the local filesystem over an in-process SFTP connection
+func startSFTP(t *testing.T) *sftp.Client {
+	t.Helper()
+
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	server, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverReader, serverWriter})
+	if err != nil {
+		t.Fatalf("Failed to create SFTP server: %v", err)
+	}
+	go server.Serve()
+
+	client, err := sftp.NewClientPipe(clientReader, clientWriter)
+	if err != nil {
+		t.Fatalf("Failed to create SFTP client: %v", err)
+	}
+	t.Cleanup(func() {
+		// Closing the server ends the client's read loop, so it must go first
+		server.Close()
+		client.Close()
+	})
+
+	return client
+}
+
+func writeRemote(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set time of %s: %v", path, err)
+	}
+}
+
+func TestCollectDownloadsOnlyNewFiles(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	remoteDir := t.TempDir()
+	downloadDir := t.TempDir()
+	modTime := time.Date(2025, 10, 21, 9, 9, 6, 0, time.UTC)
+
+	writeRemote(t, filepath.Join(remoteDir, "iwdli_output_host1_20251021_090906.csv"), "one", modTime)
+	writeRemote(t, filepath.Join(remoteDir, "2025-10-21", "iwdli_output_host2_20251021_090906.csv"), "two", modTime)
+	writeRemote(t, filepath.Join(remoteDir, "notes.txt"), "ignored", modTime)
+
+	source := collector.Source{Name: "drop", Address: "drop.example.com", User: "iwldr", RemoteDir: remoteDir}
+	c := collector.NewCollector(db, downloadDir)
+	client := startSFTP(t)
+
+	// First run downloads both inspector files, keeping the directory layout
+	result, err := c.CollectFrom(source, client)
+	if err != nil {
+		t.Fatalf("CollectFrom failed: %v", err)
+	}
+	if len(result.Downloaded) != 2 || result.Unchanged != 0 {
+		t.Fatalf("Expected 2 downloads, got %d (unchanged %d)", len(result.Downloaded), result.Unchanged)
+	}
+	local := filepath.Join(downloadDir, "drop", "2025-10-21", "iwdli_output_host2_20251021_090906.csv")
+	if data, err := os.ReadFile(local); err != nil || string(data) != "two" {
+		t.Errorf("Expected downloaded file %s with content 'two', got %q (%v)", local, data, err)
+	}
+
+	// Second run finds nothing new
+	result, err = c.CollectFrom(source, client)
+	if err != nil {
+		t.Fatalf("CollectFrom failed: %v", err)
+	}
+	if len(result.Downloaded) != 0 || result.Unchanged != 2 {
+		t.Errorf("Expected no downloads and 2 unchanged, got %d / %d", len(result.Downloaded), result.Unchanged)
+	}
+
+	// A changed file is downloaded again
+	writeRemote(t, filepath.Join(remoteDir, "iwdli_output_host1_20251021_090906.csv"), "one, updated", modTime.Add(time.Hour))
+	result, err = c.CollectFrom(source, client)
+	if err != nil {
+		t.Fatalf("CollectFrom failed: %v", err)
+	}
+	if len(result.Downloaded) != 1 {
+		t.Errorf("Expected the changed file to be downloaded, got %v", result.Downloaded)
+	}
+
+	sources, err := c.ListSources()
+	if err != nil {
+		t.Fatalf("ListSources failed: %v", err)
+	}
+	if len(sources) != 1 || sources[0].FilesCollected != 3 || sources[0].LastSuccessAt == nil || sources[0].LastError != "" {
+		t.Errorf("Unexpected collection state: %+v", sources)
+	}
+}
+
+func TestLoadSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sources.csv")
+	content := `source-name,address,user,identity-file,remote-dir
+# nightly drop server
+drop,drop.example.com,iwldr,/home/iwldr/.ssh/id_ed25519,/data/inspector
+aix1,aix1.example.com:2222,inspect,,/opt/iwdli/output
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write sources: %v", err)
+	}
+
+	sources, err := collector.LoadSources(path)
+	if err != nil {
+		t.Fatalf("LoadSources failed: %v", err)
+	}
+	if len(sources) != 2 {
+		t.Fatalf("Expected 2 sources, got %d", len(sources))
+	}
+	if sources[0].HostPort() != "drop.example.com:22" || sources[1].HostPort() != "aix1.example.com:2222" {
+		t.Errorf("Unexpected addresses: %s, %s", sources[0].HostPort(), sources[1].HostPort())
+	}
+
+	duplicate := content + "drop,other.example.com,iwldr,,/data\n"
+	if err := os.WriteFile(path, []byte(duplicate), 0644); err != nil {
+		t.Fatalf("Failed to write sources: %v", err)
+	}
+	if _, err := collector.LoadSources(path); err == nil {
+		t.Error("Expected an error for a duplicate source name")
+	}
+}
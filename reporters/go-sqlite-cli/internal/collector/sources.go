@@ -0,0 +1,107 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// Source is a remote host or drop server that inspector files are collected from
+type Source struct {
+	Name         string // Unique name, used to track collection state
+	Address      string // host or host:port (default port 22)
+	User         string
+	IdentityFile string // Private key file; empty to rely on IWLDR_SFTP_PASSWORD
+	RemoteDir    string // Directory scanned recursively for iwdli_output_*.csv files
+}
+
+// HostPort returns the address with the default SSH port added when missing
+func (s Source) HostPort() string {
+	if _, _, err := net.SplitHostPort(s.Address); err == nil {
+		return s.Address
+	}
+	return net.JoinHostPort(s.Address, "22")
+}
+
+var sourcesHeader = []string{"source-name", "address", "user", "identity-file", "remote-dir"}
+
+// LoadSources reads the collection sources from a CSV file
+// CSV format: source-name,address,user,identity-file,remote-dir
+func LoadSources(filePath string) ([]Source, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1 // Allow variable number of fields
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if len(header) != len(sourcesHeader) {
+		return nil, fmt.Errorf("invalid CSV header, expected: %v", sourcesHeader)
+	}
+	for i := range header {
+		if strings.TrimSpace(header[i]) != sourcesHeader[i] {
+			return nil, fmt.Errorf("invalid CSV header, expected: %v", sourcesHeader)
+		}
+	}
+
+	var sources []Source
+	seen := make(map[string]bool)
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read row: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		if len(row) < len(sourcesHeader) {
+			return nil, fmt.Errorf("line %d: expected %d fields, got %d", line, len(sourcesHeader), len(row))
+		}
+
+		source := Source{
+			Name:         strings.TrimSpace(row[0]),
+			Address:      strings.TrimSpace(row[1]),
+			User:         strings.TrimSpace(row[2]),
+			IdentityFile: strings.TrimSpace(row[3]),
+			RemoteDir:    strings.TrimSpace(row[4]),
+		}
+		if source.Name == "" || source.Address == "" || source.User == "" || source.RemoteDir == "" {
+			return nil, fmt.Errorf("line %d: source-name, address, user and remote-dir are required", line)
+		}
+		if seen[source.Name] {
+			return nil, fmt.Errorf("line %d: duplicate source name %s", line, source.Name)
+		}
+		seen[source.Name] = true
+
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// PasswordEnvVar is the environment variable holding the SFTP password used
+// for sources without an identity file
+const PasswordEnvVar = "IWLDR_SFTP_PASSWORD"
+
+// AuthConfig holds the SSH settings shared by all collection sources
+type AuthConfig struct {
+	KnownHostsFile        string
+	InsecureIgnoreHostKey bool // Skip host key verification (testing only)
+	Timeout               time.Duration
+}
+
+// dial opens an SSH connection to the source and starts an SFTP session on it
+func dial(source Source, auth AuthConfig) (*ssh.Client, *sftp.Client, error) {
+	config, err := clientConfig(source, auth)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sshClient, err := ssh.Dial("tcp", source.HostPort(), config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to %s: %w", source.HostPort(), err)
+	}
+
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, nil, fmt.Errorf("failed to start SFTP session on %s: %w", source.HostPort(), err)
+	}
+
+	return sshClient, client, nil
+}
+
+// clientConfig builds the SSH client configuration: key authentication when the
+// source has an identity file, otherwise password authentication from PasswordEnvVar
+func clientConfig(source Source, auth AuthConfig) (*ssh.ClientConfig, error) {
+	var methods []ssh.AuthMethod
+	if source.IdentityFile != "" {
+		key, err := os.ReadFile(source.IdentityFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read identity file: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse identity file %s: %w", source.IdentityFile, err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	} else if password := os.Getenv(PasswordEnvVar); password != "" {
+		methods = append(methods, ssh.Password(password))
+	} else {
+		return nil, fmt.Errorf("source %s has no identity-file and %s is not set", source.Name, PasswordEnvVar)
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	if auth.InsecureIgnoreHostKey {
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	} else {
+		callback, err := knownhosts.New(auth.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load known hosts: %w", err)
+		}
+		hostKeyCallback = callback
+	}
+
+	return &ssh.ClientConfig{
+		User:            source.User,
+		Auth:            methods,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         auth.Timeout,
+	}, nil
+}
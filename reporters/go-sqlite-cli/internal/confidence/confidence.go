@@ -0,0 +1,322 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package confidence raises or lowers the confidence of physical host IDs
+// reported by the inspectors with rules based on corroborating evidence: VMs
+// agreeing on the host, the host known to vCenter, or a manual confirmation.
+package confidence
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Confidence levels of a physical host ID, from the lowest to the highest
+const (
+	Low    = "low"
+	Medium = "medium"
+	High   = "high"
+)
+
+// Evidence a rule is based on
+const (
+	// EvidenceVMAgreement holds when at least MinVMs nodes report the host
+	// ID, all with the same physical cores
+	EvidenceVMAgreement = "vm-agreement"
+	// EvidenceCoreConflict holds when the nodes of the host report different
+	// physical cores
+	EvidenceCoreConflict = "core-conflict"
+	// EvidenceVCenter holds when the host is listed in hypervisor_hosts, by
+	// 'sync vcenter' or 'import host-capacity'
+	EvidenceVCenter = "vcenter"
+	// EvidenceManual holds when the host was confirmed with 'hosts confirm'
+	EvidenceManual = "manual"
+)
+
+// DefaultMinVMs is the number of agreeing nodes of a vm-agreement rule without min-vms
+const DefaultMinVMs = 2
+
+// Rule sets the confidence of the hosts its evidence holds for
+type Rule struct {
+	Name     string
+	Evidence string
+	MinVMs   int // vm-agreement only
+	Set      string
+}
+
+// DefaultRules are applied when the configuration file lists no rules
+var DefaultRules = []Rule{
+	{Name: "vms-agree", Evidence: EvidenceVMAgreement, MinVMs: 3, Set: Medium},
+	{Name: "vcenter", Evidence: EvidenceVCenter, Set: High},
+	{Name: "cores-disagree", Evidence: EvidenceCoreConflict, Set: Low},
+	{Name: "confirmed", Evidence: EvidenceManual, Set: High},
+}
+
+// Rank orders the confidence levels: 0 for low or unknown, 1 for medium and 2
+// for high
+func Rank(level string) int {
+	switch level {
+	case High:
+		return 2
+	case Medium:
+		return 1
+	}
+	return 0
+}
+
+// IsLevel reports whether level is a known confidence level
+func IsLevel(level string) bool {
+	switch level {
+	case Low, Medium, High:
+		return true
+	}
+	return false
+}
+
+// IsEvidence reports whether name is a known evidence type
+func IsEvidence(name string) bool {
+	switch name {
+	case EvidenceVMAgreement, EvidenceCoreConflict, EvidenceVCenter, EvidenceManual:
+		return true
+	}
+	return false
+}
+
+// Evaluation is the confidence of a physical host after the rules
+type Evaluation struct {
+	PhysicalHostID string   `json:"physical_host_id"`
+	Nodes          int      `json:"nodes"`
+	HostCores      []int    `json:"host_cores"`          // distinct physical cores reported by the nodes
+	Reported       string   `json:"reported_confidence"` // lowest confidence reported by the nodes
+	Confidence     string   `json:"confidence"`
+	Rule           string   `json:"rule"` // last rule whose evidence holds, empty when none
+	Evidence       []string `json:"evidence"`
+}
+
+// Changed reports whether the rules changed the reported confidence
+func (e Evaluation) Changed() bool {
+	return e.Confidence != e.Reported
+}
+
+// Engine evaluates the rules against the latest measurement of each node
+type Engine struct {
+	db    *sql.DB
+	rules []Rule
+}
+
+// NewEngine creates a rules engine; DefaultRules apply when rules is empty
+func NewEngine(db *sql.DB, rules []Rule) *Engine {
+	if len(rules) == 0 {
+		rules = DefaultRules
+	}
+	return &Engine{db: db, rules: rules}
+}
+
+// host is the evidence gathered for a physical host
+type host struct {
+	nodes     int
+	reported  string
+	cores     map[int]bool
+	unknown   bool // a node does not report the host cores
+	vcenter   bool
+	confirmed bool
+}
+
+// holds reports whether the evidence of rule holds for h
+func (h *host) holds(rule Rule) bool {
+	switch rule.Evidence {
+	case EvidenceVMAgreement:
+		minVMs := rule.MinVMs
+		if minVMs <= 0 {
+			minVMs = DefaultMinVMs
+		}
+		return h.nodes >= minVMs && len(h.cores) == 1 && !h.unknown
+	case EvidenceCoreConflict:
+		return len(h.cores) > 1
+	case EvidenceVCenter:
+		return h.vcenter
+	case EvidenceManual:
+		return h.confirmed
+	}
+	return false
+}
+
+// Evaluate applies the rules, in order, to the physical host of the latest
+// measurement of each virtualized node; the last rule whose evidence holds
+// sets the confidence of the host. Results are sorted by host ID.
+func (e *Engine) Evaluate() ([]Evaluation, error) {
+	rows, err := e.db.Query(`
+		SELECT physical_host_id, COALESCE(host_id_confidence, ''), COALESCE(host_physical_cpus, '')
+		FROM v_latest_measurements
+		WHERE physical_host_id != '' AND physical_host_id != 'unknown' AND is_virtualized != 'no'
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the latest measurements: %w", err)
+	}
+	hosts := make(map[string]*host)
+	for rows.Next() {
+		var hostID, reported, cores string
+		if err := rows.Scan(&hostID, &reported, &cores); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan measurement: %w", err)
+		}
+		h := hosts[hostID]
+		if h == nil {
+			h = &host{reported: High, cores: make(map[int]bool)}
+			hosts[hostID] = h
+		}
+		h.nodes++
+		if !IsLevel(reported) {
+			reported = Low
+		}
+		if Rank(reported) < Rank(h.reported) {
+			h.reported = reported
+		}
+		var n int
+		if _, err := fmt.Sscanf(strings.TrimSpace(cores), "%d", &n); err == nil && n > 0 {
+			h.cores[n] = true
+		} else {
+			h.unknown = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := e.mark(hosts, "SELECT physical_host_id FROM hypervisor_hosts", func(h *host) { h.vcenter = true }); err != nil {
+		return nil, err
+	}
+	if err := e.mark(hosts, "SELECT physical_host_id FROM host_confirmations", func(h *host) { h.confirmed = true }); err != nil {
+		return nil, err
+	}
+
+	results := make([]Evaluation, 0, len(hosts))
+	for hostID, h := range hosts {
+		eval := Evaluation{
+			PhysicalHostID: hostID,
+			Nodes:          h.nodes,
+			Reported:       h.reported,
+			Confidence:     h.reported,
+		}
+		for cores := range h.cores {
+			eval.HostCores = append(eval.HostCores, cores)
+		}
+		sort.Ints(eval.HostCores)
+		for _, rule := range e.rules {
+			if h.holds(rule) {
+				eval.Confidence = rule.Set
+				eval.Rule = rule.Name
+				eval.Evidence = append(eval.Evidence, rule.Evidence)
+			}
+		}
+		results = append(results, eval)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].PhysicalHostID < results[j].PhysicalHostID })
+	return results, nil
+}
+
+// mark calls set for the hosts whose ID query returns
+func (e *Engine) mark(hosts map[string]*host, query string, set func(*host)) error {
+	rows, err := e.db.Query(query)
+	if err != nil {
+		return fmt.Errorf("failed to query host evidence: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hostID string
+		if err := rows.Scan(&hostID); err != nil {
+			return fmt.Errorf("failed to scan host evidence: %w", err)
+		}
+		if h := hosts[hostID]; h != nil {
+			set(h)
+		}
+	}
+	return rows.Err()
+}
+
+// Apply replaces host_confidence_escalations with the evaluations that changed
+// the reported confidence and returns their number. The measurements keep the
+// confidence the inspectors reported, so the rules can be evaluated again.
+func (e *Engine) Apply(evaluations []Evaluation) (int, error) {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM host_confidence_escalations"); err != nil {
+		return 0, fmt.Errorf("failed to clear the host confidence escalations: %w", err)
+	}
+
+	now := time.Now().UTC()
+	applied := 0
+	for _, eval := range evaluations {
+		if !eval.Changed() {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO host_confidence_escalations
+			(physical_host_id, reported_confidence, confidence, rule, evidence, evaluated_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, eval.PhysicalHostID, eval.Reported, eval.Confidence, eval.Rule, strings.Join(eval.Evidence, ","), now)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record the confidence of %s: %w", eval.PhysicalHostID, err)
+		}
+		applied++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return applied, nil
+}
+
+// Confirm records a manual confirmation of a physical host ID, the evidence
+// of the manual rules
+func Confirm(db *sql.DB, hostID, confirmedBy, reason string) error {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM physical_hosts WHERE physical_host_id = ?", hostID).Scan(&count); err != nil {
+		return fmt.Errorf("failed to check physical host: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("physical host %s not found", hostID)
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO host_confirmations (physical_host_id, confirmed_by, reason, confirmed_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(physical_host_id) DO UPDATE SET
+		    confirmed_by = excluded.confirmed_by, reason = excluded.reason, confirmed_at = excluded.confirmed_at
+	`, hostID, confirmedBy, reason, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to confirm physical host %s: %w", hostID, err)
+	}
+	return nil
+}
+
+// Unconfirm removes the manual confirmation of a physical host ID
+func Unconfirm(db *sql.DB, hostID string) error {
+	res, err := db.Exec("DELETE FROM host_confirmations WHERE physical_host_id = ?", hostID)
+	if err != nil {
+		return fmt.Errorf("failed to remove the confirmation of %s: %w", hostID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("physical host %s is not confirmed", hostID)
+	}
+	return nil
+}
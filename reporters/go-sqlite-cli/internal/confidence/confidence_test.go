@@ -0,0 +1,116 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confidence_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/confidence"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+)
+
+func TestEngine(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	measure := func(fqdn, hostID, confidence, hostCores string) {
+		t.Helper()
+		if _, err := db.Exec(`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES (?, ?, 'PROD')`, fqdn, fqdn); err != nil {
+			t.Fatalf("Failed to insert node: %v", err)
+		}
+		if _, err := db.Exec(`INSERT OR IGNORE INTO physical_hosts (physical_host_id, host_id_method, host_id_confidence, first_seen, last_seen)
+			VALUES (?, 'hostname', ?, '2025-11-01 08:00:00', '2025-11-01 08:00:00')`, hostID, confidence); err != nil {
+			t.Fatalf("Failed to insert host: %v", err)
+		}
+		if _, err := db.Exec(`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count,
+			is_virtualized, virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus,
+			physical_host_id, host_id_method, host_id_confidence)
+			VALUES (?, '2025-11-01 08:00:00', 'Linux', '8', 4, 'yes', 'VMware', ?, 'true', 'true', 'true', 4, ?, 'hostname', ?)`,
+			fqdn, hostCores, hostID, confidence); err != nil {
+			t.Fatalf("Failed to insert measurement: %v", err)
+		}
+	}
+	// esx01: three VMs agree; esx02: the VMs disagree on the cores;
+	// esx03: known to vCenter; esx04: one VM, confirmed by hand
+	measure("app01", "esx01", "low", "32")
+	measure("app02", "esx01", "medium", "32")
+	measure("app03", "esx01", "low", "32")
+	measure("app04", "esx02", "medium", "16")
+	measure("app05", "esx02", "medium", "24")
+	measure("app06", "esx03", "low", "unknown")
+	measure("app07", "esx04", "low", "8")
+	if _, err := db.Exec(`INSERT INTO hypervisor_hosts (physical_host_id, source, host_ref, host_name, physical_cores, synced_at)
+		VALUES ('esx03', 'vcenter.example.com', 'host-10', 'esx03', 32, '2025-11-01 09:00:00')`); err != nil {
+		t.Fatalf("Failed to insert hypervisor host: %v", err)
+	}
+	if err := confidence.Confirm(db, "esx04", "admin", "checked in the console"); err != nil {
+		t.Fatalf("Confirm failed: %v", err)
+	}
+	if err := confidence.Confirm(db, "esx99", "admin", ""); err == nil {
+		t.Error("Expected an error confirming an unknown host")
+	}
+
+	engine := confidence.NewEngine(db, nil)
+	evaluations, err := engine.Evaluate()
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	want := map[string][2]string{
+		"esx01": {"low", "medium"},
+		"esx02": {"medium", "low"},
+		"esx03": {"low", "high"},
+		"esx04": {"low", "high"},
+	}
+	if len(evaluations) != len(want) {
+		t.Fatalf("Expected %d hosts, got %+v", len(want), evaluations)
+	}
+	for _, eval := range evaluations {
+		if w := want[eval.PhysicalHostID]; eval.Reported != w[0] || eval.Confidence != w[1] {
+			t.Errorf("%s: expected %s -> %s, got %+v", eval.PhysicalHostID, w[0], w[1], eval)
+		}
+	}
+
+	applied, err := engine.Apply(evaluations)
+	if err != nil || applied != 4 {
+		t.Fatalf("Expected 4 escalations, got %d (%v)", applied, err)
+	}
+
+	// A configured rule set replaces the defaults, and a new evaluation the previous one
+	if err := confidence.Unconfirm(db, "esx04"); err != nil {
+		t.Fatalf("Unconfirm failed: %v", err)
+	}
+	engine = confidence.NewEngine(db, []confidence.Rule{
+		{Name: "two-vms", Evidence: confidence.EvidenceVMAgreement, Set: confidence.High},
+	})
+	evaluations, err = engine.Evaluate()
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if applied, err = engine.Apply(evaluations); err != nil || applied != 1 {
+		t.Fatalf("Expected 1 escalation, got %d (%v)", applied, err)
+	}
+	var hostID, level string
+	db.QueryRow("SELECT physical_host_id, confidence FROM host_confidence_escalations").Scan(&hostID, &level)
+	if hostID != "esx01" || level != "high" {
+		t.Errorf("Expected esx01 raised to high, got %s %s", hostID, level)
+	}
+}
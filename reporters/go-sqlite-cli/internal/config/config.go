@@ -0,0 +1,302 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package config loads the optional iwldr configuration file. Values from the
+// file act as defaults; flags given on the command line always win.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/confidence"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/licensing"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/notify"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/schedule"
+)
+
+// DefaultFileName is the name of the configuration file looked up in the home directory
+const DefaultFileName = ".iwldr.yaml"
+
+// Config is the content of the configuration file
+type Config struct {
+	DBPath     string           `yaml:"db-path"`
+	Report     ReportConfig     `yaml:"report"`
+	Collection CollectionConfig `yaml:"collection"`
+	Schedule   ScheduleConfig   `yaml:"schedule"`
+	// Queries are the SQL statements run by name with 'iwldr query --name'
+	Queries map[string]string `yaml:"queries"`
+	// Webhooks are notified of import problems and compliance breaches
+	Webhooks []Webhook `yaml:"webhooks"`
+	// Confidence holds the physical host confidence rules of 'iwldr hosts confidence'
+	Confidence ConfidenceConfig `yaml:"confidence"`
+	// Licensing holds the core rounding policy of the sub-capacity reports
+	Licensing LicensingConfig `yaml:"licensing"`
+
+	// Path is the file the configuration was loaded from, empty when there is none
+	Path string `yaml:"-"`
+}
+
+// ReportConfig holds the defaults of the report commands
+type ReportConfig struct {
+	Format     string `yaml:"format"`      // default --format
+	OutputDir  string `yaml:"output-dir"`  // directory relative --output paths are written to
+	Product    string `yaml:"product"`     // default --product filter
+	Locale     string `yaml:"locale"`      // default --locale of table output
+	DateFormat string `yaml:"date-format"` // default --date-format of table output
+	// Profiles are the report definitions run by name with 'iwldr report run'
+	Profiles map[string]ReportProfile `yaml:"profiles"`
+	// Filters are the filter sets applied by name with --filter
+	Filters map[string]ReportFilter `yaml:"filters"`
+}
+
+// CollectionConfig holds the defaults of the collect command
+type CollectionConfig struct {
+	Sources     string     `yaml:"sources"` // sources CSV file, used when no endpoints are listed
+	DownloadDir string     `yaml:"download-dir"`
+	KnownHosts  string     `yaml:"known-hosts"`
+	Endpoints   []Endpoint `yaml:"endpoints"`
+}
+
+// Endpoint is a collection source listed directly in the configuration file.
+// The fields match the columns of the sources CSV file.
+type Endpoint struct {
+	Name         string `yaml:"name"`
+	Address      string `yaml:"address"`
+	User         string `yaml:"user"`
+	IdentityFile string `yaml:"identity-file"`
+	RemoteDir    string `yaml:"remote-dir"`
+}
+
+// ScheduleConfig holds the jobs run by the daemon command
+type ScheduleConfig struct {
+	OutputDir string         `yaml:"output-dir"` // runs write to a dated directory below it
+	Jobs      []ScheduledJob `yaml:"jobs"`
+}
+
+// ScheduledJob is an iwldr command run by the daemon at the times of a cron expression
+type ScheduledJob struct {
+	Name    string   `yaml:"name"`
+	Cron    string   `yaml:"cron"`
+	Command []string `yaml:"command"` // e.g. [report, compliance, --format, csv]
+	Output  string   `yaml:"output"`  // --output file name in the dated directory
+}
+
+// Webhook is an endpoint notified by the import and collect commands
+type Webhook struct {
+	Name   string   `yaml:"name"`
+	URL    string   `yaml:"url"`
+	Format string   `yaml:"format"` // json (default), slack or teams
+	Events []string `yaml:"events"` // import-errors, unknown-product, breach; all when empty
+}
+
+// ConfidenceConfig holds the rules setting the confidence of physical host IDs
+type ConfidenceConfig struct {
+	DedupMin string           `yaml:"dedup-min"` // default --min-confidence of report subcapacity
+	Rules    []ConfidenceRule `yaml:"rules"`     // the default rules apply when empty
+}
+
+// LicensingConfig holds the core rounding policy of the license contract
+type LicensingConfig struct {
+	Rounding           string `yaml:"rounding"`              // default --rounding: vm or host
+	MinCoresPerInstall int    `yaml:"min-cores-per-install"` // default --min-cores-per-install
+}
+
+// ConfidenceRule sets the confidence of the hosts its evidence holds for
+type ConfidenceRule struct {
+	Name   string `yaml:"name"`
+	When   string `yaml:"when"`    // vm-agreement, core-conflict, vcenter or manual
+	MinVMs int    `yaml:"min-vms"` // agreeing nodes of vm-agreement, default 2
+	Set    string `yaml:"set"`     // low, medium or high
+}
+
+// scheduledCommands are the commands a scheduled job may run
+var scheduledCommands = map[string]bool{
+	"import":  true,
+	"collect": true,
+	"report":  true,
+}
+
+// DefaultPath returns ~/.iwldr.yaml, or an empty string if the home directory is unknown
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, DefaultFileName)
+}
+
+// Load reads the configuration file at path. Unknown keys are rejected so that
+// typos do not silently fall back to defaults.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := &Config{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", path, err)
+	}
+
+	cfg.Path = path
+	return cfg, nil
+}
+
+// LoadDefault reads ~/.iwldr.yaml. A missing file is not an error and yields an empty configuration.
+func LoadDefault() (*Config, error) {
+	path := DefaultPath()
+	if path == "" {
+		return &Config{}, nil
+	}
+
+	cfg, err := Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	return cfg, err
+}
+
+// validate checks the collection endpoints, the scheduled jobs, the saved
+// queries, the webhooks, the licensing policy, the confidence rules, the
+// report filters and the report profiles
+func (c *Config) validate() error {
+	if c.Collection.Sources != "" && len(c.Collection.Endpoints) > 0 {
+		return fmt.Errorf("collection: sources and endpoints cannot be combined")
+	}
+
+	names := make(map[string]bool)
+	for i, endpoint := range c.Collection.Endpoints {
+		if endpoint.Name == "" || endpoint.Address == "" || endpoint.User == "" || endpoint.RemoteDir == "" {
+			return fmt.Errorf("collection endpoint %d: name, address, user and remote-dir are required", i+1)
+		}
+		if names[endpoint.Name] {
+			return fmt.Errorf("duplicate collection endpoint %q", endpoint.Name)
+		}
+		names[endpoint.Name] = true
+	}
+
+	jobs := make(map[string]bool)
+	for i, job := range c.Schedule.Jobs {
+		if job.Name == "" || job.Cron == "" || len(job.Command) == 0 {
+			return fmt.Errorf("scheduled job %d: name, cron and command are required", i+1)
+		}
+		if jobs[job.Name] {
+			return fmt.Errorf("duplicate scheduled job %q", job.Name)
+		}
+		jobs[job.Name] = true
+		if _, err := schedule.ParseCron(job.Cron); err != nil {
+			return fmt.Errorf("scheduled job %q: %w", job.Name, err)
+		}
+		if !scheduledCommands[job.Command[0]] {
+			return fmt.Errorf("scheduled job %q: command must start with import, collect or report", job.Name)
+		}
+		if job.Output != "" && job.Command[0] != "report" {
+			return fmt.Errorf("scheduled job %q: output is only supported by report commands", job.Name)
+		}
+		if job.Output != "" && filepath.Base(job.Output) != job.Output {
+			return fmt.Errorf("scheduled job %q: output must be a file name", job.Name)
+		}
+	}
+
+	for name, statement := range c.Queries {
+		if strings.TrimSpace(statement) == "" {
+			return fmt.Errorf("query %q: statement is empty", name)
+		}
+	}
+
+	webhooks := make(map[string]bool)
+	for i, webhook := range c.Webhooks {
+		if webhook.Name == "" || webhook.URL == "" {
+			return fmt.Errorf("webhook %d: name and url are required", i+1)
+		}
+		if webhooks[webhook.Name] {
+			return fmt.Errorf("duplicate webhook %q", webhook.Name)
+		}
+		webhooks[webhook.Name] = true
+		if !strings.HasPrefix(webhook.URL, "http://") && !strings.HasPrefix(webhook.URL, "https://") {
+			return fmt.Errorf("webhook %q: url must start with http:// or https://", webhook.Name)
+		}
+		if !notify.IsFormat(webhook.Format) {
+			return fmt.Errorf("webhook %q: unknown format %q (use json, slack or teams)", webhook.Name, webhook.Format)
+		}
+		for _, event := range webhook.Events {
+			if !notify.IsEvent(event) {
+				return fmt.Errorf("webhook %q: unknown event %q (use import-errors, unknown-product or breach)", webhook.Name, event)
+			}
+		}
+	}
+	if err := c.Licensing.validate(); err != nil {
+		return err
+	}
+	if err := c.Confidence.validate(); err != nil {
+		return err
+	}
+	if err := c.validateFilters(); err != nil {
+		return err
+	}
+	return c.validateProfiles()
+}
+
+// validate checks the core rounding policy
+func (c *LicensingConfig) validate() error {
+	if c.Rounding != "" && !licensing.IsRounding(c.Rounding) {
+		return fmt.Errorf("licensing: unknown rounding %q (use vm or host)", c.Rounding)
+	}
+	if c.MinCoresPerInstall < 0 {
+		return fmt.Errorf("licensing: min-cores-per-install must not be negative")
+	}
+	return nil
+}
+
+// validate checks the levels and the evidence of the confidence rules
+func (c *ConfidenceConfig) validate() error {
+	if c.DedupMin != "" && !confidence.IsLevel(c.DedupMin) {
+		return fmt.Errorf("confidence: unknown dedup-min %q (use low, medium or high)", c.DedupMin)
+	}
+
+	rules := make(map[string]bool)
+	for i, rule := range c.Rules {
+		if rule.Name == "" || rule.When == "" || rule.Set == "" {
+			return fmt.Errorf("confidence rule %d: name, when and set are required", i+1)
+		}
+		if rules[rule.Name] {
+			return fmt.Errorf("duplicate confidence rule %q", rule.Name)
+		}
+		rules[rule.Name] = true
+		if !confidence.IsEvidence(rule.When) {
+			return fmt.Errorf("confidence rule %q: unknown evidence %q (use vm-agreement, core-conflict, vcenter or manual)", rule.Name, rule.When)
+		}
+		if !confidence.IsLevel(rule.Set) {
+			return fmt.Errorf("confidence rule %q: unknown level %q (use low, medium or high)", rule.Name, rule.Set)
+		}
+		if rule.MinVMs < 0 || (rule.MinVMs > 0 && rule.When != confidence.EvidenceVMAgreement) {
+			return fmt.Errorf("confidence rule %q: min-vms must be positive and is only used by vm-agreement", rule.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/config"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "iwldr.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+db-path: /var/lib/iwldr/license-monitor.db
+report:
+  format: csv
+  output-dir: /srv/reports
+  product: IS_ONP_PRD
+collection:
+  download-dir: /var/lib/iwldr/collected
+  endpoints:
+    - name: drop
+      address: drop.example.com
+      user: iwldr
+      identity-file: /home/iwldr/.ssh/id_ed25519
+      remote-dir: /data/inspector
+schedule:
+  output-dir: /srv/scheduled
+  jobs:
+    - name: compliance
+      cron: "30 2 * * 1-5"
+      command: [report, compliance, --format, csv]
+      output: compliance.csv
+queries:
+  big-hosts: SELECT main_fqdn, considered_cpus FROM measurements WHERE considered_cpus >= 16
+webhooks:
+  - name: licensing-channel
+    url: https://hooks.slack.com/services/T000/B000/XXXX
+    format: slack
+    events: [breach, unknown-product]
+confidence:
+  dedup-min: medium
+  rules:
+    - {name: vms-agree, when: vm-agreement, min-vms: 3, set: medium}
+    - {name: confirmed, when: manual, set: high}
+licensing:
+  rounding: host
+  min-cores-per-install: 1
+`)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.DBPath != "/var/lib/iwldr/license-monitor.db" {
+		t.Errorf("Unexpected db-path: %s", cfg.DBPath)
+	}
+	if cfg.Report.Format != "csv" || cfg.Report.OutputDir != "/srv/reports" || cfg.Report.Product != "IS_ONP_PRD" {
+		t.Errorf("Unexpected report config: %+v", cfg.Report)
+	}
+	if len(cfg.Collection.Endpoints) != 1 || cfg.Collection.Endpoints[0].RemoteDir != "/data/inspector" {
+		t.Errorf("Unexpected endpoints: %+v", cfg.Collection.Endpoints)
+	}
+	if len(cfg.Schedule.Jobs) != 1 || len(cfg.Schedule.Jobs[0].Command) != 4 || cfg.Schedule.OutputDir != "/srv/scheduled" {
+		t.Errorf("Unexpected schedule: %+v", cfg.Schedule)
+	}
+	if !strings.HasPrefix(cfg.Queries["big-hosts"], "SELECT main_fqdn") {
+		t.Errorf("Unexpected queries: %+v", cfg.Queries)
+	}
+	if len(cfg.Webhooks) != 1 || cfg.Webhooks[0].Format != "slack" || len(cfg.Webhooks[0].Events) != 2 {
+		t.Errorf("Unexpected webhooks: %+v", cfg.Webhooks)
+	}
+	if cfg.Confidence.DedupMin != "medium" || len(cfg.Confidence.Rules) != 2 || cfg.Confidence.Rules[0].MinVMs != 3 {
+		t.Errorf("Unexpected confidence config: %+v", cfg.Confidence)
+	}
+	if cfg.Licensing.Rounding != "host" || cfg.Licensing.MinCoresPerInstall != 1 {
+		t.Errorf("Unexpected licensing config: %+v", cfg.Licensing)
+	}
+	if cfg.Path != path {
+		t.Errorf("Expected path %s, got %s", path, cfg.Path)
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown key", "db_path: x.db\n", "db_path"},
+		{"incomplete endpoint", "collection:\n  endpoints:\n    - name: drop\n", "required"},
+		{"invalid cron", "schedule:\n  jobs:\n    - {name: a, cron: \"61 * * * *\", command: [report, peak]}\n", "out of range"},
+		{"unsupported command", "schedule:\n  jobs:\n    - {name: a, cron: \"@daily\", command: [daemon]}\n", "must start with"},
+		{"output path", "schedule:\n  jobs:\n    - {name: a, cron: \"@daily\", command: [report, peak], output: ../x.csv}\n", "file name"},
+		{"empty query", "queries:\n  big-hosts: \"\"\n", "statement is empty"},
+		{"webhook without url", "webhooks:\n  - name: a\n", "required"},
+		{"webhook format", "webhooks:\n  - {name: a, url: \"https://h/x\", format: xml}\n", "unknown format"},
+		{"webhook event", "webhooks:\n  - {name: a, url: \"https://h/x\", events: [import]}\n", "unknown event"},
+		{"profile without report", "report:\n  profiles:\n    ibm: {format: csv}\n", "report is required"},
+		{"profile format", "report:\n  profiles:\n    ibm: {report: compliance, format: pdf}\n", "unknown format"},
+		{"profile formats", "report:\n  profiles:\n    ibm: {report: compliance, format: \"csv,xlsx\"}\n", "need output-dir"},
+		{"profile period", "report:\n  profiles:\n    ibm: {report: compliance, period: last-month}\n", "unknown period"},
+		{"profile period and dates", "report:\n  profiles:\n    ibm: {report: compliance, period: previous-month, from: 2025-10-01}\n", "cannot be combined"},
+		{"profile date", "report:\n  profiles:\n    ibm: {report: compliance, to: 31.10.2025}\n", "invalid date"},
+		{"profile filter", "report:\n  profiles:\n    ibm: {report: compliance, filter: emea}\n", "unknown filter"},
+		{"empty filter", "report:\n  filters:\n    emea: {}\n", "no filter is set"},
+		{"filter key", "report:\n  filters:\n    emea: {hosts: \"*.emea\"}\n", "hosts"},
+		{"filter date", "report:\n  filters:\n    emea: {mode: PROD, from: 2025/10/01}\n", "invalid date"},
+		{"rounding", "licensing:\n  rounding: down\n", "unknown rounding"},
+		{"confidence level", "confidence:\n  dedup-min: certain\n", "unknown dedup-min"},
+		{"confidence evidence", "confidence:\n  rules:\n    - {name: a, when: dns, set: high}\n", "unknown evidence"},
+		{"confidence min-vms", "confidence:\n  rules:\n    - {name: a, when: vcenter, min-vms: 2, set: high}\n", "min-vms"},
+		{"sources and endpoints", "collection:\n  sources: s.csv\n  endpoints:\n    - {name: a, address: h, user: u, remote-dir: /d}\n", "cannot be combined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.Load(writeConfig(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadDefaultWithoutFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		t.Fatalf("LoadDefault failed: %v", err)
+	}
+	if cfg.DBPath != "" {
+		t.Errorf("Expected empty configuration, got %+v", cfg)
+	}
+}
+
+func TestResolvePeriod(t *testing.T) {
+	// A Wednesday in the second quarter
+	today := time.Date(2025, 5, 14, 15, 30, 0, 0, time.Local)
+	tests := []struct {
+		period   string
+		from, to string
+	}{
+		{"current-week", "2025-05-12", "2025-05-14"},
+		{"previous-week", "2025-05-05", "2025-05-11"},
+		{"current-month", "2025-05-01", "2025-05-14"},
+		{"previous-month", "2025-04-01", "2025-04-30"},
+		{"current-quarter", "2025-04-01", "2025-05-14"},
+		{"previous-quarter", "2025-01-01", "2025-03-31"},
+		{"last-7-days", "2025-05-08", "2025-05-14"},
+	}
+	for _, tt := range tests {
+		from, to, err := config.ResolvePeriod(tt.period, today)
+		if err != nil {
+			t.Errorf("%s: %v", tt.period, err)
+			continue
+		}
+		if got := from.Format("2006-01-02") + " " + to.Format("2006-01-02"); got != tt.from+" "+tt.to {
+			t.Errorf("%s: expected %s %s, got %s", tt.period, tt.from, tt.to, got)
+		}
+	}
+
+	// Weeks start on Monday, also seen from a Sunday
+	from, _, _ := config.ResolvePeriod("current-week", time.Date(2025, 5, 18, 0, 0, 0, 0, time.UTC))
+	if from.Format("2006-01-02") != "2025-05-12" {
+		t.Errorf("Expected the week of Sunday 2025-05-18 to start on 2025-05-12, got %s", from.Format("2006-01-02"))
+	}
+	if _, _, err := config.ResolvePeriod("last-0-days", today); err == nil {
+		t.Error("Expected an error for last-0-days")
+	}
+}
+
+func TestReportProfileCommand(t *testing.T) {
+	path := writeConfig(t, `
+report:
+  profiles:
+    ibm-submission:
+      report: compliance
+      format: xlsx
+      output: ibm-{from}-{date}.xlsx
+      period: previous-month
+      mode: PROD
+      args: [--fail-on-breach]
+`)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	args, err := cfg.Report.Profiles["ibm-submission"].Command(time.Date(2025, 11, 3, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	want := "compliance --format xlsx --output ibm-2025-10-01-2025-11-03.xlsx --mode PROD --from 2025-10-01 --to 2025-10-31 --fail-on-breach"
+	if got := strings.Join(args, " "); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestReportFilters(t *testing.T) {
+	path := writeConfig(t, `
+report:
+  filters:
+    emea-prod:
+      host: "*.emea.example.com"
+      mode: PROD
+      product: IS_ONP_PRD
+  profiles:
+    emea:
+      report: host-detail
+      filter: emea-prod
+`)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	flags := cfg.Report.Filters["emea-prod"].Flags()
+	want := map[string]string{"host": "*.emea.example.com", "mode": "PROD", "product": "IS_ONP_PRD"}
+	if len(flags) != len(want) {
+		t.Errorf("Expected flags %v, got %v", want, flags)
+	}
+	for name, value := range want {
+		if flags[name] != value {
+			t.Errorf("Expected --%s %q, got %q", name, value, flags[name])
+		}
+	}
+
+	args, err := cfg.Report.Profiles["emea"].Command(time.Now())
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if got := strings.Join(args, " "); got != "host-detail --filter emea-prod" {
+		t.Errorf("Expected the profile to apply its filter, got %q", got)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import "fmt"
+
+// ReportFilter is a named set of report filters applied with --filter, e.g.
+// the production nodes of a region. Each field is the value of the report flag
+// of the same name.
+type ReportFilter struct {
+	Host     string `yaml:"host"`
+	Mode     string `yaml:"mode"`
+	Product  string `yaml:"product"`
+	OS       string `yaml:"os"`
+	VirtType string `yaml:"virt-type"`
+	Org      string `yaml:"org"`
+	Owner    string `yaml:"owner"`
+	Standby  string `yaml:"standby"`
+	From     string `yaml:"from"`
+	To       string `yaml:"to"`
+}
+
+// Flags returns the report flags set by the filter with their values, by flag name
+func (f ReportFilter) Flags() map[string]string {
+	flags := make(map[string]string)
+	for name, value := range map[string]string{
+		"host":      f.Host,
+		"mode":      f.Mode,
+		"product":   f.Product,
+		"os":        f.OS,
+		"virt-type": f.VirtType,
+		"org":       f.Org,
+		"owner":     f.Owner,
+		"standby":   f.Standby,
+		"from":      f.From,
+		"to":        f.To,
+	} {
+		if value != "" {
+			flags[name] = value
+		}
+	}
+	return flags
+}
+
+// validateFilters checks the report filters
+func (c *Config) validateFilters() error {
+	for name, filter := range c.Report.Filters {
+		if len(filter.Flags()) == 0 {
+			return fmt.Errorf("report filter %q: no filter is set", name)
+		}
+		if err := validateDates(filter.From, filter.To); err != nil {
+			return fmt.Errorf("report filter %q: %w", name, err)
+		}
+	}
+	return nil
+}
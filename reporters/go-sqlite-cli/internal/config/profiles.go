@@ -0,0 +1,160 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReportProfile is a named report definition run with 'iwldr report run',
+// e.g. the monthly IBM submission or the weekly operations summary
+type ReportProfile struct {
+	Report    string `yaml:"report"`     // report to run, e.g. compliance
+	Format    string `yaml:"format"`     // several comma separated with output-dir
+	Output    string `yaml:"output"`     // may contain {from}, {to} and {date}
+	OutputDir string `yaml:"output-dir"` // may contain {from}, {to} and {date}
+	Template  string `yaml:"template"`
+	Filter    string `yaml:"filter"` // report filter applied with --filter
+	Product   string `yaml:"product"`
+	Mode      string `yaml:"mode"`
+	From      string `yaml:"from"`
+	To        string `yaml:"to"`
+	// Period is a period relative to the day of the run, instead of from and to
+	Period string `yaml:"period"`
+	// Args are further flags of the report, e.g. [--non-compliant-only]
+	Args []string `yaml:"args"`
+}
+
+// Periods are the fixed relative periods of a report profile; last-<n>-days
+// is accepted too
+var Periods = []string{"current-week", "previous-week", "current-month", "previous-month", "current-quarter", "previous-quarter"}
+
+var lastDaysPeriod = regexp.MustCompile(`^last-([1-9][0-9]*)-days$`)
+
+// ResolvePeriod returns the first and last day of a relative period as of
+// today. Current periods end today; weeks start on Monday.
+func ResolvePeriod(period string, today time.Time) (from, to time.Time, err error) {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	month := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	quarter := time.Date(today.Year(), (today.Month()-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+
+	switch period {
+	case "current-week":
+		return monday, today, nil
+	case "previous-week":
+		return monday.AddDate(0, 0, -7), monday.AddDate(0, 0, -1), nil
+	case "current-month":
+		return month, today, nil
+	case "previous-month":
+		return month.AddDate(0, -1, 0), month.AddDate(0, 0, -1), nil
+	case "current-quarter":
+		return quarter, today, nil
+	case "previous-quarter":
+		return quarter.AddDate(0, -3, 0), quarter.AddDate(0, 0, -1), nil
+	}
+
+	if m := lastDaysPeriod.FindStringSubmatch(period); m != nil {
+		days, err := strconv.Atoi(m[1])
+		if err == nil && days <= 3660 {
+			return today.AddDate(0, 0, 1-days), today, nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unknown period %q (use %s or last-<n>-days)", period, strings.Join(Periods, ", "))
+}
+
+// Command returns the arguments of 'iwldr report' run for the profile as of
+// today: the report, the flags of the profile fields and its args
+func (p ReportProfile) Command(today time.Time) ([]string, error) {
+	from, to := p.From, p.To
+	if p.Period != "" {
+		start, end, err := ResolvePeriod(p.Period, today)
+		if err != nil {
+			return nil, err
+		}
+		from, to = start.Format("2006-01-02"), end.Format("2006-01-02")
+	}
+
+	dates := strings.NewReplacer("{from}", from, "{to}", to, "{date}", today.Format("2006-01-02"))
+
+	args := []string{p.Report}
+	for _, flag := range []struct{ name, value string }{
+		{"--format", p.Format},
+		{"--output", dates.Replace(p.Output)},
+		{"--output-dir", dates.Replace(p.OutputDir)},
+		{"--template", p.Template},
+		{"--filter", p.Filter},
+		{"--product", p.Product},
+		{"--mode", p.Mode},
+		{"--from", from},
+		{"--to", to},
+	} {
+		if flag.value != "" {
+			args = append(args, flag.name, flag.value)
+		}
+	}
+	return append(args, p.Args...), nil
+}
+
+// validateProfiles checks the report profiles
+func (c *Config) validateProfiles() error {
+	for name, profile := range c.Report.Profiles {
+		if profile.Report == "" {
+			return fmt.Errorf("report profile %q: report is required", name)
+		}
+		if profile.Report == "run" || strings.HasPrefix(profile.Report, "-") {
+			return fmt.Errorf("report profile %q: invalid report %q", name, profile.Report)
+		}
+		for _, format := range strings.Split(profile.Format, ",") {
+			switch strings.TrimSpace(format) {
+			case "", "table", "csv", "json", "xlsx":
+			default:
+				return fmt.Errorf("report profile %q: unknown format %q (use table, csv, json or xlsx)", name, format)
+			}
+		}
+		if strings.Contains(profile.Format, ",") && profile.OutputDir == "" {
+			return fmt.Errorf("report profile %q: several formats need output-dir", name)
+		}
+		if profile.Period != "" {
+			if profile.From != "" || profile.To != "" {
+				return fmt.Errorf("report profile %q: period cannot be combined with from and to", name)
+			}
+			if _, _, err := ResolvePeriod(profile.Period, time.Now()); err != nil {
+				return fmt.Errorf("report profile %q: %w", name, err)
+			}
+		}
+		if err := validateDates(profile.From, profile.To); err != nil {
+			return fmt.Errorf("report profile %q: %w", name, err)
+		}
+		if _, ok := c.Report.Filters[profile.Filter]; profile.Filter != "" && !ok {
+			return fmt.Errorf("report profile %q: unknown filter %q", name, profile.Filter)
+		}
+	}
+	return nil
+}
+
+// validateDates checks that the dates given are in the YYYY-MM-DD format
+func validateDates(dates ...string) error {
+	for _, date := range dates {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			return fmt.Errorf("invalid date %q (use YYYY-MM-DD)", date)
+		}
+	}
+	return nil
+}
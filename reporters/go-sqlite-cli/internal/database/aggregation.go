@@ -0,0 +1,74 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Daily aggregation policies: how v_daily_measurements counts a node that
+// reported several measurements on one day
+const (
+	DailyAggregationMax     = "max"     // the measurement with the most license cores
+	DailyAggregationLast    = "last"    // the latest measurement
+	DailyAggregationAverage = "average" // the cores averaged over the day
+)
+
+// dailyAggregationKey is the schema_metadata key of the daily aggregation policy
+const dailyAggregationKey = "daily_aggregation"
+
+// GetDailyAggregation returns the daily aggregation policy of the database,
+// DailyAggregationMax unless another one was set
+func GetDailyAggregation(db *sql.DB) (string, error) {
+	var policy string
+	err := db.QueryRow(`SELECT value FROM schema_metadata WHERE key = ?`, dailyAggregationKey).Scan(&policy)
+	if err == sql.ErrNoRows {
+		return DailyAggregationMax, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get daily aggregation policy: %w", err)
+	}
+	return policy, nil
+}
+
+// SetDailyAggregation sets the daily aggregation policy of the database
+func SetDailyAggregation(db *sql.DB, value string) error {
+	policy := strings.ToLower(strings.TrimSpace(value))
+	switch policy {
+	case DailyAggregationMax, DailyAggregationLast, DailyAggregationAverage:
+	default:
+		return fmt.Errorf("invalid daily aggregation policy %q (expected %s, %s or %s)",
+			value, DailyAggregationMax, DailyAggregationLast, DailyAggregationAverage)
+	}
+
+	_, err := db.Exec(`INSERT OR REPLACE INTO schema_metadata (key, value) VALUES (?, ?)`, dailyAggregationKey, policy)
+	if err != nil {
+		return fmt.Errorf("failed to set daily aggregation policy: %w", err)
+	}
+	return nil
+}
+
+// DescribeDailyAggregation describes a daily aggregation policy for report output
+func DescribeDailyAggregation(policy string) string {
+	switch policy {
+	case DailyAggregationLast:
+		return "last measurement of the day per node"
+	case DailyAggregationAverage:
+		return "cores averaged over the day per node"
+	}
+	return "highest cores of the day per node"
+}
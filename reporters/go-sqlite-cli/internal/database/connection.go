@@ -16,26 +16,51 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"syscall"
+	"time"
 
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/tracing"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// ReadOnlyBusyTimeout is how long read-only connections wait for a lock held by
+// a writer
+const ReadOnlyBusyTimeout = 30 * time.Second
+
+// BusyTimeout is how long read-write connections wait for a lock held by
+// another connection, e.g. a report reading or the import lock being refreshed
+const BusyTimeout = 5 * time.Second
+
+// MemoryPath is the database path of an in-memory database, which lives until
+// the connection returned by Connect is closed
+const MemoryPath = ":memory:"
+
 // Connect establishes a connection to the SQLite database
 // Foreign keys are enabled by default for referential integrity
 func Connect(dbPath string) (*sql.DB, error) {
 	// Ensure the directory exists
-	dir := filepath.Dir(dbPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	if dbPath != MemoryPath {
+		dir := filepath.Dir(dbPath)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open("sqlite3", fmt.Sprintf("%s?_busy_timeout=%d", dbPath, BusyTimeout.Milliseconds()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	if dbPath == MemoryPath {
+		// Every connection to :memory: opens a database of its own, so the
+		// pool keeps a single connection, never closed while the pool is open
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxLifetime(0)
+		db.SetConnMaxIdleTime(0)
+	}
 
 	// Test the connection
 	if err := db.Ping(); err != nil {
@@ -52,3 +77,51 @@ func Connect(dbPath string) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// ConnectReadOnly opens an existing database without write access, for
+// commands that must never modify it. Reads wait up to ReadOnlyBusyTimeout for
+// another process committing an import. On a read-only file system, where no
+// other process can change the file, the database is opened as immutable so
+// that SQLite skips locking altogether.
+func ConnectReadOnly(dbPath string) (*sql.DB, error) {
+	if dbPath == MemoryPath {
+		return nil, fmt.Errorf("an in-memory database cannot be opened read-only: it only lives in the process that created it")
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("database does not exist at %s: %w", dbPath, err)
+	}
+
+	dsn := fmt.Sprintf("file:%s?mode=ro&_busy_timeout=%d", dbPath, ReadOnlyBusyTimeout.Milliseconds())
+	if onReadOnlyFileSystem(dbPath) {
+		dsn += "&immutable=1"
+	}
+
+	// The queries of the reports are traced when tracing is enabled
+	driverName := "sqlite3"
+	if tracing.Enabled() {
+		driverName = tracedDriverName
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Test the connection
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
+}
+
+// onReadOnlyFileSystem reports whether the file lies on a read-only mount
+func onReadOnlyFileSystem(path string) bool {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err == nil {
+		f.Close()
+		return false
+	}
+	return errors.Is(err, syscall.EROFS)
+}
@@ -15,10 +15,13 @@
 package database_test
 
 import (
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/cli/commands"
 	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
 )
 
@@ -50,6 +53,119 @@ func TestConnect(t *testing.T) {
 	}
 }
 
+func TestConnectReadOnly(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	if _, err := database.ConnectReadOnly(dbPath); err == nil {
+		t.Fatal("Expected an error for a missing database")
+	}
+
+	db, err := database.Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	if _, err := db.Exec("CREATE TABLE t (id INTEGER)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	db.Close()
+
+	ro, err := database.ConnectReadOnly(dbPath)
+	if err != nil {
+		t.Fatalf("ConnectReadOnly failed: %v", err)
+	}
+	defer ro.Close()
+
+	var count int
+	if err := ro.QueryRow("SELECT COUNT(*) FROM t").Scan(&count); err != nil {
+		t.Errorf("Read failed: %v", err)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO t (id) VALUES (1)",
+		"UPDATE t SET id = 2",
+		"DELETE FROM t",
+		"CREATE TABLE u (id INTEGER)",
+		"DROP TABLE t",
+		"PRAGMA user_version = 7",
+	} {
+		if _, err := ro.Exec(stmt); err == nil || !strings.Contains(err.Error(), "readonly") {
+			t.Errorf("Expected %q to fail on a read-only connection, got %v", stmt, err)
+		}
+	}
+}
+
+func TestReportOpensReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	db, err := database.Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	// A report definition writing through the database handle of the reports
+	defsDir := filepath.Join(dir, "reports.d")
+	if err := os.MkdirAll(defsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	definition := "description: Writes to the database\nsql: INSERT INTO license_terms (term_id, program_number, program_name) VALUES ('T9', '5900-ZZZ', 'Written')\n"
+	if err := os.WriteFile(filepath.Join(defsDir, "write-terms.yaml"), []byte(definition), 0644); err != nil {
+		t.Fatal(err)
+	}
+	commands.AddReportPlugins(defsDir)
+
+	report := commands.NewReportCmd()
+	report.SetErr(io.Discard)
+	defer report.SetArgs(nil)
+	report.SetArgs([]string{"write-terms", "--db-path", dbPath})
+	err = report.Execute()
+	if err == nil || !strings.Contains(err.Error(), "readonly") {
+		t.Errorf("Expected the write of the report to fail, got %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM license_terms WHERE term_id = 'T9'").Scan(&count); err != nil || count != 0 {
+		t.Errorf("Expected the report not to write to the database, found %d rows (%v)", count, err)
+	}
+
+	// A missing database is not created, and the error says how to create it
+	missing := filepath.Join(dir, "missing.db")
+	report.SetArgs([]string{"peak", "--db-path", missing})
+	err = report.Execute()
+	if err == nil || !strings.Contains(err.Error(), "database does not exist at "+missing) || !strings.Contains(err.Error(), "Run 'iwdlr init' first") {
+		t.Errorf("Expected the missing database error, got %v", err)
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("Expected the report not to create %s, got %v", missing, err)
+	}
+}
+
+func TestConnectMemory(t *testing.T) {
+	db, err := database.Connect(database.MemoryPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+	if err := database.VerifySchema(db); err != nil {
+		t.Fatalf("VerifySchema failed: %v", err)
+	}
+	// A second connection would open another, empty, database
+	if max := db.Stats().MaxOpenConnections; max != 1 {
+		t.Errorf("Expected a single connection, got %d", max)
+	}
+	if _, err := os.Stat(database.MemoryPath); !os.IsNotExist(err) {
+		t.Errorf("Expected no file for an in-memory database, got %v", err)
+	}
+	if _, err := database.ConnectReadOnly(database.MemoryPath); err == nil {
+		t.Error("Expected an in-memory database not to open read-only")
+	}
+}
+
 func TestInitSchema(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -93,11 +209,40 @@ func TestVerifySchemaAllTables(t *testing.T) {
 		"schema_metadata",
 		"license_terms",
 		"product_codes",
+		"product_term_mappings",
+		"entitlements",
+		"product_thresholds",
 		"landscape_nodes",
 		"physical_hosts",
 		"measurements",
 		"detected_products",
+		"detected_product_installs",
+		"detected_product_processes",
 		"import_sessions",
+		"import_sources",
+		"jobs",
+		"failed_imports",
+		"collection_sources",
+		"collected_files",
+		"physical_host_aliases",
+		"physical_host_merges",
+		"license_terms_history",
+		"product_codes_history",
+		"pvu_mappings",
+		"sites",
+		"organizations",
+		"org_entitlements",
+		"snapshots",
+		"snapshot_rows",
+		"node_aliases",
+		"detection_errors",
+		"import_lock",
+		"product_lifecycle",
+		"product_instances",
+		"license_term_documents",
+		"contract_periods",
+		"peak_grace_windows",
+		"report_cache",
 	}
 
 	for _, table := range expectedTables {
@@ -291,6 +436,10 @@ func TestIndexesCreated(t *testing.T) {
 		"idx_product_codes_term",
 		"idx_import_sessions_hostname",
 		"idx_import_sessions_timestamp",
+		"idx_detected_products_present",
+		"idx_detected_products_product_timestamp",
+		"idx_measurements_fqdn_latest",
+		"idx_measurements_date",
 	}
 
 	for _, index := range expectedIndexes {
@@ -306,6 +455,103 @@ func TestIndexesCreated(t *testing.T) {
 	}
 }
 
+func TestCreateMissingIndexes(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	missing, err := database.MissingIndexes(db)
+	if err != nil || len(missing) != 0 {
+		t.Fatalf("Expected no missing index after init, got %v (%v)", missing, err)
+	}
+
+	// A database initialized before the covering indexes were added
+	if _, err := db.Exec("DROP INDEX idx_detected_products_present"); err != nil {
+		t.Fatalf("Failed to drop index: %v", err)
+	}
+	missing, err = database.MissingIndexes(db)
+	if err != nil || len(missing) != 1 || missing[0] != "idx_detected_products_present" {
+		t.Fatalf("Expected idx_detected_products_present to be missing, got %v (%v)", missing, err)
+	}
+
+	created, err := database.CreateMissingIndexes(db)
+	if err != nil || len(created) != 1 {
+		t.Fatalf("Expected one created index, got %v (%v)", created, err)
+	}
+	if missing, _ := database.MissingIndexes(db); len(missing) != 0 {
+		t.Errorf("Expected no missing index after creating them, got %v", missing)
+	}
+
+	// The daily views read the present products through the covering index
+	plan, err := database.ExplainQueryPlan(db, "SELECT * FROM v_core_aggregation_by_product")
+	if err != nil {
+		t.Fatalf("Failed to explain view: %v", err)
+	}
+	var usesIndex bool
+	for _, step := range plan {
+		if strings.Contains(step.Detail, "idx_detected_products_present") {
+			usesIndex = true
+		}
+		if step.FullScan() && strings.HasPrefix(step.Detail, "SCAN d") {
+			t.Errorf("Unexpected full scan of detected_products: %s", step.Detail)
+		}
+	}
+	if !usesIndex {
+		t.Errorf("Expected the plan to use idx_detected_products_present: %+v", plan)
+	}
+}
+
+func TestExplainView(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	// The evidence of v_install_detail is read from both evidence tables
+	plan, err := database.ExplainView(db, "v_install_detail")
+	if err != nil {
+		t.Fatalf("Failed to explain view: %v", err)
+	}
+	scanned := map[string]bool{}
+	for _, step := range plan {
+		if step.Table != "" {
+			scanned[step.Table] = true
+		}
+		if step.Table != "" && !step.FullScan() {
+			t.Errorf("Table set on a step that is no full scan: %+v", step)
+		}
+	}
+	for _, table := range []string{"detected_product_installs", "detected_product_processes"} {
+		if !scanned[table] {
+			t.Errorf("Expected a full scan of %s, got %+v", table, plan)
+		}
+	}
+
+	// Aliases of the views are resolved to their tables
+	plan, err = database.ExplainView(db, "v_reported_measurements")
+	if err != nil {
+		t.Fatalf("Failed to explain view: %v", err)
+	}
+	var measurementsScan bool
+	for _, step := range plan {
+		if step.Table == "measurements" {
+			measurementsScan = true
+		}
+	}
+	if !measurementsScan {
+		t.Errorf("Expected a full scan of measurements, got %+v", plan)
+	}
+}
+
 func TestLatestMeasurementsView(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -21,6 +21,13 @@ import (
 
 // InitSchema creates all tables, indexes, and views
 func InitSchema(db *sql.DB) error {
+	// The views of an existing database are kept (see UpgradeViews), so their
+	// version is only recorded when they are created here
+	var views int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'view'`).Scan(&views); err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+
 	// Execute schema DDL
 	_, err := db.Exec(SchemaSQL)
 	if err != nil {
@@ -32,6 +39,12 @@ func InitSchema(db *sql.DB) error {
 	if err != nil {
 		return fmt.Errorf("failed to create views: %w", err)
 	}
+	if views == 0 {
+		_, err = db.Exec(`INSERT OR REPLACE INTO schema_metadata (key, value) VALUES (?, ?)`, viewsVersionKey, GetViewsVersion())
+		if err != nil {
+			return fmt.Errorf("failed to set views version: %w", err)
+		}
+	}
 
 	// Set schema version
 	err = SetSchemaVersion(db, GetSchemaVersion())
@@ -48,11 +61,45 @@ func VerifySchema(db *sql.DB) error {
 		"schema_metadata",
 		"license_terms",
 		"product_codes",
+		"product_term_mappings",
+		"entitlements",
+		"product_thresholds",
 		"landscape_nodes",
 		"physical_hosts",
 		"measurements",
 		"detected_products",
+		"detected_product_installs",
+		"detected_product_processes",
 		"import_sessions",
+		"import_sources",
+		"jobs",
+		"report_runs",
+		"report_run_artifacts",
+		"custom_views",
+		"eligibility_rules",
+		"failed_imports",
+		"collection_sources",
+		"collected_files",
+		"physical_host_aliases",
+		"physical_host_merges",
+		"license_terms_history",
+		"product_codes_history",
+		"pvu_mappings",
+		"sites",
+		"organizations",
+		"org_entitlements",
+		"snapshots",
+		"snapshot_rows",
+		"node_aliases",
+		"detection_errors",
+		"import_lock",
+		"product_lifecycle",
+		"node_changes",
+		"product_instances",
+		"license_term_documents",
+		"contract_periods",
+		"peak_grace_windows",
+		"report_cache",
 	}
 
 	for _, table := range requiredTables {
@@ -0,0 +1,215 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// indexStatement matches the index definitions of the schema
+var indexStatement = regexp.MustCompile(`(?m)^CREATE INDEX IF NOT EXISTS (\w+) ON [^;]+;`)
+
+// tableReference matches the tables a query reads, with their alias
+var tableReference = regexp.MustCompile(`(?i)\b(?:FROM|JOIN)\s+(\w+)(?:\s+(?:AS\s+)?(\w+))?`)
+
+// notAlias are the keywords that can follow a table reference without an alias
+var notAlias = map[string]bool{
+	"on": true, "using": true, "where": true, "join": true, "left": true, "inner": true,
+	"cross": true, "natural": true, "full": true, "group": true, "order": true,
+	"union": true, "limit": true, "having": true, "window": true,
+}
+
+// PlanStep is one step of an SQLite query plan
+type PlanStep struct {
+	ID     int
+	Parent int
+	Detail string
+	Table  string // table read by a full scan, set by ExplainView
+}
+
+// FullScan reports whether the step reads a whole table, subquery or view
+// without an index
+func (s PlanStep) FullScan() bool {
+	return strings.HasPrefix(s.Detail, "SCAN ") && !strings.Contains(s.Detail, " INDEX")
+}
+
+// ExplainQueryPlan returns the query plan SQLite chooses for a query
+func ExplainQueryPlan(db *sql.DB, query string) ([]PlanStep, error) {
+	rows, err := db.Query("EXPLAIN QUERY PLAN " + query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+	defer rows.Close()
+
+	var steps []PlanStep
+	for rows.Next() {
+		var step PlanStep
+		var notUsed int
+		if err := rows.Scan(&step.ID, &step.Parent, &notUsed, &step.Detail); err != nil {
+			return nil, fmt.Errorf("failed to scan query plan: %w", err)
+		}
+		steps = append(steps, step)
+	}
+	return steps, rows.Err()
+}
+
+// ExplainView returns the query plan of a view, with the table of each full
+// scan of a table. The plan names the scanned tables by their aliases in the
+// view and the views it reads, so an alias used there for different tables
+// resolves to all of them.
+func ExplainView(db *sql.DB, view string) ([]PlanStep, error) {
+	steps, err := ExplainQueryPlan(db, "SELECT * FROM "+view)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := scannedTables(db, view)
+	if err != nil {
+		return nil, err
+	}
+	for i, step := range steps {
+		if !step.FullScan() {
+			continue
+		}
+		name := strings.Fields(strings.TrimPrefix(step.Detail, "SCAN "))[0]
+		steps[i].Table = strings.Join(tables[strings.ToLower(name)], " or ")
+	}
+	return steps, nil
+}
+
+// scannedTables maps the names and aliases a view and the views it reads use
+// for tables to the tables
+func scannedTables(db *sql.DB, view string) (map[string][]string, error) {
+	baseTables := map[string]bool{}
+	definitions := map[string]string{}
+	rows, err := db.Query(`SELECT type, name, COALESCE(sql, '') FROM sqlite_master WHERE type IN ('table', 'view')`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the schema: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind, name, definition string
+		if err := rows.Scan(&kind, &name, &definition); err != nil {
+			return nil, fmt.Errorf("failed to scan the schema: %w", err)
+		}
+		if kind == "table" {
+			baseTables[strings.ToLower(name)] = true
+		} else {
+			definitions[strings.ToLower(name)] = definition
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tables := map[string][]string{}
+	add := func(name, table string) {
+		for _, t := range tables[name] {
+			if t == table {
+				return
+			}
+		}
+		tables[name] = append(tables[name], table)
+	}
+	visited := map[string]bool{}
+	var visit func(view string)
+	visit = func(view string) {
+		if visited[view] {
+			return
+		}
+		visited[view] = true
+		for _, match := range tableReference.FindAllStringSubmatch(definitions[view], -1) {
+			table, alias := strings.ToLower(match[1]), strings.ToLower(match[2])
+			if _, isView := definitions[table]; isView {
+				visit(table)
+			}
+			if !baseTables[table] {
+				continue
+			}
+			add(table, table)
+			if alias != "" && !notAlias[alias] {
+				add(alias, table)
+			}
+		}
+	}
+	visit(strings.ToLower(view))
+	return tables, nil
+}
+
+// ReportingViews returns the names of the views of the database, in the order
+// they were created
+func ReportingViews(db *sql.DB) ([]string, error) {
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'view' ORDER BY rowid`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list views: %w", err)
+	}
+	defer rows.Close()
+
+	var views []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan view: %w", err)
+		}
+		views = append(views, name)
+	}
+	return views, rows.Err()
+}
+
+// MissingIndexes returns the indexes of the schema that the database lacks,
+// because it was initialized with an older schema version
+func MissingIndexes(db *sql.DB) ([]string, error) {
+	var missing []string
+	for _, match := range indexStatement.FindAllStringSubmatch(SchemaSQL, -1) {
+		var count int
+		err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?`, match[1]).Scan(&count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check index %s: %w", match[1], err)
+		}
+		if count == 0 {
+			missing = append(missing, match[1])
+		}
+	}
+	return missing, nil
+}
+
+// CreateMissingIndexes creates the indexes of the schema that the database
+// lacks and refreshes the statistics the query planner chooses indexes with.
+// It returns the names of the created indexes.
+func CreateMissingIndexes(db *sql.DB) ([]string, error) {
+	missing, err := MissingIndexes(db)
+	if err != nil || len(missing) == 0 {
+		return nil, err
+	}
+
+	create := map[string]bool{}
+	for _, name := range missing {
+		create[name] = true
+	}
+	for _, match := range indexStatement.FindAllStringSubmatch(SchemaSQL, -1) {
+		if !create[match[1]] {
+			continue
+		}
+		if _, err := db.Exec(match[0]); err != nil {
+			return nil, fmt.Errorf("failed to create index %s: %w", match[1], err)
+		}
+	}
+	if _, err := db.Exec("ANALYZE"); err != nil {
+		return nil, fmt.Errorf("failed to analyze the database: %w", err)
+	}
+	return missing, nil
+}
@@ -23,5 +23,5 @@ var SchemaSQL string
 
 // GetSchemaVersion returns the current schema version
 func GetSchemaVersion() string {
-	return "1.3.0" // Updated to include node_type, environment, inspection_level, node_fqdn in measurements table
+	return "1.47.0" // partition-cap rule only for eligible nodes
 }
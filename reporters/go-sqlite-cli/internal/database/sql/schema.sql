@@ -1,6 +1,6 @@
 -- Database Schema for IBM webMethods License Monitor
--- Version: 1.3.0
--- Last Updated: 2025-10-31
+-- Version: 1.47.0
+-- Last Updated: 2026-10-16
 --
 -- Based on REQUIREMENTS.md data model for license monitoring
 
@@ -35,6 +35,119 @@ CREATE TABLE IF NOT EXISTS product_codes (
     FOREIGN KEY (term_id) REFERENCES license_terms(term_id)
 );
 
+-- Product term mappings table (effective-dated license terms of a product code,
+-- added by 'terms map'). On the days a mapping is in force the reports attribute
+-- the product to its term instead of the term_id of product_codes; the periods
+-- of the mappings of a product do not overlap
+CREATE TABLE IF NOT EXISTS product_term_mappings (
+    mapping_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_mnemo_code TEXT NOT NULL,
+    term_id TEXT NOT NULL,
+    effective_from DATE NOT NULL,
+    effective_to DATE,
+    notes TEXT DEFAULT '',
+    created_by TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (product_mnemo_code) REFERENCES product_codes(product_mnemo_code),
+    FOREIGN KEY (term_id) REFERENCES license_terms(term_id)
+);
+
+-- Entitlements table (licensed capacity per license term)
+CREATE TABLE IF NOT EXISTS entitlements (
+    term_id TEXT PRIMARY KEY,
+    licensed_cores INTEGER NOT NULL DEFAULT 0 CHECK (licensed_cores >= 0),
+    licensed_pvu INTEGER NOT NULL DEFAULT 0 CHECK (licensed_pvu >= 0),
+    notes TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (term_id) REFERENCES license_terms(term_id)
+);
+
+-- License term documents table (term documents attached by 'terms attach')
+-- location is a file path or URL; the effective periods of the documents of
+-- a term do not overlap, effective_to is NULL while a document is in force
+CREATE TABLE IF NOT EXISTS license_term_documents (
+    document_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    term_id TEXT NOT NULL,
+    location TEXT NOT NULL,
+    effective_from DATE NOT NULL,
+    effective_to DATE,
+    description TEXT DEFAULT '',
+    attached_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (term_id) REFERENCES license_terms(term_id)
+);
+
+-- Contract periods table (contract periods per license term, added by 'terms
+-- period add'); peak reports run with --period compute the peaks of each term
+-- within its selected period. The periods of a term do not overlap.
+CREATE TABLE IF NOT EXISTS contract_periods (
+    term_id TEXT NOT NULL,
+    label TEXT NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL CHECK (end_date >= start_date),
+    notes TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (term_id, label),
+    FOREIGN KEY (term_id) REFERENCES license_terms(term_id)
+);
+
+-- Product thresholds table (highest license cores allowed per product, below
+-- the entitlement of its license term)
+CREATE TABLE IF NOT EXISTS product_thresholds (
+    product_mnemo_code TEXT PRIMARY KEY,
+    max_license_cores INTEGER NOT NULL CHECK (max_license_cores >= 0),
+    notes TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (product_mnemo_code) REFERENCES product_codes(product_mnemo_code)
+);
+
+-- Peak grace windows table (spikes shorter than grace_days consecutive days are
+-- ignored by the smoothed peak, e.g. transient DR failover usage)
+CREATE TABLE IF NOT EXISTS peak_grace_windows (
+    product_mnemo_code TEXT PRIMARY KEY,
+    grace_days INTEGER NOT NULL CHECK (grace_days >= 1),
+    notes TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (product_mnemo_code) REFERENCES product_codes(product_mnemo_code)
+);
+
+-- Sites table (datacenters or clusters landscape nodes are grouped by for chargeback)
+CREATE TABLE IF NOT EXISTS sites (
+    site_id TEXT PRIMARY KEY,
+    site_name TEXT NOT NULL,
+    description TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Organizations table (subsidiaries or tenants sharing the database whose
+-- license entitlements are reported separately)
+CREATE TABLE IF NOT EXISTS organizations (
+    org_id TEXT PRIMARY KEY,
+    org_name TEXT NOT NULL,
+    description TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Organization entitlements table (licensed capacity of an organization per
+-- license term); the reports of an organization compare its usage against
+-- these instead of the entitlements table
+CREATE TABLE IF NOT EXISTS org_entitlements (
+    org_id TEXT NOT NULL,
+    term_id TEXT NOT NULL,
+    licensed_cores INTEGER NOT NULL DEFAULT 0 CHECK (licensed_cores >= 0),
+    licensed_pvu INTEGER NOT NULL DEFAULT 0 CHECK (licensed_pvu >= 0),
+    notes TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (org_id, term_id),
+    FOREIGN KEY (org_id) REFERENCES organizations(org_id) ON DELETE CASCADE,
+    FOREIGN KEY (term_id) REFERENCES license_terms(term_id)
+);
+
 -- Landscape nodes table
 CREATE TABLE IF NOT EXISTS landscape_nodes (
     main_fqdn TEXT PRIMARY KEY,
@@ -42,8 +155,24 @@ CREATE TABLE IF NOT EXISTS landscape_nodes (
     mode TEXT NOT NULL CHECK (mode IN ('PROD', 'NON PROD')),
     expected_product_codes_list TEXT DEFAULT '',
     expected_cpu_no INTEGER,
+    site_id TEXT,
+    org_id TEXT,
+    -- Owner, team and cost center of the node, set by 'landscape import' and
+    -- 'landscape owner'; the cost center groups the usage for chargeback
+    owner TEXT NOT NULL DEFAULT '',
+    team TEXT NOT NULL DEFAULT '',
+    cost_center TEXT NOT NULL DEFAULT '',
+    -- Role of the node: active, standby, dr (disaster recovery) or decommissioned,
+    -- and for standby and DR nodes the IBM backup type (cold, warm or hot)
+    classification TEXT NOT NULL DEFAULT 'active' CHECK (classification IN ('active', 'standby', 'dr', 'decommissioned')),
+    standby_type TEXT NOT NULL DEFAULT '' CHECK (standby_type IN ('', 'cold', 'warm', 'hot')),
+    -- Day a decommissioned node was taken out of service; the reports leave out
+    -- its measurements taken after that day
+    decommissioned_on DATE,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (site_id) REFERENCES sites(site_id),
+    FOREIGN KEY (org_id) REFERENCES organizations(org_id)
 );
 
 -- Physical hosts table
@@ -77,6 +206,9 @@ CREATE TABLE IF NOT EXISTS measurements (
     processor_brand TEXT DEFAULT '',
     host_physical_cpus TEXT DEFAULT 'unknown',
     partition_cpus TEXT DEFAULT '',
+    -- partition_cpus rounded up to whole cores, NULL when no cap is reported;
+    -- considered_cpus of a virtualized node is capped at it
+    partition_cap_cores INTEGER,
     processor_eligible TEXT NOT NULL CHECK (processor_eligible IN ('true', 'false', 'unknown')),
     os_eligible TEXT NOT NULL CHECK (os_eligible IN ('true', 'false', 'unknown')),
     virt_eligible TEXT NOT NULL CHECK (virt_eligible IN ('true', 'false', 'unknown')),
@@ -84,6 +216,22 @@ CREATE TABLE IF NOT EXISTS measurements (
     physical_host_id TEXT DEFAULT '',
     host_id_method TEXT DEFAULT '',
     host_id_confidence TEXT DEFAULT '',
+    -- Containerized deployments: empty for nodes that are not containers.
+    -- container_cpu_limit is the limit as reported (e.g. 2, 1.5, 500m) and
+    -- container_limit_cores the limit rounded up to whole cores; considered_cpus
+    -- is capped at container_limit_cores when a limit is set
+    container_platform TEXT DEFAULT '',
+    container_namespace TEXT DEFAULT '',
+    container_pod TEXT DEFAULT '',
+    container_name TEXT DEFAULT '',
+    container_cpu_limit TEXT DEFAULT '',
+    container_limit_cores INTEGER,
+    -- Cloud instance metadata: empty for nodes that are not cloud instances
+    -- (cloud_provider is stored lowercase, e.g. aws, azure, gcp)
+    cloud_provider TEXT DEFAULT '',
+    instance_type TEXT DEFAULT '',
+    region TEXT DEFAULT '',
+    account_id TEXT DEFAULT '',
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (main_fqdn, detection_timestamp),
     FOREIGN KEY (main_fqdn) REFERENCES landscape_nodes(main_fqdn)
@@ -105,6 +253,31 @@ CREATE TABLE IF NOT EXISTS detected_products (
     FOREIGN KEY (product_mnemo_code) REFERENCES product_codes(product_mnemo_code)
 );
 
+-- Detected product installs table (install paths reported for a detected product)
+-- seq is the position of the path in the inspector output, starting at 1
+CREATE TABLE IF NOT EXISTS detected_product_installs (
+    main_fqdn TEXT NOT NULL,
+    product_mnemo_code TEXT NOT NULL,
+    detection_timestamp DATETIME NOT NULL,
+    seq INTEGER NOT NULL,
+    install_path TEXT NOT NULL,
+    PRIMARY KEY (main_fqdn, product_mnemo_code, detection_timestamp, seq),
+    FOREIGN KEY (main_fqdn, product_mnemo_code, detection_timestamp)
+        REFERENCES detected_products(main_fqdn, product_mnemo_code, detection_timestamp) ON DELETE CASCADE
+);
+
+-- Detected product processes table (running process command lines reported for a detected product)
+CREATE TABLE IF NOT EXISTS detected_product_processes (
+    main_fqdn TEXT NOT NULL,
+    product_mnemo_code TEXT NOT NULL,
+    detection_timestamp DATETIME NOT NULL,
+    seq INTEGER NOT NULL,
+    commandline TEXT NOT NULL,
+    PRIMARY KEY (main_fqdn, product_mnemo_code, detection_timestamp, seq),
+    FOREIGN KEY (main_fqdn, product_mnemo_code, detection_timestamp)
+        REFERENCES detected_products(main_fqdn, product_mnemo_code, detection_timestamp) ON DELETE CASCADE
+);
+
 -- Import sessions table (audit trail)
 CREATE TABLE IF NOT EXISTS import_sessions (
     session_id TEXT PRIMARY KEY,
@@ -115,9 +288,475 @@ CREATE TABLE IF NOT EXISTS import_sessions (
     records_updated INTEGER DEFAULT 0,
     records_skipped INTEGER DEFAULT 0,
     status TEXT NOT NULL CHECK (status IN ('success', 'partial', 'failed')),
+    error_message TEXT DEFAULT '',
+    file_sha256 TEXT,  -- SHA-256 of the imported file content, used to skip files already imported
+    main_fqdn TEXT,  -- Measurement written by the session, used by import rollback
+    detection_timestamp DATETIME,
+    signature_status TEXT CHECK (signature_status IN ('gpg', 'sha256')),  -- Verified signature of the file, NULL when not verified
+    signature_signer TEXT  -- Key ID and user ID of the OpenPGP signer
+);
+
+-- Import sources table (original CSV content of import sessions)
+-- Written by 'iwdlr import --archive-source' and read by 'iwdlr import show-source'
+CREATE TABLE IF NOT EXISTS import_sources (
+    session_id TEXT PRIMARY KEY,
+    file_sha256 TEXT NOT NULL,  -- SHA-256 of the uncompressed content, as recorded on the session
+    content BLOB NOT NULL,  -- gzip compressed CSV content
+    size INTEGER NOT NULL,  -- Uncompressed size in bytes
+    stored_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (session_id) REFERENCES import_sessions(session_id) ON DELETE CASCADE
+);
+
+-- Jobs table (queue of long-running operations)
+-- Submitted by 'iwdlr jobs submit' and run by 'iwdlr jobs work', the daemon or
+-- 'iwdlr serve --jobs', whichever claims the job first
+CREATE TABLE IF NOT EXISTS jobs (
+    job_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL CHECK (kind IN ('import', 'refresh-cache', 'report')),
+    args TEXT NOT NULL DEFAULT '[]',  -- JSON array of the command arguments
+    status TEXT NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'cancelled')),
+    cancel_requested INTEGER NOT NULL DEFAULT 0,
+    submitted_by TEXT,
+    submitted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    worker TEXT,  -- hostname:pid of the process running the job
+    started_at DATETIME,
+    heartbeat_at DATETIME,  -- Refreshed by the worker while the job runs
+    finished_at DATETIME,
+    output TEXT DEFAULT '',  -- Command output, the last 64 KiB
     error_message TEXT DEFAULT ''
 );
 
+-- Report runs table (reports written to files by the daemon, the job queue or
+-- a report run with --record), so that the numbers delivered can be proven later
+-- Read by 'iwdlr report runs list' and 'iwdlr report runs show'
+CREATE TABLE IF NOT EXISTS report_runs (
+    run_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    report TEXT NOT NULL,  -- Report command, e.g. compliance
+    command_line TEXT NOT NULL,  -- iwldr command line of the run
+    parameters TEXT NOT NULL DEFAULT '{}',  -- JSON object of the report flags in effect
+    format TEXT NOT NULL DEFAULT '',
+    trigger_source TEXT NOT NULL DEFAULT 'manual' CHECK (trigger_source IN ('manual', 'schedule', 'queue')),
+    job_name TEXT NOT NULL DEFAULT '',  -- Scheduled job or queued job ID
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('succeeded', 'failed')),
+    row_count INTEGER NOT NULL DEFAULT 0,
+    data_as_of DATETIME,  -- Latest measurement in the database when the report ran
+    error_message TEXT DEFAULT ''
+);
+
+-- Report run artifacts table (files written by a report run)
+CREATE TABLE IF NOT EXISTS report_run_artifacts (
+    run_id INTEGER NOT NULL,
+    path TEXT NOT NULL,  -- Absolute path of the file
+    size INTEGER NOT NULL,  -- Size in bytes
+    sha256 TEXT NOT NULL,  -- SHA-256 of the file content
+    PRIMARY KEY (run_id, path),
+    FOREIGN KEY (run_id) REFERENCES report_runs(run_id) ON DELETE CASCADE
+);
+
+-- Custom views table (user-defined reporting views installed by 'views install')
+-- The views are named vx_*, apart from the built-in v_* views
+CREATE TABLE IF NOT EXISTS custom_views (
+    name TEXT PRIMARY KEY,  -- View name, with the vx_ prefix
+    definition TEXT NOT NULL,  -- CREATE VIEW statement as installed
+    source_file TEXT DEFAULT '',  -- File the view was installed from
+    installed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Eligibility rules table (versioned rules files of 'eligibility load')
+-- The active version derives the eligibility of the imported measurements
+-- instead of the eligibility reported by the inspectors
+CREATE TABLE IF NOT EXISTS eligibility_rules (
+    version TEXT PRIMARY KEY,  -- Version given by the rules file
+    content TEXT NOT NULL,  -- Rules file as loaded
+    source_file TEXT DEFAULT '',  -- File the rules were loaded from, empty for the built-in rules
+    active INTEGER NOT NULL DEFAULT 0,  -- 1 for the version in use; at most one
+    loaded_by TEXT DEFAULT '',
+    loaded_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Failed imports table (dead-letter queue for files that could not be imported)
+-- A row is kept per file until a later import or retry of the same file succeeds
+CREATE TABLE IF NOT EXISTS failed_imports (
+    file_path TEXT PRIMARY KEY,  -- Absolute path of the file as last seen on disk
+    error_message TEXT NOT NULL,
+    attempt_count INTEGER NOT NULL DEFAULT 1,
+    first_failed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_failed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Collection sources table (state of each remote host or drop server the collect command downloads from)
+CREATE TABLE IF NOT EXISTS collection_sources (
+    source_name TEXT PRIMARY KEY,
+    address TEXT NOT NULL,
+    remote_dir TEXT NOT NULL,
+    last_run_at DATETIME,
+    last_success_at DATETIME,
+    last_error TEXT DEFAULT '',
+    files_collected INTEGER NOT NULL DEFAULT 0
+);
+
+-- Collected files table (remote files already downloaded, so a run only fetches new or changed files)
+CREATE TABLE IF NOT EXISTS collected_files (
+    source_name TEXT NOT NULL,
+    remote_path TEXT NOT NULL,
+    remote_size INTEGER NOT NULL,
+    remote_mtime DATETIME NOT NULL,
+    local_path TEXT NOT NULL,
+    collected_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (source_name, remote_path),
+    FOREIGN KEY (source_name) REFERENCES collection_sources(source_name)
+);
+
+-- Physical host aliases table (host IDs renamed or merged into another one)
+-- Imports store measurements reporting an alias under the surviving physical_host_id
+CREATE TABLE IF NOT EXISTS physical_host_aliases (
+    alias_id TEXT PRIMARY KEY,
+    physical_host_id TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Hypervisor hosts table (ESXi hosts read from vCenter by 'sync vcenter', and
+-- other hypervisor hosts such as HMC managed Power servers loaded by 'import
+-- host-capacity'); physical_cores is the authoritative core count of the host
+CREATE TABLE IF NOT EXISTS hypervisor_hosts (
+    physical_host_id TEXT PRIMARY KEY,
+    source TEXT NOT NULL,
+    host_ref TEXT NOT NULL,
+    host_name TEXT NOT NULL,
+    host_uuid TEXT DEFAULT '',
+    cpu_packages INTEGER,
+    physical_cores INTEGER NOT NULL,
+    synced_at DATETIME NOT NULL
+);
+
+-- Hypervisor VMs table (VM placement read from vCenter by 'sync vcenter')
+-- main_fqdn is the landscape node of the VM, NULL when none matches
+CREATE TABLE IF NOT EXISTS hypervisor_vms (
+    source TEXT NOT NULL,
+    vm_ref TEXT NOT NULL,
+    vm_name TEXT NOT NULL,
+    guest_hostname TEXT DEFAULT '',
+    main_fqdn TEXT,
+    physical_host_id TEXT NOT NULL,
+    vcpus INTEGER,
+    power_state TEXT DEFAULT '',
+    synced_at DATETIME NOT NULL,
+    PRIMARY KEY (source, vm_ref)
+);
+
+-- Host ID corrections table (low confidence physical host IDs of a node's
+-- measurements replaced with the host vCenter runs it on)
+CREATE TABLE IF NOT EXISTS host_id_corrections (
+    correction_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    main_fqdn TEXT NOT NULL,
+    old_host_id TEXT NOT NULL,
+    new_host_id TEXT NOT NULL,
+    source TEXT NOT NULL,
+    measurements_updated INTEGER NOT NULL DEFAULT 0,
+    corrected_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Host confidence escalations table (physical host ID confidence set by the
+-- rules of 'hosts confidence'); the sub-capacity reports use it instead of the
+-- confidence the inspectors reported in the measurements
+CREATE TABLE IF NOT EXISTS host_confidence_escalations (
+    physical_host_id TEXT PRIMARY KEY,
+    reported_confidence TEXT NOT NULL,
+    confidence TEXT NOT NULL CHECK (confidence IN ('high', 'medium', 'low')),
+    rule TEXT NOT NULL,
+    evidence TEXT DEFAULT '',
+    evaluated_at DATETIME NOT NULL
+);
+
+-- Host confirmations table (physical host IDs confirmed by hand with 'hosts
+-- confirm', the evidence of the manual confidence rules)
+CREATE TABLE IF NOT EXISTS host_confirmations (
+    physical_host_id TEXT PRIMARY KEY,
+    confirmed_by TEXT DEFAULT '',
+    reason TEXT DEFAULT '',
+    confirmed_at DATETIME NOT NULL
+);
+
+-- Node aliases table (former FQDNs of renamed or re-addressed nodes)
+-- Imports store measurements reporting an alias under main_fqdn
+CREATE TABLE IF NOT EXISTS node_aliases (
+    alias_fqdn TEXT PRIMARY KEY,
+    main_fqdn TEXT NOT NULL,
+    measurements_moved INTEGER NOT NULL DEFAULT 0,
+    reason TEXT DEFAULT '',
+    created_by TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Detection errors table (inspector runs reporting DETECTION_RESULT=ERROR)
+-- One row per node and detection time; re-importing the file updates it
+CREATE TABLE IF NOT EXISTS detection_errors (
+    main_fqdn TEXT NOT NULL,
+    detection_timestamp DATETIME NOT NULL,
+    hostname TEXT NOT NULL,
+    error_message TEXT NOT NULL,
+    source_file TEXT NOT NULL,
+    recorded_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (main_fqdn, detection_timestamp)
+);
+
+-- Import lock table (advisory lock keeping importer processes one at a time)
+-- The holder refreshes refreshed_at every minute while it runs; a stale lock is taken over
+CREATE TABLE IF NOT EXISTS import_lock (
+    lock_name TEXT PRIMARY KEY,
+    holder TEXT NOT NULL,        -- hostname:pid of the importing process
+    command TEXT NOT NULL,
+    acquired_at DATETIME NOT NULL,
+    refreshed_at DATETIME NOT NULL
+);
+
+-- Product lifecycle table (first and last detection of each product per node)
+-- Recomputed from detected_products by every import, rollback and node alias
+CREATE TABLE IF NOT EXISTS product_lifecycle (
+    main_fqdn TEXT NOT NULL,
+    product_mnemo_code TEXT NOT NULL,
+    first_detected DATETIME NOT NULL,
+    last_detected DATETIME NOT NULL,
+    detection_count INTEGER NOT NULL DEFAULT 0,  -- measurements the product was present in
+    PRIMARY KEY (main_fqdn, product_mnemo_code)
+);
+
+-- Databases created before the table get it filled from the detections kept so far
+INSERT OR IGNORE INTO product_lifecycle (main_fqdn, product_mnemo_code, first_detected, last_detected, detection_count)
+SELECT main_fqdn, product_mnemo_code, MIN(detection_timestamp), MAX(detection_timestamp), COUNT(*)
+FROM detected_products
+WHERE status = 'present'
+GROUP BY main_fqdn, product_mnemo_code;
+
+-- Node changes table (fields of a measurement that differ from the previous
+-- measurement of the node, e.g. CPU count, OS version or virtualization)
+-- Recomputed from measurements by every import, rollback and node alias
+CREATE TABLE IF NOT EXISTS node_changes (
+    main_fqdn TEXT NOT NULL,
+    detection_timestamp DATETIME NOT NULL,
+    previous_timestamp DATETIME NOT NULL,
+    field TEXT NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    PRIMARY KEY (main_fqdn, detection_timestamp, field)
+);
+
+-- Databases created before the table get it filled from the measurements kept so far
+INSERT OR IGNORE INTO node_changes (main_fqdn, detection_timestamp, previous_timestamp, field, old_value, new_value)
+WITH history AS (
+    SELECT main_fqdn, detection_timestamp, LAG(detection_timestamp) OVER w AS previous_timestamp,
+    CAST(cpu_count AS TEXT) AS cpu_count, CAST(LAG(cpu_count) OVER w AS TEXT) AS previous_cpu_count,
+    CAST(considered_cpus AS TEXT) AS considered_cpus, CAST(LAG(considered_cpus) OVER w AS TEXT) AS previous_considered_cpus,
+    CAST(os_name AS TEXT) AS os_name, CAST(LAG(os_name) OVER w AS TEXT) AS previous_os_name,
+    CAST(os_version AS TEXT) AS os_version, CAST(LAG(os_version) OVER w AS TEXT) AS previous_os_version,
+    CAST(is_virtualized AS TEXT) AS is_virtualized, CAST(LAG(is_virtualized) OVER w AS TEXT) AS previous_is_virtualized,
+    CAST(virt_type AS TEXT) AS virt_type, CAST(LAG(virt_type) OVER w AS TEXT) AS previous_virt_type,
+    CAST(host_physical_cpus AS TEXT) AS host_physical_cpus, CAST(LAG(host_physical_cpus) OVER w AS TEXT) AS previous_host_physical_cpus,
+    CAST(physical_host_id AS TEXT) AS physical_host_id, CAST(LAG(physical_host_id) OVER w AS TEXT) AS previous_physical_host_id,
+    CAST(processor_eligible AS TEXT) AS processor_eligible, CAST(LAG(processor_eligible) OVER w AS TEXT) AS previous_processor_eligible,
+    CAST(os_eligible AS TEXT) AS os_eligible, CAST(LAG(os_eligible) OVER w AS TEXT) AS previous_os_eligible,
+    CAST(virt_eligible AS TEXT) AS virt_eligible, CAST(LAG(virt_eligible) OVER w AS TEXT) AS previous_virt_eligible
+    FROM measurements
+    WINDOW w AS (PARTITION BY main_fqdn ORDER BY detection_timestamp)
+)
+SELECT main_fqdn, detection_timestamp, previous_timestamp, 'cpu_count', previous_cpu_count, cpu_count
+FROM history WHERE previous_timestamp IS NOT NULL AND previous_cpu_count IS NOT cpu_count
+UNION ALL
+SELECT main_fqdn, detection_timestamp, previous_timestamp, 'considered_cpus', previous_considered_cpus, considered_cpus
+FROM history WHERE previous_timestamp IS NOT NULL AND previous_considered_cpus IS NOT considered_cpus
+UNION ALL
+SELECT main_fqdn, detection_timestamp, previous_timestamp, 'os_name', previous_os_name, os_name
+FROM history WHERE previous_timestamp IS NOT NULL AND previous_os_name IS NOT os_name
+UNION ALL
+SELECT main_fqdn, detection_timestamp, previous_timestamp, 'os_version', previous_os_version, os_version
+FROM history WHERE previous_timestamp IS NOT NULL AND previous_os_version IS NOT os_version
+UNION ALL
+SELECT main_fqdn, detection_timestamp, previous_timestamp, 'is_virtualized', previous_is_virtualized, is_virtualized
+FROM history WHERE previous_timestamp IS NOT NULL AND previous_is_virtualized IS NOT is_virtualized
+UNION ALL
+SELECT main_fqdn, detection_timestamp, previous_timestamp, 'virt_type', previous_virt_type, virt_type
+FROM history WHERE previous_timestamp IS NOT NULL AND previous_virt_type IS NOT virt_type
+UNION ALL
+SELECT main_fqdn, detection_timestamp, previous_timestamp, 'host_physical_cpus', previous_host_physical_cpus, host_physical_cpus
+FROM history WHERE previous_timestamp IS NOT NULL AND previous_host_physical_cpus IS NOT host_physical_cpus
+UNION ALL
+SELECT main_fqdn, detection_timestamp, previous_timestamp, 'physical_host_id', previous_physical_host_id, physical_host_id
+FROM history WHERE previous_timestamp IS NOT NULL AND previous_physical_host_id IS NOT physical_host_id
+UNION ALL
+SELECT main_fqdn, detection_timestamp, previous_timestamp, 'processor_eligible', previous_processor_eligible, processor_eligible
+FROM history WHERE previous_timestamp IS NOT NULL AND previous_processor_eligible IS NOT processor_eligible
+UNION ALL
+SELECT main_fqdn, detection_timestamp, previous_timestamp, 'os_eligible', previous_os_eligible, os_eligible
+FROM history WHERE previous_timestamp IS NOT NULL AND previous_os_eligible IS NOT os_eligible
+UNION ALL
+SELECT main_fqdn, detection_timestamp, previous_timestamp, 'virt_eligible', previous_virt_eligible, virt_eligible
+FROM history WHERE previous_timestamp IS NOT NULL AND previous_virt_eligible IS NOT virt_eligible;
+
+-- Product instances table (one row per install path of a product on a node)
+-- An install is running in a detection when a process command line of the
+-- product contains its path. Recomputed from detected_product_installs and
+-- detected_product_processes by every import, rollback and node alias
+CREATE TABLE IF NOT EXISTS product_instances (
+    main_fqdn TEXT NOT NULL,
+    product_mnemo_code TEXT NOT NULL,
+    install_path TEXT NOT NULL,
+    first_detected DATETIME NOT NULL,
+    last_detected DATETIME NOT NULL,
+    last_running DATETIME,                       -- NULL if never seen running
+    detection_count INTEGER NOT NULL DEFAULT 0,  -- measurements the install was reported in
+    running_count INTEGER NOT NULL DEFAULT 0,    -- measurements it was running in
+    PRIMARY KEY (main_fqdn, product_mnemo_code, install_path)
+);
+
+-- Databases created before the table get it filled from the detections kept so far
+INSERT OR IGNORE INTO product_instances (
+    main_fqdn, product_mnemo_code, install_path, first_detected, last_detected,
+    last_running, detection_count, running_count
+)
+SELECT
+    main_fqdn, product_mnemo_code, install_path,
+    MIN(detection_timestamp), MAX(detection_timestamp),
+    MAX(CASE WHEN running THEN detection_timestamp END),
+    COUNT(DISTINCT detection_timestamp),
+    COUNT(DISTINCT CASE WHEN running THEN detection_timestamp END)
+FROM (
+    SELECT
+        i.main_fqdn, i.product_mnemo_code, i.install_path, i.detection_timestamp,
+        EXISTS (
+            SELECT 1 FROM detected_product_processes p
+            WHERE p.main_fqdn = i.main_fqdn
+              AND p.product_mnemo_code = i.product_mnemo_code
+              AND p.detection_timestamp = i.detection_timestamp
+              AND (instr(p.commandline || ' ', i.install_path || ' ') > 0
+                OR instr(p.commandline, i.install_path || '/') > 0
+                OR instr(p.commandline, i.install_path || '\') > 0
+                OR instr(p.commandline, i.install_path || '"') > 0)
+        ) AS running
+    FROM detected_product_installs i
+)
+GROUP BY main_fqdn, product_mnemo_code, install_path;
+
+-- Physical host merges table (audit trail of the hosts rename and merge commands)
+CREATE TABLE IF NOT EXISTS physical_host_merges (
+    merge_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    operation TEXT NOT NULL CHECK (operation IN ('rename', 'merge')),
+    source_host_id TEXT NOT NULL,
+    target_host_id TEXT NOT NULL,
+    measurements_updated INTEGER NOT NULL DEFAULT 0,
+    reason TEXT DEFAULT '',
+    performed_by TEXT DEFAULT '',
+    performed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- License terms history table (prior and new values of every license term
+-- inserted or changed by a reference load, since changing a term retroactively
+-- alters the compliance numbers; changed_columns lists the updated columns)
+CREATE TABLE IF NOT EXISTS license_terms_history (
+    history_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    term_id TEXT NOT NULL,
+    change TEXT NOT NULL CHECK (change IN ('insert', 'update')),
+    changed_columns TEXT DEFAULT '',
+    old_program_number TEXT,
+    old_program_name TEXT,
+    new_program_number TEXT,
+    new_program_name TEXT,
+    source TEXT DEFAULT '',
+    changed_by TEXT DEFAULT '',
+    changed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Product codes history table (prior and new values of every product code
+-- inserted or changed by a reference load, including its license term mapping)
+CREATE TABLE IF NOT EXISTS product_codes_history (
+    history_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_mnemo_code TEXT NOT NULL,
+    change TEXT NOT NULL CHECK (change IN ('insert', 'update')),
+    changed_columns TEXT DEFAULT '',
+    old_ibm_product_code TEXT,
+    old_product_name TEXT,
+    old_mode TEXT,
+    old_term_id TEXT,
+    old_notes TEXT,
+    new_ibm_product_code TEXT,
+    new_product_name TEXT,
+    new_mode TEXT,
+    new_term_id TEXT,
+    new_notes TEXT,
+    source TEXT DEFAULT '',
+    changed_by TEXT DEFAULT '',
+    changed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- PVU mappings table (processor value units per core, from the IBM PVU table)
+-- processor_brand is a prefix of the measured brand; an empty processor_model
+-- matches every model of the brand, otherwise the brand must contain it
+CREATE TABLE IF NOT EXISTS pvu_mappings (
+    processor_vendor TEXT NOT NULL,
+    processor_brand TEXT NOT NULL,
+    processor_model TEXT NOT NULL DEFAULT '',
+    pvu_per_core INTEGER NOT NULL CHECK (pvu_per_core > 0),
+    notes TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (processor_vendor, processor_brand, processor_model)
+);
+
+-- Snapshots table (report aggregates frozen by 'snapshot create', so that the
+-- reported numbers can be reproduced after later corrections or re-imports)
+CREATE TABLE IF NOT EXISTS snapshots (
+    label TEXT PRIMARY KEY,
+    period_from DATE,
+    period_to DATE,
+    product_mnemo_code TEXT DEFAULT '',
+    mode TEXT DEFAULT '',
+    schema_version TEXT NOT NULL,
+    notes TEXT DEFAULT '',
+    created_by TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Snapshot rows table (the report rows of a snapshot as JSON, in report order)
+CREATE TABLE IF NOT EXISTS snapshot_rows (
+    label TEXT NOT NULL,
+    report TEXT NOT NULL CHECK (report IN ('peak', 'monthly-peak', 'compliance')),
+    row_number INTEGER NOT NULL,
+    row_data TEXT NOT NULL,
+    PRIMARY KEY (label, report, row_number),
+    FOREIGN KEY (label) REFERENCES snapshots(label)
+);
+
+-- Snapshots are immutable once created
+CREATE TRIGGER IF NOT EXISTS trg_snapshots_no_update BEFORE UPDATE ON snapshots
+BEGIN
+    SELECT RAISE(ABORT, 'snapshots are immutable');
+END;
+CREATE TRIGGER IF NOT EXISTS trg_snapshots_no_delete BEFORE DELETE ON snapshots
+BEGIN
+    SELECT RAISE(ABORT, 'snapshots are immutable');
+END;
+CREATE TRIGGER IF NOT EXISTS trg_snapshot_rows_no_update BEFORE UPDATE ON snapshot_rows
+BEGIN
+    SELECT RAISE(ABORT, 'snapshots are immutable');
+END;
+CREATE TRIGGER IF NOT EXISTS trg_snapshot_rows_no_delete BEFORE DELETE ON snapshot_rows
+BEGIN
+    SELECT RAISE(ABORT, 'snapshots are immutable');
+END;
+
+-- Report cache table (one row per reporting view materialized into its
+-- cache_<view> table by 'db refresh-cache'; the reports read the cache table
+-- while no import happened since the refresh)
+CREATE TABLE IF NOT EXISTS report_cache (
+    view_name TEXT PRIMARY KEY,
+    refreshed_at DATETIME NOT NULL,
+    row_count INTEGER NOT NULL,
+    -- Count and latest imported_at of the import sessions at the refresh
+    import_fingerprint TEXT NOT NULL
+);
+
 -- Indexes for performance
 CREATE INDEX IF NOT EXISTS idx_measurements_timestamp ON measurements(detection_timestamp);
 CREATE INDEX IF NOT EXISTS idx_measurements_fqdn ON measurements(main_fqdn);
@@ -127,6 +766,30 @@ CREATE INDEX IF NOT EXISTS idx_detected_products_status ON detected_products(sta
 CREATE INDEX IF NOT EXISTS idx_product_codes_term ON product_codes(term_id);
 CREATE INDEX IF NOT EXISTS idx_import_sessions_hostname ON import_sessions(hostname);
 CREATE INDEX IF NOT EXISTS idx_import_sessions_timestamp ON import_sessions(imported_at);
+CREATE INDEX IF NOT EXISTS idx_import_sessions_sha256 ON import_sessions(file_sha256);
+CREATE INDEX IF NOT EXISTS idx_failed_imports_last_failed ON failed_imports(last_failed_at);
+CREATE INDEX IF NOT EXISTS idx_physical_host_aliases_target ON physical_host_aliases(physical_host_id);
+CREATE INDEX IF NOT EXISTS idx_hypervisor_vms_node ON hypervisor_vms(main_fqdn);
+CREATE INDEX IF NOT EXISTS idx_landscape_nodes_site ON landscape_nodes(site_id);
+CREATE INDEX IF NOT EXISTS idx_landscape_nodes_org ON landscape_nodes(org_id);
+CREATE INDEX IF NOT EXISTS idx_landscape_nodes_classification ON landscape_nodes(classification);
+CREATE INDEX IF NOT EXISTS idx_detection_errors_timestamp ON detection_errors(detection_timestamp);
+CREATE INDEX IF NOT EXISTS idx_license_term_documents_term ON license_term_documents(term_id, effective_from);
+CREATE INDEX IF NOT EXISTS idx_product_term_mappings_product ON product_term_mappings(product_mnemo_code, effective_from);
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status, job_id);
+CREATE INDEX IF NOT EXISTS idx_report_runs_report ON report_runs(report, started_at);
+CREATE INDEX IF NOT EXISTS idx_report_run_artifacts_sha256 ON report_run_artifacts(sha256);
+
+-- Covering indexes for the reporting views. Databases initialized before 1.30.0
+-- get them with 'iwdlr db analyze-performance --create-indexes'.
+-- Detected products present on a node at a measurement (the joins of the daily views)
+CREATE INDEX IF NOT EXISTS idx_detected_products_present ON detected_products(status, main_fqdn, detection_timestamp, product_mnemo_code, install_count);
+-- Measurements of one product over a period
+CREATE INDEX IF NOT EXISTS idx_detected_products_product_timestamp ON detected_products(product_mnemo_code, detection_timestamp, status);
+-- Latest measurement of each node
+CREATE INDEX IF NOT EXISTS idx_measurements_fqdn_latest ON measurements(main_fqdn, detection_timestamp DESC);
+-- Daily grouping and the 31-day window of the peak usage views
+CREATE INDEX IF NOT EXISTS idx_measurements_date ON measurements(DATE(detection_timestamp), main_fqdn);
 
 -- View: Latest measurements for each node (helper view)
 CREATE VIEW IF NOT EXISTS v_latest_measurements AS
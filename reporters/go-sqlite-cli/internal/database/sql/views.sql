@@ -1,8 +1,148 @@
 -- Reporting Views for IBM webMethods License Monitor
--- Version: 1.2.0
--- Last Updated: 2025-10-31
+-- Version: 1.47.0
+-- Last Updated: 2026-10-16
 --
 -- These views provide various aggregations and reports for license monitoring
+--
+-- Measurements are attributed to the license term a product is mapped to on the
+-- measurement date: the product_term_mappings row in force that day, otherwise
+-- the term_id of product_codes. A product moved to another program mid-year is
+-- reported under its former term for the days before the move.
+
+-- View 0: Reported Measurements
+-- The measurements counted by the reports: the measurements of a decommissioned
+-- node taken after its decommission date are left out
+CREATE VIEW IF NOT EXISTS v_reported_measurements AS
+SELECT m.*
+FROM measurements m
+WHERE NOT EXISTS (
+    SELECT 1 FROM landscape_nodes n
+    WHERE n.main_fqdn = m.main_fqdn
+        AND n.decommissioned_on IS NOT NULL
+        AND DATE(m.detection_timestamp) > DATE(n.decommissioned_on)
+);
+
+-- View 0b: Daily Measurements
+-- One measurement per node and day, so that nodes reporting several times a day
+-- are counted once. The measurement is chosen by the daily aggregation policy,
+-- the daily_aggregation key of schema_metadata (see 'iwdlr db daily-aggregation'):
+--   max     - the measurement with the most license cores, latest on ties (default)
+--   last    - the latest measurement of the day
+--   average - the latest measurement, with its cores averaged over the day
+--             (rounded to whole cores)
+-- The products detected are those of the chosen measurement.
+CREATE VIEW IF NOT EXISTS v_daily_measurements AS
+WITH ranked AS (
+    SELECT 
+        m.*,
+        p.policy,
+        ROW_NUMBER() OVER (
+            PARTITION BY m.main_fqdn, DATE(m.detection_timestamp)
+            ORDER BY CASE WHEN p.policy = 'max' THEN m.considered_cpus ELSE 0 END DESC,
+                m.detection_timestamp DESC
+        ) as day_rank,
+        AVG(m.cpu_count) OVER node_day as avg_cpu_count,
+        AVG(m.considered_cpus) OVER node_day as avg_considered_cpus
+    FROM v_reported_measurements m
+    CROSS JOIN (
+        SELECT COALESCE((SELECT value FROM schema_metadata WHERE key = 'daily_aggregation'), 'max') as policy
+    ) p
+    WINDOW node_day AS (PARTITION BY m.main_fqdn, DATE(m.detection_timestamp))
+)
+SELECT 
+    main_fqdn,
+    detection_timestamp,
+    session_directory,
+    node_type,
+    environment,
+    inspection_level,
+    node_fqdn,
+    os_name,
+    os_version,
+    CASE WHEN policy = 'average' THEN CAST(ROUND(avg_cpu_count) AS INTEGER) ELSE cpu_count END as cpu_count,
+    is_virtualized,
+    virt_type,
+    processor_vendor,
+    processor_brand,
+    host_physical_cpus,
+    partition_cpus,
+    partition_cap_cores,
+    processor_eligible,
+    os_eligible,
+    virt_eligible,
+    CASE WHEN policy = 'average' THEN CAST(ROUND(avg_considered_cpus) AS INTEGER) ELSE considered_cpus END as considered_cpus,
+    physical_host_id,
+    host_id_method,
+    host_id_confidence,
+    container_platform,
+    container_namespace,
+    container_pod,
+    container_name,
+    container_cpu_limit,
+    container_limit_cores,
+    cloud_provider,
+    instance_type,
+    region,
+    account_id,
+    created_at
+FROM ranked
+WHERE day_rank = 1;
+
+-- View 0c: Carry-Forward Measurements
+-- The daily measurement of each node with its detected products, and the same
+-- measurement carried forward over the following days the node did not report,
+-- so that a collection outage does not look like the node stopped running. A
+-- measurement is carried for up to 31 days, never past the next measurement of
+-- the node, the latest measured day of the database or the decommission date
+-- of the node. carried_days is 0 on measured days and the days since the
+-- measurement on carried ones; the reports only count carried days up to their
+-- --carry-forward option.
+CREATE VIEW IF NOT EXISTS v_carry_forward_measurements AS
+WITH RECURSIVE measured AS (
+    SELECT 
+        m.main_fqdn,
+        m.detection_timestamp,
+        DATE(m.detection_timestamp) as measured_date,
+        -- The first day the measurement is no longer carried to
+        MIN(
+            COALESCE(
+                LEAD(DATE(m.detection_timestamp)) OVER (PARTITION BY m.main_fqdn ORDER BY m.detection_timestamp),
+                DATE(latest.latest_date, '+1 day')),
+            DATE(m.detection_timestamp, '+32 days'),
+            COALESCE(DATE(n.decommissioned_on, '+1 day'), '9999-12-31')
+        ) as carried_until
+    FROM v_daily_measurements m
+    CROSS JOIN (SELECT MAX(DATE(detection_timestamp)) as latest_date FROM measurements) latest
+    LEFT JOIN landscape_nodes n ON n.main_fqdn = m.main_fqdn
+),
+node_days(main_fqdn, detection_timestamp, measurement_date, carried_days, carried_until) AS (
+    SELECT main_fqdn, detection_timestamp, measured_date, 0, carried_until
+    FROM measured
+    UNION ALL
+    SELECT main_fqdn, detection_timestamp, DATE(measurement_date, '+1 day'), carried_days + 1, carried_until
+    FROM node_days
+    WHERE DATE(measurement_date, '+1 day') < carried_until
+)
+SELECT 
+    nd.measurement_date,
+    nd.carried_days,
+    m.main_fqdn,
+    m.detection_timestamp,
+    d.product_mnemo_code,
+    d.status,
+    d.install_count,
+    m.cpu_count,
+    m.considered_cpus,
+    m.is_virtualized,
+    m.os_eligible,
+    m.virt_eligible,
+    m.physical_host_id,
+    m.host_physical_cpus
+FROM node_days nd
+JOIN v_daily_measurements m ON m.main_fqdn = nd.main_fqdn
+    AND m.detection_timestamp = nd.detection_timestamp
+JOIN detected_products d ON d.main_fqdn = nd.main_fqdn
+    AND d.detection_timestamp = nd.detection_timestamp;
 
 -- View 1: Core Aggregation by Product
 -- Shows daily core counts per product with eligibility breakdown
@@ -17,6 +157,7 @@ SELECT
     -- VM/Partition cores
     m.cpu_count as vm_cores,
     CAST(m.partition_cpus AS INTEGER) as partition_cores,
+    m.partition_cap_cores,
     -- Eligibility flags
     m.processor_eligible,
     m.os_eligible,
@@ -52,7 +193,7 @@ SELECT
     m.os_version
 FROM detected_products d
 JOIN product_codes p ON d.product_mnemo_code = p.product_mnemo_code
-JOIN measurements m ON d.main_fqdn = m.main_fqdn 
+JOIN v_daily_measurements m ON d.main_fqdn = m.main_fqdn 
     AND d.detection_timestamp = m.detection_timestamp
 JOIN landscape_nodes n ON d.main_fqdn = n.main_fqdn
 WHERE d.status = 'present'
@@ -63,16 +204,16 @@ ORDER BY measurement_date DESC, p.product_name, n.hostname;
 -- Requirements:
 --   a) Running products: count virtual and physical cores once per host per day
 --   b) Installed products: count cores based on install_count
---   c) Multiple datapoints same day: count cores once (use MAX timestamp)
+--   c) Multiple datapoints same day: count cores once (the daily aggregation policy of v_daily_measurements)
 --   d) Physical host deduplication: count physical cores once per physical host
 CREATE VIEW IF NOT EXISTS v_daily_product_summary AS
 WITH latest_daily_measurements AS (
-    -- Get latest measurement per host per day (requirement c)
+    -- The measurement of each host per day (requirement c)
     SELECT 
         DATE(m.detection_timestamp) as measurement_date,
         m.main_fqdn,
         MAX(m.detection_timestamp) as latest_timestamp
-    FROM measurements m
+    FROM v_daily_measurements m
     GROUP BY DATE(m.detection_timestamp), m.main_fqdn
 ),
 running_cores AS (
@@ -103,12 +244,19 @@ running_cores AS (
         END) as running_physical_cores,
         COUNT(DISTINCT d.main_fqdn) as running_node_count
     FROM latest_daily_measurements ldm
-    JOIN measurements m ON ldm.main_fqdn = m.main_fqdn 
+    JOIN v_daily_measurements m ON ldm.main_fqdn = m.main_fqdn 
         AND ldm.latest_timestamp = m.detection_timestamp
     JOIN detected_products d ON m.main_fqdn = d.main_fqdn 
         AND m.detection_timestamp = d.detection_timestamp
     JOIN product_codes p ON d.product_mnemo_code = p.product_mnemo_code
-    JOIN license_terms l ON p.term_id = l.term_id
+    JOIN license_terms l ON l.term_id = COALESCE((
+        SELECT tm.term_id FROM product_term_mappings tm
+        WHERE tm.product_mnemo_code = p.product_mnemo_code
+          AND DATE(tm.effective_from) <= ldm.measurement_date
+          AND (tm.effective_to IS NULL OR DATE(tm.effective_to) >= ldm.measurement_date)
+        ORDER BY tm.effective_from DESC
+        LIMIT 1
+    ), p.term_id)
     WHERE d.status = 'present'
     GROUP BY ldm.measurement_date, p.product_mnemo_code, p.product_name, p.mode,
              l.term_id, l.program_number, l.program_name
@@ -142,12 +290,19 @@ installed_cores AS (
         END) as installed_physical_cores,
         COUNT(DISTINCT CASE WHEN d.install_count > 0 THEN d.main_fqdn END) as installed_node_count
     FROM latest_daily_measurements ldm
-    JOIN measurements m ON ldm.main_fqdn = m.main_fqdn 
+    JOIN v_daily_measurements m ON ldm.main_fqdn = m.main_fqdn 
         AND ldm.latest_timestamp = m.detection_timestamp
     JOIN detected_products d ON m.main_fqdn = d.main_fqdn 
         AND m.detection_timestamp = d.detection_timestamp
     JOIN product_codes p ON d.product_mnemo_code = p.product_mnemo_code
-    JOIN license_terms l ON p.term_id = l.term_id
+    JOIN license_terms l ON l.term_id = COALESCE((
+        SELECT tm.term_id FROM product_term_mappings tm
+        WHERE tm.product_mnemo_code = p.product_mnemo_code
+          AND DATE(tm.effective_from) <= ldm.measurement_date
+          AND (tm.effective_to IS NULL OR DATE(tm.effective_to) >= ldm.measurement_date)
+        ORDER BY tm.effective_from DESC
+        LIMIT 1
+    ), p.term_id)
     WHERE d.install_count > 0
     GROUP BY ldm.measurement_date, p.product_mnemo_code, p.product_name, p.mode,
              l.term_id, l.program_number, l.program_name
@@ -162,7 +317,7 @@ physical_host_cores AS (
             THEN CAST(m.host_physical_cpus AS INTEGER)
             ELSE NULL
         END) as max_physical_cores
-    FROM measurements m
+    FROM v_daily_measurements m
     WHERE m.physical_host_id != '' AND m.physical_host_id != 'unknown'
     GROUP BY DATE(m.detection_timestamp), m.physical_host_id
 ),
@@ -174,7 +329,7 @@ running_phys_hosts_detail AS (
         m.physical_host_id,
         phc.max_physical_cores
     FROM latest_daily_measurements ldm
-    JOIN measurements m ON ldm.main_fqdn = m.main_fqdn 
+    JOIN v_daily_measurements m ON ldm.main_fqdn = m.main_fqdn 
         AND ldm.latest_timestamp = m.detection_timestamp
     JOIN detected_products d ON m.main_fqdn = d.main_fqdn 
         AND m.detection_timestamp = d.detection_timestamp
@@ -203,7 +358,7 @@ installed_phys_hosts_detail AS (
         m.physical_host_id,
         phc.max_physical_cores
     FROM latest_daily_measurements ldm
-    JOIN measurements m ON ldm.main_fqdn = m.main_fqdn 
+    JOIN v_daily_measurements m ON ldm.main_fqdn = m.main_fqdn 
         AND ldm.latest_timestamp = m.detection_timestamp
     JOIN detected_products d ON m.main_fqdn = d.main_fqdn 
         AND m.detection_timestamp = d.detection_timestamp
@@ -269,7 +424,7 @@ WITH latest_daily_measurements AS (
         DATE(m.detection_timestamp) as measurement_date,
         m.main_fqdn,
         MAX(m.detection_timestamp) as latest_timestamp
-    FROM measurements m
+    FROM v_daily_measurements m
     GROUP BY DATE(m.detection_timestamp), m.main_fqdn
 )
 SELECT 
@@ -285,7 +440,7 @@ SELECT
     -- Latest timestamp for this physical host
     MAX(m.detection_timestamp) as latest_measurement
 FROM latest_daily_measurements ldm
-JOIN measurements m ON ldm.main_fqdn = m.main_fqdn 
+JOIN v_daily_measurements m ON ldm.main_fqdn = m.main_fqdn 
     AND ldm.latest_timestamp = m.detection_timestamp
 JOIN physical_hosts ph ON m.physical_host_id = ph.physical_host_id
 WHERE m.physical_host_id != '' AND m.physical_host_id != 'unknown'
@@ -301,7 +456,7 @@ WITH latest_daily_measurements AS (
         DATE(m.detection_timestamp) as measurement_date,
         m.main_fqdn,
         MAX(m.detection_timestamp) as latest_timestamp
-    FROM measurements m
+    FROM v_daily_measurements m
     GROUP BY DATE(m.detection_timestamp), m.main_fqdn
 )
 SELECT 
@@ -312,7 +467,7 @@ SELECT
     d.status,
     d.install_count
 FROM latest_daily_measurements ldm
-JOIN measurements m ON ldm.main_fqdn = m.main_fqdn 
+JOIN v_daily_measurements m ON ldm.main_fqdn = m.main_fqdn 
     AND ldm.latest_timestamp = m.detection_timestamp
 JOIN detected_products d ON m.main_fqdn = d.main_fqdn 
     AND m.detection_timestamp = d.detection_timestamp
@@ -323,7 +478,30 @@ ORDER BY ldm.measurement_date DESC, p.product_mnemo_code;
 
 -- View 4: License Compliance Report
 -- Complete compliance report with proper core counting
+-- license_cores deduplicates ineligible cores per physical host, so VMs sharing
+-- a host are only counted once (eligible cores are summed directly)
 CREATE VIEW IF NOT EXISTS v_license_compliance_report AS
+WITH ineligible_host_cores AS (
+    SELECT 
+        DATE(m.detection_timestamp) as measurement_date,
+        d.product_mnemo_code,
+        CASE 
+            WHEN m.physical_host_id != '' AND m.physical_host_id != 'unknown' THEN m.physical_host_id
+            ELSE m.main_fqdn
+        END as host_key,
+        MAX(m.considered_cpus) as host_cores
+    FROM detected_products d
+    JOIN v_daily_measurements m ON d.main_fqdn = m.main_fqdn 
+        AND d.detection_timestamp = m.detection_timestamp
+    WHERE d.status = 'present'
+      AND (m.os_eligible = 'false' OR m.virt_eligible = 'false')
+    GROUP BY measurement_date, d.product_mnemo_code, host_key
+),
+ineligible_totals AS (
+    SELECT measurement_date, product_mnemo_code, SUM(host_cores) as ineligible_cores_dedup
+    FROM ineligible_host_cores
+    GROUP BY measurement_date, product_mnemo_code
+)
 SELECT 
     DATE(m.detection_timestamp) as measurement_date,
     p.product_mnemo_code,
@@ -352,6 +530,12 @@ SELECT
         THEN m.considered_cpus 
         ELSE 0 
     END) as ineligible_cores_sum,
+    -- License cores: eligible cores plus ineligible cores deduplicated per physical host
+    SUM(CASE 
+        WHEN m.os_eligible = 'true' AND m.virt_eligible = 'true' 
+        THEN m.considered_cpus 
+        ELSE 0 
+    END) + COALESCE(MAX(it.ineligible_cores_dedup), 0) as license_cores,
     -- Physical host details
     COUNT(DISTINCT CASE 
         WHEN m.physical_host_id != '' AND m.physical_host_id != 'unknown' 
@@ -362,11 +546,22 @@ SELECT
     COUNT(DISTINCT CASE WHEN m.is_virtualized = 'no' THEN m.main_fqdn END) as physical_nodes
 FROM detected_products d
 JOIN product_codes p ON d.product_mnemo_code = p.product_mnemo_code
-JOIN license_terms l ON p.term_id = l.term_id
-JOIN measurements m ON d.main_fqdn = m.main_fqdn 
+JOIN v_daily_measurements m ON d.main_fqdn = m.main_fqdn 
     AND d.detection_timestamp = m.detection_timestamp
+JOIN license_terms l ON l.term_id = COALESCE((
+    SELECT tm.term_id FROM product_term_mappings tm
+    WHERE tm.product_mnemo_code = p.product_mnemo_code
+      AND DATE(tm.effective_from) <= DATE(m.detection_timestamp)
+      AND (tm.effective_to IS NULL OR DATE(tm.effective_to) >= DATE(m.detection_timestamp))
+    ORDER BY tm.effective_from DESC
+    LIMIT 1
+), p.term_id)
+LEFT JOIN ineligible_totals it ON it.measurement_date = DATE(m.detection_timestamp)
+    AND it.product_mnemo_code = p.product_mnemo_code
 WHERE d.status = 'present'
-GROUP BY measurement_date, p.product_mnemo_code, p.product_name, p.mode, 
+-- Grouped on the day of m: the measurement_date of ineligible_totals is NULL on
+-- days without ineligible cores
+GROUP BY DATE(m.detection_timestamp), p.product_mnemo_code, p.product_name, p.mode, 
          l.term_id, l.program_number, l.program_name
 ORDER BY measurement_date DESC, p.product_name;
 
@@ -391,8 +586,10 @@ SELECT
     END as physical_cpus,
     m.os_name || ' ' || m.os_version as operating_system,
     m.os_eligible as eligible_os,
-    m.virt_eligible as eligible_virtualization
-FROM measurements m
+    m.virt_eligible as eligible_virtualization,
+    m.os_name,
+    COALESCE(m.virt_type, '') as virt_type
+FROM v_reported_measurements m
 JOIN detected_products d ON m.main_fqdn = d.main_fqdn 
     AND m.detection_timestamp = d.detection_timestamp
 ORDER BY date DESC, host_fqdn, product_code;
@@ -434,9 +631,16 @@ WITH daily_host_peaks AS (
         MAX(m.cpu_count) as max_actual_cores
     FROM detected_products d
     JOIN product_codes p ON d.product_mnemo_code = p.product_mnemo_code
-    JOIN license_terms l ON p.term_id = l.term_id
-    JOIN measurements m ON d.main_fqdn = m.main_fqdn 
+    JOIN v_daily_measurements m ON d.main_fqdn = m.main_fqdn 
         AND d.detection_timestamp = m.detection_timestamp
+    JOIN license_terms l ON l.term_id = COALESCE((
+        SELECT tm.term_id FROM product_term_mappings tm
+        WHERE tm.product_mnemo_code = p.product_mnemo_code
+          AND DATE(tm.effective_from) <= DATE(m.detection_timestamp)
+          AND (tm.effective_to IS NULL OR DATE(tm.effective_to) >= DATE(m.detection_timestamp))
+        ORDER BY tm.effective_from DESC
+        LIMIT 1
+    ), p.term_id)
     WHERE DATE(m.detection_timestamp) >= DATE('now', '-31 days')
     GROUP BY DATE(m.detection_timestamp), p.product_mnemo_code, p.ibm_product_code, 
              p.product_name, p.mode, l.term_id, l.program_number, l.program_name,
@@ -510,6 +714,7 @@ SELECT
     (SELECT measurement_date 
      FROM daily_product_totals dpt2 
      WHERE dpt2.product_mnemo_code = daily_product_totals.product_mnemo_code 
+       AND dpt2.term_id = daily_product_totals.term_id
      ORDER BY (running_eligible + COALESCE(running_ineligible, 0)) DESC 
      LIMIT 1) as peak_date
 FROM daily_product_totals
@@ -537,6 +742,7 @@ WITH daily_host_peaks AS (
         MAX(ineligible_cores) as max_ineligible_cores,
         MIN(physical_host_id) as physical_host_id,
         MIN(physical_host_cores) as physical_host_cores,
+        MIN(partition_cap_cores) as partition_cap_cores,
         -- Keep first values for descriptive fields
         MIN(processor_eligible) as processor_eligible,
         MIN(os_eligible) as os_eligible,
@@ -607,6 +813,15 @@ SELECT
     hp.os_name,
     hp.os_version,
     hp.is_virtualized,
+    hp.partition_cap_cores,
+    -- Rule that lowered the license cores below the visible vCPUs: a capped
+    -- partition (LPAR, zone) with an eligible OS and virtualization is
+    -- licensed on its capacity
+    CASE 
+        WHEN hp.is_virtualized = 'yes' AND hp.os_eligible = 'true' AND hp.virt_eligible = 'true'
+         AND hp.partition_cap_cores < hp.max_vm_cores THEN 'partition-cap'
+        ELSE ''
+    END as core_rule,
     -- Daily total for this product (sum with physical host deduplication)
     dt.total_eligible + COALESCE(dt.total_ineligible, 0) as daily_running_total,
     dt.total_nodes as daily_running_nodes,
@@ -632,3 +847,488 @@ JOIN daily_product_totals_dedup dt
     ON hp.product_mnemo_code = dt.product_mnemo_code 
     AND hp.measurement_date = dt.measurement_date
 ORDER BY hp.measurement_date DESC, hp.product_mnemo_code, hp.max_license_cores DESC;
+
+-- View 8: Daily License Cores
+-- One row per day per product with licensable cores, tracked separately for
+-- running products (status='present') and installed products (install_count > 0)
+-- Properly calculates: MAX per host per day, eligible cores summed directly,
+-- ineligible cores counted once per physical host (full host cores when known)
+CREATE VIEW IF NOT EXISTS v_daily_license_cores AS
+WITH daily_host_peaks AS (
+    -- Step 1: For each host/day/product, take the MAX of all measurements
+    SELECT 
+        DATE(m.detection_timestamp) as measurement_date,
+        d.product_mnemo_code,
+        d.main_fqdn,
+        -- Hosts with an unknown physical host are deduplicated on their own FQDN
+        CASE 
+            WHEN m.physical_host_id != '' AND m.physical_host_id != 'unknown' THEN m.physical_host_id
+            ELSE m.main_fqdn
+        END as host_key,
+        MAX(CASE WHEN d.status = 'present' THEN 1 ELSE 0 END) as is_running,
+        MAX(CASE WHEN d.install_count > 0 THEN 1 ELSE 0 END) as is_installed,
+        MAX(CASE 
+            WHEN m.os_eligible = 'true' AND m.virt_eligible = 'true' 
+            THEN m.considered_cpus 
+            ELSE 0 
+        END) as eligible_cores,
+        MAX(CASE 
+            WHEN m.os_eligible = 'false' OR m.virt_eligible = 'false'
+            THEN COALESCE(
+                CASE WHEN m.host_physical_cpus NOT IN ('', 'unknown') THEN CAST(m.host_physical_cpus AS INTEGER) END,
+                m.considered_cpus)
+            ELSE 0 
+        END) as ineligible_cores
+    FROM detected_products d
+    JOIN v_daily_measurements m ON d.main_fqdn = m.main_fqdn 
+        AND d.detection_timestamp = m.detection_timestamp
+    WHERE d.status = 'present' OR d.install_count > 0
+    GROUP BY measurement_date, d.product_mnemo_code, d.main_fqdn, host_key
+),
+ineligible_hosts AS (
+    -- Step 2: Ineligible cores once per physical host, per scope
+    SELECT 
+        measurement_date,
+        product_mnemo_code,
+        host_key,
+        MAX(CASE WHEN is_running = 1 THEN ineligible_cores ELSE 0 END) as running_cores,
+        MAX(CASE WHEN is_installed = 1 THEN ineligible_cores ELSE 0 END) as installed_cores
+    FROM daily_host_peaks
+    WHERE ineligible_cores > 0
+    GROUP BY measurement_date, product_mnemo_code, host_key
+),
+ineligible_totals AS (
+    SELECT 
+        measurement_date,
+        product_mnemo_code,
+        SUM(running_cores) as running_ineligible,
+        SUM(installed_cores) as installed_ineligible
+    FROM ineligible_hosts
+    GROUP BY measurement_date, product_mnemo_code
+)
+SELECT 
+    h.measurement_date,
+    h.product_mnemo_code,
+    -- Running products
+    COUNT(DISTINCT CASE WHEN h.is_running = 1 THEN h.main_fqdn END) as running_nodes,
+    SUM(CASE WHEN h.is_running = 1 THEN h.eligible_cores ELSE 0 END) as running_eligible_cores,
+    COALESCE(MAX(it.running_ineligible), 0) as running_ineligible_cores,
+    SUM(CASE WHEN h.is_running = 1 THEN h.eligible_cores ELSE 0 END)
+        + COALESCE(MAX(it.running_ineligible), 0) as running_license_cores,
+    -- Installed products
+    COUNT(DISTINCT CASE WHEN h.is_installed = 1 THEN h.main_fqdn END) as installed_nodes,
+    SUM(CASE WHEN h.is_installed = 1 THEN h.eligible_cores ELSE 0 END) as installed_eligible_cores,
+    COALESCE(MAX(it.installed_ineligible), 0) as installed_ineligible_cores,
+    SUM(CASE WHEN h.is_installed = 1 THEN h.eligible_cores ELSE 0 END)
+        + COALESCE(MAX(it.installed_ineligible), 0) as installed_license_cores
+FROM daily_host_peaks h
+LEFT JOIN ineligible_totals it ON it.measurement_date = h.measurement_date
+    AND it.product_mnemo_code = h.product_mnemo_code
+GROUP BY h.measurement_date, h.product_mnemo_code;
+
+-- View 9: Monthly Peak
+-- Per-month peak license cores per product, the figure reported to IBM under
+-- sub-capacity terms. Running and installed peaks are tracked separately, each
+-- with the day on which it occurred (earliest day wins on ties). A product
+-- remapped to another license term during a month has one row per term.
+CREATE VIEW IF NOT EXISTS v_monthly_peak AS
+WITH daily_terms AS (
+    -- The license term of the product on each day
+    SELECT 
+        dlc.*,
+        COALESCE((
+            SELECT tm.term_id FROM product_term_mappings tm
+            WHERE tm.product_mnemo_code = dlc.product_mnemo_code
+              AND DATE(tm.effective_from) <= dlc.measurement_date
+              AND (tm.effective_to IS NULL OR DATE(tm.effective_to) >= dlc.measurement_date)
+            ORDER BY tm.effective_from DESC
+            LIMIT 1
+        ), p.term_id) as term_id
+    FROM v_daily_license_cores dlc
+    JOIN product_codes p ON dlc.product_mnemo_code = p.product_mnemo_code
+),
+ranked_days AS (
+    SELECT 
+        strftime('%Y-%m', dt.measurement_date) as month,
+        dt.*,
+        ROW_NUMBER() OVER (
+            PARTITION BY strftime('%Y-%m', dt.measurement_date), dt.product_mnemo_code, dt.term_id
+            ORDER BY dt.running_license_cores DESC, dt.measurement_date
+        ) as running_rank,
+        ROW_NUMBER() OVER (
+            PARTITION BY strftime('%Y-%m', dt.measurement_date), dt.product_mnemo_code, dt.term_id
+            ORDER BY dt.installed_license_cores DESC, dt.measurement_date
+        ) as installed_rank
+    FROM daily_terms dt
+)
+SELECT 
+    r.month,
+    r.product_mnemo_code,
+    p.ibm_product_code,
+    p.product_name,
+    p.mode,
+    l.term_id,
+    l.program_number,
+    l.program_name,
+    -- Running peak and the day it occurred
+    MAX(CASE WHEN r.running_rank = 1 THEN r.running_license_cores END) as peak_running_cores,
+    MAX(CASE WHEN r.running_rank = 1 THEN r.measurement_date END) as peak_running_date,
+    MAX(CASE WHEN r.running_rank = 1 THEN r.running_nodes END) as peak_running_nodes,
+    -- Installed peak and the day it occurred
+    MAX(CASE WHEN r.installed_rank = 1 THEN r.installed_license_cores END) as peak_installed_cores,
+    MAX(CASE WHEN r.installed_rank = 1 THEN r.measurement_date END) as peak_installed_date,
+    MAX(CASE WHEN r.installed_rank = 1 THEN r.installed_nodes END) as peak_installed_nodes,
+    -- Coverage of the month
+    COUNT(*) as days_measured
+FROM ranked_days r
+JOIN product_codes p ON r.product_mnemo_code = p.product_mnemo_code
+JOIN license_terms l ON r.term_id = l.term_id
+GROUP BY r.month, r.product_mnemo_code, p.ibm_product_code, p.product_name, p.mode,
+         l.term_id, l.program_number, l.program_name
+ORDER BY r.month DESC, r.product_mnemo_code;
+
+-- View 10: Install Detail
+-- One row per piece of installation evidence reported by the inspector:
+-- install paths (evidence_type 'install') and running process command lines
+-- (evidence_type 'process') of each detected product
+CREATE VIEW IF NOT EXISTS v_install_detail AS
+SELECT 
+    d.main_fqdn as host_fqdn,
+    DATE(d.detection_timestamp) as date,
+    d.detection_timestamp,
+    d.product_mnemo_code as product_code,
+    COALESCE(p.product_name, '') as product_name,
+    d.status,
+    d.install_count,
+    d.running_count,
+    e.evidence_type,
+    e.seq,
+    e.evidence
+FROM (
+    SELECT main_fqdn, product_mnemo_code, detection_timestamp, 'install' as evidence_type, seq, install_path as evidence
+    FROM detected_product_installs
+    UNION ALL
+    SELECT main_fqdn, product_mnemo_code, detection_timestamp, 'process' as evidence_type, seq, commandline as evidence
+    FROM detected_product_processes
+) e
+JOIN detected_products d ON d.main_fqdn = e.main_fqdn
+    AND d.product_mnemo_code = e.product_mnemo_code
+    AND d.detection_timestamp = e.detection_timestamp
+LEFT JOIN product_codes p ON d.product_mnemo_code = p.product_mnemo_code
+ORDER BY date DESC, host_fqdn, product_code, e.evidence_type, e.seq;
+
+-- View 11: Measurement PVU
+-- PVU per core of each measurement, from the best matching pvu_mappings row:
+-- a mapping with a model beats one without, longer brands and models beat
+-- shorter ones. pvu_per_core is NULL when no mapping matches
+CREATE VIEW IF NOT EXISTS v_measurement_pvu AS
+SELECT 
+    m.main_fqdn,
+    m.detection_timestamp,
+    m.processor_vendor,
+    m.processor_brand,
+    (
+        SELECT pm.pvu_per_core
+        FROM pvu_mappings pm
+        WHERE UPPER(pm.processor_vendor) = UPPER(m.processor_vendor)
+            AND UPPER(m.processor_brand) LIKE UPPER(pm.processor_brand) || '%'
+            AND (pm.processor_model = '' OR INSTR(UPPER(m.processor_brand), UPPER(pm.processor_model)) > 0)
+        ORDER BY pm.processor_model != '' DESC,
+                 LENGTH(pm.processor_brand) DESC,
+                 LENGTH(pm.processor_model) DESC
+        LIMIT 1
+    ) as pvu_per_core
+FROM measurements m;
+
+-- View 12: Daily License PVU
+-- One row per day per product with the PVUs of the running products, counted
+-- like v_daily_license_cores (MAX per host per day, ineligible cores once per
+-- physical host) with the cores multiplied by the PVU per core of the node.
+-- Nodes without a PVU mapping count 0 PVUs and are reported in unmapped_nodes
+CREATE VIEW IF NOT EXISTS v_daily_license_pvu AS
+WITH daily_host_peaks AS (
+    SELECT 
+        DATE(m.detection_timestamp) as measurement_date,
+        d.product_mnemo_code,
+        d.main_fqdn,
+        CASE 
+            WHEN m.physical_host_id != '' AND m.physical_host_id != 'unknown' THEN m.physical_host_id
+            ELSE m.main_fqdn
+        END as host_key,
+        MAX(CASE WHEN mp.pvu_per_core IS NULL THEN 1 ELSE 0 END) as unmapped,
+        MAX(CASE 
+            WHEN m.os_eligible = 'true' AND m.virt_eligible = 'true' 
+            THEN m.considered_cpus * COALESCE(mp.pvu_per_core, 0)
+            ELSE 0 
+        END) as eligible_pvu,
+        MAX(CASE 
+            WHEN m.os_eligible = 'false' OR m.virt_eligible = 'false'
+            THEN COALESCE(
+                CASE WHEN m.host_physical_cpus NOT IN ('', 'unknown') THEN CAST(m.host_physical_cpus AS INTEGER) END,
+                m.considered_cpus) * COALESCE(mp.pvu_per_core, 0)
+            ELSE 0 
+        END) as ineligible_pvu
+    FROM detected_products d
+    JOIN v_daily_measurements m ON d.main_fqdn = m.main_fqdn 
+        AND d.detection_timestamp = m.detection_timestamp
+    JOIN v_measurement_pvu mp ON mp.main_fqdn = m.main_fqdn
+        AND mp.detection_timestamp = m.detection_timestamp
+    WHERE d.status = 'present'
+    GROUP BY measurement_date, d.product_mnemo_code, d.main_fqdn, host_key
+),
+ineligible_totals AS (
+    SELECT 
+        measurement_date,
+        product_mnemo_code,
+        SUM(host_pvu) as ineligible_pvu
+    FROM (
+        SELECT measurement_date, product_mnemo_code, host_key, MAX(ineligible_pvu) as host_pvu
+        FROM daily_host_peaks
+        WHERE ineligible_pvu > 0
+        GROUP BY measurement_date, product_mnemo_code, host_key
+    )
+    GROUP BY measurement_date, product_mnemo_code
+)
+SELECT 
+    h.measurement_date,
+    h.product_mnemo_code,
+    COUNT(DISTINCT h.main_fqdn) as running_nodes,
+    SUM(h.unmapped) as unmapped_nodes,
+    SUM(h.eligible_pvu) as running_eligible_pvu,
+    COALESCE(MAX(it.ineligible_pvu), 0) as running_ineligible_pvu,
+    SUM(h.eligible_pvu) + COALESCE(MAX(it.ineligible_pvu), 0) as running_license_pvu
+FROM daily_host_peaks h
+LEFT JOIN ineligible_totals it ON it.measurement_date = h.measurement_date
+    AND it.product_mnemo_code = h.product_mnemo_code
+GROUP BY h.measurement_date, h.product_mnemo_code;
+
+-- View 13: Daily Site License Cores
+-- v_daily_license_cores per site of the landscape nodes (site_id '' for nodes
+-- without a site), for chargeback. Ineligible cores are counted once per
+-- physical host within each site, so a host running VMs of several sites is
+-- counted by each of them
+CREATE VIEW IF NOT EXISTS v_daily_site_license_cores AS
+WITH daily_host_peaks AS (
+    SELECT 
+        DATE(m.detection_timestamp) as measurement_date,
+        COALESCE(n.site_id, '') as site_id,
+        d.product_mnemo_code,
+        d.main_fqdn,
+        CASE 
+            WHEN m.physical_host_id != '' AND m.physical_host_id != 'unknown' THEN m.physical_host_id
+            ELSE m.main_fqdn
+        END as host_key,
+        MAX(CASE WHEN d.status = 'present' THEN 1 ELSE 0 END) as is_running,
+        MAX(CASE WHEN d.install_count > 0 THEN 1 ELSE 0 END) as is_installed,
+        MAX(CASE 
+            WHEN m.os_eligible = 'true' AND m.virt_eligible = 'true' 
+            THEN m.considered_cpus 
+            ELSE 0 
+        END) as eligible_cores,
+        MAX(CASE 
+            WHEN m.os_eligible = 'false' OR m.virt_eligible = 'false'
+            THEN COALESCE(
+                CASE WHEN m.host_physical_cpus NOT IN ('', 'unknown') THEN CAST(m.host_physical_cpus AS INTEGER) END,
+                m.considered_cpus)
+            ELSE 0 
+        END) as ineligible_cores
+    FROM detected_products d
+    JOIN v_daily_measurements m ON d.main_fqdn = m.main_fqdn 
+        AND d.detection_timestamp = m.detection_timestamp
+    LEFT JOIN landscape_nodes n ON n.main_fqdn = d.main_fqdn
+    WHERE d.status = 'present' OR d.install_count > 0
+    GROUP BY measurement_date, site_id, d.product_mnemo_code, d.main_fqdn, host_key
+),
+ineligible_totals AS (
+    SELECT 
+        measurement_date,
+        site_id,
+        product_mnemo_code,
+        SUM(running_cores) as running_ineligible,
+        SUM(installed_cores) as installed_ineligible
+    FROM (
+        SELECT 
+            measurement_date,
+            site_id,
+            product_mnemo_code,
+            host_key,
+            MAX(CASE WHEN is_running = 1 THEN ineligible_cores ELSE 0 END) as running_cores,
+            MAX(CASE WHEN is_installed = 1 THEN ineligible_cores ELSE 0 END) as installed_cores
+        FROM daily_host_peaks
+        WHERE ineligible_cores > 0
+        GROUP BY measurement_date, site_id, product_mnemo_code, host_key
+    )
+    GROUP BY measurement_date, site_id, product_mnemo_code
+)
+SELECT 
+    h.measurement_date,
+    h.site_id,
+    h.product_mnemo_code,
+    -- Running products
+    COUNT(DISTINCT CASE WHEN h.is_running = 1 THEN h.main_fqdn END) as running_nodes,
+    SUM(CASE WHEN h.is_running = 1 THEN h.eligible_cores ELSE 0 END)
+        + COALESCE(MAX(it.running_ineligible), 0) as running_license_cores,
+    -- Installed products
+    COUNT(DISTINCT CASE WHEN h.is_installed = 1 THEN h.main_fqdn END) as installed_nodes,
+    SUM(CASE WHEN h.is_installed = 1 THEN h.eligible_cores ELSE 0 END)
+        + COALESCE(MAX(it.installed_ineligible), 0) as installed_license_cores
+FROM daily_host_peaks h
+LEFT JOIN ineligible_totals it ON it.measurement_date = h.measurement_date
+    AND it.site_id = h.site_id
+    AND it.product_mnemo_code = h.product_mnemo_code
+GROUP BY h.measurement_date, h.site_id, h.product_mnemo_code;
+
+-- View 14: Daily Organization License Cores
+-- v_daily_license_cores per organization of the landscape nodes (org_id '' for
+-- nodes without an organization), compared against org_entitlements by the
+-- reports of an organization. Ineligible cores are counted once per physical
+-- host within each organization, so a host running VMs of several
+-- organizations is counted by each of them
+CREATE VIEW IF NOT EXISTS v_daily_org_license_cores AS
+WITH daily_host_peaks AS (
+    SELECT 
+        DATE(m.detection_timestamp) as measurement_date,
+        COALESCE(n.org_id, '') as org_id,
+        d.product_mnemo_code,
+        d.main_fqdn,
+        CASE 
+            WHEN m.physical_host_id != '' AND m.physical_host_id != 'unknown' THEN m.physical_host_id
+            ELSE m.main_fqdn
+        END as host_key,
+        MAX(CASE WHEN d.status = 'present' THEN 1 ELSE 0 END) as is_running,
+        MAX(CASE WHEN d.install_count > 0 THEN 1 ELSE 0 END) as is_installed,
+        MAX(CASE 
+            WHEN m.os_eligible = 'true' AND m.virt_eligible = 'true' 
+            THEN m.considered_cpus 
+            ELSE 0 
+        END) as eligible_cores,
+        MAX(CASE 
+            WHEN m.os_eligible = 'false' OR m.virt_eligible = 'false'
+            THEN COALESCE(
+                CASE WHEN m.host_physical_cpus NOT IN ('', 'unknown') THEN CAST(m.host_physical_cpus AS INTEGER) END,
+                m.considered_cpus)
+            ELSE 0 
+        END) as ineligible_cores
+    FROM detected_products d
+    JOIN v_daily_measurements m ON d.main_fqdn = m.main_fqdn 
+        AND d.detection_timestamp = m.detection_timestamp
+    LEFT JOIN landscape_nodes n ON n.main_fqdn = d.main_fqdn
+    WHERE d.status = 'present' OR d.install_count > 0
+    GROUP BY measurement_date, org_id, d.product_mnemo_code, d.main_fqdn, host_key
+),
+ineligible_totals AS (
+    SELECT 
+        measurement_date,
+        org_id,
+        product_mnemo_code,
+        SUM(running_cores) as running_ineligible,
+        SUM(installed_cores) as installed_ineligible
+    FROM (
+        SELECT 
+            measurement_date,
+            org_id,
+            product_mnemo_code,
+            host_key,
+            MAX(CASE WHEN is_running = 1 THEN ineligible_cores ELSE 0 END) as running_cores,
+            MAX(CASE WHEN is_installed = 1 THEN ineligible_cores ELSE 0 END) as installed_cores
+        FROM daily_host_peaks
+        WHERE ineligible_cores > 0
+        GROUP BY measurement_date, org_id, product_mnemo_code, host_key
+    )
+    GROUP BY measurement_date, org_id, product_mnemo_code
+)
+SELECT 
+    h.measurement_date,
+    h.org_id,
+    h.product_mnemo_code,
+    -- Running products
+    COUNT(DISTINCT CASE WHEN h.is_running = 1 THEN h.main_fqdn END) as running_nodes,
+    SUM(CASE WHEN h.is_running = 1 THEN h.eligible_cores ELSE 0 END)
+        + COALESCE(MAX(it.running_ineligible), 0) as running_license_cores,
+    -- Installed products
+    COUNT(DISTINCT CASE WHEN h.is_installed = 1 THEN h.main_fqdn END) as installed_nodes,
+    SUM(CASE WHEN h.is_installed = 1 THEN h.eligible_cores ELSE 0 END)
+        + COALESCE(MAX(it.installed_ineligible), 0) as installed_license_cores
+FROM daily_host_peaks h
+LEFT JOIN ineligible_totals it ON it.measurement_date = h.measurement_date
+    AND it.org_id = h.org_id
+    AND it.product_mnemo_code = h.product_mnemo_code
+GROUP BY h.measurement_date, h.org_id, h.product_mnemo_code;
+
+-- View 15: Daily Cost Center License Cores
+-- v_daily_license_cores per cost center of the landscape nodes (cost_center ''
+-- for nodes without one, set by 'landscape owner' or 'landscape import'), read
+-- by 'report daily-summary' and 'report compliance' with --group-by
+-- cost-center for internal chargeback. Ineligible cores are counted once per
+-- physical host within each cost center, as in v_daily_org_license_cores, so
+-- a host running VMs of several cost centers is charged to each of them
+CREATE VIEW IF NOT EXISTS v_daily_cost_center_license_cores AS
+WITH daily_host_peaks AS (
+    SELECT 
+        DATE(m.detection_timestamp) as measurement_date,
+        COALESCE(n.cost_center, '') as cost_center,
+        d.product_mnemo_code,
+        d.main_fqdn,
+        CASE 
+            WHEN m.physical_host_id != '' AND m.physical_host_id != 'unknown' THEN m.physical_host_id
+            ELSE m.main_fqdn
+        END as host_key,
+        MAX(CASE WHEN d.status = 'present' THEN 1 ELSE 0 END) as is_running,
+        MAX(CASE WHEN d.install_count > 0 THEN 1 ELSE 0 END) as is_installed,
+        MAX(CASE 
+            WHEN m.os_eligible = 'true' AND m.virt_eligible = 'true' 
+            THEN m.considered_cpus 
+            ELSE 0 
+        END) as eligible_cores,
+        MAX(CASE 
+            WHEN m.os_eligible = 'false' OR m.virt_eligible = 'false'
+            THEN COALESCE(
+                CASE WHEN m.host_physical_cpus NOT IN ('', 'unknown') THEN CAST(m.host_physical_cpus AS INTEGER) END,
+                m.considered_cpus)
+            ELSE 0 
+        END) as ineligible_cores
+    FROM detected_products d
+    JOIN v_daily_measurements m ON d.main_fqdn = m.main_fqdn 
+        AND d.detection_timestamp = m.detection_timestamp
+    LEFT JOIN landscape_nodes n ON n.main_fqdn = d.main_fqdn
+    WHERE d.status = 'present' OR d.install_count > 0
+    GROUP BY measurement_date, cost_center, d.product_mnemo_code, d.main_fqdn, host_key
+),
+ineligible_totals AS (
+    SELECT 
+        measurement_date,
+        cost_center,
+        product_mnemo_code,
+        SUM(running_cores) as running_ineligible,
+        SUM(installed_cores) as installed_ineligible
+    FROM (
+        SELECT 
+            measurement_date,
+            cost_center,
+            product_mnemo_code,
+            host_key,
+            MAX(CASE WHEN is_running = 1 THEN ineligible_cores ELSE 0 END) as running_cores,
+            MAX(CASE WHEN is_installed = 1 THEN ineligible_cores ELSE 0 END) as installed_cores
+        FROM daily_host_peaks
+        WHERE ineligible_cores > 0
+        GROUP BY measurement_date, cost_center, product_mnemo_code, host_key
+    )
+    GROUP BY measurement_date, cost_center, product_mnemo_code
+)
+SELECT 
+    h.measurement_date,
+    h.cost_center,
+    h.product_mnemo_code,
+    -- Running products
+    COUNT(DISTINCT CASE WHEN h.is_running = 1 THEN h.main_fqdn END) as running_nodes,
+    SUM(CASE WHEN h.is_running = 1 THEN h.eligible_cores ELSE 0 END)
+        + COALESCE(MAX(it.running_ineligible), 0) as running_license_cores,
+    -- Installed products
+    COUNT(DISTINCT CASE WHEN h.is_installed = 1 THEN h.main_fqdn END) as installed_nodes,
+    SUM(CASE WHEN h.is_installed = 1 THEN h.eligible_cores ELSE 0 END)
+        + COALESCE(MAX(it.installed_ineligible), 0) as installed_license_cores
+FROM daily_host_peaks h
+LEFT JOIN ineligible_totals it ON it.measurement_date = h.measurement_date
+    AND it.cost_center = h.cost_center
+    AND it.product_mnemo_code = h.product_mnemo_code
+GROUP BY h.measurement_date, h.cost_center, h.product_mnemo_code;
@@ -0,0 +1,160 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/tracing"
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// tracedDriverName is the SQLite driver tracing the queries of read-only
+// connections as spans, used while tracing is enabled
+const tracedDriverName = "sqlite3_traced"
+
+// maxTracedStatement bounds the statement recorded on a query span
+const maxTracedStatement = 2000
+
+// viewPattern finds the reporting views a query reads
+var viewPattern = regexp.MustCompile(`\bv_[a-z0-9_]+`)
+
+func init() {
+	sql.Register(tracedDriverName, tracedDriver{&sqlite3.SQLiteDriver{}})
+}
+
+// tracedDriver opens SQLite connections whose queries are traced
+type tracedDriver struct {
+	driver.Driver
+}
+
+func (d tracedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn}, nil
+}
+
+// tracedConn traces each query as a span lasting until its rows are closed,
+// named after the first view it reads (e.g. "SELECT v_peak_usage")
+type tracedConn struct {
+	driver.Conn
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	name := "SELECT"
+	if view := viewPattern.FindString(query); view != "" {
+		name += " " + view
+	}
+	statement := strings.Join(strings.Fields(query), " ")
+	if len(statement) > maxTracedStatement {
+		statement = statement[:maxTracedStatement]
+	}
+	span := tracing.StartKind(name, tracing.KindClient,
+		tracing.String("db.system", "sqlite"),
+		tracing.String("db.statement", statement))
+
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		span.End(err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := c.Conn.(driver.ExecerContext); ok {
+		return execer.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// tracedRows ends the span of its query when closed, with the rows read
+type tracedRows struct {
+	driver.Rows
+	span  *tracing.Span
+	count int
+	err   error
+}
+
+func (r *tracedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err == nil {
+		r.count++
+	} else if err != io.EOF {
+		r.err = err
+	}
+	return err
+}
+
+func (r *tracedRows) Close() error {
+	r.span.SetAttributes(tracing.Int("db.rows", r.count))
+	r.span.End(r.err)
+	return r.Rows.Close()
+}
+
+func (r *tracedRows) ColumnTypeDatabaseTypeName(index int) string {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return typed.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *tracedRows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return typed.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *tracedRows) ColumnTypeScanType(index int) reflect.Type {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return typed.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
@@ -0,0 +1,186 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+)
+
+// addedColumn is a column added to a table that already existed in an
+// earlier schema version. CREATE TABLE IF NOT EXISTS leaves existing tables
+// as they are, so an upgrade adds these columns with ALTER TABLE.
+type addedColumn struct {
+	version    string // schema version the column was added in
+	table      string
+	column     string
+	definition string // type and constraints, as accepted by ALTER TABLE ADD COLUMN
+}
+
+// addedColumns are the columns added since the first versioned schema, in the
+// order they were added. Columns of tables created after 1.3.0 are created
+// with their table and need no entry.
+var addedColumns = []addedColumn{
+	{"1.8.0", "import_sessions", "file_sha256", "TEXT"},
+	{"1.11.0", "import_sessions", "main_fqdn", "TEXT"},
+	{"1.11.0", "import_sessions", "detection_timestamp", "DATETIME"},
+	{"1.12.0", "landscape_nodes", "site_id", "TEXT REFERENCES sites(site_id)"},
+	{"1.13.0", "measurements", "container_platform", "TEXT DEFAULT ''"},
+	{"1.13.0", "measurements", "container_namespace", "TEXT DEFAULT ''"},
+	{"1.13.0", "measurements", "container_pod", "TEXT DEFAULT ''"},
+	{"1.13.0", "measurements", "container_name", "TEXT DEFAULT ''"},
+	{"1.13.0", "measurements", "container_cpu_limit", "TEXT DEFAULT ''"},
+	{"1.13.0", "measurements", "container_limit_cores", "INTEGER"},
+	{"1.14.0", "measurements", "cloud_provider", "TEXT DEFAULT ''"},
+	{"1.14.0", "measurements", "instance_type", "TEXT DEFAULT ''"},
+	{"1.14.0", "measurements", "region", "TEXT DEFAULT ''"},
+	{"1.14.0", "measurements", "account_id", "TEXT DEFAULT ''"},
+	{"1.15.0", "measurements", "partition_cap_cores", "INTEGER"},
+	{"1.26.0", "landscape_nodes", "classification",
+		"TEXT NOT NULL DEFAULT 'active' CHECK (classification IN ('active', 'standby', 'dr', 'decommissioned'))"},
+	{"1.26.0", "landscape_nodes", "standby_type", "TEXT NOT NULL DEFAULT '' CHECK (standby_type IN ('', 'cold', 'warm', 'hot'))"},
+	{"1.27.0", "landscape_nodes", "decommissioned_on", "DATE"},
+	{"1.36.0", "import_sessions", "signature_status", "TEXT CHECK (signature_status IN ('gpg', 'sha256'))"},
+	{"1.36.0", "import_sessions", "signature_signer", "TEXT"},
+	{"1.38.0", "landscape_nodes", "org_id", "TEXT REFERENCES organizations(org_id)"},
+	{"1.39.0", "landscape_nodes", "owner", "TEXT NOT NULL DEFAULT ''"},
+	{"1.43.0", "landscape_nodes", "team", "TEXT NOT NULL DEFAULT ''"},
+	{"1.43.0", "landscape_nodes", "cost_center", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// SchemaUpgrade is the outcome of UpgradeSchema
+type SchemaUpgrade struct {
+	PreviousVersion string
+	Version         string
+	Tables          []string // the tables created
+	Columns         []string // the columns added, as table.column
+}
+
+// UpgradeSchema upgrades the schema of an existing database to the one of this
+// build: it adds the columns added since the recorded schema version, creates
+// the missing tables, indexes and triggers, and records the new version. It
+// runs in one transaction, rolled back on any error. The reporting views are
+// left as they are; upgrade them afterwards with UpgradeViews. Databases
+// without a schema version, or with a newer one than this build, are refused.
+func UpgradeSchema(db *sql.DB) (*SchemaUpgrade, error) {
+	upgrade := &SchemaUpgrade{Version: GetSchemaVersion()}
+	var err error
+	if upgrade.PreviousVersion, err = GetCurrentSchemaVersion(db); err != nil {
+		return nil, err
+	}
+	switch {
+	case upgrade.PreviousVersion == "":
+		return nil, fmt.Errorf("the database has no schema version: run 'iwdlr init' to create the schema")
+	case CompareVersions(upgrade.PreviousVersion, upgrade.Version) > 0:
+		return nil, fmt.Errorf("the database schema version %s is newer than the schema of this build (%s): upgrade iwldr instead",
+			upgrade.PreviousVersion, upgrade.Version)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	existing, err := tableNames(tx)
+	if err != nil {
+		return nil, err
+	}
+	// The columns go first: the indexes of the schema are created on some of them
+	if upgrade.Columns, err = addMissingColumns(tx, existing); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(SchemaSQL); err != nil {
+		return nil, fmt.Errorf("failed to execute schema: %w", err)
+	}
+	tables, err := tableNames(tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		if !slices.Contains(existing, table) {
+			upgrade.Tables = append(upgrade.Tables, table)
+		}
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO schema_metadata (key, value) VALUES ('schema_version', ?)`, upgrade.Version); err != nil {
+		return nil, fmt.Errorf("failed to set schema version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit schema: %w", err)
+	}
+	return upgrade, nil
+}
+
+// CheckSchemaVersion returns an error naming the command to run when the
+// schema of the database is older than the one of this build, whose commands
+// would read tables or columns the database lacks. Newer schemas are accepted:
+// their tables keep the columns of the older ones.
+func CheckSchemaVersion(db *sql.DB) error {
+	version, err := GetCurrentSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	switch {
+	case version == "":
+		return fmt.Errorf("the database has no schema version: run 'iwdlr init' to create the schema")
+	case CompareVersions(version, GetSchemaVersion()) < 0:
+		return fmt.Errorf("the database schema version %s is older than the schema of this build (%s): run 'iwdlr db upgrade' first",
+			version, GetSchemaVersion())
+	}
+	return nil
+}
+
+// addMissingColumns adds the columns of addedColumns missing from the existing
+// tables, and returns them as table.column
+func addMissingColumns(tx *sql.Tx, tables []string) ([]string, error) {
+	var added []string
+	for _, c := range addedColumns {
+		if !slices.Contains(tables, c.table) {
+			continue
+		}
+		var count int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, c.table, c.column).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to read the columns of %s: %w", c.table, err)
+		}
+		if count > 0 {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, c.table, c.column, c.definition)); err != nil {
+			return nil, fmt.Errorf("failed to add column %s.%s (schema %s): %w", c.table, c.column, c.version, err)
+		}
+		added = append(added, c.table+"."+c.column)
+	}
+	return added, nil
+}
+
+// tableNames returns the names of the tables of the database
+func tableNames(tx *sql.Tx) ([]string, error) {
+	rows, err := tx.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY rowid`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,154 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+)
+
+// setupSchema130DB creates a database as 'iwdlr init' of schema 1.3.0 did,
+// with one node measured once
+func setupSchema130DB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, file := range []string{"testdata/schema-1.3.0.sql", "testdata/views-1.3.0.sql"} {
+		ddl, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		mustExec(t, db, string(ddl))
+	}
+	mustExec(t, db, `INSERT INTO schema_metadata (key, value) VALUES ('schema_version', '1.3.0')`)
+
+	mustExec(t, db, `INSERT INTO license_terms (term_id, program_number, program_name) VALUES ('T1', '5900-AAA', 'Test Program')`)
+	mustExec(t, db, `INSERT INTO product_codes (product_mnemo_code, ibm_product_code, product_name, mode, term_id)
+		VALUES ('IS_ONP_PRD', 'D0YYWZX', 'Integration Server', 'PROD', 'T1')`)
+	mustExec(t, db, `INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('app01.example.com', 'app01', 'PROD')`)
+	mustExec(t, db, `INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count, is_virtualized,
+			host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus)
+		VALUES ('app01.example.com', '2025-10-01 08:00:00', 'Linux', '8', 4, 'no', 'unknown', 'true', 'true', 'true', 4)`)
+	mustExec(t, db, `INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, install_count)
+		VALUES ('app01.example.com', 'IS_ONP_PRD', '2025-10-01 08:00:00', 'present', 1)`)
+	mustExec(t, db, `INSERT INTO import_sessions (session_id, source_file, hostname, status)
+		VALUES ('app01_20251001_080000', 'app01.csv', 'app01', 'success')`)
+	return db
+}
+
+// tableColumns returns the columns of every table as "table.column type notnull default"
+func tableColumns(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query(`SELECT m.name, c.name, c.type, c."notnull", COALESCE(c.dflt_value, '')
+		FROM sqlite_master m JOIN pragma_table_info(m.name) c
+		WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%'
+		ORDER BY m.name, c.name`)
+	if err != nil {
+		t.Fatalf("Failed to read the columns: %v", err)
+	}
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var table, column, kind, notNull, dflt string
+		if err := rows.Scan(&table, &column, &kind, &notNull, &dflt); err != nil {
+			t.Fatalf("Failed to scan column: %v", err)
+		}
+		columns = append(columns, table+"."+column+" "+kind+" "+notNull+" "+dflt)
+	}
+	return columns
+}
+
+func TestUpgradeSchema(t *testing.T) {
+	db := setupSchema130DB(t)
+
+	if err := database.CheckSchemaVersion(db); err == nil || !strings.Contains(err.Error(), "iwdlr db upgrade") {
+		t.Errorf("Expected the 1.3.0 schema to be refused with the upgrade command, got %v", err)
+	}
+	if _, err := database.UpgradeViews(db); err == nil || !strings.Contains(err.Error(), "iwdlr db upgrade") {
+		t.Errorf("Expected the views upgrade to name the schema upgrade, got %v", err)
+	}
+
+	upgrade, err := database.UpgradeSchema(db)
+	if err != nil {
+		t.Fatalf("UpgradeSchema failed: %v", err)
+	}
+	if upgrade.PreviousVersion != "1.3.0" || upgrade.Version != database.GetSchemaVersion() {
+		t.Errorf("Unexpected versions: %+v", upgrade)
+	}
+	if len(upgrade.Columns) != 24 {
+		t.Errorf("Expected 24 added columns, got %d: %v", len(upgrade.Columns), upgrade.Columns)
+	}
+	if err := database.VerifySchema(db); err != nil {
+		t.Errorf("Upgraded schema incomplete: %v", err)
+	}
+	if err := database.CheckSchemaVersion(db); err != nil {
+		t.Errorf("Expected the upgraded schema to be accepted: %v", err)
+	}
+	if missing, err := database.MissingIndexes(db); err != nil || len(missing) != 0 {
+		t.Errorf("Expected no missing index after the upgrade, got %v (%v)", missing, err)
+	}
+
+	// The tables match those of a new database, column for column
+	fresh, err := database.Connect(filepath.Join(t.TempDir(), "fresh.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer fresh.Close()
+	if err := database.InitSchema(fresh); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+	if got, want := strings.Join(tableColumns(t, db), "\n"), strings.Join(tableColumns(t, fresh), "\n"); got != want {
+		t.Errorf("Upgraded columns differ from a new database:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The added columns take their defaults on the existing rows, and their constraints apply
+	var classification, owner string
+	if err := db.QueryRow(`SELECT classification, owner FROM landscape_nodes`).Scan(&classification, &owner); err != nil || classification != "active" || owner != "" {
+		t.Errorf("Unexpected defaults %q, %q (%v)", classification, owner, err)
+	}
+	if _, err := db.Exec(`UPDATE landscape_nodes SET standby_type = 'lukewarm'`); err == nil {
+		t.Error("Expected the standby_type check constraint to be added")
+	}
+
+	// The views can be upgraded and read the existing measurements
+	if _, err := database.UpgradeViews(db); err != nil {
+		t.Fatalf("UpgradeViews failed: %v", err)
+	}
+	var cores int
+	if err := db.QueryRow(`SELECT running_license_cores FROM v_daily_license_cores WHERE product_mnemo_code = 'IS_ONP_PRD'`).Scan(&cores); err != nil || cores != 4 {
+		t.Errorf("Expected 4 running license cores after the upgrade, got %d (%v)", cores, err)
+	}
+
+	// Upgrading again changes nothing
+	again, err := database.UpgradeSchema(db)
+	if err != nil || again.PreviousVersion != again.Version || len(again.Columns) != 0 || len(again.Tables) != 0 {
+		t.Errorf("Expected a second upgrade to change nothing, got %+v (%v)", again, err)
+	}
+
+	mustExec(t, db, `UPDATE schema_metadata SET value = '99.0.0' WHERE key = 'schema_version'`)
+	if _, err := database.UpgradeSchema(db); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Expected a newer schema to be refused, got %v", err)
+	}
+}
@@ -17,6 +17,8 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 // GetCurrentSchemaVersion retrieves version from database
@@ -43,3 +45,25 @@ func SetSchemaVersion(db *sql.DB, version string) error {
 	}
 	return nil
 }
+
+// CompareVersions compares two dotted version numbers (e.g. 1.43.0) part by
+// part, and returns -1, 0 or 1 when a is older than, the same as or newer than b
+func CompareVersions(a, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var aPart, bPart int
+		if i < len(aParts) {
+			aPart, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			bPart, _ = strconv.Atoi(bParts[i])
+		}
+		if aPart != bPart {
+			if aPart < bPart {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -15,14 +15,158 @@
 package database
 
 import (
+	"database/sql"
 	_ "embed"
+	"fmt"
+	"regexp"
 )
 
 //go:embed sql/views.sql
 var ViewsSQL string
 
-// CreateViews creates all reporting views
-func CreateViews(db interface{ Exec(query string, args ...interface{}) (interface{}, error) }) error {
-	_, err := db.Exec(ViewsSQL)
-	return err
+// viewsVersionKey is the schema_metadata key of the version of the reporting
+// views the database was last given
+const viewsVersionKey = "views_version"
+
+// viewsVersionHeader matches the version in the header of views.sql
+var viewsVersionHeader = regexp.MustCompile(`(?m)^-- Version: ([0-9.]+)`)
+
+// embeddedView matches the views created by views.sql and captures their name
+var embeddedView = regexp.MustCompile(`(?m)^CREATE VIEW IF NOT EXISTS (\w+) AS`)
+
+// GetViewsVersion returns the version of the embedded reporting views: the
+// schema version they last changed in, which the tables they read need
+func GetViewsVersion() string {
+	match := viewsVersionHeader.FindStringSubmatch(ViewsSQL)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// GetCurrentViewsVersion returns the version of the reporting views of the
+// database, empty when they were created before the views were versioned
+func GetCurrentViewsVersion(db *sql.DB) (string, error) {
+	var version string
+	err := db.QueryRow(`SELECT value FROM schema_metadata WHERE key = ?`, viewsVersionKey).Scan(&version)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get views version: %w", err)
+	}
+	return version, nil
+}
+
+// EmbeddedViews returns the names of the views of views.sql, in the order
+// they are created
+func EmbeddedViews() []string {
+	var names []string
+	for _, match := range embeddedView.FindAllStringSubmatch(ViewsSQL, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// ViewsUpgrade is the outcome of UpgradeViews
+type ViewsUpgrade struct {
+	PreviousVersion string // empty when the views were not versioned
+	Version         string
+	Views           []string // the views recreated
+}
+
+// CheckViewsCompatible returns an error when the embedded views cannot be
+// created in a database of the schema version: the schema is older than the
+// views, which would read tables or columns it lacks, or newer than this
+// build, whose views would replace those of the newer schema.
+func CheckViewsCompatible(schemaVersion string) error {
+	viewsVersion := GetViewsVersion()
+	switch {
+	case schemaVersion == "":
+		return fmt.Errorf("the database has no schema version: run 'iwdlr init' to create the schema")
+	case CompareVersions(schemaVersion, viewsVersion) < 0:
+		return fmt.Errorf("the database schema version %s is older than the version of the views (%s): run 'iwdlr db upgrade' first",
+			schemaVersion, viewsVersion)
+	case CompareVersions(schemaVersion, GetSchemaVersion()) > 0:
+		return fmt.Errorf("the database schema version %s is newer than the schema of this build (%s): upgrade iwldr instead",
+			schemaVersion, GetSchemaVersion())
+	}
+	return nil
+}
+
+// UpgradeViews drops and recreates the embedded reporting views, and records
+// their version in schema_metadata. It runs in one transaction, which is
+// rolled back unless every view of the database, the custom views reading the
+// embedded ones included, can be queried afterwards. Databases whose schema
+// version is incompatible with the views (see CheckViewsCompatible) are refused.
+func UpgradeViews(db *sql.DB) (*ViewsUpgrade, error) {
+	schemaVersion, err := GetCurrentSchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckViewsCompatible(schemaVersion); err != nil {
+		return nil, err
+	}
+	upgrade := &ViewsUpgrade{Version: GetViewsVersion(), Views: EmbeddedViews()}
+	if upgrade.PreviousVersion, err = GetCurrentViewsVersion(db); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// SQLite does not check the views reading a view when it is dropped
+	for _, name := range upgrade.Views {
+		if _, err := tx.Exec(`DROP VIEW IF EXISTS "` + name + `"`); err != nil {
+			return nil, fmt.Errorf("failed to drop view %s: %w", name, err)
+		}
+	}
+	if _, err := tx.Exec(ViewsSQL); err != nil {
+		return nil, fmt.Errorf("failed to create views: %w", err)
+	}
+	if err := checkViews(tx); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO schema_metadata (key, value) VALUES (?, ?)`,
+		viewsVersionKey, upgrade.Version); err != nil {
+		return nil, fmt.Errorf("failed to set views version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit views: %w", err)
+	}
+	return upgrade, nil
+}
+
+// checkViews queries every view of the database without reading its rows
+func checkViews(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT name FROM sqlite_master WHERE type = 'view' ORDER BY rowid`)
+	if err != nil {
+		return fmt.Errorf("failed to list views: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan view: %w", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list views: %w", err)
+	}
+
+	for _, name := range names {
+		check, err := tx.Query(`SELECT * FROM "` + name + `" LIMIT 0`)
+		if err != nil {
+			return fmt.Errorf("view %s would no longer work: %w", name, err)
+		}
+		check.Close()
+	}
+	return nil
 }
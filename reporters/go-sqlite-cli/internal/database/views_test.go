@@ -0,0 +1,342 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database_test
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+)
+
+// setupViewDB creates an initialized database with one license term and product
+func setupViewDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	mustExec(t, db, `INSERT INTO license_terms (term_id, program_number, program_name) VALUES ('T1', '5900-AAA', 'Test Program')`)
+	mustExec(t, db, `INSERT INTO product_codes (product_mnemo_code, ibm_product_code, product_name, mode, term_id)
+		VALUES ('IS_ONP_PRD', 'D0YYWZX', 'Integration Server', 'PROD', 'T1')`)
+
+	return db
+}
+
+// viewMeasurement describes one measurement of a node with a single detected product
+type viewMeasurement struct {
+	fqdn           string
+	timestamp      string
+	cores          int
+	eligible       bool
+	physicalHostID string
+	hostCores      string
+	status         string
+	installCount   int
+}
+
+// seedMeasurement inserts the node (if new), the measurement and the detected product
+func seedMeasurement(t *testing.T, db *sql.DB, m viewMeasurement) {
+	t.Helper()
+
+	eligible := "true"
+	virtualized := "no"
+	if !m.eligible {
+		eligible = "false"
+	}
+	if m.physicalHostID != "" {
+		virtualized = "yes"
+	}
+
+	mustExec(t, db, `INSERT OR IGNORE INTO landscape_nodes (main_fqdn, hostname, mode) VALUES (?, ?, 'PROD')`, m.fqdn, m.fqdn)
+	mustExec(t, db, `INSERT INTO measurements (
+			main_fqdn, detection_timestamp, os_name, os_version, cpu_count, is_virtualized,
+			host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus, physical_host_id
+		) VALUES (?, ?, 'Linux', '8', ?, ?, ?, 'true', 'true', ?, ?, ?)`,
+		m.fqdn, m.timestamp, m.cores, virtualized, m.hostCores, eligible, m.cores, m.physicalHostID)
+	mustExec(t, db, `INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, install_count)
+		VALUES (?, 'IS_ONP_PRD', ?, ?, ?)`, m.fqdn, m.timestamp, m.status, m.installCount)
+}
+
+func mustExec(t *testing.T, db *sql.DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("Exec failed: %v\n%s", err, query)
+	}
+}
+
+func TestMonthlyPeakView(t *testing.T) {
+	db := setupViewDB(t)
+
+	// Day 1: eligible vm1 (4) plus ineligible vm2 and vm3 sharing a 32-core host,
+	// which counts once -> running 36, installed 36
+	seedMeasurement(t, db, viewMeasurement{"vm1", "2025-10-01 08:00:00", 4, true, "", "unknown", "present", 1})
+	seedMeasurement(t, db, viewMeasurement{"vm2", "2025-10-01 08:00:00", 2, false, "H1", "32", "present", 1})
+	seedMeasurement(t, db, viewMeasurement{"vm3", "2025-10-01 08:00:00", 2, false, "H1", "32", "present", 1})
+
+	// Day 2: vm1 measured twice (max 8 counts), vm2 only installed, vm4 installed
+	// on its own 16-core host -> running 8, installed 8 + 32 + 16 = 56
+	seedMeasurement(t, db, viewMeasurement{"vm1", "2025-10-02 08:00:00", 4, true, "", "unknown", "present", 1})
+	seedMeasurement(t, db, viewMeasurement{"vm1", "2025-10-02 20:00:00", 8, true, "", "unknown", "present", 1})
+	seedMeasurement(t, db, viewMeasurement{"vm2", "2025-10-02 08:00:00", 2, false, "H1", "32", "absent", 1})
+	seedMeasurement(t, db, viewMeasurement{"vm4", "2025-10-02 08:00:00", 2, false, "H2", "16", "absent", 1})
+
+	// Next month: a single running day
+	seedMeasurement(t, db, viewMeasurement{"vm1", "2025-11-05 08:00:00", 6, true, "", "unknown", "present", 1})
+
+	rows, err := db.Query(`SELECT month, peak_running_cores, peak_running_date,
+		peak_installed_cores, peak_installed_date, days_measured
+		FROM v_monthly_peak WHERE product_mnemo_code = 'IS_ONP_PRD' ORDER BY month`)
+	if err != nil {
+		t.Fatalf("Failed to query v_monthly_peak: %v", err)
+	}
+	defer rows.Close()
+
+	type peak struct {
+		month         string
+		running       int
+		runningDate   string
+		installed     int
+		installedDate string
+		days          int
+	}
+	var got []peak
+	for rows.Next() {
+		var p peak
+		if err := rows.Scan(&p.month, &p.running, &p.runningDate, &p.installed, &p.installedDate, &p.days); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		got = append(got, p)
+	}
+
+	want := []peak{
+		{"2025-10", 36, "2025-10-01", 56, "2025-10-02", 2},
+		{"2025-11", 6, "2025-11-05", 6, "2025-11-05", 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d months, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Month %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestDailyLicensePVUView(t *testing.T) {
+	db := setupViewDB(t)
+
+	mustExec(t, db, `INSERT INTO pvu_mappings (processor_vendor, processor_brand, processor_model, pvu_per_core)
+		VALUES ('IBM', 'POWER', '', 70), ('IBM', 'POWER9', '', 100), ('IBM', 'POWER9', 'E980', 120)`)
+
+	// vm1 eligible POWER9 (4 x 100), vm2 and vm3 ineligible on a 32-core
+	// POWER9 E980 host counted once (32 x 120), vm4 with an unmapped processor
+	seedMeasurement(t, db, viewMeasurement{"vm1", "2025-10-01 08:00:00", 4, true, "", "unknown", "present", 1})
+	seedMeasurement(t, db, viewMeasurement{"vm2", "2025-10-01 08:00:00", 2, false, "H1", "32", "present", 1})
+	seedMeasurement(t, db, viewMeasurement{"vm3", "2025-10-01 08:00:00", 2, false, "H1", "32", "present", 1})
+	seedMeasurement(t, db, viewMeasurement{"vm4", "2025-10-01 08:00:00", 8, true, "", "unknown", "present", 1})
+	mustExec(t, db, `UPDATE measurements SET processor_vendor = 'IBM', processor_brand = 'POWER9' WHERE main_fqdn = 'vm1'`)
+	mustExec(t, db, `UPDATE measurements SET processor_vendor = 'IBM', processor_brand = 'POWER9 E980' WHERE main_fqdn IN ('vm2', 'vm3')`)
+	mustExec(t, db, `UPDATE measurements SET processor_vendor = 'Oracle', processor_brand = 'SPARC M7' WHERE main_fqdn = 'vm4'`)
+
+	var pvu, unmapped int
+	err := db.QueryRow(`SELECT running_license_pvu, unmapped_nodes FROM v_daily_license_pvu
+		WHERE product_mnemo_code = 'IS_ONP_PRD' AND measurement_date = '2025-10-01'`).Scan(&pvu, &unmapped)
+	if err != nil {
+		t.Fatalf("Failed to query v_daily_license_pvu: %v", err)
+	}
+	if pvu != 4*100+32*120 || unmapped != 1 {
+		t.Errorf("Expected %d PVU with 1 unmapped node, got %d PVU with %d", 4*100+32*120, pvu, unmapped)
+	}
+}
+
+func TestDailySiteLicenseCoresView(t *testing.T) {
+	db := setupViewDB(t)
+
+	// vm2 and vm3 are ineligible VMs of the 32-core host H1 in two sites, so
+	// each site counts the host once
+	seedMeasurement(t, db, viewMeasurement{"vm1", "2025-10-01 08:00:00", 4, true, "", "unknown", "present", 1})
+	seedMeasurement(t, db, viewMeasurement{"vm2", "2025-10-01 08:00:00", 2, false, "H1", "32", "present", 1})
+	seedMeasurement(t, db, viewMeasurement{"vm3", "2025-10-01 08:00:00", 2, false, "H1", "32", "present", 1})
+	seedMeasurement(t, db, viewMeasurement{"vm4", "2025-10-01 08:00:00", 8, true, "", "unknown", "absent", 1})
+	mustExec(t, db, `INSERT INTO sites (site_id, site_name) VALUES ('A', 'Site A'), ('B', 'Site B')`)
+	mustExec(t, db, `UPDATE landscape_nodes SET site_id = 'A' WHERE main_fqdn IN ('vm1', 'vm2')`)
+	mustExec(t, db, `UPDATE landscape_nodes SET site_id = 'B' WHERE main_fqdn = 'vm3'`)
+
+	rows, err := db.Query(`SELECT site_id, running_nodes, running_license_cores, installed_nodes, installed_license_cores
+		FROM v_daily_site_license_cores WHERE measurement_date = '2025-10-01' ORDER BY site_id`)
+	if err != nil {
+		t.Fatalf("Failed to query v_daily_site_license_cores: %v", err)
+	}
+	defer rows.Close()
+
+	var got []string
+	for rows.Next() {
+		var site string
+		var runningNodes, running, installedNodes, installed int
+		if err := rows.Scan(&site, &runningNodes, &running, &installedNodes, &installed); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		got = append(got, fmt.Sprintf("%q %d/%d %d/%d", site, runningNodes, running, installedNodes, installed))
+	}
+
+	// vm4 has no site and is installed but not running
+	want := []string{`"" 0/0 1/8`, `"A" 2/36 2/36`, `"B" 1/32 1/32`}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestDecommissionedNodeMeasurementsExcluded(t *testing.T) {
+	db := setupViewDB(t)
+
+	// vm1 is decommissioned on 2025-10-02 but still reported the next day
+	seedMeasurement(t, db, viewMeasurement{"vm1", "2025-10-01 08:00:00", 4, true, "", "unknown", "present", 1})
+	seedMeasurement(t, db, viewMeasurement{"vm1", "2025-10-02 08:00:00", 4, true, "", "unknown", "present", 1})
+	seedMeasurement(t, db, viewMeasurement{"vm1", "2025-10-03 08:00:00", 4, true, "", "unknown", "present", 1})
+	seedMeasurement(t, db, viewMeasurement{"vm2", "2025-10-03 08:00:00", 2, true, "", "unknown", "present", 1})
+	mustExec(t, db, `UPDATE landscape_nodes SET classification = 'decommissioned', decommissioned_on = '2025-10-02'
+		WHERE main_fqdn = 'vm1'`)
+
+	rows, err := db.Query(`SELECT measurement_date, running_license_cores FROM v_daily_license_cores
+		WHERE product_mnemo_code = 'IS_ONP_PRD' ORDER BY measurement_date`)
+	if err != nil {
+		t.Fatalf("Failed to query v_daily_license_cores: %v", err)
+	}
+	defer rows.Close()
+
+	var got []string
+	for rows.Next() {
+		var day string
+		var cores int
+		if err := rows.Scan(&day, &cores); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		got = append(got, fmt.Sprintf("%s=%d", day, cores))
+	}
+
+	want := "2025-10-01=4 2025-10-02=4 2025-10-03=2"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Expected %s, got %s", want, strings.Join(got, " "))
+	}
+}
+
+func TestDailyAggregationPolicy(t *testing.T) {
+	db := setupViewDB(t)
+
+	// An hourly cron job: three measurements of app01 on one day
+	seedMeasurement(t, db, viewMeasurement{"app01", "2025-10-01 08:00:00", 4, true, "", "unknown", "present", 1})
+	seedMeasurement(t, db, viewMeasurement{"app01", "2025-10-01 12:00:00", 8, true, "", "unknown", "present", 1})
+	seedMeasurement(t, db, viewMeasurement{"app01", "2025-10-01 18:00:00", 2, true, "", "unknown", "present", 1})
+
+	policy, err := database.GetDailyAggregation(db)
+	if err != nil || policy != database.DailyAggregationMax {
+		t.Fatalf("Expected the max policy by default, got %q (%v)", policy, err)
+	}
+
+	tests := []struct {
+		policy string
+		cores  int
+	}{
+		{database.DailyAggregationMax, 8},
+		{database.DailyAggregationLast, 2},
+		{database.DailyAggregationAverage, 5},
+	}
+	for _, tt := range tests {
+		if err := database.SetDailyAggregation(db, tt.policy); err != nil {
+			t.Fatalf("SetDailyAggregation(%s) failed: %v", tt.policy, err)
+		}
+
+		var measurements, cores int
+		err := db.QueryRow(`SELECT COUNT(*), MAX(considered_cpus) FROM v_daily_measurements WHERE main_fqdn = 'app01'`).
+			Scan(&measurements, &cores)
+		if err != nil {
+			t.Fatalf("Failed to query v_daily_measurements: %v", err)
+		}
+		if measurements != 1 || cores != tt.cores {
+			t.Errorf("%s: expected 1 measurement with %d cores, got %d with %d", tt.policy, tt.cores, measurements, cores)
+		}
+
+		err = db.QueryRow(`SELECT running_license_cores FROM v_daily_license_cores
+			WHERE product_mnemo_code = 'IS_ONP_PRD' AND measurement_date = '2025-10-01'`).Scan(&cores)
+		if err != nil {
+			t.Fatalf("Failed to query v_daily_license_cores: %v", err)
+		}
+		if cores != tt.cores {
+			t.Errorf("%s: expected %d running license cores, got %d", tt.policy, tt.cores, cores)
+		}
+	}
+
+	if err := database.SetDailyAggregation(db, "median"); err == nil {
+		t.Error("Expected an error for an unknown policy")
+	}
+}
+
+func TestUpgradeViews(t *testing.T) {
+	db := setupViewDB(t)
+
+	version, err := database.GetCurrentViewsVersion(db)
+	if err != nil || version != database.GetViewsVersion() {
+		t.Fatalf("Expected views version %s after init, got %q (%v)", database.GetViewsVersion(), version, err)
+	}
+
+	// A view changed since, with a custom view reading a column only it has
+	mustExec(t, db, `DROP VIEW v_monthly_peak`)
+	mustExec(t, db, `CREATE VIEW v_monthly_peak AS SELECT 1 AS stale`)
+	mustExec(t, db, `CREATE VIEW vx_stale AS SELECT stale FROM v_monthly_peak`)
+	mustExec(t, db, `DELETE FROM schema_metadata WHERE key = 'views_version'`)
+
+	if _, err := database.UpgradeViews(db); err == nil || !strings.Contains(err.Error(), "vx_stale") {
+		t.Fatalf("Expected the upgrade breaking vx_stale to fail, got %v", err)
+	}
+	var definition string
+	if err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'v_monthly_peak'`).Scan(&definition); err != nil || !strings.Contains(definition, "stale") {
+		t.Errorf("Expected the failed upgrade to be rolled back, got %q (%v)", definition, err)
+	}
+
+	mustExec(t, db, `DROP VIEW vx_stale`)
+	upgrade, err := database.UpgradeViews(db)
+	if err != nil {
+		t.Fatalf("UpgradeViews failed: %v", err)
+	}
+	if upgrade.PreviousVersion != "" || upgrade.Version != database.GetViewsVersion() || len(upgrade.Views) != len(database.EmbeddedViews()) {
+		t.Errorf("Unexpected upgrade: %+v", upgrade)
+	}
+	if _, err := db.Exec(`SELECT month FROM v_monthly_peak LIMIT 0`); err != nil {
+		t.Errorf("Expected v_monthly_peak to be recreated: %v", err)
+	}
+	if version, _ := database.GetCurrentViewsVersion(db); version != upgrade.Version {
+		t.Errorf("Expected views version %s, got %q", upgrade.Version, version)
+	}
+
+	for _, schemaVersion := range []string{"", "1.0.0", "99.0.0"} {
+		if err := database.CheckViewsCompatible(schemaVersion); err == nil {
+			t.Errorf("Expected schema version %q to be refused", schemaVersion)
+		}
+	}
+	if database.CompareVersions("1.9.0", "1.10.0") >= 0 || database.CompareVersions("1.43", "1.43.0") != 0 {
+		t.Error("Expected versions to compare part by part")
+	}
+}
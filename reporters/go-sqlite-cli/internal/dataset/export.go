@@ -0,0 +1,269 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dataset exports the tables of the database as files for ingestion
+// into a data lake
+package dataset
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/parquet"
+)
+
+// Dataset formats
+const (
+	// FormatJSONL writes each table as a JSON Lines file: one JSON object per row
+	FormatJSONL = "jsonl"
+	// FormatParquet writes the measurements and detected products, the tables
+	// growing with every import, as Parquet files for Spark and DuckDB
+	FormatParquet = "parquet"
+)
+
+// ManifestFile is the file describing an export, written last to the output
+// directory; its watermark is where the next incremental export starts
+const ManifestFile = "manifest.json"
+
+// exportedTable is a table of the dataset with the column recording when its
+// rows were added or last changed
+type exportedTable struct {
+	name      string
+	changedAt string
+	measured  bool // measurement data, exported in every format
+}
+
+// exportedTables are the measurements, the landscape and the reference data.
+// Measurements and detected products are never updated, so their creation
+// time is their change time.
+var exportedTables = []exportedTable{
+	{"measurements", "created_at", true},
+	{"detected_products", "created_at", true},
+	{"physical_hosts", "updated_at", false},
+	{"landscape_nodes", "updated_at", false},
+	{"sites", "updated_at", false},
+	{"organizations", "updated_at", false},
+	{"license_terms", "updated_at", false},
+	{"product_codes", "updated_at", false},
+	{"entitlements", "updated_at", false},
+	{"org_entitlements", "updated_at", false},
+	{"product_thresholds", "updated_at", false},
+	{"peak_grace_windows", "updated_at", false},
+	{"pvu_mappings", "updated_at", false},
+}
+
+// TableFile is one exported table file
+type TableFile struct {
+	Table string `json:"table"`
+	File  string `json:"file"`
+	Rows  int    `json:"rows"`
+}
+
+// Manifest describes an export. Since is empty for a full export; Watermark
+// is the database time the export started at.
+type Manifest struct {
+	Format     string      `json:"format"`
+	Since      string      `json:"since,omitempty"`
+	Watermark  string      `json:"watermark"`
+	ExportedAt string      `json:"exported_at"`
+	Tables     []TableFile `json:"tables"`
+}
+
+// Exporter writes the tables of the database as files
+type Exporter struct {
+	db *sql.DB
+}
+
+// NewExporter creates a new dataset exporter
+func NewExporter(db *sql.DB) *Exporter {
+	return &Exporter{db: db}
+}
+
+// ReadWatermark returns the watermark of the previous export to dir, empty
+// when the directory holds no export
+func ReadWatermark(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the previous export: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", ManifestFile, err)
+	}
+	return manifest.Watermark, nil
+}
+
+// Export writes one <table>.<format> file per table to dir, creating it if
+// needed, and then the manifest. Parquet exports only hold the measurement
+// tables. With since (YYYY-MM-DD HH:MM:SS UTC) only
+// the rows added or changed at or after it are written: rows changed in the
+// second of the watermark are exported again by the next incremental export,
+// so consumers deduplicate on the primary key. Existing files are overwritten.
+func (e *Exporter) Export(dir, format, since string) (*Manifest, error) {
+	if format != FormatJSONL && format != FormatParquet {
+		return nil, fmt.Errorf("unsupported dataset format %q (expected %s or %s)", format, FormatJSONL, FormatParquet)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	manifest := &Manifest{
+		Format:     format,
+		Since:      since,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		Tables:     []TableFile{},
+	}
+	if err := e.db.QueryRow("SELECT CURRENT_TIMESTAMP").Scan(&manifest.Watermark); err != nil {
+		return nil, fmt.Errorf("failed to read the database time: %w", err)
+	}
+
+	for _, table := range exportedTables {
+		if format == FormatParquet && !table.measured {
+			continue
+		}
+		file := table.name + "." + format
+		rows, err := e.exportTable(filepath.Join(dir, file), table, format, since)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Tables = append(manifest.Tables, TableFile{Table: table.name, File: file, Rows: rows})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// exportTable writes the rows of a table changed since the watermark to path
+// in the given format, in insertion order
+func (e *Exporter) exportTable(path string, table exportedTable, format, since string) (int, error) {
+	query := "SELECT * FROM " + table.name
+	args := []interface{}{}
+	if since != "" {
+		query += " WHERE " + table.changedAt + " >= ?"
+		args = append(args, since)
+	}
+	query += " ORDER BY rowid"
+
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s: %w", table.name, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the columns of %s: %w", table.name, err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	var write func([]interface{}) error
+	var finish func() error
+	switch format {
+	case FormatParquet:
+		write, finish, err = parquetWriter(file, columns)
+		if err != nil {
+			return 0, err
+		}
+	default:
+		write, finish = jsonlWriter(file, columns)
+	}
+
+	values := make([]interface{}, len(columns))
+	targets := make([]interface{}, len(columns))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+
+	count := 0
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+		for i, value := range values {
+			if b, ok := value.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		if err := write(values); err != nil {
+			return 0, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if err := finish(); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return count, file.Close()
+}
+
+// jsonlWriter writes each row as a JSON object keyed by column name
+func jsonlWriter(w io.Writer, columns []*sql.ColumnType) (func([]interface{}) error, func() error) {
+	encoder := json.NewEncoder(w)
+	write := func(values []interface{}) error {
+		record := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			record[column.Name()] = values[i]
+		}
+		return encoder.Encode(record)
+	}
+	return write, func() error { return nil }
+}
+
+// parquetWriter writes the rows as a Parquet file with a column per table
+// column, typed from its declared SQLite type: INTEGER as INT64, REAL as
+// DOUBLE, DATETIME as a UTC timestamp and anything else as a string
+func parquetWriter(w io.Writer, columns []*sql.ColumnType) (func([]interface{}) error, func() error, error) {
+	schema := make([]parquet.Column, len(columns))
+	for i, column := range columns {
+		schema[i] = parquet.Column{Name: column.Name(), Type: parquet.String}
+		switch strings.ToUpper(column.DatabaseTypeName()) {
+		case "INTEGER":
+			schema[i].Type = parquet.Int64
+		case "REAL":
+			schema[i].Type = parquet.Double
+		case "DATETIME", "TIMESTAMP":
+			schema[i].Type = parquet.Timestamp
+		}
+	}
+
+	pw, err := parquet.NewWriter(w, schema)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pw.Write, pw.Close, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dataset_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/dataset"
+)
+
+// readJSONL returns the objects of a JSON Lines file
+func readJSONL(t *testing.T, path string) []map[string]interface{} {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Invalid JSON line in %s: %v", path, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestExportIncremental(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	measure := func(fqdn, createdAt string) {
+		t.Helper()
+		if _, err := db.Exec(`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES (?, ?, 'PROD')`, fqdn, fqdn); err != nil {
+			t.Fatalf("Failed to insert node: %v", err)
+		}
+		if _, err := db.Exec(`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count,
+			is_virtualized, virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus, created_at)
+			VALUES (?, '2025-11-06 08:00:00', 'Linux', '8', 4, 'no', '', 'unknown', 'true', 'true', 'true', 4, ?)`,
+			fqdn, createdAt); err != nil {
+			t.Fatalf("Failed to insert measurement: %v", err)
+		}
+	}
+	measure("app01", "2025-11-06 09:00:00")
+	measure("app02", "2025-11-06 09:00:00")
+
+	dir := t.TempDir()
+	if watermark, err := dataset.ReadWatermark(dir); err != nil || watermark != "" {
+		t.Fatalf("Expected no watermark before the first export, got %q (%v)", watermark, err)
+	}
+
+	exporter := dataset.NewExporter(db)
+	manifest, err := exporter.Export(dir, dataset.FormatJSONL, "")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if manifest.Tables[0].Table != "measurements" || manifest.Tables[0].Rows != 2 {
+		t.Errorf("Expected 2 exported measurements, got %+v", manifest.Tables[0])
+	}
+	records := readJSONL(t, filepath.Join(dir, "measurements.jsonl"))
+	if len(records) != 2 || records[0]["main_fqdn"] != "app01" || records[0]["cpu_count"] != float64(4) {
+		t.Fatalf("Unexpected exported measurements: %v", records)
+	}
+
+	watermark, err := dataset.ReadWatermark(dir)
+	if err != nil || watermark != manifest.Watermark || watermark == "" {
+		t.Fatalf("Expected the watermark %q of the manifest, got %q (%v)", manifest.Watermark, watermark, err)
+	}
+
+	measure("app03", "2999-01-01 00:00:00")
+	manifest, err = exporter.Export(dir, dataset.FormatJSONL, watermark)
+	if err != nil {
+		t.Fatalf("Incremental export failed: %v", err)
+	}
+	if manifest.Since != watermark {
+		t.Errorf("Expected the manifest to record since %q, got %q", watermark, manifest.Since)
+	}
+	records = readJSONL(t, filepath.Join(dir, "measurements.jsonl"))
+	if len(records) != 1 || records[0]["main_fqdn"] != "app03" {
+		t.Errorf("Expected only the measurement added after the watermark, got %v", records)
+	}
+
+	parquetDir := t.TempDir()
+	manifest, err = exporter.Export(parquetDir, dataset.FormatParquet, "")
+	if err != nil {
+		t.Fatalf("Parquet export failed: %v", err)
+	}
+	if len(manifest.Tables) != 2 || manifest.Tables[0].Rows != 3 || manifest.Tables[1].Table != "detected_products" {
+		t.Errorf("Expected the measurement tables only, got %+v", manifest.Tables)
+	}
+	data, err := os.ReadFile(filepath.Join(parquetDir, "measurements.parquet"))
+	if err != nil || len(data) < 8 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Errorf("Expected a Parquet file of the measurements (%v)", err)
+	}
+
+	if _, err := exporter.Export(dir, "csv", ""); err == nil {
+		t.Error("Expected an unsupported format to be rejected")
+	}
+}
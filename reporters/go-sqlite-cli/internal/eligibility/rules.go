@@ -0,0 +1,308 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package eligibility derives the IBM sub-capacity eligibility of the
+// processor, operating system and virtualization of the measurements from
+// versioned rules files, instead of the eligibility the inspectors report
+package eligibility
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Values of the eligibility fields of the measurements
+const (
+	True    = "true"
+	False   = "false"
+	Unknown = "unknown"
+)
+
+//go:embed rules.yaml
+var defaultRules []byte
+
+// Rules are the processor families, operating systems and virtualization
+// technologies eligible for sub-capacity licensing
+type Rules struct {
+	Version          string            `yaml:"version"`
+	Description      string            `yaml:"description,omitempty"`
+	Processors       []ProcessorFamily `yaml:"processors"`
+	OperatingSystems []OperatingSystem `yaml:"operating_systems"`
+	Virtualization   []Technology      `yaml:"virtualization"`
+}
+
+// ProcessorFamily is an eligible processor vendor; its processors are
+// eligible when their brand starts with one of Brands
+type ProcessorFamily struct {
+	Vendor string   `yaml:"vendor"`
+	Brands []string `yaml:"brands"`
+}
+
+// OperatingSystem is an eligible operating system. MinVersion applies to
+// virtualized nodes; a VirtualOnly operating system is not eligible on
+// physical nodes.
+type OperatingSystem struct {
+	Name        string `yaml:"name"`
+	MinVersion  string `yaml:"min_version,omitempty"`
+	VirtualOnly bool   `yaml:"virtual_only,omitempty"`
+}
+
+// Technology is an eligible virtualization technology. Aliases are the other
+// VIRT_TYPE values reported for it and OS the operating systems it is
+// eligible with; an empty OS allows every eligible operating system.
+type Technology struct {
+	Name    string   `yaml:"name"`
+	Aliases []string `yaml:"aliases,omitempty"`
+	OS      []string `yaml:"os,omitempty"`
+}
+
+// Inputs are the fields of a measurement the eligibility is derived from
+type Inputs struct {
+	ProcessorVendor string
+	ProcessorBrand  string
+	OSName          string
+	OSVersion       string
+	IsVirtualized   string // yes or no
+	VirtType        string
+}
+
+// Result is the eligibility of a measurement: True, False or Unknown each
+type Result struct {
+	Processor string
+	OS        string
+	Virt      string
+}
+
+// Default returns the built-in rules, which give the eligibility the
+// inspector reports
+func Default() *Rules {
+	rules, err := Parse(defaultRules)
+	if err != nil {
+		panic("invalid built-in eligibility rules: " + err.Error())
+	}
+	return rules
+}
+
+// DefaultContent returns the built-in rules file
+func DefaultContent() []byte {
+	return defaultRules
+}
+
+// Parse reads and validates a rules file
+func Parse(data []byte) (*Rules, error) {
+	var rules Rules
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse eligibility rules: %w", err)
+	}
+	if err := rules.validate(); err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
+
+func (r *Rules) validate() error {
+	if strings.TrimSpace(r.Version) == "" {
+		return fmt.Errorf("eligibility rules: version is required")
+	}
+
+	for i, family := range r.Processors {
+		if family.Vendor == "" {
+			return fmt.Errorf("eligibility rules: processor %d: vendor is required", i+1)
+		}
+		if len(family.Brands) == 0 {
+			return fmt.Errorf("eligibility rules: processor vendor %s: brands are required", family.Vendor)
+		}
+	}
+
+	systems := map[string]bool{}
+	for i, system := range r.OperatingSystems {
+		if system.Name == "" {
+			return fmt.Errorf("eligibility rules: operating system %d: name is required", i+1)
+		}
+		if systems[strings.ToLower(system.Name)] {
+			return fmt.Errorf("eligibility rules: operating system %s is listed twice", system.Name)
+		}
+		systems[strings.ToLower(system.Name)] = true
+		if system.MinVersion != "" {
+			if _, ok := parseVersion(system.MinVersion); !ok {
+				return fmt.Errorf("eligibility rules: operating system %s: invalid min_version %q", system.Name, system.MinVersion)
+			}
+		}
+	}
+
+	names := map[string]string{}
+	for i, tech := range r.Virtualization {
+		if tech.Name == "" {
+			return fmt.Errorf("eligibility rules: virtualization technology %d: name is required", i+1)
+		}
+		for _, name := range append([]string{tech.Name}, tech.Aliases...) {
+			if other, ok := names[strings.ToLower(name)]; ok {
+				return fmt.Errorf("eligibility rules: virtualization type %s is given to %s and %s", name, other, tech.Name)
+			}
+			names[strings.ToLower(name)] = tech.Name
+		}
+		for _, os := range tech.OS {
+			if !systems[strings.ToLower(os)] {
+				return fmt.Errorf("eligibility rules: virtualization technology %s: operating system %s is not listed in operating_systems", tech.Name, os)
+			}
+		}
+	}
+	return nil
+}
+
+// Evaluate returns the eligibility of a measurement. Measurements without a
+// processor vendor and brand, an OS name or a virtualization status have an
+// Unknown eligibility, as files of older inspectors do. A physical node is
+// never virtualization eligible, and a virtualized node only when its
+// operating system is eligible as well.
+func (r *Rules) Evaluate(in Inputs) Result {
+	result := Result{Processor: r.processor(in.ProcessorVendor, in.ProcessorBrand), OS: Unknown, Virt: Unknown}
+
+	virtualized := strings.TrimSpace(in.IsVirtualized)
+	if strings.TrimSpace(in.OSName) == "" || (virtualized != "yes" && virtualized != "no") {
+		return result
+	}
+	result.OS = r.operatingSystem(in.OSName, in.OSVersion, virtualized == "yes")
+	result.Virt = False
+	if virtualized == "yes" && result.OS == True && r.virtualization(in.VirtType, in.OSName) {
+		result.Virt = True
+	}
+	return result
+}
+
+// processor returns the eligibility of a processor. Like the inspector, a
+// processor of an Unknown vendor or brand is not eligible.
+func (r *Rules) processor(vendor, brand string) string {
+	vendor, brand = strings.TrimSpace(vendor), strings.TrimSpace(brand)
+	if vendor == "" || brand == "" {
+		return Unknown
+	}
+	for _, family := range r.Processors {
+		if !strings.EqualFold(family.Vendor, vendor) {
+			continue
+		}
+		for _, prefix := range family.Brands {
+			if len(brand) >= len(prefix) && strings.EqualFold(brand[:len(prefix)], prefix) {
+				return True
+			}
+		}
+	}
+	return False
+}
+
+// operatingSystem returns the eligibility of an operating system
+func (r *Rules) operatingSystem(name, version string, virtualized bool) string {
+	for _, system := range r.OperatingSystems {
+		if !strings.EqualFold(system.Name, strings.TrimSpace(name)) {
+			continue
+		}
+		if !virtualized {
+			if system.VirtualOnly {
+				return False
+			}
+			return True
+		}
+		if system.MinVersion == "" {
+			return True
+		}
+		if strings.TrimSpace(version) == "" {
+			return Unknown
+		}
+		if versionAtLeast(version, system.MinVersion) {
+			return True
+		}
+		return False
+	}
+	return False
+}
+
+// virtualization reports whether a virtualization type is eligible with an
+// operating system
+func (r *Rules) virtualization(virtType, osName string) bool {
+	virtType = strings.TrimSpace(virtType)
+	for _, tech := range r.Virtualization {
+		if !strings.EqualFold(tech.Name, virtType) && !containsFold(tech.Aliases, virtType) {
+			continue
+		}
+		return len(tech.OS) == 0 || containsFold(tech.OS, strings.TrimSpace(osName))
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// versionAtLeast compares dotted versions component by component, missing
+// components counting as 0. Versions that do not start with a number are
+// below every minimum.
+func versionAtLeast(version, min string) bool {
+	v, ok := parseVersion(version)
+	if !ok {
+		return false
+	}
+	m, _ := parseVersion(min)
+	for i := 0; i < len(v) || i < len(m); i++ {
+		var a, b int
+		if i < len(v) {
+			a = v[i]
+		}
+		if i < len(m) {
+			b = m[i]
+		}
+		if a != b {
+			return a > b
+		}
+	}
+	return true
+}
+
+// parseVersion returns the numeric components of a dotted version, each read
+// up to its first non-digit (8.10 LTS is 8.10). Components after one without a
+// number are ignored.
+func parseVersion(version string) ([]int, bool) {
+	var components []int
+	for _, part := range strings.Split(strings.TrimSpace(version), ".") {
+		end := 0
+		for end < len(part) && part[end] >= '0' && part[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			break
+		}
+		n, err := strconv.Atoi(part[:end])
+		if err != nil {
+			return nil, false
+		}
+		components = append(components, n)
+		if end < len(part) {
+			break
+		}
+	}
+	return components, len(components) > 0
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eligibility_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/eligibility"
+)
+
+func TestEvaluate(t *testing.T) {
+	rules := eligibility.Default()
+
+	tests := []struct {
+		name string
+		in   eligibility.Inputs
+		want eligibility.Result
+	}{
+		{
+			name: "AIX 7.2 LPAR",
+			in:   eligibility.Inputs{"IBM", "POWER8", "AIX", "7.200", "yes", "PowerVM - Micro-Partitioning"},
+			want: eligibility.Result{"true", "true", "true"},
+		},
+		{
+			name: "AIX 6.1 LPAR",
+			in:   eligibility.Inputs{"IBM", "POWER8", "AIX", "6.100", "yes", "PowerVM - Micro-Partitioning"},
+			want: eligibility.Result{"true", "false", "false"},
+		},
+		{
+			name: "physical Solaris 8",
+			in:   eligibility.Inputs{"Oracle", "SPARC M7", "Solaris", "8", "no", "none"},
+			want: eligibility.Result{"true", "true", "false"},
+		},
+		{
+			name: "unknown processor",
+			in:   eligibility.Inputs{"Unknown", "Unknown", "Solaris", "10", "no", "none"},
+			want: eligibility.Result{"false", "true", "false"},
+		},
+		{
+			name: "RHEL 9 on VMware",
+			in:   eligibility.Inputs{"Intel", "Xeon - All Processor Numbers", "Red Hat Enterprise Linux", "9.4", "yes", "VMware vSphere"},
+			want: eligibility.Result{"true", "true", "true"},
+		},
+		{
+			name: "Debian on z/VM",
+			in:   eligibility.Inputs{"IBM", "System z - All IFL or CP engines", "Debian", "12", "yes", "z/VM"},
+			want: eligibility.Result{"true", "true", "false"},
+		},
+		{
+			name: "physical Ubuntu",
+			in:   eligibility.Inputs{"AMD", "Epyc", "Ubuntu", "22.04", "no", "none"},
+			want: eligibility.Result{"true", "false", "false"},
+		},
+		{
+			name: "unlisted hypervisor",
+			in:   eligibility.Inputs{"Intel", "Core - All Processor Numbers", "Ubuntu", "22.04", "yes", "Xen"},
+			want: eligibility.Result{"true", "true", "false"},
+		},
+		{
+			name: "older inspector",
+			in:   eligibility.Inputs{OSName: "Linux", OSVersion: "8"},
+			want: eligibility.Result{"unknown", "unknown", "unknown"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules.Evaluate(tt.in); got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, content := range []string{
+		"processors: []\n",
+		"version: '1'\nunknown: true\n",
+		"version: '1'\noperating_systems: [{name: AIX, min_version: seven}]\n",
+		"version: '1'\nprocessors: [{vendor: IBM}]\n",
+		"version: '1'\nvirtualization: [{name: z/VM, os: [Linux]}]\n",
+		"version: '1'\nvirtualization: [{name: KVM}, {name: QEMU, aliases: [kvm]}]\n",
+	} {
+		if _, err := eligibility.Parse([]byte(content)); err == nil {
+			t.Errorf("Expected %q to be rejected", content)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	if rules, err := eligibility.Active(t.Context(), db); rules != nil || err != nil {
+		t.Fatalf("Expected no active rules, got %v (%v)", rules, err)
+	}
+
+	if _, err := eligibility.Load(t.Context(), db, eligibility.DefaultContent(), "", "tester"); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	custom := strings.Replace(string(eligibility.DefaultContent()), `version: "2025.07"`, `version: "2025.11"`, 1)
+	custom = strings.Replace(custom, `min_version: "7.100"`, `min_version: "7.300"`, 1)
+	if _, err := eligibility.Load(t.Context(), db, []byte(custom), "custom.yaml", "tester"); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	rules, err := eligibility.Active(t.Context(), db)
+	if err != nil || rules == nil || rules.Version != "2025.11" {
+		t.Fatalf("Expected rules 2025.11 active, got %v (%v)", rules, err)
+	}
+	if got := rules.Evaluate(eligibility.Inputs{"IBM", "POWER9", "AIX", "7.200", "yes", "PowerVM"}); got.OS != "false" {
+		t.Errorf("Expected AIX 7.2 ineligible with the corrected rules, got %+v", got)
+	}
+
+	edited := strings.Replace(custom, "(July 2025)", "(November 2025)", 1)
+	if _, err := eligibility.Load(t.Context(), db, []byte(edited), "custom.yaml", "tester"); err == nil || !strings.Contains(err.Error(), "new version") {
+		t.Error("Expected a loaded version with other content to be refused")
+	}
+
+	if err := eligibility.Activate(t.Context(), db, "2024.01"); err == nil {
+		t.Error("Expected a version not loaded to be refused")
+	}
+	if err := eligibility.Activate(t.Context(), db, "2025.07"); err != nil {
+		t.Fatalf("Activate failed: %v", err)
+	}
+	sets, err := eligibility.List(t.Context(), db)
+	if err != nil || len(sets) != 2 {
+		t.Fatalf("Expected 2 versions, got %+v (%v)", sets, err)
+	}
+	for _, set := range sets {
+		if set.Active != (set.Version == "2025.07") {
+			t.Errorf("Expected only 2025.07 active, got %+v", set)
+		}
+	}
+
+	if disabled, err := eligibility.Disable(t.Context(), db); !disabled || err != nil {
+		t.Fatalf("Expected the rules disabled, got %v (%v)", disabled, err)
+	}
+	if version, _ := eligibility.ActiveVersion(t.Context(), db); version != "" {
+		t.Errorf("Expected no active rules, got %s", version)
+	}
+	if set, _ := eligibility.Get(t.Context(), db, "2025.11"); set == nil || set.SourceFile != "custom.yaml" || set.Content != custom {
+		t.Errorf("Expected the loaded rules kept, got %+v", set)
+	}
+}
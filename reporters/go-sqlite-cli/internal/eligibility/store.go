@@ -0,0 +1,192 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eligibility
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// RuleSet is a version of the rules loaded in the database
+type RuleSet struct {
+	Version     string
+	Description string
+	Content     string // the rules file as loaded
+	SourceFile  string // empty for the built-in rules
+	Active      bool
+	LoadedBy    string
+	LoadedAt    string
+}
+
+// querier is implemented by *sql.DB and *sql.Tx
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Load stores a rules file under its version and makes it the active
+// version. A version cannot be loaded again with other content: edited rules
+// need a new version, so that the eligibility of past measurements can be
+// traced to the rules they were derived from.
+func Load(ctx context.Context, db *sql.DB, content []byte, sourceFile, loadedBy string) (*Rules, error) {
+	rules, err := Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var loaded string
+	err = tx.QueryRowContext(ctx, `SELECT content FROM eligibility_rules WHERE version = ?`, rules.Version).Scan(&loaded)
+	switch {
+	case err == sql.ErrNoRows:
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO eligibility_rules (version, content, source_file, loaded_by) VALUES (?, ?, ?, ?)
+		`, rules.Version, string(content), sourceFile, loadedBy); err != nil {
+			return nil, fmt.Errorf("failed to store eligibility rules %s: %w", rules.Version, err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("failed to read eligibility rules %s: %w", rules.Version, err)
+	case loaded != string(content):
+		return nil, fmt.Errorf("eligibility rules %s are already loaded with other content; give the rules a new version", rules.Version)
+	}
+
+	if err := activate(ctx, tx, rules.Version); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit eligibility rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Activate makes a loaded version the active rules
+func Activate(ctx context.Context, db *sql.DB, version string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := activate(ctx, tx, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func activate(ctx context.Context, tx *sql.Tx, version string) error {
+	if _, err := tx.ExecContext(ctx, `UPDATE eligibility_rules SET active = 0 WHERE active = 1`); err != nil {
+		return fmt.Errorf("failed to deactivate eligibility rules: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE eligibility_rules SET active = 1 WHERE version = ?`, version)
+	if err != nil {
+		return fmt.Errorf("failed to activate eligibility rules %s: %w", version, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("eligibility rules %s are not loaded", version)
+	}
+	return nil
+}
+
+// Disable deactivates the active rules: the measurements imported afterwards
+// keep the eligibility reported by the inspectors. It reports whether rules
+// were active.
+func Disable(ctx context.Context, db *sql.DB) (bool, error) {
+	res, err := db.ExecContext(ctx, `UPDATE eligibility_rules SET active = 0 WHERE active = 1`)
+	if err != nil {
+		return false, fmt.Errorf("failed to deactivate eligibility rules: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ActiveVersion returns the version of the active rules, empty when no rules
+// are active
+func ActiveVersion(ctx context.Context, q querier) (string, error) {
+	var version string
+	err := q.QueryRowContext(ctx, `SELECT version FROM eligibility_rules WHERE active = 1`).Scan(&version)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read active eligibility rules: %w", err)
+	}
+	return version, nil
+}
+
+// Active returns the active rules, nil when no rules are active
+func Active(ctx context.Context, q querier) (*Rules, error) {
+	var version, content string
+	err := q.QueryRowContext(ctx, `SELECT version, content FROM eligibility_rules WHERE active = 1`).Scan(&version, &content)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read active eligibility rules: %w", err)
+	}
+	rules, err := Parse([]byte(content))
+	if err != nil {
+		return nil, fmt.Errorf("active eligibility rules %s: %w", version, err)
+	}
+	return rules, nil
+}
+
+// Get returns a loaded version of the rules, nil when it is not loaded
+func Get(ctx context.Context, db *sql.DB, version string) (*RuleSet, error) {
+	var set RuleSet
+	err := db.QueryRowContext(ctx, `
+		SELECT version, content, COALESCE(source_file, ''), active, COALESCE(loaded_by, ''), COALESCE(loaded_at, '')
+		FROM eligibility_rules WHERE version = ?
+	`, version).Scan(&set.Version, &set.Content, &set.SourceFile, &set.Active, &set.LoadedBy, &set.LoadedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read eligibility rules %s: %w", version, err)
+	}
+	if rules, err := Parse([]byte(set.Content)); err == nil {
+		set.Description = rules.Description
+	}
+	return &set, nil
+}
+
+// List returns the loaded versions of the rules, the latest loaded first
+func List(ctx context.Context, db *sql.DB) ([]RuleSet, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT version, content, COALESCE(source_file, ''), active, COALESCE(loaded_by, ''), COALESCE(loaded_at, '')
+		FROM eligibility_rules ORDER BY loaded_at DESC, version DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list eligibility rules: %w", err)
+	}
+	defer rows.Close()
+
+	var sets []RuleSet
+	for rows.Next() {
+		var set RuleSet
+		if err := rows.Scan(&set.Version, &set.Content, &set.SourceFile, &set.Active, &set.LoadedBy, &set.LoadedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan eligibility rules: %w", err)
+		}
+		if rules, err := Parse([]byte(set.Content)); err == nil {
+			set.Description = rules.Description
+		}
+		sets = append(sets, set)
+	}
+	return sets, rows.Err()
+}
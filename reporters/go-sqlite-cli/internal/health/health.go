@@ -0,0 +1,253 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package health checks that a license monitor database can be read and is
+// kept up to date, for 'iwldr health' and the /healthz endpoint of serve.
+package health
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+)
+
+// Statuses of a check and of the report
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped" // the check needs the database, which cannot be read
+)
+
+// Names of the checks
+const (
+	CheckDatabase   = "database"
+	CheckSchema     = "schema"
+	CheckLastImport = "last_import"
+	CheckDiskSpace  = "disk_space"
+)
+
+// Default thresholds of the checks
+const (
+	DefaultMaxImportAge = 48 * time.Hour
+	DefaultMinFreeBytes = 1 << 30
+)
+
+// Options are the thresholds of the checks
+type Options struct {
+	// MaxImportAge is the age of the last successful import above which the
+	// database is considered stale; 0 disables the check
+	MaxImportAge time.Duration
+	// MinFreeBytes is the free space required on the file system of the
+	// database; 0 disables the check
+	MinFreeBytes uint64
+}
+
+// DefaultOptions returns the default thresholds
+func DefaultOptions() Options {
+	return Options{MaxImportAge: DefaultMaxImportAge, MinFreeBytes: DefaultMinFreeBytes}
+}
+
+// Check is the result of one check
+type Check struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// Report is the result of all the checks. The values measured are kept next to
+// the checks, so that monitoring agents can graph them.
+type Report struct {
+	Status                string     `json:"status"`
+	CheckedAt             time.Time  `json:"checked_at"`
+	Database              string     `json:"database"`
+	SchemaVersion         string     `json:"schema_version,omitempty"`
+	ExpectedSchemaVersion string     `json:"expected_schema_version"`
+	LastImport            *time.Time `json:"last_import,omitempty"`
+	LastImportAgeSeconds  *int64     `json:"last_import_age_seconds,omitempty"`
+	FreeBytes             *uint64    `json:"free_bytes,omitempty"`
+	Checks                []Check    `json:"checks"`
+}
+
+// Healthy reports whether no check failed
+func (r *Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+// add appends a check and fails the report when the check failed
+func (r *Report) add(name, status, format string, args ...interface{}) {
+	r.Checks = append(r.Checks, Check{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+	if status == StatusFailed {
+		r.Status = StatusFailed
+	}
+}
+
+// newReport returns a report without checks for the database at dbPath
+func newReport(dbPath string) *Report {
+	return &Report{
+		Status:                StatusOK,
+		CheckedAt:             time.Now().UTC(),
+		Database:              dbPath,
+		ExpectedSchemaVersion: database.GetSchemaVersion(),
+	}
+}
+
+// Unavailable returns the report of a database that cannot be opened: the
+// database check fails with err, and the checks reading it are skipped
+func Unavailable(dbPath string, err error, opts Options) *Report {
+	r := newReport(dbPath)
+	r.failDatabase(err)
+	r.checkDiskSpace(opts.MinFreeBytes)
+	return r
+}
+
+// failDatabase fails the database check and skips the checks reading it
+func (r *Report) failDatabase(err error) {
+	r.add(CheckDatabase, StatusFailed, "%v", err)
+	for _, name := range []string{CheckSchema, CheckLastImport} {
+		r.add(name, StatusSkipped, "database not readable")
+	}
+}
+
+// Run checks the database: that it can be read, that its schema version is the
+// one of this build, the age of its last successful import and the free space
+// of its file system
+func Run(db *sql.DB, opts Options) *Report {
+	r := newReport(databaseFile(db))
+
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&tables); err != nil {
+		r.failDatabase(fmt.Errorf("failed to read the database: %w", err))
+	} else {
+		r.add(CheckDatabase, StatusOK, "%d tables", tables)
+		r.checkSchema(db)
+		r.checkLastImport(db, opts.MaxImportAge)
+	}
+
+	r.checkDiskSpace(opts.MinFreeBytes)
+	return r
+}
+
+// checkSchema compares the schema version of the database with the one of
+// this build
+func (r *Report) checkSchema(db *sql.DB) {
+	version, err := database.GetCurrentSchemaVersion(db)
+	switch {
+	case err != nil:
+		r.add(CheckSchema, StatusFailed, "%v", err)
+	case version == "":
+		r.add(CheckSchema, StatusFailed, "no schema version: create the schema with 'iwldr init'")
+	case database.CompareVersions(version, r.ExpectedSchemaVersion) < 0:
+		r.SchemaVersion = version
+		r.add(CheckSchema, StatusFailed, "schema version %s is older than %s: upgrade it with 'iwldr db upgrade --db-path %s'",
+			version, r.ExpectedSchemaVersion, r.Database)
+	case database.CompareVersions(version, r.ExpectedSchemaVersion) > 0:
+		r.SchemaVersion = version
+		r.add(CheckSchema, StatusFailed, "schema version %s is newer than %s: upgrade iwldr", version, r.ExpectedSchemaVersion)
+	default:
+		r.SchemaVersion = version
+		r.add(CheckSchema, StatusOK, "schema version %s", version)
+	}
+}
+
+// checkLastImport checks the age of the last successful import session
+func (r *Report) checkLastImport(db *sql.DB, maxAge time.Duration) {
+	var last sql.NullString
+	err := db.QueryRow(`SELECT strftime('%Y-%m-%d %H:%M:%S', MAX(imported_at)) FROM import_sessions WHERE status = 'success'`).Scan(&last)
+	if err != nil {
+		r.add(CheckLastImport, StatusFailed, "failed to read the import sessions: %v", err)
+		return
+	}
+	if !last.Valid {
+		if maxAge > 0 {
+			r.add(CheckLastImport, StatusFailed, "no successful import")
+		} else {
+			r.add(CheckLastImport, StatusOK, "no successful import")
+		}
+		return
+	}
+
+	// imported_at is written by SQLite in UTC
+	at, err := time.Parse("2006-01-02 15:04:05", last.String)
+	if err != nil {
+		r.add(CheckLastImport, StatusFailed, "invalid import time %q", last.String)
+		return
+	}
+	age := r.CheckedAt.Sub(at)
+	seconds := int64(age.Seconds())
+	r.LastImport = &at
+	r.LastImportAgeSeconds = &seconds
+
+	if maxAge > 0 && age > maxAge {
+		r.add(CheckLastImport, StatusFailed, "last successful import %s ago, at %s UTC (limit %s)", formatAge(age), last.String, formatAge(maxAge))
+		return
+	}
+	r.add(CheckLastImport, StatusOK, "last successful import %s ago, at %s UTC", formatAge(age), last.String)
+}
+
+// checkDiskSpace checks the free space of the file system of the database
+func (r *Report) checkDiskSpace(minFree uint64) {
+	if r.Database == "" {
+		r.add(CheckDiskSpace, StatusSkipped, "in-memory database")
+		return
+	}
+	free, err := freeBytes(filepath.Dir(r.Database))
+	if err != nil {
+		r.add(CheckDiskSpace, StatusSkipped, "%v", err)
+		return
+	}
+	r.FreeBytes = &free
+
+	if minFree > 0 && free < minFree {
+		r.add(CheckDiskSpace, StatusFailed, "%s free, below %s", formatBytes(free), formatBytes(minFree))
+		return
+	}
+	r.add(CheckDiskSpace, StatusOK, "%s free", formatBytes(free))
+}
+
+// databaseFile returns the file of the main database of db, empty for an
+// in-memory database
+func databaseFile(db *sql.DB) string {
+	var seq int
+	var name, file string
+	if err := db.QueryRow(`PRAGMA database_list`).Scan(&seq, &name, &file); err != nil {
+		return ""
+	}
+	return file
+}
+
+// formatAge renders a duration rounded to minutes, e.g. 26h5m
+func formatAge(d time.Duration) string {
+	s := d.Round(time.Minute).String()
+	if len(s) > 2 && s[len(s)-2:] == "0s" {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// formatBytes renders a byte count in binary units, e.g. 1.5 GiB
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
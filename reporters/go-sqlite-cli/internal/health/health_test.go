@@ -0,0 +1,141 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/health"
+)
+
+// statuses returns the status of each check of a report
+func statuses(report *health.Report) map[string]string {
+	m := make(map[string]string)
+	for _, check := range report.Checks {
+		m[check.Name] = check.Status
+	}
+	return m
+}
+
+func TestRun(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := database.Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	// A new database has no import yet
+	report := health.Run(db, health.Options{MaxImportAge: time.Hour})
+	if report.Healthy() || statuses(report)[health.CheckLastImport] != health.StatusFailed {
+		t.Errorf("Expected the last import check to fail, got %+v", report.Checks)
+	}
+	if report.Database != dbPath || report.SchemaVersion != database.GetSchemaVersion() || report.FreeBytes == nil {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	if _, err := db.Exec(`INSERT INTO import_sessions (session_id, imported_at, source_file, hostname, status)
+		VALUES ('s1', datetime('now', '-3 hours'), 'a.csv', 'app01', 'success'),
+		       ('s2', datetime('now'), 'b.csv', 'app01', 'failed')`); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	// Failed imports do not count
+	report = health.Run(db, health.Options{MaxImportAge: time.Hour})
+	if statuses(report)[health.CheckLastImport] != health.StatusFailed ||
+		report.LastImportAgeSeconds == nil || *report.LastImportAgeSeconds < 3*3600-60 {
+		t.Errorf("Expected the import of 3 hours ago to be stale, got %+v", report)
+	}
+
+	report = health.Run(db, health.Options{MaxImportAge: 4 * time.Hour})
+	if !report.Healthy() {
+		t.Errorf("Expected a healthy database, got %+v", report.Checks)
+	}
+
+	// No file system has that much free space
+	report = health.Run(db, health.Options{MinFreeBytes: 1 << 62})
+	if statuses(report)[health.CheckDiskSpace] != health.StatusFailed {
+		t.Errorf("Expected the disk space check to fail, got %+v", report.Checks)
+	}
+
+}
+
+func TestSchemaCheck(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := database.Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	tests := []struct {
+		version string
+		status  string
+		advice  string
+	}{
+		{database.GetSchemaVersion(), health.StatusOK, ""},
+		{"1.3.0", health.StatusFailed, "upgrade it with 'iwldr db upgrade --db-path " + dbPath + "'"},
+		{"1.9.0", health.StatusFailed, "upgrade it with 'iwldr db upgrade --db-path " + dbPath + "'"},
+		{"99.0.0", health.StatusFailed, "newer than " + database.GetSchemaVersion() + ": upgrade iwldr"},
+	}
+	for _, tt := range tests {
+		if _, err := db.Exec(`UPDATE schema_metadata SET value = ? WHERE key = 'schema_version'`, tt.version); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		report := health.Run(db, health.Options{})
+		for _, check := range report.Checks {
+			if check.Name != health.CheckSchema {
+				continue
+			}
+			if check.Status != tt.status || !strings.Contains(check.Message, tt.advice) || strings.Contains(check.Message, "iwldr init") {
+				t.Errorf("Schema %s: got %s %q, want %s with %q", tt.version, check.Status, check.Message, tt.status, tt.advice)
+			}
+		}
+		if report.SchemaVersion != tt.version {
+			t.Errorf("Schema %s: reported version %q", tt.version, report.SchemaVersion)
+		}
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	dir := t.TempDir()
+	report := health.Unavailable(filepath.Join(dir, "missing.db"), errors.New("no such file"), health.Options{})
+	want := map[string]string{
+		health.CheckDatabase:   health.StatusFailed,
+		health.CheckSchema:     health.StatusSkipped,
+		health.CheckLastImport: health.StatusSkipped,
+		health.CheckDiskSpace:  health.StatusOK,
+	}
+	got := statuses(report)
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("Check %s: expected %s, got %s", name, status, got[name])
+		}
+	}
+	if report.Healthy() {
+		t.Error("Expected an unhealthy report")
+	}
+}
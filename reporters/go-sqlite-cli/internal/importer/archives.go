@@ -0,0 +1,443 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bundleExtensions are the file extensions of inspector bundles: archives
+// holding several inspector CSV files
+var bundleExtensions = []string{".zip", ".tar.gz", ".tgz"}
+
+// Inspector files and bundles are read into memory; larger ones are refused
+// rather than exhausting it. Inspector files are a few KB, so these limits
+// only stop files that are not inspector output, such as decompression bombs.
+const (
+	// maxInspectorFileSize is the largest inspector file, after decompression
+	maxInspectorFileSize = 64 << 20
+	// maxBundleSize is the largest bundle file, before decompression
+	maxBundleSize = 1 << 30
+)
+
+// IsBundleName reports whether a base filename is a zip or tar.gz bundle
+func IsBundleName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range bundleExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGzipName reports whether a filename is a gzip compressed file
+func isGzipName(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".gz")
+}
+
+// SplitBundlePath splits the path of a bundle member, <bundle>/<member>, into
+// the path of the bundle file and the name of the member inside it. ok is
+// false for paths that are not inside a bundle.
+func SplitBundlePath(filePath string) (bundle, member string, ok bool) {
+	lower := strings.ToLower(filePath)
+	for _, ext := range bundleExtensions {
+		for _, sep := range []string{"/", string(filepath.Separator)} {
+			offset := 0
+			for {
+				i := strings.Index(lower[offset:], ext+sep)
+				if i < 0 {
+					break
+				}
+				end := offset + i + len(ext)
+				if info, err := os.Stat(filePath[:end]); err == nil && !info.IsDir() {
+					return filePath[:end], filepath.ToSlash(filePath[end+1:]), true
+				}
+				offset = end
+			}
+		}
+	}
+	return "", "", false
+}
+
+// ExpandBundles replaces every bundle in files by the inspector CSV files it
+// holds, as <bundle>/<member> paths that ImportCSVFile reads in place. Bundles
+// that cannot be read or hold no inspector files are kept, so that importing
+// them fails and they are recorded like any failing file.
+func ExpandBundles(files []string) []string {
+	expanded := make([]string, 0, len(files))
+	for _, file := range files {
+		if !IsBundleName(filepath.Base(file)) {
+			expanded = append(expanded, file)
+			continue
+		}
+		members, err := ListBundleMembers(file)
+		if err != nil || len(members) == 0 {
+			expanded = append(expanded, file)
+			continue
+		}
+		for _, member := range members {
+			expanded = append(expanded, file+"/"+member)
+		}
+	}
+	return expanded
+}
+
+// ListBundleMembers returns the inspector CSV files (plain or gzip compressed)
+// of a zip or tar.gz bundle, in archive order
+func ListBundleMembers(bundle string) ([]string, error) {
+	var members []string
+	err := walkBundle(bundle, func(name string, open func() (io.Reader, error)) (bool, error) {
+		if IsInspectorFileName(path.Base(name)) {
+			members = append(members, name)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle %s: %w", filepath.Base(bundle), err)
+	}
+	return members, nil
+}
+
+// bundleVisitor is called by walkBundle for each regular file of a bundle,
+// with a function opening its content; it returns true to stop the walk
+type bundleVisitor func(name string, open func() (io.Reader, error)) (bool, error)
+
+// walkBundle calls visit for each regular file of a zip or tar.gz bundle,
+// until visit returns true
+func walkBundle(bundle string, visit bundleVisitor) error {
+	if strings.HasSuffix(strings.ToLower(bundle), ".zip") {
+		archive, err := zip.OpenReader(bundle)
+		if err != nil {
+			return err
+		}
+		defer archive.Close()
+		return walkZip(&archive.Reader, visit)
+	}
+
+	file, err := os.Open(bundle)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return walkTarGz(file, visit)
+}
+
+// walkBundleData walks a bundle read into memory like walkBundle
+func walkBundleData(bundle string, data []byte, visit bundleVisitor) error {
+	if strings.HasSuffix(strings.ToLower(bundle), ".zip") {
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return err
+		}
+		return walkZip(archive, visit)
+	}
+	return walkTarGz(bytes.NewReader(data), visit)
+}
+
+// walkZip walks the regular files of a zip archive
+func walkZip(archive *zip.Reader, visit bundleVisitor) error {
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		var rc io.ReadCloser
+		open := func() (io.Reader, error) {
+			var err error
+			rc, err = entry.Open()
+			return rc, err
+		}
+		done, err := visit(entry.Name, open)
+		if rc != nil {
+			rc.Close()
+		}
+		if err != nil || done {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkTarGz walks the regular files of a tar.gz stream
+func walkTarGz(r io.Reader, visit bundleVisitor) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		done, err := visit(header.Name, func() (io.Reader, error) { return archive, nil })
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// openInspectorFile opens an inspector CSV file for reading: a plain or gzip
+// compressed file, or a member of a bundle addressed as <bundle>/<member>.
+// The content is returned decompressed.
+func openInspectorFile(filePath string) (io.ReadCloser, error) {
+	if info, err := os.Stat(filePath); err == nil {
+		if !info.IsDir() && IsBundleName(filepath.Base(filePath)) {
+			members, err := ListBundleMembers(filePath)
+			return nil, bundleFileError(filePath, members, err)
+		}
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if !isGzipName(filePath) {
+			return file, nil
+		}
+		return newGzipFile(file)
+	}
+
+	bundle, member, ok := SplitBundlePath(filePath)
+	if !ok {
+		// Report the error of the plain file
+		return os.Open(filePath)
+	}
+	return openBundleMember(bundle, member)
+}
+
+// bundleFileError returns the error of reading a bundle, whose members are
+// listed, as one inspector file
+func bundleFileError(bundle string, members []string, err error) error {
+	if err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("bundle %s holds no inspector CSV files", filepath.Base(bundle))
+	}
+	return fmt.Errorf("%s is a bundle; import its members", filepath.Base(bundle))
+}
+
+// openBundleMember reads one member of a bundle from the inspector files of
+// the bundle extracted by extractedBundles
+func openBundleMember(bundle, member string) (io.ReadCloser, error) {
+	members, err := extractedBundles.members(bundle)
+	if err != nil {
+		return nil, err
+	}
+	content, err := bundleMember(bundle, members, member)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// extractedBundles keeps the inspector files of the bundle read last by
+// openBundleMember. Its members are read one after the other, and a tar.gz
+// stream is decompressed from its start up to the member read: the bundle is
+// extracted once instead of once per member.
+var extractedBundles bundleCache
+
+// bundleCache keeps the inspector files extracted from a bundle, while the
+// bundle file keeps its size and modification time
+type bundleCache struct {
+	mu      sync.Mutex
+	bundle  string
+	size    int64
+	modTime time.Time
+	files   map[string]bundleFile
+}
+
+// members returns the decompressed inspector files of a bundle by member name
+func (c *bundleCache) members(bundle string) (map[string]bundleFile, error) {
+	info, err := os.Stat(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.bundle == bundle && c.size == info.Size() && c.modTime.Equal(info.ModTime()) {
+		return c.files, nil
+	}
+	if info.Size() > maxBundleSize {
+		return nil, fmt.Errorf("bundle %s is larger than %d MiB", filepath.Base(bundle), maxBundleSize>>20)
+	}
+	files, err := extractBundle(bundle, func(visit bundleVisitor) error {
+		return walkBundle(bundle, visit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.bundle, c.size, c.modTime, c.files = bundle, info.Size(), info.ModTime(), files
+	return files, nil
+}
+
+// bundleFile is an inspector file extracted from a bundle: its decompressed
+// content, or the error of reading it, which fails only this member
+type bundleFile struct {
+	content []byte
+	err     error
+}
+
+// extractBundle returns the inspector files of a bundle walked by walk, by
+// member name
+func extractBundle(bundle string, walk func(bundleVisitor) error) (map[string]bundleFile, error) {
+	files := make(map[string]bundleFile)
+	err := walk(func(name string, open func() (io.Reader, error)) (bool, error) {
+		if !IsInspectorFileName(path.Base(name)) {
+			return false, nil
+		}
+		r, err := open()
+		if err != nil {
+			return true, err
+		}
+		content, err := readDecompressed(r, name)
+		files[name] = bundleFile{content: content, err: err}
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle %s: %w", filepath.Base(bundle), err)
+	}
+	return files, nil
+}
+
+// bundleMember returns the content of a member of a bundle extracted by
+// extractBundle
+func bundleMember(bundle string, files map[string]bundleFile, member string) ([]byte, error) {
+	file, ok := files[member]
+	if !ok {
+		return nil, fmt.Errorf("bundle %s has no inspector file %s", filepath.Base(bundle), member)
+	}
+	if file.err != nil {
+		return nil, fmt.Errorf("failed to read %s from bundle %s: %w", member, filepath.Base(bundle), file.err)
+	}
+	return file.content, nil
+}
+
+// readDecompressed reads a plain or, by its name, gzip compressed inspector
+// file decompressed, up to maxInspectorFileSize
+func readDecompressed(r io.Reader, name string) ([]byte, error) {
+	if isGzipName(name) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return readLimited(r, maxInspectorFileSize, path.Base(filepath.ToSlash(name)))
+}
+
+// readLimited reads r to its end, failing when it holds more than limit bytes
+func readLimited(r io.Reader, limit int64, name string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is larger than %d MiB", name, limit>>20)
+	}
+	return data, nil
+}
+
+// readDeliveredFile reads a file as delivered, before decompression, as
+// signatures cover it: an inspector file or a bundle
+func readDeliveredFile(filePath string) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	limit := int64(maxInspectorFileSize)
+	if IsBundleName(filepath.Base(filePath)) {
+		limit = maxBundleSize
+	}
+	return readLimited(file, limit, filepath.Base(filePath))
+}
+
+// inspectorContent returns the decompressed content of an inspector file from
+// its content as delivered (see readDeliveredFile), so that the content
+// imported is the content verified. Bundles are refused like by
+// openInspectorFile; their members are read with extractBundle.
+func inspectorContent(filePath string, data []byte) ([]byte, error) {
+	if IsBundleName(filepath.Base(filePath)) {
+		var members []string
+		err := walkBundleData(filePath, data, func(name string, open func() (io.Reader, error)) (bool, error) {
+			if IsInspectorFileName(path.Base(name)) {
+				members = append(members, name)
+			}
+			return false, nil
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to read bundle %s: %w", filepath.Base(filePath), err)
+		}
+		return nil, bundleFileError(filePath, members, err)
+	}
+	return readDecompressed(bytes.NewReader(data), filePath)
+}
+
+// gzipFile decompresses a gzip file and closes it with the reader
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func newGzipFile(file *os.File) (io.ReadCloser, error) {
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &gzipFile{Reader: gz, file: file}, nil
+}
+
+// decompressStream returns a reader of the decompressed content of a stream
+// that is gzip compressed, recognized by its magic number, and the stream
+// itself otherwise
+func decompressStream(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return buffered, nil
+	}
+	return gzip.NewReader(buffered)
+}
+
+// Close closes the gzip reader and the file
+func (f *gzipFile) Close() error {
+	f.Reader.Close()
+	return f.file.Close()
+}
@@ -0,0 +1,198 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func gzipBytes(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(content)); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func writeZip(t *testing.T, path string, members map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range members {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Failed to write zip: %v", err)
+	}
+	writeFile(t, path, buf.String())
+}
+
+func writeTarGz(t *testing.T, path string, members map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := tar.NewWriter(&buf)
+	for name, content := range members {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := archive.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		archive.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Failed to write tar: %v", err)
+	}
+	writeFile(t, path, string(gzipBytes(t, buf.String())))
+}
+
+func TestImportCompressedFilesAndBundles(t *testing.T) {
+	db := setupImportDB(t)
+	root := t.TempDir()
+
+	writeFile(t, filepath.Join(root, "iwdli_output_host1_20251021_090906.csv.gz"), string(gzipBytes(t, testInspectorCSV)))
+	writeZip(t, filepath.Join(root, "bundle.zip"), map[string]string{
+		"out/iwdli_output_host2_20251021_090906.csv": testInspectorCSV,
+		"README.txt": "ignored",
+	})
+	writeTarGz(t, filepath.Join(root, "bundle.tar.gz"), map[string]string{
+		"iwdli_output_host3_20251021_090906.csv":    testInspectorCSV,
+		"iwdli_output_host4_20251021_090906.csv.gz": string(gzipBytes(t, testInspectorCSV)),
+	})
+
+	files, err := importer.FindInspectorFiles(root)
+	if err != nil {
+		t.Fatalf("FindInspectorFiles failed: %v", err)
+	}
+	files = importer.ExpandBundles(files)
+	if len(files) != 4 {
+		t.Fatalf("Expected 4 inspector files, got %d: %v", len(files), files)
+	}
+
+	batch := importer.NewImportService(db).ImportFiles(t.Context(), files, nil)
+	if batch.FilesOK != 4 || batch.FilesFailed != 0 {
+		for _, fr := range batch.Files {
+			if fr.Err != nil {
+				t.Logf("%s: %v", fr.FilePath, fr.Err)
+			}
+		}
+		t.Fatalf("Expected 4 ok / 0 failed, got %d / %d", batch.FilesOK, batch.FilesFailed)
+	}
+
+	var hosts int
+	if err := db.QueryRow("SELECT COUNT(DISTINCT main_fqdn) FROM measurements").Scan(&hosts); err != nil {
+		t.Fatalf("Failed to count hosts: %v", err)
+	}
+	if hosts != 4 {
+		t.Errorf("Expected measurements of 4 hosts, got %d", hosts)
+	}
+
+	// A bundle member is read in place by its <bundle>/<member> path
+	record, err := importer.ParseCSVFile(filepath.Join(root, "bundle.zip") + "/out/iwdli_output_host2_20251021_090906.csv")
+	if err != nil {
+		t.Fatalf("ParseCSVFile failed: %v", err)
+	}
+	if record.Hostname != "host2" {
+		t.Errorf("Expected hostname host2, got %s", record.Hostname)
+	}
+}
+
+func TestImportCompressedFileMatchesPlainContent(t *testing.T) {
+	db := setupImportDB(t)
+	root := t.TempDir()
+
+	plain := filepath.Join(root, "iwdli_output_host1_20251021_090906.csv")
+	compressed := filepath.Join(root, "later", "iwdli_output_host1_20251021_090906.csv.gz")
+	writeFile(t, plain, testInspectorCSV)
+	writeFile(t, compressed, string(gzipBytes(t, testInspectorCSV)))
+
+	batch := importer.NewImportService(db).ImportFiles(t.Context(), []string{plain, compressed}, nil)
+	if batch.FilesOK != 1 || batch.FilesSkipped != 1 {
+		t.Errorf("Expected the compressed copy to be skipped, got %d ok / %d skipped", batch.FilesOK, batch.FilesSkipped)
+	}
+}
+
+func TestImportEmptyBundleFails(t *testing.T) {
+	db := setupImportDB(t)
+	bundle := filepath.Join(t.TempDir(), "bundle.zip")
+	writeZip(t, bundle, map[string]string{"README.txt": "no inspector files"})
+
+	files := importer.ExpandBundles([]string{bundle})
+	if len(files) != 1 || files[0] != bundle {
+		t.Fatalf("Expected the empty bundle to be kept, got %v", files)
+	}
+
+	batch := importer.NewImportService(db).ImportFiles(t.Context(), files, nil)
+	if batch.FilesFailed != 1 || !strings.Contains(batch.Files[0].Err.Error(), "holds no inspector CSV files") {
+		t.Errorf("Expected the empty bundle to fail, got %+v", batch.Files)
+	}
+}
+
+func TestImportOversizedBundleMemberFails(t *testing.T) {
+	db := setupImportDB(t)
+	bundle := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	writeTarGz(t, bundle, map[string]string{
+		"iwdli_output_host1_20251021_090906.csv": testInspectorCSV,
+		// A few hundred KB compressed, more than the 64 MiB of an inspector file decompressed
+		"iwdli_output_host2_20251021_090906.csv.gz": string(gzipBytes(t, testInspectorCSV+strings.Repeat("\n", 65<<20))),
+	})
+
+	batch := importer.NewImportService(db).ImportFiles(t.Context(), importer.ExpandBundles([]string{bundle}), nil)
+	if batch.FilesOK != 1 || batch.FilesFailed != 1 {
+		t.Fatalf("Expected 1 ok / 1 failed, got %d / %d", batch.FilesOK, batch.FilesFailed)
+	}
+	for _, fr := range batch.Files {
+		oversized := strings.Contains(fr.FilePath, "host2")
+		if oversized && (fr.Err == nil || !strings.Contains(fr.Err.Error(), "larger than 64 MiB")) {
+			t.Errorf("Expected the oversized member to fail, got %v", fr.Err)
+		}
+	}
+}
+
+func TestSplitBundlePath(t *testing.T) {
+	root := t.TempDir()
+	bundle := filepath.Join(root, "drop.tar.gz")
+	writeTarGz(t, bundle, map[string]string{"a/iwdli_output_host1_20251021_090906.csv": testInspectorCSV})
+
+	got, member, ok := importer.SplitBundlePath(bundle + "/a/iwdli_output_host1_20251021_090906.csv")
+	if !ok || got != bundle || member != "a/iwdli_output_host1_20251021_090906.csv" {
+		t.Errorf("Expected %s and the member, got %q %q %v", bundle, got, member, ok)
+	}
+
+	// Directories named like bundles are not bundles
+	dir := filepath.Join(root, "dir.zip")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := importer.SplitBundlePath(dir + "/iwdli_output_host1_20251021_090906.csv"); ok {
+		t.Error("Expected a directory not to be a bundle")
+	}
+}
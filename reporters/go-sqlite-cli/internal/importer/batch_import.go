@@ -0,0 +1,234 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// InspectorFilePrefix is the filename prefix of every inspector output file
+const InspectorFilePrefix = "iwdli_output_"
+
+// FileImportResult pairs an input file with the outcome of importing it
+type FileImportResult struct {
+	FilePath string
+	Result   *ImportResult // nil when the import failed or was deferred
+	Err      error
+
+	// Deferred is set, with Result and Err nil, when the file is still being
+	// written and was left for a later import (see ImportService.DeferPartial)
+	Deferred *PartialFileError
+}
+
+// BatchImportResult contains per-file results and the aggregate of a bulk import
+type BatchImportResult struct {
+	Files        []FileImportResult
+	Total        ImportResult // Aggregated counts and warnings across all successful files
+	FilesOK      int
+	FilesFailed  int
+	FilesSkipped int // Files whose content was already imported
+
+	// FilesDeferred counts the files left for a later import because they are
+	// still being written
+	FilesDeferred int
+
+	// DetectionErrors counts the failed files whose inspector reported a failed
+	// detection; they are recorded in detection_errors, not failed_imports
+	DetectionErrors int
+
+	// UnknownProductCodes counts, per unmapped product code, the files detecting
+	// it; in strict mode these files were rejected
+	UnknownProductCodes map[string]int
+
+	// Err is the error of the context when the batch was cancelled; the file
+	// being imported was rolled back and the files after it were not read
+	Err error
+}
+
+// FindInspectorFiles walks a directory tree and returns all inspector CSV files
+// (iwdli_output_*.csv, optionally gzip compressed) and bundles (.zip, .tar.gz)
+// sorted by path so imports happen in a stable order. Bundles are listed as
+// they are; see ExpandBundles.
+func FindInspectorFiles(root string) ([]string, error) {
+	var files []string
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if IsInspectorFileName(d.Name()) || IsBundleName(d.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory %s: %w", root, err)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// IsInspectorFileName reports whether a base filename looks like inspector
+// output, plain or gzip compressed
+func IsInspectorFileName(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasPrefix(lower, InspectorFilePrefix) &&
+		(strings.HasSuffix(lower, ".csv") || strings.HasSuffix(lower, ".csv.gz"))
+}
+
+// ImportFiles imports each file in turn and collects per-file and aggregate results.
+// A failing file does not stop the batch; it is recorded in the failed_imports table
+// so it can be retried later, and a successful import removes any earlier record.
+// Files reporting a failed detection are recorded in detection_errors instead,
+// and with DeferPartial files still being written are deferred.
+// Cancelling ctx stops the batch at the file being imported, which is neither
+// recorded nor reported to onFile (see BatchImportResult.Err).
+// If onFile is not nil it is called after each file so callers can report progress
+// or move the file.
+func (s *ImportService) ImportFiles(ctx context.Context, files []string, onFile func(index int, fr FileImportResult)) *BatchImportResult {
+	batch := &BatchImportResult{
+		Files:               make([]FileImportResult, 0, len(files)),
+		Total:               ImportResult{Errors: []string{}},
+		UnknownProductCodes: map[string]int{},
+	}
+	// The members of a bundle are read from the bundle read for the first
+	// one; the next import reads the bundle again
+	defer func() { s.bundle = nil }()
+
+	for i, file := range files {
+		if ctx.Err() != nil {
+			batch.Err = ctx.Err()
+			break
+		}
+		result, err := s.ImportCSVFile(ctx, file)
+		if err != nil && ctx.Err() != nil {
+			// An interrupted import is not a failure of the file
+			batch.Err = ctx.Err()
+			break
+		}
+		var detectionErr *DetectionFailedError
+		var partialErr *PartialFileError
+		if s.DeferPartial && errors.As(err, &partialErr) {
+			// Nothing is recorded: the next import reads the complete file
+			fr := FileImportResult{FilePath: file, Deferred: partialErr}
+			batch.add(fr)
+			if onFile != nil {
+				onFile(i, fr)
+			}
+			continue
+		} else if errors.As(err, &detectionErr) {
+			// Failed detections are kept in detection_errors; a retry cannot succeed
+			if clearErr := s.ClearFailedImport(file); clearErr != nil {
+				err = fmt.Errorf("%w (%v)", err, clearErr)
+			}
+		} else if err != nil {
+			if recordErr := s.RecordFailedImport(file, err); recordErr != nil {
+				err = fmt.Errorf("%w (%v)", err, recordErr)
+			}
+		} else if clearErr := s.ClearFailedImport(file); clearErr != nil {
+			result.Errors = append(result.Errors, clearErr.Error())
+		}
+		fr := FileImportResult{FilePath: file, Result: result, Err: err}
+		batch.add(fr)
+
+		if s.Lock != nil {
+			if lockErr := s.Lock.Refresh(); lockErr != nil {
+				batch.Total.Errors = append(batch.Total.Errors, lockErr.Error())
+			}
+		}
+
+		if onFile != nil {
+			onFile(i, fr)
+		}
+	}
+
+	return batch
+}
+
+// ImportStream imports an inspector CSV read from r as a batch of one file
+// named sourceName, so that it is reported like the files of ImportFiles. A
+// stream cannot be read again, so a failing import is not recorded for retry.
+func (s *ImportService) ImportStream(ctx context.Context, r io.Reader, sourceName string, onFile func(index int, fr FileImportResult)) *BatchImportResult {
+	batch := &BatchImportResult{
+		Total:               ImportResult{Errors: []string{}},
+		UnknownProductCodes: map[string]int{},
+	}
+
+	result, err := s.ImportCSV(ctx, r, sourceName)
+	if err != nil && ctx.Err() != nil {
+		batch.Err = ctx.Err()
+		return batch
+	}
+	fr := FileImportResult{FilePath: sourceName, Result: result, Err: err}
+	batch.add(fr)
+	if onFile != nil {
+		onFile(0, fr)
+	}
+	return batch
+}
+
+// add records a single file result and folds it into the aggregate
+func (b *BatchImportResult) add(fr FileImportResult) {
+	b.Files = append(b.Files, fr)
+
+	if fr.Deferred != nil {
+		b.FilesDeferred++
+		return
+	}
+
+	if fr.Err != nil {
+		b.FilesFailed++
+
+		var detectionErr *DetectionFailedError
+		if errors.As(fr.Err, &detectionErr) {
+			b.DetectionErrors++
+		}
+
+		var unknown *UnknownProductCodesError
+		if errors.As(fr.Err, &unknown) {
+			for _, code := range unknown.Codes {
+				b.UnknownProductCodes[code]++
+			}
+		}
+		return
+	}
+
+	if fr.Result.AlreadyImported {
+		b.FilesSkipped++
+		return
+	}
+
+	b.FilesOK++
+	for _, code := range fr.Result.UnknownProductCodes {
+		b.UnknownProductCodes[code]++
+	}
+	b.Total.RecordsCreated += fr.Result.RecordsCreated
+	b.Total.RecordsUpdated += fr.Result.RecordsUpdated
+	b.Total.RecordsSkipped += fr.Result.RecordsSkipped
+	for _, msg := range fr.Result.Errors {
+		b.Total.Errors = append(b.Total.Errors, filepath.Base(fr.FilePath)+": "+msg)
+	}
+}
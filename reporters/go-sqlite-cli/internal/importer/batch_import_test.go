@@ -0,0 +1,221 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+const testInspectorCSV = `Parameter,Value
+DETECTION_TIMESTAMP,2025-10-21T09:09:06Z
+OS_NAME,Linux
+OS_VERSION,8
+CPU_COUNT,4
+IS_VIRTUALIZED,no
+PROCESSOR_ELIGIBLE,true
+OS_ELIGIBLE,true
+VIRT_ELIGIBLE,true
+CONSIDERED_CPUS,4
+IS_ONP_PRD,present
+IS_ONP_PRD_INSTALL_COUNT,1
+`
+
+// setupImportDB creates an initialized database with a single product code,
+// then runs the statements of the scenario
+func setupImportDB(t *testing.T, stmts ...string) *sql.DB {
+	t.Helper()
+
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO license_terms (term_id, program_number, program_name) VALUES ('T1', '5900-AAA', 'Test Program')`)
+	if err != nil {
+		t.Fatalf("Failed to insert license term: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO product_codes (product_mnemo_code, ibm_product_code, product_name, mode, term_id)
+		VALUES ('IS_ONP_PRD', 'D0YYWZX', 'Integration Server', 'PROD', 'T1')`)
+	if err != nil {
+		t.Fatalf("Failed to insert product code: %v", err)
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Exec failed: %v\n%s", err, stmt)
+		}
+	}
+
+	return db
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestFindInspectorFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "iwdli_output_host1_20251021_090906.csv"), testInspectorCSV)
+	writeFile(t, filepath.Join(root, "2025-10-21", "iwdli_output_host2_20251021_090906.csv"), testInspectorCSV)
+	writeFile(t, filepath.Join(root, "2025-10-21", "deep", "iwdli_output_host3_20251021_090906.CSV"), testInspectorCSV)
+	writeFile(t, filepath.Join(root, "product-codes.csv"), "ignored")
+	writeFile(t, filepath.Join(root, "iwdli_output_host4_20251021_090906.txt"), "ignored")
+
+	files, err := importer.FindInspectorFiles(root)
+	if err != nil {
+		t.Fatalf("FindInspectorFiles failed: %v", err)
+	}
+
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 inspector files, got %d: %v", len(files), files)
+	}
+}
+
+func TestImportFilesAggregatesResults(t *testing.T) {
+	db := setupImportDB(t)
+	root := t.TempDir()
+
+	good1 := filepath.Join(root, "iwdli_output_host1_20251021_090906.csv")
+	good2 := filepath.Join(root, "sub", "iwdli_output_host2_20251021_090906.csv")
+	bad := filepath.Join(root, "iwdli_output_host3_20251021_090906.csv")
+	writeFile(t, good1, testInspectorCSV)
+	writeFile(t, good2, testInspectorCSV)
+	writeFile(t, bad, "Parameter,Value\nOS_NAME,Linux\n") // missing DETECTION_TIMESTAMP
+
+	files, err := importer.FindInspectorFiles(root)
+	if err != nil {
+		t.Fatalf("FindInspectorFiles failed: %v", err)
+	}
+
+	calls := 0
+	batch := importer.NewImportService(db).ImportFiles(t.Context(), files, func(i int, fr importer.FileImportResult) {
+		calls++
+	})
+
+	if calls != 3 {
+		t.Errorf("Expected progress callback 3 times, got %d", calls)
+	}
+	if batch.FilesOK != 2 || batch.FilesFailed != 1 {
+		t.Errorf("Expected 2 ok / 1 failed, got %d / %d", batch.FilesOK, batch.FilesFailed)
+	}
+	// Each good file creates one measurement and one detected product
+	if batch.Total.RecordsCreated != 4 {
+		t.Errorf("Expected 4 records created in total, got %d", batch.Total.RecordsCreated)
+	}
+	if len(batch.Files) != 3 {
+		t.Errorf("Expected 3 per-file results, got %d", len(batch.Files))
+	}
+}
+
+func TestImportFilesStopsWhenCancelled(t *testing.T) {
+	db := setupImportDB(t)
+	root := t.TempDir()
+	file := filepath.Join(root, "iwdli_output_host1_20251021_090906.csv")
+	writeFile(t, file, testInspectorCSV)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	service := importer.NewImportService(db)
+	batch := service.ImportFiles(ctx, []string{file}, func(int, importer.FileImportResult) {
+		t.Error("Expected no file to be reported")
+	})
+	if !errors.Is(batch.Err, context.Canceled) || len(batch.Files) != 0 {
+		t.Fatalf("Expected a cancelled batch without files, got %+v", batch)
+	}
+
+	// An interrupted import is not a failure to retry
+	if failed, _ := service.ListFailedImports(); len(failed) != 0 {
+		t.Errorf("Expected no failed import, got %+v", failed)
+	}
+	var sessions int
+	db.QueryRow("SELECT COUNT(*) FROM import_sessions").Scan(&sessions)
+	if sessions != 0 {
+		t.Errorf("Expected no import session, got %d", sessions)
+	}
+}
+
+func TestImportFilesStrictRejectsUnknownProductCodes(t *testing.T) {
+	db := setupImportDB(t)
+	root := t.TempDir()
+
+	known := filepath.Join(root, "iwdli_output_host1_20251021_090906.csv")
+	unknown := filepath.Join(root, "iwdli_output_host2_20251021_090906.csv")
+	writeFile(t, known, testInspectorCSV)
+	writeFile(t, unknown, testInspectorCSV+"ZZ_ONP_PRD,present\nYY_ONP_NPR,absent\n")
+
+	service := importer.NewImportService(db)
+	service.Strict = true
+	batch := service.ImportFiles(t.Context(), []string{known, unknown}, nil)
+
+	if batch.FilesOK != 1 || batch.FilesFailed != 1 {
+		t.Fatalf("Expected 1 ok / 1 failed, got %d / %d", batch.FilesOK, batch.FilesFailed)
+	}
+
+	var codesErr *importer.UnknownProductCodesError
+	if !errors.As(batch.Files[1].Err, &codesErr) {
+		t.Fatalf("Expected UnknownProductCodesError, got %v", batch.Files[1].Err)
+	}
+	if len(codesErr.Codes) != 2 || codesErr.Codes[0] != "YY_ONP_NPR" || codesErr.Codes[1] != "ZZ_ONP_PRD" {
+		t.Errorf("Unexpected unknown codes: %v", codesErr.Codes)
+	}
+	if batch.UnknownProductCodes["ZZ_ONP_PRD"] != 1 || batch.UnknownProductCodes["YY_ONP_NPR"] != 1 {
+		t.Errorf("Unexpected unknown code summary: %v", batch.UnknownProductCodes)
+	}
+
+	// Nothing from the rejected file may reach the database
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM measurements WHERE main_fqdn LIKE 'host2%'").Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected no measurements for rejected file, got %d", count)
+	}
+}
+
+func TestImportFilesReportsUnknownProductCodes(t *testing.T) {
+	db := setupImportDB(t)
+	root := t.TempDir()
+
+	unknown := filepath.Join(root, "iwdli_output_host2_20251021_090906.csv")
+	writeFile(t, unknown, testInspectorCSV+"ZZ_ONP_PRD,present\n")
+
+	batch := importer.NewImportService(db).ImportFiles(t.Context(), []string{unknown}, nil)
+
+	if batch.FilesOK != 1 || batch.FilesFailed != 0 {
+		t.Fatalf("Expected 1 ok / 0 failed, got %d / %d", batch.FilesOK, batch.FilesFailed)
+	}
+	if codes := batch.Files[0].Result.UnknownProductCodes; len(codes) != 1 || codes[0] != "ZZ_ONP_PRD" {
+		t.Errorf("Unexpected unknown codes: %v", codes)
+	}
+	if batch.UnknownProductCodes["ZZ_ONP_PRD"] != 1 {
+		t.Errorf("Unexpected unknown code summary: %v", batch.UnknownProductCodes)
+	}
+}
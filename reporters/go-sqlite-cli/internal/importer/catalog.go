@@ -0,0 +1,121 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/csv"
+	"fmt"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/tracing"
+)
+
+// catalogFiles is the catalog of known webMethods products embedded in the
+// binary: the license terms and product codes of the default inspector
+// configuration (inspectors/default), in the reference data CSV formats
+//
+//go:embed catalog/license-terms.csv catalog/product-codes.csv
+var catalogFiles embed.FS
+
+// CatalogProduct is a product of the embedded catalog
+type CatalogProduct struct {
+	MnemoCode     string
+	ProductCode   string
+	ProductName   string
+	Mode          string
+	TermID        string
+	ProgramNumber string
+}
+
+// LoadCatalog seeds the license terms and product codes with the embedded
+// catalog of webMethods products. Terms and product codes already in the
+// database are kept unless overwrite is set, so a catalog bootstrap never
+// undoes reference data loaded from CSV files.
+func (l *ReferenceDataLoader) LoadCatalog(ctx context.Context, overwrite bool) (err error) {
+	span := tracing.Start("bootstrap reference")
+	defer func() { span.End(err) }()
+
+	terms, err := catalogFiles.ReadFile("catalog/license-terms.csv")
+	if err != nil {
+		return err
+	}
+	if err := l.loadLicenseTerms(ctx, bytes.NewReader(terms), SourceCatalog, overwrite); err != nil {
+		return fmt.Errorf("failed to load the license terms of the catalog: %w", err)
+	}
+
+	products, err := catalogFiles.ReadFile("catalog/product-codes.csv")
+	if err != nil {
+		return err
+	}
+	if err := l.loadProductCodes(ctx, bytes.NewReader(products), SourceCatalog, overwrite); err != nil {
+		return fmt.Errorf("failed to load the product codes of the catalog: %w", err)
+	}
+	return nil
+}
+
+// Catalog returns the products of the embedded catalog with the program
+// number of their license term
+func Catalog() ([]CatalogProduct, error) {
+	terms, err := readCatalogFile("catalog/license-terms.csv")
+	if err != nil {
+		return nil, err
+	}
+	programs := map[string]string{}
+	for _, row := range terms {
+		programs[row[0]] = row[1]
+	}
+
+	rows, err := readCatalogFile("catalog/product-codes.csv")
+	if err != nil {
+		return nil, err
+	}
+	products := make([]CatalogProduct, 0, len(rows))
+	for _, row := range rows {
+		products = append(products, CatalogProduct{
+			MnemoCode:     row[0],
+			ProductCode:   row[1],
+			ProductName:   row[2],
+			Mode:          row[3],
+			TermID:        row[4],
+			ProgramNumber: programs[row[4]],
+		})
+	}
+	return products, nil
+}
+
+// readCatalogFile returns the rows of an embedded catalog file without its
+// header, each with at least five fields
+func readCatalogFile(name string) ([][]string, error) {
+	data, err := catalogFiles.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	rows := make([][]string, 0, len(records))
+	for _, record := range records[1:] {
+		for len(record) < 5 {
+			record = append(record, "")
+		}
+		rows = append(rows, record)
+	}
+	return rows, nil
+}
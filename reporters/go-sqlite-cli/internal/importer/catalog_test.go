@@ -0,0 +1,66 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package importer_test
+
+import (
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestLoadCatalog(t *testing.T) {
+	db := setupImportDB(t)
+	products, err := importer.Catalog()
+	if err != nil {
+		t.Fatalf("Catalog failed: %v", err)
+	}
+	for _, p := range products {
+		if p.MnemoCode == "" || p.TermID == "" || p.ProgramNumber == "" {
+			t.Errorf("Incomplete catalog product %+v", p)
+		}
+	}
+
+	// IS_ONP_PRD is already mapped to the term T1 and is kept
+	loader := importer.NewReferenceDataLoader(db)
+	if err := loader.LoadCatalog(t.Context(), false); err != nil {
+		t.Fatalf("LoadCatalog failed: %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM product_codes").Scan(&count); err != nil || count != len(products) {
+		t.Errorf("Expected the %d catalog products, got %d (%v)", len(products), count, err)
+	}
+	termOf := func(code string) string {
+		t.Helper()
+		var term string
+		if err := db.QueryRow("SELECT term_id FROM product_codes WHERE product_mnemo_code = ?", code).Scan(&term); err != nil {
+			t.Fatalf("Failed to read product code %s: %v", code, err)
+		}
+		return term
+	}
+	if term := termOf("IS_ONP_PRD"); term != "T1" {
+		t.Errorf("Expected the existing product code kept, got term %s", term)
+	}
+	if term := termOf("UM_ONP_PRD"); term != "L-WDZH-E9T7UL" {
+		t.Errorf("Expected UM_ONP_PRD seeded with its catalog term, got %s", term)
+	}
+
+	if err := loader.LoadCatalog(t.Context(), true); err != nil {
+		t.Fatalf("LoadCatalog with overwrite failed: %v", err)
+	}
+	if term := termOf("IS_ONP_PRD"); term != "L-JGNZ-K3Z366" {
+		t.Errorf("Expected the product code reset to the catalog, got term %s", term)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// containerLimitCores converts a container CPU limit to the whole cores it is
+// licensed on. The limit uses the Kubernetes CPU quantity notation: cores
+// ("2", "1.5") or millicores ("500m"). Partial cores are rounded up and any
+// limit counts at least one core. An empty limit, or one reported as "none" or
+// "unlimited", returns nil: the container is counted on the node cores.
+func containerLimitCores(limit string) (*int, error) {
+	switch strings.ToLower(limit) {
+	case "", "none", "unlimited", "unknown":
+		return nil, nil
+	}
+
+	value := limit
+	divisor := 1.0
+	if strings.HasSuffix(value, "m") {
+		value = strings.TrimSuffix(value, "m")
+		divisor = 1000
+	}
+	quantity, err := strconv.ParseFloat(value, 64)
+	if err != nil || !(quantity > 0) || math.IsInf(quantity, 0) {
+		return nil, fmt.Errorf("invalid CONTAINER_CPU_LIMIT value: %s", limit)
+	}
+
+	cores := int(math.Ceil(quantity / divisor))
+	if cores < 1 {
+		cores = 1
+	}
+	return &cores, nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+)
+
+// ContractPeriodEditor manages the contract periods of the license terms. Peak
+// reports run with --period compute the peaks of each term within one of its
+// periods, so that peaks of former contracts do not drive the current numbers.
+type ContractPeriodEditor struct {
+	db *sql.DB
+}
+
+// NewContractPeriodEditor creates a new contract period editor
+func NewContractPeriodEditor(db *sql.DB) *ContractPeriodEditor {
+	return &ContractPeriodEditor{db: db}
+}
+
+// Add adds a contract period to a license term. An empty label defaults to the
+// year the period starts. Labels are unique per term, and the periods of a term
+// must not overlap.
+func (e *ContractPeriodEditor) Add(period *models.ContractPeriod) error {
+	period.Label = strings.TrimSpace(period.Label)
+	if period.Label == "" {
+		period.Label = period.StartDate.Format("2006")
+	}
+	if period.EndDate.Before(period.StartDate) {
+		return fmt.Errorf("end date %s is before the start date %s",
+			period.EndDate.Format("2006-01-02"), period.StartDate.Format("2006-01-02"))
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM license_terms WHERE term_id = ?", period.TermID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check license term %s: %w", period.TermID, err)
+	}
+	if exists == 0 {
+		return fmt.Errorf("license term %s does not exist; load the reference data first", period.TermID)
+	}
+
+	start := period.StartDate.Format("2006-01-02")
+	end := period.EndDate.Format("2006-01-02")
+
+	var label, overlapStart, overlapEnd string
+	err = tx.QueryRow(`
+		SELECT label, strftime('%Y-%m-%d', start_date), strftime('%Y-%m-%d', end_date)
+		FROM contract_periods
+		WHERE term_id = ? AND (label = ? OR (DATE(start_date) <= ? AND DATE(end_date) >= ?))
+		LIMIT 1
+	`, period.TermID, period.Label, end, start).Scan(&label, &overlapStart, &overlapEnd)
+	if err == nil {
+		if label == period.Label {
+			return fmt.Errorf("term %s already has a contract period %s", period.TermID, label)
+		}
+		return fmt.Errorf("contract period %s of term %s (%s to %s) overlaps %s to %s",
+			label, period.TermID, overlapStart, overlapEnd, start, end)
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check contract periods of term %s: %w", period.TermID, err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO contract_periods (term_id, label, start_date, end_date, notes)
+		VALUES (?, ?, ?, ?, ?)
+	`, period.TermID, period.Label, start, end, period.Notes)
+	if err != nil {
+		return fmt.Errorf("failed to add contract period to term %s: %w", period.TermID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes a contract period
+func (e *ContractPeriodEditor) Remove(termID, label string) error {
+	res, err := e.db.Exec("DELETE FROM contract_periods WHERE term_id = ? AND label = ?", termID, label)
+	if err != nil {
+		return fmt.Errorf("failed to remove contract period %s of term %s: %w", label, termID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("term %s has no contract period %s", termID, label)
+	}
+	return nil
+}
+
+// List returns the contract periods of a term, or of all terms when termID is
+// empty, ordered by term and start date
+func (e *ContractPeriodEditor) List(termID string) ([]models.ContractPeriod, error) {
+	query := `
+		SELECT term_id, label, start_date, end_date, COALESCE(notes, ''), created_at
+		FROM contract_periods
+	`
+	args := []interface{}{}
+	if termID != "" {
+		query += " WHERE term_id = ?"
+		args = append(args, termID)
+	}
+	query += " ORDER BY term_id, start_date"
+
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contract periods: %w", err)
+	}
+	defer rows.Close()
+
+	var periods []models.ContractPeriod
+	for rows.Next() {
+		var p models.ContractPeriod
+		if err := rows.Scan(&p.TermID, &p.Label, &p.StartDate, &p.EndDate, &p.Notes, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan contract period: %w", err)
+		}
+		periods = append(periods, p)
+	}
+	return periods, rows.Err()
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestContractPeriodEditor(t *testing.T) {
+	db := setupImportDB(t)
+	editor := importer.NewContractPeriodEditor(db)
+
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	for _, p := range []*models.ContractPeriod{
+		{TermID: "T1", StartDate: day("2024-10-01"), EndDate: day("2025-09-30")},
+		{TermID: "T1", Label: "renewal", StartDate: day("2025-10-01"), EndDate: day("2026-09-30")},
+	} {
+		if err := editor.Add(p); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	for name, p := range map[string]*models.ContractPeriod{
+		"overlap":         {TermID: "T1", Label: "other", StartDate: day("2025-09-01"), EndDate: day("2025-12-31")},
+		"duplicate label": {TermID: "T1", Label: "2024", StartDate: day("2020-01-01"), EndDate: day("2020-12-31")},
+		"unknown term":    {TermID: "T2", StartDate: day("2024-01-01"), EndDate: day("2024-12-31")},
+		"reversed dates":  {TermID: "T1", StartDate: day("2021-12-31"), EndDate: day("2021-01-01")},
+	} {
+		if err := editor.Add(p); err == nil {
+			t.Errorf("Expected an error adding a period with %s", name)
+		}
+	}
+
+	periods, err := editor.List("T1")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(periods) != 2 || periods[0].Label != "2024" || periods[1].Label != "renewal" {
+		t.Fatalf("Unexpected periods: %+v", periods)
+	}
+
+	// Peaks are computed within the selected period only
+	path := filepath.Join(t.TempDir(), "iwdli_output_host1_20251021_090906.csv")
+	writeFile(t, path, testInspectorCSV)
+	if _, err := importer.NewImportService(db).ImportCSVFile(t.Context(), path); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	peak := reports.NewPeakUsageReport(db)
+	rows, err := peak.QueryPeriod(t.Context(), "", "", "renewal")
+	if err != nil {
+		t.Fatalf("QueryPeriod failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0].PeakDate != "2025-10-21" || rows[0].ContractPeriod != "renewal" ||
+		rows[0].PeriodStart != "2025-10-01" || rows[0].PeriodEnd != "2026-09-30" || rows[0].PeakRunningNodes != 1 {
+		t.Errorf("Unexpected peaks in the renewal period: %+v", rows)
+	}
+	if rows, err := peak.QueryPeriod(t.Context(), "", "", "2024"); err != nil || len(rows) != 0 {
+		t.Errorf("Expected no peaks in the 2024 period, got %+v (%v)", rows, err)
+	}
+
+	monthly, err := reports.NewMonthlyPeakReport(db).QueryPeriod(t.Context(), "IS_ONP_PRD", "", "renewal")
+	if err != nil {
+		t.Fatalf("QueryPeriod failed: %v", err)
+	}
+	if len(monthly) != 1 || monthly[0].Month != "2025-10" || monthly[0].DaysMeasured != 1 {
+		t.Errorf("Unexpected monthly peaks: %+v", monthly)
+	}
+
+	if err := editor.Remove("T1", "2024"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := editor.Remove("T1", "2024"); err == nil {
+		t.Error("Expected an error removing a missing period")
+	}
+}
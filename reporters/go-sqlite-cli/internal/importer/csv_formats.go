@@ -0,0 +1,190 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CSVFormatVersionField is the system field inspectors report the version of
+// their CSV format in
+const CSVFormatVersionField = "CSV_FORMAT_VERSION"
+
+// CSV format versions
+const (
+	// CSVFormatV1 is the format of older inspectors: all install paths in one
+	// semicolon separated <PRODUCT>_INSTALL_PATHS field, and all process command
+	// lines in one <PRODUCT>_RUNNING_COMMANDLINES field
+	CSVFormatV1 = "1"
+	// CSVFormatV2 writes one numbered field per install path and process:
+	// <PRODUCT>_INSTALL_PATH_01, <PRODUCT>_RUNNING_COMMANDLINES_01, ...
+	CSVFormatV2 = "2"
+)
+
+// productFieldSetter stores the value of one product field in the detection,
+// or returns an error for a value it cannot parse
+type productFieldSetter func(d *ProductDetection, value string) error
+
+// csvFormat maps the product fields of one CSV format version onto a
+// ProductDetection. Fields are named without their product code prefix and
+// without the number of numbered fields; "" is the present/absent status.
+// Fields the version does not define are ignored.
+type csvFormat struct {
+	fields   map[string]productFieldSetter
+	numbered map[string]productFieldSetter // applied in file order
+}
+
+// csvFormats holds the adapter of each supported CSV format version
+var csvFormats = map[string]*csvFormat{
+	CSVFormatV1: newCSVFormat(map[string]productFieldSetter{
+		"INSTALL_PATHS": func(d *ProductDetection, value string) error {
+			if value != "" {
+				d.InstallPaths = strings.Split(value, ";")
+			}
+			return nil
+		},
+		"RUNNING_COMMANDLINES": setCommandlines,
+		"RUNNING_COMMANDLINE":  setCommandlines,
+	}, nil),
+	CSVFormatV2: newCSVFormat(nil, map[string]productFieldSetter{
+		"INSTALL_PATH": func(d *ProductDetection, value string) error {
+			if value != "" {
+				d.InstallPaths = append(d.InstallPaths, value)
+			}
+			return nil
+		},
+		"RUNNING_COMMANDLINES": appendCommandline,
+		"RUNNING_COMMANDLINE":  appendCommandline,
+	}),
+}
+
+// newCSVFormat creates a format from the fields specific to the version; the
+// status, product code, running and install status and count fields are
+// common to all versions
+func newCSVFormat(fields, numbered map[string]productFieldSetter) *csvFormat {
+	f := &csvFormat{
+		fields: map[string]productFieldSetter{
+			"":                 setString(func(d *ProductDetection) *string { return &d.Status }),
+			"IBM_PRODUCT_CODE": setString(func(d *ProductDetection) *string { return &d.IBMProductCode }),
+			"RUNNING_STATUS":   setString(func(d *ProductDetection) *string { return &d.RunningStatus }),
+			"RUNNING_COUNT":    setCount(func(d *ProductDetection) *int { return &d.RunningCount }),
+			"INSTALL_STATUS":   setString(func(d *ProductDetection) *string { return &d.InstallStatus }),
+			"INSTALL_COUNT":    setCount(func(d *ProductDetection) *int { return &d.InstallCount }),
+		},
+		numbered: map[string]productFieldSetter{},
+	}
+	for name, set := range fields {
+		f.fields[name] = set
+	}
+	for name, set := range numbered {
+		f.numbered[name] = set
+	}
+	return f
+}
+
+// apply stores one product field; number is empty for unnumbered fields. An
+// error leaves the field unset.
+func (f *csvFormat) apply(d *ProductDetection, field, number, value string) error {
+	setters := f.fields
+	if number != "" {
+		setters = f.numbered
+	}
+	if set, ok := setters[field]; ok {
+		return set(d, value)
+	}
+	return nil
+}
+
+// setString returns a setter storing the value in a string field
+func setString(field func(d *ProductDetection) *string) productFieldSetter {
+	return func(d *ProductDetection, value string) error {
+		*field(d) = value
+		return nil
+	}
+}
+
+// setCount returns a setter storing the value in a count field
+func setCount(field func(d *ProductDetection) *int) productFieldSetter {
+	return func(d *ProductDetection, value string) error {
+		count, err := parseCount(value)
+		if err != nil {
+			return err
+		}
+		*field(d) = count
+		return nil
+	}
+}
+
+// setCommandlines stores the command lines of all processes from one field
+func setCommandlines(d *ProductDetection, value string) error {
+	d.RunningCommandlines = value
+	return nil
+}
+
+// appendCommandline adds the command line of one process
+func appendCommandline(d *ProductDetection, value string) error {
+	if d.RunningCommandlines != "" {
+		d.RunningCommandlines += "\n"
+	}
+	d.RunningCommandlines += value
+	return nil
+}
+
+// parseCount parses a count that may be padded with spaces; an empty count is 0
+func parseCount(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("invalid count %q", value)
+	}
+	return count, nil
+}
+
+// SupportedCSVFormatVersions returns the CSV format versions the parser reads
+func SupportedCSVFormatVersions() []string {
+	versions := make([]string, 0, len(csvFormats))
+	for version := range csvFormats {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// lookupCSVFormat returns the adapter of a version
+func lookupCSVFormat(version string) (*csvFormat, error) {
+	format, ok := csvFormats[version]
+	if !ok {
+		return nil, fmt.Errorf("unsupported %s %q (supported: %s)",
+			CSVFormatVersionField, version, strings.Join(SupportedCSVFormatVersions(), ", "))
+	}
+	return format, nil
+}
+
+// detectCSVFormatVersion picks the version of files written before inspectors
+// reported CSV_FORMAT_VERSION: only version 2 has numbered product fields
+func detectCSVFormatVersion(fields []productField) string {
+	for _, f := range fields {
+		if f.number != "" {
+			return CSVFormatV2
+		}
+	}
+	return CSVFormatV1
+}
@@ -18,7 +18,6 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -32,8 +31,19 @@ type CSVRecord struct {
 	SourceFile         string
 	DetectionResult    string // SUCCESS, ERROR, or empty
 	ErrorMessage       string // Error message if detection failed
+	// SystemFields holds the system field values by their uppercase name; use
+	// GetSystemField for a case-insensitive lookup
 	SystemFields       map[string]string
+	// systemFieldNames maps the uppercase name of the system fields written in
+	// another case to their name in the file
+	systemFieldNames   map[string]string
 	ProductDetections  map[string]*ProductDetection
+	// FormatVersion is the CSV format version the product fields were read
+	// with: CSV_FORMAT_VERSION, or detected for files without it
+	FormatVersion      string
+	// Warnings lists the product fields that could not be parsed; they are
+	// skipped and the rest of the file is read
+	Warnings           []string
 }
 
 // ProductDetection represents detection data for a product
@@ -49,27 +59,80 @@ type ProductDetection struct {
 	InstallPaths          []string
 }
 
+// Commandlines returns the running process command lines, one per process
+func (d *ProductDetection) Commandlines() []string {
+	var lines []string
+	for _, line := range strings.Split(d.RunningCommandlines, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
 // ParseCSVFile parses an inspector CSV file in Parameter,Value format
 func ParseCSVFile(filePath string) (*CSVRecord, error) {
-	// Extract hostname from filename pattern: iwdli_output_<hostname>_<timestamp>.csv
-	hostname, err := extractHostnameFromFilename(filePath)
+	// Open file, decompressing gzip files and bundle members
+	file, err := openInspectorFile(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract hostname from filename: %w", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
+	defer file.Close()
+
+	return parseInspectorFile(file, filePath)
+}
+
+// parseInspectorFile parses the decompressed content of an inspector file
+func parseInspectorFile(r io.Reader, filePath string) (*CSVRecord, error) {
+	// Extract hostname from filename pattern: iwdli_output_<hostname>_<timestamp>.csv.
+	// Files renamed in transfer take it from the HOSTNAME field.
+	hostname, filenameErr := extractHostnameFromFilename(filePath)
 
-	// Open file
-	file, err := os.Open(filePath)
+	record, err := parseCSV(r, filePath, hostname)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return nil, err
 	}
-	defer file.Close()
+	if record.Hostname == "" {
+		return nil, fmt.Errorf("missing required field: HOSTNAME (%v)", filenameErr)
+	}
+	return record, nil
+}
 
+// ParseCSV parses an inspector CSV read from r, e.g. from standard input.
+// Without a filename the hostname must come from the HOSTNAME field; gzip
+// compressed content is decompressed. sourceName identifies the input in the
+// import session.
+func ParseCSV(r io.Reader, sourceName string) (*CSVRecord, error) {
+	r, err := decompressStream(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress input: %w", err)
+	}
+
+	record, err := parseCSV(r, sourceName, "")
+	if err != nil {
+		return nil, err
+	}
+	if record.Hostname == "" {
+		return nil, fmt.Errorf("missing required field: HOSTNAME (required without an iwdli_output_<hostname>_<timestamp>.csv filename)")
+	}
+	return record, nil
+}
+
+// parseCSV parses the content of an inspector CSV file; hostname is the one of
+// the filename, overridden by the HOSTNAME field
+func parseCSV(r io.Reader, sourceFile, hostname string) (*CSVRecord, error) {
 	// Parse CSV
-	reader := csv.NewReader(file)
+	tail := &tailReader{r: r}
+	reader := csv.NewReader(tail)
 	reader.TrimLeadingSpace = true
+	// Rows are not kept, only the strings of their fields
+	reader.ReuseRecord = true
 
 	// Read header
 	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, &PartialFileError{Reason: "the file is empty"}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to read CSV header: %w", err)
 	}
@@ -79,20 +142,29 @@ func ParseCSVFile(filePath string) (*CSVRecord, error) {
 
 	record := &CSVRecord{
 		Hostname:          hostname,
-		SourceFile:        filePath,
+		SourceFile:        sourceFile,
 		SystemFields:      make(map[string]string),
 		ProductDetections: make(map[string]*ProductDetection),
 	}
 
+	// Product fields are mapped once the format version is known
+	var productFields []productField
+
 	// Read all records
+	rows := 0
 	for {
 		row, err := reader.Read()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
+			// A file cut in the middle of its last line is still being written
+			if _, drainErr := io.Copy(io.Discard, tail); drainErr == nil && !tail.endsWithNewline() {
+				return nil, &PartialFileError{Reason: fmt.Sprintf("the last line is incomplete (%v)", err)}
+			}
 			return nil, fmt.Errorf("failed to read CSV row: %w", err)
 		}
+		rows++
 
 		if len(row) < 2 {
 			continue // Skip empty rows
@@ -106,15 +178,15 @@ func ParseCSVFile(filePath string) (*CSVRecord, error) {
 
 		// Check if this is a product field
 		if isProductField(parameterUpper) {
-			if err := parseProductField(record, parameterUpper, value); err != nil {
-				return nil, fmt.Errorf("failed to parse product field %s: %w", parameter, err)
+			field, err := splitProductField(parameterUpper, value)
+			if err != nil {
+				record.Warnings = append(record.Warnings, fmt.Sprintf("skipped product field %s: %v", parameter, err))
+				continue
 			}
+			field.parameter = parameter
+			productFields = append(productFields, field)
 		} else {
-			// Store both original and uppercase versions for compatibility
-			record.SystemFields[parameter] = value
-			if parameterUpper != parameter {
-				record.SystemFields[parameterUpper] = value
-			}
+			record.setSystemField(parameter, parameterUpper, value)
 
 			// Parse timestamp if this is the detection_timestamp field (case-insensitive)
 			if parameterUpper == "DETECTION_TIMESTAMP" {
@@ -143,10 +215,39 @@ func ParseCSVFile(filePath string) (*CSVRecord, error) {
 	}
 
 	// Validate required fields
+	if rows == 0 {
+		return nil, &PartialFileError{Reason: "the file holds only the header"}
+	}
 	if record.Timestamp.IsZero() {
 		return nil, fmt.Errorf("missing required field: DETECTION_TIMESTAMP")
 	}
 
+	record.FormatVersion = record.GetSystemField(CSVFormatVersionField)
+	declared := record.FormatVersion != ""
+	if !declared {
+		record.FormatVersion = detectCSVFormatVersion(productFields)
+	}
+	format, err := lookupCSVFormat(record.FormatVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// Inspectors declaring their format version write DETECTION_RESULT last;
+	// the files of older inspectors may not have it
+	if declared && record.DetectionResult == "" {
+		return nil, &PartialFileError{Reason: "DETECTION_RESULT, the last field written by the inspector, is missing"}
+	}
+	for _, field := range productFields {
+		detection, exists := record.ProductDetections[field.productCode]
+		if !exists {
+			detection = &ProductDetection{ProductCode: field.productCode}
+			record.ProductDetections[field.productCode] = detection
+		}
+		if err := format.apply(detection, field.field, field.number, field.value); err != nil {
+			record.Warnings = append(record.Warnings, fmt.Sprintf("skipped product field %s: %v", field.parameter, err))
+		}
+	}
+
 	// Check for failed detection
 	if record.DetectionResult == "ERROR" {
 		// Return record with error information for logging
@@ -158,14 +259,14 @@ func ParseCSVFile(filePath string) (*CSVRecord, error) {
 }
 
 // extractHostnameFromFilename extracts hostname from filename pattern
-// Expected pattern: iwdli_output_<hostname>_<timestamp>.csv
+// Expected pattern: iwdli_output_<hostname>_<timestamp>.csv, optionally with .gz
 // Timestamp format: YYYY-MM-DD_HHMMSS (e.g., 2025-10-31_161910) or YYYYMMDD_HHMMSS (e.g., 20251021_090906)
 func extractHostnameFromFilename(filePath string) (string, error) {
 	filename := filepath.Base(filePath)
 	
 	// Pattern: iwdli_output_<hostname>_<timestamp>.csv
 	// Support both date formats: YYYY-MM-DD_HHMMSS and YYYYMMDD_HHMMSS
-	re := regexp.MustCompile(`^iwdli_output_([^_]+)_\d{4}-?\d{2}-?\d{2}_\d{6}\.csv$`)
+	re := regexp.MustCompile(`^iwdli_output_([^_]+)_\d{4}-?\d{2}-?\d{2}_\d{6}\.csv(\.gz)?$`)
 	matches := re.FindStringSubmatch(filename)
 	
 	if len(matches) < 2 {
@@ -190,119 +291,97 @@ func isProductField(parameter string) bool {
 	       strings.Contains(parameter, "_NONPROD")
 }
 
-// parseProductField parses a product-related field and updates the record
+// productField is a product field split into the product code, the field name
+// and, for numbered fields, the number
+type productField struct {
+	parameter   string // the parameter name as written in the file
+	productCode string
+	field       string // "" for the present/absent status field
+	number      string // e.g. "01" for IS_ONP_PRD_INSTALL_PATH_01, "" when not numbered
+	value       string
+}
+
+// numberedFieldSuffix matches the number of numbered fields
+var numberedFieldSuffix = regexp.MustCompile(`^\d+$`)
+
+// splitProductField splits a product parameter name
 // Note: This function expects uppercase parameter names
-func parseProductField(record *CSVRecord, parameter, value string) error {
-	// Split parameter to extract product code and field type
+func splitProductField(parameter, value string) (productField, error) {
 	// Examples:
 	// IS_ONP_PRD -> product code: IS_ONP_PRD, field: (status)
 	// IS_ONP_NPR -> product code: IS_ONP_NPR, field: (status)
 	// IS_ONP_PRD_IBM_PRODUCT_CODE -> product code: IS_ONP_PRD, field: IBM_PRODUCT_CODE
 	// IS_ONP_NPR_RUNNING_STATUS -> product code: IS_ONP_NPR, field: RUNNING_STATUS
-	// IS_ONP_NPR_INSTALL_PATH_01 -> product code: IS_ONP_NPR, field: INSTALL_PATH (numbered)
-	// IS_ONP_NPR_RUNNING_COMMANDLINES_02 -> product code: IS_ONP_NPR, field: RUNNING_COMMANDLINES (numbered)
-	
+	// IS_ONP_NPR_INSTALL_PATH_01 -> product code: IS_ONP_NPR, field: INSTALL_PATH, number: 01
+	// IS_ONP_NPR_RUNNING_COMMANDLINES_02 -> product code: IS_ONP_NPR, field: RUNNING_COMMANDLINES, number: 02
+	field := productField{value: value}
+
 	parts := strings.Split(parameter, "_")
 	if len(parts) < 3 {
-		return fmt.Errorf("invalid product parameter format: %s", parameter)
+		return field, fmt.Errorf("invalid product parameter format: %s", parameter)
 	}
 
 	// Find the product code (everything up to and including _PRD, _NPR, or _NONPROD)
-	var productCode string
-	var fieldType string
-	var fieldNumber string
-	
 	for i, part := range parts {
 		if part == "PRD" || part == "NPR" || part == "NONPROD" {
-			productCode = strings.Join(parts[:i+1], "_")
+			field.productCode = strings.Join(parts[:i+1], "_")
 			if i+1 < len(parts) {
 				remainingParts := parts[i+1:]
-				
+
 				// Check if the last part is a number (e.g., _01, _02)
 				lastPart := remainingParts[len(remainingParts)-1]
-				if matched, _ := regexp.MatchString(`^\d+$`, lastPart); matched {
-					fieldNumber = lastPart
-					if len(remainingParts) > 1 {
-						fieldType = strings.Join(remainingParts[:len(remainingParts)-1], "_")
-					}
+				if numberedFieldSuffix.MatchString(lastPart) {
+					field.number = lastPart
+					field.field = strings.Join(remainingParts[:len(remainingParts)-1], "_")
 				} else {
-					fieldType = strings.Join(remainingParts, "_")
+					field.field = strings.Join(remainingParts, "_")
 				}
 			}
 			break
 		}
 	}
 
-	if productCode == "" {
-		return fmt.Errorf("could not extract product code from: %s", parameter)
+	if field.productCode == "" {
+		return field, fmt.Errorf("could not extract product code from: %s", parameter)
 	}
 
-	// Get or create product detection entry
-	detection, exists := record.ProductDetections[productCode]
-	if !exists {
-		detection = &ProductDetection{
-			ProductCode: productCode,
-		}
-		record.ProductDetections[productCode] = detection
-	}
+	return field, nil
+}
 
-	// Parse field based on type
-	switch fieldType {
-	case "":
-		// This is the main status field (present/absent)
-		detection.Status = value
-	case "IBM_PRODUCT_CODE":
-		detection.IBMProductCode = value
-	case "RUNNING_STATUS":
-		detection.RunningStatus = value
-	case "RUNNING_COUNT":
-		// Parse running count (may have leading spaces)
-		var count int
-		fmt.Sscanf(value, "%d", &count)
-		detection.RunningCount = count
-	case "RUNNING_COMMANDLINES", "RUNNING_COMMANDLINE":
-		// Handle both old format (single field) and new format (numbered fields)
-		if fieldNumber != "" {
-			// New numbered format: append with newline separator
-			if detection.RunningCommandlines != "" {
-				detection.RunningCommandlines += "\n"
-			}
-			detection.RunningCommandlines += value
-		} else {
-			// Old format: single field with all commandlines
-			detection.RunningCommandlines = value
-		}
-	case "INSTALL_STATUS":
-		detection.InstallStatus = value
-	case "INSTALL_COUNT":
-		// Parse install count (may have leading spaces)
-		var count int
-		fmt.Sscanf(value, "%d", &count)
-		detection.InstallCount = count
-	case "INSTALL_PATHS":
-		// Old format: semicolon-separated paths in single field
-		if value != "" {
-			detection.InstallPaths = strings.Split(value, ";")
-		}
-	case "INSTALL_PATH":
-		// New numbered format: individual path fields
-		if value != "" {
-			detection.InstallPaths = append(detection.InstallPaths, value)
+// setSystemField stores a system field under its uppercase name, and indexes
+// the name written in the file when it differs
+func (r *CSVRecord) setSystemField(name, upper, value string) {
+	r.SystemFields[upper] = value
+	if name != upper {
+		if r.systemFieldNames == nil {
+			r.systemFieldNames = make(map[string]string)
 		}
+		r.systemFieldNames[upper] = name
+	} else if r.systemFieldNames != nil {
+		delete(r.systemFieldNames, upper)
 	}
+}
 
-	return nil
+// SetSystemField sets a system field value (case-insensitive)
+func (r *CSVRecord) SetSystemField(name, value string) {
+	r.setSystemField(name, strings.ToUpper(name), value)
 }
 
 // GetSystemField retrieves a system field value (case-insensitive)
 func (r *CSVRecord) GetSystemField(name string) string {
-	// Try exact match first
-	if val, exists := r.SystemFields[name]; exists {
-		return val
+	// strings.ToUpper does not allocate for names already in uppercase
+	return r.SystemFields[strings.ToUpper(name)]
+}
+
+// SystemFieldName returns the name of a system field as written in the file,
+// empty when the record does not have the field
+func (r *CSVRecord) SystemFieldName(name string) string {
+	upper := strings.ToUpper(name)
+	if original, ok := r.systemFieldNames[upper]; ok {
+		return original
 	}
-	// Try uppercase match
-	if val, exists := r.SystemFields[strings.ToUpper(name)]; exists {
-		return val
+	if _, ok := r.SystemFields[upper]; ok {
+		return upper
 	}
 	return ""
 }
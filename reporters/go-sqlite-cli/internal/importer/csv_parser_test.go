@@ -15,8 +15,11 @@
 package importer_test
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -305,4 +308,142 @@ CPU_COUNT,8
 	if record.GetSystemFieldWithDefault("MISSING_FIELD", "default") != "default" {
 		t.Error("GetSystemFieldWithDefault failed for missing field")
 	}
+
+	// Fields are stored once, under their uppercase name, whatever their case
+	if len(record.SystemFields) != 3 {
+		t.Errorf("Expected 3 system fields, got %v", record.SystemFields)
+	}
+	if record.GetSystemField("Detection_Timestamp") != "2025-10-21T09:09:06Z" || record.GetSystemField("os_name") != "Linux" {
+		t.Error("GetSystemField is not case-insensitive")
+	}
+	if name := record.SystemFieldName("DETECTION_TIMESTAMP"); name != "detection_timestamp" {
+		t.Errorf("Expected the name in the file, got %q", name)
+	}
+	if name := record.SystemFieldName("os_name"); name != "OS_NAME" {
+		t.Errorf("Expected OS_NAME, got %q", name)
+	}
+	if name := record.SystemFieldName("MISSING_FIELD"); name != "" {
+		t.Errorf("Expected no name for a missing field, got %q", name)
+	}
+}
+
+func TestParseCSVFileFormatVersions(t *testing.T) {
+	const header = "Parameter,Value\nDETECTION_TIMESTAMP,2025-10-21T09:09:06Z\n"
+
+	tests := []struct {
+		name         string
+		content      string
+		version      string
+		paths        []string
+		commandlines []string
+	}{
+		{
+			name:         "version 1 detected",
+			content:      "IS_ONP_PRD_INSTALL_PATHS,/opt/is1;/opt/is2\nIS_ONP_PRD_RUNNING_COMMANDLINES,java -Dis1\n",
+			version:      importer.CSVFormatV1,
+			paths:        []string{"/opt/is1", "/opt/is2"},
+			commandlines: []string{"java -Dis1"},
+		},
+		{
+			name:         "version 2 detected",
+			content:      "IS_ONP_PRD_INSTALL_PATH_01,/opt/is1\nIS_ONP_PRD_INSTALL_PATH_02,/opt/is2\nIS_ONP_PRD_RUNNING_COMMANDLINES_01,java -Dis1\nIS_ONP_PRD_RUNNING_COMMANDLINES_02,java -Dis2\n",
+			version:      importer.CSVFormatV2,
+			paths:        []string{"/opt/is1", "/opt/is2"},
+			commandlines: []string{"java -Dis1", "java -Dis2"},
+		},
+		{
+			// A declared version only maps the fields of that version
+			name:    "version 2 declared",
+			content: "CSV_FORMAT_VERSION,2\nIS_ONP_PRD_INSTALL_PATHS,/opt/is1;/opt/is2\nIS_ONP_PRD_INSTALL_PATH_01,/opt/is3\nDETECTION_RESULT,SUCCESS\n",
+			version: importer.CSVFormatV2,
+			paths:   []string{"/opt/is3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csvPath := filepath.Join(t.TempDir(), "iwdli_output_test_20251021_090906.csv")
+			if err := os.WriteFile(csvPath, []byte(header+"IS_ONP_PRD,present\n"+tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create test CSV: %v", err)
+			}
+
+			record, err := importer.ParseCSVFile(csvPath)
+			if err != nil {
+				t.Fatalf("ParseCSVFile failed: %v", err)
+			}
+			if record.FormatVersion != tt.version {
+				t.Errorf("Expected format version %s, got %s", tt.version, record.FormatVersion)
+			}
+
+			detection := record.ProductDetections["IS_ONP_PRD"]
+			if strings.Join(detection.InstallPaths, ",") != strings.Join(tt.paths, ",") {
+				t.Errorf("Expected install paths %v, got %v", tt.paths, detection.InstallPaths)
+			}
+			if strings.Join(detection.Commandlines(), ",") != strings.Join(tt.commandlines, ",") {
+				t.Errorf("Expected command lines %v, got %v", tt.commandlines, detection.Commandlines())
+			}
+		})
+	}
+}
+
+func TestParseCSVFileRejectsUnknownFormatVersion(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "iwdli_output_test_20251021_090906.csv")
+	content := "Parameter,Value\nDETECTION_TIMESTAMP,2025-10-21T09:09:06Z\nCSV_FORMAT_VERSION,9\n"
+	if err := os.WriteFile(csvPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test CSV: %v", err)
+	}
+
+	_, err := importer.ParseCSVFile(csvPath)
+	if err == nil || !strings.Contains(err.Error(), `unsupported CSV_FORMAT_VERSION "9"`) {
+		t.Errorf("Expected unsupported version error, got %v", err)
+	}
+}
+
+// inspectorFile500 returns an inspector file of 500 fields: 100 system fields,
+// half of them in lowercase as written by older inspectors, and the fields of
+// 80 products
+func inspectorFile500() []byte {
+	var b bytes.Buffer
+	b.WriteString("Parameter,Value\ndetection_timestamp,2025-10-21T09:09:06Z\nHOSTNAME,app01\n")
+	for i := 0; i < 98; i++ {
+		name := fmt.Sprintf("SYSTEM_FIELD_%03d", i)
+		if i%2 == 0 {
+			name = strings.ToLower(name)
+		}
+		fmt.Fprintf(&b, "%s,value %d\n", name, i)
+	}
+	for i := 0; i < 80; i++ {
+		code := fmt.Sprintf("P%02d_ONP_PRD", i)
+		fmt.Fprintf(&b, "%s,present\n%s_IBM_PRODUCT_CODE,D0%05d\n%s_INSTALL_STATUS,installed\n%s_INSTALL_COUNT,1\n", code, code, i, code, code)
+		fmt.Fprintf(&b, "%s_INSTALL_PATH_01,/opt/p%02d\n", code, i)
+	}
+	return b.Bytes()
+}
+
+func BenchmarkParseCSV500Fields(b *testing.B) {
+	content := inspectorFile500()
+	b.ReportAllocs()
+	b.ResetTimer()
+	var record *importer.CSVRecord
+	for i := 0; i < b.N; i++ {
+		var err error
+		if record, err = importer.ParseCSV(bytes.NewReader(content), "iwdli_output_app01_20251021_090906.csv"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	// Each system field is stored once, whatever its case in the file
+	b.ReportMetric(float64(len(record.SystemFields)), "system-fields/op")
+}
+
+func BenchmarkGetSystemField(b *testing.B) {
+	record, err := importer.ParseCSV(bytes.NewReader(inspectorFile500()), "iwdli_output_app01_20251021_090906.csv")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		record.GetSystemField("SYSTEM_FIELD_100")
+		record.GetSystemField("system_field_101")
+	}
 }
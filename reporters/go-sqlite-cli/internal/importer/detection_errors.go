@@ -0,0 +1,76 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DetectionFailedError is returned for files whose inspector run reported
+// DETECTION_RESULT=ERROR. The failure is recorded in the detection_errors table
+// instead of failed_imports: importing the file again cannot succeed.
+type DetectionFailedError struct {
+	Hostname  string
+	MainFQDN  string
+	Timestamp time.Time
+	Message   string
+}
+
+func (e *DetectionFailedError) Error() string {
+	return fmt.Sprintf("inspector detection failed for %s: %s", e.Hostname, e.Message)
+}
+
+// recordDetectionError stores a failed detection in detection_errors, under the
+// main FQDN of the node, and returns the error reported for the file
+func (s *ImportService) recordDetectionError(ctx context.Context, record *CSVRecord) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	mainFQDN, err := resolveNodeAlias(tx, record.GetSystemFieldWithDefault("main_fqdn", record.Hostname+".local"))
+	if err != nil {
+		return err
+	}
+
+	detectionErr := &DetectionFailedError{
+		Hostname:  record.Hostname,
+		MainFQDN:  mainFQDN,
+		Timestamp: record.Timestamp,
+		Message:   record.GetDetectionError(),
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO detection_errors (main_fqdn, detection_timestamp, hostname, error_message, source_file)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(main_fqdn, detection_timestamp) DO UPDATE SET
+			hostname = excluded.hostname,
+			error_message = excluded.error_message,
+			source_file = excluded.source_file,
+			recorded_at = CURRENT_TIMESTAMP
+	`, mainFQDN, record.Timestamp, record.Hostname, detectionErr.Message, record.SourceFile)
+	if err != nil {
+		return fmt.Errorf("failed to record detection error: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return detectionErr
+}
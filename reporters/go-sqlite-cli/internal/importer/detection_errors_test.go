@@ -0,0 +1,93 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestDetectionErrorsAreRecorded(t *testing.T) {
+	db := setupImportDB(t)
+	service := importer.NewImportService(db)
+	root := t.TempDir()
+
+	failed := testInspectorCSV + "DETECTION_RESULT,ERROR\nERROR_MESSAGE,lscpu not found\n"
+	files := map[string]string{
+		// host1 failed after its last measurement, host2 recovered
+		"iwdli_output_host1_20251021_090906.csv": testInspectorCSV,
+		"iwdli_output_host1_20251022_090906.csv": strings.Replace(failed, "2025-10-21", "2025-10-22", 1),
+		"iwdli_output_host2_20251021_090906.csv": failed,
+		"iwdli_output_host2_20251022_090906.csv": strings.Replace(testInspectorCSV, "2025-10-21", "2025-10-22", 1),
+	}
+	var paths []string
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		writeFile(t, path, content)
+		paths = append(paths, path)
+	}
+
+	batch := service.ImportFiles(t.Context(), paths, nil)
+	if batch.FilesOK != 2 || batch.FilesFailed != 2 || batch.DetectionErrors != 2 {
+		t.Fatalf("Expected 2 ok / 2 failed detections, got %d / %d / %d", batch.FilesOK, batch.FilesFailed, batch.DetectionErrors)
+	}
+	for _, fr := range batch.Files {
+		var detectionErr *importer.DetectionFailedError
+		if fr.Err != nil && (!errors.As(fr.Err, &detectionErr) || detectionErr.Message != "lscpu not found") {
+			t.Errorf("Unexpected error for %s: %v", filepath.Base(fr.FilePath), fr.Err)
+		}
+	}
+
+	// A failed detection cannot be retried
+	var failedImports int
+	if err := db.QueryRow("SELECT COUNT(*) FROM failed_imports").Scan(&failedImports); err != nil {
+		t.Fatalf("Failed to count failed imports: %v", err)
+	}
+	if failedImports != 0 {
+		t.Errorf("Expected no failed imports, got %d", failedImports)
+	}
+
+	// Importing the file again updates its row
+	if _, err := service.ImportCSVFile(t.Context(), filepath.Join(root, "iwdli_output_host2_20251021_090906.csv")); err == nil {
+		t.Error("Expected the failed detection to be reported")
+	}
+
+	rows, err := reports.NewDetectionErrorReport(db).Query(t.Context(), "", nil, nil, false)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 hosts, got %+v", rows)
+	}
+	if rows[0].MainFQDN != "host1.local" || !rows[0].StillBroken || rows[0].LastSuccess != "2025-10-21 09:09:06" {
+		t.Errorf("Expected host1 to be still broken, got %+v", rows[0])
+	}
+	if rows[1].MainFQDN != "host2.local" || rows[1].StillBroken || rows[1].ErrorCount != 1 || rows[1].LastMessage != "lscpu not found" {
+		t.Errorf("Expected host2 to have recovered, got %+v", rows[1])
+	}
+
+	rows, err = reports.NewDetectionErrorReport(db).Query(t.Context(), "host", nil, nil, true)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0].MainFQDN != "host1.local" {
+		t.Errorf("Expected only host1 with --broken-only, got %+v", rows)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+)
+
+// RecordFailedImport stores a failed import in the failed_imports table.
+// Repeated failures of the same file update the error and bump the attempt count.
+func (s *ImportService) RecordFailedImport(filePath string, importErr error) error {
+	path := absPath(filePath)
+
+	_, err := s.db.Exec(`
+		INSERT INTO failed_imports (file_path, error_message)
+		VALUES (?, ?)
+		ON CONFLICT(file_path) DO UPDATE SET
+			error_message = excluded.error_message,
+			attempt_count = attempt_count + 1,
+			last_failed_at = CURRENT_TIMESTAMP
+	`, path, importErr.Error())
+	if err != nil {
+		return fmt.Errorf("failed to record failed import for %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// ClearFailedImport removes a file from the failed_imports table, if present
+func (s *ImportService) ClearFailedImport(filePath string) error {
+	path := absPath(filePath)
+
+	if _, err := s.db.Exec("DELETE FROM failed_imports WHERE file_path = ?", path); err != nil {
+		return fmt.Errorf("failed to clear failed import for %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// MoveFailedImport updates the stored path after a failed file has been moved
+// (e.g. into the discards directory) so that a retry can still find it
+func (s *ImportService) MoveFailedImport(oldPath, newPath string) error {
+	_, err := s.db.Exec("UPDATE failed_imports SET file_path = ? WHERE file_path = ?",
+		absPath(newPath), absPath(oldPath))
+	if err != nil {
+		return fmt.Errorf("failed to update failed import path for %s: %w", oldPath, err)
+	}
+
+	return nil
+}
+
+// ListFailedImports returns all files waiting to be retried, oldest failure first
+func (s *ImportService) ListFailedImports() ([]models.FailedImport, error) {
+	rows, err := s.db.Query(`
+		SELECT file_path, error_message, attempt_count, first_failed_at, last_failed_at
+		FROM failed_imports
+		ORDER BY first_failed_at, file_path
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failed imports: %w", err)
+	}
+	defer rows.Close()
+
+	var failed []models.FailedImport
+	for rows.Next() {
+		var f models.FailedImport
+		if err := rows.Scan(&f.FilePath, &f.ErrorMessage, &f.AttemptCount, &f.FirstFailedAt, &f.LastFailedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan failed import: %w", err)
+		}
+		failed = append(failed, f)
+	}
+
+	return failed, rows.Err()
+}
+
+// RetryFailedImports re-imports every file in the failed_imports table.
+// Files that now import successfully are removed from the table; files that
+// fail again stay in it with an updated error and attempt count.
+func (s *ImportService) RetryFailedImports(ctx context.Context, onFile func(index int, fr FileImportResult)) (*BatchImportResult, error) {
+	failed, err := s.ListFailedImports()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(failed))
+	for _, f := range failed {
+		files = append(files, f.FilePath)
+	}
+
+	return s.ImportFiles(ctx, files, onFile), nil
+}
+
+// absPath returns an absolute path so failures can be retried from any working directory
+func absPath(filePath string) string {
+	if abs, err := filepath.Abs(filePath); err == nil {
+		return abs
+	}
+	return filePath
+}
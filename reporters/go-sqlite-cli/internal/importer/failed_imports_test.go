@@ -0,0 +1,77 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestFailedImportsAreRecordedAndRetried(t *testing.T) {
+	db := setupImportDB(t)
+	service := importer.NewImportService(db)
+	root := t.TempDir()
+
+	bad := filepath.Join(root, "iwdli_output_host1_20251021_090906.csv")
+	writeFile(t, bad, "Parameter,Value\nOS_NAME,Linux\n") // missing DETECTION_TIMESTAMP
+
+	// First failure creates the record
+	service.ImportFiles(t.Context(), []string{bad}, nil)
+	failed, err := service.ListFailedImports()
+	if err != nil {
+		t.Fatalf("ListFailedImports failed: %v", err)
+	}
+	if len(failed) != 1 {
+		t.Fatalf("Expected 1 failed import, got %d", len(failed))
+	}
+	if failed[0].FilePath != bad || failed[0].AttemptCount != 1 || failed[0].ErrorMessage == "" {
+		t.Errorf("Unexpected failed import record: %+v", failed[0])
+	}
+
+	// Retrying without a fix keeps the record and bumps the attempt count
+	batch, err := service.RetryFailedImports(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("RetryFailedImports failed: %v", err)
+	}
+	if batch.FilesFailed != 1 {
+		t.Errorf("Expected retry to fail again, got %d failures", batch.FilesFailed)
+	}
+	failed, _ = service.ListFailedImports()
+	if len(failed) != 1 || failed[0].AttemptCount != 2 {
+		t.Fatalf("Expected 1 record with 2 attempts, got %+v", failed)
+	}
+
+	// Moving the file keeps it retryable under its new path
+	moved := filepath.Join(root, "discards", filepath.Base(bad))
+	writeFile(t, moved, testInspectorCSV) // fixed content at the new location
+	if err := service.MoveFailedImport(bad, moved); err != nil {
+		t.Fatalf("MoveFailedImport failed: %v", err)
+	}
+
+	batch, err = service.RetryFailedImports(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("RetryFailedImports failed: %v", err)
+	}
+	if batch.FilesOK != 1 || batch.Files[0].FilePath != moved {
+		t.Errorf("Expected moved file to import on retry, got %+v", batch.Files)
+	}
+
+	failed, _ = service.ListFailedImports()
+	if len(failed) != 0 {
+		t.Errorf("Expected failed imports to be cleared after success, got %d", len(failed))
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var hostCapacityHeader = []string{"physical-host-id", "physical-cores", "host-name"}
+
+// HostCapacityLoader loads the physical core counts of hypervisor hosts from
+// an inventory other than vCenter, e.g. an HMC export of the IBM Power
+// servers running LPARs, into hypervisor_hosts
+type HostCapacityLoader struct {
+	db *sql.DB
+}
+
+// NewHostCapacityLoader creates a new host capacity loader
+func NewHostCapacityLoader(db *sql.DB) *HostCapacityLoader {
+	return &HostCapacityLoader{db: db}
+}
+
+// Load replaces the hosts of source in hypervisor_hosts with those of a CSV
+// file with the header physical-host-id,physical-cores,host-name and returns
+// the number of hosts loaded. Unlike 'sync vcenter' it leaves the physical
+// hosts and the measurements unchanged: the hosts are only the reference of
+// the capacity reconciliation report.
+func (l *HostCapacityLoader) Load(filePath, source string) (int, error) {
+	if source == "" {
+		return 0, fmt.Errorf("the source of the host capacity is required")
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1 // Allow variable number of fields
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read header: %w", err)
+	}
+	if !equalHeaders(header, hostCapacityHeader) {
+		return 0, fmt.Errorf("invalid CSV header, expected: %v", hostCapacityHeader)
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM hypervisor_hosts WHERE source = ?", source); err != nil {
+		return 0, fmt.Errorf("failed to clear the hosts of %s: %w", source, err)
+	}
+
+	now := time.Now().UTC()
+	loaded := 0
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read line %d: %w", line, err)
+		}
+		if len(row) < 2 || strings.TrimSpace(row[0]) == "" {
+			continue // Skip incomplete rows
+		}
+
+		hostID := strings.TrimSpace(row[0])
+		cores, err := strconv.Atoi(strings.TrimSpace(row[1]))
+		if err != nil || cores < 1 {
+			return 0, fmt.Errorf("line %d: invalid physical-cores for %s: %q (expected a positive number)", line, hostID, row[1])
+		}
+		hostName := hostID
+		if len(row) > 2 && strings.TrimSpace(row[2]) != "" {
+			hostName = strings.TrimSpace(row[2])
+		}
+
+		// Store the host under the ID it was renamed or merged into
+		var aliased string
+		err = tx.QueryRow("SELECT physical_host_id FROM physical_host_aliases WHERE alias_id = ?", hostID).Scan(&aliased)
+		if err == nil {
+			hostID = aliased
+		} else if err != sql.ErrNoRows {
+			return 0, fmt.Errorf("failed to resolve physical host alias: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO hypervisor_hosts
+			(physical_host_id, source, host_ref, host_name, physical_cores, synced_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(physical_host_id) DO UPDATE SET
+			    source = excluded.source, host_ref = excluded.host_ref, host_name = excluded.host_name,
+			    host_uuid = '', cpu_packages = NULL,
+			    physical_cores = excluded.physical_cores, synced_at = excluded.synced_at
+		`, hostID, source, hostID, hostName, cores, now)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load host %s: %w", hostID, err)
+		}
+		loaded++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return loaded, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestHostCapacityLoader(t *testing.T) {
+	db := setupImportDB(t)
+
+	if _, err := db.Exec(`INSERT INTO physical_host_aliases (alias_id, physical_host_id) VALUES ('p9-old', 'p9-1')`); err != nil {
+		t.Fatalf("Failed to insert alias: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "hmc.csv")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write CSV: %v", err)
+		}
+	}
+	loader := importer.NewHostCapacityLoader(db)
+
+	write("physical-host-id,physical-cores,host-name\np9-old,48,Server-9080-M9S\np9-2,24,\n")
+	loaded, err := loader.Load(path, "hmc01")
+	if err != nil || loaded != 2 {
+		t.Fatalf("Expected 2 hosts loaded, got %d (%v)", loaded, err)
+	}
+
+	var name string
+	var cores int
+	if err := db.QueryRow(`SELECT host_name, physical_cores FROM hypervisor_hosts WHERE physical_host_id = 'p9-1'`).Scan(&name, &cores); err != nil {
+		t.Fatalf("Expected the aliased host stored as p9-1: %v", err)
+	}
+	if name != "Server-9080-M9S" || cores != 48 {
+		t.Errorf("Unexpected host p9-1: %s, %d cores", name, cores)
+	}
+
+	// A second load replaces the hosts of the source
+	write("physical-host-id,physical-cores,host-name\np9-2,32,p9-2\n")
+	if _, err := loader.Load(path, "hmc01"); err != nil {
+		t.Fatalf("Second load failed: %v", err)
+	}
+	var count int
+	db.QueryRow(`SELECT COUNT(*) FROM hypervisor_hosts WHERE source = 'hmc01'`).Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected the hosts of hmc01 replaced, got %d", count)
+	}
+
+	write("physical-host-id,physical-cores,host-name\np9-3,many,\n")
+	if _, err := loader.Load(path, "hmc01"); err == nil {
+		t.Error("Expected an error for invalid physical-cores")
+	}
+}
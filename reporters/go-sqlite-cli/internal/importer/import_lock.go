@@ -0,0 +1,209 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ImportLockStaleAfter is how long a lock whose holder stopped refreshing it is
+// honored. The holder refreshes the lock every importLockRefresh for as long as
+// it holds it, so an older lock was left behind by a process that crashed or
+// was killed and is taken over.
+const ImportLockStaleAfter = 5 * time.Minute
+
+// importLockRefresh is how often the holder refreshes the lock, well within
+// ImportLockStaleAfter so that a slow refresh does not let the lock go stale
+var importLockRefresh = time.Minute
+
+// importLockPoll is how often a waiting process checks whether the lock was released
+const importLockPoll = time.Second
+
+// importLockName is the lock_name row of the import lock
+const importLockName = "import"
+
+// ImportLock is the advisory lock that keeps importer processes working on the
+// same database one at a time. Imports count created and updated records by
+// what they find in the database, which two concurrent imports of the same
+// measurements would get wrong.
+type ImportLock struct {
+	db      *sql.DB
+	holder  string
+	stop    chan struct{} // closed by Release to stop the refreshes
+	stopped chan struct{} // closed once the refreshes stopped
+	release sync.Once
+}
+
+// ImportLockedError is returned when another process holds the import lock
+type ImportLockedError struct {
+	Holder     string // hostname:pid of the holding process
+	Command    string
+	AcquiredAt time.Time
+}
+
+func (e *ImportLockedError) Error() string {
+	return fmt.Sprintf("database is locked by another import: %s by %s since %s", e.Command, e.Holder, e.AcquiredAt.Format("2006-01-02 15:04:05"))
+}
+
+// AcquireImportLock takes the import lock of the database for command. While
+// another process holds it, AcquireImportLock waits up to wait for it to be
+// released; with a zero wait it returns an ImportLockedError at once.
+func AcquireImportLock(db *sql.DB, command string, wait time.Duration) (*ImportLock, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	lock := &ImportLock{db: db, holder: fmt.Sprintf("%s:%d", hostname, os.Getpid())}
+
+	deadline := time.Now().Add(wait)
+	for {
+		held, err := lock.tryAcquire(command)
+		if err != nil {
+			return nil, err
+		}
+		if held == nil {
+			lock.keepAlive()
+			return lock, nil
+		}
+		if !time.Now().Before(deadline) {
+			return nil, held
+		}
+		time.Sleep(min(importLockPoll, time.Until(deadline)))
+	}
+}
+
+// tryAcquire takes the lock if it is free or stale, and otherwise returns the
+// current holder
+func (l *ImportLock) tryAcquire(command string) (*ImportLockedError, error) {
+	staleAfter := fmt.Sprintf("-%d seconds", int(ImportLockStaleAfter.Seconds()))
+	for {
+		res, err := l.db.Exec(`
+			INSERT INTO import_lock (lock_name, holder, command, acquired_at, refreshed_at)
+			VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			ON CONFLICT(lock_name) DO UPDATE SET
+				holder = excluded.holder,
+				command = excluded.command,
+				acquired_at = excluded.acquired_at,
+				refreshed_at = excluded.refreshed_at
+			WHERE import_lock.refreshed_at < datetime('now', ?)
+		`, importLockName, l.holder, command, staleAfter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire import lock: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			return nil, nil
+		}
+
+		held, err := readImportLock(l.db)
+		if err != nil {
+			return nil, err
+		}
+		// A nil holder released the lock in the meantime; try again
+		if held != nil {
+			return held, nil
+		}
+	}
+}
+
+// readImportLock returns the holder of the import lock, or nil when it is free
+func readImportLock(db *sql.DB) (*ImportLockedError, error) {
+	held := &ImportLockedError{}
+	err := db.QueryRow("SELECT holder, command, acquired_at FROM import_lock WHERE lock_name = ?", importLockName).
+		Scan(&held.Holder, &held.Command, &held.AcquiredAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import lock: %w", err)
+	}
+	return held, nil
+}
+
+// keepAlive refreshes the lock from a goroutine until Release, like the
+// heartbeat of a running job, so that a single long import, rollback or retry
+// does not let the lock go stale and be taken over while it writes
+func (l *ImportLock) keepAlive() {
+	l.stop = make(chan struct{})
+	l.stopped = make(chan struct{})
+	ticker := time.NewTicker(importLockRefresh)
+	go func() {
+		defer close(l.stopped)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ticker.C:
+				// A lock broken by 'import unlock' is not taken back; the
+				// next Refresh of the batch reports it
+				if err := l.Refresh(); err != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// Refresh marks the lock as still in use. The lock refreshes itself while it
+// is held; batches also refresh it after every file to find out it was taken
+// over.
+func (l *ImportLock) Refresh() error {
+	res, err := l.db.Exec("UPDATE import_lock SET refreshed_at = CURRENT_TIMESTAMP WHERE lock_name = ? AND holder = ?",
+		importLockName, l.holder)
+	if err != nil {
+		return fmt.Errorf("failed to refresh import lock: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("import lock was taken over by another process")
+	}
+	return nil
+}
+
+// Release stops the refreshes and gives up the lock
+func (l *ImportLock) Release() error {
+	l.release.Do(func() {
+		if l.stop != nil {
+			close(l.stop)
+			<-l.stopped
+		}
+	})
+	if _, err := l.db.Exec("DELETE FROM import_lock WHERE lock_name = ? AND holder = ?", importLockName, l.holder); err != nil {
+		return fmt.Errorf("failed to release import lock: %w", err)
+	}
+	return nil
+}
+
+// BreakImportLock removes the import lock whoever holds it, for locks left by
+// a process that can no longer release them. It returns the former holder, or
+// nil when the lock was free.
+func BreakImportLock(db *sql.DB) (*ImportLockedError, error) {
+	held, err := readImportLock(db)
+	if err != nil || held == nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec("DELETE FROM import_lock WHERE lock_name = ?", importLockName); err != nil {
+		return nil, fmt.Errorf("failed to remove import lock: %w", err)
+	}
+	return held, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+)
+
+func TestImportLockRefreshesWhileHeld(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	defer func(refresh time.Duration) { importLockRefresh = refresh }(importLockRefresh)
+	importLockRefresh = 20 * time.Millisecond
+
+	lock, err := AcquireImportLock(db, "import", 0)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	// A single file taking longer than ImportLockStaleAfter: the lock would
+	// look stale without the refreshes of the holder
+	if _, err := db.Exec("UPDATE import_lock SET refreshed_at = datetime('now', '-1 hour')"); err != nil {
+		t.Fatalf("Failed to age the lock: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var stale bool
+		if err := db.QueryRow("SELECT refreshed_at < datetime('now', '-1 minute') FROM import_lock").Scan(&stale); err != nil {
+			t.Fatalf("Failed to read the lock: %v", err)
+		}
+		if !stale {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the held lock to be refreshed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	var held *ImportLockedError
+	if _, err := AcquireImportLock(db, "collect", 0); !errors.As(err, &held) || held.Command != "import" {
+		t.Fatalf("Expected the refreshed lock to stay with import, got %v", err)
+	}
+
+	// Release stops the refreshes and frees the lock, also when called twice
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Second release failed: %v", err)
+	}
+	if held, err := readImportLock(db); err != nil || held != nil {
+		t.Fatalf("Expected a free lock after release, got %+v %v", held, err)
+	}
+	time.Sleep(3 * importLockRefresh)
+	if held, err := readImportLock(db); err != nil || held != nil {
+		t.Errorf("Expected the released lock to stay free, got %+v %v", held, err)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestImportLock(t *testing.T) {
+	db := setupImportDB(t)
+
+	lock, err := importer.AcquireImportLock(db, "import", 0)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	// A second import fails at once, or after waiting
+	var held *importer.ImportLockedError
+	if _, err := importer.AcquireImportLock(db, "collect", 0); !errors.As(err, &held) || held.Command != "import" {
+		t.Fatalf("Expected the lock to be held by import, got %v", err)
+	}
+	start := time.Now()
+	if _, err := importer.AcquireImportLock(db, "collect", 1500*time.Millisecond); !errors.As(err, &held) {
+		t.Fatalf("Expected the lock to be held after waiting, got %v", err)
+	}
+	if waited := time.Since(start); waited < time.Second {
+		t.Errorf("Expected to wait for the lock, waited %s", waited)
+	}
+
+	if err := lock.Refresh(); err != nil {
+		t.Errorf("Refresh failed: %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+
+	// A lock that is no longer refreshed is taken over
+	if _, err := importer.AcquireImportLock(db, "import", 0); err != nil {
+		t.Fatalf("Acquire after release failed: %v", err)
+	}
+	if _, err := db.Exec("UPDATE import_lock SET holder = 'crashed:1', refreshed_at = datetime('now', '-1 hour')"); err != nil {
+		t.Fatalf("Failed to age the lock: %v", err)
+	}
+	lock, err = importer.AcquireImportLock(db, "collect", 0)
+	if err != nil {
+		t.Fatalf("Expected the stale lock to be taken over, got %v", err)
+	}
+
+	held, err = importer.BreakImportLock(db)
+	if err != nil || held == nil || held.Command != "collect" {
+		t.Fatalf("Expected to break the lock of collect, got %+v %v", held, err)
+	}
+	if err := lock.Refresh(); err == nil {
+		t.Error("Expected refreshing a broken lock to fail")
+	}
+	if held, err := importer.BreakImportLock(db); err != nil || held != nil {
+		t.Errorf("Expected a free lock, got %+v %v", held, err)
+	}
+}
@@ -15,21 +15,75 @@
 package importer
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/eligibility"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/licensing"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/tracing"
 )
 
 // ImportService handles importing CSV data into the database
 type ImportService struct {
 	db *sql.DB
+
+	// Strict rejects files that detect product codes missing from product_codes
+	Strict bool
+
+	// Force re-imports files whose content was already imported
+	Force bool
+
+	// RequireFilenamePattern rejects files not named
+	// iwdli_output_<hostname>_<timestamp>.csv instead of taking the hostname
+	// from the HOSTNAME field
+	RequireFilenamePattern bool
+
+	// MaxWarnings fails files with more warnings (unparsable product fields,
+	// products that could not be stored); negative means no limit
+	MaxWarnings int
+
+	// ArchiveSource stores the original CSV content of each imported file
+	// with its import session, for 'import show-source'
+	ArchiveSource bool
+
+	// Org, when set, puts the nodes of the imported files in this
+	// organization; files of nodes of another organization fail
+	Org string
+
+	// Signatures, when set, rejects files that do not match the signature or
+	// checksum file delivered with them
+	Signatures *SignatureVerifier
+
+	// Lock, when set, is the import lock held by the process; ImportFiles
+	// refreshes it after each file
+	Lock *ImportLock
+
+	// DeferPartial makes ImportFiles leave the files that are still being
+	// written (PartialFileError) for a later import, instead of recording them
+	// as failed imports
+	DeferPartial bool
+
+	// rules are the active eligibility rules last read, kept while their
+	// version stays active
+	rules *eligibility.Rules
+
+	// bundle is the bundle of the member imported last, read and verified
+	// once for all its members; ImportFiles drops it when done
+	bundle *deliveredBundle
 }
 
 // NewImportService creates a new import service
 func NewImportService(db *sql.DB) *ImportService {
-	return &ImportService{db: db}
+	return &ImportService{db: db, MaxWarnings: -1}
 }
 
 // ImportResult contains the results of an import operation
@@ -39,50 +93,311 @@ type ImportResult struct {
 	RecordsUpdated int
 	RecordsSkipped int
 	Errors         []string
+
+	// FileSHA256 is the SHA-256 of the file content
+	FileSHA256 string
+	// AlreadyImported is set when the file was skipped because a file with the same
+	// content was imported before; SessionID then refers to that earlier session
+	AlreadyImported bool
+	// UnknownProductCodes are the detected product codes missing from the
+	// product_codes reference table (outside strict mode, which rejects the file)
+	UnknownProductCodes []string
+	// Signature is the verified signature of the file, nil when signatures are
+	// not verified
+	Signature *SignatureVerification
+}
+
+// TooManyWarningsError is returned for files with more warnings than
+// MaxWarnings allows; nothing of the file is imported
+type TooManyWarningsError struct {
+	Warnings []string
+	Max      int
+}
+
+func (e *TooManyWarningsError) Error() string {
+	return fmt.Sprintf("too many warnings (%d, maximum %d): %s", len(e.Warnings), e.Max, strings.Join(e.Warnings, "; "))
+}
+
+// ParseError is returned for files that cannot be read as inspector CSV
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return "failed to parse CSV: " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
 }
 
-// ImportCSVFile imports a single CSV file
-func (s *ImportService) ImportCSVFile(filePath string) (*ImportResult, error) {
+// ImportCSVFile imports a single CSV file. Cancelling ctx rolls back the
+// import of the file.
+// Unless Force is set, a file whose content was already imported for the same host
+// is skipped and reported with AlreadyImported.
+func (s *ImportService) ImportCSVFile(ctx context.Context, filePath string) (result *ImportResult, err error) {
+	span := tracing.Start("import file", tracing.String("file.path", filePath))
+	defer func() { span.End(err) }()
+
+	if s.RequireFilenamePattern {
+		if _, err := extractHostnameFromFilename(filePath); err != nil {
+			return nil, fmt.Errorf("failed to extract hostname from filename: %w", err)
+		}
+	}
+
+	content, signature, err := s.readInspectorFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
 	// Parse CSV
-	record, err := ParseCSVFile(filePath)
+	parse := tracing.Start("parse csv", tracing.String("file.path", filePath))
+	record, err := parseInspectorFile(bytes.NewReader(content), filePath)
+	parse.End(err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse CSV: %w", err)
+		return nil, &ParseError{Err: err}
+	}
+
+	// Hashed after decompression: a file imported compressed and plain is the same content
+	fileHash := contentSHA256(content)
+	if !s.ArchiveSource {
+		content = nil
+	}
+	return s.importRecord(ctx, record, fileHash, content, signature)
+}
+
+// deliveredBundle is a bundle read, verified and extracted once for all its
+// members
+type deliveredBundle struct {
+	path      string
+	signature *SignatureVerification
+	files     map[string]bundleFile // inspector files by member name
+	err       error                 // error of reading, verifying or extracting the bundle
+}
+
+// readInspectorFile returns the decompressed content of an inspector file with
+// the verification of its signature, nil when signatures are not verified.
+// The file delivered is read once: the signature is checked on its bytes, and
+// the content is decompressed from them. The bundle of a member is read when
+// its first member is imported, and kept for the next ones.
+func (s *ImportService) readInspectorFile(filePath string) ([]byte, *SignatureVerification, error) {
+	delivered := signedFile(filePath)
+	if delivered == filePath {
+		data, err := readDeliveredFile(filePath)
+		if err != nil {
+			return nil, nil, &ParseError{Err: fmt.Errorf("failed to open file: %w", err)}
+		}
+		signature, err := s.verify(filePath, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		content, err := inspectorContent(filePath, data)
+		if err != nil {
+			return nil, nil, &ParseError{Err: fmt.Errorf("failed to open file: %w", err)}
+		}
+		return content, signature, nil
+	}
+
+	if s.bundle == nil || s.bundle.path != delivered {
+		s.bundle = s.readBundle(delivered)
+	}
+	if s.bundle.err != nil {
+		return nil, nil, s.bundle.err
+	}
+	content, err := bundleMember(delivered, s.bundle.files, filepath.ToSlash(filePath[len(delivered)+1:]))
+	if err != nil {
+		return nil, nil, &ParseError{Err: fmt.Errorf("failed to open file: %w", err)}
+	}
+	return content, s.bundle.signature, nil
+}
+
+// readBundle reads, verifies and extracts a bundle
+func (s *ImportService) readBundle(bundle string) *deliveredBundle {
+	b := &deliveredBundle{path: bundle}
+	data, err := readDeliveredFile(bundle)
+	if err != nil {
+		b.err = &ParseError{Err: fmt.Errorf("failed to open file: %w", err)}
+		return b
+	}
+	if b.signature, err = s.verify(bundle, data); err != nil {
+		b.err = err
+		return b
+	}
+	b.files, err = extractBundle(bundle, func(visit bundleVisitor) error {
+		return walkBundleData(bundle, data, visit)
+	})
+	if err != nil {
+		b.err = &ParseError{Err: fmt.Errorf("failed to open file: %w", err)}
+	}
+	return b
+}
+
+// verify checks a file as delivered against its signature file when
+// signatures are verified
+func (s *ImportService) verify(delivered string, data []byte) (*SignatureVerification, error) {
+	if s.Signatures == nil {
+		return nil, nil
+	}
+	signature, err := s.Signatures.Verify(delivered, data)
+	if err != nil {
+		return nil, fmt.Errorf("signature verification failed: %w", err)
+	}
+	return signature, nil
+}
+
+// ImportCSV imports an inspector CSV read from r, e.g. from standard input in
+// a collection pipeline. The hostname comes from the HOSTNAME field, since
+// there is no filename. sourceName is recorded as the source file of the
+// import session.
+func (s *ImportService) ImportCSV(ctx context.Context, r io.Reader, sourceName string) (result *ImportResult, err error) {
+	span := tracing.Start("import file", tracing.String("file.path", sourceName))
+	defer func() { span.End(err) }()
+
+	if s.Signatures != nil {
+		return nil, fmt.Errorf("signature verification failed: %s has no signature file; import the file with its signature", sourceName)
+	}
+
+	// The content is hashed and parsed, so it is read once into memory;
+	// inspector files are small
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", sourceName, err)
+	}
+
+	parse := tracing.Start("parse csv", tracing.String("file.path", sourceName))
+	record, err := ParseCSV(bytes.NewReader(content), sourceName)
+	parse.End(err)
+	if err != nil {
+		return nil, &ParseError{Err: err}
+	}
+
+	decompressed, err := decompressStream(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", sourceName, err)
+	}
+	plain, err := io.ReadAll(decompressed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", sourceName, err)
+	}
+	fileHash := contentSHA256(plain)
+	if !s.ArchiveSource {
+		plain = nil
+	}
+	return s.importRecord(ctx, record, fileHash, plain, nil)
+}
+
+// importRecord imports a parsed inspector CSV whose content has the given
+// hash. The content is archived with the session when it is not nil, and the
+// verified signature of the file, if any, is recorded on it.
+func (s *ImportService) importRecord(ctx context.Context, record *CSVRecord, fileHash string, content []byte, signature *SignatureVerification) (*ImportResult, error) {
+	// The hostname usually comes from the filename, so identical content of two
+	// hosts is only a duplicate when the host matches as well
+	if !s.Force {
+		sessionID, err := s.findImportedHash(ctx, record.Hostname, fileHash)
+		if err != nil {
+			return nil, err
+		}
+		if sessionID != "" {
+			if content != nil {
+				if err := s.archiveSkippedSource(ctx, sessionID, fileHash, content); err != nil {
+					return nil, err
+				}
+			}
+			return &ImportResult{
+				SessionID:       sessionID,
+				Errors:          []string{},
+				FileSHA256:      fileHash,
+				AlreadyImported: true,
+				Signature:       signature,
+			}, nil
+		}
 	}
 
 	// Check if detection was successful
 	if record.IsDetectionError() {
-		// Return error for failed detection - don't import incomplete data
-		return nil, fmt.Errorf("inspector detection failed for %s: %s", record.Hostname, record.GetDetectionError())
+		// Don't import incomplete data; record the failure for report detection-errors
+		return nil, s.recordDetectionError(ctx, record)
 	}
 
-	// Start transaction
-	tx, err := s.db.Begin()
+	// In strict mode every detected product must be mapped in the reference data;
+	// otherwise the unmapped codes are only reported with the result
+	var unknown *UnknownProductCodesError
+	if err := s.checkProductCodes(ctx, record); err != nil {
+		if s.Strict || !errors.As(err, &unknown) {
+			return nil, err
+		}
+	}
+
+	// Start transaction; cancelling ctx rolls it back
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	// Product fields that could not be parsed were skipped; they are warnings
+	// of the import session
 	result := &ImportResult{
-		SessionID: generateSessionID(record.Hostname, record.Timestamp),
-		Errors:    []string{},
+		SessionID:  generateSessionID(record.Hostname, record.Timestamp),
+		Errors:     append([]string{}, record.Warnings...),
+		FileSHA256: fileHash,
+		Signature:  signature,
+	}
+	if unknown != nil {
+		result.UnknownProductCodes = unknown.Codes
 	}
 
-	// 1. Ensure landscape node exists (auto-create)
-	mainFQDN := record.GetSystemFieldWithDefault("main_fqdn", record.Hostname+".local")
-	if err := s.ensureLandscapeNode(tx, mainFQDN, record.Hostname); err != nil {
+	// 1. Ensure landscape node exists (auto-create), under its main FQDN if it was renamed
+	mainFQDN, err := resolveNodeAlias(tx, record.GetSystemFieldWithDefault("main_fqdn", record.Hostname+".local"))
+	if err != nil {
+		return nil, err
+	}
+	span := traceTable("upsert", "landscape_nodes")
+	err = s.ensureLandscapeNode(tx, mainFQDN, record.Hostname)
+	span.End(err)
+	if err != nil {
 		return nil, fmt.Errorf("failed to ensure landscape node: %w", err)
 	}
+	if s.Org != "" {
+		if err := assignImportedNode(tx, mainFQDN, s.Org); err != nil {
+			return nil, err
+		}
+	}
+	if warning, err := decommissionWarning(tx, mainFQDN, record.Timestamp); err != nil {
+		return nil, err
+	} else if warning != "" {
+		result.Errors = append(result.Errors, warning)
+	}
 
-	// 2. Ensure physical host exists (if provided)
+	// 2. Ensure physical host exists (if provided), under its new ID if it was renamed or merged
 	physicalHostID := record.GetSystemField("PHYSICAL_HOST_ID")
 	if physicalHostID != "" && physicalHostID != "unknown" {
-		if err := s.ensurePhysicalHost(tx, record); err != nil {
+		if err := resolvePhysicalHostAlias(tx, record); err != nil {
+			return nil, err
+		}
+		span := traceTable("upsert", "physical_hosts")
+		err := s.ensurePhysicalHost(tx, record)
+		span.End(err)
+		if err != nil {
 			return nil, fmt.Errorf("failed to ensure physical host: %w", err)
 		}
 	}
 
-	// 3. Insert or update measurement
-	isNewMeasurement, err := s.insertMeasurement(tx, mainFQDN, record)
+	// Statements used per row are prepared once for the whole transaction
+	stmts, err := prepareImportStatements(tx)
+	if err != nil {
+		return nil, err
+	}
+	defer stmts.close()
+
+	// 3. Insert or update measurement, with the eligibility of the active rules
+	rules, err := s.eligibilityRules(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	span = traceTable("upsert", "measurements")
+	isNewMeasurement, err := s.insertMeasurement(stmts, mainFQDN, record, rules)
+	span.End(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert measurement: %w", err)
 	}
@@ -93,33 +408,70 @@ func (s *ImportService) ImportCSVFile(filePath string) (*ImportResult, error) {
 	}
 
 	// 4. Insert or update detected products
-	for _, detection := range record.ProductDetections {
-		isNewProduct, err := s.insertDetectedProduct(tx, mainFQDN, record.Timestamp, detection)
-		if err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("failed to insert product %s: %v", detection.ProductCode, err))
-			// Continue with other products
-		} else {
-			if isNewProduct {
-				result.RecordsCreated++
-			} else {
-				result.RecordsUpdated++
-			}
-		}
+	span = traceTable("upsert", "detected_products")
+	span.SetAttributes(tracing.Int("import.products", len(record.ProductDetections)))
+	err = s.insertDetectedProducts(stmts, mainFQDN, record.Timestamp, record.ProductDetections, result)
+	span.End(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert detected products: %w", err)
+	}
+
+	// First and last detection of the products and instances of the node, and
+	// the changes against the previous measurement
+	span = traceTable("refresh", "product_lifecycle")
+	err = refreshProductLifecycle(tx, mainFQDN)
+	span.End(err)
+	if err != nil {
+		return nil, err
+	}
+	span = traceTable("refresh", "product_instances")
+	err = refreshProductInstances(tx, mainFQDN)
+	span.End(err)
+	if err != nil {
+		return nil, err
+	}
+	span = traceTable("refresh", "node_changes")
+	err = refreshNodeChanges(tx, mainFQDN)
+	span.End(err)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.MaxWarnings >= 0 && len(result.Errors) > s.MaxWarnings {
+		return nil, &TooManyWarningsError{Warnings: result.Errors, Max: s.MaxWarnings}
 	}
 
 	// 5. Insert import session record
-	if err := s.insertImportSession(tx, record, result); err != nil {
+	if err := s.insertImportSession(tx, mainFQDN, record, result); err != nil {
 		return nil, fmt.Errorf("failed to insert import session: %w", err)
 	}
+	if content != nil {
+		if err := storeImportSource(tx, result.SessionID, fileHash, content); err != nil {
+			return nil, err
+		}
+	} else if _, err := tx.Exec("DELETE FROM import_sources WHERE session_id = ? AND file_sha256 != ?",
+		result.SessionID, fileHash); err != nil {
+		// The archived source of an earlier import no longer backs the measurement
+		return nil, fmt.Errorf("failed to delete the archived source of %s: %w", result.SessionID, err)
+	}
 
 	// Commit transaction
-	if err := tx.Commit(); err != nil {
+	span = tracing.Start("commit")
+	err = tx.Commit()
+	span.End(err)
+	if err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return result, nil
 }
 
+// traceTable starts the span of an operation writing a table, e.g.
+// "upsert measurements"
+func traceTable(operation, table string) *tracing.Span {
+	return tracing.Start(operation+" "+table, tracing.String("db.sql.table", table))
+}
+
 // ensureLandscapeNode creates landscape node if it doesn't exist
 func (s *ImportService) ensureLandscapeNode(tx *sql.Tx, mainFQDN, hostname string) error {
 	// Check if exists
@@ -143,6 +495,21 @@ func (s *ImportService) ensureLandscapeNode(tx *sql.Tx, mainFQDN, hostname strin
 	return nil
 }
 
+// decommissionWarning returns a warning when a measurement of a node is taken
+// after the node was decommissioned; the reports leave such measurements out
+func decommissionWarning(tx *sql.Tx, mainFQDN string, timestamp time.Time) (string, error) {
+	var decommissionedOn sql.NullTime
+	err := tx.QueryRow("SELECT decommissioned_on FROM landscape_nodes WHERE main_fqdn = ?", mainFQDN).Scan(&decommissionedOn)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the decommission date of %s: %w", mainFQDN, err)
+	}
+	if !decommissionedOn.Valid || timestamp.Format("2006-01-02") <= decommissionedOn.Time.Format("2006-01-02") {
+		return "", nil
+	}
+	return fmt.Sprintf("node %s was decommissioned on %s; its measurement of %s is left out of the reports",
+		mainFQDN, decommissionedOn.Time.Format("2006-01-02"), timestamp.Format("2006-01-02")), nil
+}
+
 // ensurePhysicalHost creates or updates physical host record
 func (s *ImportService) ensurePhysicalHost(tx *sql.Tx, record *CSVRecord) error {
 	physicalHostID := record.GetSystemField("PHYSICAL_HOST_ID")
@@ -204,8 +571,25 @@ func (s *ImportService) ensurePhysicalHost(tx *sql.Tx, record *CSVRecord) error
 	return nil
 }
 
-// insertMeasurement inserts or updates a measurement record (idempotent)
-func (s *ImportService) insertMeasurement(tx *sql.Tx, mainFQDN string, record *CSVRecord) (bool, error) {
+// eligibilityRules returns the active eligibility rules, nil when none are
+// active. The rules are parsed again only when another version is activated.
+func (s *ImportService) eligibilityRules(ctx context.Context, tx *sql.Tx) (*eligibility.Rules, error) {
+	version, err := eligibility.ActiveVersion(ctx, tx)
+	if err != nil || version == "" {
+		return nil, err
+	}
+	if s.rules == nil || s.rules.Version != version {
+		if s.rules, err = eligibility.Active(ctx, tx); err != nil {
+			return nil, err
+		}
+	}
+	return s.rules, nil
+}
+
+// insertMeasurement inserts or updates a measurement record (idempotent). With
+// rules, the eligibility is derived from the rules instead of taken from the
+// record, and the considered CPUs from that eligibility.
+func (s *ImportService) insertMeasurement(st *importStatements, mainFQDN string, record *CSVRecord, rules *eligibility.Rules) (bool, error) {
 	// Parse CPU count
 	cpuCountStr := strings.TrimSpace(record.GetSystemField("CPU_COUNT"))
 	cpuCount, err := strconv.Atoi(cpuCountStr)
@@ -220,41 +604,62 @@ func (s *ImportService) insertMeasurement(tx *sql.Tx, mainFQDN string, record *C
 		return false, fmt.Errorf("invalid CONSIDERED_CPUS value: %s", consideredCPUsStr)
 	}
 
+	processorEligible := record.GetSystemFieldWithDefault("PROCESSOR_ELIGIBLE", "unknown")
+	osEligible := record.GetSystemFieldWithDefault("OS_ELIGIBLE", "unknown")
+	virtEligible := record.GetSystemFieldWithDefault("VIRT_ELIGIBLE", "unknown")
+	if rules != nil {
+		eligible := rules.Evaluate(eligibility.Inputs{
+			ProcessorVendor: record.GetSystemField("PROCESSOR_VENDOR"),
+			ProcessorBrand:  record.GetSystemField("PROCESSOR_BRAND"),
+			OSName:          record.GetSystemField("OS_NAME"),
+			OSVersion:       record.GetSystemField("OS_VERSION"),
+			IsVirtualized:   record.GetSystemField("IS_VIRTUALIZED"),
+			VirtType:        record.GetSystemField("VIRT_TYPE"),
+		})
+		processorEligible, osEligible, virtEligible = eligible.Processor, eligible.OS, eligible.Virt
+	}
+	// Nodes with an ineligible OS or virtualization are licensed on the full
+	// capacity of their host, which the reporting views count
+	subCapacity := osEligible == eligibility.True && virtEligible == eligibility.True
+
+	// Containers are licensed on their CPU limit when it is below the node cores
+	cpuLimit := strings.TrimSpace(record.GetSystemField("CONTAINER_CPU_LIMIT"))
+	limitCores, err := containerLimitCores(cpuLimit)
+	if err != nil {
+		return false, err
+	}
+	if subCapacity && limitCores != nil && *limitCores < consideredCPUs {
+		consideredCPUs = *limitCores
+	}
+
+	// A capped partition is licensed on its capacity, not on its visible vCPUs
+	partition := licensing.ApplyPartitionCap(consideredCPUs,
+		record.GetSystemField("IS_VIRTUALIZED") == "yes", subCapacity, record.GetSystemField("PARTITION_CPUS"))
+	consideredCPUs = partition.Cores
+
+	if rules != nil {
+		hostCores, _ := strconv.Atoi(strings.TrimSpace(record.GetSystemField("HOST_PHYSICAL_CPUS")))
+		consideredCPUs = licensing.ConsideredCPUs(licensing.CPUInputs{
+			CPUCount:            cpuCount,
+			Virtualized:         record.GetSystemField("IS_VIRTUALIZED") == "yes",
+			OSEligible:          osEligible == eligibility.True,
+			VirtEligible:        virtEligible == eligibility.True,
+			HostCores:           hostCores,
+			PartitionCPUs:       record.GetSystemField("PARTITION_CPUS"),
+			ContainerLimitCores: limitCores,
+		})
+	}
+
+	// An upsert reports one affected row either way, so look the measurement up first
+	var existing int
+	err = st.tx.QueryRow("SELECT COUNT(*) FROM measurements WHERE main_fqdn = ? AND detection_timestamp = ?",
+		mainFQDN, record.Timestamp).Scan(&existing)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up measurement: %w", err)
+	}
+
 	// Use INSERT ... ON CONFLICT DO UPDATE for idempotent operation
-	result, err := tx.Exec(`
-		INSERT INTO measurements (
-			main_fqdn, detection_timestamp, session_directory,
-			node_type, environment, inspection_level, node_fqdn,
-			os_name, os_version, cpu_count,
-			is_virtualized, virt_type, processor_vendor, processor_brand,
-			host_physical_cpus, partition_cpus,
-			processor_eligible, os_eligible, virt_eligible,
-			considered_cpus, physical_host_id, host_id_method, host_id_confidence,
-			created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
-		ON CONFLICT(main_fqdn, detection_timestamp) DO UPDATE SET
-			session_directory = excluded.session_directory,
-			node_type = excluded.node_type,
-			environment = excluded.environment,
-			inspection_level = excluded.inspection_level,
-			node_fqdn = excluded.node_fqdn,
-			os_name = excluded.os_name,
-			os_version = excluded.os_version,
-			cpu_count = excluded.cpu_count,
-			is_virtualized = excluded.is_virtualized,
-			virt_type = excluded.virt_type,
-			processor_vendor = excluded.processor_vendor,
-			processor_brand = excluded.processor_brand,
-			host_physical_cpus = excluded.host_physical_cpus,
-			partition_cpus = excluded.partition_cpus,
-			processor_eligible = excluded.processor_eligible,
-			os_eligible = excluded.os_eligible,
-			virt_eligible = excluded.virt_eligible,
-			considered_cpus = excluded.considered_cpus,
-			physical_host_id = excluded.physical_host_id,
-			host_id_method = excluded.host_id_method,
-			host_id_confidence = excluded.host_id_confidence
-	`,
+	_, err = st.measurement.Exec(
 		mainFQDN,
 		record.Timestamp,
 		record.GetSystemField("session_audit_directory"), // CSV field name is session_audit_directory
@@ -271,59 +676,92 @@ func (s *ImportService) insertMeasurement(tx *sql.Tx, mainFQDN string, record *C
 		record.GetSystemField("PROCESSOR_BRAND"),
 		record.GetSystemFieldWithDefault("HOST_PHYSICAL_CPUS", "unknown"),
 		record.GetSystemField("PARTITION_CPUS"),
-		record.GetSystemFieldWithDefault("PROCESSOR_ELIGIBLE", "unknown"),
-		record.GetSystemFieldWithDefault("OS_ELIGIBLE", "unknown"),
-		record.GetSystemFieldWithDefault("VIRT_ELIGIBLE", "unknown"),
+		processorEligible,
+		osEligible,
+		virtEligible,
 		consideredCPUs,
 		record.GetSystemField("PHYSICAL_HOST_ID"),
 		record.GetSystemField("HOST_ID_METHOD"),
 		record.GetSystemField("HOST_ID_CONFIDENCE"),
+		record.GetSystemField("CONTAINER_PLATFORM"),
+		record.GetSystemField("CONTAINER_NAMESPACE"),
+		record.GetSystemField("CONTAINER_POD"),
+		record.GetSystemField("CONTAINER_NAME"),
+		cpuLimit,
+		limitCores,
+		strings.ToLower(record.GetSystemField("CLOUD_PROVIDER")),
+		record.GetSystemField("CLOUD_INSTANCE_TYPE"),
+		record.GetSystemField("CLOUD_REGION"),
+		record.GetSystemField("CLOUD_ACCOUNT_ID"),
+		partition.CapCores,
 	)
 
 	if err != nil {
 		return false, fmt.Errorf("failed to insert/update measurement: %w", err)
 	}
 
-	// Check if this was an insert or update
-	rowsAffected, _ := result.RowsAffected()
-	isNew := rowsAffected == 1
-
-	return isNew, nil
+	return existing == 0, nil
 }
 
-// insertDetectedProduct inserts or updates a detected product record (idempotent)
-func (s *ImportService) insertDetectedProduct(tx *sql.Tx, mainFQDN string, timestamp time.Time, detection *ProductDetection) (bool, error) {
-	result, err := tx.Exec(`
-		INSERT INTO detected_products (
-			main_fqdn, product_mnemo_code, detection_timestamp,
-			status, running_status, running_count, install_status, install_count, created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
-		ON CONFLICT(main_fqdn, product_mnemo_code, detection_timestamp) DO UPDATE SET
-			status = excluded.status,
-			running_status = excluded.running_status,
-			running_count = excluded.running_count,
-			install_status = excluded.install_status,
-			install_count = excluded.install_count
-	`,
-		mainFQDN,
-		detection.ProductCode,
-		timestamp,
-		detection.Status,
-		getFieldWithDefault(detection.RunningStatus, "unknown"),
-		detection.RunningCount,
-		getFieldWithDefault(detection.InstallStatus, "unknown"),
-		detection.InstallCount,
-	)
+// insertDetectedProducts inserts or updates the detected products of a record (idempotent).
+// Detections are written in multi-row batches in product code order. When a batch
+// fails (e.g. a product code missing from product_codes) its rows are retried one
+// by one, so only the offending products end up in result.Errors.
+func (s *ImportService) insertDetectedProducts(st *importStatements, mainFQDN string, timestamp time.Time, detections map[string]*ProductDetection, result *ImportResult) error {
+	if len(detections) == 0 {
+		return nil
+	}
 
+	// Products already stored for this measurement are updates, not creations
+	existing, err := st.existingProductCodes(mainFQDN, timestamp)
 	if err != nil {
-		return false, fmt.Errorf("failed to insert/update detected product: %w", err)
+		return err
 	}
 
-	// Check if this was an insert or update
-	rowsAffected, _ := result.RowsAffected()
-	isNew := rowsAffected == 1
+	codes := make([]string, 0, len(detections))
+	for code := range detections {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
 
-	return isNew, nil
+	ordered := make([]*ProductDetection, len(codes))
+	for i, code := range codes {
+		ordered[i] = detections[code]
+	}
+
+	// stored counts a written detection and stores its installation evidence
+	stored := func(detection *ProductDetection) {
+		if existing[detection.ProductCode] {
+			result.RecordsUpdated++
+		} else {
+			result.RecordsCreated++
+		}
+		if err := st.replaceEvidence(mainFQDN, timestamp, detection, existing[detection.ProductCode]); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("failed to store evidence for product %s: %v", detection.ProductCode, err))
+		}
+	}
+
+	for start := 0; start < len(ordered); start += detectionBatchSize {
+		batch := ordered[start:min(start+detectionBatchSize, len(ordered))]
+
+		if err := st.upsertDetections(mainFQDN, timestamp, batch); err == nil {
+			for _, detection := range batch {
+				stored(detection)
+			}
+			continue
+		}
+
+		// Retry row by row to find the failing products and keep the others
+		for _, detection := range batch {
+			if err := st.upsertDetections(mainFQDN, timestamp, []*ProductDetection{detection}); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("failed to insert product %s: %v", detection.ProductCode, err))
+				continue
+			}
+			stored(detection)
+		}
+	}
+
+	return nil
 }
 
 // getFieldWithDefault returns value or default if empty
@@ -335,7 +773,7 @@ func getFieldWithDefault(value, defaultValue string) string {
 }
 
 // insertImportSession records the import session
-func (s *ImportService) insertImportSession(tx *sql.Tx, record *CSVRecord, result *ImportResult) error {
+func (s *ImportService) insertImportSession(tx *sql.Tx, mainFQDN string, record *CSVRecord, result *ImportResult) error {
 	status := "success"
 	if len(result.Errors) > 0 {
 		status = "partial"
@@ -346,12 +784,33 @@ func (s *ImportService) insertImportSession(tx *sql.Tx, record *CSVRecord, resul
 		errorMessage = strings.Join(result.Errors, "; ")
 	}
 
+	var signatureStatus, signatureSigner interface{}
+	if result.Signature != nil {
+		signatureStatus, signatureSigner = result.Signature.Status, result.Signature.Signer
+	}
+
+	// A forced or corrected re-import of the same measurement replaces its session
 	_, err := tx.Exec(`
 		INSERT INTO import_sessions (
 			session_id, source_file, hostname,
 			records_created, records_updated, records_skipped,
-			status, error_message
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			status, error_message, file_sha256,
+			main_fqdn, detection_timestamp,
+			signature_status, signature_signer
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(session_id) DO UPDATE SET
+			imported_at = CURRENT_TIMESTAMP,
+			source_file = excluded.source_file,
+			records_created = excluded.records_created,
+			records_updated = excluded.records_updated,
+			records_skipped = excluded.records_skipped,
+			status = excluded.status,
+			error_message = excluded.error_message,
+			file_sha256 = excluded.file_sha256,
+			main_fqdn = excluded.main_fqdn,
+			detection_timestamp = excluded.detection_timestamp,
+			signature_status = excluded.signature_status,
+			signature_signer = excluded.signature_signer
 	`,
 		result.SessionID,
 		record.SourceFile,
@@ -361,6 +820,11 @@ func (s *ImportService) insertImportSession(tx *sql.Tx, record *CSVRecord, resul
 		result.RecordsSkipped,
 		status,
 		errorMessage,
+		result.FileSHA256,
+		mainFQDN,
+		record.Timestamp,
+		signatureStatus,
+		signatureSigner,
 	)
 
 	if err != nil {
@@ -374,3 +838,18 @@ func (s *ImportService) insertImportSession(tx *sql.Tx, record *CSVRecord, resul
 func generateSessionID(hostname string, timestamp time.Time) string {
 	return hostname + "_" + timestamp.Format("20060102_150405")
 }
+
+// findImportedHash returns the session that imported a file of the host with the
+// given content hash, or an empty string if there is none
+func (s *ImportService) findImportedHash(ctx context.Context, hostname, fileHash string) (string, error) {
+	var sessionID string
+	err := s.db.QueryRowContext(ctx, "SELECT session_id FROM import_sessions WHERE hostname = ? AND file_sha256 = ? LIMIT 1",
+		hostname, fileHash).Scan(&sessionID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up file hash: %w", err)
+	}
+	return sessionID, nil
+}
@@ -0,0 +1,484 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/eligibility"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestImportCSVFileBatchesDetections(t *testing.T) {
+	db := setupImportDB(t)
+
+	// More detections than fit in one batch, plus one unknown product code that
+	// makes its batch fall back to row-by-row inserts
+	const products = 250
+	var csv strings.Builder
+	csv.WriteString(testInspectorCSV)
+	for i := 0; i < products; i++ {
+		code := fmt.Sprintf("P%03d_ONP_PRD", i)
+		if _, err := db.Exec(`INSERT INTO product_codes (product_mnemo_code, ibm_product_code, product_name, mode, term_id)
+			VALUES (?, '', ?, 'PROD', 'T1')`, code, code); err != nil {
+			t.Fatalf("Failed to insert product code: %v", err)
+		}
+		fmt.Fprintf(&csv, "%s,present\n%s_INSTALL_COUNT,1\n", code, code)
+	}
+	csv.WriteString("ZZ_ONP_PRD,present\n")
+
+	file := filepath.Join(t.TempDir(), "iwdli_output_host1_20251021_090906.csv")
+	writeFile(t, file, csv.String())
+
+	result, err := importer.NewImportService(db).ImportCSVFile(t.Context(), file)
+	if err != nil {
+		t.Fatalf("ImportCSVFile failed: %v", err)
+	}
+
+	// One measurement, IS_ONP_PRD and the generated products
+	if result.RecordsCreated != 1+1+products {
+		t.Errorf("Expected %d records created, got %d", 1+1+products, result.RecordsCreated)
+	}
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "ZZ_ONP_PRD") {
+		t.Errorf("Expected a single error for ZZ_ONP_PRD, got %v", result.Errors)
+	}
+
+	var stored int
+	if err := db.QueryRow("SELECT COUNT(*) FROM detected_products").Scan(&stored); err != nil {
+		t.Fatalf("Failed to count detected products: %v", err)
+	}
+	if stored != 1+products {
+		t.Errorf("Expected %d detected products stored, got %d", 1+products, stored)
+	}
+
+	var status string
+	if err := db.QueryRow("SELECT status FROM import_sessions").Scan(&status); err != nil {
+		t.Fatalf("Failed to read import session: %v", err)
+	}
+	if status != "partial" {
+		t.Errorf("Expected partial import session, got %s", status)
+	}
+}
+
+func TestImportCSVFileStoresInstallEvidence(t *testing.T) {
+	db := setupImportDB(t)
+
+	csv := testInspectorCSV +
+		"IS_ONP_PRD_INSTALL_PATH_01,/opt/sag/is\n" +
+		"IS_ONP_PRD_INSTALL_PATH_02,/opt/sag/is2\n" +
+		"IS_ONP_PRD_RUNNING_COMMANDLINES_01,/opt/sag/is/bin/java -Dwatt.server\n"
+
+	file := filepath.Join(t.TempDir(), "iwdli_output_host1_20251021_090906.csv")
+	writeFile(t, file, csv)
+
+	result, err := importer.NewImportService(db).ImportCSVFile(t.Context(), file)
+	if err != nil {
+		t.Fatalf("ImportCSVFile failed: %v", err)
+	}
+	if len(result.Errors) != 0 {
+		t.Fatalf("Unexpected import errors: %v", result.Errors)
+	}
+
+	rows, err := db.Query(`SELECT evidence_type, seq, evidence FROM v_install_detail
+		WHERE product_code = 'IS_ONP_PRD' ORDER BY evidence_type, seq`)
+	if err != nil {
+		t.Fatalf("Failed to query v_install_detail: %v", err)
+	}
+	defer rows.Close()
+
+	var got []string
+	for rows.Next() {
+		var evidenceType, evidence string
+		var seq int
+		if err := rows.Scan(&evidenceType, &seq, &evidence); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		got = append(got, fmt.Sprintf("%s %d %s", evidenceType, seq, evidence))
+	}
+
+	want := []string{
+		"install 1 /opt/sag/is",
+		"install 2 /opt/sag/is2",
+		"process 1 /opt/sag/is/bin/java -Dwatt.server",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected evidence %q, got %q", want, got)
+	}
+}
+
+func TestImportCSVFileSkipsAlreadyImportedContent(t *testing.T) {
+	db := setupImportDB(t)
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "iwdli_output_host1_20251021_090906.csv")
+	copied := filepath.Join(dir, "copy", "iwdli_output_host1_20251021_090906.csv")
+	writeFile(t, file, testInspectorCSV)
+	writeFile(t, copied, testInspectorCSV)
+
+	service := importer.NewImportService(db)
+	first, err := service.ImportCSVFile(t.Context(), file)
+	if err != nil {
+		t.Fatalf("First import failed: %v", err)
+	}
+	if first.AlreadyImported || first.FileSHA256 == "" {
+		t.Fatalf("Expected a hashed first import, got %+v", first)
+	}
+
+	// The same content is skipped, wherever the file is
+	second, err := service.ImportCSVFile(t.Context(), copied)
+	if err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if !second.AlreadyImported || second.SessionID != first.SessionID {
+		t.Errorf("Expected the copy to be skipped as session %s, got %+v", first.SessionID, second)
+	}
+
+	// --force imports it again and replaces the session
+	service.Force = true
+	forced, err := service.ImportCSVFile(t.Context(), file)
+	if err != nil {
+		t.Fatalf("Forced import failed: %v", err)
+	}
+	if forced.AlreadyImported || forced.RecordsCreated != 0 || forced.RecordsUpdated != 2 {
+		t.Errorf("Expected 2 records updated by the forced import, got %+v", forced)
+	}
+
+	var sessions int
+	if err := db.QueryRow("SELECT COUNT(*) FROM import_sessions").Scan(&sessions); err != nil {
+		t.Fatalf("Failed to count import sessions: %v", err)
+	}
+	if sessions != 1 {
+		t.Errorf("Expected 1 import session, got %d", sessions)
+	}
+}
+
+func TestImportCSVFromStream(t *testing.T) {
+	db := setupImportDB(t)
+	service := importer.NewImportService(db)
+
+	// Without a filename the hostname must be in the file
+	if _, err := service.ImportCSV(t.Context(), strings.NewReader(testInspectorCSV), "<stdin>"); err == nil ||
+		!strings.Contains(err.Error(), "HOSTNAME") {
+		t.Fatalf("Expected a missing HOSTNAME error, got %v", err)
+	}
+
+	content := strings.Replace(testInspectorCSV, "OS_NAME,Linux", "HOSTNAME,host1\nOS_NAME,Linux", 1)
+	result, err := service.ImportCSV(t.Context(), strings.NewReader(content), "<stdin>")
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.SessionID != "host1_20251021_090906" || result.RecordsCreated != 2 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	var sourceFile string
+	if err := db.QueryRow("SELECT source_file FROM import_sessions WHERE hostname = 'host1'").Scan(&sourceFile); err != nil {
+		t.Fatalf("Failed to read import session: %v", err)
+	}
+	if sourceFile != "<stdin>" {
+		t.Errorf("Expected source file <stdin>, got %q", sourceFile)
+	}
+
+	// The same content compressed is skipped as already imported
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(content))
+	gz.Close()
+	again, err := service.ImportCSV(t.Context(), &compressed, "<stdin>")
+	if err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if !again.AlreadyImported || again.SessionID != result.SessionID {
+		t.Errorf("Expected the compressed content to be skipped, got %+v", again)
+	}
+
+	// A later measurement of the host is other content
+	later := strings.Replace(content, "2025-10-21T09:09:06Z", "2025-10-22T09:09:06Z", 1)
+	next, err := service.ImportCSV(t.Context(), strings.NewReader(later), "<stdin>")
+	if err != nil || next.AlreadyImported {
+		t.Errorf("Expected the later measurement to be imported, got %+v (%v)", next, err)
+	}
+}
+
+func TestImportCSVFileRenamedInTransfer(t *testing.T) {
+	db := setupImportDB(t)
+	dir := t.TempDir()
+
+	// A renamed file takes the hostname from the HOSTNAME field
+	renamed := filepath.Join(dir, "upload-0001.csv")
+	writeFile(t, renamed, strings.Replace(testInspectorCSV, "OS_NAME,Linux", "HOSTNAME,host1\nOS_NAME,Linux", 1))
+	withoutHostname := filepath.Join(dir, "upload-0002.csv")
+	writeFile(t, withoutHostname, testInspectorCSV)
+
+	service := importer.NewImportService(db)
+	service.RequireFilenamePattern = true
+	if _, err := service.ImportCSVFile(t.Context(), renamed); err == nil {
+		t.Fatal("Expected the renamed file to be rejected with RequireFilenamePattern")
+	}
+
+	service.RequireFilenamePattern = false
+	result, err := service.ImportCSVFile(t.Context(), renamed)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.SessionID != "host1_20251021_090906" {
+		t.Errorf("Expected session host1_20251021_090906, got %s", result.SessionID)
+	}
+
+	if _, err := service.ImportCSVFile(t.Context(), withoutHostname); err == nil || !strings.Contains(err.Error(), "HOSTNAME") {
+		t.Errorf("Expected a missing HOSTNAME error, got %v", err)
+	}
+}
+
+func TestImportCSVFileSkipsUnparsableProductFields(t *testing.T) {
+	db := setupImportDB(t)
+	dir := t.TempDir()
+
+	// A bad count and a malformed parameter are skipped, the rest is imported
+	content := strings.Replace(testInspectorCSV, "IS_ONP_PRD_INSTALL_COUNT,1", "IS_ONP_PRD_INSTALL_COUNT,one", 1) +
+		"IS_ONP_PRD_RUNNING_COUNT,-2\nX_PRD,present\n"
+	file := filepath.Join(dir, "iwdli_output_host1_20251021_090906.csv")
+	writeFile(t, file, content)
+
+	service := importer.NewImportService(db)
+	service.MaxWarnings = 2
+	if _, err := service.ImportCSVFile(t.Context(), file); err == nil || !strings.Contains(err.Error(), "too many warnings (3, maximum 2)") {
+		t.Fatalf("Expected the file to exceed --max-warnings, got %v", err)
+	}
+	var measurements int
+	if err := db.QueryRow("SELECT COUNT(*) FROM measurements").Scan(&measurements); err != nil {
+		t.Fatalf("Failed to count measurements: %v", err)
+	}
+	if measurements != 0 {
+		t.Errorf("Expected nothing imported, got %d measurements", measurements)
+	}
+
+	service.MaxWarnings = -1
+	result, err := service.ImportCSVFile(t.Context(), file)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(result.Errors) != 3 || !strings.Contains(result.Errors[1], `IS_ONP_PRD_INSTALL_COUNT: invalid count "one"`) {
+		t.Errorf("Expected 3 field warnings, got %v", result.Errors)
+	}
+
+	var status, message string
+	var installCount int
+	err = db.QueryRow(`
+		SELECT s.status, s.error_message, d.install_count
+		FROM import_sessions s
+		JOIN detected_products d ON d.main_fqdn = s.main_fqdn AND d.detection_timestamp = s.detection_timestamp
+	`).Scan(&status, &message, &installCount)
+	if err != nil {
+		t.Fatalf("Failed to read import session: %v", err)
+	}
+	if status != "partial" || !strings.Contains(message, "X_PRD") || installCount != 0 {
+		t.Errorf("Expected a partial session with the warnings, got %s %q (install count %d)", status, message, installCount)
+	}
+}
+
+func TestImportCSVFileContainerLimit(t *testing.T) {
+	container := "CONTAINER_PLATFORM,kubernetes\n" +
+		"CONTAINER_NAMESPACE,integration\n" +
+		"CONTAINER_POD,is-0\n" +
+		"CONTAINER_NAME,msr\n"
+
+	tests := []struct {
+		limit      string
+		considered int
+		limitCores sql.NullInt64
+		wantErr    bool
+	}{
+		{limit: "1500m", considered: 2, limitCores: sql.NullInt64{Int64: 2, Valid: true}},
+		{limit: "0.2", considered: 1, limitCores: sql.NullInt64{Int64: 1, Valid: true}},
+		{limit: "8", considered: 4, limitCores: sql.NullInt64{Int64: 8, Valid: true}}, // above the node cores
+		{limit: "", considered: 4},
+		{limit: "two", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.limit, func(t *testing.T) {
+			db := setupImportDB(t)
+			file := filepath.Join(t.TempDir(), "iwdli_output_host1_20251021_090906.csv")
+			writeFile(t, file, testInspectorCSV+container+"CONTAINER_CPU_LIMIT,"+tt.limit+"\n")
+
+			_, err := importer.NewImportService(db).ImportCSVFile(t.Context(), file)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "CONTAINER_CPU_LIMIT") {
+					t.Fatalf("Expected an invalid CONTAINER_CPU_LIMIT error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ImportCSVFile failed: %v", err)
+			}
+
+			var considered int
+			var limitCores sql.NullInt64
+			var namespace, pod string
+			err = db.QueryRow(`SELECT considered_cpus, container_limit_cores, container_namespace, container_pod
+				FROM measurements`).Scan(&considered, &limitCores, &namespace, &pod)
+			if err != nil {
+				t.Fatalf("Failed to read measurement: %v", err)
+			}
+			if considered != tt.considered || limitCores != tt.limitCores {
+				t.Errorf("Expected %d considered cores and limit cores %v, got %d and %v",
+					tt.considered, tt.limitCores, considered, limitCores)
+			}
+			if namespace != "integration" || pod != "is-0" {
+				t.Errorf("Expected namespace integration and pod is-0, got %q and %q", namespace, pod)
+			}
+		})
+	}
+}
+
+// The compliance report counts the considered CPUs of a node, monthly-peak
+// the host cores of an ineligible node: the caps applied on import must not
+// make them disagree
+func TestImportCSVFileCapsKeepReportsInAgreement(t *testing.T) {
+	// A VM with an ineligible OS on a 48-core host
+	ineligible := strings.NewReplacer(
+		"IS_VIRTUALIZED,no", "IS_VIRTUALIZED,yes\nHOST_PHYSICAL_CPUS,48",
+		"OS_ELIGIBLE,true", "OS_ELIGIBLE,false",
+		"CONSIDERED_CPUS,4", "CONSIDERED_CPUS,48",
+	).Replace(testInspectorCSV)
+
+	tests := []struct {
+		name   string
+		fields string
+		cores  int
+	}{
+		{name: "ineligible container", fields: "CONTAINER_CPU_LIMIT,4\n", cores: 48},
+		{name: "ineligible capped partition", fields: "PARTITION_CPUS,1.5\n", cores: 48},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupImportDB(t)
+			file := filepath.Join(t.TempDir(), "iwdli_output_host1_20251021_090906.csv")
+			writeFile(t, file, ineligible+tt.fields)
+			if _, err := importer.NewImportService(db).ImportCSVFile(t.Context(), file); err != nil {
+				t.Fatalf("ImportCSVFile failed: %v", err)
+			}
+
+			compliance, err := reports.NewComplianceReport(db).Query(t.Context(), "", "", nil, nil, false)
+			if err != nil || len(compliance) != 1 {
+				t.Fatalf("Expected 1 compliance row, got %d (%v)", len(compliance), err)
+			}
+			peaks, err := reports.NewMonthlyPeakReport(db).Query(t.Context(), "", "", nil, nil)
+			if err != nil || len(peaks) != 1 {
+				t.Fatalf("Expected 1 monthly peak, got %d (%v)", len(peaks), err)
+			}
+			if compliance[0].LicenseCores != tt.cores || peaks[0].PeakRunningCores != tt.cores {
+				t.Errorf("Expected %d cores in both reports, got compliance %d and monthly-peak %d",
+					tt.cores, compliance[0].LicenseCores, peaks[0].PeakRunningCores)
+			}
+		})
+	}
+}
+
+func TestImportCSVFileCloudMetadata(t *testing.T) {
+	db := setupImportDB(t)
+
+	csv := testInspectorCSV +
+		"CLOUD_PROVIDER,AWS\n" +
+		"CLOUD_INSTANCE_TYPE,m5.xlarge\n" +
+		"CLOUD_REGION,eu-west-1\n" +
+		"CLOUD_ACCOUNT_ID,123456789012\n"
+	file := filepath.Join(t.TempDir(), "iwdli_output_host1_20251021_090906.csv")
+	writeFile(t, file, csv)
+
+	if _, err := importer.NewImportService(db).ImportCSVFile(t.Context(), file); err != nil {
+		t.Fatalf("ImportCSVFile failed: %v", err)
+	}
+
+	var provider, instanceType, region, account string
+	err := db.QueryRow("SELECT cloud_provider, instance_type, region, account_id FROM measurements").
+		Scan(&provider, &instanceType, &region, &account)
+	if err != nil {
+		t.Fatalf("Failed to read measurement: %v", err)
+	}
+	got := strings.Join([]string{provider, instanceType, region, account}, " ")
+	if want := "aws m5.xlarge eu-west-1 123456789012"; got != want {
+		t.Errorf("Expected cloud metadata %q, got %q", want, got)
+	}
+}
+
+func TestImportCSVFilePartitionCap(t *testing.T) {
+	db := setupImportDB(t)
+
+	// A virtualized node with 4 visible vCPUs in a partition capped at 1.5 processors
+	csv := strings.Replace(testInspectorCSV, "IS_VIRTUALIZED,no", "IS_VIRTUALIZED,yes", 1) + "PARTITION_CPUS,1.5\n"
+	file := filepath.Join(t.TempDir(), "iwdli_output_host1_20251021_090906.csv")
+	writeFile(t, file, csv)
+
+	if _, err := importer.NewImportService(db).ImportCSVFile(t.Context(), file); err != nil {
+		t.Fatalf("ImportCSVFile failed: %v", err)
+	}
+
+	var considered, capCores int
+	if err := db.QueryRow("SELECT considered_cpus, partition_cap_cores FROM measurements").Scan(&considered, &capCores); err != nil {
+		t.Fatalf("Failed to read measurement: %v", err)
+	}
+	if considered != 2 || capCores != 2 {
+		t.Errorf("Expected 2 considered cores capped at 2, got %d capped at %d", considered, capCores)
+	}
+}
+
+func TestImportCSVFileEligibilityRules(t *testing.T) {
+	db := setupImportDB(t)
+
+	// An AIX 6.1 LPAR the inspector reported eligible, counting its 4 vCPUs
+	csv := strings.NewReplacer(
+		"OS_NAME,Linux", "OS_NAME,AIX",
+		"OS_VERSION,8", "OS_VERSION,6.100",
+		"IS_VIRTUALIZED,no", "IS_VIRTUALIZED,yes\nVIRT_TYPE,PowerVM - Micro-Partitioning\nHOST_PHYSICAL_CPUS,24\n"+
+			"PROCESSOR_VENDOR,IBM\nPROCESSOR_BRAND,POWER9",
+	).Replace(testInspectorCSV)
+	service := importer.NewImportService(db)
+	importFile := func(name string) (processor, os, virt string, cores int) {
+		t.Helper()
+		file := filepath.Join(t.TempDir(), name)
+		writeFile(t, file, csv)
+		if _, err := service.ImportCSVFile(t.Context(), file); err != nil {
+			t.Fatalf("ImportCSVFile failed: %v", err)
+		}
+		err := db.QueryRow(`SELECT processor_eligible, os_eligible, virt_eligible, considered_cpus FROM measurements
+			ORDER BY created_at DESC, rowid DESC LIMIT 1`).Scan(&processor, &os, &virt, &cores)
+		if err != nil {
+			t.Fatalf("Failed to read measurement: %v", err)
+		}
+		return processor, os, virt, cores
+	}
+
+	if _, os, _, cores := importFile("iwdli_output_host1_20251021_090906.csv"); os != "true" || cores != 4 {
+		t.Errorf("Expected the eligibility of the inspector without rules, got os %s and %d cores", os, cores)
+	}
+
+	if _, err := eligibility.Load(t.Context(), db, eligibility.DefaultContent(), "", "tester"); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	processor, os, virt, cores := importFile("iwdli_output_host2_20251021_090906.csv")
+	if processor != "true" || os != "false" || virt != "false" || cores != 24 {
+		t.Errorf("Expected an ineligible OS counting the host cores, got %s/%s/%s and %d cores", processor, os, virt, cores)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ImportSource is the original CSV content of an import session, archived
+// with ArchiveSource
+type ImportSource struct {
+	SessionID  string
+	SourceFile string
+	// FileSHA256 is the content hash recorded on the import session
+	FileSHA256 string
+	// Content is the uncompressed CSV content
+	Content  []byte
+	StoredAt time.Time
+}
+
+// Verified reports whether the content still has the hash recorded on the
+// import session
+func (s *ImportSource) Verified() bool {
+	return contentSHA256(s.Content) == s.FileSHA256
+}
+
+// contentSHA256 returns the hex encoded SHA-256 of content
+func contentSHA256(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// storeImportSource archives the content of an import session gzip compressed,
+// replacing the content archived by an earlier import of the same measurement
+func storeImportSource(tx *sql.Tx, sessionID, fileHash string, content []byte) error {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(content); err != nil {
+		return fmt.Errorf("failed to compress the source of %s: %w", sessionID, err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress the source of %s: %w", sessionID, err)
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO import_sources (session_id, file_sha256, content, size)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(session_id) DO UPDATE SET
+			file_sha256 = excluded.file_sha256,
+			content = excluded.content,
+			size = excluded.size,
+			stored_at = CURRENT_TIMESTAMP
+	`, sessionID, fileHash, compressed.Bytes(), len(content))
+	if err != nil {
+		return fmt.Errorf("failed to archive the source of %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+// archiveSkippedSource archives the content of a file skipped as already
+// imported when its session has no archived source yet, so that sessions
+// imported before archiving was enabled get it from a later run
+func (s *ImportService) archiveSkippedSource(ctx context.Context, sessionID, fileHash string, content []byte) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var archived int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM import_sources WHERE session_id = ?", sessionID).Scan(&archived); err != nil {
+		return fmt.Errorf("failed to look up the source of %s: %w", sessionID, err)
+	}
+	if archived > 0 {
+		return nil
+	}
+	if err := storeImportSource(tx, sessionID, fileHash, content); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetImportSource returns the archived CSV content of an import session
+func GetImportSource(db *sql.DB, sessionID string) (*ImportSource, error) {
+	source := &ImportSource{SessionID: sessionID}
+	var compressed []byte
+	var size int
+	err := db.QueryRow(`
+		SELECT COALESCE(s.source_file, ''), a.file_sha256, a.content, a.size, a.stored_at
+		FROM import_sources a
+		LEFT JOIN import_sessions s ON s.session_id = a.session_id
+		WHERE a.session_id = ?
+	`, sessionID).Scan(&source.SourceFile, &source.FileSHA256, &compressed, &size, &source.StoredAt)
+	if err == sql.ErrNoRows {
+		var sessions int
+		if err := db.QueryRow("SELECT COUNT(*) FROM import_sessions WHERE session_id = ?", sessionID).Scan(&sessions); err != nil {
+			return nil, fmt.Errorf("failed to read import session %s: %w", sessionID, err)
+		}
+		if sessions == 0 {
+			return nil, fmt.Errorf("import session %s not found", sessionID)
+		}
+		return nil, fmt.Errorf("import session %s has no archived source (imported without --archive-source)", sessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the source of %s: %w", sessionID, err)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress the source of %s: %w", sessionID, err)
+	}
+	defer gz.Close()
+	if source.Content, err = io.ReadAll(gz); err != nil {
+		return nil, fmt.Errorf("failed to decompress the source of %s: %w", sessionID, err)
+	}
+	if len(source.Content) != size {
+		return nil, fmt.Errorf("source of %s is %d bytes, %d were archived", sessionID, len(source.Content), size)
+	}
+	return source, nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestImportSource(t *testing.T) {
+	db := setupImportDB(t)
+
+	file := filepath.Join(t.TempDir(), "iwdli_output_host1_20251021_090906.csv")
+	writeFile(t, file, testInspectorCSV)
+
+	// Without archiving the session has no source
+	service := importer.NewImportService(db)
+	imported, err := service.ImportCSVFile(t.Context(), file)
+	if err != nil {
+		t.Fatalf("ImportCSVFile failed: %v", err)
+	}
+	if _, err := importer.GetImportSource(db, imported.SessionID); err == nil || !strings.Contains(err.Error(), "no archived source") {
+		t.Errorf("Expected a missing source error, got %v", err)
+	}
+	if _, err := importer.GetImportSource(db, "unknown"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected an unknown session error, got %v", err)
+	}
+
+	// A file skipped as already imported gets its source archived
+	service.ArchiveSource = true
+	skipped, err := service.ImportCSVFile(t.Context(), file)
+	if err != nil {
+		t.Fatalf("ImportCSVFile failed: %v", err)
+	}
+	if !skipped.AlreadyImported {
+		t.Fatal("Expected the file to be skipped as already imported")
+	}
+	source, err := importer.GetImportSource(db, imported.SessionID)
+	if err != nil {
+		t.Fatalf("GetImportSource failed: %v", err)
+	}
+	if string(source.Content) != testInspectorCSV || !source.Verified() {
+		t.Errorf("Expected the verified file content, got %q (verified %v)", source.Content, source.Verified())
+	}
+	if source.SourceFile != file || source.FileSHA256 != imported.FileSHA256 {
+		t.Errorf("Expected the source file and hash of the session, got %s %s", source.SourceFile, source.FileSHA256)
+	}
+
+	// A forced re-import of changed content without archiving drops the stale source
+	writeFile(t, file, testInspectorCSV+"IS_ONP_PRD_INSTALL_PATH_01,/opt/sag/is\n")
+	service.ArchiveSource = false
+	if _, err := service.ImportCSVFile(t.Context(), file); err != nil {
+		t.Fatalf("Re-import failed: %v", err)
+	}
+	if _, err := importer.GetImportSource(db, imported.SessionID); err == nil {
+		t.Error("Expected the stale source to be removed")
+	}
+
+	// Rolling back the session removes its source
+	service.ArchiveSource = true
+	service.Force = true
+	if _, err := service.ImportCSVFile(t.Context(), file); err != nil {
+		t.Fatalf("Re-import failed: %v", err)
+	}
+	if _, err := importer.GetImportSource(db, imported.SessionID); err != nil {
+		t.Fatalf("Expected the source to be archived: %v", err)
+	}
+	if _, err := service.Rollback(t.Context(), imported.SessionID, false); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	var sources int
+	if err := db.QueryRow("SELECT COUNT(*) FROM import_sources").Scan(&sources); err != nil {
+		t.Fatalf("Failed to count import sources: %v", err)
+	}
+	if sources != 0 {
+		t.Errorf("Expected the source to be removed with the session, got %d", sources)
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// CMDBNode is a landscape node of a CMDB export. Empty fields keep the value
+// of the landscape node.
+type CMDBNode struct {
+	Line             int // line of the export, for error messages
+	MainFQDN         string
+	Mode             string // PROD or NON PROD
+	SiteID           string
+	Owner            string
+	Team             string
+	CostCenter       string
+	ExpectedProducts string // product codes separated by commas, semicolons or spaces
+}
+
+// cmdbColumns are the accepted column names of a CMDB export per field, in
+// lower case with spaces and hyphens as underscores
+var cmdbColumns = map[string][]string{
+	"fqdn":              {"fqdn", "main_fqdn", "host_fqdn", "dns_name"},
+	"environment":       {"environment", "env", "mode", "used_for"},
+	"site":              {"site", "site_id", "location", "datacenter"},
+	"owner":             {"owner", "owned_by", "managed_by"},
+	"team":              {"team", "support_group", "assignment_group"},
+	"cost_center":       {"cost_center", "cost_centre", "costcenter", "cost_center_code"},
+	"expected_products": {"expected_products", "expected_product_codes", "expected_product_codes_list", "products"},
+}
+
+// cmdbEnvironments maps the environment values of CMDB exports to node modes
+var cmdbEnvironments = map[string]string{
+	"prod": "PROD", "prd": "PROD", "production": "PROD",
+	"non prod": "NON PROD", "nonprod": "NON PROD", "non-prod": "NON PROD", "non_prod": "NON PROD",
+	"npr": "NON PROD", "non-production": "NON PROD", "nonproduction": "NON PROD",
+	"dev": "NON PROD", "development": "NON PROD", "test": "NON PROD", "testing": "NON PROD",
+	"qa": "NON PROD", "uat": "NON PROD", "staging": "NON PROD", "stage": "NON PROD",
+	"sandbox": "NON PROD", "training": "NON PROD",
+}
+
+// ReadCMDBExport reads a CMDB export in CSV format. The header names the
+// columns: fqdn is required, environment, site, owner, team, cost_center and
+// expected_products are optional (see cmdbColumns for the accepted names); other columns are
+// ignored.
+func ReadCMDBExport(r io.Reader) ([]CMDBNode, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	index := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+		for field, names := range cmdbColumns {
+			for _, accepted := range names {
+				if _, seen := index[field]; name == accepted && !seen {
+					index[field] = i
+				}
+			}
+		}
+	}
+	if _, ok := index["fqdn"]; !ok {
+		return nil, fmt.Errorf("the CMDB export has no FQDN column (one of %s)", strings.Join(cmdbColumns["fqdn"], ", "))
+	}
+
+	var nodes []CMDBNode
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read line %d: %w", line, err)
+		}
+		field := func(name string) string {
+			if i, ok := index[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		node := CMDBNode{
+			Line:       line,
+			MainFQDN:   field("fqdn"),
+			SiteID:     field("site"),
+			Owner:      field("owner"),
+			Team:       field("team"),
+			CostCenter: field("cost_center"),
+		}
+		if node.MainFQDN == "" {
+			continue // Skip rows without a host, e.g. blank lines
+		}
+		if environment := field("environment"); environment != "" {
+			mode, ok := cmdbEnvironments[strings.ToLower(environment)]
+			if !ok {
+				return nil, fmt.Errorf("line %d: unknown environment %q of %s (expected production, non-production, dev, test, qa, uat or staging)", line, environment, node.MainFQDN)
+			}
+			node.Mode = mode
+		}
+		node.ExpectedProducts = normalizeProductList(field("expected_products"))
+
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// normalizeProductList returns a product code list separated by commas,
+// semicolons or spaces as sorted, unique codes separated by commas
+func normalizeProductList(list string) string {
+	seen := make(map[string]bool)
+	var codes []string
+	for _, code := range strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t'
+	}) {
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return strings.Join(codes, ",")
+}
+
+// FieldChange is the change of one field of a landscape node
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// LandscapeChange is a landscape node added or updated by a CMDB import
+type LandscapeChange struct {
+	MainFQDN string
+	Added    bool
+	Changes  []FieldChange // the fields set, for an added node
+}
+
+// LandscapePlan is the preview of a CMDB import, applied with Apply
+type LandscapePlan struct {
+	Changes   []LandscapeChange
+	Unchanged int
+	// NewSites are the sites of the export not yet defined; they are created
+	// with their ID as name
+	NewSites []string
+	// Missing are the landscape nodes not in the export; they are kept
+	Missing []string
+}
+
+// Added returns the number of nodes the plan adds
+func (p *LandscapePlan) Added() int {
+	added := 0
+	for _, change := range p.Changes {
+		if change.Added {
+			added++
+		}
+	}
+	return added
+}
+
+// LandscapeImporter populates and refreshes the landscape nodes from CMDB
+// exports
+type LandscapeImporter struct {
+	db *sql.DB
+}
+
+// NewLandscapeImporter creates a new landscape importer
+func NewLandscapeImporter(db *sql.DB) *LandscapeImporter {
+	return &LandscapeImporter{db: db}
+}
+
+// landscapeFields are the columns of landscape_nodes set by a CMDB import
+var landscapeFields = []string{"mode", "site_id", "owner", "team", "cost_center", "expected_product_codes_list"}
+
+// Plan compares the nodes of a CMDB export with the landscape nodes, without
+// changing them. Nodes are matched by their main FQDN; an FQDN recorded as a
+// node alias updates its main node. New nodes without an environment are
+// PROD, as nodes created by an import.
+func (l *LandscapeImporter) Plan(nodes []CMDBNode) (*LandscapePlan, error) {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	plan := &LandscapePlan{}
+	listed := make(map[string]int)
+	newSites := make(map[string]bool)
+	for _, node := range nodes {
+		mainFQDN, err := resolveNodeAlias(tx, node.MainFQDN)
+		if err != nil {
+			return nil, err
+		}
+		if line, ok := listed[mainFQDN]; ok {
+			return nil, fmt.Errorf("line %d: %s is listed twice (first on line %d)", node.Line, mainFQDN, line)
+		}
+		listed[mainFQDN] = node.Line
+
+		if node.SiteID != "" && !newSites[node.SiteID] {
+			var count int
+			if err := tx.QueryRow("SELECT COUNT(*) FROM sites WHERE site_id = ?", node.SiteID).Scan(&count); err != nil {
+				return nil, fmt.Errorf("failed to look up site %s: %w", node.SiteID, err)
+			}
+			if count == 0 {
+				newSites[node.SiteID] = true
+				plan.NewSites = append(plan.NewSites, node.SiteID)
+			}
+		}
+
+		values := []string{node.Mode, node.SiteID, node.Owner, node.Team, node.CostCenter, node.ExpectedProducts}
+		var current [6]string
+		err = tx.QueryRow(`
+			SELECT mode, COALESCE(site_id, ''), owner, team, cost_center, COALESCE(expected_product_codes_list, '')
+			FROM landscape_nodes WHERE main_fqdn = ?
+		`, mainFQDN).Scan(&current[0], &current[1], &current[2], &current[3], &current[4], &current[5])
+		if err == sql.ErrNoRows {
+			if values[0] == "" {
+				values[0] = "PROD"
+			}
+			change := LandscapeChange{MainFQDN: mainFQDN, Added: true}
+			for i, field := range landscapeFields {
+				if values[i] != "" {
+					change.Changes = append(change.Changes, FieldChange{Field: field, New: values[i]})
+				}
+			}
+			plan.Changes = append(plan.Changes, change)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up node %s: %w", mainFQDN, err)
+		}
+
+		current[5] = normalizeProductList(current[5])
+		change := LandscapeChange{MainFQDN: mainFQDN}
+		for i, field := range landscapeFields {
+			if values[i] != "" && values[i] != current[i] {
+				change.Changes = append(change.Changes, FieldChange{Field: field, Old: current[i], New: values[i]})
+			}
+		}
+		if len(change.Changes) == 0 {
+			plan.Unchanged++
+			continue
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	rows, err := tx.Query("SELECT main_fqdn FROM landscape_nodes ORDER BY main_fqdn")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list landscape nodes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var fqdn string
+		if err := rows.Scan(&fqdn); err != nil {
+			return nil, err
+		}
+		if _, ok := listed[fqdn]; !ok {
+			plan.Missing = append(plan.Missing, fqdn)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(plan.Changes, func(i, j int) bool { return plan.Changes[i].MainFQDN < plan.Changes[j].MainFQDN })
+	sort.Strings(plan.NewSites)
+	return plan, nil
+}
+
+// Apply creates the sites and the nodes, and updates the nodes of a plan in
+// one transaction
+func (l *LandscapeImporter) Apply(plan *LandscapePlan) error {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, siteID := range plan.NewSites {
+		if _, err := tx.Exec(`INSERT INTO sites (site_id, site_name) VALUES (?, ?)`, siteID, siteID); err != nil {
+			return fmt.Errorf("failed to insert site %s: %w", siteID, err)
+		}
+	}
+
+	for _, change := range plan.Changes {
+		if change.Added {
+			hostname, _, _ := strings.Cut(change.MainFQDN, ".")
+			if _, err := tx.Exec(`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES (?, ?, 'PROD')`, change.MainFQDN, hostname); err != nil {
+				return fmt.Errorf("failed to insert landscape node %s: %w", change.MainFQDN, err)
+			}
+		}
+		for _, field := range change.Changes {
+			// The field names come from landscapeFields
+			_, err := tx.Exec(`UPDATE landscape_nodes SET `+field.Field+` = ?, updated_at = CURRENT_TIMESTAMP WHERE main_fqdn = ?`,
+				field.New, change.MainFQDN)
+			if err != nil {
+				return fmt.Errorf("failed to update %s of node %s: %w", field.Field, change.MainFQDN, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestReadCMDBExport(t *testing.T) {
+	export := "Name,FQDN,Environment,Location,Owned By,Support Group,Cost Centre,Products\n" +
+		"a,a.example.com,Production,dc1,alice,integration,CC-4711,\"IS_ONP_PRD; BRK_ONP_PRD IS_ONP_PRD\"\n" +
+		"b,b.example.com,UAT,,,,,\n" +
+		",,,,,,,\n"
+	nodes, err := importer.ReadCMDBExport(strings.NewReader(export))
+	if err != nil {
+		t.Fatalf("ReadCMDBExport failed: %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("Expected 2 nodes, got %+v", nodes)
+	}
+	want := importer.CMDBNode{Line: 2, MainFQDN: "a.example.com", Mode: "PROD", SiteID: "dc1", Owner: "alice", Team: "integration", CostCenter: "CC-4711", ExpectedProducts: "BRK_ONP_PRD,IS_ONP_PRD"}
+	if nodes[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, nodes[0])
+	}
+	if nodes[1].Mode != "NON PROD" || nodes[1].Owner != "" {
+		t.Errorf("Expected a NON PROD node without owner, got %+v", nodes[1])
+	}
+
+	if _, err := importer.ReadCMDBExport(strings.NewReader("host,env\na,prod\n")); err == nil {
+		t.Error("Expected an error for an export without FQDN column")
+	}
+	if _, err := importer.ReadCMDBExport(strings.NewReader("fqdn,env\na.example.com,lab\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected an error for the unknown environment on line 2, got %v", err)
+	}
+}
+
+func TestLandscapeImport(t *testing.T) {
+	db := setupImportDB(t)
+	for _, fqdn := range []string{"a.example.com", "b.example.com", "old.example.com"} {
+		if _, err := db.Exec(`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES (?, ?, 'PROD')`, fqdn, fqdn); err != nil {
+			t.Fatalf("Failed to insert node: %v", err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO node_aliases (alias_fqdn, main_fqdn) VALUES ('b.old.example.com', 'b.example.com')`); err != nil {
+		t.Fatalf("Failed to insert alias: %v", err)
+	}
+
+	nodes := []importer.CMDBNode{
+		{Line: 2, MainFQDN: "a.example.com", Mode: "PROD"},
+		{Line: 3, MainFQDN: "b.old.example.com", Mode: "NON PROD", SiteID: "dc1", Owner: "bob"},
+		{Line: 4, MainFQDN: "c.example.com", Owner: "carol", ExpectedProducts: "IS_ONP_PRD"},
+	}
+	landscape := importer.NewLandscapeImporter(db)
+	plan, err := landscape.Plan(nodes)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if plan.Unchanged != 1 || len(plan.Changes) != 2 || plan.Added() != 1 {
+		t.Fatalf("Expected 1 unchanged, 1 updated and 1 added node, got %+v", plan)
+	}
+	if update := plan.Changes[0]; update.MainFQDN != "b.example.com" || len(update.Changes) != 3 ||
+		update.Changes[0] != (importer.FieldChange{Field: "mode", Old: "PROD", New: "NON PROD"}) {
+		t.Errorf("Expected the alias to update b.example.com, got %+v", update)
+	}
+	if strings.Join(plan.NewSites, ",") != "dc1" || strings.Join(plan.Missing, ",") != "old.example.com" {
+		t.Errorf("Expected new site dc1 and missing old.example.com, got %v and %v", plan.NewSites, plan.Missing)
+	}
+
+	// The plan changes nothing until it is applied
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM landscape_nodes").Scan(&count)
+	if count != 3 {
+		t.Fatalf("Expected the plan to leave the nodes unchanged, got %d nodes", count)
+	}
+
+	if err := landscape.Apply(plan); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	var mode, site, owner, products string
+	db.QueryRow("SELECT mode, site_id, owner FROM landscape_nodes WHERE main_fqdn = 'b.example.com'").Scan(&mode, &site, &owner)
+	if mode != "NON PROD" || site != "dc1" || owner != "bob" {
+		t.Errorf("Expected b.example.com updated, got %s %s %s", mode, site, owner)
+	}
+	db.QueryRow("SELECT hostname, mode, owner, expected_product_codes_list FROM landscape_nodes WHERE main_fqdn = 'c.example.com'").Scan(&site, &mode, &owner, &products)
+	if site != "c" || mode != "PROD" || owner != "carol" || products != "IS_ONP_PRD" {
+		t.Errorf("Expected c.example.com added, got %s %s %s %s", site, mode, owner, products)
+	}
+
+	plan, err = landscape.Plan(nodes)
+	if err != nil || len(plan.Changes) != 0 || len(plan.NewSites) != 0 || plan.Unchanged != 3 {
+		t.Errorf("Expected nothing left to change, got %+v, %v", plan, err)
+	}
+
+	if _, err := landscape.Plan(append(nodes, importer.CMDBNode{Line: 5, MainFQDN: "b.example.com"})); err == nil {
+		t.Error("Expected an error for a node listed twice")
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+)
+
+// nodeHistoryTables hold the measurement history of a node by main_fqdn
+var nodeHistoryTables = []string{
+	"measurements",
+	"detected_products",
+	"detected_product_installs",
+	"detected_product_processes",
+	"import_sessions",
+}
+
+// NodeAliasEditor keeps the measurement history of renamed or re-addressed
+// nodes under one main FQDN. An alias moves the history of the former FQDN to
+// the main FQDN, and later imports reporting the former FQDN are stored under
+// the main one.
+type NodeAliasEditor struct {
+	db        *sql.DB
+	CreatedBy string // user recorded with the alias
+}
+
+// NewNodeAliasEditor creates a new node alias editor
+func NewNodeAliasEditor(db *sql.DB) *NodeAliasEditor {
+	return &NodeAliasEditor{db: db}
+}
+
+// Add makes aliasFQDN an alias of mainFQDN. Measurements already imported
+// under aliasFQDN are moved to mainFQDN, which takes over the node if it is not
+// known yet; the two nodes must not have measurements at the same time.
+func (e *NodeAliasEditor) Add(aliasFQDN, mainFQDN, reason string) (*models.NodeAlias, error) {
+	if aliasFQDN == "" || mainFQDN == "" {
+		return nil, fmt.Errorf("node FQDNs must not be empty")
+	}
+	if aliasFQDN == mainFQDN {
+		return nil, fmt.Errorf("alias and main FQDN are the same: %s", aliasFQDN)
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if target, err := lookupNodeAlias(tx, aliasFQDN); err != nil {
+		return nil, err
+	} else if target != "" {
+		return nil, fmt.Errorf("%s is already an alias of %s", aliasFQDN, target)
+	}
+	if target, err := lookupNodeAlias(tx, mainFQDN); err != nil {
+		return nil, err
+	} else if target != "" {
+		return nil, fmt.Errorf("%s is an alias of %s; use %s as main FQDN", mainFQDN, target, target)
+	}
+
+	moved, err := moveNodeHistory(tx, aliasFQDN, mainFQDN)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep aliases flat: earlier aliases of the alias now point to the main FQDN
+	if _, err := tx.Exec("UPDATE node_aliases SET main_fqdn = ? WHERE main_fqdn = ?", mainFQDN, aliasFQDN); err != nil {
+		return nil, fmt.Errorf("failed to update node aliases: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO node_aliases (alias_fqdn, main_fqdn, measurements_moved, reason, created_by)
+		VALUES (?, ?, ?, ?, ?)
+	`, aliasFQDN, mainFQDN, moved, reason, e.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record node alias: %w", err)
+	}
+
+	alias := &models.NodeAlias{}
+	err = tx.QueryRow(`
+		SELECT alias_fqdn, main_fqdn, measurements_moved, reason, created_by, created_at
+		FROM node_aliases
+		WHERE alias_fqdn = ?
+	`, aliasFQDN).Scan(&alias.AliasFQDN, &alias.MainFQDN, &alias.MeasurementsMoved,
+		&alias.Reason, &alias.CreatedBy, &alias.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node alias: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return alias, nil
+}
+
+// moveNodeHistory moves the node and measurements of aliasFQDN to mainFQDN and
+// returns the number of measurements moved
+func moveNodeHistory(tx *sql.Tx, aliasFQDN, mainFQDN string) (int, error) {
+	var known int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM landscape_nodes WHERE main_fqdn = ?", aliasFQDN).Scan(&known); err != nil {
+		return 0, fmt.Errorf("failed to look up node %s: %w", aliasFQDN, err)
+	}
+	if known == 0 {
+		return 0, nil
+	}
+
+	var overlap int
+	err := tx.QueryRow(`
+		SELECT COUNT(*)
+		FROM measurements a
+		JOIN measurements m ON m.detection_timestamp = a.detection_timestamp
+		WHERE a.main_fqdn = ? AND m.main_fqdn = ?
+	`, aliasFQDN, mainFQDN).Scan(&overlap)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compare measurements: %w", err)
+	}
+	if overlap > 0 {
+		return 0, fmt.Errorf("%s and %s both have %d measurements at the same time; they are different nodes", aliasFQDN, mainFQDN, overlap)
+	}
+
+	// Child rows are moved before their parents; check the keys at commit
+	if _, err := tx.Exec("PRAGMA defer_foreign_keys = ON"); err != nil {
+		return 0, fmt.Errorf("failed to defer foreign keys: %w", err)
+	}
+
+	// The main FQDN takes over the node when it was not imported yet
+	_, err = tx.Exec(`
+		INSERT INTO landscape_nodes (main_fqdn, hostname, mode, expected_product_codes_list, expected_cpu_no, site_id,
+			org_id, owner, team, cost_center, classification, standby_type, decommissioned_on, created_at)
+		SELECT ?, hostname, mode, expected_product_codes_list, expected_cpu_no, site_id,
+			org_id, owner, team, cost_center, classification, standby_type, decommissioned_on, created_at
+		FROM landscape_nodes
+		WHERE main_fqdn = ?
+		ON CONFLICT(main_fqdn) DO NOTHING
+	`, mainFQDN, aliasFQDN)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create node %s: %w", mainFQDN, err)
+	}
+
+	moved := 0
+	for _, table := range nodeHistoryTables {
+		res, err := tx.Exec("UPDATE "+table+" SET main_fqdn = ? WHERE main_fqdn = ?", mainFQDN, aliasFQDN)
+		if err != nil {
+			return 0, fmt.Errorf("failed to move %s: %w", table, err)
+		}
+		if table == "measurements" {
+			n, err := res.RowsAffected()
+			if err != nil {
+				return 0, err
+			}
+			moved = int(n)
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM landscape_nodes WHERE main_fqdn = ?", aliasFQDN); err != nil {
+		return 0, fmt.Errorf("failed to delete node %s: %w", aliasFQDN, err)
+	}
+
+	// The lifecycle of the products and instances, and the changes of the
+	// node, now span both histories
+	for _, fqdn := range []string{aliasFQDN, mainFQDN} {
+		if err := refreshProductLifecycle(tx, fqdn); err != nil {
+			return 0, err
+		}
+		if err := refreshProductInstances(tx, fqdn); err != nil {
+			return 0, err
+		}
+		if err := refreshNodeChanges(tx, fqdn); err != nil {
+			return 0, err
+		}
+	}
+
+	return moved, nil
+}
+
+// Remove deletes an alias. Measurements moved when the alias was added stay
+// under the main FQDN; later imports reporting the alias create a node again.
+func (e *NodeAliasEditor) Remove(aliasFQDN string) error {
+	res, err := e.db.Exec("DELETE FROM node_aliases WHERE alias_fqdn = ?", aliasFQDN)
+	if err != nil {
+		return fmt.Errorf("failed to remove node alias: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("node alias %s not found", aliasFQDN)
+	}
+	return nil
+}
+
+// List returns all node aliases, by main FQDN
+func (e *NodeAliasEditor) List() ([]models.NodeAlias, error) {
+	rows, err := e.db.Query(`
+		SELECT alias_fqdn, main_fqdn, measurements_moved, reason, created_by, created_at
+		FROM node_aliases
+		ORDER BY main_fqdn, alias_fqdn
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query node aliases: %w", err)
+	}
+	defer rows.Close()
+
+	var aliases []models.NodeAlias
+	for rows.Next() {
+		var a models.NodeAlias
+		if err := rows.Scan(&a.AliasFQDN, &a.MainFQDN, &a.MeasurementsMoved, &a.Reason, &a.CreatedBy, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan node alias: %w", err)
+		}
+		aliases = append(aliases, a)
+	}
+
+	return aliases, rows.Err()
+}
+
+// lookupNodeAlias returns the main FQDN of an alias, or "" when fqdn is not an alias
+func lookupNodeAlias(tx *sql.Tx, fqdn string) (string, error) {
+	var mainFQDN string
+	err := tx.QueryRow("SELECT main_fqdn FROM node_aliases WHERE alias_fqdn = ?", fqdn).Scan(&mainFQDN)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve node alias: %w", err)
+	}
+	return mainFQDN, nil
+}
+
+// resolveNodeAlias returns the main FQDN measurements reporting fqdn are stored under
+func resolveNodeAlias(tx *sql.Tx, fqdn string) (string, error) {
+	mainFQDN, err := lookupNodeAlias(tx, fqdn)
+	if err != nil || mainFQDN == "" {
+		return fqdn, err
+	}
+	return mainFQDN, nil
+}
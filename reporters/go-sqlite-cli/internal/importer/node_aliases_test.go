@@ -0,0 +1,115 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestNodeAliasKeepsHistory(t *testing.T) {
+	db := setupImportDB(t)
+	service := importer.NewImportService(db)
+	root := t.TempDir()
+
+	// The host was imported under its former name
+	before := filepath.Join(root, "iwdli_output_oldname_20251021_090906.csv")
+	writeFile(t, before, testInspectorCSV)
+	if _, err := service.ImportCSVFile(t.Context(), before); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	editor := importer.NewNodeAliasEditor(db)
+	editor.CreatedBy = "tester"
+	alias, err := editor.Add("oldname.local", "newname.local", "renamed")
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if alias.MeasurementsMoved != 1 || alias.CreatedBy != "tester" {
+		t.Errorf("Unexpected alias record: %+v", alias)
+	}
+
+	// A later file still reporting the former name joins the same history
+	after := filepath.Join(root, "iwdli_output_oldname_20251022_090906.csv")
+	writeFile(t, after, strings.Replace(testInspectorCSV, "2025-10-21", "2025-10-22", 1))
+	if _, err := service.ImportCSVFile(t.Context(), after); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	for table, want := range map[string]int{"measurements": 2, "detected_products": 2, "import_sessions": 2} {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table + " WHERE main_fqdn = 'newname.local'").Scan(&count); err != nil {
+			t.Fatalf("Failed to count %s: %v", table, err)
+		}
+		if count != want {
+			t.Errorf("Expected %d %s under the main FQDN, got %d", want, table, count)
+		}
+	}
+	var nodes int
+	if err := db.QueryRow("SELECT COUNT(*) FROM landscape_nodes").Scan(&nodes); err != nil {
+		t.Fatalf("Failed to count nodes: %v", err)
+	}
+	if nodes != 1 {
+		t.Errorf("Expected a single landscape node, got %d", nodes)
+	}
+
+	// Aliases stay flat
+	if _, err := editor.Add("newname.local", "oldname.local", ""); err == nil {
+		t.Error("Expected an alias onto an alias to fail")
+	}
+	if _, err := editor.Add("third.local", "oldname.local", ""); err == nil || !strings.Contains(err.Error(), "use newname.local") {
+		t.Errorf("Expected an alias onto an alias to be rejected, got %v", err)
+	}
+
+	aliases, err := editor.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(aliases) != 1 || aliases[0].AliasFQDN != "oldname.local" {
+		t.Errorf("Unexpected aliases: %+v", aliases)
+	}
+
+	if err := editor.Remove("oldname.local"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := editor.Remove("oldname.local"); err == nil {
+		t.Error("Expected removing an unknown alias to fail")
+	}
+}
+
+func TestNodeAliasRejectsOverlappingNodes(t *testing.T) {
+	db := setupImportDB(t)
+	service := importer.NewImportService(db)
+	root := t.TempDir()
+
+	// Two hosts measured at the same time are different nodes
+	var paths []string
+	for _, host := range []string{"host1", "host2"} {
+		path := filepath.Join(root, "iwdli_output_"+host+"_20251021_090906.csv")
+		writeFile(t, path, testInspectorCSV)
+		paths = append(paths, path)
+	}
+	if batch := service.ImportFiles(t.Context(), paths, nil); batch.FilesOK != 2 {
+		t.Fatalf("Expected 2 imported files, got %+v", batch.Files)
+	}
+
+	_, err := importer.NewNodeAliasEditor(db).Add("host1.local", "host2.local", "")
+	if err == nil || !strings.Contains(err.Error(), "different nodes") {
+		t.Errorf("Expected overlapping measurements to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// nodeChangeFields are the measurement columns compared with the previous
+// measurement of a node: the changes that move its license cores
+var nodeChangeFields = []string{
+	"cpu_count",
+	"considered_cpus",
+	"os_name",
+	"os_version",
+	"is_virtualized",
+	"virt_type",
+	"host_physical_cpus",
+	"physical_host_id",
+	"processor_eligible",
+	"os_eligible",
+	"virt_eligible",
+}
+
+// nodeChangesQuery compares each measurement of a node with the previous one
+// and returns one row per changed field
+var nodeChangesQuery = func() string {
+	columns := make([]string, 0, len(nodeChangeFields))
+	selects := make([]string, 0, len(nodeChangeFields))
+	for _, field := range nodeChangeFields {
+		columns = append(columns, fmt.Sprintf("CAST(%[1]s AS TEXT) AS %[1]s, CAST(LAG(%[1]s) OVER w AS TEXT) AS previous_%[1]s", field))
+		selects = append(selects, fmt.Sprintf(`
+		SELECT main_fqdn, detection_timestamp, previous_timestamp, '%[1]s', previous_%[1]s, %[1]s
+		FROM history
+		WHERE previous_timestamp IS NOT NULL AND previous_%[1]s IS NOT %[1]s`, field))
+	}
+	return `
+		WITH history AS (
+			SELECT main_fqdn, detection_timestamp, LAG(detection_timestamp) OVER w AS previous_timestamp,
+				` + strings.Join(columns, ",\n\t\t\t\t") + `
+			FROM measurements
+			WHERE main_fqdn = ?
+			WINDOW w AS (ORDER BY detection_timestamp)
+		)` + strings.Join(selects, "\n\t\tUNION ALL")
+}()
+
+// refreshNodeChanges recomputes the changes of a node between consecutive
+// measurements. Like refreshProductLifecycle it runs after each import,
+// rollback and alias of the node, so files imported out of order and removed
+// measurements are compared with the right neighbour.
+func refreshNodeChanges(tx *sql.Tx, mainFQDN string) error {
+	if _, err := tx.Exec("DELETE FROM node_changes WHERE main_fqdn = ?", mainFQDN); err != nil {
+		return fmt.Errorf("failed to clear node changes of %s: %w", mainFQDN, err)
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO node_changes (main_fqdn, detection_timestamp, previous_timestamp, field, old_value, new_value)
+	`+nodeChangesQuery, mainFQDN)
+	if err != nil {
+		return fmt.Errorf("failed to update node changes of %s: %w", mainFQDN, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestNodeChanges(t *testing.T) {
+	db := setupImportDB(t)
+	service := importer.NewImportService(db)
+	root := t.TempDir()
+
+	resized := strings.NewReplacer("2025-10-21", "2025-10-23", "CPU_COUNT,4", "CPU_COUNT,8",
+		"CONSIDERED_CPUS,4", "CONSIDERED_CPUS,8", "OS_VERSION,8", "OS_VERSION,9").Replace(testInspectorCSV)
+	files := []struct{ name, content string }{
+		// Imported out of order: the node grows on the 23rd
+		{"iwdli_output_host1_20251023_090906.csv", resized},
+		{"iwdli_output_host1_20251021_090906.csv", testInspectorCSV},
+		{"iwdli_output_host1_20251022_090906.csv", strings.Replace(testInspectorCSV, "2025-10-21", "2025-10-22", 1)},
+	}
+	var sessions []string
+	for _, f := range files {
+		path := filepath.Join(root, f.name)
+		writeFile(t, path, f.content)
+		result, err := service.ImportCSVFile(t.Context(), path)
+		if err != nil {
+			t.Fatalf("Failed to import %s: %v", f.name, err)
+		}
+		sessions = append(sessions, result.SessionID)
+	}
+
+	report := reports.NewNodeChangesReport(db)
+	rows, err := report.Query(t.Context(), "host1", "", "", nil, nil)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	want := map[string][2]string{"considered_cpus": {"4", "8"}, "cpu_count": {"4", "8"}, "os_version": {"8", "9"}}
+	if len(rows) != len(want) {
+		t.Fatalf("Expected %d changes, got %+v", len(want), rows)
+	}
+	for _, row := range rows {
+		if w := want[row.Field]; row.OldValue != w[0] || row.NewValue != w[1] ||
+			row.DetectionTimestamp != "2025-10-23 09:09:06" || row.PreviousTimestamp != "2025-10-22 09:09:06" {
+			t.Errorf("Unexpected change: %+v", row)
+		}
+	}
+
+	if rows, err := report.Query(t.Context(), "", "os_version", "", nil, nil); err != nil || len(rows) != 1 {
+		t.Errorf("Expected the OS version change, got %+v (%v)", rows, err)
+	}
+
+	// Rolling back the measurement of the 22nd compares the 23rd with the 21st
+	if _, err := service.Rollback(t.Context(), sessions[2], false); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	rows, err = report.Query(t.Context(), "", "cpu_count", "", nil, nil)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0].PreviousTimestamp != "2025-10-21 09:09:06" {
+		t.Errorf("Unexpected changes after rollback: %+v", rows)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+)
+
+// Node classifications
+const (
+	NodeActive         = "active"
+	NodeStandby        = "standby"
+	NodeDR             = "dr"
+	NodeDecommissioned = "decommissioned"
+)
+
+// Standby types of standby and DR nodes, after the IBM backup licensing rules:
+// cold backups are not started and need no license, warm and hot backups do
+const (
+	StandbyCold = "cold"
+	StandbyWarm = "warm"
+	StandbyHot  = "hot"
+)
+
+// NodeClassificationEditor manages the classification of the landscape nodes
+type NodeClassificationEditor struct {
+	db *sql.DB
+}
+
+// NewNodeClassificationEditor creates a new node classification editor
+func NewNodeClassificationEditor(db *sql.DB) *NodeClassificationEditor {
+	return &NodeClassificationEditor{db: db}
+}
+
+// ParseNodeClassification validates a classification and a standby type,
+// case-insensitively. The standby type only applies to standby and DR nodes,
+// and may be left empty when unknown.
+func ParseNodeClassification(classification, standbyType string) (string, string, error) {
+	classification = strings.ToLower(strings.TrimSpace(classification))
+	standbyType = strings.ToLower(strings.TrimSpace(standbyType))
+
+	switch classification {
+	case NodeActive, NodeDecommissioned:
+		if standbyType != "" {
+			return "", "", fmt.Errorf("a standby type only applies to standby and DR nodes")
+		}
+	case NodeStandby, NodeDR:
+		switch standbyType {
+		case "", StandbyCold, StandbyWarm, StandbyHot:
+		default:
+			return "", "", fmt.Errorf("invalid standby type %q (expected %s, %s or %s)", standbyType, StandbyCold, StandbyWarm, StandbyHot)
+		}
+	default:
+		return "", "", fmt.Errorf("invalid classification %q (expected %s, %s, %s or %s)",
+			classification, NodeActive, NodeStandby, NodeDR, NodeDecommissioned)
+	}
+	return classification, standbyType, nil
+}
+
+// Classify sets the classification and standby type of landscape nodes. A node
+// classified anything but decommissioned loses its decommission date.
+func (e *NodeClassificationEditor) Classify(classification, standbyType string, fqdns []string) error {
+	classification, standbyType, err := ParseNodeClassification(classification, standbyType)
+	if err != nil {
+		return err
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, fqdn := range fqdns {
+		res, err := tx.Exec(`
+			UPDATE landscape_nodes SET classification = ?, standby_type = ?,
+				decommissioned_on = CASE WHEN ? = 'decommissioned' THEN decommissioned_on END,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE main_fqdn = ?
+		`, classification, standbyType, classification, fqdn)
+		if err != nil {
+			return fmt.Errorf("failed to classify node %s: %w", fqdn, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("landscape node %s not found (nodes are created by their first import)", fqdn)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Decommission classifies a landscape node as decommissioned on a day. The
+// reports leave out its measurements taken after that day, and imports of later
+// measurements warn about them.
+func (e *NodeClassificationEditor) Decommission(fqdn string, day time.Time) error {
+	res, err := e.db.Exec(`
+		UPDATE landscape_nodes SET classification = 'decommissioned', standby_type = '',
+			decommissioned_on = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE main_fqdn = ?
+	`, day.Format("2006-01-02"), fqdn)
+	if err != nil {
+		return fmt.Errorf("failed to decommission node %s: %w", fqdn, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("landscape node %s not found (nodes are created by their first import)", fqdn)
+	}
+	return nil
+}
+
+// List returns the landscape nodes with a classification, or all nodes when
+// classification is empty, ordered by FQDN
+func (e *NodeClassificationEditor) List(classification string) ([]models.LandscapeNode, error) {
+	query := `
+		SELECT main_fqdn, hostname, mode, site_id, owner, team, cost_center, classification, standby_type, decommissioned_on
+		FROM landscape_nodes
+	`
+	args := []interface{}{}
+	if classification != "" {
+		query += " WHERE classification = ?"
+		args = append(args, strings.ToLower(classification))
+	}
+	query += " ORDER BY main_fqdn"
+
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list landscape nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []models.LandscapeNode
+	for rows.Next() {
+		var node models.LandscapeNode
+		var siteID sql.NullString
+		var decommissionedOn sql.NullTime
+		if err := rows.Scan(&node.MainFQDN, &node.Hostname, &node.Mode, &siteID, &node.Owner, &node.Team, &node.CostCenter, &node.Classification, &node.StandbyType,
+			&decommissionedOn); err != nil {
+			return nil, fmt.Errorf("failed to scan landscape node: %w", err)
+		}
+		if siteID.Valid {
+			node.SiteID = &siteID.String
+		}
+		if decommissionedOn.Valid {
+			node.DecommissionedOn = &decommissionedOn.Time
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestNodeClassification(t *testing.T) {
+	db := setupImportDB(t)
+	root := t.TempDir()
+	service := importer.NewImportService(db)
+	for _, host := range []string{"host1", "host2", "host3"} {
+		path := filepath.Join(root, "iwdli_output_"+host+"_20251021_090906.csv")
+		writeFile(t, path, testInspectorCSV)
+		if _, err := service.ImportCSVFile(t.Context(), path); err != nil {
+			t.Fatalf("Import of %s failed: %v", host, err)
+		}
+	}
+
+	editor := importer.NewNodeClassificationEditor(db)
+	if err := editor.Classify("Standby", "COLD", []string{"host2.local"}); err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+	if err := editor.Classify("dr", "hot", []string{"host3.local"}); err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+
+	for name, args := range map[string][]string{
+		"unknown classification":    {"spare", ""},
+		"unknown standby type":      {"standby", "lukewarm"},
+		"standby type of an active": {"active", "cold"},
+	} {
+		if err := editor.Classify(args[0], args[1], []string{"host1.local"}); err == nil {
+			t.Errorf("Expected an error classifying with %s", name)
+		}
+	}
+	if err := editor.Classify("dr", "", []string{"unknown.local"}); err == nil {
+		t.Error("Expected an error classifying an unknown node")
+	}
+
+	standby, err := editor.List("standby")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(standby) != 1 || standby[0].MainFQDN != "host2.local" || standby[0].StandbyType != "cold" {
+		t.Errorf("Unexpected standby nodes: %+v", standby)
+	}
+
+	// The node-level reports select the standby and DR nodes
+	report := reports.NewHostDetailReport(db)
+	for policy, want := range map[string]int{
+		reports.StandbyInclude:    3,
+		reports.StandbyExclude:    1,
+		reports.StandbyLicensable: 2,
+	} {
+		report.SetStandbyPolicy(policy)
+		rows, err := report.Query(t.Context(), "", "", "", "", "")
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		hosts := map[string]bool{}
+		for _, row := range rows {
+			hosts[row.HostFQDN] = true
+		}
+		if len(hosts) != want {
+			t.Errorf("Expected %d hosts with --standby %s, got %v", want, policy, hosts)
+		}
+	}
+}
+
+func TestNodeDecommission(t *testing.T) {
+	db := setupImportDB(t)
+	root := t.TempDir()
+	service := importer.NewImportService(db)
+	paths := map[string]string{}
+	for _, host := range []string{"host1", "host2"} {
+		paths[host] = filepath.Join(root, "iwdli_output_"+host+"_20251021_090906.csv")
+		writeFile(t, paths[host], testInspectorCSV)
+		if _, err := service.ImportCSVFile(t.Context(), paths[host]); err != nil {
+			t.Fatalf("Import of %s failed: %v", host, err)
+		}
+	}
+
+	editor := importer.NewNodeClassificationEditor(db)
+	if err := editor.Decommission("host1.local", time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Decommission failed: %v", err)
+	}
+	if err := editor.Decommission("unknown.local", time.Now()); err == nil {
+		t.Error("Expected an error decommissioning an unknown node")
+	}
+
+	nodes, err := editor.List(importer.NodeDecommissioned)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].DecommissionedOn == nil || nodes[0].DecommissionedOn.Format("2006-01-02") != "2025-10-20" {
+		t.Fatalf("Unexpected decommissioned nodes: %+v", nodes)
+	}
+
+	// Importing a later measurement of the node warns about it
+	service.Force = true
+	result, err := service.ImportCSVFile(t.Context(), paths["host1"])
+	if err != nil {
+		t.Fatalf("Reimport failed: %v", err)
+	}
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "decommissioned on 2025-10-20") {
+		t.Errorf("Expected a decommission warning, got %v", result.Errors)
+	}
+
+	// The reports leave the node out and explain it below the table
+	report := reports.NewHostDetailReport(db)
+	rows, err := report.Query(t.Context(), "", "", "", "", "")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	for _, row := range rows {
+		if row.HostFQDN == "host1.local" {
+			t.Fatalf("Expected host1.local to be left out, got %+v", row)
+		}
+	}
+	var out bytes.Buffer
+	if err := report.WriteTable(&out, rows); err != nil {
+		t.Fatalf("WriteTable failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "host1.local decommissioned on 2025-10-20") {
+		t.Errorf("Expected a decommission footnote, got:\n%s", out.String())
+	}
+
+	// Classifying the node again takes the decommission date back
+	if err := editor.Classify(importer.NodeActive, "", []string{"host1.local"}); err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+	if rows, err = report.Query(t.Context(), "host1.local", "", "", "", ""); err != nil || len(rows) == 0 {
+		t.Errorf("Expected host1.local to be reported again, got %d rows (%v)", len(rows), err)
+	}
+}
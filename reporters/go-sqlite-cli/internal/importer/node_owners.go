@@ -0,0 +1,88 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// NodeOwner is the contact metadata of a landscape node. Nil fields are left
+// unchanged by SetOwner, empty ones are cleared.
+type NodeOwner struct {
+	Owner      *string
+	Team       *string
+	CostCenter *string
+}
+
+// NodeOwnerEditor manages the owner, team and cost center of the landscape
+// nodes, so usage questions and chargeback reach the right team
+type NodeOwnerEditor struct {
+	db *sql.DB
+}
+
+// NewNodeOwnerEditor creates a new node owner editor
+func NewNodeOwnerEditor(db *sql.DB) *NodeOwnerEditor {
+	return &NodeOwnerEditor{db: db}
+}
+
+// SetOwner sets the given fields of the owner of landscape nodes
+func (e *NodeOwnerEditor) SetOwner(owner NodeOwner, fqdns []string) error {
+	var sets []string
+	var values []interface{}
+	for _, field := range []struct {
+		column string
+		value  *string
+	}{
+		{"owner", owner.Owner},
+		{"team", owner.Team},
+		{"cost_center", owner.CostCenter},
+	} {
+		if field.value != nil {
+			sets = append(sets, field.column+" = ?")
+			values = append(values, strings.TrimSpace(*field.value))
+		}
+	}
+	if len(sets) == 0 {
+		return fmt.Errorf("nothing to set: give an owner, a team or a cost center")
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE landscape_nodes SET " + strings.Join(sets, ", ") + ", updated_at = CURRENT_TIMESTAMP WHERE main_fqdn = ?"
+	for _, fqdn := range fqdns {
+		res, err := tx.Exec(query, append(values, fqdn)...)
+		if err != nil {
+			return fmt.Errorf("failed to set the owner of node %s: %w", fqdn, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("landscape node %s not found (nodes are created by their first import)", fqdn)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestNodeOwners(t *testing.T) {
+	db := setupImportDB(t)
+	root := t.TempDir()
+	service := importer.NewImportService(db)
+	for _, host := range []string{"host1", "host2", "host3"} {
+		path := filepath.Join(root, "iwdli_output_"+host+"_20251021_090906.csv")
+		writeFile(t, path, testInspectorCSV)
+		if _, err := service.ImportCSVFile(t.Context(), path); err != nil {
+			t.Fatalf("Import of %s failed: %v", host, err)
+		}
+	}
+
+	editor := importer.NewNodeOwnerEditor(db)
+	alice, integration, cc := "alice", "Integration", "CC-4711"
+	if err := editor.SetOwner(importer.NodeOwner{Owner: &alice, Team: &integration, CostCenter: &cc},
+		[]string{"host1.local", "host2.local"}); err != nil {
+		t.Fatalf("SetOwner failed: %v", err)
+	}
+	// Only the given fields change
+	bob := "bob"
+	if err := editor.SetOwner(importer.NodeOwner{Owner: &bob}, []string{"host2.local"}); err != nil {
+		t.Fatalf("SetOwner failed: %v", err)
+	}
+	if err := editor.SetOwner(importer.NodeOwner{}, []string{"host1.local"}); err == nil {
+		t.Error("Expected an error setting no field")
+	}
+	if err := editor.SetOwner(importer.NodeOwner{Owner: &bob}, []string{"unknown.local"}); err == nil {
+		t.Error("Expected an error for an unknown node")
+	}
+
+	nodes, err := importer.NewNodeClassificationEditor(db).List("")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(nodes) != 3 || nodes[1].Owner != "bob" || nodes[1].Team != "Integration" || nodes[1].CostCenter != "CC-4711" ||
+		nodes[2].Owner != "" {
+		t.Errorf("Unexpected owners: %+v", nodes)
+	}
+
+	// host-detail --owner matches the owner or the team, case-insensitively
+	report := reports.NewHostDetailReport(db)
+	for filter, want := range map[string]int{"ALICE": 1, "integ*": 2, "bob,carol": 1, "": 3} {
+		report.SetOwner(filter)
+		rows, err := report.Query(t.Context(), "", "", "", "", "")
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		hosts := make(map[string]bool)
+		for _, row := range rows {
+			hosts[row.HostFQDN] = true
+			if row.HostFQDN == "host1.local" && (row.Owner != "alice" || row.CostCenter != "CC-4711") {
+				t.Errorf("Expected the owner of host1.local in its rows, got %+v", row)
+			}
+		}
+		if len(hosts) != want {
+			t.Errorf("Expected %d node(s) owned by %q, got %v", want, filter, hosts)
+		}
+	}
+
+	// The chargeback splits the usage of the term between the cost centers
+	rows, err := reports.NewCostCenterComplianceReport(db).Query(t.Context(), "", "", nil, nil)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) == 0 {
+		t.Fatal("Expected cost center rows")
+	}
+	shares := make(map[string]float64)
+	for _, row := range rows {
+		if row.RunningLicenseCores > 0 {
+			shares[row.CostCenter] = row.TermSharePercent
+		}
+	}
+	if len(shares) != 2 || shares["CC-4711"] < 66 || shares["CC-4711"] > 67 || shares[""] < 33 || shares[""] > 34 {
+		t.Errorf("Expected two thirds of the cores charged to CC-4711, got %v", shares)
+	}
+}
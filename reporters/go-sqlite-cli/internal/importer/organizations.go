@@ -0,0 +1,233 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+)
+
+// OrgEditor manages the organizations (subsidiaries or tenants) sharing the
+// database, whose landscape nodes and entitlements are reported separately
+type OrgEditor struct {
+	db *sql.DB
+}
+
+// NewOrgEditor creates a new organization editor
+func NewOrgEditor(db *sql.DB) *OrgEditor {
+	return &OrgEditor{db: db}
+}
+
+// Save creates the organization or updates the name and description of an
+// existing one. It reports whether the organization was created.
+func (e *OrgEditor) Save(orgID, name, description string) (bool, error) {
+	orgID = strings.TrimSpace(orgID)
+	if orgID == "" {
+		return false, fmt.Errorf("organization ID must not be empty")
+	}
+	if name == "" {
+		name = orgID
+	}
+
+	res, err := e.db.Exec(`
+		UPDATE organizations SET org_name = ?, description = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE org_id = ?
+	`, name, description, orgID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update organization %s: %w", orgID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return false, err
+	}
+
+	_, err = e.db.Exec(`INSERT INTO organizations (org_id, org_name, description) VALUES (?, ?, ?)`, orgID, name, description)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert organization %s: %w", orgID, err)
+	}
+	return true, nil
+}
+
+// Remove deletes an organization with its entitlements. Organizations with
+// nodes are only removed with unassign, which clears the organization of those
+// nodes; it returns their number.
+func (e *OrgEditor) Remove(orgID string, unassign bool) (int, error) {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := RequireOrg(tx, orgID); err != nil {
+		return 0, err
+	}
+
+	var nodes int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM landscape_nodes WHERE org_id = ?", orgID).Scan(&nodes); err != nil {
+		return 0, fmt.Errorf("failed to count nodes of organization %s: %w", orgID, err)
+	}
+	if nodes > 0 && !unassign {
+		return 0, fmt.Errorf("organization %s has %d node(s) (unassign them first)", orgID, nodes)
+	}
+
+	if _, err := tx.Exec("UPDATE landscape_nodes SET org_id = NULL, updated_at = CURRENT_TIMESTAMP WHERE org_id = ?", orgID); err != nil {
+		return 0, fmt.Errorf("failed to unassign nodes of organization %s: %w", orgID, err)
+	}
+	if _, err := tx.Exec("DELETE FROM organizations WHERE org_id = ?", orgID); err != nil {
+		return 0, fmt.Errorf("failed to delete organization %s: %w", orgID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nodes, nil
+}
+
+// Assign sets the organization of the given landscape nodes. An empty orgID
+// clears their organization. All nodes must exist; nothing is changed
+// otherwise.
+func (e *OrgEditor) Assign(orgID string, fqdns []string) error {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var org interface{}
+	if orgID != "" {
+		if err := RequireOrg(tx, orgID); err != nil {
+			return err
+		}
+		org = orgID
+	}
+
+	for _, fqdn := range fqdns {
+		res, err := tx.Exec(`
+			UPDATE landscape_nodes SET org_id = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE main_fqdn = ?
+		`, org, fqdn)
+		if err != nil {
+			return fmt.Errorf("failed to assign node %s: %w", fqdn, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("landscape node %s not found (nodes are created by their first import)", fqdn)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListOrgs returns all organizations with the number of their nodes and of
+// the license terms they have entitlements for
+func (e *OrgEditor) ListOrgs() ([]models.Organization, error) {
+	rows, err := e.db.Query(`
+		SELECT o.org_id, o.org_name, COALESCE(o.description, ''),
+		       (SELECT COUNT(*) FROM landscape_nodes n WHERE n.org_id = o.org_id),
+		       (SELECT COUNT(*) FROM org_entitlements oe WHERE oe.org_id = o.org_id),
+		       o.created_at, o.updated_at
+		FROM organizations o
+		ORDER BY o.org_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var orgs []models.Organization
+	for rows.Next() {
+		var o models.Organization
+		if err := rows.Scan(&o.OrgID, &o.OrgName, &o.Description, &o.NodeCount, &o.TermCount, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+// ListOrgNodes returns the FQDNs of the nodes of an organization; an empty
+// orgID lists the nodes without an organization
+func (e *OrgEditor) ListOrgNodes(orgID string) ([]string, error) {
+	query := "SELECT main_fqdn FROM landscape_nodes WHERE org_id = ? ORDER BY main_fqdn"
+	args := []interface{}{orgID}
+	if orgID == "" {
+		query = "SELECT main_fqdn FROM landscape_nodes WHERE org_id IS NULL ORDER BY main_fqdn"
+		args = nil
+	}
+
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organization nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var fqdns []string
+	for rows.Next() {
+		var fqdn string
+		if err := rows.Scan(&fqdn); err != nil {
+			return nil, fmt.Errorf("failed to scan node: %w", err)
+		}
+		fqdns = append(fqdns, fqdn)
+	}
+	return fqdns, rows.Err()
+}
+
+// queryRower is implemented by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// RequireOrg returns an error unless the organization exists
+func RequireOrg(q queryRower, orgID string) error {
+	var count int
+	if err := q.QueryRow("SELECT COUNT(*) FROM organizations WHERE org_id = ?", orgID).Scan(&count); err != nil {
+		return fmt.Errorf("failed to check organization %s: %w", orgID, err)
+	}
+	if count == 0 {
+		return fmt.Errorf("organization %s not found (add it with 'iwdlr orgs add')", orgID)
+	}
+	return nil
+}
+
+// assignImportedNode puts a node imported with --org in its organization. A
+// node already in another organization is not moved: its import fails, so
+// that a misrouted file cannot shift usage between organizations.
+func assignImportedNode(tx *sql.Tx, mainFQDN, orgID string) error {
+	var current sql.NullString
+	if err := tx.QueryRow("SELECT org_id FROM landscape_nodes WHERE main_fqdn = ?", mainFQDN).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read the organization of %s: %w", mainFQDN, err)
+	}
+	if current.Valid && current.String != orgID {
+		return fmt.Errorf("node %s belongs to organization %s, not %s (move it with 'iwdlr orgs assign')",
+			mainFQDN, current.String, orgID)
+	}
+	if current.Valid {
+		return nil
+	}
+
+	if _, err := tx.Exec("UPDATE landscape_nodes SET org_id = ?, updated_at = CURRENT_TIMESTAMP WHERE main_fqdn = ?",
+		orgID, mainFQDN); err != nil {
+		return fmt.Errorf("failed to assign node %s to organization %s: %w", mainFQDN, orgID, err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestOrgEditor(t *testing.T) {
+	db := setupImportDB(t)
+	for _, fqdn := range []string{"n1.local", "n2.local", "n3.local"} {
+		if _, err := db.Exec(`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES (?, ?, 'PROD')`, fqdn, fqdn); err != nil {
+			t.Fatalf("Failed to insert node: %v", err)
+		}
+	}
+
+	editor := importer.NewOrgEditor(db)
+	if created, err := editor.Save("acme", "", ""); err != nil || !created {
+		t.Fatalf("Expected organization acme to be created, got %v, %v", created, err)
+	}
+	if created, err := editor.Save("acme", "ACME GmbH", "Subsidiary"); err != nil || created {
+		t.Fatalf("Expected organization acme to be updated, got %v, %v", created, err)
+	}
+
+	if err := editor.Assign("beta", []string{"n1.local"}); err == nil {
+		t.Error("Expected an error assigning to an unknown organization")
+	}
+	if err := editor.Assign("acme", []string{"n1.local", "missing.local"}); err == nil {
+		t.Error("Expected an error assigning an unknown node")
+	}
+	if err := editor.Assign("acme", []string{"n1.local", "n2.local"}); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	if err := editor.Assign("", []string{"n2.local"}); err != nil {
+		t.Fatalf("Unassign failed: %v", err)
+	}
+
+	orgs, err := editor.ListOrgs()
+	if err != nil {
+		t.Fatalf("ListOrgs failed: %v", err)
+	}
+	if len(orgs) != 1 || orgs[0].OrgName != "ACME GmbH" || orgs[0].Description != "Subsidiary" || orgs[0].NodeCount != 1 {
+		t.Errorf("Unexpected organizations: %+v", orgs)
+	}
+
+	unassigned, err := editor.ListOrgNodes("")
+	if err != nil {
+		t.Fatalf("ListOrgNodes failed: %v", err)
+	}
+	if strings.Join(unassigned, ",") != "n2.local,n3.local" {
+		t.Errorf("Expected n2 and n3 without an organization, got %v", unassigned)
+	}
+
+	if _, err := editor.Remove("acme", false); err == nil {
+		t.Error("Expected an error removing an organization with nodes")
+	}
+	nodes, err := editor.Remove("acme", true)
+	if err != nil || nodes != 1 {
+		t.Fatalf("Expected 1 node unassigned, got %d, %v", nodes, err)
+	}
+	if unassigned, _ := editor.ListOrgNodes(""); len(unassigned) != 3 {
+		t.Errorf("Expected all nodes without an organization, got %v", unassigned)
+	}
+}
+
+func TestLoadOrgEntitlementsCSV(t *testing.T) {
+	db := setupImportDB(t)
+	csvPath := filepath.Join(t.TempDir(), "entitlements.csv")
+	writeFile(t, csvPath, "license-terms-id,licensed-cores,licensed-pvu,notes\nT1,16,0,\n")
+
+	loader := importer.NewReferenceDataLoader(db)
+	if err := loader.LoadOrgEntitlementsCSV(t.Context(), csvPath, "acme"); err == nil {
+		t.Error("Expected an error loading entitlements of an unknown organization")
+	}
+
+	if _, err := importer.NewOrgEditor(db).Save("acme", "", ""); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := loader.LoadOrgEntitlementsCSV(t.Context(), csvPath, "acme"); err != nil {
+		t.Fatalf("LoadOrgEntitlementsCSV failed: %v", err)
+	}
+
+	var cores, shared int
+	db.QueryRow("SELECT licensed_cores FROM org_entitlements WHERE org_id = 'acme' AND term_id = 'T1'").Scan(&cores)
+	if cores != 16 {
+		t.Errorf("Expected 16 licensed cores for acme, got %d", cores)
+	}
+	// The entitlements of the whole database are left alone
+	db.QueryRow("SELECT COUNT(*) FROM entitlements").Scan(&shared)
+	if shared != 0 {
+		t.Errorf("Expected no shared entitlements, got %d", shared)
+	}
+}
+
+func TestImportAssignsOrganization(t *testing.T) {
+	db := setupImportDB(t)
+	editor := importer.NewOrgEditor(db)
+	for _, org := range []string{"acme", "beta"} {
+		if _, err := editor.Save(org, "", ""); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "iwdli_output_host1_20251021_090906.csv")
+	writeFile(t, path, testInspectorCSV)
+
+	service := importer.NewImportService(db)
+	service.Org = "acme"
+	if _, err := service.ImportCSVFile(t.Context(), path); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	var org string
+	if err := db.QueryRow("SELECT org_id FROM landscape_nodes").Scan(&org); err != nil || org != "acme" {
+		t.Fatalf("Expected the node in acme, got %q, %v", org, err)
+	}
+
+	// A node of another organization is not moved by an import
+	later := filepath.Join(t.TempDir(), "iwdli_output_host1_20251022_090906.csv")
+	writeFile(t, later, strings.Replace(testInspectorCSV, "2025-10-21", "2025-10-22", 1))
+	service.Org = "beta"
+	if _, err := service.ImportCSVFile(t.Context(), later); err == nil || !strings.Contains(err.Error(), "belongs to organization acme") {
+		t.Errorf("Expected an error importing a node of acme into beta, got %v", err)
+	}
+}
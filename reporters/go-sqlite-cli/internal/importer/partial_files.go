@@ -0,0 +1,79 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// PartialFileError is returned for inspector files that end before the
+// inspector finished writing them: an empty file, a file holding only the
+// header, a last line cut in the middle, or a file of an inspector declaring
+// CSV_FORMAT_VERSION without the DETECTION_RESULT field it writes last. Such
+// files are usually still being written or copied and import once complete.
+type PartialFileError struct {
+	Reason string
+}
+
+func (e *PartialFileError) Error() string {
+	return "partial file, still being written? " + e.Reason
+}
+
+// IsPartialFile reports whether err is, or wraps, a PartialFileError
+func IsPartialFile(err error) bool {
+	var partial *PartialFileError
+	return errors.As(err, &partial)
+}
+
+// tailReader remembers the last byte read, so that the parser can tell a file
+// ending with a complete line from one cut in the middle of a line
+type tailReader struct {
+	r    io.Reader
+	last byte
+	read bool
+}
+
+func (t *tailReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.last = p[n-1]
+		t.read = true
+	}
+	return n, err
+}
+
+// endsWithNewline reports whether the content read so far ends a line
+func (t *tailReader) endsWithNewline() bool {
+	return t.read && t.last == '\n'
+}
+
+// SplitStableFiles separates the files last modified at least stableFor
+// before now from those modified since, which may still be being written.
+// Files that cannot be read are left with the stable ones, so that the import
+// reports them.
+func SplitStableFiles(files []string, stableFor time.Duration, now time.Time) (stable, changing []string) {
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err == nil && now.Sub(info.ModTime()) < stableFor {
+			changing = append(changing, file)
+			continue
+		}
+		stable = append(stable, file)
+	}
+	return stable, changing
+}
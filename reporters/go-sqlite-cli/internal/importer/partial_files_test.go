@@ -0,0 +1,89 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestPartialFilesAreDeferred(t *testing.T) {
+	db := setupImportDB(t)
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"empty":      "",
+		"header":     "Parameter,Value\n",
+		"cut":        testInspectorCSV[:strings.LastIndex(testInspectorCSV, ",")],
+		"no-trailer": testInspectorCSV + "CSV_FORMAT_VERSION,2\n",
+		"complete":   testInspectorCSV + "CSV_FORMAT_VERSION,2\nDETECTION_RESULT,SUCCESS\n",
+		"no-version": testInspectorCSV,
+	}
+	var paths []string
+	for _, name := range []string{"empty", "header", "cut", "no-trailer", "complete", "no-version"} {
+		path := filepath.Join(dir, "iwdli_output_"+name+"_20251021_090906.csv")
+		writeFile(t, path, files[name])
+		paths = append(paths, path)
+	}
+
+	service := importer.NewImportService(db)
+	service.DeferPartial = true
+	batch := service.ImportFiles(t.Context(), paths, nil)
+
+	if batch.FilesDeferred != 4 || batch.FilesOK != 2 || batch.FilesFailed != 0 {
+		t.Fatalf("Expected 4 deferred and 2 imported files, got %d deferred, %d imported, %d failed",
+			batch.FilesDeferred, batch.FilesOK, batch.FilesFailed)
+	}
+	for _, fr := range batch.Files[:4] {
+		if fr.Deferred == nil || fr.Err != nil {
+			t.Errorf("Expected %s to be deferred, got %+v", filepath.Base(fr.FilePath), fr)
+		}
+	}
+	if failed, _ := service.ListFailedImports(); len(failed) != 0 {
+		t.Errorf("Expected no failed import recorded, got %+v", failed)
+	}
+
+	// Without DeferPartial, a partial file fails and is recorded
+	service.DeferPartial = false
+	batch = service.ImportFiles(t.Context(), paths[2:3], nil)
+	if batch.FilesFailed != 1 || !importer.IsPartialFile(batch.Files[0].Err) {
+		t.Errorf("Expected a partial file error, got %+v", batch.Files[0])
+	}
+	if failed, _ := service.ListFailedImports(); len(failed) != 1 {
+		t.Errorf("Expected the failed import to be recorded, got %+v", failed)
+	}
+}
+
+func TestSplitStableFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.csv")
+	recent := filepath.Join(dir, "recent.csv")
+	writeFile(t, old, "x")
+	writeFile(t, recent, "x")
+	now := time.Now()
+	if err := os.Chtimes(old, now.Add(-time.Hour), now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	stable, changing := importer.SplitStableFiles([]string{old, recent, filepath.Join(dir, "missing.csv")}, time.Minute, now)
+	if len(stable) != 2 || stable[0] != old || len(changing) != 1 || changing[0] != recent {
+		t.Errorf("Unexpected split: stable %v, changing %v", stable, changing)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+)
+
+// Operations recorded in physical_host_merges
+const (
+	HostOperationRename = "rename"
+	HostOperationMerge  = "merge"
+)
+
+// PhysicalHostEditor corrects physical host IDs, e.g. when the VMware UUID and the
+// hostname methods produced two IDs for the same chassis. Every change updates the
+// measurements referencing the host, is recorded in physical_host_merges and leaves
+// an alias so that later imports reporting the old ID are stored under the new one.
+type PhysicalHostEditor struct {
+	db          *sql.DB
+	PerformedBy string // user recorded in the audit trail
+}
+
+// NewPhysicalHostEditor creates a new physical host editor
+func NewPhysicalHostEditor(db *sql.DB) *PhysicalHostEditor {
+	return &PhysicalHostEditor{db: db}
+}
+
+// Rename changes the ID of a physical host. The new ID must not be in use yet;
+// use Merge to combine two known hosts.
+func (e *PhysicalHostEditor) Rename(oldID, newID, reason string) (*models.PhysicalHostMerge, error) {
+	if err := checkHostIDs(oldID, newID); err != nil {
+		return nil, err
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := requirePhysicalHost(tx, oldID, true); err != nil {
+		return nil, err
+	}
+	if err := requirePhysicalHost(tx, newID, false); err != nil {
+		return nil, fmt.Errorf("%w (use merge to combine the two hosts)", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE physical_hosts
+		SET physical_host_id = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE physical_host_id = ?
+	`, newID, oldID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename physical host: %w", err)
+	}
+
+	return e.finish(tx, HostOperationRename, oldID, newID, reason)
+}
+
+// Merge folds the source host into the target host. The target keeps its ID
+// detection method and confidence and takes the earliest first_seen, the latest
+// last_seen and the larger max_physical_cpus of both hosts. The source host is deleted.
+func (e *PhysicalHostEditor) Merge(sourceID, targetID, reason string) (*models.PhysicalHostMerge, error) {
+	if err := checkHostIDs(sourceID, targetID); err != nil {
+		return nil, err
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := requirePhysicalHost(tx, sourceID, true); err != nil {
+		return nil, err
+	}
+	if err := requirePhysicalHost(tx, targetID, true); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE physical_hosts AS t
+		SET first_seen = MIN(t.first_seen, s.first_seen),
+		    last_seen = MAX(t.last_seen, s.last_seen),
+		    max_physical_cpus = CASE
+		        WHEN t.max_physical_cpus IS NULL OR t.max_physical_cpus < s.max_physical_cpus THEN s.max_physical_cpus
+		        ELSE t.max_physical_cpus
+		    END,
+		    updated_at = CURRENT_TIMESTAMP
+		FROM physical_hosts AS s
+		WHERE t.physical_host_id = ? AND s.physical_host_id = ?
+	`, targetID, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update target physical host: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM physical_hosts WHERE physical_host_id = ?", sourceID); err != nil {
+		return nil, fmt.Errorf("failed to delete source physical host: %w", err)
+	}
+
+	return e.finish(tx, HostOperationMerge, sourceID, targetID, reason)
+}
+
+// finish moves the measurements and aliases of sourceID to targetID, records the
+// operation and commits the transaction
+func (e *PhysicalHostEditor) finish(tx *sql.Tx, operation, sourceID, targetID, reason string) (*models.PhysicalHostMerge, error) {
+	res, err := tx.Exec("UPDATE measurements SET physical_host_id = ? WHERE physical_host_id = ?", targetID, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update measurements: %w", err)
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep aliases flat: earlier aliases of the source now point to the target,
+	// and the target is a real host ID again if it was an alias before
+	if _, err := tx.Exec("UPDATE physical_host_aliases SET physical_host_id = ? WHERE physical_host_id = ?", targetID, sourceID); err != nil {
+		return nil, fmt.Errorf("failed to update physical host aliases: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO physical_host_aliases (alias_id, physical_host_id)
+		VALUES (?, ?)
+		ON CONFLICT(alias_id) DO UPDATE SET physical_host_id = excluded.physical_host_id
+	`, sourceID, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record physical host alias: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM physical_host_aliases WHERE alias_id = ?", targetID); err != nil {
+		return nil, fmt.Errorf("failed to update physical host aliases: %w", err)
+	}
+
+	res, err = tx.Exec(`
+		INSERT INTO physical_host_merges
+		(operation, source_host_id, target_host_id, measurements_updated, reason, performed_by)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, operation, sourceID, targetID, updated, reason, e.PerformedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record physical host %s: %w", operation, err)
+	}
+	mergeID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	merge := &models.PhysicalHostMerge{}
+	err = tx.QueryRow(`
+		SELECT merge_id, operation, source_host_id, target_host_id, measurements_updated, reason, performed_by, performed_at
+		FROM physical_host_merges
+		WHERE merge_id = ?
+	`, mergeID).Scan(&merge.MergeID, &merge.Operation, &merge.SourceHostID, &merge.TargetHostID,
+		&merge.MeasurementsUpdated, &merge.Reason, &merge.PerformedBy, &merge.PerformedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read physical host %s: %w", operation, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return merge, nil
+}
+
+// ListMerges returns the audit trail of renames and merges, oldest first
+func (e *PhysicalHostEditor) ListMerges() ([]models.PhysicalHostMerge, error) {
+	rows, err := e.db.Query(`
+		SELECT merge_id, operation, source_host_id, target_host_id, measurements_updated, reason, performed_by, performed_at
+		FROM physical_host_merges
+		ORDER BY merge_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query physical host merges: %w", err)
+	}
+	defer rows.Close()
+
+	var merges []models.PhysicalHostMerge
+	for rows.Next() {
+		var m models.PhysicalHostMerge
+		err := rows.Scan(&m.MergeID, &m.Operation, &m.SourceHostID, &m.TargetHostID,
+			&m.MeasurementsUpdated, &m.Reason, &m.PerformedBy, &m.PerformedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan physical host merge: %w", err)
+		}
+		merges = append(merges, m)
+	}
+
+	return merges, rows.Err()
+}
+
+// checkHostIDs validates the source and target IDs of a rename or merge
+func checkHostIDs(sourceID, targetID string) error {
+	if sourceID == "" || targetID == "" || sourceID == "unknown" || targetID == "unknown" {
+		return fmt.Errorf("physical host IDs must not be empty or unknown")
+	}
+	if sourceID == targetID {
+		return fmt.Errorf("source and target physical host ID are the same: %s", sourceID)
+	}
+	return nil
+}
+
+// requirePhysicalHost checks whether a physical host exists or not
+func requirePhysicalHost(tx *sql.Tx, physicalHostID string, exists bool) error {
+	var count int
+	err := tx.QueryRow("SELECT COUNT(*) FROM physical_hosts WHERE physical_host_id = ?", physicalHostID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to look up physical host %s: %w", physicalHostID, err)
+	}
+	if exists && count == 0 {
+		return fmt.Errorf("physical host %s not found", physicalHostID)
+	}
+	if !exists && count > 0 {
+		return fmt.Errorf("physical host %s already exists", physicalHostID)
+	}
+	return nil
+}
+
+// resolvePhysicalHostAlias replaces a renamed or merged physical host ID of the
+// record with the ID it was renamed or merged into
+func resolvePhysicalHostAlias(tx *sql.Tx, record *CSVRecord) error {
+	var physicalHostID string
+	err := tx.QueryRow("SELECT physical_host_id FROM physical_host_aliases WHERE alias_id = ?",
+		record.GetSystemField("PHYSICAL_HOST_ID")).Scan(&physicalHostID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve physical host alias: %w", err)
+	}
+
+	record.SetSystemField("PHYSICAL_HOST_ID", physicalHostID)
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestPhysicalHostMergeAndRename(t *testing.T) {
+	db := setupImportDB(t)
+	service := importer.NewImportService(db)
+	root := t.TempDir()
+
+	// Two VMs on the same chassis, identified by different methods
+	files := map[string]string{
+		"iwdli_output_vm1_20251021_090906.csv": "PHYSICAL_HOST_ID,vmware-uuid-42\nHOST_PHYSICAL_CPUS,32\n",
+		"iwdli_output_vm2_20251021_090906.csv": "PHYSICAL_HOST_ID,esx01.local\nHOST_PHYSICAL_CPUS,16\n",
+	}
+	var paths []string
+	for name, extra := range files {
+		path := filepath.Join(root, name)
+		writeFile(t, path, testInspectorCSV+extra)
+		paths = append(paths, path)
+	}
+	if batch := service.ImportFiles(t.Context(), paths, nil); batch.FilesOK != 2 {
+		t.Fatalf("Expected 2 imported files, got %+v", batch.Files)
+	}
+
+	editor := importer.NewPhysicalHostEditor(db)
+	editor.PerformedBy = "tester"
+
+	// Renaming onto an existing host must be a merge
+	if _, err := editor.Rename("esx01.local", "vmware-uuid-42", ""); err == nil {
+		t.Error("Expected rename onto an existing host to fail")
+	}
+
+	merge, err := editor.Merge("esx01.local", "vmware-uuid-42", "same chassis")
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if merge.Operation != importer.HostOperationMerge || merge.MeasurementsUpdated != 1 || merge.PerformedBy != "tester" {
+		t.Errorf("Unexpected merge record: %+v", merge)
+	}
+
+	var hosts, cpus int
+	if err := db.QueryRow("SELECT COUNT(*), MAX(max_physical_cpus) FROM physical_hosts").Scan(&hosts, &cpus); err != nil {
+		t.Fatalf("Failed to query physical hosts: %v", err)
+	}
+	if hosts != 1 || cpus != 32 {
+		t.Errorf("Expected 1 physical host with 32 CPUs, got %d hosts with %d CPUs", hosts, cpus)
+	}
+
+	if _, err := editor.Rename("vmware-uuid-42", "chassis-01", "inventory name"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+
+	// A later import reporting the merged ID is stored under the final ID
+	later := filepath.Join(root, "iwdli_output_vm2_20251022_090906.csv")
+	content := strings.Replace(testInspectorCSV, "2025-10-21T09:09:06Z", "2025-10-22T09:09:06Z", 1)
+	writeFile(t, later, content+"PHYSICAL_HOST_ID,esx01.local\n")
+	if _, err := service.ImportCSVFile(t.Context(), later); err != nil {
+		t.Fatalf("Import after merge failed: %v", err)
+	}
+
+	var onChassis, total int
+	err = db.QueryRow(`
+		SELECT SUM(physical_host_id = 'chassis-01'), COUNT(*) FROM measurements
+	`).Scan(&onChassis, &total)
+	if err != nil {
+		t.Fatalf("Failed to query measurements: %v", err)
+	}
+	if onChassis != 3 || total != 3 {
+		t.Errorf("Expected all 3 measurements on chassis-01, got %d of %d", onChassis, total)
+	}
+
+	merges, err := editor.ListMerges()
+	if err != nil {
+		t.Fatalf("ListMerges failed: %v", err)
+	}
+	if len(merges) != 2 || merges[1].Operation != importer.HostOperationRename || merges[1].MeasurementsUpdated != 2 {
+		t.Errorf("Unexpected audit trail: %+v", merges)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// refreshProductInstances recomputes the instances of every product of a node,
+// one per install path, from its detected installs and processes. An instance
+// is running in a detection when a process command line of the product
+// contains its install path, followed by a path separator, a space or a quote
+// so that /opt/IS1 does not match /opt/IS10. Like the product lifecycle it runs
+// after each import, rollback and alias of the node.
+func refreshProductInstances(tx *sql.Tx, mainFQDN string) error {
+	if _, err := tx.Exec("DELETE FROM product_instances WHERE main_fqdn = ?", mainFQDN); err != nil {
+		return fmt.Errorf("failed to clear product instances of %s: %w", mainFQDN, err)
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO product_instances (
+			main_fqdn, product_mnemo_code, install_path, first_detected, last_detected,
+			last_running, detection_count, running_count
+		)
+		SELECT
+			main_fqdn, product_mnemo_code, install_path,
+			MIN(detection_timestamp), MAX(detection_timestamp),
+			MAX(CASE WHEN running THEN detection_timestamp END),
+			COUNT(DISTINCT detection_timestamp),
+			COUNT(DISTINCT CASE WHEN running THEN detection_timestamp END)
+		FROM (
+			SELECT
+				i.main_fqdn, i.product_mnemo_code, i.install_path, i.detection_timestamp,
+				EXISTS (
+					SELECT 1 FROM detected_product_processes p
+					WHERE p.main_fqdn = i.main_fqdn
+					  AND p.product_mnemo_code = i.product_mnemo_code
+					  AND p.detection_timestamp = i.detection_timestamp
+					  AND (instr(p.commandline || ' ', i.install_path || ' ') > 0
+						OR instr(p.commandline, i.install_path || '/') > 0
+						OR instr(p.commandline, i.install_path || '\') > 0
+						OR instr(p.commandline, i.install_path || '"') > 0)
+				) AS running
+			FROM detected_product_installs i
+			WHERE i.main_fqdn = ?
+		)
+		GROUP BY main_fqdn, product_mnemo_code, install_path
+	`, mainFQDN)
+	if err != nil {
+		return fmt.Errorf("failed to update product instances of %s: %w", mainFQDN, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestProductInstances(t *testing.T) {
+	db := setupImportDB(t)
+	service := importer.NewImportService(db)
+	root := t.TempDir()
+
+	files := []struct{ name, content string }{
+		{"iwdli_output_host1_20251021_090906.csv", testInspectorCSV +
+			"IS_ONP_PRD_INSTALL_PATH_01,/opt/IS1\n" +
+			"IS_ONP_PRD_INSTALL_PATH_02,/opt/IS10\n" +
+			"IS_ONP_PRD_INSTALL_PATH_03,/opt/IS2\n" +
+			"IS_ONP_PRD_RUNNING_COMMANDLINES_01,/opt/IS1/jvm/bin/java -server\n"},
+		// /opt/IS2 was uninstalled, and /opt/IS10 runs instead of /opt/IS1
+		{"iwdli_output_host1_20251022_090906.csv", strings.Replace(testInspectorCSV, "2025-10-21", "2025-10-22", 1) +
+			"IS_ONP_PRD_INSTALL_PATH_01,/opt/IS1\n" +
+			"IS_ONP_PRD_INSTALL_PATH_02,/opt/IS10\n" +
+			"IS_ONP_PRD_RUNNING_COMMANDLINES_01,/opt/IS10/jvm/bin/java -server\n"},
+	}
+	var sessions []string
+	for _, f := range files {
+		path := filepath.Join(root, f.name)
+		writeFile(t, path, f.content)
+		result, err := service.ImportCSVFile(t.Context(), path)
+		if err != nil {
+			t.Fatalf("Failed to import %s: %v", f.name, err)
+		}
+		sessions = append(sessions, result.SessionID)
+	}
+
+	report := reports.NewInstanceReport(db)
+	rows, err := report.Query(t.Context(), "host1", "", "", "")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 installed instances, got %+v", rows)
+	}
+	if rows[0].InstallPath != "/opt/IS1" || rows[0].Status != reports.InstanceDormant ||
+		rows[0].LastRunning != "2025-10-21 09:09:06" || rows[0].RunningCount != 1 || rows[0].DetectionCount != 2 {
+		t.Errorf("Expected /opt/IS1 to be dormant, got %+v", rows[0])
+	}
+	if rows[1].InstallPath != "/opt/IS10" || rows[1].Status != reports.InstanceRunning || rows[1].RunningCount != 1 {
+		t.Errorf("Expected /opt/IS10 to be running, got %+v", rows[1])
+	}
+
+	rows, err = report.Query(t.Context(), "", "", "", reports.InstanceRemoved)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0].InstallPath != "/opt/IS2" || rows[0].LastRunning != "" {
+		t.Errorf("Expected /opt/IS2 to be removed, got %+v", rows)
+	}
+
+	// Rolling back the second measurement restores the state of the first one
+	if _, err := service.Rollback(t.Context(), sessions[1], false); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	rows, err = report.Query(t.Context(), "", "", "", reports.InstanceRunning)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0].InstallPath != "/opt/IS1" {
+		t.Errorf("Expected only /opt/IS1 to be running after rollback, got %+v", rows)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// refreshProductLifecycle recomputes the first and last detection of every
+// product of a node from its detected products. It runs after each import,
+// rollback and alias of the node, so files imported out of order and removed
+// measurements are accounted for.
+func refreshProductLifecycle(tx *sql.Tx, mainFQDN string) error {
+	if _, err := tx.Exec("DELETE FROM product_lifecycle WHERE main_fqdn = ?", mainFQDN); err != nil {
+		return fmt.Errorf("failed to clear product lifecycle of %s: %w", mainFQDN, err)
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO product_lifecycle (main_fqdn, product_mnemo_code, first_detected, last_detected, detection_count)
+		SELECT main_fqdn, product_mnemo_code, MIN(detection_timestamp), MAX(detection_timestamp), COUNT(*)
+		FROM detected_products
+		WHERE main_fqdn = ? AND status = 'present'
+		GROUP BY main_fqdn, product_mnemo_code
+	`, mainFQDN)
+	if err != nil {
+		return fmt.Errorf("failed to update product lifecycle of %s: %w", mainFQDN, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestProductLifecycle(t *testing.T) {
+	db := setupImportDB(t)
+	service := importer.NewImportService(db)
+	root := t.TempDir()
+
+	withoutProduct := strings.Replace(testInspectorCSV, "IS_ONP_PRD,present\nIS_ONP_PRD_INSTALL_COUNT,1\n", "", 1)
+	files := []struct{ name, content string }{
+		// Imported out of order: the product is gone on the 23rd
+		{"iwdli_output_host1_20251023_090906.csv", strings.Replace(withoutProduct, "2025-10-21", "2025-10-23", 1)},
+		{"iwdli_output_host1_20251022_090906.csv", strings.Replace(testInspectorCSV, "2025-10-21", "2025-10-22", 1)},
+		{"iwdli_output_host1_20251021_090906.csv", testInspectorCSV},
+	}
+	var sessions []string
+	for _, f := range files {
+		path := filepath.Join(root, f.name)
+		writeFile(t, path, f.content)
+		result, err := service.ImportCSVFile(t.Context(), path)
+		if err != nil {
+			t.Fatalf("Failed to import %s: %v", f.name, err)
+		}
+		sessions = append(sessions, result.SessionID)
+	}
+
+	report := reports.NewProductHistoryReport(db)
+	rows, err := report.Query(t.Context(), "host1", "", "", nil, nil)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("Expected 1 row, got %+v", rows)
+	}
+	row := rows[0]
+	if row.FirstDetected != "2025-10-21 09:09:06" || row.LastDetected != "2025-10-22 09:09:06" ||
+		row.DisappearedAt != "2025-10-23 09:09:06" || row.DetectionCount != 2 || row.Status != reports.ProductHistoryGone {
+		t.Errorf("Unexpected product history: %+v", row)
+	}
+
+	// The product disappeared in the period, and appeared before it
+	from := time.Date(2025, 10, 23, 0, 0, 0, 0, time.UTC)
+	if rows, err := report.Query(t.Context(), "", "", "", &from, nil); err != nil || len(rows) != 1 {
+		t.Errorf("Expected the disappearance on the 23rd, got %+v (%v)", rows, err)
+	}
+	to := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	if rows, err := report.Query(t.Context(), "", "", "", nil, &to); err != nil || len(rows) != 0 {
+		t.Errorf("Expected nothing before the 21st, got %+v (%v)", rows, err)
+	}
+
+	// Rolling back the measurement without the product makes it present again,
+	// and rolling back its first detection moves first_detected
+	for _, session := range []string{sessions[0], sessions[2]} {
+		if _, err := service.Rollback(t.Context(), session, false); err != nil {
+			t.Fatalf("Rollback failed: %v", err)
+		}
+	}
+	rows, err = report.Query(t.Context(), "", "", "", nil, nil)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0].FirstDetected != "2025-10-22 09:09:06" || rows[0].DetectionCount != 1 || rows[0].Status != reports.ProductHistoryPresent {
+		t.Errorf("Unexpected product history after rollback: %+v", rows)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+)
+
+// ProductTermMappingEditor manages the effective-dated license terms of the
+// product codes. A product moved to another program mid-year is mapped to its
+// former term up to the move, so that the reports of the earlier days are not
+// rewritten under the new term. On days without a mapping the term_id of the
+// product code applies; the periods of the mappings of a product do not overlap.
+type ProductTermMappingEditor struct {
+	db *sql.DB
+	// CreatedBy is recorded as the user who added the mappings
+	CreatedBy string
+}
+
+// NewProductTermMappingEditor creates a new product term mapping editor
+func NewProductTermMappingEditor(db *sql.DB) *ProductTermMappingEditor {
+	return &ProductTermMappingEditor{db: db}
+}
+
+// Map adds a mapping and sets its MappingID. A mapping of the product still in
+// force from an earlier date is superseded: it ends the day before the new one
+// starts. Map returns the ID of the superseded mapping, or 0.
+func (e *ProductTermMappingEditor) Map(mapping *models.ProductTermMapping) (int64, error) {
+	if mapping.EffectiveTo != nil && mapping.EffectiveTo.Before(mapping.EffectiveFrom) {
+		return 0, fmt.Errorf("effective end date %s is before the start date %s",
+			mapping.EffectiveTo.Format("2006-01-02"), mapping.EffectiveFrom.Format("2006-01-02"))
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM product_codes WHERE product_mnemo_code = ?", mapping.ProductMnemoCode).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("failed to check product code %s: %w", mapping.ProductMnemoCode, err)
+	}
+	if exists == 0 {
+		return 0, fmt.Errorf("product code %s does not exist; load the reference data first", mapping.ProductMnemoCode)
+	}
+	if err := tx.QueryRow("SELECT COUNT(*) FROM license_terms WHERE term_id = ?", mapping.TermID).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("failed to check license term %s: %w", mapping.TermID, err)
+	}
+	if exists == 0 {
+		return 0, fmt.Errorf("license term %s does not exist; load the reference data first", mapping.TermID)
+	}
+
+	from := mapping.EffectiveFrom.Format("2006-01-02")
+	to := openEnded
+	if mapping.EffectiveTo != nil {
+		to = mapping.EffectiveTo.Format("2006-01-02")
+	}
+
+	// Supersede the mapping in force before the new one
+	var superseded int64
+	err = tx.QueryRow(`
+		SELECT mapping_id FROM product_term_mappings
+		WHERE product_mnemo_code = ? AND effective_to IS NULL AND effective_from < ?
+	`, mapping.ProductMnemoCode, from).Scan(&superseded)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to read the mappings of product %s: %w", mapping.ProductMnemoCode, err)
+	}
+	if superseded != 0 {
+		end := mapping.EffectiveFrom.AddDate(0, 0, -1).Format("2006-01-02")
+		if _, err := tx.Exec("UPDATE product_term_mappings SET effective_to = ? WHERE mapping_id = ?", end, superseded); err != nil {
+			return 0, fmt.Errorf("failed to supersede mapping %d: %w", superseded, err)
+		}
+	}
+
+	var overlapID int64
+	var overlapTerm, overlapFrom, overlapTo string
+	err = tx.QueryRow(`
+		SELECT mapping_id, term_id, strftime('%Y-%m-%d', effective_from), COALESCE(strftime('%Y-%m-%d', effective_to), 'open')
+		FROM product_term_mappings
+		WHERE product_mnemo_code = ? AND effective_from <= ? AND COALESCE(effective_to, ?) >= ?
+		ORDER BY effective_from
+		LIMIT 1
+	`, mapping.ProductMnemoCode, to, openEnded, from).Scan(&overlapID, &overlapTerm, &overlapFrom, &overlapTo)
+	if err == nil {
+		return 0, fmt.Errorf("mapping %d already maps product %s to term %s from %s to %s; remove it or choose other dates",
+			overlapID, mapping.ProductMnemoCode, overlapTerm, overlapFrom, overlapTo)
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to check the mappings of product %s: %w", mapping.ProductMnemoCode, err)
+	}
+
+	var effectiveTo interface{}
+	if mapping.EffectiveTo != nil {
+		effectiveTo = to
+	}
+	res, err := tx.Exec(`
+		INSERT INTO product_term_mappings (product_mnemo_code, term_id, effective_from, effective_to, notes, created_by)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, mapping.ProductMnemoCode, mapping.TermID, from, effectiveTo, mapping.Notes, e.CreatedBy)
+	if err != nil {
+		return 0, fmt.Errorf("failed to map product %s to term %s: %w", mapping.ProductMnemoCode, mapping.TermID, err)
+	}
+	if mapping.MappingID, err = res.LastInsertId(); err != nil {
+		return 0, err
+	}
+	mapping.CreatedBy = e.CreatedBy
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return superseded, nil
+}
+
+// Remove deletes a mapping; the product is reported under the term of its
+// product code on the days it covered
+func (e *ProductTermMappingEditor) Remove(mappingID int64) error {
+	res, err := e.db.Exec("DELETE FROM product_term_mappings WHERE mapping_id = ?", mappingID)
+	if err != nil {
+		return fmt.Errorf("failed to remove mapping %d: %w", mappingID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("mapping %d does not exist", mappingID)
+	}
+	return nil
+}
+
+// List returns the mappings of a product code, or of all product codes when
+// productCode is empty, ordered by product code and effective date
+func (e *ProductTermMappingEditor) List(productCode string) ([]models.ProductTermMapping, error) {
+	query := `
+		SELECT mapping_id, product_mnemo_code, term_id, effective_from, effective_to,
+			COALESCE(notes, ''), COALESCE(created_by, ''), created_at
+		FROM product_term_mappings
+	`
+	args := []interface{}{}
+	if productCode != "" {
+		query += " WHERE product_mnemo_code = ?"
+		args = append(args, productCode)
+	}
+	query += " ORDER BY product_mnemo_code, effective_from"
+
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list product term mappings: %w", err)
+	}
+	defer rows.Close()
+
+	var mappings []models.ProductTermMapping
+	for rows.Next() {
+		var m models.ProductTermMapping
+		var effectiveTo sql.NullTime
+		if err := rows.Scan(&m.MappingID, &m.ProductMnemoCode, &m.TermID, &m.EffectiveFrom, &effectiveTo,
+			&m.Notes, &m.CreatedBy, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan product term mapping: %w", err)
+		}
+		if effectiveTo.Valid {
+			m.EffectiveTo = &effectiveTo.Time
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, rows.Err()
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestProductTermMappingEditor(t *testing.T) {
+	db := setupImportDB(t)
+	if _, err := db.Exec(`INSERT INTO license_terms (term_id, program_number, program_name) VALUES ('T2', '5900-BBB', 'New Program')`); err != nil {
+		t.Fatalf("Failed to insert license term: %v", err)
+	}
+	editor := importer.NewProductTermMappingEditor(db)
+
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	// IS_ONP_PRD moved from T1 to T2 on 2025-10-22
+	first := &models.ProductTermMapping{ProductMnemoCode: "IS_ONP_PRD", TermID: "T1", EffectiveFrom: day("2025-01-01")}
+	if _, err := editor.Map(first); err != nil {
+		t.Fatalf("Map failed: %v", err)
+	}
+	second := &models.ProductTermMapping{ProductMnemoCode: "IS_ONP_PRD", TermID: "T2", EffectiveFrom: day("2025-10-22")}
+	superseded, err := editor.Map(second)
+	if err != nil {
+		t.Fatalf("Map failed: %v", err)
+	}
+	if superseded != first.MappingID {
+		t.Errorf("Expected mapping %d to be superseded, got %d", first.MappingID, superseded)
+	}
+
+	end := day("2025-03-31")
+	for name, mapping := range map[string]*models.ProductTermMapping{
+		"overlap":         {ProductMnemoCode: "IS_ONP_PRD", TermID: "T2", EffectiveFrom: day("2025-03-01"), EffectiveTo: &end},
+		"unknown term":    {ProductMnemoCode: "IS_ONP_PRD", TermID: "T3", EffectiveFrom: day("2020-01-01")},
+		"unknown product": {ProductMnemoCode: "MWS_PRD", TermID: "T1", EffectiveFrom: day("2020-01-01")},
+	} {
+		if _, err := editor.Map(mapping); err == nil {
+			t.Errorf("Expected an error mapping a product with %s", name)
+		}
+	}
+
+	mappings, err := editor.List("IS_ONP_PRD")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(mappings) != 2 || mappings[0].EffectiveTo == nil || mappings[0].EffectiveTo.Format("2006-01-02") != "2025-10-21" || mappings[1].EffectiveTo != nil {
+		t.Errorf("Unexpected mappings: %+v", mappings)
+	}
+
+	// The measurements before the move stay under T1
+	root := t.TempDir()
+	for _, stamp := range []string{"20251021_090906", "20251022_090906"} {
+		path := filepath.Join(root, "iwdli_output_host1_"+stamp+".csv")
+		date := stamp[:4] + "-" + stamp[4:6] + "-" + stamp[6:8]
+		writeFile(t, path, strings.Replace(testInspectorCSV, "2025-10-21", date, 1))
+		if _, err := importer.NewImportService(db).ImportCSVFile(t.Context(), path); err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+	}
+	termOn := func() map[string]string {
+		t.Helper()
+		rows, err := reports.NewComplianceReport(db).Query(t.Context(), "", "", nil, nil, false)
+		if err != nil {
+			t.Fatalf("Compliance query failed: %v", err)
+		}
+		terms := map[string]string{}
+		for _, row := range rows {
+			terms[row.MeasurementDate.Format("2006-01-02")] = row.TermID + " " + row.ProgramNumber
+		}
+		return terms
+	}
+	terms := termOn()
+	if terms["2025-10-21"] != "T1 5900-AAA" || terms["2025-10-22"] != "T2 5900-BBB" {
+		t.Errorf("Expected T1 before the move and T2 after it, got %v", terms)
+	}
+
+	monthly, err := reports.NewMonthlyPeakReport(db).Query(t.Context(), "", "", nil, nil)
+	if err != nil {
+		t.Fatalf("Monthly peak query failed: %v", err)
+	}
+	if len(monthly) != 2 {
+		t.Errorf("Expected one monthly peak per term in the month of the move, got %+v", monthly)
+	}
+
+	// Without mappings the term of the product code applies to every day
+	for _, m := range mappings {
+		if err := editor.Remove(m.MappingID); err != nil {
+			t.Fatalf("Remove failed: %v", err)
+		}
+	}
+	if err := editor.Remove(first.MappingID); err == nil {
+		t.Error("Expected an error removing a missing mapping")
+	}
+	terms = termOn()
+	if terms["2025-10-21"] != "T1 5900-AAA" || terms["2025-10-22"] != "T1 5900-AAA" {
+		t.Errorf("Expected T1 on every day without mappings, got %v", terms)
+	}
+}
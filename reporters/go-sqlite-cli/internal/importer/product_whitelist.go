@@ -0,0 +1,55 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// UnknownProductCodesError is returned in strict mode when a file detects
+// product codes that have no mapping in the product_codes reference table
+type UnknownProductCodesError struct {
+	Codes []string
+}
+
+func (e *UnknownProductCodesError) Error() string {
+	return fmt.Sprintf("unknown product codes (not in product_codes): %s", strings.Join(e.Codes, ", "))
+}
+
+// checkProductCodes validates all detected product codes against product_codes
+func (s *ImportService) checkProductCodes(ctx context.Context, record *CSVRecord) error {
+	var unknown []string
+
+	for code := range record.ProductDetections {
+		var count int
+		err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM product_codes WHERE product_mnemo_code = ?", code).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to check product code %s: %w", code, err)
+		}
+		if count == 0 {
+			unknown = append(unknown, code)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return &UnknownProductCodesError{Codes: unknown}
+	}
+
+	return nil
+}
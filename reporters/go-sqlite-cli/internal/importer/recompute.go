@@ -0,0 +1,244 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/licensing"
+)
+
+// RecomputedFields are the calculated fields of the measurements Recompute
+// derives again
+var RecomputedFields = []string{"processor_eligible", "os_eligible", "virt_eligible", "considered_cpus"}
+
+// RecomputeOptions selects the measurements recomputed
+type RecomputeOptions struct {
+	MainFQDN string     // empty for every node
+	From, To *time.Time // days of the measurements, inclusive; nil for no bound
+	// Eligibility, when set, re-derives the eligibility of the measurements;
+	// otherwise the eligibility they were imported with is kept
+	Eligibility func(m *RecomputedMeasurement) (processor, os, virt string)
+	DryRun      bool
+}
+
+// RecomputedMeasurement is a measurement with the fields the calculated
+// fields are derived from
+type RecomputedMeasurement struct {
+	MainFQDN           string
+	DetectionTimestamp time.Time
+	OSName             string
+	OSVersion          string
+	CPUCount           int
+	IsVirtualized      string
+	VirtType           string
+	ProcessorVendor    string
+	ProcessorBrand     string
+	HostPhysicalCPUs   string
+	PartitionCPUs      string
+	// HostCores are the physical cores of the host recorded in
+	// hypervisor_hosts, nil when the host is not listed there
+	HostCores           *int
+	ContainerLimitCores *int
+
+	ProcessorEligible string
+	OSEligible        string
+	VirtEligible      string
+	ConsideredCPUs    int
+
+	rowID int64
+}
+
+// RecomputedChange is a calculated field of a measurement given a new value
+type RecomputedChange struct {
+	MainFQDN           string
+	DetectionTimestamp time.Time
+	Field              string
+	Old                string
+	New                string
+}
+
+// RecomputeResult summarizes the calculated fields changed by a recompute
+type RecomputeResult struct {
+	Measurements int            // measurements recomputed
+	Updated      int            // measurements with a changed field
+	Fields       map[string]int // measurements changed per field
+	Nodes        []string       // nodes with a changed measurement
+	Dates        []string       // days of the changed measurements, YYYY-MM-DD
+	Changes      []RecomputedChange
+}
+
+// Recompute derives the calculated fields of stored measurements again from
+// the fields the inspector measured: the eligibility (with opts.Eligibility)
+// and the considered CPUs, after reference data or eligibility rules changed.
+// The physical cores of hosts listed in hypervisor_hosts, by 'sync vcenter' or
+// 'import host-capacity', replace the host cores the inspector reported. The
+// node changes of the nodes updated are refreshed. With opts.DryRun the changes
+// are returned without being written.
+func (s *ImportService) Recompute(ctx context.Context, opts RecomputeOptions) (*RecomputeResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	measurements, err := recomputedMeasurements(ctx, tx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RecomputeResult{Measurements: len(measurements), Fields: map[string]int{}}
+	nodes, dates := map[string]bool{}, map[string]bool{}
+	for _, m := range measurements {
+		old := *m
+		if opts.Eligibility != nil {
+			m.ProcessorEligible, m.OSEligible, m.VirtEligible = opts.Eligibility(m)
+		}
+		m.ConsideredCPUs = licensing.ConsideredCPUs(m.cpuInputs())
+
+		var changes []RecomputedChange
+		for _, field := range []struct{ name, old, new string }{
+			{"processor_eligible", old.ProcessorEligible, m.ProcessorEligible},
+			{"os_eligible", old.OSEligible, m.OSEligible},
+			{"virt_eligible", old.VirtEligible, m.VirtEligible},
+			{"considered_cpus", strconv.Itoa(old.ConsideredCPUs), strconv.Itoa(m.ConsideredCPUs)},
+		} {
+			if field.old != field.new {
+				changes = append(changes, RecomputedChange{MainFQDN: m.MainFQDN, DetectionTimestamp: m.DetectionTimestamp,
+					Field: field.name, Old: field.old, New: field.new})
+				result.Fields[field.name]++
+			}
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		result.Updated++
+		result.Changes = append(result.Changes, changes...)
+		nodes[m.MainFQDN] = true
+		dates[m.DetectionTimestamp.Format("2006-01-02")] = true
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE measurements
+			SET processor_eligible = ?, os_eligible = ?, virt_eligible = ?, considered_cpus = ?
+			WHERE rowid = ?
+		`, m.ProcessorEligible, m.OSEligible, m.VirtEligible, m.ConsideredCPUs, m.rowID); err != nil {
+			return nil, fmt.Errorf("failed to update measurement of %s at %s: %w",
+				m.MainFQDN, m.DetectionTimestamp.Format("2006-01-02 15:04:05"), err)
+		}
+	}
+
+	for node := range nodes {
+		result.Nodes = append(result.Nodes, node)
+	}
+	sort.Strings(result.Nodes)
+	for date := range dates {
+		result.Dates = append(result.Dates, date)
+	}
+	sort.Strings(result.Dates)
+
+	for _, node := range result.Nodes {
+		if err := refreshNodeChanges(tx, node); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// recomputedMeasurements reads the measurements selected by opts
+func recomputedMeasurements(ctx context.Context, tx *sql.Tx, opts RecomputeOptions) ([]*RecomputedMeasurement, error) {
+	query := `
+		SELECT m.rowid, m.main_fqdn, m.detection_timestamp, m.os_name, m.os_version, m.cpu_count,
+			m.is_virtualized, COALESCE(m.virt_type, ''), COALESCE(m.processor_vendor, ''),
+			COALESCE(m.processor_brand, ''), COALESCE(m.host_physical_cpus, ''), COALESCE(m.partition_cpus, ''),
+			h.physical_cores, m.container_limit_cores,
+			m.processor_eligible, m.os_eligible, m.virt_eligible, m.considered_cpus
+		FROM measurements m
+		LEFT JOIN hypervisor_hosts h ON h.physical_host_id = m.physical_host_id AND m.physical_host_id != ''
+		WHERE 1 = 1`
+	var args []interface{}
+	if opts.MainFQDN != "" {
+		query += " AND m.main_fqdn = ?"
+		args = append(args, opts.MainFQDN)
+	}
+	if opts.From != nil {
+		query += " AND DATE(m.detection_timestamp) >= ?"
+		args = append(args, opts.From.Format("2006-01-02"))
+	}
+	if opts.To != nil {
+		query += " AND DATE(m.detection_timestamp) <= ?"
+		args = append(args, opts.To.Format("2006-01-02"))
+	}
+	query += " ORDER BY m.main_fqdn, m.detection_timestamp"
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read measurements: %w", err)
+	}
+	defer rows.Close()
+
+	var measurements []*RecomputedMeasurement
+	for rows.Next() {
+		m := &RecomputedMeasurement{}
+		var hostCores, limitCores sql.NullInt64
+		if err := rows.Scan(&m.rowID, &m.MainFQDN, &m.DetectionTimestamp, &m.OSName, &m.OSVersion, &m.CPUCount,
+			&m.IsVirtualized, &m.VirtType, &m.ProcessorVendor, &m.ProcessorBrand, &m.HostPhysicalCPUs, &m.PartitionCPUs,
+			&hostCores, &limitCores, &m.ProcessorEligible, &m.OSEligible, &m.VirtEligible, &m.ConsideredCPUs); err != nil {
+			return nil, fmt.Errorf("failed to scan measurement: %w", err)
+		}
+		if hostCores.Valid {
+			cores := int(hostCores.Int64)
+			m.HostCores = &cores
+		}
+		if limitCores.Valid {
+			cores := int(limitCores.Int64)
+			m.ContainerLimitCores = &cores
+		}
+		measurements = append(measurements, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read measurements: %w", err)
+	}
+	return measurements, nil
+}
+
+// cpuInputs returns the inputs of the considered CPUs of the measurement
+func (m *RecomputedMeasurement) cpuInputs() licensing.CPUInputs {
+	in := licensing.CPUInputs{
+		CPUCount:            m.CPUCount,
+		Virtualized:         m.IsVirtualized == "yes",
+		OSEligible:          m.OSEligible == "true",
+		VirtEligible:        m.VirtEligible == "true",
+		PartitionCPUs:       m.PartitionCPUs,
+		ContainerLimitCores: m.ContainerLimitCores,
+	}
+	if m.HostCores != nil {
+		in.HostCores = *m.HostCores
+	} else if cores, err := strconv.Atoi(m.HostPhysicalCPUs); err == nil {
+		in.HostCores = cores
+	}
+	return in
+}
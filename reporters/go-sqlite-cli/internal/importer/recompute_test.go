@@ -0,0 +1,87 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestRecompute(t *testing.T) {
+	// vm01 is an ineligible VM whose host cores were unknown to the inspector;
+	// vm02 is an eligible VM counted correctly
+	stmts := []string{
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('vm01.example.com', 'vm01', 'PROD'), ('vm02.example.com', 'vm02', 'PROD')`,
+		`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count, is_virtualized,
+			virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus, physical_host_id)
+			VALUES ('vm01.example.com', '2025-10-01 08:00:00', 'Linux', '8', 4, 'yes', 'kvm', 'unknown', 'true', 'true', 'false', 4, 'esx-1'),
+			       ('vm01.example.com', '2025-10-02 08:00:00', 'Linux', '8', 4, 'yes', 'kvm', 'unknown', 'true', 'true', 'false', 4, 'esx-1'),
+			       ('vm01.example.com', '2025-10-03 08:00:00', 'Linux', '8', 4, 'yes', 'kvm', 'unknown', 'true', 'true', 'false', 4, 'esx-1'),
+			       ('vm02.example.com', '2025-10-01 08:00:00', 'Linux', '8', 2, 'yes', 'VMware', '32', 'true', 'true', 'true', 2, 'esx-2')`,
+		`INSERT INTO hypervisor_hosts (physical_host_id, source, host_ref, host_name, physical_cores, synced_at)
+			VALUES ('esx-1', 'hmc01', 'esx-1', 'esx-1', 24, '2025-10-05 00:00:00'), ('esx-2', 'hmc01', 'esx-2', 'esx-2', 32, '2025-10-05 00:00:00')`,
+	}
+	db := setupImportDB(t, stmts...)
+	service := importer.NewImportService(db)
+	consideredCPUs := func(timestamp string) int {
+		t.Helper()
+		var cores int
+		if err := db.QueryRow(`SELECT considered_cpus FROM measurements WHERE main_fqdn = 'vm01.example.com' AND detection_timestamp = ?`, timestamp).Scan(&cores); err != nil {
+			t.Fatal(err)
+		}
+		return cores
+	}
+
+	result, err := service.Recompute(t.Context(), importer.RecomputeOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if result.Measurements != 4 || result.Updated != 3 || result.Fields["considered_cpus"] != 3 {
+		t.Errorf("Expected the 3 measurements of vm01 changed, got %+v", result)
+	}
+	if len(result.Nodes) != 1 || len(result.Dates) != 3 || result.Changes[0].Old != "4" || result.Changes[0].New != "24" {
+		t.Errorf("Unexpected changes: %+v", result)
+	}
+	if cores := consideredCPUs("2025-10-01 08:00:00"); cores != 4 {
+		t.Errorf("Expected the dry run to change nothing, got %d cores", cores)
+	}
+
+	from := time.Date(2025, 10, 2, 0, 0, 0, 0, time.UTC)
+	result, err = service.Recompute(t.Context(), importer.RecomputeOptions{MainFQDN: "vm01.example.com", From: &from})
+	if err != nil || result.Measurements != 2 || result.Updated != 2 {
+		t.Fatalf("Expected the 2 measurements from October 2 changed, got %+v (%v)", result, err)
+	}
+	if consideredCPUs("2025-10-01 08:00:00") != 4 || consideredCPUs("2025-10-02 08:00:00") != 24 {
+		t.Error("Expected only the measurements from October 2 updated")
+	}
+	var changes int
+	db.QueryRow(`SELECT COUNT(*) FROM node_changes WHERE main_fqdn = 'vm01.example.com' AND field = 'considered_cpus'`).Scan(&changes)
+	if changes != 1 {
+		t.Errorf("Expected the node changes refreshed, got %d considered_cpus changes", changes)
+	}
+
+	// Eligibility re-derived as eligible counts the vCPUs again
+	result, err = service.Recompute(t.Context(), importer.RecomputeOptions{
+		Eligibility: func(m *importer.RecomputedMeasurement) (string, string, string) { return "true", "true", "true" },
+	})
+	if err != nil || result.Updated != 3 || result.Fields["virt_eligible"] != 3 || result.Fields["considered_cpus"] != 2 {
+		t.Errorf("Expected the eligibility of vm01 changed, got %+v (%v)", result, err)
+	}
+	if cores := consideredCPUs("2025-10-03 08:00:00"); cores != 4 {
+		t.Errorf("Expected the vCPUs counted, got %d", cores)
+	}
+}
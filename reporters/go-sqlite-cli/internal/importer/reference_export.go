@@ -0,0 +1,146 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ReferenceFile is a reference data CSV file written by the exporter
+type ReferenceFile struct {
+	Path string
+	Rows int
+}
+
+// referenceExport describes one exported table
+type referenceExport struct {
+	fileName string
+	header   []string
+	query    string
+}
+
+// referenceExports are written in the order they have to be loaded back:
+// license terms before the product codes and entitlements referencing them,
+// product codes before their thresholds and grace windows.
+// Rows are sorted by primary key so that exports can be diffed in git.
+var referenceExports = []referenceExport{
+	{"license-terms.csv", licenseTermsHeader, `
+		SELECT term_id, program_number, program_name
+		FROM license_terms ORDER BY term_id`},
+	{"product-codes.csv", productCodesHeader, `
+		SELECT product_mnemo_code, ibm_product_code, product_name, mode, term_id, COALESCE(notes, '')
+		FROM product_codes ORDER BY product_mnemo_code`},
+	{"entitlements.csv", entitlementsHeader, `
+		SELECT term_id, licensed_cores, licensed_pvu, COALESCE(notes, '')
+		FROM entitlements ORDER BY term_id`},
+	{"product-thresholds.csv", thresholdsHeader, `
+		SELECT product_mnemo_code, max_license_cores, COALESCE(notes, '')
+		FROM product_thresholds ORDER BY product_mnemo_code`},
+	{"peak-grace-windows.csv", graceWindowsHeader, `
+		SELECT product_mnemo_code, grace_days, COALESCE(notes, '')
+		FROM peak_grace_windows ORDER BY product_mnemo_code`},
+	{"pvu-table.csv", pvuMappingsHeader, `
+		SELECT processor_vendor, processor_brand, processor_model, pvu_per_core, COALESCE(notes, '')
+		FROM pvu_mappings ORDER BY processor_vendor, processor_brand, processor_model`},
+}
+
+// ReferenceDataExporter writes the reference data tables as CSV files in the
+// format read by ReferenceDataLoader
+type ReferenceDataExporter struct {
+	db *sql.DB
+}
+
+// NewReferenceDataExporter creates a new reference data exporter
+func NewReferenceDataExporter(db *sql.DB) *ReferenceDataExporter {
+	return &ReferenceDataExporter{db: db}
+}
+
+// ExportDir writes license-terms.csv, product-codes.csv, entitlements.csv,
+// product-thresholds.csv, peak-grace-windows.csv and pvu-table.csv to dir, creating it if needed. Existing files are overwritten.
+func (e *ReferenceDataExporter) ExportDir(dir string) ([]ReferenceFile, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var files []ReferenceFile
+	for _, export := range referenceExports {
+		path := filepath.Join(dir, export.fileName)
+		rows, err := e.exportTable(path, export)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, ReferenceFile{Path: path, Rows: rows})
+	}
+
+	return files, nil
+}
+
+// exportTable writes the rows of one reference table to path
+func (e *ReferenceDataExporter) exportTable(path string, export referenceExport) (int, error) {
+	rows, err := e.db.Query(export.query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s data: %w", export.fileName, err)
+	}
+	defer rows.Close()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(export.header); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	count := 0
+	values := make([]sql.NullString, len(export.header))
+	targets := make([]interface{}, len(values))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		record := make([]string, len(values))
+		for i, v := range values {
+			record[i] = v.String
+		}
+		if err := writer.Write(record); err != nil {
+			return 0, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return count, nil
+}
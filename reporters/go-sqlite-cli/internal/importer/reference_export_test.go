@@ -0,0 +1,111 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestExportReferenceRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "license-terms.csv"), `license-terms-id,program-number,program-name
+T2,5900-BBB,"Program, with comma"
+`)
+	writeFile(t, filepath.Join(dir, "product-codes.csv"), `product-mnemo-id,product-code,product-name,mode,license-terms-id,notes
+UM_ONP_PRD,D0000AB,Universal Messaging,PROD,T2,"note with ""quotes"""
+BR_ONP_NPR,D0000CD,Broker Non Production,NON PROD,T3,
+`)
+	writeFile(t, filepath.Join(dir, "entitlements.csv"), "license-terms-id,licensed-cores,licensed-pvu,notes\nT2,16,0,contract 2025\n")
+	writeFile(t, filepath.Join(dir, "product-thresholds.csv"), "product-mnemo-id,max-license-cores,notes\nUM_ONP_PRD,8,\n")
+	writeFile(t, filepath.Join(dir, "peak-grace-windows.csv"), "product-mnemo-id,grace-days,notes\nUM_ONP_PRD,3,DR failover\n")
+	writeFile(t, filepath.Join(dir, "pvu-table.csv"), "processor-vendor,processor-brand,processor-model,pvu-per-core,notes\nIBM,POWER9,,100,\nIntel,Xeon,Gold,70,two sockets\n")
+
+	load := func(loader *importer.ReferenceDataLoader, dir string) {
+		t.Helper()
+		if err := loader.LoadLicenseTermsCSV(t.Context(), filepath.Join(dir, "license-terms.csv")); err != nil {
+			t.Fatalf("LoadLicenseTermsCSV failed: %v", err)
+		}
+		if err := loader.LoadProductCodesCSV(t.Context(), filepath.Join(dir, "product-codes.csv")); err != nil {
+			t.Fatalf("LoadProductCodesCSV failed: %v", err)
+		}
+		if err := loader.LoadEntitlementsCSV(t.Context(), filepath.Join(dir, "entitlements.csv")); err != nil {
+			t.Fatalf("LoadEntitlementsCSV failed: %v", err)
+		}
+		if err := loader.LoadProductThresholdsCSV(t.Context(), filepath.Join(dir, "product-thresholds.csv")); err != nil {
+			t.Fatalf("LoadProductThresholdsCSV failed: %v", err)
+		}
+		if err := loader.LoadPeakGraceWindowsCSV(t.Context(), filepath.Join(dir, "peak-grace-windows.csv")); err != nil {
+			t.Fatalf("LoadPeakGraceWindowsCSV failed: %v", err)
+		}
+		if err := loader.LoadPVUMappingsCSV(t.Context(), filepath.Join(dir, "pvu-table.csv")); err != nil {
+			t.Fatalf("LoadPVUMappingsCSV failed: %v", err)
+		}
+	}
+
+	source := setupImportDB(t)
+	load(importer.NewReferenceDataLoader(source), dir)
+
+	first := filepath.Join(t.TempDir(), "reference")
+	files, err := importer.NewReferenceDataExporter(source).ExportDir(first)
+	if err != nil {
+		t.Fatalf("ExportDir failed: %v", err)
+	}
+
+	// T1 and IS_ONP_PRD come from setupImportDB, T3 is the placeholder term
+	// created for the product code referencing it
+	want := map[string]int{"license-terms.csv": 3, "product-codes.csv": 3, "entitlements.csv": 1, "product-thresholds.csv": 1, "peak-grace-windows.csv": 1, "pvu-table.csv": 2}
+	for _, f := range files {
+		if f.Rows != want[filepath.Base(f.Path)] {
+			t.Errorf("Expected %d rows in %s, got %d", want[filepath.Base(f.Path)], f.Path, f.Rows)
+		}
+	}
+
+	// Loading the export into another database and exporting it again gives the same files
+	target := setupImportDB(t)
+	load(importer.NewReferenceDataLoader(target), first)
+
+	second := filepath.Join(t.TempDir(), "reference")
+	if _, err := importer.NewReferenceDataExporter(target).ExportDir(second); err != nil {
+		t.Fatalf("Second ExportDir failed: %v", err)
+	}
+
+	for name := range want {
+		a, err := os.ReadFile(filepath.Join(first, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(filepath.Join(second, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(a) != string(b) {
+			t.Errorf("%s differs after the round trip:\n%s\nvs\n%s", name, a, b)
+		}
+	}
+
+	productCodes, _ := os.ReadFile(filepath.Join(first, "product-codes.csv"))
+	wantCodes := `product-mnemo-id,product-code,product-name,mode,license-terms-id,notes
+BR_ONP_NPR,D0000CD,Broker Non Production,NON PROD,T3,
+IS_ONP_PRD,D0YYWZX,Integration Server,PROD,T1,
+UM_ONP_PRD,D0000AB,Universal Messaging,PROD,T2,"note with ""quotes"""
+`
+	if string(productCodes) != wantCodes {
+		t.Errorf("Unexpected product-codes.csv:\n%s", productCodes)
+	}
+}
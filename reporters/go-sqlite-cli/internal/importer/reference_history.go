@@ -0,0 +1,192 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+)
+
+// Changes recorded in the reference history tables
+const (
+	ReferenceInsert = "insert"
+	ReferenceUpdate = "update"
+)
+
+// SourceCatalog is the source of the changes made by 'reference bootstrap'
+const SourceCatalog = "catalog"
+
+// referenceHistory records the changes of a reference table in its
+// <table>_history table, with the old and new value of each column
+type referenceHistory struct {
+	table   string
+	key     string
+	columns []string
+}
+
+var (
+	licenseTermsHistory = referenceHistory{
+		table:   "license_terms",
+		key:     "term_id",
+		columns: []string{"program_number", "program_name"},
+	}
+	productCodesHistory = referenceHistory{
+		table:   "product_codes",
+		key:     "product_mnemo_code",
+		columns: []string{"ibm_product_code", "product_name", "mode", "term_id", "notes"},
+	}
+)
+
+// current returns the recorded columns of the row with the key, nil when the
+// table has no such row
+func (h referenceHistory) current(tx *sql.Tx, key string) ([]string, error) {
+	selects := make([]string, len(h.columns))
+	for i, column := range h.columns {
+		selects[i] = "COALESCE(" + column + ", '')"
+	}
+	values := make([]string, len(h.columns))
+	targets := make([]interface{}, len(h.columns))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+
+	query := "SELECT " + strings.Join(selects, ", ") + " FROM " + h.table + " WHERE " + h.key + " = ?"
+	err := tx.QueryRow(query, key).Scan(targets...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s %s: %w", h.table, key, err)
+	}
+	return values, nil
+}
+
+// record adds an insert (old is nil) or an update of the row with the key to
+// the history table. Updates leaving every column unchanged are not recorded.
+func (h referenceHistory) record(tx *sql.Tx, key string, old, new []string, source, changedBy string) error {
+	change := ReferenceInsert
+	var changed []string
+	if old != nil {
+		change = ReferenceUpdate
+		for i, column := range h.columns {
+			if old[i] != new[i] {
+				changed = append(changed, column)
+			}
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+	}
+
+	columns := []string{h.key, "change", "changed_columns", "source", "changed_by"}
+	args := []interface{}{key, change, strings.Join(changed, ","), source, changedBy}
+	for i, column := range h.columns {
+		columns = append(columns, "old_"+column, "new_"+column)
+		if old != nil {
+			args = append(args, old[i], new[i])
+		} else {
+			args = append(args, nil, new[i])
+		}
+	}
+
+	query := "INSERT INTO " + h.table + "_history (" + strings.Join(columns, ", ") +
+		") VALUES (?" + strings.Repeat(", ?", len(columns)-1) + ")"
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to record the history of %s %s: %w", h.table, key, err)
+	}
+	return nil
+}
+
+// list returns the recorded changes of the table, of one row when key is set
+func (h referenceHistory) list(db *sql.DB, key string) ([]models.ReferenceChange, error) {
+	selects := []string{"history_id", h.key, "change", "COALESCE(changed_columns, '')",
+		"COALESCE(source, '')", "COALESCE(changed_by, '')", "changed_at"}
+	for _, column := range h.columns {
+		selects = append(selects, "COALESCE(old_"+column+", '')", "COALESCE(new_"+column+", '')")
+	}
+	query := "SELECT " + strings.Join(selects, ", ") + " FROM " + h.table + "_history"
+	args := []interface{}{}
+	if key != "" {
+		query += " WHERE " + h.key + " = ?"
+		args = append(args, key)
+	}
+	query += " ORDER BY history_id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the history of %s: %w", h.table, err)
+	}
+	defer rows.Close()
+
+	var changes []models.ReferenceChange
+	for rows.Next() {
+		c := models.ReferenceChange{Table: h.table}
+		var changedColumns string
+		values := make([]string, 2*len(h.columns))
+		targets := []interface{}{&c.HistoryID, &c.Key, &c.Change, &changedColumns, &c.Source, &c.ChangedBy, &c.ChangedAt}
+		for i := range values {
+			targets = append(targets, &values[i])
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return nil, fmt.Errorf("failed to scan the history of %s: %w", h.table, err)
+		}
+
+		changed := map[string]bool{}
+		for _, column := range strings.Split(changedColumns, ",") {
+			changed[column] = true
+		}
+		for i, column := range h.columns {
+			if c.Change == ReferenceUpdate && !changed[column] {
+				continue
+			}
+			c.Values = append(c.Values, models.ReferenceValue{Column: column, Old: values[2*i], New: values[2*i+1]})
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// History returns the recorded changes of the license terms and product
+// codes, oldest first. A non-empty termID or productCode limits the changes to
+// that license term or product code; with both set, the changes of either are
+// returned.
+func (l *ReferenceDataLoader) History(termID, productCode string) ([]models.ReferenceChange, error) {
+	var changes []models.ReferenceChange
+	if termID != "" || productCode == "" {
+		terms, err := licenseTermsHistory.list(l.db, termID)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, terms...)
+	}
+	if productCode != "" || termID == "" {
+		products, err := productCodesHistory.list(l.db, productCode)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, products...)
+	}
+
+	// License terms are loaded before the product codes mapped to them, so a
+	// stable sort keeps them first within the same second
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].ChangedAt.Before(changes[j].ChangedAt)
+	})
+	return changes, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestReferenceHistory(t *testing.T) {
+	db := setupImportDB(t)
+	dir := t.TempDir()
+	loader := importer.NewReferenceDataLoader(db)
+	loader.ChangedBy = "auditor"
+
+	products := filepath.Join(dir, "product-codes.csv")
+	header := "product-mnemo-id,product-code,product-name,mode,license-terms-id,notes\n"
+
+	// Reloading IS_ONP_PRD unchanged records nothing; remapping it to a new
+	// term records the update and the placeholder term
+	writeFile(t, products, header+"IS_ONP_PRD,D0YYWZX,Integration Server,PROD,T1,\n")
+	if err := loader.LoadProductCodesCSV(t.Context(), products); err != nil {
+		t.Fatalf("LoadProductCodesCSV failed: %v", err)
+	}
+	changes, err := loader.History("", "")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("Expected no change recorded for an unchanged product code, got %+v", changes)
+	}
+
+	writeFile(t, products, header+"IS_ONP_PRD,D0YYWZX,Integration Server,PROD,T2,\n")
+	if err := loader.LoadProductCodesCSV(t.Context(), products); err != nil {
+		t.Fatalf("LoadProductCodesCSV failed: %v", err)
+	}
+
+	changes, err = loader.History("", "IS_ONP_PRD")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("Expected 1 product code change, got %+v", changes)
+	}
+	c := changes[0]
+	if c.Change != importer.ReferenceUpdate || c.Table != "product_codes" || c.Source != products || c.ChangedBy != "auditor" {
+		t.Errorf("Unexpected change %+v", c)
+	}
+	if len(c.Values) != 1 || c.Values[0].Column != "term_id" || c.Values[0].Old != "T1" || c.Values[0].New != "T2" {
+		t.Errorf("Expected only the term mapping T1 -> T2, got %+v", c.Values)
+	}
+
+	changes, err = loader.History("T2", "")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Change != importer.ReferenceInsert || changes[0].Table != "license_terms" {
+		t.Fatalf("Expected the placeholder term T2 recorded as inserted, got %+v", changes)
+	}
+
+	// A catalog bootstrap without overwrite keeps IS_ONP_PRD
+	if err := loader.LoadCatalog(t.Context(), false); err != nil {
+		t.Fatalf("LoadCatalog failed: %v", err)
+	}
+	changes, err = loader.History("", "IS_ONP_PRD")
+	if err != nil || len(changes) != 1 {
+		t.Errorf("Expected the kept product code without new change, got %d (%v)", len(changes), err)
+	}
+	changes, err = loader.History("", "UM_ONP_PRD")
+	if err != nil || len(changes) != 1 || changes[0].Source != importer.SourceCatalog {
+		t.Errorf("Expected UM_ONP_PRD inserted from the catalog, got %+v (%v)", changes, err)
+	}
+}
@@ -15,17 +15,34 @@
 package importer
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/tracing"
+)
+
+// Headers of the reference data CSV files, shared by the loader and the exporter
+var (
+	licenseTermsHeader = []string{"license-terms-id", "program-number", "program-name"}
+	productCodesHeader = []string{"product-mnemo-id", "product-code", "product-name", "mode", "license-terms-id", "notes"}
+	entitlementsHeader = []string{"license-terms-id", "licensed-cores", "licensed-pvu", "notes"}
+	pvuMappingsHeader  = []string{"processor-vendor", "processor-brand", "processor-model", "pvu-per-core", "notes"}
+	thresholdsHeader   = []string{"product-mnemo-id", "max-license-cores", "notes"}
+	graceWindowsHeader = []string{"product-mnemo-id", "grace-days", "notes"}
 )
 
 // ReferenceDataLoader loads reference data (product codes, license terms) into database
 type ReferenceDataLoader struct {
 	db *sql.DB
+	// ChangedBy is recorded as the user of the license term and product code
+	// changes in their history tables
+	ChangedBy string
 }
 
 // NewReferenceDataLoader creates a new reference data loader
@@ -35,14 +52,25 @@ func NewReferenceDataLoader(db *sql.DB) *ReferenceDataLoader {
 
 // LoadLicenseTermsCSV loads license terms from CSV file
 // CSV format: license-terms-id,program-number,program-name
-func (l *ReferenceDataLoader) LoadLicenseTermsCSV(filePath string) error {
+func (l *ReferenceDataLoader) LoadLicenseTermsCSV(ctx context.Context, filePath string) (err error) {
+	span := traceTable("upsert", "license_terms")
+	span.SetAttributes(tracing.String("file.path", filePath))
+	defer func() { span.End(err) }()
+
 	file, err := os.Open(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
+	return l.loadLicenseTerms(ctx, file, filePath, true)
+}
+
+// loadLicenseTerms loads license terms from CSV. Existing terms are updated
+// when overwrite is set and kept as they are otherwise. Inserted and changed
+// terms are recorded in license_terms_history with the source they came from.
+func (l *ReferenceDataLoader) loadLicenseTerms(ctx context.Context, r io.Reader, source string, overwrite bool) error {
+	reader := csv.NewReader(r)
 	reader.FieldsPerRecord = -1 // Allow variable number of fields
 	reader.TrimLeadingSpace = true
 
@@ -53,12 +81,12 @@ func (l *ReferenceDataLoader) LoadLicenseTermsCSV(filePath string) error {
 	}
 
 	// Validate header
-	expectedHeader := []string{"license-terms-id", "program-number", "program-name"}
+	expectedHeader := licenseTermsHeader
 	if !equalHeaders(header, expectedHeader) {
 		return fmt.Errorf("invalid CSV header, expected: %v", expectedHeader)
 	}
 
-	tx, err := l.db.Begin()
+	tx, err := l.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to start transaction: %w", err)
 	}
@@ -66,6 +94,7 @@ func (l *ReferenceDataLoader) LoadLicenseTermsCSV(filePath string) error {
 
 	insertedCount := 0
 	updatedCount := 0
+	keptCount := 0
 
 	// Read records
 	for {
@@ -90,13 +119,12 @@ func (l *ReferenceDataLoader) LoadLicenseTermsCSV(filePath string) error {
 		}
 
 		// Check if license term already exists
-		var count int
-		err = tx.QueryRow("SELECT COUNT(*) FROM license_terms WHERE term_id = ?", termID).Scan(&count)
+		current, err := licenseTermsHistory.current(tx, termID)
 		if err != nil {
 			return fmt.Errorf("failed to check license term existence: %w", err)
 		}
 
-		if count == 0 {
+		if current == nil {
 			// Insert new license term
 			_, err = tx.Exec(`
 				INSERT INTO license_terms (term_id, program_number, program_name)
@@ -105,7 +133,12 @@ func (l *ReferenceDataLoader) LoadLicenseTermsCSV(filePath string) error {
 			if err != nil {
 				return fmt.Errorf("failed to insert license term %s: %w", termID, err)
 			}
+			if err := licenseTermsHistory.record(tx, termID, nil, []string{programNumber, programName}, source, l.ChangedBy); err != nil {
+				return err
+			}
 			insertedCount++
+		} else if !overwrite {
+			keptCount++
 		} else {
 			// Update existing license term
 			_, err = tx.Exec(`
@@ -116,6 +149,9 @@ func (l *ReferenceDataLoader) LoadLicenseTermsCSV(filePath string) error {
 			if err != nil {
 				return fmt.Errorf("failed to update license term %s: %w", termID, err)
 			}
+			if err := licenseTermsHistory.record(tx, termID, current, []string{programNumber, programName}, source, l.ChangedBy); err != nil {
+				return err
+			}
 			updatedCount++
 		}
 	}
@@ -124,20 +160,32 @@ func (l *ReferenceDataLoader) LoadLicenseTermsCSV(filePath string) error {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	fmt.Printf("License terms loaded: %d inserted, %d updated\n", insertedCount, updatedCount)
+	fmt.Printf("License terms loaded: %d inserted, %d updated%s\n", insertedCount, updatedCount, keptSuffix(keptCount))
 	return nil
 }
 
 // LoadProductCodesCSV loads product codes from CSV file
 // CSV format: product-mnemo-id,product-code,product-name,mode,license-terms-id,notes
-func (l *ReferenceDataLoader) LoadProductCodesCSV(filePath string) error {
+func (l *ReferenceDataLoader) LoadProductCodesCSV(ctx context.Context, filePath string) (err error) {
+	span := traceTable("upsert", "product_codes")
+	span.SetAttributes(tracing.String("file.path", filePath))
+	defer func() { span.End(err) }()
+
 	file, err := os.Open(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
+	return l.loadProductCodes(ctx, file, filePath, true)
+}
+
+// loadProductCodes loads product codes from CSV. Existing product codes are
+// updated when overwrite is set and kept as they are otherwise. Inserted and
+// changed product codes are recorded in product_codes_history with the source
+// they came from.
+func (l *ReferenceDataLoader) loadProductCodes(ctx context.Context, r io.Reader, source string, overwrite bool) error {
+	reader := csv.NewReader(r)
 	reader.FieldsPerRecord = -1 // Allow variable number of fields
 	reader.TrimLeadingSpace = true
 
@@ -148,12 +196,12 @@ func (l *ReferenceDataLoader) LoadProductCodesCSV(filePath string) error {
 	}
 
 	// Validate header
-	expectedHeader := []string{"product-mnemo-id", "product-code", "product-name", "mode", "license-terms-id", "notes"}
+	expectedHeader := productCodesHeader
 	if !equalHeaders(header, expectedHeader) {
 		return fmt.Errorf("invalid CSV header, expected: %v", expectedHeader)
 	}
 
-	tx, err := l.db.Begin()
+	tx, err := l.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to start transaction: %w", err)
 	}
@@ -161,6 +209,7 @@ func (l *ReferenceDataLoader) LoadProductCodesCSV(filePath string) error {
 
 	insertedCount := 0
 	updatedCount := 0
+	keptCount := 0
 
 	// Read records
 	for {
@@ -192,20 +241,20 @@ func (l *ReferenceDataLoader) LoadProductCodesCSV(filePath string) error {
 
 		// First ensure license term exists
 		if licenseTermsID != "" {
-			err = l.ensureLicenseTerm(tx, licenseTermsID)
+			err = l.ensureLicenseTerm(tx, licenseTermsID, source)
 			if err != nil {
 				return fmt.Errorf("failed to ensure license term %s: %w", licenseTermsID, err)
 			}
 		}
 
 		// Check if product code already exists
-		var count int
-		err = tx.QueryRow("SELECT COUNT(*) FROM product_codes WHERE product_mnemo_code = ?", productMnemoID).Scan(&count)
+		current, err := productCodesHistory.current(tx, productMnemoID)
 		if err != nil {
 			return fmt.Errorf("failed to check product code existence: %w", err)
 		}
+		values := []string{productCode, productName, mode, licenseTermsID, notes}
 
-		if count == 0 {
+		if current == nil {
 			// Insert new product code
 			_, err = tx.Exec(`
 				INSERT INTO product_codes 
@@ -215,7 +264,12 @@ func (l *ReferenceDataLoader) LoadProductCodesCSV(filePath string) error {
 			if err != nil {
 				return fmt.Errorf("failed to insert product code %s: %w", productMnemoID, err)
 			}
+			if err := productCodesHistory.record(tx, productMnemoID, nil, values, source, l.ChangedBy); err != nil {
+				return err
+			}
 			insertedCount++
+		} else if !overwrite {
+			keptCount++
 		} else {
 			// Update existing product code
 			_, err = tx.Exec(`
@@ -227,6 +281,148 @@ func (l *ReferenceDataLoader) LoadProductCodesCSV(filePath string) error {
 			if err != nil {
 				return fmt.Errorf("failed to update product code %s: %w", productMnemoID, err)
 			}
+			if err := productCodesHistory.record(tx, productMnemoID, current, values, source, l.ChangedBy); err != nil {
+				return err
+			}
+			updatedCount++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	fmt.Printf("Product codes loaded: %d inserted, %d updated%s\n", insertedCount, updatedCount, keptSuffix(keptCount))
+	return nil
+}
+
+// LoadEntitlementsCSV loads licensed capacity per license term from CSV file
+// CSV format: license-terms-id,licensed-cores,licensed-pvu,notes
+func (l *ReferenceDataLoader) LoadEntitlementsCSV(ctx context.Context, filePath string) error {
+	return l.loadEntitlements(ctx, filePath, "")
+}
+
+// LoadOrgEntitlementsCSV loads the licensed capacity of an organization per
+// license term from a CSV file of the LoadEntitlementsCSV format. The
+// organization must exist.
+func (l *ReferenceDataLoader) LoadOrgEntitlementsCSV(ctx context.Context, filePath, orgID string) error {
+	if err := RequireOrg(l.db, orgID); err != nil {
+		return err
+	}
+	return l.loadEntitlements(ctx, filePath, orgID)
+}
+
+// loadEntitlements loads entitlements into the entitlements table, or into
+// org_entitlements for an organization
+func (l *ReferenceDataLoader) loadEntitlements(ctx context.Context, filePath, orgID string) (err error) {
+	table, key, keyArgs := "entitlements", "term_id = ?", []interface{}{}
+	if orgID != "" {
+		table, key, keyArgs = "org_entitlements", "org_id = ? AND term_id = ?", []interface{}{orgID}
+	}
+	span := traceTable("upsert", table)
+	span.SetAttributes(tracing.String("file.path", filePath))
+	defer func() { span.End(err) }()
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1 // Allow variable number of fields
+	reader.TrimLeadingSpace = true
+
+	// Read header
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+
+	// Validate header
+	expectedHeader := entitlementsHeader
+	if !equalHeaders(header, expectedHeader) {
+		return fmt.Errorf("invalid CSV header, expected: %v", expectedHeader)
+	}
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertedCount := 0
+	updatedCount := 0
+
+	// Read records
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read row: %w", err)
+		}
+
+		if len(row) < 3 {
+			continue // Skip incomplete rows
+		}
+
+		termID := strings.TrimSpace(row[0])
+		if termID == "" {
+			continue // Skip empty rows
+		}
+
+		licensedCores, err := parseEntitlementCount(row[1])
+		if err != nil {
+			return fmt.Errorf("invalid licensed-cores for %s: %w", termID, err)
+		}
+		licensedPVU, err := parseEntitlementCount(row[2])
+		if err != nil {
+			return fmt.Errorf("invalid licensed-pvu for %s: %w", termID, err)
+		}
+		notes := ""
+		if len(row) > 3 {
+			notes = strings.TrimSpace(row[3])
+		}
+
+		// Entitlements reference license terms
+		if err := l.ensureLicenseTerm(tx, termID, filePath); err != nil {
+			return fmt.Errorf("failed to ensure license term %s: %w", termID, err)
+		}
+
+		// Check if entitlement already exists
+		keys := append(keyArgs, termID)
+		var count int
+		err = tx.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE "+key, keys...).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to check entitlement existence: %w", err)
+		}
+
+		if count == 0 {
+			if orgID != "" {
+				_, err = tx.Exec(`
+					INSERT INTO org_entitlements (org_id, term_id, licensed_cores, licensed_pvu, notes)
+					VALUES (?, ?, ?, ?, ?)
+				`, orgID, termID, licensedCores, licensedPVU, notes)
+			} else {
+				_, err = tx.Exec(`
+					INSERT INTO entitlements (term_id, licensed_cores, licensed_pvu, notes)
+					VALUES (?, ?, ?, ?)
+				`, termID, licensedCores, licensedPVU, notes)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to insert entitlement %s: %w", termID, err)
+			}
+			insertedCount++
+		} else {
+			_, err = tx.Exec(`
+				UPDATE `+table+`
+				SET licensed_cores = ?, licensed_pvu = ?, notes = ?, updated_at = CURRENT_TIMESTAMP
+				WHERE `+key, append([]interface{}{licensedCores, licensedPVU, notes}, keys...)...)
+			if err != nil {
+				return fmt.Errorf("failed to update entitlement %s: %w", termID, err)
+			}
 			updatedCount++
 		}
 	}
@@ -235,12 +431,351 @@ func (l *ReferenceDataLoader) LoadProductCodesCSV(filePath string) error {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	fmt.Printf("Product codes loaded: %d inserted, %d updated\n", insertedCount, updatedCount)
+	if orgID != "" {
+		fmt.Printf("Entitlements of %s loaded: %d inserted, %d updated\n", orgID, insertedCount, updatedCount)
+	} else {
+		fmt.Printf("Entitlements loaded: %d inserted, %d updated\n", insertedCount, updatedCount)
+	}
 	return nil
 }
 
-// ensureLicenseTerm creates license term if it doesn't exist
-func (l *ReferenceDataLoader) ensureLicenseTerm(tx *sql.Tx, termID string) error {
+// parseEntitlementCount parses a non-negative capacity value (empty means 0)
+func parseEntitlementCount(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("value must not be negative: %d", n)
+	}
+	return n, nil
+}
+
+// LoadProductThresholdsCSV loads the highest license cores allowed per product
+// from CSV file. The products must be known.
+func (l *ReferenceDataLoader) LoadProductThresholdsCSV(ctx context.Context, filePath string) (err error) {
+	span := traceTable("upsert", "product_thresholds")
+	span.SetAttributes(tracing.String("file.path", filePath))
+	defer func() { span.End(err) }()
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1 // Allow variable number of fields
+	reader.TrimLeadingSpace = true
+
+	// Read header
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+
+	// Validate header
+	if !equalHeaders(header, thresholdsHeader) {
+		return fmt.Errorf("invalid CSV header, expected: %v", thresholdsHeader)
+	}
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertedCount := 0
+	updatedCount := 0
+
+	// Read records
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read row: %w", err)
+		}
+
+		if len(row) < 2 {
+			continue // Skip incomplete rows
+		}
+
+		productCode := strings.TrimSpace(row[0])
+		if productCode == "" {
+			continue // Skip empty rows
+		}
+
+		maxCores, err := parseEntitlementCount(row[1])
+		if err != nil {
+			return fmt.Errorf("invalid max-license-cores for %s: %w", productCode, err)
+		}
+		notes := ""
+		if len(row) > 2 {
+			notes = strings.TrimSpace(row[2])
+		}
+
+		var known int
+		err = tx.QueryRow("SELECT COUNT(*) FROM product_codes WHERE product_mnemo_code = ?", productCode).Scan(&known)
+		if err != nil {
+			return fmt.Errorf("failed to check product code: %w", err)
+		}
+		if known == 0 {
+			return fmt.Errorf("unknown product code %s (load the product codes first)", productCode)
+		}
+
+		// Check if threshold already exists
+		var count int
+		err = tx.QueryRow("SELECT COUNT(*) FROM product_thresholds WHERE product_mnemo_code = ?", productCode).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to check threshold existence: %w", err)
+		}
+
+		if count == 0 {
+			_, err = tx.Exec(`
+				INSERT INTO product_thresholds (product_mnemo_code, max_license_cores, notes)
+				VALUES (?, ?, ?)
+			`, productCode, maxCores, notes)
+			if err != nil {
+				return fmt.Errorf("failed to insert threshold %s: %w", productCode, err)
+			}
+			insertedCount++
+		} else {
+			_, err = tx.Exec(`
+				UPDATE product_thresholds
+				SET max_license_cores = ?, notes = ?, updated_at = CURRENT_TIMESTAMP
+				WHERE product_mnemo_code = ?
+			`, maxCores, notes, productCode)
+			if err != nil {
+				return fmt.Errorf("failed to update threshold %s: %w", productCode, err)
+			}
+			updatedCount++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	fmt.Printf("Product thresholds loaded: %d inserted, %d updated\n", insertedCount, updatedCount)
+	return nil
+}
+
+// LoadPeakGraceWindowsCSV loads the grace window of peak smoothing per product
+// from CSV file: the smoothed peak ignores spikes shorter than grace-days
+// consecutive days. The products must be known.
+func (l *ReferenceDataLoader) LoadPeakGraceWindowsCSV(ctx context.Context, filePath string) (err error) {
+	span := traceTable("upsert", "peak_grace_windows")
+	span.SetAttributes(tracing.String("file.path", filePath))
+	defer func() { span.End(err) }()
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1 // Allow variable number of fields
+	reader.TrimLeadingSpace = true
+
+	// Read header
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+
+	// Validate header
+	if !equalHeaders(header, graceWindowsHeader) {
+		return fmt.Errorf("invalid CSV header, expected: %v", graceWindowsHeader)
+	}
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	loadedCount := 0
+
+	// Read records
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read row: %w", err)
+		}
+
+		if len(row) < 2 {
+			continue // Skip incomplete rows
+		}
+
+		productCode := strings.TrimSpace(row[0])
+		if productCode == "" {
+			continue // Skip empty rows
+		}
+
+		graceDays, err := strconv.Atoi(strings.TrimSpace(row[1]))
+		if err != nil || graceDays < 1 {
+			return fmt.Errorf("invalid grace-days for %s: %q (expected a number of days of at least 1)", productCode, row[1])
+		}
+		notes := ""
+		if len(row) > 2 {
+			notes = strings.TrimSpace(row[2])
+		}
+
+		var known int
+		err = tx.QueryRow("SELECT COUNT(*) FROM product_codes WHERE product_mnemo_code = ?", productCode).Scan(&known)
+		if err != nil {
+			return fmt.Errorf("failed to check product code: %w", err)
+		}
+		if known == 0 {
+			return fmt.Errorf("unknown product code %s (load the product codes first)", productCode)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO peak_grace_windows (product_mnemo_code, grace_days, notes)
+			VALUES (?, ?, ?)
+			ON CONFLICT (product_mnemo_code) DO UPDATE SET
+				grace_days = excluded.grace_days,
+				notes = excluded.notes,
+				updated_at = CURRENT_TIMESTAMP
+		`, productCode, graceDays, notes)
+		if err != nil {
+			return fmt.Errorf("failed to load grace window %s: %w", productCode, err)
+		}
+		loadedCount++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	fmt.Printf("Peak grace windows loaded: %d\n", loadedCount)
+	return nil
+}
+
+// LoadPVUMappingsCSV loads the processor value units per core from CSV file
+func (l *ReferenceDataLoader) LoadPVUMappingsCSV(ctx context.Context, filePath string) (err error) {
+	span := traceTable("upsert", "pvu_mappings")
+	span.SetAttributes(tracing.String("file.path", filePath))
+	defer func() { span.End(err) }()
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1 // Allow variable number of fields
+	reader.TrimLeadingSpace = true
+
+	// Read header
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+
+	// Validate header
+	expectedHeader := pvuMappingsHeader
+	if !equalHeaders(header, expectedHeader) {
+		return fmt.Errorf("invalid CSV header, expected: %v", expectedHeader)
+	}
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertedCount := 0
+	updatedCount := 0
+
+	// Read records
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read row: %w", err)
+		}
+
+		if len(row) < 4 {
+			continue // Skip incomplete rows
+		}
+
+		vendor := strings.TrimSpace(row[0])
+		brand := strings.TrimSpace(row[1])
+		model := strings.TrimSpace(row[2])
+		if vendor == "" || brand == "" {
+			continue // Skip empty rows
+		}
+		key := strings.Join([]string{vendor, brand, model}, "/")
+
+		pvuPerCore, err := parseEntitlementCount(row[3])
+		if err != nil {
+			return fmt.Errorf("invalid pvu-per-core for %s: %w", key, err)
+		}
+		if pvuPerCore == 0 {
+			return fmt.Errorf("invalid pvu-per-core for %s: value must be positive", key)
+		}
+		notes := ""
+		if len(row) > 4 {
+			notes = strings.TrimSpace(row[4])
+		}
+
+		// Check if mapping already exists
+		var count int
+		err = tx.QueryRow(`
+			SELECT COUNT(*) FROM pvu_mappings
+			WHERE processor_vendor = ? AND processor_brand = ? AND processor_model = ?
+		`, vendor, brand, model).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to check PVU mapping existence: %w", err)
+		}
+
+		if count == 0 {
+			_, err = tx.Exec(`
+				INSERT INTO pvu_mappings (processor_vendor, processor_brand, processor_model, pvu_per_core, notes)
+				VALUES (?, ?, ?, ?, ?)
+			`, vendor, brand, model, pvuPerCore, notes)
+			if err != nil {
+				return fmt.Errorf("failed to insert PVU mapping %s: %w", key, err)
+			}
+			insertedCount++
+		} else {
+			_, err = tx.Exec(`
+				UPDATE pvu_mappings
+				SET pvu_per_core = ?, notes = ?, updated_at = CURRENT_TIMESTAMP
+				WHERE processor_vendor = ? AND processor_brand = ? AND processor_model = ?
+			`, pvuPerCore, notes, vendor, brand, model)
+			if err != nil {
+				return fmt.Errorf("failed to update PVU mapping %s: %w", key, err)
+			}
+			updatedCount++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	fmt.Printf("PVU mappings loaded: %d inserted, %d updated\n", insertedCount, updatedCount)
+	return nil
+}
+
+// ensureLicenseTerm creates license term if it doesn't exist, recording the
+// placeholder in license_terms_history
+func (l *ReferenceDataLoader) ensureLicenseTerm(tx *sql.Tx, termID, source string) error {
 	var count int
 	err := tx.QueryRow("SELECT COUNT(*) FROM license_terms WHERE term_id = ?", termID).Scan(&count)
 	if err != nil {
@@ -256,11 +791,20 @@ func (l *ReferenceDataLoader) ensureLicenseTerm(tx *sql.Tx, termID string) error
 		if err != nil {
 			return fmt.Errorf("failed to insert license term: %w", err)
 		}
+		return licenseTermsHistory.record(tx, termID, nil, []string{"Unknown", "License term " + termID}, source, l.ChangedBy)
 	}
 
 	return nil
 }
 
+// keptSuffix describes the existing rows a load without overwrite kept
+func keptSuffix(kept int) string {
+	if kept == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", %d already present", kept)
+}
+
 // equalHeaders compares two header slices
 func equalHeaders(a, b []string) bool {
 	if len(a) != len(b) {
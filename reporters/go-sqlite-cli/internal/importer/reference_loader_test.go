@@ -0,0 +1,144 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestLoadEntitlementsCSV(t *testing.T) {
+	db := setupImportDB(t)
+	csvPath := filepath.Join(t.TempDir(), "entitlements.csv")
+	writeFile(t, csvPath, `license-terms-id,licensed-cores,licensed-pvu,notes
+T1,40,0,Integration Server
+T2,8,560,
+`)
+
+	loader := importer.NewReferenceDataLoader(db)
+	if err := loader.LoadEntitlementsCSV(t.Context(), csvPath); err != nil {
+		t.Fatalf("LoadEntitlementsCSV failed: %v", err)
+	}
+
+	var cores, pvu int
+	err := db.QueryRow("SELECT licensed_cores, licensed_pvu FROM entitlements WHERE term_id = 'T2'").Scan(&cores, &pvu)
+	if err != nil {
+		t.Fatalf("Failed to read entitlement: %v", err)
+	}
+	if cores != 8 || pvu != 560 {
+		t.Errorf("Expected 8 cores / 560 PVU, got %d / %d", cores, pvu)
+	}
+
+	// Unknown terms get a placeholder license term so the foreign key holds
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM license_terms WHERE term_id = 'T2'").Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected placeholder license term T2 to be created")
+	}
+
+	// Reloading updates in place
+	writeFile(t, csvPath, "license-terms-id,licensed-cores,licensed-pvu,notes\nT1,64,0,\n")
+	if err := loader.LoadEntitlementsCSV(t.Context(), csvPath); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	db.QueryRow("SELECT licensed_cores FROM entitlements WHERE term_id = 'T1'").Scan(&cores)
+	if cores != 64 {
+		t.Errorf("Expected updated licensed cores 64, got %d", cores)
+	}
+}
+
+func TestLoadEntitlementsCSVRejectsInvalidValues(t *testing.T) {
+	db := setupImportDB(t)
+	csvPath := filepath.Join(t.TempDir(), "entitlements.csv")
+
+	writeFile(t, csvPath, "license-terms-id,licensed-cores,licensed-pvu,notes\nT1,-5,0,\n")
+	if err := importer.NewReferenceDataLoader(db).LoadEntitlementsCSV(t.Context(), csvPath); err == nil {
+		t.Error("Expected error for negative licensed cores")
+	}
+
+	writeFile(t, csvPath, "term,cores\nT1,5\n")
+	if err := importer.NewReferenceDataLoader(db).LoadEntitlementsCSV(t.Context(), csvPath); err == nil {
+		t.Error("Expected error for invalid header")
+	}
+}
+
+func TestLoadProductThresholdsCSV(t *testing.T) {
+	db := setupImportDB(t)
+	csvPath := filepath.Join(t.TempDir(), "product-thresholds.csv")
+	writeFile(t, csvPath, "product-mnemo-id,max-license-cores,notes\nIS_ONP_PRD,24,half of the term\n")
+
+	loader := importer.NewReferenceDataLoader(db)
+	if err := loader.LoadProductThresholdsCSV(t.Context(), csvPath); err != nil {
+		t.Fatalf("LoadProductThresholdsCSV failed: %v", err)
+	}
+
+	var maxCores int
+	err := db.QueryRow("SELECT max_license_cores FROM product_thresholds WHERE product_mnemo_code = 'IS_ONP_PRD'").Scan(&maxCores)
+	if err != nil {
+		t.Fatalf("Failed to read threshold: %v", err)
+	}
+	if maxCores != 24 {
+		t.Errorf("Expected 24 cores, got %d", maxCores)
+	}
+
+	// Thresholds only apply to known products
+	writeFile(t, csvPath, "product-mnemo-id,max-license-cores,notes\nNOPE_PRD,8,\n")
+	if err := loader.LoadProductThresholdsCSV(t.Context(), csvPath); err == nil {
+		t.Error("Expected error for unknown product code")
+	}
+
+	writeFile(t, csvPath, "product-mnemo-id,max-license-cores,notes\nIS_ONP_PRD,-1,\n")
+	if err := loader.LoadProductThresholdsCSV(t.Context(), csvPath); err == nil {
+		t.Error("Expected error for negative threshold")
+	}
+}
+
+func TestLoadPVUMappingsCSV(t *testing.T) {
+	db := setupImportDB(t)
+	csvPath := filepath.Join(t.TempDir(), "pvu-table.csv")
+	writeFile(t, csvPath, `processor-vendor,processor-brand,processor-model,pvu-per-core,notes
+IBM,POWER9,,100,scale-out
+IBM,POWER9,E980,120,
+`)
+
+	loader := importer.NewReferenceDataLoader(db)
+	if err := loader.LoadPVUMappingsCSV(t.Context(), csvPath); err != nil {
+		t.Fatalf("LoadPVUMappingsCSV failed: %v", err)
+	}
+
+	var count, pvu int
+	db.QueryRow("SELECT COUNT(*) FROM pvu_mappings").Scan(&count)
+	if count != 2 {
+		t.Errorf("Expected 2 PVU mappings, got %d", count)
+	}
+
+	// Reloading updates in place
+	writeFile(t, csvPath, "processor-vendor,processor-brand,processor-model,pvu-per-core,notes\nIBM,POWER9,,70,\n")
+	if err := loader.LoadPVUMappingsCSV(t.Context(), csvPath); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	db.QueryRow("SELECT pvu_per_core FROM pvu_mappings WHERE processor_brand = 'POWER9' AND processor_model = ''").Scan(&pvu)
+	if pvu != 70 {
+		t.Errorf("Expected updated PVU per core 70, got %d", pvu)
+	}
+
+	// A mapping must count PVUs
+	writeFile(t, csvPath, "processor-vendor,processor-brand,processor-model,pvu-per-core,notes\nIBM,POWER8,,0,\n")
+	if err := loader.LoadPVUMappingsCSV(t.Context(), csvPath); err == nil {
+		t.Error("Expected an error for a zero PVU per core")
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RollbackResult describes the data removed by rolling back an import session
+type RollbackResult struct {
+	SessionID          string
+	SourceFile         string
+	MainFQDN           string
+	DetectionTimestamp time.Time
+	Measurements       int
+	DetectedProducts   int
+	Installs           int
+	Processes          int
+}
+
+// Rollback deletes the measurement written by an import session, with its
+// detected products, install paths and processes, and the session itself so
+// that the file can be imported again. Landscape nodes and physical hosts are
+// kept. With dryRun the deletes are counted and rolled back.
+func (s *ImportService) Rollback(ctx context.Context, sessionID string, dryRun bool) (*RollbackResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &RollbackResult{SessionID: sessionID}
+	var mainFQDN sql.NullString
+	var timestamp sql.NullTime
+	var hostname string
+	err = tx.QueryRow(`
+		SELECT source_file, hostname, main_fqdn, detection_timestamp
+		FROM import_sessions WHERE session_id = ?
+	`, sessionID).Scan(&result.SourceFile, &hostname, &mainFQDN, &timestamp)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("import session %s not found", sessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import session %s: %w", sessionID, err)
+	}
+
+	// Sessions recorded before the measurement key was stored are matched on
+	// the hostname and timestamp the session ID is made of
+	if !mainFQDN.Valid || !timestamp.Valid {
+		err = tx.QueryRow(`
+			SELECT m.main_fqdn, m.detection_timestamp
+			FROM measurements m
+			JOIN landscape_nodes n ON m.main_fqdn = n.main_fqdn
+			WHERE n.hostname = ?
+			  AND ? = n.hostname || '_' || strftime('%Y%m%d_%H%M%S', m.detection_timestamp)
+		`, hostname, sessionID).Scan(&mainFQDN, &timestamp)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to find the measurement of session %s: %w", sessionID, err)
+		}
+	}
+	result.MainFQDN = mainFQDN.String
+	result.DetectionTimestamp = timestamp.Time
+
+	if mainFQDN.Valid && timestamp.Valid {
+		deletes := []struct {
+			count *int
+			query string
+		}{
+			{&result.Installs, "DELETE FROM detected_product_installs WHERE main_fqdn = ? AND detection_timestamp = ?"},
+			{&result.Processes, "DELETE FROM detected_product_processes WHERE main_fqdn = ? AND detection_timestamp = ?"},
+			{&result.DetectedProducts, "DELETE FROM detected_products WHERE main_fqdn = ? AND detection_timestamp = ?"},
+			{&result.Measurements, "DELETE FROM measurements WHERE main_fqdn = ? AND detection_timestamp = ?"},
+		}
+		for _, d := range deletes {
+			res, err := tx.Exec(d.query, mainFQDN.String, timestamp.Time)
+			if err != nil {
+				return nil, fmt.Errorf("failed to roll back session %s: %w", sessionID, err)
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return nil, err
+			}
+			*d.count = int(n)
+		}
+
+		if err := refreshProductLifecycle(tx, mainFQDN.String); err != nil {
+			return nil, err
+		}
+		if err := refreshProductInstances(tx, mainFQDN.String); err != nil {
+			return nil, err
+		}
+		if err := refreshNodeChanges(tx, mainFQDN.String); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM import_sessions WHERE session_id = ?", sessionID); err != nil {
+		return nil, fmt.Errorf("failed to delete import session %s: %w", sessionID, err)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
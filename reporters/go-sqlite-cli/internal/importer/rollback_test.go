@@ -0,0 +1,101 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestRollback(t *testing.T) {
+	db := setupImportDB(t)
+
+	file := filepath.Join(t.TempDir(), "iwdli_output_host1_20251021_090906.csv")
+	writeFile(t, file, testInspectorCSV+"IS_ONP_PRD_INSTALL_PATH_01,/opt/sag/is\n")
+
+	service := importer.NewImportService(db)
+	imported, err := service.ImportCSVFile(t.Context(), file)
+	if err != nil {
+		t.Fatalf("ImportCSVFile failed: %v", err)
+	}
+
+	count := func(table string) int {
+		t.Helper()
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+			t.Fatalf("Failed to count %s: %v", table, err)
+		}
+		return n
+	}
+
+	// A dry run reports the rows without removing them
+	dry, err := service.Rollback(t.Context(), imported.SessionID, true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if dry.Measurements != 1 || dry.DetectedProducts != 1 || dry.Installs != 1 {
+		t.Errorf("Expected 1 measurement, product and install, got %+v", dry)
+	}
+	if count("measurements") != 1 || count("import_sessions") != 1 {
+		t.Fatal("Dry run removed data")
+	}
+
+	result, err := service.Rollback(t.Context(), imported.SessionID, false)
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if result.MainFQDN == "" || result.DetectionTimestamp.IsZero() {
+		t.Errorf("Expected the measurement key in the result, got %+v", result)
+	}
+	for _, table := range []string{"measurements", "detected_products", "detected_product_installs", "import_sessions"} {
+		if n := count(table); n != 0 {
+			t.Errorf("Expected %s to be empty, got %d rows", table, n)
+		}
+	}
+	if count("landscape_nodes") != 1 {
+		t.Error("Expected the landscape node to be kept")
+	}
+
+	if _, err := service.Rollback(t.Context(), imported.SessionID, false); err == nil {
+		t.Error("Expected an error rolling back an unknown session")
+	}
+
+	// The file can be imported again once rolled back
+	reimported, err := service.ImportCSVFile(t.Context(), file)
+	if err != nil {
+		t.Fatalf("Re-import failed: %v", err)
+	}
+	if reimported.AlreadyImported || count("measurements") != 1 {
+		t.Errorf("Expected the file to be imported again, got %+v", reimported)
+	}
+
+	// Sessions recorded without the measurement key are matched on their ID
+	if _, err := db.Exec("UPDATE import_sessions SET main_fqdn = NULL, detection_timestamp = NULL"); err != nil {
+		t.Fatalf("Failed to clear the measurement key: %v", err)
+	}
+	legacy, err := service.Rollback(t.Context(), reimported.SessionID, false)
+	if err != nil {
+		t.Fatalf("Legacy rollback failed: %v", err)
+	}
+	if legacy.Measurements != 1 || count("measurements") != 0 {
+		t.Errorf("Expected the legacy session measurement to be removed, got %+v", legacy)
+	}
+
+	if count("import_sessions") != 0 {
+		t.Error("Expected no import session left")
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// Signature statuses recorded on import sessions; sessions imported without
+// signature verification have none
+const (
+	// SignatureGPG is a file verified against a detached OpenPGP signature
+	// (<file>.asc or <file>.sig) made by a key of the keyring
+	SignatureGPG = "gpg"
+	// SignatureSHA256 is a file verified against a SHA-256 checksum file
+	// (<file>.sha256, in sha256sum format)
+	SignatureSHA256 = "sha256"
+)
+
+// signatureExtensions are the signature files looked up next to a file, in
+// order of preference
+var signatureExtensions = []string{".asc", ".sig", ".sha256"}
+
+// gpgSignatureExtensions are the OpenPGP signature files among signatureExtensions
+var gpgSignatureExtensions = []string{".asc", ".sig"}
+
+// SignatureVerification is the outcome of a successful signature check
+type SignatureVerification struct {
+	Status string
+	// Signer is the key ID and user ID of the OpenPGP key that made the
+	// signature; empty for checksums
+	Signer string
+	// SignatureFile is the signature or checksum file that was checked
+	SignatureFile string
+}
+
+// SignatureVerifier checks inspector files against the signature files
+// delivered with them, so that files altered in transit are rejected
+type SignatureVerifier struct {
+	keyring openpgp.EntityList
+}
+
+// NewSignatureVerifier creates a verifier trusting the public keys of an
+// OpenPGP keyring file, binary or ASCII armored (e.g. 'gpg --export' with or
+// without --armor). With a keyring every file needs an OpenPGP signature:
+// anyone able to alter a file in transit can also replace its checksum file.
+// Without a keyring only checksum files can be verified.
+func NewSignatureVerifier(keyringPath string) (*SignatureVerifier, error) {
+	v := &SignatureVerifier{}
+	if keyringPath == "" {
+		return v, nil
+	}
+
+	data, err := os.ReadFile(keyringPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+	if isArmored(data) {
+		v.keyring, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	} else {
+		v.keyring, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring %s: %w", keyringPath, err)
+	}
+	if len(v.keyring) == 0 {
+		return nil, fmt.Errorf("keyring %s holds no keys", keyringPath)
+	}
+	return v, nil
+}
+
+// isArmored reports whether OpenPGP data is ASCII armored
+func isArmored(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN PGP"))
+}
+
+// signedFile returns the file a signature is delivered for: the bundle of a
+// bundle member, the file itself otherwise
+func signedFile(filePath string) string {
+	if _, err := os.Stat(filePath); err != nil {
+		if bundle, _, ok := SplitBundlePath(filePath); ok {
+			return bundle
+		}
+	}
+	return filePath
+}
+
+// SignatureFiles returns the signature and checksum files found next to a
+// file, so that they can be moved with it
+func SignatureFiles(filePath string) []string {
+	return findSignatureFiles(filePath, signatureExtensions)
+}
+
+// findSignatureFiles returns the files next to a file with the extensions, in
+// their order
+func findSignatureFiles(filePath string, extensions []string) []string {
+	var files []string
+	for _, ext := range extensions {
+		if info, err := os.Stat(filePath + ext); err == nil && !info.IsDir() {
+			files = append(files, filePath+ext)
+		}
+	}
+	return files
+}
+
+// VerifyFile checks a file against the first signature file found next to
+// it: an OpenPGP signature when the verifier has a keyring, whose checksum
+// files are ignored, and otherwise a checksum. The signature covers the file
+// as delivered, before decompression; the members of a bundle are covered by
+// the signature of the bundle. A file without a signature file fails.
+func (v *SignatureVerifier) VerifyFile(filePath string) (*SignatureVerification, error) {
+	signed := signedFile(filePath)
+	data, err := os.ReadFile(signed)
+	if err != nil {
+		return nil, err
+	}
+	return v.Verify(signed, data)
+}
+
+// Verify checks the content of a delivered file, read by the caller, like
+// VerifyFile, so that the content verified is the content the caller uses
+func (v *SignatureVerifier) Verify(signed string, data []byte) (*SignatureVerification, error) {
+	signatures := v.signatureFiles(signed)
+	if len(signatures) == 0 {
+		return nil, v.missingSignature(signed)
+	}
+	signature := signatures[0]
+
+	if strings.HasSuffix(signature, ".sha256") {
+		return verifyChecksum(bytes.NewReader(data), signed, signature)
+	}
+	return v.verifyGPG(bytes.NewReader(data), signed, signature)
+}
+
+// signatureFiles returns the signature files next to a file the verifier
+// accepts, in order of preference
+func (v *SignatureVerifier) signatureFiles(signed string) []string {
+	if len(v.keyring) > 0 {
+		return findSignatureFiles(signed, gpgSignatureExtensions)
+	}
+	return findSignatureFiles(signed, signatureExtensions)
+}
+
+// missingSignature returns the error of a file without a signature file the
+// verifier accepts
+func (v *SignatureVerifier) missingSignature(signed string) error {
+	name := filepath.Base(signed)
+	if len(v.keyring) == 0 {
+		return fmt.Errorf("no signature file found for %s (expected %s.asc, .sig or .sha256)", name, name)
+	}
+	if len(findSignatureFiles(signed, []string{".sha256"})) > 0 {
+		return fmt.Errorf("no OpenPGP signature found for %s (expected %s.asc or .sig): a checksum file is not accepted with a keyring, since it can be replaced with the file",
+			name, name)
+	}
+	return fmt.Errorf("no OpenPGP signature found for %s (expected %s.asc or .sig)", name, name)
+}
+
+// verifyGPG checks a detached OpenPGP signature, binary or ASCII armored
+func (v *SignatureVerifier) verifyGPG(file io.Reader, signed, signature string) (*SignatureVerification, error) {
+	if len(v.keyring) == 0 {
+		return nil, fmt.Errorf("%s is signed with OpenPGP but no keyring was given", filepath.Base(signed))
+	}
+
+	data, err := os.ReadFile(signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature: %w", err)
+	}
+	var signer *openpgp.Entity
+	if isArmored(data) {
+		signer, err = openpgp.CheckArmoredDetachedSignature(v.keyring, file, bytes.NewReader(data), nil)
+	} else {
+		signer, err = openpgp.CheckDetachedSignature(v.keyring, file, bytes.NewReader(data), nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bad signature %s for %s: %w", filepath.Base(signature), filepath.Base(signed), err)
+	}
+
+	return &SignatureVerification{
+		Status:        SignatureGPG,
+		Signer:        signerName(signer),
+		SignatureFile: signature,
+	}, nil
+}
+
+// signerName returns the key ID of a key with its primary user ID, or the
+// first by name when none is marked primary
+func signerName(signer *openpgp.Entity) string {
+	var names []string
+	for name, identity := range signer.Identities {
+		if identity.SelfSignature != nil && identity.SelfSignature.IsPrimaryId != nil && *identity.SelfSignature.IsPrimaryId {
+			return signer.PrimaryKey.KeyIdString() + " " + name
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return signer.PrimaryKey.KeyIdString()
+	}
+	sort.Strings(names)
+	return signer.PrimaryKey.KeyIdString() + " " + names[0]
+}
+
+// verifyChecksum checks a file against its SHA-256 in a checksum file of
+// sha256sum format: one "<hex>  <name>" line per file, or the hash alone
+func verifyChecksum(file io.Reader, signed, checksumFile string) (*SignatureVerification, error) {
+	expected, err := readChecksum(checksumFile, filepath.Base(signed))
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, fmt.Errorf("failed to hash %s: %w", filepath.Base(signed), err)
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		return nil, fmt.Errorf("checksum mismatch for %s: %s expects %s, the file has %s",
+			filepath.Base(signed), filepath.Base(checksumFile), expected, actual)
+	}
+	return &SignatureVerification{Status: SignatureSHA256, SignatureFile: checksumFile}, nil
+}
+
+// readChecksum returns the SHA-256 listed for name in a checksum file, or the
+// only one listed when its line names no file
+func readChecksum(checksumFile, name string) (string, error) {
+	file, err := os.Open(checksumFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum file: %w", err)
+	}
+	defer file.Close()
+
+	var only string
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		lines++
+		hash := strings.ToLower(fields[0])
+		if len(fields) == 1 {
+			only = hash
+			continue
+		}
+		if strings.TrimPrefix(fields[1], "*") == name {
+			return hash, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read checksum file: %w", err)
+	}
+	if lines == 1 && only != "" {
+		return only, nil
+	}
+	return "", fmt.Errorf("checksum file %s lists no SHA-256 for %s", filepath.Base(checksumFile), name)
+}
@@ -0,0 +1,180 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestVerifySignatures(t *testing.T) {
+	db := setupImportDB(t)
+	dir := t.TempDir()
+
+	// A signing key, trusted through its exported public keyring
+	signer, err := openpgp.NewEntity("Collector", "", "collector@example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	var keyring bytes.Buffer
+	if err := signer.Serialize(&keyring); err != nil {
+		t.Fatalf("Failed to export key: %v", err)
+	}
+	keyringFile := filepath.Join(dir, "collectors.gpg")
+	writeFile(t, keyringFile, keyring.String())
+
+	verifier, err := importer.NewSignatureVerifier(keyringFile)
+	if err != nil {
+		t.Fatalf("NewSignatureVerifier failed: %v", err)
+	}
+	service := importer.NewImportService(db)
+	service.Signatures = verifier
+
+	// An unsigned file is rejected
+	file := filepath.Join(dir, "iwdli_output_host1_20251021_090906.csv")
+	writeFile(t, file, testInspectorCSV)
+	if _, err := service.ImportCSVFile(t.Context(), file); err == nil || !strings.Contains(err.Error(), "no OpenPGP signature") {
+		t.Fatalf("Expected an unsigned file to be rejected, got %v", err)
+	}
+
+	// With a keyring a matching checksum file is not enough: whoever altered
+	// the file in transit could have dropped its signature and shipped one
+	sum := sha256.Sum256([]byte(testInspectorCSV))
+	writeFile(t, file+".sha256", hex.EncodeToString(sum[:])+"  "+filepath.Base(file)+"\n")
+	if _, err := service.ImportCSVFile(t.Context(), file); err == nil || !strings.Contains(err.Error(), "checksum file is not accepted with a keyring") {
+		t.Fatalf("Expected a file with only a checksum to be rejected with a keyring, got %v", err)
+	}
+
+	// A file altered after signing is rejected
+	var signature bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&signature, signer, strings.NewReader(testInspectorCSV), nil); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	writeFile(t, file+".asc", signature.String())
+	writeFile(t, file, testInspectorCSV+"IS_ONP_PRD_INSTALL_PATH_01,/opt/sag/is\n")
+	if _, err := service.ImportCSVFile(t.Context(), file); err == nil || !strings.Contains(err.Error(), "bad signature") {
+		t.Fatalf("Expected an altered file to be rejected, got %v", err)
+	}
+
+	// The signed content is imported and its signer recorded on the session
+	writeFile(t, file, testInspectorCSV)
+	result, err := service.ImportCSVFile(t.Context(), file)
+	if err != nil {
+		t.Fatalf("ImportCSVFile failed: %v", err)
+	}
+	if result.Signature == nil || result.Signature.Status != importer.SignatureGPG ||
+		!strings.Contains(result.Signature.Signer, "collector@example.com") {
+		t.Errorf("Expected a gpg signature by the collector key, got %+v", result.Signature)
+	}
+	var status, signerName string
+	if err := db.QueryRow("SELECT signature_status, signature_signer FROM import_sessions WHERE session_id = ?",
+		result.SessionID).Scan(&status, &signerName); err != nil {
+		t.Fatalf("Failed to read import session: %v", err)
+	}
+	if status != importer.SignatureGPG || signerName != result.Signature.Signer {
+		t.Errorf("Expected the signature on the session, got %s %s", status, signerName)
+	}
+
+	// A checksum file in sha256sum format verifies a file without a keyring
+	checksumVerifier, err := importer.NewSignatureVerifier("")
+	if err != nil {
+		t.Fatalf("NewSignatureVerifier failed: %v", err)
+	}
+	other := filepath.Join(dir, "iwdli_output_host1_20251022_090906.csv")
+	writeFile(t, other, strings.Replace(testInspectorCSV, "2025-10-21", "2025-10-22", 1))
+	content, err := os.ReadFile(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum = sha256.Sum256(content)
+	writeFile(t, other+".sha256", hex.EncodeToString(sum[:])+"  "+filepath.Base(other)+"\n")
+	verification, err := checksumVerifier.VerifyFile(other)
+	if err != nil {
+		t.Fatalf("VerifyFile failed: %v", err)
+	}
+	if verification.Status != importer.SignatureSHA256 || verification.Signer != "" {
+		t.Errorf("Expected a sha256 verification, got %+v", verification)
+	}
+	// Verify checks the content read by the caller, not the file on disk
+	if _, err := checksumVerifier.Verify(other, append(content, "EXTRA,1\n"...)); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected a checksum mismatch for altered content, got %v", err)
+	}
+	writeFile(t, other+".sha256", strings.Repeat("0", 64)+"  "+filepath.Base(other)+"\n")
+	if _, err := checksumVerifier.VerifyFile(other); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+
+	// OpenPGP signatures need a keyring
+	if _, err := checksumVerifier.VerifyFile(file); err == nil || !strings.Contains(err.Error(), "no keyring") {
+		t.Errorf("Expected an error without keyring, got %v", err)
+	}
+}
+
+func TestVerifySignedBundle(t *testing.T) {
+	db := setupImportDB(t)
+	bundle := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	writeTarGz(t, bundle, map[string]string{
+		"iwdli_output_host1_20251021_090906.csv":    testInspectorCSV,
+		"iwdli_output_host2_20251021_090906.csv.gz": string(gzipBytes(t, testInspectorCSV)),
+	})
+	content, err := os.ReadFile(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	writeFile(t, bundle+".sha256", hex.EncodeToString(sum[:])+"  "+filepath.Base(bundle)+"\n")
+
+	verifier, err := importer.NewSignatureVerifier("")
+	if err != nil {
+		t.Fatalf("NewSignatureVerifier failed: %v", err)
+	}
+	service := importer.NewImportService(db)
+	service.Signatures = verifier
+
+	// The members are covered by the checksum of their bundle
+	batch := service.ImportFiles(t.Context(), importer.ExpandBundles([]string{bundle}), nil)
+	if batch.FilesOK != 2 || batch.FilesFailed != 0 {
+		t.Fatalf("Expected 2 ok / 0 failed, got %d / %d: %+v", batch.FilesOK, batch.FilesFailed, batch.Files)
+	}
+	for _, fr := range batch.Files {
+		if fr.Result.Signature == nil || fr.Result.Signature.Status != importer.SignatureSHA256 {
+			t.Errorf("Expected a sha256 verification of %s, got %+v", fr.FilePath, fr.Result.Signature)
+		}
+	}
+
+	// A bundle altered after its checksum was taken fails for every member
+	writeTarGz(t, bundle, map[string]string{
+		"iwdli_output_host1_20251021_090906.csv":    testInspectorCSV,
+		"iwdli_output_host2_20251021_090906.csv.gz": string(gzipBytes(t, strings.Replace(testInspectorCSV, "2025-10-21", "2025-10-22", 1))),
+	})
+	service.Force = true
+	batch = service.ImportFiles(t.Context(), importer.ExpandBundles([]string{bundle}), nil)
+	if batch.FilesFailed != 2 {
+		t.Fatalf("Expected both members of the altered bundle to fail, got %+v", batch.Files)
+	}
+	for _, fr := range batch.Files {
+		if !strings.Contains(fr.Err.Error(), "checksum mismatch") {
+			t.Errorf("Expected a checksum mismatch for %s, got %v", fr.FilePath, fr.Err)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+)
+
+// SiteEditor manages the sites (datacenters or clusters) landscape nodes are
+// assigned to, so that license usage can be reported per site
+type SiteEditor struct {
+	db *sql.DB
+}
+
+// NewSiteEditor creates a new site editor
+func NewSiteEditor(db *sql.DB) *SiteEditor {
+	return &SiteEditor{db: db}
+}
+
+// Save creates the site or updates the name and description of an existing
+// one. It reports whether the site was created.
+func (e *SiteEditor) Save(siteID, name, description string) (bool, error) {
+	siteID = strings.TrimSpace(siteID)
+	if siteID == "" {
+		return false, fmt.Errorf("site ID must not be empty")
+	}
+	if name == "" {
+		name = siteID
+	}
+
+	res, err := e.db.Exec(`
+		UPDATE sites SET site_name = ?, description = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE site_id = ?
+	`, name, description, siteID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update site %s: %w", siteID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return false, err
+	}
+
+	_, err = e.db.Exec(`INSERT INTO sites (site_id, site_name, description) VALUES (?, ?, ?)`, siteID, name, description)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert site %s: %w", siteID, err)
+	}
+	return true, nil
+}
+
+// Remove deletes a site. Sites with assigned nodes are only removed with
+// unassign, which clears the site of those nodes; it returns their number.
+func (e *SiteEditor) Remove(siteID string, unassign bool) (int, error) {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := requireSite(tx, siteID); err != nil {
+		return 0, err
+	}
+
+	var nodes int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM landscape_nodes WHERE site_id = ?", siteID).Scan(&nodes); err != nil {
+		return 0, fmt.Errorf("failed to count nodes of site %s: %w", siteID, err)
+	}
+	if nodes > 0 && !unassign {
+		return 0, fmt.Errorf("site %s has %d node(s) assigned (unassign them first)", siteID, nodes)
+	}
+
+	if _, err := tx.Exec("UPDATE landscape_nodes SET site_id = NULL, updated_at = CURRENT_TIMESTAMP WHERE site_id = ?", siteID); err != nil {
+		return 0, fmt.Errorf("failed to unassign nodes of site %s: %w", siteID, err)
+	}
+	if _, err := tx.Exec("DELETE FROM sites WHERE site_id = ?", siteID); err != nil {
+		return 0, fmt.Errorf("failed to delete site %s: %w", siteID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nodes, nil
+}
+
+// Assign sets the site of the given landscape nodes. An empty siteID clears
+// their site. All nodes must exist; nothing is changed otherwise.
+func (e *SiteEditor) Assign(siteID string, fqdns []string) error {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var site interface{}
+	if siteID != "" {
+		if err := requireSite(tx, siteID); err != nil {
+			return err
+		}
+		site = siteID
+	}
+
+	for _, fqdn := range fqdns {
+		res, err := tx.Exec(`
+			UPDATE landscape_nodes SET site_id = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE main_fqdn = ?
+		`, site, fqdn)
+		if err != nil {
+			return fmt.Errorf("failed to assign node %s: %w", fqdn, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("landscape node %s not found (nodes are created by their first import)", fqdn)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListSites returns all sites with the number of nodes assigned to them
+func (e *SiteEditor) ListSites() ([]models.Site, error) {
+	rows, err := e.db.Query(`
+		SELECT s.site_id, s.site_name, COALESCE(s.description, ''),
+		       (SELECT COUNT(*) FROM landscape_nodes n WHERE n.site_id = s.site_id),
+		       s.created_at, s.updated_at
+		FROM sites s
+		ORDER BY s.site_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sites: %w", err)
+	}
+	defer rows.Close()
+
+	var sites []models.Site
+	for rows.Next() {
+		var s models.Site
+		if err := rows.Scan(&s.SiteID, &s.SiteName, &s.Description, &s.NodeCount, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan site: %w", err)
+		}
+		sites = append(sites, s)
+	}
+	return sites, rows.Err()
+}
+
+// ListSiteNodes returns the FQDNs of the nodes assigned to a site; an empty
+// siteID lists the nodes without a site
+func (e *SiteEditor) ListSiteNodes(siteID string) ([]string, error) {
+	query := "SELECT main_fqdn FROM landscape_nodes WHERE site_id = ? ORDER BY main_fqdn"
+	args := []interface{}{siteID}
+	if siteID == "" {
+		query = "SELECT main_fqdn FROM landscape_nodes WHERE site_id IS NULL ORDER BY main_fqdn"
+		args = nil
+	}
+
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query site nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var fqdns []string
+	for rows.Next() {
+		var fqdn string
+		if err := rows.Scan(&fqdn); err != nil {
+			return nil, fmt.Errorf("failed to scan node: %w", err)
+		}
+		fqdns = append(fqdns, fqdn)
+	}
+	return fqdns, rows.Err()
+}
+
+// requireSite returns an error unless the site exists
+func requireSite(tx *sql.Tx, siteID string) error {
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM sites WHERE site_id = ?", siteID).Scan(&count); err != nil {
+		return fmt.Errorf("failed to check site %s: %w", siteID, err)
+	}
+	if count == 0 {
+		return fmt.Errorf("site %s not found", siteID)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+func TestSiteEditor(t *testing.T) {
+	db := setupImportDB(t)
+	for _, fqdn := range []string{"n1.local", "n2.local", "n3.local"} {
+		if _, err := db.Exec(`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES (?, ?, 'PROD')`, fqdn, fqdn); err != nil {
+			t.Fatalf("Failed to insert node: %v", err)
+		}
+	}
+
+	editor := importer.NewSiteEditor(db)
+	if created, err := editor.Save("fra1", "", ""); err != nil || !created {
+		t.Fatalf("Expected site fra1 to be created, got %v, %v", created, err)
+	}
+	if created, err := editor.Save("fra1", "Frankfurt", "DC 1"); err != nil || created {
+		t.Fatalf("Expected site fra1 to be updated, got %v, %v", created, err)
+	}
+
+	if err := editor.Assign("ams", []string{"n1.local"}); err == nil {
+		t.Error("Expected an error assigning to an unknown site")
+	}
+	// Nothing is assigned when a node is unknown
+	if err := editor.Assign("fra1", []string{"n1.local", "missing.local"}); err == nil {
+		t.Error("Expected an error assigning an unknown node")
+	}
+	if err := editor.Assign("fra1", []string{"n1.local", "n2.local"}); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	if err := editor.Assign("", []string{"n2.local"}); err != nil {
+		t.Fatalf("Unassign failed: %v", err)
+	}
+
+	sites, err := editor.ListSites()
+	if err != nil {
+		t.Fatalf("ListSites failed: %v", err)
+	}
+	if len(sites) != 1 || sites[0].SiteName != "Frankfurt" || sites[0].Description != "DC 1" || sites[0].NodeCount != 1 {
+		t.Errorf("Unexpected sites: %+v", sites)
+	}
+
+	unassigned, err := editor.ListSiteNodes("")
+	if err != nil {
+		t.Fatalf("ListSiteNodes failed: %v", err)
+	}
+	if strings.Join(unassigned, ",") != "n2.local,n3.local" {
+		t.Errorf("Expected n2 and n3 without a site, got %v", unassigned)
+	}
+
+	// Sites with nodes are only removed when unassigning them
+	if _, err := editor.Remove("fra1", false); err == nil {
+		t.Error("Expected an error removing a site with nodes")
+	}
+	nodes, err := editor.Remove("fra1", true)
+	if err != nil || nodes != 1 {
+		t.Fatalf("Expected 1 node unassigned, got %d, %v", nodes, err)
+	}
+	if unassigned, _ := editor.ListSiteNodes(""); len(unassigned) != 3 {
+		t.Errorf("Expected all nodes without a site, got %v", unassigned)
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// detectionBatchSize is the number of product detections written by one multi-row
+// INSERT. Each row binds 8 parameters, well below SQLite's host parameter limit.
+const detectionBatchSize = 100
+
+const measurementUpsertSQL = `
+	INSERT INTO measurements (
+		main_fqdn, detection_timestamp, session_directory,
+		node_type, environment, inspection_level, node_fqdn,
+		os_name, os_version, cpu_count,
+		is_virtualized, virt_type, processor_vendor, processor_brand,
+		host_physical_cpus, partition_cpus,
+		processor_eligible, os_eligible, virt_eligible,
+		considered_cpus, physical_host_id, host_id_method, host_id_confidence,
+		container_platform, container_namespace, container_pod, container_name,
+		container_cpu_limit, container_limit_cores,
+		cloud_provider, instance_type, region, account_id,
+		partition_cap_cores,
+		created_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(main_fqdn, detection_timestamp) DO UPDATE SET
+		session_directory = excluded.session_directory,
+		node_type = excluded.node_type,
+		environment = excluded.environment,
+		inspection_level = excluded.inspection_level,
+		node_fqdn = excluded.node_fqdn,
+		os_name = excluded.os_name,
+		os_version = excluded.os_version,
+		cpu_count = excluded.cpu_count,
+		is_virtualized = excluded.is_virtualized,
+		virt_type = excluded.virt_type,
+		processor_vendor = excluded.processor_vendor,
+		processor_brand = excluded.processor_brand,
+		host_physical_cpus = excluded.host_physical_cpus,
+		partition_cpus = excluded.partition_cpus,
+		processor_eligible = excluded.processor_eligible,
+		os_eligible = excluded.os_eligible,
+		virt_eligible = excluded.virt_eligible,
+		considered_cpus = excluded.considered_cpus,
+		physical_host_id = excluded.physical_host_id,
+		host_id_method = excluded.host_id_method,
+		host_id_confidence = excluded.host_id_confidence,
+		container_platform = excluded.container_platform,
+		container_namespace = excluded.container_namespace,
+		container_pod = excluded.container_pod,
+		container_name = excluded.container_name,
+		container_cpu_limit = excluded.container_cpu_limit,
+		container_limit_cores = excluded.container_limit_cores,
+		cloud_provider = excluded.cloud_provider,
+		instance_type = excluded.instance_type,
+		region = excluded.region,
+		account_id = excluded.account_id,
+		partition_cap_cores = excluded.partition_cap_cores
+`
+
+// detectionUpsertSQL builds an idempotent detected_products INSERT for the given number of rows
+func detectionUpsertSQL(rows int) string {
+	values := make([]string, rows)
+	for i := range values {
+		values[i] = "(?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)"
+	}
+
+	return `
+	INSERT INTO detected_products (
+		main_fqdn, product_mnemo_code, detection_timestamp,
+		status, running_status, running_count, install_status, install_count, created_at
+	) VALUES ` + strings.Join(values, ", ") + `
+	ON CONFLICT(main_fqdn, product_mnemo_code, detection_timestamp) DO UPDATE SET
+		status = excluded.status,
+		running_status = excluded.running_status,
+		running_count = excluded.running_count,
+		install_status = excluded.install_status,
+		install_count = excluded.install_count
+`
+}
+
+// importStatements holds the statements of an import, prepared once per transaction
+// so SQLite does not re-parse them for every row
+type importStatements struct {
+	tx                 *sql.Tx
+	measurement        *sql.Stmt
+	detection          *sql.Stmt
+	detectionBatch     *sql.Stmt // full batch of detectionBatchSize rows, prepared on first use
+	existingDetections *sql.Stmt
+	deleteInstalls     *sql.Stmt
+	deleteProcesses    *sql.Stmt
+	insertInstall      *sql.Stmt
+	insertProcess      *sql.Stmt
+}
+
+// prepareImportStatements prepares the import statements on the transaction
+func prepareImportStatements(tx *sql.Tx) (*importStatements, error) {
+	st := &importStatements{tx: tx}
+
+	var err error
+	if st.measurement, err = tx.Prepare(measurementUpsertSQL); err != nil {
+		return nil, fmt.Errorf("failed to prepare measurement statement: %w", err)
+	}
+	if st.detection, err = tx.Prepare(detectionUpsertSQL(1)); err != nil {
+		st.close()
+		return nil, fmt.Errorf("failed to prepare detected product statement: %w", err)
+	}
+	if st.existingDetections, err = tx.Prepare(`
+		SELECT product_mnemo_code FROM detected_products
+		WHERE main_fqdn = ? AND detection_timestamp = ?
+	`); err != nil {
+		st.close()
+		return nil, fmt.Errorf("failed to prepare detected product lookup: %w", err)
+	}
+
+	evidence := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&st.deleteInstalls, `DELETE FROM detected_product_installs
+			WHERE main_fqdn = ? AND product_mnemo_code = ? AND detection_timestamp = ?`},
+		{&st.deleteProcesses, `DELETE FROM detected_product_processes
+			WHERE main_fqdn = ? AND product_mnemo_code = ? AND detection_timestamp = ?`},
+		{&st.insertInstall, `INSERT INTO detected_product_installs
+			(main_fqdn, product_mnemo_code, detection_timestamp, seq, install_path) VALUES (?, ?, ?, ?, ?)`},
+		{&st.insertProcess, `INSERT INTO detected_product_processes
+			(main_fqdn, product_mnemo_code, detection_timestamp, seq, commandline) VALUES (?, ?, ?, ?, ?)`},
+	}
+	for _, e := range evidence {
+		if *e.stmt, err = tx.Prepare(e.query); err != nil {
+			st.close()
+			return nil, fmt.Errorf("failed to prepare installation evidence statement: %w", err)
+		}
+	}
+
+	return st, nil
+}
+
+// close releases the prepared statements
+func (st *importStatements) close() {
+	for _, stmt := range []*sql.Stmt{
+		st.measurement, st.detection, st.detectionBatch, st.existingDetections,
+		st.deleteInstalls, st.deleteProcesses, st.insertInstall, st.insertProcess,
+	} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+}
+
+// existingProductCodes returns the product codes already stored for a measurement
+func (st *importStatements) existingProductCodes(mainFQDN string, timestamp time.Time) (map[string]bool, error) {
+	rows, err := st.existingDetections.Query(mainFQDN, timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query detected products: %w", err)
+	}
+	defer rows.Close()
+
+	codes := map[string]bool{}
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		codes[code] = true
+	}
+
+	return codes, rows.Err()
+}
+
+// upsertDetections writes up to detectionBatchSize detections with a single INSERT.
+// Full batches reuse the prepared batch statement; a shorter final batch is
+// executed directly.
+func (st *importStatements) upsertDetections(mainFQDN string, timestamp time.Time, detections []*ProductDetection) error {
+	args := make([]interface{}, 0, len(detections)*8)
+	for _, detection := range detections {
+		args = append(args, detectionArgs(mainFQDN, timestamp, detection)...)
+	}
+
+	switch {
+	case len(detections) == 1:
+		_, err := st.detection.Exec(args...)
+		return err
+	case len(detections) == detectionBatchSize:
+		if st.detectionBatch == nil {
+			stmt, err := st.tx.Prepare(detectionUpsertSQL(detectionBatchSize))
+			if err != nil {
+				return fmt.Errorf("failed to prepare detected product batch statement: %w", err)
+			}
+			st.detectionBatch = stmt
+		}
+		_, err := st.detectionBatch.Exec(args...)
+		return err
+	default:
+		_, err := st.tx.Exec(detectionUpsertSQL(len(detections)), args...)
+		return err
+	}
+}
+
+// detectionArgs returns the statement arguments of one detected product row
+func detectionArgs(mainFQDN string, timestamp time.Time, detection *ProductDetection) []interface{} {
+	return []interface{}{
+		mainFQDN,
+		detection.ProductCode,
+		timestamp,
+		detection.Status,
+		getFieldWithDefault(detection.RunningStatus, "unknown"),
+		detection.RunningCount,
+		getFieldWithDefault(detection.InstallStatus, "unknown"),
+		detection.InstallCount,
+	}
+}
+
+// replaceEvidence stores the install paths and process command lines of a detection.
+// When the detection was stored before, its previous evidence is removed first so
+// re-importing keeps the rows in line with the file.
+func (st *importStatements) replaceEvidence(mainFQDN string, timestamp time.Time, detection *ProductDetection, existed bool) error {
+	if existed {
+		if _, err := st.deleteInstalls.Exec(mainFQDN, detection.ProductCode, timestamp); err != nil {
+			return fmt.Errorf("failed to delete install paths: %w", err)
+		}
+		if _, err := st.deleteProcesses.Exec(mainFQDN, detection.ProductCode, timestamp); err != nil {
+			return fmt.Errorf("failed to delete process command lines: %w", err)
+		}
+	}
+
+	seq := 0
+	for _, installPath := range detection.InstallPaths {
+		if installPath = strings.TrimSpace(installPath); installPath == "" {
+			continue
+		}
+		seq++
+		if _, err := st.insertInstall.Exec(mainFQDN, detection.ProductCode, timestamp, seq, installPath); err != nil {
+			return fmt.Errorf("failed to insert install path: %w", err)
+		}
+	}
+
+	for i, commandline := range detection.Commandlines() {
+		if _, err := st.insertProcess.Exec(mainFQDN, detection.ProductCode, timestamp, i+1, commandline); err != nil {
+			return fmt.Errorf("failed to insert process command line: %w", err)
+		}
+	}
+
+	return nil
+}
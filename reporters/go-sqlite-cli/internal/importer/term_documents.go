@@ -0,0 +1,204 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+)
+
+// openEnded is the effective_to used to compare the periods of documents still
+// in force
+const openEnded = "9999-12-31"
+
+// TermDocumentEditor manages the documents attached to license terms. Program
+// terms change over contract renewals, so every document has the period it is
+// in force for, and the periods of the documents of a term do not overlap.
+type TermDocumentEditor struct {
+	db *sql.DB
+}
+
+// NewTermDocumentEditor creates a new term document editor
+func NewTermDocumentEditor(db *sql.DB) *TermDocumentEditor {
+	return &TermDocumentEditor{db: db}
+}
+
+// Attach adds a document to a license term and sets its DocumentID. A location
+// that is not a URL must be an existing file, and is stored as an absolute
+// path. A document of the term still in force from an earlier date is
+// superseded: it ends the day before the new one starts. Attach returns the ID
+// of the superseded document, or 0.
+func (e *TermDocumentEditor) Attach(doc *models.LicenseTermDocument) (int64, error) {
+	location, err := termDocumentLocation(doc.Location)
+	if err != nil {
+		return 0, err
+	}
+	if doc.EffectiveTo != nil && doc.EffectiveTo.Before(doc.EffectiveFrom) {
+		return 0, fmt.Errorf("effective end date %s is before the start date %s",
+			doc.EffectiveTo.Format("2006-01-02"), doc.EffectiveFrom.Format("2006-01-02"))
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM license_terms WHERE term_id = ?", doc.TermID).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("failed to check license term %s: %w", doc.TermID, err)
+	}
+	if exists == 0 {
+		return 0, fmt.Errorf("license term %s does not exist; load the reference data first", doc.TermID)
+	}
+
+	from := doc.EffectiveFrom.Format("2006-01-02")
+	to := openEnded
+	if doc.EffectiveTo != nil {
+		to = doc.EffectiveTo.Format("2006-01-02")
+	}
+
+	// Supersede the document in force before the new one
+	var superseded int64
+	err = tx.QueryRow(`
+		SELECT document_id FROM license_term_documents
+		WHERE term_id = ? AND effective_to IS NULL AND effective_from < ?
+	`, doc.TermID, from).Scan(&superseded)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to read documents of term %s: %w", doc.TermID, err)
+	}
+	if superseded != 0 {
+		end := doc.EffectiveFrom.AddDate(0, 0, -1).Format("2006-01-02")
+		if _, err := tx.Exec("UPDATE license_term_documents SET effective_to = ? WHERE document_id = ?", end, superseded); err != nil {
+			return 0, fmt.Errorf("failed to supersede document %d: %w", superseded, err)
+		}
+	}
+
+	var overlapID int64
+	var overlapFrom, overlapTo string
+	err = tx.QueryRow(`
+		SELECT document_id, strftime('%Y-%m-%d', effective_from), COALESCE(strftime('%Y-%m-%d', effective_to), 'open')
+		FROM license_term_documents
+		WHERE term_id = ? AND effective_from <= ? AND COALESCE(effective_to, ?) >= ?
+		ORDER BY effective_from
+		LIMIT 1
+	`, doc.TermID, to, openEnded, from).Scan(&overlapID, &overlapFrom, &overlapTo)
+	if err == nil {
+		return 0, fmt.Errorf("document %d of term %s is already in force from %s to %s; detach it or choose other dates",
+			overlapID, doc.TermID, overlapFrom, overlapTo)
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to check documents of term %s: %w", doc.TermID, err)
+	}
+
+	var effectiveTo interface{}
+	if doc.EffectiveTo != nil {
+		effectiveTo = to
+	}
+	res, err := tx.Exec(`
+		INSERT INTO license_term_documents (term_id, location, effective_from, effective_to, description)
+		VALUES (?, ?, ?, ?, ?)
+	`, doc.TermID, location, from, effectiveTo, doc.Description)
+	if err != nil {
+		return 0, fmt.Errorf("failed to attach document to term %s: %w", doc.TermID, err)
+	}
+	if doc.DocumentID, err = res.LastInsertId(); err != nil {
+		return 0, err
+	}
+	doc.Location = location
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return superseded, nil
+}
+
+// termDocumentLocation validates a document location: URLs are kept as they
+// are, files must exist and are made absolute
+func termDocumentLocation(location string) (string, error) {
+	location = strings.TrimSpace(location)
+	if location == "" {
+		return "", fmt.Errorf("document location must not be empty")
+	}
+	if strings.Contains(location, "://") {
+		return location, nil
+	}
+
+	abs, err := filepath.Abs(location)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", fmt.Errorf("document %s: %w", location, err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("document %s is a directory", location)
+	}
+	return abs, nil
+}
+
+// Detach removes a document
+func (e *TermDocumentEditor) Detach(documentID int64) error {
+	res, err := e.db.Exec("DELETE FROM license_term_documents WHERE document_id = ?", documentID)
+	if err != nil {
+		return fmt.Errorf("failed to detach document %d: %w", documentID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("document %d does not exist", documentID)
+	}
+	return nil
+}
+
+// List returns the documents of a term, or of all terms when termID is empty,
+// ordered by term and effective date
+func (e *TermDocumentEditor) List(termID string) ([]models.LicenseTermDocument, error) {
+	query := `
+		SELECT document_id, term_id, location, effective_from, effective_to, COALESCE(description, ''), attached_at
+		FROM license_term_documents
+	`
+	args := []interface{}{}
+	if termID != "" {
+		query += " WHERE term_id = ?"
+		args = append(args, termID)
+	}
+	query += " ORDER BY term_id, effective_from"
+
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list term documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []models.LicenseTermDocument
+	for rows.Next() {
+		var doc models.LicenseTermDocument
+		var effectiveTo sql.NullTime
+		if err := rows.Scan(&doc.DocumentID, &doc.TermID, &doc.Location, &doc.EffectiveFrom, &effectiveTo,
+			&doc.Description, &doc.AttachedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan term document: %w", err)
+		}
+		if effectiveTo.Valid {
+			doc.EffectiveTo = &effectiveTo.Time
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/models"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestTermDocumentEditor(t *testing.T) {
+	db := setupImportDB(t)
+	editor := importer.NewTermDocumentEditor(db)
+	root := t.TempDir()
+	pdf := filepath.Join(root, "terms-2024.pdf")
+	writeFile(t, pdf, "%PDF")
+
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	first := &models.LicenseTermDocument{TermID: "T1", Location: pdf, EffectiveFrom: day("2024-01-01")}
+	if _, err := editor.Attach(first); err != nil {
+		t.Fatalf("Attach failed: %v", err)
+	}
+	// The renewal supersedes the document in force
+	second := &models.LicenseTermDocument{TermID: "T1", Location: "https://example.com/terms-2025", EffectiveFrom: day("2025-10-01")}
+	superseded, err := editor.Attach(second)
+	if err != nil {
+		t.Fatalf("Attach failed: %v", err)
+	}
+	if superseded != first.DocumentID {
+		t.Errorf("Expected document %d to be superseded, got %d", first.DocumentID, superseded)
+	}
+
+	end := day("2024-12-31")
+	for name, doc := range map[string]*models.LicenseTermDocument{
+		"overlap":      {TermID: "T1", Location: pdf, EffectiveFrom: day("2024-06-01"), EffectiveTo: &end},
+		"unknown term": {TermID: "T2", Location: pdf, EffectiveFrom: day("2024-01-01")},
+		"missing file": {TermID: "T1", Location: filepath.Join(root, "missing.pdf"), EffectiveFrom: day("2020-01-01")},
+	} {
+		if _, err := editor.Attach(doc); err == nil {
+			t.Errorf("Expected an error attaching a document with %s", name)
+		}
+	}
+
+	docs, err := editor.List("T1")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(docs) != 2 || docs[0].EffectiveTo == nil || docs[0].EffectiveTo.Format("2006-01-02") != "2025-09-30" || docs[1].EffectiveTo != nil {
+		t.Errorf("Unexpected documents: %+v", docs)
+	}
+
+	// Reports resolve the document in force on the measurement date
+	path := filepath.Join(root, "iwdli_output_host1_20251021_090906.csv")
+	writeFile(t, path, testInspectorCSV)
+	if _, err := importer.NewImportService(db).ImportCSVFile(t.Context(), path); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	rows, err := reports.NewDailySummaryReport(db).Query(t.Context(), "", "", nil, nil)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0].TermsDocument != "https://example.com/terms-2025" {
+		t.Errorf("Expected the 2025 terms on 2025-10-21, got %+v", rows)
+	}
+
+	if err := editor.Detach(second.DocumentID); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	if err := editor.Detach(second.DocumentID); err == nil {
+		t.Error("Expected an error detaching a missing document")
+	}
+}
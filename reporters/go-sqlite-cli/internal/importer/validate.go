@@ -0,0 +1,375 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validation issue severities
+const (
+	// ValidationError marks data the importer rejects or silently misreads
+	ValidationError = "error"
+	// ValidationWarning marks data the importer accepts but that does not
+	// follow the inspector CSV schema
+	ValidationWarning = "warning"
+)
+
+// ValidationIssue is one problem found in an inspector CSV file
+type ValidationIssue struct {
+	Line     int    `json:"line,omitempty"` // 0 for issues of the whole file
+	Field    string `json:"field,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// ValidationResult lists the issues found in one inspector CSV file
+type ValidationResult struct {
+	File          string            `json:"file"`
+	FormatVersion string            `json:"format_version,omitempty"`
+	Products      int               `json:"products"`
+	Issues        []ValidationIssue `json:"issues"`
+}
+
+// Errors returns the number of issues of severity error
+func (r *ValidationResult) Errors() int {
+	return r.count(ValidationError)
+}
+
+// Warnings returns the number of issues of severity warning
+func (r *ValidationResult) Warnings() int {
+	return r.count(ValidationWarning)
+}
+
+// Valid returns true when the file has no errors
+func (r *ValidationResult) Valid() bool {
+	return r.Errors() == 0
+}
+
+func (r *ValidationResult) count(severity string) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *ValidationResult) addf(line int, field, severity, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, ValidationIssue{
+		Line:     line,
+		Field:    field,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Value domains of the system fields written by the inspector
+var systemFieldDomains = map[string][]string{
+	"DETECTION_RESULT":   {"SUCCESS", "ERROR"},
+	"IS_VIRTUALIZED":     {"yes", "no"},
+	"PROCESSOR_ELIGIBLE": {"true", "false"},
+	"OS_ELIGIBLE":        {"true", "false"},
+	"VIRT_ELIGIBLE":      {"true", "false"},
+	"HOST_ID_CONFIDENCE": {"high", "medium", "low"},
+	"NODE_TYPE":          {"PROD", "NON_PROD"},
+}
+
+// Value domains of the product fields, by field name without the product code
+var productFieldDomains = map[string][]string{
+	"":               {"present", "absent"},
+	"RUNNING_STATUS": {"running", "not-running"},
+	"INSTALL_STATUS": {"installed", "not-installed"},
+}
+
+// System fields holding CPU counts; the license cores cannot be computed
+// without the required ones, the others may be "unknown"
+var systemCountFields = []struct {
+	name     string
+	required bool
+}{
+	{"CPU_COUNT", true},
+	{"CONSIDERED_CPUS", true},
+	{"HOST_PHYSICAL_CPUS", false},
+	{"PARTITION_CPUS", false},
+}
+
+// Product fields holding counts
+var productCountFields = map[string]bool{"RUNNING_COUNT": true, "INSTALL_COUNT": true}
+
+// validatedField is a parameter of the file with the line it was read from
+type validatedField struct {
+	line  int
+	name  string // uppercase parameter name
+	value string
+}
+
+// ValidateCSVFile checks an inspector CSV file against the schema the importer
+// expects, without importing it: the file name, the Parameter,Value layout, the
+// required fields, the value domains, the timestamp format, the CSV format
+// version and the product field names of that version. All issues are
+// collected; the file is valid when none of them is an error.
+func ValidateCSVFile(filePath string) *ValidationResult {
+	result := &ValidationResult{File: filePath, Issues: []ValidationIssue{}}
+
+	_, filenameErr := extractHostnameFromFilename(filePath)
+
+	fields, ok := readValidatedFields(filePath, result)
+	if !ok {
+		return result
+	}
+
+	system := make(map[string]validatedField)
+	var products []validatedField
+	seen := make(map[string]int)
+	for _, f := range fields {
+		if f.name == "" {
+			result.addf(f.line, "", ValidationWarning, "empty parameter name; the row is ignored")
+			continue
+		}
+		if line, dup := seen[f.name]; dup {
+			result.addf(f.line, f.name, ValidationWarning, "repeats the parameter of line %d; the last value is used", line)
+		}
+		seen[f.name] = f.line
+
+		if isProductField(f.name) {
+			products = append(products, f)
+		} else {
+			system[f.name] = f
+		}
+	}
+
+	validateSystemFields(system, filenameErr, result)
+	validateProductFields(system, products, result)
+
+	// Issues of the whole file first, then in file order
+	sort.SliceStable(result.Issues, func(i, j int) bool {
+		return result.Issues[i].Line < result.Issues[j].Line
+	})
+	return result
+}
+
+// readValidatedFields reads the rows of the file after the Parameter,Value
+// header; it returns false when the file cannot be read as an inspector CSV
+func readValidatedFields(filePath string, result *ValidationResult) ([]validatedField, bool) {
+	file, err := openInspectorFile(filePath)
+	if err != nil {
+		result.addf(0, "", ValidationError, "failed to open file: %v", err)
+		return nil, false
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		result.addf(1, "", ValidationError, "failed to read CSV header: %v", err)
+		return nil, false
+	}
+	if len(header) < 2 || header[0] != "Parameter" || header[1] != "Value" {
+		result.addf(1, "", ValidationError, "invalid CSV format: expected 'Parameter,Value' header")
+		return nil, false
+	}
+
+	var fields []validatedField
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				result.addf(parseErr.Line, "", ValidationError, "%v", parseErr.Err)
+			} else {
+				result.addf(0, "", ValidationError, "failed to read CSV row: %v", err)
+			}
+			return nil, false
+		}
+
+		line, _ := reader.FieldPos(0)
+		switch {
+		case len(row) == 1 && strings.TrimSpace(row[0]) == "":
+			continue // empty line
+		case len(row) < 2:
+			result.addf(line, strings.ToUpper(strings.TrimSpace(row[0])), ValidationWarning, "row has no value; it is ignored")
+			continue
+		case len(row) > 2:
+			result.addf(line, strings.ToUpper(strings.TrimSpace(row[0])), ValidationWarning, "row has %d columns; the columns after the value are ignored", len(row))
+		}
+
+		fields = append(fields, validatedField{
+			line:  line,
+			name:  strings.ToUpper(strings.TrimSpace(row[0])),
+			value: strings.TrimSpace(row[1]),
+		})
+	}
+	return fields, true
+}
+
+// validateSystemFields checks the required system fields and the value
+// domains of the known ones. filenameErr is the error of a file name that does
+// not give the host name, which must then come from the HOSTNAME field.
+func validateSystemFields(system map[string]validatedField, filenameErr error, result *ValidationResult) {
+	ts, ok := system["DETECTION_TIMESTAMP"]
+	if !ok {
+		result.addf(0, "DETECTION_TIMESTAMP", ValidationError, "missing required field")
+	} else if _, err := time.Parse(time.RFC3339, ts.value); err != nil {
+		result.addf(ts.line, ts.name, ValidationError, "invalid timestamp %q (expected RFC 3339, e.g. 2025-11-06T13:35:25Z)", ts.value)
+	}
+
+	hostname, ok := system["HOSTNAME"]
+	switch {
+	case filenameErr != nil && (!ok || hostname.value == ""):
+		result.addf(0, "HOSTNAME", ValidationError, "missing required field: %v", filenameErr)
+	case filenameErr != nil:
+		result.addf(0, "", ValidationWarning, "%v; the host name is taken from the HOSTNAME field", filenameErr)
+	case !ok:
+		result.addf(0, "HOSTNAME", ValidationWarning, "missing; the host name is taken from the file name")
+	}
+
+	names := make([]string, 0, len(systemFieldDomains))
+	for name := range systemFieldDomains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if f, ok := system[name]; ok && !inDomain(f.value, systemFieldDomains[name]) {
+			result.addf(f.line, name, ValidationError, "invalid value %q (expected %s)", f.value, strings.Join(systemFieldDomains[name], " or "))
+		}
+	}
+
+	// A failed detection is rejected on import; its other fields are not checked
+	if f := system["DETECTION_RESULT"]; f.value == "ERROR" {
+		message := system["ERROR_MESSAGE"].value
+		if message == "" {
+			message = "no error message provided"
+		}
+		result.addf(f.line, f.name, ValidationError, "inspector detection failed: %s", message)
+		return
+	}
+
+	for _, count := range systemCountFields {
+		f, ok := system[count.name]
+		if !ok {
+			if count.required {
+				result.addf(0, count.name, ValidationError, "missing required field")
+			}
+			continue
+		}
+		if !isCount(f.value) && (count.required || (f.value != "unknown" && f.value != "")) {
+			result.addf(f.line, f.name, ValidationError, "invalid value %q (expected a non-negative integer)", f.value)
+		}
+	}
+}
+
+// validateProductFields checks the product fields against the fields of the
+// CSV format version of the file
+func validateProductFields(system map[string]validatedField, fields []validatedField, result *ValidationResult) {
+	var parsed []productField
+	lines := make(map[int]int) // index in parsed -> line
+	for _, f := range fields {
+		pf, err := splitProductField(f.name, f.value)
+		if err != nil {
+			result.addf(f.line, f.name, ValidationError, "%v", err)
+			continue
+		}
+		lines[len(parsed)] = f.line
+		parsed = append(parsed, pf)
+	}
+
+	versionField, hasVersion := system[CSVFormatVersionField]
+	result.FormatVersion = versionField.value
+	if !hasVersion || versionField.value == "" {
+		result.FormatVersion = detectCSVFormatVersion(parsed)
+		result.addf(0, CSVFormatVersionField, ValidationWarning, "missing; format version %s detected from the product fields", result.FormatVersion)
+	}
+	format, err := lookupCSVFormat(result.FormatVersion)
+	if err != nil {
+		result.addf(versionField.line, CSVFormatVersionField, ValidationError, "%v", err)
+		return
+	}
+
+	hasStatus := make(map[string]bool)
+	var codes []string
+	for i, pf := range parsed {
+		line := lines[i]
+		name := pf.productCode
+		if pf.field != "" {
+			name += "_" + pf.field
+		}
+		if pf.number != "" {
+			name += "_" + pf.number
+		}
+
+		if _, ok := hasStatus[pf.productCode]; !ok {
+			hasStatus[pf.productCode] = false
+			codes = append(codes, pf.productCode)
+		}
+		if pf.field == "" && pf.number == "" {
+			hasStatus[pf.productCode] = true
+		}
+
+		known := format.fields
+		if pf.number != "" {
+			known = format.numbered
+		}
+		if _, ok := known[pf.field]; !ok {
+			result.addf(line, name, ValidationWarning, "not a product field of CSV format version %s; it is ignored", result.FormatVersion)
+			continue
+		}
+
+		if domain, ok := productFieldDomains[pf.field]; ok && pf.number == "" && !inDomain(pf.value, domain) {
+			result.addf(line, name, ValidationError, "invalid value %q (expected %s)", pf.value, strings.Join(domain, " or "))
+		}
+		if productCountFields[pf.field] && !isCount(pf.value) {
+			result.addf(line, name, ValidationError, "invalid value %q (expected a non-negative integer)", pf.value)
+		}
+	}
+
+	for _, code := range codes {
+		if !hasStatus[code] {
+			result.addf(0, code, ValidationWarning, "product has fields but no present/absent status field")
+		}
+	}
+	result.Products = len(codes)
+}
+
+// inDomain checks a value against the allowed values; the reports compare
+// them case-sensitively
+func inDomain(value string, domain []string) bool {
+	for _, allowed := range domain {
+		if value == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// isCount checks that a value is a non-negative integer
+func isCount(value string) bool {
+	n, err := strconv.Atoi(value)
+	return err == nil && n >= 0
+}
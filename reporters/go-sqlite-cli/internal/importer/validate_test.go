@@ -0,0 +1,113 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+const validCSV = `Parameter,Value
+DETECTION_TIMESTAMP,2025-10-21T09:09:06Z
+HOSTNAME,test
+CSV_FORMAT_VERSION,2
+CPU_COUNT,4
+IS_VIRTUALIZED,yes
+HOST_PHYSICAL_CPUS,unknown
+CONSIDERED_CPUS,4
+IS_ONP_PRD,present
+IS_ONP_PRD_RUNNING_STATUS,running
+IS_ONP_PRD_RUNNING_COUNT,1
+IS_ONP_PRD_INSTALL_PATH_01,/opt/is1
+DETECTION_RESULT,SUCCESS
+`
+
+func TestValidateCSVFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "iwdli_output_test_20251021_090906.csv")
+	writeFile(t, path, validCSV)
+
+	result := importer.ValidateCSVFile(path)
+	if len(result.Issues) != 0 {
+		t.Fatalf("Expected no issues, got %+v", result.Issues)
+	}
+	if result.FormatVersion != importer.CSVFormatV2 || result.Products != 1 {
+		t.Errorf("Expected format 2 with 1 product, got format %s with %d products", result.FormatVersion, result.Products)
+	}
+}
+
+func TestValidateCSVFileIssues(t *testing.T) {
+	content := `Parameter,Value
+DETECTION_TIMESTAMP,2025-10-21 09:09:06
+HOSTNAME,test
+CPU_COUNT,two
+IS_VIRTUALIZED,maybe
+IS_ONP_PRD,present
+IS_ONP_PRD_RUNNING_COUNT,-1
+IS_ONP_PRD_INSTALL_PATHS,/opt/is1
+IS_ONP_PRD_INSTALL_PATH_01,/opt/is1
+`
+	path := filepath.Join(t.TempDir(), "test.csv")
+	writeFile(t, path, content)
+
+	result := importer.ValidateCSVFile(path)
+	if result.Valid() {
+		t.Fatal("Expected the file to be invalid")
+	}
+
+	// The file name does not give the host name, which is taken from HOSTNAME
+	expected := []importer.ValidationIssue{
+		{Line: 0, Field: "", Severity: importer.ValidationWarning},
+		{Line: 0, Field: "CONSIDERED_CPUS", Severity: importer.ValidationError},
+		{Line: 0, Field: "CSV_FORMAT_VERSION", Severity: importer.ValidationWarning},
+		{Line: 2, Field: "DETECTION_TIMESTAMP", Severity: importer.ValidationError},
+		{Line: 4, Field: "CPU_COUNT", Severity: importer.ValidationError},
+		{Line: 5, Field: "IS_VIRTUALIZED", Severity: importer.ValidationError},
+		{Line: 7, Field: "IS_ONP_PRD_RUNNING_COUNT", Severity: importer.ValidationError},
+		{Line: 8, Field: "IS_ONP_PRD_INSTALL_PATHS", Severity: importer.ValidationWarning},
+	}
+	if len(result.Issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %d: %+v", len(expected), len(result.Issues), result.Issues)
+	}
+	for i, want := range expected {
+		got := result.Issues[i]
+		if got.Line != want.Line || got.Field != want.Field || got.Severity != want.Severity {
+			t.Errorf("Issue %d: expected line %d %s %s, got line %d %s %s (%s)",
+				i, want.Line, want.Field, want.Severity, got.Line, got.Field, got.Severity, got.Message)
+		}
+	}
+	if result.Errors() != 5 || result.Warnings() != 3 {
+		t.Errorf("Expected 5 errors and 3 warnings, got %d and %d", result.Errors(), result.Warnings())
+	}
+}
+
+func TestValidateCSVFileDetectionError(t *testing.T) {
+	content := `Parameter,Value
+DETECTION_TIMESTAMP,2025-10-21T09:09:06Z
+HOSTNAME,test
+CSV_FORMAT_VERSION,2
+DETECTION_RESULT,ERROR
+ERROR_MESSAGE,lscpu not found
+`
+	path := filepath.Join(t.TempDir(), "iwdli_output_test_20251021_090906.csv")
+	writeFile(t, path, content)
+
+	// The CPU fields are not required once the detection failed
+	result := importer.ValidateCSVFile(path)
+	if len(result.Issues) != 1 || result.Issues[0].Message != "inspector detection failed: lscpu not found" {
+		t.Errorf("Expected only the detection failure, got %+v", result.Issues)
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jobs is a queue of long-running operations kept in the jobs table:
+// bulk imports, report cache refreshes and report generation. Jobs are
+// submitted from the command line and run by a worker of 'jobs work', the
+// daemon or the dashboard server, whichever claims them first.
+package jobs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Job kinds
+const (
+	// KindImport runs 'iwldr import' with the job arguments
+	KindImport = "import"
+	// KindRefreshCache runs 'iwldr db refresh-cache'
+	KindRefreshCache = "refresh-cache"
+	// KindReport runs 'iwldr report' with the job arguments, e.g. compliance --output c.csv
+	KindReport = "report"
+)
+
+// Kinds are the job kinds, in the order they are documented
+var Kinds = []string{KindImport, KindRefreshCache, KindReport}
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Statuses are the job statuses, in the order of a job's life
+var Statuses = []string{StatusQueued, StatusRunning, StatusSucceeded, StatusFailed, StatusCancelled}
+
+// HeartbeatStaleAfter is how long a running job whose worker stopped
+// reporting is considered running. Cancelling an older job marks it cancelled
+// at once, and the next claim of a worker marks it failed, as its worker no
+// longer runs.
+const HeartbeatStaleAfter = time.Minute
+
+// staleWorkerMessage is the error message of the jobs whose worker no longer runs
+const staleWorkerMessage = "worker no longer runs"
+
+// Job is a queued, running or finished operation
+type Job struct {
+	ID              int64
+	Kind            string
+	Args            []string
+	Status          string
+	CancelRequested bool
+	SubmittedBy     string
+	SubmittedAt     time.Time
+	Worker          string // hostname:pid of the process running the job
+	StartedAt       *time.Time
+	FinishedAt      *time.Time
+	Output          string
+	ErrorMessage    string
+}
+
+// Command returns the iwldr command line run for the job
+func (j *Job) Command() []string {
+	switch j.Kind {
+	case KindRefreshCache:
+		return []string{"db", "refresh-cache"}
+	default:
+		return append([]string{j.Kind}, j.Args...)
+	}
+}
+
+// Queue is the jobs table of a database
+type Queue struct {
+	db *sql.DB
+}
+
+// NewQueue creates a queue over the jobs table of db
+func NewQueue(db *sql.DB) *Queue {
+	return &Queue{db: db}
+}
+
+// Submit queues a job and returns its ID
+func (q *Queue) Submit(kind string, args []string, submittedBy string) (int64, error) {
+	switch kind {
+	case KindImport, KindReport:
+		if kind == KindReport && len(args) == 0 {
+			return 0, fmt.Errorf("a report job needs the report to run, e.g. compliance")
+		}
+	case KindRefreshCache:
+		if len(args) > 0 {
+			return 0, fmt.Errorf("a %s job takes no arguments", kind)
+		}
+	default:
+		return 0, fmt.Errorf("unknown job kind %q (expected %s)", kind, strings.Join(Kinds, ", "))
+	}
+	if args == nil {
+		args = []string{}
+	}
+
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode job arguments: %w", err)
+	}
+	res, err := q.db.Exec("INSERT INTO jobs (kind, args, submitted_by) VALUES (?, ?, ?)", kind, string(encoded), submittedBy)
+	if err != nil {
+		return 0, fmt.Errorf("failed to submit job: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+const jobColumns = `job_id, kind, args, status, cancel_requested, COALESCE(submitted_by, ''), submitted_at,
+	COALESCE(worker, ''), started_at, finished_at, COALESCE(output, ''), COALESCE(error_message, '')`
+
+// scanJob reads a row of jobColumns
+func scanJob(scan func(...interface{}) error) (*Job, error) {
+	job := &Job{}
+	var args string
+	var started, finished sql.NullTime
+	err := scan(&job.ID, &job.Kind, &args, &job.Status, &job.CancelRequested, &job.SubmittedBy, &job.SubmittedAt,
+		&job.Worker, &started, &finished, &job.Output, &job.ErrorMessage)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(args), &job.Args); err != nil {
+		return nil, fmt.Errorf("invalid arguments of job %d: %w", job.ID, err)
+	}
+	if started.Valid {
+		job.StartedAt = &started.Time
+	}
+	if finished.Valid {
+		job.FinishedAt = &finished.Time
+	}
+	return job, nil
+}
+
+// Get returns a job
+func (q *Queue) Get(id int64) (*Job, error) {
+	job, err := scanJob(q.db.QueryRow("SELECT "+jobColumns+" FROM jobs WHERE job_id = ?", id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("job %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job %d: %w", id, err)
+	}
+	return job, nil
+}
+
+// List returns the jobs, newest first, of one status when status is set.
+// A positive limit returns only the newest jobs.
+func (q *Queue) List(status string, limit int) ([]Job, error) {
+	query := "SELECT " + jobColumns + " FROM jobs"
+	args := []interface{}{}
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY job_id DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := q.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		job, err := scanJob(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// Cancel cancels a job. A queued job is cancelled at once; a running job is
+// flagged and stopped by its worker, unless the worker no longer runs. It
+// returns the job as left by the cancellation.
+func (q *Queue) Cancel(id int64) (*Job, error) {
+	job, err := q.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch job.Status {
+	case StatusQueued:
+		_, err = q.db.Exec(`UPDATE jobs SET status = ?, finished_at = CURRENT_TIMESTAMP
+			WHERE job_id = ? AND status = ?`, StatusCancelled, id, StatusQueued)
+	case StatusRunning:
+		staleAfter := staleAfterModifier()
+		_, err = q.db.Exec(`UPDATE jobs SET
+				cancel_requested = 1,
+				status = CASE WHEN heartbeat_at < datetime('now', ?) THEN ? ELSE status END,
+				finished_at = CASE WHEN heartbeat_at < datetime('now', ?) THEN CURRENT_TIMESTAMP END,
+				error_message = CASE WHEN heartbeat_at < datetime('now', ?) THEN ? ELSE error_message END
+			WHERE job_id = ? AND status = ?`,
+			staleAfter, StatusCancelled, staleAfter, staleAfter, staleWorkerMessage, id, StatusRunning)
+	default:
+		return nil, fmt.Errorf("job %d already %s", id, job.Status)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel job %d: %w", id, err)
+	}
+	return q.Get(id)
+}
+
+// staleAfterModifier returns HeartbeatStaleAfter as a datetime() modifier
+func staleAfterModifier() string {
+	return fmt.Sprintf("-%d seconds", int(HeartbeatStaleAfter.Seconds()))
+}
+
+// claim marks the oldest queued job as running by worker and returns it, nil
+// when no job is queued. Workers of several processes claim from the same
+// table; the single UPDATE gives each job to one of them. The running jobs
+// whose worker no longer runs are marked failed first, so that they do not
+// stay running forever.
+func (q *Queue) claim(worker string) (*Job, error) {
+	if err := q.failStale(); err != nil {
+		return nil, err
+	}
+
+	job, err := scanJob(q.db.QueryRow(`
+		UPDATE jobs SET
+			status = ?,
+			worker = ?,
+			started_at = CURRENT_TIMESTAMP,
+			heartbeat_at = CURRENT_TIMESTAMP
+		WHERE job_id = (SELECT job_id FROM jobs WHERE status = ? ORDER BY job_id LIMIT 1)
+			AND status = ?
+		RETURNING `+jobColumns, StatusRunning, worker, StatusQueued, StatusQueued).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+	return job, nil
+}
+
+// failStale marks the running jobs without a heartbeat for HeartbeatStaleAfter
+// as failed: their worker stopped without recording their outcome
+func (q *Queue) failStale() error {
+	_, err := q.db.Exec(`UPDATE jobs SET status = ?, error_message = ?, finished_at = CURRENT_TIMESTAMP
+		WHERE status = ? AND heartbeat_at < datetime('now', ?)`,
+		StatusFailed, staleWorkerMessage, StatusRunning, staleAfterModifier())
+	if err != nil {
+		return fmt.Errorf("failed to fail stale jobs: %w", err)
+	}
+	return nil
+}
+
+// heartbeat marks a running job as still worked on and reports whether it
+// must stop: its cancellation was requested, or it is no longer running
+// because it was marked failed or cancelled as stale
+func (q *Queue) heartbeat(id int64) (bool, error) {
+	var cancel bool
+	err := q.db.QueryRow(`UPDATE jobs SET heartbeat_at = CURRENT_TIMESTAMP WHERE job_id = ? AND status = ?
+		RETURNING cancel_requested`, id, StatusRunning).Scan(&cancel)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update job %d: %w", id, err)
+	}
+	return cancel, nil
+}
+
+// finish records the outcome of a running job. A job no longer running keeps
+// the outcome recorded when it was marked stale.
+func (q *Queue) finish(id int64, status, output, errorMessage string) error {
+	res, err := q.db.Exec(`UPDATE jobs SET status = ?, output = ?, error_message = ?, finished_at = CURRENT_TIMESTAMP
+		WHERE job_id = ? AND status = ?`, status, output, errorMessage, id, StatusRunning)
+	if err != nil {
+		return fmt.Errorf("failed to finish job %d: %w", id, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("job %d is no longer running; its outcome (%s) was not recorded", id, status)
+	}
+	return nil
+}
+
+// workerName returns the hostname:pid of the process
+func workerName() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
@@ -0,0 +1,230 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+)
+
+func setupQueue(t *testing.T) *Queue {
+	t.Helper()
+
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+	return NewQueue(db)
+}
+
+func TestQueueSubmitAndCancel(t *testing.T) {
+	q := setupQueue(t)
+
+	for _, tt := range []struct {
+		kind string
+		args []string
+		want string
+	}{
+		{"backup", nil, "unknown job kind"},
+		{KindRefreshCache, []string{"--full"}, "takes no arguments"},
+		{KindReport, nil, "needs the report"},
+	} {
+		if _, err := q.Submit(tt.kind, tt.args, "alice"); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Submit(%s, %v): expected %q, got %v", tt.kind, tt.args, tt.want, err)
+		}
+	}
+
+	first, err := q.Submit(KindImport, []string{"--dir", "/srv/drop"}, "alice")
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	second, err := q.Submit(KindRefreshCache, nil, "bob")
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	// Jobs are claimed oldest first
+	job, err := q.claim("test:1")
+	if err != nil || job == nil || job.ID != first {
+		t.Fatalf("Expected to claim job %d, got %+v (%v)", first, job, err)
+	}
+	if got := strings.Join(job.Command(), " "); got != "import --dir /srv/drop" || job.Status != StatusRunning {
+		t.Errorf("Unexpected claimed job %q %s", got, job.Status)
+	}
+
+	// A queued job is cancelled at once and no longer claimed
+	cancelled, err := q.Cancel(second)
+	if err != nil || cancelled.Status != StatusCancelled {
+		t.Fatalf("Expected job %d cancelled, got %+v (%v)", second, cancelled, err)
+	}
+	if job, err := q.claim("test:1"); err != nil || job != nil {
+		t.Errorf("Expected no job to claim, got %+v (%v)", job, err)
+	}
+	if _, err := q.Cancel(second); err == nil || !strings.Contains(err.Error(), "already cancelled") {
+		t.Errorf("Expected a finished job not to be cancelled again, got %v", err)
+	}
+
+	// A running job is flagged while its worker sends heartbeats...
+	running, err := q.Cancel(first)
+	if err != nil || running.Status != StatusRunning || !running.CancelRequested {
+		t.Fatalf("Expected a cancellation request, got %+v (%v)", running, err)
+	}
+	if requested, err := q.heartbeat(first); err != nil || !requested {
+		t.Errorf("Expected the heartbeat to report the cancellation, got %v (%v)", requested, err)
+	}
+
+	// ...and cancelled at once when its worker stopped
+	if _, err := q.db.Exec("UPDATE jobs SET heartbeat_at = datetime('now', '-5 minutes') WHERE job_id = ?", first); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := q.Cancel(first)
+	if err != nil || stale.Status != StatusCancelled || stale.ErrorMessage != "worker no longer runs" {
+		t.Errorf("Expected the stale job cancelled, got %+v (%v)", stale, err)
+	}
+
+	list, err := q.List(StatusCancelled, 0)
+	if err != nil || len(list) != 2 || list[0].ID != second {
+		t.Errorf("Expected both jobs cancelled, newest first, got %+v (%v)", list, err)
+	}
+}
+
+func TestWorkerRunNext(t *testing.T) {
+	q := setupQueue(t)
+
+	outcomes := map[string]error{"compliance": nil, "peak-usage": errors.New("exit status 1")}
+	w := NewWorker(q, func(ctx context.Context, job *Job) (string, error) {
+		if job.Args[0] == "slow" {
+			<-ctx.Done()
+			return "interrupted", ctx.Err()
+		}
+		return "ran " + job.Args[0], outcomes[job.Args[0]]
+	})
+	w.Poll = 10 * time.Millisecond
+
+	for _, report := range []string{"compliance", "peak-usage", "slow"} {
+		if _, err := q.Submit(KindReport, []string{report}, "alice"); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if ran, err := w.RunNext(context.Background()); err != nil || !ran {
+			t.Fatalf("Expected a job to run, got %v (%v)", ran, err)
+		}
+	}
+
+	// The slow job runs until its cancellation is seen by the heartbeat
+	done := make(chan error, 1)
+	go func() {
+		_, err := w.RunNext(context.Background())
+		done <- err
+	}()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if job, err := q.Get(3); err == nil && job.Status == StatusRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Job 3 did not start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := q.Cancel(3); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RunNext failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("The cancelled job was not stopped")
+	}
+
+	want := map[int64][3]string{
+		1: {StatusSucceeded, "ran compliance", ""},
+		2: {StatusFailed, "ran peak-usage", "exit status 1"},
+		3: {StatusCancelled, "interrupted", "cancelled while running"},
+	}
+	for id, w := range want {
+		job, err := q.Get(id)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if job.Status != w[0] || job.Output != w[1] || job.ErrorMessage != w[2] || job.FinishedAt == nil {
+			t.Errorf("Job %d: expected %v, got %s %q %q", id, w, job.Status, job.Output, job.ErrorMessage)
+		}
+	}
+
+	if ran, err := w.RunNext(context.Background()); err != nil || ran {
+		t.Errorf("Expected an empty queue, got %v (%v)", ran, err)
+	}
+}
+
+func TestQueueFailsStaleJobs(t *testing.T) {
+	q := setupQueue(t)
+
+	stale, err := q.Submit(KindRefreshCache, nil, "alice")
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	next, err := q.Submit(KindRefreshCache, nil, "bob")
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if job, err := q.claim("test:1"); err != nil || job == nil || job.ID != stale {
+		t.Fatalf("Expected to claim job %d, got %+v (%v)", stale, job, err)
+	}
+
+	// The worker of the first job was killed: the next claim marks it failed
+	if _, err := q.db.Exec("UPDATE jobs SET heartbeat_at = datetime('now', '-5 minutes') WHERE job_id = ?", stale); err != nil {
+		t.Fatal(err)
+	}
+	if job, err := q.claim("test:2"); err != nil || job == nil || job.ID != next {
+		t.Fatalf("Expected to claim job %d, got %+v (%v)", next, job, err)
+	}
+	failed, err := q.Get(stale)
+	if err != nil || failed.Status != StatusFailed || failed.ErrorMessage != staleWorkerMessage || failed.FinishedAt == nil {
+		t.Fatalf("Expected the stale job failed, got %+v (%v)", failed, err)
+	}
+
+	// A worker resuming the stale job is told to stop, and cannot overwrite its outcome
+	if stop, err := q.heartbeat(stale); err != nil || !stop {
+		t.Errorf("Expected the heartbeat of a failed job to stop it, got %v (%v)", stop, err)
+	}
+	if err := q.finish(stale, StatusSucceeded, "done", ""); err == nil || !strings.Contains(err.Error(), "no longer running") {
+		t.Errorf("Expected finishing a failed job to be refused, got %v", err)
+	}
+	if job, err := q.Get(stale); err != nil || job.Status != StatusFailed || job.Output != "" {
+		t.Errorf("Expected the failed job unchanged, got %+v (%v)", job, err)
+	}
+
+	// The running job with a recent heartbeat is finished as usual
+	if err := q.finish(next, StatusSucceeded, "done", ""); err != nil {
+		t.Errorf("finish failed: %v", err)
+	}
+	if job, err := q.Get(next); err != nil || job.Status != StatusSucceeded {
+		t.Errorf("Expected job %d succeeded, got %+v (%v)", next, job, err)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MaxOutput is the most bytes of command output kept with a job; longer
+// output keeps its end, where the summary and errors are
+const MaxOutput = 64 * 1024
+
+// DefaultPoll is how often an idle worker looks for queued jobs, and a busy
+// one checks whether its job was cancelled
+const DefaultPoll = 2 * time.Second
+
+// ExecFunc runs the command of a job and returns its output. It must stop
+// when ctx is cancelled.
+type ExecFunc func(ctx context.Context, job *Job) (string, error)
+
+// Worker runs the queued jobs one at a time
+type Worker struct {
+	Queue *Queue
+	Exec  ExecFunc
+	Poll  time.Duration
+	Logf  func(format string, args ...interface{})
+
+	name string
+}
+
+// NewWorker creates a worker running the jobs of queue with exec
+func NewWorker(queue *Queue, exec ExecFunc) *Worker {
+	return &Worker{
+		Queue: queue,
+		Exec:  exec,
+		Poll:  DefaultPoll,
+		Logf:  func(string, ...interface{}) {},
+		name:  workerName(),
+	}
+}
+
+// Run runs queued jobs until ctx is cancelled. A failing job is recorded and
+// logged and does not stop the worker.
+func (w *Worker) Run(ctx context.Context) error {
+	for {
+		ran, err := w.RunNext(ctx)
+		if err != nil {
+			w.Logf("Job queue: %v", err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if ran {
+			continue
+		}
+
+		timer := time.NewTimer(w.Poll)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// RunNext runs the oldest queued job and reports whether there was one. The
+// error is that of the queue; the outcome of the job is recorded with it.
+func (w *Worker) RunNext(ctx context.Context) (bool, error) {
+	job, err := w.Queue.claim(w.name)
+	if err != nil || job == nil {
+		return false, err
+	}
+	w.Logf("Running job %d (%s)", job.ID, job.Kind)
+
+	// The heartbeat tells 'jobs cancel' the worker still runs, and stops the
+	// job when its cancellation was requested
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var cancelled bool
+	var mu sync.Mutex
+	done := make(chan struct{})
+	go func() {
+		// A heartbeat every Poll, but often enough not to look stale
+		ticker := time.NewTicker(min(w.Poll, HeartbeatStaleAfter/4))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				requested, err := w.Queue.heartbeat(job.ID)
+				if err != nil {
+					w.Logf("Job queue: %v", err)
+					continue
+				}
+				if requested {
+					mu.Lock()
+					cancelled = true
+					mu.Unlock()
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	output, execErr := w.Exec(jobCtx, job)
+	close(done)
+	if len(output) > MaxOutput {
+		output = "...\n" + output[len(output)-MaxOutput:]
+	}
+
+	mu.Lock()
+	status := StatusSucceeded
+	message := ""
+	switch {
+	case cancelled:
+		status, message = StatusCancelled, "cancelled while running"
+	case execErr != nil && ctx.Err() != nil:
+		status, message = StatusFailed, "worker stopped while the job was running"
+	case execErr != nil:
+		status, message = StatusFailed, execErr.Error()
+	}
+	mu.Unlock()
+
+	w.Logf("Job %d %s", job.ID, status)
+	return true, w.Queue.finish(job.ID, status, output, message)
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licensing
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CPUInputs are the fields of a measurement the license cores of a node are
+// derived from
+type CPUInputs struct {
+	CPUCount      int    // CPU_COUNT, the vCPUs or logical CPUs of the node
+	Virtualized   bool   // IS_VIRTUALIZED is yes
+	OSEligible    bool   // OS_ELIGIBLE is true
+	VirtEligible  bool   // VIRT_ELIGIBLE is true
+	HostCores     int    // physical cores of the host, 0 when unknown
+	PartitionCPUs string // PARTITION_CPUS as reported
+	// ContainerLimitCores is the CPU limit of a container in whole cores, nil
+	// when the node is not a container with a limit
+	ContainerLimitCores *int
+}
+
+// ConsideredCPUs returns the license cores of a node, as the inspector
+// computes CONSIDERED_CPUS and the import caps it:
+//   - a physical node counts its CPUs
+//   - a VM with an eligible OS and virtualization counts its vCPUs; otherwise
+//     the cores of its partition, else of its host, else its vCPUs
+//   - a VM never counts more than the cores of its partition or host
+//   - a container with an eligible OS and virtualization counts at most its
+//     CPU limit; other containers are licensed on the full capacity of their
+//     host, as the reporting views count them
+//   - a capped partition with an eligible OS and virtualization counts at
+//     most its capacity (see ApplyPartitionCap)
+func ConsideredCPUs(in CPUInputs) int {
+	cores := in.CPUCount
+	if in.Virtualized {
+		// The inspector only reads whole partition CPUs here; fractional
+		// capacities are left to the partition cap
+		physical := 0
+		if partition, err := strconv.Atoi(strings.TrimSpace(in.PartitionCPUs)); err == nil && partition > 0 {
+			physical = partition
+		} else if in.HostCores > 0 {
+			physical = in.HostCores
+		}
+
+		if !(in.OSEligible && in.VirtEligible) && physical > 0 {
+			cores = physical
+		}
+		if physical > 0 && physical < in.CPUCount {
+			cores = physical
+		}
+	}
+
+	if in.OSEligible && in.VirtEligible && in.ContainerLimitCores != nil && *in.ContainerLimitCores < cores {
+		cores = *in.ContainerLimitCores
+	}
+	return ApplyPartitionCap(cores, in.Virtualized, in.OSEligible && in.VirtEligible, in.PartitionCPUs).Cores
+}
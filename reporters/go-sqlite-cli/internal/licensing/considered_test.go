@@ -0,0 +1,47 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licensing_test
+
+import (
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/licensing"
+)
+
+func TestConsideredCPUs(t *testing.T) {
+	tests := []struct {
+		name string
+		in   licensing.CPUInputs
+		want int
+	}{
+		{name: "physical node counts its CPUs", in: licensing.CPUInputs{CPUCount: 16, HostCores: 8}, want: 16},
+		{name: "eligible VM counts its vCPUs", in: licensing.CPUInputs{CPUCount: 4, Virtualized: true, OSEligible: true, VirtEligible: true, HostCores: 32}, want: 4},
+		{name: "ineligible VM counts the host", in: licensing.CPUInputs{CPUCount: 4, Virtualized: true, OSEligible: true, HostCores: 32}, want: 32},
+		{name: "ineligible VM prefers the partition", in: licensing.CPUInputs{CPUCount: 4, Virtualized: true, HostCores: 32, PartitionCPUs: "8"}, want: 8},
+		{name: "ineligible VM of an unknown host counts its vCPUs", in: licensing.CPUInputs{CPUCount: 4, Virtualized: true}, want: 4},
+		{name: "over-provisioned VM counts the host", in: licensing.CPUInputs{CPUCount: 16, Virtualized: true, OSEligible: true, VirtEligible: true, HostCores: 8}, want: 8},
+		{name: "container counts its limit", in: licensing.CPUInputs{CPUCount: 8, Virtualized: true, OSEligible: true, VirtEligible: true, ContainerLimitCores: cores(2)}, want: 2},
+		{name: "ineligible container counts the host", in: licensing.CPUInputs{CPUCount: 8, Virtualized: true, OSEligible: true, HostCores: 48, ContainerLimitCores: cores(4)}, want: 48},
+		{name: "fractional partition cap is rounded up", in: licensing.CPUInputs{CPUCount: 8, Virtualized: true, OSEligible: true, VirtEligible: true, PartitionCPUs: "1.5"}, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := licensing.ConsideredCPUs(tt.in); got != tt.want {
+				t.Errorf("Expected %d cores, got %d", tt.want, got)
+			}
+		})
+	}
+}
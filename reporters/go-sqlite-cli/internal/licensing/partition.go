@@ -0,0 +1,66 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licensing
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RulePartitionCap counts the capacity of a capped partition (AIX LPAR,
+// Solaris zone with a CPU cap) instead of the vCPUs visible in it
+const RulePartitionCap = "partition-cap"
+
+// PartitionResult is the outcome of the partition cap rule for one node
+type PartitionResult struct {
+	CapCores *int // partition capacity in whole cores, nil when no cap is reported
+	Cores    int  // license cores of the node after the rule
+	Applied  bool // the cap was below the node cores and replaced them
+}
+
+// ApplyPartitionCap applies the partition cap reported in PARTITION_CPUS to the
+// cores of a node. The cap may be fractional (1.5 processing units of a
+// shared-processor LPAR) and is rounded up to whole cores. A virtualized node
+// with an eligible OS and virtualization (eligible) and a cap below its cores
+// is licensed on the cap; the cap never raises the cores. Ineligible nodes are
+// licensed on the full capacity of their host and keep their cores. Empty,
+// "unknown" and non-positive values mean no cap.
+func ApplyPartitionCap(cores int, virtualized, eligible bool, partitionCPUs string) PartitionResult {
+	result := PartitionResult{Cores: cores}
+
+	capacity, ok := PartitionCapacity(partitionCPUs)
+	if !ok {
+		return result
+	}
+	capCores := int(math.Ceil(capacity))
+	result.CapCores = &capCores
+
+	if virtualized && eligible && capCores < cores {
+		result.Cores = capCores
+		result.Applied = true
+	}
+	return result
+}
+
+// PartitionCapacity parses the partition cap reported in PARTITION_CPUS, which
+// may be fractional. It returns false when no cap is reported.
+func PartitionCapacity(partitionCPUs string) (float64, bool) {
+	capacity, err := strconv.ParseFloat(strings.TrimSpace(partitionCPUs), 64)
+	if err != nil || !(capacity > 0) || math.IsInf(capacity, 0) {
+		return 0, false
+	}
+	return capacity, true
+}
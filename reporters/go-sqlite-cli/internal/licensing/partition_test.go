@@ -0,0 +1,55 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licensing_test
+
+import (
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/licensing"
+)
+
+func TestApplyPartitionCap(t *testing.T) {
+	tests := []struct {
+		name          string
+		cores         int
+		virtualized   bool
+		eligible      bool
+		partitionCPUs string
+		want          int
+		capCores      *int
+		applied       bool
+	}{
+		{name: "capped LPAR counts the cap", cores: 16, virtualized: true, eligible: true, partitionCPUs: "4", want: 4, capCores: cores(4), applied: true},
+		{name: "fractional capacity is rounded up", cores: 8, virtualized: true, eligible: true, partitionCPUs: "1.5", want: 2, capCores: cores(2), applied: true},
+		{name: "cap above the vCPUs keeps the vCPUs", cores: 4, virtualized: true, eligible: true, partitionCPUs: "48", want: 4, capCores: cores(48)},
+		{name: "unknown cap", cores: 4, virtualized: true, eligible: true, partitionCPUs: "unknown", want: 4},
+		{name: "empty cap", cores: 4, virtualized: true, eligible: true, partitionCPUs: "", want: 4},
+		{name: "zero cap", cores: 4, virtualized: true, eligible: true, partitionCPUs: "0", want: 4},
+		{name: "ineligible LPAR counts its full cores", cores: 48, virtualized: true, partitionCPUs: "1.5", want: 48, capCores: cores(2)},
+		{name: "physical node ignores the cap", cores: 16, virtualized: false, partitionCPUs: "4", want: 16, capCores: cores(4)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := licensing.ApplyPartitionCap(tt.cores, tt.virtualized, tt.eligible, tt.partitionCPUs)
+			if got.Cores != tt.want || got.Applied != tt.applied {
+				t.Errorf("Expected %d cores (applied %v), got %d (applied %v)", tt.want, tt.applied, got.Cores, got.Applied)
+			}
+			if (got.CapCores == nil) != (tt.capCores == nil) || (got.CapCores != nil && *got.CapCores != *tt.capCores) {
+				t.Errorf("Expected cap %v, got %v", tt.capCores, got.CapCores)
+			}
+		})
+	}
+}
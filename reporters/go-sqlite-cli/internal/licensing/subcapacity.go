@@ -0,0 +1,292 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package licensing applies the IBM sub-capacity counting rules to the nodes
+// running a product, and explains which rule produced each number.
+package licensing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/confidence"
+)
+
+// Rules applied to a physical host
+const (
+	// RuleSubCapacity counts the cores of eligible VMs on an identified host
+	RuleSubCapacity = "sub-capacity"
+	// RuleHostCap caps the VM cores of an identified host at its physical cores
+	RuleHostCap = "host-cap"
+	// RuleFullCapacity counts all physical cores of a host with an ineligible OS or virtualization
+	RuleFullCapacity = "full-capacity"
+	// RuleLowConfidence counts all physical cores when the host is not reliably identified
+	RuleLowConfidence = "low-confidence"
+	// RulePhysical counts all cores of a non-virtualized node
+	RulePhysical = "physical"
+)
+
+// Core rounding policies of the partial cores of capped partitions
+const (
+	// RoundPerVM rounds the capacity of each partition up to whole cores
+	RoundPerVM = "vm"
+	// RoundPerHost adds up the capacities of the partitions of a host and
+	// rounds the sum up to whole cores
+	RoundPerHost = "host"
+)
+
+// IsRounding reports whether s is a core rounding policy
+func IsRounding(s string) bool {
+	return s == RoundPerVM || s == RoundPerHost
+}
+
+// Node is a node running the product, as measured by the inspector
+type Node struct {
+	MainFQDN       string
+	Virtualized    bool   // false only when the node is known to run on bare metal
+	Cores          int    // cores of the node (considered_cpus)
+	Eligible       bool   // OS and virtualization are eligible for sub-capacity
+	HostID         string // physical host ID, empty when unknown
+	HostConfidence string // high, medium or low
+	HostCores      *int   // physical cores of the host, nil when unknown
+	// Capacity is the partial capacity of a capped partition (e.g. 1.5
+	// processing units) that Cores rounds up, 0 when the node has whole cores
+	Capacity float64
+	Installs int // installations of the product on the node
+}
+
+// HostResult is the license count of one physical host. Nodes without a known
+// physical host ID are counted as a host of their own.
+type HostResult struct {
+	HostKey      string   `json:"host_key"`
+	Nodes        []string `json:"nodes"`
+	NodeCores    int      `json:"node_cores"`
+	HostCores    *int     `json:"host_cores"`
+	LicenseCores int      `json:"license_cores"`
+	FullCapacity int      `json:"full_capacity_cores"` // cores counted under full-capacity licensing
+	Rule         string   `json:"rule"`
+	Explanation  string   `json:"explanation"`
+}
+
+// Calculate groups the nodes by physical host and counts the license cores of
+// each host:
+//   - non-virtualized nodes count all their cores;
+//   - a host with an ineligible (or unknown) OS or virtualization on any of its
+//     nodes counts all its physical cores (full capacity);
+//   - a host identified with low confidence, or not identified at all, also
+//     falls back to full capacity;
+//   - otherwise every VM counts min(VM cores, host cores) and the host counts
+//     the sum of its VMs, capped at its physical cores.
+//
+// When full capacity applies but the host cores are unknown, the node cores
+// are counted and the explanation says so. Results are sorted by host key.
+func Calculate(nodes []Node) []HostResult {
+	return CalculateWith(nodes, Options{})
+}
+
+// Options change how CalculateWith groups the nodes by physical host
+type Options struct {
+	// MinConfidence is the lowest host ID confidence for which the cores of
+	// the nodes of a host are deduplicated; a node identified with a lower
+	// confidence is counted as a host of its own. Empty or low deduplicates
+	// every identified host.
+	MinConfidence string
+	// Rounding is the core rounding policy of the sub-capacity rule: per VM
+	// (the default) or per host
+	Rounding string
+	// MinCoresPerInstall is the lowest number of cores each installation of
+	// the product counts for, 0 for none
+	MinCoresPerInstall int
+}
+
+// CalculateWith is Calculate with options
+func CalculateWith(nodes []Node, opts Options) []HostResult {
+	groups := make(map[string][]Node)
+	var keys []string
+	untrusted := make(map[string]string) // host IDs of the nodes below MinConfidence, by host key
+	raised := make(map[string][]string)  // nodes raised to the minimum cores, by host key
+	for _, node := range nodes {
+		raise := node.minimumCores(opts.MinCoresPerInstall) > node.Cores
+		if raise {
+			node.Cores = node.minimumCores(opts.MinCoresPerInstall)
+			node.Capacity = 0
+		}
+
+		key := node.HostID
+		if key == "" {
+			key = node.MainFQDN
+		} else if node.Virtualized && confidence.Rank(node.HostConfidence) < confidence.Rank(opts.MinConfidence) {
+			key = node.MainFQDN
+			untrusted[key] = node.HostID
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], node)
+		if raise {
+			raised[key] = append(raised[key], node.MainFQDN)
+		}
+	}
+	sort.Strings(keys)
+
+	results := make([]HostResult, 0, len(keys))
+	for _, key := range keys {
+		result := calculateHost(key, groups[key], opts.Rounding)
+		if hostID, ok := untrusted[key]; ok {
+			result.Explanation = fmt.Sprintf("host %s not deduplicated, confidence below %s; %s",
+				hostID, opts.MinConfidence, result.Explanation)
+		}
+		if list := raised[key]; len(list) > 0 {
+			sort.Strings(list)
+			result.Explanation += fmt.Sprintf("; %s raised to the minimum of %d core(s) per install",
+				strings.Join(list, ", "), opts.MinCoresPerInstall)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// Total returns the sum of the license cores and of the full-capacity cores of the results
+func Total(results []HostResult) (license, fullCapacity int) {
+	for _, r := range results {
+		license += r.LicenseCores
+		fullCapacity += r.FullCapacity
+	}
+	return license, fullCapacity
+}
+
+// minimumCores returns the lowest cores the node counts for with a minimum
+// per install; a node running the product has at least one installation
+func (n Node) minimumCores(perInstall int) int {
+	installs := n.Installs
+	if installs < 1 {
+		installs = 1
+	}
+	return perInstall * installs
+}
+
+// calculateHost applies the rules to the nodes of one physical host
+func calculateHost(key string, nodes []Node, rounding string) HostResult {
+	result := HostResult{HostKey: key}
+
+	virtualized := false
+	identified := true
+	var ineligible, lowConfidence []string
+	for _, node := range nodes {
+		result.Nodes = append(result.Nodes, node.MainFQDN)
+		result.NodeCores += node.Cores
+		if node.HostCores != nil && (result.HostCores == nil || *node.HostCores > *result.HostCores) {
+			cores := *node.HostCores
+			result.HostCores = &cores
+		}
+		if !node.Virtualized {
+			continue
+		}
+		virtualized = true
+		if !node.Eligible {
+			ineligible = append(ineligible, node.MainFQDN)
+		}
+		if node.HostID == "" {
+			identified = false
+		} else if node.HostConfidence == "low" {
+			lowConfidence = append(lowConfidence, node.MainFQDN)
+		}
+	}
+	sort.Strings(result.Nodes)
+
+	result.FullCapacity = result.NodeCores
+	if result.HostCores != nil {
+		result.FullCapacity = *result.HostCores
+	}
+
+	switch {
+	case !virtualized:
+		// The same machine may be measured under several names; count it once
+		for _, node := range nodes {
+			if node.Cores > result.LicenseCores {
+				result.LicenseCores = node.Cores
+			}
+		}
+		result.FullCapacity = result.LicenseCores
+		result.Rule = RulePhysical
+		result.Explanation = fmt.Sprintf("non-virtualized node: all %d cores count", result.LicenseCores)
+
+	case len(ineligible) > 0:
+		result.Rule = RuleFullCapacity
+		result.Explanation = fullCapacity(&result,
+			fmt.Sprintf("OS or virtualization not eligible for sub-capacity on %s", strings.Join(ineligible, ", ")))
+
+	case !identified:
+		result.Rule = RuleLowConfidence
+		result.Explanation = fullCapacity(&result, "physical host not identified")
+
+	case len(lowConfidence) > 0:
+		result.Rule = RuleLowConfidence
+		result.Explanation = fullCapacity(&result,
+			fmt.Sprintf("physical host identified with low confidence by %s", strings.Join(lowConfidence, ", ")))
+
+	default:
+		vmCores := 0
+		partial := 0.0 // capacities rounded up per host
+		for _, node := range nodes {
+			if rounding == RoundPerHost && node.Capacity > 0 {
+				partial += node.Capacity
+				continue
+			}
+			cores := node.Cores
+			if result.HostCores != nil && cores > *result.HostCores {
+				cores = *result.HostCores
+			}
+			vmCores += cores
+		}
+		rounded := ""
+		if partial > 0 {
+			// Round the sum to a millionth first, 0.1 + 0.2 is not 0.3 in floating point
+			partial = math.Round(partial*1e6) / 1e6
+			vmCores += int(math.Ceil(partial))
+			rounded = fmt.Sprintf(" (partial cores rounded up per host: %s)", strconv.FormatFloat(partial, 'f', -1, 64))
+		}
+
+		if result.HostCores != nil && vmCores > *result.HostCores {
+			result.LicenseCores = *result.HostCores
+			result.Rule = RuleHostCap
+			result.Explanation = fmt.Sprintf("%d VM cores%s on %d node(s) exceed the %d physical cores of the host: capped at the host cores",
+				vmCores, rounded, len(nodes), *result.HostCores)
+		} else {
+			result.LicenseCores = vmCores
+			result.Rule = RuleSubCapacity
+			host := "host with unknown physical cores"
+			if result.HostCores != nil {
+				host = fmt.Sprintf("%d-core host", *result.HostCores)
+			}
+			result.Explanation = fmt.Sprintf("eligible virtualization: %d VM cores%s on %d node(s) of a %s", vmCores, rounded, len(nodes), host)
+		}
+	}
+
+	return result
+}
+
+// fullCapacity counts all physical cores of the host, or the node cores when the
+// host cores are unknown, and returns the explanation
+func fullCapacity(result *HostResult, reason string) string {
+	if result.HostCores == nil {
+		result.LicenseCores = result.NodeCores
+		return fmt.Sprintf("%s: full capacity applies but the host cores are unknown, counting the %d node cores", reason, result.NodeCores)
+	}
+	result.LicenseCores = *result.HostCores
+	return fmt.Sprintf("%s: full capacity of the %d-core host", reason, *result.HostCores)
+}
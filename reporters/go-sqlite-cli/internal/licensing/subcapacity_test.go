@@ -0,0 +1,172 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licensing_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/licensing"
+)
+
+func cores(n int) *int {
+	return &n
+}
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		name    string
+		nodes   []licensing.Node
+		rule    string
+		license int
+	}{
+		{
+			name: "eligible VMs count their cores",
+			nodes: []licensing.Node{
+				{MainFQDN: "vm1", Virtualized: true, Cores: 4, Eligible: true, HostID: "esx1", HostConfidence: "high", HostCores: cores(32)},
+				{MainFQDN: "vm2", Virtualized: true, Cores: 8, Eligible: true, HostID: "esx1", HostConfidence: "medium", HostCores: cores(32)},
+			},
+			rule:    licensing.RuleSubCapacity,
+			license: 12,
+		},
+		{
+			name: "VM cores are capped at the host cores",
+			nodes: []licensing.Node{
+				{MainFQDN: "vm1", Virtualized: true, Cores: 16, Eligible: true, HostID: "esx1", HostConfidence: "high", HostCores: cores(24)},
+				{MainFQDN: "vm2", Virtualized: true, Cores: 40, Eligible: true, HostID: "esx1", HostConfidence: "high", HostCores: cores(24)},
+			},
+			rule:    licensing.RuleHostCap,
+			license: 24,
+		},
+		{
+			name: "one ineligible VM makes the host full capacity",
+			nodes: []licensing.Node{
+				{MainFQDN: "vm1", Virtualized: true, Cores: 4, Eligible: true, HostID: "lpar1", HostConfidence: "high", HostCores: cores(48)},
+				{MainFQDN: "vm2", Virtualized: true, Cores: 2, Eligible: false, HostID: "lpar1", HostConfidence: "high", HostCores: cores(48)},
+			},
+			rule:    licensing.RuleFullCapacity,
+			license: 48,
+		},
+		{
+			name: "low confidence falls back to full capacity",
+			nodes: []licensing.Node{
+				{MainFQDN: "vm1", Virtualized: true, Cores: 4, Eligible: true, HostID: "esx?", HostConfidence: "low", HostCores: cores(64)},
+			},
+			rule:    licensing.RuleLowConfidence,
+			license: 64,
+		},
+		{
+			name: "full capacity with unknown host cores counts the node cores",
+			nodes: []licensing.Node{
+				{MainFQDN: "vm1", Virtualized: true, Cores: 6, Eligible: true},
+			},
+			rule:    licensing.RuleLowConfidence,
+			license: 6,
+		},
+		{
+			name: "non-virtualized node counts all its cores",
+			nodes: []licensing.Node{
+				{MainFQDN: "box1", Virtualized: false, Cores: 8, Eligible: false, HostID: "box1", HostConfidence: "high"},
+			},
+			rule:    licensing.RulePhysical,
+			license: 8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := licensing.Calculate(tt.nodes)
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 host, got %d", len(results))
+			}
+			if results[0].Rule != tt.rule || results[0].LicenseCores != tt.license {
+				t.Errorf("Expected %s with %d cores, got %s with %d cores (%s)",
+					tt.rule, tt.license, results[0].Rule, results[0].LicenseCores, results[0].Explanation)
+			}
+			if results[0].Explanation == "" {
+				t.Error("Expected an explanation")
+			}
+		})
+	}
+}
+
+func TestCalculateGroupsByHost(t *testing.T) {
+	nodes := []licensing.Node{
+		{MainFQDN: "vm1", Virtualized: true, Cores: 4, Eligible: true, HostID: "esx1", HostConfidence: "high", HostCores: cores(32)},
+		{MainFQDN: "vm2", Virtualized: true, Cores: 4, Eligible: true, HostID: "esx2", HostConfidence: "high", HostCores: cores(32)},
+		{MainFQDN: "vm3", Virtualized: true, Cores: 2, Eligible: true, HostID: "esx1", HostConfidence: "high", HostCores: cores(32)},
+	}
+
+	results := licensing.Calculate(nodes)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 hosts, got %d", len(results))
+	}
+	if results[0].HostKey != "esx1" || len(results[0].Nodes) != 2 || results[0].LicenseCores != 6 {
+		t.Errorf("Unexpected result for esx1: %+v", results[0])
+	}
+	if license, full := licensing.Total(results); license != 10 || full != 64 {
+		t.Errorf("Expected 10 license and 64 full-capacity cores, got %d and %d", license, full)
+	}
+}
+
+func TestCalculateWithMinConfidence(t *testing.T) {
+	nodes := []licensing.Node{
+		{MainFQDN: "vm1", Virtualized: true, Cores: 16, Eligible: true, HostID: "esx1", HostConfidence: "medium", HostCores: cores(24)},
+		{MainFQDN: "vm2", Virtualized: true, Cores: 16, Eligible: true, HostID: "esx1", HostConfidence: "medium", HostCores: cores(24)},
+		{MainFQDN: "vm3", Virtualized: true, Cores: 8, Eligible: true, HostID: "esx2", HostConfidence: "high", HostCores: cores(24)},
+	}
+
+	// Every host ID is deduplicated: esx1 is capped at its cores
+	if license, _ := licensing.Total(licensing.CalculateWith(nodes, licensing.Options{MinConfidence: "low"})); license != 32 {
+		t.Errorf("Expected 32 license cores with every host deduplicated, got %d", license)
+	}
+
+	// The VMs of esx1 are counted on their own
+	results := licensing.CalculateWith(nodes, licensing.Options{MinConfidence: "high"})
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 hosts, got %+v", results)
+	}
+	if results[1].HostKey != "vm1" || results[1].LicenseCores != 16 || !strings.Contains(results[1].Explanation, "esx1 not deduplicated") {
+		t.Errorf("Unexpected result for vm1: %+v", results[1])
+	}
+	if license, _ := licensing.Total(results); license != 40 {
+		t.Errorf("Expected 40 license cores without deduplicating esx1, got %d", license)
+	}
+}
+
+func TestCalculateWithCoreRounding(t *testing.T) {
+	// Two shared-processor LPARs of 0.5 and 1.2 processing units, rounded up to 1 and 2 cores
+	nodes := []licensing.Node{
+		{MainFQDN: "lpar1", Virtualized: true, Cores: 1, Capacity: 0.5, Eligible: true, HostID: "p9", HostConfidence: "high", HostCores: cores(16)},
+		{MainFQDN: "lpar2", Virtualized: true, Cores: 2, Capacity: 1.2, Eligible: true, HostID: "p9", HostConfidence: "high", HostCores: cores(16)},
+		{MainFQDN: "lpar3", Virtualized: true, Cores: 4, Eligible: true, HostID: "p9", HostConfidence: "high", HostCores: cores(16)},
+	}
+
+	if license, _ := licensing.Total(licensing.CalculateWith(nodes, licensing.Options{})); license != 7 {
+		t.Errorf("Expected 7 license cores rounded per VM, got %d", license)
+	}
+
+	results := licensing.CalculateWith(nodes, licensing.Options{Rounding: licensing.RoundPerHost})
+	if len(results) != 1 || results[0].LicenseCores != 6 || !strings.Contains(results[0].Explanation, "rounded up per host: 1.7") {
+		t.Errorf("Expected 6 license cores rounded per host, got %+v", results)
+	}
+
+	// Each installation counts at least 3 cores
+	nodes[0].Installs = 2
+	results = licensing.CalculateWith(nodes, licensing.Options{Rounding: licensing.RoundPerHost, MinCoresPerInstall: 3})
+	if len(results) != 1 || results[0].LicenseCores != 6+3+4 || !strings.Contains(results[0].Explanation, "lpar1, lpar2 raised to the minimum of 3 core(s) per install") {
+		t.Errorf("Expected 13 license cores with the minimum per install, got %+v", results)
+	}
+}
@@ -37,15 +37,90 @@ type ProductCode struct {
 	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// Entitlement represents the licensed capacity owned for a license term
+type Entitlement struct {
+	TermID        string    `json:"term_id" db:"term_id"`
+	LicensedCores int       `json:"licensed_cores" db:"licensed_cores"`
+	LicensedPVU   int       `json:"licensed_pvu" db:"licensed_pvu"`
+	Notes         string    `json:"notes" db:"notes"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// LicenseTermDocument represents a license term document (PDF path or URL)
+// and the period it is in force for
+type LicenseTermDocument struct {
+	DocumentID    int64      `json:"document_id" db:"document_id"`
+	TermID        string     `json:"term_id" db:"term_id"`
+	Location      string     `json:"location" db:"location"`
+	EffectiveFrom time.Time  `json:"effective_from" db:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to" db:"effective_to"` // nil while in force
+	Description   string     `json:"description" db:"description"`
+	AttachedAt    time.Time  `json:"attached_at" db:"attached_at"`
+}
+
+// ProductTermMapping attributes a product code to a license term over a
+// period, overriding the term of the product code on those days
+type ProductTermMapping struct {
+	MappingID        int64      `json:"mapping_id" db:"mapping_id"`
+	ProductMnemoCode string     `json:"product_mnemo_code" db:"product_mnemo_code"`
+	TermID           string     `json:"term_id" db:"term_id"`
+	EffectiveFrom    time.Time  `json:"effective_from" db:"effective_from"`
+	EffectiveTo      *time.Time `json:"effective_to" db:"effective_to"` // nil while in force
+	Notes            string     `json:"notes" db:"notes"`
+	CreatedBy        string     `json:"created_by" db:"created_by"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ContractPeriod represents a contract period of a license term
+type ContractPeriod struct {
+	TermID    string    `json:"term_id" db:"term_id"`
+	Label     string    `json:"label" db:"label"` // e.g. the contract year
+	StartDate time.Time `json:"start_date" db:"start_date"`
+	EndDate   time.Time `json:"end_date" db:"end_date"`
+	Notes     string    `json:"notes" db:"notes"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
 // LandscapeNode represents a node in the landscape
 type LandscapeNode struct {
-	MainFQDN                 string    `json:"main_fqdn" db:"main_fqdn"`
-	Hostname                 string    `json:"hostname" db:"hostname"`
-	Mode                     string    `json:"mode" db:"mode"` // PROD or NON PROD
-	ExpectedProductCodesList string    `json:"expected_product_codes_list" db:"expected_product_codes_list"`
-	ExpectedCPUNo            *int      `json:"expected_cpu_no" db:"expected_cpu_no"`
-	CreatedAt                time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt                time.Time `json:"updated_at" db:"updated_at"`
+	MainFQDN                 string     `json:"main_fqdn" db:"main_fqdn"`
+	Hostname                 string     `json:"hostname" db:"hostname"`
+	Mode                     string     `json:"mode" db:"mode"` // PROD or NON PROD
+	ExpectedProductCodesList string     `json:"expected_product_codes_list" db:"expected_product_codes_list"`
+	ExpectedCPUNo            *int       `json:"expected_cpu_no" db:"expected_cpu_no"`
+	SiteID                   *string    `json:"site_id" db:"site_id"`
+	OrgID                    *string    `json:"org_id" db:"org_id"`
+	Owner                    string     `json:"owner" db:"owner"`
+	Team                     string     `json:"team" db:"team"`
+	CostCenter               string     `json:"cost_center" db:"cost_center"`
+	Classification           string     `json:"classification" db:"classification"` // active, standby, dr or decommissioned
+	StandbyType              string     `json:"standby_type" db:"standby_type"`     // cold, warm or hot for standby and DR nodes
+	DecommissionedOn         *time.Time `json:"decommissioned_on" db:"decommissioned_on"`
+	CreatedAt                time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt                time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Site represents a datacenter or cluster landscape nodes are grouped by
+type Site struct {
+	SiteID      string    `json:"site_id" db:"site_id"`
+	SiteName    string    `json:"site_name" db:"site_name"`
+	Description string    `json:"description" db:"description"`
+	NodeCount   int       `json:"node_count" db:"-"` // landscape nodes assigned to the site
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Organization represents a subsidiary or tenant whose license entitlements
+// are reported separately
+type Organization struct {
+	OrgID       string    `json:"org_id" db:"org_id"`
+	OrgName     string    `json:"org_name" db:"org_name"`
+	Description string    `json:"description" db:"description"`
+	NodeCount   int       `json:"node_count" db:"-"` // landscape nodes of the organization
+	TermCount   int       `json:"term_count" db:"-"` // license terms the organization has entitlements for
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // PhysicalHost represents a physical host that may run multiple VMs
@@ -61,6 +136,48 @@ type PhysicalHost struct {
 	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// PhysicalHostMerge records a rename or merge of physical host IDs
+type PhysicalHostMerge struct {
+	MergeID             int64     `json:"merge_id" db:"merge_id"`
+	Operation           string    `json:"operation" db:"operation"` // rename or merge
+	SourceHostID        string    `json:"source_host_id" db:"source_host_id"`
+	TargetHostID        string    `json:"target_host_id" db:"target_host_id"`
+	MeasurementsUpdated int       `json:"measurements_updated" db:"measurements_updated"`
+	Reason              string    `json:"reason" db:"reason"`
+	PerformedBy         string    `json:"performed_by" db:"performed_by"`
+	PerformedAt         time.Time `json:"performed_at" db:"performed_at"`
+}
+
+// ReferenceChange records a license term or product code inserted or changed
+// by a reference data load
+type ReferenceChange struct {
+	HistoryID int64            `json:"history_id" db:"history_id"`
+	Table     string           `json:"table"`              // license_terms or product_codes
+	Key       string           `json:"key"`                // term ID or product mnemo code
+	Change    string           `json:"change" db:"change"` // insert or update
+	Values    []ReferenceValue `json:"values"`             // every column of an insert, the changed columns of an update
+	Source    string           `json:"source" db:"source"`
+	ChangedBy string           `json:"changed_by" db:"changed_by"`
+	ChangedAt time.Time        `json:"changed_at" db:"changed_at"`
+}
+
+// ReferenceValue is the old and new value of a column of a reference change
+type ReferenceValue struct {
+	Column string `json:"column"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+}
+
+// NodeAlias maps the former FQDN of a renamed node to its main FQDN
+type NodeAlias struct {
+	AliasFQDN         string    `json:"alias_fqdn" db:"alias_fqdn"`
+	MainFQDN          string    `json:"main_fqdn" db:"main_fqdn"`
+	MeasurementsMoved int       `json:"measurements_moved" db:"measurements_moved"`
+	Reason            string    `json:"reason" db:"reason"`
+	CreatedBy         string    `json:"created_by" db:"created_by"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+}
+
 // Measurement represents system measurements from an inspector run
 type Measurement struct {
 	MainFQDN           string    `json:"main_fqdn" db:"main_fqdn"`
@@ -79,6 +196,7 @@ type Measurement struct {
 	ProcessorBrand     string    `json:"processor_brand" db:"processor_brand"`
 	HostPhysicalCPUs   string    `json:"host_physical_cpus" db:"host_physical_cpus"`
 	PartitionCPUs      string    `json:"partition_cpus" db:"partition_cpus"`
+	PartitionCapCores  *int      `json:"partition_cap_cores" db:"partition_cap_cores"` // nil when no cap is reported
 	ProcessorEligible  string    `json:"processor_eligible" db:"processor_eligible"`
 	OSEligible         string    `json:"os_eligible" db:"os_eligible"`
 	VirtEligible       string    `json:"virt_eligible" db:"virt_eligible"`
@@ -86,6 +204,16 @@ type Measurement struct {
 	PhysicalHostID     string    `json:"physical_host_id" db:"physical_host_id"`
 	HostIDMethod       string    `json:"host_id_method" db:"host_id_method"`
 	HostIDConfidence   string    `json:"host_id_confidence" db:"host_id_confidence"`
+	ContainerPlatform  string    `json:"container_platform" db:"container_platform"`
+	ContainerNamespace string    `json:"container_namespace" db:"container_namespace"`
+	ContainerPod       string    `json:"container_pod" db:"container_pod"`
+	ContainerName      string    `json:"container_name" db:"container_name"`
+	ContainerCPULimit  string    `json:"container_cpu_limit" db:"container_cpu_limit"`
+	ContainerCores     *int      `json:"container_limit_cores" db:"container_limit_cores"` // nil when no CPU limit is set
+	CloudProvider      string    `json:"cloud_provider" db:"cloud_provider"`
+	InstanceType       string    `json:"instance_type" db:"instance_type"`
+	Region             string    `json:"region" db:"region"`
+	AccountID          string    `json:"account_id" db:"account_id"`
 	CreatedAt          time.Time `json:"created_at" db:"created_at"`
 }
 
@@ -115,6 +243,26 @@ type ImportSession struct {
 	ErrorMessage   string    `json:"error_message" db:"error_message"`
 }
 
+// FailedImport is a file whose import failed and is waiting to be retried
+type FailedImport struct {
+	FilePath      string    `json:"file_path" db:"file_path"`
+	ErrorMessage  string    `json:"error_message" db:"error_message"`
+	AttemptCount  int       `json:"attempt_count" db:"attempt_count"`
+	FirstFailedAt time.Time `json:"first_failed_at" db:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at" db:"last_failed_at"`
+}
+
+// CollectionSource is the collection state of a remote host or drop server
+type CollectionSource struct {
+	SourceName     string     `json:"source_name" db:"source_name"`
+	Address        string     `json:"address" db:"address"`
+	RemoteDir      string     `json:"remote_dir" db:"remote_dir"`
+	LastRunAt      *time.Time `json:"last_run_at" db:"last_run_at"`
+	LastSuccessAt  *time.Time `json:"last_success_at" db:"last_success_at"`
+	LastError      string     `json:"last_error" db:"last_error"`
+	FilesCollected int        `json:"files_collected" db:"files_collected"`
+}
+
 // SchemaMetadata represents database schema metadata
 type SchemaMetadata struct {
 	ID        int       `json:"id" db:"id"`
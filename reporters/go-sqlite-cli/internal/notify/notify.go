@@ -0,0 +1,190 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package notify posts import and compliance events to webhooks (Slack,
+// Microsoft Teams or any endpoint accepting a JSON POST).
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Event types a webhook can subscribe to
+const (
+	EventImportErrors   = "import-errors"   // an import finished with failed files or warnings
+	EventUnknownProduct = "unknown-product" // imported files detect product codes missing from product_codes
+	EventBreach         = "breach"          // a compliance breach appeared (see 'report compliance')
+)
+
+// Webhook payload formats
+const (
+	FormatJSON  = "json"
+	FormatSlack = "slack"
+	FormatTeams = "teams"
+)
+
+// maxDetails limits the detail lines of a Slack or Teams message
+const maxDetails = 20
+
+// IsEvent reports whether name is a known event type
+func IsEvent(name string) bool {
+	switch name {
+	case EventImportErrors, EventUnknownProduct, EventBreach:
+		return true
+	}
+	return false
+}
+
+// IsFormat reports whether name is a known payload format; empty means json
+func IsFormat(name string) bool {
+	switch name {
+	case "", FormatJSON, FormatSlack, FormatTeams:
+		return true
+	}
+	return false
+}
+
+// Event is a notification sent to the webhooks subscribed to its type
+type Event struct {
+	Type      string    `json:"event"`
+	Summary   string    `json:"summary"`
+	Details   []string  `json:"details,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewEvent creates an event of the current time
+func NewEvent(eventType, summary string, details []string) Event {
+	return Event{Type: eventType, Summary: summary, Details: details, Timestamp: time.Now()}
+}
+
+// Webhook is an endpoint notified of events
+type Webhook struct {
+	Name   string
+	URL    string
+	Format string   // json (default), slack or teams
+	Events []string // event types sent; all when empty
+}
+
+// Subscribed reports whether the webhook receives events of eventType
+func (w Webhook) Subscribed(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload encodes the event in the format of the webhook
+func (w Webhook) Payload(e Event) ([]byte, error) {
+	switch w.Format {
+	case FormatSlack:
+		return json.Marshal(map[string]string{"text": messageText(e, "*", "\n")})
+	case FormatTeams:
+		return json.Marshal(map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  e.Summary,
+			"title":    "iwldr: " + e.Type,
+			"text":     messageText(e, "**", "\n\n"),
+		})
+	case "", FormatJSON:
+		return json.Marshal(e)
+	}
+	return nil, fmt.Errorf("unknown webhook format %q", w.Format)
+}
+
+// messageText renders the summary in bold followed by the details, one per
+// line, for chat messages
+func messageText(e Event, bold, newline string) string {
+	lines := []string{bold + e.Summary + bold}
+	for i, detail := range e.Details {
+		if i == maxDetails {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(e.Details)-maxDetails))
+			break
+		}
+		lines = append(lines, "- "+detail)
+	}
+	return strings.Join(lines, newline)
+}
+
+// Notifier sends events to the configured webhooks
+type Notifier struct {
+	webhooks []Webhook
+	client   *http.Client
+}
+
+// NewNotifier creates a notifier for the webhooks
+func NewNotifier(webhooks []Webhook) *Notifier {
+	return &Notifier{
+		webhooks: webhooks,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Subscribed reports whether any webhook receives events of eventType, so
+// that events costly to compute can be skipped
+func (n *Notifier) Subscribed(eventType string) bool {
+	for _, w := range n.webhooks {
+		if w.Subscribed(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Send posts the event to every subscribed webhook. A failing webhook does not
+// stop the others; all failures are returned together.
+func (n *Notifier) Send(e Event) error {
+	var errs []error
+	for _, w := range n.webhooks {
+		if !w.Subscribed(e.Type) {
+			continue
+		}
+		if err := n.post(w, e); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", w.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// post sends one event to one webhook; any status other than 2xx is an error
+func (n *Notifier) post(w Webhook, e Event) error {
+	payload, err := w.Payload(e)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(w.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
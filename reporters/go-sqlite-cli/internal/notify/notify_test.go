@@ -0,0 +1,126 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/notify"
+)
+
+// recorder collects the bodies posted to a test server
+type recorder struct {
+	bodies []string
+}
+
+func (r *recorder) server(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.bodies = append(r.bodies, string(body))
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSendHonoursSubscriptions(t *testing.T) {
+	var all, breaches recorder
+	allSrv := all.server(t, http.StatusOK)
+	breachSrv := breaches.server(t, http.StatusNoContent)
+
+	n := notify.NewNotifier([]notify.Webhook{
+		{Name: "all", URL: allSrv.URL},
+		{Name: "breaches", URL: breachSrv.URL, Events: []string{notify.EventBreach}},
+	})
+
+	if err := n.Send(notify.NewEvent(notify.EventImportErrors, "1 file failed", []string{"a.csv"})); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if err := n.Send(notify.NewEvent(notify.EventBreach, "1 new breach", nil)); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if len(all.bodies) != 2 || len(breaches.bodies) != 1 {
+		t.Fatalf("Expected 2 and 1 posts, got %d and %d", len(all.bodies), len(breaches.bodies))
+	}
+
+	var event notify.Event
+	if err := json.Unmarshal([]byte(all.bodies[0]), &event); err != nil {
+		t.Fatalf("Invalid JSON payload: %v", err)
+	}
+	if event.Type != notify.EventImportErrors || event.Summary != "1 file failed" || len(event.Details) != 1 {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
+
+func TestSendReportsFailingWebhooks(t *testing.T) {
+	var ok, failing recorder
+	okSrv := ok.server(t, http.StatusOK)
+	failingSrv := failing.server(t, http.StatusInternalServerError)
+
+	n := notify.NewNotifier([]notify.Webhook{
+		{Name: "failing", URL: failingSrv.URL},
+		{Name: "ok", URL: okSrv.URL},
+	})
+
+	err := n.Send(notify.NewEvent(notify.EventUnknownProduct, "unknown", nil))
+	if err == nil || !strings.Contains(err.Error(), "webhook failing") {
+		t.Errorf("Expected failure of the failing webhook, got %v", err)
+	}
+	if len(ok.bodies) != 1 {
+		t.Errorf("Expected the other webhook to be notified, got %d posts", len(ok.bodies))
+	}
+}
+
+func TestPayloadFormats(t *testing.T) {
+	details := make([]string, 25)
+	for i := range details {
+		details[i] = "IS_ONP_PRD"
+	}
+	event := notify.NewEvent(notify.EventBreach, "25 new breaches", details)
+
+	slack, err := notify.Webhook{Format: notify.FormatSlack}.Payload(event)
+	if err != nil {
+		t.Fatalf("Payload failed: %v", err)
+	}
+	var message map[string]string
+	if err := json.Unmarshal(slack, &message); err != nil {
+		t.Fatalf("Invalid Slack payload: %v", err)
+	}
+	if !strings.HasPrefix(message["text"], "*25 new breaches*\n- IS_ONP_PRD") || !strings.HasSuffix(message["text"], "... and 5 more") {
+		t.Errorf("Unexpected Slack text: %q", message["text"])
+	}
+
+	teams, err := notify.Webhook{Format: notify.FormatTeams}.Payload(event)
+	if err != nil {
+		t.Fatalf("Payload failed: %v", err)
+	}
+	if err := json.Unmarshal(teams, &message); err != nil {
+		t.Fatalf("Invalid Teams payload: %v", err)
+	}
+	if message["@type"] != "MessageCard" || message["summary"] != "25 new breaches" {
+		t.Errorf("Unexpected Teams card: %v", message)
+	}
+
+	if _, err := (notify.Webhook{Format: "xml"}).Payload(event); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,386 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package parquet writes minimal Apache Parquet files. It supports flat
+// schemas of optional 64-bit integer, double, string and timestamp columns,
+// PLAIN encoded and gzip compressed, one data page per column chunk; nothing
+// more is needed to load exported tables in Spark or DuckDB.
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// magic starts and ends every Parquet file
+const magic = "PAR1"
+
+// RowGroupRows is the number of rows buffered in memory before they are
+// written as a row group
+const RowGroupRows = 100000
+
+// Type is the type of a column
+type Type int
+
+// Column types
+const (
+	Int64     Type = iota // INT64
+	Double                // DOUBLE
+	String                // BYTE_ARRAY annotated UTF8
+	Timestamp             // INT64 annotated TIMESTAMP_MICROS, UTC
+)
+
+// Physical types, converted types, encodings, codec and page type of the
+// Parquet format (parquet.thrift)
+const (
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecGzip = 2
+
+	pageData = 0
+)
+
+// Column is a column of the schema; every column is optional (nullable)
+type Column struct {
+	Name string
+	Type Type
+}
+
+// columnChunk buffers the values of a column for the current row group
+type columnChunk struct {
+	defined []bool       // one per row, false for NULL
+	values  bytes.Buffer // PLAIN encoded values of the defined rows
+}
+
+// chunkMeta is the ColumnMetaData of a written column chunk
+type chunkMeta struct {
+	numValues        int64
+	uncompressedSize int64
+	compressedSize   int64
+	dataPageOffset   int64
+}
+
+// rowGroupMeta is the RowGroup of a written row group
+type rowGroupMeta struct {
+	columns []chunkMeta
+	numRows int64
+	size    int64
+}
+
+// Writer writes rows to a Parquet file. Rows are buffered in memory by row
+// group; Close writes the last row group and the file footer.
+type Writer struct {
+	w         io.Writer
+	offset    int64
+	columns   []Column
+	chunks    []columnChunk
+	rows      int
+	rowGroups []rowGroupMeta
+}
+
+// NewWriter starts a Parquet file with the given columns
+func NewWriter(w io.Writer, columns []Column) (*Writer, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("parquet schema has no columns")
+	}
+	pw := &Writer{w: w, columns: columns, chunks: make([]columnChunk, len(columns))}
+	if err := pw.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// Write appends a row with one value per column: nil for NULL, an int64 (or
+// bool) for Int64, a float64 or int64 for Double, a time.Time for Timestamp
+// and any value for String (formatted with fmt unless a string or []byte)
+func (pw *Writer) Write(row []interface{}) error {
+	if len(row) != len(pw.columns) {
+		return fmt.Errorf("row has %d values, expected %d", len(row), len(pw.columns))
+	}
+	encoded := make([][]byte, len(row))
+	for i, value := range row {
+		var err error
+		if encoded[i], err = encode(pw.columns[i], value); err != nil {
+			return err
+		}
+	}
+	for i, value := range encoded {
+		pw.chunks[i].defined = append(pw.chunks[i].defined, value != nil)
+		pw.chunks[i].values.Write(value)
+	}
+	pw.rows++
+	if pw.rows >= RowGroupRows {
+		return pw.flush()
+	}
+	return nil
+}
+
+// encode returns the PLAIN encoding of a value of the column, nil for NULL
+func encode(column Column, value interface{}) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	b := make([]byte, 8)
+	switch column.Type {
+	case Int64:
+		var v int64
+		switch value := value.(type) {
+		case int64:
+			v = value
+		case int:
+			v = int64(value)
+		case bool:
+			if value {
+				v = 1
+			}
+		default:
+			return nil, fmt.Errorf("column %s: %T value %v is not an integer", column.Name, value, value)
+		}
+		binary.LittleEndian.PutUint64(b, uint64(v))
+	case Double:
+		var v float64
+		switch value := value.(type) {
+		case float64:
+			v = value
+		case int64:
+			v = float64(value)
+		default:
+			return nil, fmt.Errorf("column %s: %T value %v is not a number", column.Name, value, value)
+		}
+		binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+	case Timestamp:
+		v, ok := value.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("column %s: %T value %v is not a timestamp", column.Name, value, value)
+		}
+		binary.LittleEndian.PutUint64(b, uint64(v.UnixMicro()))
+	case String:
+		var v string
+		switch value := value.(type) {
+		case string:
+			v = value
+		case []byte:
+			v = string(value)
+		case time.Time:
+			v = value.UTC().Format(time.RFC3339)
+		default:
+			v = fmt.Sprint(value)
+		}
+		b = binary.LittleEndian.AppendUint32(nil, uint32(len(v)))
+		b = append(b, v...)
+	default:
+		return nil, fmt.Errorf("column %s: unknown type %d", column.Name, column.Type)
+	}
+	return b, nil
+}
+
+// Close writes the buffered rows and the file footer. It does not close the
+// underlying writer.
+func (pw *Writer) Close() error {
+	if pw.rows > 0 {
+		if err := pw.flush(); err != nil {
+			return err
+		}
+	}
+
+	footer := pw.fileMetaData()
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+	for _, b := range [][]byte{footer, length[:], []byte(magic)} {
+		if err := pw.write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group, one data page per column
+func (pw *Writer) flush() error {
+	group := rowGroupMeta{numRows: int64(pw.rows)}
+	for i := range pw.chunks {
+		meta, err := pw.writeChunk(&pw.chunks[i])
+		if err != nil {
+			return fmt.Errorf("column %s: %w", pw.columns[i].Name, err)
+		}
+		group.columns = append(group.columns, meta)
+		group.size += meta.uncompressedSize
+		pw.chunks[i] = columnChunk{}
+	}
+	pw.rowGroups = append(pw.rowGroups, group)
+	pw.rows = 0
+	return nil
+}
+
+// writeChunk writes a column chunk as a single gzip compressed data page: the
+// definition levels (RLE encoded, prefixed by their length) then the values
+func (pw *Writer) writeChunk(c *columnChunk) (chunkMeta, error) {
+	levels := encodeLevels(c.defined)
+	var page bytes.Buffer
+	binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+	page.Write(levels)
+	page.Write(c.values.Bytes())
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(page.Bytes()); err != nil {
+		return chunkMeta{}, err
+	}
+	if err := gz.Close(); err != nil {
+		return chunkMeta{}, err
+	}
+
+	header := &thriftWriter{}
+	header.i32(1, pageData)
+	header.i32(2, int32(page.Len()))
+	header.i32(3, int32(compressed.Len()))
+	header.structField(5) // DataPageHeader
+	header.i32(1, int32(len(c.defined)))
+	header.i32(2, encodingPlain)
+	header.i32(3, encodingRLE)
+	header.i32(4, encodingRLE)
+	header.structEnd()
+	header.stop()
+
+	meta := chunkMeta{
+		numValues:        int64(len(c.defined)),
+		uncompressedSize: int64(header.buf.Len() + page.Len()),
+		compressedSize:   int64(header.buf.Len() + compressed.Len()),
+		dataPageOffset:   pw.offset,
+	}
+	if err := pw.write(header.buf.Bytes()); err != nil {
+		return chunkMeta{}, err
+	}
+	if err := pw.write(compressed.Bytes()); err != nil {
+		return chunkMeta{}, err
+	}
+	return meta, nil
+}
+
+// encodeLevels encodes definition levels of bit width 1 as runs of the
+// RLE/bit-packing hybrid encoding: a varint run length shifted left by one,
+// then the level in one byte
+func encodeLevels(defined []bool) []byte {
+	var buf []byte
+	for start := 0; start < len(defined); {
+		end := start
+		for end < len(defined) && defined[end] == defined[start] {
+			end++
+		}
+		buf = binary.AppendUvarint(buf, uint64(end-start)<<1)
+		if defined[start] {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+		start = end
+	}
+	return buf
+}
+
+// fileMetaData encodes the FileMetaData of the footer
+func (pw *Writer) fileMetaData() []byte {
+	var numRows int64
+	for _, group := range pw.rowGroups {
+		numRows += group.numRows
+	}
+
+	t := &thriftWriter{}
+	t.i32(1, 1) // version
+	t.listBegin(2, thriftStruct, len(pw.columns)+1)
+	t.structBegin() // root of the schema
+	t.binary(4, "schema")
+	t.i32(5, int32(len(pw.columns)))
+	t.structEnd()
+	for _, column := range pw.columns {
+		t.structBegin()
+		t.i32(1, int32(column.physicalType()))
+		t.i32(3, repetitionOptional)
+		t.binary(4, column.Name)
+		switch column.Type {
+		case String:
+			t.i32(6, convertedUTF8)
+		case Timestamp:
+			t.i32(6, convertedTimestampMicros)
+		}
+		t.structEnd()
+	}
+	t.i64(3, numRows)
+	t.listBegin(4, thriftStruct, len(pw.rowGroups))
+	for _, group := range pw.rowGroups {
+		t.structBegin()
+		t.listBegin(1, thriftStruct, len(group.columns))
+		for i, chunk := range group.columns {
+			t.structBegin()
+			t.i64(2, chunk.dataPageOffset) // file_offset
+			t.structField(3)               // ColumnMetaData
+			t.i32(1, int32(pw.columns[i].physicalType()))
+			t.listBegin(2, thriftI32, 2)
+			t.listI32(encodingPlain)
+			t.listI32(encodingRLE)
+			t.listBegin(3, thriftBinary, 1)
+			t.listBinary(pw.columns[i].Name)
+			t.i32(4, codecGzip)
+			t.i64(5, chunk.numValues)
+			t.i64(6, chunk.uncompressedSize)
+			t.i64(7, chunk.compressedSize)
+			t.i64(9, chunk.dataPageOffset)
+			t.structEnd()
+			t.structEnd()
+		}
+		t.i64(2, group.size)
+		t.i64(3, group.numRows)
+		t.structEnd()
+	}
+	t.binary(6, "iwldr")
+	t.stop()
+	return t.buf.Bytes()
+}
+
+// physicalType returns the Parquet physical type storing the column
+func (c Column) physicalType() int {
+	switch c.Type {
+	case Double:
+		return typeDouble
+	case String:
+		return typeByteArray
+	default:
+		return typeInt64
+	}
+}
+
+// write writes to the file, tracking the offset of the column chunks
+func (pw *Writer) write(b []byte) error {
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write parquet file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/parquet"
+)
+
+// thriftReader decodes the Thrift compact protocol into maps of field ids,
+// slices, int64 and []byte values
+type thriftReader struct {
+	r *bytes.Reader
+	t *testing.T
+}
+
+func (tr *thriftReader) uvarint() uint64 {
+	v, err := binary.ReadUvarint(tr.r)
+	if err != nil {
+		tr.t.Fatalf("Invalid varint: %v", err)
+	}
+	return v
+}
+
+func (tr *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case 1, 2: // boolean true, false
+		return typ == 1
+	case 5, 6: // i32, i64
+		v := tr.uvarint()
+		return int64(v>>1) ^ -int64(v&1)
+	case 8: // binary
+		b := make([]byte, tr.uvarint())
+		io.ReadFull(tr.r, b)
+		return b
+	case 9: // list
+		header, _ := tr.r.ReadByte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(tr.uvarint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = tr.value(header & 0x0f)
+		}
+		return list
+	case 12: // struct
+		fields := map[int16]interface{}{}
+		var id int16
+		for {
+			header, err := tr.r.ReadByte()
+			if err != nil {
+				tr.t.Fatalf("Truncated struct: %v", err)
+			}
+			if header == 0 {
+				return fields
+			}
+			if delta := int16(header >> 4); delta != 0 {
+				id += delta
+			} else {
+				v := tr.uvarint()
+				id = int16(int64(v>>1) ^ -int64(v&1))
+			}
+			fields[id] = tr.value(header & 0x0f)
+		}
+	}
+	tr.t.Fatalf("Unexpected thrift type %d", typ)
+	return nil
+}
+
+func TestWriter(t *testing.T) {
+	columns := []parquet.Column{
+		{Name: "main_fqdn", Type: parquet.String},
+		{Name: "cpu_count", Type: parquet.Int64},
+		{Name: "ratio", Type: parquet.Double},
+		{Name: "created_at", Type: parquet.Timestamp},
+	}
+	created := time.Date(2025, 11, 6, 8, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	w, err := parquet.NewWriter(&buf, columns)
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	rows := [][]interface{}{
+		{"app01", int64(4), 0.5, created},
+		{"app02", nil, int64(2), nil},
+		{[]byte("app03"), int64(8), nil, created},
+	}
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := w.Write([]interface{}{"app04", "four", nil, nil}); err == nil {
+		t.Error("Expected a string in an integer column to be rejected")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data := buf.Bytes()
+	if string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatal("File does not start and end with PAR1")
+	}
+	footerLength := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := data[len(data)-8-footerLength : len(data)-8]
+	meta := (&thriftReader{r: bytes.NewReader(footer), t: t}).value(12).(map[int16]interface{})
+
+	if meta[3].(int64) != 3 {
+		t.Errorf("Expected 3 rows, got %v", meta[3])
+	}
+	schema := meta[2].([]interface{})
+	if len(schema) != 5 || schema[0].(map[int16]interface{})[5].(int64) != 4 {
+		t.Fatalf("Expected a root with 4 columns, got %v", schema)
+	}
+	for i, column := range columns {
+		if name := string(schema[i+1].(map[int16]interface{})[4].([]byte)); name != column.Name {
+			t.Errorf("Expected column %d to be %s, got %s", i, column.Name, name)
+		}
+	}
+
+	// Decode the integer column chunk of the single row group
+	groups := meta[4].([]interface{})
+	if len(groups) != 1 {
+		t.Fatalf("Expected 1 row group, got %d", len(groups))
+	}
+	chunks := groups[0].(map[int16]interface{})[1].([]interface{})
+	for i, chunk := range chunks {
+		if values := chunk.(map[int16]interface{})[3].(map[int16]interface{})[5].(int64); values != 3 {
+			t.Errorf("Expected 3 values in column %s, got %d", columns[i].Name, values)
+		}
+	}
+	chunk := chunks[1].(map[int16]interface{})[3].(map[int16]interface{})
+	page := bytes.NewReader(data[chunk[9].(int64):])
+	header := (&thriftReader{r: page, t: t}).value(12).(map[int16]interface{})
+	compressed := make([]byte, header[3].(int64))
+	io.ReadFull(page, compressed)
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("Page is not gzip compressed: %v", err)
+	}
+	body, _ := io.ReadAll(gz)
+	if int64(len(body)) != header[2].(int64) {
+		t.Errorf("Expected an uncompressed page of %d bytes, got %d", header[2], len(body))
+	}
+
+	// Definition levels: runs of 1 defined, 1 null, 1 defined
+	levelsLength := binary.LittleEndian.Uint32(body)
+	if levels := body[4 : 4+levelsLength]; !bytes.Equal(levels, []byte{2, 1, 2, 0, 2, 1}) {
+		t.Errorf("Unexpected definition levels %v", levels)
+	}
+	values := body[4+levelsLength:]
+	if len(values) != 16 || binary.LittleEndian.Uint64(values) != 4 || binary.LittleEndian.Uint64(values[8:]) != 8 {
+		t.Errorf("Unexpected PLAIN values %v", values)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Element types of the Thrift compact protocol used by lists and field headers
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the Parquet metadata structures with the Thrift compact
+// protocol. Field headers carry the delta from the previous field id of the
+// enclosing struct, so the last field id of every open struct is kept.
+type thriftWriter struct {
+	buf     bytes.Buffer
+	lastID  int16
+	parents []int16
+}
+
+// field writes a field header
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.lastID; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.lastID = id
+}
+
+// varint writes a zigzag encoded varint
+func (t *thriftWriter) varint(v int64) {
+	t.buf.Write(binary.AppendUvarint(nil, uint64((v<<1)^(v>>63))))
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.listBinary(s)
+}
+
+// structField starts a struct field, ended by structEnd
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.structBegin()
+}
+
+// structBegin starts a struct value, e.g. an element of a list
+func (t *thriftWriter) structBegin() {
+	t.parents = append(t.parents, t.lastID)
+	t.lastID = 0
+}
+
+// structEnd writes the stop field of a struct value
+func (t *thriftWriter) structEnd() {
+	t.stop()
+	t.lastID = t.parents[len(t.parents)-1]
+	t.parents = t.parents[:len(t.parents)-1]
+}
+
+// stop writes the stop field ending the outermost struct
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
+
+// listBegin starts a list field of size elements of the given type, written
+// with listI32, listBinary or structBegin and structEnd
+func (t *thriftWriter) listBegin(id int16, elem byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elem)
+	} else {
+		t.buf.WriteByte(0xf0 | elem)
+		t.buf.Write(binary.AppendUvarint(nil, uint64(size)))
+	}
+}
+
+func (t *thriftWriter) listI32(v int32) {
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) listBinary(s string) {
+	t.buf.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	t.buf.WriteString(s)
+}
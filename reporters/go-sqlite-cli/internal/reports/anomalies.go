@@ -0,0 +1,349 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Anomaly severities, from least to most severe
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// severityRank orders the severities for --min-severity
+var severityRank = map[string]int{
+	SeverityInfo:     0,
+	SeverityWarning:  1,
+	SeverityCritical: 2,
+}
+
+// ParseSeverity validates a severity name; empty means info
+func ParseSeverity(value string) (string, error) {
+	if value == "" {
+		return SeverityInfo, nil
+	}
+	if _, ok := severityRank[value]; !ok {
+		return "", fmt.Errorf("invalid severity %q (expected info, warning or critical)", value)
+	}
+	return value, nil
+}
+
+// SeverityAtLeast reports whether severity is at least as severe as min
+func SeverityAtLeast(severity, min string) bool {
+	return severityRank[severity] >= severityRank[min]
+}
+
+// Anomaly types
+const (
+	AnomalyCPUChange            = "cpu-change"
+	AnomalyVirtualizationChange = "virtualization-change"
+	AnomalyHostChange           = "host-change"
+	AnomalyProductAppeared      = "product-appeared"
+	AnomalyProductDisappeared   = "product-disappeared"
+)
+
+// AnomalyMeasurement is the part of a measurement compared by the anomaly checks
+type AnomalyMeasurement struct {
+	MainFQDN           string
+	DetectionTimestamp time.Time
+	CPUCount           int
+	ConsideredCPUs     int
+	IsVirtualized      string
+	PhysicalHostID     string
+	Products           []string // products present (running) at the measurement
+}
+
+// AnomalyRow is a suspicious change between two consecutive measurements of a node
+type AnomalyRow struct {
+	MainFQDN          string    `json:"main_fqdn"`
+	PreviousTimestamp time.Time `json:"previous_timestamp"`
+	DetectedAt        time.Time `json:"detection_timestamp"`
+	Type              string    `json:"type"`
+	Severity          string    `json:"severity"`
+	Previous          string    `json:"previous"`
+	Current           string    `json:"current"`
+	Message           string    `json:"message"`
+}
+
+// DetectAnomalies compares each measurement with the previous measurement of
+// the same node. The measurements must be sorted by node and timestamp.
+//   - cpu-change: the CPU count or the considered cores changed; critical when
+//     the considered cores at least doubled, warning when they grew, info otherwise
+//   - virtualization-change: the node switched between virtualized and bare
+//     metal, which changes the counting rule (critical)
+//   - host-change: the node moved to another physical host ID (warning)
+//   - product-appeared (warning) and product-disappeared (info)
+func DetectAnomalies(measurements []AnomalyMeasurement) []AnomalyRow {
+	var anomalies []AnomalyRow
+	for i := 1; i < len(measurements); i++ {
+		prev, cur := measurements[i-1], measurements[i]
+		if prev.MainFQDN != cur.MainFQDN {
+			continue
+		}
+
+		add := func(anomalyType, severity, previous, current, message string) {
+			anomalies = append(anomalies, AnomalyRow{
+				MainFQDN:          cur.MainFQDN,
+				PreviousTimestamp: prev.DetectionTimestamp,
+				DetectedAt:        cur.DetectionTimestamp,
+				Type:              anomalyType,
+				Severity:          severity,
+				Previous:          previous,
+				Current:           current,
+				Message:           message,
+			})
+		}
+
+		if prev.CPUCount != cur.CPUCount || prev.ConsideredCPUs != cur.ConsideredCPUs {
+			severity := SeverityInfo
+			switch {
+			case cur.ConsideredCPUs >= 2*prev.ConsideredCPUs && cur.ConsideredCPUs > prev.ConsideredCPUs:
+				severity = SeverityCritical
+			case cur.ConsideredCPUs > prev.ConsideredCPUs:
+				severity = SeverityWarning
+			}
+			add(AnomalyCPUChange, severity,
+				fmt.Sprintf("%d CPUs, %d considered", prev.CPUCount, prev.ConsideredCPUs),
+				fmt.Sprintf("%d CPUs, %d considered", cur.CPUCount, cur.ConsideredCPUs),
+				fmt.Sprintf("considered cores changed by %+d", cur.ConsideredCPUs-prev.ConsideredCPUs))
+		}
+
+		if prev.IsVirtualized != cur.IsVirtualized {
+			add(AnomalyVirtualizationChange, SeverityCritical, prev.IsVirtualized, cur.IsVirtualized,
+				"virtualization flag changed; the node is counted under another rule")
+		}
+
+		if prev.PhysicalHostID != cur.PhysicalHostID {
+			add(AnomalyHostChange, SeverityWarning, prev.PhysicalHostID, cur.PhysicalHostID,
+				"node moved to another physical host")
+		}
+
+		appeared, disappeared := diffProducts(prev.Products, cur.Products)
+		for _, product := range appeared {
+			add(AnomalyProductAppeared, SeverityWarning, "", product, "product started running")
+		}
+		for _, product := range disappeared {
+			add(AnomalyProductDisappeared, SeverityInfo, product, "", "product no longer running")
+		}
+	}
+	return anomalies
+}
+
+// diffProducts returns the products only in cur and the products only in prev
+func diffProducts(prev, cur []string) (appeared, disappeared []string) {
+	inPrev := make(map[string]bool, len(prev))
+	for _, p := range prev {
+		inPrev[p] = true
+	}
+	inCur := make(map[string]bool, len(cur))
+	for _, p := range cur {
+		inCur[p] = true
+		if !inPrev[p] {
+			appeared = append(appeared, p)
+		}
+	}
+	for _, p := range prev {
+		if !inCur[p] {
+			disappeared = append(disappeared, p)
+		}
+	}
+	sort.Strings(appeared)
+	sort.Strings(disappeared)
+	return appeared, disappeared
+}
+
+// AnomalyReport flags suspicious changes between consecutive measurements
+type AnomalyReport struct {
+	db *sql.DB
+}
+
+// NewAnomalyReport creates a new report generator
+func NewAnomalyReport(db *sql.DB) *AnomalyReport {
+	return &AnomalyReport{db: db}
+}
+
+// Query loads the measurements, detects the anomalies and returns those of at
+// least minSeverity, newest first. host matches a substring of the FQDN; the
+// dates filter on the day of the later measurement, which is still compared
+// with its predecessor when that one is outside the range.
+func (r *AnomalyReport) Query(ctx context.Context, host, minSeverity string, fromDate, toDate *time.Time) ([]AnomalyRow, error) {
+	query := `
+		SELECT
+			m.main_fqdn,
+			m.detection_timestamp,
+			m.cpu_count,
+			m.considered_cpus,
+			m.is_virtualized,
+			COALESCE(m.physical_host_id, ''),
+			COALESCE((SELECT GROUP_CONCAT(d.product_mnemo_code)
+			          FROM detected_products d
+			          WHERE d.main_fqdn = m.main_fqdn
+			            AND d.detection_timestamp = m.detection_timestamp
+			            AND d.status = 'present'), '')
+		FROM measurements m
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if host != "" {
+		query += " AND m.main_fqdn LIKE ?"
+		args = append(args, "%"+host+"%")
+	}
+
+	if toDate != nil {
+		query += " AND DATE(m.detection_timestamp) <= ?"
+		args = append(args, toDate.Format("2006-01-02"))
+	}
+
+	query += " ORDER BY m.main_fqdn, m.detection_timestamp"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query measurements: %w", err)
+	}
+	defer rows.Close()
+
+	var measurements []AnomalyMeasurement
+	for rows.Next() {
+		var m AnomalyMeasurement
+		var products string
+		err := rows.Scan(&m.MainFQDN, &m.DetectionTimestamp, &m.CPUCount, &m.ConsideredCPUs,
+			&m.IsVirtualized, &m.PhysicalHostID, &products)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if products != "" {
+			m.Products = strings.Split(products, ",")
+		}
+		measurements = append(measurements, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var results []AnomalyRow
+	for _, anomaly := range DetectAnomalies(measurements) {
+		if !SeverityAtLeast(anomaly.Severity, minSeverity) {
+			continue
+		}
+		if fromDate != nil && anomaly.DetectedAt.Format("2006-01-02") < fromDate.Format("2006-01-02") {
+			continue
+		}
+		results = append(results, anomaly)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if !results[i].DetectedAt.Equal(results[j].DetectedAt) {
+			return results[i].DetectedAt.After(results[j].DetectedAt)
+		}
+		return severityRank[results[i].Severity] > severityRank[results[j].Severity]
+	})
+
+	return results, nil
+}
+
+// WriteTable writes data in ASCII table format
+func (r *AnomalyReport) WriteTable(w io.Writer, rows []AnomalyRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "DETECTED_AT\tHOST\tSEVERITY\tTYPE\tPREVIOUS\tCURRENT\tMESSAGE")
+	fmt.Fprintln(tw, "-----------\t----\t--------\t----\t--------\t-------\t-------")
+
+	// Data rows
+	counts := make(map[string]int)
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			row.DetectedAt.Format("2006-01-02 15:04:05"),
+			row.MainFQDN,
+			row.Severity,
+			row.Type,
+			row.Previous,
+			row.Current,
+			row.Message,
+		)
+		counts[row.Severity]++
+	}
+
+	// Summary
+	if len(rows) > 0 {
+		tw.Flush()
+		fmt.Fprintf(w, "\n%d critical, %d warning, %d info\n",
+			counts[SeverityCritical], counts[SeverityWarning], counts[SeverityInfo])
+	}
+
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *AnomalyReport) csvHeader() []string {
+	return []string{
+		"main_fqdn",
+		"previous_timestamp",
+		"detection_timestamp",
+		"type",
+		"severity",
+		"previous",
+		"current",
+		"message",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *AnomalyReport) csvRecord(row AnomalyRow) []string {
+	return []string{
+		row.MainFQDN,
+		row.PreviousTimestamp.Format("2006-01-02 15:04:05"),
+		row.DetectedAt.Format("2006-01-02 15:04:05"),
+		row.Type,
+		row.Severity,
+		row.Previous,
+		row.Current,
+		row.Message,
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *AnomalyReport) WriteCSV(w io.Writer, rows []AnomalyRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *AnomalyReport) WriteJSON(w io.Writer, rows []AnomalyRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with one sheet per severity
+func (r *AnomalyReport) WriteXLSX(w io.Writer, rows []AnomalyRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return sheetsByColumn(r.csvHeader(), records, 4, "Anomalies").Write(w)
+}
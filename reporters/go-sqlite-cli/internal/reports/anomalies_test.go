@@ -0,0 +1,43 @@
+package reports_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestDetectAnomalies(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2025, 10, d, 9, 0, 0, 0, time.UTC)
+	}
+
+	measurements := []reports.AnomalyMeasurement{
+		{MainFQDN: "a.local", DetectionTimestamp: day(1), CPUCount: 4, ConsideredCPUs: 4, IsVirtualized: "yes", PhysicalHostID: "esx1", Products: []string{"IS_ONP_PRD"}},
+		{MainFQDN: "a.local", DetectionTimestamp: day(2), CPUCount: 4, ConsideredCPUs: 4, IsVirtualized: "yes", PhysicalHostID: "esx1", Products: []string{"IS_ONP_PRD"}},
+		{MainFQDN: "a.local", DetectionTimestamp: day(3), CPUCount: 8, ConsideredCPUs: 8, IsVirtualized: "yes", PhysicalHostID: "esx2", Products: []string{"BRK_ONP_PRD", "IS_ONP_PRD"}},
+		{MainFQDN: "a.local", DetectionTimestamp: day(4), CPUCount: 6, ConsideredCPUs: 6, IsVirtualized: "no", PhysicalHostID: "esx2", Products: []string{"BRK_ONP_PRD"}},
+		// The first measurement of another node is not compared with a.local
+		{MainFQDN: "b.local", DetectionTimestamp: day(4), CPUCount: 2, ConsideredCPUs: 2, IsVirtualized: "no"},
+		{MainFQDN: "b.local", DetectionTimestamp: day(5), CPUCount: 2, ConsideredCPUs: 3, IsVirtualized: "no"},
+	}
+
+	var got []string
+	for _, a := range reports.DetectAnomalies(measurements) {
+		got = append(got, a.DetectedAt.Format("02")+" "+a.MainFQDN+" "+a.Type+" "+a.Severity+" "+a.Previous+" -> "+a.Current)
+	}
+
+	want := []string{
+		"03 a.local cpu-change critical 4 CPUs, 4 considered -> 8 CPUs, 8 considered",
+		"03 a.local host-change warning esx1 -> esx2",
+		"03 a.local product-appeared warning  -> BRK_ONP_PRD",
+		"04 a.local cpu-change info 8 CPUs, 8 considered -> 6 CPUs, 6 considered",
+		"04 a.local virtualization-change critical yes -> no",
+		"04 a.local product-disappeared info IS_ONP_PRD -> ",
+		"05 b.local cpu-change warning 2 CPUs, 2 considered -> 2 CPUs, 3 considered",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected anomalies:\ngot:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
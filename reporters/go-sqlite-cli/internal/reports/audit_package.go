@@ -0,0 +1,380 @@
+package reports
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+)
+
+// AuditFile describes one evidence file of an audit package
+type AuditFile struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Rows        int    `json:"rows"`
+	SHA256      string `json:"sha256"`
+}
+
+// AuditManifest describes the content of an audit package; it is stored in the
+// archive as manifest.json. The measurements of the decommissioned nodes taken
+// after their decommission date are left out of every file and listed here.
+type AuditManifest struct {
+	GeneratedAt         string               `json:"generated_at"`
+	PeriodFrom          string               `json:"period_from"`
+	PeriodTo            string               `json:"period_to"`
+	SchemaVersion       string               `json:"schema_version"`
+	DailyAggregation    string               `json:"daily_aggregation"`
+	DecommissionedNodes []DecommissionedNode `json:"decommissioned_nodes"`
+	Files               []AuditFile          `json:"files"`
+}
+
+// AuditPackage bundles the evidence files an IBM license audit expects into a zip archive
+type AuditPackage struct {
+	db *sql.DB
+}
+
+// NewAuditPackage creates a new audit package generator
+func NewAuditPackage(db *sql.DB) *AuditPackage {
+	return &AuditPackage{db: db}
+}
+
+// auditQuery is one CSV evidence file produced straight from a SQL query
+type auditQuery struct {
+	name        string
+	description string
+	query       string
+}
+
+// Program peaks add up the daily license cores of v_daily_license_cores of the
+// products attributed to each license term on that day, so they count nodes,
+// decommissioned nodes and the daily aggregation policy as the monthly-peak
+// report does. A node running several products of the same term counts once
+// per product, as it does in monthly-peak.
+var auditProgramPeakQuery = `
+	WITH term_days AS (
+		SELECT
+			dlc.measurement_date,
+			` + productTermColumn("p", "dlc.measurement_date") + ` as term_id,
+			SUM(dlc.running_nodes) as running_nodes,
+			SUM(dlc.running_license_cores) as running_cores,
+			SUM(dlc.installed_nodes) as installed_nodes,
+			SUM(dlc.installed_license_cores) as installed_cores
+		FROM v_daily_license_cores dlc
+		JOIN product_codes p ON dlc.product_mnemo_code = p.product_mnemo_code
+		WHERE dlc.measurement_date BETWEEN ? AND ?
+		GROUP BY dlc.measurement_date, term_id
+	),
+	ranked_days AS (
+		SELECT *,
+			ROW_NUMBER() OVER (PARTITION BY term_id ORDER BY running_cores DESC, measurement_date) as running_rank,
+			ROW_NUMBER() OVER (PARTITION BY term_id ORDER BY installed_cores DESC, measurement_date) as installed_rank
+		FROM term_days
+	)
+	SELECT
+		l.program_number,
+		l.program_name,
+		r.term_id,
+		(SELECT GROUP_CONCAT(product_mnemo_code, ' ') FROM (
+			SELECT product_mnemo_code FROM product_codes pc
+			WHERE pc.term_id = r.term_id
+			UNION
+			SELECT product_mnemo_code FROM product_term_mappings tm
+			WHERE tm.term_id = r.term_id
+			ORDER BY product_mnemo_code
+		)) as product_codes,
+		MAX(CASE WHEN r.running_rank = 1 THEN r.running_cores END) as peak_running_cores,
+		MAX(CASE WHEN r.running_rank = 1 AND r.running_cores > 0 THEN r.measurement_date END) as peak_running_date,
+		MAX(CASE WHEN r.running_rank = 1 THEN r.running_nodes END) as peak_running_nodes,
+		MAX(CASE WHEN r.installed_rank = 1 THEN r.installed_cores END) as peak_installed_cores,
+		MAX(CASE WHEN r.installed_rank = 1 AND r.installed_cores > 0 THEN r.measurement_date END) as peak_installed_date,
+		MAX(CASE WHEN r.installed_rank = 1 THEN r.installed_nodes END) as peak_installed_nodes,
+		COUNT(*) as days_measured
+	FROM ranked_days r
+	JOIN license_terms l ON l.term_id = r.term_id
+	GROUP BY l.program_number, l.program_name, r.term_id
+	ORDER BY l.program_number, r.term_id
+`
+
+// The daily measurement of each host and product, chosen by the daily
+// aggregation policy as in the other reports, with the basis on which the host
+// is licensed and the number of measurements taken that day
+var auditHostBreakdownQuery = `
+	SELECT
+		DATE(m.detection_timestamp) as measurement_date,
+		l.program_number,
+		d.product_mnemo_code,
+		d.main_fqdn as host_fqdn,
+		CASE WHEN d.status = 'present' THEN 'true' ELSE 'false' END as running,
+		CASE WHEN d.install_count > 0 THEN 'true' ELSE 'false' END as installed,
+		CASE WHEN m.os_eligible = 'true' AND m.virt_eligible = 'true' THEN 'true' ELSE 'false' END as eligible,
+		m.is_virtualized as virtualized,
+		m.cpu_count,
+		m.considered_cpus,
+		CASE WHEN m.physical_host_id IN ('', 'unknown') THEN '' ELSE m.physical_host_id END as physical_host_id,
+		m.host_physical_cpus as physical_host_cpus,
+		CASE
+			WHEN m.os_eligible = 'true' AND m.virt_eligible = 'true' THEN 'sub-capacity (considered cores)'
+			WHEN m.host_physical_cpus NOT IN ('', 'unknown') THEN 'full capacity (physical host cores)'
+			ELSE 'full capacity (considered cores, physical host unknown)'
+		END as license_basis,
+		(SELECT COUNT(*) FROM v_reported_measurements r
+			WHERE r.main_fqdn = m.main_fqdn
+				AND DATE(r.detection_timestamp) = DATE(m.detection_timestamp)) as measurements
+	FROM detected_products d
+	JOIN product_codes p ON d.product_mnemo_code = p.product_mnemo_code
+	JOIN v_daily_measurements m ON d.main_fqdn = m.main_fqdn
+		AND d.detection_timestamp = m.detection_timestamp
+	JOIN license_terms l ON l.term_id = ` + productTermColumn("p", "DATE(m.detection_timestamp)") + `
+	WHERE (d.status = 'present' OR d.install_count > 0)
+		AND DATE(m.detection_timestamp) BETWEEN ? AND ?
+	ORDER BY measurement_date, l.program_number, d.product_mnemo_code, d.main_fqdn
+`
+
+// Ineligible hosts sharing a physical host: the host capacity is counted once
+// instead of once per virtual machine
+const auditHostDeduplicationQuery = `
+	WITH node_days AS (
+		SELECT
+			DATE(m.detection_timestamp) as measurement_date,
+			d.product_mnemo_code,
+			m.physical_host_id,
+			d.main_fqdn,
+			MAX(m.considered_cpus) as node_cores,
+			MAX(CASE WHEN m.host_physical_cpus NOT IN ('', 'unknown') THEN CAST(m.host_physical_cpus AS INTEGER) END) as host_cores
+		FROM detected_products d
+		JOIN v_daily_measurements m ON d.main_fqdn = m.main_fqdn
+			AND d.detection_timestamp = m.detection_timestamp
+		WHERE (d.status = 'present' OR d.install_count > 0)
+			AND (m.os_eligible = 'false' OR m.virt_eligible = 'false')
+			AND m.physical_host_id != '' AND m.physical_host_id != 'unknown'
+			AND DATE(m.detection_timestamp) BETWEEN ? AND ?
+		GROUP BY measurement_date, d.product_mnemo_code, m.physical_host_id, d.main_fqdn
+		ORDER BY d.main_fqdn
+	)
+	SELECT
+		v.measurement_date,
+		v.product_mnemo_code,
+		v.physical_host_id,
+		COALESCE(ph.host_id_method, '') as host_id_method,
+		COALESCE(ph.host_id_confidence, '') as host_id_confidence,
+		COUNT(*) as node_count,
+		GROUP_CONCAT(v.main_fqdn, ' ') as node_fqdns,
+		SUM(v.node_cores) as node_cores_total,
+		MAX(v.host_cores) as physical_host_cpus,
+		COALESCE(MAX(v.host_cores), MAX(v.node_cores)) as cores_counted
+	FROM node_days v
+	LEFT JOIN physical_hosts ph ON v.physical_host_id = ph.physical_host_id
+	GROUP BY v.measurement_date, v.product_mnemo_code, v.physical_host_id,
+		ph.host_id_method, ph.host_id_confidence
+	ORDER BY v.measurement_date, v.product_mnemo_code, v.physical_host_id
+`
+
+// Every measurement in the period with the import session and file it came from,
+// and the signature the file was verified against. Sessions are matched on the
+// measurement they wrote, which node aliases move along with the measurement.
+const auditImportProvenanceQuery = `
+	SELECT
+		m.main_fqdn as host_fqdn,
+		m.detection_timestamp,
+		COALESCE(s.session_id, '') as session_id,
+		COALESCE(s.source_file, '') as source_file,
+		COALESCE(s.imported_at, '') as imported_at,
+		COALESCE(s.status, '') as import_status,
+		COALESCE(s.error_message, '') as import_error,
+		COALESCE(s.signature_status, '') as signature_status,
+		COALESCE(s.signature_signer, '') as signature_signer
+	FROM v_reported_measurements m
+	LEFT JOIN import_sessions s ON s.main_fqdn = m.main_fqdn
+		AND s.detection_timestamp = m.detection_timestamp
+	WHERE DATE(m.detection_timestamp) BETWEEN ? AND ?
+	ORDER BY m.detection_timestamp, m.main_fqdn
+`
+
+// License terms of the products detected in the period with the term documents
+// in force during it, one row per document
+var auditLicenseTermsQuery = `
+	SELECT
+		l.term_id,
+		l.program_number,
+		l.program_name,
+		COALESCE(td.location, '') as terms_document,
+		COALESCE(strftime('%Y-%m-%d', td.effective_from), '') as effective_from,
+		COALESCE(strftime('%Y-%m-%d', td.effective_to), '') as effective_to,
+		COALESCE(td.description, '') as description
+	FROM license_terms l
+	LEFT JOIN license_term_documents td ON td.term_id = l.term_id
+		AND DATE(td.effective_from) <= ?2
+		AND (td.effective_to IS NULL OR DATE(td.effective_to) >= ?1)
+	WHERE l.term_id IN (
+		SELECT ` + productTermColumn("p", "DATE(d.detection_timestamp)") + `
+		FROM detected_products d
+		JOIN product_codes p ON d.product_mnemo_code = p.product_mnemo_code
+		WHERE DATE(d.detection_timestamp) BETWEEN ?1 AND ?2
+	)
+	ORDER BY l.program_number, l.term_id, td.effective_from
+`
+
+var auditQueries = []auditQuery{
+	{"peak-usage-by-program.csv", "Peak license cores per IBM program number and license term over the period, running and installed, with the day each peak occurred", auditProgramPeakQuery},
+	{"host-breakdown.csv", "Daily measurement per host and product, chosen by the daily aggregation policy, with the cores considered and the licensing basis applied", auditHostBreakdownQuery},
+	{"physical-host-deduplication.csv", "Ineligible nodes grouped by physical host; the physical host capacity is counted once per day and product", auditHostDeduplicationQuery},
+	{"import-provenance.csv", "Import session, source file and verified signature of every measurement in the period", auditImportProvenanceQuery},
+	{"license-terms.csv", "License terms of the detected products with the term documents in force during the period", auditLicenseTermsQuery},
+}
+
+// Write generates the evidence files for the period [from, to] and writes them as a
+// zip archive. All files are stored under the folder dir inside the archive.
+func (p *AuditPackage) Write(ctx context.Context, w io.Writer, dir string, from, to time.Time) (*AuditManifest, error) {
+	generatedAt := time.Now().UTC()
+	manifest := &AuditManifest{
+		GeneratedAt: generatedAt.Format(time.RFC3339),
+		PeriodFrom:  from.Format("2006-01-02"),
+		PeriodTo:    to.Format("2006-01-02"),
+		Files:       []AuditFile{},
+	}
+
+	version, err := database.GetCurrentSchemaVersion(p.db)
+	if err != nil {
+		return nil, err
+	}
+	manifest.SchemaVersion = version
+
+	policy, err := database.GetDailyAggregation(p.db)
+	if err != nil {
+		return nil, err
+	}
+	manifest.DailyAggregation = policy
+
+	nodes, err := excludedDecommissionedNodes(p.db)
+	if err != nil {
+		return nil, err
+	}
+	manifest.DecommissionedNodes = append([]DecommissionedNode{}, nodes...)
+
+	zw := zip.NewWriter(w)
+
+	for _, q := range auditQueries {
+		header, records, err := queryRecords(ctx, p.db, q.query, manifest.PeriodFrom, manifest.PeriodTo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build %s: %w", q.name, err)
+		}
+		data, err := csvBytes(header, records)
+		if err != nil {
+			return nil, err
+		}
+		file, err := addZipFile(zw, generatedAt, dir, q.name, data)
+		if err != nil {
+			return nil, err
+		}
+		file.Description = q.description
+		file.Rows = len(records)
+		manifest.Files = append(manifest.Files, *file)
+	}
+
+	// Monthly peaks as reported by the monthly-peak report
+	monthly := NewMonthlyPeakReport(p.db)
+	rows, err := monthly.Query(ctx, "", "", &from, &to)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := monthly.WriteCSV(&buf, rows); err != nil {
+		return nil, err
+	}
+	file, err := addZipFile(zw, generatedAt, dir, "monthly-peak.csv", buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	file.Description = "Peak license cores per product for every month touched by the period"
+	file.Rows = len(rows)
+	manifest.Files = append(manifest.Files, *file)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := addZipFile(zw, generatedAt, dir, "manifest.json", append(data, '\n')); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize zip archive: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// addZipFile stores data in the archive and returns its name and checksum
+func addZipFile(zw *zip.Writer, modified time.Time, dir, name string, data []byte) (*AuditFile, error) {
+	fw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     path.Join(dir, name),
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add %s to archive: %w", name, err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write %s to archive: %w", name, err)
+	}
+
+	sum := sha256.Sum256(data)
+	return &AuditFile{Name: name, SHA256: hex.EncodeToString(sum[:])}, nil
+}
+
+// queryRecords runs a query and returns the column names and all rows as strings
+func queryRecords(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]string, [][]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	header, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records := [][]string{}
+	for rows.Next() {
+		values := make([]sql.NullString, len(header))
+		dest := make([]interface{}, len(header))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		record := make([]string, len(header))
+		for i, v := range values {
+			record[i] = v.String
+		}
+		records = append(records, record)
+	}
+
+	return header, records, rows.Err()
+}
+
+// csvBytes encodes a header and records as CSV
+func csvBytes(header []string, records [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	if err := writer.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,170 @@
+package reports_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestAuditPackage(t *testing.T) {
+	stmts := []string{
+		`INSERT INTO product_codes (product_mnemo_code, ibm_product_code, product_name, mode, term_id) VALUES
+			('IS_ONP_NPR', 'D0YYXZX', 'Integration Server Non-Production', 'NON PROD', 'T1')`,
+	}
+	for _, m := range []struct {
+		fqdn, timestamp, product string
+		cpus                     int
+	}{
+		// app01 reports twice on 2025-08-05; the max policy counts 6 cores
+		{"app01.example.com", "2025-08-05 08:00:00", "IS_ONP_PRD", 4},
+		{"app01.example.com", "2025-08-05 14:00:00", "IS_ONP_PRD", 6},
+		{"app01.example.com", "2025-08-06 08:00:00", "IS_ONP_PRD", 4},
+		{"app02.example.com", "2025-08-05 08:00:00", "IS_ONP_NPR", 8},
+		{"app03.example.com", "2025-08-05 08:00:00", "IS_ONP_PRD", 48},
+	} {
+		stmts = append(stmts,
+			fmt.Sprintf(`INSERT OR IGNORE INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('%s', '%s', 'PROD')`,
+				m.fqdn, strings.Split(m.fqdn, ".")[0]),
+			fmt.Sprintf(`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count, is_virtualized,
+				virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus)
+				VALUES ('%s', '%s', 'Linux', '9', %d, 'no', '', 'unknown', 'true', 'true', 'true', %d)`,
+				m.fqdn, m.timestamp, m.cpus, m.cpus),
+			fmt.Sprintf(`INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, install_count)
+				VALUES ('%s', '%s', '%s', 'present', 1)`, m.fqdn, m.product, m.timestamp))
+	}
+	stmts = append(stmts,
+		// app03 is decommissioned before it reports its largest measurement
+		`UPDATE landscape_nodes SET decommissioned_on = '2025-08-01' WHERE main_fqdn = 'app03.example.com'`,
+		`INSERT INTO import_sessions (session_id, source_file, hostname, status, main_fqdn, detection_timestamp)
+			VALUES ('app01_20250805_080000', 'app01.csv', 'app01', 'success', 'app01.example.com', '2025-08-05 08:00:00')`)
+	db := newTestDB(t, stmts...)
+
+	from := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 9, 30, 0, 0, 0, 0, time.UTC)
+	var archive bytes.Buffer
+	manifest, err := reports.NewAuditPackage(db).Write(t.Context(), &archive, "audit", from, to)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", f.Name, err)
+		}
+		files[path.Base(f.Name)] = data
+	}
+
+	// manifest.json matches the returned manifest, and every file its checksum
+	var stored reports.AuditManifest
+	if err := json.Unmarshal(files["manifest.json"], &stored); err != nil {
+		t.Fatalf("Failed to parse manifest.json: %v", err)
+	}
+	if len(stored.Files) != len(manifest.Files) || len(stored.Files) != len(files)-1 {
+		t.Fatalf("Manifest lists %d files, archive has %d", len(stored.Files), len(files)-1)
+	}
+	for _, f := range stored.Files {
+		data, ok := files[f.Name]
+		if !ok {
+			t.Errorf("%s is missing from the archive", f.Name)
+			continue
+		}
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); got != f.SHA256 {
+			t.Errorf("%s: checksum %s, manifest says %s", f.Name, got, f.SHA256)
+		}
+		records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", f.Name, err)
+		}
+		if len(records)-1 != f.Rows {
+			t.Errorf("%s: %d rows, manifest says %d", f.Name, len(records)-1, f.Rows)
+		}
+	}
+	if stored.DailyAggregation != "max" {
+		t.Errorf("Unexpected daily aggregation %q", stored.DailyAggregation)
+	}
+	if len(stored.DecommissionedNodes) != 1 || stored.DecommissionedNodes[0].MainFQDN != "app03.example.com" {
+		t.Errorf("Unexpected decommissioned nodes: %+v", stored.DecommissionedNodes)
+	}
+
+	// The program peak adds up the monthly peaks of the products of the term on
+	// the peak day, without the decommissioned node
+	programs := csvRows(t, files["peak-usage-by-program.csv"])
+	if len(programs) != 1 {
+		t.Fatalf("Expected one program row, got %v", programs)
+	}
+	program := programs[0]
+	monthly, err := reports.NewMonthlyPeakReport(db).Query(t.Context(), "", "", &from, &to)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	var cores, nodes int
+	for _, row := range monthly {
+		if row.TermID == program["term_id"] && row.PeakRunningDate == program["peak_running_date"] {
+			cores += row.PeakRunningCores
+			nodes += row.PeakRunningNodes
+		}
+	}
+	if program["peak_running_date"] != "2025-08-05" || program["peak_running_cores"] != strconv.Itoa(cores) ||
+		program["peak_running_nodes"] != strconv.Itoa(nodes) || cores != 14 || nodes != 2 {
+		t.Errorf("Program peak %v does not match monthly-peak (%d cores on %d nodes)", program, cores, nodes)
+	}
+
+	// One row per node and day, the measurement chosen by the daily aggregation policy
+	var breakdown []string
+	for _, row := range csvRows(t, files["host-breakdown.csv"]) {
+		breakdown = append(breakdown, fmt.Sprintf("%s %s:%s/%s", row["measurement_date"], row["host_fqdn"], row["considered_cpus"], row["measurements"]))
+	}
+	if got := strings.Join(breakdown, " "); got != "2025-08-05 app02.example.com:8/1 2025-08-05 app01.example.com:6/2 2025-08-06 app01.example.com:4/1" {
+		t.Errorf("Unexpected host breakdown: %s", got)
+	}
+
+	var sessions []string
+	for _, row := range csvRows(t, files["import-provenance.csv"]) {
+		sessions = append(sessions, row["host_fqdn"]+":"+row["session_id"])
+	}
+	if got := strings.Join(sessions, " "); got != "app01.example.com:app01_20250805_080000 app02.example.com: app01.example.com: app01.example.com:" {
+		t.Errorf("Unexpected import provenance: %s", got)
+	}
+}
+
+// csvRows parses CSV data into one map per row, keyed by column name
+func csvRows(t *testing.T, data []byte) []map[string]string {
+	t.Helper()
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	var rows []map[string]string
+	for _, record := range records[1:] {
+		row := make(map[string]string)
+		for i, name := range records[0] {
+			row[name] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
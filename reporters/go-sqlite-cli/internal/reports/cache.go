@@ -0,0 +1,169 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// cachedView is a reporting view that 'db refresh-cache' materializes
+type cachedView struct {
+	name string
+	// window is set for views over the last 31 days, whose rows change with
+	// the date: their cache is only used on the day of the refresh
+	window bool
+}
+
+// cachedViews are the expensive views behind the peak and daily summary reports
+var cachedViews = []cachedView{
+	{name: "v_peak_usage", window: true},
+	{name: "v_peak_usage_breakdown", window: true},
+	{name: "v_daily_product_summary"},
+}
+
+// importFingerprintQuery identifies the imported data: any import, forced
+// re-import or rollback changes the count or latest time of the sessions. The
+// daily aggregation policy is part of it, as changing it changes the views.
+const importFingerprintQuery = `SELECT COUNT(*) || ':' || COALESCE(MAX(imported_at), '') || ':' ||
+	COALESCE((SELECT value FROM schema_metadata WHERE key = 'daily_aggregation'), 'max') FROM import_sessions`
+
+// CacheStatus describes the cache of a view
+type CacheStatus struct {
+	View        string
+	RefreshedAt time.Time
+	Rows        int
+	Current     bool // the reports read the cache instead of the view
+}
+
+// ReportCache materializes the expensive reporting views into cache_<view>
+// tables. The cache is optional: until it is refreshed for the first time the
+// reports always read the views.
+type ReportCache struct {
+	db *sql.DB
+}
+
+// NewReportCache creates a new report cache
+func NewReportCache(db *sql.DB) *ReportCache {
+	return &ReportCache{db: db}
+}
+
+// Refresh recreates the cache table of every cached view from the view
+func (c *ReportCache) Refresh() ([]CacheStatus, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var fingerprint string
+	if err := tx.QueryRow(importFingerprintQuery).Scan(&fingerprint); err != nil {
+		return nil, fmt.Errorf("failed to read the import sessions: %w", err)
+	}
+
+	for _, view := range cachedViews {
+		table := cacheTable(view.name)
+		if _, err := tx.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+			return nil, fmt.Errorf("failed to drop %s: %w", table, err)
+		}
+		if _, err := tx.Exec("CREATE TABLE " + table + " AS SELECT * FROM " + view.name); err != nil {
+			return nil, fmt.Errorf("failed to cache %s: %w", view.name, err)
+		}
+		_, err := tx.Exec(`
+			INSERT INTO report_cache (view_name, refreshed_at, row_count, import_fingerprint)
+			VALUES (?, CURRENT_TIMESTAMP, (SELECT COUNT(*) FROM `+table+`), ?)
+			ON CONFLICT(view_name) DO UPDATE SET
+				refreshed_at = excluded.refreshed_at,
+				row_count = excluded.row_count,
+				import_fingerprint = excluded.import_fingerprint
+		`, view.name, fingerprint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record the cache of %s: %w", view.name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return c.Status()
+}
+
+// Enabled reports whether the cache was refreshed before, so that imports
+// keep it current
+func (c *ReportCache) Enabled() (bool, error) {
+	var count int
+	if err := c.db.QueryRow("SELECT COUNT(*) FROM report_cache").Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to read the report cache: %w", err)
+	}
+	return count > 0, nil
+}
+
+// Drop removes the cache tables, so that the reports read the views again
+func (c *ReportCache) Drop() error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, view := range cachedViews {
+		if _, err := tx.Exec("DROP TABLE IF EXISTS " + cacheTable(view.name)); err != nil {
+			return fmt.Errorf("failed to drop the cache of %s: %w", view.name, err)
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM report_cache"); err != nil {
+		return fmt.Errorf("failed to clear the report cache: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Status returns the cache of each cached view that was refreshed
+func (c *ReportCache) Status() ([]CacheStatus, error) {
+	var statuses []CacheStatus
+	for _, view := range cachedViews {
+		var status CacheStatus
+		err := c.db.QueryRow(`
+			SELECT view_name, refreshed_at, row_count, `+currentCacheCondition(view)+`
+			FROM report_cache WHERE view_name = ?
+		`, view.name).Scan(&status.View, &status.RefreshedAt, &status.Rows, &status.Current)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the cache of %s: %w", view.name, err)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// cachedSource returns the table a report reads a view from: the cache table
+// of the view while it is current, otherwise the view itself
+func cachedSource(db *sql.DB, viewName string) string {
+	for _, view := range cachedViews {
+		if view.name != viewName {
+			continue
+		}
+		var current bool
+		err := db.QueryRow(`SELECT `+currentCacheCondition(view)+` FROM report_cache WHERE view_name = ?`,
+			view.name).Scan(&current)
+		if err == nil && current {
+			return cacheTable(view.name)
+		}
+	}
+	return viewName
+}
+
+// currentCacheCondition is an SQL expression on report_cache that is true
+// while the cache of a view can be read instead of the view
+func currentCacheCondition(view cachedView) string {
+	condition := "import_fingerprint = (" + importFingerprintQuery + ")"
+	if view.window {
+		condition += " AND DATE(refreshed_at) = DATE('now')"
+	}
+	return condition
+}
+
+// cacheTable is the name of the cache table of a view
+func cacheTable(viewName string) string {
+	return "cache_" + viewName
+}
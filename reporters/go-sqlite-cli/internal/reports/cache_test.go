@@ -0,0 +1,74 @@
+package reports_test
+
+import (
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestReportCache(t *testing.T) {
+	stmts := []string{
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('app01.example.com', 'app01', 'PROD')`,
+		`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count, is_virtualized,
+			virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus)
+			VALUES ('app01.example.com', '2025-10-01 08:00:00', 'Linux', '1', 4, 'no', '', 'unknown', 'true', 'true', 'true', 4)`,
+		`INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, install_count)
+			VALUES ('app01.example.com', 'IS_ONP_PRD', '2025-10-01 08:00:00', 'present', 1)`,
+		`INSERT INTO import_sessions (session_id, source_file, hostname, status) VALUES ('s1', 'a.csv', 'app01', 'success')`,
+	}
+	db := newTestDB(t, stmts...)
+
+	cache := reports.NewReportCache(db)
+	if enabled, err := cache.Enabled(); err != nil || enabled {
+		t.Fatalf("Expected a disabled cache before the first refresh, got %v (%v)", enabled, err)
+	}
+
+	statuses, err := cache.Refresh()
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("Expected 3 cached views, got %+v", statuses)
+	}
+	for _, status := range statuses {
+		if !status.Current {
+			t.Errorf("Expected %s to be current after the refresh", status.View)
+		}
+		if status.View == "v_daily_product_summary" && status.Rows != 1 {
+			t.Errorf("Expected 1 cached daily summary row, got %d", status.Rows)
+		}
+	}
+
+	// The report reads the cache table while it is current
+	if _, err := db.Exec(`DELETE FROM cache_v_daily_product_summary`); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	summary := reports.NewDailySummaryReport(db)
+	rows, err := summary.Query(t.Context(), "", "", nil, nil)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 0 {
+		t.Errorf("Expected the report to read the emptied cache, got %d row(s)", len(rows))
+	}
+
+	// An import makes the cache out of date, so the report reads the view again
+	if _, err := db.Exec(`INSERT INTO import_sessions (session_id, source_file, hostname, status)
+		VALUES ('s2', 'b.csv', 'app01', 'success')`); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	rows, err = summary.Query(t.Context(), "", "", nil, nil)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Errorf("Expected the report to read the view after an import, got %d row(s)", len(rows))
+	}
+
+	if err := cache.Drop(); err != nil {
+		t.Fatalf("Drop failed: %v", err)
+	}
+	if enabled, err := cache.Enabled(); err != nil || enabled {
+		t.Errorf("Expected a disabled cache after the drop, got %v (%v)", enabled, err)
+	}
+}
@@ -0,0 +1,302 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/licensing"
+)
+
+// Capacity reconciliation statuses
+const (
+	CapacityMatch       = "match"        // the inspectors report the reference cores
+	CapacityMismatch    = "mismatch"     // an inspector reports other cores than the reference
+	CapacityNotReported = "not-reported" // the inspectors do not report the host cores
+	CapacityNotMeasured = "not-measured" // no node running a product is measured on the host
+)
+
+// CapacityReconciliationRow compares the physical cores of a hypervisor host
+// reported by the inspectors with the reference of vCenter or another
+// inventory, and the license cores counted with each
+type CapacityReconciliationRow struct {
+	PhysicalHostID  string   `json:"physical_host_id"`
+	HostName        string   `json:"host_name"`
+	Source          string   `json:"source"`
+	MeasurementDate string   `json:"measurement_date"` // empty when not measured
+	Nodes           []string `json:"nodes"`
+	ReportedCores   []int    `json:"reported_cores"` // distinct HOST_PHYSICAL_CPUS of the nodes
+	ReferenceCores  int      `json:"reference_cores"`
+	Status          string   `json:"status"`
+	LicenseCores    int      `json:"license_cores"`            // with the reported cores
+	ReconciledCores int      `json:"reconciled_license_cores"` // with the reference cores
+	Delta           int      `json:"delta"`
+	Products        []string `json:"products"` // products whose license cores change, with the change
+}
+
+// CapacityReconciliationReport compares the host cores of the inspectors with
+// hypervisor_hosts
+type CapacityReconciliationReport struct {
+	db *sql.DB
+}
+
+// NewCapacityReconciliationReport creates a new report generator
+func NewCapacityReconciliationReport(db *sql.DB) *CapacityReconciliationReport {
+	return &CapacityReconciliationReport{db: db}
+}
+
+// Query compares the hosts of hypervisor_hosts with the latest day measured
+// in the date range. The license cores of each product are counted with the
+// sub-capacity rules twice, with the host cores reported by the inspectors
+// and with the reference cores, and summed per host over the products.
+func (r *CapacityReconciliationReport) Query(ctx context.Context, productCode, mode string, fromDate, toDate *time.Time) ([]CapacityReconciliationRow, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT physical_host_id, host_name, source, physical_cores
+		FROM hypervisor_hosts
+		ORDER BY physical_host_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hypervisor hosts: %w", err)
+	}
+	var results []CapacityReconciliationRow
+	reference := make(map[string]int)
+	for rows.Next() {
+		var row CapacityReconciliationRow
+		if err := rows.Scan(&row.PhysicalHostID, &row.HostName, &row.Source, &row.ReferenceCores); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan hypervisor host: %w", err)
+		}
+		reference[row.PhysicalHostID] = row.ReferenceCores
+		results = append(results, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	groups, err := queryProductNodes(ctx, r.db, productCode, mode, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+	date := ""
+	for key := range groups {
+		if key.date > date {
+			date = key.date
+		}
+	}
+
+	nodes := make(map[string]map[string]bool) // FQDNs by host
+	reported := make(map[string]map[int]bool) // reported cores by host
+	unreported := make(map[string]bool)       // hosts a node reports no cores for
+	license := make(map[string][2]int)        // license cores by host, reported and reconciled
+	products := make(map[string][]string)     // product changes by host
+	var keys []productDay
+	for key := range groups {
+		if key.date == date {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].product < keys[j].product })
+
+	for _, key := range keys {
+		reconciled := make([]licensing.Node, len(groups[key]))
+		for i, node := range groups[key] {
+			reconciled[i] = node
+			cores, ok := reference[node.HostID]
+			if !ok {
+				continue
+			}
+			if nodes[node.HostID] == nil {
+				nodes[node.HostID] = make(map[string]bool)
+				reported[node.HostID] = make(map[int]bool)
+			}
+			nodes[node.HostID][node.MainFQDN] = true
+			if node.HostCores != nil {
+				reported[node.HostID][*node.HostCores] = true
+			} else {
+				unreported[node.HostID] = true
+			}
+			if node.Virtualized {
+				reconciled[i].HostCores = &cores
+			}
+		}
+
+		before := make(map[string]int)
+		for _, host := range licensing.Calculate(groups[key]) {
+			before[host.HostKey] = host.LicenseCores
+		}
+		for _, host := range licensing.Calculate(reconciled) {
+			if _, ok := reference[host.HostKey]; !ok {
+				continue
+			}
+			counts := license[host.HostKey]
+			counts[0] += before[host.HostKey]
+			counts[1] += host.LicenseCores
+			license[host.HostKey] = counts
+			if delta := host.LicenseCores - before[host.HostKey]; delta != 0 {
+				products[host.HostKey] = append(products[host.HostKey], fmt.Sprintf("%s:%+d", key.product, delta))
+			}
+		}
+	}
+
+	for i := range results {
+		row := &results[i]
+		if len(nodes[row.PhysicalHostID]) == 0 {
+			row.Status = CapacityNotMeasured
+			continue
+		}
+		row.MeasurementDate = date
+		for fqdn := range nodes[row.PhysicalHostID] {
+			row.Nodes = append(row.Nodes, fqdn)
+		}
+		sort.Strings(row.Nodes)
+		for cores := range reported[row.PhysicalHostID] {
+			row.ReportedCores = append(row.ReportedCores, cores)
+		}
+		sort.Ints(row.ReportedCores)
+
+		row.Status = CapacityMatch
+		switch {
+		case len(row.ReportedCores) > 1 || (len(row.ReportedCores) == 1 && row.ReportedCores[0] != row.ReferenceCores):
+			row.Status = CapacityMismatch
+		case unreported[row.PhysicalHostID]:
+			row.Status = CapacityNotReported
+		}
+
+		row.LicenseCores = license[row.PhysicalHostID][0]
+		row.ReconciledCores = license[row.PhysicalHostID][1]
+		row.Delta = row.ReconciledCores - row.LicenseCores
+		row.Products = products[row.PhysicalHostID]
+	}
+
+	return results, nil
+}
+
+// formatReportedCores returns the reported cores of a host separated by
+// slashes
+func formatReportedCores(cores []int) string {
+	values := make([]string, len(cores))
+	for i, c := range cores {
+		values[i] = strconv.Itoa(c)
+	}
+	return strings.Join(values, "/")
+}
+
+// WriteTable writes data in ASCII table format, with the totals of the hosts
+// that differ from the reference
+func (r *CapacityReconciliationReport) WriteTable(w io.Writer, rows []CapacityReconciliationRow) error {
+	tw := newTableWriter(w)
+
+	fmt.Fprintln(tw, "HOST\tNAME\tSOURCE\tDATE\tNODES\tREPORTED\tREFERENCE\tSTATUS\tLIC_CORES\tRECONCILED\tDELTA\tPRODUCTS")
+	fmt.Fprintln(tw, "----\t----\t------\t----\t-----\t--------\t---------\t------\t---------\t----------\t-----\t--------")
+
+	mismatches, license, reconciled := 0, 0, 0
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%d\t%s\t%d\t%d\t%+d\t%s\n",
+			row.PhysicalHostID,
+			row.HostName,
+			row.Source,
+			row.MeasurementDate,
+			len(row.Nodes),
+			formatReportedCores(row.ReportedCores),
+			row.ReferenceCores,
+			row.Status,
+			row.LicenseCores,
+			row.ReconciledCores,
+			row.Delta,
+			strings.Join(row.Products, " "),
+		)
+		if row.Status == CapacityMismatch || row.Status == CapacityNotReported {
+			mismatches++
+		}
+		license += row.LicenseCores
+		reconciled += row.ReconciledCores
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\n%d of %d host(s) differ from the reference; license cores %d with the reported cores, %d with the reference (%+d)\n",
+		mismatches, len(rows), license, reconciled, reconciled-license)
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *CapacityReconciliationReport) csvHeader() []string {
+	return []string{
+		"physical_host_id",
+		"host_name",
+		"source",
+		"measurement_date",
+		"nodes",
+		"reported_cores",
+		"reference_cores",
+		"status",
+		"license_cores",
+		"reconciled_license_cores",
+		"delta",
+		"products",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *CapacityReconciliationReport) csvRecord(row CapacityReconciliationRow) []string {
+	return []string{
+		row.PhysicalHostID,
+		row.HostName,
+		row.Source,
+		row.MeasurementDate,
+		strings.Join(row.Nodes, " "),
+		formatReportedCores(row.ReportedCores),
+		strconv.Itoa(row.ReferenceCores),
+		row.Status,
+		strconv.Itoa(row.LicenseCores),
+		strconv.Itoa(row.ReconciledCores),
+		strconv.Itoa(row.Delta),
+		strings.Join(row.Products, " "),
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *CapacityReconciliationReport) WriteCSV(w io.Writer, rows []CapacityReconciliationRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *CapacityReconciliationReport) WriteJSON(w io.Writer, rows []CapacityReconciliationRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with one sheet
+func (r *CapacityReconciliationReport) WriteXLSX(w io.Writer, rows []CapacityReconciliationRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return singleSheet("Capacity reconciliation", r.csvHeader(), records).Write(w)
+}
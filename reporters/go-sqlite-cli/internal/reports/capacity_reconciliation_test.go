@@ -0,0 +1,60 @@
+package reports_test
+
+import (
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestCapacityReconciliationReport(t *testing.T) {
+	// esx01 has 32 cores but its low confidence VMs report 16, esx02 is
+	// reported right and esx03 runs no product
+	stmts := []string{
+		`INSERT INTO hypervisor_hosts (physical_host_id, source, host_ref, host_name, physical_cores, synced_at) VALUES
+			('esx01', 'vcenter.example.com', 'host-1', 'esx01.example.com', 32, '2025-10-21 09:00:00'),
+			('esx02', 'vcenter.example.com', 'host-2', 'esx02.example.com', 24, '2025-10-21 09:00:00'),
+			('esx03', 'vcenter.example.com', 'host-3', 'esx03.example.com', 16, '2025-10-21 09:00:00')`,
+	}
+	nodes := []struct{ fqdn, host, hostCores, confidence string }{
+		{"vm1", "esx01", "16", "low"},
+		{"vm2", "esx01", "16", "low"},
+		{"vm3", "esx02", "24", "high"},
+	}
+	for _, n := range nodes {
+		stmts = append(stmts,
+			`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('`+n.fqdn+`', '`+n.fqdn+`', 'PROD')`,
+			`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count,
+			is_virtualized, virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus,
+			physical_host_id, host_id_confidence)
+			VALUES ('`+n.fqdn+`', '2025-10-21 08:00:00', 'Linux', '8', 4, 'yes', 'VMware', '`+n.hostCores+`', 'true', 'true', 'true', 4,
+			'`+n.host+`', '`+n.confidence+`')`,
+			`INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, install_count)
+			VALUES ('`+n.fqdn+`', 'IS_ONP_PRD', '2025-10-21 08:00:00', 'present', 1)`)
+	}
+	db := newTestDB(t, stmts...)
+
+	rows, err := reports.NewCapacityReconciliationReport(db).Query(t.Context(), "", "", nil, nil)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected a row per reference host, got %+v", rows)
+	}
+
+	esx01, esx02, esx03 := rows[0], rows[1], rows[2]
+	if esx01.Status != reports.CapacityMismatch || len(esx01.Nodes) != 2 || len(esx01.ReportedCores) != 1 ||
+		esx01.ReportedCores[0] != 16 || esx01.MeasurementDate != "2025-10-21" {
+		t.Errorf("Expected esx01 to mismatch, got %+v", esx01)
+	}
+	// Low confidence hosts count all their cores: 16 reported, 32 in vCenter
+	if esx01.LicenseCores != 16 || esx01.ReconciledCores != 32 || esx01.Delta != 16 ||
+		len(esx01.Products) != 1 || esx01.Products[0] != "IS_ONP_PRD:+16" {
+		t.Errorf("Expected esx01 to add 16 license cores, got %+v", esx01)
+	}
+	if esx02.Status != reports.CapacityMatch || esx02.LicenseCores != 4 || esx02.Delta != 0 {
+		t.Errorf("Expected esx02 to match, got %+v", esx02)
+	}
+	if esx03.Status != reports.CapacityNotMeasured || esx03.MeasurementDate != "" {
+		t.Errorf("Expected esx03 not measured, got %+v", esx03)
+	}
+}
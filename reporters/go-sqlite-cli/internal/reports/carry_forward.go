@@ -0,0 +1,208 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+)
+
+// MaxCarryForwardDays is the most days v_carry_forward_measurements carries
+// the measurement of a node that stopped reporting
+const MaxCarryForwardDays = 31
+
+// carriedMeasurementsCTE selects the running products of the measured and
+// carried days of v_carry_forward_measurements, up to the carry-forward days
+// bound as its only parameter
+const carriedMeasurementsCTE = `
+	carried_measurements AS (
+		SELECT *
+		FROM v_carry_forward_measurements
+		WHERE carried_days <= ? AND status = 'present'
+	)`
+
+// carriedComplianceCTE computes the columns of v_license_compliance_report from
+// the carried measurements, with the nodes carried forward on each day
+var carriedComplianceCTE = carriedMeasurementsCTE + `,
+	carried_ineligible AS (
+		SELECT measurement_date, product_mnemo_code, SUM(host_cores) as ineligible_cores_dedup
+		FROM (
+			SELECT
+				measurement_date,
+				product_mnemo_code,
+				CASE
+					WHEN physical_host_id != '' AND physical_host_id != 'unknown' THEN physical_host_id
+					ELSE main_fqdn
+				END as host_key,
+				MAX(considered_cpus) as host_cores
+			FROM carried_measurements
+			WHERE os_eligible = 'false' OR virt_eligible = 'false'
+			GROUP BY measurement_date, product_mnemo_code, host_key
+		)
+		GROUP BY measurement_date, product_mnemo_code
+	),
+	carried_compliance AS (
+		SELECT
+			m.measurement_date,
+			p.product_mnemo_code,
+			p.product_name,
+			p.mode,
+			l.term_id,
+			l.program_number,
+			l.program_name,
+			COUNT(DISTINCT m.main_fqdn) as total_nodes,
+			COUNT(DISTINCT m.main_fqdn) as running_nodes,
+			SUM(m.install_count) as total_installations,
+			SUM(m.cpu_count) as total_vm_cores,
+			SUM(m.considered_cpus) as total_license_cores_raw,
+			SUM(CASE
+				WHEN m.os_eligible = 'true' AND m.virt_eligible = 'true'
+				THEN m.considered_cpus
+				ELSE 0
+			END) as eligible_cores_sum,
+			SUM(CASE
+				WHEN m.os_eligible = 'false' OR m.virt_eligible = 'false'
+				THEN m.considered_cpus
+				ELSE 0
+			END) as ineligible_cores_sum,
+			SUM(CASE
+				WHEN m.os_eligible = 'true' AND m.virt_eligible = 'true'
+				THEN m.considered_cpus
+				ELSE 0
+			END) + COALESCE(MAX(it.ineligible_cores_dedup), 0) as license_cores,
+			COUNT(DISTINCT CASE
+				WHEN m.physical_host_id != '' AND m.physical_host_id != 'unknown'
+				THEN m.physical_host_id
+			END) as unique_physical_hosts,
+			COUNT(DISTINCT CASE WHEN m.is_virtualized = 'yes' THEN m.main_fqdn END) as virtualized_nodes,
+			COUNT(DISTINCT CASE WHEN m.is_virtualized = 'no' THEN m.main_fqdn END) as physical_nodes,
+			COUNT(DISTINCT CASE WHEN m.carried_days > 0 THEN m.main_fqdn END) as carried_nodes
+		FROM carried_measurements m
+		JOIN product_codes p ON m.product_mnemo_code = p.product_mnemo_code
+		JOIN license_terms l ON l.term_id = ` + productTermColumn("p", "m.measurement_date") + `
+		LEFT JOIN carried_ineligible it ON it.measurement_date = m.measurement_date
+			AND it.product_mnemo_code = p.product_mnemo_code
+		GROUP BY m.measurement_date, p.product_mnemo_code, p.product_name, p.mode,
+			l.term_id, l.program_number, l.program_name
+	)`
+
+// carriedDailyCoresQuery computes the running license cores of
+// v_daily_license_cores from the carried measurements of a product between two
+// dates, with the nodes carried forward on each day
+const carriedDailyCoresQuery = `
+	WITH` + carriedMeasurementsCTE + `,
+	host_days AS (
+		SELECT
+			measurement_date,
+			main_fqdn,
+			CASE
+				WHEN physical_host_id != '' AND physical_host_id != 'unknown' THEN physical_host_id
+				ELSE main_fqdn
+			END as host_key,
+			MAX(carried_days) as carried_days,
+			MAX(CASE
+				WHEN os_eligible = 'true' AND virt_eligible = 'true'
+				THEN considered_cpus
+				ELSE 0
+			END) as eligible_cores,
+			MAX(CASE
+				WHEN os_eligible = 'false' OR virt_eligible = 'false'
+				THEN COALESCE(
+					CASE WHEN host_physical_cpus NOT IN ('', 'unknown') THEN CAST(host_physical_cpus AS INTEGER) END,
+					considered_cpus)
+				ELSE 0
+			END) as ineligible_cores
+		FROM carried_measurements
+		WHERE product_mnemo_code = ? AND measurement_date BETWEEN ? AND ?
+		GROUP BY measurement_date, main_fqdn, host_key
+	),
+	ineligible_hosts AS (
+		SELECT measurement_date, host_key, MAX(ineligible_cores) as cores
+		FROM host_days
+		WHERE ineligible_cores > 0
+		GROUP BY measurement_date, host_key
+	)
+	SELECT
+		h.measurement_date,
+		SUM(h.eligible_cores),
+		COALESCE((SELECT SUM(ih.cores) FROM ineligible_hosts ih WHERE ih.measurement_date = h.measurement_date), 0),
+		COUNT(DISTINCT h.main_fqdn),
+		COUNT(DISTINCT CASE WHEN h.carried_days > 0 THEN h.main_fqdn END)
+	FROM host_days h
+	GROUP BY h.measurement_date
+	ORDER BY h.measurement_date
+`
+
+// carriedDay is the running license cores of a product on one day, counting
+// the nodes carried forward
+type carriedDay struct {
+	DailyCores
+	EligibleCores   int
+	IneligibleCores int
+	Nodes           int
+	CarriedNodes    int // nodes whose measurement was carried forward
+}
+
+// SetCarryForwardDays makes the report carry the last measurement of a node
+// forward over up to days days it did not report (0 disables it)
+func (r *PeakUsageReport) SetCarryForwardDays(days int) {
+	r.carryForwardDays = days
+}
+
+// SetCarryForwardDays makes the report carry the last measurement of a node
+// forward over up to days days it did not report (0 disables it)
+func (r *ComplianceReport) SetCarryForwardDays(days int) {
+	r.carryForwardDays = days
+}
+
+// carriedDailyCores returns the running license cores of a product per day
+// between two dates (YYYY-MM-DD, inclusive), with the measurements of the
+// nodes that missed up to days days carried forward
+func (r *PeakUsageReport) carriedDailyCores(ctx context.Context, productCode, from, to string) ([]carriedDay, error) {
+	rows, err := r.db.QueryContext(ctx, carriedDailyCoresQuery, r.carryForwardDays, productCode, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query carried license cores of %s: %w", productCode, err)
+	}
+	defer rows.Close()
+
+	var days []carriedDay
+	for rows.Next() {
+		var day carriedDay
+		err := rows.Scan(&day.Date, &day.EligibleCores, &day.IneligibleCores, &day.Nodes, &day.CarriedNodes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		day.Cores = day.EligibleCores + day.IneligibleCores
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
+
+// applyCarryForward raises the running peak of the rows to the carried
+// license cores of a day with nodes carried forward, when higher. The daily
+// license cores are read between the dates returned by period for each row.
+func (r *PeakUsageReport) applyCarryForward(ctx context.Context, rows []PeakUsageRow, period func(PeakUsageRow) (string, string)) error {
+	if r.carryForwardDays <= 0 {
+		return nil
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		from, to := period(*row)
+		days, err := r.carriedDailyCores(ctx, row.ProductMnemoCode, from, to)
+		if err != nil {
+			return err
+		}
+		for _, day := range days {
+			if day.CarriedNodes == 0 || day.Cores <= row.PeakRunningTotalCores {
+				continue
+			}
+			row.PeakRunningVCores = day.Cores
+			row.PeakRunningTotalCores = day.Cores
+			row.PeakEligibleCores = day.EligibleCores
+			row.PeakIneligibleCores = day.IneligibleCores
+			row.PeakRunningNodes = day.Nodes
+			row.PeakDate = day.Date
+			row.CarriedForwardNodes = day.CarriedNodes
+		}
+	}
+	return nil
+}
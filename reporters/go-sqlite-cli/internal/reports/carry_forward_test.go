@@ -0,0 +1,86 @@
+package reports_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestCarryForward(t *testing.T) {
+	stmts := []string{
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('app01', 'app01', 'PROD'), ('app02', 'app02', 'PROD')`,
+	}
+	db := newTestDB(t, stmts...)
+
+	// app01 (4 cores) misses the second day, when app02 grows to 6 cores
+	day := func(offset int) string {
+		return time.Now().UTC().AddDate(0, 0, offset).Format("2006-01-02")
+	}
+	measure := func(fqdn, date string, cores int) {
+		t.Helper()
+		timestamp := date + " 08:00:00"
+		if _, err := db.Exec(`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count,
+			is_virtualized, virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus)
+			VALUES (?, ?, 'Linux', '8', ?, 'no', '', 'unknown', 'true', 'true', 'true', ?)`, fqdn, timestamp, cores, cores); err != nil {
+			t.Fatalf("Failed to insert measurement: %v", err)
+		}
+		if _, err := db.Exec(`INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, install_count)
+			VALUES (?, 'IS_ONP_PRD', ?, 'present', 1)`, fqdn, timestamp); err != nil {
+			t.Fatalf("Failed to insert detected product: %v", err)
+		}
+	}
+	measure("app01", day(-3), 4)
+	measure("app02", day(-3), 2)
+	measure("app02", day(-2), 6)
+	measure("app01", day(-1), 4)
+	measure("app02", day(-1), 2)
+
+	compliance := reports.NewComplianceReport(db)
+	licenseCores := func() map[string]reports.ComplianceRow {
+		t.Helper()
+		rows, err := compliance.Query(t.Context(), "", "", nil, nil, false)
+		if err != nil {
+			t.Fatalf("Compliance query failed: %v", err)
+		}
+		byDate := map[string]reports.ComplianceRow{}
+		for _, row := range rows {
+			byDate[row.MeasurementDate.Format("2006-01-02")] = row
+		}
+		return byDate
+	}
+
+	if row := licenseCores()[day(-2)]; row.LicenseCores != 6 || row.CarriedForwardNodes != 0 {
+		t.Errorf("Expected 6 measured license cores without carry-forward, got %d (%d carried)", row.LicenseCores, row.CarriedForwardNodes)
+	}
+	compliance.SetCarryForwardDays(1)
+	rows := licenseCores()
+	if row := rows[day(-2)]; row.LicenseCores != 10 || row.RunningNodes != 2 || row.CarriedForwardNodes != 1 {
+		t.Errorf("Expected 10 license cores on 2 nodes with 1 carried, got %d on %d with %d carried",
+			row.LicenseCores, row.RunningNodes, row.CarriedForwardNodes)
+	}
+	if row := rows[day(-1)]; row.LicenseCores != 6 || row.CarriedForwardNodes != 0 {
+		t.Errorf("Expected the measured day unchanged, got %d license cores (%d carried)", row.LicenseCores, row.CarriedForwardNodes)
+	}
+	if _, ok := rows[day(0)]; ok {
+		t.Error("Expected no measurement carried past the latest measured day")
+	}
+
+	peak := reports.NewPeakUsageReport(db)
+	peakRows, err := peak.Query(t.Context(), "", "")
+	if err != nil || len(peakRows) != 1 {
+		t.Fatalf("Expected 1 peak row, got %d (%v)", len(peakRows), err)
+	}
+	if peakRows[0].PeakRunningTotalCores != 6 || peakRows[0].CarriedForwardNodes != 0 {
+		t.Errorf("Expected a measured peak of 6 cores, got %d (%d carried)", peakRows[0].PeakRunningTotalCores, peakRows[0].CarriedForwardNodes)
+	}
+	peak.SetCarryForwardDays(1)
+	peakRows, err = peak.Query(t.Context(), "", "")
+	if err != nil {
+		t.Fatalf("Peak query failed: %v", err)
+	}
+	if peakRows[0].PeakRunningTotalCores != 10 || peakRows[0].PeakDate != day(-2) || peakRows[0].CarriedForwardNodes != 1 {
+		t.Errorf("Expected a carried peak of 10 cores on %s with 1 carried node, got %d on %s with %d",
+			day(-2), peakRows[0].PeakRunningTotalCores, peakRows[0].PeakDate, peakRows[0].CarriedForwardNodes)
+	}
+}
@@ -0,0 +1,273 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OnPremisesName is shown for the nodes without cloud instance metadata
+const OnPremisesName = "(on-premises)"
+
+// CloudUsageRow represents the nodes of one cloud provider and account running
+// products of one mode on a day
+type CloudUsageRow struct {
+	MeasurementDate time.Time `json:"measurement_date"`
+	CloudProvider   string    `json:"cloud_provider"`
+	AccountID       string    `json:"account_id"`
+	Mode            string    `json:"mode"`
+	Regions         string    `json:"regions"`        // distinct regions, comma separated
+	InstanceTypes   string    `json:"instance_types"` // distinct instance types, comma separated
+	Nodes           int       `json:"nodes"`
+	VCPUs           int       `json:"vcpus"`
+	ConsideredCores int       `json:"considered_cores"`
+	// Share of the considered cores of the day and mode over all providers
+	SharePercent float64 `json:"share_percent"`
+}
+
+// CloudUsageReport summarizes core usage per cloud provider and account
+type CloudUsageReport struct {
+	db *sql.DB
+}
+
+// NewCloudUsageReport creates a new report generator
+func NewCloudUsageReport(db *sql.DB) *CloudUsageReport {
+	return &CloudUsageReport{db: db}
+}
+
+// Query retrieves the latest measurement per node and day of the nodes running
+// a product, grouped by provider, account and product mode. Cores are summed
+// per node: VMs sharing an on-premises physical host are not deduplicated.
+func (r *CloudUsageReport) Query(ctx context.Context, productCode, mode string, fromDate, toDate *time.Time) ([]CloudUsageRow, error) {
+	filter := ""
+	args := []interface{}{}
+
+	if productCode != "" {
+		filter += " AND d.product_mnemo_code = ?"
+		args = append(args, productCode)
+	}
+
+	if mode != "" {
+		filter += " AND p.mode = ?"
+		args = append(args, mode)
+	}
+
+	if fromDate != nil {
+		filter += " AND ld.measurement_date >= ?"
+		args = append(args, fromDate.Format("2006-01-02"))
+	}
+
+	if toDate != nil {
+		filter += " AND ld.measurement_date <= ?"
+		args = append(args, toDate.Format("2006-01-02"))
+	}
+
+	query := `
+		WITH latest_daily AS (
+			SELECT
+				DATE(detection_timestamp) as measurement_date,
+				main_fqdn,
+				MAX(detection_timestamp) as latest_timestamp
+			FROM measurements
+			GROUP BY DATE(detection_timestamp), main_fqdn
+		),
+		running_nodes AS (
+			SELECT DISTINCT ld.measurement_date, ld.main_fqdn, ld.latest_timestamp, p.mode
+			FROM latest_daily ld
+			JOIN detected_products d ON d.main_fqdn = ld.main_fqdn
+				AND d.detection_timestamp = ld.latest_timestamp
+			JOIN product_codes p ON d.product_mnemo_code = p.product_mnemo_code
+			WHERE d.status = 'present'` + filter + `
+		)
+		SELECT
+			rn.measurement_date,
+			m.cloud_provider,
+			m.account_id,
+			rn.mode,
+			COALESCE(GROUP_CONCAT(DISTINCT NULLIF(m.region, '')), ''),
+			COALESCE(GROUP_CONCAT(DISTINCT NULLIF(m.instance_type, '')), ''),
+			COUNT(*),
+			SUM(m.cpu_count),
+			SUM(m.considered_cpus)
+		FROM running_nodes rn
+		JOIN measurements m ON m.main_fqdn = rn.main_fqdn
+			AND m.detection_timestamp = rn.latest_timestamp
+		GROUP BY rn.measurement_date, m.cloud_provider, m.account_id, rn.mode
+		ORDER BY rn.measurement_date DESC, m.cloud_provider = '', m.cloud_provider, m.account_id, rn.mode
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cloud usage: %w", err)
+	}
+	defer rows.Close()
+
+	var results []CloudUsageRow
+	for rows.Next() {
+		var row CloudUsageRow
+		var dateStr string
+
+		err := rows.Scan(
+			&dateStr,
+			&row.CloudProvider,
+			&row.AccountID,
+			&row.Mode,
+			&row.Regions,
+			&row.InstanceTypes,
+			&row.Nodes,
+			&row.VCPUs,
+			&row.ConsideredCores,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if row.CloudProvider == "" {
+			row.CloudProvider = OnPremisesName
+		}
+		row.Regions = sortedList(row.Regions)
+		row.InstanceTypes = sortedList(row.InstanceTypes)
+
+		// Parse date
+		row.MeasurementDate, err = time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse date: %w", err)
+		}
+
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Share of each provider and account in the cores of the day and mode,
+	// on-premises nodes included
+	type dayMode struct {
+		date time.Time
+		mode string
+	}
+	totals := make(map[dayMode]int)
+	for _, row := range results {
+		totals[dayMode{row.MeasurementDate, row.Mode}] += row.ConsideredCores
+	}
+	for i := range results {
+		if total := totals[dayMode{results[i].MeasurementDate, results[i].Mode}]; total > 0 {
+			results[i].SharePercent = float64(results[i].ConsideredCores) * 100 / float64(total)
+		}
+	}
+
+	return results, nil
+}
+
+// sortedList sorts the values of a comma separated list
+func sortedList(list string) string {
+	if list == "" {
+		return ""
+	}
+	values := strings.Split(list, ",")
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
+
+// WriteTable writes data in ASCII table format
+func (r *CloudUsageReport) WriteTable(w io.Writer, rows []CloudUsageRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "DATE\tPROVIDER\tACCOUNT\tMODE\tREGIONS\tINSTANCE_TYPES\tNODES\tVCPUS\tCORES\tSHARE")
+	fmt.Fprintln(tw, "----\t--------\t-------\t----\t-------\t--------------\t-----\t-----\t-----\t-----")
+
+	// Data rows
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%.1f%%\n",
+			row.MeasurementDate.Format("2006-01-02"),
+			row.CloudProvider,
+			row.AccountID,
+			row.Mode,
+			row.Regions,
+			row.InstanceTypes,
+			row.Nodes,
+			row.VCPUs,
+			row.ConsideredCores,
+			row.SharePercent,
+		)
+	}
+
+	fmt.Fprintln(tw, "\nCORES is the sum of the considered cores of the nodes; VMs sharing a physical host are not deduplicated.")
+	fmt.Fprintln(tw, "SHARE is the part of the cores of the day and mode over all providers.")
+
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *CloudUsageReport) csvHeader() []string {
+	return []string{
+		"measurement_date",
+		"cloud_provider",
+		"account_id",
+		"mode",
+		"regions",
+		"instance_types",
+		"nodes",
+		"vcpus",
+		"considered_cores",
+		"share_percent",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *CloudUsageReport) csvRecord(row CloudUsageRow) []string {
+	return []string{
+		row.MeasurementDate.Format("2006-01-02"),
+		row.CloudProvider,
+		row.AccountID,
+		row.Mode,
+		row.Regions,
+		row.InstanceTypes,
+		fmt.Sprintf("%d", row.Nodes),
+		fmt.Sprintf("%d", row.VCPUs),
+		fmt.Sprintf("%d", row.ConsideredCores),
+		fmt.Sprintf("%.1f", row.SharePercent),
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *CloudUsageReport) WriteCSV(w io.Writer, rows []CloudUsageRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *CloudUsageReport) WriteJSON(w io.Writer, rows []CloudUsageRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with one sheet per provider
+func (r *CloudUsageReport) WriteXLSX(w io.Writer, rows []CloudUsageRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return sheetsByColumn(r.csvHeader(), records, 1, "Providers").Write(w)
+}
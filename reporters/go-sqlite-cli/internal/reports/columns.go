@@ -0,0 +1,138 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Column is a CSV column of a report picked by --columns, with the header it
+// is written under
+type Column struct {
+	Name   string
+	Header string // empty keeps the name
+}
+
+// ColumnLayout selects, orders and renames the columns of the CSV output of
+// a report, for spreadsheets expecting a fixed layout. A zero layout keeps the
+// report output as is.
+type ColumnLayout struct {
+	Columns  []Column // all columns in report order when empty
+	NoHeader bool
+}
+
+// ParseColumns parses a comma separated list of CSV column names, each
+// optionally renamed with name=header
+func ParseColumns(list string) ([]Column, error) {
+	var columns []Column
+	for _, item := range splitPatterns(list) {
+		name, header, _ := strings.Cut(item, "=")
+		name, header = strings.TrimSpace(name), strings.TrimSpace(header)
+		if name == "" {
+			return nil, fmt.Errorf("invalid column %q (use name or name=header)", item)
+		}
+		columns = append(columns, Column{Name: name, Header: header})
+	}
+	return columns, nil
+}
+
+// IsZero reports whether the layout keeps the report output as is
+func (l ColumnLayout) IsZero() bool {
+	return len(l.Columns) == 0 && !l.NoHeader
+}
+
+// Records reads the CSV output of a report and returns the header and the
+// records with the columns of the layout
+func (l ColumnLayout) Records(data []byte) ([]string, [][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read report columns: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil, nil
+	}
+	names, records := records[0], records[1:]
+
+	if len(l.Columns) == 0 {
+		return names, records, nil
+	}
+
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		index[name] = i
+	}
+	header := make([]string, len(l.Columns))
+	positions := make([]int, len(l.Columns))
+	for i, column := range l.Columns {
+		position, ok := index[column.Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown column %q (the report has %s)", column.Name, strings.Join(names, ", "))
+		}
+		positions[i] = position
+		header[i] = column.Name
+		if column.Header != "" {
+			header[i] = column.Header
+		}
+	}
+
+	selected := make([][]string, len(records))
+	for i, record := range records {
+		selected[i] = make([]string, len(positions))
+		for j, position := range positions {
+			if position < len(record) {
+				selected[i][j] = record[position]
+			}
+		}
+	}
+	return header, selected, nil
+}
+
+// WriteCSV writes the CSV output of a report with the columns of the layout
+func (l ColumnLayout) WriteCSV(w io.Writer, data []byte) error {
+	header, records, err := l.Records(data)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if !l.NoHeader && header != nil {
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+	}
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// WriteTable writes the CSV output of a report as a plain table with the
+// columns of the layout. Renamed columns keep their header; the others are
+// shown upper case, as in the report tables.
+func (l ColumnLayout) WriteTable(w io.Writer, data []byte) error {
+	header, records, err := l.Records(data)
+	if err != nil {
+		return err
+	}
+
+	tw := newTableWriter(w)
+	if !l.NoHeader && header != nil {
+		separator := make([]string, len(header))
+		for i, name := range header {
+			if len(l.Columns) == 0 || l.Columns[i].Header == "" {
+				header[i] = strings.ToUpper(name)
+			}
+			separator[i] = strings.Repeat("-", len(header[i]))
+		}
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		fmt.Fprintln(tw, strings.Join(separator, "\t"))
+	}
+	for _, record := range records {
+		fmt.Fprintln(tw, strings.Join(record, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,57 @@
+package reports_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+const columnsCSV = `measurement_date,product_mnemo_code,license_cores
+2025-10-21,IS_ONP_PRD,16
+2025-10-21,"BRK,ONP",4
+`
+
+func TestColumnLayout(t *testing.T) {
+	columns, err := reports.ParseColumns("license_cores=Cores, product_mnemo_code")
+	if err != nil {
+		t.Fatalf("ParseColumns failed: %v", err)
+	}
+	layout := reports.ColumnLayout{Columns: columns}
+
+	var out bytes.Buffer
+	if err := layout.WriteCSV(&out, []byte(columnsCSV)); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	if want := "Cores,product_mnemo_code\n16,IS_ONP_PRD\n4,\"BRK,ONP\"\n"; out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+
+	out.Reset()
+	if err := layout.WriteTable(&out, []byte(columnsCSV)); err != nil {
+		t.Fatalf("WriteTable failed: %v", err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasPrefix(lines[0], "Cores  PRODUCT_MNEMO_CODE") || !strings.HasPrefix(lines[3], "4      BRK,ONP") {
+		t.Errorf("Unexpected table:\n%s", out.String())
+	}
+
+	// Without columns, --no-header keeps every column
+	out.Reset()
+	layout = reports.ColumnLayout{NoHeader: true}
+	if err := layout.WriteCSV(&out, []byte(columnsCSV)); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "2025-10-21,IS_ONP_PRD,16\n") {
+		t.Errorf("Expected the records without header, got %q", out.String())
+	}
+
+	layout = reports.ColumnLayout{Columns: []reports.Column{{Name: "cores"}}}
+	if err := layout.WriteCSV(&out, []byte(columnsCSV)); err == nil || !strings.Contains(err.Error(), "license_cores") {
+		t.Errorf("Expected an unknown column error listing the columns, got %v", err)
+	}
+	if _, err := reports.ParseColumns("=Cores"); err == nil {
+		t.Error("Expected an error for a column without a name")
+	}
+}
@@ -0,0 +1,32 @@
+package reports
+
+import "strings"
+
+// Contract period selectors of --period. Any other value selects the periods
+// with that label.
+const (
+	PeriodCurrent  = "current"  // the period of each term covering today
+	PeriodPrevious = "previous" // the last period of each term ended before today
+)
+
+// selectedPeriodsCTE returns the body of a common table expression listing the
+// contract period selected for each term, with the columns term_id, label,
+// start_date and end_date formatted as YYYY-MM-DD, and its arguments
+func selectedPeriodsCTE(selector string) (string, []interface{}) {
+	query := `
+		SELECT c.term_id, c.label,
+			strftime('%Y-%m-%d', c.start_date) AS start_date,
+			strftime('%Y-%m-%d', c.end_date) AS end_date
+		FROM contract_periods c
+	`
+	switch strings.ToLower(strings.TrimSpace(selector)) {
+	case PeriodCurrent:
+		return query + " WHERE DATE('now') BETWEEN DATE(c.start_date) AND DATE(c.end_date)", nil
+	case PeriodPrevious:
+		return query + ` WHERE c.end_date = (
+			SELECT MAX(c2.end_date) FROM contract_periods c2
+			WHERE c2.term_id = c.term_id AND DATE(c2.end_date) < DATE('now')
+		)`, nil
+	}
+	return query + " WHERE c.label = ?", []interface{}{selector}
+}
@@ -1,12 +1,12 @@
 package reports
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
-	"text/tabwriter"
 	"time"
 )
 
@@ -38,6 +38,9 @@ type CoreAggregationRow struct {
 // CoreAggregationReport generates reports from v_core_aggregation_by_product view
 type CoreAggregationReport struct {
 	db *sql.DB
+
+	// standby is the standby policy selecting the nodes
+	standby string
 }
 
 // NewCoreAggregationReport creates a new report generator
@@ -45,8 +48,24 @@ func NewCoreAggregationReport(db *sql.DB) *CoreAggregationReport {
 	return &CoreAggregationReport{db: db}
 }
 
+// SetStandbyPolicy selects the standby and DR nodes reported (see ParseStandbyPolicy)
+func (r *CoreAggregationReport) SetStandbyPolicy(policy string) {
+	r.standby = policy
+}
+
 // Query retrieves data from the view with optional filters
-func (r *CoreAggregationReport) Query(productCode string, fromDate, toDate *time.Time) ([]CoreAggregationRow, error) {
+func (r *CoreAggregationReport) Query(ctx context.Context, productCode, mode string, fromDate, toDate *time.Time) ([]CoreAggregationRow, error) {
+	var results []CoreAggregationRow
+	err := r.Each(ctx, productCode, mode, fromDate, toDate, func(row CoreAggregationRow) error {
+		results = append(results, row)
+		return nil
+	})
+	return results, err
+}
+
+// Each runs the query like Query and calls fn for each row as it is read, so
+// that large exports are written without holding every row
+func (r *CoreAggregationReport) Each(ctx context.Context, productCode, mode string, fromDate, toDate *time.Time, fn func(CoreAggregationRow) error) error {
 	query := `
 		SELECT 
 			measurement_date,
@@ -81,6 +100,11 @@ func (r *CoreAggregationReport) Query(productCode string, fromDate, toDate *time
 		args = append(args, productCode)
 	}
 	
+	if mode != "" {
+		query += " AND mode = ?"
+		args = append(args, mode)
+	}
+	
 	if fromDate != nil {
 		query += " AND measurement_date >= ?"
 		args = append(args, fromDate.Format("2006-01-02"))
@@ -91,15 +115,16 @@ func (r *CoreAggregationReport) Query(productCode string, fromDate, toDate *time
 		args = append(args, toDate.Format("2006-01-02"))
 	}
 	
+	query += standbyCondition("main_fqdn", r.standby)
+
 	query += " ORDER BY measurement_date DESC, product_mnemo_code, hostname"
 	
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query core aggregation: %w", err)
+		return fmt.Errorf("failed to query core aggregation: %w", err)
 	}
 	defer rows.Close()
 	
-	var results []CoreAggregationRow
 	for rows.Next() {
 		var row CoreAggregationRow
 		var dateStr string
@@ -129,13 +154,13 @@ func (r *CoreAggregationReport) Query(productCode string, fromDate, toDate *time
 			&row.OSVersion,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+			return fmt.Errorf("failed to scan row: %w", err)
 		}
 		
 		// Parse date
 		row.MeasurementDate, err = time.Parse("2006-01-02", dateStr)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse date: %w", err)
+			return fmt.Errorf("failed to parse date: %w", err)
 		}
 		
 		// Handle NULL physical_host_cores
@@ -144,15 +169,17 @@ func (r *CoreAggregationReport) Query(productCode string, fromDate, toDate *time
 			row.PhysicalHostCores = &cores
 		}
 		
-		results = append(results, row)
+		if err := fn(row); err != nil {
+			return err
+		}
 	}
 	
-	return results, rows.Err()
+	return rows.Err()
 }
 
 // WriteTable writes data in ASCII table format
 func (r *CoreAggregationReport) WriteTable(w io.Writer, rows []CoreAggregationRow) error {
-	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	tw := newTableWriter(w)
 	defer tw.Flush()
 	
 	// Header
@@ -186,27 +213,28 @@ func (r *CoreAggregationReport) WriteTable(w io.Writer, rows []CoreAggregationRo
 		totalLic := 0
 		totalElig := 0
 		totalInelig := 0
+		subtotals := newModeTotals()
 		for _, row := range rows {
 			totalVM += row.VMCores
 			totalLic += row.LicenseCores
 			totalElig += row.EligibleCores
 			totalInelig += row.IneligibleCores
+			subtotals.add(row.Mode, row.VMCores, row.LicenseCores, row.EligibleCores, row.IneligibleCores)
 		}
 		
 		fmt.Fprintln(tw, "----\t-------\t--------\t--------\t---------\t----\t------\t-------\t------")
+		subtotals.each(func(mode string, sums []int) {
+			fmt.Fprintf(tw, "%s\t\t\t%d\t%d\t%d\t%d\t\t\n", mode, sums[0], sums[1], sums[2], sums[3])
+		})
 		fmt.Fprintf(tw, "TOTAL\t\t\t%d\t%d\t%d\t%d\t\t\n", totalVM, totalLic, totalElig, totalInelig)
 	}
 	
-	return nil
+	return writeDailyFootnotes(tw, r.db)
 }
 
-// WriteCSV writes data in CSV format
-func (r *CoreAggregationReport) WriteCSV(w io.Writer, rows []CoreAggregationRow) error {
-	writer := csv.NewWriter(w)
-	defer writer.Flush()
-	
-	// Header
-	err := writer.Write([]string{
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *CoreAggregationReport) csvHeader() []string {
+	return []string{
 		"measurement_date",
 		"product_mnemo_code",
 		"product_name",
@@ -228,42 +256,52 @@ func (r *CoreAggregationReport) WriteCSV(w io.Writer, rows []CoreAggregationRow)
 		"is_virtualized",
 		"os_name",
 		"os_version",
-	})
-	if err != nil {
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *CoreAggregationReport) csvRecord(row CoreAggregationRow) []string {
+	physCores := ""
+	if row.PhysicalHostCores != nil {
+		physCores = fmt.Sprintf("%d", *row.PhysicalHostCores)
+	}
+	
+	return []string{
+		row.MeasurementDate.Format("2006-01-02"),
+		row.ProductMnemoCode,
+		row.ProductName,
+		row.Mode,
+		row.MainFQDN,
+		row.Hostname,
+		fmt.Sprintf("%d", row.VMCores),
+		fmt.Sprintf("%d", row.PartitionCores),
+		row.ProcessorEligible,
+		row.OSEligible,
+		row.VirtEligible,
+		fmt.Sprintf("%d", row.LicenseCores),
+		row.PhysicalHostID,
+		physCores,
+		fmt.Sprintf("%d", row.EligibleCores),
+		fmt.Sprintf("%d", row.IneligibleCores),
+		row.ProductStatus,
+		fmt.Sprintf("%d", row.InstallCount),
+		row.IsVirtualized,
+		row.OSName,
+		row.OSVersion,
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *CoreAggregationReport) WriteCSV(w io.Writer, rows []CoreAggregationRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+	
+	if err := writer.Write(r.csvHeader()); err != nil {
 		return err
 	}
 	
-	// Data rows
 	for _, row := range rows {
-		physCores := ""
-		if row.PhysicalHostCores != nil {
-			physCores = fmt.Sprintf("%d", *row.PhysicalHostCores)
-		}
-		
-		err := writer.Write([]string{
-			row.MeasurementDate.Format("2006-01-02"),
-			row.ProductMnemoCode,
-			row.ProductName,
-			row.Mode,
-			row.MainFQDN,
-			row.Hostname,
-			fmt.Sprintf("%d", row.VMCores),
-			fmt.Sprintf("%d", row.PartitionCores),
-			row.ProcessorEligible,
-			row.OSEligible,
-			row.VirtEligible,
-			fmt.Sprintf("%d", row.LicenseCores),
-			row.PhysicalHostID,
-			physCores,
-			fmt.Sprintf("%d", row.EligibleCores),
-			fmt.Sprintf("%d", row.IneligibleCores),
-			row.ProductStatus,
-			fmt.Sprintf("%d", row.InstallCount),
-			row.IsVirtualized,
-			row.OSName,
-			row.OSVersion,
-		})
-		if err != nil {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
 			return err
 		}
 	}
@@ -271,6 +309,21 @@ func (r *CoreAggregationReport) WriteCSV(w io.Writer, rows []CoreAggregationRow)
 	return nil
 }
 
+// WriteXLSX writes an Excel workbook with one sheet per product
+func (r *CoreAggregationReport) WriteXLSX(w io.Writer, rows []CoreAggregationRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return sheetsByColumn(r.csvHeader(), records, 1, "Cores").Write(w)
+}
+
+// NewRowWriter returns a writer streaming rows to w in the csv or json format,
+// with the same output as WriteCSV and WriteJSON
+func (r *CoreAggregationReport) NewRowWriter(w io.Writer, format string) (RowWriter[CoreAggregationRow], error) {
+	return newRowWriter(w, format, r.csvHeader(), r.csvRecord)
+}
+
 // WriteJSON writes data in JSON format
 func (r *CoreAggregationReport) WriteJSON(w io.Writer, rows []CoreAggregationRow) error {
 	encoder := json.NewEncoder(w)
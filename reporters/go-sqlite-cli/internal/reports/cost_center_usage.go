@@ -0,0 +1,384 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// NoCostCenterName is shown for the nodes without a cost center
+const NoCostCenterName = "(no cost center)"
+
+// CostCenterUsageRow represents a row from v_daily_cost_center_license_cores
+type CostCenterUsageRow struct {
+	MeasurementDate       time.Time `json:"measurement_date"`
+	CostCenter            string    `json:"cost_center"`
+	ProductMnemoCode      string    `json:"product_mnemo_code"`
+	ProductName           string    `json:"product_name"`
+	Mode                  string    `json:"mode"`
+	TermID                string    `json:"term_id"`
+	ProgramNumber         string    `json:"program_number"`
+	RunningNodes          int       `json:"running_nodes"`
+	RunningLicenseCores   int       `json:"running_license_cores"`
+	InstalledNodes        int       `json:"installed_nodes"`
+	InstalledLicenseCores int       `json:"installed_license_cores"`
+	// Chargeback: the running license cores of the term in this cost center,
+	// their sum over all cost centers and the share of the cost center in that sum
+	CostCenterTermCores     int     `json:"cost_center_term_license_cores"`
+	AllCostCentersTermCores int     `json:"all_cost_centers_term_cores"`
+	TermSharePercent        float64 `json:"term_share_percent"`
+	// Entitlements are per license term, so the status is the one of the term
+	// over all cost centers, as in the compliance report
+	LicensedCores    *int   `json:"licensed_cores"`
+	ComplianceStatus string `json:"compliance_status,omitempty"`
+}
+
+// CostCenterUsageReport generates the daily-summary and compliance reports
+// grouped by the cost center of the nodes from v_daily_cost_center_license_cores,
+// for the chargeback of the license cores
+type CostCenterUsageReport struct {
+	db         *sql.DB
+	compliance bool
+}
+
+// NewCostCenterSummaryReport creates the report generator of daily-summary --group-by cost-center
+func NewCostCenterSummaryReport(db *sql.DB) *CostCenterUsageReport {
+	return &CostCenterUsageReport{db: db}
+}
+
+// NewCostCenterComplianceReport creates the report generator of compliance --group-by cost-center
+func NewCostCenterComplianceReport(db *sql.DB) *CostCenterUsageReport {
+	return &CostCenterUsageReport{db: db, compliance: true}
+}
+
+// Query retrieves data from the view with optional filters
+func (r *CostCenterUsageReport) Query(ctx context.Context, productCode, mode string, fromDate, toDate *time.Time) ([]CostCenterUsageRow, error) {
+	query := `
+		SELECT
+			c.measurement_date,
+			c.cost_center,
+			c.product_mnemo_code,
+			p.product_name,
+			p.mode,
+			l.term_id,
+			l.program_number,
+			c.running_nodes,
+			c.running_license_cores,
+			c.installed_nodes,
+			c.installed_license_cores,
+			(SELECT SUM(c2.running_license_cores)
+			 FROM v_daily_cost_center_license_cores c2
+			 JOIN product_codes p2 ON c2.product_mnemo_code = p2.product_mnemo_code
+			 WHERE ` + productTermColumn("p2", "c2.measurement_date") + ` = l.term_id
+			   AND c2.cost_center = c.cost_center
+			   AND c2.measurement_date = c.measurement_date) as cost_center_term_license_cores,
+			(SELECT SUM(c2.running_license_cores)
+			 FROM v_daily_cost_center_license_cores c2
+			 JOIN product_codes p2 ON c2.product_mnemo_code = p2.product_mnemo_code
+			 WHERE ` + productTermColumn("p2", "c2.measurement_date") + ` = l.term_id
+			   AND c2.measurement_date = c.measurement_date) as all_cost_centers_term_cores
+		FROM v_daily_cost_center_license_cores c
+		JOIN product_codes p ON c.product_mnemo_code = p.product_mnemo_code
+		JOIN license_terms l ON l.term_id = ` + productTermColumn("p", "c.measurement_date") + `
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if productCode != "" {
+		query += " AND c.product_mnemo_code = ?"
+		args = append(args, productCode)
+	}
+
+	if mode != "" {
+		query += " AND p.mode = ?"
+		args = append(args, mode)
+	}
+
+	if fromDate != nil {
+		query += " AND c.measurement_date >= ?"
+		args = append(args, fromDate.Format("2006-01-02"))
+	}
+
+	if toDate != nil {
+		query += " AND c.measurement_date <= ?"
+		args = append(args, toDate.Format("2006-01-02"))
+	}
+
+	// Nodes without a cost center sort last
+	query += " ORDER BY c.measurement_date DESC, c.cost_center = '', c.cost_center, c.product_mnemo_code"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cost center usage: %w", err)
+	}
+	defer rows.Close()
+
+	var results []CostCenterUsageRow
+	for rows.Next() {
+		var row CostCenterUsageRow
+		var dateStr string
+
+		err := rows.Scan(
+			&dateStr,
+			&row.CostCenter,
+			&row.ProductMnemoCode,
+			&row.ProductName,
+			&row.Mode,
+			&row.TermID,
+			&row.ProgramNumber,
+			&row.RunningNodes,
+			&row.RunningLicenseCores,
+			&row.InstalledNodes,
+			&row.InstalledLicenseCores,
+			&row.CostCenterTermCores,
+			&row.AllCostCentersTermCores,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if row.AllCostCentersTermCores > 0 {
+			row.TermSharePercent = float64(row.CostCenterTermCores) * 100 / float64(row.AllCostCentersTermCores)
+		}
+
+		// Parse date
+		row.MeasurementDate, err = time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse date: %w", err)
+		}
+
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if r.compliance && len(results) > 0 {
+		if err := r.addComplianceStatus(ctx, results, productCode, mode, fromDate, toDate); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// addComplianceStatus copies the entitlement and status of each term and day
+// from the compliance report
+func (r *CostCenterUsageReport) addComplianceStatus(ctx context.Context, rows []CostCenterUsageRow, productCode, mode string, fromDate, toDate *time.Time) error {
+	compliance, err := NewComplianceReport(r.db).Query(ctx, productCode, mode, fromDate, toDate, false)
+	if err != nil {
+		return err
+	}
+
+	type key struct {
+		date    time.Time
+		product string
+	}
+	byProduct := make(map[key]ComplianceRow, len(compliance))
+	for _, c := range compliance {
+		byProduct[key{c.MeasurementDate, c.ProductMnemoCode}] = c
+	}
+
+	for i := range rows {
+		if c, ok := byProduct[key{rows[i].MeasurementDate, rows[i].ProductMnemoCode}]; ok {
+			rows[i].LicensedCores = c.LicensedCores
+			rows[i].ComplianceStatus = c.ComplianceStatus
+		} else {
+			// Installed but not running products are not in the compliance report
+			rows[i].ComplianceStatus = StatusNoEntitlement
+		}
+	}
+	return nil
+}
+
+// costCenterName returns the cost center shown in tables
+func costCenterName(costCenter string) string {
+	if costCenter == "" {
+		return NoCostCenterName
+	}
+	return costCenter
+}
+
+// WriteTable writes data in ASCII table format
+func (r *CostCenterUsageReport) WriteTable(w io.Writer, rows []CostCenterUsageRow) error {
+	if r.compliance {
+		return r.writeComplianceTable(w, rows)
+	}
+
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "DATE\tCOST_CENTER\tPRODUCT\tMODE\tRUN_NODES\tRUN_CORES\tINST_NODES\tINST_CORES")
+	fmt.Fprintln(tw, "----\t-----------\t-------\t----\t---------\t---------\t----------\t----------")
+
+	// Data rows
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\n",
+			row.MeasurementDate.Format("2006-01-02"),
+			costCenterName(row.CostCenter),
+			row.ProductMnemoCode,
+			row.Mode,
+			row.RunningNodes,
+			row.RunningLicenseCores,
+			row.InstalledNodes,
+			row.InstalledLicenseCores,
+		)
+	}
+
+	// Summary per cost center and mode, as PROD and NON PROD cores are licensed separately
+	if len(rows) > 0 {
+		type costCenterTotal struct {
+			costCenter, mode string
+			sums             [4]int
+		}
+		var totals []*costCenterTotal
+		index := make(map[string]*costCenterTotal)
+		for _, row := range rows {
+			key := row.CostCenter + "\x00" + row.Mode
+			t, ok := index[key]
+			if !ok {
+				t = &costCenterTotal{costCenter: costCenterName(row.CostCenter), mode: row.Mode}
+				index[key] = t
+				totals = append(totals, t)
+			}
+			t.sums[0] += row.RunningNodes
+			t.sums[1] += row.RunningLicenseCores
+			t.sums[2] += row.InstalledNodes
+			t.sums[3] += row.InstalledLicenseCores
+		}
+
+		fmt.Fprintln(tw, "----\t-----------\t-------\t----\t---------\t---------\t----------\t----------")
+		for _, t := range totals {
+			fmt.Fprintf(tw, "TOTAL\t%s\t\t%s\t%d\t%d\t%d\t%d\n", t.costCenter, t.mode, t.sums[0], t.sums[1], t.sums[2], t.sums[3])
+		}
+	}
+
+	return writeDailyFootnotes(tw, r.db)
+}
+
+// writeComplianceTable writes the compliance and chargeback columns in ASCII table format
+func (r *CostCenterUsageReport) writeComplianceTable(w io.Writer, rows []CostCenterUsageRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "DATE\tCOST_CENTER\tPRODUCT\tMODE\tPROGRAM\tRUN_NODES\tLIC_CORES\tCC_TERM_CORES\tALL_CCS\tSHARE\tENTITLED\tTERM_STATUS")
+	fmt.Fprintln(tw, "----\t-----------\t-------\t----\t-------\t---------\t---------\t-------------\t-------\t-----\t--------\t-----------")
+
+	// Data rows
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%.1f%%\t%s\t%s\n",
+			row.MeasurementDate.Format("2006-01-02"),
+			costCenterName(row.CostCenter),
+			row.ProductMnemoCode,
+			row.Mode,
+			row.ProgramNumber,
+			row.RunningNodes,
+			row.RunningLicenseCores,
+			row.CostCenterTermCores,
+			row.AllCostCentersTermCores,
+			row.TermSharePercent,
+			formatOptionalInt(row.LicensedCores, "N/A"),
+			row.ComplianceStatus,
+		)
+	}
+
+	fmt.Fprintln(tw, "\nSHARE is the cost center's part of the running license cores of the term over all cost centers.")
+	fmt.Fprintln(tw, "Entitlements are per license term: TERM_STATUS is the status over all cost centers.")
+
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *CostCenterUsageReport) csvHeader() []string {
+	header := []string{
+		"measurement_date",
+		"cost_center",
+		"product_mnemo_code",
+		"product_name",
+		"mode",
+		"term_id",
+		"program_number",
+		"running_nodes",
+		"running_license_cores",
+		"installed_nodes",
+		"installed_license_cores",
+	}
+	if r.compliance {
+		header = append(header,
+			"cost_center_term_license_cores",
+			"all_cost_centers_term_cores",
+			"term_share_percent",
+			"licensed_cores",
+			"compliance_status",
+		)
+	}
+	return header
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *CostCenterUsageReport) csvRecord(row CostCenterUsageRow) []string {
+	record := []string{
+		row.MeasurementDate.Format("2006-01-02"),
+		row.CostCenter,
+		row.ProductMnemoCode,
+		row.ProductName,
+		row.Mode,
+		row.TermID,
+		row.ProgramNumber,
+		fmt.Sprintf("%d", row.RunningNodes),
+		fmt.Sprintf("%d", row.RunningLicenseCores),
+		fmt.Sprintf("%d", row.InstalledNodes),
+		fmt.Sprintf("%d", row.InstalledLicenseCores),
+	}
+	if r.compliance {
+		record = append(record,
+			fmt.Sprintf("%d", row.CostCenterTermCores),
+			fmt.Sprintf("%d", row.AllCostCentersTermCores),
+			fmt.Sprintf("%.1f", row.TermSharePercent),
+			formatOptionalInt(row.LicensedCores, ""),
+			row.ComplianceStatus,
+		)
+	}
+	return record
+}
+
+// WriteCSV writes data in CSV format
+func (r *CostCenterUsageReport) WriteCSV(w io.Writer, rows []CostCenterUsageRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *CostCenterUsageReport) WriteJSON(w io.Writer, rows []CostCenterUsageRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with one sheet per cost center
+func (r *CostCenterUsageReport) WriteXLSX(w io.Writer, rows []CostCenterUsageRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return sheetsByColumn(r.csvHeader(), records, 1, "No Cost Center").Write(w)
+}
@@ -0,0 +1,323 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Characters of CoverageRow.Days
+const (
+	coverageMeasured    = '#'
+	coverageMissing     = '.'
+	coverageNotExpected = '-'
+)
+
+// CoverageRow is the measurement continuity of a node within one month
+type CoverageRow struct {
+	HostFQDN        string   `json:"host_fqdn"`
+	Mode            string   `json:"mode"`
+	Month           string   `json:"month"` // YYYY-MM
+	ExpectedDays    int      `json:"expected_days"`
+	MeasuredDays    int      `json:"measured_days"`
+	CoveragePercent float64  `json:"coverage_percent"`
+	MissingDates    []string `json:"missing_dates"`
+	// Days has one character per day of the month: # measured, . missing and
+	// - outside the range the node was expected to report in
+	Days string `json:"days"`
+}
+
+// CoverageReport shows, per node and day, whether a measurement exists
+type CoverageReport struct {
+	db *sql.DB
+}
+
+// NewCoverageReport creates a new report generator
+func NewCoverageReport(db *sql.DB) *CoverageReport {
+	return &CoverageReport{db: db}
+}
+
+// coverageNode is a landscape node with the days it is expected to report on
+type coverageNode struct {
+	fqdn, mode string
+	start, end time.Time
+	measured   map[string]bool
+}
+
+// Query returns the coverage of every landscape node per month between two
+// dates, by default the first and latest days measured in the database. A node
+// is expected to report every day from its first measurement (or fromDate,
+// whichever is later) until its decommission date (or toDate, whichever is
+// earlier); a node never measured is expected over the whole range. The host
+// filter is a substring of the FQDN, the mode filter the mode of the node.
+func (r *CoverageReport) Query(ctx context.Context, hostFilter, mode string, fromDate, toDate *time.Time) ([]CoverageRow, error) {
+	var firstDay, lastDay sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT MIN(DATE(detection_timestamp)), MAX(DATE(detection_timestamp)) FROM measurements`).
+		Scan(&firstDay, &lastDay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the measured days: %w", err)
+	}
+	if !firstDay.Valid {
+		return nil, nil
+	}
+
+	from, err := time.Parse("2006-01-02", firstDay.String)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse date: %w", err)
+	}
+	to, err := time.Parse("2006-01-02", lastDay.String)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse date: %w", err)
+	}
+	if fromDate != nil {
+		from = *fromDate
+	}
+	if toDate != nil {
+		to = *toDate
+	}
+
+	query := `
+		SELECT
+			n.main_fqdn,
+			n.mode,
+			COALESCE((SELECT MIN(DATE(m.detection_timestamp)) FROM measurements m WHERE m.main_fqdn = n.main_fqdn), ''),
+			COALESCE(DATE(n.decommissioned_on), '')
+		FROM landscape_nodes n
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if hostFilter != "" {
+		query += " AND n.main_fqdn LIKE ?"
+		args = append(args, "%"+hostFilter+"%")
+	}
+
+	if mode != "" {
+		query += " AND n.mode = ?"
+		args = append(args, mode)
+	}
+
+	query += " ORDER BY n.main_fqdn"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query landscape nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []*coverageNode
+	byFQDN := map[string]*coverageNode{}
+	for rows.Next() {
+		node := &coverageNode{start: from, end: to, measured: map[string]bool{}}
+		var firstMeasured, decommissioned string
+		if err := rows.Scan(&node.fqdn, &node.mode, &firstMeasured, &decommissioned); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if day, err := time.Parse("2006-01-02", firstMeasured); err == nil && day.After(node.start) {
+			node.start = day
+		}
+		if day, err := time.Parse("2006-01-02", decommissioned); err == nil && day.Before(node.end) {
+			node.end = day
+		}
+		if node.start.After(node.end) {
+			continue
+		}
+		nodes = append(nodes, node)
+		byFQDN[node.fqdn] = node
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	days, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT main_fqdn, DATE(detection_timestamp)
+		FROM measurements
+		WHERE DATE(detection_timestamp) BETWEEN ? AND ?
+	`, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query measured days: %w", err)
+	}
+	defer days.Close()
+	for days.Next() {
+		var fqdn, day string
+		if err := days.Scan(&fqdn, &day); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if node := byFQDN[fqdn]; node != nil {
+			node.measured[day] = true
+		}
+	}
+	if err := days.Err(); err != nil {
+		return nil, err
+	}
+
+	var results []CoverageRow
+	for _, node := range nodes {
+		results = append(results, node.months()...)
+	}
+	return results, nil
+}
+
+// months returns the coverage rows of a node, one per month it is expected in
+func (n *coverageNode) months() []CoverageRow {
+	var rows []CoverageRow
+	for month := time.Date(n.start.Year(), n.start.Month(), 1, 0, 0, 0, 0, time.UTC); !month.After(n.end); month = month.AddDate(0, 1, 0) {
+		row := CoverageRow{
+			HostFQDN:     n.fqdn,
+			Mode:         n.mode,
+			Month:        month.Format("2006-01"),
+			MissingDates: []string{},
+		}
+		var days strings.Builder
+		for day := month; day.Month() == month.Month(); day = day.AddDate(0, 0, 1) {
+			date := day.Format("2006-01-02")
+			switch {
+			case day.Before(n.start) || day.After(n.end):
+				days.WriteRune(coverageNotExpected)
+			case n.measured[date]:
+				days.WriteRune(coverageMeasured)
+				row.ExpectedDays++
+				row.MeasuredDays++
+			default:
+				days.WriteRune(coverageMissing)
+				row.ExpectedDays++
+				row.MissingDates = append(row.MissingDates, date)
+			}
+		}
+		row.Days = days.String()
+		row.CoveragePercent = coveragePercent(row.MeasuredDays, row.ExpectedDays)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// coveragePercent returns the share of measured days, rounded to 0.1%
+func coveragePercent(measured, expected int) float64 {
+	if expected == 0 {
+		return 0
+	}
+	return math.Round(float64(measured)*1000/float64(expected)) / 10
+}
+
+// WriteTable writes data in ASCII table format, with the coverage of all
+// nodes per month
+func (r *CoverageReport) WriteTable(w io.Writer, rows []CoverageRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "HOST\tMODE\tMONTH\tMEASURED\tEXPECTED\tCOVERAGE\tDAYS")
+	fmt.Fprintln(tw, "----\t----\t-----\t--------\t--------\t--------\t----")
+
+	// Data rows
+	type monthTotal struct{ measured, expected, nodes int }
+	totals := map[string]*monthTotal{}
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%.1f%%\t%s\n",
+			row.HostFQDN,
+			row.Mode,
+			row.Month,
+			row.MeasuredDays,
+			row.ExpectedDays,
+			row.CoveragePercent,
+			row.Days,
+		)
+		total := totals[row.Month]
+		if total == nil {
+			total = &monthTotal{}
+			totals[row.Month] = total
+		}
+		total.measured += row.MeasuredDays
+		total.expected += row.ExpectedDays
+		total.nodes++
+	}
+
+	// Coverage of all nodes per month
+	if len(totals) > 0 {
+		months := make([]string, 0, len(totals))
+		for month := range totals {
+			months = append(months, month)
+		}
+		sort.Strings(months)
+
+		fmt.Fprintln(tw, "----\t----\t-----\t--------\t--------\t--------\t----")
+		for _, month := range months {
+			total := totals[month]
+			fmt.Fprintf(tw, "ALL (%d nodes)\t\t%s\t%d\t%d\t%.1f%%\t\n", total.nodes, month,
+				total.measured, total.expected, coveragePercent(total.measured, total.expected))
+		}
+	}
+
+	fmt.Fprintln(tw, "\nDAYS: # measured, . missing, - not expected (before the first measurement or after the decommission date)")
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *CoverageReport) csvHeader() []string {
+	return []string{
+		"host_fqdn",
+		"mode",
+		"month",
+		"expected_days",
+		"measured_days",
+		"coverage_percent",
+		"missing_dates",
+		"days",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *CoverageReport) csvRecord(row CoverageRow) []string {
+	return []string{
+		row.HostFQDN,
+		row.Mode,
+		row.Month,
+		fmt.Sprintf("%d", row.ExpectedDays),
+		fmt.Sprintf("%d", row.MeasuredDays),
+		fmt.Sprintf("%.1f", row.CoveragePercent),
+		strings.Join(row.MissingDates, " "),
+		row.Days,
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *CoverageReport) WriteCSV(w io.Writer, rows []CoverageRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *CoverageReport) WriteJSON(w io.Writer, rows []CoverageRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with one sheet per month
+func (r *CoverageReport) WriteXLSX(w io.Writer, rows []CoverageRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return sheetsByColumn(r.csvHeader(), records, 2, "Coverage").Write(w)
+}
@@ -0,0 +1,65 @@
+package reports_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestCoverageReport(t *testing.T) {
+	stmts := []string{
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('app01', 'app01', 'PROD'), ('app02', 'app02', 'PROD')`,
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode, decommissioned_on) VALUES ('app03', 'app03', 'PROD', '2025-10-30')`,
+	}
+	// app01 reports from Oct 28 to Nov 2 but misses Oct 31, app02 starts on
+	// Nov 1, app03 is decommissioned on Oct 30
+	for _, m := range [][2]string{{"app01", "2025-10-28"}, {"app01", "2025-10-29"}, {"app01", "2025-10-30"},
+		{"app01", "2025-11-01"}, {"app01", "2025-11-02"}, {"app02", "2025-11-01"}, {"app02", "2025-11-02"}, {"app03", "2025-10-28"}} {
+		fqdn, day := m[0], m[1]
+		stmts = append(stmts, `INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count,
+			is_virtualized, virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus)
+			VALUES ('`+fqdn+`', '`+day+` 08:00:00', 'Linux', '8', 4, 'no', '', 'unknown', 'true', 'true', 'true', 4)`)
+	}
+	db := newTestDB(t, stmts...)
+
+	rows, err := reports.NewCoverageReport(db).Query(t.Context(), "", "", nil, nil)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	type coverage struct {
+		measured, expected int
+		percent            float64
+		missing            int
+	}
+	want := map[string]coverage{
+		"app01 2025-10": {3, 4, 75, 1},
+		"app01 2025-11": {2, 2, 100, 0},
+		"app02 2025-11": {2, 2, 100, 0},
+		"app03 2025-10": {1, 3, 33.3, 2},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("Expected %d rows, got %+v", len(want), rows)
+	}
+	for _, row := range rows {
+		w, ok := want[row.HostFQDN+" "+row.Month]
+		if !ok {
+			t.Errorf("Unexpected row %+v", row)
+			continue
+		}
+		if row.MeasuredDays != w.measured || row.ExpectedDays != w.expected || row.CoveragePercent != w.percent || len(row.MissingDates) != w.missing {
+			t.Errorf("%s %s: expected %+v, got %d/%d (%.1f%%) missing %v",
+				row.HostFQDN, row.Month, w, row.MeasuredDays, row.ExpectedDays, row.CoveragePercent, row.MissingDates)
+		}
+	}
+	if rows[0].Days != "---------------------------###." {
+		t.Errorf("Unexpected days of app01 in October: %q", rows[0].Days)
+	}
+
+	from := time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)
+	rows, err = reports.NewCoverageReport(db).Query(t.Context(), "app01", "", &from, nil)
+	if err != nil || len(rows) != 1 || rows[0].Month != "2025-11" {
+		t.Errorf("Expected the November row of app01 only, got %+v (%v)", rows, err)
+	}
+}
@@ -0,0 +1,22 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+)
+
+// writeDailyFootnotes writes the footnotes of the reports built from daily
+// measurements: the daily aggregation policy the cores were counted with and
+// the decommissioned nodes left out
+func writeDailyFootnotes(w io.Writer, db *sql.DB) error {
+	policy, err := database.GetDailyAggregation(db)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nDaily aggregation: %s (%s, see 'iwdlr db daily-aggregation')\n",
+		database.DescribeDailyAggregation(policy), policy)
+	return writeDecommissionedFootnote(w, db)
+}
@@ -1,13 +1,13 @@
 package reports
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
 	"strings"
-	"text/tabwriter"
 	"time"
 )
 
@@ -20,6 +20,7 @@ type DailySummaryRow struct {
 	TermID                          string    `json:"term_id"`
 	ProgramNumber                   string    `json:"program_number"`
 	ProgramName                     string    `json:"program_name"`
+	TermsDocument                   string    `json:"terms_document"` // term document in force on the date
 	// Running products
 	RunningNodeCount                int       `json:"running_node_count"`
 	RunningVCores                   int       `json:"running_vcores"`
@@ -46,7 +47,7 @@ func NewDailySummaryReport(db *sql.DB) *DailySummaryReport {
 }
 
 // Query retrieves data from the view with optional filters
-func (r *DailySummaryReport) Query(productCode string, fromDate, toDate *time.Time) ([]DailySummaryRow, error) {
+func (r *DailySummaryReport) Query(ctx context.Context, productCode, mode string, fromDate, toDate *time.Time) ([]DailySummaryRow, error) {
 	query := `
 		SELECT 
 			measurement_date,
@@ -56,6 +57,7 @@ func (r *DailySummaryReport) Query(productCode string, fromDate, toDate *time.Ti
 			term_id,
 			program_number,
 			program_name,
+			` + termDocumentColumn("term_id", "measurement_date") + `,
 			running_node_count,
 			running_vcores,
 			running_physical_cores_direct,
@@ -67,7 +69,7 @@ func (r *DailySummaryReport) Query(productCode string, fromDate, toDate *time.Ti
 			installed_physical_cores_direct,
 			installed_unique_phys_hosts,
 			installed_physical_cores_from_hosts
-		FROM v_daily_product_summary
+		FROM ` + cachedSource(r.db, "v_daily_product_summary") + `
 		WHERE 1=1
 	`
 	
@@ -78,6 +80,11 @@ func (r *DailySummaryReport) Query(productCode string, fromDate, toDate *time.Ti
 		args = append(args, productCode)
 	}
 	
+	if mode != "" {
+		query += " AND mode = ?"
+		args = append(args, mode)
+	}
+	
 	if fromDate != nil {
 		query += " AND measurement_date >= ?"
 		args = append(args, fromDate.Format("2006-01-02"))
@@ -90,7 +97,7 @@ func (r *DailySummaryReport) Query(productCode string, fromDate, toDate *time.Ti
 	
 	query += " ORDER BY measurement_date DESC, product_mnemo_code"
 	
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query daily summary: %w", err)
 	}
@@ -109,6 +116,7 @@ func (r *DailySummaryReport) Query(productCode string, fromDate, toDate *time.Ti
 			&row.TermID,
 			&row.ProgramNumber,
 			&row.ProgramName,
+			&row.TermsDocument,
 			&row.RunningNodeCount,
 			&row.RunningVCores,
 			&row.RunningPhysicalCoresDirect,
@@ -139,7 +147,7 @@ func (r *DailySummaryReport) Query(productCode string, fromDate, toDate *time.Ti
 
 // WriteTable writes data in ASCII table format
 func (r *DailySummaryReport) WriteTable(w io.Writer, rows []DailySummaryRow) error {
-	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	tw := newTableWriter(w)
 	defer tw.Flush()
 	
 	// Header
@@ -164,6 +172,9 @@ func (r *DailySummaryReport) WriteTable(w io.Writer, rows []DailySummaryRow) err
 		// Product header
 		fmt.Fprintf(tw, "\nProduct:\t%s (%s) - %s\n", row.ProductName, row.ProductCode, row.Mode)
 		fmt.Fprintf(tw, "License:\t%s - %s (%s)\n", row.ProgramName, row.ProgramNumber, row.TermID)
+		if row.TermsDocument != "" {
+			fmt.Fprintf(tw, "Terms:\t%s\n", row.TermsDocument)
+		}
 		
 		// Running products section
 		if row.RunningNodeCount > 0 || row.RunningVCores > 0 || row.RunningPhysicalCoresFromHosts > 0 {
@@ -192,16 +203,12 @@ func (r *DailySummaryReport) WriteTable(w io.Writer, rows []DailySummaryRow) err
 	fmt.Fprintln(tw, "")
 	fmt.Fprintln(tw, strings.Repeat("=", 160))
 	
-	return nil
+	return writeDailyFootnotes(tw, r.db)
 }
 
-// WriteCSV writes data in CSV format
-func (r *DailySummaryReport) WriteCSV(w io.Writer, rows []DailySummaryRow) error {
-	writer := csv.NewWriter(w)
-	defer writer.Flush()
-	
-	// Header
-	err := writer.Write([]string{
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *DailySummaryReport) csvHeader() []string {
+	return []string{
 		"measurement_date",
 		"product_code",
 		"product_name",
@@ -209,6 +216,7 @@ func (r *DailySummaryReport) WriteCSV(w io.Writer, rows []DailySummaryRow) error
 		"term_id",
 		"program_number",
 		"program_name",
+		"terms_document",
 		"running_node_count",
 		"running_vcores",
 		"running_physical_cores_direct",
@@ -220,34 +228,45 @@ func (r *DailySummaryReport) WriteCSV(w io.Writer, rows []DailySummaryRow) error
 		"installed_physical_cores_direct",
 		"installed_unique_phys_hosts",
 		"installed_physical_cores_from_hosts",
-	})
-	if err != nil {
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *DailySummaryReport) csvRecord(row DailySummaryRow) []string {
+	return []string{
+		row.MeasurementDate.Format("2006-01-02"),
+		row.ProductCode,
+		row.ProductName,
+		row.Mode,
+		row.TermID,
+		row.ProgramNumber,
+		row.ProgramName,
+		row.TermsDocument,
+		fmt.Sprintf("%d", row.RunningNodeCount),
+		fmt.Sprintf("%d", row.RunningVCores),
+		fmt.Sprintf("%d", row.RunningPhysicalCoresDirect),
+		fmt.Sprintf("%d", row.RunningUniquePhysHosts),
+		fmt.Sprintf("%d", row.RunningPhysicalCoresFromHosts),
+		fmt.Sprintf("%d", row.TotalInstalls),
+		fmt.Sprintf("%d", row.InstalledNodeCount),
+		fmt.Sprintf("%d", row.InstalledVCores),
+		fmt.Sprintf("%d", row.InstalledPhysicalCoresDirect),
+		fmt.Sprintf("%d", row.InstalledUniquePhysHosts),
+		fmt.Sprintf("%d", row.InstalledPhysicalCoresFromHosts),
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *DailySummaryReport) WriteCSV(w io.Writer, rows []DailySummaryRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+	
+	if err := writer.Write(r.csvHeader()); err != nil {
 		return err
 	}
 	
-	// Data rows
 	for _, row := range rows {
-		err := writer.Write([]string{
-			row.MeasurementDate.Format("2006-01-02"),
-			row.ProductCode,
-			row.ProductName,
-			row.Mode,
-			row.TermID,
-			row.ProgramNumber,
-			row.ProgramName,
-			fmt.Sprintf("%d", row.RunningNodeCount),
-			fmt.Sprintf("%d", row.RunningVCores),
-			fmt.Sprintf("%d", row.RunningPhysicalCoresDirect),
-			fmt.Sprintf("%d", row.RunningUniquePhysHosts),
-			fmt.Sprintf("%d", row.RunningPhysicalCoresFromHosts),
-			fmt.Sprintf("%d", row.TotalInstalls),
-			fmt.Sprintf("%d", row.InstalledNodeCount),
-			fmt.Sprintf("%d", row.InstalledVCores),
-			fmt.Sprintf("%d", row.InstalledPhysicalCoresDirect),
-			fmt.Sprintf("%d", row.InstalledUniquePhysHosts),
-			fmt.Sprintf("%d", row.InstalledPhysicalCoresFromHosts),
-		})
-		if err != nil {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
 			return err
 		}
 	}
@@ -255,6 +274,15 @@ func (r *DailySummaryReport) WriteCSV(w io.Writer, rows []DailySummaryRow) error
 	return nil
 }
 
+// WriteXLSX writes an Excel workbook with one sheet per product
+func (r *DailySummaryReport) WriteXLSX(w io.Writer, rows []DailySummaryRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return sheetsByColumn(r.csvHeader(), records, 1, "Daily Summary").Write(w)
+}
+
 // WriteJSON writes data in JSON format
 func (r *DailySummaryReport) WriteJSON(w io.Writer, rows []DailySummaryRow) error {
 	encoder := json.NewEncoder(w)
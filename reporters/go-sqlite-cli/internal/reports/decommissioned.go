@@ -0,0 +1,61 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+)
+
+// DecommissionedNode is a decommissioned node whose measurements taken after
+// its decommission date are left out of the reports
+type DecommissionedNode struct {
+	MainFQDN             string `json:"main_fqdn"`
+	DecommissionedOn     string `json:"decommissioned_on"`
+	ExcludedMeasurements int    `json:"excluded_measurements"`
+}
+
+// excludedDecommissionedNodes returns the decommissioned nodes with measurements
+// after their decommission date, ordered by FQDN
+func excludedDecommissionedNodes(db *sql.DB) ([]DecommissionedNode, error) {
+	rows, err := db.Query(`
+		SELECT n.main_fqdn, strftime('%Y-%m-%d', n.decommissioned_on), COUNT(*)
+		FROM landscape_nodes n
+		JOIN measurements m ON m.main_fqdn = n.main_fqdn
+		WHERE n.decommissioned_on IS NOT NULL
+			AND DATE(m.detection_timestamp) > DATE(n.decommissioned_on)
+		GROUP BY n.main_fqdn, n.decommissioned_on
+		ORDER BY n.main_fqdn
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query decommissioned nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []DecommissionedNode
+	for rows.Next() {
+		var node DecommissionedNode
+		if err := rows.Scan(&node.MainFQDN, &node.DecommissionedOn, &node.ExcludedMeasurements); err != nil {
+			return nil, fmt.Errorf("failed to scan decommissioned node: %w", err)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
+
+// writeDecommissionedFootnote explains which measurements of decommissioned
+// nodes the table leaves out, if any
+func writeDecommissionedFootnote(w io.Writer, db *sql.DB) error {
+	nodes, err := excludedDecommissionedNodes(db)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(w, "\nMeasurements taken after the decommission date of these nodes are left out (see 'iwdlr landscape decommission'):")
+	for _, node := range nodes {
+		fmt.Fprintf(w, "  %s decommissioned on %s (%d measurements left out)\n", node.MainFQDN, node.DecommissionedOn, node.ExcludedMeasurements)
+	}
+	return nil
+}
@@ -0,0 +1,409 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Types of the columns of report definitions
+const (
+	ColumnText    = "text"
+	ColumnInteger = "integer"
+	ColumnNumber  = "number"
+	ColumnDate    = "date"
+)
+
+// reportNamePattern is the pattern of the names of report definitions, which
+// are command names
+var reportNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// filterParameters are the named parameters given to the statements of report
+// definitions, with the patterns of their use in a statement
+var filterParameters = map[string]*regexp.Regexp{
+	"product": regexp.MustCompile(`[:@$]product\b`),
+	"mode":    regexp.MustCompile(`[:@$]mode\b`),
+	"from":    regexp.MustCompile(`[:@$]from\b`),
+	"to":      regexp.MustCompile(`[:@$]to\b`),
+}
+
+// ReportDefinition is a report defined outside the binary by an SQL statement
+// and the metadata of its columns, read from a YAML file:
+//
+//	description: Hosts with 16 cores or more
+//	sql: |
+//	  SELECT main_fqdn, MAX(considered_cpus) AS cores
+//	  FROM measurements
+//	  WHERE (:from IS NULL OR date(detection_timestamp) >= :from)
+//	  GROUP BY main_fqdn HAVING cores >= 16
+//	columns:
+//	  - {name: main_fqdn, title: Host}
+//	  - {name: cores, title: Cores, type: integer}
+//
+// The statement gets the filters of the report command as the named
+// parameters :product, :mode, :from and :to (YYYY-MM-DD), NULL when not given.
+type ReportDefinition struct {
+	// Name is the name of the report command, by default the file name
+	// without its extension
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	SQL         string `yaml:"sql"`
+	// Columns are the columns written, in this order; all the columns of the
+	// statement when empty
+	Columns []DefinitionColumn `yaml:"columns"`
+}
+
+// DefinitionColumn describes a column of the result of a report definition
+type DefinitionColumn struct {
+	Name  string `yaml:"name"`  // column of the statement result
+	Title string `yaml:"title"` // header of the table and xlsx outputs, the name when empty
+	Type  string `yaml:"type"`  // text, integer, number or date; as returned by SQLite when empty
+}
+
+// DefinedReport is the Reporter of a report definition
+type DefinedReport struct {
+	def  ReportDefinition
+	path string
+
+	// columns and titles are those of the last query
+	columns []string
+	titles  []string
+}
+
+// NewDefinedReport checks a report definition and returns its reporter
+func NewDefinedReport(def ReportDefinition) (*DefinedReport, error) {
+	if !reportNamePattern.MatchString(def.Name) {
+		return nil, fmt.Errorf("invalid report name %q (use lower case letters, digits and dashes)", def.Name)
+	}
+	if strings.TrimSpace(def.SQL) == "" {
+		return nil, fmt.Errorf("report %s: sql is required", def.Name)
+	}
+	seen := map[string]bool{}
+	for i, column := range def.Columns {
+		if column.Name == "" {
+			return nil, fmt.Errorf("report %s: column %d has no name", def.Name, i+1)
+		}
+		if seen[column.Name] {
+			return nil, fmt.Errorf("report %s: duplicate column %q", def.Name, column.Name)
+		}
+		seen[column.Name] = true
+		switch column.Type {
+		case "", ColumnText, ColumnInteger, ColumnNumber, ColumnDate:
+		default:
+			return nil, fmt.Errorf("report %s: column %s: invalid type %q (expected text, integer, number or date)", def.Name, column.Name, column.Type)
+		}
+	}
+	return &DefinedReport{def: def}, nil
+}
+
+// LoadReportDefinition reads the report definition of a YAML file
+func LoadReportDefinition(path string) (*DefinedReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report definition: %w", err)
+	}
+
+	var def ReportDefinition
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&def); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if def.Name == "" {
+		def.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	report, err := NewDefinedReport(def)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	report.path = path
+	return report, nil
+}
+
+// LoadReportDefinitions reads the report definitions of the .yaml and .yml
+// files of a directory, sorted by file name. A missing directory has none.
+// Files that cannot be read are left out and their errors returned.
+func LoadReportDefinitions(dir string) ([]*DefinedReport, []error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to read report definitions: %w", err)}
+	}
+
+	var reports []*DefinedReport
+	var errs []error
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		report, err := LoadReportDefinition(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		reports = append(reports, report)
+	}
+	return reports, errs
+}
+
+// Name returns the name of the report
+func (r *DefinedReport) Name() string {
+	return r.def.Name
+}
+
+// Description returns the description of the report, or the file it was
+// defined in
+func (r *DefinedReport) Description() string {
+	if r.def.Description != "" {
+		return r.def.Description
+	}
+	if r.path != "" {
+		return "Report defined in " + r.path
+	}
+	return "Report " + r.def.Name
+}
+
+// Path returns the file the report was defined in, empty when it was not
+// read from a file
+func (r *DefinedReport) Path() string {
+	return r.path
+}
+
+// Columns returns the column names of the rows of the last query
+func (r *DefinedReport) Columns() []string {
+	return r.columns
+}
+
+// parameterArgs returns the named parameters of the filters, those the
+// statement uses
+func (r *DefinedReport) parameterArgs(params Params) []interface{} {
+	values := map[string]interface{}{"product": nil, "mode": nil, "from": nil, "to": nil}
+	if params.ProductCode != "" {
+		values["product"] = params.ProductCode
+	}
+	if params.Mode != "" {
+		values["mode"] = params.Mode
+	}
+	if params.From != nil {
+		values["from"] = params.From.Format("2006-01-02")
+	}
+	if params.To != nil {
+		values["to"] = params.To.Format("2006-01-02")
+	}
+
+	var args []interface{}
+	for name, pattern := range filterParameters {
+		if pattern.MatchString(r.def.SQL) {
+			args = append(args, sql.Named(name, values[name]))
+		}
+	}
+	return args
+}
+
+// Query runs the statement of the definition with the filters and returns its
+// rows, made of the columns of the definition converted to their type
+func (r *DefinedReport) Query(ctx context.Context, db *sql.DB, params Params) ([]SQLRow, error) {
+	columns, rows, err := querySQLRows(ctx, db, r.def.SQL, r.parameterArgs(params)...)
+	if err != nil {
+		return nil, fmt.Errorf("report %s: %w", r.def.Name, err)
+	}
+
+	if len(r.def.Columns) == 0 {
+		r.columns, r.titles = columns, columns
+		return rows, nil
+	}
+
+	indexes := make(map[string]int, len(columns))
+	for i, column := range columns {
+		indexes[column] = i
+	}
+	r.columns = make([]string, len(r.def.Columns))
+	r.titles = make([]string, len(r.def.Columns))
+	picked := make([]int, len(r.def.Columns))
+	for i, column := range r.def.Columns {
+		index, ok := indexes[column.Name]
+		if !ok {
+			return nil, fmt.Errorf("report %s: column %q is not a column of its statement", r.def.Name, column.Name)
+		}
+		picked[i] = index
+		r.columns[i] = column.Name
+		r.titles[i] = column.Title
+		if r.titles[i] == "" {
+			r.titles[i] = column.Name
+		}
+	}
+
+	results := make([]SQLRow, len(rows))
+	for n, row := range rows {
+		result := make(SQLRow, len(picked))
+		for i, index := range picked {
+			value, err := convertColumnValue(row[index], r.def.Columns[i].Type)
+			if err != nil {
+				return nil, fmt.Errorf("report %s: row %d: column %s: %w", r.def.Name, n+1, r.columns[i], err)
+			}
+			result[i] = value
+		}
+		results[n] = result
+	}
+	return results, nil
+}
+
+// convertColumnValue converts a value of the result to the type of its column
+func convertColumnValue(value interface{}, columnType string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch columnType {
+	case ColumnText:
+		return formatSQLValue(value), nil
+	case ColumnInteger:
+		switch v := value.(type) {
+		case int64:
+			return v, nil
+		case float64:
+			return int64(v), nil
+		case string:
+			n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an integer", v)
+			}
+			return n, nil
+		}
+	case ColumnNumber:
+		switch v := value.(type) {
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a number", v)
+			}
+			return f, nil
+		}
+	case ColumnDate:
+		switch v := value.(type) {
+		case time.Time:
+			return v.Format("2006-01-02"), nil
+		case string:
+			if len(v) >= 10 {
+				if _, err := time.Parse("2006-01-02", v[:10]); err == nil {
+					return v[:10], nil
+				}
+			}
+			return nil, fmt.Errorf("%q is not a date", v)
+		}
+	default:
+		return value, nil
+	}
+	return nil, fmt.Errorf("%v cannot be converted to %s", value, columnType)
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *DefinedReport) csvRecord(row SQLRow) []string {
+	record := make([]string, len(row))
+	for i, value := range row {
+		record[i] = formatSQLValue(value)
+	}
+	return record
+}
+
+// WriteTable writes data in ASCII table format
+func (r *DefinedReport) WriteTable(w io.Writer, rows []SQLRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	header := make([]string, len(r.titles))
+	separator := make([]string, len(r.titles))
+	for i, title := range r.titles {
+		header[i] = strings.ToUpper(title)
+		separator[i] = strings.Repeat("-", len(title))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	fmt.Fprintln(tw, strings.Join(separator, "\t"))
+
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(r.csvRecord(row), "\t"))
+	}
+
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d rows\n", len(rows))
+
+	return nil
+}
+
+// WriteCSV writes data in CSV format, with the column names as header
+func (r *DefinedReport) WriteCSV(w io.Writer, rows []SQLRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.columns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes data in JSON format, one object per row with one member
+// per column
+func (r *DefinedReport) WriteJSON(w io.Writer, rows []SQLRow) error {
+	objects := make([]sqlObject, 0, len(rows))
+	for _, row := range rows {
+		objects = append(objects, sqlObject{columns: r.columns, row: row})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(objects)
+}
+
+// WriteXLSX writes an Excel workbook with a single sheet named after the
+// report, with the column titles as header
+func (r *DefinedReport) WriteXLSX(w io.Writer, rows []SQLRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return singleSheet(r.def.Name, r.titles, records).Write(w)
+}
+
+// RegisterReportDefinitions registers the reports of the definitions of a
+// directory (see LoadReportDefinitions). Definitions named after a registered
+// report are left out with an error.
+func RegisterReportDefinitions(dir string) ([]*DefinedReport, []error) {
+	reports, errs := LoadReportDefinitions(dir)
+	registered := reports[:0]
+	for _, report := range reports {
+		if err := Register(report); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", report.path, err))
+			continue
+		}
+		registered = append(registered, report)
+	}
+	sort.Slice(registered, func(i, j int) bool { return registered[i].Name() < registered[j].Name() })
+	return registered, errs
+}
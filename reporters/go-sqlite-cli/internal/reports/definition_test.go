@@ -0,0 +1,119 @@
+package reports_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+const hostCoresDefinition = `description: Largest core count of the hosts
+sql: |
+  SELECT m.main_fqdn, MAX(m.considered_cpus) AS cores, MAX(m.detection_timestamp) AS last_seen
+  FROM measurements m JOIN landscape_nodes n ON n.main_fqdn = m.main_fqdn
+  WHERE (:from IS NULL OR date(m.detection_timestamp) >= :from)
+    AND (:mode IS NULL OR n.mode = :mode)
+  GROUP BY m.main_fqdn
+  ORDER BY m.main_fqdn
+columns:
+  - {name: main_fqdn, title: Host}
+  - {name: cores, title: Cores, type: integer}
+  - {name: last_seen, title: Last Seen, type: date}
+`
+
+func TestReportDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	stmts := []string{
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('app01.example.com', 'app01', 'PROD'), ('dev01.example.com', 'dev01', 'NON PROD')`,
+		`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count, is_virtualized,
+			virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus)
+			VALUES ('app01.example.com', '2025-10-01 08:00:00', 'Linux', '1', 4, 'no', '', 'unknown', 'true', 'true', 'true', 4),
+			       ('app01.example.com', '2025-10-02 08:00:00', 'Linux', '1', 8, 'no', '', 'unknown', 'true', 'true', 'true', 8),
+			       ('dev01.example.com', '2025-09-01 08:00:00', 'Linux', '1', 2, 'no', '', 'unknown', 'true', 'true', 'true', 2)`,
+	}
+	db := newTestDB(t, stmts...)
+
+	defsDir := filepath.Join(dir, "reports.d")
+	files := map[string]string{
+		"plugin-host-cores.yaml": hostCoresDefinition,
+		"plugin-bad-type.yaml":   "sql: SELECT 1\ncolumns: [{name: x, type: bool}]\n",
+		"plugin-unknown.yml":     "sql: SELECT 1\nquery: SELECT 2\n",
+		"notes.txt":              "not a definition",
+	}
+	if err := os.MkdirAll(defsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(defsDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	registered, errs := reports.RegisterReportDefinitions(defsDir)
+	if len(registered) != 1 || len(errs) != 2 {
+		t.Fatalf("Expected 1 report and 2 errors, got %d reports and %v", len(registered), errs)
+	}
+	reporter, ok := reports.LookupReporter("plugin-host-cores")
+	if !ok || reporter.Description() != "Largest core count of the hosts" {
+		t.Fatalf("Expected the report to be registered, got %v", reporter)
+	}
+	if _, errs := reports.RegisterReportDefinitions(defsDir); len(errs) != 3 {
+		t.Errorf("Expected the second registration to fail, got %v", errs)
+	}
+
+	rows, err := reporter.Query(t.Context(), db, reports.Params{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 2 || rows[0][1] != int64(8) || rows[0][2] != "2025-10-02" {
+		t.Errorf("Unexpected rows: %v", rows)
+	}
+
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	rows, err = reporter.Query(t.Context(), db, reports.Params{Mode: reports.ModeProd, From: &from})
+	if err != nil || len(rows) != 1 || rows[0][0] != "app01.example.com" {
+		t.Errorf("Expected the PROD host measured in October, got %v (%v)", rows, err)
+	}
+
+	var buf bytes.Buffer
+	if err := reporter.WriteTable(&buf, rows); err != nil || !strings.HasPrefix(buf.String(), "HOST") {
+		t.Errorf("Expected the titles as table header, got %q (%v)", buf.String(), err)
+	}
+	buf.Reset()
+	var objects []map[string]interface{}
+	if err := reporter.WriteJSON(&buf, rows); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &objects); err != nil || objects[0]["cores"] != float64(8) {
+		t.Errorf("Expected the cores as a number, got %s (%v)", buf.String(), err)
+	}
+
+	rows, err = reporter.Query(t.Context(), db, reports.Params{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	paged, err := reports.PaginateSQLRows(reporter.Columns(), rows, reports.Page{Sort: "cores", Limit: 1})
+	if err != nil || len(paged) != 1 || paged[0][0] != "dev01.example.com" {
+		t.Errorf("Expected the host with the fewest cores, got %v (%v)", paged, err)
+	}
+	if _, err := reports.PaginateSQLRows(reporter.Columns(), rows, reports.Page{Sort: "memory"}); err == nil {
+		t.Error("Expected an error for an unknown sort column")
+	}
+
+	missing, err := reports.NewDefinedReport(reports.ReportDefinition{Name: "plugin-missing", SQL: "SELECT 1 AS a",
+		Columns: []reports.DefinitionColumn{{Name: "b"}}})
+	if err != nil {
+		t.Fatalf("NewDefinedReport failed: %v", err)
+	}
+	if _, err := missing.Query(t.Context(), db, reports.Params{}); err == nil {
+		t.Error("Expected an error for a column missing from the statement")
+	}
+	if _, err := reports.NewDefinedReport(reports.ReportDefinition{Name: "Bad Name", SQL: "SELECT 1"}); err == nil {
+		t.Error("Expected an error for an invalid name")
+	}
+}
@@ -0,0 +1,248 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// DetectionErrorRow summarizes the failed inspector detections of one node
+type DetectionErrorRow struct {
+	MainFQDN       string `json:"main_fqdn"`
+	Hostname       string `json:"hostname"`
+	ErrorCount     int    `json:"error_count"`
+	FirstError     string `json:"first_error"`
+	LastError      string `json:"last_error"`
+	LastMessage    string `json:"last_message"`
+	LastSourceFile string `json:"last_source_file"`
+	LastSuccess    string `json:"last_success"`
+	StillBroken    bool   `json:"still_broken"`
+}
+
+// DetectionErrorReport lists the nodes whose inspector reported a failed detection
+type DetectionErrorReport struct {
+	db *sql.DB
+}
+
+// NewDetectionErrorReport creates a new report generator
+func NewDetectionErrorReport(db *sql.DB) *DetectionErrorReport {
+	return &DetectionErrorReport{db: db}
+}
+
+// Query retrieves one row per node with failed detections, the nodes still
+// broken first. A node is still broken when no measurement was imported after
+// its last failed detection. host matches a substring of the hostname or main
+// FQDN; the dates filter on the day of the detection. With brokenOnly the nodes
+// that recovered are left out.
+func (r *DetectionErrorReport) Query(ctx context.Context, host string, fromDate, toDate *time.Time, brokenOnly bool) ([]DetectionErrorRow, error) {
+	// SQLite takes the bare hostname, error_message and source_file columns from
+	// the row holding MAX(detection_timestamp)
+	query := `
+		SELECT
+			e.main_fqdn,
+			e.hostname,
+			COUNT(*),
+			strftime('%Y-%m-%d %H:%M:%S', MIN(e.detection_timestamp)),
+			strftime('%Y-%m-%d %H:%M:%S', MAX(e.detection_timestamp)),
+			e.error_message,
+			e.source_file,
+			COALESCE((
+				SELECT strftime('%Y-%m-%d %H:%M:%S', MAX(m.detection_timestamp))
+				FROM measurements m
+				WHERE m.main_fqdn = e.main_fqdn
+			), '') AS last_success
+		FROM detection_errors e
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if host != "" {
+		query += " AND (e.hostname LIKE ? OR e.main_fqdn LIKE ?)"
+		args = append(args, "%"+host+"%", "%"+host+"%")
+	}
+
+	if fromDate != nil {
+		query += " AND DATE(e.detection_timestamp) >= ?"
+		args = append(args, fromDate.Format("2006-01-02"))
+	}
+
+	if toDate != nil {
+		query += " AND DATE(e.detection_timestamp) <= ?"
+		args = append(args, toDate.Format("2006-01-02"))
+	}
+
+	query += `
+		GROUP BY e.main_fqdn
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query detection errors: %w", err)
+	}
+	defer rows.Close()
+
+	var broken, recovered []DetectionErrorRow
+	for rows.Next() {
+		var row DetectionErrorRow
+
+		err := rows.Scan(
+			&row.MainFQDN,
+			&row.Hostname,
+			&row.ErrorCount,
+			&row.FirstError,
+			&row.LastError,
+			&row.LastMessage,
+			&row.LastSourceFile,
+			&row.LastSuccess,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		// Both timestamps use the same layout, so they compare as strings
+		row.StillBroken = row.LastSuccess < row.LastError
+		if row.StillBroken {
+			broken = append(broken, row)
+		} else if !brokenOnly {
+			recovered = append(recovered, row)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sortDetectionErrors(broken)
+	sortDetectionErrors(recovered)
+	return append(broken, recovered...), nil
+}
+
+// sortDetectionErrors orders rows by last failed detection, newest first
+func sortDetectionErrors(rows []DetectionErrorRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].LastError != rows[j].LastError {
+			return rows[i].LastError > rows[j].LastError
+		}
+		return rows[i].MainFQDN < rows[j].MainFQDN
+	})
+}
+
+// detectionStatus returns the status column of a row
+func detectionStatus(row DetectionErrorRow) string {
+	if row.StillBroken {
+		return "BROKEN"
+	}
+	return "RECOVERED"
+}
+
+// WriteTable writes data in ASCII table format
+func (r *DetectionErrorReport) WriteTable(w io.Writer, rows []DetectionErrorRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "HOST\tMAIN_FQDN\tERRORS\tFIRST_ERROR\tLAST_ERROR\tLAST_SUCCESS\tSTATUS\tLAST_MESSAGE")
+	fmt.Fprintln(tw, "----\t---------\t------\t-----------\t----------\t------------\t------\t------------")
+
+	// Data rows
+	var total, broken int
+	for _, row := range rows {
+		lastSuccess := row.LastSuccess
+		if lastSuccess == "" {
+			lastSuccess = "never"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			row.Hostname,
+			row.MainFQDN,
+			row.ErrorCount,
+			row.FirstError,
+			row.LastError,
+			lastSuccess,
+			detectionStatus(row),
+			row.LastMessage,
+		)
+		total += row.ErrorCount
+		if row.StillBroken {
+			broken++
+		}
+	}
+
+	// Summary
+	if len(rows) > 0 {
+		fmt.Fprintln(tw, "----\t---------\t------\t-----------\t----------\t------------\t------\t------------")
+		fmt.Fprintf(tw, "TOTAL (%d hosts)\t\t%d\t\t\t\t\t\n", len(rows), total)
+		tw.Flush()
+		fmt.Fprintf(w, "\n%d still broken, %d recovered\n", broken, len(rows)-broken)
+	}
+
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *DetectionErrorReport) csvHeader() []string {
+	return []string{
+		"main_fqdn",
+		"hostname",
+		"error_count",
+		"first_error",
+		"last_error",
+		"last_message",
+		"last_source_file",
+		"last_success",
+		"still_broken",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *DetectionErrorReport) csvRecord(row DetectionErrorRow) []string {
+	return []string{
+		row.MainFQDN,
+		row.Hostname,
+		fmt.Sprintf("%d", row.ErrorCount),
+		row.FirstError,
+		row.LastError,
+		row.LastMessage,
+		row.LastSourceFile,
+		row.LastSuccess,
+		fmt.Sprintf("%t", row.StillBroken),
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *DetectionErrorReport) WriteCSV(w io.Writer, rows []DetectionErrorRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *DetectionErrorReport) WriteJSON(w io.Writer, rows []DetectionErrorRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with a single sheet
+func (r *DetectionErrorReport) WriteXLSX(w io.Writer, rows []DetectionErrorRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return singleSheet("Detection errors", r.csvHeader(), records).Write(w)
+}
@@ -0,0 +1,391 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Scopes of the diff rows
+const (
+	DiffScopeProduct = "product"
+	DiffScopeHost    = "host"
+)
+
+// DiffRow compares a product or a host between two measurement dates
+type DiffRow struct {
+	Scope string `json:"scope"` // product or host
+	Name  string `json:"name"`  // product code or host FQDN
+	Mode  string `json:"mode"`  // mode of the product, empty for hosts
+	// Product rows: running nodes and running license cores, as in the
+	// compliance report. Host rows: 1 when the host was measured, and its
+	// considered cores.
+	NodesA        int `json:"nodes_a"`
+	NodesB        int `json:"nodes_b"`
+	NodesDelta    int `json:"nodes_delta"`
+	CoresA        int `json:"cores_a"`
+	CoresB        int `json:"cores_b"`
+	CoresDelta    int `json:"cores_delta"`
+	InstallsA     int `json:"installs_a"`
+	InstallsB     int `json:"installs_b"`
+	InstallsDelta int `json:"installs_delta"`
+	// Products that started (+) or stopped (-) running on a host
+	Detail string `json:"detail"`
+}
+
+// DiffReport compares two measurement dates side by side
+type DiffReport struct {
+	db    *sql.DB
+	dateA time.Time
+	dateB time.Time
+}
+
+// NewDiffReport creates a new report generator comparing dateA with dateB
+func NewDiffReport(db *sql.DB, dateA, dateB time.Time) *DiffReport {
+	return &DiffReport{db: db, dateA: dateA, dateB: dateB}
+}
+
+// diffSnapshot holds the values of one date
+type diffSnapshot struct {
+	products map[string]*diffValues
+	hosts    map[string]*diffValues
+	modes    map[string]string   // product code -> mode
+	running  map[string][]string // host -> running products
+}
+
+// diffValues are the compared values of a product or host on one date
+type diffValues struct {
+	nodes, cores, installs int
+}
+
+// Query compares the products and hosts of both dates, leaving out the
+// decommissioned nodes as the other reports do. Hosts use their measurement of
+// the day chosen by the daily aggregation policy (see v_daily_measurements);
+// products use the running license cores of v_daily_license_cores. With product or mode set, only those products are
+// compared, and only the hosts running one of them. Unless all is true, rows
+// without any change are left out.
+func (r *DiffReport) Query(ctx context.Context, productCode, mode string, all bool) ([]DiffRow, error) {
+	a, err := r.snapshot(ctx, r.dateA, productCode, mode)
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.snapshot(ctx, r.dateB, productCode, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	modes := make(map[string]string)
+	for _, s := range []*diffSnapshot{a, b} {
+		for product, m := range s.modes {
+			modes[product] = m
+		}
+	}
+
+	var results []DiffRow
+	for _, scope := range []string{DiffScopeProduct, DiffScopeHost} {
+		valuesA, valuesB := a.products, b.products
+		if scope == DiffScopeHost {
+			valuesA, valuesB = a.hosts, b.hosts
+		}
+
+		names := make(map[string]bool)
+		for name := range valuesA {
+			names[name] = true
+		}
+		for name := range valuesB {
+			names[name] = true
+		}
+		sorted := make([]string, 0, len(names))
+		for name := range names {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+
+		for _, name := range sorted {
+			va, vb := valuesA[name], valuesB[name]
+			if va == nil {
+				va = &diffValues{}
+			}
+			if vb == nil {
+				vb = &diffValues{}
+			}
+
+			row := DiffRow{
+				Scope:         scope,
+				Name:          name,
+				NodesA:        va.nodes,
+				NodesB:        vb.nodes,
+				NodesDelta:    vb.nodes - va.nodes,
+				CoresA:        va.cores,
+				CoresB:        vb.cores,
+				CoresDelta:    vb.cores - va.cores,
+				InstallsA:     va.installs,
+				InstallsB:     vb.installs,
+				InstallsDelta: vb.installs - va.installs,
+			}
+			if scope == DiffScopeProduct {
+				row.Mode = modes[name]
+			} else {
+				appeared, disappeared := diffProducts(a.running[name], b.running[name])
+				var changes []string
+				for _, p := range appeared {
+					changes = append(changes, "+"+p)
+				}
+				for _, p := range disappeared {
+					changes = append(changes, "-"+p)
+				}
+				row.Detail = strings.Join(changes, " ")
+			}
+
+			if !all && row.NodesDelta == 0 && row.CoresDelta == 0 && row.InstallsDelta == 0 && row.Detail == "" {
+				continue
+			}
+			results = append(results, row)
+		}
+	}
+
+	return results, nil
+}
+
+// snapshot reads the products and hosts of one date
+func (r *DiffReport) snapshot(ctx context.Context, date time.Time, productCode, mode string) (*diffSnapshot, error) {
+	day := date.Format("2006-01-02")
+	s := &diffSnapshot{
+		products: make(map[string]*diffValues),
+		hosts:    make(map[string]*diffValues),
+		modes:    make(map[string]string),
+		running:  make(map[string][]string),
+	}
+
+	var measured int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM v_reported_measurements WHERE DATE(detection_timestamp) = ?", day).Scan(&measured)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count measurements of %s: %w", day, err)
+	}
+	if measured == 0 {
+		return nil, fmt.Errorf("no measurements on %s", day)
+	}
+
+	filter := ""
+	args := []interface{}{day}
+	if productCode != "" {
+		filter += " AND d.product_mnemo_code = ?"
+		args = append(args, productCode)
+	}
+	if mode != "" {
+		filter += " AND p.mode = ?"
+		args = append(args, mode)
+	}
+
+	// Hosts, with the products of their measurement of the day
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			m.main_fqdn,
+			m.considered_cpus,
+			d.product_mnemo_code,
+			p.mode,
+			d.status,
+			COALESCE(d.install_count, 0)
+		FROM v_daily_measurements m
+		JOIN detected_products d ON d.main_fqdn = m.main_fqdn
+			AND d.detection_timestamp = m.detection_timestamp
+		JOIN product_codes p ON d.product_mnemo_code = p.product_mnemo_code
+		WHERE DATE(m.detection_timestamp) = ?
+			AND (d.status = 'present' OR d.install_count > 0)`+filter, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hosts of %s: %w", day, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var host, product, productMode, status string
+		var cores, installs int
+		if err := rows.Scan(&host, &cores, &product, &productMode, &status, &installs); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		h, ok := s.hosts[host]
+		if !ok {
+			h = &diffValues{nodes: 1, cores: cores}
+			s.hosts[host] = h
+		}
+		h.installs += installs
+		if status == "present" {
+			s.running[host] = append(s.running[host], product)
+		}
+
+		p, ok := s.products[product]
+		if !ok {
+			p = &diffValues{}
+			s.products[product] = p
+		}
+		p.installs += installs
+		s.modes[product] = productMode
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Running nodes and license cores as counted by the compliance report
+	rows, err = r.db.QueryContext(ctx, `
+		SELECT d.product_mnemo_code, d.running_nodes, d.running_license_cores
+		FROM v_daily_license_cores d
+		JOIN product_codes p ON d.product_mnemo_code = p.product_mnemo_code
+		WHERE d.measurement_date = ?`+filter, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query license cores of %s: %w", day, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product string
+		var nodes, cores int
+		if err := rows.Scan(&product, &nodes, &cores); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		p, ok := s.products[product]
+		if !ok {
+			p = &diffValues{}
+			s.products[product] = p
+		}
+		p.nodes, p.cores = nodes, cores
+	}
+
+	return s, rows.Err()
+}
+
+// WriteTable writes data in ASCII table format
+func (r *DiffReport) WriteTable(w io.Writer, rows []DiffRow) error {
+	fmt.Fprintf(w, "A: %s  B: %s\n\n", r.dateA.Format("2006-01-02"), r.dateB.Format("2006-01-02"))
+
+	tw := newTableWriter(w)
+
+	// Header
+	fmt.Fprintln(tw, "SCOPE\tNAME\tMODE\tNODES_A\tNODES_B\tDELTA\tCORES_A\tCORES_B\tDELTA\tINST_A\tINST_B\tDELTA\tDETAIL")
+	fmt.Fprintln(tw, "-----\t----\t----\t-------\t-------\t-----\t-------\t-------\t-----\t------\t------\t-----\t------")
+
+	// Data rows
+	totals := newModeTotals()
+	var total [3]int
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%+d\t%d\t%d\t%+d\t%d\t%d\t%+d\t%s\n",
+			row.Scope,
+			row.Name,
+			row.Mode,
+			row.NodesA,
+			row.NodesB,
+			row.NodesDelta,
+			row.CoresA,
+			row.CoresB,
+			row.CoresDelta,
+			row.InstallsA,
+			row.InstallsB,
+			row.InstallsDelta,
+			row.Detail,
+		)
+		if row.Scope == DiffScopeProduct {
+			totals.add(row.Mode, row.CoresA, row.CoresB, row.CoresDelta)
+			total[0] += row.CoresA
+			total[1] += row.CoresB
+			total[2] += row.CoresDelta
+		}
+	}
+
+	// License core totals of the products listed
+	if len(rows) > 0 {
+		fmt.Fprintln(tw, "-----\t----\t----\t-------\t-------\t-----\t-------\t-------\t-----\t------\t------\t-----\t------")
+		totals.each(func(mode string, sums []int) {
+			fmt.Fprintf(tw, "TOTAL\t%s\t\t\t\t\t%d\t%d\t%+d\t\t\t\t\n", mode, sums[0], sums[1], sums[2])
+		})
+		fmt.Fprintf(tw, "TOTAL\tproducts\t\t\t\t\t%d\t%d\t%+d\t\t\t\t\n", total[0], total[1], total[2])
+	}
+
+	tw.Flush()
+	return writeDecommissionedFootnote(w, r.db)
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *DiffReport) csvHeader() []string {
+	return []string{
+		"scope",
+		"name",
+		"mode",
+		"date_a",
+		"date_b",
+		"nodes_a",
+		"nodes_b",
+		"nodes_delta",
+		"cores_a",
+		"cores_b",
+		"cores_delta",
+		"installs_a",
+		"installs_b",
+		"installs_delta",
+		"detail",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *DiffReport) csvRecord(row DiffRow) []string {
+	return []string{
+		row.Scope,
+		row.Name,
+		row.Mode,
+		r.dateA.Format("2006-01-02"),
+		r.dateB.Format("2006-01-02"),
+		fmt.Sprintf("%d", row.NodesA),
+		fmt.Sprintf("%d", row.NodesB),
+		fmt.Sprintf("%d", row.NodesDelta),
+		fmt.Sprintf("%d", row.CoresA),
+		fmt.Sprintf("%d", row.CoresB),
+		fmt.Sprintf("%d", row.CoresDelta),
+		fmt.Sprintf("%d", row.InstallsA),
+		fmt.Sprintf("%d", row.InstallsB),
+		fmt.Sprintf("%d", row.InstallsDelta),
+		row.Detail,
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *DiffReport) WriteCSV(w io.Writer, rows []DiffRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format, with the compared dates
+func (r *DiffReport) WriteJSON(w io.Writer, rows []DiffRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		DateA string    `json:"date_a"`
+		DateB string    `json:"date_b"`
+		Rows  []DiffRow `json:"rows"`
+	}{r.dateA.Format("2006-01-02"), r.dateB.Format("2006-01-02"), rows})
+}
+
+// WriteXLSX writes an Excel workbook with one sheet per scope
+func (r *DiffReport) WriteXLSX(w io.Writer, rows []DiffRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return sheetsByColumn(r.csvHeader(), records, 0, "Diff").Write(w)
+}
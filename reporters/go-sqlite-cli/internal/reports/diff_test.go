@@ -0,0 +1,135 @@
+package reports_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestDiffReport(t *testing.T) {
+	stmts := []string{
+		`INSERT INTO product_codes (product_mnemo_code, ibm_product_code, product_name, mode, term_id) VALUES
+			('BRK_ONP_PRD', 'D0YYVZX', 'Broker', 'PROD', 'T1'),
+			('UM_ONP_NPR', 'D0YYUZX', 'Universal Messaging Non-Production', 'NON PROD', 'T1')`,
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES
+			('app01.example.com', 'app01', 'PROD'), ('app02.example.com', 'app02', 'PROD'),
+			('app03.example.com', 'app03', 'PROD'), ('dev01.example.com', 'dev01', 'NON PROD')`,
+		// app03 is decommissioned before both dates
+		`UPDATE landscape_nodes SET decommissioned_on = '2025-08-01' WHERE main_fqdn = 'app03.example.com'`,
+	}
+	for _, m := range []struct {
+		fqdn, timestamp string
+		cpus            int
+		products        []string
+	}{
+		// app01 reports twice on 2025-08-05: the max policy counts the first measurement
+		{"app01.example.com", "2025-08-05 08:00:00", 6, []string{"IS_ONP_PRD"}},
+		{"app01.example.com", "2025-08-05 14:00:00", 4, []string{"IS_ONP_PRD"}},
+		{"app02.example.com", "2025-08-05 08:00:00", 8, []string{"IS_ONP_PRD"}},
+		{"dev01.example.com", "2025-08-05 08:00:00", 2, []string{"UM_ONP_NPR"}},
+		{"app01.example.com", "2025-08-06 08:00:00", 4, []string{"IS_ONP_PRD", "BRK_ONP_PRD"}},
+		{"app03.example.com", "2025-08-06 08:00:00", 16, []string{"IS_ONP_PRD"}},
+		{"dev01.example.com", "2025-08-06 08:00:00", 2, []string{"UM_ONP_NPR"}},
+	} {
+		stmts = append(stmts, fmt.Sprintf(`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count, is_virtualized,
+			virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus)
+			VALUES ('%s', '%s', 'Linux', '9', %d, 'no', '', 'unknown', 'true', 'true', 'true', %d)`,
+			m.fqdn, m.timestamp, m.cpus, m.cpus))
+		for _, product := range m.products {
+			stmts = append(stmts, fmt.Sprintf(`INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, install_count)
+				VALUES ('%s', '%s', '%s', 'present', 1)`, m.fqdn, product, m.timestamp))
+		}
+	}
+	db := newTestDB(t, stmts...)
+
+	dateA := time.Date(2025, 8, 5, 0, 0, 0, 0, time.UTC)
+	dateB := time.Date(2025, 8, 6, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		product string
+		mode    string
+		all     bool
+		want    []string
+	}{
+		{
+			name: "changes only",
+			want: []string{
+				"product BRK_ONP_PRD PROD nodes 0>1 cores 0>4 installs 0>1",
+				"product IS_ONP_PRD PROD nodes 2>1 cores 14>4 installs 2>1",
+				"host app01.example.com  nodes 1>1 cores 6>4 installs 1>2 +BRK_ONP_PRD",
+				"host app02.example.com  nodes 1>0 cores 8>0 installs 1>0 -IS_ONP_PRD",
+			},
+		},
+		{
+			name: "all rows",
+			all:  true,
+			want: []string{
+				"product BRK_ONP_PRD PROD nodes 0>1 cores 0>4 installs 0>1",
+				"product IS_ONP_PRD PROD nodes 2>1 cores 14>4 installs 2>1",
+				"product UM_ONP_NPR NON PROD nodes 1>1 cores 2>2 installs 1>1",
+				"host app01.example.com  nodes 1>1 cores 6>4 installs 1>2 +BRK_ONP_PRD",
+				"host app02.example.com  nodes 1>0 cores 8>0 installs 1>0 -IS_ONP_PRD",
+				"host dev01.example.com  nodes 1>1 cores 2>2 installs 1>1",
+			},
+		},
+		{
+			name:    "one product",
+			product: "IS_ONP_PRD",
+			want: []string{
+				"product IS_ONP_PRD PROD nodes 2>1 cores 14>4 installs 2>1",
+				"host app01.example.com  nodes 1>1 cores 6>4 installs 1>1",
+				"host app02.example.com  nodes 1>0 cores 8>0 installs 1>0 -IS_ONP_PRD",
+			},
+		},
+		{
+			name: "one mode",
+			mode: reports.ModeNonProd,
+			all:  true,
+			want: []string{
+				"product UM_ONP_NPR NON PROD nodes 1>1 cores 2>2 installs 1>1",
+				"host dev01.example.com  nodes 1>1 cores 2>2 installs 1>1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := reports.NewDiffReport(db, dateA, dateB).Query(t.Context(), tt.product, tt.mode, tt.all)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			var got []string
+			for _, row := range rows {
+				if row.NodesDelta != row.NodesB-row.NodesA || row.CoresDelta != row.CoresB-row.CoresA || row.InstallsDelta != row.InstallsB-row.InstallsA {
+					t.Errorf("Inconsistent deltas in %+v", row)
+				}
+				got = append(got, strings.TrimSpace(fmt.Sprintf("%s %s %s nodes %d>%d cores %d>%d installs %d>%d %s", row.Scope, row.Name, row.Mode,
+					row.NodesA, row.NodesB, row.CoresA, row.CoresB, row.InstallsA, row.InstallsB, row.Detail)))
+			}
+			if g, w := strings.Join(got, "\n"), strings.Join(tt.want, "\n"); g != w {
+				t.Errorf("Unexpected rows:\n%s\nwant:\n%s", g, w)
+			}
+		})
+	}
+
+	report := reports.NewDiffReport(db, dateA, dateB)
+	rows, err := report.Query(t.Context(), "", "", false)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	var out strings.Builder
+	if err := report.WriteTable(&out, rows); err != nil {
+		t.Fatalf("WriteTable failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "app03.example.com decommissioned on 2025-08-01") {
+		t.Errorf("Missing decommissioned footnote:\n%s", out.String())
+	}
+
+	if _, err := reports.NewDiffReport(db, dateA, time.Date(2025, 8, 7, 0, 0, 0, 0, time.UTC)).Query(t.Context(), "", "", false); err == nil ||
+		!strings.Contains(err.Error(), "no measurements on 2025-08-07") {
+		t.Errorf("Expected a date without measurements to be rejected, got %v", err)
+	}
+}
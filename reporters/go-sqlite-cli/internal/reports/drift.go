@@ -0,0 +1,296 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DriftRow compares the expected landscape of a node with its latest measurement
+type DriftRow struct {
+	HostFQDN           string   `json:"host_fqdn"`
+	Mode               string   `json:"mode"`
+	LastMeasured       string   `json:"last_measured"` // empty if the node was never measured
+	ExpectedProducts   []string `json:"expected_products"`
+	DetectedProducts   []string `json:"detected_products"`
+	MissingProducts    []string `json:"missing_products"`
+	UnexpectedProducts []string `json:"unexpected_products"`
+	ExpectedCPUs       *int     `json:"expected_cpus"`
+	MeasuredCPUs       *int     `json:"measured_cpus"`
+	Drift              []string `json:"drift"` // empty when the node matches its expectations
+}
+
+// Drift types reported in DriftRow.Drift
+const (
+	DriftNotMeasured       = "not-measured"
+	DriftMissingProduct    = "missing-product"
+	DriftUnexpectedProduct = "unexpected-product"
+	DriftCPUChange         = "cpu-change"
+)
+
+// DriftReport compares landscape_nodes expectations with the latest measurements
+type DriftReport struct {
+	db *sql.DB
+}
+
+// NewDriftReport creates a new report generator
+func NewDriftReport(db *sql.DB) *DriftReport {
+	return &DriftReport{db: db}
+}
+
+// Query compares every node with an expected product list or CPU count against its
+// latest measurement. A product counts as detected when it is running or installed.
+// Product checks only apply to nodes with expected_product_codes_list set, the CPU
+// check only to nodes with expected_cpu_no set. Unless all is true, only nodes with
+// drift are returned. The mode filter applies to the mode of the node.
+func (r *DriftReport) Query(ctx context.Context, hostFilter, mode string, all bool) ([]DriftRow, error) {
+	query := `
+		SELECT
+			n.main_fqdn,
+			n.mode,
+			COALESCE(n.expected_product_codes_list, ''),
+			n.expected_cpu_no,
+			COALESCE(lm.detection_timestamp, ''),
+			lm.cpu_count,
+			COALESCE((
+				SELECT GROUP_CONCAT(d.product_mnemo_code, ' ')
+				FROM detected_products d
+				WHERE d.main_fqdn = lm.main_fqdn
+					AND d.detection_timestamp = lm.detection_timestamp
+					AND (d.status = 'present' OR d.install_count > 0)
+			), '')
+		FROM landscape_nodes n
+		LEFT JOIN v_latest_measurements lm ON lm.main_fqdn = n.main_fqdn
+		WHERE (COALESCE(n.expected_product_codes_list, '') != '' OR n.expected_cpu_no IS NOT NULL)
+	`
+
+	args := []interface{}{}
+
+	if hostFilter != "" {
+		query += " AND n.main_fqdn LIKE ?"
+		args = append(args, "%"+hostFilter+"%")
+	}
+
+	if mode != "" {
+		query += " AND n.mode = ?"
+		args = append(args, mode)
+	}
+
+	query += " ORDER BY n.main_fqdn"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query landscape drift: %w", err)
+	}
+	defer rows.Close()
+
+	var results []DriftRow
+	for rows.Next() {
+		var row DriftRow
+		var expectedList, detectedList string
+		var expectedCPUs, measuredCPUs sql.NullInt64
+
+		err := rows.Scan(
+			&row.HostFQDN,
+			&row.Mode,
+			&expectedList,
+			&expectedCPUs,
+			&row.LastMeasured,
+			&measuredCPUs,
+			&detectedList,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if expectedCPUs.Valid {
+			cpus := int(expectedCPUs.Int64)
+			row.ExpectedCPUs = &cpus
+		}
+		if measuredCPUs.Valid {
+			cpus := int(measuredCPUs.Int64)
+			row.MeasuredCPUs = &cpus
+		}
+		row.ExpectedProducts = splitProductList(expectedList)
+		row.DetectedProducts = splitProductList(detectedList)
+		row.compare(expectedList != "")
+
+		if all || len(row.Drift) > 0 {
+			results = append(results, row)
+		}
+	}
+
+	return results, rows.Err()
+}
+
+// compare fills the missing/unexpected products and the drift flags
+func (row *DriftRow) compare(checkProducts bool) {
+	row.MissingProducts = []string{}
+	row.UnexpectedProducts = []string{}
+	row.Drift = []string{}
+
+	if row.LastMeasured == "" {
+		row.Drift = append(row.Drift, DriftNotMeasured)
+		return
+	}
+
+	if checkProducts {
+		row.MissingProducts = difference(row.ExpectedProducts, row.DetectedProducts)
+		row.UnexpectedProducts = difference(row.DetectedProducts, row.ExpectedProducts)
+		if len(row.MissingProducts) > 0 {
+			row.Drift = append(row.Drift, DriftMissingProduct)
+		}
+		if len(row.UnexpectedProducts) > 0 {
+			row.Drift = append(row.Drift, DriftUnexpectedProduct)
+		}
+	}
+
+	if row.ExpectedCPUs != nil && row.MeasuredCPUs != nil && *row.ExpectedCPUs != *row.MeasuredCPUs {
+		row.Drift = append(row.Drift, DriftCPUChange)
+	}
+}
+
+// splitProductList splits a product code list separated by commas, semicolons or
+// whitespace into sorted, unique codes
+func splitProductList(list string) []string {
+	fields := strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n'
+	})
+
+	seen := make(map[string]bool)
+	codes := []string{}
+	for _, field := range fields {
+		code := strings.TrimSpace(field)
+		if code != "" && !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// difference returns the codes of a that are not in b
+func difference(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, code := range b {
+		inB[code] = true
+	}
+
+	result := []string{}
+	for _, code := range a {
+		if !inB[code] {
+			result = append(result, code)
+		}
+	}
+	return result
+}
+
+// formatCPUs formats an optional CPU count, empty when unknown
+func formatCPUs(cpus *int) string {
+	if cpus == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", *cpus)
+}
+
+// WriteTable writes data in ASCII table format
+func (r *DriftReport) WriteTable(w io.Writer, rows []DriftRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "HOST\tMODE\tLAST_MEASURED\tMISSING\tUNEXPECTED\tEXP_CPUS\tCPUS\tDRIFT")
+	fmt.Fprintln(tw, "----\t----\t-------------\t-------\t----------\t--------\t----\t-----")
+
+	// Data rows
+	for _, row := range rows {
+		drift := strings.Join(row.Drift, ",")
+		if drift == "" {
+			drift = "none"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			row.HostFQDN,
+			row.Mode,
+			row.LastMeasured,
+			strings.Join(row.MissingProducts, " "),
+			strings.Join(row.UnexpectedProducts, " "),
+			formatCPUs(row.ExpectedCPUs),
+			formatCPUs(row.MeasuredCPUs),
+			drift,
+		)
+	}
+
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *DriftReport) csvHeader() []string {
+	return []string{
+		"host_fqdn",
+		"mode",
+		"last_measured",
+		"expected_products",
+		"detected_products",
+		"missing_products",
+		"unexpected_products",
+		"expected_cpus",
+		"measured_cpus",
+		"drift",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *DriftReport) csvRecord(row DriftRow) []string {
+	return []string{
+		row.HostFQDN,
+		row.Mode,
+		row.LastMeasured,
+		strings.Join(row.ExpectedProducts, " "),
+		strings.Join(row.DetectedProducts, " "),
+		strings.Join(row.MissingProducts, " "),
+		strings.Join(row.UnexpectedProducts, " "),
+		formatCPUs(row.ExpectedCPUs),
+		formatCPUs(row.MeasuredCPUs),
+		strings.Join(row.Drift, " "),
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *DriftReport) WriteCSV(w io.Writer, rows []DriftRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *DriftReport) WriteJSON(w io.Writer, rows []DriftRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with all nodes on one sheet
+func (r *DriftReport) WriteXLSX(w io.Writer, rows []DriftRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return singleSheet("Landscape Drift", r.csvHeader(), records).Write(w)
+}
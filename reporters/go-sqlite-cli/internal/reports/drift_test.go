@@ -0,0 +1,116 @@
+package reports
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDriftRowCompare(t *testing.T) {
+	cpus := func(n int) *int { return &n }
+
+	tests := []struct {
+		name           string
+		row            DriftRow
+		checkProducts  bool
+		wantMissing    []string
+		wantUnexpected []string
+		wantDrift      []string
+	}{
+		{
+			name:           "matches its expectations",
+			row:            DriftRow{LastMeasured: "2025-10-01", ExpectedProducts: []string{"IS_ONP_PRD"}, DetectedProducts: []string{"IS_ONP_PRD"}, ExpectedCPUs: cpus(4), MeasuredCPUs: cpus(4)},
+			checkProducts:  true,
+			wantMissing:    []string{},
+			wantUnexpected: []string{},
+			wantDrift:      []string{},
+		},
+		{
+			name:           "never measured",
+			row:            DriftRow{ExpectedProducts: []string{"IS_ONP_PRD"}, ExpectedCPUs: cpus(4)},
+			checkProducts:  true,
+			wantMissing:    []string{},
+			wantUnexpected: []string{},
+			wantDrift:      []string{DriftNotMeasured},
+		},
+		{
+			name:           "missing product",
+			row:            DriftRow{LastMeasured: "2025-10-01", ExpectedProducts: []string{"BRK_ONP_PRD", "IS_ONP_PRD"}, DetectedProducts: []string{"IS_ONP_PRD"}},
+			checkProducts:  true,
+			wantMissing:    []string{"BRK_ONP_PRD"},
+			wantUnexpected: []string{},
+			wantDrift:      []string{DriftMissingProduct},
+		},
+		{
+			name:           "unexpected product",
+			row:            DriftRow{LastMeasured: "2025-10-01", ExpectedProducts: []string{"IS_ONP_PRD"}, DetectedProducts: []string{"IS_ONP_PRD", "UM_ONP_PRD"}},
+			checkProducts:  true,
+			wantMissing:    []string{},
+			wantUnexpected: []string{"UM_ONP_PRD"},
+			wantDrift:      []string{DriftUnexpectedProduct},
+		},
+		{
+			name:           "every drift at once",
+			row:            DriftRow{LastMeasured: "2025-10-01", ExpectedProducts: []string{"BRK_ONP_PRD"}, DetectedProducts: []string{"UM_ONP_PRD"}, ExpectedCPUs: cpus(4), MeasuredCPUs: cpus(8)},
+			checkProducts:  true,
+			wantMissing:    []string{"BRK_ONP_PRD"},
+			wantUnexpected: []string{"UM_ONP_PRD"},
+			wantDrift:      []string{DriftMissingProduct, DriftUnexpectedProduct, DriftCPUChange},
+		},
+		{
+			name:           "products not checked without an expected list",
+			row:            DriftRow{LastMeasured: "2025-10-01", ExpectedProducts: []string{}, DetectedProducts: []string{"IS_ONP_PRD"}, ExpectedCPUs: cpus(4), MeasuredCPUs: cpus(2)},
+			wantMissing:    []string{},
+			wantUnexpected: []string{},
+			wantDrift:      []string{DriftCPUChange},
+		},
+		{
+			name:           "no expected cpus",
+			row:            DriftRow{LastMeasured: "2025-10-01", MeasuredCPUs: cpus(8)},
+			checkProducts:  true,
+			wantMissing:    []string{},
+			wantUnexpected: []string{},
+			wantDrift:      []string{},
+		},
+		{
+			name:           "previous results are replaced",
+			row:            DriftRow{LastMeasured: "2025-10-01", MissingProducts: []string{"OLD"}, Drift: []string{DriftCPUChange}},
+			checkProducts:  true,
+			wantMissing:    []string{},
+			wantUnexpected: []string{},
+			wantDrift:      []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := tt.row
+			row.compare(tt.checkProducts)
+			if !reflect.DeepEqual(row.MissingProducts, tt.wantMissing) {
+				t.Errorf("MissingProducts = %v, want %v", row.MissingProducts, tt.wantMissing)
+			}
+			if !reflect.DeepEqual(row.UnexpectedProducts, tt.wantUnexpected) {
+				t.Errorf("UnexpectedProducts = %v, want %v", row.UnexpectedProducts, tt.wantUnexpected)
+			}
+			if !reflect.DeepEqual(row.Drift, tt.wantDrift) {
+				t.Errorf("Drift = %v, want %v", row.Drift, tt.wantDrift)
+			}
+		})
+	}
+}
+
+func TestSplitProductList(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{"", []string{}},
+		{"IS_ONP_PRD", []string{"IS_ONP_PRD"}},
+		{"UM_ONP_PRD,IS_ONP_PRD", []string{"IS_ONP_PRD", "UM_ONP_PRD"}},
+		{" IS_ONP_PRD ; BRK_ONP_PRD\tUM_ONP_PRD\n", []string{"BRK_ONP_PRD", "IS_ONP_PRD", "UM_ONP_PRD"}},
+		{"IS_ONP_PRD,,IS_ONP_PRD", []string{"IS_ONP_PRD"}},
+	}
+	for _, tt := range tests {
+		if got := splitProductList(tt.list); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitProductList(%q) = %v, want %v", tt.list, got, tt.want)
+		}
+	}
+}
@@ -15,12 +15,12 @@
 package reports
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
-	"text/tabwriter"
 	"time"
 )
 
@@ -31,6 +31,7 @@ type HostDetailRow struct {
 	Date                   time.Time      `json:"date"`
 	Virtual                string         `json:"virtual"`
 	ProductCode            sql.NullString `json:"product_code"`
+	Mode                   string         `json:"mode"` // mode of the product, empty if unknown
 	Running                sql.NullString `json:"running"`
 	Installed              sql.NullString `json:"installed"`
 	VirtualCPUs            int            `json:"virtual_cpus"`
@@ -39,11 +40,51 @@ type HostDetailRow struct {
 	OperatingSystem        string         `json:"operating_system"`
 	EligibleOS             string         `json:"eligible_os"`
 	EligibleVirtualization string         `json:"eligible_virtualization"`
+	Owner                  string         `json:"owner"`       // owner of the node, empty if unknown
+	Team                   string         `json:"team"`        // team running the node, empty if unknown
+	CostCenter             string         `json:"cost_center"` // cost center charged, empty if unknown
 }
 
 // HostDetailReport generates host detail reports
 type HostDetailReport struct {
 	db *sql.DB
+
+	// standby is the standby policy selecting the nodes
+	standby string
+
+	// osFilter and virtTypeFilter select the nodes by operating system name
+	// and virtualization type, as comma-separated glob patterns
+	osFilter       string
+	virtTypeFilter string
+
+	// org selects the nodes of an organization
+	org string
+
+	// owner selects the nodes by owner or team, as comma-separated glob patterns
+	owner string
+
+	// page sorts and pages the rows in the query
+	page Page
+}
+
+// hostDetailSortColumns are the sortable columns of the host detail report
+var hostDetailSortColumns = map[string]string{
+	"host_fqdn":               "h.host_fqdn",
+	"date":                    "h.date",
+	"virtual":                 "h.virtual",
+	"product_code":            "h.product_code",
+	"mode":                    "p.mode",
+	"running":                 "h.running",
+	"installed":               "h.installed",
+	"virtual_cpus":            "h.virtual_cpus",
+	"physical_host_id":        "h.physical_host_id",
+	"physical_cpus":           "h.physical_cpus",
+	"operating_system":        "h.operating_system",
+	"eligible_os":             "h.eligible_os",
+	"eligible_virtualization": "h.eligible_virtualization",
+	"owner":                   "n.owner",
+	"team":                    "n.team",
+	"cost_center":             "n.cost_center",
 }
 
 // NewHostDetailReport creates a new host detail report generator
@@ -51,62 +92,132 @@ func NewHostDetailReport(db *sql.DB) *HostDetailReport {
 	return &HostDetailReport{db: db}
 }
 
-// Query executes the host detail query with optional filters
-func (r *HostDetailReport) Query(hostFilter, productFilter, fromDate, toDate string) ([]HostDetailRow, error) {
+// SetStandbyPolicy selects the standby and DR nodes reported (see ParseStandbyPolicy)
+func (r *HostDetailReport) SetStandbyPolicy(policy string) {
+	r.standby = policy
+}
+
+// SetSystemFilters selects the nodes by operating system name (e.g. Linux or
+// AIX) and virtualization type (e.g. VMware*, LPAR), each a comma-separated
+// list of case-insensitive glob patterns; empty filters select all nodes
+func (r *HostDetailReport) SetSystemFilters(os, virtType string) {
+	r.osFilter = os
+	r.virtTypeFilter = virtType
+}
+
+// SetOrg selects the nodes of an organization; empty selects all nodes
+func (r *HostDetailReport) SetOrg(orgID string) {
+	r.org = orgID
+}
+
+// SetOwner selects the nodes whose owner or team matches a comma-separated
+// list of case-insensitive glob patterns; empty selects all nodes
+func (r *HostDetailReport) SetOwner(owner string) {
+	r.owner = owner
+}
+
+// SetPage sorts and pages the rows in the query, so that a page of a large
+// estate is read without loading every row
+func (r *HostDetailReport) SetPage(page Page) {
+	r.page = page
+}
+
+// Query executes the host detail query with optional filters.
+// The host filter is a comma-separated list of glob patterns (* and ?) matched
+// case-insensitively against the FQDN, or against the host name for patterns
+// without a dot. The mode filter applies to the mode of the detected product.
+func (r *HostDetailReport) Query(ctx context.Context, hostFilter, productFilter, mode, fromDate, toDate string) ([]HostDetailRow, error) {
+	var results []HostDetailRow
+	err := r.Each(ctx, hostFilter, productFilter, mode, fromDate, toDate, func(row HostDetailRow) error {
+		results = append(results, row)
+		return nil
+	})
+	return results, err
+}
+
+// Each runs the host detail query like Query and calls fn for each row as it
+// is read, so that large estates are written without holding every row
+func (r *HostDetailReport) Each(ctx context.Context, hostFilter, productFilter, mode, fromDate, toDate string, fn func(HostDetailRow) error) error {
 	query := `
 		SELECT 
-			host_fqdn,
-			date,
-			virtual,
-			product_code,
-			running,
-			installed,
-			virtual_cpus,
-			physical_host_id,
-			physical_cpus,
-			operating_system,
-			eligible_os,
-			eligible_virtualization
-		FROM v_host_detail
+			h.host_fqdn,
+			h.date,
+			h.virtual,
+			h.product_code,
+			COALESCE(p.mode, ''),
+			h.running,
+			h.installed,
+			h.virtual_cpus,
+			h.physical_host_id,
+			h.physical_cpus,
+			h.operating_system,
+			h.eligible_os,
+			h.eligible_virtualization,
+			COALESCE(n.owner, ''),
+			COALESCE(n.team, ''),
+			COALESCE(n.cost_center, '')
+		FROM v_host_detail h
+		LEFT JOIN product_codes p ON p.product_mnemo_code = h.product_code
+		LEFT JOIN landscape_nodes n ON n.main_fqdn = h.host_fqdn
 		WHERE 1=1
 	`
 
 	args := []interface{}{}
-	argNum := 1
 
-	if hostFilter != "" {
-		query += fmt.Sprintf(" AND host_fqdn LIKE $%d", argNum)
-		args = append(args, "%"+hostFilter+"%")
-		argNum++
-	}
+	where, whereArgs := hostCondition("h.host_fqdn", hostFilter)
+	query += where
+	args = append(args, whereArgs...)
+
+	where, whereArgs = globCondition("h.os_name", r.osFilter)
+	query += where
+	args = append(args, whereArgs...)
+
+	where, whereArgs = globCondition("h.virt_type", r.virtTypeFilter)
+	query += where
+	args = append(args, whereArgs...)
+
+	where, whereArgs = orgCondition("h.host_fqdn", r.org)
+	query += where
+	args = append(args, whereArgs...)
+
+	where, whereArgs = ownerCondition("n", r.owner)
+	query += where
+	args = append(args, whereArgs...)
 
 	if productFilter != "" {
-		query += fmt.Sprintf(" AND product_code = $%d", argNum)
+		query += " AND h.product_code = ?"
 		args = append(args, productFilter)
-		argNum++
+	}
+
+	if mode != "" {
+		query += " AND p.mode = ?"
+		args = append(args, mode)
 	}
 
 	if fromDate != "" {
-		query += fmt.Sprintf(" AND date >= $%d", argNum)
+		query += " AND h.date >= ?"
 		args = append(args, fromDate)
-		argNum++
 	}
 
 	if toDate != "" {
-		query += fmt.Sprintf(" AND date <= $%d", argNum)
+		query += " AND h.date <= ?"
 		args = append(args, toDate)
-		argNum++
 	}
 
-	query += " ORDER BY date DESC, host_fqdn, product_code"
+	query += standbyCondition("h.host_fqdn", r.standby)
 
-	rows, err := r.db.Query(query, args...)
+	clause, err := r.page.sqlClause(hostDetailSortColumns, "h.date DESC, h.host_fqdn, h.product_code")
 	if err != nil {
-		return nil, fmt.Errorf("failed to query host detail: %w", err)
+		return err
+	}
+	query += clause
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query host detail: %w", err)
 	}
 	defer rows.Close()
 
-	var results []HostDetailRow
 	for rows.Next() {
 		var row HostDetailRow
 		var dateStr string
@@ -116,6 +227,7 @@ func (r *HostDetailReport) Query(hostFilter, productFilter, fromDate, toDate str
 			&dateStr,
 			&row.Virtual,
 			&row.ProductCode,
+			&row.Mode,
 			&row.Running,
 			&row.Installed,
 			&row.VirtualCPUs,
@@ -124,21 +236,26 @@ func (r *HostDetailReport) Query(hostFilter, productFilter, fromDate, toDate str
 			&row.OperatingSystem,
 			&row.EligibleOS,
 			&row.EligibleVirtualization,
+			&row.Owner,
+			&row.Team,
+			&row.CostCenter,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+			return fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		// Parse date
 		row.Date, err = time.Parse("2006-01-02", dateStr)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse date: %w", err)
+			return fmt.Errorf("failed to parse date: %w", err)
 		}
 
-		results = append(results, row)
+		if err := fn(row); err != nil {
+			return err
+		}
 	}
 
-	return results, rows.Err()
+	return rows.Err()
 }
 
 // WriteTable writes the report in table format
@@ -152,11 +269,11 @@ func (r *HostDetailReport) WriteTable(w io.Writer, rows []HostDetailRow) error {
 	fmt.Fprintln(w, "==========================================================================================================")
 	fmt.Fprintln(w, "")
 
-	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	tw := newTableWriter(w)
 	
 	// Write header
-	fmt.Fprintln(tw, "Host FQDN\tDate\tVirt\tProduct\tRun\tInst\tvCPUs\tPhysical Host\tpCPUs\tOS\tOS Elig\tVirt Elig")
-	fmt.Fprintln(tw, "--------\t----\t----\t-------\t---\t----\t-----\t-------------\t-----\t--\t-------\t---------")
+	fmt.Fprintln(tw, "Host FQDN\tDate\tVirt\tProduct\tMode\tRun\tInst\tvCPUs\tPhysical Host\tpCPUs\tOS\tOS Elig\tVirt Elig\tOwner\tTeam\tCost Center")
+	fmt.Fprintln(tw, "--------\t----\t----\t-------\t----\t---\t----\t-----\t-------------\t-----\t--\t-------\t---------\t-----\t----\t-----------")
 	
 	for _, row := range rows {
 		physHostID := "N/A"
@@ -184,11 +301,12 @@ func (r *HostDetailReport) WriteTable(w io.Writer, rows []HostDetailRow) error {
 			installed = row.Installed.String
 		}
 
-		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 			row.HostFQDN,
 			row.Date.Format("2006-01-02"),
 			row.Virtual,
 			productCode,
+			row.Mode,
 			running,
 			installed,
 			row.VirtualCPUs,
@@ -197,26 +315,34 @@ func (r *HostDetailReport) WriteTable(w io.Writer, rows []HostDetailRow) error {
 			row.OperatingSystem,
 			row.EligibleOS,
 			row.EligibleVirtualization,
+			orDash(row.Owner),
+			orDash(row.Team),
+			orDash(row.CostCenter),
 		)
 	}
 
 	tw.Flush()
 	fmt.Fprintln(w, "")
 	fmt.Fprintf(w, "Total rows: %d\n", len(rows))
-	return nil
+	return writeDecommissionedFootnote(w, r.db)
 }
 
-// WriteCSV writes the report in CSV format
-func (r *HostDetailReport) WriteCSV(w io.Writer, rows []HostDetailRow) error {
-	writer := csv.NewWriter(w)
-	defer writer.Flush()
+// orDash returns value, or "-" when it is empty
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
 
-	// Write header
-	header := []string{
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *HostDetailReport) csvHeader() []string {
+	return []string{
 		"host_fqdn",
 		"date",
 		"virtual",
 		"product_code",
+		"mode",
 		"running",
 		"installed",
 		"virtual_cpus",
@@ -225,53 +351,70 @@ func (r *HostDetailReport) WriteCSV(w io.Writer, rows []HostDetailRow) error {
 		"operating_system",
 		"eligible_os",
 		"eligible_virtualization",
+		"owner",
+		"team",
+		"cost_center",
 	}
-	if err := writer.Write(header); err != nil {
-		return fmt.Errorf("failed to write CSV header: %w", err)
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *HostDetailReport) csvRecord(row HostDetailRow) []string {
+	physHostID := ""
+	if row.PhysicalHostID.Valid {
+		physHostID = row.PhysicalHostID.String
 	}
 
-	// Write data rows
-	for _, row := range rows {
-		physHostID := ""
-		if row.PhysicalHostID.Valid {
-			physHostID = row.PhysicalHostID.String
-		}
+	physCPUs := ""
+	if row.PhysicalCPUs.Valid {
+		physCPUs = fmt.Sprintf("%d", row.PhysicalCPUs.Int64)
+	}
 
-		physCPUs := ""
-		if row.PhysicalCPUs.Valid {
-			physCPUs = fmt.Sprintf("%d", row.PhysicalCPUs.Int64)
-		}
+	productCode := ""
+	if row.ProductCode.Valid {
+		productCode = row.ProductCode.String
+	}
 
-		productCode := ""
-		if row.ProductCode.Valid {
-			productCode = row.ProductCode.String
-		}
+	running := ""
+	if row.Running.Valid {
+		running = row.Running.String
+	}
 
-		running := ""
-		if row.Running.Valid {
-			running = row.Running.String
-		}
+	installed := ""
+	if row.Installed.Valid {
+		installed = row.Installed.String
+	}
 
-		installed := ""
-		if row.Installed.Valid {
-			installed = row.Installed.String
-		}
+	return []string{
+		row.HostFQDN,
+		row.Date.Format("2006-01-02"),
+		row.Virtual,
+		productCode,
+		row.Mode,
+		running,
+		installed,
+		fmt.Sprintf("%d", row.VirtualCPUs),
+		physHostID,
+		physCPUs,
+		row.OperatingSystem,
+		row.EligibleOS,
+		row.EligibleVirtualization,
+		row.Owner,
+		row.Team,
+		row.CostCenter,
+	}
+}
 
-		record := []string{
-			row.HostFQDN,
-			row.Date.Format("2006-01-02"),
-			row.Virtual,
-			productCode,
-			running,
-			installed,
-			fmt.Sprintf("%d", row.VirtualCPUs),
-			physHostID,
-			physCPUs,
-			row.OperatingSystem,
-			row.EligibleOS,
-			row.EligibleVirtualization,
-		}
-		if err := writer.Write(record); err != nil {
+// WriteCSV writes the report in CSV format
+func (r *HostDetailReport) WriteCSV(w io.Writer, rows []HostDetailRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
 			return fmt.Errorf("failed to write CSV record: %w", err)
 		}
 	}
@@ -279,6 +422,22 @@ func (r *HostDetailReport) WriteCSV(w io.Writer, rows []HostDetailRow) error {
 	return nil
 }
 
+// WriteXLSX writes the report as an Excel workbook with one sheet per product.
+// Hosts without any detected product are collected on a "No Product" sheet.
+func (r *HostDetailReport) WriteXLSX(w io.Writer, rows []HostDetailRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return sheetsByColumn(r.csvHeader(), records, 3, "No Product").Write(w)
+}
+
+// NewRowWriter returns a writer streaming rows to w in the csv or json format,
+// with the same output as WriteCSV and WriteJSON
+func (r *HostDetailReport) NewRowWriter(w io.Writer, format string) (RowWriter[HostDetailRow], error) {
+	return newRowWriter(w, format, r.csvHeader(), r.csvRecord)
+}
+
 // WriteJSON writes the report in JSON format
 func (r *HostDetailReport) WriteJSON(w io.Writer, rows []HostDetailRow) error {
 	encoder := json.NewEncoder(w)
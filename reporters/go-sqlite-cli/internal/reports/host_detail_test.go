@@ -0,0 +1,78 @@
+package reports_test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestHostDetailFilters(t *testing.T) {
+	var stmts []string
+	for _, node := range []struct{ fqdn, os, virtType string }{
+		{"app01.example.com", "Linux", "VMware ESXi"},
+		{"app02.example.com", "Linux", "KVM"},
+		{"app10.example.com", "AIX", "LPAR"},
+		{"db01.example.com", "Linux", "VMware ESXi"},
+		{"myapp01.example.com", "Windows", ""},
+	} {
+		stmts = append(stmts,
+			fmt.Sprintf(`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('%s', '%s', 'PROD')`,
+				node.fqdn, strings.Split(node.fqdn, ".")[0]),
+			fmt.Sprintf(`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count, is_virtualized,
+				virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus)
+				VALUES ('%s', '2025-10-01 08:00:00', '%s', '1', 4, 'yes', '%s', 'unknown', 'true', 'true', 'true', 4)`,
+				node.fqdn, node.os, node.virtType),
+			fmt.Sprintf(`INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, install_count)
+				VALUES ('%s', 'IS_ONP_PRD', '2025-10-01 08:00:00', 'present', 1)`, node.fqdn))
+	}
+	db := newTestDB(t, stmts...)
+
+	tests := []struct {
+		host, os, virtType string
+		want               string
+	}{
+		{"", "", "", "app01 app02 app10 db01 myapp01"},
+		{"app01", "", "", "app01"}, // a host name, not a substring of myapp01
+		{"APP01.example.com", "", "", "app01"},
+		{"app0?", "", "", "app01 app02"},
+		{"app*,db01", "", "", "app01 app02 app10 db01"},
+		{"*.example.com", "linux", "", "app01 app02 db01"},
+		{"", "Linux,AIX", "vmware*", "app01 db01"},
+		{"", "", "LPAR, KVM", "app02 app10"},
+		{"app_1", "", "", ""}, // _ is not a wildcard
+	}
+	report := reports.NewHostDetailReport(db)
+	for _, tt := range tests {
+		report.SetSystemFilters(tt.os, tt.virtType)
+		rows, err := report.Query(t.Context(), tt.host, "", "", "", "")
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		var hosts []string
+		for _, row := range rows {
+			hosts = append(hosts, strings.Split(row.HostFQDN, ".")[0])
+		}
+		sort.Strings(hosts)
+		if got := strings.Join(hosts, " "); got != tt.want {
+			t.Errorf("--host %q --os %q --virt-type %q: got %q, want %q", tt.host, tt.os, tt.virtType, got, tt.want)
+		}
+	}
+
+	// Pages are read in the query
+	report.SetSystemFilters("", "")
+	report.SetPage(reports.Page{Sort: "-host_fqdn", Limit: 2, Offset: 1})
+	rows, err := report.Query(t.Context(), "", "", "", "", "")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 2 || rows[0].HostFQDN != "db01.example.com" || rows[1].HostFQDN != "app10.example.com" {
+		t.Errorf("Unexpected page: %+v", rows)
+	}
+	report.SetPage(reports.Page{Sort: "cores"})
+	if _, err := report.Query(t.Context(), "", "", "", "", ""); err == nil {
+		t.Error("Expected an error for an unknown sort column")
+	}
+}
@@ -0,0 +1,93 @@
+package reports
+
+import "strings"
+
+// splitPatterns splits a comma-separated filter into its trimmed, non-empty patterns
+func splitPatterns(filter string) []string {
+	var patterns []string
+	for _, p := range strings.Split(filter, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// globToLike converts a glob pattern (* for any characters, ? for one
+// character) to a LIKE pattern escaped with a backslash
+func globToLike(pattern string) string {
+	var b strings.Builder
+	for _, c := range pattern {
+		switch c {
+		case '*':
+			b.WriteByte('%')
+		case '?':
+			b.WriteByte('_')
+		case '%', '_', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// globCondition returns an SQL condition matching column against a
+// comma-separated list of glob patterns, case-insensitively, and its
+// arguments. An empty filter matches everything.
+func globCondition(column, filter string) (string, []interface{}) {
+	patterns := splitPatterns(filter)
+	if len(patterns) == 0 {
+		return "", nil
+	}
+
+	conds := make([]string, 0, len(patterns))
+	args := make([]interface{}, 0, len(patterns))
+	for _, p := range patterns {
+		conds = append(conds, column+` LIKE ? ESCAPE '\'`)
+		args = append(args, globToLike(p))
+	}
+	return " AND (" + strings.Join(conds, " OR ") + ")", args
+}
+
+// hostCondition is globCondition for host FQDNs, where a pattern without a dot
+// also matches the host name, so that app01 and app* select app01.example.com
+func hostCondition(column, filter string) (string, []interface{}) {
+	patterns := splitPatterns(filter)
+	if len(patterns) == 0 {
+		return "", nil
+	}
+
+	conds := make([]string, 0, len(patterns))
+	args := make([]interface{}, 0, len(patterns))
+	for _, p := range patterns {
+		conds = append(conds, column+` LIKE ? ESCAPE '\'`)
+		args = append(args, globToLike(p))
+		if !strings.Contains(p, ".") {
+			conds = append(conds, column+` LIKE ? ESCAPE '\'`)
+			args = append(args, globToLike(p)+".%")
+		}
+	}
+	return " AND (" + strings.Join(conds, " OR ") + ")", args
+}
+
+// ownerCondition is globCondition for the owner and team of the landscape
+// nodes joined as alias: a pattern selects the nodes whose owner or team
+// matches it
+func ownerCondition(alias, filter string) (string, []interface{}) {
+	patterns := splitPatterns(filter)
+	if len(patterns) == 0 {
+		return "", nil
+	}
+
+	conds := make([]string, 0, 2*len(patterns))
+	args := make([]interface{}, 0, 2*len(patterns))
+	for _, p := range patterns {
+		for _, column := range []string{alias + ".owner", alias + ".team"} {
+			conds = append(conds, column+` LIKE ? ESCAPE '\'`)
+			args = append(args, globToLike(p))
+		}
+	}
+	return " AND (" + strings.Join(conds, " OR ") + ")", args
+}
@@ -0,0 +1,242 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ImportSessionRow represents a row from the import_sessions table
+type ImportSessionRow struct {
+	SessionID          string `json:"session_id"`
+	ImportedAt         string `json:"imported_at"`
+	SourceFile         string `json:"source_file"`
+	Hostname           string `json:"hostname"`
+	MainFQDN           string `json:"main_fqdn"`
+	DetectionTimestamp string `json:"detection_timestamp"`
+	RecordsCreated     int    `json:"records_created"`
+	RecordsUpdated     int    `json:"records_updated"`
+	RecordsSkipped     int    `json:"records_skipped"`
+	Status             string `json:"status"`
+	ErrorMessage       string `json:"error_message"`
+	FileSHA256         string `json:"file_sha256"`
+	SignatureStatus    string `json:"signature_status"` // gpg or sha256, empty when not verified
+	SignatureSigner    string `json:"signature_signer"`
+}
+
+// ImportSessionReport lists the import audit trail
+type ImportSessionReport struct {
+	db *sql.DB
+}
+
+// NewImportSessionReport creates a new report generator
+func NewImportSessionReport(db *sql.DB) *ImportSessionReport {
+	return &ImportSessionReport{db: db}
+}
+
+// Query retrieves import sessions, newest first. host matches a substring of
+// the hostname; the dates filter on the day of the import.
+func (r *ImportSessionReport) Query(ctx context.Context, host, status string, fromDate, toDate *time.Time) ([]ImportSessionRow, error) {
+	query := `
+		SELECT
+			session_id,
+			strftime('%Y-%m-%d %H:%M:%S', imported_at),
+			source_file,
+			hostname,
+			COALESCE(main_fqdn, ''),
+			COALESCE(strftime('%Y-%m-%d %H:%M:%S', detection_timestamp), ''),
+			COALESCE(records_created, 0),
+			COALESCE(records_updated, 0),
+			COALESCE(records_skipped, 0),
+			status,
+			COALESCE(error_message, ''),
+			COALESCE(file_sha256, ''),
+			COALESCE(signature_status, ''),
+			COALESCE(signature_signer, '')
+		FROM import_sessions
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if host != "" {
+		query += " AND hostname LIKE ?"
+		args = append(args, "%"+host+"%")
+	}
+
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+
+	if fromDate != nil {
+		query += " AND DATE(imported_at) >= ?"
+		args = append(args, fromDate.Format("2006-01-02"))
+	}
+
+	if toDate != nil {
+		query += " AND DATE(imported_at) <= ?"
+		args = append(args, toDate.Format("2006-01-02"))
+	}
+
+	query += " ORDER BY imported_at DESC, session_id"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query import sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var results []ImportSessionRow
+	for rows.Next() {
+		var row ImportSessionRow
+
+		err := rows.Scan(
+			&row.SessionID,
+			&row.ImportedAt,
+			&row.SourceFile,
+			&row.Hostname,
+			&row.MainFQDN,
+			&row.DetectionTimestamp,
+			&row.RecordsCreated,
+			&row.RecordsUpdated,
+			&row.RecordsSkipped,
+			&row.Status,
+			&row.ErrorMessage,
+			&row.FileSHA256,
+			&row.SignatureStatus,
+			&row.SignatureSigner,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		results = append(results, row)
+	}
+
+	return results, rows.Err()
+}
+
+// WriteTable writes data in ASCII table format
+func (r *ImportSessionReport) WriteTable(w io.Writer, rows []ImportSessionRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "SESSION_ID\tIMPORTED_AT\tHOST\tDETECTED_AT\tCREATED\tUPDATED\tSKIPPED\tSTATUS\tSIGNATURE\tSOURCE_FILE")
+	fmt.Fprintln(tw, "----------\t-----------\t----\t-----------\t-------\t-------\t-------\t------\t---------\t-----------")
+
+	// Data rows
+	var created, updated, skipped int
+	statuses := make(map[string]int)
+	for _, row := range rows {
+		signature := row.SignatureStatus
+		if signature == "" {
+			signature = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\t%s\t%s\n",
+			row.SessionID,
+			row.ImportedAt,
+			row.Hostname,
+			row.DetectionTimestamp,
+			row.RecordsCreated,
+			row.RecordsUpdated,
+			row.RecordsSkipped,
+			row.Status,
+			signature,
+			row.SourceFile,
+		)
+		created += row.RecordsCreated
+		updated += row.RecordsUpdated
+		skipped += row.RecordsSkipped
+		statuses[row.Status]++
+	}
+
+	// Summary
+	if len(rows) > 0 {
+		fmt.Fprintln(tw, "----------\t-----------\t----\t-----------\t-------\t-------\t-------\t------\t---------\t-----------")
+		fmt.Fprintf(tw, "TOTAL (%d sessions)\t\t\t\t%d\t%d\t%d\t\t\t\n", len(rows), created, updated, skipped)
+		tw.Flush()
+		fmt.Fprintf(w, "\n%d success, %d partial, %d failed\n", statuses["success"], statuses["partial"], statuses["failed"])
+	}
+
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *ImportSessionReport) csvHeader() []string {
+	return []string{
+		"session_id",
+		"imported_at",
+		"source_file",
+		"hostname",
+		"main_fqdn",
+		"detection_timestamp",
+		"records_created",
+		"records_updated",
+		"records_skipped",
+		"status",
+		"error_message",
+		"file_sha256",
+		"signature_status",
+		"signature_signer",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *ImportSessionReport) csvRecord(row ImportSessionRow) []string {
+	return []string{
+		row.SessionID,
+		row.ImportedAt,
+		row.SourceFile,
+		row.Hostname,
+		row.MainFQDN,
+		row.DetectionTimestamp,
+		fmt.Sprintf("%d", row.RecordsCreated),
+		fmt.Sprintf("%d", row.RecordsUpdated),
+		fmt.Sprintf("%d", row.RecordsSkipped),
+		row.Status,
+		row.ErrorMessage,
+		row.FileSHA256,
+		row.SignatureStatus,
+		row.SignatureSigner,
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *ImportSessionReport) WriteCSV(w io.Writer, rows []ImportSessionRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *ImportSessionReport) WriteJSON(w io.Writer, rows []ImportSessionRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with a single sheet
+func (r *ImportSessionReport) WriteXLSX(w io.Writer, rows []ImportSessionRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return singleSheet("Imports", r.csvHeader(), records).Write(w)
+}
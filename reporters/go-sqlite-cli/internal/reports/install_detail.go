@@ -0,0 +1,225 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// InstallDetailRow represents a row from v_install_detail
+type InstallDetailRow struct {
+	HostFQDN           string `json:"host_fqdn"`
+	Date               string `json:"date"`
+	DetectionTimestamp string `json:"detection_timestamp"`
+	ProductCode        string `json:"product_code"`
+	ProductName        string `json:"product_name"`
+	Mode               string `json:"mode"` // mode of the product, empty if unknown
+	Status             string `json:"status"`
+	InstallCount       int    `json:"install_count"`
+	RunningCount       int    `json:"running_count"`
+	EvidenceType       string `json:"evidence_type"` // install or process
+	Seq                int    `json:"seq"`
+	Evidence           string `json:"evidence"` // install path or process command line
+}
+
+// InstallDetailReport generates reports from v_install_detail view
+type InstallDetailReport struct {
+	db *sql.DB
+}
+
+// NewInstallDetailReport creates a new report generator
+func NewInstallDetailReport(db *sql.DB) *InstallDetailReport {
+	return &InstallDetailReport{db: db}
+}
+
+// Query retrieves data from the view with optional filters.
+// When latest is true, only the evidence of the latest measurement of each host is returned.
+// The mode filter applies to the mode of the product.
+func (r *InstallDetailReport) Query(ctx context.Context, hostFilter, productCode, mode string, fromDate, toDate *time.Time, latest bool) ([]InstallDetailRow, error) {
+	query := `
+		SELECT
+			i.host_fqdn,
+			i.date,
+			i.detection_timestamp,
+			i.product_code,
+			i.product_name,
+			COALESCE(p.mode, ''),
+			COALESCE(i.status, ''),
+			COALESCE(i.install_count, 0),
+			COALESCE(i.running_count, 0),
+			i.evidence_type,
+			i.seq,
+			i.evidence
+		FROM v_install_detail i
+		LEFT JOIN product_codes p ON p.product_mnemo_code = i.product_code
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if hostFilter != "" {
+		query += " AND i.host_fqdn LIKE ?"
+		args = append(args, "%"+hostFilter+"%")
+	}
+
+	if productCode != "" {
+		query += " AND i.product_code = ?"
+		args = append(args, productCode)
+	}
+
+	if mode != "" {
+		query += " AND p.mode = ?"
+		args = append(args, mode)
+	}
+
+	if fromDate != nil {
+		query += " AND i.date >= ?"
+		args = append(args, fromDate.Format("2006-01-02"))
+	}
+
+	if toDate != nil {
+		query += " AND i.date <= ?"
+		args = append(args, toDate.Format("2006-01-02"))
+	}
+
+	if latest {
+		query += ` AND i.detection_timestamp = (
+			SELECT MAX(m.detection_timestamp) FROM measurements m WHERE m.main_fqdn = i.host_fqdn
+		)`
+	}
+
+	query += " ORDER BY i.date DESC, i.host_fqdn, i.product_code, i.evidence_type, i.seq"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query install detail: %w", err)
+	}
+	defer rows.Close()
+
+	var results []InstallDetailRow
+	for rows.Next() {
+		var row InstallDetailRow
+
+		err := rows.Scan(
+			&row.HostFQDN,
+			&row.Date,
+			&row.DetectionTimestamp,
+			&row.ProductCode,
+			&row.ProductName,
+			&row.Mode,
+			&row.Status,
+			&row.InstallCount,
+			&row.RunningCount,
+			&row.EvidenceType,
+			&row.Seq,
+			&row.Evidence,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		results = append(results, row)
+	}
+
+	return results, rows.Err()
+}
+
+// WriteTable writes data in ASCII table format
+func (r *InstallDetailReport) WriteTable(w io.Writer, rows []InstallDetailRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "DATE\tHOST\tPRODUCT\tMODE\tSTATUS\tTYPE\t#\tEVIDENCE")
+	fmt.Fprintln(tw, "----\t----\t-------\t----\t------\t----\t-\t--------")
+
+	// Data rows
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			row.Date,
+			row.HostFQDN,
+			row.ProductCode,
+			row.Mode,
+			row.Status,
+			row.EvidenceType,
+			row.Seq,
+			row.Evidence,
+		)
+	}
+
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *InstallDetailReport) csvHeader() []string {
+	return []string{
+		"host_fqdn",
+		"date",
+		"detection_timestamp",
+		"product_code",
+		"product_name",
+		"mode",
+		"status",
+		"install_count",
+		"running_count",
+		"evidence_type",
+		"seq",
+		"evidence",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *InstallDetailReport) csvRecord(row InstallDetailRow) []string {
+	return []string{
+		row.HostFQDN,
+		row.Date,
+		row.DetectionTimestamp,
+		row.ProductCode,
+		row.ProductName,
+		row.Mode,
+		row.Status,
+		fmt.Sprintf("%d", row.InstallCount),
+		fmt.Sprintf("%d", row.RunningCount),
+		row.EvidenceType,
+		fmt.Sprintf("%d", row.Seq),
+		row.Evidence,
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *InstallDetailReport) WriteCSV(w io.Writer, rows []InstallDetailRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *InstallDetailReport) WriteJSON(w io.Writer, rows []InstallDetailRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with one sheet per product
+func (r *InstallDetailReport) WriteXLSX(w io.Writer, rows []InstallDetailRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return sheetsByColumn(r.csvHeader(), records, 3, "Install Detail").Write(w)
+}
@@ -0,0 +1,281 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Instance statuses
+const (
+	InstanceRunning = "running" // running in the latest measurement of the node
+	InstanceDormant = "dormant" // installed but not running in the latest measurement
+	InstanceRemoved = "removed" // no longer reported in the latest measurement
+)
+
+// ParseInstanceStatus validates an --status value, empty meaning the running
+// and dormant instances
+func ParseInstanceStatus(status string) (string, error) {
+	switch status {
+	case "", InstanceRunning, InstanceDormant, InstanceRemoved:
+		return status, nil
+	}
+	return "", fmt.Errorf("invalid status %q: must be %s, %s or %s", status, InstanceRunning, InstanceDormant, InstanceRemoved)
+}
+
+// InstanceRow describes one install of a product on a node
+type InstanceRow struct {
+	MainFQDN       string `json:"main_fqdn"`
+	Hostname       string `json:"hostname"`
+	ProductCode    string `json:"product_code"`
+	ProductName    string `json:"product_name"`
+	Mode           string `json:"mode"` // mode of the product, empty if unknown
+	InstallPath    string `json:"install_path"`
+	Status         string `json:"status"`
+	FirstDetected  string `json:"first_detected"`
+	LastDetected   string `json:"last_detected"`
+	LastRunning    string `json:"last_running"` // empty if never seen running
+	DetectionCount int    `json:"detection_count"`
+	RunningCount   int    `json:"running_count"`
+}
+
+// InstanceReport generates reports from the product_instances table
+type InstanceReport struct {
+	db *sql.DB
+
+	// standby is the standby policy selecting the nodes
+	standby string
+}
+
+// NewInstanceReport creates a new report generator
+func NewInstanceReport(db *sql.DB) *InstanceReport {
+	return &InstanceReport{db: db}
+}
+
+// SetStandbyPolicy selects the standby and DR nodes reported (see ParseStandbyPolicy)
+func (r *InstanceReport) SetStandbyPolicy(policy string) {
+	r.standby = policy
+}
+
+// Query retrieves one row per install path of a product on a node. An instance
+// is removed when the latest measurement of its node no longer reports it, and
+// otherwise running or dormant depending on whether a process of the product
+// ran from it in that measurement. host matches a substring of the main FQDN or
+// hostname, and the mode filter applies to the mode of the product. An empty
+// status lists the running and dormant instances.
+func (r *InstanceReport) Query(ctx context.Context, host, productCode, mode, status string) ([]InstanceRow, error) {
+	query := `
+		SELECT
+			h.main_fqdn,
+			h.hostname,
+			h.product_code,
+			h.product_name,
+			h.mode,
+			h.install_path,
+			h.status,
+			h.first_detected,
+			h.last_detected,
+			h.last_running,
+			h.detection_count,
+			h.running_count
+		FROM (
+			SELECT
+				i.main_fqdn,
+				COALESCE(n.hostname, '') AS hostname,
+				i.product_mnemo_code AS product_code,
+				COALESCE(p.product_name, '') AS product_name,
+				COALESCE(p.mode, '') AS mode,
+				i.install_path,
+				CASE
+					WHEN i.last_detected < (
+						SELECT MAX(m.detection_timestamp) FROM measurements m WHERE m.main_fqdn = i.main_fqdn
+					) THEN 'removed'
+					WHEN i.last_running = i.last_detected THEN 'running'
+					ELSE 'dormant'
+				END AS status,
+				strftime('%Y-%m-%d %H:%M:%S', i.first_detected) AS first_detected,
+				strftime('%Y-%m-%d %H:%M:%S', i.last_detected) AS last_detected,
+				COALESCE(strftime('%Y-%m-%d %H:%M:%S', i.last_running), '') AS last_running,
+				i.detection_count,
+				i.running_count
+			FROM product_instances i
+			LEFT JOIN landscape_nodes n ON n.main_fqdn = i.main_fqdn
+			LEFT JOIN product_codes p ON p.product_mnemo_code = i.product_mnemo_code
+		) h
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if host != "" {
+		query += " AND (h.main_fqdn LIKE ? OR h.hostname LIKE ?)"
+		args = append(args, "%"+host+"%", "%"+host+"%")
+	}
+
+	if productCode != "" {
+		query += " AND h.product_code = ?"
+		args = append(args, productCode)
+	}
+
+	if mode != "" {
+		query += " AND h.mode = ?"
+		args = append(args, mode)
+	}
+
+	if status != "" {
+		query += " AND h.status = ?"
+		args = append(args, status)
+	} else {
+		query += " AND h.status <> 'removed'"
+	}
+
+	query += standbyCondition("h.main_fqdn", r.standby)
+
+	query += " ORDER BY h.main_fqdn, h.product_code, h.install_path"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product instances: %w", err)
+	}
+	defer rows.Close()
+
+	var results []InstanceRow
+	for rows.Next() {
+		var row InstanceRow
+
+		err := rows.Scan(
+			&row.MainFQDN,
+			&row.Hostname,
+			&row.ProductCode,
+			&row.ProductName,
+			&row.Mode,
+			&row.InstallPath,
+			&row.Status,
+			&row.FirstDetected,
+			&row.LastDetected,
+			&row.LastRunning,
+			&row.DetectionCount,
+			&row.RunningCount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		results = append(results, row)
+	}
+
+	return results, rows.Err()
+}
+
+// WriteTable writes data in ASCII table format
+func (r *InstanceReport) WriteTable(w io.Writer, rows []InstanceRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "HOST\tPRODUCT\tMODE\tINSTALL_PATH\tSTATUS\tFIRST_DETECTED\tLAST_RUNNING\tRUNNING_IN")
+	fmt.Fprintln(tw, "----\t-------\t----\t------------\t------\t--------------\t------------\t----------")
+
+	// Data rows
+	counts := map[string]int{}
+	for _, row := range rows {
+		lastRunning := row.LastRunning
+		if lastRunning == "" {
+			lastRunning = "never"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d/%d\n",
+			row.MainFQDN,
+			row.ProductCode,
+			row.Mode,
+			row.InstallPath,
+			row.Status,
+			row.FirstDetected,
+			lastRunning,
+			row.RunningCount,
+			row.DetectionCount,
+		)
+		counts[row.Status]++
+	}
+
+	// Summary
+	if len(rows) > 0 {
+		tw.Flush()
+		fmt.Fprintf(w, "\n%d instances: %d running, %d dormant, %d removed\n",
+			len(rows), counts[InstanceRunning], counts[InstanceDormant], counts[InstanceRemoved])
+	}
+
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *InstanceReport) csvHeader() []string {
+	return []string{
+		"main_fqdn",
+		"hostname",
+		"product_code",
+		"product_name",
+		"mode",
+		"install_path",
+		"status",
+		"first_detected",
+		"last_detected",
+		"last_running",
+		"detection_count",
+		"running_count",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *InstanceReport) csvRecord(row InstanceRow) []string {
+	return []string{
+		row.MainFQDN,
+		row.Hostname,
+		row.ProductCode,
+		row.ProductName,
+		row.Mode,
+		row.InstallPath,
+		row.Status,
+		row.FirstDetected,
+		row.LastDetected,
+		row.LastRunning,
+		fmt.Sprintf("%d", row.DetectionCount),
+		fmt.Sprintf("%d", row.RunningCount),
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *InstanceReport) WriteCSV(w io.Writer, rows []InstanceRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *InstanceReport) WriteJSON(w io.Writer, rows []InstanceRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with a single sheet
+func (r *InstanceReport) WriteXLSX(w io.Writer, rows []InstanceRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return singleSheet("Instances", r.csvHeader(), records).Write(w)
+}
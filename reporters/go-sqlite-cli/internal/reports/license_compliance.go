@@ -1,12 +1,12 @@
 package reports
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
-	"text/tabwriter"
 	"time"
 )
 
@@ -19,6 +19,7 @@ type ComplianceRow struct {
 	TermID                 string    `json:"term_id"`
 	ProgramNumber          string    `json:"program_number"`
 	ProgramName            string    `json:"program_name"`
+	TermsDocument          string    `json:"terms_document"` // term document in force on the date
 	TotalNodes             int       `json:"total_nodes"`
 	RunningNodes           int       `json:"running_nodes"`
 	TotalInstallations     int       `json:"total_installations"`
@@ -29,11 +30,64 @@ type ComplianceRow struct {
 	UniquePhysicalHosts    int       `json:"unique_physical_hosts"`
 	VirtualizedNodes       int       `json:"virtualized_nodes"`
 	PhysicalNodes          int       `json:"physical_nodes"`
+	// Entitlement gap analysis (entitlements are per license term, so the
+	// usage compared against them is the sum over all products of the term)
+	LicenseCores           int       `json:"license_cores"`
+	TermLicenseCores       int       `json:"term_license_cores"`
+	LicensedCores          *int      `json:"licensed_cores"`
+	LicensedPVU            *int      `json:"licensed_pvu"`
+	ComplianceDelta        *int      `json:"compliance_delta"`
+	// PVU usage (license cores times the PVU per core of each node's processor);
+	// nodes without a PVU mapping count 0 PVUs
+	LicensePVU             int       `json:"license_pvu"`
+	TermLicensePVU         int       `json:"term_license_pvu"`
+	UnmappedPVUNodes       int       `json:"unmapped_pvu_nodes"`
+	PVUDelta               *int      `json:"pvu_delta"`
+	ComplianceStatus       string    `json:"compliance_status"`
+	// Product threshold (see 'iwdlr import thresholds'): license cores the
+	// product may use within the entitlement of its term
+	ThresholdCores         *int      `json:"threshold_cores"`
+	ThresholdDelta         *int      `json:"threshold_delta"`
+	// Breach is set when the term is under-licensed or the product uses more
+	// license cores than its threshold
+	Breach                 bool      `json:"breach"`
+	// Nodes whose last measurement was carried forward to the date (see
+	// SetCarryForwardDays)
+	CarriedForwardNodes    int       `json:"carried_forward_nodes"`
+}
+
+// Compliance status values derived from entitlement vs usage
+const (
+	StatusOverLicensed  = "over-licensed"
+	StatusAtLimit       = "at-limit"
+	StatusUnderLicensed = "under-licensed"
+	StatusNoEntitlement = "no-entitlement"
+)
+
+// complianceStatus classifies the deltas (licensed minus used cores or PVUs)
+// of a term; the worst delta that is set wins
+func complianceStatus(deltas ...*int) string {
+	status := StatusNoEntitlement
+	for _, delta := range deltas {
+		switch {
+		case delta == nil:
+		case *delta < 0:
+			return StatusUnderLicensed
+		case *delta == 0:
+			status = StatusAtLimit
+		case status == StatusNoEntitlement:
+			status = StatusOverLicensed
+		}
+	}
+	return status
 }
 
 // ComplianceReport generates reports from v_license_compliance_report view
 type ComplianceReport struct {
 	db *sql.DB
+
+	// carryForwardDays is the most days a measurement is carried forward
+	carryForwardDays int
 }
 
 // NewComplianceReport creates a new report generator
@@ -41,53 +95,85 @@ func NewComplianceReport(db *sql.DB) *ComplianceReport {
 	return &ComplianceReport{db: db}
 }
 
-// Query retrieves data from the view with optional filters
-func (r *ComplianceReport) Query(productCode string, fromDate, toDate *time.Time, nonCompliantOnly bool) ([]ComplianceRow, error) {
-	query := `
+// Query retrieves data from the view with optional filters. With
+// nonCompliantOnly, only the rows flagged as a breach are returned. When the
+// report carries measurements forward, the rows are computed from
+// v_carry_forward_measurements instead, with the same columns.
+func (r *ComplianceReport) Query(ctx context.Context, productCode, mode string, fromDate, toDate *time.Time, nonCompliantOnly bool) ([]ComplianceRow, error) {
+	source, carriedNodes, with := "v_license_compliance_report", "0", ""
+	args := []interface{}{}
+	if r.carryForwardDays > 0 {
+		source, carriedNodes, with = "carried_compliance", "c.carried_nodes", "WITH"+carriedComplianceCTE
+		args = append(args, r.carryForwardDays)
+	}
+
+	query := with + `
 		SELECT 
-			measurement_date,
-			product_mnemo_code,
-			product_name,
-			mode,
-			term_id,
-			program_number,
-			program_name,
-			total_nodes,
-			running_nodes,
-			total_installations,
-			total_vm_cores,
-			total_license_cores_raw,
-			eligible_cores_sum,
-			ineligible_cores_sum,
-			unique_physical_hosts,
-			virtualized_nodes,
-			physical_nodes
-		FROM v_license_compliance_report
+			c.measurement_date,
+			c.product_mnemo_code,
+			c.product_name,
+			c.mode,
+			c.term_id,
+			c.program_number,
+			c.program_name,
+			` + termDocumentColumn("c.term_id", "c.measurement_date") + `,
+			c.total_nodes,
+			c.running_nodes,
+			c.total_installations,
+			c.total_vm_cores,
+			c.total_license_cores_raw,
+			c.eligible_cores_sum,
+			c.ineligible_cores_sum,
+			c.unique_physical_hosts,
+			c.virtualized_nodes,
+			c.physical_nodes,
+			c.license_cores,
+			(SELECT SUM(c2.license_cores)
+			 FROM ` + source + ` c2
+			 WHERE c2.term_id = c.term_id
+			   AND c2.measurement_date = c.measurement_date) as term_license_cores,
+			e.licensed_cores,
+			e.licensed_pvu,
+			COALESCE(pv.running_license_pvu, 0),
+			(SELECT COALESCE(SUM(pv2.running_license_pvu), 0)
+			 FROM v_daily_license_pvu pv2
+			 JOIN product_codes p2 ON pv2.product_mnemo_code = p2.product_mnemo_code
+			 WHERE ` + productTermColumn("p2", "pv2.measurement_date") + ` = c.term_id
+			   AND pv2.measurement_date = c.measurement_date) as term_license_pvu,
+			COALESCE(pv.unmapped_nodes, 0),
+			t.max_license_cores,
+			` + carriedNodes + `
+		FROM ` + source + ` c
+		LEFT JOIN entitlements e ON c.term_id = e.term_id
+		LEFT JOIN product_thresholds t ON c.product_mnemo_code = t.product_mnemo_code
+		LEFT JOIN v_daily_license_pvu pv ON c.measurement_date = pv.measurement_date
+			AND c.product_mnemo_code = pv.product_mnemo_code
 		WHERE 1=1
 	`
 	
-	args := []interface{}{}
-	
 	if productCode != "" {
-		query += " AND product_mnemo_code = ?"
+		query += " AND c.product_mnemo_code = ?"
 		args = append(args, productCode)
 	}
 	
+	if mode != "" {
+		query += " AND c.mode = ?"
+		args = append(args, mode)
+	}
+	
 	if fromDate != nil {
-		query += " AND measurement_date >= ?"
+		query += " AND c.measurement_date >= ?"
 		args = append(args, fromDate.Format("2006-01-02"))
 	}
 	
 	if toDate != nil {
-		query += " AND measurement_date <= ?"
+		query += " AND c.measurement_date <= ?"
 		args = append(args, toDate.Format("2006-01-02"))
 	}
 	
-	// Note: nonCompliantOnly filter removed as view doesn't have compliance_status
+	query += " ORDER BY c.measurement_date DESC, c.product_mnemo_code"
 	
-	query += " ORDER BY measurement_date DESC, product_mnemo_code"
-	
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query compliance: %w", err)
 	}
@@ -97,6 +183,7 @@ func (r *ComplianceReport) Query(productCode string, fromDate, toDate *time.Time
 	for rows.Next() {
 		var row ComplianceRow
 		var dateStr string
+		var licensedCores, licensedPVU, thresholdCores sql.NullInt64
 		
 		err := rows.Scan(
 			&dateStr,
@@ -106,6 +193,7 @@ func (r *ComplianceReport) Query(productCode string, fromDate, toDate *time.Time
 			&row.TermID,
 			&row.ProgramNumber,
 			&row.ProgramName,
+			&row.TermsDocument,
 			&row.TotalNodes,
 			&row.RunningNodes,
 			&row.TotalInstallations,
@@ -116,11 +204,51 @@ func (r *ComplianceReport) Query(productCode string, fromDate, toDate *time.Time
 			&row.UniquePhysicalHosts,
 			&row.VirtualizedNodes,
 			&row.PhysicalNodes,
+			&row.LicenseCores,
+			&row.TermLicenseCores,
+			&licensedCores,
+			&licensedPVU,
+			&row.LicensePVU,
+			&row.TermLicensePVU,
+			&row.UnmappedPVUNodes,
+			&thresholdCores,
+			&row.CarriedForwardNodes,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 		
+		// Compute gap against entitlement (positive = spare, negative = shortfall).
+		// A term entitled in PVUs only has no core delta.
+		if licensedCores.Valid {
+			licensed := int(licensedCores.Int64)
+			pvu := int(licensedPVU.Int64)
+			row.LicensedCores = &licensed
+			row.LicensedPVU = &pvu
+			if licensed > 0 || pvu == 0 {
+				delta := licensed - row.TermLicenseCores
+				row.ComplianceDelta = &delta
+			}
+			if pvu > 0 {
+				pvuDelta := pvu - row.TermLicensePVU
+				row.PVUDelta = &pvuDelta
+			}
+		}
+		row.ComplianceStatus = complianceStatus(row.ComplianceDelta, row.PVUDelta)
+		
+		// Gap against the product threshold (negative = over the threshold)
+		if thresholdCores.Valid {
+			threshold := int(thresholdCores.Int64)
+			thresholdDelta := threshold - row.LicenseCores
+			row.ThresholdCores = &threshold
+			row.ThresholdDelta = &thresholdDelta
+		}
+		row.Breach = row.ComplianceStatus == StatusUnderLicensed ||
+			(row.ThresholdDelta != nil && *row.ThresholdDelta < 0)
+		if nonCompliantOnly && !row.Breach {
+			continue
+		}
+		
 		// Parse date
 		row.MeasurementDate, err = time.Parse("2006-01-02", dateStr)
 		if err != nil {
@@ -135,16 +263,32 @@ func (r *ComplianceReport) Query(productCode string, fromDate, toDate *time.Time
 
 // WriteTable writes data in ASCII table format
 func (r *ComplianceReport) WriteTable(w io.Writer, rows []ComplianceRow) error {
-	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	tw := newTableWriter(w)
 	defer tw.Flush()
 	
 	// Header
-	fmt.Fprintln(tw, "DATE\tPRODUCT\tMODE\tPROGRAM\tNODES\tRUN\tINST\tVM_CORES\tELIG\tINELIG")
-	fmt.Fprintln(tw, "----\t-------\t----\t-------\t-----\t---\t----\t--------\t----\t------")
+	fmt.Fprintln(tw, "DATE\tPRODUCT\tMODE\tPROGRAM\tNODES\tRUN\tINST\tVM_CORES\tELIG\tINELIG\tLIC_CORES\tTERM_CORES\tENTITLED\tDELTA\tTERM_PVU\tPVU_DELTA\tTHRESHOLD\tSTATUS\tBREACH")
+	fmt.Fprintln(tw, "----\t-------\t----\t-------\t-----\t---\t----\t--------\t----\t------\t---------\t----------\t--------\t-----\t--------\t---------\t---------\t------\t------")
 	
 	// Data rows
+	unmapped := false
+	carried := false
 	for _, row := range rows {
-		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\n",
+		licenseCores := fmt.Sprintf("%d", row.LicenseCores)
+		if row.CarriedForwardNodes > 0 {
+			licenseCores += "+"
+			carried = true
+		}
+		termPVU := fmt.Sprintf("%d", row.TermLicensePVU)
+		if row.UnmappedPVUNodes > 0 {
+			termPVU += "*"
+			unmapped = true
+		}
+		breach := ""
+		if row.Breach {
+			breach = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 			row.MeasurementDate.Format("2006-01-02"),
 			row.ProductMnemoCode,
 			row.Mode,
@@ -155,6 +299,15 @@ func (r *ComplianceReport) WriteTable(w io.Writer, rows []ComplianceRow) error {
 			row.TotalVMCores,
 			row.EligibleCoresSum,
 			row.IneligibleCoresSum,
+			licenseCores,
+			row.TermLicenseCores,
+			formatOptionalInt(row.LicensedCores, "N/A"),
+			formatOptionalInt(row.ComplianceDelta, "N/A"),
+			termPVU,
+			formatOptionalInt(row.PVUDelta, "N/A"),
+			formatOptionalInt(row.ThresholdCores, "N/A"),
+			row.ComplianceStatus,
+			breach,
 		)
 	}
 	
@@ -164,27 +317,52 @@ func (r *ComplianceReport) WriteTable(w io.Writer, rows []ComplianceRow) error {
 		totalVM := 0
 		totalElig := 0
 		totalInelig := 0
+		totalLicense := 0
+		underLicensed := 0
+		breaches := 0
+		subtotals := newModeTotals()
 		for _, row := range rows {
 			totalNodes += row.TotalNodes
 			totalVM += row.TotalVMCores
 			totalElig += row.EligibleCoresSum
 			totalInelig += row.IneligibleCoresSum
+			totalLicense += row.LicenseCores
+			under := 0
+			if row.ComplianceStatus == StatusUnderLicensed {
+				underLicensed++
+				under = 1
+			}
+			breach := 0
+			if row.Breach {
+				breaches++
+				breach = 1
+			}
+			subtotals.add(row.Mode, row.TotalNodes, row.TotalVMCores, row.EligibleCoresSum, row.IneligibleCoresSum, row.LicenseCores, under, breach)
 		}
 		
-		fmt.Fprintln(tw, "----\t-------\t----\t-------\t-----\t---\t----\t--------\t----\t------")
-		fmt.Fprintf(tw, "TOTAL\t\t\t\t%d\t\t\t%d\t%d\t%d\n", totalNodes, totalVM, totalElig, totalInelig)
+		fmt.Fprintln(tw, "----\t-------\t----\t-------\t-----\t---\t----\t--------\t----\t------\t---------\t----------\t--------\t-----\t--------\t---------\t---------\t------\t------")
+		subtotals.each(func(mode string, sums []int) {
+			fmt.Fprintf(tw, "\t\t%s\t\t%d\t\t\t%d\t%d\t%d\t%d\t\t\t\t\t\t\t%d under-licensed\t%d\n",
+				mode, sums[0], sums[1], sums[2], sums[3], sums[4], sums[5], sums[6])
+		})
+		fmt.Fprintf(tw, "TOTAL\t\t\t\t%d\t\t\t%d\t%d\t%d\t%d\t\t\t\t\t\t\t%d under-licensed\t%d\n",
+			totalNodes, totalVM, totalElig, totalInelig, totalLicense, underLicensed, breaches)
 	}
 	
-	return nil
+	if unmapped {
+		fmt.Fprintln(tw, "\n* Some nodes have no PVU mapping for their processor and count 0 PVUs (see 'iwdlr import pvu')")
+	}
+	
+	if carried {
+		fmt.Fprintf(tw, "\n+ Counts nodes that missed up to %d day(s) of reporting with their last measurement (--carry-forward)\n", r.carryForwardDays)
+	}
+	
+	return writeDailyFootnotes(tw, r.db)
 }
 
-// WriteCSV writes data in CSV format
-func (r *ComplianceReport) WriteCSV(w io.Writer, rows []ComplianceRow) error {
-	writer := csv.NewWriter(w)
-	defer writer.Flush()
-	
-	// Header
-	err := writer.Write([]string{
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *ComplianceReport) csvHeader() []string {
+	return []string{
 		"measurement_date",
 		"product_mnemo_code",
 		"product_name",
@@ -192,6 +370,7 @@ func (r *ComplianceReport) WriteCSV(w io.Writer, rows []ComplianceRow) error {
 		"term_id",
 		"program_number",
 		"program_name",
+		"terms_document",
 		"total_nodes",
 		"running_nodes",
 		"total_installations",
@@ -202,33 +381,72 @@ func (r *ComplianceReport) WriteCSV(w io.Writer, rows []ComplianceRow) error {
 		"unique_physical_hosts",
 		"virtualized_nodes",
 		"physical_nodes",
-	})
-	if err != nil {
+		"license_cores",
+		"term_license_cores",
+		"licensed_cores",
+		"licensed_pvu",
+		"compliance_delta",
+		"license_pvu",
+		"term_license_pvu",
+		"unmapped_pvu_nodes",
+		"pvu_delta",
+		"compliance_status",
+		"threshold_cores",
+		"threshold_delta",
+		"breach",
+		"carried_forward_nodes",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *ComplianceReport) csvRecord(row ComplianceRow) []string {
+	return []string{
+		row.MeasurementDate.Format("2006-01-02"),
+		row.ProductMnemoCode,
+		row.ProductName,
+		row.Mode,
+		row.TermID,
+		row.ProgramNumber,
+		row.ProgramName,
+		row.TermsDocument,
+		fmt.Sprintf("%d", row.TotalNodes),
+		fmt.Sprintf("%d", row.RunningNodes),
+		fmt.Sprintf("%d", row.TotalInstallations),
+		fmt.Sprintf("%d", row.TotalVMCores),
+		fmt.Sprintf("%d", row.TotalLicenseCoresRaw),
+		fmt.Sprintf("%d", row.EligibleCoresSum),
+		fmt.Sprintf("%d", row.IneligibleCoresSum),
+		fmt.Sprintf("%d", row.UniquePhysicalHosts),
+		fmt.Sprintf("%d", row.VirtualizedNodes),
+		fmt.Sprintf("%d", row.PhysicalNodes),
+		fmt.Sprintf("%d", row.LicenseCores),
+		fmt.Sprintf("%d", row.TermLicenseCores),
+		formatOptionalInt(row.LicensedCores, ""),
+		formatOptionalInt(row.LicensedPVU, ""),
+		formatOptionalInt(row.ComplianceDelta, ""),
+		fmt.Sprintf("%d", row.LicensePVU),
+		fmt.Sprintf("%d", row.TermLicensePVU),
+		fmt.Sprintf("%d", row.UnmappedPVUNodes),
+		formatOptionalInt(row.PVUDelta, ""),
+		row.ComplianceStatus,
+		formatOptionalInt(row.ThresholdCores, ""),
+		formatOptionalInt(row.ThresholdDelta, ""),
+		fmt.Sprintf("%t", row.Breach),
+		fmt.Sprintf("%d", row.CarriedForwardNodes),
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *ComplianceReport) WriteCSV(w io.Writer, rows []ComplianceRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+	
+	if err := writer.Write(r.csvHeader()); err != nil {
 		return err
 	}
 	
-	// Data rows
 	for _, row := range rows {
-		err := writer.Write([]string{
-			row.MeasurementDate.Format("2006-01-02"),
-			row.ProductMnemoCode,
-			row.ProductName,
-			row.Mode,
-			row.TermID,
-			row.ProgramNumber,
-			row.ProgramName,
-			fmt.Sprintf("%d", row.TotalNodes),
-			fmt.Sprintf("%d", row.RunningNodes),
-			fmt.Sprintf("%d", row.TotalInstallations),
-			fmt.Sprintf("%d", row.TotalVMCores),
-			fmt.Sprintf("%d", row.TotalLicenseCoresRaw),
-			fmt.Sprintf("%d", row.EligibleCoresSum),
-			fmt.Sprintf("%d", row.IneligibleCoresSum),
-			fmt.Sprintf("%d", row.UniquePhysicalHosts),
-			fmt.Sprintf("%d", row.VirtualizedNodes),
-			fmt.Sprintf("%d", row.PhysicalNodes),
-		})
-		if err != nil {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
 			return err
 		}
 	}
@@ -236,9 +454,26 @@ func (r *ComplianceReport) WriteCSV(w io.Writer, rows []ComplianceRow) error {
 	return nil
 }
 
+// WriteXLSX writes an Excel workbook with one sheet per product
+func (r *ComplianceReport) WriteXLSX(w io.Writer, rows []ComplianceRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return sheetsByColumn(r.csvHeader(), records, 1, "Compliance").Write(w)
+}
+
 // WriteJSON writes data in JSON format
 func (r *ComplianceReport) WriteJSON(w io.Writer, rows []ComplianceRow) error {
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
 	return encoder.Encode(rows)
 }
+
+// formatOptionalInt renders an optional integer, using fallback when unset
+func formatOptionalInt(v *int, fallback string) string {
+	if v == nil {
+		return fallback
+	}
+	return fmt.Sprintf("%d", *v)
+}
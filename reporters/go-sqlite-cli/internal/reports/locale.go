@@ -0,0 +1,220 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// TableFormat is how the table output writes numbers and dates: the thousands
+// and decimal separators of a locale and a date layout. A zero TableFormat
+// keeps the report tables as they are.
+type TableFormat struct {
+	Thousands  string // grouping separator of the integer digits, none when empty
+	Decimal    string // decimal separator, "." when empty
+	DateLayout string // Go time layout of the dates, 2006-01-02 when empty
+}
+
+// locales are the table formats of --locale, by lower case language or
+// language-region tag
+var locales = map[string]TableFormat{
+	"en":    {Thousands: ",", Decimal: ".", DateLayout: "01/02/2006"},
+	"en-gb": {Thousands: ",", Decimal: ".", DateLayout: "02/01/2006"},
+	"en-ie": {Thousands: ",", Decimal: ".", DateLayout: "02/01/2006"},
+	"de":    {Thousands: ".", Decimal: ",", DateLayout: "02.01.2006"},
+	"de-ch": {Thousands: "'", Decimal: ".", DateLayout: "02.01.2006"},
+	"fr":    {Thousands: " ", Decimal: ",", DateLayout: "02/01/2006"},
+	"fr-ch": {Thousands: " ", Decimal: ",", DateLayout: "02.01.2006"},
+	"it":    {Thousands: ".", Decimal: ",", DateLayout: "02/01/2006"},
+	"es":    {Thousands: ".", Decimal: ",", DateLayout: "02/01/2006"},
+	"pt":    {Thousands: ".", Decimal: ",", DateLayout: "02/01/2006"},
+	"nl":    {Thousands: ".", Decimal: ",", DateLayout: "02-01-2006"},
+	"pl":    {Thousands: " ", Decimal: ",", DateLayout: "02.01.2006"},
+	"sv":    {Thousands: " ", Decimal: ",", DateLayout: "2006-01-02"},
+	"iso":   {Thousands: "", Decimal: ".", DateLayout: "2006-01-02"},
+}
+
+// LookupLocale returns the table format of a locale such as de, de-DE or
+// de_DE.UTF-8. A region without its own format takes that of its language.
+func LookupLocale(name string) (TableFormat, error) {
+	tag := strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+	if i := strings.IndexAny(tag, ".@"); i >= 0 {
+		tag = tag[:i]
+	}
+	if format, ok := locales[tag]; ok {
+		return format, nil
+	}
+	language, _, _ := strings.Cut(tag, "-")
+	if format, ok := locales[language]; ok {
+		return format, nil
+	}
+
+	names := make([]string, 0, len(locales))
+	for name := range locales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return TableFormat{}, fmt.Errorf("unknown locale %q (known: %s)", name, strings.Join(names, ", "))
+}
+
+// ParseDateFormat converts a date format made of YYYY, YY, MM and DD and
+// separators, such as DD.MM.YYYY, to a Go time layout
+func ParseDateFormat(format string) (string, error) {
+	var layout strings.Builder
+	var year, month, day bool
+	for rest := format; rest != ""; {
+		switch {
+		case strings.HasPrefix(rest, "YYYY"):
+			layout.WriteString("2006")
+			rest, year = rest[4:], true
+		case strings.HasPrefix(rest, "YY"):
+			layout.WriteString("06")
+			rest, year = rest[2:], true
+		case strings.HasPrefix(rest, "MM"):
+			layout.WriteString("01")
+			rest, month = rest[2:], true
+		case strings.HasPrefix(rest, "DD"):
+			layout.WriteString("02")
+			rest, day = rest[2:], true
+		case strings.ContainsAny(rest[:1], "./- "):
+			layout.WriteString(rest[:1])
+			rest = rest[1:]
+		default:
+			return "", fmt.Errorf("invalid date format %q: use YYYY, YY, MM and DD separated by '.', '/', '-' or ' '", format)
+		}
+	}
+	if !year || !month || !day {
+		return "", fmt.Errorf("invalid date format %q: the year, month and day are required", format)
+	}
+	return layout.String(), nil
+}
+
+var (
+	numberCell = regexp.MustCompile(`^([-+]?)([0-9]+)(\.[0-9]+)?([%*+]?)$`)
+	// dateCell is a date with an optional time, after an optional label such
+	// as "DATE: "
+	dateCell = regexp.MustCompile(`^([A-Za-z ]+: )?([0-9]{4}-[0-9]{2}-[0-9]{2})((?: [0-9]{2}:[0-9]{2}(?::[0-9]{2})?)?)$`)
+)
+
+// IsZero reports whether the format keeps the report tables as they are
+func (f TableFormat) IsZero() bool {
+	return f == TableFormat{}
+}
+
+// FormatCell formats a table cell holding only a number, or a date, with the
+// separators and date layout of f; other cells are returned as they are
+func (f TableFormat) FormatCell(cell string) string {
+	if m := numberCell.FindStringSubmatch(cell); m != nil {
+		digits := m[2]
+		if f.Thousands != "" {
+			var grouped strings.Builder
+			for i, digit := range digits {
+				if i > 0 && (len(digits)-i)%3 == 0 {
+					grouped.WriteString(f.Thousands)
+				}
+				grouped.WriteRune(digit)
+			}
+			digits = grouped.String()
+		}
+		fraction := m[3]
+		if fraction != "" && f.Decimal != "" {
+			fraction = f.Decimal + fraction[1:]
+		}
+		return m[1] + digits + fraction + m[4]
+	}
+
+	if m := dateCell.FindStringSubmatch(cell); m != nil && f.DateLayout != "" {
+		date, err := time.Parse("2006-01-02", m[2])
+		if err != nil {
+			return cell
+		}
+		return m[1] + date.Format(f.DateLayout) + m[3]
+	}
+	return cell
+}
+
+// NewFormattedWriter returns a writer for the WriteTable methods of the
+// reports, whose tables then format their numbers and dates with f
+func NewFormattedWriter(w io.Writer, f TableFormat) io.Writer {
+	if f.IsZero() {
+		return w
+	}
+	return &formattedWriter{Writer: w, format: f}
+}
+
+// formattedWriter carries the table format of the output to newTableWriter
+type formattedWriter struct {
+	io.Writer
+	format TableFormat
+}
+
+// tableWriter aligns the tab-separated cells of report tables, formatting
+// each cell before it is aligned
+type tableWriter struct {
+	tw      *tabwriter.Writer
+	format  TableFormat
+	pending []byte // last line, until its newline is written
+}
+
+// newTableWriter creates the writer of a report table on w, formatting its
+// cells with the table format of w when it comes from NewFormattedWriter
+func newTableWriter(w io.Writer) *tableWriter {
+	t := &tableWriter{}
+	if fw, ok := w.(*formattedWriter); ok {
+		w, t.format = fw.Writer, fw.format
+	}
+	t.tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	return t
+}
+
+func (t *tableWriter) Write(p []byte) (int, error) {
+	if t.format.IsZero() {
+		return t.tw.Write(p)
+	}
+
+	t.pending = append(t.pending, p...)
+	end := bytes.LastIndexByte(t.pending, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+	if err := t.writeLines(t.pending[:end+1]); err != nil {
+		return 0, err
+	}
+	t.pending = append(t.pending[:0], t.pending[end+1:]...)
+	return len(p), nil
+}
+
+// writeLines formats the cells of complete lines and passes them on
+func (t *tableWriter) writeLines(lines []byte) error {
+	for _, line := range strings.SplitAfter(string(lines), "\n") {
+		if line == "" {
+			continue
+		}
+		text := strings.TrimSuffix(line, "\n")
+		cells := strings.Split(text, "\t")
+		for i, cell := range cells {
+			cells[i] = t.format.FormatCell(cell)
+		}
+		if _, err := io.WriteString(t.tw, strings.Join(cells, "\t")+line[len(text):]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes a last line without newline and aligns the table
+func (t *tableWriter) Flush() error {
+	if len(t.pending) > 0 {
+		pending := t.pending
+		t.pending = nil
+		if err := t.writeLines(pending); err != nil {
+			return err
+		}
+	}
+	return t.tw.Flush()
+}
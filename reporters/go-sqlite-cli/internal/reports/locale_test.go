@@ -0,0 +1,64 @@
+package reports_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestTableFormat(t *testing.T) {
+	de, err := reports.LookupLocale("de_DE.UTF-8")
+	if err != nil {
+		t.Fatalf("LookupLocale failed: %v", err)
+	}
+	tests := map[string]string{
+		"1234567":             "1.234.567",
+		"-1500":               "-1.500",
+		"+42":                 "+42",
+		"33.3%":               "33,3%",
+		"2048*":               "2.048*",
+		"2025-10-21":          "21.10.2025",
+		"2025-10-21 09:09:06": "21.10.2025 09:09:06",
+		"DATE: 2025-10-21":    "DATE: 21.10.2025",
+		"5900-BGP":            "5900-BGP",
+		"AIX 7.200":           "AIX 7.200",
+		"2025-10":             "2025-10",
+	}
+	for cell, want := range tests {
+		if got := de.FormatCell(cell); got != want {
+			t.Errorf("%q: expected %q, got %q", cell, want, got)
+		}
+	}
+
+	if ch, _ := reports.LookupLocale("de-CH"); ch.FormatCell("1234.5") != "1'234.5" {
+		t.Errorf("Expected Swiss grouping, got %q", ch.FormatCell("1234.5"))
+	}
+	if at, _ := reports.LookupLocale("de-AT"); at != de {
+		t.Errorf("Expected de-AT to fall back to de, got %+v", at)
+	}
+	if _, err := reports.LookupLocale("tlh"); err == nil {
+		t.Error("Expected an error for an unknown locale")
+	}
+
+	layout, err := reports.ParseDateFormat("DD/MM/YYYY")
+	if err != nil || layout != "02/01/2006" {
+		t.Errorf("Expected layout 02/01/2006, got %q (%v)", layout, err)
+	}
+	for _, format := range []string{"DD.MM", "YYYY-MM-DDTHH", "D.M.YYYY"} {
+		if _, err := reports.ParseDateFormat(format); err == nil {
+			t.Errorf("Expected an error for date format %q", format)
+		}
+	}
+
+	// Cells are formatted before they are aligned
+	var out bytes.Buffer
+	data := []byte("date,license_cores\n2025-10-21,1234\n2025-10-22,16\n")
+	if err := (reports.ColumnLayout{}).WriteTable(reports.NewFormattedWriter(&out, de), data); err != nil {
+		t.Fatalf("WriteTable failed: %v", err)
+	}
+	want := "DATE        LICENSE_CORES\n----        -------------\n21.10.2025  1.234\n22.10.2025  16\n"
+	if out.String() != want {
+		t.Errorf("Expected table\n%s\ngot\n%s", want, out.String())
+	}
+}
@@ -0,0 +1,68 @@
+package reports
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Modes (environments) of products and landscape nodes
+const (
+	ModeProd    = "PROD"
+	ModeNonProd = "NON PROD"
+)
+
+// ParseMode normalizes a mode filter. PROD and NON PROD are accepted case-insensitively,
+// NONPROD, NON-PROD, NON_PROD and NPR are accepted as NON PROD. An empty value means no filter.
+func ParseMode(value string) (string, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(value))
+	switch normalized {
+	case "":
+		return "", nil
+	case ModeProd, "PRD":
+		return ModeProd, nil
+	case ModeNonProd, "NONPROD", "NON-PROD", "NON_PROD", "NPR":
+		return ModeNonProd, nil
+	}
+	return "", fmt.Errorf("invalid mode %q (expected PROD or NON PROD)", value)
+}
+
+// modeTotals accumulates integer columns per mode for the subtotal lines of table outputs.
+// The CSV, JSON and XLSX outputs have no subtotal rows: they hold one record per
+// row with its mode, so that row counts, column selection and templates stay
+// valid, and a spreadsheet or script groups the rows on the mode column.
+type modeTotals struct {
+	modes []string
+	sums  map[string][]int
+}
+
+// newModeTotals creates an empty accumulator
+func newModeTotals() *modeTotals {
+	return &modeTotals{sums: make(map[string][]int)}
+}
+
+// add adds the values of one row to the subtotals of its mode
+func (t *modeTotals) add(mode string, values ...int) {
+	if mode == "" {
+		mode = "UNKNOWN"
+	}
+	sums, ok := t.sums[mode]
+	if !ok {
+		t.modes = append(t.modes, mode)
+		sums = make([]int, len(values))
+	}
+	for i, value := range values {
+		sums[i] += value
+	}
+	t.sums[mode] = sums
+}
+
+// each calls fn with the subtotals of every mode in order of first appearance.
+// Nothing is called when all rows have the same mode, as the subtotal would repeat the total.
+func (t *modeTotals) each(fn func(mode string, sums []int)) {
+	if len(t.modes) < 2 {
+		return
+	}
+	for _, mode := range t.modes {
+		fn(mode, t.sums[mode])
+	}
+}
@@ -0,0 +1,78 @@
+package reports
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"  ", "", false},
+		{"PROD", ModeProd, false},
+		{"prod", ModeProd, false},
+		{" Prod ", ModeProd, false},
+		{"PRD", ModeProd, false},
+		{"NON PROD", ModeNonProd, false},
+		{"non prod", ModeNonProd, false},
+		{"NONPROD", ModeNonProd, false},
+		{"non-prod", ModeNonProd, false},
+		{"NON_PROD", ModeNonProd, false},
+		{"npr", ModeNonProd, false},
+		{"DEV", "", true},
+		{"NON  PROD", "", true},
+		{"PRODUCTION", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseMode(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMode(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMode(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModeTotals(t *testing.T) {
+	type row struct {
+		mode   string
+		values []int
+	}
+	tests := []struct {
+		name string
+		rows []row
+		want string
+	}{
+		{"no rows", nil, ""},
+		{"one mode", []row{{ModeProd, []int{4, 1}}, {ModeProd, []int{8, 2}}}, ""},
+		{
+			"both modes in order of first appearance",
+			[]row{{ModeNonProd, []int{2, 1}}, {ModeProd, []int{4, 1}}, {ModeNonProd, []int{6, 0}}, {ModeProd, []int{8, 2}}},
+			"NON PROD=[8 1] PROD=[12 3]",
+		},
+		{"empty mode counts as UNKNOWN", []row{{ModeProd, []int{4}}, {"", []int{2}}, {"", []int{3}}}, "PROD=[4] UNKNOWN=[5]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totals := newModeTotals()
+			for _, r := range tt.rows {
+				totals.add(r.mode, r.values...)
+			}
+			var got []string
+			totals.each(func(mode string, sums []int) {
+				got = append(got, fmt.Sprintf("%s=%v", mode, sums))
+			})
+			if s := strings.Join(got, " "); s != tt.want {
+				t.Errorf("subtotals = %q, want %q", s, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// MonthlyPeakRow represents a row from v_monthly_peak
+type MonthlyPeakRow struct {
+	Month            string `json:"month"` // YYYY-MM
+	ProductMnemoCode string `json:"product_mnemo_code"`
+	IBMProductCode   string `json:"ibm_product_code"`
+	ProductName      string `json:"product_name"`
+	Mode             string `json:"mode"`
+	TermID           string `json:"term_id"`
+	ProgramNumber    string `json:"program_number"`
+	ProgramName      string `json:"program_name"`
+	// Running products
+	PeakRunningCores int    `json:"peak_running_cores"`
+	PeakRunningDate  string `json:"peak_running_date"`
+	PeakRunningNodes int    `json:"peak_running_nodes"`
+	// Installed products
+	PeakInstalledCores int    `json:"peak_installed_cores"`
+	PeakInstalledDate  string `json:"peak_installed_date"`
+	PeakInstalledNodes int    `json:"peak_installed_nodes"`
+	DaysMeasured       int    `json:"days_measured"`
+	// Contract period the days were restricted to, empty for whole months
+	ContractPeriod string `json:"contract_period,omitempty"`
+}
+
+// MonthlyPeakReport generates reports from v_monthly_peak view
+type MonthlyPeakReport struct {
+	db *sql.DB
+}
+
+// NewMonthlyPeakReport creates a new report generator
+func NewMonthlyPeakReport(db *sql.DB) *MonthlyPeakReport {
+	return &MonthlyPeakReport{db: db}
+}
+
+// Query retrieves data from the view with optional filters.
+// Date filters select whole months: every month touched by the range is included.
+func (r *MonthlyPeakReport) Query(ctx context.Context, productCode, mode string, fromDate, toDate *time.Time) ([]MonthlyPeakRow, error) {
+	query := `
+		SELECT
+			month,
+			product_mnemo_code,
+			COALESCE(ibm_product_code, ''),
+			product_name,
+			mode,
+			term_id,
+			program_number,
+			program_name,
+			peak_running_cores,
+			COALESCE(peak_running_date, ''),
+			peak_running_nodes,
+			peak_installed_cores,
+			COALESCE(peak_installed_date, ''),
+			peak_installed_nodes,
+			days_measured
+		FROM v_monthly_peak
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if productCode != "" {
+		query += " AND product_mnemo_code = ?"
+		args = append(args, productCode)
+	}
+
+	if mode != "" {
+		query += " AND mode = ?"
+		args = append(args, mode)
+	}
+
+	if fromDate != nil {
+		query += " AND month >= ?"
+		args = append(args, fromDate.Format("2006-01"))
+	}
+
+	if toDate != nil {
+		query += " AND month <= ?"
+		args = append(args, toDate.Format("2006-01"))
+	}
+
+	query += " ORDER BY month DESC, product_mnemo_code"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query monthly peak: %w", err)
+	}
+	defer rows.Close()
+
+	var results []MonthlyPeakRow
+	for rows.Next() {
+		var row MonthlyPeakRow
+
+		err := rows.Scan(
+			&row.Month,
+			&row.ProductMnemoCode,
+			&row.IBMProductCode,
+			&row.ProductName,
+			&row.Mode,
+			&row.TermID,
+			&row.ProgramNumber,
+			&row.ProgramName,
+			&row.PeakRunningCores,
+			&row.PeakRunningDate,
+			&row.PeakRunningNodes,
+			&row.PeakInstalledCores,
+			&row.PeakInstalledDate,
+			&row.PeakInstalledNodes,
+			&row.DaysMeasured,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		results = append(results, row)
+	}
+
+	return results, rows.Err()
+}
+
+// QueryPeriod retrieves the monthly peaks of each product within a contract
+// period of its license term. selector is current, previous or the label of a
+// period. The first and last months of the period only count its own days, so
+// a month split by a renewal does not mix the peaks of two contracts.
+func (r *MonthlyPeakReport) QueryPeriod(ctx context.Context, productCode, mode, selector string) ([]MonthlyPeakRow, error) {
+	periods, args := selectedPeriodsCTE(selector)
+
+	filters := ""
+	if productCode != "" {
+		filters += " AND dlc.product_mnemo_code = ?"
+		args = append(args, productCode)
+	}
+	if mode != "" {
+		filters += " AND COALESCE(p.mode, '') = ?"
+		args = append(args, mode)
+	}
+
+	query := `
+		WITH selected_periods AS (` + periods + `),
+		ranked_days AS (
+			SELECT
+				strftime('%Y-%m', dlc.measurement_date) AS month,
+				dlc.*,
+				sp.term_id,
+				sp.label,
+				ROW_NUMBER() OVER (
+					PARTITION BY strftime('%Y-%m', dlc.measurement_date), dlc.product_mnemo_code, sp.term_id
+					ORDER BY dlc.running_license_cores DESC, dlc.measurement_date
+				) AS running_rank,
+				ROW_NUMBER() OVER (
+					PARTITION BY strftime('%Y-%m', dlc.measurement_date), dlc.product_mnemo_code, sp.term_id
+					ORDER BY dlc.installed_license_cores DESC, dlc.measurement_date
+				) AS installed_rank
+			FROM v_daily_license_cores dlc
+			JOIN product_codes p ON p.product_mnemo_code = dlc.product_mnemo_code
+			JOIN selected_periods sp ON sp.term_id = ` + productTermColumn("p", "dlc.measurement_date") + `
+			WHERE dlc.measurement_date BETWEEN sp.start_date AND sp.end_date` + filters + `
+		)
+		SELECT
+			r.month,
+			r.product_mnemo_code,
+			COALESCE(p.ibm_product_code, ''),
+			p.product_name,
+			COALESCE(p.mode, ''),
+			l.term_id,
+			l.program_number,
+			l.program_name,
+			MAX(CASE WHEN r.running_rank = 1 THEN r.running_license_cores END),
+			COALESCE(MAX(CASE WHEN r.running_rank = 1 THEN r.measurement_date END), ''),
+			MAX(CASE WHEN r.running_rank = 1 THEN r.running_nodes END),
+			MAX(CASE WHEN r.installed_rank = 1 THEN r.installed_license_cores END),
+			COALESCE(MAX(CASE WHEN r.installed_rank = 1 THEN r.measurement_date END), ''),
+			MAX(CASE WHEN r.installed_rank = 1 THEN r.installed_nodes END),
+			COUNT(*),
+			r.label
+		FROM ranked_days r
+		JOIN product_codes p ON r.product_mnemo_code = p.product_mnemo_code
+		JOIN license_terms l ON r.term_id = l.term_id
+		GROUP BY r.month, r.product_mnemo_code, p.ibm_product_code, p.product_name, p.mode,
+			l.term_id, l.program_number, l.program_name, r.label
+		ORDER BY r.month DESC, r.product_mnemo_code
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query monthly peak: %w", err)
+	}
+	defer rows.Close()
+
+	var results []MonthlyPeakRow
+	for rows.Next() {
+		var row MonthlyPeakRow
+
+		err := rows.Scan(
+			&row.Month,
+			&row.ProductMnemoCode,
+			&row.IBMProductCode,
+			&row.ProductName,
+			&row.Mode,
+			&row.TermID,
+			&row.ProgramNumber,
+			&row.ProgramName,
+			&row.PeakRunningCores,
+			&row.PeakRunningDate,
+			&row.PeakRunningNodes,
+			&row.PeakInstalledCores,
+			&row.PeakInstalledDate,
+			&row.PeakInstalledNodes,
+			&row.DaysMeasured,
+			&row.ContractPeriod,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		results = append(results, row)
+	}
+
+	return results, rows.Err()
+}
+
+// WriteTable writes data in ASCII table format
+func (r *MonthlyPeakReport) WriteTable(w io.Writer, rows []MonthlyPeakRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "MONTH\tPRODUCT\tTERM\tRUN_PEAK\tRUN_PEAK_DAY\tRUN_NODES\tINST_PEAK\tINST_PEAK_DAY\tINST_NODES\tDAYS")
+	fmt.Fprintln(tw, "-----\t-------\t----\t--------\t------------\t---------\t---------\t-------------\t----------\t----")
+
+	// Data rows
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%d\t%d\t%s\t%d\t%d\n",
+			row.Month,
+			row.ProductMnemoCode,
+			row.TermID,
+			row.PeakRunningCores,
+			row.PeakRunningDate,
+			row.PeakRunningNodes,
+			row.PeakInstalledCores,
+			row.PeakInstalledDate,
+			row.PeakInstalledNodes,
+			row.DaysMeasured,
+		)
+	}
+
+	return writeDailyFootnotes(tw, r.db)
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *MonthlyPeakReport) csvHeader() []string {
+	return []string{
+		"month",
+		"product_mnemo_code",
+		"ibm_product_code",
+		"product_name",
+		"mode",
+		"term_id",
+		"program_number",
+		"program_name",
+		"peak_running_cores",
+		"peak_running_date",
+		"peak_running_nodes",
+		"peak_installed_cores",
+		"peak_installed_date",
+		"peak_installed_nodes",
+		"days_measured",
+		"contract_period",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *MonthlyPeakReport) csvRecord(row MonthlyPeakRow) []string {
+	return []string{
+		row.Month,
+		row.ProductMnemoCode,
+		row.IBMProductCode,
+		row.ProductName,
+		row.Mode,
+		row.TermID,
+		row.ProgramNumber,
+		row.ProgramName,
+		fmt.Sprintf("%d", row.PeakRunningCores),
+		row.PeakRunningDate,
+		fmt.Sprintf("%d", row.PeakRunningNodes),
+		fmt.Sprintf("%d", row.PeakInstalledCores),
+		row.PeakInstalledDate,
+		fmt.Sprintf("%d", row.PeakInstalledNodes),
+		fmt.Sprintf("%d", row.DaysMeasured),
+		row.ContractPeriod,
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *MonthlyPeakReport) WriteCSV(w io.Writer, rows []MonthlyPeakRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *MonthlyPeakReport) WriteJSON(w io.Writer, rows []MonthlyPeakRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with one sheet per product
+func (r *MonthlyPeakReport) WriteXLSX(w io.Writer, rows []MonthlyPeakRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return sheetsByColumn(r.csvHeader(), records, 1, "Monthly Peak").Write(w)
+}
@@ -0,0 +1,217 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NodeChangeRow is a field of a measurement that differs from the previous
+// measurement of the node
+type NodeChangeRow struct {
+	MainFQDN           string `json:"main_fqdn"`
+	Hostname           string `json:"hostname"`
+	Mode               string `json:"mode"` // mode of the node
+	DetectionTimestamp string `json:"detection_timestamp"`
+	PreviousTimestamp  string `json:"previous_timestamp"`
+	Field              string `json:"field"`
+	OldValue           string `json:"old_value"`
+	NewValue           string `json:"new_value"`
+}
+
+// NodeChangesReport generates reports from the node_changes table
+type NodeChangesReport struct {
+	db *sql.DB
+}
+
+// NewNodeChangesReport creates a new report generator
+func NewNodeChangesReport(db *sql.DB) *NodeChangesReport {
+	return &NodeChangesReport{db: db}
+}
+
+// Query retrieves the changes of the nodes, newest first. host is a
+// comma-separated list of FQDNs, host names or glob patterns, fields a
+// comma-separated list of measurement columns (e.g. cpu_count,os_version),
+// and the mode filter applies to the mode of the node. The dates select the
+// measurements the changes were detected in.
+func (r *NodeChangesReport) Query(ctx context.Context, host, fields, mode string, fromDate, toDate *time.Time) ([]NodeChangeRow, error) {
+	query := `
+		SELECT
+			c.main_fqdn,
+			COALESCE(n.hostname, ''),
+			COALESCE(n.mode, ''),
+			strftime('%Y-%m-%d %H:%M:%S', c.detection_timestamp),
+			strftime('%Y-%m-%d %H:%M:%S', c.previous_timestamp),
+			c.field,
+			COALESCE(c.old_value, ''),
+			COALESCE(c.new_value, '')
+		FROM node_changes c
+		LEFT JOIN landscape_nodes n ON n.main_fqdn = c.main_fqdn
+		WHERE 1=1
+	`
+
+	where, args := hostCondition("c.main_fqdn", host)
+	query += where
+
+	if list := splitPatterns(fields); len(list) > 0 {
+		query += " AND c.field IN (?" + strings.Repeat(", ?", len(list)-1) + ")"
+		for _, field := range list {
+			args = append(args, strings.ToLower(field))
+		}
+	}
+
+	if mode != "" {
+		query += " AND n.mode = ?"
+		args = append(args, mode)
+	}
+
+	if fromDate != nil {
+		query += " AND DATE(c.detection_timestamp) >= ?"
+		args = append(args, fromDate.Format("2006-01-02"))
+	}
+
+	if toDate != nil {
+		query += " AND DATE(c.detection_timestamp) <= ?"
+		args = append(args, toDate.Format("2006-01-02"))
+	}
+
+	query += " ORDER BY c.detection_timestamp DESC, c.main_fqdn, c.field"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query node changes: %w", err)
+	}
+	defer rows.Close()
+
+	var results []NodeChangeRow
+	for rows.Next() {
+		var row NodeChangeRow
+		err := rows.Scan(
+			&row.MainFQDN,
+			&row.Hostname,
+			&row.Mode,
+			&row.DetectionTimestamp,
+			&row.PreviousTimestamp,
+			&row.Field,
+			&row.OldValue,
+			&row.NewValue,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		results = append(results, row)
+	}
+
+	return results, rows.Err()
+}
+
+// WriteTable writes data in ASCII table format, with the number of changes per field
+func (r *NodeChangesReport) WriteTable(w io.Writer, rows []NodeChangeRow) error {
+	tw := newTableWriter(w)
+
+	// Header
+	fmt.Fprintln(tw, "DETECTED\tHOST\tFIELD\tOLD\tNEW\tPREVIOUS")
+	fmt.Fprintln(tw, "--------\t----\t-----\t---\t---\t--------")
+
+	// Data rows
+	counts := make(map[string]int)
+	nodes := make(map[string]bool)
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			row.DetectionTimestamp,
+			row.MainFQDN,
+			row.Field,
+			row.OldValue,
+			row.NewValue,
+			row.PreviousTimestamp,
+		)
+		counts[row.Field]++
+		nodes[row.MainFQDN] = true
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// Summary
+	if len(rows) > 0 {
+		fields := make([]string, 0, len(counts))
+		for field := range counts {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for i, field := range fields {
+			fields[i] = fmt.Sprintf("%s %d", field, counts[field])
+		}
+		fmt.Fprintf(w, "\n%d change(s) on %d node(s): %s\n", len(rows), len(nodes), strings.Join(fields, ", "))
+	}
+
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *NodeChangesReport) csvHeader() []string {
+	return []string{
+		"main_fqdn",
+		"hostname",
+		"mode",
+		"detection_timestamp",
+		"previous_timestamp",
+		"field",
+		"old_value",
+		"new_value",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *NodeChangesReport) csvRecord(row NodeChangeRow) []string {
+	return []string{
+		row.MainFQDN,
+		row.Hostname,
+		row.Mode,
+		row.DetectionTimestamp,
+		row.PreviousTimestamp,
+		row.Field,
+		row.OldValue,
+		row.NewValue,
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *NodeChangesReport) WriteCSV(w io.Writer, rows []NodeChangeRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *NodeChangesReport) WriteJSON(w io.Writer, rows []NodeChangeRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with a single sheet
+func (r *NodeChangesReport) WriteXLSX(w io.Writer, rows []NodeChangeRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return singleSheet("Node changes", r.csvHeader(), records).Write(w)
+}
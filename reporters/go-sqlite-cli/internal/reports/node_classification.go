@@ -0,0 +1,39 @@
+package reports
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Standby policies of --standby, selecting the standby and DR nodes of the
+// node-level reports
+const (
+	StandbyInclude    = "include"    // all nodes
+	StandbyExclude    = "exclude"    // no standby or DR nodes
+	StandbyLicensable = "licensable" // no cold standby or DR nodes, which IBM does not charge
+)
+
+// ParseStandbyPolicy validates a --standby value, empty meaning include
+func ParseStandbyPolicy(value string) (string, error) {
+	policy := strings.ToLower(strings.TrimSpace(value))
+	switch policy {
+	case "":
+		return StandbyInclude, nil
+	case StandbyInclude, StandbyExclude, StandbyLicensable:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid standby policy %q (expected %s, %s or %s)", value, StandbyInclude, StandbyExclude, StandbyLicensable)
+}
+
+// standbyCondition returns an SQL condition keeping the nodes of fqdnColumn
+// selected by a standby policy, or an empty string when all nodes are kept.
+// Standby and DR nodes without a standby type are licensable.
+func standbyCondition(fqdnColumn, policy string) string {
+	switch policy {
+	case StandbyExclude:
+		return fmt.Sprintf(" AND %s NOT IN (SELECT main_fqdn FROM landscape_nodes WHERE classification IN ('standby', 'dr'))", fqdnColumn)
+	case StandbyLicensable:
+		return fmt.Sprintf(" AND %s NOT IN (SELECT main_fqdn FROM landscape_nodes WHERE classification IN ('standby', 'dr') AND standby_type = 'cold')", fqdnColumn)
+	}
+	return ""
+}
@@ -0,0 +1,366 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Node timeline events
+const (
+	TimelineMeasurement     = "measurement"
+	TimelineProductAppeared = "product-appeared"
+	TimelineProductGone     = "product-gone"
+	TimelineCPUChange       = "cpu-change"
+	TimelineHostMove        = "host-move"
+	TimelineChange          = "change"
+	TimelineImport          = "import"
+	TimelineDetectionError  = "detection-error"
+)
+
+// timelineEventOrder orders the events of the same timestamp
+var timelineEventOrder = map[string]int{
+	TimelineMeasurement:     0,
+	TimelineDetectionError:  0,
+	TimelineCPUChange:       1,
+	TimelineHostMove:        2,
+	TimelineChange:          3,
+	TimelineProductAppeared: 4,
+	TimelineProductGone:     5,
+	TimelineImport:          6,
+}
+
+// NodeTimelineRow is an event in the history of a node
+type NodeTimelineRow struct {
+	Timestamp string `json:"timestamp"`
+	Event     string `json:"event"`
+	Subject   string `json:"subject"` // product code, changed field or import session ID
+	OldValue  string `json:"old_value"`
+	NewValue  string `json:"new_value"`
+	Detail    string `json:"detail"`
+}
+
+// NodeTimelineReport renders the history of a single node in chronological order
+type NodeTimelineReport struct {
+	db *sql.DB
+
+	// mainFQDN is the node of the last query
+	mainFQDN string
+}
+
+// NewNodeTimelineReport creates a new report generator
+func NewNodeTimelineReport(db *sql.DB) *NodeTimelineReport {
+	return &NodeTimelineReport{db: db}
+}
+
+// ResolveNode returns the main FQDN of the node host names: its main FQDN,
+// a former FQDN recorded as a node alias, or its host name when that is unique
+func (r *NodeTimelineReport) ResolveNode(host string) (string, error) {
+	var mainFQDN string
+	err := r.db.QueryRow(`
+		SELECT main_fqdn FROM landscape_nodes WHERE LOWER(main_fqdn) = LOWER(?)
+		UNION ALL
+		SELECT main_fqdn FROM node_aliases WHERE LOWER(alias_fqdn) = LOWER(?)
+		LIMIT 1
+	`, host, host).Scan(&mainFQDN)
+	if err == nil {
+		return mainFQDN, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to look up node %s: %w", host, err)
+	}
+
+	rows, err := r.db.Query("SELECT main_fqdn FROM landscape_nodes WHERE LOWER(hostname) = LOWER(?) ORDER BY main_fqdn", host)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up node %s: %w", host, err)
+	}
+	defer rows.Close()
+	var matches []string
+	for rows.Next() {
+		if err := rows.Scan(&mainFQDN); err != nil {
+			return "", err
+		}
+		matches = append(matches, mainFQDN)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("node %s not found", host)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("host name %s matches several nodes, use the FQDN: %s", host, strings.Join(matches, ", "))
+}
+
+// Query returns the events of a node, oldest first: its measurements and
+// failed detections, the products appearing and disappearing between
+// consecutive measurements, the changes of node_changes and host ID
+// corrections, and the import sessions that wrote its measurements. The
+// dates select the events by day.
+func (r *NodeTimelineReport) Query(ctx context.Context, host string, fromDate, toDate *time.Time) ([]NodeTimelineRow, error) {
+	mainFQDN, err := r.ResolveNode(host)
+	if err != nil {
+		return nil, err
+	}
+	r.mainFQDN = mainFQDN
+
+	events, err := r.measurementEvents(ctx, mainFQDN)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := []struct {
+		name  string
+		query string
+	}{
+		{"node changes", `
+			SELECT strftime('%Y-%m-%d %H:%M:%S', detection_timestamp),
+				CASE field
+					WHEN 'cpu_count' THEN 'cpu-change'
+					WHEN 'considered_cpus' THEN 'cpu-change'
+					WHEN 'physical_host_id' THEN 'host-move'
+					ELSE 'change'
+				END,
+				field, COALESCE(old_value, ''), COALESCE(new_value, ''),
+				'since ' || strftime('%Y-%m-%d %H:%M:%S', previous_timestamp)
+			FROM node_changes
+			WHERE main_fqdn = ?`},
+		{"host ID corrections", `
+			SELECT strftime('%Y-%m-%d %H:%M:%S', corrected_at), 'host-move', 'physical_host_id', old_host_id, new_host_id,
+				'corrected from ' || source || ', ' || measurements_updated || ' measurement(s)'
+			FROM host_id_corrections
+			WHERE main_fqdn = ?`},
+		{"import sessions", `
+			SELECT strftime('%Y-%m-%d %H:%M:%S', imported_at), 'import', session_id, '', status,
+				source_file || CASE WHEN error_message != '' THEN ': ' || error_message ELSE '' END
+			FROM import_sessions
+			WHERE main_fqdn = ?`},
+		{"detection errors", `
+			SELECT strftime('%Y-%m-%d %H:%M:%S', detection_timestamp), 'detection-error', '', '', '',
+				error_message || ' (' || source_file || ')'
+			FROM detection_errors
+			WHERE main_fqdn = ?`},
+	}
+	for _, q := range queries {
+		rows, err := r.db.QueryContext(ctx, q.query, mainFQDN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", q.name, err)
+		}
+		for rows.Next() {
+			var row NodeTimelineRow
+			if err := rows.Scan(&row.Timestamp, &row.Event, &row.Subject, &row.OldValue, &row.NewValue, &row.Detail); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan %s: %w", q.name, err)
+			}
+			events = append(events, row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	var results []NodeTimelineRow
+	for _, event := range events {
+		day := event.Timestamp
+		if len(day) > 10 {
+			day = day[:10]
+		}
+		if (fromDate != nil && day < fromDate.Format("2006-01-02")) || (toDate != nil && day > toDate.Format("2006-01-02")) {
+			continue
+		}
+		results = append(results, event)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Timestamp != results[j].Timestamp {
+			return results[i].Timestamp < results[j].Timestamp
+		}
+		return timelineEventOrder[results[i].Event] < timelineEventOrder[results[j].Event]
+	})
+
+	return results, nil
+}
+
+// measurementEvents returns the measurements of a node, and the products
+// appearing in or disappearing from each of them compared with the previous one
+func (r *NodeTimelineReport) measurementEvents(ctx context.Context, mainFQDN string) ([]NodeTimelineRow, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			strftime('%Y-%m-%d %H:%M:%S', m.detection_timestamp),
+			m.os_name || ' ' || m.os_version,
+			m.cpu_count,
+			m.considered_cpus,
+			m.is_virtualized,
+			COALESCE(m.virt_type, ''),
+			COALESCE(m.physical_host_id, ''),
+			COALESCE((
+				SELECT GROUP_CONCAT(d.product_mnemo_code, ' ')
+				FROM (
+					SELECT product_mnemo_code
+					FROM detected_products
+					WHERE main_fqdn = m.main_fqdn AND detection_timestamp = m.detection_timestamp AND status = 'present'
+					ORDER BY product_mnemo_code
+				) d
+			), '')
+		FROM measurements m
+		WHERE m.main_fqdn = ?
+		ORDER BY m.detection_timestamp
+	`, mainFQDN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query measurements: %w", err)
+	}
+	defer rows.Close()
+
+	var events []NodeTimelineRow
+	var previous map[string]bool
+	for rows.Next() {
+		var timestamp, os, virtualized, virtType, hostID, products string
+		var cpus, considered int
+		if err := rows.Scan(&timestamp, &os, &cpus, &considered, &virtualized, &virtType, &hostID, &products); err != nil {
+			return nil, fmt.Errorf("failed to scan measurement: %w", err)
+		}
+
+		detail := fmt.Sprintf("%s, %d CPUs (%d considered)", os, cpus, considered)
+		switch {
+		case virtualized == "yes" && hostID != "" && hostID != "unknown":
+			detail += fmt.Sprintf(", %s on %s", virtType, hostID)
+		case virtualized == "yes":
+			detail += ", " + virtType
+		case virtualized == "no":
+			detail += ", physical"
+		}
+		list := strings.Fields(products)
+		events = append(events, NodeTimelineRow{
+			Timestamp: timestamp,
+			Event:     TimelineMeasurement,
+			NewValue:  fmt.Sprintf("%d", considered),
+			Detail:    fmt.Sprintf("%s, %d product(s) running", detail, len(list)),
+		})
+
+		current := make(map[string]bool, len(list))
+		for _, product := range list {
+			current[product] = true
+			if !previous[product] {
+				events = append(events, NodeTimelineRow{Timestamp: timestamp, Event: TimelineProductAppeared, Subject: product})
+			}
+		}
+		var gone []string
+		for product := range previous {
+			if !current[product] {
+				gone = append(gone, product)
+			}
+		}
+		sort.Strings(gone)
+		for _, product := range gone {
+			events = append(events, NodeTimelineRow{Timestamp: timestamp, Event: TimelineProductGone, Subject: product})
+		}
+		previous = current
+	}
+
+	return events, rows.Err()
+}
+
+// WriteTable writes data in ASCII table format, headed by the node
+func (r *NodeTimelineReport) WriteTable(w io.Writer, rows []NodeTimelineRow) error {
+	if r.mainFQDN != "" {
+		fmt.Fprintf(w, "Timeline of %s\n\n", r.mainFQDN)
+	}
+
+	tw := newTableWriter(w)
+
+	// Header
+	fmt.Fprintln(tw, "TIMESTAMP\tEVENT\tSUBJECT\tOLD\tNEW\tDETAIL")
+	fmt.Fprintln(tw, "---------\t-----\t-------\t---\t---\t------")
+
+	// Data rows
+	counts := make(map[string]int)
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			row.Timestamp,
+			row.Event,
+			row.Subject,
+			row.OldValue,
+			row.NewValue,
+			row.Detail,
+		)
+		counts[row.Event]++
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// Summary
+	if len(rows) > 0 {
+		fmt.Fprintf(w, "\n%d measurement(s), %d product change(s), %d CPU change(s), %d host move(s), %d import(s)\n",
+			counts[TimelineMeasurement], counts[TimelineProductAppeared]+counts[TimelineProductGone],
+			counts[TimelineCPUChange], counts[TimelineHostMove], counts[TimelineImport])
+	}
+
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *NodeTimelineReport) csvHeader() []string {
+	return []string{
+		"timestamp",
+		"event",
+		"subject",
+		"old_value",
+		"new_value",
+		"detail",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *NodeTimelineReport) csvRecord(row NodeTimelineRow) []string {
+	return []string{
+		row.Timestamp,
+		row.Event,
+		row.Subject,
+		row.OldValue,
+		row.NewValue,
+		row.Detail,
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *NodeTimelineReport) WriteCSV(w io.Writer, rows []NodeTimelineRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *NodeTimelineReport) WriteJSON(w io.Writer, rows []NodeTimelineRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with a single sheet
+func (r *NodeTimelineReport) WriteXLSX(w io.Writer, rows []NodeTimelineRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return singleSheet("Node timeline", r.csvHeader(), records).Write(w)
+}
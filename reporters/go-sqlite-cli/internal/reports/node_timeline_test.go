@@ -0,0 +1,79 @@
+package reports_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestNodeTimeline(t *testing.T) {
+	stmts := []string{
+		`INSERT INTO product_codes (product_mnemo_code, ibm_product_code, product_name, mode, term_id)
+			VALUES ('BRK_ONP_PRD', 'D0YYX0X', 'Broker', 'PROD', 'T1'),
+			('UM_ONP_PRD', 'D0YYX1X', 'Universal Messaging', 'PROD', 'T1')`,
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('app01.example.com', 'app01', 'PROD')`,
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('app01.other.com', 'app01', 'PROD')`,
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('db01.example.com', 'db01', 'PROD')`,
+		`INSERT INTO node_aliases (alias_fqdn, main_fqdn) VALUES ('db01.old.com', 'db01.example.com')`,
+		`INSERT INTO node_changes (main_fqdn, detection_timestamp, previous_timestamp, field, old_value, new_value)
+			VALUES ('db01.example.com', '2025-10-02 08:00:00', '2025-10-01 08:00:00', 'cpu_count', '4', '8'),
+			('db01.example.com', '2025-10-02 08:00:00', '2025-10-01 08:00:00', 'physical_host_id', 'esx1', 'esx2')`,
+		`INSERT INTO import_sessions (session_id, imported_at, source_file, hostname, status, main_fqdn, detection_timestamp)
+			VALUES ('s1', '2025-10-02 09:00:00', 'db01_20251002.csv', 'db01', 'success', 'db01.example.com', '2025-10-02 08:00:00')`,
+	}
+	for i, m := range []struct {
+		cpus     int
+		host     string
+		products []string
+	}{
+		{4, "esx1", []string{"IS_ONP_PRD", "BRK_ONP_PRD"}},
+		{8, "esx2", []string{"IS_ONP_PRD", "UM_ONP_PRD"}},
+	} {
+		timestamp := fmt.Sprintf("2025-10-0%d 08:00:00", i+1)
+		stmts = append(stmts, fmt.Sprintf(`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count,
+			is_virtualized, virt_type, physical_host_id, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus)
+			VALUES ('db01.example.com', '%s', 'Linux', '9', %d, 'yes', 'VMware ESXi', '%s', '32', 'true', 'true', 'true', %d)`,
+			timestamp, m.cpus, m.host, m.cpus))
+		for _, product := range m.products {
+			stmts = append(stmts, fmt.Sprintf(`INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, install_count)
+				VALUES ('db01.example.com', '%s', '%s', 'present', 1)`, product, timestamp))
+		}
+	}
+	db := newTestDB(t, stmts...)
+
+	report := reports.NewNodeTimelineReport(db)
+	rows, err := report.Query(t.Context(), "db01.old.com", nil, nil)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	var events []string
+	for _, row := range rows {
+		events = append(events, strings.TrimSpace(row.Timestamp[8:10]+" "+row.Event+" "+row.Subject))
+	}
+	want := []string{
+		"01 measurement",
+		"01 product-appeared BRK_ONP_PRD",
+		"01 product-appeared IS_ONP_PRD",
+		"02 measurement",
+		"02 cpu-change cpu_count",
+		"02 host-move physical_host_id",
+		"02 product-appeared UM_ONP_PRD",
+		"02 product-gone BRK_ONP_PRD",
+		"02 import s1",
+	}
+	if got := strings.Join(events, ", "); got != strings.Join(want, ", ") {
+		t.Errorf("Unexpected timeline:\n got %s\nwant %s", got, strings.Join(want, ", "))
+	}
+	if rows[3].Detail != "Linux 9, 8 CPUs (8 considered), VMware ESXi on esx2, 2 product(s) running" {
+		t.Errorf("Unexpected measurement detail: %q", rows[3].Detail)
+	}
+
+	if _, err := report.Query(t.Context(), "app01", nil, nil); err == nil || !strings.Contains(err.Error(), "several nodes") {
+		t.Errorf("Expected an ambiguous host name error, got %v", err)
+	}
+	if _, err := report.Query(t.Context(), "nope", nil, nil); err == nil {
+		t.Error("Expected an unknown node error")
+	}
+}
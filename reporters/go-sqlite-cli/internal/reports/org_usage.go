@@ -0,0 +1,377 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// NoOrgName is shown for the nodes that do not belong to an organization
+const NoOrgName = "(no organization)"
+
+// OrgUsageRow represents a row from v_daily_org_license_cores
+type OrgUsageRow struct {
+	MeasurementDate       time.Time `json:"measurement_date"`
+	OrgID                 string    `json:"org_id"`
+	OrgName               string    `json:"org_name"`
+	ProductMnemoCode      string    `json:"product_mnemo_code"`
+	ProductName           string    `json:"product_name"`
+	Mode                  string    `json:"mode"`
+	TermID                string    `json:"term_id"`
+	ProgramNumber         string    `json:"program_number"`
+	RunningNodes          int       `json:"running_nodes"`
+	RunningLicenseCores   int       `json:"running_license_cores"`
+	InstalledNodes        int       `json:"installed_nodes"`
+	InstalledLicenseCores int       `json:"installed_license_cores"`
+	// Entitlement gap analysis against the entitlements of the organization:
+	// the usage compared is the sum over all products of the term within the
+	// organization
+	OrgTermLicenseCores int    `json:"org_term_license_cores"`
+	LicensedCores       *int   `json:"licensed_cores"`
+	ComplianceDelta     *int   `json:"compliance_delta"`
+	ComplianceStatus    string `json:"compliance_status,omitempty"`
+	Breach              bool   `json:"breach,omitempty"`
+}
+
+// OrgUsageReport generates the daily-summary and compliance reports of
+// organizations from v_daily_org_license_cores
+type OrgUsageReport struct {
+	db         *sql.DB
+	compliance bool
+
+	// org selects one organization; empty reports every organization
+	org string
+}
+
+// NewOrgSummaryReport creates the report generator of daily-summary --org and --group-by org
+func NewOrgSummaryReport(db *sql.DB) *OrgUsageReport {
+	return &OrgUsageReport{db: db}
+}
+
+// NewOrgComplianceReport creates the report generator of compliance --org and --group-by org
+func NewOrgComplianceReport(db *sql.DB) *OrgUsageReport {
+	return &OrgUsageReport{db: db, compliance: true}
+}
+
+// SetOrg selects the organization reported; empty reports every organization,
+// and the nodes without one
+func (r *OrgUsageReport) SetOrg(orgID string) {
+	r.org = orgID
+}
+
+// Query retrieves data from the view with optional filters. With
+// nonCompliantOnly, only the rows of under-licensed terms are returned.
+func (r *OrgUsageReport) Query(ctx context.Context, productCode, mode string, fromDate, toDate *time.Time, nonCompliantOnly bool) ([]OrgUsageRow, error) {
+	query := `
+		SELECT
+			o.measurement_date,
+			o.org_id,
+			COALESCE(org.org_name, ''),
+			o.product_mnemo_code,
+			p.product_name,
+			p.mode,
+			l.term_id,
+			l.program_number,
+			o.running_nodes,
+			o.running_license_cores,
+			o.installed_nodes,
+			o.installed_license_cores,
+			(SELECT SUM(o2.running_license_cores)
+			 FROM v_daily_org_license_cores o2
+			 JOIN product_codes p2 ON o2.product_mnemo_code = p2.product_mnemo_code
+			 WHERE ` + productTermColumn("p2", "o2.measurement_date") + ` = l.term_id
+			   AND o2.org_id = o.org_id
+			   AND o2.measurement_date = o.measurement_date) as org_term_license_cores,
+			oe.licensed_cores
+		FROM v_daily_org_license_cores o
+		JOIN product_codes p ON o.product_mnemo_code = p.product_mnemo_code
+		JOIN license_terms l ON l.term_id = ` + productTermColumn("p", "o.measurement_date") + `
+		LEFT JOIN organizations org ON o.org_id = org.org_id
+		LEFT JOIN org_entitlements oe ON oe.org_id = o.org_id AND oe.term_id = l.term_id
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if r.org != "" {
+		query += " AND o.org_id = ?"
+		args = append(args, r.org)
+	}
+
+	if productCode != "" {
+		query += " AND o.product_mnemo_code = ?"
+		args = append(args, productCode)
+	}
+
+	if mode != "" {
+		query += " AND p.mode = ?"
+		args = append(args, mode)
+	}
+
+	if fromDate != nil {
+		query += " AND o.measurement_date >= ?"
+		args = append(args, fromDate.Format("2006-01-02"))
+	}
+
+	if toDate != nil {
+		query += " AND o.measurement_date <= ?"
+		args = append(args, toDate.Format("2006-01-02"))
+	}
+
+	// Nodes without an organization sort last
+	query += " ORDER BY o.measurement_date DESC, o.org_id = '', o.org_id, o.product_mnemo_code"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organization usage: %w", err)
+	}
+	defer rows.Close()
+
+	var results []OrgUsageRow
+	for rows.Next() {
+		var row OrgUsageRow
+		var dateStr string
+		var licensedCores sql.NullInt64
+
+		err := rows.Scan(
+			&dateStr,
+			&row.OrgID,
+			&row.OrgName,
+			&row.ProductMnemoCode,
+			&row.ProductName,
+			&row.Mode,
+			&row.TermID,
+			&row.ProgramNumber,
+			&row.RunningNodes,
+			&row.RunningLicenseCores,
+			&row.InstalledNodes,
+			&row.InstalledLicenseCores,
+			&row.OrgTermLicenseCores,
+			&licensedCores,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if row.OrgID == "" {
+			row.OrgName = NoOrgName
+		}
+
+		// Compute gap against the entitlement of the organization
+		// (positive = spare, negative = shortfall)
+		if licensedCores.Valid {
+			licensed := int(licensedCores.Int64)
+			delta := licensed - row.OrgTermLicenseCores
+			row.LicensedCores = &licensed
+			row.ComplianceDelta = &delta
+		}
+		if r.compliance {
+			row.ComplianceStatus = complianceStatus(row.ComplianceDelta)
+			row.Breach = row.ComplianceStatus == StatusUnderLicensed
+		}
+		if nonCompliantOnly && !row.Breach {
+			continue
+		}
+
+		// Parse date
+		row.MeasurementDate, err = time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse date: %w", err)
+		}
+
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
+
+// WriteTable writes data in ASCII table format
+func (r *OrgUsageReport) WriteTable(w io.Writer, rows []OrgUsageRow) error {
+	if r.compliance {
+		return r.writeComplianceTable(w, rows)
+	}
+
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "DATE\tORG\tPRODUCT\tMODE\tRUN_NODES\tRUN_CORES\tINST_NODES\tINST_CORES")
+	fmt.Fprintln(tw, "----\t---\t-------\t----\t---------\t---------\t----------\t----------")
+
+	// Data rows
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\n",
+			row.MeasurementDate.Format("2006-01-02"),
+			row.OrgName,
+			row.ProductMnemoCode,
+			row.Mode,
+			row.RunningNodes,
+			row.RunningLicenseCores,
+			row.InstalledNodes,
+			row.InstalledLicenseCores,
+		)
+	}
+
+	// Summary per organization and mode, as PROD and NON PROD cores are licensed separately
+	if len(rows) > 0 {
+		type orgTotal struct {
+			org, mode string
+			sums      [4]int
+		}
+		var totals []*orgTotal
+		index := make(map[string]*orgTotal)
+		for _, row := range rows {
+			key := row.OrgID + "\x00" + row.Mode
+			t, ok := index[key]
+			if !ok {
+				t = &orgTotal{org: row.OrgName, mode: row.Mode}
+				index[key] = t
+				totals = append(totals, t)
+			}
+			t.sums[0] += row.RunningNodes
+			t.sums[1] += row.RunningLicenseCores
+			t.sums[2] += row.InstalledNodes
+			t.sums[3] += row.InstalledLicenseCores
+		}
+
+		fmt.Fprintln(tw, "----\t---\t-------\t----\t---------\t---------\t----------\t----------")
+		for _, t := range totals {
+			fmt.Fprintf(tw, "TOTAL\t%s\t\t%s\t%d\t%d\t%d\t%d\n", t.org, t.mode, t.sums[0], t.sums[1], t.sums[2], t.sums[3])
+		}
+	}
+
+	return writeDailyFootnotes(tw, r.db)
+}
+
+// writeComplianceTable writes the compliance columns in ASCII table format
+func (r *OrgUsageReport) writeComplianceTable(w io.Writer, rows []OrgUsageRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "DATE\tORG\tPRODUCT\tMODE\tPROGRAM\tRUN_NODES\tLIC_CORES\tORG_TERM_CORES\tENTITLED\tDELTA\tSTATUS")
+	fmt.Fprintln(tw, "----\t---\t-------\t----\t-------\t---------\t---------\t--------------\t--------\t-----\t------")
+
+	// Data rows
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\t%s\t%s\n",
+			row.MeasurementDate.Format("2006-01-02"),
+			row.OrgName,
+			row.ProductMnemoCode,
+			row.Mode,
+			row.ProgramNumber,
+			row.RunningNodes,
+			row.RunningLicenseCores,
+			row.OrgTermLicenseCores,
+			formatOptionalInt(row.LicensedCores, "N/A"),
+			formatOptionalInt(row.ComplianceDelta, "N/A"),
+			row.ComplianceStatus,
+		)
+	}
+
+	fmt.Fprintln(tw, "\nENTITLED are the licensed cores of the organization for the term (see 'import entitlements --org').")
+
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *OrgUsageReport) csvHeader() []string {
+	header := []string{
+		"measurement_date",
+		"org_id",
+		"org_name",
+		"product_mnemo_code",
+		"product_name",
+		"mode",
+		"term_id",
+		"program_number",
+		"running_nodes",
+		"running_license_cores",
+		"installed_nodes",
+		"installed_license_cores",
+	}
+	if r.compliance {
+		header = append(header,
+			"org_term_license_cores",
+			"licensed_cores",
+			"compliance_delta",
+			"compliance_status",
+			"breach",
+		)
+	}
+	return header
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *OrgUsageReport) csvRecord(row OrgUsageRow) []string {
+	record := []string{
+		row.MeasurementDate.Format("2006-01-02"),
+		row.OrgID,
+		row.OrgName,
+		row.ProductMnemoCode,
+		row.ProductName,
+		row.Mode,
+		row.TermID,
+		row.ProgramNumber,
+		fmt.Sprintf("%d", row.RunningNodes),
+		fmt.Sprintf("%d", row.RunningLicenseCores),
+		fmt.Sprintf("%d", row.InstalledNodes),
+		fmt.Sprintf("%d", row.InstalledLicenseCores),
+	}
+	if r.compliance {
+		record = append(record,
+			fmt.Sprintf("%d", row.OrgTermLicenseCores),
+			formatOptionalInt(row.LicensedCores, ""),
+			formatOptionalInt(row.ComplianceDelta, ""),
+			row.ComplianceStatus,
+			fmt.Sprintf("%t", row.Breach),
+		)
+	}
+	return record
+}
+
+// WriteCSV writes data in CSV format
+func (r *OrgUsageReport) WriteCSV(w io.Writer, rows []OrgUsageRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *OrgUsageReport) WriteJSON(w io.Writer, rows []OrgUsageRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with one sheet per organization
+func (r *OrgUsageReport) WriteXLSX(w io.Writer, rows []OrgUsageRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return sheetsByColumn(r.csvHeader(), records, 2, "Organizations").Write(w)
+}
+
+// orgCondition returns an SQL condition selecting the nodes of an
+// organization by their FQDN column; empty selects every node
+func orgCondition(fqdnColumn, orgID string) (string, []interface{}) {
+	if orgID == "" {
+		return "", nil
+	}
+	return fmt.Sprintf(" AND %s IN (SELECT main_fqdn FROM landscape_nodes WHERE org_id = ?)", fqdnColumn), []interface{}{orgID}
+}
@@ -0,0 +1,59 @@
+package reports_test
+
+import (
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestOrgComplianceReport(t *testing.T) {
+	// acme runs 8 cores on two nodes with 6 licensed, beta 4 cores with 8
+	// licensed, and app04 belongs to no organization
+	stmts := []string{
+		`INSERT INTO organizations (org_id, org_name) VALUES ('acme', 'ACME GmbH'), ('beta', 'Beta Ltd')`,
+		`INSERT INTO org_entitlements (org_id, term_id, licensed_cores) VALUES ('acme', 'T1', 6), ('beta', 'T1', 8)`,
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode, org_id) VALUES
+			('app01', 'app01', 'PROD', 'acme'), ('app02', 'app02', 'PROD', 'acme'), ('app03', 'app03', 'PROD', 'beta')`,
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('app04', 'app04', 'PROD')`,
+	}
+	for _, fqdn := range []string{"app01", "app02", "app03", "app04"} {
+		stmts = append(stmts, `INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count,
+			is_virtualized, virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus)
+			VALUES ('`+fqdn+`', '2025-10-21 08:00:00', 'Linux', '8', 4, 'no', '', 'unknown', 'true', 'true', 'true', 4)`,
+			`INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, install_count)
+			VALUES ('`+fqdn+`', 'IS_ONP_PRD', '2025-10-21 08:00:00', 'present', 1)`)
+	}
+	db := newTestDB(t, stmts...)
+
+	report := reports.NewOrgComplianceReport(db)
+	rows, err := report.Query(t.Context(), "", "", nil, nil, false)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected a row per organization and one without, got %+v", rows)
+	}
+
+	acme, beta, none := rows[0], rows[1], rows[2]
+	if acme.OrgName != "ACME GmbH" || acme.RunningNodes != 2 || acme.OrgTermLicenseCores != 8 ||
+		acme.ComplianceDelta == nil || *acme.ComplianceDelta != -2 || !acme.Breach {
+		t.Errorf("Expected acme under-licensed by 2 cores, got %+v", acme)
+	}
+	if beta.OrgID != "beta" || beta.ComplianceDelta == nil || *beta.ComplianceDelta != 4 || beta.Breach {
+		t.Errorf("Expected beta compliant with 4 spare cores, got %+v", beta)
+	}
+	if none.OrgID != "" || none.OrgName != reports.NoOrgName || none.LicensedCores != nil {
+		t.Errorf("Expected the node without an organization last and unlicensed, got %+v", none)
+	}
+
+	rows, err = report.Query(t.Context(), "", "", nil, nil, true)
+	if err != nil || len(rows) != 1 || rows[0].OrgID != "acme" {
+		t.Errorf("Expected only acme as non-compliant, got %+v (%v)", rows, err)
+	}
+
+	report.SetOrg("beta")
+	rows, err = report.Query(t.Context(), "", "", nil, nil, false)
+	if err != nil || len(rows) != 1 || rows[0].OrgID != "beta" || rows[0].RunningNodes != 1 {
+		t.Errorf("Expected the row of beta only, got %+v (%v)", rows, err)
+	}
+}
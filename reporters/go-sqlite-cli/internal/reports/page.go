@@ -0,0 +1,228 @@
+package reports
+
+import (
+	"cmp"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Page selects a part of the rows of a report: the rows are sorted by the
+// Sort columns, then Offset rows are skipped and at most Limit rows are kept.
+// A zero Page keeps all rows in report order.
+type Page struct {
+	Limit  int    // maximum number of rows, 0 for no limit
+	Offset int    // number of rows skipped
+	Sort   string // comma-separated column names, each descending with a - prefix
+}
+
+// IsZero reports whether the page keeps all rows in report order
+func (p Page) IsZero() bool {
+	return p.Limit == 0 && p.Offset == 0 && p.Sort == ""
+}
+
+// Validate checks the limit and offset of the page
+func (p Page) Validate() error {
+	if p.Limit < 0 {
+		return fmt.Errorf("--limit must not be negative")
+	}
+	if p.Offset < 0 {
+		return fmt.Errorf("--offset must not be negative")
+	}
+	return nil
+}
+
+// sortKey is one column of a sort order
+type sortKey struct {
+	column     string
+	descending bool
+}
+
+// parseSort splits a sort order into its columns
+func parseSort(order string) []sortKey {
+	var keys []sortKey
+	for _, column := range splitPatterns(order) {
+		key := sortKey{column: column}
+		if strings.HasPrefix(column, "-") {
+			key = sortKey{column: strings.TrimSpace(column[1:]), descending: true}
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Paginate returns the rows of a page. The sort columns are the JSON names of
+// the row fields, which are also the CSV column names of most reports; rows
+// with equal values keep their report order.
+func Paginate[T any](rows []T, page Page) ([]T, error) {
+	if err := page.Validate(); err != nil {
+		return nil, err
+	}
+
+	if keys := parseSort(page.Sort); len(keys) > 0 {
+		columns := rowColumns(reflect.TypeOf(rows).Elem())
+		indexes := make([][]int, len(keys))
+		for i, key := range keys {
+			index, ok := columns[key.column]
+			if !ok {
+				return nil, unknownSortColumnError(key.column, columns)
+			}
+			indexes[i] = index
+		}
+
+		rows = append([]T(nil), rows...)
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := reflect.ValueOf(rows[i]), reflect.ValueOf(rows[j])
+			for k, key := range keys {
+				c := compareValues(a.FieldByIndex(indexes[k]), b.FieldByIndex(indexes[k]))
+				if c != 0 {
+					return (c < 0) != key.descending
+				}
+			}
+			return false
+		})
+	}
+
+	if page.Offset >= len(rows) {
+		return rows[:0], nil
+	}
+	rows = rows[page.Offset:]
+	if page.Limit > 0 && page.Limit < len(rows) {
+		rows = rows[:page.Limit]
+	}
+	return rows, nil
+}
+
+// sqlClause returns the ORDER BY, LIMIT and OFFSET clauses of the page for a
+// query whose sortable columns are given by their JSON names, with the report
+// order appended to break ties
+func (p Page) sqlClause(columns map[string]string, reportOrder string) (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+
+	var order []string
+	for _, key := range parseSort(p.Sort) {
+		column, ok := columns[key.column]
+		if !ok {
+			return "", unknownSortColumnError(key.column, columns)
+		}
+		if key.descending {
+			column += " DESC"
+		}
+		order = append(order, column)
+	}
+	order = append(order, reportOrder)
+
+	clause := " ORDER BY " + strings.Join(order, ", ")
+	if p.Limit > 0 || p.Offset > 0 {
+		limit := -1
+		if p.Limit > 0 {
+			limit = p.Limit
+		}
+		clause += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, p.Offset)
+	}
+	return clause, nil
+}
+
+// unknownSortColumnError lists the sortable columns of a report
+func unknownSortColumnError[V any](column string, columns map[string]V) error {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("unknown sort column %q (expected one of %s)", column, strings.Join(names, ", "))
+}
+
+// rowColumns maps the JSON names of the fields of a row struct, including the
+// fields of embedded structs, to their field indexes
+func rowColumns(t reflect.Type) map[string][]int {
+	columns := map[string][]int{}
+	if t.Kind() != reflect.Struct {
+		return columns
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for name, index := range rowColumns(field.Type) {
+				columns[name] = append([]int{i}, index...)
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		columns[name] = []int{i}
+	}
+	return columns
+}
+
+// compareValues compares two field values of the same type: missing values
+// (nil pointers, invalid sql.Null values) sort first, then numbers, times and
+// booleans by value and anything else by its text
+func compareValues(a, b reflect.Value) int {
+	a, aok := fieldValue(a)
+	b, bok := fieldValue(b)
+	if !aok || !bok {
+		return compareBools(aok, bok)
+	}
+
+	if t, isTime := a.Interface().(time.Time); isTime {
+		return t.Compare(b.Interface().(time.Time))
+	}
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.Bool:
+		return compareBools(a.Bool(), b.Bool())
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	}
+	return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+}
+
+// fieldValue dereferences pointers and unwraps sql.Null values; ok is false
+// for missing values
+func fieldValue(v reflect.Value) (reflect.Value, bool) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	if valuer, isValuer := v.Interface().(driver.Valuer); isValuer {
+		value, err := valuer.Value()
+		if err != nil || value == nil {
+			return v, false
+		}
+		return reflect.ValueOf(value), true
+	}
+	return v, true
+}
+
+// compareBools orders false before true
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	}
+	return 1
+}
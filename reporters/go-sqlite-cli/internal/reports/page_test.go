@@ -0,0 +1,53 @@
+package reports_test
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestPaginate(t *testing.T) {
+	rows := []reports.HostDetailRow{
+		{HostFQDN: "a", VirtualCPUs: 4, PhysicalHostID: sql.NullString{String: "H2", Valid: true}},
+		{HostFQDN: "b", VirtualCPUs: 16},
+		{HostFQDN: "c", VirtualCPUs: 8, PhysicalHostID: sql.NullString{String: "H1", Valid: true}},
+		{HostFQDN: "d", VirtualCPUs: 16, PhysicalHostID: sql.NullString{String: "H1", Valid: true}},
+	}
+
+	tests := []struct {
+		page reports.Page
+		want string
+	}{
+		{reports.Page{}, "a b c d"},
+		{reports.Page{Limit: 2}, "a b"},
+		{reports.Page{Limit: 2, Offset: 3}, "d"},
+		{reports.Page{Offset: 10}, ""},
+		{reports.Page{Sort: "-virtual_cpus"}, "b d c a"}, // stable for equal values
+		{reports.Page{Sort: "-virtual_cpus, -host_fqdn", Limit: 3}, "d b c"},
+		{reports.Page{Sort: "physical_host_id,host_fqdn"}, "b c d a"}, // missing values first
+	}
+	for _, tt := range tests {
+		page, err := reports.Paginate(rows, tt.page)
+		if err != nil {
+			t.Fatalf("Paginate(%+v) failed: %v", tt.page, err)
+		}
+		var hosts []string
+		for _, row := range page {
+			hosts = append(hosts, row.HostFQDN)
+		}
+		if got := strings.Join(hosts, " "); got != tt.want {
+			t.Errorf("Paginate(%+v) = %q, want %q", tt.page, got, tt.want)
+		}
+	}
+
+	if rows[0].HostFQDN != "a" || rows[1].HostFQDN != "b" {
+		t.Error("Paginate must not reorder the rows it is given")
+	}
+	for _, page := range []reports.Page{{Sort: "cores"}, {Limit: -1}, {Offset: -1}} {
+		if _, err := reports.Paginate(rows, page); err == nil {
+			t.Errorf("Expected an error for %+v", page)
+		}
+	}
+}
@@ -1,12 +1,12 @@
 package reports
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
-	"text/tabwriter"
 )
 
 // PeakBreakdownRow represents a row from v_peak_usage_breakdown
@@ -33,6 +33,8 @@ type PeakBreakdownRow struct {
 	OSName               string `json:"os_name"`
 	OSVersion            string `json:"os_version"`
 	IsVirtualized        string `json:"is_virtualized"`
+	PartitionCapCores    sql.NullInt64 `json:"partition_cap_cores"`
+	CoreRule             string `json:"core_rule"` // partition-cap when the license cores are the partition capacity
 	DailyRunningTotal    int    `json:"daily_running_total"`
 	DailyRunningNodes    int    `json:"daily_running_nodes"`
 	DeduplicatedCores    int    `json:"deduplicated_cores"`
@@ -49,7 +51,7 @@ func NewPeakBreakdownReport(db *sql.DB) *PeakBreakdownReport {
 }
 
 // Query retrieves breakdown data for a specific product
-func (r *PeakBreakdownReport) Query(productCode string, fromDate, toDate string) ([]PeakBreakdownRow, error) {
+func (r *PeakBreakdownReport) Query(ctx context.Context, productCode, mode string, fromDate, toDate string) ([]PeakBreakdownRow, error) {
 	query := `
 		SELECT 
 			measurement_date,
@@ -74,10 +76,12 @@ func (r *PeakBreakdownReport) Query(productCode string, fromDate, toDate string)
 			os_name,
 			os_version,
 			is_virtualized,
+			partition_cap_cores,
+			core_rule,
 			daily_running_total,
 			daily_running_nodes,
 			deduplicated_cores
-		FROM v_peak_usage_breakdown
+		FROM ` + cachedSource(r.db, "v_peak_usage_breakdown") + `
 		WHERE 1=1
 	`
 	
@@ -88,6 +92,11 @@ func (r *PeakBreakdownReport) Query(productCode string, fromDate, toDate string)
 		args = append(args, productCode)
 	}
 	
+	if mode != "" {
+		query += " AND mode = ?"
+		args = append(args, mode)
+	}
+	
 	if fromDate != "" {
 		query += " AND measurement_date >= ?"
 		args = append(args, fromDate)
@@ -102,7 +111,7 @@ func (r *PeakBreakdownReport) Query(productCode string, fromDate, toDate string)
 	query += " AND product_status = 'present'"
 	query += " ORDER BY measurement_date DESC, daily_running_total DESC, license_cores DESC"
 	
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query peak breakdown: %w", err)
 	}
@@ -135,6 +144,8 @@ func (r *PeakBreakdownReport) Query(productCode string, fromDate, toDate string)
 			&row.OSName,
 			&row.OSVersion,
 			&row.IsVirtualized,
+			&row.PartitionCapCores,
+			&row.CoreRule,
 			&row.DailyRunningTotal,
 			&row.DailyRunningNodes,
 			&row.DeduplicatedCores,
@@ -155,7 +166,7 @@ func (r *PeakBreakdownReport) WriteTable(w io.Writer, rows []PeakBreakdownRow) e
 		return nil
 	}
 	
-	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	tw := newTableWriter(w)
 	defer tw.Flush()
 	
 	// Print summary header
@@ -167,6 +178,7 @@ func (r *PeakBreakdownReport) WriteTable(w io.Writer, rows []PeakBreakdownRow) e
 	
 	// Group by date
 	currentDate := ""
+	capped := false
 	for _, row := range rows {
 		if row.MeasurementDate != currentDate {
 			if currentDate != "" {
@@ -179,8 +191,8 @@ func (r *PeakBreakdownReport) WriteTable(w io.Writer, rows []PeakBreakdownRow) e
 			fmt.Fprintln(w, "-----------------------------------------------------------------------------------------------------")
 			
 			// Column headers
-			fmt.Fprintln(tw, "HOST\tHOSTNAME\tINST\tVM_CORES\tLIC_CORES\tELIG\tINELIG\tPHYS_HOST\tPHYS_CORES\tOS")
-			fmt.Fprintln(tw, "----\t--------\t----\t--------\t---------\t----\t------\t---------\t----------\t--")
+			fmt.Fprintln(tw, "HOST\tHOSTNAME\tINST\tVM_CORES\tPART_CAP\tLIC_CORES\tELIG\tINELIG\tPHYS_HOST\tPHYS_CORES\tOS")
+			fmt.Fprintln(tw, "----\t--------\t----\t--------\t--------\t---------\t----\t------\t---------\t----------\t--")
 		}
 		
 		physCores := "N/A"
@@ -188,17 +200,28 @@ func (r *PeakBreakdownReport) WriteTable(w io.Writer, rows []PeakBreakdownRow) e
 			physCores = fmt.Sprintf("%d", row.PhysicalHostCores.Int64)
 		}
 		
-		// Format license cores with deduplicated cores in parentheses if applicable
+		partCap := "-"
+		if row.PartitionCapCores.Valid {
+			partCap = fmt.Sprintf("%d", row.PartitionCapCores.Int64)
+		}
+		
+		// Format license cores with deduplicated cores in parentheses if applicable,
+		// and mark the cores limited by a partition cap
 		licCoresDisplay := fmt.Sprintf("%d", row.LicenseCores)
 		if row.DeduplicatedCores > 0 {
 			licCoresDisplay = fmt.Sprintf("(%d)", row.LicenseCores)
 		}
+		if row.CoreRule != "" {
+			licCoresDisplay += "*"
+			capped = true
+		}
 		
-		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%d\t%d\t%s\t%s\t%s %s\n",
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%s\t%d\t%d\t%s\t%s\t%s %s\n",
 			row.MainFQDN,
 			row.Hostname,
 			row.InstanceCount,
 			row.VMCores,
+			partCap,
 			licCoresDisplay,
 			row.EligibleCores,
 			row.IneligibleCores,
@@ -210,17 +233,17 @@ func (r *PeakBreakdownReport) WriteTable(w io.Writer, rows []PeakBreakdownRow) e
 	}
 	
 	fmt.Fprintln(w, "")
+	if capped {
+		tw.Flush()
+		fmt.Fprintln(w, "\n* license cores limited by the partition capacity (PART_CAP) instead of the visible vCPUs")
+	}
 	
-	return nil
+	return writeDailyFootnotes(tw, r.db)
 }
 
-// WriteCSV writes data in CSV format
-func (r *PeakBreakdownReport) WriteCSV(w io.Writer, rows []PeakBreakdownRow) error {
-	writer := csv.NewWriter(w)
-	defer writer.Flush()
-	
-	// Header
-	err := writer.Write([]string{
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *PeakBreakdownReport) csvHeader() []string {
+	return []string{
 		"measurement_date",
 		"product_mnemo_code",
 		"ibm_product_code",
@@ -243,47 +266,66 @@ func (r *PeakBreakdownReport) WriteCSV(w io.Writer, rows []PeakBreakdownRow) err
 		"os_name",
 		"os_version",
 		"is_virtualized",
+		"partition_cap_cores",
+		"core_rule",
 		"daily_running_total",
 		"daily_running_nodes",
-	})
-	if err != nil {
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *PeakBreakdownReport) csvRecord(row PeakBreakdownRow) []string {
+	physCores := ""
+	if row.PhysicalHostCores.Valid {
+		physCores = fmt.Sprintf("%d", row.PhysicalHostCores.Int64)
+	}
+	
+	partCap := ""
+	if row.PartitionCapCores.Valid {
+		partCap = fmt.Sprintf("%d", row.PartitionCapCores.Int64)
+	}
+	
+	return []string{
+		row.MeasurementDate,
+		row.ProductMnemoCode,
+		row.IBMProductCode,
+		row.ProductName,
+		row.Mode,
+		row.MainFQDN,
+		row.Hostname,
+		fmt.Sprintf("%d", row.VMCores),
+		fmt.Sprintf("%d", row.LicenseCores),
+		row.PhysicalHostID,
+		physCores,
+		fmt.Sprintf("%d", row.EligibleCores),
+		fmt.Sprintf("%d", row.IneligibleCores),
+		row.ProcessorEligible,
+		row.OSEligible,
+		row.VirtEligible,
+		row.ProductStatus,
+		fmt.Sprintf("%d", row.InstallCount),
+		fmt.Sprintf("%d", row.InstanceCount),
+		row.OSName,
+		row.OSVersion,
+		row.IsVirtualized,
+		partCap,
+		row.CoreRule,
+		fmt.Sprintf("%d", row.DailyRunningTotal),
+		fmt.Sprintf("%d", row.DailyRunningNodes),
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *PeakBreakdownReport) WriteCSV(w io.Writer, rows []PeakBreakdownRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+	
+	if err := writer.Write(r.csvHeader()); err != nil {
 		return err
 	}
 	
-	// Data rows
 	for _, row := range rows {
-		physCores := ""
-		if row.PhysicalHostCores.Valid {
-			physCores = fmt.Sprintf("%d", row.PhysicalHostCores.Int64)
-		}
-		
-		err := writer.Write([]string{
-			row.MeasurementDate,
-			row.ProductMnemoCode,
-			row.IBMProductCode,
-			row.ProductName,
-			row.Mode,
-			row.MainFQDN,
-			row.Hostname,
-			fmt.Sprintf("%d", row.VMCores),
-			fmt.Sprintf("%d", row.LicenseCores),
-			row.PhysicalHostID,
-			physCores,
-			fmt.Sprintf("%d", row.EligibleCores),
-			fmt.Sprintf("%d", row.IneligibleCores),
-			row.ProcessorEligible,
-			row.OSEligible,
-			row.VirtEligible,
-			row.ProductStatus,
-			fmt.Sprintf("%d", row.InstallCount),
-			fmt.Sprintf("%d", row.InstanceCount),
-			row.OSName,
-			row.OSVersion,
-			row.IsVirtualized,
-			fmt.Sprintf("%d", row.DailyRunningTotal),
-			fmt.Sprintf("%d", row.DailyRunningNodes),
-		})
-		if err != nil {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
 			return err
 		}
 	}
@@ -291,6 +333,35 @@ func (r *PeakBreakdownReport) WriteCSV(w io.Writer, rows []PeakBreakdownRow) err
 	return nil
 }
 
+// WriteXLSX writes an Excel workbook with a per-host breakdown sheet and a
+// "Daily Totals" sheet holding one row per measurement date
+func (r *PeakBreakdownReport) WriteXLSX(w io.Writer, rows []PeakBreakdownRow) error {
+	records := make([][]string, 0, len(rows))
+	var totals [][]string
+	currentDate := ""
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+		if row.MeasurementDate != currentDate {
+			currentDate = row.MeasurementDate
+			totals = append(totals, []string{
+				row.MeasurementDate,
+				row.ProductMnemoCode,
+				fmt.Sprintf("%d", row.DailyRunningTotal),
+				fmt.Sprintf("%d", row.DailyRunningNodes),
+			})
+		}
+	}
+	
+	wb := singleSheet("Breakdown", r.csvHeader(), records)
+	wb.AddSheet("Daily Totals", []string{
+		"measurement_date",
+		"product_mnemo_code",
+		"daily_running_total",
+		"daily_running_nodes",
+	}, totals)
+	return wb.Write(w)
+}
+
 // WriteJSON writes data in JSON format
 func (r *PeakBreakdownReport) WriteJSON(w io.Writer, rows []PeakBreakdownRow) error {
 	encoder := json.NewEncoder(w)
@@ -0,0 +1,94 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+)
+
+// PeakEvidenceReport renders the per-host breakdown of the peak day of a
+// product: the peak of v_peak_usage over the last 31 days, and the nodes of
+// v_peak_usage_breakdown that add up to it
+type PeakEvidenceReport struct {
+	db        *sql.DB
+	breakdown *PeakBreakdownReport
+
+	// peaks are the peaks of the last query, one per product and mode
+	peaks []PeakUsageRow
+}
+
+// NewPeakEvidenceReport creates a new report generator
+func NewPeakEvidenceReport(db *sql.DB) *PeakEvidenceReport {
+	return &PeakEvidenceReport{db: db, breakdown: NewPeakBreakdownReport(db)}
+}
+
+// Peaks returns the peaks of the last query
+func (r *PeakEvidenceReport) Peaks() []PeakUsageRow {
+	return r.peaks
+}
+
+// Query finds the peak date of the product in v_peak_usage and returns the
+// breakdown of that day, one row per running node. A product without a peak in
+// the last 31 days returns no rows.
+func (r *PeakEvidenceReport) Query(ctx context.Context, productCode, mode string) ([]PeakBreakdownRow, error) {
+	peaks, err := NewPeakUsageReport(r.db).Query(ctx, productCode, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	r.peaks = nil
+	var results []PeakBreakdownRow
+	for _, peak := range peaks {
+		if peak.PeakDate == "" {
+			continue
+		}
+		rows, err := r.breakdown.Query(ctx, peak.ProductMnemoCode, peak.Mode, peak.PeakDate, peak.PeakDate)
+		if err != nil {
+			return nil, err
+		}
+		r.peaks = append(r.peaks, peak)
+		results = append(results, rows...)
+	}
+
+	return results, nil
+}
+
+// WriteTable writes the peak of each product followed by the breakdown of its peak day
+func (r *PeakEvidenceReport) WriteTable(w io.Writer, rows []PeakBreakdownRow) error {
+	for _, peak := range r.peaks {
+		fmt.Fprintf(w, "Peak of %s over the last 31 days: %d cores on %d node(s) on %s\n",
+			peak.ProductMnemoCode, peak.PeakRunningTotalCores, peak.PeakRunningNodes, peak.PeakDate)
+		fmt.Fprintf(w, "License term: %s (%s %s)\n\n", peak.TermID, peak.ProgramNumber, peak.ProgramName)
+	}
+	return r.breakdown.WriteTable(w, rows)
+}
+
+// WriteCSV writes the breakdown rows in CSV format
+func (r *PeakEvidenceReport) WriteCSV(w io.Writer, rows []PeakBreakdownRow) error {
+	return r.breakdown.WriteCSV(w, rows)
+}
+
+// WriteJSON writes the breakdown rows in JSON format
+func (r *PeakEvidenceReport) WriteJSON(w io.Writer, rows []PeakBreakdownRow) error {
+	return r.breakdown.WriteJSON(w, rows)
+}
+
+// WriteXLSX writes an Excel workbook with the breakdown of the peak day and a
+// "Peak" sheet holding the peak of each product
+func (r *PeakEvidenceReport) WriteXLSX(w io.Writer, rows []PeakBreakdownRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.breakdown.csvRecord(row))
+	}
+
+	usage := NewPeakUsageReport(r.db)
+	peaks := make([][]string, 0, len(r.peaks))
+	for _, peak := range r.peaks {
+		peaks = append(peaks, usage.csvRecord(peak))
+	}
+
+	wb := singleSheet("Peak Day Breakdown", r.breakdown.csvHeader(), records)
+	wb.AddSheet("Peak", usage.csvHeader(), peaks)
+	return wb.Write(w)
+}
@@ -0,0 +1,67 @@
+package reports_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestPeakEvidence(t *testing.T) {
+	today := time.Now().UTC()
+	peakDay := today.AddDate(0, 0, -3).Format("2006-01-02")
+	var stmts []string
+	for _, m := range []struct {
+		fqdn string
+		day  string
+		cpus int
+	}{
+		{"app01.example.com", peakDay, 4},
+		{"app02.example.com", peakDay, 8},
+		{"app01.example.com", today.AddDate(0, 0, -1).Format("2006-01-02"), 4},
+	} {
+		stmts = append(stmts,
+			fmt.Sprintf(`INSERT OR IGNORE INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('%s', '%s', 'PROD')`,
+				m.fqdn, strings.Split(m.fqdn, ".")[0]),
+			fmt.Sprintf(`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count, is_virtualized,
+				virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus)
+				VALUES ('%s', '%s 08:00:00', 'Linux', '9', %d, 'no', '', 'unknown', 'true', 'true', 'true', %d)`,
+				m.fqdn, m.day, m.cpus, m.cpus),
+			fmt.Sprintf(`INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, install_count)
+				VALUES ('%s', 'IS_ONP_PRD', '%s 08:00:00', 'present', 1)`, m.fqdn, m.day))
+	}
+	db := newTestDB(t, stmts...)
+
+	report := reports.NewPeakEvidenceReport(db)
+	rows, err := report.Query(t.Context(), "IS_ONP_PRD", "")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if peaks := report.Peaks(); len(peaks) != 1 || peaks[0].PeakDate != peakDay || peaks[0].PeakRunningTotalCores != 12 {
+		t.Fatalf("Unexpected peak: %+v", peaks)
+	}
+	var hosts []string
+	for _, row := range rows {
+		if row.MeasurementDate != peakDay || row.DailyRunningTotal != 12 {
+			t.Errorf("Unexpected breakdown row: %+v", row)
+		}
+		hosts = append(hosts, fmt.Sprintf("%s:%d", row.Hostname, row.LicenseCores))
+	}
+	if got := strings.Join(hosts, " "); got != "app02:8 app01:4" {
+		t.Errorf("Unexpected peak day nodes: %s", got)
+	}
+
+	var out strings.Builder
+	if err := report.WriteTable(&out, rows); err != nil {
+		t.Fatalf("WriteTable failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Peak of IS_ONP_PRD over the last 31 days: 12 cores on 2 node(s) on "+peakDay) {
+		t.Errorf("Missing peak line:\n%s", out.String())
+	}
+
+	if rows, err := report.Query(t.Context(), "UNKNOWN", ""); err != nil || len(rows) != 0 {
+		t.Errorf("Expected no rows for an unknown product, got %+v (%v)", rows, err)
+	}
+}
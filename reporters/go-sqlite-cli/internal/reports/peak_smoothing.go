@@ -0,0 +1,133 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+)
+
+// DailyCores is the license cores of a product on one day
+type DailyCores struct {
+	Date  string // YYYY-MM-DD
+	Cores int
+}
+
+// SmoothedPeak returns the highest license cores held for at least graceDays
+// consecutive days with measurements, and the first day it was held, ignoring
+// shorter spikes. days must be ordered by date. When there are fewer days than
+// the grace window, the lowest day is returned, as no level was held long
+// enough. A grace window of 1 day or less returns the raw peak.
+func SmoothedPeak(days []DailyCores, graceDays int) (int, string) {
+	if len(days) == 0 {
+		return 0, ""
+	}
+	if graceDays < 1 {
+		graceDays = 1
+	}
+	if len(days) < graceDays {
+		graceDays = len(days)
+	}
+
+	peak, peakDate := -1, ""
+	for start := 0; start+graceDays <= len(days); start++ {
+		level := days[start].Cores
+		for _, day := range days[start+1 : start+graceDays] {
+			if day.Cores < level {
+				level = day.Cores
+			}
+		}
+		if level > peak {
+			peak, peakDate = level, days[start].Date
+		}
+	}
+	return peak, peakDate
+}
+
+// applyGraceWindows sets the grace window and smoothed peak of the rows. The
+// grace window of a product comes from peak_grace_windows, or else from the
+// default of the report. The daily license cores are read between the dates
+// returned by period for each row; rows with a grace window take their raw
+// running peak from these daily cores too.
+func (r *PeakUsageReport) applyGraceWindows(ctx context.Context, rows []PeakUsageRow, period func(PeakUsageRow) (string, string)) error {
+	graceDays := map[string]int{}
+	windows, err := r.db.QueryContext(ctx, "SELECT product_mnemo_code, grace_days FROM peak_grace_windows")
+	if err != nil {
+		return fmt.Errorf("failed to query peak grace windows: %w", err)
+	}
+	for windows.Next() {
+		var code string
+		var days int
+		if err := windows.Scan(&code, &days); err != nil {
+			windows.Close()
+			return fmt.Errorf("failed to scan grace window: %w", err)
+		}
+		graceDays[code] = days
+	}
+	windows.Close()
+	if err := windows.Err(); err != nil {
+		return err
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		row.GraceDays = r.defaultGraceDays
+		if days, ok := graceDays[row.ProductMnemoCode]; ok {
+			row.GraceDays = days
+		}
+		row.SmoothedRunningCores, row.SmoothedPeakDate = row.PeakRunningTotalCores, row.PeakDate
+		if row.GraceDays <= 1 {
+			continue
+		}
+
+		from, to := period(*row)
+		days, err := r.dailyRunningCores(ctx, row.ProductMnemoCode, from, to)
+		if err != nil {
+			return err
+		}
+		if len(days) == 0 {
+			continue
+		}
+		// The raw peak is taken from the same daily series as the smoothed one,
+		// so that the smoothed peak never exceeds it
+		row.PeakRunningTotalCores, row.PeakDate = SmoothedPeak(days, 1)
+		row.SmoothedRunningCores, row.SmoothedPeakDate = SmoothedPeak(days, row.GraceDays)
+	}
+	return nil
+}
+
+// dailyRunningCores returns the running license cores of a product per day
+// between two dates (YYYY-MM-DD, inclusive), with the measurements carried
+// forward when the report carries them
+func (r *PeakUsageReport) dailyRunningCores(ctx context.Context, productCode, from, to string) ([]DailyCores, error) {
+	if r.carryForwardDays > 0 {
+		carried, err := r.carriedDailyCores(ctx, productCode, from, to)
+		if err != nil {
+			return nil, err
+		}
+		days := make([]DailyCores, 0, len(carried))
+		for _, day := range carried {
+			days = append(days, day.DailyCores)
+		}
+		return days, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT measurement_date, running_license_cores
+		FROM v_daily_license_cores
+		WHERE product_mnemo_code = ? AND measurement_date BETWEEN ? AND ?
+		ORDER BY measurement_date
+	`, productCode, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily license cores of %s: %w", productCode, err)
+	}
+	defer rows.Close()
+
+	var days []DailyCores
+	for rows.Next() {
+		var day DailyCores
+		if err := rows.Scan(&day.Date, &day.Cores); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
@@ -0,0 +1,88 @@
+package reports_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestSmoothedPeak(t *testing.T) {
+	// A two-day DR failover spike to 32 cores over a baseline of 16, and a
+	// three-day increase to 24 cores
+	days := []reports.DailyCores{
+		{Date: "2025-10-01", Cores: 16},
+		{Date: "2025-10-02", Cores: 32},
+		{Date: "2025-10-03", Cores: 32},
+		{Date: "2025-10-04", Cores: 16},
+		{Date: "2025-10-05", Cores: 24},
+		{Date: "2025-10-06", Cores: 24},
+		{Date: "2025-10-07", Cores: 24},
+		{Date: "2025-10-08", Cores: 16},
+	}
+
+	tests := []struct {
+		graceDays int
+		cores     int
+		date      string
+	}{
+		{0, 32, "2025-10-02"},
+		{1, 32, "2025-10-02"},
+		{2, 32, "2025-10-02"},
+		{3, 24, "2025-10-05"},
+		{4, 16, "2025-10-01"},
+		{20, 16, "2025-10-01"}, // fewer days than the grace window
+	}
+	for _, tt := range tests {
+		cores, date := reports.SmoothedPeak(days, tt.graceDays)
+		if cores != tt.cores || date != tt.date {
+			t.Errorf("SmoothedPeak(%d days) = %d on %s, want %d on %s", tt.graceDays, cores, date, tt.cores, tt.date)
+		}
+	}
+
+	if cores, date := reports.SmoothedPeak(nil, 3); cores != 0 || date != "" {
+		t.Errorf("Expected no peak without days, got %d on %s", cores, date)
+	}
+}
+
+func TestPeakUsageGraceWindow(t *testing.T) {
+	// app01 runs 4 cores for five days; app02 adds 8 cores on a single day
+	today := time.Now().UTC()
+	spikeDay := today.AddDate(0, 0, -3).Format("2006-01-02")
+	stmts := []string{
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES
+			('app01.example.com', 'app01', 'PROD'), ('app02.example.com', 'app02', 'PROD')`,
+	}
+	measure := func(fqdn, day string, cpus int) {
+		stmts = append(stmts,
+			fmt.Sprintf(`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count, is_virtualized,
+				virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus)
+				VALUES ('%s', '%s 08:00:00', 'Linux', '9', %d, 'no', '', 'unknown', 'true', 'true', 'true', %d)`,
+				fqdn, day, cpus, cpus),
+			fmt.Sprintf(`INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, install_count)
+				VALUES ('%s', 'IS_ONP_PRD', '%s 08:00:00', 'present', 1)`, fqdn, day))
+	}
+	for i := 5; i >= 1; i-- {
+		measure("app01.example.com", today.AddDate(0, 0, -i).Format("2006-01-02"), 4)
+	}
+	measure("app02.example.com", spikeDay, 8)
+	db := newTestDB(t, stmts...)
+
+	report := reports.NewPeakUsageReport(db)
+	report.SetDefaultGraceDays(2)
+	rows, err := report.Query(t.Context(), "IS_ONP_PRD", "")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("Expected one row, got %+v", rows)
+	}
+	row := rows[0]
+	if row.PeakRunningTotalCores != 12 || row.PeakDate != spikeDay {
+		t.Errorf("Raw peak = %d on %s, want 12 on %s", row.PeakRunningTotalCores, row.PeakDate, spikeDay)
+	}
+	if row.GraceDays != 2 || row.SmoothedRunningCores != 4 {
+		t.Errorf("Smoothed peak = %d over %d days, want 4 over 2 days", row.SmoothedRunningCores, row.GraceDays)
+	}
+}
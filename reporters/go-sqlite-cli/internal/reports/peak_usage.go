@@ -1,12 +1,13 @@
 package reports
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
-	"text/tabwriter"
+	"time"
 )
 
 // PeakUsageRow represents a row from v_peak_usage
@@ -30,11 +31,31 @@ type PeakUsageRow struct {
 	PeakIneligibleCores        int    `json:"peak_ineligible_cores"`
 	PeakActualVCores           int    `json:"peak_actual_vcores"`
 	PeakDate                   string `json:"peak_date"`
+	PeakRunningPVU             int    `json:"peak_running_pvu"`
+	UnmappedPVUNodes           int    `json:"unmapped_pvu_nodes"`
+	// Running peak ignoring spikes shorter than the grace window of the product.
+	// With a grace window, PeakRunningTotalCores and PeakDate are the raw peak
+	// of the same daily license cores.
+	GraceDays            int    `json:"grace_days"` // 0 without grace window
+	SmoothedRunningCores int    `json:"smoothed_running_cores"`
+	SmoothedPeakDate     string `json:"smoothed_peak_date"`
+	// Contract period the peaks were computed in, empty for the last 31 days
+	ContractPeriod string `json:"contract_period,omitempty"`
+	PeriodStart    string `json:"period_start,omitempty"`
+	PeriodEnd      string `json:"period_end,omitempty"`
+	// Nodes whose last measurement was carried forward to the peak day
+	// (see SetCarryForwardDays)
+	CarriedForwardNodes int `json:"carried_forward_nodes"`
 }
 
 // PeakUsageReport generates reports from v_peak_usage view
 type PeakUsageReport struct {
 	db *sql.DB
+
+	// defaultGraceDays is the grace window of products without their own
+	defaultGraceDays int
+	// carryForwardDays is the most days a measurement is carried forward
+	carryForwardDays int
 }
 
 // NewPeakUsageReport creates a new report generator
@@ -42,8 +63,15 @@ func NewPeakUsageReport(db *sql.DB) *PeakUsageReport {
 	return &PeakUsageReport{db: db}
 }
 
-// Query retrieves data from the view with optional filters
-func (r *PeakUsageReport) Query(productCode string) ([]PeakUsageRow, error) {
+// SetDefaultGraceDays sets the grace window of the smoothed peak for products
+// without a grace window in peak_grace_windows
+func (r *PeakUsageReport) SetDefaultGraceDays(days int) {
+	r.defaultGraceDays = days
+}
+
+// Query retrieves data from the view with optional filters. The smoothed peak
+// is computed over the same 31 days.
+func (r *PeakUsageReport) Query(ctx context.Context, productCode, mode string) ([]PeakUsageRow, error) {
 	query := `
 		SELECT 
 			product_mnemo_code,
@@ -64,8 +92,17 @@ func (r *PeakUsageReport) Query(productCode string) ([]PeakUsageRow, error) {
 			peak_eligible_cores,
 			peak_ineligible_cores,
 			peak_actual_vcores,
-			peak_date
-		FROM v_peak_usage
+			peak_date,
+			-- PVU peak of the same 31 days, from the daily PVU view
+			COALESCE((SELECT MAX(pv.running_license_pvu)
+			 FROM v_daily_license_pvu pv
+			 WHERE pv.product_mnemo_code = u.product_mnemo_code
+			   AND pv.measurement_date >= DATE('now', '-31 days')), 0),
+			COALESCE((SELECT MAX(pv.unmapped_nodes)
+			 FROM v_daily_license_pvu pv
+			 WHERE pv.product_mnemo_code = u.product_mnemo_code
+			   AND pv.measurement_date >= DATE('now', '-31 days')), 0)
+		FROM ` + cachedSource(r.db, "v_peak_usage") + ` u
 		WHERE 1=1
 	`
 	
@@ -76,9 +113,14 @@ func (r *PeakUsageReport) Query(productCode string) ([]PeakUsageRow, error) {
 		args = append(args, productCode)
 	}
 	
+	if mode != "" {
+		query += " AND mode = ?"
+		args = append(args, mode)
+	}
+	
 	query += " ORDER BY peak_running_total_cores DESC, product_mnemo_code"
 	
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query peak usage: %w", err)
 	}
@@ -108,6 +150,8 @@ func (r *PeakUsageReport) Query(productCode string) ([]PeakUsageRow, error) {
 			&row.PeakIneligibleCores,
 			&row.PeakActualVCores,
 			&row.PeakDate,
+			&row.PeakRunningPVU,
+			&row.UnmappedPVUNodes,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
@@ -115,26 +159,209 @@ func (r *PeakUsageReport) Query(productCode string) ([]PeakUsageRow, error) {
 		
 		results = append(results, row)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 	
-	return results, rows.Err()
+	from := time.Now().UTC().AddDate(0, 0, -31).Format("2006-01-02")
+	window := func(PeakUsageRow) (string, string) {
+		return from, "9999-12-31"
+	}
+	if err := r.applyCarryForward(ctx, results, window); err != nil {
+		return nil, err
+	}
+	err = r.applyGraceWindows(ctx, results, window)
+	return results, err
+}
+
+// QueryPeriod retrieves the peaks of each product within a contract period of
+// its license term instead of the last 31 days. selector is current, previous
+// or the label of a period; products whose term has no such period are left
+// out. The peak date is the earliest day of the running peak. The smoothed peak
+// is computed within the period too.
+func (r *PeakUsageReport) QueryPeriod(ctx context.Context, productCode, mode, selector string) ([]PeakUsageRow, error) {
+	periods, args := selectedPeriodsCTE(selector)
+
+	filters := ""
+	if productCode != "" {
+		filters += " AND dlc.product_mnemo_code = ?"
+		args = append(args, productCode)
+	}
+	if mode != "" {
+		filters += " AND COALESCE(p.mode, '') = ?"
+		args = append(args, mode)
+	}
+
+	query := `
+		WITH selected_periods AS (` + periods + `),
+		period_days AS (
+			SELECT
+				dlc.*,
+				COALESCE(p.ibm_product_code, '') AS ibm_product_code,
+				p.product_name,
+				COALESCE(p.mode, '') AS mode,
+				l.term_id,
+				l.program_number,
+				l.program_name,
+				sp.label,
+				sp.start_date,
+				sp.end_date,
+				ROW_NUMBER() OVER (
+					PARTITION BY dlc.product_mnemo_code, l.term_id
+					ORDER BY dlc.running_license_cores DESC, dlc.measurement_date
+				) AS running_rank
+			FROM v_daily_license_cores dlc
+			JOIN product_codes p ON p.product_mnemo_code = dlc.product_mnemo_code
+			JOIN license_terms l ON l.term_id = ` + productTermColumn("p", "dlc.measurement_date") + `
+			JOIN selected_periods sp ON sp.term_id = l.term_id
+			WHERE dlc.measurement_date BETWEEN sp.start_date AND sp.end_date` + filters + `
+		),
+		actual_days AS (
+			-- Actual cores of the running nodes (regardless of eligibility)
+			SELECT measurement_date, product_mnemo_code, SUM(cores) AS actual_vcores
+			FROM (
+				SELECT
+					DATE(m.detection_timestamp) AS measurement_date,
+					d.product_mnemo_code,
+					d.main_fqdn,
+					MAX(m.cpu_count) AS cores
+				FROM detected_products d
+				JOIN v_reported_measurements m ON d.main_fqdn = m.main_fqdn
+					AND d.detection_timestamp = m.detection_timestamp
+				WHERE d.status = 'present'
+				GROUP BY measurement_date, d.product_mnemo_code, d.main_fqdn
+			)
+			GROUP BY measurement_date, product_mnemo_code
+		)
+		SELECT
+			pd.product_mnemo_code,
+			pd.ibm_product_code,
+			pd.product_name,
+			pd.mode,
+			pd.term_id,
+			pd.program_number,
+			pd.program_name,
+			MAX(pd.running_license_cores),
+			0,
+			MAX(pd.running_license_cores),
+			MAX(pd.installed_license_cores),
+			0,
+			MAX(pd.installed_license_cores),
+			MAX(pd.running_nodes),
+			MAX(pd.installed_nodes),
+			MAX(pd.running_eligible_cores),
+			MAX(pd.running_ineligible_cores),
+			COALESCE(MAX(a.actual_vcores), 0),
+			MAX(CASE WHEN pd.running_rank = 1 THEN pd.measurement_date END),
+			COALESCE((SELECT MAX(pv.running_license_pvu)
+			 FROM v_daily_license_pvu pv
+			 WHERE pv.product_mnemo_code = pd.product_mnemo_code
+			   AND pv.measurement_date BETWEEN pd.start_date AND pd.end_date), 0),
+			COALESCE((SELECT MAX(pv.unmapped_nodes)
+			 FROM v_daily_license_pvu pv
+			 WHERE pv.product_mnemo_code = pd.product_mnemo_code
+			   AND pv.measurement_date BETWEEN pd.start_date AND pd.end_date), 0),
+			pd.label,
+			pd.start_date,
+			pd.end_date
+		FROM period_days pd
+		LEFT JOIN actual_days a ON a.measurement_date = pd.measurement_date
+			AND a.product_mnemo_code = pd.product_mnemo_code
+		GROUP BY pd.product_mnemo_code, pd.ibm_product_code, pd.product_name, pd.mode,
+			pd.term_id, pd.program_number, pd.program_name, pd.label, pd.start_date, pd.end_date
+		ORDER BY MAX(pd.running_license_cores) DESC, pd.product_mnemo_code
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query peak usage: %w", err)
+	}
+	defer rows.Close()
+
+	var results []PeakUsageRow
+	for rows.Next() {
+		var row PeakUsageRow
+
+		err := rows.Scan(
+			&row.ProductMnemoCode,
+			&row.IBMProductCode,
+			&row.ProductName,
+			&row.Mode,
+			&row.TermID,
+			&row.ProgramNumber,
+			&row.ProgramName,
+			&row.PeakRunningVCores,
+			&row.PeakRunningPhysicalCores,
+			&row.PeakRunningTotalCores,
+			&row.PeakInstalledVCores,
+			&row.PeakInstalledPhysicalCores,
+			&row.PeakInstalledTotalCores,
+			&row.PeakRunningNodes,
+			&row.PeakInstalledNodes,
+			&row.PeakEligibleCores,
+			&row.PeakIneligibleCores,
+			&row.PeakActualVCores,
+			&row.PeakDate,
+			&row.PeakRunningPVU,
+			&row.UnmappedPVUNodes,
+			&row.ContractPeriod,
+			&row.PeriodStart,
+			&row.PeriodEnd,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	period := func(row PeakUsageRow) (string, string) {
+		return row.PeriodStart, row.PeriodEnd
+	}
+	if err := r.applyCarryForward(ctx, results, period); err != nil {
+		return nil, err
+	}
+	err = r.applyGraceWindows(ctx, results, period)
+	return results, err
 }
 
 // WriteTable writes data in ASCII table format
 func (r *PeakUsageReport) WriteTable(w io.Writer, rows []PeakUsageRow) error {
-	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	tw := newTableWriter(w)
 	defer tw.Flush()
 	
 	// Header
-	fmt.Fprintln(tw, "PRODUCT\tIBM_CODE\tPEAK_CORES\tACTUAL_VC\tPEAK_NODES\tPEAK_DATE\tMODE\tPROGRAM")
-	fmt.Fprintln(tw, "-------\t--------\t----------\t---------\t----------\t---------\t----\t-------")
+	fmt.Fprintln(tw, "PRODUCT\tIBM_CODE\tPEAK_CORES\tSMOOTHED\tACTUAL_VC\tPEAK_PVU\tPEAK_NODES\tPEAK_DATE\tMODE\tPROGRAM")
+	fmt.Fprintln(tw, "-------\t--------\t----------\t--------\t---------\t--------\t----------\t---------\t----\t-------")
 	
 	// Data rows
+	unmapped := false
+	carried := false
 	for _, row := range rows {
-		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%s\t%s\t%s\n",
+		peakCores := fmt.Sprintf("%d", row.PeakRunningTotalCores)
+		if row.CarriedForwardNodes > 0 {
+			peakCores += "+"
+			carried = true
+		}
+		peakPVU := fmt.Sprintf("%d", row.PeakRunningPVU)
+		if row.UnmappedPVUNodes > 0 {
+			peakPVU += "*"
+			unmapped = true
+		}
+		smoothed := "-"
+		if row.GraceDays > 1 {
+			smoothed = fmt.Sprintf("%d (%dd)", row.SmoothedRunningCores, row.GraceDays)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%d\t%s\t%s\t%s\n",
 			row.ProductMnemoCode,
 			row.IBMProductCode,
-			row.PeakRunningTotalCores,
+			peakCores,
+			smoothed,
 			row.PeakActualVCores,
+			peakPVU,
 			row.PeakRunningNodes,
 			row.PeakDate,
 			row.Mode,
@@ -145,26 +372,60 @@ func (r *PeakUsageReport) WriteTable(w io.Writer, rows []PeakUsageRow) error {
 	// Summary
 	if len(rows) > 0 {
 		totalPeakCores := 0
+		totalSmoothed := 0
 		totalActualVCores := 0
+		totalPeakPVU := 0
+		subtotals := newModeTotals()
 		for _, row := range rows {
 			totalPeakCores += row.PeakRunningTotalCores
+			totalSmoothed += row.SmoothedRunningCores
 			totalActualVCores += row.PeakActualVCores
+			totalPeakPVU += row.PeakRunningPVU
+			subtotals.add(row.Mode, 1, row.PeakRunningTotalCores, row.SmoothedRunningCores, row.PeakActualVCores, row.PeakRunningPVU)
 		}
 		
-		fmt.Fprintln(tw, "-------\t--------\t----------\t---------\t----------\t---------\t----\t-------")
-		fmt.Fprintf(tw, "TOTAL (%d products)\t\t%d\t%d\t\t\t\t\n", len(rows), totalPeakCores, totalActualVCores)
+		fmt.Fprintln(tw, "-------\t--------\t----------\t--------\t---------\t--------\t----------\t---------\t----\t-------")
+		subtotals.each(func(mode string, sums []int) {
+			fmt.Fprintf(tw, "%s (%d products)\t\t%d\t%d\t%d\t%d\t\t\t\t\n", mode, sums[0], sums[1], sums[2], sums[3], sums[4])
+		})
+		fmt.Fprintf(tw, "TOTAL (%d products)\t\t%d\t%d\t%d\t%d\t\t\t\t\n", len(rows), totalPeakCores, totalSmoothed, totalActualVCores, totalPeakPVU)
 	}
 	
-	return nil
+	// Contract periods the peaks were computed in
+	periods := map[string]bool{}
+	for _, row := range rows {
+		key := row.TermID + "\t" + row.ContractPeriod
+		if row.ContractPeriod == "" || periods[key] {
+			continue
+		}
+		if len(periods) == 0 {
+			fmt.Fprintln(tw, "\nContract periods:")
+		}
+		periods[key] = true
+		fmt.Fprintf(tw, "  %s %s: %s to %s\n", row.TermID, row.ContractPeriod, row.PeriodStart, row.PeriodEnd)
+	}
+	
+	for _, row := range rows {
+		if row.GraceDays > 1 {
+			fmt.Fprintln(tw, "\nSMOOTHED ignores spikes shorter than the grace window of the product (see 'iwdlr import grace-windows')")
+			break
+		}
+	}
+	
+	if unmapped {
+		fmt.Fprintln(tw, "\n* Some nodes have no PVU mapping for their processor and count 0 PVUs (see 'iwdlr import pvu')")
+	}
+	
+	if carried {
+		fmt.Fprintf(tw, "\n+ The peak day counts nodes that missed up to %d day(s) of reporting with their last measurement (--carry-forward)\n", r.carryForwardDays)
+	}
+	
+	return writeDailyFootnotes(tw, r.db)
 }
 
-// WriteCSV writes data in CSV format
-func (r *PeakUsageReport) WriteCSV(w io.Writer, rows []PeakUsageRow) error {
-	writer := csv.NewWriter(w)
-	defer writer.Flush()
-	
-	// Header
-	err := writer.Write([]string{
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *PeakUsageReport) csvHeader() []string {
+	return []string{
 		"product_mnemo_code",
 		"ibm_product_code",
 		"product_name",
@@ -184,35 +445,63 @@ func (r *PeakUsageReport) WriteCSV(w io.Writer, rows []PeakUsageRow) error {
 		"peak_ineligible_cores",
 		"peak_actual_vcores",
 		"peak_date",
-	})
-	if err != nil {
+		"peak_running_pvu",
+		"unmapped_pvu_nodes",
+		"grace_days",
+		"smoothed_running_cores",
+		"smoothed_peak_date",
+		"contract_period",
+		"period_start",
+		"period_end",
+		"carried_forward_nodes",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *PeakUsageReport) csvRecord(row PeakUsageRow) []string {
+	return []string{
+		row.ProductMnemoCode,
+		row.IBMProductCode,
+		row.ProductName,
+		row.Mode,
+		row.TermID,
+		row.ProgramNumber,
+		row.ProgramName,
+		fmt.Sprintf("%d", row.PeakRunningVCores),
+		fmt.Sprintf("%d", row.PeakRunningPhysicalCores),
+		fmt.Sprintf("%d", row.PeakRunningTotalCores),
+		fmt.Sprintf("%d", row.PeakInstalledVCores),
+		fmt.Sprintf("%d", row.PeakInstalledPhysicalCores),
+		fmt.Sprintf("%d", row.PeakInstalledTotalCores),
+		fmt.Sprintf("%d", row.PeakRunningNodes),
+		fmt.Sprintf("%d", row.PeakInstalledNodes),
+		fmt.Sprintf("%d", row.PeakEligibleCores),
+		fmt.Sprintf("%d", row.PeakIneligibleCores),
+		fmt.Sprintf("%d", row.PeakActualVCores),
+		row.PeakDate,
+		fmt.Sprintf("%d", row.PeakRunningPVU),
+		fmt.Sprintf("%d", row.UnmappedPVUNodes),
+		fmt.Sprintf("%d", row.GraceDays),
+		fmt.Sprintf("%d", row.SmoothedRunningCores),
+		row.SmoothedPeakDate,
+		row.ContractPeriod,
+		row.PeriodStart,
+		row.PeriodEnd,
+		fmt.Sprintf("%d", row.CarriedForwardNodes),
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *PeakUsageReport) WriteCSV(w io.Writer, rows []PeakUsageRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+	
+	if err := writer.Write(r.csvHeader()); err != nil {
 		return err
 	}
 	
-	// Data rows
 	for _, row := range rows {
-		err := writer.Write([]string{
-			row.ProductMnemoCode,
-			row.IBMProductCode,
-			row.ProductName,
-			row.Mode,
-			row.TermID,
-			row.ProgramNumber,
-			row.ProgramName,
-			fmt.Sprintf("%d", row.PeakRunningVCores),
-			fmt.Sprintf("%d", row.PeakRunningPhysicalCores),
-			fmt.Sprintf("%d", row.PeakRunningTotalCores),
-			fmt.Sprintf("%d", row.PeakInstalledVCores),
-			fmt.Sprintf("%d", row.PeakInstalledPhysicalCores),
-			fmt.Sprintf("%d", row.PeakInstalledTotalCores),
-			fmt.Sprintf("%d", row.PeakRunningNodes),
-			fmt.Sprintf("%d", row.PeakInstalledNodes),
-			fmt.Sprintf("%d", row.PeakEligibleCores),
-			fmt.Sprintf("%d", row.PeakIneligibleCores),
-			fmt.Sprintf("%d", row.PeakActualVCores),
-			row.PeakDate,
-		})
-		if err != nil {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
 			return err
 		}
 	}
@@ -220,6 +509,15 @@ func (r *PeakUsageReport) WriteCSV(w io.Writer, rows []PeakUsageRow) error {
 	return nil
 }
 
+// WriteXLSX writes an Excel workbook with all products on a single sheet
+func (r *PeakUsageReport) WriteXLSX(w io.Writer, rows []PeakUsageRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return singleSheet("Peak Usage", r.csvHeader(), records).Write(w)
+}
+
 // WriteJSON writes data in JSON format
 func (r *PeakUsageReport) WriteJSON(w io.Writer, rows []PeakUsageRow) error {
 	encoder := json.NewEncoder(w)
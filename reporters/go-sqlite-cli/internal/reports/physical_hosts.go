@@ -1,12 +1,12 @@
 package reports
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
-	"text/tabwriter"
 )
 
 // PhysicalHostRow represents a row from v_physical_host_cores_aggregated
@@ -33,7 +33,7 @@ func NewPhysicalHostReport(db *sql.DB) *PhysicalHostReport {
 }
 
 // Query retrieves data from the view with optional filters
-func (r *PhysicalHostReport) Query(systemType string) ([]PhysicalHostRow, error) {
+func (r *PhysicalHostReport) Query(ctx context.Context, systemType string) ([]PhysicalHostRow, error) {
 	query := `
 		SELECT 
 			measurement_date,
@@ -56,7 +56,7 @@ func (r *PhysicalHostReport) Query(systemType string) ([]PhysicalHostRow, error)
 	
 	query += " ORDER BY measurement_date DESC, physical_host_id"
 	
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query physical hosts: %w", err)
 	}
@@ -97,7 +97,7 @@ func (r *PhysicalHostReport) Query(systemType string) ([]PhysicalHostRow, error)
 
 // WriteTable writes data in ASCII table format
 func (r *PhysicalHostReport) WriteTable(w io.Writer, rows []PhysicalHostRow) error {
-	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	tw := newTableWriter(w)
 	defer tw.Flush()
 	
 	// Header
@@ -129,16 +129,12 @@ func (r *PhysicalHostReport) WriteTable(w io.Writer, rows []PhysicalHostRow) err
 		fmt.Fprintf(tw, "TOTAL (%d hosts)\t\t\t\t%d\t%d\t%d\n", len(rows), totalPhysCores, totalVMs, totalVMCores)
 	}
 	
-	return nil
+	return writeDailyFootnotes(tw, r.db)
 }
 
-// WriteCSV writes data in CSV format
-func (r *PhysicalHostReport) WriteCSV(w io.Writer, rows []PhysicalHostRow) error {
-	writer := csv.NewWriter(w)
-	defer writer.Flush()
-	
-	// Header
-	err := writer.Write([]string{
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *PhysicalHostReport) csvHeader() []string {
+	return []string{
 		"measurement_date",
 		"physical_host_id",
 		"host_id_method",
@@ -148,25 +144,35 @@ func (r *PhysicalHostReport) WriteCSV(w io.Writer, rows []PhysicalHostRow) error
 		"vm_list",
 		"total_vm_cores",
 		"latest_measurement",
-	})
-	if err != nil {
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *PhysicalHostReport) csvRecord(row PhysicalHostRow) []string {
+	return []string{
+		row.MeasurementDate,
+		row.PhysicalHostID,
+		row.HostIDMethod,
+		row.HostIDConfidence,
+		fmt.Sprintf("%d", row.PhysicalCores),
+		fmt.Sprintf("%d", row.VMCount),
+		row.VMList,
+		fmt.Sprintf("%d", row.TotalVMCores),
+		row.LatestMeasurement,
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *PhysicalHostReport) WriteCSV(w io.Writer, rows []PhysicalHostRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+	
+	if err := writer.Write(r.csvHeader()); err != nil {
 		return err
 	}
 	
-	// Data rows
 	for _, row := range rows {
-		err := writer.Write([]string{
-			row.MeasurementDate,
-			row.PhysicalHostID,
-			row.HostIDMethod,
-			row.HostIDConfidence,
-			fmt.Sprintf("%d", row.PhysicalCores),
-			fmt.Sprintf("%d", row.VMCount),
-			row.VMList,
-			fmt.Sprintf("%d", row.TotalVMCores),
-			row.LatestMeasurement,
-		})
-		if err != nil {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
 			return err
 		}
 	}
@@ -174,6 +180,15 @@ func (r *PhysicalHostReport) WriteCSV(w io.Writer, rows []PhysicalHostRow) error
 	return nil
 }
 
+// WriteXLSX writes an Excel workbook with all physical hosts on a single sheet
+func (r *PhysicalHostReport) WriteXLSX(w io.Writer, rows []PhysicalHostRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return singleSheet("Physical Hosts", r.csvHeader(), records).Write(w)
+}
+
 // WriteJSON writes data in JSON format
 func (r *PhysicalHostReport) WriteJSON(w io.Writer, rows []PhysicalHostRow) error {
 	encoder := json.NewEncoder(w)
@@ -0,0 +1,262 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Product history statuses
+const (
+	ProductHistoryPresent = "present"
+	ProductHistoryGone    = "gone"
+)
+
+// ProductHistoryRow describes when a product appeared on and disappeared from a node
+type ProductHistoryRow struct {
+	MainFQDN       string `json:"main_fqdn"`
+	Hostname       string `json:"hostname"`
+	ProductCode    string `json:"product_code"`
+	ProductName    string `json:"product_name"`
+	Mode           string `json:"mode"` // mode of the product, empty if unknown
+	FirstDetected  string `json:"first_detected"`
+	LastDetected   string `json:"last_detected"`
+	DisappearedAt  string `json:"disappeared_at"` // first measurement without the product, empty while present
+	DetectionCount int    `json:"detection_count"`
+	Status         string `json:"status"` // present or gone
+}
+
+// ProductHistoryReport generates reports from the product_lifecycle table
+type ProductHistoryReport struct {
+	db *sql.DB
+}
+
+// NewProductHistoryReport creates a new report generator
+func NewProductHistoryReport(db *sql.DB) *ProductHistoryReport {
+	return &ProductHistoryReport{db: db}
+}
+
+// Query retrieves one row per node and product ever detected on it. A product
+// disappeared at the first measurement of the node after its last detection.
+// host matches a substring of the main FQDN or hostname, and the mode filter
+// applies to the mode of the product. The dates keep the products that
+// appeared or disappeared in the period.
+func (r *ProductHistoryReport) Query(ctx context.Context, host, productCode, mode string, fromDate, toDate *time.Time) ([]ProductHistoryRow, error) {
+	query := `
+		SELECT
+			h.main_fqdn,
+			h.hostname,
+			h.product_code,
+			h.product_name,
+			h.mode,
+			h.first_detected,
+			h.last_detected,
+			h.disappeared_at,
+			h.detection_count
+		FROM (
+			SELECT
+				l.main_fqdn,
+				COALESCE(n.hostname, '') AS hostname,
+				l.product_mnemo_code AS product_code,
+				COALESCE(p.product_name, '') AS product_name,
+				COALESCE(p.mode, '') AS mode,
+				strftime('%Y-%m-%d %H:%M:%S', l.first_detected) AS first_detected,
+				strftime('%Y-%m-%d %H:%M:%S', l.last_detected) AS last_detected,
+				COALESCE((
+					SELECT strftime('%Y-%m-%d %H:%M:%S', MIN(m.detection_timestamp))
+					FROM measurements m
+					WHERE m.main_fqdn = l.main_fqdn AND m.detection_timestamp > l.last_detected
+				), '') AS disappeared_at,
+				l.detection_count
+			FROM product_lifecycle l
+			LEFT JOIN landscape_nodes n ON n.main_fqdn = l.main_fqdn
+			LEFT JOIN product_codes p ON p.product_mnemo_code = l.product_mnemo_code
+		) h
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if host != "" {
+		query += " AND (h.main_fqdn LIKE ? OR h.hostname LIKE ?)"
+		args = append(args, "%"+host+"%", "%"+host+"%")
+	}
+
+	if productCode != "" {
+		query += " AND h.product_code = ?"
+		args = append(args, productCode)
+	}
+
+	if mode != "" {
+		query += " AND h.mode = ?"
+		args = append(args, mode)
+	}
+
+	// Products that appeared or disappeared in the period
+	if fromDate != nil || toDate != nil {
+		appeared, disappeared := "1=1", "h.disappeared_at <> ''"
+		var appearedArgs, disappearedArgs []interface{}
+		if fromDate != nil {
+			appeared += " AND DATE(h.first_detected) >= ?"
+			disappeared += " AND DATE(h.disappeared_at) >= ?"
+			appearedArgs = append(appearedArgs, fromDate.Format("2006-01-02"))
+			disappearedArgs = append(disappearedArgs, fromDate.Format("2006-01-02"))
+		}
+		if toDate != nil {
+			appeared += " AND DATE(h.first_detected) <= ?"
+			disappeared += " AND DATE(h.disappeared_at) <= ?"
+			appearedArgs = append(appearedArgs, toDate.Format("2006-01-02"))
+			disappearedArgs = append(disappearedArgs, toDate.Format("2006-01-02"))
+		}
+		query += " AND ((" + appeared + ") OR (" + disappeared + "))"
+		args = append(args, appearedArgs...)
+		args = append(args, disappearedArgs...)
+	}
+
+	query += " ORDER BY h.main_fqdn, h.product_code"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product history: %w", err)
+	}
+	defer rows.Close()
+
+	var results []ProductHistoryRow
+	for rows.Next() {
+		var row ProductHistoryRow
+
+		err := rows.Scan(
+			&row.MainFQDN,
+			&row.Hostname,
+			&row.ProductCode,
+			&row.ProductName,
+			&row.Mode,
+			&row.FirstDetected,
+			&row.LastDetected,
+			&row.DisappearedAt,
+			&row.DetectionCount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		row.Status = ProductHistoryPresent
+		if row.DisappearedAt != "" {
+			row.Status = ProductHistoryGone
+		}
+
+		results = append(results, row)
+	}
+
+	return results, rows.Err()
+}
+
+// WriteTable writes data in ASCII table format
+func (r *ProductHistoryReport) WriteTable(w io.Writer, rows []ProductHistoryRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "HOST\tPRODUCT\tMODE\tFIRST_DETECTED\tLAST_DETECTED\tDISAPPEARED\tMEASUREMENTS\tSTATUS")
+	fmt.Fprintln(tw, "----\t-------\t----\t--------------\t-------------\t-----------\t------------\t------")
+
+	// Data rows
+	gone := 0
+	for _, row := range rows {
+		disappeared := row.DisappearedAt
+		if disappeared == "" {
+			disappeared = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			row.MainFQDN,
+			row.ProductCode,
+			row.Mode,
+			row.FirstDetected,
+			row.LastDetected,
+			disappeared,
+			row.DetectionCount,
+			row.Status,
+		)
+		if row.Status == ProductHistoryGone {
+			gone++
+		}
+	}
+
+	// Summary
+	if len(rows) > 0 {
+		tw.Flush()
+		fmt.Fprintf(w, "\n%d products present, %d gone\n", len(rows)-gone, gone)
+	}
+
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *ProductHistoryReport) csvHeader() []string {
+	return []string{
+		"main_fqdn",
+		"hostname",
+		"product_code",
+		"product_name",
+		"mode",
+		"first_detected",
+		"last_detected",
+		"disappeared_at",
+		"detection_count",
+		"status",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *ProductHistoryReport) csvRecord(row ProductHistoryRow) []string {
+	return []string{
+		row.MainFQDN,
+		row.Hostname,
+		row.ProductCode,
+		row.ProductName,
+		row.Mode,
+		row.FirstDetected,
+		row.LastDetected,
+		row.DisappearedAt,
+		fmt.Sprintf("%d", row.DetectionCount),
+		row.Status,
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *ProductHistoryReport) WriteCSV(w io.Writer, rows []ProductHistoryRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *ProductHistoryReport) WriteJSON(w io.Writer, rows []ProductHistoryRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with a single sheet
+func (r *ProductHistoryReport) WriteXLSX(w io.Writer, rows []ProductHistoryRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return singleSheet("Product history", r.csvHeader(), records).Write(w)
+}
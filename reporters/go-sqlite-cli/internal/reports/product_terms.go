@@ -0,0 +1,19 @@
+package reports
+
+import "fmt"
+
+// productTermColumn returns a SQL expression selecting the license term a
+// product code is attributed to on a day: the term of the product term mapping
+// in force that day, otherwise the term_id of the product_codes row aliased
+// productAlias. A product moved to another program keeps its former term on
+// the days before the move.
+func productTermColumn(productAlias, dateColumn string) string {
+	return fmt.Sprintf(`COALESCE((
+		SELECT tm.term_id FROM product_term_mappings tm
+		WHERE tm.product_mnemo_code = %[1]s.product_mnemo_code
+		  AND DATE(tm.effective_from) <= %[2]s
+		  AND (tm.effective_to IS NULL OR DATE(tm.effective_to) >= %[2]s)
+		ORDER BY tm.effective_from DESC
+		LIMIT 1
+	), %[1]s.term_id)`, productAlias, dateColumn)
+}
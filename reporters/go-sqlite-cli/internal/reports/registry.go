@@ -0,0 +1,80 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Params are the filters of the report commands passed to registered reports
+type Params struct {
+	ProductCode string
+	Mode        string // PROD, NON PROD or empty for both (see ParseMode)
+	From, To    *time.Time
+}
+
+// Reporter is a report discovered at run time rather than built into the
+// report command: the CLI adds a report command for each registered reporter,
+// with the filters and output flags of the other reports. Its rows are the
+// values of its columns, known once it has been queried.
+type Reporter interface {
+	// Name is the name of the report command
+	Name() string
+	// Description is the one line help of the report command
+	Description() string
+
+	// Query returns the rows of the report matching the filters
+	Query(ctx context.Context, db *sql.DB, params Params) ([]SQLRow, error)
+	// Columns returns the column names of the rows of the last query, the
+	// names of the CSV header and of the JSON members
+	Columns() []string
+
+	WriteTable(w io.Writer, rows []SQLRow) error
+	WriteCSV(w io.Writer, rows []SQLRow) error
+	WriteJSON(w io.Writer, rows []SQLRow) error
+	WriteXLSX(w io.Writer, rows []SQLRow) error
+}
+
+// registry holds the registered reporters by name
+var registry = struct {
+	sync.Mutex
+	reporters map[string]Reporter
+}{reporters: map[string]Reporter{}}
+
+// Register adds a reporter to the registry. Names are unique.
+func Register(r Reporter) error {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.reporters[r.Name()]; ok {
+		return fmt.Errorf("report %q is already registered", r.Name())
+	}
+	registry.reporters[r.Name()] = r
+	return nil
+}
+
+// Registered returns the registered reporters sorted by name
+func Registered() []Reporter {
+	registry.Lock()
+	defer registry.Unlock()
+
+	reporters := make([]Reporter, 0, len(registry.reporters))
+	for _, r := range registry.reporters {
+		reporters = append(reporters, r)
+	}
+	sort.Slice(reporters, func(i, j int) bool { return reporters[i].Name() < reporters[j].Name() })
+	return reporters
+}
+
+// LookupReporter returns the registered reporter of a name
+func LookupReporter(name string) (Reporter, bool) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	r, ok := registry.reporters[name]
+	return r, ok
+}
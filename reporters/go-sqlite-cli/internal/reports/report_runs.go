@@ -0,0 +1,456 @@
+package reports
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Trigger sources of report runs
+const (
+	RunTriggerManual   = "manual"   // report run with --record
+	RunTriggerSchedule = "schedule" // scheduled job of the daemon
+	RunTriggerQueue    = "queue"    // queued job of 'iwdlr jobs'
+)
+
+// Statuses of report runs
+const (
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+// States of the artifacts of a report run compared with the file on disk
+const (
+	ArtifactUnchanged = "unchanged"
+	ArtifactChanged   = "changed"
+	ArtifactMissing   = "missing"
+)
+
+// ReportRun is a report written by the daemon, the job queue or a report run
+// with --record, with the files it wrote
+type ReportRun struct {
+	RunID       int64             `json:"run_id"`
+	Report      string            `json:"report"`
+	CommandLine string            `json:"command_line"`
+	Parameters  map[string]string `json:"parameters"`
+	Format      string            `json:"format"`
+	Trigger     string            `json:"trigger"`
+	JobName     string            `json:"job_name"`
+	StartedAt   string            `json:"started_at"`
+	FinishedAt  string            `json:"finished_at"`
+	Status      string            `json:"status"`
+	RowCount    int               `json:"row_count"`
+	DataAsOf    string            `json:"data_as_of"` // latest measurement when the report ran
+	Error       string            `json:"error_message"`
+	Artifacts   []ReportArtifact  `json:"artifacts"`
+}
+
+// ReportArtifact is a file written by a report run
+type ReportArtifact struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// State compares the file on disk with the checksum recorded, set by Show
+	State string `json:"state,omitempty"`
+}
+
+// RecordReportRun stores a report run with the size and checksum of the files
+// at paths, and sets its ID. The run is stored with the latest measurement of
+// the database, so that the data the report was computed from is known.
+func RecordReportRun(db *sql.DB, run *ReportRun, paths []string) error {
+	run.Artifacts = nil
+	for _, path := range paths {
+		artifact, err := newReportArtifact(path)
+		if err != nil {
+			return err
+		}
+		run.Artifacts = append(run.Artifacts, artifact)
+	}
+
+	parameters, err := json.Marshal(run.Parameters)
+	if err != nil {
+		return err
+	}
+	if run.Parameters == nil {
+		parameters = []byte("{}")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var dataAsOf sql.NullString
+	if err := tx.QueryRow(`SELECT strftime('%Y-%m-%d %H:%M:%S', MAX(detection_timestamp)) FROM measurements`).Scan(&dataAsOf); err != nil {
+		return fmt.Errorf("failed to read the latest measurement: %w", err)
+	}
+	run.DataAsOf = dataAsOf.String
+
+	res, err := tx.Exec(`
+		INSERT INTO report_runs (report, command_line, parameters, format, trigger_source, job_name,
+			started_at, finished_at, status, row_count, data_as_of, error_message)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.Report, run.CommandLine, string(parameters), run.Format, run.Trigger, run.JobName,
+		run.StartedAt, run.FinishedAt, run.Status, run.RowCount, dataAsOf, run.Error)
+	if err != nil {
+		return fmt.Errorf("failed to record report run: %w", err)
+	}
+	if run.RunID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+
+	for _, artifact := range run.Artifacts {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO report_run_artifacts (run_id, path, size, sha256) VALUES (?, ?, ?, ?)`,
+			run.RunID, artifact.Path, artifact.Size, artifact.SHA256); err != nil {
+			return fmt.Errorf("failed to record report artifact %s: %w", artifact.Path, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// newReportArtifact returns the absolute path, size and checksum of a file
+func newReportArtifact(path string) (ReportArtifact, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ReportArtifact{}, fmt.Errorf("invalid report file %s: %w", path, err)
+	}
+	size, sum, err := fileChecksum(abs)
+	if err != nil {
+		return ReportArtifact{}, fmt.Errorf("failed to read report file: %w", err)
+	}
+	return ReportArtifact{Path: abs, Size: size, SHA256: sum}, nil
+}
+
+// fileChecksum returns the size and hex SHA-256 of a file
+func fileChecksum(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ReportRunsReport lists the report runs recorded in report_runs
+type ReportRunsReport struct {
+	db *sql.DB
+}
+
+// NewReportRunsReport creates a new report generator
+func NewReportRunsReport(db *sql.DB) *ReportRunsReport {
+	return &ReportRunsReport{db: db}
+}
+
+// Query retrieves the report runs with their artifacts, newest first. report
+// and trigger select the runs of a report command and a trigger source,
+// checksum the runs that wrote a file with this SHA-256, and the dates filter
+// on the day the run started.
+func (r *ReportRunsReport) Query(ctx context.Context, report, trigger, checksum string, fromDate, toDate *time.Time) ([]ReportRun, error) {
+	query := `
+		SELECT run_id, report, command_line, parameters, format, trigger_source, job_name,
+			strftime('%Y-%m-%d %H:%M:%S', started_at), strftime('%Y-%m-%d %H:%M:%S', finished_at),
+			status, row_count, COALESCE(strftime('%Y-%m-%d %H:%M:%S', data_as_of), ''), COALESCE(error_message, '')
+		FROM report_runs
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if report != "" {
+		query += " AND report = ?"
+		args = append(args, report)
+	}
+
+	if trigger != "" {
+		query += " AND trigger_source = ?"
+		args = append(args, trigger)
+	}
+
+	if checksum != "" {
+		query += " AND run_id IN (SELECT run_id FROM report_run_artifacts WHERE sha256 = ?)"
+		args = append(args, strings.ToLower(checksum))
+	}
+
+	if fromDate != nil {
+		query += " AND DATE(started_at) >= ?"
+		args = append(args, fromDate.Format("2006-01-02"))
+	}
+
+	if toDate != nil {
+		query += " AND DATE(started_at) <= ?"
+		args = append(args, toDate.Format("2006-01-02"))
+	}
+
+	query += " ORDER BY started_at DESC, run_id DESC"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report runs: %w", err)
+	}
+	defer rows.Close()
+
+	var results []ReportRun
+	index := make(map[int64]int)
+	for rows.Next() {
+		var run ReportRun
+		var parameters string
+		err := rows.Scan(
+			&run.RunID,
+			&run.Report,
+			&run.CommandLine,
+			&parameters,
+			&run.Format,
+			&run.Trigger,
+			&run.JobName,
+			&run.StartedAt,
+			&run.FinishedAt,
+			&run.Status,
+			&run.RowCount,
+			&run.DataAsOf,
+			&run.Error,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(parameters), &run.Parameters); err != nil {
+			return nil, fmt.Errorf("invalid parameters of report run %d: %w", run.RunID, err)
+		}
+		index[run.RunID] = len(results)
+		results = append(results, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	artifacts, err := r.db.QueryContext(ctx, `SELECT run_id, path, size, sha256 FROM report_run_artifacts ORDER BY run_id, path`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report artifacts: %w", err)
+	}
+	defer artifacts.Close()
+
+	for artifacts.Next() {
+		var runID int64
+		var artifact ReportArtifact
+		if err := artifacts.Scan(&runID, &artifact.Path, &artifact.Size, &artifact.SHA256); err != nil {
+			return nil, fmt.Errorf("failed to scan artifact: %w", err)
+		}
+		if i, ok := index[runID]; ok {
+			results[i].Artifacts = append(results[i].Artifacts, artifact)
+		}
+	}
+	return results, artifacts.Err()
+}
+
+// Show returns a report run and compares each of its files with the checksum
+// recorded: unchanged, changed or missing
+func (r *ReportRunsReport) Show(ctx context.Context, runID int64) (*ReportRun, error) {
+	runs, err := r.Query(ctx, "", "", "", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range runs {
+		if runs[i].RunID != runID {
+			continue
+		}
+		run := &runs[i]
+		for j := range run.Artifacts {
+			run.Artifacts[j].State = artifactState(run.Artifacts[j])
+		}
+		return run, nil
+	}
+	return nil, fmt.Errorf("report run %d not found", runID)
+}
+
+// artifactState compares an artifact with its file on disk
+func artifactState(artifact ReportArtifact) string {
+	_, sum, err := fileChecksum(artifact.Path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return ArtifactMissing
+	case err != nil || sum != artifact.SHA256:
+		return ArtifactChanged
+	}
+	return ArtifactUnchanged
+}
+
+// WriteTable writes data in ASCII table format
+func (r *ReportRunsReport) WriteTable(w io.Writer, rows []ReportRun) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "RUN\tSTARTED\tREPORT\tTRIGGER\tJOB\tSTATUS\tROWS\tDATA_AS_OF\tFILES")
+	fmt.Fprintln(tw, "---\t-------\t------\t-------\t---\t------\t----\t----------\t-----")
+
+	// Data rows
+	for _, row := range rows {
+		files := make([]string, 0, len(row.Artifacts))
+		for _, artifact := range row.Artifacts {
+			files = append(files, filepath.Base(artifact.Path))
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			row.RunID,
+			row.StartedAt,
+			row.Report,
+			row.Trigger,
+			orDash(row.JobName),
+			row.Status,
+			row.RowCount,
+			orDash(row.DataAsOf),
+			orDash(strings.Join(files, ", ")),
+		)
+	}
+
+	return nil
+}
+
+// WriteRun writes a report run with its parameters and files, as shown by
+// 'report runs show'
+func (r *ReportRunsReport) WriteRun(w io.Writer, run *ReportRun) error {
+	fmt.Fprintf(w, "Report run %d: %s (%s)\n", run.RunID, run.Report, run.Status)
+	fmt.Fprintf(w, "  Command:    iwldr %s\n", run.CommandLine)
+	fmt.Fprintf(w, "  Trigger:    %s", run.Trigger)
+	if run.JobName != "" {
+		fmt.Fprintf(w, " (%s)", run.JobName)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "  Started:    %s\n", run.StartedAt)
+	fmt.Fprintf(w, "  Finished:   %s\n", run.FinishedAt)
+	fmt.Fprintf(w, "  Rows:       %d\n", run.RowCount)
+	fmt.Fprintf(w, "  Data as of: %s\n", orDash(run.DataAsOf))
+	if run.Error != "" {
+		fmt.Fprintf(w, "  Error:      %s\n", run.Error)
+	}
+
+	if len(run.Parameters) > 0 {
+		names := make([]string, 0, len(run.Parameters))
+		for name := range run.Parameters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintln(w, "\nParameters:")
+		for _, name := range names {
+			fmt.Fprintf(w, "  --%s %s\n", name, run.Parameters[name])
+		}
+	}
+
+	fmt.Fprintln(w, "\nFiles:")
+	if len(run.Artifacts) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for _, artifact := range run.Artifacts {
+		fmt.Fprintf(w, "  %s\n", artifact.Path)
+		fmt.Fprintf(w, "    %d bytes, sha256 %s", artifact.Size, artifact.SHA256)
+		if artifact.State != "" {
+			fmt.Fprintf(w, ", %s", artifact.State)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *ReportRunsReport) csvHeader() []string {
+	return []string{
+		"run_id",
+		"report",
+		"command_line",
+		"parameters",
+		"format",
+		"trigger",
+		"job_name",
+		"started_at",
+		"finished_at",
+		"status",
+		"row_count",
+		"data_as_of",
+		"error_message",
+		"artifacts",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX
+// outputs; the artifacts are listed as path=sha256, separated by semicolons
+func (r *ReportRunsReport) csvRecord(row ReportRun) []string {
+	parameters, _ := json.Marshal(row.Parameters)
+	artifacts := make([]string, 0, len(row.Artifacts))
+	for _, artifact := range row.Artifacts {
+		artifacts = append(artifacts, artifact.Path+"="+artifact.SHA256)
+	}
+	return []string{
+		fmt.Sprintf("%d", row.RunID),
+		row.Report,
+		row.CommandLine,
+		string(parameters),
+		row.Format,
+		row.Trigger,
+		row.JobName,
+		row.StartedAt,
+		row.FinishedAt,
+		row.Status,
+		fmt.Sprintf("%d", row.RowCount),
+		row.DataAsOf,
+		row.Error,
+		strings.Join(artifacts, ";"),
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *ReportRunsReport) WriteCSV(w io.Writer, rows []ReportRun) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *ReportRunsReport) WriteJSON(w io.Writer, rows []ReportRun) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with a single sheet
+func (r *ReportRunsReport) WriteXLSX(w io.Writer, rows []ReportRun) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return singleSheet("Report runs", r.csvHeader(), records).Write(w)
+}
@@ -0,0 +1,93 @@
+package reports_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestReportRuns(t *testing.T) {
+	dir := t.TempDir()
+	stmts := []string{
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('app01.example.com', 'app01', 'PROD')`,
+		`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count, is_virtualized,
+			virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus)
+			VALUES ('app01.example.com', '2025-10-01 08:00:00', 'Linux', '1', 4, 'no', '', 'unknown', 'true', 'true', 'true', 4)`,
+	}
+	db := newTestDB(t, stmts...)
+
+	csvPath := filepath.Join(dir, "compliance.csv")
+	xlsxPath := filepath.Join(dir, "compliance.xlsx")
+	for _, path := range []string{csvPath, xlsxPath} {
+		if err := os.WriteFile(path, []byte(filepath.Base(path)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runs := []*reports.ReportRun{
+		{Report: "compliance", CommandLine: "report compliance --format csv,xlsx", Parameters: map[string]string{"format": "csv,xlsx"},
+			Format: "csv,xlsx", Trigger: reports.RunTriggerSchedule, JobName: "monthly", StartedAt: "2025-10-02 06:00:00",
+			FinishedAt: "2025-10-02 06:00:01", Status: reports.RunSucceeded, RowCount: 3},
+		{Report: "daily-summary", CommandLine: "report daily-summary", Trigger: reports.RunTriggerManual,
+			StartedAt: "2025-10-03 06:00:00", FinishedAt: "2025-10-03 06:00:01", Status: reports.RunFailed, Error: "boom"},
+	}
+	if err := reports.RecordReportRun(db, runs[0], []string{csvPath, xlsxPath}); err != nil {
+		t.Fatalf("RecordReportRun failed: %v", err)
+	}
+	if err := reports.RecordReportRun(db, runs[1], nil); err != nil {
+		t.Fatalf("RecordReportRun failed: %v", err)
+	}
+	if err := reports.RecordReportRun(db, &reports.ReportRun{Report: "x", Trigger: reports.RunTriggerManual,
+		StartedAt: "2025-10-04 06:00:00", FinishedAt: "2025-10-04 06:00:00", Status: reports.RunSucceeded},
+		[]string{filepath.Join(dir, "missing.csv")}); err == nil {
+		t.Error("Expected an error for a missing report file")
+	}
+
+	report := reports.NewReportRunsReport(db)
+	rows, err := report.Query(t.Context(), "", "", "", nil, nil)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Report != "daily-summary" || rows[1].Report != "compliance" {
+		t.Fatalf("Expected the runs newest first, got %+v", rows)
+	}
+	if run := rows[1]; run.DataAsOf != "2025-10-01 08:00:00" || run.RowCount != 3 || run.Parameters["format"] != "csv,xlsx" ||
+		run.JobName != "monthly" || len(run.Artifacts) != 2 {
+		t.Errorf("Unexpected run: %+v", run)
+	}
+
+	sum := sha256.Sum256([]byte("compliance.csv"))
+	rows, err = report.Query(t.Context(), "", "", hex.EncodeToString(sum[:]), nil, nil)
+	if err != nil || len(rows) != 1 || rows[0].RunID != runs[0].RunID {
+		t.Errorf("Expected the run that wrote compliance.csv, got %+v (%v)", rows, err)
+	}
+	if rows, err := report.Query(t.Context(), "", reports.RunTriggerSchedule, "", nil, nil); err != nil || len(rows) != 1 {
+		t.Errorf("Expected the scheduled run, got %+v (%v)", rows, err)
+	}
+
+	// The files are checked against their checksum
+	if err := os.WriteFile(csvPath, []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(xlsxPath); err != nil {
+		t.Fatal(err)
+	}
+	run, err := report.Show(t.Context(), runs[0].RunID)
+	if err != nil {
+		t.Fatalf("Show failed: %v", err)
+	}
+	states := map[string]string{}
+	for _, artifact := range run.Artifacts {
+		states[filepath.Base(artifact.Path)] = artifact.State
+	}
+	if states["compliance.csv"] != reports.ArtifactChanged || states["compliance.xlsx"] != reports.ArtifactMissing {
+		t.Errorf("Unexpected artifact states: %v", states)
+	}
+	if _, err := report.Show(t.Context(), 99); err == nil {
+		t.Error("Expected an error for an unknown run")
+	}
+}
@@ -0,0 +1,384 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// NoSiteName is shown for the nodes that are not assigned to a site
+const NoSiteName = "(no site)"
+
+// SiteUsageRow represents a row from v_daily_site_license_cores
+type SiteUsageRow struct {
+	MeasurementDate       time.Time `json:"measurement_date"`
+	SiteID                string    `json:"site_id"`
+	SiteName              string    `json:"site_name"`
+	ProductMnemoCode      string    `json:"product_mnemo_code"`
+	ProductName           string    `json:"product_name"`
+	Mode                  string    `json:"mode"`
+	TermID                string    `json:"term_id"`
+	ProgramNumber         string    `json:"program_number"`
+	RunningNodes          int       `json:"running_nodes"`
+	RunningLicenseCores   int       `json:"running_license_cores"`
+	InstalledNodes        int       `json:"installed_nodes"`
+	InstalledLicenseCores int       `json:"installed_license_cores"`
+	// Chargeback: the running license cores of the term at this site, their
+	// sum over all sites and the share of the site in that sum
+	SiteTermLicenseCores int     `json:"site_term_license_cores"`
+	AllSitesTermCores    int     `json:"all_sites_term_cores"`
+	TermSharePercent     float64 `json:"term_share_percent"`
+	// Entitlements are per license term, so the status is the one of the term
+	// over all sites, as in the compliance report
+	LicensedCores    *int   `json:"licensed_cores"`
+	ComplianceStatus string `json:"compliance_status,omitempty"`
+}
+
+// SiteUsageReport generates the daily-summary and compliance reports grouped
+// by site from v_daily_site_license_cores
+type SiteUsageReport struct {
+	db         *sql.DB
+	compliance bool
+}
+
+// NewSiteSummaryReport creates the report generator of daily-summary --group-by site
+func NewSiteSummaryReport(db *sql.DB) *SiteUsageReport {
+	return &SiteUsageReport{db: db}
+}
+
+// NewSiteComplianceReport creates the report generator of compliance --group-by site
+func NewSiteComplianceReport(db *sql.DB) *SiteUsageReport {
+	return &SiteUsageReport{db: db, compliance: true}
+}
+
+// Query retrieves data from the view with optional filters
+func (r *SiteUsageReport) Query(ctx context.Context, productCode, mode string, fromDate, toDate *time.Time) ([]SiteUsageRow, error) {
+	query := `
+		SELECT
+			s.measurement_date,
+			s.site_id,
+			COALESCE(st.site_name, ''),
+			s.product_mnemo_code,
+			p.product_name,
+			p.mode,
+			l.term_id,
+			l.program_number,
+			s.running_nodes,
+			s.running_license_cores,
+			s.installed_nodes,
+			s.installed_license_cores,
+			(SELECT SUM(s2.running_license_cores)
+			 FROM v_daily_site_license_cores s2
+			 JOIN product_codes p2 ON s2.product_mnemo_code = p2.product_mnemo_code
+			 WHERE ` + productTermColumn("p2", "s2.measurement_date") + ` = l.term_id
+			   AND s2.site_id = s.site_id
+			   AND s2.measurement_date = s.measurement_date) as site_term_license_cores,
+			(SELECT SUM(s2.running_license_cores)
+			 FROM v_daily_site_license_cores s2
+			 JOIN product_codes p2 ON s2.product_mnemo_code = p2.product_mnemo_code
+			 WHERE ` + productTermColumn("p2", "s2.measurement_date") + ` = l.term_id
+			   AND s2.measurement_date = s.measurement_date) as all_sites_term_cores
+		FROM v_daily_site_license_cores s
+		JOIN product_codes p ON s.product_mnemo_code = p.product_mnemo_code
+		JOIN license_terms l ON l.term_id = ` + productTermColumn("p", "s.measurement_date") + `
+		LEFT JOIN sites st ON s.site_id = st.site_id
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if productCode != "" {
+		query += " AND s.product_mnemo_code = ?"
+		args = append(args, productCode)
+	}
+
+	if mode != "" {
+		query += " AND p.mode = ?"
+		args = append(args, mode)
+	}
+
+	if fromDate != nil {
+		query += " AND s.measurement_date >= ?"
+		args = append(args, fromDate.Format("2006-01-02"))
+	}
+
+	if toDate != nil {
+		query += " AND s.measurement_date <= ?"
+		args = append(args, toDate.Format("2006-01-02"))
+	}
+
+	// Nodes without a site sort last
+	query += " ORDER BY s.measurement_date DESC, s.site_id = '', s.site_id, s.product_mnemo_code"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query site usage: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SiteUsageRow
+	for rows.Next() {
+		var row SiteUsageRow
+		var dateStr string
+
+		err := rows.Scan(
+			&dateStr,
+			&row.SiteID,
+			&row.SiteName,
+			&row.ProductMnemoCode,
+			&row.ProductName,
+			&row.Mode,
+			&row.TermID,
+			&row.ProgramNumber,
+			&row.RunningNodes,
+			&row.RunningLicenseCores,
+			&row.InstalledNodes,
+			&row.InstalledLicenseCores,
+			&row.SiteTermLicenseCores,
+			&row.AllSitesTermCores,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if row.SiteID == "" {
+			row.SiteName = NoSiteName
+		}
+		if row.AllSitesTermCores > 0 {
+			row.TermSharePercent = float64(row.SiteTermLicenseCores) * 100 / float64(row.AllSitesTermCores)
+		}
+
+		// Parse date
+		row.MeasurementDate, err = time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse date: %w", err)
+		}
+
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if r.compliance && len(results) > 0 {
+		if err := r.addComplianceStatus(ctx, results, productCode, mode, fromDate, toDate); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// addComplianceStatus copies the entitlement and status of each term and day
+// from the compliance report
+func (r *SiteUsageReport) addComplianceStatus(ctx context.Context, rows []SiteUsageRow, productCode, mode string, fromDate, toDate *time.Time) error {
+	compliance, err := NewComplianceReport(r.db).Query(ctx, productCode, mode, fromDate, toDate, false)
+	if err != nil {
+		return err
+	}
+
+	type key struct {
+		date    time.Time
+		product string
+	}
+	byProduct := make(map[key]ComplianceRow, len(compliance))
+	for _, c := range compliance {
+		byProduct[key{c.MeasurementDate, c.ProductMnemoCode}] = c
+	}
+
+	for i := range rows {
+		if c, ok := byProduct[key{rows[i].MeasurementDate, rows[i].ProductMnemoCode}]; ok {
+			rows[i].LicensedCores = c.LicensedCores
+			rows[i].ComplianceStatus = c.ComplianceStatus
+		} else {
+			// Installed but not running products are not in the compliance report
+			rows[i].ComplianceStatus = StatusNoEntitlement
+		}
+	}
+	return nil
+}
+
+// WriteTable writes data in ASCII table format
+func (r *SiteUsageReport) WriteTable(w io.Writer, rows []SiteUsageRow) error {
+	if r.compliance {
+		return r.writeComplianceTable(w, rows)
+	}
+
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "DATE\tSITE\tPRODUCT\tMODE\tRUN_NODES\tRUN_CORES\tINST_NODES\tINST_CORES")
+	fmt.Fprintln(tw, "----\t----\t-------\t----\t---------\t---------\t----------\t----------")
+
+	// Data rows
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\n",
+			row.MeasurementDate.Format("2006-01-02"),
+			row.SiteName,
+			row.ProductMnemoCode,
+			row.Mode,
+			row.RunningNodes,
+			row.RunningLicenseCores,
+			row.InstalledNodes,
+			row.InstalledLicenseCores,
+		)
+	}
+
+	// Summary per site and mode, as PROD and NON PROD cores are licensed separately
+	if len(rows) > 0 {
+		type siteTotal struct {
+			site, mode string
+			sums       [4]int
+		}
+		var totals []*siteTotal
+		index := make(map[string]*siteTotal)
+		for _, row := range rows {
+			key := row.SiteID + "\x00" + row.Mode
+			t, ok := index[key]
+			if !ok {
+				t = &siteTotal{site: row.SiteName, mode: row.Mode}
+				index[key] = t
+				totals = append(totals, t)
+			}
+			t.sums[0] += row.RunningNodes
+			t.sums[1] += row.RunningLicenseCores
+			t.sums[2] += row.InstalledNodes
+			t.sums[3] += row.InstalledLicenseCores
+		}
+
+		fmt.Fprintln(tw, "----\t----\t-------\t----\t---------\t---------\t----------\t----------")
+		for _, t := range totals {
+			fmt.Fprintf(tw, "TOTAL\t%s\t\t%s\t%d\t%d\t%d\t%d\n", t.site, t.mode, t.sums[0], t.sums[1], t.sums[2], t.sums[3])
+		}
+	}
+
+	return writeDailyFootnotes(tw, r.db)
+}
+
+// writeComplianceTable writes the compliance columns in ASCII table format
+func (r *SiteUsageReport) writeComplianceTable(w io.Writer, rows []SiteUsageRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "DATE\tSITE\tPRODUCT\tMODE\tPROGRAM\tRUN_NODES\tLIC_CORES\tSITE_TERM_CORES\tALL_SITES\tSHARE\tENTITLED\tTERM_STATUS")
+	fmt.Fprintln(tw, "----\t----\t-------\t----\t-------\t---------\t---------\t---------------\t---------\t-----\t--------\t-----------")
+
+	// Data rows
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%.1f%%\t%s\t%s\n",
+			row.MeasurementDate.Format("2006-01-02"),
+			row.SiteName,
+			row.ProductMnemoCode,
+			row.Mode,
+			row.ProgramNumber,
+			row.RunningNodes,
+			row.RunningLicenseCores,
+			row.SiteTermLicenseCores,
+			row.AllSitesTermCores,
+			row.TermSharePercent,
+			formatOptionalInt(row.LicensedCores, "N/A"),
+			row.ComplianceStatus,
+		)
+	}
+
+	fmt.Fprintln(tw, "\nSHARE is the site's part of the running license cores of the term over all sites.")
+	fmt.Fprintln(tw, "Entitlements are per license term: TERM_STATUS is the status over all sites.")
+
+	return nil
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *SiteUsageReport) csvHeader() []string {
+	header := []string{
+		"measurement_date",
+		"site_id",
+		"site_name",
+		"product_mnemo_code",
+		"product_name",
+		"mode",
+		"term_id",
+		"program_number",
+		"running_nodes",
+		"running_license_cores",
+		"installed_nodes",
+		"installed_license_cores",
+	}
+	if r.compliance {
+		header = append(header,
+			"site_term_license_cores",
+			"all_sites_term_cores",
+			"term_share_percent",
+			"licensed_cores",
+			"compliance_status",
+		)
+	}
+	return header
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *SiteUsageReport) csvRecord(row SiteUsageRow) []string {
+	record := []string{
+		row.MeasurementDate.Format("2006-01-02"),
+		row.SiteID,
+		row.SiteName,
+		row.ProductMnemoCode,
+		row.ProductName,
+		row.Mode,
+		row.TermID,
+		row.ProgramNumber,
+		fmt.Sprintf("%d", row.RunningNodes),
+		fmt.Sprintf("%d", row.RunningLicenseCores),
+		fmt.Sprintf("%d", row.InstalledNodes),
+		fmt.Sprintf("%d", row.InstalledLicenseCores),
+	}
+	if r.compliance {
+		record = append(record,
+			fmt.Sprintf("%d", row.SiteTermLicenseCores),
+			fmt.Sprintf("%d", row.AllSitesTermCores),
+			fmt.Sprintf("%.1f", row.TermSharePercent),
+			formatOptionalInt(row.LicensedCores, ""),
+			row.ComplianceStatus,
+		)
+	}
+	return record
+}
+
+// WriteCSV writes data in CSV format
+func (r *SiteUsageReport) WriteCSV(w io.Writer, rows []SiteUsageRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *SiteUsageReport) WriteJSON(w io.Writer, rows []SiteUsageRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with one sheet per site
+func (r *SiteUsageReport) WriteXLSX(w io.Writer, rows []SiteUsageRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return sheetsByColumn(r.csvHeader(), records, 2, "Sites").Write(w)
+}
@@ -0,0 +1,260 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Reports frozen by a snapshot
+const (
+	SnapshotPeak        = "peak"
+	SnapshotMonthlyPeak = "monthly-peak"
+	SnapshotCompliance  = "compliance"
+)
+
+// SnapshotReports lists the reports of a snapshot, in the order they are stored
+var SnapshotReports = []string{SnapshotPeak, SnapshotMonthlyPeak, SnapshotCompliance}
+
+// Snapshot describes the report aggregates frozen under a label
+type Snapshot struct {
+	Label         string
+	PeriodFrom    string // YYYY-MM-DD, empty when not set
+	PeriodTo      string // YYYY-MM-DD, empty when not set
+	Product       string
+	Mode          string
+	SchemaVersion string
+	Notes         string
+	CreatedBy     string
+	CreatedAt     time.Time
+	Rows          map[string]int // row count per report
+}
+
+// SnapshotStore creates and reads the immutable snapshots of the snapshots and
+// snapshot_rows tables
+type SnapshotStore struct {
+	db *sql.DB
+}
+
+// NewSnapshotStore creates a new snapshot store
+func NewSnapshotStore(db *sql.DB) *SnapshotStore {
+	return &SnapshotStore{db: db}
+}
+
+// Create runs the peak, monthly-peak and compliance reports with the filters of
+// the snapshot and stores their rows under its label. The peak report always
+// covers the last 31 days; the period applies to the other reports. A label
+// can only be used once.
+func (s *SnapshotStore) Create(ctx context.Context, snap *Snapshot) error {
+	fromDate, err := parseSnapshotDate(snap.PeriodFrom)
+	if err != nil {
+		return err
+	}
+	toDate, err := parseSnapshotDate(snap.PeriodTo)
+	if err != nil {
+		return err
+	}
+
+	var exists int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM snapshots WHERE label = ?", snap.Label).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check snapshot: %w", err)
+	}
+	if exists > 0 {
+		return fmt.Errorf("snapshot %s already exists; snapshots cannot be replaced", snap.Label)
+	}
+
+	peak, err := NewPeakUsageReport(s.db).Query(ctx, snap.Product, snap.Mode)
+	if err != nil {
+		return err
+	}
+	monthlyPeak, err := NewMonthlyPeakReport(s.db).Query(ctx, snap.Product, snap.Mode, fromDate, toDate)
+	if err != nil {
+		return err
+	}
+	compliance, err := NewComplianceReport(s.db).Query(ctx, snap.Product, snap.Mode, fromDate, toDate, false)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO snapshots (label, period_from, period_to, product_mnemo_code, mode, schema_version, notes, created_by)
+		VALUES (?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?)
+	`, snap.Label, snap.PeriodFrom, snap.PeriodTo, snap.Product, snap.Mode, snap.SchemaVersion, snap.Notes, snap.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	snap.Rows = make(map[string]int)
+	for _, report := range []struct {
+		name string
+		rows interface{}
+	}{{SnapshotPeak, peak}, {SnapshotMonthlyPeak, monthlyPeak}, {SnapshotCompliance, compliance}} {
+		n, err := insertSnapshotRows(tx, snap.Label, report.name, report.rows)
+		if err != nil {
+			return err
+		}
+		snap.Rows[report.name] = n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+	return nil
+}
+
+// parseSnapshotDate parses a YYYY-MM-DD period bound; empty means no bound
+func parseSnapshotDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", value)
+	}
+	return &t, nil
+}
+
+// insertSnapshotRows stores each element of rows, a slice of report rows, as JSON
+func insertSnapshotRows(tx *sql.Tx, label, report string, rows interface{}) (int, error) {
+	// Encode the slice once and split it into its elements
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode %s rows: %w", report, err)
+	}
+	var elements []json.RawMessage
+	if err := json.Unmarshal(data, &elements); err != nil {
+		return 0, fmt.Errorf("failed to encode %s rows: %w", report, err)
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO snapshot_rows (label, report, row_number, row_data) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare snapshot rows: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, element := range elements {
+		if _, err := stmt.Exec(label, report, i+1, string(element)); err != nil {
+			return 0, fmt.Errorf("failed to store %s row: %w", report, err)
+		}
+	}
+	return len(elements), nil
+}
+
+// List returns all snapshots, newest first
+func (s *SnapshotStore) List() ([]Snapshot, error) {
+	rows, err := s.db.Query(`
+		SELECT label, COALESCE(period_from, ''), COALESCE(period_to, ''), product_mnemo_code, mode,
+		       schema_version, notes, created_by, created_at
+		FROM snapshots
+		ORDER BY created_at DESC, label
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []Snapshot
+	for rows.Next() {
+		var snap Snapshot
+		var periodFrom, periodTo interface{}
+		err := rows.Scan(&snap.Label, &periodFrom, &periodTo, &snap.Product, &snap.Mode,
+			&snap.SchemaVersion, &snap.Notes, &snap.CreatedBy, &snap.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		snap.PeriodFrom = formatSnapshotDate(periodFrom)
+		snap.PeriodTo = formatSnapshotDate(periodTo)
+		snapshots = append(snapshots, snap)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range snapshots {
+		if snapshots[i].Rows, err = s.rowCounts(snapshots[i].Label); err != nil {
+			return nil, err
+		}
+	}
+	return snapshots, nil
+}
+
+// Get returns the snapshot with the label
+func (s *SnapshotStore) Get(label string) (*Snapshot, error) {
+	snapshots, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range snapshots {
+		if snapshots[i].Label == label {
+			return &snapshots[i], nil
+		}
+	}
+	return nil, fmt.Errorf("snapshot %s not found (see 'iwdlr snapshot list')", label)
+}
+
+// rowCounts counts the stored rows of each report of a snapshot
+func (s *SnapshotStore) rowCounts(label string) (map[string]int, error) {
+	rows, err := s.db.Query("SELECT report, COUNT(*) FROM snapshot_rows WHERE label = ? GROUP BY report", label)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count snapshot rows: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var report string
+		var count int
+		if err := rows.Scan(&report, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot rows: %w", err)
+		}
+		counts[report] = count
+	}
+	return counts, rows.Err()
+}
+
+// formatSnapshotDate formats a DATE column; the driver returns dates written
+// as YYYY-MM-DD text as time.Time
+func formatSnapshotDate(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format("2006-01-02")
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// LoadSnapshotRows returns the rows of one report of a snapshot, exactly as
+// they were when the snapshot was created. T must be the row type of the
+// report (PeakUsageRow, MonthlyPeakRow or ComplianceRow).
+func LoadSnapshotRows[T any](db *sql.DB, label, report string) ([]T, error) {
+	rows, err := db.Query("SELECT row_data FROM snapshot_rows WHERE label = ? AND report = ? ORDER BY row_number", label, report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshot rows: %w", err)
+	}
+	defer rows.Close()
+
+	var results []T
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot row: %w", err)
+		}
+		var row T
+		if err := json.Unmarshal([]byte(data), &row); err != nil {
+			return nil, fmt.Errorf("failed to decode %s row of snapshot %s: %w", report, label, err)
+		}
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,58 @@
+package reports_test
+
+import (
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestSnapshotSurvivesLaterChanges(t *testing.T) {
+	db := newTestDB(t,
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('vm1', 'vm1', 'PROD')`,
+		`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count, is_virtualized,
+			host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus)
+			VALUES ('vm1', '2025-10-01 08:00:00', 'Linux', '8', 4, 'no', 'unknown', 'true', 'true', 'true', 4)`,
+		`INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, install_count)
+			VALUES ('vm1', 'IS_ONP_PRD', '2025-10-01 08:00:00', 'present', 1)`)
+
+	store := reports.NewSnapshotStore(db)
+	snap := &reports.Snapshot{Label: "2025-Q4", PeriodFrom: "2025-10-01", PeriodTo: "2025-12-31", SchemaVersion: "test"}
+	if err := store.Create(t.Context(), snap); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if snap.Rows[reports.SnapshotMonthlyPeak] != 1 || snap.Rows[reports.SnapshotCompliance] != 1 {
+		t.Fatalf("Unexpected row counts: %v", snap.Rows)
+	}
+
+	// A later correction changes the live report but not the snapshot
+	if _, err := db.Exec(`UPDATE measurements SET considered_cpus = 16`); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	rows, err := reports.LoadSnapshotRows[reports.MonthlyPeakRow](db, "2025-Q4", reports.SnapshotMonthlyPeak)
+	if err != nil {
+		t.Fatalf("LoadSnapshotRows failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Month != "2025-10" || rows[0].PeakRunningCores != 4 {
+		t.Errorf("Unexpected snapshot rows: %+v", rows)
+	}
+
+	saved, err := store.Get("2025-Q4")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if saved.PeriodFrom != "2025-10-01" || saved.PeriodTo != "2025-12-31" || saved.Rows[reports.SnapshotCompliance] != 1 {
+		t.Errorf("Unexpected snapshot: %+v", saved)
+	}
+
+	// Labels cannot be reused and snapshots cannot be changed
+	if err := store.Create(t.Context(), &reports.Snapshot{Label: "2025-Q4", SchemaVersion: "test"}); err == nil {
+		t.Error("Expected an error for a duplicate label")
+	}
+	if _, err := db.Exec(`DELETE FROM snapshot_rows WHERE label = '2025-Q4'`); err == nil {
+		t.Error("Expected snapshot rows to be immutable")
+	}
+	if _, err := db.Exec(`UPDATE snapshots SET notes = 'x'`); err == nil {
+		t.Error("Expected snapshots to be immutable")
+	}
+}
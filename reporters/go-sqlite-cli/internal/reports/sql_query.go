@@ -0,0 +1,277 @@
+package reports
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SQLRow holds the values of one result row of an ad-hoc SQL statement, in
+// column order. Values are nil, int64, float64, string or time.Time.
+type SQLRow []interface{}
+
+// SQLQueryReport renders the result of an ad-hoc SQL statement. The columns
+// are only known once the statement has run.
+type SQLQueryReport struct {
+	db      *sql.DB
+	columns []string
+}
+
+// NewSQLQueryReport creates a new report generator. The database should be
+// opened read-only: the statement is run as given.
+func NewSQLQueryReport(db *sql.DB) *SQLQueryReport {
+	return &SQLQueryReport{db: db}
+}
+
+// Columns returns the column names of the last statement run
+func (r *SQLQueryReport) Columns() []string {
+	return r.columns
+}
+
+// Query runs the statement and returns all its rows
+func (r *SQLQueryReport) Query(ctx context.Context, statement string) ([]SQLRow, error) {
+	var results []SQLRow
+	var err error
+	r.columns, results, err = querySQLRows(ctx, r.db, statement)
+	return results, err
+}
+
+// querySQLRows runs a statement and returns its column names and all its rows
+func querySQLRows(ctx context.Context, db *sql.DB, statement string, args ...interface{}) ([]string, []SQLRow, error) {
+	rows, err := db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to run query: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	var results []SQLRow
+	for rows.Next() {
+		row := make(SQLRow, len(columns))
+		pointers := make([]interface{}, len(row))
+		for i := range row {
+			pointers[i] = &row[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		// TEXT and BLOB values are returned as bytes
+		for i, value := range row {
+			if b, ok := value.([]byte); ok {
+				row[i] = string(b)
+			}
+		}
+
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to run query: %w", err)
+	}
+
+	return columns, results, nil
+}
+
+// formatSQLValue formats a value for the table, CSV and XLSX outputs; NULL is
+// empty
+func formatSQLValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05")
+	case float64:
+		return fmt.Sprintf("%g", v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// WriteTable writes data in ASCII table format
+func (r *SQLQueryReport) WriteTable(w io.Writer, rows []SQLRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	header := make([]string, len(r.columns))
+	separator := make([]string, len(r.columns))
+	for i, column := range r.columns {
+		header[i] = strings.ToUpper(column)
+		separator[i] = strings.Repeat("-", len(column))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	fmt.Fprintln(tw, strings.Join(separator, "\t"))
+
+	// Data rows
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(r.csvRecord(row), "\t"))
+	}
+
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d rows\n", len(rows))
+
+	return nil
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *SQLQueryReport) csvRecord(row SQLRow) []string {
+	record := make([]string, len(row))
+	for i, value := range row {
+		record[i] = formatSQLValue(value)
+	}
+	return record
+}
+
+// WriteCSV writes data in CSV format
+func (r *SQLQueryReport) WriteCSV(w io.Writer, rows []SQLRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.columns); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sqlObject is a row encoded as a JSON object keeping the column order
+type sqlObject struct {
+	columns []string
+	row     SQLRow
+}
+
+// MarshalJSON encodes the row as an object with one member per column
+func (o sqlObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, column := range o.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.row[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// WriteJSON writes data in JSON format, one object per row
+func (r *SQLQueryReport) WriteJSON(w io.Writer, rows []SQLRow) error {
+	objects := make([]sqlObject, 0, len(rows))
+	for _, row := range rows {
+		objects = append(objects, sqlObject{columns: r.columns, row: row})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(objects)
+}
+
+// WriteXLSX writes an Excel workbook with a single sheet
+func (r *SQLQueryReport) WriteXLSX(w io.Writer, rows []SQLRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return singleSheet("Query", r.columns, records).Write(w)
+}
+
+// PaginateSQLRows returns the rows of a page, sorted by the named columns;
+// rows with equal values keep their order
+func PaginateSQLRows(columns []string, rows []SQLRow, page Page) ([]SQLRow, error) {
+	if err := page.Validate(); err != nil {
+		return nil, err
+	}
+
+	if keys := parseSort(page.Sort); len(keys) > 0 {
+		indexes := make(map[string]int, len(columns))
+		for i, column := range columns {
+			indexes[column] = i
+		}
+		order := make([]int, len(keys))
+		for i, key := range keys {
+			index, ok := indexes[key.column]
+			if !ok {
+				return nil, unknownSortColumnError(key.column, indexes)
+			}
+			order[i] = index
+		}
+
+		rows = append([]SQLRow(nil), rows...)
+		sort.SliceStable(rows, func(i, j int) bool {
+			for k, key := range keys {
+				c := compareSQLValues(rows[i][order[k]], rows[j][order[k]])
+				if c != 0 {
+					return (c < 0) != key.descending
+				}
+			}
+			return false
+		})
+	}
+
+	if page.Offset >= len(rows) {
+		return rows[:0], nil
+	}
+	rows = rows[page.Offset:]
+	if page.Limit > 0 && page.Limit < len(rows) {
+		rows = rows[:page.Limit]
+	}
+	return rows, nil
+}
+
+// compareSQLValues compares two values of a column: NULL sorts first, then
+// numbers and times by value and anything else by its text
+func compareSQLValues(a, b interface{}) int {
+	if a == nil || b == nil {
+		return compareBools(a != nil, b != nil)
+	}
+	if x, ok := sqlNumber(a); ok {
+		if y, ok := sqlNumber(b); ok {
+			return cmp.Compare(x, y)
+		}
+	}
+	if x, ok := a.(time.Time); ok {
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y)
+		}
+	}
+	return strings.Compare(formatSQLValue(a), formatSQLValue(b))
+}
+
+// sqlNumber returns the value of an INTEGER or REAL value
+func sqlNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
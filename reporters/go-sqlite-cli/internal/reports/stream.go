@@ -0,0 +1,91 @@
+package reports
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// RowWriter writes report rows one at a time, so that exports larger than the
+// memory of the reporting host can be written while the query runs. Close
+// completes the output; it must be called once all rows are written.
+type RowWriter[T any] interface {
+	WriteRow(row T) error
+	Close() error
+}
+
+// newRowWriter returns a RowWriter for the csv or json format, with the CSV
+// columns of a report. Other formats need all rows and cannot be streamed.
+func newRowWriter[T any](w io.Writer, format string, header []string, record func(T) []string) (RowWriter[T], error) {
+	switch format {
+	case "csv":
+		return &csvRowWriter[T]{writer: csv.NewWriter(w), header: header, record: record}, nil
+	case "json":
+		return &jsonRowWriter[T]{w: w}, nil
+	}
+	return nil, fmt.Errorf("the %s format cannot be streamed (use csv or json)", format)
+}
+
+// csvRowWriter streams rows like WriteCSV
+type csvRowWriter[T any] struct {
+	writer  *csv.Writer
+	header  []string
+	record  func(T) []string
+	started bool
+}
+
+func (c *csvRowWriter[T]) WriteRow(row T) error {
+	if !c.started {
+		c.started = true
+		if err := c.writer.Write(c.header); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+	if err := c.writer.Write(c.record(row)); err != nil {
+		return fmt.Errorf("failed to write CSV record: %w", err)
+	}
+	return nil
+}
+
+func (c *csvRowWriter[T]) Close() error {
+	if !c.started {
+		if err := c.writer.Write(c.header); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// jsonRowWriter streams rows as the indented JSON array written by WriteJSON
+type jsonRowWriter[T any] struct {
+	w    io.Writer
+	rows int
+}
+
+func (j *jsonRowWriter[T]) WriteRow(row T) error {
+	data, err := json.MarshalIndent(row, "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode row: %w", err)
+	}
+	sep := ",\n  "
+	if j.rows == 0 {
+		sep = "[\n  "
+	}
+	j.rows++
+	if _, err := io.WriteString(j.w, sep); err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonRowWriter[T]) Close() error {
+	end := "\n]\n"
+	if j.rows == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
@@ -0,0 +1,51 @@
+package reports_test
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestRowWriterMatchesWriters(t *testing.T) {
+	report := reports.NewHostDetailReport(nil)
+	rows := []reports.HostDetailRow{
+		{HostFQDN: "a.example.com", Date: time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC), VirtualCPUs: 4,
+			ProductCode: sql.NullString{String: "IS_ONP_PRD", Valid: true}},
+		{HostFQDN: "b.example.com", Date: time.Date(2025, 10, 2, 0, 0, 0, 0, time.UTC), VirtualCPUs: 8,
+			PhysicalCPUs: sql.NullInt64{Int64: 32, Valid: true}},
+	}
+
+	for format, write := range map[string]func(*bytes.Buffer, []reports.HostDetailRow) error{
+		"csv":  func(b *bytes.Buffer, rows []reports.HostDetailRow) error { return report.WriteCSV(b, rows) },
+		"json": func(b *bytes.Buffer, rows []reports.HostDetailRow) error { return report.WriteJSON(b, rows) },
+	} {
+		var want, got bytes.Buffer
+		if err := write(&want, rows); err != nil {
+			t.Fatalf("Write %s failed: %v", format, err)
+		}
+
+		writer, err := report.NewRowWriter(&got, format)
+		if err != nil {
+			t.Fatalf("NewRowWriter(%s) failed: %v", format, err)
+		}
+		for _, row := range rows {
+			if err := writer.WriteRow(row); err != nil {
+				t.Fatalf("WriteRow failed: %v", err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		if got.String() != want.String() {
+			t.Errorf("Streamed %s differs:\n%s\nwant:\n%s", format, got.String(), want.String())
+		}
+	}
+
+	if _, err := report.NewRowWriter(&bytes.Buffer{}, "xlsx"); err == nil {
+		t.Error("Expected an error streaming a workbook")
+	}
+}
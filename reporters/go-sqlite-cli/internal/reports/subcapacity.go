@@ -0,0 +1,344 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/confidence"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/licensing"
+)
+
+// SubcapacityRow is the license count of one physical host for a product and day
+type SubcapacityRow struct {
+	MeasurementDate  string `json:"measurement_date"`
+	ProductMnemoCode string `json:"product_mnemo_code"`
+	Mode             string `json:"mode"`
+	licensing.HostResult
+}
+
+// SubcapacityReport counts license cores with the sub-capacity rules of the licensing package
+type SubcapacityReport struct {
+	db *sql.DB
+
+	// minConfidence is the lowest host ID confidence whose cores are deduplicated
+	minConfidence string
+
+	// rounding and minCoresPerInstall are the core rounding policy (see licensing.Options)
+	rounding           string
+	minCoresPerInstall int
+
+	// dedupAll is the license cores of each product and day with every host
+	// ID deduplicated, when minConfidence leaves some hosts out
+	dedupAll map[productDay]int
+}
+
+// NewSubcapacityReport creates a new report generator
+func NewSubcapacityReport(db *sql.DB) *SubcapacityReport {
+	return &SubcapacityReport{db: db}
+}
+
+// SetMinConfidence sets the lowest host ID confidence (low, medium or high)
+// for which the cores of the VMs of a host are deduplicated; the nodes of a
+// host identified with less are counted on their own. Empty is low. Only this
+// report applies it: the reports built on the views (compliance, monthly-peak,
+// peak) deduplicate every identified host ID whatever its confidence.
+func (r *SubcapacityReport) SetMinConfidence(level string) {
+	r.minConfidence = level
+}
+
+// SetCorePolicy sets how partial cores are rounded up, per VM (vm, the
+// default) or per host, and the lowest cores each installation counts for
+func (r *SubcapacityReport) SetCorePolicy(rounding string, minCoresPerInstall int) {
+	r.rounding = rounding
+	r.minCoresPerInstall = minCoresPerInstall
+}
+
+// Query applies the sub-capacity rules to the nodes running each product, per
+// day and physical host. The measurement of a node on a day is chosen by the
+// daily aggregation policy (see v_daily_measurements). With a minimum
+// confidence, the totals with every host ID deduplicated are kept for the
+// table output.
+func (r *SubcapacityReport) Query(ctx context.Context, productCode, mode string, fromDate, toDate *time.Time) ([]SubcapacityRow, error) {
+	groups, err := queryProductNodes(ctx, r.db, productCode, mode, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]productDay, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].date != keys[j].date {
+			return keys[i].date > keys[j].date
+		}
+		return keys[i].product < keys[j].product
+	})
+
+	r.dedupAll = nil
+	opts := licensing.Options{
+		MinConfidence:      r.minConfidence,
+		Rounding:           r.rounding,
+		MinCoresPerInstall: r.minCoresPerInstall,
+	}
+	dedupOpts := opts
+	dedupOpts.MinConfidence = ""
+	var results []SubcapacityRow
+	for _, key := range keys {
+		hosts := licensing.CalculateWith(groups[key], opts)
+		if confidence.Rank(r.minConfidence) > 0 {
+			if r.dedupAll == nil {
+				r.dedupAll = make(map[productDay]int)
+			}
+			r.dedupAll[key], _ = licensing.Total(licensing.CalculateWith(groups[key], dedupOpts))
+		}
+		for _, host := range hosts {
+			results = append(results, SubcapacityRow{
+				MeasurementDate:  key.date,
+				ProductMnemoCode: key.product,
+				Mode:             key.mode,
+				HostResult:       host,
+			})
+		}
+	}
+
+	return results, nil
+}
+
+// productDay is a product counted on a day
+type productDay struct{ date, product, mode string }
+
+// queryProductNodes returns the nodes running each product per day, with the
+// fields used by the sub-capacity rules, ordered by FQDN. The host ID
+// confidence set by the rules of 'hosts confidence' replaces the reported one,
+// and a node licensed on the cap of its partition keeps the partial capacity
+// of the cap for the per-host rounding.
+func queryProductNodes(ctx context.Context, db *sql.DB, productCode, mode string, fromDate, toDate *time.Time) (map[productDay][]licensing.Node, error) {
+	query := `
+		SELECT
+			DATE(m.detection_timestamp),
+			d.product_mnemo_code,
+			COALESCE(p.mode, ''),
+			m.main_fqdn,
+			m.is_virtualized,
+			m.considered_cpus,
+			m.os_eligible,
+			m.virt_eligible,
+			COALESCE(m.physical_host_id, ''),
+			COALESCE(e.confidence, m.host_id_confidence, ''),
+			COALESCE(m.host_physical_cpus, ''),
+			COALESCE(m.partition_cpus, ''),
+			COALESCE(m.partition_cap_cores = m.considered_cpus, 0),
+			COALESCE(d.install_count, 1)
+		FROM detected_products d
+		JOIN v_daily_measurements m ON d.main_fqdn = m.main_fqdn
+			AND d.detection_timestamp = m.detection_timestamp
+		LEFT JOIN product_codes p ON d.product_mnemo_code = p.product_mnemo_code
+		LEFT JOIN host_confidence_escalations e ON m.physical_host_id = e.physical_host_id
+		WHERE d.status = 'present'
+	`
+
+	args := []interface{}{}
+
+	if productCode != "" {
+		query += " AND d.product_mnemo_code = ?"
+		args = append(args, productCode)
+	}
+
+	if mode != "" {
+		query += " AND p.mode = ?"
+		args = append(args, mode)
+	}
+
+	if fromDate != nil {
+		query += " AND DATE(m.detection_timestamp) >= ?"
+		args = append(args, fromDate.Format("2006-01-02"))
+	}
+
+	if toDate != nil {
+		query += " AND DATE(m.detection_timestamp) <= ?"
+		args = append(args, toDate.Format("2006-01-02"))
+	}
+
+	// Later measurements of a node on the same day replace earlier ones
+	query += " ORDER BY m.detection_timestamp"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product nodes: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make(map[productDay]map[string]licensing.Node)
+
+	for rows.Next() {
+		var key productDay
+		var node licensing.Node
+		var virtualized, osEligible, virtEligible, hostCores, partitionCPUs string
+		var capped bool
+
+		err := rows.Scan(&key.date, &key.product, &key.mode, &node.MainFQDN, &virtualized, &node.Cores,
+			&osEligible, &virtEligible, &node.HostID, &node.HostConfidence, &hostCores,
+			&partitionCPUs, &capped, &node.Installs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		node.Virtualized = virtualized != "no"
+		node.Eligible = osEligible == "true" && virtEligible == "true"
+		if node.HostID == "unknown" {
+			node.HostID = ""
+		}
+		if capacity, ok := licensing.PartitionCapacity(partitionCPUs); ok && capped && node.Virtualized &&
+			capacity < float64(node.Cores) {
+			node.Capacity = capacity
+		}
+		if !node.Virtualized {
+			// A bare-metal node is its own physical host
+			cores := node.Cores
+			node.HostCores = &cores
+		} else if hostCores != "" && hostCores != "unknown" {
+			var cores int
+			if _, err := fmt.Sscanf(strings.TrimSpace(hostCores), "%d", &cores); err == nil {
+				node.HostCores = &cores
+			}
+		}
+
+		if groups[key] == nil {
+			groups[key] = make(map[string]licensing.Node)
+		}
+		groups[key][node.MainFQDN] = node
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[productDay][]licensing.Node, len(groups))
+	for key, byFQDN := range groups {
+		list := make([]licensing.Node, 0, len(byFQDN))
+		for _, node := range byFQDN {
+			list = append(list, node)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].MainFQDN < list[j].MainFQDN })
+		nodes[key] = list
+	}
+	return nodes, nil
+}
+
+// WriteTable writes data in ASCII table format, with the total of each product and day
+func (r *SubcapacityReport) WriteTable(w io.Writer, rows []SubcapacityRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "DATE\tPRODUCT\tHOST\tNODES\tNODE_CORES\tHOST_CORES\tLIC_CORES\tFULL_CAP\tRULE\tEXPLANATION")
+	fmt.Fprintln(tw, "----\t-------\t----\t-----\t----------\t----------\t---------\t--------\t----\t-----------")
+
+	// Data rows
+	license, full := 0, 0
+	for i, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\t%d\t%d\t%s\t%s\n",
+			row.MeasurementDate,
+			row.ProductMnemoCode,
+			row.HostKey,
+			len(row.Nodes),
+			row.NodeCores,
+			formatCPUs(row.HostCores),
+			row.LicenseCores,
+			row.FullCapacity,
+			row.Rule,
+			row.Explanation,
+		)
+
+		license += row.LicenseCores
+		full += row.FullCapacity
+		last := i == len(rows)-1 || rows[i+1].MeasurementDate != row.MeasurementDate ||
+			rows[i+1].ProductMnemoCode != row.ProductMnemoCode
+		if last {
+			fmt.Fprintf(tw, "\t%s TOTAL\t\t\t\t\t%d\t%d\t\t\n", row.ProductMnemoCode, license, full)
+			key := productDay{row.MeasurementDate, row.ProductMnemoCode, row.Mode}
+			if all, ok := r.dedupAll[key]; ok && all != license {
+				fmt.Fprintf(tw, "\t%s TOTAL (all host IDs deduplicated)\t\t\t\t\t%d\t\t\t%+d with host IDs below %s confidence deduplicated\n",
+					row.ProductMnemoCode, all, all-license, r.minConfidence)
+			}
+			license, full = 0, 0
+		}
+	}
+
+	return writeDailyFootnotes(tw, r.db)
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *SubcapacityReport) csvHeader() []string {
+	return []string{
+		"measurement_date",
+		"product_mnemo_code",
+		"mode",
+		"host_key",
+		"nodes",
+		"node_cores",
+		"host_cores",
+		"license_cores",
+		"full_capacity_cores",
+		"rule",
+		"explanation",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *SubcapacityReport) csvRecord(row SubcapacityRow) []string {
+	return []string{
+		row.MeasurementDate,
+		row.ProductMnemoCode,
+		row.Mode,
+		row.HostKey,
+		strings.Join(row.Nodes, " "),
+		fmt.Sprintf("%d", row.NodeCores),
+		formatCPUs(row.HostCores),
+		fmt.Sprintf("%d", row.LicenseCores),
+		fmt.Sprintf("%d", row.FullCapacity),
+		row.Rule,
+		row.Explanation,
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *SubcapacityReport) WriteCSV(w io.Writer, rows []SubcapacityRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *SubcapacityReport) WriteJSON(w io.Writer, rows []SubcapacityRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with one sheet per product
+func (r *SubcapacityReport) WriteXLSX(w io.Writer, rows []SubcapacityRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return sheetsByColumn(r.csvHeader(), records, 1, "Sub-capacity").Write(w)
+}
@@ -0,0 +1,118 @@
+package reports
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateMetadata describes the report run, for custom report templates
+type TemplateMetadata struct {
+	Report      string    // report name, e.g. compliance
+	GeneratedAt time.Time // time the report was generated
+	Database    string    // database file the report was read from
+	Product     string    // --product filter, empty when not set
+	Mode        string    // --mode filter, empty when not set
+	From        string    // --from filter (YYYY-MM-DD), empty when not set
+	To          string    // --to filter (YYYY-MM-DD), empty when not set
+}
+
+// TemplateData is the context of a custom report template
+type TemplateData struct {
+	Meta TemplateMetadata
+	// Rows are the report rows, with the fields of the JSON output (Go names)
+	Rows interface{}
+	// Count is the number of rows
+	Count int
+	// Totals holds the sum of every numeric field of the rows, by field name
+	Totals map[string]interface{}
+}
+
+// templateFuncs are the functions available to custom report templates
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+	"date": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	"pad": func(width int, value interface{}) string {
+		return fmt.Sprintf("%-*v", width, value)
+	},
+	"lpad": func(width int, value interface{}) string {
+		return fmt.Sprintf("%*v", width, value)
+	},
+}
+
+// NewTemplateData builds the template context of the rows of any report
+func NewTemplateData[T any](meta TemplateMetadata, rows []T) TemplateData {
+	return TemplateData{
+		Meta:   meta,
+		Rows:   rows,
+		Count:  len(rows),
+		Totals: sumNumericFields(rows),
+	}
+}
+
+// sumNumericFields adds up the integer and floating point fields of struct
+// rows. Integers are summed as int64, floating point values as float64;
+// pointers and other types are skipped.
+func sumNumericFields[T any](rows []T) map[string]interface{} {
+	totals := make(map[string]interface{})
+
+	rowType := reflect.TypeOf((*T)(nil)).Elem()
+	if rowType.Kind() != reflect.Struct {
+		return totals
+	}
+
+	for i := 0; i < rowType.NumField(); i++ {
+		field := rowType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			var sum int64
+			for _, row := range rows {
+				sum += reflect.ValueOf(row).Field(i).Int()
+			}
+			totals[field.Name] = sum
+		case reflect.Float32, reflect.Float64:
+			var sum float64
+			for _, row := range rows {
+				sum += reflect.ValueOf(row).Field(i).Float()
+			}
+			totals[field.Name] = sum
+		}
+	}
+
+	return totals
+}
+
+// ParseTemplate reads a text/template file for WriteTemplate
+func ParseTemplate(path string) (*template.Template, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// WriteTemplate renders the template with the report data
+func WriteTemplate(w io.Writer, tmpl *template.Template, data TemplateData) error {
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+	return nil
+}
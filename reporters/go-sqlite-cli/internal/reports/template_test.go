@@ -0,0 +1,65 @@
+package reports_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+func TestWriteTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memo.tmpl")
+	content := `{{ .Meta.Report }} {{ date "2006-01-02" .Meta.GeneratedAt }}
+{{ range .Rows }}{{ pad 12 .Name }}|{{ lpad 3 .CoresDelta }}
+{{ end }}{{ .Count }} rows, {{ .Totals.CoresDelta }} cores, {{ .Totals.NodesB }} nodes
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	tmpl, err := reports.ParseTemplate(path)
+	if err != nil {
+		t.Fatalf("ParseTemplate failed: %v", err)
+	}
+
+	rows := []reports.DiffRow{
+		{Scope: reports.DiffScopeProduct, Name: "IS_ONP_PRD", NodesB: 2, CoresDelta: 4},
+		{Scope: reports.DiffScopeHost, Name: "i12.local", NodesB: 1, CoresDelta: -2},
+	}
+	meta := reports.TemplateMetadata{Report: "diff", GeneratedAt: time.Date(2025, 10, 31, 8, 0, 0, 0, time.UTC)}
+
+	var out strings.Builder
+	if err := reports.WriteTemplate(&out, tmpl, reports.NewTemplateData(meta, rows)); err != nil {
+		t.Fatalf("WriteTemplate failed: %v", err)
+	}
+
+	want := `diff 2025-10-31
+IS_ONP_PRD  |  4
+i12.local   | -2
+2 rows, 2 cores, 3 nodes
+`
+	if out.String() != want {
+		t.Errorf("Unexpected output:\ngot:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestWriteTemplateUnknownTotal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memo.tmpl")
+	if err := os.WriteFile(path, []byte("{{ .Totals.Missing }}"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	tmpl, err := reports.ParseTemplate(path)
+	if err != nil {
+		t.Fatalf("ParseTemplate failed: %v", err)
+	}
+
+	var out strings.Builder
+	err = reports.WriteTemplate(&out, tmpl, reports.NewTemplateData(reports.TemplateMetadata{}, []reports.DiffRow{}))
+	if err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Errorf("Expected an error for the unknown total, got %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+package reports
+
+import "fmt"
+
+// termDocumentColumn returns a SQL expression selecting the location of the
+// license term document in force on a day, or an empty string when no document
+// attached to the term covers it. Program terms change over contract renewals,
+// so reports resolve the document per measurement date rather than per term.
+func termDocumentColumn(termIDColumn, dateColumn string) string {
+	return fmt.Sprintf(`COALESCE((
+		SELECT td.location FROM license_term_documents td
+		WHERE td.term_id = %[1]s
+		  AND DATE(td.effective_from) <= %[2]s
+		  AND (td.effective_to IS NULL OR DATE(td.effective_to) >= %[2]s)
+		ORDER BY td.effective_from DESC
+		LIMIT 1
+	), '')`, termIDColumn, dateColumn)
+}
@@ -0,0 +1,36 @@
+package reports_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+)
+
+// newTestDB creates a database with the schema, the license term T1 and its
+// product code IS_ONP_PRD, then runs the statements of the scenario
+func newTestDB(t *testing.T, stmts ...string) *sql.DB {
+	t.Helper()
+
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	stmts = append([]string{
+		`INSERT INTO license_terms (term_id, program_number, program_name) VALUES ('T1', '5900-AAA', 'Test Program')`,
+		`INSERT INTO product_codes (product_mnemo_code, ibm_product_code, product_name, mode, term_id)
+			VALUES ('IS_ONP_PRD', 'D0YYWZX', 'Integration Server', 'PROD', 'T1')`,
+	}, stmts...)
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Exec failed: %v\n%s", err, stmt)
+		}
+	}
+	return db
+}
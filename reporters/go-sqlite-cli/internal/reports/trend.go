@@ -0,0 +1,409 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// TrendRow describes the growth of the running license cores of one product
+type TrendRow struct {
+	ProductMnemoCode string `json:"product_mnemo_code"`
+	ProductName      string `json:"product_name"`
+	Mode             string `json:"mode"`
+	TermID           string `json:"term_id"`
+	FirstDate        string `json:"first_date"`
+	LatestDate       string `json:"latest_date"`
+	DaysMeasured     int    `json:"days_measured"`
+	CurrentCores     int    `json:"current_cores"` // running license cores on the latest date
+	// Peaks of the 7 days ending on the latest date and of the 7 days before
+	WeekPeak      int      `json:"week_peak"`
+	PrevWeekPeak  int      `json:"prev_week_peak"`
+	WeekGrowthPct *float64 `json:"week_growth_pct"` // nil when the previous week has no usage
+	// Peaks of the 30 days ending on the latest date and of the 30 days before
+	MonthPeak      int      `json:"month_peak"`
+	PrevMonthPeak  int      `json:"prev_month_peak"`
+	MonthGrowthPct *float64 `json:"month_growth_pct"` // nil when the previous month has no usage
+	// Linear projection of the license term usage (all products of the term)
+	// against its entitlement
+	CoresPerDay     float64 `json:"cores_per_day"`
+	TermCores       int     `json:"term_cores"`
+	LicensedCores   *int    `json:"licensed_cores"`
+	ProjectedExceed string  `json:"projected_exceed"` // date, "exceeded", or empty when not projected
+}
+
+// ProjectionExceeded marks terms whose current usage already exceeds the entitlement
+const ProjectionExceeded = "exceeded"
+
+// trendPoint is the usage of one day
+type trendPoint struct {
+	date  time.Time
+	cores int
+}
+
+// TrendReport computes growth rates from v_daily_license_cores
+type TrendReport struct {
+	db *sql.DB
+}
+
+// NewTrendReport creates a new report generator
+func NewTrendReport(db *sql.DB) *TrendReport {
+	return &TrendReport{db: db}
+}
+
+// Query computes the week-over-week and month-over-month growth of the running
+// license cores of each product, and projects when the usage of its license term
+// will exceed the entitlement. The projection is a least-squares line through the
+// daily term usage of the selected period, so the period should cover several weeks.
+func (r *TrendReport) Query(ctx context.Context, productCode, mode string, fromDate, toDate *time.Time) ([]TrendRow, error) {
+	query := `
+		SELECT
+			d.measurement_date,
+			d.product_mnemo_code,
+			COALESCE(p.product_name, ''),
+			COALESCE(p.mode, ''),
+			COALESCE(` + productTermColumn("p", "d.measurement_date") + `, ''),
+			e.licensed_cores,
+			d.running_license_cores
+		FROM v_daily_license_cores d
+		LEFT JOIN product_codes p ON d.product_mnemo_code = p.product_mnemo_code
+		LEFT JOIN entitlements e ON e.term_id = ` + productTermColumn("p", "d.measurement_date") + `
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if fromDate != nil {
+		query += " AND d.measurement_date >= ?"
+		args = append(args, fromDate.Format("2006-01-02"))
+	}
+
+	if toDate != nil {
+		query += " AND d.measurement_date <= ?"
+		args = append(args, toDate.Format("2006-01-02"))
+	}
+
+	query += " ORDER BY d.product_mnemo_code, d.measurement_date"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily license cores: %w", err)
+	}
+	defer rows.Close()
+
+	// Series per product, and per term for the entitlement projection. The term
+	// series needs every product, so the product and mode filters are applied afterwards.
+	products := make(map[string]*TrendRow)
+	productSeries := make(map[string][]trendPoint)
+	termDays := make(map[string]map[time.Time]int)
+	licensed := make(map[string]*int)
+	var order []string
+
+	for rows.Next() {
+		var date, code, name, mode, termID string
+		var licensedCores sql.NullInt64
+		var cores int
+
+		if err := rows.Scan(&date, &code, &name, &mode, &termID, &licensedCores, &cores); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		day, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid measurement date %q: %w", date, err)
+		}
+
+		if _, ok := products[code]; !ok {
+			products[code] = &TrendRow{ProductMnemoCode: code, ProductName: name, Mode: mode, TermID: termID}
+			order = append(order, code)
+		}
+		productSeries[code] = append(productSeries[code], trendPoint{day, cores})
+
+		if termID != "" {
+			if termDays[termID] == nil {
+				termDays[termID] = make(map[time.Time]int)
+			}
+			termDays[termID][day] += cores
+			if licensedCores.Valid {
+				v := int(licensedCores.Int64)
+				licensed[termID] = &v
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Term series and projections
+	type projection struct {
+		slope   float64
+		current int
+		exceed  string
+	}
+	projections := make(map[string]projection)
+	for termID, days := range termDays {
+		series := make([]trendPoint, 0, len(days))
+		for day, cores := range days {
+			series = append(series, trendPoint{day, cores})
+		}
+		sort.Slice(series, func(i, j int) bool { return series[i].date.Before(series[j].date) })
+
+		p := projection{current: series[len(series)-1].cores}
+		p.slope, p.exceed = projectExceed(series, licensed[termID])
+		projections[termID] = p
+	}
+
+	var results []TrendRow
+	for _, code := range order {
+		row := products[code]
+		if (productCode != "" && code != productCode) || (mode != "" && row.Mode != mode) {
+			continue
+		}
+
+		series := productSeries[code]
+		latest := series[len(series)-1]
+
+		row.FirstDate = series[0].date.Format("2006-01-02")
+		row.LatestDate = latest.date.Format("2006-01-02")
+		row.DaysMeasured = len(series)
+		row.CurrentCores = latest.cores
+
+		row.WeekPeak = windowPeak(series, latest.date, 0, 7)
+		row.PrevWeekPeak = windowPeak(series, latest.date, 7, 7)
+		row.WeekGrowthPct = growthPct(row.WeekPeak, row.PrevWeekPeak)
+		row.MonthPeak = windowPeak(series, latest.date, 0, 30)
+		row.PrevMonthPeak = windowPeak(series, latest.date, 30, 30)
+		row.MonthGrowthPct = growthPct(row.MonthPeak, row.PrevMonthPeak)
+
+		if p, ok := projections[row.TermID]; ok {
+			row.CoresPerDay = p.slope
+			row.TermCores = p.current
+			row.LicensedCores = licensed[row.TermID]
+			row.ProjectedExceed = p.exceed
+		}
+
+		results = append(results, *row)
+	}
+
+	return results, nil
+}
+
+// windowPeak returns the highest usage in the window of days ending offset days
+// before end (offset 0 includes end itself)
+func windowPeak(series []trendPoint, end time.Time, offset, days int) int {
+	last := end.AddDate(0, 0, -offset)
+	first := last.AddDate(0, 0, -(days - 1))
+
+	peak := 0
+	for _, p := range series {
+		if !p.date.Before(first) && !p.date.After(last) && p.cores > peak {
+			peak = p.cores
+		}
+	}
+	return peak
+}
+
+// growthPct returns the relative change from previous to current in percent,
+// nil when there is no previous usage to compare with
+func growthPct(current, previous int) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	pct := float64(current-previous) / float64(previous) * 100
+	return &pct
+}
+
+// projectExceed fits a least-squares line through the series and returns its slope
+// in cores per day together with the first day on which the line exceeds the
+// licensed cores. The date is empty when there is no entitlement, fewer than two days, or no
+// growth, and ProjectionExceeded when the latest usage is already above it.
+func projectExceed(series []trendPoint, licensedCores *int) (float64, string) {
+	latest := series[len(series)-1]
+	exceeded := licensedCores != nil && latest.cores > *licensedCores
+	if len(series) < 2 {
+		if exceeded {
+			return 0, ProjectionExceeded
+		}
+		return 0, ""
+	}
+
+	// x is the number of days since the first measurement
+	origin := series[0].date
+	var sumX, sumY, sumXY, sumXX float64
+	for _, p := range series {
+		x := p.date.Sub(origin).Hours() / 24
+		y := float64(p.cores)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	n := float64(len(series))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, ""
+	}
+	slope := (n*sumXY - sumX*sumY) / denominator
+	intercept := (sumY - slope*sumX) / n
+
+	if exceeded {
+		return slope, ProjectionExceeded
+	}
+	if licensedCores == nil || slope <= 0 {
+		return slope, ""
+	}
+
+	// First whole day after the latest measurement on which the line is above the entitlement
+	crossing := (float64(*licensedCores) - intercept) / slope
+	latestX := latest.date.Sub(origin).Hours() / 24
+	day := math.Floor(crossing) + 1
+	if day <= latestX {
+		day = latestX + 1
+	}
+	return slope, origin.AddDate(0, 0, int(day)).Format("2006-01-02")
+}
+
+// formatPct formats an optional percentage, empty when unknown
+func formatPct(pct *float64) string {
+	if pct == nil {
+		return ""
+	}
+	return fmt.Sprintf("%+.1f%%", *pct)
+}
+
+// formatLicensed formats optional licensed cores, "-" when there is no entitlement
+func formatLicensed(cores *int) string {
+	if cores == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", *cores)
+}
+
+// WriteTable writes data in ASCII table format
+func (r *TrendReport) WriteTable(w io.Writer, rows []TrendRow) error {
+	tw := newTableWriter(w)
+	defer tw.Flush()
+
+	// Header
+	fmt.Fprintln(tw, "PRODUCT\tLATEST\tCORES\tWEEK_PEAK\tWoW\tMONTH_PEAK\tMoM\tCORES/DAY\tTERM\tTERM_CORES\tLICENSED\tEXCEEDS")
+	fmt.Fprintln(tw, "-------\t------\t-----\t---------\t---\t----------\t---\t---------\t----\t----------\t--------\t-------")
+
+	// Data rows
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%d\t%s\t%.2f\t%s\t%d\t%s\t%s\n",
+			row.ProductMnemoCode,
+			row.LatestDate,
+			row.CurrentCores,
+			row.WeekPeak,
+			formatPct(row.WeekGrowthPct),
+			row.MonthPeak,
+			formatPct(row.MonthGrowthPct),
+			row.CoresPerDay,
+			row.TermID,
+			row.TermCores,
+			formatLicensed(row.LicensedCores),
+			row.ProjectedExceed,
+		)
+	}
+
+	return writeDailyFootnotes(tw, r.db)
+}
+
+// csvHeader returns the column names used by the CSV and XLSX outputs
+func (r *TrendReport) csvHeader() []string {
+	return []string{
+		"product_mnemo_code",
+		"product_name",
+		"mode",
+		"term_id",
+		"first_date",
+		"latest_date",
+		"days_measured",
+		"current_cores",
+		"week_peak",
+		"prev_week_peak",
+		"week_growth_pct",
+		"month_peak",
+		"prev_month_peak",
+		"month_growth_pct",
+		"cores_per_day",
+		"term_cores",
+		"licensed_cores",
+		"projected_exceed",
+	}
+}
+
+// csvRecord converts a row to the field values used by the CSV and XLSX outputs
+func (r *TrendReport) csvRecord(row TrendRow) []string {
+	pct := func(p *float64) string {
+		if p == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", *p)
+	}
+	licensed := ""
+	if row.LicensedCores != nil {
+		licensed = fmt.Sprintf("%d", *row.LicensedCores)
+	}
+
+	return []string{
+		row.ProductMnemoCode,
+		row.ProductName,
+		row.Mode,
+		row.TermID,
+		row.FirstDate,
+		row.LatestDate,
+		fmt.Sprintf("%d", row.DaysMeasured),
+		fmt.Sprintf("%d", row.CurrentCores),
+		fmt.Sprintf("%d", row.WeekPeak),
+		fmt.Sprintf("%d", row.PrevWeekPeak),
+		pct(row.WeekGrowthPct),
+		fmt.Sprintf("%d", row.MonthPeak),
+		fmt.Sprintf("%d", row.PrevMonthPeak),
+		pct(row.MonthGrowthPct),
+		fmt.Sprintf("%.4f", row.CoresPerDay),
+		fmt.Sprintf("%d", row.TermCores),
+		licensed,
+		row.ProjectedExceed,
+	}
+}
+
+// WriteCSV writes data in CSV format
+func (r *TrendReport) WriteCSV(w io.Writer, rows []TrendRow) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write(r.csvHeader()); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(r.csvRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes data in JSON format
+func (r *TrendReport) WriteJSON(w io.Writer, rows []TrendRow) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// WriteXLSX writes an Excel workbook with all products on one sheet
+func (r *TrendReport) WriteXLSX(w io.Writer, rows []TrendRow) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, r.csvRecord(row))
+	}
+	return singleSheet("Usage Trend", r.csvHeader(), records).Write(w)
+}
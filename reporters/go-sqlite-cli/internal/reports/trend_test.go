@@ -0,0 +1,106 @@
+package reports
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// trendSeries returns one point per day from 2025-10-01 with the given cores
+func trendSeries(cores ...int) []trendPoint {
+	origin := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	series := make([]trendPoint, 0, len(cores))
+	for i, c := range cores {
+		series = append(series, trendPoint{date: origin.AddDate(0, 0, i), cores: c})
+	}
+	return series
+}
+
+func TestProjectExceed(t *testing.T) {
+	licensed := func(cores int) *int { return &cores }
+
+	tests := []struct {
+		name      string
+		series    []trendPoint
+		licensed  *int
+		wantSlope float64
+		wantDate  string
+	}{
+		{"flat series never crosses", trendSeries(10, 10, 10), licensed(20), 0, ""},
+		{"declining series never crosses", trendSeries(10, 8, 6), licensed(20), -2, ""},
+		{"already exceeded", trendSeries(18, 22, 26), licensed(20), 4, ProjectionExceeded},
+		{"declining but still exceeded", trendSeries(30, 28, 26), licensed(20), -2, ProjectionExceeded},
+		{"single day", trendSeries(10), licensed(20), 0, ""},
+		{"single day exceeded", trendSeries(25), licensed(20), 0, ProjectionExceeded},
+		{"no entitlement", trendSeries(10, 12, 14), nil, 2, ""},
+		// 10 + 2x reaches 20 on day 5 without exceeding it; it exceeds it on day 6
+		{"crossing on a whole day", trendSeries(10, 12, 14), licensed(20), 2, "2025-10-07"},
+		{"crossing within a day", trendSeries(10, 12, 14), licensed(21), 2, "2025-10-07"},
+		// The line is already above the entitlement although the latest day is not
+		{"crossing before the latest day", trendSeries(10, 30, 19), licensed(20), 4.5, "2025-10-04"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slope, date := projectExceed(tt.series, tt.licensed)
+			if math.Abs(slope-tt.wantSlope) > 1e-9 || date != tt.wantDate {
+				t.Errorf("projectExceed() = %v, %q, want %v, %q", slope, date, tt.wantSlope, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestWindowPeak(t *testing.T) {
+	// 2025-10-01 to 2025-10-10
+	series := trendSeries(5, 9, 3, 4, 7, 2, 8, 1, 6, 4)
+	end := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		offset, days int
+		want         int
+	}{
+		{"window including the end", 0, 3, 6},
+		{"single day window", 0, 1, 4},
+		{"previous window", 3, 3, 8},
+		{"window reaching the first day", 5, 5, 9},
+		{"window before the series", 10, 5, 0},
+		{"window longer than the series", 0, 30, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := windowPeak(series, end, tt.offset, tt.days); got != tt.want {
+				t.Errorf("windowPeak(%d, %d) = %d, want %d", tt.offset, tt.days, got, tt.want)
+			}
+		})
+	}
+
+	if got := windowPeak(trendSeries(4), end.AddDate(0, 0, -9), 0, 30); got != 4 {
+		t.Errorf("windowPeak of a single day = %d, want 4", got)
+	}
+}
+
+func TestGrowthPct(t *testing.T) {
+	tests := []struct {
+		current, previous int
+		want              *float64
+	}{
+		{10, 0, nil},
+		{0, 0, nil},
+		{15, 10, ptr(50.0)},
+		{10, 10, ptr(0.0)},
+		{5, 10, ptr(-50.0)},
+		{0, 8, ptr(-100.0)},
+	}
+	for _, tt := range tests {
+		got := growthPct(tt.current, tt.previous)
+		switch {
+		case tt.want == nil && got != nil:
+			t.Errorf("growthPct(%d, %d) = %v, want nil", tt.current, tt.previous, *got)
+		case tt.want != nil && (got == nil || math.Abs(*got-*tt.want) > 1e-9):
+			t.Errorf("growthPct(%d, %d) = %v, want %v", tt.current, tt.previous, got, *tt.want)
+		}
+	}
+}
+
+// ptr returns a pointer to v
+func ptr(v float64) *float64 { return &v }
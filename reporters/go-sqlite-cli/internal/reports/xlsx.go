@@ -0,0 +1,42 @@
+package reports
+
+import (
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/xlsx"
+)
+
+// sheetsByColumn builds a workbook with one sheet per distinct value of the
+// given column, in order of first appearance. An empty result still produces
+// a single sheet (named emptyName) holding just the header.
+func sheetsByColumn(header []string, records [][]string, column int, emptyName string) *xlsx.Workbook {
+	wb := &xlsx.Workbook{}
+	if len(records) == 0 {
+		wb.AddSheet(emptyName, header, nil)
+		return wb
+	}
+	
+	var keys []string
+	groups := make(map[string][][]string)
+	for _, record := range records {
+		key := record[column]
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], record)
+	}
+	
+	for _, key := range keys {
+		name := key
+		if name == "" {
+			name = emptyName
+		}
+		wb.AddSheet(name, header, groups[key])
+	}
+	return wb
+}
+
+// singleSheet builds a workbook holding all records on one sheet
+func singleSheet(name string, header []string, records [][]string) *xlsx.Workbook {
+	wb := &xlsx.Workbook{}
+	wb.AddSheet(name, header, records)
+	return wb
+}
@@ -0,0 +1,242 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sam exports the latest measurement of each node and the products
+// detected on it as the CSV import files of software asset management tools,
+// so that a corporate SAM team can consolidate them with the data of other
+// vendors: the inventory staging tables of Flexera One / FlexNet Manager
+// Suite, and the computer and software installation import sets of
+// ServiceNow SAM Pro.
+package sam
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Export formats
+const (
+	FormatFlexera    = "flexera"
+	FormatServiceNow = "servicenow"
+)
+
+// Publisher is the publisher of the detected products
+const Publisher = "IBM"
+
+// DiscoverySource names iwldr as the source of the exported records
+const DiscoverySource = "iwldr"
+
+// File is an exported file with its number of rows
+type File struct {
+	Path string
+	Rows int
+}
+
+// node is the latest measurement of a landscape node
+type node struct {
+	mainFQDN       string
+	hostname       string
+	measuredAt     string // YYYY-MM-DD HH:MM:SS UTC
+	osName         string
+	osVersion      string
+	cpuCount       int
+	virtualized    string // yes, no or unknown
+	virtType       string
+	vendor         string
+	brand          string
+	hostCPUs       string
+	consideredCPUs int
+	physicalHostID string
+}
+
+// install is a product detected by the latest measurement of a node
+type install struct {
+	mainFQDN      string
+	mnemoCode     string
+	ibmCode       string
+	productName   string
+	programNumber string
+	programName   string
+	running       string // running, not-running or unknown
+	installCount  int
+	installPath   string // first install path reported, if any
+	firstSeen     string // first measurement detecting the product on the node
+	measuredAt    string
+}
+
+// Exporter writes the measurements and detected products in the import
+// formats of SAM tools
+type Exporter struct {
+	db *sql.DB
+}
+
+// NewExporter creates a new SAM exporter
+func NewExporter(db *sql.DB) *Exporter {
+	return &Exporter{db: db}
+}
+
+// Export writes the files of a format to dir, creating it if needed, and
+// returns them. Only the latest measurement of each node is exported;
+// decommissioned nodes are left out. Existing files are overwritten.
+func (e *Exporter) Export(dir, format string) ([]File, error) {
+	if format != FormatFlexera && format != FormatServiceNow {
+		return nil, fmt.Errorf("unsupported SAM format %q (expected %s or %s)", format, FormatFlexera, FormatServiceNow)
+	}
+
+	nodes, err := e.loadNodes()
+	if err != nil {
+		return nil, err
+	}
+	installs, err := e.loadInstalls()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var tables []table
+	if format == FormatFlexera {
+		tables = flexeraTables(nodes, installs)
+	} else {
+		tables = serviceNowTables(nodes, installs)
+	}
+
+	files := make([]File, 0, len(tables))
+	for _, t := range tables {
+		path := filepath.Join(dir, t.file)
+		if err := writeCSV(path, t.header, t.records); err != nil {
+			return nil, err
+		}
+		files = append(files, File{Path: path, Rows: len(t.records)})
+	}
+	return files, nil
+}
+
+// table is the content of an exported file
+type table struct {
+	file    string
+	header  []string
+	records [][]string
+}
+
+func (e *Exporter) loadNodes() ([]node, error) {
+	rows, err := e.db.Query(`
+		SELECT lm.main_fqdn, n.hostname, strftime('%Y-%m-%d %H:%M:%S', lm.detection_timestamp),
+			lm.os_name, lm.os_version, lm.cpu_count, lm.is_virtualized, COALESCE(lm.virt_type, ''),
+			COALESCE(lm.processor_vendor, ''), COALESCE(lm.processor_brand, ''),
+			COALESCE(lm.host_physical_cpus, ''), lm.considered_cpus, COALESCE(lm.physical_host_id, '')
+		FROM v_latest_measurements lm
+		JOIN landscape_nodes n ON n.main_fqdn = lm.main_fqdn
+		WHERE n.classification != 'decommissioned'
+		ORDER BY lm.main_fqdn
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query measurements: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []node
+	for rows.Next() {
+		var n node
+		if err := rows.Scan(&n.mainFQDN, &n.hostname, &n.measuredAt, &n.osName, &n.osVersion, &n.cpuCount,
+			&n.virtualized, &n.virtType, &n.vendor, &n.brand, &n.hostCPUs, &n.consideredCPUs, &n.physicalHostID); err != nil {
+			return nil, fmt.Errorf("failed to scan measurement: %w", err)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, rows.Err()
+}
+
+func (e *Exporter) loadInstalls() ([]install, error) {
+	rows, err := e.db.Query(`
+		SELECT d.main_fqdn, d.product_mnemo_code, p.ibm_product_code, p.product_name,
+			COALESCE(t.program_number, ''), COALESCE(t.program_name, ''),
+			COALESCE(d.running_status, 'unknown'), COALESCE(d.install_count, 0),
+			COALESCE((SELECT i.install_path FROM detected_product_installs i
+				WHERE i.main_fqdn = d.main_fqdn AND i.product_mnemo_code = d.product_mnemo_code
+					AND i.detection_timestamp = d.detection_timestamp
+				ORDER BY i.seq LIMIT 1), ''),
+			(SELECT strftime('%Y-%m-%d %H:%M:%S', MIN(f.detection_timestamp)) FROM detected_products f
+				WHERE f.main_fqdn = d.main_fqdn AND f.product_mnemo_code = d.product_mnemo_code
+					AND (f.status = 'present' OR f.install_count > 0)),
+			strftime('%Y-%m-%d %H:%M:%S', d.detection_timestamp)
+		FROM detected_products d
+		JOIN v_latest_measurements lm ON lm.main_fqdn = d.main_fqdn AND lm.detection_timestamp = d.detection_timestamp
+		JOIN landscape_nodes n ON n.main_fqdn = d.main_fqdn
+		JOIN product_codes p ON p.product_mnemo_code = d.product_mnemo_code
+		LEFT JOIN license_terms t ON t.term_id = p.term_id
+		WHERE (d.status = 'present' OR d.install_count > 0)
+			AND n.classification != 'decommissioned'
+		ORDER BY d.main_fqdn, d.product_mnemo_code
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query detected products: %w", err)
+	}
+	defer rows.Close()
+
+	var installs []install
+	for rows.Next() {
+		var i install
+		if err := rows.Scan(&i.mainFQDN, &i.mnemoCode, &i.ibmCode, &i.productName, &i.programNumber, &i.programName,
+			&i.running, &i.installCount, &i.installPath, &i.firstSeen, &i.measuredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan detected product: %w", err)
+		}
+		installs = append(installs, i)
+	}
+	return installs, rows.Err()
+}
+
+// domain returns the DNS domain of an FQDN, empty for a short name
+func domain(fqdn string) string {
+	_, rest, _ := strings.Cut(fqdn, ".")
+	return rest
+}
+
+// operatingSystem returns the operating system name with its version
+func (n node) operatingSystem() string {
+	return strings.TrimSpace(n.osName + " " + n.osVersion)
+}
+
+// physicalCPUs returns the physical cores of the host of a virtualized node,
+// empty when unknown
+func (n node) physicalCPUs() string {
+	if n.hostCPUs == "unknown" {
+		return ""
+	}
+	return n.hostCPUs
+}
+
+// writeCSV writes a CSV file with a header
+func writeCSV(path string, header []string, records [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := writer.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return file.Close()
+}
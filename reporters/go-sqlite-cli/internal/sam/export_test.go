@@ -0,0 +1,154 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sam_test
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/sam"
+)
+
+// readCSV returns the rows of a CSV file as maps by header
+func readCSV(t *testing.T, path string) []map[string]string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	var rows []map[string]string
+	for _, record := range records[1:] {
+		row := make(map[string]string)
+		for i, name := range records[0] {
+			row[name] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestExport(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	for _, stmt := range []string{
+		`INSERT INTO license_terms (term_id, program_number, program_name) VALUES ('T1', '5724-L01', 'IBM webMethods Integration')`,
+		`INSERT INTO product_codes (product_mnemo_code, ibm_product_code, product_name, mode, term_id)
+			VALUES ('IS_ONP_PRD', 'D0R4ZLL', 'webMethods Integration Server', 'PROD', 'T1')`,
+		`INSERT INTO product_codes (product_mnemo_code, ibm_product_code, product_name, mode, term_id)
+			VALUES ('BRK_ONP_PRD', 'D0R50LL', 'webMethods Broker', 'PROD', 'T1')`,
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('app01.example.com', 'app01', 'PROD')`,
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('app02.example.com', 'app02', 'PROD')`,
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode, classification) VALUES ('old01.example.com', 'old01', 'PROD', 'decommissioned')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed: %v", err)
+		}
+	}
+
+	measure := func(fqdn, at, virtualized, hostID string, products ...string) {
+		t.Helper()
+		if _, err := db.Exec(`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count,
+			is_virtualized, virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus, physical_host_id)
+			VALUES (?, ?, 'Linux', '8', 4, ?, 'vmware', '16', 'true', 'true', 'true', 4, ?)`,
+			fqdn, at, virtualized, hostID); err != nil {
+			t.Fatalf("Failed to insert measurement: %v", err)
+		}
+		for _, product := range products {
+			if _, err := db.Exec(`INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, running_status, install_count)
+				VALUES (?, ?, ?, 'present', 'running', 2)`, fqdn, product, at); err != nil {
+				t.Fatalf("Failed to insert detected product: %v", err)
+			}
+		}
+	}
+	measure("app01.example.com", "2025-10-01 08:00:00", "yes", "esx01", "IS_ONP_PRD")
+	measure("app01.example.com", "2025-11-01 08:00:00", "yes", "esx01", "IS_ONP_PRD", "BRK_ONP_PRD")
+	measure("app02.example.com", "2025-11-01 08:00:00", "no", "")
+	measure("old01.example.com", "2025-11-01 08:00:00", "no", "", "IS_ONP_PRD")
+	if _, err := db.Exec(`INSERT INTO detected_product_installs (main_fqdn, product_mnemo_code, detection_timestamp, seq, install_path)
+		VALUES ('app01.example.com', 'IS_ONP_PRD', '2025-11-01 08:00:00', 1, '/opt/wm')`); err != nil {
+		t.Fatalf("Failed to insert install path: %v", err)
+	}
+
+	exporter := sam.NewExporter(db)
+
+	t.Run("flexera", func(t *testing.T) {
+		dir := t.TempDir()
+		files, err := exporter.Export(dir, sam.FormatFlexera)
+		if err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		if len(files) != 3 || files[0].Rows != 2 || files[1].Rows != 1 || files[2].Rows != 2 {
+			t.Fatalf("Unexpected files: %+v", files)
+		}
+
+		computers := readCSV(t, filepath.Join(dir, "ImportedComputer.csv"))
+		if computers[0]["ExternalID"] != "app01.example.com" || computers[0]["Domain"] != "example.com" ||
+			computers[0]["IsVirtual"] != "True" || computers[0]["InventoryDate"] != "2025-11-01 08:00:00" {
+			t.Errorf("Unexpected computer: %v", computers[0])
+		}
+		vms := readCSV(t, filepath.Join(dir, "ImportedVirtualMachine.csv"))
+		if vms[0]["HostExternalID"] != "esx01" || vms[0]["HostNumberOfCores"] != "16" {
+			t.Errorf("Unexpected virtual machine: %v", vms[0])
+		}
+
+		evidence := readCSV(t, filepath.Join(dir, "ImportedInstallerEvidence.csv"))
+		is := evidence[1] // ordered by product code, BRK_ONP_PRD first
+		if is["ProductCode"] != "D0R4ZLL" || is["Publisher"] != "IBM" || is["InstallLocation"] != "/opt/wm" ||
+			is["InstallDate"] != "2025-10-01 08:00:00" || is["ProgramNumber"] != "5724-L01" {
+			t.Errorf("Unexpected installer evidence: %v", is)
+		}
+	})
+
+	t.Run("servicenow", func(t *testing.T) {
+		dir := t.TempDir()
+		files, err := exporter.Export(dir, sam.FormatServiceNow)
+		if err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		if len(files) != 2 || files[0].Rows != 2 || files[1].Rows != 2 {
+			t.Fatalf("Unexpected files: %+v", files)
+		}
+
+		computers := readCSV(t, filepath.Join(dir, "cmdb_ci_computer.csv"))
+		if computers[1]["name"] != "app02.example.com" || computers[1]["virtual"] != "false" {
+			t.Errorf("Unexpected computer: %v", computers[1])
+		}
+		installs := readCSV(t, filepath.Join(dir, "cmdb_sam_sw_install.csv"))
+		if installs[0]["installed_on"] != "app01.example.com" || installs[0]["display_name"] != "webMethods Broker" ||
+			installs[0]["instances"] != "2" || installs[0]["running"] != "true" || installs[0]["discovery_source"] != "iwldr" {
+			t.Errorf("Unexpected software installation: %v", installs[0])
+		}
+	})
+
+	if _, err := exporter.Export(t.TempDir(), "csv"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
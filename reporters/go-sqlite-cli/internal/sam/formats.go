@@ -0,0 +1,118 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sam
+
+import "strconv"
+
+// flexeraTables returns the files of the Flexera format, named after the
+// inventory staging tables of FlexNet Manager Suite they are loaded into by a
+// custom inventory adapter: ImportedComputer, ImportedVirtualMachine for the
+// virtualized nodes and ImportedInstallerEvidence. Computers are identified by
+// their main FQDN (ExternalID).
+func flexeraTables(nodes []node, installs []install) []table {
+	computers := table{
+		file: "ImportedComputer.csv",
+		header: []string{"ExternalID", "ComputerName", "Domain", "OperatingSystem", "NumberOfProcessors",
+			"NumberOfCores", "ProcessorType", "Manufacturer", "IsVirtual", "InventoryDate", "InventoryAgent"},
+		records: [][]string{},
+	}
+	vms := table{
+		file: "ImportedVirtualMachine.csv",
+		header: []string{"ExternalID", "VMName", "VMType", "HostExternalID", "HostNumberOfCores",
+			"NumberOfProcessors", "InventoryDate"},
+		records: [][]string{},
+	}
+	for _, n := range nodes {
+		isVirtual := "False"
+		if n.virtualized == "yes" {
+			isVirtual = "True"
+			vms.records = append(vms.records, []string{
+				n.mainFQDN, n.hostname, n.virtType, n.physicalHostID, n.physicalCPUs(),
+				strconv.Itoa(n.consideredCPUs), n.measuredAt,
+			})
+		}
+		computers.records = append(computers.records, []string{
+			n.mainFQDN, n.hostname, domain(n.mainFQDN), n.operatingSystem(), strconv.Itoa(n.cpuCount),
+			strconv.Itoa(n.cpuCount), n.brand, n.vendor, isVirtual, n.measuredAt, DiscoverySource,
+		})
+	}
+
+	evidence := table{
+		file: "ImportedInstallerEvidence.csv",
+		header: []string{"ComputerID", "DisplayName", "Publisher", "ProductCode", "ProgramNumber", "InstallLocation",
+			"InstallDate", "Running", "InventoryDate"},
+		records: [][]string{},
+	}
+	for _, i := range installs {
+		running := ""
+		switch i.running {
+		case "running":
+			running = "True"
+		case "not-running":
+			running = "False"
+		}
+		evidence.records = append(evidence.records, []string{
+			i.mainFQDN, i.productName, Publisher, i.ibmCode, i.programNumber, i.installPath,
+			i.firstSeen, running, i.measuredAt,
+		})
+	}
+	return []table{computers, vms, evidence}
+}
+
+// serviceNowTables returns the files of the ServiceNow format, named after the
+// tables their import sets transform into: cmdb_ci_computer for the computers
+// and cmdb_sam_sw_install for the software installations, which reference
+// their computer by name (installed_on)
+func serviceNowTables(nodes []node, installs []install) []table {
+	computers := table{
+		file: "cmdb_ci_computer.csv",
+		header: []string{"name", "fqdn", "dns_domain", "os", "os_version", "cpu_count", "cpu_core_count",
+			"cpu_manufacturer", "cpu_name", "virtual", "virtualization_type", "physical_host", "host_cpu_core_count",
+			"license_cpu_count", "discovery_source", "last_discovered"},
+		records: [][]string{},
+	}
+	for _, n := range nodes {
+		computers.records = append(computers.records, []string{
+			n.mainFQDN, n.mainFQDN, domain(n.mainFQDN), n.osName, n.osVersion, strconv.Itoa(n.cpuCount),
+			strconv.Itoa(n.cpuCount), n.vendor, n.brand, strconv.FormatBool(n.virtualized == "yes"), n.virtType,
+			n.physicalHostID, n.physicalCPUs(), strconv.Itoa(n.consideredCPUs), DiscoverySource, n.measuredAt,
+		})
+	}
+
+	software := table{
+		file: "cmdb_sam_sw_install.csv",
+		header: []string{"installed_on", "display_name", "publisher", "prod_id", "u_program_number", "install_location",
+			"install_date", "instances", "running", "discovery_source", "last_scanned"},
+		records: [][]string{},
+	}
+	for _, i := range installs {
+		instances := i.installCount
+		if instances == 0 {
+			instances = 1
+		}
+		running := ""
+		switch i.running {
+		case "running":
+			running = "true"
+		case "not-running":
+			running = "false"
+		}
+		software.records = append(software.records, []string{
+			i.mainFQDN, i.productName, Publisher, i.ibmCode, i.programNumber, i.installPath,
+			i.firstSeen, strconv.Itoa(instances), running, DiscoverySource, i.measuredAt,
+		})
+	}
+	return []table{computers, software}
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/schedule"
+)
+
+func TestCronNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2025, 10, 22, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"* * * * *", "2025-10-22 10:18"},
+		{"*/15 * * * *", "2025-10-22 10:30"},
+		{"30 2 * * *", "2025-10-23 02:30"},
+		{"@daily", "2025-10-23 00:00"},
+		{"@monthly", "2025-11-01 00:00"},
+		{"0 9 * * 1-5", "2025-10-23 09:00"},
+		{"0 9 * * 6,7", "2025-10-25 09:00"},
+		{"0 0 * * 0", "2025-10-26 00:00"},
+		{"0 12 1 * 3", "2025-10-22 12:00"}, // day of month or day of week
+		{"0 0 29 2 *", "2028-02-29 00:00"},
+		{"5/20 10 22 10 *", "2025-10-22 10:25"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			cron, err := schedule.ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron failed: %v", err)
+			}
+			if got := cron.Next(from).Format("2006-01-02 15:04"); got != tt.want {
+				t.Errorf("Next = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNextNever(t *testing.T) {
+	cron, err := schedule.ParseCron("0 0 31 2 *")
+	if err != nil {
+		t.Fatalf("ParseCron failed: %v", err)
+	}
+	if next := cron.Next(time.Now()); !next.IsZero() {
+		t.Errorf("Expected no next run, got %s", next)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *", "@often"} {
+		if _, err := schedule.ParseCron(expr); err == nil {
+			t.Errorf("Expected an error for %q", expr)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Job is a command run on a schedule
+type Job struct {
+	Name   string
+	Cron   *Cron
+	Args   []string // iwldr command line, e.g. report compliance --format csv
+	Output string   // output file name written to the dated directory, if any
+}
+
+// ExecFunc runs a job. dir is the dated output directory of the run, which
+// exists when ExecFunc is called.
+type ExecFunc func(ctx context.Context, job Job, dir string) error
+
+// Runner runs jobs when they are due. Jobs run one at a time, so that two jobs
+// never write to the database concurrently; a job that becomes due while
+// another one runs starts as soon as that one finishes.
+type Runner struct {
+	Jobs      []Job
+	OutputDir string // runs write to OutputDir/YYYY-MM-DD
+	Exec      ExecFunc
+	Logf      func(format string, args ...interface{})
+
+	now func() time.Time
+}
+
+// NewRunner creates a runner writing below outputDir
+func NewRunner(jobs []Job, outputDir string, exec ExecFunc) *Runner {
+	return &Runner{
+		Jobs:      jobs,
+		OutputDir: outputDir,
+		Exec:      exec,
+		Logf:      func(string, ...interface{}) {},
+		now:       time.Now,
+	}
+}
+
+// NextRuns returns the next run time of each job after t, in job order
+func (r *Runner) NextRuns(t time.Time) []time.Time {
+	next := make([]time.Time, len(r.Jobs))
+	for i, job := range r.Jobs {
+		next[i] = job.Cron.Next(t)
+	}
+	return next
+}
+
+// Run runs the jobs on their schedule until ctx is cancelled. A failing job is
+// logged and does not stop the runner.
+func (r *Runner) Run(ctx context.Context) error {
+	if len(r.Jobs) == 0 {
+		return fmt.Errorf("no scheduled jobs configured")
+	}
+
+	next := r.NextRuns(r.now())
+	for {
+		due := -1
+		for i, t := range next {
+			if !t.IsZero() && (due < 0 || t.Before(next[due])) {
+				due = i
+			}
+		}
+		if due < 0 {
+			return fmt.Errorf("no scheduled job will run again")
+		}
+
+		if wait := next[due].Sub(r.now()); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+			}
+		}
+
+		job := r.Jobs[due]
+		if err := r.RunJob(ctx, job); err != nil {
+			r.Logf("Job %s failed: %v", job.Name, err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		next[due] = job.Cron.Next(r.now())
+	}
+}
+
+// RunJob runs one job immediately in the dated directory of the current day
+func (r *Runner) RunJob(ctx context.Context, job Job) error {
+	start := r.now()
+	dir := filepath.Join(r.OutputDir, start.Format("2006-01-02"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	r.Logf("Running job %s", job.Name)
+	if err := r.Exec(ctx, job, dir); err != nil {
+		return err
+	}
+	r.Logf("Job %s finished in %s", job.Name, r.now().Sub(start).Round(time.Second))
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunnerRunsDueJobs(t *testing.T) {
+	cron, err := ParseCron("* * * * *")
+	if err != nil {
+		t.Fatalf("ParseCron failed: %v", err)
+	}
+
+	outputDir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Every run takes a minute of the fake clock, so the next job is already due
+	clock := time.Date(2025, 10, 22, 23, 57, 59, 999e6, time.Local)
+	var ran []string
+	runner := NewRunner([]Job{
+		{Name: "failing", Cron: cron},
+		{Name: "report", Cron: cron},
+	}, outputDir, func(ctx context.Context, job Job, dir string) error {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("Output directory missing: %v", err)
+		}
+		ran = append(ran, job.Name)
+		clock = clock.Add(time.Minute)
+		if len(ran) == 4 {
+			cancel()
+		}
+		if job.Name == "failing" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	runner.now = func() time.Time { return clock }
+
+	done := make(chan error)
+	go func() { done <- runner.Run(ctx) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Runner did not run the jobs")
+	}
+
+	// Jobs alternate; a failing job does not stop the runner
+	want := []string{"failing", "report", "failing", "report"}
+	for i := range want {
+		if i >= len(ran) || ran[i] != want[i] {
+			t.Fatalf("Expected runs %v, got %v", want, ran)
+		}
+	}
+
+	// Runs after midnight write to the next day's directory
+	for _, day := range []string{"2025-10-22", "2025-10-23"} {
+		if _, err := os.Stat(filepath.Join(outputDir, day)); err != nil {
+			t.Errorf("Expected run directory %s: %v", day, err)
+		}
+	}
+}
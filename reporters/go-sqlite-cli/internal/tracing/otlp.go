@@ -0,0 +1,229 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// batchSize is the number of ended spans exported in one request
+const batchSize = 512
+
+// scopeName is the instrumentation scope of the spans
+const scopeName = "github.com/ibm-webmethods-aftermarket-tools/iwldr"
+
+// exporter posts spans to the OTLP/HTTP traces endpoint of a collector,
+// encoded as JSON
+type exporter struct {
+	endpoint string
+	headers  map[string]string
+	resource []Attribute
+	client   *http.Client
+
+	mu     sync.Mutex // one export at a time
+	warned bool
+}
+
+// Init enables tracing when an OTLP endpoint is configured in the environment:
+//
+//	OTEL_EXPORTER_OTLP_TRACES_ENDPOINT  traces URL, e.g. http://collector:4318/v1/traces
+//	OTEL_EXPORTER_OTLP_ENDPOINT         base URL, /v1/traces is appended
+//	OTEL_EXPORTER_OTLP_HEADERS          request headers, key=value,key=value
+//	OTEL_EXPORTER_OTLP_PROTOCOL         http/json (the only protocol supported)
+//	OTEL_SERVICE_NAME                   service.name of the spans (default: iwldr)
+//	OTEL_RESOURCE_ATTRIBUTES            more resource attributes, key=value,key=value
+//
+// The TRACES_ variants of the headers and protocol take precedence. Init
+// returns false when no endpoint is set.
+func Init() (bool, error) {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+			endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+		}
+	}
+	if endpoint == "" {
+		return false, nil
+	}
+
+	protocol := envWithFallback("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL")
+	if protocol != "" && protocol != "http/json" {
+		return false, fmt.Errorf("unsupported OTLP protocol %q (only http/json is supported)", protocol)
+	}
+	headers, err := parseKeyValues(envWithFallback("OTEL_EXPORTER_OTLP_TRACES_HEADERS", "OTEL_EXPORTER_OTLP_HEADERS"))
+	if err != nil {
+		return false, fmt.Errorf("invalid OTLP headers: %w", err)
+	}
+	attributes, err := parseKeyValues(os.Getenv("OTEL_RESOURCE_ATTRIBUTES"))
+	if err != nil {
+		return false, fmt.Errorf("invalid OTEL_RESOURCE_ATTRIBUTES: %w", err)
+	}
+
+	service := os.Getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = attributes["service.name"]
+	}
+	if service == "" {
+		service = "iwldr"
+	}
+	resource := []Attribute{String("service.name", service)}
+	for key, value := range attributes {
+		if key != "service.name" {
+			resource = append(resource, String(key, value))
+		}
+	}
+	if host, err := os.Hostname(); err == nil {
+		resource = append(resource, String("host.name", host))
+	}
+
+	active = &tracer{exporter: &exporter{
+		endpoint: endpoint,
+		headers:  headers,
+		resource: resource,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}}
+	return true, nil
+}
+
+// envWithFallback returns the first environment variable set
+func envWithFallback(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseKeyValues parses the key=value,key=value lists of the OpenTelemetry
+// environment variables; values are URL-decoded
+func parseKeyValues(s string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not key=value", pair)
+		}
+		decoded, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", pair, err)
+		}
+		values[strings.TrimSpace(key)] = decoded
+	}
+	return values, nil
+}
+
+// export posts a batch of spans. A failed export is reported once on standard
+// error; the spans are dropped.
+func (e *exporter) export(spans []*Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.post(spans); err != nil && !e.warned {
+		e.warned = true
+		fmt.Fprintf(os.Stderr, "WARNING: Failed to export traces to %s: %v\n", e.endpoint, err)
+	}
+}
+
+func (e *exporter) post(spans []*Span) error {
+	payload, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// request builds the ExportTraceServiceRequest of the spans in the JSON
+// encoding of OTLP: ids in hex, 64-bit integers as strings
+func (e *exporter) request(spans []*Span) map[string]interface{} {
+	encoded := make([]map[string]interface{}, 0, len(spans))
+	for _, span := range spans {
+		s := map[string]interface{}{
+			"traceId":           hex.EncodeToString(span.traceID[:]),
+			"spanId":            hex.EncodeToString(span.spanID[:]),
+			"name":              span.name,
+			"kind":              span.kind,
+			"startTimeUnixNano": strconv.FormatInt(span.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(span.end.UnixNano(), 10),
+			"attributes":        encodeAttributes(span.attributes),
+		}
+		if span.parentID != [8]byte{} {
+			s["parentSpanId"] = hex.EncodeToString(span.parentID[:])
+		}
+		if span.err != "" {
+			s["status"] = map[string]interface{}{"code": 2, "message": span.err}
+		}
+		encoded = append(encoded, s)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{"attributes": encodeAttributes(e.resource)},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": scopeName},
+				"spans": encoded,
+			}},
+		}},
+	}
+}
+
+// encodeAttributes encodes attributes as OTLP KeyValues
+func encodeAttributes(attributes []Attribute) []interface{} {
+	encoded := make([]interface{}, 0, len(attributes))
+	for _, attribute := range attributes {
+		var value map[string]interface{}
+		switch v := attribute.Value.(type) {
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		encoded = append(encoded, map[string]interface{}{"key": attribute.Key, "value": value})
+	}
+	return encoded
+}
@@ -0,0 +1,177 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing records spans of the import and report operations and
+// exports them to an OpenTelemetry collector with OTLP over HTTP. The CLI only
+// needs spans and their JSON export, so this package implements just that
+// instead of pulling in the OpenTelemetry SDK and its metrics, logs, context
+// propagation and exporter modules.
+//
+// Tracing is enabled by the standard OTEL_EXPORTER_OTLP_ENDPOINT (or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) environment variable; without it every
+// span is nil and costs nothing. The CLI runs one operation at a time, so a
+// span started while another is open becomes its child.
+package tracing
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// Attribute is a key and value describing a span
+type Attribute struct {
+	Key   string
+	Value interface{} // string or int64
+}
+
+// String returns a string attribute
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer attribute
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+// Span kinds of the spans exported (OTLP SpanKind)
+const (
+	KindInternal = 1
+	KindClient   = 3 // a query sent to the database
+)
+
+// Span is an operation being traced. All methods of a nil span are no-ops.
+type Span struct {
+	tracer     *tracer
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte
+	name       string
+	kind       int
+	start, end time.Time
+	attributes []Attribute
+	err        string
+}
+
+// tracer keeps the spans not yet ended and the ended spans not yet exported
+type tracer struct {
+	mu       sync.Mutex
+	exporter *exporter
+	open     []*Span
+	ended    []*Span
+}
+
+// active is the tracer configured by Init, nil while tracing is disabled
+var active *tracer
+
+// Enabled reports whether spans are recorded
+func Enabled() bool {
+	return active != nil
+}
+
+// Start starts a span, a child of the latest span started and not yet ended
+func Start(name string, attributes ...Attribute) *Span {
+	return StartKind(name, KindInternal, attributes...)
+}
+
+// StartKind starts a span of the given kind
+func StartKind(name string, kind int, attributes ...Attribute) *Span {
+	t := active
+	if t == nil {
+		return nil
+	}
+
+	span := &Span{tracer: t, name: name, kind: kind, start: time.Now(), attributes: attributes}
+	rand.Read(span.spanID[:])
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.open) > 0 {
+		parent := t.open[len(t.open)-1]
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		rand.Read(span.traceID[:])
+	}
+	t.open = append(t.open, span)
+	return span
+}
+
+// SetAttributes adds attributes to the span, e.g. counts known once the
+// operation is done
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.attributes = append(s.attributes, attributes...)
+}
+
+// End ends the span, marking it failed when err is not nil. Ended spans are
+// exported in batches.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	t := s.tracer
+
+	t.mu.Lock()
+	s.end = time.Now()
+	if err != nil {
+		s.err = err.Error()
+	}
+	for i, open := range t.open {
+		if open == s {
+			t.open = append(t.open[:i], t.open[i+1:]...)
+			break
+		}
+	}
+	t.ended = append(t.ended, s)
+	var batch []*Span
+	if len(t.ended) >= batchSize {
+		batch, t.ended = t.ended, nil
+	}
+	t.mu.Unlock()
+
+	if batch != nil {
+		t.exporter.export(batch)
+	}
+}
+
+// Shutdown ends the spans still open, exports the pending spans and disables
+// tracing. Export failures are reported as warnings on standard error and
+// never fail the command.
+func Shutdown() {
+	t := active
+	if t == nil {
+		return
+	}
+	active = nil
+
+	t.mu.Lock()
+	now := time.Now()
+	for _, span := range t.open {
+		span.end = now
+		t.ended = append(t.ended, span)
+	}
+	batch := t.ended
+	t.open, t.ended = nil, nil
+	t.mu.Unlock()
+
+	if len(batch) > 0 {
+		t.exporter.export(batch)
+	}
+}
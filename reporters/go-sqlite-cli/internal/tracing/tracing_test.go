@@ -0,0 +1,138 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/tracing"
+)
+
+func TestDisabledWithoutEndpoint(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	if enabled, err := tracing.Init(); enabled || err != nil {
+		t.Fatalf("Expected tracing disabled, got %v (%v)", enabled, err)
+	}
+
+	span := tracing.Start("import file")
+	if span != nil {
+		t.Fatal("Expected a nil span while tracing is disabled")
+	}
+	span.SetAttributes(tracing.Int("rows", 1))
+	span.End(nil)
+	tracing.Shutdown()
+}
+
+func TestExportSpans(t *testing.T) {
+	type request struct {
+		auth string
+		body struct {
+			ResourceSpans []struct {
+				Resource struct {
+					Attributes []map[string]interface{} `json:"attributes"`
+				} `json:"resource"`
+				ScopeSpans []struct {
+					Spans []map[string]interface{} `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+	}
+	var received []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		req := request{auth: r.Header.Get("Authorization")}
+		if err := json.NewDecoder(r.Body).Decode(&req.body); err != nil {
+			t.Errorf("Invalid OTLP JSON: %v", err)
+		}
+		received = append(received, req)
+	}))
+	defer server.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", server.URL+"/")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer%20secret")
+	t.Setenv("OTEL_SERVICE_NAME", "license-monitor")
+	if enabled, err := tracing.Init(); !enabled || err != nil {
+		t.Fatalf("Expected tracing enabled, got %v (%v)", enabled, err)
+	}
+
+	root := tracing.Start("iwldr import")
+	child := tracing.Start("upsert measurements", tracing.String("db.sql.table", "measurements"))
+	child.SetAttributes(tracing.Int("rows", 3))
+	child.End(errors.New("constraint failed"))
+	tracing.Start("commit").End(nil)
+	root.End(nil)
+	tracing.Shutdown()
+
+	if tracing.Enabled() {
+		t.Error("Expected tracing disabled after Shutdown")
+	}
+	if len(received) != 1 {
+		t.Fatalf("Expected 1 export request, got %d", len(received))
+	}
+	if received[0].auth != "Bearer secret" {
+		t.Errorf("Expected the configured header, got %q", received[0].auth)
+	}
+
+	resource := received[0].body.ResourceSpans[0]
+	if value := resource.Resource.Attributes[0]["value"].(map[string]interface{})["stringValue"]; value != "license-monitor" {
+		t.Errorf("Expected service.name license-monitor, got %v", value)
+	}
+	spans := map[string]map[string]interface{}{}
+	for _, span := range resource.ScopeSpans[0].Spans {
+		spans[span["name"].(string)] = span
+	}
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+
+	parent := spans["iwldr import"]
+	if _, ok := parent["parentSpanId"]; ok {
+		t.Error("Expected the command span to be a root span")
+	}
+	for _, name := range []string{"upsert measurements", "commit"} {
+		span := spans[name]
+		if span["parentSpanId"] != parent["spanId"] || span["traceId"] != parent["traceId"] {
+			t.Errorf("Expected %s to be a child of the command span", name)
+		}
+	}
+	if len(parent["traceId"].(string)) != 32 || len(parent["spanId"].(string)) != 16 {
+		t.Errorf("Expected hex trace and span ids, got %v and %v", parent["traceId"], parent["spanId"])
+	}
+
+	failed := spans["upsert measurements"]
+	status, _ := failed["status"].(map[string]interface{})
+	if status["code"] != float64(2) || status["message"] != "constraint failed" {
+		t.Errorf("Expected an error status, got %v", failed["status"])
+	}
+	if attributes := failed["attributes"].([]interface{}); len(attributes) != 2 {
+		t.Errorf("Expected 2 attributes, got %v", attributes)
+	}
+}
+
+func TestInitRejectsUnsupportedProtocol(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+	if enabled, err := tracing.Init(); enabled || err == nil {
+		t.Errorf("Expected the grpc protocol to be rejected, got %v (%v)", enabled, err)
+	}
+}
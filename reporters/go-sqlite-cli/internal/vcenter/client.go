@@ -0,0 +1,304 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vcenter reads the host and VM topology of a VMware vCenter Server
+// and stores it as the authoritative physical host data. The topology is read
+// with the vSphere Web Services (SOAP) API, the only vCenter API exposing the
+// physical core count of the ESXi hosts.
+package vcenter
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PasswordEnvVar is the environment variable holding the vCenter password
+const PasswordEnvVar = "IWLDR_VCENTER_PASSWORD"
+
+// soapAction is the vSphere API version requested; vCenter 6.7 and later
+// answer it
+const soapAction = "urn:vim25/6.7"
+
+// Host is an ESXi host of the vCenter inventory
+type Host struct {
+	Ref         string // managed object ID, e.g. host-10
+	Name        string
+	UUID        string // hardware UUID (BIOS), empty when not reported
+	CPUPackages int
+	CPUCores    int // physical cores of all packages
+}
+
+// VM is a virtual machine of the vCenter inventory
+type VM struct {
+	Ref           string // managed object ID, e.g. vm-42
+	Name          string
+	GuestHostName string // host name reported by VMware Tools, empty without them
+	HostRef       string // host the VM runs on
+	NumCPU        int
+	PowerState    string // poweredOn, poweredOff or suspended
+}
+
+// Topology is the host and VM inventory of a vCenter Server. Templates are
+// left out.
+type Topology struct {
+	Hosts []Host
+	VMs   []VM
+}
+
+// Client reads the topology of a vCenter Server
+type Client struct {
+	endpoint string
+	user     string
+	password string
+	http     *http.Client
+}
+
+// NewClient creates a client of the vCenter Server at server, a host name
+// (https is used) or a URL. With insecure the certificate of the server is
+// not verified.
+func NewClient(server, user, password string, insecure bool, timeout time.Duration) (*Client, error) {
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid vCenter server %q", server)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/sdk"
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return &Client{
+		endpoint: u.String(),
+		user:     user,
+		password: password,
+		http:     &http.Client{Jar: jar, Transport: transport, Timeout: timeout},
+	}, nil
+}
+
+// moRef is a managed object reference
+type moRef struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// xml returns the reference as the element name of a request
+func (r moRef) xml(name string) string {
+	return fmt.Sprintf(`<vim25:%s type="%s">%s</vim25:%s>`, name, escape(r.Type), escape(r.Value), name)
+}
+
+type serviceContent struct {
+	RootFolder        moRef `xml:"rootFolder"`
+	PropertyCollector moRef `xml:"propertyCollector"`
+	ViewManager       moRef `xml:"viewManager"`
+	SessionManager    moRef `xml:"sessionManager"`
+}
+
+// objectContent is an object returned by RetrievePropertiesEx with its
+// properties; only the text of the values is kept
+type objectContent struct {
+	Obj     moRef `xml:"obj"`
+	PropSet []struct {
+		Name string `xml:"name"`
+		Val  string `xml:"val"`
+	} `xml:"propSet"`
+}
+
+// properties returns the properties of an object by name
+func (o objectContent) properties() map[string]string {
+	props := make(map[string]string, len(o.PropSet))
+	for _, p := range o.PropSet {
+		props[p.Name] = strings.TrimSpace(p.Val)
+	}
+	return props
+}
+
+type retrieveResult struct {
+	Token   string          `xml:"token"`
+	Objects []objectContent `xml:"objects"`
+}
+
+// Topology logs in, reads the hosts and VMs of the inventory and logs out
+func (c *Client) Topology() (*Topology, error) {
+	var content struct {
+		Returnval serviceContent `xml:"returnval"`
+	}
+	if err := c.call(`<vim25:RetrieveServiceContent><vim25:_this type="ServiceInstance">ServiceInstance</vim25:_this></vim25:RetrieveServiceContent>`, &content); err != nil {
+		return nil, fmt.Errorf("failed to connect to vCenter: %w", err)
+	}
+	sc := content.Returnval
+
+	login := fmt.Sprintf(`<vim25:Login>%s<vim25:userName>%s</vim25:userName><vim25:password>%s</vim25:password></vim25:Login>`,
+		sc.SessionManager.xml("_this"), escape(c.user), escape(c.password))
+	if err := c.call(login, nil); err != nil {
+		return nil, fmt.Errorf("failed to log in to vCenter as %s: %w", c.user, err)
+	}
+	defer c.call(`<vim25:Logout>`+sc.SessionManager.xml("_this")+`</vim25:Logout>`, nil)
+
+	hosts, err := c.retrieve(sc, "HostSystem",
+		"name", "summary.hardware.uuid", "summary.hardware.numCpuPkgs", "summary.hardware.numCpuCores")
+	if err != nil {
+		return nil, err
+	}
+	vms, err := c.retrieve(sc, "VirtualMachine",
+		"name", "guest.hostName", "runtime.host", "runtime.powerState", "summary.config.numCpu", "config.template")
+	if err != nil {
+		return nil, err
+	}
+
+	topology := &Topology{}
+	for _, h := range hosts {
+		props := h.properties()
+		packages, _ := strconv.Atoi(props["summary.hardware.numCpuPkgs"])
+		cores, _ := strconv.Atoi(props["summary.hardware.numCpuCores"])
+		topology.Hosts = append(topology.Hosts, Host{
+			Ref:         h.Obj.Value,
+			Name:        props["name"],
+			UUID:        strings.ToLower(props["summary.hardware.uuid"]),
+			CPUPackages: packages,
+			CPUCores:    cores,
+		})
+	}
+	for _, v := range vms {
+		props := v.properties()
+		if props["config.template"] == "true" {
+			continue
+		}
+		numCPU, _ := strconv.Atoi(props["summary.config.numCpu"])
+		topology.VMs = append(topology.VMs, VM{
+			Ref:           v.Obj.Value,
+			Name:          props["name"],
+			GuestHostName: props["guest.hostName"],
+			HostRef:       props["runtime.host"],
+			NumCPU:        numCPU,
+			PowerState:    props["runtime.powerState"],
+		})
+	}
+	return topology, nil
+}
+
+// retrieve returns the properties of all the objects of a type in the
+// inventory, through a container view of the root folder
+func (c *Client) retrieve(sc serviceContent, objectType string, paths ...string) ([]objectContent, error) {
+	var view struct {
+		Returnval moRef `xml:"returnval"`
+	}
+	create := fmt.Sprintf(`<vim25:CreateContainerView>%s%s<vim25:type>%s</vim25:type><vim25:recursive>true</vim25:recursive></vim25:CreateContainerView>`,
+		sc.ViewManager.xml("_this"), sc.RootFolder.xml("container"), objectType)
+	if err := c.call(create, &view); err != nil {
+		return nil, fmt.Errorf("failed to create the %s view: %w", objectType, err)
+	}
+
+	var pathSet strings.Builder
+	for _, path := range paths {
+		pathSet.WriteString("<vim25:pathSet>" + path + "</vim25:pathSet>")
+	}
+	request := fmt.Sprintf(`<vim25:RetrievePropertiesEx>%s<vim25:specSet>`+
+		`<vim25:propSet><vim25:type>%s</vim25:type>%s</vim25:propSet>`+
+		`<vim25:objectSet>%s<vim25:skip>true</vim25:skip>`+
+		`<vim25:selectSet xsi:type="vim25:TraversalSpec"><vim25:type>ContainerView</vim25:type><vim25:path>view</vim25:path><vim25:skip>false</vim25:skip></vim25:selectSet>`+
+		`</vim25:objectSet></vim25:specSet><vim25:options></vim25:options></vim25:RetrievePropertiesEx>`,
+		sc.PropertyCollector.xml("_this"), objectType, pathSet.String(), view.Returnval.xml("obj"))
+
+	var objects []objectContent
+	for {
+		var result struct {
+			Returnval retrieveResult `xml:"returnval"`
+		}
+		if err := c.call(request, &result); err != nil {
+			return nil, fmt.Errorf("failed to read the %s objects: %w", objectType, err)
+		}
+		objects = append(objects, result.Returnval.Objects...)
+		if result.Returnval.Token == "" {
+			break
+		}
+		request = fmt.Sprintf(`<vim25:ContinueRetrievePropertiesEx>%s<vim25:token>%s</vim25:token></vim25:ContinueRetrievePropertiesEx>`,
+			sc.PropertyCollector.xml("_this"), escape(result.Returnval.Token))
+	}
+
+	c.call(`<vim25:DestroyView>`+view.Returnval.xml("_this")+`</vim25:DestroyView>`, nil)
+	return objects, nil
+}
+
+// call sends a request body and decodes the response body into result,
+// unless it is nil
+func (c *Client) call(body string, result interface{}) error {
+	request := xml.Header + `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" ` +
+		`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:vim25="urn:vim25">` +
+		`<soapenv:Body>` + body + `</soapenv:Body></soapenv:Envelope>`
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, strings.NewReader(request))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	req.Header.Set("SOAPAction", soapAction)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var envelope struct {
+		Body struct {
+			Fault *struct {
+				String string `xml:"faultstring"`
+			} `xml:"Fault"`
+			Content []byte `xml:",innerxml"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("unexpected response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if envelope.Body.Fault != nil {
+		return fmt.Errorf("%s", envelope.Body.Fault.String)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if result == nil {
+		return nil
+	}
+	return xml.Unmarshal(bytes.TrimSpace(envelope.Body.Content), result)
+}
+
+// escape escapes the text of an XML element
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
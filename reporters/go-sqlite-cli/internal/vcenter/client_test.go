@@ -0,0 +1,149 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vcenter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeVCenter answers the requests of the client like a vCenter Server with
+// one host, one VM and one template; the VMs are returned in two pages
+func fakeVCenter(t *testing.T) *httptest.Server {
+	t.Helper()
+	respond := func(w http.ResponseWriter, body string) {
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" `+
+			`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns="urn:vim25"><soapenv:Body>`+body+`</soapenv:Body></soapenv:Envelope>`)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		request := string(data)
+		switch {
+		case strings.Contains(request, "<vim25:RetrieveServiceContent>"):
+			respond(w, `<RetrieveServiceContentResponse><returnval>`+
+				`<rootFolder type="Folder">group-d1</rootFolder><propertyCollector type="PropertyCollector">propertyCollector</propertyCollector>`+
+				`<viewManager type="ViewManager">ViewManager</viewManager><sessionManager type="SessionManager">SessionManager</sessionManager>`+
+				`</returnval></RetrieveServiceContentResponse>`)
+		case strings.Contains(request, "<vim25:Login>"):
+			if !strings.Contains(request, "<vim25:password>s3cr&amp;t</vim25:password>") {
+				w.WriteHeader(http.StatusInternalServerError)
+				respond(w, `<soapenv:Fault><faultcode>ServerFaultCode</faultcode><faultstring>Cannot complete login due to an incorrect user name or password.</faultstring></soapenv:Fault>`)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "vmware_soap_session", Value: "session"})
+			respond(w, `<LoginResponse><returnval><key>session</key></returnval></LoginResponse>`)
+		case strings.Contains(request, "<vim25:CreateContainerView>"):
+			if strings.Contains(request, "<vim25:type>HostSystem</vim25:type>") {
+				respond(w, `<CreateContainerViewResponse><returnval type="ContainerView">session[1]host</returnval></CreateContainerViewResponse>`)
+			} else {
+				respond(w, `<CreateContainerViewResponse><returnval type="ContainerView">session[1]vm</returnval></CreateContainerViewResponse>`)
+			}
+		case strings.Contains(request, "<vim25:RetrievePropertiesEx>") && strings.Contains(request, "session[1]host"):
+			if _, err := r.Cookie("vmware_soap_session"); err != nil {
+				t.Error("Expected the session cookie")
+			}
+			respond(w, `<RetrievePropertiesExResponse><returnval><objects><obj type="HostSystem">host-10</obj>`+
+				`<propSet><name>name</name><val xsi:type="xsd:string">esx01.example.com</val></propSet>`+
+				`<propSet><name>summary.hardware.numCpuCores</name><val xsi:type="xsd:short">32</val></propSet>`+
+				`<propSet><name>summary.hardware.numCpuPkgs</name><val xsi:type="xsd:short">2</val></propSet>`+
+				`<propSet><name>summary.hardware.uuid</name><val xsi:type="xsd:string">4C4C4544-0042-3510-8052-B4C04F4E4B32</val></propSet>`+
+				`</objects></returnval></RetrievePropertiesExResponse>`)
+		case strings.Contains(request, "<vim25:RetrievePropertiesEx>"):
+			respond(w, `<RetrievePropertiesExResponse><returnval><token>1</token><objects><obj type="VirtualMachine">vm-42</obj>`+
+				`<propSet><name>config.template</name><val xsi:type="xsd:boolean">false</val></propSet>`+
+				`<propSet><name>guest.hostName</name><val xsi:type="xsd:string">app01.example.com</val></propSet>`+
+				`<propSet><name>name</name><val xsi:type="xsd:string">APP01</val></propSet>`+
+				`<propSet><name>runtime.host</name><val type="HostSystem" xsi:type="ManagedObjectReference">host-10</val></propSet>`+
+				`<propSet><name>runtime.powerState</name><val xsi:type="VirtualMachinePowerState">poweredOn</val></propSet>`+
+				`<propSet><name>summary.config.numCpu</name><val xsi:type="xsd:int">4</val></propSet>`+
+				`</objects></returnval></RetrievePropertiesExResponse>`)
+		case strings.Contains(request, "<vim25:ContinueRetrievePropertiesEx>"):
+			respond(w, `<ContinueRetrievePropertiesExResponse><returnval><objects><obj type="VirtualMachine">vm-43</obj>`+
+				`<propSet><name>config.template</name><val xsi:type="xsd:boolean">true</val></propSet>`+
+				`<propSet><name>name</name><val xsi:type="xsd:string">rhel9-template</val></propSet>`+
+				`</objects></returnval></ContinueRetrievePropertiesExResponse>`)
+		default:
+			respond(w, `<Response></Response>`)
+		}
+	}))
+}
+
+func TestTopology(t *testing.T) {
+	server := fakeVCenter(t)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "iwldr@vsphere.local", "s3cr&t", false, 10*time.Second)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	topology, err := client.Topology()
+	if err != nil {
+		t.Fatalf("Topology failed: %v", err)
+	}
+
+	if len(topology.Hosts) != 1 {
+		t.Fatalf("Expected 1 host, got %+v", topology.Hosts)
+	}
+	host := topology.Hosts[0]
+	if host.Ref != "host-10" || host.Name != "esx01.example.com" || host.CPUCores != 32 || host.CPUPackages != 2 ||
+		host.UUID != "4c4c4544-0042-3510-8052-b4c04f4e4b32" {
+		t.Errorf("Unexpected host: %+v", host)
+	}
+
+	// The template of the second page is left out
+	if len(topology.VMs) != 1 {
+		t.Fatalf("Expected 1 VM, got %+v", topology.VMs)
+	}
+	vm := topology.VMs[0]
+	if vm.Ref != "vm-42" || vm.Name != "APP01" || vm.GuestHostName != "app01.example.com" || vm.HostRef != "host-10" ||
+		vm.NumCPU != 4 || vm.PowerState != "poweredOn" {
+		t.Errorf("Unexpected VM: %+v", vm)
+	}
+
+	client, _ = NewClient(server.URL, "iwldr@vsphere.local", "wrong", false, 10*time.Second)
+	if _, err := client.Topology(); err == nil || !strings.Contains(err.Error(), "incorrect user name or password") {
+		t.Errorf("Expected the login fault, got %v", err)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		server   string
+		endpoint string
+	}{
+		{"vcenter.example.com", "https://vcenter.example.com/sdk"},
+		{"https://vcenter.example.com:8443", "https://vcenter.example.com:8443/sdk"},
+		{"https://vcenter.example.com/custom/sdk", "https://vcenter.example.com/custom/sdk"},
+	}
+	for _, tt := range tests {
+		client, err := NewClient(tt.server, "user", "password", false, time.Second)
+		if err != nil {
+			t.Fatalf("NewClient(%q) failed: %v", tt.server, err)
+		}
+		if client.endpoint != tt.endpoint {
+			t.Errorf("NewClient(%q) endpoint = %q, want %q", tt.server, client.endpoint, tt.endpoint)
+		}
+	}
+
+	if _, err := NewClient("https://", "user", "password", false, time.Second); err == nil {
+		t.Error("Expected an error for a server without host")
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vcenter
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HostIDMethod is the host_id_method of physical hosts and measurements
+// whose host was taken from vCenter
+const HostIDMethod = "vcenter"
+
+// Correction is a node whose low confidence physical host ID was replaced
+// with the host vCenter runs it on
+type Correction struct {
+	MainFQDN            string
+	OldHostID           string
+	NewHostID           string
+	MeasurementsUpdated int
+}
+
+// Conflict is a node whose medium or high confidence physical host ID differs
+// from the host vCenter runs it on; it is not corrected
+type Conflict struct {
+	MainFQDN      string
+	HostID        string
+	Confidence    string
+	VCenterHostID string
+}
+
+// SyncResult summarizes a vCenter synchronization
+type SyncResult struct {
+	HostsAdded   int
+	HostsUpdated int
+	VMs          int
+	VMsMatched   int // VMs matched to a landscape node
+	Corrections  []Correction
+	Conflicts    []Conflict
+}
+
+// Syncer stores the topology of a vCenter Server in the database
+type Syncer struct {
+	db *sql.DB
+}
+
+// NewSyncer creates a new vCenter syncer
+func NewSyncer(db *sql.DB) *Syncer {
+	return &Syncer{db: db}
+}
+
+// Sync stores the topology read from the vCenter Server source in one
+// transaction:
+//   - each ESXi host becomes a physical host with method vcenter and high
+//     confidence, its physical core count replacing the one reported by the
+//     inspectors. A host already known under its hardware UUID or its name
+//     (or an alias of them) keeps its ID, else the UUID is its ID.
+//   - the hosts and the VMs are recorded in hypervisor_hosts and
+//     hypervisor_vms, replacing those of the previous sync of source.
+//   - a VM is matched to a landscape node by its guest host name, else its VM
+//     name: the main FQDN, a node alias, or a unique short host name. When the
+//     latest measurement of the node has a low confidence host ID other than
+//     the vCenter host, the low confidence measurements of the node with that
+//     ID are moved to the vCenter host and the correction recorded in
+//     host_id_corrections.
+//
+// With dryRun nothing is changed and the result shows what would be.
+func (s *Syncer) Sync(source string, topology *Topology, dryRun bool) (*SyncResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	result := &SyncResult{}
+
+	hostIDs := make(map[string]string) // physical host ID by host ref
+	if _, err := tx.Exec("DELETE FROM hypervisor_hosts WHERE source = ?", source); err != nil {
+		return nil, fmt.Errorf("failed to clear hypervisor hosts: %w", err)
+	}
+	for _, host := range topology.Hosts {
+		hostID, exists, err := physicalHostID(tx, host)
+		if err != nil {
+			return nil, err
+		}
+		hostIDs[host.Ref] = hostID
+
+		if exists {
+			result.HostsUpdated++
+			_, err = tx.Exec(`
+				UPDATE physical_hosts
+				SET host_id_method = ?, host_id_confidence = 'high', max_physical_cpus = ?,
+				    last_seen = ?, updated_at = CURRENT_TIMESTAMP
+				WHERE physical_host_id = ?
+			`, HostIDMethod, host.CPUCores, now, hostID)
+		} else {
+			result.HostsAdded++
+			_, err = tx.Exec(`
+				INSERT INTO physical_hosts
+				(physical_host_id, host_id_method, host_id_confidence, first_seen, last_seen, max_physical_cpus, notes)
+				VALUES (?, ?, 'high', ?, ?, ?, ?)
+			`, hostID, HostIDMethod, now, now, host.CPUCores, "ESXi host "+host.Name+" of vCenter "+source)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to store physical host %s: %w", hostID, err)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO hypervisor_hosts
+			(physical_host_id, source, host_ref, host_name, host_uuid, cpu_packages, physical_cores, synced_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(physical_host_id) DO UPDATE SET
+			    source = excluded.source, host_ref = excluded.host_ref, host_name = excluded.host_name,
+			    host_uuid = excluded.host_uuid, cpu_packages = excluded.cpu_packages,
+			    physical_cores = excluded.physical_cores, synced_at = excluded.synced_at
+		`, hostID, source, host.Ref, host.Name, host.UUID, host.CPUPackages, host.CPUCores, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store hypervisor host %s: %w", host.Name, err)
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM hypervisor_vms WHERE source = ?", source); err != nil {
+		return nil, fmt.Errorf("failed to clear hypervisor VMs: %w", err)
+	}
+	for _, vm := range topology.VMs {
+		hostID, ok := hostIDs[vm.HostRef]
+		if !ok {
+			continue // VM without a host, e.g. orphaned or inaccessible
+		}
+		result.VMs++
+
+		mainFQDN, err := matchNode(tx, vm)
+		if err != nil {
+			return nil, err
+		}
+		var node interface{}
+		if mainFQDN != "" {
+			node = mainFQDN
+			result.VMsMatched++
+		}
+		_, err = tx.Exec(`
+			INSERT INTO hypervisor_vms
+			(source, vm_ref, vm_name, guest_hostname, main_fqdn, physical_host_id, vcpus, power_state, synced_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, source, vm.Ref, vm.Name, vm.GuestHostName, node, hostID, vm.NumCPU, vm.PowerState, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store hypervisor VM %s: %w", vm.Name, err)
+		}
+
+		if mainFQDN != "" {
+			if err := correctHostID(tx, source, mainFQDN, hostID, result); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// physicalHostID returns the physical host ID of an ESXi host and whether it
+// is already known
+func physicalHostID(tx *sql.Tx, host Host) (string, bool, error) {
+	shortName, _, _ := strings.Cut(host.Name, ".")
+	for _, candidate := range []string{host.UUID, host.Name, shortName} {
+		if candidate == "" {
+			continue
+		}
+		var hostID string
+		err := tx.QueryRow(`
+			SELECT physical_host_id FROM physical_hosts WHERE LOWER(physical_host_id) = LOWER(?)
+			UNION ALL
+			SELECT physical_host_id FROM physical_host_aliases WHERE LOWER(alias_id) = LOWER(?)
+			LIMIT 1
+		`, candidate, candidate).Scan(&hostID)
+		if err == nil {
+			var count int
+			if err := tx.QueryRow("SELECT COUNT(*) FROM physical_hosts WHERE physical_host_id = ?", hostID).Scan(&count); err != nil {
+				return "", false, fmt.Errorf("failed to look up physical host %s: %w", hostID, err)
+			}
+			return hostID, count > 0, nil
+		}
+		if err != sql.ErrNoRows {
+			return "", false, fmt.Errorf("failed to look up physical host %s: %w", candidate, err)
+		}
+	}
+	if host.UUID != "" {
+		return host.UUID, false, nil
+	}
+	return host.Name, false, nil
+}
+
+// matchNode returns the main FQDN of the landscape node of a VM, empty when
+// none matches
+func matchNode(tx *sql.Tx, vm VM) (string, error) {
+	for _, name := range []string{vm.GuestHostName, vm.Name} {
+		if name == "" {
+			continue
+		}
+		var mainFQDN string
+		err := tx.QueryRow(`
+			SELECT main_fqdn FROM landscape_nodes WHERE LOWER(main_fqdn) = LOWER(?)
+			UNION ALL
+			SELECT main_fqdn FROM node_aliases WHERE LOWER(alias_fqdn) = LOWER(?)
+			LIMIT 1
+		`, name, name).Scan(&mainFQDN)
+		if err == nil {
+			return mainFQDN, nil
+		}
+		if err != sql.ErrNoRows {
+			return "", fmt.Errorf("failed to match VM %s: %w", vm.Name, err)
+		}
+
+		shortName, _, _ := strings.Cut(name, ".")
+		rows, err := tx.Query("SELECT main_fqdn FROM landscape_nodes WHERE LOWER(hostname) = LOWER(?)", shortName)
+		if err != nil {
+			return "", fmt.Errorf("failed to match VM %s: %w", vm.Name, err)
+		}
+		var matches []string
+		for rows.Next() {
+			if err := rows.Scan(&mainFQDN); err != nil {
+				rows.Close()
+				return "", err
+			}
+			matches = append(matches, mainFQDN)
+		}
+		rows.Close()
+		if len(matches) == 1 {
+			return matches[0], nil
+		}
+	}
+	return "", nil
+}
+
+// correctHostID moves the low confidence measurements of a node to the
+// vCenter host, or records a conflict with a higher confidence host ID
+func correctHostID(tx *sql.Tx, source, mainFQDN, hostID string, result *SyncResult) error {
+	var current, confidence string
+	err := tx.QueryRow(`
+		SELECT COALESCE(physical_host_id, ''), COALESCE(host_id_confidence, '')
+		FROM measurements WHERE main_fqdn = ?
+		ORDER BY detection_timestamp DESC LIMIT 1
+	`, mainFQDN).Scan(&current, &confidence)
+	if err == sql.ErrNoRows || current == hostID {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the latest measurement of %s: %w", mainFQDN, err)
+	}
+
+	if confidence != "low" && confidence != "" {
+		result.Conflicts = append(result.Conflicts, Conflict{
+			MainFQDN: mainFQDN, HostID: current, Confidence: confidence, VCenterHostID: hostID,
+		})
+		return nil
+	}
+
+	res, err := tx.Exec(`
+		UPDATE measurements
+		SET physical_host_id = ?, host_id_method = ?, host_id_confidence = 'high'
+		WHERE main_fqdn = ? AND physical_host_id = ? AND COALESCE(host_id_confidence, '') IN ('low', '')
+	`, hostID, HostIDMethod, mainFQDN, current)
+	if err != nil {
+		return fmt.Errorf("failed to correct the host ID of %s: %w", mainFQDN, err)
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO host_id_corrections (main_fqdn, old_host_id, new_host_id, source, measurements_updated)
+		VALUES (?, ?, ?, ?, ?)
+	`, mainFQDN, current, hostID, source, updated)
+	if err != nil {
+		return fmt.Errorf("failed to record the host ID correction of %s: %w", mainFQDN, err)
+	}
+
+	result.Corrections = append(result.Corrections, Correction{
+		MainFQDN: mainFQDN, OldHostID: current, NewHostID: hostID, MeasurementsUpdated: int(updated),
+	})
+	return nil
+}
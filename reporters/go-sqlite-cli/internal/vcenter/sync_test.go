@@ -0,0 +1,135 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vcenter
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+)
+
+func TestSync(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	for _, stmt := range []string{
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('app01.example.com', 'app01', 'PROD')`,
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('app02.example.com', 'app02', 'PROD')`,
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('db01.example.com', 'db01', 'PROD')`,
+		// esx02 is known to the inspectors by its name
+		`INSERT INTO physical_hosts (physical_host_id, host_id_method, host_id_confidence, first_seen, last_seen, max_physical_cpus)
+			VALUES ('esx02.example.com', 'hostname', 'medium', '2025-10-01 08:00:00', '2025-11-01 08:00:00', 16)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed: %v", err)
+		}
+	}
+	measure := func(fqdn, at, hostID, confidence string) {
+		t.Helper()
+		if _, err := db.Exec(`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count,
+			is_virtualized, virt_type, host_physical_cpus, processor_eligible, os_eligible, virt_eligible, considered_cpus,
+			physical_host_id, host_id_method, host_id_confidence)
+			VALUES (?, ?, 'Linux', '8', 4, 'yes', 'VMware', '16', 'true', 'true', 'true', 4, ?, 'hostname', ?)`,
+			fqdn, at, hostID, confidence); err != nil {
+			t.Fatalf("Failed to insert measurement: %v", err)
+		}
+	}
+	measure("app01.example.com", "2025-10-01 08:00:00", "app01-guess", "low")
+	measure("app01.example.com", "2025-11-01 08:00:00", "app01-guess", "low")
+	measure("app02.example.com", "2025-11-01 08:00:00", "esx02.example.com", "medium")
+	measure("db01.example.com", "2025-11-01 08:00:00", "esx02.example.com", "medium")
+
+	topology := &Topology{
+		Hosts: []Host{
+			{Ref: "host-10", Name: "esx01.example.com", UUID: "4c4c4544-0042-3510-8052-b4c04f4e4b32", CPUPackages: 2, CPUCores: 32},
+			{Ref: "host-11", Name: "esx02.example.com", UUID: "4c4c4544-0042-3510-8052-b4c04f4e4b33", CPUPackages: 2, CPUCores: 24},
+		},
+		VMs: []VM{
+			{Ref: "vm-1", Name: "APP01", GuestHostName: "app01.example.com", HostRef: "host-10", NumCPU: 4},
+			{Ref: "vm-2", Name: "app02", HostRef: "host-10", NumCPU: 4},            // matched by short name, conflict
+			{Ref: "vm-3", Name: "db01.example.com", HostRef: "host-11", NumCPU: 8}, // already right
+			{Ref: "vm-4", Name: "jump01", HostRef: "host-11", NumCPU: 2},           // not a landscape node
+		},
+	}
+	syncer := NewSyncer(db)
+	esx01 := "4c4c4544-0042-3510-8052-b4c04f4e4b32"
+
+	dryRun, err := syncer.Sync("vcenter.example.com", topology, true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if len(dryRun.Corrections) != 1 {
+		t.Errorf("Expected 1 correction in the dry run, got %+v", dryRun.Corrections)
+	}
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM hypervisor_hosts").Scan(&count)
+	if count != 0 {
+		t.Fatalf("Dry run changed the database")
+	}
+
+	result, err := syncer.Sync("vcenter.example.com", topology, false)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.HostsAdded != 1 || result.HostsUpdated != 1 || result.VMs != 4 || result.VMsMatched != 3 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if len(result.Corrections) != 1 || result.Corrections[0].MainFQDN != "app01.example.com" ||
+		result.Corrections[0].OldHostID != "app01-guess" || result.Corrections[0].NewHostID != esx01 ||
+		result.Corrections[0].MeasurementsUpdated != 2 {
+		t.Errorf("Unexpected corrections: %+v", result.Corrections)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0].MainFQDN != "app02.example.com" || result.Conflicts[0].VCenterHostID != esx01 {
+		t.Errorf("Unexpected conflicts: %+v", result.Conflicts)
+	}
+
+	var cores int
+	var method, confidence string
+	db.QueryRow("SELECT max_physical_cpus, host_id_method, host_id_confidence FROM physical_hosts WHERE physical_host_id = 'esx02.example.com'").
+		Scan(&cores, &method, &confidence)
+	if cores != 24 || method != HostIDMethod || confidence != "high" {
+		t.Errorf("esx02 not updated: %d cores, %s, %s", cores, method, confidence)
+	}
+	db.QueryRow("SELECT COUNT(*) FROM measurements WHERE physical_host_id = ? AND host_id_confidence = 'high'", esx01).Scan(&count)
+	if count != 2 {
+		t.Errorf("Expected the 2 measurements of app01 on esx01, got %d", count)
+	}
+	var node *string
+	db.QueryRow("SELECT main_fqdn FROM hypervisor_vms WHERE vm_ref = 'vm-4'").Scan(&node)
+	if node != nil {
+		t.Errorf("Expected no node for jump01, got %s", *node)
+	}
+
+	// A second sync finds nothing left to correct and replaces the VMs
+	topology.VMs = topology.VMs[:1]
+	result, err = syncer.Sync("vcenter.example.com", topology, false)
+	if err != nil {
+		t.Fatalf("Second sync failed: %v", err)
+	}
+	if len(result.Corrections) != 0 || result.HostsUpdated != 2 {
+		t.Errorf("Unexpected second result: %+v", result)
+	}
+	db.QueryRow("SELECT COUNT(*) FROM hypervisor_vms").Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected the VMs to be replaced, got %d", count)
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package views
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Prefix is the name prefix of the custom views, which keeps them apart from
+// the built-in v_ views that schema upgrades replace
+const Prefix = "vx_"
+
+// Outcomes of the installation of a view
+const (
+	Created   = "created"
+	Updated   = "updated"
+	Unchanged = "unchanged"
+)
+
+// createViewStatement matches the start of a CREATE VIEW statement and captures
+// the view name
+var createViewStatement = regexp.MustCompile("(?is)^CREATE\\s+VIEW\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?(?:main\\.)?[\"`\\[]?([A-Za-z_][A-Za-z0-9_]*)[\"`\\]]?\\s*(?:\\([^)]*\\)\\s*)?AS\\s")
+
+// CustomView is a user-defined reporting view
+type CustomView struct {
+	Name       string
+	Definition string // the CREATE VIEW statement
+	SourceFile string
+
+	// InstalledAt and UpdatedAt are empty for views created without
+	// 'views install'
+	InstalledAt string
+	UpdatedAt   string
+
+	// Columns are the columns of the view, and Err why it cannot be queried
+	Columns []string
+	Err     error
+}
+
+// InstallResult is the outcome of the installation of a view
+type InstallResult struct {
+	Name    string
+	Outcome string // Created, Updated or Unchanged
+	Columns []string
+}
+
+// ParseFile returns the views of the CREATE VIEW statements of an SQL file.
+// Statements of any other kind, and views not named with the vx_ prefix, are
+// rejected, so that installing the file can only add views.
+func ParseFile(content string) ([]CustomView, error) {
+	var views []CustomView
+	seen := map[string]bool{}
+	for i, statement := range splitStatements(content) {
+		match := createViewStatement.FindStringSubmatch(statement)
+		if match == nil {
+			return nil, fmt.Errorf("statement %d is not a CREATE VIEW statement: %s", i+1, firstLine(statement))
+		}
+		name := match[1]
+		if !strings.HasPrefix(strings.ToLower(name), Prefix) {
+			return nil, fmt.Errorf("statement %d: view %s must be named with the %s prefix", i+1, name, Prefix)
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("statement %d: view %s is defined twice", i+1, name)
+		}
+		seen[strings.ToLower(name)] = true
+		views = append(views, CustomView{Name: name, Definition: statement})
+	}
+	if len(views) == 0 {
+		return nil, fmt.Errorf("no CREATE VIEW statement found")
+	}
+	return views, nil
+}
+
+// Install creates or replaces the views in one transaction and records them
+// in custom_views. Each view is queried once created, and so are the other
+// custom views, which may read it: when one fails, nothing is installed. With
+// dryRun the views are checked the same way and the transaction rolled back.
+func Install(ctx context.Context, db *sql.DB, views []CustomView, sourceFile string, dryRun bool) ([]InstallResult, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var results []InstallResult
+	for _, view := range views {
+		result := InstallResult{Name: view.Name, Outcome: Created}
+		// sqlite_master keeps the statement normalized, custom_views as installed
+		var installed sql.NullString
+		err := tx.QueryRowContext(ctx, `
+			SELECT c.definition FROM sqlite_master m
+			LEFT JOIN custom_views c ON c.name = m.name COLLATE NOCASE
+			WHERE m.type = 'view' AND m.name = ? COLLATE NOCASE
+		`, view.Name).Scan(&installed)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return nil, fmt.Errorf("failed to read view %s: %w", view.Name, err)
+		case installed.Valid && installed.String == view.Definition:
+			result.Outcome = Unchanged
+		default:
+			result.Outcome = Updated
+		}
+
+		if result.Outcome != Unchanged {
+			if _, err := tx.ExecContext(ctx, `DROP VIEW IF EXISTS "`+view.Name+`"`); err != nil {
+				return nil, fmt.Errorf("failed to drop view %s: %w", view.Name, err)
+			}
+			if _, err := tx.ExecContext(ctx, view.Definition); err != nil {
+				return nil, fmt.Errorf("failed to create view %s: %w", view.Name, err)
+			}
+		}
+		if result.Columns, err = viewColumns(ctx, tx, view.Name); err != nil {
+			return nil, fmt.Errorf("view %s cannot be queried: %w", view.Name, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO custom_views (name, definition, source_file) VALUES (?, ?, ?)
+			ON CONFLICT (name) DO UPDATE SET
+				definition = excluded.definition,
+				source_file = excluded.source_file,
+				updated_at = CASE WHEN definition = excluded.definition THEN updated_at ELSE CURRENT_TIMESTAMP END
+		`, view.Name, view.Definition, sourceFile); err != nil {
+			return nil, fmt.Errorf("failed to record view %s: %w", view.Name, err)
+		}
+		results = append(results, result)
+	}
+
+	// Replacing a view breaks the custom views reading columns it lost
+	names, err := customViewNames(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if _, err := viewColumns(ctx, tx, name); err != nil {
+			return nil, fmt.Errorf("view %s would no longer work: %w", name, err)
+		}
+	}
+
+	if dryRun {
+		return results, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit views: %w", err)
+	}
+	return results, nil
+}
+
+// List returns the custom views of the database, those created by hand with
+// the vx_ prefix included, each queried to report whether it still works
+func List(ctx context.Context, db *sql.DB) ([]CustomView, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT m.name, m.sql, COALESCE(c.source_file, ''), COALESCE(c.installed_at, ''), COALESCE(c.updated_at, '')
+		FROM sqlite_master m
+		LEFT JOIN custom_views c ON c.name = m.name COLLATE NOCASE
+		WHERE m.type = 'view' AND m.name LIKE 'vx\_%' ESCAPE '\'
+		ORDER BY m.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list custom views: %w", err)
+	}
+	defer rows.Close()
+
+	var views []CustomView
+	for rows.Next() {
+		var view CustomView
+		if err := rows.Scan(&view.Name, &view.Definition, &view.SourceFile, &view.InstalledAt, &view.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan custom view: %w", err)
+		}
+		views = append(views, view)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list custom views: %w", err)
+	}
+	rows.Close()
+
+	for i := range views {
+		views[i].Columns, views[i].Err = viewColumns(ctx, db, views[i].Name)
+	}
+	return views, nil
+}
+
+// Drop removes custom views and their record. Names without the vx_ prefix are
+// refused, so that the built-in views cannot be dropped.
+func Drop(ctx context.Context, db *sql.DB, names []string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, name := range names {
+		if !strings.HasPrefix(strings.ToLower(name), Prefix) {
+			return fmt.Errorf("%s is not a custom view (no %s prefix)", name, Prefix)
+		}
+		var count int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'view' AND name = ? COLLATE NOCASE`, name).Scan(&count); err != nil {
+			return fmt.Errorf("failed to read view %s: %w", name, err)
+		}
+		if count == 0 {
+			return fmt.Errorf("custom view %s does not exist", name)
+		}
+		if _, err := tx.ExecContext(ctx, `DROP VIEW "`+name+`"`); err != nil {
+			return fmt.Errorf("failed to drop view %s: %w", name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM custom_views WHERE name = ? COLLATE NOCASE`, name); err != nil {
+			return fmt.Errorf("failed to remove view %s: %w", name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// querier is implemented by *sql.DB and *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// viewColumns queries a view without reading its rows and returns its columns
+func viewColumns(ctx context.Context, q querier, name string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT * FROM "`+name+`" LIMIT 0`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return rows.Columns()
+}
+
+// customViewNames returns the names of the custom views of the database
+func customViewNames(ctx context.Context, q querier) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'view' AND name LIKE 'vx\_%' ESCAPE '\' ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list custom views: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan custom view: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// splitStatements splits SQL text into its statements, without the comments
+// before them and the semicolons ending them. Semicolons inside quotes and
+// comments do not end a statement.
+func splitStatements(content string) []string {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '-' && i+1 < len(content) && content[i+1] == '-':
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				end = len(content) - i
+			}
+			if strings.TrimSpace(current.String()) != "" {
+				current.WriteString(content[i : i+end])
+			}
+			i += end - 1
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				end = len(content) - i - 2
+			} else {
+				end += 2
+			}
+			if strings.TrimSpace(current.String()) != "" {
+				current.WriteString(content[i : i+2+end])
+			}
+			i += 2 + end - 1
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			end := strings.IndexByte(content[i+1:], closing)
+			if end < 0 {
+				end = len(content) - i - 1
+			} else {
+				end++
+			}
+			current.WriteString(content[i : i+1+end])
+			i += end
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
+
+// firstLine returns the first line of a statement, for error messages
+func firstLine(statement string) string {
+	line, _, _ := strings.Cut(statement, "\n")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package views_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/views"
+)
+
+const customViewsFile = `-- Hosts and their largest core count; the ';' in comments is ignored
+CREATE VIEW vx_host_cores AS
+SELECT main_fqdn, MAX(considered_cpus) AS cores
+FROM measurements
+WHERE os_name != 'a;b'
+GROUP BY main_fqdn;
+
+/* Hosts with more than 4 cores */
+CREATE VIEW vx_large_hosts AS
+SELECT main_fqdn, cores FROM vx_host_cores WHERE cores > 4;
+`
+
+func TestCustomViews(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	for _, content := range []string{
+		"CREATE VIEW v_hosts AS SELECT 1",
+		"DROP TABLE measurements",
+		"CREATE VIEW vx_a AS SELECT 1; CREATE VIEW VX_A AS SELECT 2",
+		"-- nothing",
+	} {
+		if _, err := views.ParseFile(content); err == nil {
+			t.Errorf("Expected %q to be rejected", content)
+		}
+	}
+
+	parsed, err := views.ParseFile(customViewsFile)
+	if err != nil || len(parsed) != 2 {
+		t.Fatalf("Expected 2 views, got %d (%v)", len(parsed), err)
+	}
+
+	if _, err := views.Install(t.Context(), db, parsed, "custom.sql", true); err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if list, _ := views.List(t.Context(), db); len(list) != 0 {
+		t.Fatalf("Expected the dry run to install nothing, got %d views", len(list))
+	}
+
+	results, err := views.Install(t.Context(), db, parsed, "custom.sql", false)
+	if err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if results[0].Outcome != views.Created || len(results[0].Columns) != 2 {
+		t.Errorf("Expected vx_host_cores created with 2 columns, got %+v", results[0])
+	}
+	results, err = views.Install(t.Context(), db, parsed, "custom.sql", false)
+	if err != nil || results[0].Outcome != views.Unchanged || results[1].Outcome != views.Unchanged {
+		t.Errorf("Expected the views unchanged, got %+v (%v)", results, err)
+	}
+
+	updated, _ := views.ParseFile("CREATE VIEW vx_host_cores AS SELECT main_fqdn, MAX(cpu_count) AS cores, 1 AS n FROM measurements GROUP BY main_fqdn")
+	results, err = views.Install(t.Context(), db, updated, "update.sql", false)
+	if err != nil || results[0].Outcome != views.Updated || len(results[0].Columns) != 3 {
+		t.Errorf("Expected vx_host_cores updated with 3 columns, got %+v (%v)", results, err)
+	}
+
+	// vx_large_hosts reads the cores column of vx_host_cores
+	broken, _ := views.ParseFile("CREATE VIEW vx_host_cores AS SELECT main_fqdn FROM measurements")
+	if _, err := views.Install(t.Context(), db, broken, "broken.sql", false); err == nil {
+		t.Error("Expected the install breaking vx_large_hosts to fail")
+	}
+	invalid, _ := views.ParseFile("CREATE VIEW vx_invalid AS SELECT missing FROM measurements")
+	if _, err := views.Install(t.Context(), db, invalid, "invalid.sql", false); err == nil {
+		t.Error("Expected an invalid view to fail")
+	}
+
+	if _, err := db.Exec("CREATE VIEW vx_manual AS SELECT 1 AS one"); err != nil {
+		t.Fatal(err)
+	}
+	list, err := views.List(t.Context(), db)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 3 || list[0].Name != "vx_host_cores" || len(list[0].Columns) != 3 || list[0].SourceFile != "update.sql" {
+		t.Fatalf("Unexpected views: %+v", list)
+	}
+	if list[2].Name != "vx_manual" || list[2].InstalledAt != "" || list[2].Err != nil {
+		t.Errorf("Expected the view created by hand to be listed, got %+v", list[2])
+	}
+
+	if err := views.Drop(t.Context(), db, []string{"v_peak_usage"}); err == nil {
+		t.Error("Expected a built-in view to be refused")
+	}
+	if err := views.Drop(t.Context(), db, []string{"vx_large_hosts", "vx_manual"}); err != nil {
+		t.Fatalf("Drop failed: %v", err)
+	}
+	if list, _ := views.List(t.Context(), db); len(list) != 1 {
+		t.Errorf("Expected 1 view left, got %d", len(list))
+	}
+}
@@ -12,6 +12,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+// Package views installs and lists the custom reporting views of the database.
 package views
 
 import (
@@ -19,7 +20,7 @@ import (
 )
 
 // DataView provides methods for extracting data according to different views
-// NOTE: This package is a placeholder from the seed project template.
+// NOTE: DataView is a placeholder from the seed project template.
 // It is not currently used by the license monitor application.
 // Kept for potential future use.
 type DataView struct {
@@ -0,0 +1,123 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// inventoryRow is a node with its latest measurement
+type inventoryRow struct {
+	MainFQDN       string
+	Mode           string
+	OS             string
+	CPUCount       int
+	Virtualized    string
+	ConsideredCPUs int
+	PhysicalHostID string
+	LastSeen       string
+	Products       string // products running at the latest measurement
+}
+
+// failedImportRow is a file waiting in the dead-letter queue
+type failedImportRow struct {
+	FilePath     string
+	ErrorMessage string
+	Attempts     int
+	LastFailedAt string
+}
+
+// queryInventory lists the nodes with their latest measurement from
+// v_latest_measurements. host filters by a substring of the FQDN.
+func queryInventory(ctx context.Context, db *sql.DB, host, mode string) ([]inventoryRow, error) {
+	query := `
+		SELECT
+			m.main_fqdn,
+			n.mode,
+			TRIM(m.os_name || ' ' || m.os_version),
+			m.cpu_count,
+			m.is_virtualized,
+			m.considered_cpus,
+			COALESCE(m.physical_host_id, ''),
+			strftime('%Y-%m-%d %H:%M', m.detection_timestamp),
+			COALESCE((SELECT GROUP_CONCAT(d.product_mnemo_code, ' ')
+			          FROM detected_products d
+			          WHERE d.main_fqdn = m.main_fqdn
+			            AND d.detection_timestamp = m.detection_timestamp
+			            AND d.status = 'present'), '')
+		FROM v_latest_measurements m
+		JOIN landscape_nodes n ON m.main_fqdn = n.main_fqdn
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if host != "" {
+		query += " AND m.main_fqdn LIKE ?"
+		args = append(args, "%"+host+"%")
+	}
+
+	if mode != "" {
+		query += " AND n.mode = ?"
+		args = append(args, mode)
+	}
+
+	query += " ORDER BY m.main_fqdn"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query host inventory: %w", err)
+	}
+	defer rows.Close()
+
+	var results []inventoryRow
+	for rows.Next() {
+		var row inventoryRow
+		err := rows.Scan(&row.MainFQDN, &row.Mode, &row.OS, &row.CPUCount, &row.Virtualized,
+			&row.ConsideredCPUs, &row.PhysicalHostID, &row.LastSeen, &row.Products)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		results = append(results, row)
+	}
+
+	return results, rows.Err()
+}
+
+// queryFailedImports lists the files of the failed_imports dead-letter queue
+func queryFailedImports(ctx context.Context, db *sql.DB) ([]failedImportRow, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT file_path, error_message, attempt_count, strftime('%Y-%m-%d %H:%M:%S', last_failed_at)
+		FROM failed_imports
+		ORDER BY last_failed_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failed imports: %w", err)
+	}
+	defer rows.Close()
+
+	var results []failedImportRow
+	for rows.Next() {
+		var row failedImportRow
+		if err := rows.Scan(&row.FilePath, &row.ErrorMessage, &row.Attempts, &row.LastFailedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		results = append(results, row)
+	}
+
+	return results, rows.Err()
+}
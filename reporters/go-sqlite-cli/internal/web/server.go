@@ -0,0 +1,368 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package web serves a read-only dashboard over the reporting views: compliance
+// status, peak usage, host inventory, import history and the job queue.
+package web
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/health"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/jobs"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// pages are the dashboard pages, each rendered with the layout template
+var pages = []string{"compliance", "peak", "hosts", "imports", "jobs"}
+
+// importHistoryLimit is the number of import sessions shown on the imports page
+const importHistoryLimit = 200
+
+// jobListLimit is the number of jobs shown on the jobs page
+const jobListLimit = 200
+
+// DefaultQueryTimeout is the default time the queries of a request may take
+const DefaultQueryTimeout = time.Minute
+
+// Server serves the dashboard pages
+type Server struct {
+	db        *sql.DB
+	templates map[string]*template.Template
+
+	// Health are the thresholds of the checks of /healthz
+	Health health.Options
+
+	// QueryTimeout cancels the queries of a request taking longer; 0 for no
+	// limit. The queries of a request are also cancelled when its client goes
+	// away.
+	QueryTimeout time.Duration
+}
+
+// NewServer creates a dashboard server reading from db
+func NewServer(db *sql.DB) (*Server, error) {
+	funcs := template.FuncMap{
+		"optional": func(v *int) string {
+			if v == nil {
+				return "N/A"
+			}
+			return fmt.Sprintf("%d", *v)
+		},
+		"date": func(t time.Time) string {
+			return t.Format("2006-01-02")
+		},
+		"join": strings.Join,
+	}
+
+	templates := make(map[string]*template.Template)
+	for _, page := range pages {
+		tmpl, err := template.New(page).Funcs(funcs).ParseFS(templateFS, "templates/layout.html", "templates/"+page+".html")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s template: %w", page, err)
+		}
+		templates[page] = tmpl
+	}
+
+	return &Server{db: db, templates: templates, Health: health.DefaultOptions(), QueryTimeout: DefaultQueryTimeout}, nil
+}
+
+// Handler returns the HTTP handler of the dashboard
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, "/compliance", http.StatusFound)
+	})
+	mux.HandleFunc("/compliance", s.readOnly(s.handleCompliance))
+	mux.HandleFunc("/peak", s.readOnly(s.handlePeak))
+	mux.HandleFunc("/hosts", s.readOnly(s.handleHosts))
+	mux.HandleFunc("/imports", s.readOnly(s.handleImports))
+	mux.HandleFunc("/jobs", s.readOnly(s.handleJobs))
+	mux.HandleFunc("/healthz", s.readOnly(s.handleHealth))
+	return mux
+}
+
+// pageData is passed to the page templates
+type pageData struct {
+	Page   string
+	Title  string
+	Filter filter
+	Error  string
+	Note   string
+	Rows   interface{}
+	Extra  interface{}
+}
+
+// filter holds the query string filters shared by the pages
+type filter struct {
+	Product string
+	Mode    string
+	From    string
+	To      string
+	Host    string
+
+	mode     string
+	fromDate *time.Time
+	toDate   *time.Time
+}
+
+// parseFilter reads the filters of the request
+func parseFilter(r *http.Request) (filter, error) {
+	q := r.URL.Query()
+	f := filter{
+		Product: q.Get("product"),
+		Mode:    q.Get("mode"),
+		From:    q.Get("from"),
+		To:      q.Get("to"),
+		Host:    q.Get("host"),
+	}
+
+	var err error
+	if f.mode, err = reports.ParseMode(f.Mode); err != nil {
+		return f, err
+	}
+	for _, d := range []struct {
+		value  string
+		target **time.Time
+	}{{f.From, &f.fromDate}, {f.To, &f.toDate}} {
+		if d.value == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", d.value)
+		if err != nil {
+			return f, fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", d.value)
+		}
+		*d.target = &t
+	}
+	return f, nil
+}
+
+// readOnly rejects every method but GET and HEAD, and bounds the queries of
+// the request with QueryTimeout
+func (s *Server) readOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if s.QueryTimeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), s.QueryTimeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		handler(w, r)
+	}
+}
+
+// queryErrorStatus is the status of a page whose query failed: 503 when the
+// query was cancelled by QueryTimeout, else 500
+func queryErrorStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) handleCompliance(w http.ResponseWriter, r *http.Request) {
+	data := pageData{Page: "compliance", Title: "Compliance status"}
+	f, err := parseFilter(r)
+	data.Filter = f
+	if err != nil {
+		s.render(w, http.StatusBadRequest, data.withError(err))
+		return
+	}
+
+	report := reports.NewComplianceReport(s.db)
+	rows, err := report.Query(r.Context(), f.Product, f.mode, f.fromDate, f.toDate, false)
+	if err != nil {
+		s.render(w, queryErrorStatus(err), data.withError(err))
+		return
+	}
+
+	// Without a date range only the latest measured day is shown
+	if f.fromDate == nil && f.toDate == nil && len(rows) > 0 {
+		latest := rows[0].MeasurementDate
+		n := 0
+		for n < len(rows) && rows[n].MeasurementDate.Equal(latest) {
+			n++
+		}
+		rows = rows[:n]
+		data.Note = fmt.Sprintf("Latest measured day %s. Set From/To for earlier days.", latest.Format("2006-01-02"))
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		s.writeCSV(w, "compliance.csv", func(out io.Writer) error { return report.WriteCSV(out, rows) })
+		return
+	}
+
+	data.Rows = rows
+	s.render(w, http.StatusOK, data)
+}
+
+func (s *Server) handlePeak(w http.ResponseWriter, r *http.Request) {
+	data := pageData{Page: "peak", Title: "Peak usage (last 31 days)"}
+	f, err := parseFilter(r)
+	data.Filter = f
+	if err != nil {
+		s.render(w, http.StatusBadRequest, data.withError(err))
+		return
+	}
+
+	report := reports.NewPeakUsageReport(s.db)
+	rows, err := report.Query(r.Context(), f.Product, f.mode)
+	if err != nil {
+		s.render(w, queryErrorStatus(err), data.withError(err))
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		s.writeCSV(w, "peak-usage.csv", func(out io.Writer) error { return report.WriteCSV(out, rows) })
+		return
+	}
+
+	data.Rows = rows
+	s.render(w, http.StatusOK, data)
+}
+
+func (s *Server) handleHosts(w http.ResponseWriter, r *http.Request) {
+	data := pageData{Page: "hosts", Title: "Host inventory"}
+	f, err := parseFilter(r)
+	data.Filter = f
+	if err != nil {
+		s.render(w, http.StatusBadRequest, data.withError(err))
+		return
+	}
+
+	rows, err := queryInventory(r.Context(), s.db, f.Host, f.mode)
+	if err != nil {
+		s.render(w, queryErrorStatus(err), data.withError(err))
+		return
+	}
+
+	data.Rows = rows
+	s.render(w, http.StatusOK, data)
+}
+
+func (s *Server) handleImports(w http.ResponseWriter, r *http.Request) {
+	data := pageData{Page: "imports", Title: "Import history"}
+
+	sessions, err := reports.NewImportSessionReport(s.db).Query(r.Context(), "", "", nil, nil)
+	if err != nil {
+		s.render(w, queryErrorStatus(err), data.withError(err))
+		return
+	}
+	failed, err := queryFailedImports(r.Context(), s.db)
+	if err != nil {
+		s.render(w, queryErrorStatus(err), data.withError(err))
+		return
+	}
+
+	if len(sessions) > importHistoryLimit {
+		sessions = sessions[:importHistoryLimit]
+		data.Note = fmt.Sprintf("Showing the latest %d imports.", importHistoryLimit)
+	}
+	data.Rows = sessions
+	data.Extra = failed
+	s.render(w, http.StatusOK, data)
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	data := pageData{Page: "jobs", Title: "Job queue"}
+
+	list, err := jobs.NewQueue(s.db).List("", jobListLimit)
+	if err != nil {
+		s.render(w, http.StatusInternalServerError, data.withError(err))
+		return
+	}
+
+	if len(list) == jobListLimit {
+		data.Note = fmt.Sprintf("Showing the latest %d jobs.", jobListLimit)
+	}
+	data.Rows = list
+	s.render(w, http.StatusOK, data)
+}
+
+// handleHealth answers the checks of 'iwldr health' as JSON for monitoring
+// agents and load balancers: 200 when the database is healthy, else 503
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	report := health.Run(s.db, s.Health)
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+// withError returns the page data with an error message and no rows
+func (d pageData) withError(err error) pageData {
+	d.Error = err.Error()
+	return d
+}
+
+// render executes the page template; the page is buffered so that a template
+// error results in a 500 response instead of a truncated page
+func (s *Server) render(w http.ResponseWriter, status int, data pageData) {
+	var buf bytes.Buffer
+	if err := s.templates[data.Page].ExecuteTemplate(&buf, "layout", data); err != nil {
+		log.Printf("Failed to render %s: %v", data.Page, err)
+		http.Error(w, "failed to render page", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// writeCSV sends a report as a CSV download
+func (s *Server) writeCSV(w http.ResponseWriter, name string, write func(io.Writer) error) {
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	buf.WriteTo(w)
+}
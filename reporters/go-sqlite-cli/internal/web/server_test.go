@@ -0,0 +1,140 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/web"
+)
+
+func setupServer(t *testing.T) http.Handler {
+	t.Helper()
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := database.Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	statements := []string{
+		`INSERT INTO license_terms (term_id, program_number, program_name) VALUES ('T1', '5900-AAA', 'Test Program')`,
+		`INSERT INTO product_codes (product_mnemo_code, ibm_product_code, product_name, mode, term_id)
+			VALUES ('IS_ONP_PRD', 'D0YYWZX', 'Integration Server', 'PROD', 'T1')`,
+		`INSERT INTO entitlements (term_id, licensed_cores) VALUES ('T1', 2)`,
+		`INSERT INTO landscape_nodes (main_fqdn, hostname, mode) VALUES ('vm1.example.com', 'vm1', 'PROD')`,
+		`INSERT INTO measurements (main_fqdn, detection_timestamp, os_name, os_version, cpu_count, is_virtualized,
+			processor_eligible, os_eligible, virt_eligible, considered_cpus)
+			VALUES ('vm1.example.com', '2025-10-21 09:09:06', 'Linux', '8', 4, 'yes', 'true', 'true', 'true', 4)`,
+		`INSERT INTO detected_products (main_fqdn, product_mnemo_code, detection_timestamp, status, install_count)
+			VALUES ('vm1.example.com', 'IS_ONP_PRD', '2025-10-21 09:09:06', 'present', 1)`,
+		`INSERT INTO import_sessions (session_id, source_file, hostname, status)
+			VALUES ('vm1_20251021_090906', 'iwdli_output_vm1_20251021_090906.csv', 'vm1', 'success')`,
+		`INSERT INTO jobs (kind, args, submitted_by) VALUES ('report', '["compliance","--output","c.csv"]', 'alice')`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Exec failed: %v\n%s", err, stmt)
+		}
+	}
+	db.Close()
+
+	ro, err := database.ConnectReadOnly(dbPath)
+	if err != nil {
+		t.Fatalf("ConnectReadOnly failed: %v", err)
+	}
+	t.Cleanup(func() { ro.Close() })
+
+	server, err := web.NewServer(ro)
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	return server.Handler()
+}
+
+func TestPages(t *testing.T) {
+	handler := setupServer(t)
+
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{"/compliance", http.StatusOK, "under-licensed"},
+		{"/compliance?mode=PROD&from=2025-10-01", http.StatusOK, "IS_ONP_PRD"},
+		{"/compliance?mode=NON+PROD", http.StatusOK, "No data found"},
+		{"/compliance?mode=TEST", http.StatusBadRequest, "invalid mode"},
+		{"/compliance?from=21.10.2025", http.StatusBadRequest, "invalid date"},
+		{"/compliance?format=csv", http.StatusOK, "measurement_date,product_mnemo_code"},
+		{"/peak", http.StatusOK, "Peak usage"},
+		{"/hosts?host=vm1", http.StatusOK, "vm1.example.com"},
+		{"/imports", http.StatusOK, "iwdli_output_vm1_20251021_090906.csv"},
+		{"/jobs", http.StatusOK, "compliance --output c.csv"},
+		{"/healthz", http.StatusOK, `"status": "ok"`},
+		{"/missing", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("Expected the response to contain %q:\n%s", tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestPagesAreReadOnly(t *testing.T) {
+	handler := setupServer(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/imports", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/compliance" {
+		t.Errorf("Expected a redirect to /compliance, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	handler := setupServer(t)
+
+	// The queries of a request whose deadline passed are not run
+	ctx, cancel := context.WithDeadline(t.Context(), time.Now().Add(-time.Second))
+	defer cancel()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/compliance", nil).WithContext(ctx))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "deadline exceeded") {
+		t.Errorf("Expected status 503, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package xlsx writes minimal Office Open XML workbooks using only the
+// standard library, so the reporter binary stays dependency-free on AIX.
+// It supports multiple sheets, a styled header row, frozen header panes and
+// numeric cell detection; nothing more is needed for tabular reports.
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Sheet is a single worksheet with a header row and data rows
+type Sheet struct {
+	Name   string
+	Header []string
+	Rows   [][]string
+}
+
+// Workbook is an ordered collection of sheets
+type Workbook struct {
+	Sheets []Sheet
+}
+
+// AddSheet appends a sheet, making its name valid and unique within the workbook
+func (wb *Workbook) AddSheet(name string, header []string, rows [][]string) {
+	wb.Sheets = append(wb.Sheets, Sheet{
+		Name:   wb.uniqueName(sanitizeSheetName(name)),
+		Header: header,
+		Rows:   rows,
+	})
+}
+
+// Write serializes the workbook as an .xlsx (zip) stream
+func (wb *Workbook) Write(w io.Writer) error {
+	if len(wb.Sheets) == 0 {
+		return fmt.Errorf("workbook has no sheets")
+	}
+
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", wb.contentTypes()},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", wb.workbookXML()},
+		{"xl/_rels/workbook.xml.rels", wb.workbookRels()},
+		{"xl/styles.xml", stylesXML},
+	}
+	for _, f := range files {
+		if err := writeZipFile(zw, f.name, f.content); err != nil {
+			return err
+		}
+	}
+
+	for i, sheet := range wb.Sheets {
+		name := fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)
+		if err := writeZipFile(zw, name, sheet.xml()); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func writeZipFile(zw *zip.Writer, name, content string) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	if _, err := io.WriteString(fw, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// sanitizeSheetName applies Excel's sheet naming rules (max 31 chars, no []:*?/\)
+func sanitizeSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	name = strings.Trim(name, "'")
+	if name == "" {
+		name = "Sheet"
+	}
+	return truncateName(name, 31)
+}
+
+// truncateName shortens name to at most max characters, never splitting a rune
+func truncateName(name string, max int) string {
+	if r := []rune(name); len(r) > max {
+		return string(r[:max])
+	}
+	return name
+}
+
+// uniqueName appends a counter when a sheet name is already taken
+func (wb *Workbook) uniqueName(name string) string {
+	taken := func(n string) bool {
+		for _, s := range wb.Sheets {
+			if strings.EqualFold(s.Name, n) {
+				return true
+			}
+		}
+		return false
+	}
+
+	candidate := name
+	for i := 2; taken(candidate); i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		candidate = truncateName(name, 31-len(suffix)) + suffix
+	}
+	return candidate
+}
+
+func (wb *Workbook) contentTypes() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range wb.Sheets {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func (wb *Workbook) workbookXML() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, s := range wb.Sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(s.Name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	return b.String()
+}
+
+func (wb *Workbook) workbookRels() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range wb.Sheets {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(wb.Sheets)+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+// xml renders the worksheet; the header row uses style 1 and is frozen
+func (s Sheet) xml() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0">`)
+	b.WriteString(`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`)
+	b.WriteString(`<selection pane="bottomLeft" activeCell="A2" sqref="A2"/>`)
+	b.WriteString(`</sheetView></sheetViews>`)
+
+	if widths := s.columnWidths(); len(widths) > 0 {
+		b.WriteString(`<cols>`)
+		for i, width := range widths {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width)
+		}
+		b.WriteString(`</cols>`)
+	}
+
+	b.WriteString(`<sheetData>`)
+	writeRow(&b, 1, s.Header, true)
+	for i, row := range s.Rows {
+		writeRow(&b, i+2, row, false)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// columnWidths sizes columns to their longest value, within sensible bounds
+func (s Sheet) columnWidths() []int {
+	widths := make([]int, len(s.Header))
+	measure := func(row []string) {
+		for i, v := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if l := len(v) + 2; l > widths[i] {
+				widths[i] = l
+			}
+		}
+	}
+	measure(s.Header)
+	for _, row := range s.Rows {
+		measure(row)
+	}
+	for i := range widths {
+		if widths[i] < 8 {
+			widths[i] = 8
+		}
+		if widths[i] > 60 {
+			widths[i] = 60
+		}
+	}
+	return widths
+}
+
+func writeRow(b *strings.Builder, rowNum int, values []string, header bool) {
+	fmt.Fprintf(b, `<row r="%d">`, rowNum)
+	for col, v := range values {
+		ref := ColumnName(col) + strconv.Itoa(rowNum)
+		switch {
+		case header:
+			fmt.Fprintf(b, `<c r="%s" t="inlineStr" s="1"><is><t>%s</t></is></c>`, ref, escape(v))
+		case v == "":
+			// Leave empty cells out entirely
+		case isNumeric(v):
+			fmt.Fprintf(b, `<c r="%s"><v>%s</v></c>`, ref, v)
+		default:
+			fmt.Fprintf(b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, escape(v))
+		}
+	}
+	b.WriteString(`</row>`)
+}
+
+// ColumnName converts a zero-based column index to its letter name (0 -> A, 26 -> AA)
+func ColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// isNumeric reports whether a value should be stored as a number. Values with
+// leading zeros (e.g. codes) are kept as text so they round-trip unchanged.
+func isNumeric(v string) bool {
+	if len(v) > 15 {
+		return false
+	}
+	digits := strings.TrimPrefix(v, "-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return false
+	}
+	_, err := strconv.ParseFloat(v, 64)
+	return err == nil && !strings.ContainsAny(v, "eEInfinityNaN+")
+}
+
+func escape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+const rootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// stylesXML defines style 0 (default) and style 1 (bold white on dark blue header)
+const stylesXML = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font>` +
+	`<font><b/><sz val="11"/><color rgb="FFFFFFFF"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FF1F4E78"/><bgColor indexed="64"/></patternFill></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/></cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
@@ -0,0 +1,127 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xlsx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/xlsx"
+)
+
+func TestColumnName(t *testing.T) {
+	tests := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"}
+	for index, want := range tests {
+		if got := xlsx.ColumnName(index); got != want {
+			t.Errorf("ColumnName(%d) = %s, want %s", index, got, want)
+		}
+	}
+}
+
+func TestWorkbookWrite(t *testing.T) {
+	wb := &xlsx.Workbook{}
+	wb.AddSheet("IS_ONP_PRD", []string{"date", "cores", "code"}, [][]string{
+		{"2025-10-31", "48", "0042"},
+		{"2025-10-30", "16", "<tag> & more"},
+	})
+	wb.AddSheet("IS_ONP_PRD", []string{"a"}, nil)                          // duplicate name
+	wb.AddSheet("a/very:long*sheet?name[with]bad\\chars", []string{"a"}, nil) // invalid chars
+
+	var buf bytes.Buffer
+	if err := wb.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Output is not a valid zip: %v", err)
+	}
+
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(data)
+
+		// Every part must be well-formed XML
+		dec := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed XML: %v", f.Name, err)
+			}
+		}
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "xl/workbook.xml", "xl/styles.xml",
+		"xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml", "xl/worksheets/sheet3.xml"} {
+		if _, ok := contents[name]; !ok {
+			t.Errorf("Missing part %s", name)
+		}
+	}
+
+	sheet1 := contents["xl/worksheets/sheet1.xml"]
+	if !strings.Contains(sheet1, `state="frozen"`) {
+		t.Error("Header row is not frozen")
+	}
+	if !strings.Contains(sheet1, `<c r="B2"><v>48</v></c>`) {
+		t.Error("Numeric value not stored as number")
+	}
+	if !strings.Contains(sheet1, `0042`) || strings.Contains(sheet1, `<v>0042</v>`) {
+		t.Error("Value with leading zero should be stored as text")
+	}
+	if !strings.Contains(sheet1, `&lt;tag&gt; &amp; more`) {
+		t.Error("Text not escaped")
+	}
+
+	workbook := contents["xl/workbook.xml"]
+	if !strings.Contains(workbook, `name="IS_ONP_PRD (2)"`) {
+		t.Error("Duplicate sheet name not made unique")
+	}
+	if strings.ContainsAny(wb.Sheets[2].Name, `[]:*?/\`) || len(wb.Sheets[2].Name) > 31 {
+		t.Errorf("Sheet name not sanitized: %q", wb.Sheets[2].Name)
+	}
+}
+
+func TestWorkbookSheetNameLength(t *testing.T) {
+	long := strings.Repeat("Übersicht", 5) // 45 characters, 50 bytes
+	wb := &xlsx.Workbook{}
+	wb.AddSheet(long, []string{"a"}, nil)
+	wb.AddSheet(long, []string{"a"}, nil)
+
+	for i, want := range []string{string([]rune(long)[:31]), string([]rune(long)[:27]) + " (2)"} {
+		name := wb.Sheets[i].Name
+		if !utf8.ValidString(name) || name != want {
+			t.Errorf("Sheet %d name = %q, want %q", i+1, name, want)
+		}
+	}
+}
+
+func TestWorkbookWriteRequiresSheet(t *testing.T) {
+	wb := &xlsx.Workbook{}
+	if err := wb.Write(io.Discard); err == nil {
+		t.Error("Expected error for empty workbook")
+	}
+}
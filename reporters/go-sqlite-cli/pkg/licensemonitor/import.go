@@ -0,0 +1,194 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licensemonitor
+
+import (
+	"context"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/importer"
+)
+
+// Results and errors of the imports
+type (
+	// ImportResult is the result of importing one inspector file
+	ImportResult = importer.ImportResult
+	// FileImportResult pairs an inspector file with the outcome of its import
+	FileImportResult = importer.FileImportResult
+	// BatchImportResult is the result of importing several files
+	BatchImportResult = importer.BatchImportResult
+	// ImportLockedError is returned when another process holds the import
+	// lock for longer than ImportOptions.LockWait
+	ImportLockedError = importer.ImportLockedError
+)
+
+// lockCommand is the command recorded with the import lock taken by the API
+const lockCommand = "licensemonitor"
+
+// ImportOptions are the options of the imports, those of 'iwldr import'
+type ImportOptions struct {
+	// Strict rejects files that detect product codes missing from the
+	// product codes, as --strict
+	Strict bool
+	// Force re-imports files whose content was already imported, as --force
+	Force bool
+	// MaxWarnings fails files with more warnings, as --max-warnings; 0 for no
+	// limit
+	MaxWarnings int
+	// ArchiveSource stores the content of the files with their import session,
+	// as --archive-source
+	ArchiveSource bool
+	// Org puts the nodes of the files in this organization, as --org
+	Org string
+	// DeferPartial leaves the files still being written for a later import
+	// instead of recording them as failed imports
+	DeferPartial bool
+	// LockWait is how long to wait for another import of the database to
+	// finish, as --wait; 0 fails at once with an ImportLockedError
+	LockWait time.Duration
+}
+
+// importService returns the import service of opts holding the import lock,
+// and a function releasing the lock
+func (m *Monitor) importService(opts ImportOptions) (*importer.ImportService, func(), error) {
+	if err := m.writable(); err != nil {
+		return nil, nil, err
+	}
+	lock, err := importer.AcquireImportLock(m.db, lockCommand, opts.LockWait)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	service := importer.NewImportService(m.db)
+	service.Strict = opts.Strict
+	service.Force = opts.Force
+	if opts.MaxWarnings > 0 {
+		service.MaxWarnings = opts.MaxWarnings
+	}
+	service.ArchiveSource = opts.ArchiveSource
+	service.Org = opts.Org
+	service.DeferPartial = opts.DeferPartial
+	service.Lock = lock
+	return service, func() { lock.Release() }, nil
+}
+
+// ImportFile imports one inspector file. A file whose content was already
+// imported is skipped, with ImportResult.AlreadyImported set, unless Force is
+// set. A file that fails is not recorded in the failed imports; see
+// ImportFiles.
+func (m *Monitor) ImportFile(ctx context.Context, path string, opts ImportOptions) (*ImportResult, error) {
+	service, release, err := m.importService(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return service.ImportCSVFile(ctx, path)
+}
+
+// ImportFiles imports inspector files, and the inspector files of the
+// bundles (.zip, .tar.gz) among them, one after the other as 'iwldr import'
+// does: the files that fail are recorded in the failed imports, for
+// 'iwldr import retry-failed'. onFile, when not nil, is called after each
+// file. Cancelling ctx stops the import at the file being imported, which is
+// rolled back; BatchImportResult.Err is then set.
+func (m *Monitor) ImportFiles(ctx context.Context, paths []string, opts ImportOptions, onFile func(index int, fr FileImportResult)) (*BatchImportResult, error) {
+	service, release, err := m.importService(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return service.ImportFiles(ctx, importer.ExpandBundles(paths), onFile), nil
+}
+
+// ImportDirectory imports the inspector files and bundles found under dir,
+// as ImportFiles
+func (m *Monitor) ImportDirectory(ctx context.Context, dir string, opts ImportOptions, onFile func(index int, fr FileImportResult)) (*BatchImportResult, error) {
+	files, err := importer.FindInspectorFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return m.ImportFiles(ctx, files, opts, onFile)
+}
+
+// referenceLoader returns the loader of the reference data
+func (m *Monitor) referenceLoader() (*importer.ReferenceDataLoader, error) {
+	if err := m.writable(); err != nil {
+		return nil, err
+	}
+	loader := importer.NewReferenceDataLoader(m.db)
+	loader.ChangedBy = m.ChangedBy
+	return loader, nil
+}
+
+// LoadLicenseTerms loads the license terms of a CSV file, as
+// 'iwldr import --license-terms'
+func (m *Monitor) LoadLicenseTerms(ctx context.Context, path string) error {
+	loader, err := m.referenceLoader()
+	if err != nil {
+		return err
+	}
+	return loader.LoadLicenseTermsCSV(ctx, path)
+}
+
+// LoadProductCodes loads the product codes of a CSV file, as
+// 'iwldr import --product-codes'; their license terms must be loaded first
+func (m *Monitor) LoadProductCodes(ctx context.Context, path string) error {
+	loader, err := m.referenceLoader()
+	if err != nil {
+		return err
+	}
+	return loader.LoadProductCodesCSV(ctx, path)
+}
+
+// LoadEntitlements loads the licensed capacity per license term of a CSV file,
+// as 'iwldr import entitlements'
+func (m *Monitor) LoadEntitlements(ctx context.Context, path string) error {
+	loader, err := m.referenceLoader()
+	if err != nil {
+		return err
+	}
+	return loader.LoadEntitlementsCSV(ctx, path)
+}
+
+// LoadPVUMappings loads the PVU per core of processor models of a CSV file, as
+// 'iwldr import pvu'
+func (m *Monitor) LoadPVUMappings(ctx context.Context, path string) error {
+	loader, err := m.referenceLoader()
+	if err != nil {
+		return err
+	}
+	return loader.LoadPVUMappingsCSV(ctx, path)
+}
+
+// LoadThresholds loads the core thresholds of the products of a CSV file, as
+// 'iwldr import thresholds'
+func (m *Monitor) LoadThresholds(ctx context.Context, path string) error {
+	loader, err := m.referenceLoader()
+	if err != nil {
+		return err
+	}
+	return loader.LoadProductThresholdsCSV(ctx, path)
+}
+
+// LoadCatalog seeds the license terms and product codes with the catalog of
+// webMethods products built into iwldr, keeping those already loaded unless
+// overwrite is set, as 'iwldr reference bootstrap'
+func (m *Monitor) LoadCatalog(ctx context.Context, overwrite bool) error {
+	loader, err := m.referenceLoader()
+	if err != nil {
+		return err
+	}
+	return loader.LoadCatalog(ctx, overwrite)
+}
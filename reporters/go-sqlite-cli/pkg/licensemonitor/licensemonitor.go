@@ -0,0 +1,149 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package licensemonitor is the Go API of the iwldr engine, for tools that
+// embed it instead of running the iwldr command line. It opens a license
+// monitor database, imports inspector files and reference data into it and
+// queries the usage reports, with the same rules as the commands:
+//
+//	m, err := licensemonitor.Open("data/license-monitor.db")
+//	if err != nil {
+//		return err
+//	}
+//	defer m.Close()
+//
+//	if _, err := m.ImportFile(ctx, "iwdli_output_app01_20251021.csv", licensemonitor.ImportOptions{}); err != nil {
+//		return err
+//	}
+//	rows, err := m.QueryPeakUsage(ctx, licensemonitor.Filter{Mode: "PROD"})
+//
+// Every method takes a context; cancelling it stops the query, or rolls back
+// the file being imported. The rows returned are the ones the reports of the
+// command line print, with the same JSON field names.
+package licensemonitor
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/database"
+)
+
+// ErrReadOnly is returned by the methods writing to a database opened with
+// OpenReadOnly
+var ErrReadOnly = errors.New("database opened read-only")
+
+// Monitor is an open license monitor database. A Monitor is safe for
+// concurrent use; imports into the same database are serialized by the import
+// lock shared with the iwldr processes.
+type Monitor struct {
+	db       *sql.DB
+	readOnly bool
+
+	// ChangedBy is recorded as the user of the reference data changes in
+	// their history tables
+	ChangedBy string
+
+	// GraceDays is the grace window of the smoothed peak for products without
+	// their own (see 'iwldr import peak-grace'), as --grace-days
+	GraceDays int
+	// CarryForwardDays carries the last measurement of a node forward over the
+	// days it was not measured, up to this many days, as --carry-forward
+	CarryForwardDays int
+}
+
+// Open opens the database at path for reading and writing. The database is
+// created when it does not exist, as 'iwldr init' does, and the schema and
+// views of an existing one upgraded to those of this build, as 'iwldr db
+// upgrade' does. The path ":memory:" opens an in-memory database discarded by
+// Close, e.g. for tests.
+func Open(path string) (*Monitor, error) {
+	db, err := database.Connect(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := openSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Monitor{db: db}, nil
+}
+
+// openSchema initializes the schema of a new database, or upgrades the one of
+// an existing database
+func openSchema(db *sql.DB) error {
+	var initialized int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_metadata'`).Scan(&initialized); err != nil {
+		return fmt.Errorf("failed to read the schema: %w", err)
+	}
+	if initialized == 0 {
+		if err := database.InitSchema(db); err != nil {
+			return fmt.Errorf("failed to initialize the schema: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := database.UpgradeSchema(db); err != nil {
+		return fmt.Errorf("failed to upgrade the schema: %w", err)
+	}
+	version, err := database.GetCurrentViewsVersion(db)
+	if err != nil {
+		return err
+	}
+	if version != database.GetViewsVersion() {
+		if _, err := database.UpgradeViews(db); err != nil {
+			return fmt.Errorf("failed to upgrade the views: %w", err)
+		}
+	}
+	return nil
+}
+
+// OpenReadOnly opens an existing database for queries only, as the report
+// commands do. The database must have been initialized.
+func OpenReadOnly(path string) (*Monitor, error) {
+	db, err := database.ConnectReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := database.VerifySchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Monitor{db: db, readOnly: true}, nil
+}
+
+// Close closes the database
+func (m *Monitor) Close() error {
+	return m.db.Close()
+}
+
+// DB returns the database, for queries of the reporting views the API does not
+// cover. The schema is documented in the sql directory of the repository.
+func (m *Monitor) DB() *sql.DB {
+	return m.db
+}
+
+// SchemaVersion returns the schema version of the database
+func (m *Monitor) SchemaVersion() (string, error) {
+	return database.GetCurrentSchemaVersion(m.db)
+}
+
+// writable returns ErrReadOnly for a database opened read-only
+func (m *Monitor) writable() error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licensemonitor_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/pkg/licensemonitor"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "license-monitor.db")
+
+	m, err := licensemonitor.Open(dbPath)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer m.Close()
+
+	termsPath := filepath.Join(dir, "license-terms.csv")
+	writeFile(t, termsPath, "license-terms-id,program-number,program-name\nT1,5900-AAA,Test Program\n")
+	productsPath := filepath.Join(dir, "product-codes.csv")
+	writeFile(t, productsPath, "product-mnemo-id,product-code,product-name,mode,license-terms-id,notes\n"+
+		"IS_ONP_PRD,D0YYWZX,Integration Server,PROD,T1,\n")
+	if err := m.LoadLicenseTerms(t.Context(), termsPath); err != nil {
+		t.Fatalf("LoadLicenseTerms failed: %v", err)
+	}
+	if err := m.LoadProductCodes(t.Context(), productsPath); err != nil {
+		t.Fatalf("LoadProductCodes failed: %v", err)
+	}
+
+	// Measured today, so that it counts in the peak of the last 31 days
+	today := time.Now().UTC()
+	csvPath := filepath.Join(dir, "iwdli_output_app01_"+today.Format("20060102_150405")+".csv")
+	writeFile(t, csvPath, fmt.Sprintf(`Parameter,Value
+DETECTION_TIMESTAMP,%s
+OS_NAME,Linux
+OS_VERSION,8
+CPU_COUNT,4
+IS_VIRTUALIZED,no
+PROCESSOR_ELIGIBLE,true
+OS_ELIGIBLE,true
+VIRT_ELIGIBLE,true
+CONSIDERED_CPUS,4
+IS_ONP_PRD,present
+IS_ONP_PRD_INSTALL_COUNT,1
+`, today.Format(time.RFC3339)))
+
+	result, err := m.ImportFile(t.Context(), csvPath, licensemonitor.ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportFile failed: %v", err)
+	}
+	if result.SessionID == "" || result.AlreadyImported {
+		t.Errorf("Unexpected import result: %+v", result)
+	}
+
+	batch, err := m.ImportDirectory(t.Context(), dir, licensemonitor.ImportOptions{}, nil)
+	if err != nil {
+		t.Fatalf("ImportDirectory failed: %v", err)
+	}
+	if batch.FilesSkipped != 1 {
+		t.Errorf("Expected the file imported before to be skipped, got %+v", batch)
+	}
+
+	peaks, err := m.QueryPeakUsage(t.Context(), licensemonitor.Filter{Mode: "prod"})
+	if err != nil {
+		t.Fatalf("QueryPeakUsage failed: %v", err)
+	}
+	if len(peaks) != 1 || peaks[0].ProductMnemoCode != "IS_ONP_PRD" || peaks[0].PeakRunningTotalCores != 4 {
+		t.Errorf("Unexpected peak usage: %+v", peaks)
+	}
+	if _, err := m.QueryPeakUsage(t.Context(), licensemonitor.Filter{Mode: "TEST"}); err == nil {
+		t.Error("Expected an error for an invalid mode")
+	}
+
+	days, err := m.QueryDailySummary(t.Context(), licensemonitor.Filter{From: today.AddDate(0, 0, -1)})
+	if err != nil || len(days) != 1 {
+		t.Errorf("Expected the day of the measurement, got %+v (%v)", days, err)
+	}
+	if days, err := m.QueryDailySummary(t.Context(), licensemonitor.Filter{To: today.AddDate(0, 0, -1)}); err != nil || len(days) != 0 {
+		t.Errorf("Expected no day before the measurement, got %+v (%v)", days, err)
+	}
+	if rows, err := m.QueryCompliance(t.Context(), licensemonitor.Filter{ProductCode: "IS_ONP_PRD"}, false); err != nil || len(rows) != 1 {
+		t.Errorf("Expected the compliance of the measurement, got %+v (%v)", rows, err)
+	}
+
+	// A read-only monitor queries but does not write
+	ro, err := licensemonitor.OpenReadOnly(dbPath)
+	if err != nil {
+		t.Fatalf("OpenReadOnly failed: %v", err)
+	}
+	defer ro.Close()
+	if rows, err := ro.QueryMonthlyPeak(t.Context(), licensemonitor.Filter{}); err != nil || len(rows) != 1 {
+		t.Errorf("Expected the month of the measurement, got %+v (%v)", rows, err)
+	}
+	if _, err := ro.ImportFile(t.Context(), csvPath, licensemonitor.ImportOptions{}); !errors.Is(err, licensemonitor.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+
+	if _, err := licensemonitor.OpenReadOnly(filepath.Join(dir, "missing.db")); err == nil {
+		t.Error("Expected an error for a missing database")
+	}
+}
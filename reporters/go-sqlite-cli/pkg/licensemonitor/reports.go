@@ -0,0 +1,123 @@
+// Copyright 2025 Mihai Ungureanu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licensemonitor
+
+import (
+	"context"
+	"time"
+
+	"github.com/ibm-webmethods-aftermarket-tools/iwldr/internal/reports"
+)
+
+// Rows of the reports
+type (
+	// PeakUsageRow is a row of the peak usage report, 'iwldr report peak'
+	PeakUsageRow = reports.PeakUsageRow
+	// MonthlyPeakRow is a row of the monthly peak report,
+	// 'iwldr report monthly-peak'
+	MonthlyPeakRow = reports.MonthlyPeakRow
+	// DailySummaryRow is a row of the daily summary report,
+	// 'iwldr report daily-summary'
+	DailySummaryRow = reports.DailySummaryRow
+	// ComplianceRow is a row of the compliance report,
+	// 'iwldr report compliance'
+	ComplianceRow = reports.ComplianceRow
+)
+
+// Filter selects the rows of the reports, as the --product, --mode, --from,
+// --to and --period flags of the report commands
+type Filter struct {
+	// ProductCode keeps the rows of one product code; empty for all
+	ProductCode string
+	// Mode keeps the rows of PROD or NON-PROD nodes; empty for both
+	Mode string
+	// From and To bound the days of the rows, both included; the zero time
+	// leaves the bound open
+	From, To time.Time
+	// Period computes the peaks over a contract period of the license terms,
+	// current, previous or the label of a period; see 'iwldr terms period'
+	Period string
+}
+
+// mode returns the validated mode of the filter
+func (f Filter) mode() (string, error) {
+	return reports.ParseMode(f.Mode)
+}
+
+// dates returns the date bounds of the filter, nil when open
+func (f Filter) dates() (from, to *time.Time) {
+	if !f.From.IsZero() {
+		from = &f.From
+	}
+	if !f.To.IsZero() {
+		to = &f.To
+	}
+	return from, to
+}
+
+// QueryPeakUsage returns the peak usage per product over the last 31 days, or
+// over the contract period of the filter. The dates of the filter are not
+// used.
+func (m *Monitor) QueryPeakUsage(ctx context.Context, f Filter) ([]PeakUsageRow, error) {
+	mode, err := f.mode()
+	if err != nil {
+		return nil, err
+	}
+	report := reports.NewPeakUsageReport(m.db)
+	report.SetDefaultGraceDays(m.GraceDays)
+	report.SetCarryForwardDays(m.CarryForwardDays)
+	if f.Period != "" {
+		return report.QueryPeriod(ctx, f.ProductCode, mode, f.Period)
+	}
+	return report.Query(ctx, f.ProductCode, mode)
+}
+
+// QueryMonthlyPeak returns the peak usage per product and month, over the
+// dates or the contract period of the filter
+func (m *Monitor) QueryMonthlyPeak(ctx context.Context, f Filter) ([]MonthlyPeakRow, error) {
+	mode, err := f.mode()
+	if err != nil {
+		return nil, err
+	}
+	report := reports.NewMonthlyPeakReport(m.db)
+	if f.Period != "" {
+		return report.QueryPeriod(ctx, f.ProductCode, mode, f.Period)
+	}
+	from, to := f.dates()
+	return report.Query(ctx, f.ProductCode, mode, from, to)
+}
+
+// QueryDailySummary returns the usage per product and day
+func (m *Monitor) QueryDailySummary(ctx context.Context, f Filter) ([]DailySummaryRow, error) {
+	mode, err := f.mode()
+	if err != nil {
+		return nil, err
+	}
+	from, to := f.dates()
+	return reports.NewDailySummaryReport(m.db).Query(ctx, f.ProductCode, mode, from, to)
+}
+
+// QueryCompliance returns the usage per product and day compared with the
+// entitlements and thresholds; nonCompliantOnly keeps the rows breaching them
+func (m *Monitor) QueryCompliance(ctx context.Context, f Filter, nonCompliantOnly bool) ([]ComplianceRow, error) {
+	mode, err := f.mode()
+	if err != nil {
+		return nil, err
+	}
+	report := reports.NewComplianceReport(m.db)
+	report.SetCarryForwardDays(m.CarryForwardDays)
+	from, to := f.dates()
+	return report.Query(ctx, f.ProductCode, mode, from, to, nonCompliantOnly)
+}